@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/mcp"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+)
+
+// Subcommands let shell scripts and editors capture and recall memories without an
+// MCP client:
+//
+//	remember-me-mcp login --url https://memory.example.com --api-key <key>
+//	remember-me-mcp search "coffee"
+//	remember-me-mcp store --type fact --category personal "Prefers espresso"
+//
+// They talk to the HTTP API when credentials are stored (or given through
+// REMEMBER_ME_API_URL and REMEMBER_ME_API_KEY) and to the database otherwise.
+// --local and --remote force either mode.
+
+// cliTimeout bounds a single CLI command
+const cliTimeout = 30 * time.Second
+
+// cliDrainTimeout bounds how long a local command waits on exit for the
+// embeddings of memories it stored
+const cliDrainTimeout = 10 * time.Second
+
+// Credentials are the HTTP API location and key saved by the login subcommand
+type Credentials struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+// memoryClient is the part of the memory API the CLI uses, served either by a
+// local memory service or by the HTTP API
+type memoryClient interface {
+	Search(ctx context.Context, req *services.SearchMemoriesRequest) ([]*models.Memory, error)
+	Store(ctx context.Context, req *services.StoreMemoryRequest) (*models.Memory, error)
+	Close() error
+}
+
+// isSubcommand reports whether the first argument names a CLI subcommand
+func isSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "search", "store", "login", "logout", "compact", "vector-index", "partition":
+		return true
+	}
+	return false
+}
+
+// runSubcommand runs a CLI subcommand and returns the process exit code
+func runSubcommand(args []string) int {
+	var err error
+	switch args[0] {
+	case "search":
+		err = runSearch(args[1:])
+	case "store":
+		err = runStore(args[1:])
+	case "login":
+		err = runLogin(args[1:])
+	case "logout":
+		err = runLogout()
+	case "compact":
+		err = runCompact(args[1:])
+	case "vector-index":
+		err = runVectorIndex(args[1:])
+	case "partition":
+		err = runPartition(args[1:])
+	}
+
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// connectionFlags are the flags shared by subcommands that reach the memory store
+type connectionFlags struct {
+	configPath string
+	local      bool
+	remote     bool
+	userID     uint
+	jsonOutput bool
+}
+
+func (f *connectionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.configPath, "config", "", "Path to configuration file (local mode)")
+	fs.BoolVar(&f.local, "local", false, "Use the database directly even if credentials are stored")
+	fs.BoolVar(&f.remote, "remote", false, "Use the HTTP API; fails if no credentials are stored")
+	fs.UintVar(&f.userID, "user-id", 1, "User whose memories are used (local mode)")
+	fs.BoolVar(&f.jsonOutput, "json", false, "Print results as JSON")
+}
+
+// client returns the memory client the flags select
+func (f *connectionFlags) client() (memoryClient, error) {
+	if f.local && f.remote {
+		return nil, fmt.Errorf("--local and --remote cannot be combined")
+	}
+
+	if !f.local {
+		creds, err := loadCredentials()
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			return newAPIClient(creds), nil
+		}
+		if f.remote {
+			return nil, fmt.Errorf("no credentials stored; run login first")
+		}
+	}
+
+	return newLocalClient(f.configPath, f.userID)
+}
+
+func runSearch(args []string) error {
+	var (
+		conn     connectionFlags
+		category string
+		memType  string
+		tags     string
+		limit    int
+		keyword  bool
+	)
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&category, "category", "", "Filter by category (personal, project, business)")
+	fs.StringVar(&memType, "type", "", "Filter by type (fact, conversation, context, preference)")
+	fs.StringVar(&tags, "tags", "", "Comma-separated tags to filter by")
+	fs.IntVar(&limit, "limit", 10, "Maximum number of results")
+	fs.BoolVar(&keyword, "keyword", false, "Use keyword instead of semantic search")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("usage: search [flags] \"query\"")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	memories, err := client.Search(ctx, &services.SearchMemoriesRequest{
+		Query:             query,
+		Category:          category,
+		Type:              memType,
+		Limit:             limit,
+		UseSemanticSearch: !keyword,
+		Tags:              splitTags(tags),
+	})
+	if err != nil {
+		return err
+	}
+
+	if conn.jsonOutput {
+		return printJSON(memories)
+	}
+	if len(memories) == 0 {
+		fmt.Fprintln(os.Stderr, "No memories found")
+		return nil
+	}
+	for _, memory := range memories {
+		printMemory(memory)
+	}
+	return nil
+}
+
+func runStore(args []string) error {
+	var (
+		conn     connectionFlags
+		category string
+		memType  string
+		tags     string
+	)
+	fs := flag.NewFlagSet("store", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&category, "category", models.CategoryPersonal, "Category (personal, project, business)")
+	fs.StringVar(&memType, "type", models.TypeFact, "Type (fact, conversation, context, preference)")
+	fs.StringVar(&tags, "tags", "", "Comma-separated tags")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// With no text argument, or "-", the content is read from stdin so editors can
+	// pipe a selection in
+	content := strings.Join(fs.Args(), " ")
+	if content == "" || content == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		content = string(data)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fmt.Errorf("usage: store [flags] \"text\" (or pipe the text on stdin)")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	memory, err := client.Store(ctx, &services.StoreMemoryRequest{
+		Type:     memType,
+		Category: category,
+		Content:  content,
+		Tags:     splitTags(tags),
+	})
+	if err != nil {
+		return err
+	}
+
+	if conn.jsonOutput {
+		return printJSON(memory)
+	}
+	fmt.Printf("Stored memory #%d\n", memory.ID)
+	return nil
+}
+
+func runLogin(args []string) error {
+	var creds Credentials
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	fs.StringVar(&creds.URL, "url", "", "Base URL of the HTTP API server, e.g. https://memory.example.com")
+	fs.StringVar(&creds.APIKey, "api-key", "", "API key created in the web UI or with POST /api/v1/keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if creds.URL == "" || creds.APIKey == "" {
+		return fmt.Errorf("usage: login --url <server> --api-key <key>")
+	}
+	creds.URL = strings.TrimRight(creds.URL, "/")
+	if _, err := url.ParseRequestURI(creds.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	// Check the key works before saving it. Every key type, backup keys included,
+	// may read the memory stats.
+	client := newAPIClient(&creds)
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+	var stats map[string]interface{}
+	if err := client.do(ctx, http.MethodGet, "/api/v1/memories/stats", nil, &stats); err != nil {
+		return fmt.Errorf("credentials were not accepted: %w", err)
+	}
+
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+
+	fmt.Printf("Logged in to %s\n", creds.URL)
+	return nil
+}
+
+func runLogout() error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove credentials: %w", err)
+	}
+	fmt.Println("Logged out")
+	return nil
+}
+
+// credentialsPath is where login saves credentials, next to the log directory
+func credentialsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "remember-me-mcp", "credentials.json"), nil
+}
+
+// loadCredentials returns the API credentials from the environment or the saved
+// credentials file, or nil if there are none
+func loadCredentials() (*Credentials, error) {
+	if apiURL, apiKey := os.Getenv("REMEMBER_ME_API_URL"), os.Getenv("REMEMBER_ME_API_KEY"); apiURL != "" && apiKey != "" {
+		return &Credentials{URL: strings.TrimRight(apiURL, "/"), APIKey: apiKey}, nil
+	}
+
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	if creds.URL == "" || creds.APIKey == "" {
+		return nil, nil
+	}
+	return &creds, nil
+}
+
+// localClient serves CLI commands from the database configured for the MCP server
+type localClient struct {
+	service *services.MemoryService
+	close   func() error
+}
+
+func newLocalClient(configPath string, userID uint) (*localClient, error) {
+	cfg, err := loadConfiguration(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logger := setupLogging(cfg)
+
+	db, err := connectToDatabase(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := buildServiceConfig(cfg, createEncryptionService(cfg, logger), logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Embeddings of stored memories are generated in the background; closing waits
+	// for them, and ones that do not finish are picked up by the server's
+	// embedding backfill
+	service := services.NewMemoryServiceWithUser(db.DB(), createEmbeddingService(cfg, logger), logger, serviceConfig, userID)
+	return &localClient{service: service, close: func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cliDrainTimeout)
+		defer cancel()
+		if err := service.Drain(ctx); err != nil {
+			logger.Warn().Err(err).Msg("Embeddings still being generated were not all queued")
+		}
+		return db.Close()
+	}}, nil
+}
+
+func (c *localClient) Search(ctx context.Context, req *services.SearchMemoriesRequest) ([]*models.Memory, error) {
+	return c.service.SearchMemories(ctx, req)
+}
+
+func (c *localClient) Store(ctx context.Context, req *services.StoreMemoryRequest) (*models.Memory, error) {
+	return c.service.StoreMemory(ctx, req)
+}
+
+func (c *localClient) Close() error {
+	return c.close()
+}
+
+// apiClient serves CLI commands from the HTTP API
+type apiClient struct {
+	creds      *Credentials
+	httpClient *http.Client
+}
+
+func newAPIClient(creds *Credentials) *apiClient {
+	return &apiClient{creds: creds, httpClient: &http.Client{Timeout: cliTimeout}}
+}
+
+func (c *apiClient) Search(ctx context.Context, req *services.SearchMemoriesRequest) ([]*models.Memory, error) {
+	params := url.Values{}
+	params.Set("query", req.Query)
+	params.Set("useSemanticSearch", strconv.FormatBool(req.UseSemanticSearch))
+	if req.Category != "" {
+		params.Set("category", req.Category)
+	}
+	if req.Type != "" {
+		params.Set("type", req.Type)
+	}
+	if req.Limit > 0 {
+		params.Set("limit", strconv.Itoa(req.Limit))
+	}
+	if len(req.Tags) > 0 {
+		params.Set("tags", strings.Join(req.Tags, ","))
+	}
+
+	var resp mcp.SearchMemoriesResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/memories?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Memories, nil
+}
+
+func (c *apiClient) Store(ctx context.Context, req *services.StoreMemoryRequest) (*models.Memory, error) {
+	body := mcp.StoreMemoryRequest{
+		Type:     req.Type,
+		Category: req.Category,
+		Content:  req.Content,
+		Tags:     req.Tags,
+		Metadata: req.Metadata,
+	}
+
+	var resp mcp.StoreMemoryResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/memories", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Memory == nil {
+		return nil, fmt.Errorf("server returned no memory")
+	}
+	return resp.Memory, nil
+}
+
+func (c *apiClient) Close() error {
+	return nil
+}
+
+// do sends an authenticated request and decodes the JSON response into out.
+// Error responses are reported with the server's error message.
+func (c *apiClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.creds.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", c.creds.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// splitTags parses a comma-separated tag list
+func splitTags(list string) []string {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func printMemory(memory *models.Memory) {
+	fmt.Printf("#%d [%s/%s] %s\n", memory.ID, memory.Category, memory.Type, memory.Content)
+	if len(memory.Tags) > 0 {
+		fmt.Printf("    tags: %s\n", strings.Join(memory.Tags, ", "))
+	}
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/mcp"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// isolateCredentials points the credentials file at an empty home directory and
+// clears the credentials environment, returning the credentials file path
+func isolateCredentials(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("REMEMBER_ME_API_URL", "")
+	t.Setenv("REMEMBER_ME_API_KEY", "")
+
+	path, err := credentialsPath()
+	require.NoError(t, err)
+	return path
+}
+
+// writeCredentials saves a credentials file with the given contents
+func writeCredentials(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+}
+
+func TestSplitTags(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single", "work", []string{"work"}},
+		{"several", "work,home,travel", []string{"work", "home", "travel"}},
+		{"spaces trimmed", " work , home ", []string{"work", "home"}},
+		{"empty entries dropped", "work,,  ,home,", []string{"work", "home"}},
+		{"only separators", " , ,", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitTags(tt.list))
+		})
+	}
+}
+
+func TestIsSubcommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"search", "coffee"}, true},
+		{[]string{"store"}, true},
+		{[]string{"login", "--url", "https://memory.example.com"}, true},
+		{[]string{"logout"}, true},
+		{[]string{"compact"}, true},
+		{[]string{"vector-index"}, true},
+		{[]string{"partition"}, true},
+		{[]string{"-config", "config.yaml"}, false},
+		{[]string{"serve"}, false},
+		{[]string{"Search"}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isSubcommand(tt.args), "args %q", tt.args)
+	}
+}
+
+func TestConnectionFlagsClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   connectionFlags
+		stored  string
+		wantURL string
+		wantErr string
+	}{
+		{
+			name:    "local and remote together",
+			flags:   connectionFlags{local: true, remote: true},
+			stored:  `{"url": "https://memory.example.com", "api_key": "key"}`,
+			wantErr: "--local and --remote cannot be combined",
+		},
+		{
+			name:    "remote without credentials",
+			flags:   connectionFlags{remote: true},
+			wantErr: "no credentials stored; run login first",
+		},
+		{
+			name:    "remote with credentials",
+			flags:   connectionFlags{remote: true},
+			stored:  `{"url": "https://memory.example.com", "api_key": "key"}`,
+			wantURL: "https://memory.example.com",
+		},
+		{
+			name:    "stored credentials select the API by default",
+			stored:  `{"url": "https://memory.example.com", "api_key": "key"}`,
+			wantURL: "https://memory.example.com",
+		},
+		{
+			name:    "unreadable credentials",
+			stored:  `{"url":`,
+			wantErr: "invalid credentials file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := isolateCredentials(t)
+			if tt.stored != "" {
+				writeCredentials(t, path, tt.stored)
+			}
+
+			client, err := tt.flags.client()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			api, ok := client.(*apiClient)
+			require.True(t, ok, "expected the HTTP API client, got %T", client)
+			assert.Equal(t, tt.wantURL, api.creds.URL)
+		})
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  string
+		envURL  string
+		envKey  string
+		want    *Credentials
+		wantErr bool
+	}{
+		{name: "no file"},
+		{name: "empty file", stored: "{}"},
+		{name: "file without a key", stored: `{"url": "https://memory.example.com"}`},
+		{
+			name:   "file",
+			stored: `{"url": "https://memory.example.com", "api_key": "file-key"}`,
+			want:   &Credentials{URL: "https://memory.example.com", APIKey: "file-key"},
+		},
+		{
+			name:   "environment overrides the file",
+			stored: `{"url": "https://memory.example.com", "api_key": "file-key"}`,
+			envURL: "https://other.example.com/",
+			envKey: "env-key",
+			want:   &Credentials{URL: "https://other.example.com", APIKey: "env-key"},
+		},
+		{
+			name:   "environment without a file",
+			envURL: "https://other.example.com",
+			envKey: "env-key",
+			want:   &Credentials{URL: "https://other.example.com", APIKey: "env-key"},
+		},
+		{
+			name:   "partial environment is ignored",
+			stored: `{"url": "https://memory.example.com", "api_key": "file-key"}`,
+			envURL: "https://other.example.com",
+			want:   &Credentials{URL: "https://memory.example.com", APIKey: "file-key"},
+		},
+		{name: "invalid file", stored: "not json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := isolateCredentials(t)
+			if tt.stored != "" {
+				writeCredentials(t, path, tt.stored)
+			}
+			t.Setenv("REMEMBER_ME_API_URL", tt.envURL)
+			t.Setenv("REMEMBER_ME_API_KEY", tt.envKey)
+
+			creds, err := loadCredentials()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, creds)
+		})
+	}
+}
+
+func TestAPIClientDo(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"total": 3}`,
+			want:   map[string]interface{}{"total": float64(3)},
+		},
+		{
+			name:    "error message",
+			status:  http.StatusUnauthorized,
+			body:    `{"error": "Invalid API key"}`,
+			wantErr: "Invalid API key (HTTP 401)",
+		},
+		{
+			name:    "error without a message",
+			status:  http.StatusInternalServerError,
+			body:    "upstream failed",
+			wantErr: "HTTP 500",
+		},
+		{
+			name:    "empty error message",
+			status:  http.StatusForbidden,
+			body:    `{"error": ""}`,
+			wantErr: "HTTP 403",
+		},
+		{
+			name:    "invalid response",
+			status:  http.StatusOK,
+			body:    "<html>",
+			wantErr: "invalid response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "test-key", r.Header.Get("X-API-Key"))
+				assert.Equal(t, "/api/v1/memories/stats", r.URL.Path)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := newAPIClient(&Credentials{URL: server.URL, APIKey: "test-key"})
+			var out map[string]interface{}
+			err := client.do(context.Background(), http.MethodGet, "/api/v1/memories/stats", nil, &out)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{name: "argument", args: []string{"Prefers", "espresso"}, want: "Prefers espresso"},
+		{name: "stdin", stdin: "  Prefers espresso\n", want: "Prefers espresso"},
+		{name: "dash reads stdin", args: []string{"-"}, stdin: "Prefers espresso\n", want: "Prefers espresso"},
+		{name: "empty stdin", stdin: " \n", wantErr: "usage: store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received mcp.StoreMemoryRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/api/v1/memories", r.URL.Path)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				json.NewEncoder(w).Encode(mcp.StoreMemoryResponse{
+					Success: true,
+					Memory:  &models.Memory{ID: 7, Content: received.Content},
+				})
+			}))
+			defer server.Close()
+
+			isolateCredentials(t)
+			t.Setenv("REMEMBER_ME_API_URL", server.URL)
+			t.Setenv("REMEMBER_ME_API_KEY", "test-key")
+
+			stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+			require.NoError(t, err)
+			_, err = stdin.WriteString(tt.stdin)
+			require.NoError(t, err)
+			_, err = stdin.Seek(0, 0)
+			require.NoError(t, err)
+			original := os.Stdin
+			os.Stdin = stdin
+			t.Cleanup(func() { os.Stdin = original })
+
+			args := append([]string{"--remote", "--type", models.TypePreference, "--tags", "coffee, drinks"}, tt.args...)
+			err = runStore(args)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, received.Content)
+			assert.Equal(t, models.TypePreference, received.Type)
+			assert.Equal(t, models.CategoryPersonal, received.Category)
+			assert.Equal(t, []string{"coffee", "drinks"}, received.Tags)
+		})
+	}
+}
+
+func TestRunLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Backup keys may only export and read stats
+		if r.URL.Path != "/api/v1/memories/stats" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Insufficient permissions"}`))
+			return
+		}
+		if r.Header.Get("X-API-Key") != "backup-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Invalid API key"}`))
+			return
+		}
+		w.Write([]byte(`{"total_memories": 0}`))
+	}))
+	defer server.Close()
+
+	t.Run("backup key", func(t *testing.T) {
+		path := isolateCredentials(t)
+		require.NoError(t, runLogin([]string{"--url", server.URL + "/", "--api-key", "backup-key"}))
+
+		creds, err := loadCredentials()
+		require.NoError(t, err)
+		assert.Equal(t, &Credentials{URL: server.URL, APIKey: "backup-key"}, creds)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("rejected key", func(t *testing.T) {
+		path := isolateCredentials(t)
+		err := runLogin([]string{"--url", server.URL, "--api-key", "wrong-key"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid API key (HTTP 401)")
+		assert.NoFileExists(t, path)
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+)
+
+// The compact subcommand reports how much space the memory store takes and runs
+// the suggested maintenance, meant for a maintenance window:
+//
+//	remember-me-mcp compact --dry-run
+//	remember-me-mcp compact --full
+//
+// VACUUM and REINDEX CONCURRENTLY run without blocking the server; VACUUM FULL
+// locks its table while it rewrites it and is only run with --full.
+
+// compactTimeout bounds a compaction run; rewriting large tables takes a while
+const compactTimeout = 6 * time.Hour
+
+func runCompact(args []string) error {
+	var (
+		configPath string
+		dryRun     bool
+		full       bool
+		users      int
+		jsonOutput bool
+	)
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "Path to configuration file")
+	fs.BoolVar(&dryRun, "dry-run", false, "Only report sizes and suggested maintenance")
+	fs.BoolVar(&full, "full", false, "Also run statements that lock tables, such as VACUUM FULL")
+	fs.IntVar(&users, "users", 10, "Number of largest users to report")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfiguration(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	db, err := connectToDatabase(cfg, setupLogging(cfg))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), compactTimeout)
+	defer cancel()
+
+	report, err := database.BuildCompactionReport(ctx, db.DB(), users)
+	if err != nil {
+		return err
+	}
+
+	var actions []database.MaintenanceAction
+	for _, action := range report.Actions {
+		if full || !action.Locking {
+			actions = append(actions, action)
+		}
+	}
+	if dryRun || len(actions) == 0 {
+		if jsonOutput {
+			return printJSON(report)
+		}
+		printCompactionReport(report)
+		return nil
+	}
+
+	if !jsonOutput {
+		printCompactionReport(report)
+		fmt.Println()
+	}
+	results := database.RunMaintenance(ctx, db.DB(), actions)
+	if jsonOutput {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "FAILED %s: %s\n", result.Statement, result.Error)
+		} else if !jsonOutput {
+			fmt.Printf("%s (%s)\n", result.Statement, result.Duration.Round(time.Millisecond))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d maintenance statements failed", failed, len(results))
+	}
+	return nil
+}
+
+// printCompactionReport prints a compaction report as tables
+func printCompactionReport(report *database.CompactionReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if len(report.Tables) > 0 {
+		fmt.Fprintln(w, "TABLE\tTOTAL\tTABLE\tTOAST\tINDEXES\tLIVE\tDEAD")
+		for _, table := range report.Tables {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d (%.0f%%)\n", table.Table,
+				formatBytes(table.TotalBytes), formatBytes(table.TableBytes), formatBytes(table.ToastBytes),
+				formatBytes(table.IndexBytes), table.LiveTuples, table.DeadTuples, table.DeadRatio*100)
+		}
+		fmt.Fprintln(w)
+	} else {
+		fmt.Fprintf(w, "Table and index sizes are not available on %s\n\n", report.Dialect)
+	}
+
+	for _, index := range report.Indexes {
+		if index.Vector {
+			fmt.Fprintf(w, "Vector index %s on %s: %s\n", index.Index, index.Table, formatBytes(index.Bytes))
+		}
+	}
+
+	if len(report.Users) > 0 {
+		fmt.Fprintln(w, "USER\tMEMORIES\tCONTENT\tMETADATA\tEMBEDDINGS\tTOAST\tTOTAL")
+		for _, user := range report.Users {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", user.UserID, user.Memories,
+				formatBytes(user.ContentBytes), formatBytes(user.MetadataBytes), formatBytes(user.EmbeddingBytes),
+				formatBytes(user.ToastBytes), formatBytes(user.TotalBytes))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Actions) == 0 {
+		fmt.Fprintln(w, "No maintenance needed")
+		return
+	}
+	fmt.Fprintln(w, "Suggested maintenance:")
+	for _, action := range report.Actions {
+		locking := ""
+		if action.Locking {
+			locking = " [locks table, needs --full]"
+		}
+		fmt.Fprintf(w, "  %s%s\n    %s\n", action.Statement, locking, action.Reason)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
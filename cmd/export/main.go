@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/rs/zerolog"
+)
+
+func main() {
+	var (
+		configPath        = flag.String("config", "", "Path to configuration file")
+		userID            = flag.Uint("user-id", 1, "User whose memories are exported or imported")
+		format            = flag.String("format", services.ArchiveFormatJSON, "Archive format: json or jsonl")
+		outputPath        = flag.String("output", "", "File to write the export to (default: stdout)")
+		includeEmbeddings = flag.Bool("include-embeddings", false, "Include embeddings in the export")
+		keepEncrypted     = flag.Bool("keep-encrypted", false, "Export encrypted memories without decrypting them")
+		anonymize         = flag.String("anonymize", "", "Pseudonymize personal information: true, or a comma-separated list of emails, phones, names, companies")
+		anonymizeTerms    = flag.String("anonymize-terms", "", "Comma-separated further terms to pseudonymize")
+		importPath        = flag.String("import", "", "Import the archive in this file instead of exporting")
+		allowCrossRegion  = flag.Bool("allow-cross-region", false, "Allow importing an archive exported from another region")
+	)
+	flag.Parse()
+
+	*format = strings.ToLower(*format)
+	if !services.IsValidArchiveFormat(*format) {
+		log.Fatalf("Invalid format %q: must be json or jsonl", *format)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Log to stderr so an export written to stdout stays clean
+	output := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	logger := zerolog.New(output).With().Timestamp().Logger()
+
+	// Connect to database
+	db, err := database.Open(cfg.Database, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	serviceConfig := map[string]interface{}{
+		"memory_limit":     cfg.Memory.MaxMemories,
+		"eviction_policy":  cfg.Memory.EvictionPolicy,
+		"plan_limits":      cfg.Memory.Plans,
+		"residency_region": cfg.Residency.Region,
+	}
+	if cfg.Encryption.Enabled {
+		encryptionService, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create encryption service")
+		}
+		serviceConfig["encryption_service"] = encryptionService
+	}
+
+	// The embedding service names the model embeddings were produced with; without
+	// one, archived embeddings are neither exported nor restored, and the server's
+	// backfill worker embeds imported memories instead
+	var embeddingService services.EmbeddingService
+	if cfg.OpenAI.APIKey != "" {
+		openAI, err := services.NewOpenAIEmbeddingService(&cfg.OpenAI, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create embedding service")
+		}
+		embeddingService = openAI
+	}
+
+	memoryService := services.NewMemoryServiceWithUser(db.DB(), embeddingService, logger, serviceConfig, *userID)
+
+	ctx := context.Background()
+	if *importPath != "" {
+		if err := runImport(ctx, memoryService, logger, *importPath, *format, *allowCrossRegion); err != nil {
+			logger.Fatal().Err(err).Msg("Import failed")
+		}
+		return
+	}
+
+	anonymizeOptions, err := services.ParseAnonymizeOptions(*anonymize, *anonymizeTerms)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid anonymize option")
+	}
+	opts := services.ExportOptions{
+		IncludeEmbeddings: *includeEmbeddings,
+		KeepEncrypted:     *keepEncrypted,
+		Anonymize:         anonymizeOptions,
+	}
+	if err := runExport(ctx, memoryService, logger, *outputPath, *format, opts); err != nil {
+		logger.Fatal().Err(err).Msg("Export failed")
+	}
+}
+
+func runExport(ctx context.Context, memoryService *services.MemoryService, logger zerolog.Logger, path, format string, opts services.ExportOptions) error {
+	archive, err := memoryService.ExportMemoriesWithOptions(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == services.ArchiveFormatJSONL {
+		err = services.WriteArchiveJSONL(w, archive)
+	} else {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(archive)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	logger.Info().
+		Int("memories", len(archive.Memories)).
+		Str("format", format).
+		Bool("include_embeddings", opts.IncludeEmbeddings).
+		Bool("keep_encrypted", opts.KeepEncrypted).
+		Msg("Export completed successfully")
+	return nil
+}
+
+func runImport(ctx context.Context, memoryService *services.MemoryService, logger zerolog.Logger, path, format string, allowCrossRegion bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	// A .jsonl file is read as JSON lines whatever the format flag says
+	var archive *services.MemoryArchive
+	if format == services.ArchiveFormatJSONL || strings.HasSuffix(path, ".jsonl") {
+		archive, err = services.ReadArchiveJSONL(file)
+	} else {
+		archive = &services.MemoryArchive{}
+		err = json.NewDecoder(file).Decode(archive)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	result, err := memoryService.ImportMemories(ctx, archive, allowCrossRegion)
+	if err != nil {
+		return err
+	}
+
+	logger.Info().
+		Int("created", result.Created).
+		Int("skipped", result.Skipped).
+		Int("embeddings_restored", result.EmbeddingsRestored).
+		Msg("Import completed successfully")
+	return nil
+}
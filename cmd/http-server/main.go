@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -13,7 +17,10 @@ import (
 	"github.com/ksred/remember-me-mcp/internal/config"
 	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/database/migrations"
+	"github.com/ksred/remember-me-mcp/internal/geoip"
+	"github.com/ksred/remember-me-mcp/internal/lifecycle"
 	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 	"github.com/rs/zerolog"
 
@@ -43,14 +50,18 @@ func main() {
 	var (
 		configPath     string
 		skipMigrations bool
+		haveBackup     bool
+		dev            bool
 	)
 	flag.StringVar(&configPath, "config", "", "Path to configuration file")
 	flag.BoolVar(&skipMigrations, "skip-migrations", false, "Skip running database migrations")
+	flag.BoolVar(&haveBackup, "i-have-a-backup", false, "Confirm a database backup exists; skips automatic pre-migration backups")
+	flag.BoolVar(&dev, "dev", false, "Run with an in-memory database, mock embeddings and a demo user; nothing is persisted")
 	flag.Parse()
 
 	// Load configuration
 	fmt.Println("Loading configuration...")
-	cfg, err := loadConfiguration(configPath)
+	cfg, err := loadConfiguration(configPath, dev)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
@@ -83,34 +94,58 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Everything started from here is stopped in reverse dependency order
+	lc := lifecycle.New(logger)
+
+	// Tracing is registered first so spans are flushed after everything else stops
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "remember-me-http")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	lc.Register("tracing", nil, shutdownTracing)
+	if cfg.Tracing.Enabled {
+		logger.Info().
+			Str("endpoint", cfg.Tracing.Endpoint).
+			Float64("sample_ratio", cfg.Tracing.SampleRatio).
+			Msg("Tracing enabled")
+	}
+
 	// Connect to database
-	db, err := connectToDatabase(cfg, logger)
+	var db *database.Database
+	if dev {
+		logger.Warn().Msg("Developer mode: using an in-memory database, nothing will be persisted")
+		db, err = database.OpenInMemory(cfg.Server.LogLevel)
+	} else {
+		db, err = connectToDatabase(cfg, logger)
+	}
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to connect to database")
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Error().Err(err).Msg("Failed to close database connection")
-		}
-	}()
+	lc.Register("database", nil, func(context.Context) error {
+		return db.Close()
+	})
 
 	// Create encryption service early for migrations
 	logger.Info().Msg("Creating encryption service for migrations...")
 	encryptionService := createEncryptionService(cfg, logger)
 	
-	// Run migrations
-	logger.Info().Msg("Running database migrations...")
-	if err := runMigrations(db, logger); err != nil {
-		logger.Fatal().Err(err).Msg("Failed to run migrations")
+	// Run migrations; the in-memory database is created with the current schema
+	if !dev {
+		logger.Info().Msg("Running database migrations...")
+		if err := runMigrations(db, cfg, logger); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to run migrations")
+		}
+		logger.Info().Msg("Database migrations completed")
 	}
-	logger.Info().Msg("Database migrations completed")
 	
 	// Run versioned migrations
-	if !skipMigrations {
+	if dev {
+		logger.Info().Msg("Skipping versioned migrations in developer mode")
+	} else if !skipMigrations {
 		logger.Info().
 			Bool("has_encryption_service", encryptionService != nil).
 			Msg("Running versioned migrations...")
-		if err := runVersionedMigrations(ctx, db, encryptionService, logger); err != nil {
+		if err := runVersionedMigrations(ctx, db, encryptionService, cfg.Migrations.RequireBackupConfirmation, haveBackup, logger); err != nil {
 			logger.Fatal().Err(err).Msg("Failed to run versioned migrations")
 		}
 		logger.Info().Msg("Versioned migrations completed")
@@ -118,20 +153,207 @@ func main() {
 		logger.Warn().Msg("Skipping versioned migrations as requested")
 	}
 
+	// Mirror memory writes onto the new database during a migration
+	if cfg.DualWrite.Enabled {
+		target, err := enableDualWrite(db, cfg, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to enable dual writes")
+		}
+		lc.Register("dual_write", nil, func(context.Context) error {
+			return target.Close()
+		}, lifecycle.DependsOn("database"))
+	}
+
 	// Create services
 	embeddingService := createEmbeddingService(cfg, logger)
 	
 	// Create memory service with encryption support
+	notifier := services.NewNotifierFromConfig(cfg, logger)
 	serviceConfig := map[string]interface{}{
 		"memory_limit": cfg.Memory.MaxMemories,
 		"similarity_threshold": cfg.Memory.SimilarityThreshold,
+		"priority_boosts": cfg.Memory.PriorityBoosts,
+		"eviction_policy": cfg.Memory.EvictionPolicy,
+		"plan_limits": cfg.Memory.Plans,
+		"context_buffer_ttl": cfg.Memory.ContextBufferTTL,
+		"duplicate_action": cfg.Memory.DuplicateAction,
+		"duplicate_threshold": cfg.Memory.DuplicateThreshold,
+		"feedback_weight": cfg.Memory.FeedbackWeight,
+		"max_content_length": cfg.Memory.MaxContentLength,
+		"chunk_size": cfg.Memory.ChunkSize,
+		"attachment_max_size": cfg.Attachments.MaxSize,
+		"attachment_max_per_memory": cfg.Attachments.MaxPerMemory,
+		"exact_counts": cfg.Memory.ExactCounts,
+		"normalize_detected": cfg.Memory.NormalizeDetected,
+		"filler_words": cfg.Memory.FillerWords,
+		"maintenance_stale_after": cfg.Maintenance.StaleAfter,
+		"maintenance_max_actions": cfg.Maintenance.MaxActions,
+		"consolidation_similarity": cfg.Consolidation.Similarity,
+		"consolidation_min_cluster": cfg.Consolidation.MinClusterSize,
+		"consolidation_max_cluster": cfg.Consolidation.MaxClusterSize,
+		"write_timeout": cfg.Timeouts.Write,
+		"embedding_cache": cfg.Embedding.Cache,
+		"residency_region": cfg.Residency.Region,
+		"access_audit": cfg.Audit.MemoryAccess,
+		"notifier": notifier,
+		"llm_budget": services.NewLLMBudgetFromConfig(cfg),
 	}
 	if encryptionService != nil {
 		serviceConfig["encryption_service"] = encryptionService
 	}
+	moderationHook, err := services.NewModerationHookFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create content moderation hook")
+	}
+	if moderationHook != nil {
+		serviceConfig["moderation"] = moderationHook
+	}
+	memoryExtractor, err := services.NewMemoryExtractorFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create memory extractor")
+	}
+	if memoryExtractor != nil {
+		serviceConfig["memory_extractor"] = memoryExtractor
+	}
+	if summarizer := services.NewMemorySummarizerFromConfig(cfg, logger); summarizer != nil {
+		serviceConfig["memory_summarizer"] = summarizer
+	}
+	transcriber, err := services.NewTranscriberFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create voice memo transcriber")
+	}
+	if transcriber != nil {
+		serviceConfig["transcriber"] = transcriber
+	}
+	attachmentStore, err := services.NewAttachmentStoreFromConfig(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create attachment store")
+	}
+	if attachmentStore != nil {
+		serviceConfig["attachment_store"] = attachmentStore
+	}
+	embeddingComposer, err := services.NewEmbeddingComposer(cfg.Embedding.Document)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create embedding document composer")
+	}
+	if embeddingComposer != nil {
+		serviceConfig["embedding_composer"] = embeddingComposer
+	}
+	serviceConfig["embedding_batcher"] = services.NewEmbeddingBatcher(embeddingService, cfg.OpenAI.BatchSize, cfg.OpenAI.BatchWindow, logger)
+	serviceConfig["async_embeddings"] = services.NewAsyncEmbeddings(logger)
+
+	// Answer repeated searches from memory until the user's memories change
+	if searchCache := services.NewSearchCacheFromConfig(cfg); searchCache != nil {
+		if err := db.DB().Use(searchCache); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to enable the search cache")
+		}
+		serviceConfig["search_cache"] = searchCache
+	}
 	
 	memoryService := services.NewMemoryService(db.DB(), embeddingService, logger, serviceConfig)
+
+	// Embeddings generated in the background finish, or are queued for the
+	// backfill worker, before the database closes
+	lc.Register("async_embeddings", nil, memoryService.Drain, lifecycle.DependsOn("database"))
 	activityService := services.NewActivityService(db.DB(), logger)
+	
+	// Enrich activity with coarse location data if a GeoIP database is configured
+	if cfg.GeoIP.DatabasePath != "" {
+		geoReader, err := geoip.Open(cfg.GeoIP.DatabasePath)
+		if err != nil {
+			logger.Error().Err(err).Str("path", cfg.GeoIP.DatabasePath).Msg("Failed to load GeoIP database, activity will not be geo-enriched")
+		} else {
+			activityService.SetGeoLocator(geoReader)
+			logger.Info().Str("database_type", geoReader.DatabaseType()).Msg("GeoIP enrichment enabled")
+		}
+	}
+	
+	// Enable anomaly detection and alerting if configured
+	if cfg.Alerts.Enabled {
+		detectorConfig := services.DefaultAnomalyDetectorConfig()
+		detectorConfig.DeleteSpikeThreshold = cfg.Alerts.DeleteSpikeThreshold
+		detectorConfig.DeleteSpikeWindow = cfg.Alerts.DeleteSpikeWindow
+		detectorConfig.APIKeyLearningUses = cfg.Alerts.APIKeyLearningUses
+		
+		detector := services.NewAnomalyDetector(db.DB(), notifier, detectorConfig, logger)
+		activityService.SetAnomalyDetector(detector)
+		logger.Info().Msg("Anomaly detection enabled")
+	}
+
+	// Warm up before serving so the first semantic search runs at steady-state latency
+	if cfg.Server.WarmUp {
+		warmUpCtx, warmUpCancel := context.WithTimeout(ctx, 30*time.Second)
+		memoryService.WarmUp(warmUpCtx)
+		warmUpCancel()
+	}
+
+	// Retry embeddings that failed or were lost when the process stopped
+	if cfg.EmbeddingBackfill.Enabled {
+		backfillConfig := services.DefaultEmbeddingBackfillConfig()
+		backfillConfig.Interval = cfg.EmbeddingBackfill.Interval
+		backfillConfig.BatchSize = cfg.EmbeddingBackfill.BatchSize
+		backfillConfig.MaxAttempts = cfg.EmbeddingBackfill.MaxAttempts
+		backfillConfig.MaxBackoff = cfg.EmbeddingBackfill.MaxBackoff
+		
+		start, stop := lifecycle.Background(services.NewEmbeddingBackfillWorker(memoryService, backfillConfig).Start)
+		lc.Register("embedding_backfill", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().Dur("interval", backfillConfig.Interval).Msg("Embedding backfill worker enabled")
+	}
+
+	// Review opted-in users' memories and propose clean-ups for them to approve
+	if cfg.Maintenance.Enabled {
+		maintenanceConfig := services.DefaultMaintenanceWorkerConfig()
+		maintenanceConfig.Interval = cfg.Maintenance.Interval
+		
+		start, stop := lifecycle.Background(services.NewMaintenanceWorker(memoryService, maintenanceConfig).Start)
+		lc.Register("maintenance", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().Dur("interval", maintenanceConfig.Interval).Msg("Maintenance agent enabled")
+	}
+
+	// Merge clusters of related memories into summaries
+	if cfg.Consolidation.Enabled {
+		consolidationConfig := services.DefaultConsolidationWorkerConfig()
+		consolidationConfig.Interval = cfg.Consolidation.Interval
+		if cfg.Consolidation.MaxClusters > 0 {
+			consolidationConfig.MaxClusters = cfg.Consolidation.MaxClusters
+		}
+
+		start, stop := lifecycle.Background(services.NewConsolidationWorker(memoryService, consolidationConfig).Start)
+		lc.Register("consolidation", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().Dur("interval", consolidationConfig.Interval).Msg("Memory consolidation enabled")
+	}
+
+	// Roll up finished days of activity and prune the raw rows past retention
+	if cfg.Retention.Enabled {
+		retentionConfig := services.ActivityRetentionConfig{
+			Interval:           cfg.Retention.Interval,
+			ActivityLogs:       cfg.Retention.ActivityLogs,
+			PerformanceMetrics: cfg.Retention.PerformanceMetrics,
+		}
+
+		start, stop := lifecycle.Background(services.NewActivityRetentionWorker(activityService, retentionConfig).Start)
+		lc.Register("activity_retention", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().
+			Dur("activity_logs", retentionConfig.ActivityLogs).
+			Dur("performance_metrics", retentionConfig.PerformanceMetrics).
+			Msg("Activity retention enabled")
+	}
+
+	// Back up every user's memories on the configured schedule
+	if cfg.Backup.Enabled {
+		backupConfig, err := services.NewBackupWorkerConfig(cfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to configure backups")
+		}
+
+		start, stop := lifecycle.Background(services.NewBackupWorker(memoryService, backupConfig).Start)
+		lc.Register("backup", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().
+			Str("schedule", cfg.Backup.Schedule).
+			Str("destination", cfg.Backup.Destination).
+			Bool("encrypted", backupConfig.Encryption != nil).
+			Msg("Scheduled backups enabled")
+	}
 
 	// Create and start HTTP server
 	server, err := api.NewServer(cfg, db, memoryService, activityService, logger)
@@ -139,13 +361,26 @@ func main() {
 		logger.Fatal().Err(err).Msg("Failed to create HTTP server")
 	}
 
-	// Start server in goroutine
-	serverErrChan := make(chan error, 1)
-	go func() {
-		if err := server.Start(cfg.HTTP.Port); err != nil {
-			serverErrChan <- err
+	if dev {
+		if err := createDevUser(db, cfg, logger); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create demo user")
 		}
-	}()
+	}
+
+	// Serve HTTP in the background; stopping drains in-flight requests
+	serverErrChan := make(chan error, 1)
+	lc.Register("http_server", func(context.Context) error {
+		go func() {
+			if err := server.Start(cfg.HTTP.Port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErrChan <- err
+			}
+		}()
+		return nil
+	}, server.Shutdown, lifecycle.DependsOn("database", "async_embeddings"), lifecycle.Timeout(30*time.Second))
+
+	if err := lc.Start(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to start")
+	}
 
 	// Wait for shutdown signal or server error
 	select {
@@ -157,22 +392,25 @@ func main() {
 
 	// Graceful shutdown
 	logger.Info().Msg("Starting graceful shutdown")
-	
-	// Shutdown HTTP server with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+	// Long enough for the HTTP server to drain and the other parts to follow
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 45*time.Second)
 	defer shutdownCancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Error().Err(err).Msg("Failed to gracefully shutdown HTTP server")
+	if err := lc.Stop(shutdownCtx); err != nil {
+		logger.Warn().Err(err).Msg("Shutdown did not complete cleanly")
 	}
 
 	logger.Info().Msg("Shutdown complete")
 }
 
 // loadConfiguration loads configuration from file or environment
-func loadConfiguration(configPath string) (*config.Config, error) {
+func loadConfiguration(configPath string, dev bool) (*config.Config, error) {
 	// Use LoadConfigOrDefault which handles environment variables even when config file is missing
 	cfg := config.LoadConfigOrDefault(configPath)
+	if dev {
+		applyDevMode(cfg)
+	}
 	
 	// Validate the configuration
 	if err := cfg.Validate(); err != nil {
@@ -182,6 +420,65 @@ func loadConfiguration(configPath string) (*config.Config, error) {
 	return cfg, nil
 }
 
+// applyDevMode overrides the configuration for developer mode, so the server runs
+// with nothing but Go installed
+func applyDevMode(cfg *config.Config) {
+	cfg.Embedding.Provider = "mock"
+	cfg.DualWrite.Enabled = false
+	cfg.Server.WarmUp = false
+
+	// The demo user's credentials are printed, so only this machine may connect
+	// unless a host was set explicitly
+	if cfg.HTTP.Host == "" {
+		cfg.HTTP.Host = "127.0.0.1"
+	}
+
+	// Sign tokens with a throwaway secret; sessions end with the process
+	if cfg.JWT.Secret == "" {
+		if secret, err := utils.GenerateMasterKey(); err == nil {
+			cfg.JWT.Secret = secret
+		}
+	}
+
+	// Encrypt with a throwaway key rather than fail without one
+	if cfg.Encryption.Enabled && cfg.Encryption.MasterKey == "" {
+		if key, err := utils.GenerateMasterKey(); err == nil {
+			cfg.Encryption.MasterKey = key
+		} else {
+			cfg.Encryption.Enabled = false
+		}
+	}
+}
+
+// createDevUser creates the demo user, with a password generated for this run,
+// and an API key for developer mode and prints the credentials
+func createDevUser(db *database.Database, cfg *config.Config, logger zerolog.Logger) error {
+	const email = "demo@remember-me.local"
+	password, err := api.GeneratePassword()
+	if err != nil {
+		return err
+	}
+
+	authService := api.NewAuthService(db, logger)
+	user, err := authService.RegisterUser(email, password)
+	if err != nil {
+		return fmt.Errorf("failed to register demo user: %w", err)
+	}
+	apiKey, err := authService.GenerateAPIKey(user.ID, "dev", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create demo API key: %w", err)
+	}
+
+	baseURL := "http://" + net.JoinHostPort(cfg.HTTP.Host, strconv.Itoa(cfg.HTTP.Port))
+	fmt.Printf("\nDeveloper mode is running at %s\n", baseURL)
+	fmt.Printf("  Email:    %s\n", email)
+	fmt.Printf("  Password: %s\n", password)
+	fmt.Printf("  API key:  %s\n", apiKey.Key)
+	fmt.Printf("  MCP:      POST %s/api/v1/mcp\n", baseURL)
+	fmt.Printf("Try: curl -H 'X-API-Key: %s' %s/api/v1/memories\n\n", apiKey.Key, baseURL)
+	return nil
+}
+
 // setupLogging configures the logger based on configuration
 func setupLogging(cfg *config.Config) zerolog.Logger {
 	// For systemd services, we want to log to stderr so systemd can capture it
@@ -220,6 +517,7 @@ func connectToDatabase(cfg *config.Config, logger zerolog.Logger) (*database.Dat
 		"conn_max_lifetime": cfg.Database.ConnMaxLifetime,
 		"conn_max_idle_time": cfg.Database.ConnMaxIdleTime,
 		"log_level":        cfg.Server.LogLevel,
+		"tracing":          cfg.Tracing.Enabled,
 	})
 	
 	// Connect
@@ -239,8 +537,33 @@ func connectToDatabase(cfg *config.Config, logger zerolog.Logger) (*database.Dat
 	return db, nil
 }
 
-// runMigrations runs database migrations
-func runMigrations(db *database.Database, logger zerolog.Logger) error {
+// enableDualWrite connects to the dual-write target, migrates it and mirrors memory
+// writes onto it. Run migrate-db -backfill to copy existing memories.
+func enableDualWrite(db *database.Database, cfg *config.Config, logger zerolog.Logger) (*database.Database, error) {
+	target, err := database.Open(cfg.DualWrite.Target, "silent")
+	if err != nil {
+		return nil, fmt.Errorf("dual write target: %w", err)
+	}
+
+	if err := database.RunMigrations(target.DB()); err != nil {
+		target.Close()
+		return nil, fmt.Errorf("failed to migrate dual write target: %w", err)
+	}
+
+	if err := db.DB().Use(database.NewDualWriter(target.DB(), logger)); err != nil {
+		target.Close()
+		return nil, fmt.Errorf("failed to register dual writer: %w", err)
+	}
+
+	logger.Info().
+		Str("host", cfg.DualWrite.Target.Host).
+		Str("database", cfg.DualWrite.Target.DBName).
+		Msg("Dual writes enabled")
+	return target, nil
+}
+
+// runMigrations runs database migrations and builds the configured vector index
+func runMigrations(db *database.Database, cfg *config.Config, logger zerolog.Logger) error {
 	logger.Info().Msg("Running database migrations")
 
 	// Use the centralized migration function
@@ -248,28 +571,25 @@ func runMigrations(db *database.Database, logger zerolog.Logger) error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Semantic search still works without the index, only slower, so a failed
+	// build (such as on a pgvector without hnsw) doesn't stop the server
+	vectorIndex := database.VectorIndexOptionsFromConfig(cfg.Database.VectorIndex)
+	if err := database.EnsureVectorIndex(context.Background(), db.DB(), vectorIndex, logger); err != nil {
+		logger.Error().Err(err).Str("type", vectorIndex.Type).Msg("Failed to build vector index")
+	}
+
 	logger.Info().Msg("Database migrations completed successfully")
 	return nil
 }
 
-// createEmbeddingService creates the appropriate embedding service
+// createEmbeddingService creates the embedding service selected by embedding.provider
 func createEmbeddingService(cfg *config.Config, logger zerolog.Logger) services.EmbeddingService {
-	// Check if we should use mock service
-	if cfg.OpenAI.APIKey == "" {
-		logger.Warn().Msg("OpenAI API key not configured, using mock embedding service")
-		return services.NewMockEmbeddingService()
-	}
-	
-	// Create OpenAI embedding service
-	logger.Info().
-		Str("model", cfg.OpenAI.Model).
-		Msg("Creating OpenAI embedding service")
-	embeddingService, err := services.NewOpenAIEmbeddingService(&cfg.OpenAI, logger)
+	embeddingService, err := services.NewEmbeddingServiceFromConfig(cfg, logger)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to create OpenAI embedding service, falling back to mock")
+		logger.Error().Err(err).Str("provider", cfg.Embedding.Provider).Msg("Failed to create embedding service, falling back to mock")
 		return services.NewMockEmbeddingService()
 	}
-	
+
 	return embeddingService
 }
 
@@ -292,19 +612,24 @@ func createEncryptionService(cfg *config.Config, logger zerolog.Logger) *utils.E
 	}
 	
 	logger.Info().Msg("Attempting to create encryption service with provided key...")
-	encryptionService, err := utils.NewEncryptionService(cfg.Encryption.MasterKey)
+	encryptionService, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to create encryption service")
 		return nil
 	}
 	
-	logger.Info().Msg("Encryption service created successfully")
+	logger.Info().
+		Str("key_id", encryptionService.KeyID()).
+		Str("kms", cfg.Encryption.KMS.Provider).
+		Msg("Encryption service created successfully")
 	return encryptionService
 }
 
-// runVersionedMigrations runs versioned database migrations
-func runVersionedMigrations(ctx context.Context, db *database.Database, encryptionService *utils.EncryptionService, logger zerolog.Logger) error {
+// runVersionedMigrations runs versioned database migrations, backing up the tables
+// they rewrite unless the operator confirmed a backup
+func runVersionedMigrations(ctx context.Context, db *database.Database, encryptionService *utils.EncryptionService, requireBackupConfirmation, haveBackup bool, logger zerolog.Logger) error {
 	runner := database.NewMigrationRunner(db.DB(), logger)
+	runner.SetBackupPolicy(requireBackupConfirmation, haveBackup)
 	
 	// Register all migrations
 	migrations := migrations.GetMigrations(encryptionService)
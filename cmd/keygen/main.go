@@ -1,23 +1,82 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"flag"
 	"fmt"
 	"log"
 
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/services"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 )
 
+// keygen prints a new master key. With -kms it prints the key encrypted with the
+// configured key management service instead, to set as ENCRYPTION_MASTER_KEY;
+// -key encrypts an existing key, for moving it into the KMS.
 func main() {
+	var (
+		configPath = flag.String("config", "", "Path to configuration file")
+		useKMS     = flag.Bool("kms", false, "Encrypt the key with the configured key management service")
+		existing   = flag.String("key", "", "Existing base64 master key to encrypt with -kms instead of a new one")
+	)
+	flag.Parse()
+
+	if *useKMS {
+		encryptWithKMS(*configPath, *existing)
+		return
+	}
+
 	fmt.Println("Generating encryption master key...")
-	
+
 	key, err := utils.GenerateMasterKey()
 	if err != nil {
 		log.Fatalf("Failed to generate master key: %v", err)
 	}
-	
+
 	fmt.Println("\nGenerated master key (base64 encoded):")
 	fmt.Println(key)
 	fmt.Println("\nAdd this to your .env file or environment variables as:")
 	fmt.Printf("ENCRYPTION_MASTER_KEY=%s\n", key)
 	fmt.Println("\nIMPORTANT: Keep this key secure. If you lose it, you won't be able to decrypt your data!")
-}
\ No newline at end of file
+}
+
+// encryptWithKMS prints a master key encrypted with the configured KMS
+func encryptWithKMS(configPath, key string) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	kms, err := services.NewKeyManagerFromConfig(cfg.Encryption.KMS)
+	if err != nil {
+		log.Fatalf("Failed to create key management service: %v", err)
+	}
+	if kms == nil {
+		log.Fatal("No KMS provider configured; set ENCRYPTION_KMS_PROVIDER")
+	}
+
+	if key == "" {
+		if key, err = utils.GenerateMasterKey(); err != nil {
+			log.Fatalf("Failed to generate master key: %v", err)
+		}
+	}
+	if _, err := utils.NewEncryptionService(key); err != nil {
+		log.Fatalf("Invalid master key: %v", err)
+	}
+	plaintext, _ := base64.StdEncoding.DecodeString(key)
+
+	ciphertext, err := kms.Encrypt(context.Background(), plaintext)
+	if err != nil {
+		log.Fatalf("Failed to encrypt master key: %v", err)
+	}
+	// Decrypting it again checks the servers will be able to
+	service, err := utils.NewEncryptionServiceWithKMS(context.Background(), kms, ciphertext)
+	if err != nil {
+		log.Fatalf("Failed to decrypt the encrypted master key: %v", err)
+	}
+
+	fmt.Printf("\nMaster key %s encrypted with %s. Set it as:\n", service.KeyID(), kms.Name())
+	fmt.Printf("ENCRYPTION_MASTER_KEY=%s\n", ciphertext)
+	fmt.Println("\nIMPORTANT: The key can only be decrypted with access to the KMS key. If it is deleted, you won't be able to decrypt your data!")
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/ksred/remember-me-mcp/internal/config"
 	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/database/migrations"
+	"github.com/ksred/remember-me-mcp/internal/lifecycle"
 	"github.com/ksred/remember-me-mcp/internal/mcp"
 	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 	"github.com/rs/zerolog"
 )
@@ -22,13 +24,20 @@ import (
 const version = "v0.2.0-debug-context-fix"
 
 func main() {
+	// search, store, login and logout run as one-off commands instead of the server
+	if isSubcommand(os.Args[1:]) {
+		os.Exit(runSubcommand(os.Args[1:]))
+	}
+
 	// Parse command line flags
 	var (
 		configPath     string
 		skipMigrations bool
+		haveBackup     bool
 	)
 	flag.StringVar(&configPath, "config", "", "Path to configuration file")
 	flag.BoolVar(&skipMigrations, "skip-migrations", false, "Skip running database migrations")
+	flag.BoolVar(&haveBackup, "i-have-a-backup", false, "Confirm a database backup exists; skips automatic pre-migration backups")
 	flag.Parse()
 
 	// Load configuration
@@ -50,62 +59,143 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Everything started from here is stopped in reverse dependency order
+	lc := lifecycle.New(logger)
+
+	// Tracing is registered first so spans are flushed after everything else stops
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "remember-me-mcp")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	lc.Register("tracing", nil, shutdownTracing)
+	if cfg.Tracing.Enabled {
+		logger.Info().
+			Str("endpoint", cfg.Tracing.Endpoint).
+			Float64("sample_ratio", cfg.Tracing.SampleRatio).
+			Msg("Tracing enabled")
+	}
+
 	// Connect to database
 	db, err := connectToDatabase(cfg, logger)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to connect to database")
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Error().Err(err).Msg("Failed to close database connection")
-		}
-	}()
+	lc.Register("database", nil, func(context.Context) error {
+		return db.Close()
+	})
 
 	// Create encryption service early for migrations
 	encryptionService := createEncryptionService(cfg, logger)
 	
 	// Run migrations
-	if err := runMigrations(db, logger); err != nil {
+	if err := runMigrations(db, cfg, logger); err != nil {
 		logger.Fatal().Err(err).Msg("Failed to run migrations")
 	}
 	
 	// Run versioned migrations
 	if !skipMigrations {
-		if err := runVersionedMigrations(ctx, db, encryptionService, logger); err != nil {
+		if err := runVersionedMigrations(ctx, db, encryptionService, cfg.Migrations.RequireBackupConfirmation, haveBackup, logger); err != nil {
 			logger.Fatal().Err(err).Msg("Failed to run versioned migrations")
 		}
 	} else {
 		logger.Warn().Msg("Skipping versioned migrations as requested")
 	}
 
+	// Mirror memory writes onto the new database during a migration
+	if cfg.DualWrite.Enabled {
+		target, err := enableDualWrite(db, cfg, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to enable dual writes")
+		}
+		lc.Register("dual_write", nil, func(context.Context) error {
+			return target.Close()
+		}, lifecycle.DependsOn("database"))
+	}
+
 	// Create services
 	embeddingService := createEmbeddingService(cfg, logger)
 	
 	// Create memory service with encryption support
-	serviceConfig := map[string]interface{}{
-		"memory_limit": cfg.Memory.MaxMemories,
-		"similarity_threshold": cfg.Memory.SimilarityThreshold,
+	serviceConfig, err := buildServiceConfig(cfg, encryptionService, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to build memory service configuration")
 	}
-	if encryptionService != nil {
-		serviceConfig["encryption_service"] = encryptionService
+	
+	serviceConfig["embedding_batcher"] = services.NewEmbeddingBatcher(embeddingService, cfg.OpenAI.BatchSize, cfg.OpenAI.BatchWindow, logger)
+	serviceConfig["async_embeddings"] = services.NewAsyncEmbeddings(logger)
+
+	// Answer repeated searches from memory until the user's memories change
+	if searchCache := services.NewSearchCacheFromConfig(cfg); searchCache != nil {
+		if err := db.DB().Use(searchCache); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to enable the search cache")
+		}
+		serviceConfig["search_cache"] = searchCache
 	}
 	
 	memoryService := services.NewMemoryService(db.DB(), embeddingService, logger, serviceConfig)
 
+	// Embeddings generated in the background finish, or are queued for the
+	// backfill worker, before the database closes
+	lc.Register("async_embeddings", nil, memoryService.Drain, lifecycle.DependsOn("database"))
+
+	// Warm up before serving so the first semantic search runs at steady-state latency
+	if cfg.Server.WarmUp {
+		warmUpCtx, warmUpCancel := context.WithTimeout(ctx, 30*time.Second)
+		memoryService.WarmUp(warmUpCtx)
+		warmUpCancel()
+	}
+
+	// Retry embeddings that failed or were lost when the process stopped
+	if cfg.EmbeddingBackfill.Enabled {
+		backfillConfig := services.DefaultEmbeddingBackfillConfig()
+		backfillConfig.Interval = cfg.EmbeddingBackfill.Interval
+		backfillConfig.BatchSize = cfg.EmbeddingBackfill.BatchSize
+		backfillConfig.MaxAttempts = cfg.EmbeddingBackfill.MaxAttempts
+		backfillConfig.MaxBackoff = cfg.EmbeddingBackfill.MaxBackoff
+		
+		start, stop := lifecycle.Background(services.NewEmbeddingBackfillWorker(memoryService, backfillConfig).Start)
+		lc.Register("embedding_backfill", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().Dur("interval", backfillConfig.Interval).Msg("Embedding backfill worker enabled")
+	}
+
+	// Back up every user's memories on the configured schedule
+	if cfg.Backup.Enabled {
+		backupConfig, err := services.NewBackupWorkerConfig(cfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to configure backups")
+		}
+
+		start, stop := lifecycle.Background(services.NewBackupWorker(memoryService, backupConfig).Start)
+		lc.Register("backup", start, stop, lifecycle.DependsOn("database"))
+		logger.Info().
+			Str("schedule", cfg.Backup.Schedule).
+			Str("destination", cfg.Backup.Destination).
+			Bool("encrypted", backupConfig.Encryption != nil).
+			Msg("Scheduled backups enabled")
+	}
+
 	// Create and configure MCP server
-	mcpServer, err := mcp.NewServer(memoryService, logger)
+	mcpServer, err := mcp.NewServer(memoryService, logger, mcp.WithTimeouts(cfg.Timeouts), mcp.WithClientProfiles(cfg.ClientProfiles))
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to create MCP server")
 	}
 
-	// Start MCP server in a goroutine
+	// Serve MCP on stdio. The stdio server ends with the process, so it has no
+	// stop hook.
 	serverErrChan := make(chan error, 1)
-	go func() {
-		logger.Info().Msg("Starting MCP server on stdio")
-		if err := mcpServer.Serve(ctx); err != nil {
-			serverErrChan <- err
-		}
-	}()
+	lc.Register("mcp_server", func(ctx context.Context) error {
+		go func() {
+			logger.Info().Msg("Starting MCP server on stdio")
+			if err := mcpServer.Serve(ctx); err != nil {
+				serverErrChan <- err
+			}
+		}()
+		return nil
+	}, nil, lifecycle.DependsOn("database", "async_embeddings"))
+
+	if err := lc.Start(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to start")
+	}
 
 	// Wait for shutdown signal or server error
 	select {
@@ -117,20 +207,12 @@ func main() {
 
 	// Graceful shutdown
 	logger.Info().Msg("Starting graceful shutdown")
-	
-	// Cancel context to stop the server
-	cancel()
 
-	// Give the server time to clean up
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
-	// Wait for shutdown to complete or timeout
-	select {
-	case <-shutdownCtx.Done():
-		logger.Warn().Msg("Shutdown timeout exceeded")
-	case <-time.After(2 * time.Second):
-		// Allow some time for cleanup
+	if err := lc.Stop(shutdownCtx); err != nil {
+		logger.Warn().Err(err).Msg("Shutdown did not complete cleanly")
 	}
 
 	logger.Info().Msg("Shutdown complete")
@@ -197,6 +279,7 @@ func connectToDatabase(cfg *config.Config, logger zerolog.Logger) (*database.Dat
 		"conn_max_lifetime": cfg.Database.ConnMaxLifetime.String(),
 		"conn_max_idle_time": cfg.Database.ConnMaxIdleTime.String(),
 		"log_level":         "silent", // Use silent level for GORM to prevent interference with JSON-RPC
+		"tracing":           cfg.Tracing.Enabled,
 	}
 
 	// Create database instance
@@ -224,8 +307,33 @@ func connectToDatabase(cfg *config.Config, logger zerolog.Logger) (*database.Dat
 	return db, nil
 }
 
-// runMigrations runs database migrations
-func runMigrations(db *database.Database, logger zerolog.Logger) error {
+// enableDualWrite connects to the dual-write target, migrates it and mirrors memory
+// writes onto it. Run migrate-db -backfill to copy existing memories.
+func enableDualWrite(db *database.Database, cfg *config.Config, logger zerolog.Logger) (*database.Database, error) {
+	target, err := database.Open(cfg.DualWrite.Target, "silent")
+	if err != nil {
+		return nil, fmt.Errorf("dual write target: %w", err)
+	}
+
+	if err := database.RunMigrations(target.DB()); err != nil {
+		target.Close()
+		return nil, fmt.Errorf("failed to migrate dual write target: %w", err)
+	}
+
+	if err := db.DB().Use(database.NewDualWriter(target.DB(), logger)); err != nil {
+		target.Close()
+		return nil, fmt.Errorf("failed to register dual writer: %w", err)
+	}
+
+	logger.Info().
+		Str("host", cfg.DualWrite.Target.Host).
+		Str("database", cfg.DualWrite.Target.DBName).
+		Msg("Dual writes enabled")
+	return target, nil
+}
+
+// runMigrations runs database migrations and builds the configured vector index
+func runMigrations(db *database.Database, cfg *config.Config, logger zerolog.Logger) error {
 	logger.Info().Msg("Running database migrations")
 
 	// Use the centralized migration function
@@ -233,26 +341,98 @@ func runMigrations(db *database.Database, logger zerolog.Logger) error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Semantic search still works without the index, only slower, so a failed
+	// build (such as on a pgvector without hnsw) doesn't stop the server
+	vectorIndex := database.VectorIndexOptionsFromConfig(cfg.Database.VectorIndex)
+	if err := database.EnsureVectorIndex(context.Background(), db.DB(), vectorIndex, logger); err != nil {
+		logger.Error().Err(err).Str("type", vectorIndex.Type).Msg("Failed to build vector index")
+	}
+
 	logger.Info().Msg("Database migrations completed successfully")
 	return nil
 }
 
-// createEmbeddingService creates the appropriate embedding service
-func createEmbeddingService(cfg *config.Config, logger zerolog.Logger) services.EmbeddingService {
-	// Check if we should use mock service
-	if cfg.OpenAI.APIKey == "" {
-		logger.Warn().Msg("No OpenAI API key provided, using mock embedding service")
-		return services.NewMockEmbeddingService()
+// buildServiceConfig builds the memory service configuration from the application
+// configuration
+func buildServiceConfig(cfg *config.Config, encryptionService *utils.EncryptionService, logger zerolog.Logger) (map[string]interface{}, error) {
+	serviceConfig := map[string]interface{}{
+		"memory_limit": cfg.Memory.MaxMemories,
+		"similarity_threshold": cfg.Memory.SimilarityThreshold,
+		"priority_boosts": cfg.Memory.PriorityBoosts,
+		"eviction_policy": cfg.Memory.EvictionPolicy,
+		"plan_limits": cfg.Memory.Plans,
+		"context_buffer_ttl": cfg.Memory.ContextBufferTTL,
+		"duplicate_action": cfg.Memory.DuplicateAction,
+		"duplicate_threshold": cfg.Memory.DuplicateThreshold,
+		"feedback_weight": cfg.Memory.FeedbackWeight,
+		"max_content_length": cfg.Memory.MaxContentLength,
+		"chunk_size": cfg.Memory.ChunkSize,
+		"attachment_max_size": cfg.Attachments.MaxSize,
+		"attachment_max_per_memory": cfg.Attachments.MaxPerMemory,
+		"exact_counts": cfg.Memory.ExactCounts,
+		"normalize_detected": cfg.Memory.NormalizeDetected,
+		"filler_words": cfg.Memory.FillerWords,
+		"maintenance_stale_after": cfg.Maintenance.StaleAfter,
+		"maintenance_max_actions": cfg.Maintenance.MaxActions,
+		"consolidation_similarity": cfg.Consolidation.Similarity,
+		"consolidation_min_cluster": cfg.Consolidation.MinClusterSize,
+		"consolidation_max_cluster": cfg.Consolidation.MaxClusterSize,
+		"write_timeout": cfg.Timeouts.Write,
+		"embedding_cache": cfg.Embedding.Cache,
+		"residency_region": cfg.Residency.Region,
+		"access_audit": cfg.Audit.MemoryAccess,
+		"notifier": services.NewNotifierFromConfig(cfg, logger),
+		"llm_budget": services.NewLLMBudgetFromConfig(cfg),
 	}
+	if encryptionService != nil {
+		serviceConfig["encryption_service"] = encryptionService
+	}
+	moderationHook, err := services.NewModerationHookFromConfig(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if moderationHook != nil {
+		serviceConfig["moderation"] = moderationHook
+	}
+	memoryExtractor, err := services.NewMemoryExtractorFromConfig(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if memoryExtractor != nil {
+		serviceConfig["memory_extractor"] = memoryExtractor
+	}
+	if summarizer := services.NewMemorySummarizerFromConfig(cfg, logger); summarizer != nil {
+		serviceConfig["memory_summarizer"] = summarizer
+	}
+	transcriber, err := services.NewTranscriberFromConfig(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if transcriber != nil {
+		serviceConfig["transcriber"] = transcriber
+	}
+	attachmentStore, err := services.NewAttachmentStoreFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if attachmentStore != nil {
+		serviceConfig["attachment_store"] = attachmentStore
+	}
+	embeddingComposer, err := services.NewEmbeddingComposer(cfg.Embedding.Document)
+	if err != nil {
+		return nil, err
+	}
+	if embeddingComposer != nil {
+		serviceConfig["embedding_composer"] = embeddingComposer
+	}
+	return serviceConfig, nil
+}
 
-	// Create OpenAI embedding service
-	logger.Info().
-		Str("model", cfg.OpenAI.Model).
-		Msg("Creating OpenAI embedding service")
-
-	embeddingService, err := services.NewOpenAIEmbeddingService(&cfg.OpenAI, logger)
+// createEmbeddingService creates the embedding service selected by embedding.provider
+func createEmbeddingService(cfg *config.Config, logger zerolog.Logger) services.EmbeddingService {
+	embeddingService, err := services.NewEmbeddingServiceFromConfig(cfg, logger)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to create OpenAI embedding service, falling back to mock")
+		logger.Error().Err(err).Str("provider", cfg.Embedding.Provider).Msg("Failed to create embedding service, falling back to mock")
 		return services.NewMockEmbeddingService()
 	}
 
@@ -271,19 +451,24 @@ func createEncryptionService(cfg *config.Config, logger zerolog.Logger) *utils.E
 		return nil
 	}
 	
-	encryptionService, err := utils.NewEncryptionService(cfg.Encryption.MasterKey)
+	encryptionService, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to create encryption service")
 		return nil
 	}
 	
-	logger.Info().Msg("Encryption service created successfully")
+	logger.Info().
+		Str("key_id", encryptionService.KeyID()).
+		Str("kms", cfg.Encryption.KMS.Provider).
+		Msg("Encryption service created successfully")
 	return encryptionService
 }
 
-// runVersionedMigrations runs versioned database migrations
-func runVersionedMigrations(ctx context.Context, db *database.Database, encryptionService *utils.EncryptionService, logger zerolog.Logger) error {
+// runVersionedMigrations runs versioned database migrations, backing up the tables
+// they rewrite unless the operator confirmed a backup
+func runVersionedMigrations(ctx context.Context, db *database.Database, encryptionService *utils.EncryptionService, requireBackupConfirmation, haveBackup bool, logger zerolog.Logger) error {
 	runner := database.NewMigrationRunner(db.DB(), logger)
+	runner.SetBackupPolicy(requireBackupConfirmation, haveBackup)
 	
 	// Register all migrations
 	migrations := migrations.GetMigrations(encryptionService)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/rs/zerolog"
+)
+
+// migrate-db supports a zero-downtime move to a new Postgres alongside dual writes:
+//
+//  1. set dual_write.enabled and dual_write.target, restart the server
+//  2. migrate-db -backfill copies the memories written before dual writes started
+//  3. migrate-db -verify compares row counts and hashes; add -repair to resync drift
+//  4. migrate-db -sync-sequence, then point database at the new server
+func main() {
+	var (
+		configPath   = flag.String("config", "", "Path to configuration file")
+		backfill     = flag.Bool("backfill", false, "Copy all memories from the primary to the dual write target")
+		verify       = flag.Bool("verify", false, "Compare memory row counts and hashes between the databases")
+		repair       = flag.Bool("repair", false, "With -verify, resync the memories of mismatched users")
+		syncSequence = flag.Bool("sync-sequence", false, "Move the target's memory ID sequence past the highest copied ID")
+		batchSize    = flag.Int("batch-size", 500, "Number of memories to copy at once")
+	)
+	flag.Parse()
+
+	if !*backfill && !*verify && !*syncSequence {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	logger := zerolog.New(output).With().Timestamp().Logger()
+
+	if cfg.DualWrite.Target.Host == "" {
+		logger.Fatal().Msg("dual_write.target is not configured")
+	}
+
+	source, err := database.Open(cfg.Database, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to primary database")
+	}
+	defer source.Close()
+
+	target, err := database.Open(cfg.DualWrite.Target, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to target database")
+	}
+	defer target.Close()
+
+	ctx := context.Background()
+
+	if *backfill {
+		if err := database.RunMigrations(target.DB()); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to migrate target database")
+		}
+
+		start := time.Now()
+		copied, err := database.BackfillMemories(ctx, source.DB(), target.DB(), *batchSize)
+		if err != nil {
+			logger.Fatal().Err(err).Int("copied", copied).Msg("Backfill failed")
+		}
+		logger.Info().Int("copied", copied).Dur("took", time.Since(start)).Msg("Backfill completed")
+	}
+
+	if *verify {
+		report, err := database.VerifyMemories(ctx, source.DB(), target.DB())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Verification failed")
+		}
+		for _, diff := range report.Mismatched {
+			logger.Warn().
+				Uint("user_id", diff.UserID).
+				Int64("source_rows", diff.SourceRows).
+				Int64("target_rows", diff.TargetRows).
+				Str("source_hash", diff.SourceHash).
+				Str("target_hash", diff.TargetHash).
+				Msg("Memories differ")
+		}
+		logger.Info().
+			Int64("source_rows", report.SourceRows).
+			Int64("target_rows", report.TargetRows).
+			Int("users", report.Users).
+			Int("mismatched_users", len(report.Mismatched)).
+			Bool("match", report.Match()).
+			Msg("Verification completed")
+
+		if !report.Match() {
+			if !*repair {
+				os.Exit(1)
+			}
+
+			userIDs := make([]uint, 0, len(report.Mismatched))
+			for _, diff := range report.Mismatched {
+				userIDs = append(userIDs, diff.UserID)
+			}
+			if err := database.RepairMemories(ctx, source.DB(), target.DB(), userIDs); err != nil {
+				logger.Fatal().Err(err).Msg("Repair failed")
+			}
+			logger.Info().Int("users", len(userIDs)).Msg("Repaired mismatched users; run -verify again to confirm")
+		}
+	}
+
+	if *syncSequence {
+		if err := database.SyncMemorySequence(ctx, target.DB()); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to sync memory ID sequence")
+		}
+		logger.Info().Msg("Target memory ID sequence synced")
+	}
+}
@@ -47,7 +47,7 @@ func main() {
 	}
 
 	// Create encryption service
-	encryptionService, err := utils.NewEncryptionService(cfg.Encryption.MasterKey)
+	encryptionService, err := utils.NewEncryptionService(cfg.Encryption.MasterKey, cfg.Encryption.PreviousKeys...)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to create encryption service")
 	}
@@ -139,7 +139,11 @@ func runMigration(ctx context.Context, db *gorm.DB, encSvc *utils.EncryptionServ
 			}
 
 			// Decrypt the content
-			decryptedContent, err := encSvc.DecryptField(&encryptedData)
+			aad := models.MemoryContentAAD(memory.UserID, memory.ID)
+			if encryptedData.EnvelopeVersion() == utils.EnvelopeV2 {
+				aad = models.OwnerContentAAD(memory.UserID)
+			}
+			decryptedContent, err := encSvc.DecryptFieldWithAAD(&encryptedData, aad)
 			if err != nil {
 				logger.Error().
 					Err(err).
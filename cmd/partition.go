@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+)
+
+// The partition subcommand reports how the memories table is partitioned and
+// partitions it by user, for stores with millions of memories:
+//
+//	remember-me-mcp partition
+//	remember-me-mcp partition --apply --partitions 32
+//	remember-me-mcp partition --drop-original
+//
+// --apply copies memories into partitions while the server keeps running and
+// switches over under a short lock. The original table is kept until
+// --drop-original. Interrupting --apply is safe; running it again resumes.
+
+// partitionTimeout bounds a partitioning run; copying millions of rows takes a while
+const partitionTimeout = 24 * time.Hour
+
+func runPartition(args []string) error {
+	var (
+		configPath   string
+		apply        bool
+		dropOriginal bool
+		jsonOutput   bool
+		opts         database.PartitionOptions
+	)
+	fs := flag.NewFlagSet("partition", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "Path to configuration file")
+	fs.BoolVar(&apply, "apply", false, "Partition the memories table by user")
+	fs.BoolVar(&dropOriginal, "drop-original", false, "Drop the unpartitioned table kept after partitioning")
+	fs.IntVar(&opts.Partitions, "partitions", 16, "Number of hash partitions to create")
+	fs.IntVar(&opts.BatchSize, "batch-size", 5000, "Memories copied per statement")
+	fs.DurationVar(&opts.Pause, "pause", 0, "Rest between batches, to spare a busy database")
+	fs.DurationVar(&opts.LockTimeout, "lock-timeout", 10*time.Second, "Longest wait for the lock taken to switch tables")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if apply && dropOriginal {
+		return fmt.Errorf("--apply and --drop-original cannot be combined")
+	}
+
+	cfg, err := loadConfiguration(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logger := setupLogging(cfg)
+	db, err := connectToDatabase(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), partitionTimeout)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var status *database.PartitionStatus
+	switch {
+	case apply:
+		if !jsonOutput {
+			opts.Progress = func(progress database.PartitionProgress) {
+				fmt.Fprintf(os.Stderr, "\rCopied %d memories (through ID %d of %d)", progress.Copied, progress.LastID, progress.MaxID)
+			}
+		}
+		status, err = database.PartitionMemories(ctx, db.DB(), opts, logger)
+		if !jsonOutput {
+			fmt.Fprintln(os.Stderr)
+		}
+	case dropOriginal:
+		if err = database.DropUnpartitionedMemories(ctx, db.DB()); err == nil {
+			status, err = database.InspectPartitioning(ctx, db.DB())
+		}
+	default:
+		status, err = database.InspectPartitioning(ctx, db.DB())
+	}
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(status)
+	}
+	printPartitionStatus(status)
+	return nil
+}
+
+// printPartitionStatus prints how the memories table is partitioned
+func printPartitionStatus(status *database.PartitionStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	switch {
+	case status.Partitioned:
+		fmt.Fprintf(w, "Memories:\tpartitioned by user into %d partitions\n", len(status.Partitions))
+	case status.Migrating:
+		fmt.Fprintf(w, "Memories:\tbeing copied into %s; run --apply to finish\n", database.PartitionStagingTable)
+	default:
+		fmt.Fprintln(w, "Memories:\tnot partitioned")
+	}
+	if status.UnpartitionedKept {
+		fmt.Fprintf(w, "Original:\tkept as %s; --drop-original removes it\n", database.UnpartitionedMemoriesTable)
+	}
+	for _, partition := range status.Partitions {
+		fmt.Fprintf(w, "  %s\t~%d rows\t%s\n", partition.Name, partition.Rows, formatBytes(partition.Bytes))
+	}
+}
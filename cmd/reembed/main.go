@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/rs/zerolog"
+)
+
+// reembed regenerates every memory's embedding after switching embedding models:
+//
+//  1. point the embedding configuration at the new model and restart the servers
+//  2. reembed; rerun it to resume if it is interrupted
+//  3. reembed -status to see the progress of each run
+//
+// Searches rank less well until the run completes, as they compare embeddings
+// from both models.
+func main() {
+	var (
+		configPath = flag.String("config", "", "Path to configuration file")
+		batchSize  = flag.Int("batch-size", 100, "Number of memories to re-embed between checkpoints")
+		restart    = flag.Bool("restart", false, "Start over instead of resuming an unfinished run")
+		status     = flag.Bool("status", false, "Show re-embedding runs without running one")
+	)
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	logger := zerolog.New(output).With().Timestamp().Logger()
+
+	db, err := database.Open(cfg.Database, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	// Memories are embedded exactly as the server embeds them
+	serviceConfig := map[string]interface{}{
+		"embedding_cache": cfg.Embedding.Cache,
+	}
+	if cfg.Encryption.Enabled {
+		encryptionService, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create encryption service")
+		}
+		serviceConfig["encryption_service"] = encryptionService
+	}
+	moderationHook, err := services.NewModerationHookFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create moderation hook")
+	}
+	if moderationHook != nil {
+		serviceConfig["moderation"] = moderationHook
+	}
+	embeddingComposer, err := services.NewEmbeddingComposer(cfg.Embedding.Document)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid embedding document template")
+	}
+	if embeddingComposer != nil {
+		serviceConfig["embedding_composer"] = embeddingComposer
+	}
+
+	embeddingService, err := services.NewEmbeddingServiceFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Str("provider", cfg.Embedding.Provider).Msg("Failed to create embedding service")
+	}
+	memoryService := services.NewMemoryService(db.DB(), embeddingService, logger, serviceConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *status {
+		runs, err := memoryService.ReembedRuns(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to list re-embedding runs")
+		}
+		if len(runs) == 0 {
+			logger.Info().Msg("No re-embedding runs")
+		}
+		for _, run := range runs {
+			logRun(logger, &run).Msg("Re-embedding run")
+		}
+		return
+	}
+
+	logger.Info().
+		Str("model", memoryService.EmbeddingModel()).
+		Int("batch_size", *batchSize).
+		Bool("restart", *restart).
+		Msg("Starting re-embedding")
+
+	start := time.Now()
+	run, err := memoryService.ReembedAll(ctx, services.ReembedOptions{
+		BatchSize: *batchSize,
+		Restart:   *restart,
+		Progress: func(run *models.ReembedRun) {
+			logger.Info().
+				Uint("last_memory_id", run.LastMemoryID).
+				Int64("processed", run.Processed).
+				Int64("total", run.Total).
+				Msg("Processed batch")
+		},
+	})
+	if err != nil {
+		if run != nil {
+			logRun(logger, run).Msg("Re-embedding stopped")
+		}
+		logger.Fatal().Err(err).Msg("Re-embedding failed; rerun to resume")
+	}
+
+	logRun(logger, run).Dur("took", time.Since(start)).Msg("Re-embedding completed")
+	if run.Skipped > 0 {
+		logger.Error().
+			Int64("skipped", run.Skipped).
+			Msg("Some memories could not be decrypted and kept their old embeddings")
+		os.Exit(1)
+	}
+}
+
+// logRun starts a log event describing a re-embedding run
+func logRun(logger zerolog.Logger, run *models.ReembedRun) *zerolog.Event {
+	event := logger.Info().
+		Uint("run_id", run.ID).
+		Str("model", run.Model).
+		Str("status", run.Status).
+		Int64("processed", run.Processed).
+		Int64("skipped", run.Skipped).
+		Int64("total", run.Total).
+		Time("started_at", run.StartedAt)
+	if run.LastError != "" {
+		event = event.Str("last_error", run.LastError)
+	}
+	return event
+}
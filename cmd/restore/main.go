@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/rs/zerolog"
+)
+
+// restore rebuilds a database from a backup taken by the scheduled backup
+// worker: users, workspaces, API keys and memories, with their embeddings when
+// this deployment embeds with the model the backup was taken with. The schema is
+// migrated first, so the database may be empty.
+//
+//	restore -list                          lists the backups in the configured destination
+//	restore -backup latest                 restores the newest of them
+//	restore -backup backups/remember-me-20260101T030000Z.jsonl.gz
+//	restore -file ./remember-me-20260101T030000Z.jsonl.gz.enc
+func main() {
+	var (
+		configPath       = flag.String("config", "", "Path to configuration file")
+		backupKey        = flag.String("backup", "", "Key of the backup to restore from the configured destination, or latest")
+		filePath         = flag.String("file", "", "Backup file to restore instead of one in the configured destination")
+		list             = flag.Bool("list", false, "List the backups in the configured destination")
+		allowCrossRegion = flag.Bool("allow-cross-region", false, "Allow restoring a backup taken in another region")
+	)
+	flag.Parse()
+
+	if !*list && (*backupKey == "") == (*filePath == "") {
+		log.Fatal("Specify -list, or one of -backup and -file")
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	output := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	logger := zerolog.New(output).With().Timestamp().Logger()
+	ctx := context.Background()
+
+	if *list || *backupKey != "" {
+		store, err := services.NewBackupStoreFromConfig(cfg.Backup)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open backup destination")
+		}
+		backups, err := services.ListBackups(ctx, store)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to list backups")
+		}
+		if *list {
+			for _, key := range backups {
+				fmt.Println(key)
+			}
+			return
+		}
+		if *backupKey == "latest" {
+			if len(backups) == 0 {
+				logger.Fatal().Str("destination", cfg.Backup.Destination).Msg("No backups found")
+			}
+			*backupKey = backups[len(backups)-1]
+		}
+	}
+
+	// Connect to database and bring the schema up to date
+	db, err := database.Open(cfg.Database, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db.DB()); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to migrate database")
+	}
+
+	serviceConfig := map[string]interface{}{
+		"memory_limit":       cfg.Memory.MaxMemories,
+		"eviction_policy":    cfg.Memory.EvictionPolicy,
+		"plan_limits":        cfg.Memory.Plans,
+		"residency_region":   cfg.Residency.Region,
+		"max_content_length": cfg.Memory.MaxContentLength,
+		"chunk_size":         cfg.Memory.ChunkSize,
+	}
+	if cfg.Encryption.Enabled {
+		encryptionService, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create encryption service")
+		}
+		serviceConfig["encryption_service"] = encryptionService
+	}
+	backupEncryption, err := services.NewBackupEncryptionFromConfig(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid backup encryption key")
+	}
+
+	// The embedding service names the model embeddings were produced with; without
+	// one, backed up embeddings are not restored, and the server's backfill worker
+	// embeds the restored memories instead
+	var embeddingService services.EmbeddingService
+	if cfg.OpenAI.APIKey != "" {
+		openAI, err := services.NewOpenAIEmbeddingService(&cfg.OpenAI, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create embedding service")
+		}
+		embeddingService = openAI
+	}
+	memoryService := services.NewMemoryService(db.DB(), embeddingService, logger, serviceConfig)
+
+	var backup io.Reader
+	if *filePath != "" {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open backup")
+		}
+		defer file.Close()
+		backup = file
+	} else {
+		store, err := services.NewBackupStoreFromConfig(cfg.Backup)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open backup destination")
+		}
+		data, err := store.Get(ctx, *backupKey)
+		if err != nil {
+			logger.Fatal().Err(err).Str("backup", *backupKey).Msg("Failed to download backup")
+		}
+		backup = bytes.NewReader(data)
+	}
+
+	result, err := memoryService.RestoreBackup(ctx, backup, services.RestoreOptions{
+		Encryption:       backupEncryption,
+		AllowCrossRegion: *allowCrossRegion,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Restore failed")
+	}
+
+	// Embeddings queued during the restore are generated before exiting
+	if err := memoryService.Drain(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Some embeddings were not generated; the backfill worker will retry them")
+	}
+
+	logger.Info().
+		Int("users", result.Users).
+		Int("users_created", result.UsersCreated).
+		Int("workspaces", result.Workspaces).
+		Int("api_keys", result.APIKeys).
+		Int("memories_created", result.Created).
+		Int("memories_skipped", result.Skipped).
+		Int("embeddings_restored", result.EmbeddingsRestored).
+		Int("embeddings_queued", result.EmbeddingsQueued).
+		Msg("Restore completed successfully")
+}
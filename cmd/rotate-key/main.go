@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// rotate-key moves all encrypted data onto one master key. Without downtime:
+//
+//  1. generate a key with cmd/keygen
+//  2. set ENCRYPTION_MASTER_KEY to the new key and ENCRYPTION_PREVIOUS_KEYS to the
+//     old one, and restart the servers one at a time
+//  3. rotate-key -dry-run to see what would change
+//  4. rotate-key; rerun it to resume if it is interrupted
+//  5. remove ENCRYPTION_PREVIOUS_KEYS and restart the servers
+//
+// Alternatively, with the servers stopped, rotate-key -new-key <key> moves the
+// data from the configured master key to the new one, after which
+// ENCRYPTION_MASTER_KEY is set to the new key and the servers started.
+func main() {
+	var (
+		configPath = flag.String("config", "", "Path to configuration file")
+		newKey     = flag.String("new-key", os.Getenv("NEW_ENCRYPTION_MASTER_KEY"), "New base64 master key (default $NEW_ENCRYPTION_MASTER_KEY)")
+		dryRun     = flag.Bool("dry-run", false, "Count what would be rotated without making changes")
+		batchSize  = flag.Int("batch-size", 500, "Number of rows to rotate at once")
+	)
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	logger := zerolog.New(output).With().Timestamp().Logger()
+
+	if cfg.Encryption.MasterKey == "" {
+		logger.Fatal().Msg("No current encryption master key provided")
+	}
+	if *newKey == "" && len(cfg.Encryption.PreviousKeys) == 0 {
+		logger.Fatal().Msg("No new master key provided; use -new-key or NEW_ENCRYPTION_MASTER_KEY, or configure ENCRYPTION_PREVIOUS_KEYS")
+	}
+	if *newKey == cfg.Encryption.MasterKey {
+		logger.Fatal().Msg("The new master key is the same as the current one")
+	}
+
+	from, err := services.NewEncryptionServiceFromConfig(cfg.Encryption)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid current master key")
+	}
+	// Without a new key, data under the previous keys moves to the current one,
+	// which the running servers already encrypt with
+	to := from
+	if *newKey != "" {
+		if to, err = utils.NewEncryptionService(*newKey); err != nil {
+			logger.Fatal().Err(err).Msg("Invalid new master key")
+		}
+	}
+
+	db, err := database.Open(cfg.Database, "silent")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	logger.Info().
+		Str("key_id", to.KeyID()).
+		Bool("dry_run", *dryRun).
+		Int("batch_size", *batchSize).
+		Msg("Starting key rotation")
+
+	start := time.Now()
+	report, err := database.RotateEncryptionKey(context.Background(), db.DB(), from, to, database.KeyRotationOptions{
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+		Progress: func(p database.KeyRotationProgress) {
+			logger.Info().
+				Str("table", p.Table).
+				Uint("last_id", p.LastID).
+				Int("scanned", p.Scanned).
+				Int("rotated", p.Rotated).
+				Msg("Processed batch")
+		},
+	})
+
+	failed := 0
+	for _, p := range report {
+		failed += p.Failed
+		logger.Info().
+			Str("table", p.Table).
+			Str("column", p.Column).
+			Int("scanned", p.Scanned).
+			Int("rotated", p.Rotated).
+			Int("already_rotated", p.AlreadyRotated).
+			Int("failed", p.Failed).
+			Bool("dry_run", *dryRun).
+			Msg("Table rotated")
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Key rotation failed; rerun to resume")
+	}
+	if failed > 0 {
+		logger.Error().
+			Int("failed", failed).
+			Msg("Some rows could be decrypted with none of the keys and were left unchanged")
+		os.Exit(1)
+	}
+
+	message := "Key rotation completed; remove ENCRYPTION_PREVIOUS_KEYS"
+	if *newKey != "" {
+		message = "Key rotation completed; switch ENCRYPTION_MASTER_KEY to the new key"
+	}
+	logger.Info().
+		Dur("took", time.Since(start)).
+		Bool("dry_run", *dryRun).
+		Msg(message)
+}
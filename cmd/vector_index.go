@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+)
+
+// The vector-index subcommand reports the pgvector index on memory embeddings
+// and rebuilds it, e.g. after a bulk import:
+//
+//	remember-me-mcp vector-index
+//	remember-me-mcp vector-index --rebuild
+//
+// The rebuild runs concurrently, so the server keeps serving meanwhile.
+
+// vectorIndexTimeout bounds a rebuild; large tables take a while
+const vectorIndexTimeout = 6 * time.Hour
+
+func runVectorIndex(args []string) error {
+	var (
+		configPath string
+		rebuild    bool
+		jsonOutput bool
+	)
+	fs := flag.NewFlagSet("vector-index", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "Path to configuration file")
+	fs.BoolVar(&rebuild, "rebuild", false, "Rebuild the index and refresh planner statistics")
+	fs.BoolVar(&jsonOutput, "json", false, "Print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfiguration(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logger := setupLogging(cfg)
+	db, err := connectToDatabase(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), vectorIndexTimeout)
+	defer cancel()
+
+	opts := database.VectorIndexOptionsFromConfig(cfg.Database.VectorIndex)
+	if !rebuild {
+		status, err := database.InspectVectorIndex(ctx, db.DB(), opts)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(status)
+		}
+		printVectorIndexStatus(status)
+		return nil
+	}
+
+	results, status, err := database.RebuildVectorIndex(ctx, db.DB(), opts, logger)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(struct {
+			Results []database.MaintenanceResult `json:"results"`
+			Index   *database.VectorIndexStatus  `json:"index"`
+		}{results, status})
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "FAILED %s: %s\n", result.Statement, result.Error)
+		} else {
+			fmt.Printf("%s (%s)\n", result.Statement, result.Duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Println()
+	printVectorIndexStatus(status)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d maintenance statements failed", failed, len(results))
+	}
+	return nil
+}
+
+// printVectorIndexStatus prints the state of the vector index
+func printVectorIndexStatus(status *database.VectorIndexStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Configured:\t%s\n", status.Configured)
+	if !status.Exists {
+		fmt.Fprintln(w, "Index:\tmissing")
+		return
+	}
+	fmt.Fprintf(w, "Index:\t%s (%s)\n", status.Type, strings.Join(status.Parameters, ", "))
+	fmt.Fprintf(w, "Size:\t%s\n", formatBytes(status.Bytes))
+	fmt.Fprintf(w, "Valid:\t%t\n", status.Valid)
+	fmt.Fprintf(w, "Up to date:\t%t\n", status.UpToDate)
+	switch {
+	case status.UsedByQueries == nil:
+		fmt.Fprintln(w, "Used by queries:\tunknown (no embeddings yet)")
+	default:
+		fmt.Fprintf(w, "Used by queries:\t%t\n", *status.UsedByQueries)
+	}
+}
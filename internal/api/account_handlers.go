@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// exportAccountHandler godoc
+// @Summary Export all account data
+// @Description Download everything held about the account in one archive: the account, the memories of every workspace with their embeddings, earlier versions of memories, API keys (without the keys themselves), sessions, saved searches, rules, snapshots, support grants, LLM usage and activity. Content is decrypted, so store the archive securely. Each workspace's memories can be imported with POST /memories/import.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.AccountExport
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/export [get]
+func (s *Server) exportAccountHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	export, err := s.createScopedMemoryService(user.ID).ExportAccount(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to export account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="remember-me-account.json"`)
+	c.JSON(http.StatusOK, export)
+
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityAccountExported, map[string]interface{}{
+		"workspace_count": len(export.Workspaces),
+	}, c.ClientIP(), c.GetHeader("User-Agent"))
+}
+
+// deleteAccountHandler godoc
+// @Summary Delete the account
+// @Description Permanently delete the account and all its data: memories and their embeddings, attachments, history, snapshots, activity logs, performance metrics, API keys and settings. The first request deletes nothing and returns 409 with a confirm token; repeat it with that token to delete the account. The token stops working if memories are added or removed in between.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Param confirm query string false "Token from a first delete request"
+// @Success 200 {object} services.AccountDeletionResult
+// @Failure 400 {object} ErrorResponse "The system account cannot be deleted"
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Confirmation required; the response carries the confirm token"
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me [delete]
+func (s *Server) deleteAccountHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	result, err := s.createScopedMemoryService(user.ID).DeleteAccount(c.Request.Context(), c.Query("confirm"))
+	if err != nil {
+		var confirmErr *services.AccountDeletionConfirmationError
+		if errors.As(err, &confirmErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":    err.Error(),
+				"memories": confirmErr.Memories,
+				"confirm":  confirmErr.Token,
+			})
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to delete account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	s.mcpClients.Delete(user.ID)
+	s.mcpWorkspaces.Delete(user.ID)
+	s.logger.Info().Uint("user_id", user.ID).Interface("deleted", result.Deleted).Msg("Account deleted at the user's request")
+
+	// The account is gone, so the request's performance metric is not tied to it
+	c.Set(userContextKey, (*models.User)(nil))
+	c.JSON(http.StatusOK, result)
+}
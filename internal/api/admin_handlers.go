@@ -0,0 +1,259 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+type AdminOverviewResponse struct {
+	Users            int64                         `json:"users"`
+	Memories         int64                         `json:"memories"`
+	AnomalyDetection bool                          `json:"anomaly_detection"`
+	OpenAlerts       int64                         `json:"open_alerts"`
+	RecentAlerts     []models.Alert                `json:"recent_alerts"`
+	EmbeddingCache   *services.EmbeddingCacheStats `json:"embedding_cache"`
+}
+
+// adminOverviewHandler godoc
+// @Summary Admin overview
+// @Description Deployment-wide counts, embedding cache totals and the most recent unacknowledged anomaly alerts
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} AdminOverviewResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/overview [get]
+func (s *Server) adminOverviewHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	db := s.db.DB().WithContext(ctx)
+
+	response := AdminOverviewResponse{
+		RecentAlerts: []models.Alert{},
+	}
+
+	if err := db.Model(&models.User{}).Count(&response.Users).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to count users")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overview"})
+		return
+	}
+	if err := db.Model(&models.Memory{}).Count(&response.Memories).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to count memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overview"})
+		return
+	}
+	embeddingCache, err := s.memoryService.AllUsersEmbeddingCacheStats(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get embedding cache stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overview"})
+		return
+	}
+	response.EmbeddingCache = embeddingCache
+
+	if detector := s.activityService.AnomalyDetector(); detector != nil {
+		response.AnomalyDetection = true
+
+		openAlerts, err := detector.CountOpenAlerts(ctx)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Failed to count alerts")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overview"})
+			return
+		}
+		response.OpenAlerts = openAlerts
+
+		alerts, err := detector.ListAlerts(ctx, false, 10)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Failed to list alerts")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overview"})
+			return
+		}
+		if alerts != nil {
+			response.RecentAlerts = alerts
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// listAlertsHandler godoc
+// @Summary List anomaly alerts
+// @Description List anomaly alerts, newest first. Only unacknowledged alerts are returned unless all=true.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param all query bool false "Include acknowledged alerts"
+// @Param limit query int false "Maximum number of alerts (default: 100)"
+// @Success 200 {array} models.Alert
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/alerts [get]
+func (s *Server) listAlertsHandler(c *gin.Context) {
+	detector := s.activityService.AnomalyDetector()
+	if detector == nil {
+		c.JSON(http.StatusOK, []models.Alert{})
+		return
+	}
+
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
+			limit = parsedLimit
+		}
+	}
+
+	alerts, err := detector.ListAlerts(c.Request.Context(), c.Query("all") == "true", limit)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list alerts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alerts"})
+		return
+	}
+
+	if alerts == nil {
+		alerts = []models.Alert{}
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// acknowledgeAlertHandler godoc
+// @Summary Acknowledge an anomaly alert
+// @Description Mark an alert as handled so it no longer appears in the overview
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Alert ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/alerts/{id}/acknowledge [post]
+func (s *Server) acknowledgeAlertHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	detector := s.activityService.AnomalyDetector()
+	if detector == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+		return
+	}
+
+	if err := detector.AcknowledgeAlert(c.Request.Context(), uint(id), admin.ID); err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to acknowledge alert")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// storageReportHandler godoc
+// @Summary Storage compaction report
+// @Description Table, TOAST, index and vector index sizes with dead tuple estimates, the users whose memories take
+// @Description the most space, and suggested VACUUM and REINDEX statements. Run them with the compact subcommand.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param users query int false "Number of largest users to report (default: 20, max: 1000)"
+// @Success 200 {object} database.CompactionReport
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/storage [get]
+func (s *Server) storageReportHandler(c *gin.Context) {
+	users := 20
+	if usersStr := c.Query("users"); usersStr != "" {
+		if parsedUsers, err := strconv.Atoi(usersStr); err == nil && parsedUsers > 0 && parsedUsers <= 1000 {
+			users = parsedUsers
+		}
+	}
+
+	report, err := database.BuildCompactionReport(c.Request.Context(), s.db.DB(), users)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to build storage report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build storage report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RebuildVectorIndexResponse reports a vector index rebuild
+type RebuildVectorIndexResponse struct {
+	Results []database.MaintenanceResult `json:"results"`
+	Index   *database.VectorIndexStatus  `json:"index"`
+}
+
+// vectorIndexHandler godoc
+// @Summary Vector index status
+// @Description The pgvector index on memory embeddings as built and as configured, its size, and whether the planner uses it for similarity queries
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} database.VectorIndexStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/vector-index [get]
+func (s *Server) vectorIndexHandler(c *gin.Context) {
+	opts := database.VectorIndexOptionsFromConfig(s.config.Database.VectorIndex)
+	status, err := database.InspectVectorIndex(c.Request.Context(), s.db.DB(), opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to inspect vector index")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inspect vector index"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// rebuildVectorIndexHandler godoc
+// @Summary Rebuild the vector index
+// @Description Rebuild the pgvector index from the current embeddings and refresh planner statistics, e.g. after a bulk import. A missing index, or one built with other parameters than configured, is created as configured. Writes continue during the rebuild.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} RebuildVectorIndexResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/vector-index/rebuild [post]
+func (s *Server) rebuildVectorIndexHandler(c *gin.Context) {
+	opts := database.VectorIndexOptionsFromConfig(s.config.Database.VectorIndex)
+	results, status, err := database.RebuildVectorIndex(c.Request.Context(), s.db.DB(), opts, s.logger)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to rebuild vector index")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild vector index: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RebuildVectorIndexResponse{Results: results, Index: status})
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// AdminUser is an account as listed to admins
+type AdminUser struct {
+	ID          uint       `json:"id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	Plan        string     `json:"plan,omitempty"`
+	MemoryLimit *int       `json:"memory_limit,omitempty"`
+	Disabled    bool       `json:"disabled"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	MemoryCount int64      `json:"memory_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AdminUserListResponse is a page of accounts
+type AdminUserListResponse struct {
+	Users  []AdminUser `json:"users"`
+	Total  int64       `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// AdminUserDetailResponse is an account with its memory usage and recent activity
+type AdminUserDetailResponse struct {
+	AdminUser
+	Quota    *services.QuotaUsage   `json:"quota"`
+	Activity map[string]interface{} `json:"activity"`
+}
+
+// ResetPasswordRequest sets a user's password; without one a temporary password
+// is generated
+type ResetPasswordRequest struct {
+	Password string `json:"password,omitempty" example:"new-password-123"`
+}
+
+// ResetPasswordResponse returns a generated password, shown only once
+type ResetPasswordResponse struct {
+	Message  string `json:"message"`
+	Password string `json:"password,omitempty"`
+}
+
+// SetRoleRequest changes a user's role
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required" enums:"user,admin" example:"admin"`
+}
+
+func newAdminUser(user *models.User, memoryCount int64) AdminUser {
+	return AdminUser{
+		ID:          user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		Plan:        user.Plan,
+		MemoryLimit: user.MemoryLimit,
+		Disabled:    user.IsDisabled(),
+		DisabledAt:  user.DisabledAt,
+		MemoryCount: memoryCount,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// userIDParam parses the :id path parameter, answering 400 if it is invalid
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// listUsersHandler godoc
+// @Summary List users
+// @Description List accounts with their memory counts, oldest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param q query string false "Only users whose email contains this text"
+// @Param role query string false "Only users with this role (user, admin)"
+// @Param disabled query bool false "Only disabled (true) or active (false) users"
+// @Param limit query int false "Maximum number of users (default: 50, max: 500)"
+// @Param offset query int false "Number of users to skip"
+// @Success 200 {object} AdminUserListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users [get]
+func (s *Server) listUsersHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	db := s.db.DB().WithContext(ctx)
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 500 {
+			limit = parsedLimit
+		}
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset > 0 {
+			offset = parsedOffset
+		}
+	}
+
+	query := db.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	switch c.Query("disabled") {
+	case "true":
+		query = query.Where("disabled_at IS NOT NULL")
+	case "false":
+		query = query.Where("disabled_at IS NULL")
+	}
+
+	response := AdminUserListResponse{Users: []AdminUser{}, Limit: limit, Offset: offset}
+	if err := query.Count(&response.Total).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to count users")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list users")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	counts := make(map[uint]int64)
+	if len(users) > 0 {
+		ids := make([]uint, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		var rows []struct {
+			UserID uint
+			Count  int64
+		}
+		if err := db.Model(&models.Memory{}).
+			Select("user_id, COUNT(*) AS count").
+			Where("user_id IN ?", ids).
+			Group("user_id").
+			Scan(&rows).Error; err != nil {
+			s.logger.Error().Err(err).Msg("Failed to count memories per user")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+			return
+		}
+		for _, row := range rows {
+			counts[row.UserID] = row.Count
+		}
+	}
+
+	for i := range users {
+		response.Users = append(response.Users, newAdminUser(&users[i], counts[users[i].ID]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getUserHandler godoc
+// @Summary Get a user
+// @Description Get an account with its memory usage against its quota and its recent activity
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} AdminUserDetailResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id} [get]
+func (s *Server) getUserHandler(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var user models.User
+	if err := s.db.DB().WithContext(ctx).First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	quota, err := s.createScopedMemoryService(id).Quota(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", id).Msg("Failed to get quota")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	activity, err := s.activityService.GetUserActivityStats(ctx, id)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", id).Msg("Failed to get user activity")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AdminUserDetailResponse{
+		AdminUser: newAdminUser(&user, quota.MemoriesUsed),
+		Quota:     quota,
+		Activity:  activity,
+	})
+}
+
+// disableUserHandler godoc
+// @Summary Disable a user
+// @Description Disable an account. The user can no longer log in, and their tokens and API keys stop working;
+// @Description their memories are kept.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/disable [post]
+func (s *Server) disableUserHandler(c *gin.Context) {
+	s.setUserDisabled(c, true)
+}
+
+// enableUserHandler godoc
+// @Summary Enable a user
+// @Description Re-enable a disabled account
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/enable [post]
+func (s *Server) enableUserHandler(c *gin.Context) {
+	s.setUserDisabled(c, false)
+}
+
+func (s *Server) setUserDisabled(c *gin.Context, disabled bool) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if disabled && id == admin.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins can't disable their own account"})
+		return
+	}
+
+	if err := s.authService.SetDisabled(id, disabled); err != nil {
+		s.respondAccountError(c, err, id)
+		return
+	}
+
+	change := "enabled"
+	if disabled {
+		change = "disabled"
+	}
+	s.logAccountChange(c, admin.ID, id, map[string]interface{}{"change": change})
+	c.Status(http.StatusNoContent)
+}
+
+// resetPasswordHandler godoc
+// @Summary Reset a user's password
+// @Description Set a user's password. Without one in the body a temporary password is generated and returned;
+// @Description it is not shown again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body ResetPasswordRequest false "New password"
+// @Success 200 {object} ResetPasswordResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/reset-password [post]
+func (s *Server) resetPasswordHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ResetPasswordRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response := ResetPasswordResponse{Message: "Password reset"}
+	password := req.Password
+	if password == "" {
+		generated, err := GeneratePassword()
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Failed to generate password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		password = generated
+		response.Password = generated
+	}
+
+	if err := s.authService.SetPassword(id, password); err != nil {
+		s.respondAccountError(c, err, id)
+		return
+	}
+
+	s.logAccountChange(c, admin.ID, id, map[string]interface{}{"change": "password_reset"})
+	c.JSON(http.StatusOK, response)
+}
+
+// setUserRoleHandler godoc
+// @Summary Change a user's role
+// @Description Make a user an admin or a regular user. The change applies to bearer tokens issued after it,
+// @Description so a new admin must log in again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body SetRoleRequest true "Role"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/role [put]
+func (s *Server) setUserRoleHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if id == admin.ID && req.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins can't remove their own admin role"})
+		return
+	}
+
+	if err := s.authService.SetRole(id, req.Role); err != nil {
+		s.respondAccountError(c, err, id)
+		return
+	}
+
+	s.logAccountChange(c, admin.ID, id, map[string]interface{}{"change": "role", "role": req.Role})
+	c.Status(http.StatusNoContent)
+}
+
+// respondAccountError answers an error from changing an account
+func (s *Server) respondAccountError(c *gin.Context, err error, userID uint) {
+	switch {
+	case utils.IsValidationError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case utils.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	default:
+		s.logger.Error().Err(err).Uint("user_id", userID).Msg("Failed to update user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	}
+}
+
+// logAccountChange records an admin's change to an account in that account's
+// activity log
+func (s *Server) logAccountChange(c *gin.Context, adminID, userID uint, details map[string]interface{}) {
+	details["admin_id"] = adminID
+	s.logger.Info().Uint("admin_id", adminID).Uint("user_id", userID).Interface("change", details["change"]).Msg("Account changed by admin")
+	go s.activityService.LogActivity(context.Background(), userID, models.ActivityAccountChanged, details, c.ClientIP(), c.GetHeader("User-Agent"))
+}
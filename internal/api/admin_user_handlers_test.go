@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// adminTestServer is a test server with an admin and a regular user, and helpers
+// to call it
+type adminTestServer struct {
+	*Server
+	t     *testing.T
+	admin *models.User
+	user  *models.User
+}
+
+func setupAdminTestServer(t *testing.T) *adminTestServer {
+	server, cleanup := setupTestServer(t)
+	t.Cleanup(cleanup)
+
+	admin, err := server.authService.RegisterUser("admin@example.com", "admin-password")
+	require.NoError(t, err)
+	require.NoError(t, server.authService.SetRole(admin.ID, models.RoleAdmin))
+	user, err := server.authService.RegisterUser("user@example.com", "user-password")
+	require.NoError(t, err)
+
+	return &adminTestServer{Server: server, t: t, admin: admin, user: user}
+}
+
+// request sends a request with the given credential, a bearer token or with
+// apiKey set an API key
+func (s *adminTestServer) request(method, path, credential string, apiKey bool, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(s.t, err)
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey {
+		req.Header.Set("X-API-Key", credential)
+	} else if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// logIn returns an access token, or "" when the login is refused
+func (s *adminTestServer) logIn(email, password string) string {
+	rec := s.request(http.MethodPost, "/api/v1/auth/login", "", false, LoginRequest{Email: email, Password: password})
+	if rec.Code != http.StatusOK {
+		return ""
+	}
+	var session LoginResponse
+	require.NoError(s.t, json.Unmarshal(rec.Body.Bytes(), &session))
+	return session.Token
+}
+
+func (s *adminTestServer) userPath(id uint, action string) string {
+	return fmt.Sprintf("/api/v1/admin/users/%d/%s", id, action)
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	server := setupAdminTestServer(t)
+	adminToken := server.logIn("admin@example.com", "admin-password")
+	require.NotEmpty(t, adminToken)
+
+	assert.Equal(t, http.StatusOK, server.request(http.MethodGet, "/api/v1/admin/users", adminToken, false, nil).Code)
+
+	t.Run("admin API keys are refused", func(t *testing.T) {
+		key, err := server.authService.GenerateAPIKey(server.admin.ID, "scripts", nil)
+		require.NoError(t, err)
+		rec := server.request(http.MethodGet, "/api/v1/admin/users", key.Key, true, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bearer token")
+		assert.Equal(t, http.StatusOK, server.request(http.MethodGet, "/api/v1/keys", key.Key, true, nil).Code,
+			"the key still works for the admin's own data")
+	})
+
+	t.Run("regular users are refused", func(t *testing.T) {
+		token := server.logIn("user@example.com", "user-password")
+		assert.Equal(t, http.StatusForbidden, server.request(http.MethodGet, "/api/v1/admin/users", token, false, nil).Code)
+	})
+
+	t.Run("tokens issued before the role was removed are refused", func(t *testing.T) {
+		require.NoError(t, server.authService.SetRole(server.admin.ID, models.RoleUser))
+		assert.Equal(t, http.StatusForbidden, server.request(http.MethodGet, "/api/v1/admin/users", adminToken, false, nil).Code)
+	})
+}
+
+func TestDisableUserHandler(t *testing.T) {
+	server := setupAdminTestServer(t)
+	adminToken := server.logIn("admin@example.com", "admin-password")
+	userToken := server.logIn("user@example.com", "user-password")
+	require.NotEmpty(t, userToken)
+
+	rec := server.request(http.MethodPost, server.userPath(server.user.ID, "disable"), adminToken, false, nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, server.logIn("user@example.com", "user-password"), "disabled users cannot log in")
+	assert.NotEqual(t, http.StatusOK, server.request(http.MethodGet, "/api/v1/keys", userToken, false, nil).Code)
+
+	rec = server.request(http.MethodPost, server.userPath(server.user.ID, "enable"), adminToken, false, nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.NotEmpty(t, server.logIn("user@example.com", "user-password"))
+	assert.Equal(t, http.StatusUnauthorized, server.request(http.MethodGet, "/api/v1/keys", userToken, false, nil).Code,
+		"tokens from before the account was disabled stay revoked")
+
+	assert.Equal(t, http.StatusBadRequest, server.request(http.MethodPost, server.userPath(server.admin.ID, "disable"), adminToken, false, nil).Code,
+		"admins can't disable themselves")
+	assert.Equal(t, http.StatusNotFound, server.request(http.MethodPost, server.userPath(999, "disable"), adminToken, false, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, server.request(http.MethodPost, "/api/v1/admin/users/abc/disable", adminToken, false, nil).Code)
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	server := setupAdminTestServer(t)
+	adminToken := server.logIn("admin@example.com", "admin-password")
+	userToken := server.logIn("user@example.com", "user-password")
+
+	t.Run("generates a password", func(t *testing.T) {
+		rec := server.request(http.MethodPost, server.userPath(server.user.ID, "reset-password"), adminToken, false, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response ResetPasswordResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.NotEmpty(t, response.Password)
+
+		assert.Empty(t, server.logIn("user@example.com", "user-password"))
+		assert.NotEmpty(t, server.logIn("user@example.com", response.Password))
+		assert.Equal(t, http.StatusUnauthorized, server.request(http.MethodGet, "/api/v1/keys", userToken, false, nil).Code,
+			"sessions from before the reset end")
+	})
+
+	t.Run("sets the given password", func(t *testing.T) {
+		rec := server.request(http.MethodPost, server.userPath(server.user.ID, "reset-password"), adminToken, false, ResetPasswordRequest{Password: "chosen-password"})
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response ResetPasswordResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Empty(t, response.Password, "a chosen password is not echoed")
+		assert.NotEmpty(t, server.logIn("user@example.com", "chosen-password"))
+	})
+
+	t.Run("rejects bad input", func(t *testing.T) {
+		rec := server.request(http.MethodPost, server.userPath(server.user.ID, "reset-password"), adminToken, false, ResetPasswordRequest{Password: "short"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = server.request(http.MethodPost, server.userPath(999, "reset-password"), adminToken, false, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestSetUserRoleHandler(t *testing.T) {
+	server := setupAdminTestServer(t)
+	adminToken := server.logIn("admin@example.com", "admin-password")
+	path := server.userPath(server.user.ID, "role")
+
+	rec := server.request(http.MethodPut, path, adminToken, false, SetRoleRequest{Role: models.RoleAdmin})
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	var user models.User
+	require.NoError(t, server.db.DB().First(&user, server.user.ID).Error)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+
+	// The new admin needs a token issued after the change
+	userToken := server.logIn("user@example.com", "user-password")
+	assert.Equal(t, http.StatusOK, server.request(http.MethodGet, "/api/v1/admin/users", userToken, false, nil).Code)
+
+	rec = server.request(http.MethodPut, path, adminToken, false, SetRoleRequest{Role: models.RoleUser})
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, http.StatusForbidden, server.request(http.MethodGet, "/api/v1/admin/users", userToken, false, nil).Code)
+
+	assert.Equal(t, http.StatusBadRequest, server.request(http.MethodPut, path, adminToken, false, SetRoleRequest{Role: "owner"}).Code)
+	assert.Equal(t, http.StatusBadRequest, server.request(http.MethodPut, path, adminToken, false, map[string]string{}).Code)
+	assert.Equal(t, http.StatusBadRequest, server.request(http.MethodPut, server.userPath(server.admin.ID, "role"), adminToken, false, SetRoleRequest{Role: models.RoleUser}).Code,
+		"admins can't demote themselves")
+	assert.Equal(t, http.StatusNotFound, server.request(http.MethodPut, server.userPath(999, "role"), adminToken, false, SetRoleRequest{Role: models.RoleAdmin}).Code)
+}
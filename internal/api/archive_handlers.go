@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+type ImportMemoriesRequest struct {
+	Archive          services.MemoryArchive `json:"archive"`
+	AllowCrossRegion bool                   `json:"allow_cross_region,omitempty"`
+}
+
+// exportMemoriesHandler godoc
+// @Summary Export memories
+// @Description Download memories as a portable archive. With include_embeddings=true each memory carries its
+// @Description embedding (base64 float32 array plus model name) so a server using the same model can skip re-embedding on import.
+// @Description With format=jsonl the archive is streamed as JSON lines: a header line, then one memory per line, read from
+// @Description the database in batches so large exports are not held in memory.
+// @Description With keep_encrypted=true encrypted memories are exported as their encrypted payload, which only a server
+// @Description sharing this server's master key can import.
+// @Description The search filters query, category, type, tags, tagMatch and metadataQuery export only the matching
+// @Description memories, as for a keyword search; without them every memory is exported.
+// @Description With anonymize=true emails, phone numbers, and the names and companies the memories introduce are replaced
+// @Description with stable pseudonyms ("Person 1", "Company 1", "user1@example.com"), the same value getting the same
+// @Description pseudonym throughout the export, so it can be shared for analysis or bug reports.
+// @Tags memories
+// @Accept json
+// @Produce json,application/x-ndjson
+// @Security ApiKeyAuth
+// @Param include_embeddings query bool false "Include embeddings in the archive (default: false)"
+// @Param keep_encrypted query bool false "Export encrypted memories without decrypting them (default: false)"
+// @Param format query string false "Archive format: json or jsonl (default: json)"
+// @Param query query string false "Only export memories whose content contains this text"
+// @Param category query string false "Filter by category (personal, project, business)"
+// @Param type query string false "Filter by type (fact, conversation, context, preference)"
+// @Param tags query string false "Comma-separated tags to filter by"
+// @Param tagMatch query string false "How tags are matched: any (default) or all"
+// @Param metadataQuery query string false "Metadata filter: a JSON object matched by containment, or a JSONPath expression"
+// @Param anonymize query string false "Pseudonymize personal information: true, or a comma-separated list of emails, phones, names, companies"
+// @Param anonymize_terms query string false "Comma-separated further terms to pseudonymize, such as project names"
+// @Success 200 {object} services.MemoryArchive
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/export [get]
+func (s *Server) exportMemoriesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", services.ArchiveFormatJSON))
+	if !services.IsValidArchiveFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or jsonl"})
+		return
+	}
+	category := c.Query("category")
+	if category != "" && !models.IsValidCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category. Must be one of: personal, project, business"})
+		return
+	}
+	memoryType := c.Query("type")
+	if memoryType != "" && !models.IsValidType(memoryType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Must be one of: fact, conversation, context, preference"})
+		return
+	}
+	anonymize, err := services.ParseAnonymizeOptions(c.Query("anonymize"), c.Query("anonymize_terms"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts := services.ExportOptions{
+		IncludeEmbeddings: c.Query("include_embeddings") == "true",
+		KeepEncrypted:     c.Query("keep_encrypted") == "true",
+		Filter: services.ExportFilter{
+			Query:         c.Query("query"),
+			Category:      category,
+			Type:          memoryType,
+			MetadataQuery: c.Query("metadataQuery"),
+			Tags:          parseTagsQuery(c.QueryArray("tags")),
+			TagMatch:      c.Query("tagMatch"),
+		},
+		Anonymize: anonymize,
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	var count int
+	if format == services.ArchiveFormatJSONL {
+		count, err = s.streamMemoriesJSONL(c, userMemoryService, opts)
+	} else {
+		var archive *services.MemoryArchive
+		if archive, err = userMemoryService.ExportMemoriesWithOptions(c.Request.Context(), opts); err == nil {
+			count = len(archive.Memories)
+			c.Header("Content-Disposition", `attachment; filename="remember-me-memories.json"`)
+			c.JSON(http.StatusOK, archive)
+		}
+	}
+	if err != nil {
+		if c.Writer.Written() {
+			// The response is under way; all that can be done is to cut it short
+			s.logger.Error().Err(err).Int("memory_count", count).Msg("Failed to stream memory archive")
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to export memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export memories"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"memory_count":       count,
+		"include_embeddings": opts.IncludeEmbeddings,
+		"keep_encrypted":     opts.KeepEncrypted,
+		"format":             format,
+		"filtered":           opts.Filter.IsSet(),
+		"anonymized":         opts.Anonymize != nil,
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityMemoriesExported, details, c.ClientIP(), c.GetHeader("User-Agent"))
+}
+
+// streamMemoriesJSONL writes the export as a JSONL archive one memory at a time.
+// Nothing is written until the first memory is ready or the export turns out to
+// be empty, so filter errors can still be reported with a status code.
+func (s *Server) streamMemoriesJSONL(c *gin.Context, memoryService *services.MemoryService, opts services.ExportOptions) (int, error) {
+	archive := memoryService.NewArchive()
+	archive.Anonymized = opts.Anonymize != nil
+	writer := services.NewArchiveJSONLWriter(c.Writer, archive)
+	start := func() {
+		c.Header("Content-Disposition", `attachment; filename="remember-me-memories.jsonl"`)
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+
+	count, err := memoryService.StreamMemories(c.Request.Context(), opts, func(memory *services.ArchivedMemory) error {
+		if !writer.Started() {
+			start()
+		}
+		return writer.Write(memory)
+	})
+	if err != nil {
+		return count, err
+	}
+	if !writer.Started() {
+		start()
+	}
+	return count, writer.Close()
+}
+
+// importMemoriesHandler godoc
+// @Summary Import memories
+// @Description Import a memory archive. Memories whose content already exists are skipped. Embeddings in the archive are
+// @Description reused when they come from the model this server uses; other memories are embedded in the background.
+// @Description Archives exported from a different residency region are refused unless allow_cross_region is set.
+// @Description A JSONL archive is accepted as the raw body with Content-Type application/x-ndjson or format=jsonl; the
+// @Description allow_cross_region flag is then given as a query parameter. Encrypted payloads are decrypted with this
+// @Description server's key and re-encrypted if encryption is enabled.
+// @Tags memories
+// @Accept json,application/x-ndjson
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ImportMemoriesRequest true "Archive to import"
+// @Param format query string false "Body format: json or jsonl (default: from Content-Type)"
+// @Param allow_cross_region query bool false "Allow a JSONL archive from another region (default: false)"
+// @Success 200 {object} services.ImportMemoriesResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} QuotaExceededResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/import [post]
+func (s *Server) importMemoriesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req ImportMemoriesRequest
+	if isJSONLImport(c) {
+		archive, err := services.ReadArchiveJSONL(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Archive = *archive
+		req.AllowCrossRegion = c.Query("allow_cross_region") == "true"
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.ImportMemories(c.Request.Context(), &req.Archive, req.AllowCrossRegion)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrCrossRegion) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to import memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import memories"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"version":             req.Archive.Version,
+		"region":              req.Archive.Region,
+		"created":             result.Created,
+		"skipped":             result.Skipped,
+		"embeddings_restored": result.EmbeddingsRestored,
+		"embeddings_queued":   result.EmbeddingsQueued,
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityMemoriesImported, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusOK, result)
+}
+
+// isJSONLImport reports whether an import request carries a JSONL archive
+func isJSONLImport(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return strings.EqualFold(format, services.ArchiveFormatJSONL)
+	}
+	switch c.ContentType() {
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
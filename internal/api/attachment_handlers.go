@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// attachmentFormMemory is the memory multipart attachment forms are parsed in
+// before spilling to disk
+const attachmentFormMemory = 1 << 20
+
+// addAttachmentHandler godoc
+// @Summary Attach a file to a memory
+// @Description Attach a small file or image to a memory, within the configured size (5MB by default) and per-memory
+// @Description limits. Send a multipart form with the file in the file field and optional JSON metadata in the metadata
+// @Description field, or the raw file as the body with its Content-Type (the filename then goes in the query string).
+// @Description The content type is detected when not given, and images are described with their width and height. The
+// @Description response includes the attachment's MCP resource URI.
+// @Tags memories
+// @Accept multipart/form-data
+// @Accept octet-stream
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Memory ID"
+// @Param file formData file false "File (multipart requests)"
+// @Param metadata formData string false "JSON object describing the file (multipart requests)"
+// @Param filename query string false "File name, for raw bodies"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/{id}/attachments [post]
+func (s *Server) addAttachmentHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	// Allow some room over the limit for the multipart framing and form fields
+	maxSize := s.config.Attachments.MaxSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+attachmentFormMemory)
+	req, err := readAttachment(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("attachment must be at most %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachment, err := s.workspaceMemoryService(c, user.ID).AddAttachment(c.Request.Context(), uint(id), *req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to add attachment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add attachment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// readAttachment reads a file sent as a multipart form or as the raw body
+func readAttachment(c *gin.Context) (*services.AttachmentRequest, error) {
+	req := &services.AttachmentRequest{}
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.Request.ParseMultipartForm(attachmentFormMemory); err != nil {
+			return nil, err
+		}
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("file is required")
+		}
+		defer file.Close()
+
+		if req.Data, err = io.ReadAll(file); err != nil {
+			return nil, err
+		}
+		req.Filename = header.Filename
+		req.ContentType = header.Header.Get("Content-Type")
+		if metadata := c.PostForm("metadata"); metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &req.Metadata); err != nil {
+				return nil, fmt.Errorf("metadata must be a JSON object")
+			}
+		}
+	} else {
+		var err error
+		if req.Data, err = io.ReadAll(c.Request.Body); err != nil {
+			return nil, err
+		}
+		req.ContentType = c.GetHeader("Content-Type")
+	}
+	if filename := c.Query("filename"); filename != "" {
+		req.Filename = filename
+	}
+	return req, nil
+}
+
+// listAttachmentsHandler godoc
+// @Summary List a memory's attachments
+// @Description List the files attached to a memory, such as images or the recording of a voice memo, without their
+// @Description contents. Each has the MCP resource URI it can be read as.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Memory ID"
+// @Success 200 {array} models.Attachment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/{id}/attachments [get]
+func (s *Server) listAttachmentsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	attachments, err := s.workspaceMemoryService(c, user.ID).ListAttachments(c.Request.Context(), uint(id))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to list attachments")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list attachments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// getAttachmentHandler godoc
+// @Summary Download an attachment
+// @Description Download a file attached to a memory with its original content type
+// @Tags memories
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments/{id} [get]
+func (s *Server) getAttachmentHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, err := s.workspaceMemoryService(c, user.ID).GetAttachment(c.Request.Context(), uint(id))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("attachment_id", uint(id)).Msg("Failed to get attachment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachment"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Filename))
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Data)
+}
+
+// deleteAttachmentHandler godoc
+// @Summary Delete an attachment
+// @Description Delete a file attached to a memory, leaving the memory in place
+// @Tags memories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments/{id} [delete]
+func (s *Server) deleteAttachmentHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	if err := s.workspaceMemoryService(c, user.ID).DeleteAttachment(c.Request.Context(), uint(id)); err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("attachment_id", uint(id)).Msg("Failed to delete attachment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Attachment deleted"})
+}
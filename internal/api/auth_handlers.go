@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
 )
 
 type RegisterRequest struct {
@@ -23,17 +25,44 @@ type LoginRequest struct {
 type LoginResponse struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
-	User      UserInfo  `json:"user"`
+	// RefreshToken gets a new token from /auth/refresh once this one expires. It
+	// can be used once; the response carries its replacement.
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             UserInfo  `json:"user"`
 }
 
+// RefreshRequest exchanges a refresh token for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest names the refresh token to revoke along with the access token
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+const (
+	// defaultAccessTokenTTL and defaultRefreshTokenTTL apply when jwt.access_ttl
+	// and jwt.refresh_ttl are unset
+	defaultAccessTokenTTL  = 24 * time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type UserInfo struct {
 	ID    uint   `json:"id"`
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
 }
 
 type CreateAPIKeyRequest struct {
 	Name      string     `json:"name" binding:"required" example:"Production API Key"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	// Type is standard (default) or backup, a read-only key limited to export and stats
+	Type string `json:"type,omitempty" enums:"standard,backup" example:"standard"`
+	// Workspace is the workspace the key's requests run in unless they send an
+	// X-Workspace header (default: the default workspace)
+	Workspace string `json:"workspace,omitempty" example:"work"`
 }
 
 type APIKeyResponse struct {
@@ -43,7 +72,9 @@ type APIKeyResponse struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 	IsActive    bool       `json:"is_active"`
+	Type        string     `json:"type"`
 	Permissions []string   `json:"permissions"`
+	WorkspaceID uint       `json:"workspace_id"`
 }
 
 // registerHandler godoc
@@ -100,19 +131,15 @@ func (s *Server) loginHandler(c *gin.Context) {
 
 	user, err := s.authService.AuthenticateUser(req.Email, req.Password)
 	if err != nil {
+		if errors.Is(err, ErrUserDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate JWT token
-	expiresAt := time.Now().Add(24 * time.Hour)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"exp":     expiresAt.Unix(),
-	})
-
-	tokenString, err := token.SignedString([]byte(s.config.JWT.Secret))
+	response, err := s.issueTokens(user)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Failed to generate JWT token")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
@@ -125,16 +152,170 @@ func (s *Server) loginHandler(c *gin.Context) {
 	}
 	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityLogin, details, c.ClientIP(), c.GetHeader("User-Agent"))
 
+	c.JSON(http.StatusOK, response)
+}
+
+// refreshHandler godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. The refresh token used is
+// @Description revoked; presenting it again revokes every refresh token of the user.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (s *Server) refreshHandler(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, refreshToken, stored, err := s.authService.RotateRefreshToken(req.RefreshToken, s.refreshTokenTTL())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			s.logger.Error().Err(err).Msg("Failed to refresh token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	token, expiresAt, err := s.signAccessToken(user)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to generate JWT token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
 	c.JSON(http.StatusOK, LoginResponse{
-		Token:     tokenString,
-		ExpiresAt: expiresAt,
-		User: UserInfo{
-			ID:    user.ID,
-			Email: user.Email,
-		},
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+		User:             UserInfo{ID: user.ID, Email: user.Email, Role: user.Role},
 	})
 }
 
+// logoutHandler godoc
+// @Summary Logout
+// @Description Revoke the bearer token the request is made with and, when given, a refresh token. Either stops
+// @Description working immediately rather than when it expires.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body LogoutRequest false "Refresh token to revoke"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (s *Server) logoutHandler(c *gin.Context) {
+	user, ok := getUserFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if err := s.authService.RevokeRefreshToken(user.ID, req.RefreshToken); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to revoke refresh token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
+	// Tokens issued before revocation was supported carry no ID and simply expire
+	if tokenID := c.GetString(tokenIDKey); tokenID != "" {
+		expiresAt, _ := c.Get(tokenExpiryKey)
+		exp, _ := expiresAt.(time.Time)
+		if err := s.authService.RevokeAccessToken(user.ID, tokenID, exp); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to revoke access token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
+	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityLogout, nil, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.Status(http.StatusNoContent)
+}
+
+// issueTokens creates an access token and a refresh token for the user
+func (s *Server) issueTokens(user *models.User) (*LoginResponse, error) {
+	token, expiresAt, err := s.signAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, stored, err := s.authService.CreateRefreshToken(user.ID, s.refreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+		User:             UserInfo{ID: user.ID, Email: user.Email, Role: user.Role},
+	}, nil
+}
+
+// signAccessToken signs a JWT for the user. Its jti claim lets it be revoked, and
+// its ver claim, the user's token version, lets every token issued before the
+// user's password changed be rejected.
+func (s *Server) signAccessToken(user *models.User) (string, time.Time, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.accessTokenTTL())
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"jti":     tokenID,
+		"ver":     user.TokenVersion,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+	})
+	tokenString, err := token.SignedString([]byte(s.config.JWT.Secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+func (s *Server) accessTokenTTL() time.Duration {
+	if s.config.JWT.AccessTTL > 0 {
+		return s.config.JWT.AccessTTL
+	}
+	return defaultAccessTokenTTL
+}
+
+func (s *Server) refreshTokenTTL() time.Duration {
+	if s.config.JWT.RefreshTTL > 0 {
+		return s.config.JWT.RefreshTTL
+	}
+	return defaultRefreshTokenTTL
+}
+
 // listAPIKeysHandler godoc
 // @Summary List API keys
 // @Description Get all API keys for the authenticated user
@@ -167,7 +348,9 @@ func (s *Server) listAPIKeysHandler(c *gin.Context) {
 			CreatedAt:   key.CreatedAt,
 			ExpiresAt:   key.ExpiresAt,
 			IsActive:    key.IsActive,
+			Type:        key.KeyType,
 			Permissions: key.GetPermissions(),
+			WorkspaceID: key.WorkspaceID,
 		}
 	}
 
@@ -176,7 +359,8 @@ func (s *Server) listAPIKeysHandler(c *gin.Context) {
 
 // createAPIKeyHandler godoc
 // @Summary Create API key
-// @Description Create a new API key for authentication
+// @Description Create a new API key for authentication. Backup keys (type backup) can only export memories and
+// @Description read stats, so automated backup scripts never hold a key that can modify or delete memories.
 // @Tags keys
 // @Accept json
 // @Produce json
@@ -199,7 +383,27 @@ func (s *Server) createAPIKeyHandler(c *gin.Context) {
 		return
 	}
 
-	apiKey, err := s.authService.GenerateAPIKey(user.ID, req.Name, req.ExpiresAt)
+	keyType := req.Type
+	if keyType == "" {
+		keyType = models.APIKeyTypeStandard
+	}
+	if _, ok := models.APIKeyTypePermissions[keyType]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be standard or backup"})
+		return
+	}
+
+	workspaceID, err := s.createScopedMemoryService(user.ID).ResolveWorkspace(c.Request.Context(), req.Workspace)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to resolve API key workspace")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	apiKey, err := s.authService.GenerateTypedAPIKey(user.ID, req.Name, keyType, req.ExpiresAt, workspaceID)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Failed to create API key")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
@@ -208,8 +412,10 @@ func (s *Server) createAPIKeyHandler(c *gin.Context) {
 
 	// Log the API key creation activity
 	details := map[string]interface{}{
-		"api_key_id": apiKey.ID,
-		"name":       apiKey.Name,
+		"api_key_id":   apiKey.ID,
+		"name":         apiKey.Name,
+		"type":         keyType,
+		"workspace_id": workspaceID,
 	}
 	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityAPIKeyCreated, details, c.ClientIP(), c.GetHeader("User-Agent"))
 
@@ -220,7 +426,9 @@ func (s *Server) createAPIKeyHandler(c *gin.Context) {
 		CreatedAt:   apiKey.CreatedAt,
 		ExpiresAt:   apiKey.ExpiresAt,
 		IsActive:    apiKey.IsActive,
+		Type:        apiKey.KeyType,
 		Permissions: apiKey.GetPermissions(),
+		WorkspaceID: apiKey.WorkspaceID,
 	})
 }
 
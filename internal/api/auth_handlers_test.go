@@ -7,29 +7,36 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ksred/remember-me-mcp/internal/config"
 	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/models"
 	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 func setupTestServer(t *testing.T) (*Server, func()) {
 	gin.SetMode(gin.TestMode)
 
-	// Create in-memory SQLite database
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	require.NoError(t, err)
-
-	// Run migrations
-	err = db.AutoMigrate(&models.Memory{}, &models.User{}, &models.APIKey{})
+	// Create in-memory SQLite database with the tables the handlers touch
+	db := testutil.SQLiteDB(t,
+		&models.User{}, &models.APIKey{}, &models.RefreshToken{}, &models.RevokedToken{},
+		&models.Workspace{}, &models.ActivityLog{}, &models.MemoryRevision{}, &models.MemoryAccessLog{},
+		&models.MemorySession{}, &models.MemoryFeedback{}, &models.MemoryLink{}, &models.CategorizationRule{},
+		&models.Attachment{}, &models.SavedSearch{}, &models.SupportAccessGrant{}, &models.PerformanceMetric{},
+	)
+	// Every connection to :memory: is a separate, empty database, and handlers log
+	// activity in the background, so keep the one connection that has the tables
+	sqlDB, err := db.DB()
 	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	// Migrations reserve the first ID for the system user
+	testutil.NewUser().ID(database.SystemUserID).Email("system@remember-me.local").Create(t, db)
 
 	// Create test config
 	cfg := &config.Config{
@@ -58,8 +65,10 @@ func setupTestServer(t *testing.T) (*Server, func()) {
 		"memory_limit": cfg.Memory.MaxMemories,
 	})
 
+	activityService := services.NewActivityService(db, logger)
+
 	// Create server
-	server, err := NewServer(cfg, testDB, memoryService, logger)
+	server, err := NewServer(cfg, testDB, memoryService, activityService, logger)
 	require.NoError(t, err)
 
 	cleanup := func() {
@@ -349,4 +358,111 @@ func TestAuthMiddleware(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, rec.Code)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	user, err := server.authService.RegisterUser("test@example.com", "password123")
+	require.NoError(t, err)
+
+	post := func(path, bearer string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+	getKeys := func(bearer string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/keys", nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	rec := post("/api/v1/auth/login", "", LoginRequest{Email: "test@example.com", Password: "password123"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var login LoginResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &login))
+	require.NotEmpty(t, login.RefreshToken)
+	assert.True(t, login.RefreshExpiresAt.After(login.ExpiresAt))
+
+	t.Run("refresh rotates the refresh token", func(t *testing.T) {
+		rec := post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: login.RefreshToken})
+		require.Equal(t, http.StatusOK, rec.Code)
+		var refreshed LoginResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &refreshed))
+		assert.NotEqual(t, login.Token, refreshed.Token)
+		assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+		assert.Equal(t, user.ID, refreshed.User.ID)
+		assert.Equal(t, http.StatusOK, getKeys(refreshed.Token))
+
+		// Reusing a rotated token revokes the replacement too
+		rec = post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: login.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		rec = post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: refreshed.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("unknown refresh token", func(t *testing.T) {
+		rec := post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: "not-a-token"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("logout revokes both tokens", func(t *testing.T) {
+		rec := post("/api/v1/auth/login", "", LoginRequest{Email: "test@example.com", Password: "password123"})
+		require.Equal(t, http.StatusOK, rec.Code)
+		var session LoginResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+		require.Equal(t, http.StatusOK, getKeys(session.Token))
+
+		rec = post("/api/v1/auth/logout", session.Token, LogoutRequest{RefreshToken: session.RefreshToken})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, getKeys(session.Token))
+		rec = post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: session.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	logIn := func(password string) LoginResponse {
+		rec := post("/api/v1/auth/login", "", LoginRequest{Email: "test@example.com", Password: password})
+		require.Equal(t, http.StatusOK, rec.Code)
+		var session LoginResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+		return session
+	}
+
+	t.Run("password changes reject earlier access tokens", func(t *testing.T) {
+		session := logIn("password123")
+		require.Equal(t, http.StatusOK, getKeys(session.Token))
+
+		require.NoError(t, server.authService.SetPassword(user.ID, "new-password"))
+		assert.Equal(t, http.StatusUnauthorized, getKeys(session.Token))
+		assert.Equal(t, http.StatusOK, getKeys(logIn("new-password").Token))
+	})
+
+	t.Run("re-enabling an account does not revive its tokens", func(t *testing.T) {
+		session := logIn("new-password")
+		require.NoError(t, server.authService.SetDisabled(user.ID, true))
+		assert.Equal(t, http.StatusForbidden, getKeys(session.Token))
+
+		require.NoError(t, server.authService.SetDisabled(user.ID, false))
+		assert.Equal(t, http.StatusUnauthorized, getKeys(session.Token))
+		assert.Equal(t, http.StatusOK, getKeys(logIn("new-password").Token))
+	})
+
+	t.Run("disabled users cannot refresh", func(t *testing.T) {
+		refreshToken, _, err := server.authService.CreateRefreshToken(user.ID, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, server.authService.SetDisabled(user.ID, true))
+
+		rec := post("/api/v1/auth/refresh", "", RefreshRequest{RefreshToken: refreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "disabling revokes refresh tokens")
+	})
+}
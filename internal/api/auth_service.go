@@ -2,17 +2,28 @@ package api
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrUserDisabled is returned when a disabled account logs in or uses an API key
+var ErrUserDisabled = errors.New("account disabled")
+
+// ErrInvalidRefreshToken is returned for refresh tokens that are unknown, expired
+// or revoked
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
 type AuthService struct {
 	db     *database.Database
 	logger zerolog.Logger
@@ -74,10 +85,25 @@ func (s *AuthService) AuthenticateUser(email, password string) (*models.User, er
 		return nil, errors.New("invalid credentials")
 	}
 
+	if user.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
 	return &user, nil
 }
 
 func (s *AuthService) GenerateAPIKey(userID uint, name string, expiresAt *time.Time) (*models.APIKey, error) {
+	return s.GenerateTypedAPIKey(userID, name, models.APIKeyTypeStandard, expiresAt, 0)
+}
+
+// GenerateTypedAPIKey creates an API key of the given type (standard or backup)
+// whose requests default to the given workspace
+func (s *AuthService) GenerateTypedAPIKey(userID uint, name, keyType string, expiresAt *time.Time, workspaceID uint) (*models.APIKey, error) {
+	permissions, ok := models.APIKeyTypePermissions[keyType]
+	if !ok {
+		return nil, fmt.Errorf("invalid API key type: %s", keyType)
+	}
+
 	// Generate random API key
 	keyBytes := make([]byte, 32)
 	if _, err := rand.Read(keyBytes); err != nil {
@@ -87,13 +113,15 @@ func (s *AuthService) GenerateAPIKey(userID uint, name string, expiresAt *time.T
 	keyString := hex.EncodeToString(keyBytes)
 
 	apiKey := &models.APIKey{
-		UserID:    userID,
-		Key:       keyString,
-		Name:      name,
-		ExpiresAt: expiresAt,
-		IsActive:  true,
+		UserID:      userID,
+		Key:         keyString,
+		Name:        name,
+		ExpiresAt:   expiresAt,
+		IsActive:    true,
+		KeyType:     keyType,
+		WorkspaceID: workspaceID,
 	}
-	apiKey.SetPermissions([]string{"memory:read", "memory:write", "memory:delete"})
+	apiKey.SetPermissions(permissions)
 
 	if err := s.db.DB().Create(apiKey).Error; err != nil {
 		return nil, err
@@ -126,6 +154,9 @@ func (s *AuthService) ValidateAPIKey(key string) (*models.APIKey, error) {
 	if err := s.db.DB().First(&apiKey.User, apiKey.UserID).Error; err != nil {
 		return nil, err
 	}
+	if apiKey.User.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
 
 	// Update last used timestamp
 	now := time.Now()
@@ -160,4 +191,215 @@ func (s *AuthService) DeleteAPIKey(userID uint, keyID uint) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// SetPassword replaces a user's password
+func (s *AuthService) SetPassword(userID uint, password string) error {
+	if len(password) < 8 {
+		return utils.InvalidFieldError("password", "must be at least 8 characters long")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	// Sessions started with the old password have to log in again
+	if err := s.updateUser(userID, map[string]interface{}{
+		"password":      string(hashedPassword),
+		"token_version": gorm.Expr("token_version + 1"),
+	}); err != nil {
+		return err
+	}
+	return s.RevokeRefreshTokens(userID)
+}
+
+// SetDisabled disables or re-enables a user's account. A disabled user can't log
+// in or use their API keys, and tokens already issued stop working.
+func (s *AuthService) SetDisabled(userID uint, disabled bool) error {
+	if !disabled {
+		return s.updateUser(userID, map[string]interface{}{"disabled_at": nil})
+	}
+	// Tokens issued before the account was disabled stay invalid if it is
+	// re-enabled
+	if err := s.updateUser(userID, map[string]interface{}{
+		"disabled_at":   time.Now(),
+		"token_version": gorm.Expr("token_version + 1"),
+	}); err != nil {
+		return err
+	}
+	return s.RevokeRefreshTokens(userID)
+}
+
+// SetRole changes a user's role
+func (s *AuthService) SetRole(userID uint, role string) error {
+	if !models.IsValidRole(role) {
+		return utils.InvalidFieldError("role", fmt.Sprintf("must be %s or %s", models.RoleUser, models.RoleAdmin))
+	}
+	return s.updateUser(userID, map[string]interface{}{"role": role})
+}
+
+// GeneratePassword returns a random temporary password
+func GeneratePassword() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func (s *AuthService) updateUser(userID uint, updates map[string]interface{}) error {
+	result := s.db.DB().Model(&models.User{}).Where("id = ?", userID).Updates(updates)
+	if result.Error != nil {
+		return utils.WrapDatabaseError("update user", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("user", fmt.Sprintf("%d", userID))
+	}
+	return nil
+}
+
+// CreateRefreshToken issues a refresh token for the user, returning the plaintext
+// token, which is not stored
+func (s *AuthService) CreateRefreshToken(userID uint, ttl time.Duration) (string, *models.RefreshToken, error) {
+	return s.createRefreshToken(s.db.DB(), userID, ttl)
+}
+
+func (s *AuthService) createRefreshToken(db *gorm.DB, userID uint, ttl time.Duration) (string, *models.RefreshToken, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	refreshToken := &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(refreshToken).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, refreshToken, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one, revoking the one
+// used, and returns the user it belongs to. A token that was already revoked is
+// taken as stolen: every refresh token of its user is revoked, so whoever holds
+// the newer one has to log in again too.
+func (s *AuthService) RotateRefreshToken(token string, ttl time.Duration) (*models.User, string, *models.RefreshToken, error) {
+	var (
+		user         models.User
+		newToken     string
+		refreshToken *models.RefreshToken
+		reused       bool
+	)
+	err := s.db.DB().Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", hashRefreshToken(token)).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		now := time.Now()
+		if current.RevokedAt != nil {
+			reused = true
+			user.ID = current.UserID
+			return ErrInvalidRefreshToken
+		}
+		if !current.IsActive(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if user.IsDisabled() {
+			return ErrUserDisabled
+		}
+
+		// Only one of two concurrent refreshes with the same token wins
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidRefreshToken
+		}
+
+		var err error
+		newToken, refreshToken, err = s.createRefreshToken(tx, user.ID, ttl)
+		return err
+	})
+	if reused {
+		s.logger.Warn().Uint("user_id", user.ID).Msg("Revoked refresh token reused; revoking all of the user's refresh tokens")
+		if revokeErr := s.RevokeRefreshTokens(user.ID); revokeErr != nil {
+			s.logger.Error().Err(revokeErr).Uint("user_id", user.ID).Msg("Failed to revoke refresh tokens")
+		}
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return &user, newToken, refreshToken, nil
+}
+
+// RevokeRefreshToken revokes one of the user's refresh tokens. Revoking a token
+// that is unknown or already revoked is not an error.
+func (s *AuthService) RevokeRefreshToken(userID uint, token string) error {
+	return s.db.DB().Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", hashRefreshToken(token), userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeRefreshTokens revokes every refresh token of the user
+func (s *AuthService) RevokeRefreshTokens(userID uint) error {
+	if err := s.db.DB().Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return utils.WrapDatabaseError("revoke refresh tokens", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken blacklists an access token by its ID until it expires.
+// Blacklisted tokens that have since expired are dropped, since they no longer
+// validate anyway.
+func (s *AuthService) RevokeAccessToken(userID uint, tokenID string, expiresAt time.Time) error {
+	db := s.db.DB()
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		TokenID:   tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to prune expired revoked tokens")
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token has been blacklisted
+func (s *AuthService) IsAccessTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	if err := s.db.DB().Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// randomToken returns n random bytes, hex encoded
+func randomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// CaptureRequest is text captured by an editor or IDE plugin
+type CaptureRequest struct {
+	Text     string                  `json:"text" binding:"required"`
+	Context  services.CaptureContext `json:"context"`
+	Type     string                  `json:"type,omitempty"`
+	Category string                  `json:"category,omitempty"`
+	Tags     []string                `json:"tags,omitempty"`
+}
+
+// captureHandler godoc
+// @Summary Quick-capture text from an editor
+// @Description Store text captured by an editor or IDE plugin. Memory detection picks the type, category and update key
+// @Description unless type or category are given; the editor context is kept in the memory's metadata. The response says
+// @Description whether the text was created, updated (an existing memory with the same detected update key) or skipped
+// @Description (already remembered, exactly or as a near-identical memory), with the matched memory in existing.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CaptureRequest true "Captured text and editor context"
+// @Success 200 {object} services.CaptureResult "Updated or skipped"
+// @Success 201 {object} services.CaptureResult "Created"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} QuotaExceededResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /capture [post]
+func (s *Server) captureHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req CaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.Capture(c.Request.Context(), services.CaptureRequest{
+		Text:     req.Text,
+		Context:  req.Context,
+		Type:     req.Type,
+		Category: req.Category,
+		Tags:     req.Tags,
+	})
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrIncognito) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to capture memory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture memory"})
+		return
+	}
+
+	if result.Action == services.CaptureSkipped {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	details := map[string]interface{}{
+		"memory_id": result.Memory.ID,
+		"category":  result.Memory.Category,
+		"type":      result.Memory.Type,
+		"action":    result.Action,
+		"source":    "capture",
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityMemoryStored, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	status := http.StatusOK
+	if result.Action == services.CaptureCreated {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
+
+// ProcessContentRequest is a conversation transcript to scan for memories
+type ProcessContentRequest struct {
+	Content       string  `json:"content" binding:"required"`
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	DryRun        bool    `json:"dry_run,omitempty"`
+}
+
+// processContentHandler godoc
+// @Summary Capture memories from a conversation transcript
+// @Description Scan a transcript sentence by sentence with memory detection and store each sentence detected with at
+// @Description least min_confidence (default 0.5). Lines labelled Assistant:, AI:, Bot: or System: and the lines after
+// @Description them are not scanned until the next User: or Human: label. Sentences already remembered are skipped and
+// @Description ones sharing a detected update key update that memory. With dry_run nothing is stored.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ProcessContentRequest true "Transcript to scan"
+// @Success 200 {object} services.ProcessContentResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} QuotaExceededResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/process [post]
+func (s *Server) processContentHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req ProcessContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.ProcessContent(c.Request.Context(), services.ProcessContentRequest{
+		Content:       req.Content,
+		MinConfidence: req.MinConfidence,
+		DryRun:        req.DryRun,
+	})
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrIncognito) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to process content")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process content"})
+		return
+	}
+
+	for _, captured := range result.Captured {
+		if captured.Memory == nil || captured.Action == services.CaptureSkipped {
+			continue
+		}
+		details := map[string]interface{}{
+			"memory_id": captured.Memory.ID,
+			"category":  captured.Memory.Category,
+			"type":      captured.Memory.Type,
+			"action":    captured.Action,
+			"source":    "transcript",
+		}
+		go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityMemoryStored, details, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
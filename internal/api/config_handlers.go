@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/mcp"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+type ImportConfigRequest struct {
+	Bundle           services.ConfigBundle `json:"bundle"`
+	Overwrite        bool                  `json:"overwrite,omitempty"`
+	AllowCrossRegion bool                  `json:"allow_cross_region,omitempty"`
+}
+
+// listSavedSearchesHandler godoc
+// @Summary List saved searches
+// @Description Get all saved searches for the authenticated user
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.SavedSearch
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /searches [get]
+func (s *Server) listSavedSearchesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	searches, err := userMemoryService.ListSavedSearches(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list saved searches")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list saved searches"})
+		return
+	}
+
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// saveSearchHandler godoc
+// @Summary Save a search
+// @Description Create a saved search, or replace an existing one with the same name
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body services.SavedSearchSpec true "Saved search"
+// @Success 200 {object} models.SavedSearch
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /searches [post]
+func (s *Server) saveSearchHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req services.SavedSearchSpec
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	search, err := userMemoryService.SaveSearch(c.Request.Context(), req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to save search")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+// runSavedSearchHandler godoc
+// @Summary Run a saved search
+// @Description Execute a saved search and return matching memories
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Saved search ID"
+// @Success 200 {object} mcp.SearchMemoriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /searches/{id}/run [get]
+func (s *Server) runSavedSearchHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	search, err := userMemoryService.GetSavedSearch(c.Request.Context(), uint(id))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saved search not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to load saved search")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load saved search"})
+		return
+	}
+
+	spec := services.NewSavedSearchSpec(search)
+	memories, err := userMemoryService.SearchMemories(c.Request.Context(), spec.SearchRequest())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to run saved search")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mcp.SearchMemoriesResponse{
+		Memories: memories,
+		Count:    len(memories),
+	})
+}
+
+// deleteSavedSearchHandler godoc
+// @Summary Delete a saved search
+// @Description Delete a saved search by ID
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Saved search ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /searches/{id} [delete]
+func (s *Server) deleteSavedSearchHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	if err := userMemoryService.DeleteSavedSearch(c.Request.Context(), uint(id)); err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "saved search not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to delete saved search")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// exportConfigHandler godoc
+// @Summary Export configuration bundle
+// @Description Export the user's configuration artifacts (saved searches) as a portable JSON bundle
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.ConfigBundle
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /config/export [get]
+func (s *Server) exportConfigHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	bundle, err := userMemoryService.ExportConfigBundle(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to export config bundle")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export configuration"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="remember-me-config.json"`)
+	c.JSON(http.StatusOK, bundle)
+}
+
+// importConfigHandler godoc
+// @Summary Import configuration bundle
+// @Description Import a configuration bundle. Existing artifacts with the same name are skipped unless overwrite is set.
+// @Description Bundles exported from a different residency region are refused unless allow_cross_region is set.
+// @Tags config
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ImportConfigRequest true "Bundle to import"
+// @Success 200 {object} services.ImportConfigResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /config/import [post]
+func (s *Server) importConfigHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req ImportConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.ImportConfigBundle(c.Request.Context(), &req.Bundle, req.Overwrite, req.AllowCrossRegion)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrCrossRegion) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to import config bundle")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import configuration"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"version": req.Bundle.Version,
+		"region":  req.Bundle.Region,
+		"created": result.Created,
+		"updated": result.Updated,
+		"skipped": result.Skipped,
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityConfigImported, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// ConsolidateRequest selects the memories to merge into summaries
+type ConsolidateRequest struct {
+	// MemoryIDs merges exactly these memories; when empty, clusters of similar
+	// memories are found
+	MemoryIDs   []uint `json:"memory_ids,omitempty"`
+	MaxClusters int    `json:"max_clusters,omitempty" binding:"omitempty,min=1"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+}
+
+// consolidateMemoriesHandler godoc
+// @Summary Consolidate related memories
+// @Description Merge clusters of similar memories, or the memories given by ID, into summary memories written by a
+// @Description language model. Each summary supersedes its originals, which are kept, and records them as its
+// @Description provenance. With dry_run the clusters are listed with their memories and nothing is written.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ConsolidateRequest false "Memories to consolidate"
+// @Success 200 {object} services.ConsolidateResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /memories/consolidate [post]
+func (s *Server) consolidateMemoriesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req ConsolidateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.Consolidate(c.Request.Context(), services.ConsolidateRequest{
+		MemoryIDs:   req.MemoryIDs,
+		MaxClusters: req.MaxClusters,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		switch {
+		case utils.IsValidationError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case utils.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrConsolidationDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLLMBudgetExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			s.logger.Error().Err(err).Msg("Failed to consolidate memories")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to consolidate memories"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// AppendContextRequest is a conversation turn for a session's short-term buffer
+type AppendContextRequest struct {
+	Content   string `json:"content" binding:"required"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// appendContextHandler godoc
+// @Summary Add a turn to the short-term context buffer
+// @Description Buffer a recent conversation turn for the session (default: default). Buffered turns expire after
+// @Description memory.context_buffer_ttl and are never stored as memories; search with include_context=true to find them.
+// @Tags context
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body AppendContextRequest true "Conversation turn"
+// @Success 201 {object} models.ContextTurn
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /context [post]
+func (s *Server) appendContextHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req AppendContextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	turn, err := userMemoryService.AppendContext(c.Request.Context(), services.AppendContextRequest{
+		SessionID: req.SessionID,
+		Role:      req.Role,
+		Content:   req.Content,
+	})
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrIncognito) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to append context")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to append context"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, turn)
+}
+
+// getContextHandler godoc
+// @Summary Get the short-term context buffer
+// @Description Get a session's unexpired conversation turns, oldest first
+// @Tags context
+// @Produce json
+// @Security ApiKeyAuth
+// @Param session_id query string false "Session ID (default: default)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /context [get]
+func (s *Server) getContextHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	turns, err := userMemoryService.ContextTurns(c.Request.Context(), c.Query("session_id"))
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to get context")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get context"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"turns": turns,
+		"count": len(turns),
+	})
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// MaintenanceSettingsRequest opts in to or out of scheduled maintenance reviews
+type MaintenanceSettingsRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// getMaintenanceSettingsHandler godoc
+// @Summary Get maintenance agent settings
+// @Description Report whether the maintenance agent reviews the user's memories on a schedule and when it last did
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.MaintenanceSettings
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/maintenance [get]
+func (s *Server) getMaintenanceSettingsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	settings, err := userMemoryService.MaintenanceSettings(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get maintenance settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get maintenance settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// setMaintenanceSettingsHandler godoc
+// @Summary Opt in to or out of the maintenance agent
+// @Description Turn scheduled reviews of the user's memories on or off. Reviews propose actions and change nothing until approved.
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MaintenanceSettingsRequest true "Maintenance settings"
+// @Success 200 {object} services.MaintenanceSettings
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/maintenance [put]
+func (s *Server) setMaintenanceSettingsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req MaintenanceSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	settings, err := userMemoryService.SetMaintenanceEnabled(c.Request.Context(), *req.Enabled)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to update maintenance settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update maintenance settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// runMaintenanceHandler godoc
+// @Summary Review memories now
+// @Description Run the maintenance agent over the user's memories and return its report of proposed actions: duplicates to merge, memories a newer one contradicts, memories gone unused and memories categorization rules would refile. Nothing changes until actions are approved; actions still proposed by earlier reports expire.
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 201 {object} models.MaintenanceReport
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /maintenance/reports [post]
+func (s *Server) runMaintenanceHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	report, err := userMemoryService.RunMaintenance(c.Request.Context(), services.MaintenanceTriggerManual)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to review memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review memories"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// listMaintenanceReportsHandler godoc
+// @Summary List maintenance reports
+// @Description Get the user's latest maintenance reports, newest first, without their actions
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.MaintenanceReport
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /maintenance/reports [get]
+func (s *Server) listMaintenanceReportsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	reports, err := userMemoryService.ListMaintenanceReports(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list maintenance reports")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list maintenance reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// getMaintenanceReportHandler godoc
+// @Summary Get a maintenance report
+// @Description Get a maintenance report with its actions and the status of each
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Report ID"
+// @Success 200 {object} models.MaintenanceReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /maintenance/reports/{id} [get]
+func (s *Server) getMaintenanceReportHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	report, err := userMemoryService.GetMaintenanceReport(c.Request.Context(), uint(id))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance report not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to get maintenance report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get maintenance report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// reviewMaintenanceReportHandler godoc
+// @Summary Approve or reject proposed maintenance actions
+// @Description Apply the approved actions of a report and reject the others listed. Actions in neither list stay proposed. An approved action that can no longer be applied is marked failed with the reason; the rest still apply.
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Report ID"
+// @Param request body services.MaintenanceReview true "Actions to approve and reject"
+// @Success 200 {object} models.MaintenanceReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /maintenance/reports/{id}/review [post]
+func (s *Server) reviewMaintenanceReportHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var review services.MaintenanceReview
+	if err := c.ShouldBindJSON(&review); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	report, err := userMemoryService.ReviewMaintenanceReport(c.Request.Context(), uint(id), review)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance report not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to review maintenance report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review maintenance report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
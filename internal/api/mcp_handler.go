@@ -12,6 +12,8 @@ import (
 	"github.com/ksred/remember-me-mcp/internal/mcp"
 	"github.com/ksred/remember-me-mcp/internal/models"
 	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
+	"github.com/ksred/remember-me-mcp/internal/utils"
 	mcpTypes "github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -38,13 +40,14 @@ type MCPError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// Standard JSON-RPC 2.0 error codes
+// Standard JSON-RPC 2.0 error codes. Tool failures use the codes in utils, see
+// mcp.ToRPCError.
 const (
 	ParseError     = -32700
 	InvalidRequest = -32600
 	MethodNotFound = -32601
-	InvalidParams  = -32602
-	InternalError  = -32603
+	InvalidParams  = utils.MCPCodeInvalidParams
+	InternalError  = utils.MCPCodeInternalError
 )
 
 // HandleMCP processes MCP protocol requests over HTTP
@@ -106,7 +109,9 @@ func (s *Server) HandleMCP(c *gin.Context) {
 	}
 
 	// Create a scoped memory service for this user
-	scopedMemoryService := s.createScopedMemoryService(user.ID)
+	scopedMemoryService := s.mcpMemoryService(c, user.ID)
+	// Memory access through MCP is audited as such, not as the HTTP API's
+	c.Request = c.Request.WithContext(services.WithAccessSource(c.Request.Context(), services.AccessSourceMCP))
 
 	// Route the request based on method
 	var result interface{}
@@ -114,15 +119,22 @@ func (s *Server) HandleMCP(c *gin.Context) {
 
 	switch req.Method {
 	case "initialize":
-		result, err = s.handleMCPInitialize(req.Params)
+		result, err = s.handleMCPInitialize(c.Request.Context(), req.Params, user.ID)
 	case "tools/list":
-		result, err = s.handleMCPListTools()
+		result, err = s.handleMCPListTools(s.mcpClientProfile(user.ID))
 	case "tools/call":
-		result, err = s.handleMCPCallTool(c.Request.Context(), req.Params, scopedMemoryService, user, c)
+		ctx := mcp.WithClientProfile(c.Request.Context(), s.mcpClientProfile(user.ID))
+		result, err = s.handleMCPCallTool(ctx, req.Params, scopedMemoryService, user, c)
 	case "resources/list":
 		result, err = s.handleMCPListResources()
+	case "resources/templates/list":
+		result, err = s.handleMCPListResourceTemplates()
 	case "resources/read":
 		result, err = s.handleMCPReadResource(c.Request.Context(), req.Params, scopedMemoryService)
+	case "prompts/list":
+		result, err = s.handleMCPListPrompts()
+	case "prompts/get":
+		result, err = s.handleMCPGetPrompt(c.Request.Context(), req.Params, scopedMemoryService)
 	default:
 		c.JSON(http.StatusOK, MCPResponse{
 			JSONRPC: "2.0",
@@ -137,13 +149,19 @@ func (s *Server) HandleMCP(c *gin.Context) {
 	}
 
 	if err != nil {
-		s.logger.Error().Err(err).Str("method", req.Method).Msg("MCP method error")
+		// Domain and validation failures get specific codes and structured data
+		rpcErr := mcp.ToRPCError(err)
+		if rpcErr.Code == InternalError {
+			s.logger.Error().Err(err).Str("method", req.Method).Msg("MCP method error")
+		} else {
+			s.logger.Warn().Err(err).Str("method", req.Method).Int("code", rpcErr.Code).Msg("MCP method failed")
+		}
 		c.JSON(http.StatusOK, MCPResponse{
 			JSONRPC: "2.0",
 			Error: &MCPError{
-				Code:    InternalError,
-				Message: "Internal error",
-				Data:    err.Error(),
+				Code:    rpcErr.Code,
+				Message: rpcErr.Message,
+				Data:    rpcErr.Data,
 			},
 			ID: req.ID,
 		})
@@ -157,21 +175,56 @@ func (s *Server) HandleMCP(c *gin.Context) {
 	})
 }
 
-// handleMCPInitialize handles the initialize method
-func (s *Server) handleMCPInitialize(params json.RawMessage) (interface{}, error) {
+// handleMCPInitialize handles the initialize method, recording the client
+// profile the user's client matches and the workspace it selects. Requests over
+// HTTP carry no session, so the user's most recently initialized client decides
+// the profile, and the workspace of requests that select none themselves.
+func (s *Server) handleMCPInitialize(ctx context.Context, params json.RawMessage, userID uint) (interface{}, error) {
 	// Parse initialize params if needed
 	var initParams struct {
 		ProtocolVersion string `json:"protocolVersion"`
-		ClientInfo      struct {
+		Capabilities    struct {
+			Experimental map[string]any `json:"experimental"`
+		} `json:"capabilities"`
+		ClientInfo struct {
 			Name    string `json:"name"`
 			Version string `json:"version"`
 		} `json:"clientInfo"`
 	}
 	
 	if err := json.Unmarshal(params, &initParams); err != nil {
-		return nil, fmt.Errorf("invalid initialize params: %w", err)
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", fmt.Sprintf("invalid initialize params: %v", err), nil)
 	}
 
+	workspace, err := mcp.RequestedWorkspace(initParams.Capabilities.Experimental)
+	if err != nil {
+		return nil, err
+	}
+	workspaceID, err := s.createScopedMemoryService(userID).ResolveWorkspace(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID != 0 {
+		s.mcpWorkspaces.Store(userID, workspaceID)
+	} else {
+		s.mcpWorkspaces.Delete(userID)
+	}
+
+	profile := mcp.MatchClientProfile(s.config.ClientProfiles, initParams.ClientInfo.Name, initParams.ClientInfo.Version)
+	if profile != nil {
+		s.mcpClients.Store(userID, profile)
+	} else {
+		s.mcpClients.Delete(userID)
+	}
+	event := s.logger.Info().
+		Uint("user_id", userID).
+		Str("client", initParams.ClientInfo.Name).
+		Str("client_version", initParams.ClientInfo.Version)
+	if profile != nil {
+		event = event.Str("profile", profile.Name)
+	}
+	event.Msg("MCP client initialized")
+
 	return map[string]interface{}{
 		"protocolVersion": "0.1.0",
 		"serverInfo": map[string]interface{}{
@@ -181,12 +234,32 @@ func (s *Server) handleMCPInitialize(params json.RawMessage) (interface{}, error
 		"capabilities": map[string]interface{}{
 			"tools":     true,
 			"resources": true,
+			"prompts":   true,
 		},
 	}, nil
 }
 
-// handleMCPListTools returns the list of available tools
-func (s *Server) handleMCPListTools() (interface{}, error) {
+// mcpMemoryService returns the user's memory service for an MCP request: in the
+// workspace the request selects, else the one the user's MCP client selected when
+// it initialized
+func (s *Server) mcpMemoryService(c *gin.Context, userID uint) *services.MemoryService {
+	if _, ok := requestWorkspace(c); ok {
+		return s.workspaceMemoryService(c, userID)
+	}
+	workspaceID, _ := s.mcpWorkspaces.Load(userID)
+	id, _ := workspaceID.(uint)
+	return s.createScopedMemoryService(userID).InWorkspace(id)
+}
+
+// mcpClientProfile returns the profile of the user's MCP client, or nil
+func (s *Server) mcpClientProfile(userID uint) *mcp.ClientProfile {
+	profile, _ := s.mcpClients.Load(userID)
+	clientProfile, _ := profile.(*mcp.ClientProfile)
+	return clientProfile
+}
+
+// handleMCPListTools returns the list of available tools, shaped for the client
+func (s *Server) handleMCPListTools(profile *mcp.ClientProfile) (interface{}, error) {
 	tools := []mcpTypes.Tool{
 		{
 			Name:        "store_memory",
@@ -219,6 +292,15 @@ func (s *Server) handleMCPListTools() (interface{}, error) {
 						"type":        "object",
 						"description": "Optional metadata for the memory",
 					},
+					"on_duplicate": map[string]interface{}{
+						"type":        "string",
+						"description": "What to do when the memory is nearly the same as an existing one: merge into it, update it, reject the store, or allow a new memory (default: server setting)",
+						"enum":        []string{"merge", "update", "reject", "allow"},
+					},
+					"session_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Working session to capture the memory in (default: the session opened with start_session, if any)",
+					},
 				},
 				Required: []string{"type", "category", "content"},
 			},
@@ -298,6 +380,93 @@ func (s *Server) handleMCPListTools() (interface{}, error) {
 						"type":        "boolean",
 						"description": "Use semantic search (default: true)",
 					},
+					"metadataQuery": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter on memory metadata. Either a JSON object matched by containment, e.g. {\"source\":\"slack\"}, or a JSONPath expression starting with $, e.g. $.scores[0] > 0.9 or $.ticket (matches memories where the path exists). At most 1024 characters.",
+						"maxLength":   1024,
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"description": "Filter by tags. Returns memories with any of the tags, or all of them when tagMatch is all",
+						"items":       map[string]interface{}{"type": "string"},
+					},
+					"tagMatch": map[string]interface{}{
+						"type":        "string",
+						"description": "How tags are matched: any (default) or all",
+						"enum":        []string{"any", "all"},
+					},
+					"searchMode": map[string]interface{}{
+						"type":        "string",
+						"description": "keyword (substring match), semantic (embedding similarity, the default when a query is given) or hybrid (full-text and semantic results fused by reciprocal rank; best for names and exact terms)",
+						"enum":        []string{"keyword", "semantic", "hybrid"},
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of results to skip (default: 0)",
+						"minimum":     0,
+						"maximum":     10000,
+					},
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "next_cursor from a previous response, to fetch the next page with the same query and filters",
+					},
+					"includeContext": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also search the session's short-term conversation buffer; matching turns are returned in context",
+					},
+					"sessionId": map[string]interface{}{
+						"type":        "string",
+						"description": "Session whose buffer includeContext searches (default: default)",
+					},
+					"includeRelated": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the memories linked to the results (see link_memories) in related, so context chains can be followed",
+					},
+					"relatedDepth": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many links to follow from the results with includeRelated (default: 1, max: 3)",
+						"minimum":     1,
+						"maximum":     3,
+					},
+					"withinIds": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "Only search these memory IDs, to drill into earlier results",
+					},
+					"refineCursor": map[string]interface{}{
+						"type":        "string",
+						"description": "refine_cursor from a previous search response; only its memories are searched",
+					},
+					"createdAfter": map[string]interface{}{
+						"type":        "string",
+						"description": "Only memories created at or after this time: an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC), e.g. the first day of last month",
+					},
+					"createdBefore": map[string]interface{}{
+						"type":        "string",
+						"description": "Only memories created before this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"updatedAfter": map[string]interface{}{
+						"type":        "string",
+						"description": "Only memories last updated at or after this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"updatedBefore": map[string]interface{}{
+						"type":        "string",
+						"description": "Only memories last updated before this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"priorities": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high", "critical"}},
+						"description": "Only memories of these priorities",
+					},
+					"session": map[string]interface{}{
+						"type":        "string",
+						"description": "Only memories captured in this working session; current means the open one",
+					},
+					"minPriority": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"low", "medium", "high", "critical"},
+						"description": "Only memories of at least this priority, e.g. high for high and critical memories",
+					},
 				},
 				Required: []string{"query"},
 			},
@@ -329,8 +498,8 @@ func (s *Server) handleMCPListTools() (interface{}, error) {
 					},
 					"priority": map[string]interface{}{
 						"type":        "string",
-						"description": "Priority level: low, medium, or high",
-						"enum":        []string{"low", "medium", "high"},
+						"description": "Priority level: low, medium, high, or critical. Higher priority memories rank above lower priority ones in search results.",
+						"enum":        []string{"low", "medium", "high", "critical"},
 					},
 					"tags": map[string]interface{}{
 						"type":        "array",
@@ -347,9 +516,83 @@ func (s *Server) handleMCPListTools() (interface{}, error) {
 				Required: []string{"id"},
 			},
 		},
+		{
+			Name:        "update_memories_bulk",
+			Description: "Update several existing memories at once by ID, for example to re-tag or re-categorize a set found by a search. Provide only the fields to change for each. Each update succeeds or fails on its own and is reported in the results; set atomic to apply all of them or none.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"updates": map[string]interface{}{
+						"type":        "array",
+						"description": "Updates to apply, at most 100",
+						"items": map[string]interface{}{
+							"type":       "object",
+							"properties": map[string]interface{}{
+								"id": map[string]interface{}{
+									"type":        "integer",
+									"description": "ID of the memory to update",
+									"minimum":     1,
+								},
+								"type": map[string]interface{}{
+									"type":        "string",
+									"description": "Type of memory: fact, conversation, context, or preference",
+									"enum":        []string{"fact", "conversation", "context", "preference"},
+								},
+								"category": map[string]interface{}{
+									"type":        "string",
+									"description": "Category of memory: personal, project, or business",
+									"enum":        []string{"personal", "project", "business"},
+								},
+								"content": map[string]interface{}{
+									"type":        "string",
+									"description": "The new content of the memory",
+								},
+								"priority": map[string]interface{}{
+									"type":        "string",
+									"description": "Priority level: low, medium, high, or critical",
+									"enum":        []string{"low", "medium", "high", "critical"},
+								},
+								"tags": map[string]interface{}{
+									"type":        "array",
+									"description": "Tags to categorize the memory",
+									"items": map[string]interface{}{
+										"type": "string",
+									},
+								},
+								"metadata": map[string]interface{}{
+									"type":        "object",
+									"description": "Metadata for the memory",
+								},
+							},
+							"required": []string{"id"},
+						},
+					},
+					"atomic": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Apply every update in one transaction, so if any fails none are applied (default false)",
+					},
+				},
+				Required: []string{"updates"},
+			},
+		},
+		{
+			Name:        "get_memory",
+			Description: "Get one memory by ID with its tags, metadata, priority and timestamps. Use when you have a memory's ID, for example from a search, and need the complete record.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory",
+						"minimum":     1,
+					},
+				},
+				Required: []string{"id"},
+			},
+		},
 		{
 			Name:        "delete_memory",
-			Description: "Delete a memory by ID",
+			Description: "Delete a memory by ID. Deleting a critical memory requires a second call with the single-use confirmation token returned by the first; only make it once the user agrees.",
 			InputSchema: mcpTypes.ToolInputSchema{
 				Type: "object",
 				Properties: map[string]interface{}{
@@ -358,14 +601,362 @@ func (s *Server) handleMCPListTools() (interface{}, error) {
 						"description": "ID of the memory to delete",
 						"minimum":     1,
 					},
+					"confirm": map[string]interface{}{
+						"type":        "string",
+						"description": "Confirmation token for deleting a critical memory. Only pass the token returned by a previous delete attempt, and only after the user has explicitly agreed to the deletion.",
+					},
+				},
+				Required: []string{"id"},
+			},
+		},
+		{
+			Name:        "delete_memories",
+			Description: "Delete every memory matching filters, e.g. all memories about an old project. Always call with dryRun first: it returns how many memories match, a sample of them and a confirmation token. Show the user what would be deleted and only repeat the call with that token as confirm once they agree. Critical memories are skipped unless includeCritical is set.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"category": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"personal", "project", "business"},
+						"description": "Only delete memories of this category",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"fact", "conversation", "context", "preference"},
+						"description": "Only delete memories of this type",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only delete memories with any of these tags (all of them with tagMatch all)",
+					},
+					"tagMatch": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"any", "all"},
+						"description": "Whether memories need any (default) or all of the tags",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete memories whose content contains this text, ignoring case",
+					},
+					"createdAfter": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete memories created at or after this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"createdBefore": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete memories created before this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"updatedAfter": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete memories updated at or after this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"updatedBefore": map[string]interface{}{
+						"type":        "string",
+						"description": "Only delete memories updated before this time (RFC 3339 or YYYY-MM-DD)",
+					},
+					"includeCritical": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also delete matching critical memories (default: false)",
+					},
+					"dryRun": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Count the matching memories and return a confirmation token without deleting anything",
+					},
+					"confirm": map[string]interface{}{
+						"type":        "string",
+						"description": "Confirmation token from a dry run with the same filters. Only pass it after the user has explicitly agreed to the deletion.",
+					},
+				},
+			},
+		},
+		{
+			Name:        "consolidate_memories",
+			Description: "Merge related memories into one summary memory written by a language model. Without memoryIds, clusters of similar memories are found automatically; with them, exactly those memories are merged. Each summary supersedes its originals, which are kept and can be traced from the summary's provenance. Use dryRun first to show the user what would be merged.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"memoryIds": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "integer"},
+						"description": "Merge exactly these memories (at least two) instead of finding clusters",
+					},
+					"maxClusters": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most clusters to merge (default: 10)",
+						"minimum":     1,
+					},
+					"dryRun": map[string]interface{}{
+						"type":        "boolean",
+						"description": "List the clusters with their memories without merging anything",
+					},
+				},
+			},
+		},
+		{
+			Name:        "export_memories",
+			Description: "Export all of the user's memories as a portable archive, for backing up or moving to another server. Only use when the user asks for an export.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"include_embeddings": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include each memory's embedding (base64 float32 array with model name) so a server using the same model can skip re-embedding (default: false)",
+					},
+					"keep_encrypted": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Export encrypted memories as their encrypted payload instead of decrypted content; only a server sharing this server's encryption key can import them (default: false)",
+					},
+					"anonymize": map[string]interface{}{
+						"type":        "string",
+						"description": "Replace personal information with stable pseudonyms so the export can be shared: \"true\" for all, or a comma-separated list of emails, phones, names, companies",
+					},
+					"anonymize_terms": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Further words or phrases to pseudonymize, such as project names",
+					},
+				},
+			},
+		},
+		{
+			Name:        "import_memories",
+			Description: "Import a memory archive produced by export_memories. Memories the user already has are skipped; embeddings are reused when they come from the same model.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"archive": map[string]interface{}{
+						"type":        "object",
+						"description": "The archive returned by export_memories",
+					},
+					"allow_cross_region": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow importing an archive exported from a different residency region",
+					},
+				},
+				Required: []string{"archive"},
+			},
+		},
+		{
+			Name:        "incognito",
+			Description: "Pause remembering. Use when the user says 'go incognito', 'don't remember this', 'off the record' or similar. While incognito is on nothing is stored or auto-detected, and it turns off automatically when the duration ends. Tell the user when it will end.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"description": "start turns incognito mode on, stop turns it off, status reports whether it is on and when it ends",
+						"enum":        []string{"start", "stop", "status"},
+					},
+					"duration": map[string]interface{}{
+						"type":        "string",
+						"description": "How long incognito mode lasts when starting, e.g. 30m or 2h, or a number of minutes (default 1h, at most 24h). It turns off by itself afterwards.",
+					},
+				},
+				Required: []string{"action"},
+			},
+		},
+		{
+			Name:        "start_session",
+			Description: "Start a working session. Memories stored until end_session is called are captured in it, so they can later be recalled together (search with session \"current\" or the session ID) or purged together. Starting a session ends any session still open.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"sessionId": map[string]interface{}{
+						"type":        "string",
+						"description": "ID for the session, e.g. the conversation ID (default: generated)",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "What the session is about, e.g. 'Q3 planning'",
+					},
+				},
+			},
+		},
+		{
+			Name:        "end_session",
+			Description: "End the working session started with start_session. With purge, the memories captured in it are deleted too, apart from critical ones; only purge when the user asks to forget the session.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"purge": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Delete the memories captured in the session (default false)",
+					},
+				},
+			},
+		},
+		{
+			Name:        "memory_history",
+			Description: "Show how a memory changed over time: its current version and the earlier versions it replaced, newest first. Use when the user asks what they used to prefer, when something changed, or how a remembered fact evolved.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory",
+						"minimum":     1,
+					},
+				},
+				Required: []string{"id"},
+			},
+		},
+		{
+			Name:        "append_context",
+			Description: "Add a recent conversation turn to the session's short-term buffer. Buffered turns are working memory between tool calls: they expire after a short time and are never stored as memories, so use this for conversational context that is useful now but not worth remembering. Search with includeContext to find them.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "The conversation turn to buffer",
+					},
+					"role": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"user", "assistant"},
+						"description": "Who said it (default: user)",
+					},
+					"sessionId": map[string]interface{}{
+						"type":        "string",
+						"description": "Conversation session the turn belongs to (default: default)",
+					},
+				},
+				Required: []string{"content"},
+			},
+		},
+		{
+			Name:        "feedback_memory",
+			Description: "Tell the memory system whether a memory returned by search_memories was helpful or irrelevant for the query. Search learns from this: helpful memories rank higher in later searches and irrelevant ones lower. Use when the user confirms or dismisses a recalled memory, or when a result clearly did not answer the query.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory returned by the search",
+						"minimum":     1,
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "The search query the memory was returned for",
+					},
+					"feedback": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"helpful", "irrelevant"},
+						"description": "Whether the memory helped answer the query",
+					},
+				},
+				Required: []string{"id", "query", "feedback"},
+			},
+		},
+		{
+			Name:        "link_memories",
+			Description: "Relate two memories so related context can be followed later: supersedes when the source replaces an outdated target, related_to for memories about the same thing, part_of_project when the source belongs to the project the target describes. Use when storing a memory that updates, extends or belongs with one you already found.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"sourceId": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory the link starts from",
+						"minimum":     1,
+					},
+					"targetId": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory the link points to",
+						"minimum":     1,
+					},
+					"relation": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"supersedes", "related_to", "part_of_project"},
+						"description": "How the source relates to the target",
+					},
+				},
+				Required: []string{"sourceId", "targetId", "relation"},
+			},
+		},
+		{
+			Name:        "get_related_memories",
+			Description: "Follow a memory's links in both directions to find the memories related to it: what superseded it, what it is part of and what else belongs with it. Use to gather the full context around a memory found by search.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the memory",
+						"minimum":     1,
+					},
+					"depth": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many links to follow (default: 1, max: 3)",
+						"minimum":     1,
+						"maximum":     3,
+					},
+					"relations": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": []string{"supersedes", "related_to", "part_of_project"}},
+						"description": "Only follow these relations (default: all)",
+					},
 				},
 				Required: []string{"id"},
 			},
 		},
+		{
+			Name:        "recall_recent",
+			Description: "Recall the memories used lately: the ones searches and lookups returned most recently, or most often. Memories never returned are left out. Use at the start of a conversation to pick up where the user left off without a query.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"by": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"recent", "frequent"},
+						"description": "recent for the memories used most recently (default), frequent for the ones used most often",
+					},
+					"category": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"personal", "project", "business"},
+						"description": "Only recall memories of this category",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"fact", "conversation", "context", "preference"},
+						"description": "Only recall memories of this type",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum memories to return (default: 10, max: 100)",
+						"minimum":     1,
+						"maximum":     100,
+					},
+				},
+			},
+		},
+		{
+			Name:        "process_content",
+			Description: "Scan a conversation transcript for things worth remembering and store them. Each sentence the user said is checked for preferences, personal facts, decisions and explicit remember requests; lines labelled Assistant: or System: are ignored. Returns what was captured with confidence scores, skipping memories that already exist and updating ones with the same subject. Use at the end of a conversation, or with dryRun to preview.",
+			InputSchema: mcpTypes.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "The conversation transcript, optionally with User: and Assistant: labels on each turn",
+					},
+					"minConfidence": map[string]interface{}{
+						"type":        "number",
+						"description": "Detection confidence a sentence needs to be captured (default: 0.5)",
+						"minimum":     0,
+						"maximum":     1,
+					},
+					"dryRun": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Report what would be captured without storing anything (default: false)",
+					},
+				},
+				Required: []string{"content"},
+			},
+		},
 	}
 
 	return map[string]interface{}{
-		"tools": tools,
+		"tools": profile.ShapeTools(mcp.WithFormatArgument(tools)),
 	}, nil
 }
 
@@ -387,7 +978,7 @@ func (s *Server) handleMCPCallTool(ctx context.Context, params json.RawMessage,
 			Err(err).
 			Str("params_string", string(params)).
 			Msg("failed to unmarshal tool call params")
-		return nil, fmt.Errorf("invalid tool call params: %w", err)
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", fmt.Sprintf("invalid tool call params: %v", err), nil)
 	}
 
 	// Log the parsed tool call details
@@ -401,14 +992,28 @@ func (s *Server) handleMCPCallTool(ctx context.Context, params json.RawMessage,
 	if len(callParams.Arguments) == 0 || string(callParams.Arguments) == "null" {
 		errMsg := fmt.Sprintf("tool '%s' called without arguments. Arguments are required for all tool calls.", callParams.Name)
 		s.logger.Error().Str("tool", callParams.Name).Msg(errMsg)
-		return nil, fmt.Errorf(errMsg)
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", errMsg, nil)
+	}
+
+	format, err := mcp.RequestedFormat(ctx, callParams.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound the call by the tool's deadline
+	if timeout := s.config.Timeouts.Tool(callParams.Name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
 	// Create a handler with the scoped memory service
 	handler := mcp.NewHandler(memoryService, s.logger)
 
 	var result interface{}
-	var err error
+
+	ctx, span := tracing.StartToolCall(ctx, callParams.Name)
+	defer func() { tracing.End(span, err) }()
 
 	switch callParams.Name {
 	case "store_memory":
@@ -471,33 +1076,63 @@ func (s *Server) handleMCPCallTool(ctx context.Context, params json.RawMessage,
 		}
 	case "update_memory":
 		result, err = handler.HandleUpdateMemory(ctx, callParams.Arguments)
+	case "update_memories_bulk":
+		result, err = handler.HandleUpdateMemoriesBulk(ctx, callParams.Arguments)
+	case "get_memory":
+		result, err = handler.HandleGetMemory(ctx, callParams.Arguments)
 	case "delete_memory":
 		result, err = handler.HandleDeleteMemory(ctx, callParams.Arguments)
+	case "delete_memories":
+		result, err = handler.HandleDeleteMemories(ctx, callParams.Arguments)
+	case "consolidate_memories":
+		result, err = handler.HandleConsolidateMemories(ctx, callParams.Arguments)
+	case "export_memories":
+		result, err = handler.HandleExportMemories(ctx, callParams.Arguments)
+	case "import_memories":
+		result, err = handler.HandleImportMemories(ctx, callParams.Arguments)
+	case "incognito":
+		result, err = handler.HandleIncognito(ctx, callParams.Arguments)
+	case "start_session":
+		result, err = handler.HandleStartSession(ctx, callParams.Arguments)
+	case "end_session":
+		result, err = handler.HandleEndSession(ctx, callParams.Arguments)
+	case "memory_history":
+		result, err = handler.HandleMemoryHistory(ctx, callParams.Arguments)
+	case "append_context":
+		result, err = handler.HandleAppendContext(ctx, callParams.Arguments)
+	case "feedback_memory":
+		result, err = handler.HandleFeedbackMemory(ctx, callParams.Arguments)
+	case "link_memories":
+		result, err = handler.HandleLinkMemories(ctx, callParams.Arguments)
+	case "get_related_memories":
+		result, err = handler.HandleGetRelatedMemories(ctx, callParams.Arguments)
+	case "recall_recent":
+		result, err = handler.HandleRecallRecent(ctx, callParams.Arguments)
+	case "process_content":
+		result, err = handler.HandleProcessContent(ctx, callParams.Arguments)
 	default:
-		return nil, fmt.Errorf("unknown tool: %s", callParams.Name)
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "unknown_tool", fmt.Sprintf("unknown tool: %s", callParams.Name), map[string]interface{}{
+			"tool": callParams.Name,
+		})
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert result to the expected format
-	var content []mcpTypes.Content
-	
-	// Marshal result to JSON for text content
-	resultJSON, err := json.Marshal(result)
+	// Render the result in the requested format; JSON results are also returned
+	// as structured content
+	toolResult, err := mcp.ToolResult(callParams.Name, result, format)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
+		return nil, err
 	}
-
-	content = append(content, mcpTypes.TextContent{
-		Type: "text",
-		Text: string(resultJSON),
-	})
-
-	return map[string]interface{}{
-		"content": content,
-	}, nil
+	response := map[string]interface{}{
+		"content": toolResult.Content,
+	}
+	if format == mcp.FormatJSON {
+		response["structuredContent"] = result
+	}
+	return response, nil
 }
 
 // handleMCPListResources returns the list of available resources
@@ -516,6 +1151,14 @@ func (s *Server) handleMCPListResources() (interface{}, error) {
 	}, nil
 }
 
+// handleMCPListResourceTemplates returns the templates resources can be read
+// through, such as attachments
+func (s *Server) handleMCPListResourceTemplates() (interface{}, error) {
+	return map[string]interface{}{
+		"resourceTemplates": []mcpTypes.ResourceTemplate{mcp.AttachmentResourceTemplate()},
+	}, nil
+}
+
 // handleMCPReadResource handles resource reads
 func (s *Server) handleMCPReadResource(ctx context.Context, params json.RawMessage, memoryService *services.MemoryService) (interface{}, error) {
 	var readParams struct {
@@ -523,11 +1166,24 @@ func (s *Server) handleMCPReadResource(ctx context.Context, params json.RawMessa
 	}
 
 	if err := json.Unmarshal(params, &readParams); err != nil {
-		return nil, fmt.Errorf("invalid resource read params: %w", err)
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", fmt.Sprintf("invalid resource read params: %v", err), nil)
+	}
+
+	if mcp.IsAttachmentURI(readParams.URI) {
+		contents, err := mcp.ReadAttachment(ctx, memoryService, readParams.URI)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"contents": contents,
+		}, nil
 	}
 
 	if readParams.URI != "memory://stats" {
-		return nil, fmt.Errorf("unknown resource: %s", readParams.URI)
+		return nil, utils.NewMCPError(utils.MCPCodeNotFound, "not_found", fmt.Sprintf("unknown resource: %s", readParams.URI), map[string]interface{}{
+			"resource": "resource",
+			"id":       readParams.URI,
+		})
 	}
 
 	stats, err := memoryService.GetMemoryStats(ctx)
@@ -551,12 +1207,56 @@ func (s *Server) handleMCPReadResource(ctx context.Context, params json.RawMessa
 	}, nil
 }
 
+// handleMCPListPrompts returns the list of available prompts
+func (s *Server) handleMCPListPrompts() (interface{}, error) {
+	return map[string]interface{}{
+		"prompts": mcp.Prompts(),
+	}, nil
+}
+
+// handleMCPGetPrompt fills in a prompt from the user's memories
+func (s *Server) handleMCPGetPrompt(ctx context.Context, params json.RawMessage, memoryService *services.MemoryService) (interface{}, error) {
+	var getParams struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+
+	if err := json.Unmarshal(params, &getParams); err != nil {
+		return nil, utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", fmt.Sprintf("invalid prompt get params: %v", err), nil)
+	}
+
+	return mcp.GetPrompt(ctx, memoryService, getParams.Name, getParams.Arguments)
+}
+
 // createScopedMemoryService creates a memory service scoped to a specific user
 func (s *Server) createScopedMemoryService(userID uint) *services.MemoryService {
 	// Build config with memory limit and encryption service
 	serviceConfig := map[string]interface{}{
 		"memory_limit": s.config.Memory.MaxMemories,
 		"similarity_threshold": s.config.Memory.SimilarityThreshold,
+		"priority_boosts": s.config.Memory.PriorityBoosts,
+		"eviction_policy": s.config.Memory.EvictionPolicy,
+		"plan_limits": s.config.Memory.Plans,
+		"context_buffer_ttl": s.config.Memory.ContextBufferTTL,
+		"duplicate_action": s.config.Memory.DuplicateAction,
+		"duplicate_threshold": s.config.Memory.DuplicateThreshold,
+		"feedback_weight": s.config.Memory.FeedbackWeight,
+		"max_content_length": s.config.Memory.MaxContentLength,
+		"chunk_size": s.config.Memory.ChunkSize,
+		"attachment_max_size": s.config.Attachments.MaxSize,
+		"attachment_max_per_memory": s.config.Attachments.MaxPerMemory,
+		"exact_counts": s.config.Memory.ExactCounts,
+		"normalize_detected": s.config.Memory.NormalizeDetected,
+		"filler_words": s.config.Memory.FillerWords,
+		"maintenance_stale_after": s.config.Maintenance.StaleAfter,
+		"maintenance_max_actions": s.config.Maintenance.MaxActions,
+		"consolidation_similarity": s.config.Consolidation.Similarity,
+		"consolidation_min_cluster": s.config.Consolidation.MinClusterSize,
+		"consolidation_max_cluster": s.config.Consolidation.MaxClusterSize,
+		"write_timeout": s.config.Timeouts.Write,
+		"embedding_cache": s.config.Embedding.Cache,
+		"residency_region": s.config.Residency.Region,
+		"access_audit": s.config.Audit.MemoryAccess,
 	}
 	
 	// Pass encryption service if available
@@ -564,6 +1264,55 @@ func (s *Server) createScopedMemoryService(userID uint) *services.MemoryService
 		serviceConfig["encryption_service"] = encSvc
 	}
 	
+	// Pass notifier so critical memory changes are reported
+	if notifier := s.memoryService.GetNotifier(); notifier != nil {
+		serviceConfig["notifier"] = notifier
+	}
+	
+	// Pass the moderation hook so every store is moderated
+	if moderationHook := s.memoryService.GetModerationHook(); moderationHook != nil {
+		serviceConfig["moderation"] = moderationHook
+	}
+	
+	// Share the LLM budget so every request counts against the same limits
+	serviceConfig["llm_budget"] = s.memoryService.GetLLMBudget()
+	
+	// Extract memories from transcripts with the same engine
+	serviceConfig["memory_extractor"] = s.memoryService.GetMemoryExtractor()
+	
+	// Consolidate memories with the same summarizer
+	if summarizer := s.memoryService.GetMemorySummarizer(); summarizer != nil {
+		serviceConfig["memory_summarizer"] = summarizer
+	}
+	
+	// Transcribe voice memos with the same provider
+	if transcriber := s.memoryService.GetTranscriber(); transcriber != nil {
+		serviceConfig["transcriber"] = transcriber
+	}
+	
+	// Keep attachments in the same store
+	if attachmentStore := s.memoryService.GetAttachmentStore(); attachmentStore != nil {
+		serviceConfig["attachment_store"] = attachmentStore
+	}
+	
+	// Embed memories as the same composed document
+	if composer := s.memoryService.GetEmbeddingComposer(); composer != nil {
+		serviceConfig["embedding_composer"] = composer
+	}
+	
+	// Share the embedding batcher so stores from every request coalesce
+	if batcher := s.memoryService.GetEmbeddingBatcher(); batcher != nil {
+		serviceConfig["embedding_batcher"] = batcher
+	}
+	
+	// Share the search cache so repeated searches hit it from every request
+	if searchCache := s.memoryService.GetSearchCache(); searchCache != nil {
+		serviceConfig["search_cache"] = searchCache
+	}
+	
+	// Track background embeddings with the server's so shutdown drains them too
+	serviceConfig["async_embeddings"] = s.memoryService.GetAsyncEmbeddings()
+	
 	// Create a user-scoped memory service for this request
 	return services.NewMemoryServiceWithUser(
 		s.db.DB(),
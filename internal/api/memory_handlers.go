@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ksred/remember-me-mcp/internal/mcp"
@@ -15,7 +17,8 @@ import (
 
 // storeMemoryHandler godoc
 // @Summary Store a memory
-// @Description Store important information that can be recalled later
+// @Description Store important information that can be recalled later. A store matching an existing memory by update key,
+// @Description content or similarity updates it instead; action, matched_by and previous_content report what happened.
 // @Tags memories
 // @Accept json
 // @Produce json
@@ -24,6 +27,9 @@ import (
 // @Success 201 {object} mcp.StoreMemoryResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Incognito, or a near-duplicate rejected (with the matched memory)"
+// @Failure 429 {object} QuotaExceededResponse "Memory limit reached under the reject_new policy"
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /memories [post]
 func (s *Server) storeMemoryHandler(c *gin.Context) {
@@ -41,23 +47,52 @@ func (s *Server) storeMemoryHandler(c *gin.Context) {
 	}
 
 	// Create user-scoped memory service
-	userMemoryService := s.createScopedMemoryService(user.ID)
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
 
 	// Store memory using the memory service
 	storeReq := &services.StoreMemoryRequest{
-		Type:     req.Type,
-		Category: req.Category,
-		Content:  req.Content,
-		Metadata: req.Metadata,
+		Type:        req.Type,
+		Category:    req.Category,
+		Content:     req.Content,
+		Tags:        req.Tags,
+		Metadata:    req.Metadata,
+		OnDuplicate: req.OnDuplicate,
+		SessionID:   req.SessionID,
 	}
-	memory, err := userMemoryService.StoreMemory(c.Request.Context(), storeReq)
+	result, err := userMemoryService.StoreMemoryWithResult(c.Request.Context(), storeReq)
 	
 	if err != nil {
+		var duplicateErr *services.DuplicateMemoryError
+		if errors.As(err, &duplicateErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      err.Error(),
+				"memory":     duplicateErr.Memory,
+				"similarity": duplicateErr.Similarity,
+			})
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrIncognito) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		s.logger.Error().Err(err).Msg("Failed to store memory")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store memory"})
 		return
 	}
 
+	memory := result.Memory
+
 	// Log the activity
 	details := map[string]interface{}{
 		"memory_id": memory.ID,
@@ -67,8 +102,12 @@ func (s *Server) storeMemoryHandler(c *gin.Context) {
 	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityMemoryStored, details, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	response := mcp.StoreMemoryResponse{
-		Success: true,
-		Memory:  memory,
+		Success:         true,
+		Memory:          memory,
+		Action:          result.Action,
+		MatchedBy:       result.MatchedBy,
+		PreviousContent: result.PreviousContent,
+		Duplicate:       result.Duplicate,
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -86,6 +125,23 @@ func (s *Server) storeMemoryHandler(c *gin.Context) {
 // @Param type query string false "Filter by type (fact, conversation, context, preference)"
 // @Param limit query int false "Maximum number of results (default: 100, max: 1000)"
 // @Param useSemanticSearch query bool false "Use semantic search (default: true)"
+// @Param metadataQuery query string false "Metadata filter: a JSON object matched by containment, or a JSONPath expression such as $.scores[0] > 0.9"
+// @Param tags query string false "Comma-separated tags to filter by"
+// @Param tagMatch query string false "How tags are matched: any (default) or all"
+// @Param searchMode query string false "keyword, semantic or hybrid (full-text and semantic fused by rank); overrides useSemanticSearch"
+// @Param offset query int false "Number of results to skip (at most 10000)"
+// @Param cursor query string false "next_cursor from the previous page; takes precedence over offset"
+// @Param includeContext query bool false "Also search the session's short-term context buffer; matches are returned in context"
+// @Param sessionId query string false "Session whose context buffer is searched (default: default)"
+// @Param withinIds query string false "Comma-separated memory IDs to search within"
+// @Param refineCursor query string false "refine_cursor from a previous search, to search within its results"
+// @Param createdAfter query string false "Only memories created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param createdBefore query string false "Only memories created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param updatedAfter query string false "Only memories last updated at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param updatedBefore query string false "Only memories last updated before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param priorities query string false "Comma-separated priorities to keep (low, medium, high, critical)"
+// @Param minPriority query string false "Only memories of at least this priority"
+// @Param session query string false "Only memories captured in this working session (current for the open one)"
 // @Success 200 {object} mcp.SearchMemoriesResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -122,8 +178,43 @@ func (s *Server) searchMemoriesHandler(c *gin.Context) {
 		useSemanticSearch = false
 	}
 
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsedOffset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be an integer"})
+			return
+		}
+		offset = parsedOffset
+	}
+
+	// Searching within earlier results: withinIds=1,2,3 (or repeated)
+	var withinIDs []uint
+	if values := c.QueryArray("withinIds"); len(values) > 0 {
+		withinIDs = []uint{}
+		for _, value := range parseTagsQuery(values) {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "withinIds must be memory IDs"})
+				return
+			}
+			withinIDs = append(withinIDs, uint(id))
+		}
+	}
+
+	// Scoping by time: createdAfter=2025-03-01&createdBefore=2025-04-01
+	dateBounds := make(map[string]*time.Time)
+	for _, field := range []string{"createdAfter", "createdBefore", "updatedAfter", "updatedBefore"} {
+		bound, err := services.ParseDateBound(field, c.Query(field))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dateBounds[field] = bound
+	}
+
 	// Create user-scoped memory service
-	userMemoryService := s.createScopedMemoryService(user.ID)
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
 
 	// Search memories
 	searchReq := &services.SearchMemoriesRequest{
@@ -132,13 +223,54 @@ func (s *Server) searchMemoriesHandler(c *gin.Context) {
 		Type:              memoryType,
 		Limit:             limit,
 		UseSemanticSearch: useSemanticSearch,
+		MetadataQuery:     c.Query("metadataQuery"),
+		Tags:              parseTagsQuery(c.QueryArray("tags")),
+		TagMatch:          c.Query("tagMatch"),
+		SearchMode:        c.Query("searchMode"),
+		Offset:            offset,
+		Cursor:            c.Query("cursor"),
+		IncludeContext:    c.Query("includeContext") == "true",
+		SessionID:         c.Query("sessionId"),
+		WithinIDs:         withinIDs,
+		RefineCursor:      c.Query("refineCursor"),
+		CreatedAfter:      dateBounds["createdAfter"],
+		CreatedBefore:     dateBounds["createdBefore"],
+		UpdatedAfter:      dateBounds["updatedAfter"],
+		UpdatedBefore:     dateBounds["updatedBefore"],
+		Priorities:        parseTagsQuery(c.QueryArray("priorities")),
+		MinPriority:       c.Query("minPriority"),
+		Session:           c.Query("session"),
 	}
-	memories, err := userMemoryService.SearchMemories(c.Request.Context(), searchReq)
+	page, err := userMemoryService.SearchMemoriesPage(c.Request.Context(), searchReq)
 	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			// session=current without an open session
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		s.logger.Error().Err(err).Msg("Failed to search memories")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search memories"})
 		return
 	}
+	memories := page.Memories
+
+	var contextMatches []services.ContextMatch
+	if searchReq.IncludeContext {
+		contextMatches, err = userMemoryService.SearchContext(c.Request.Context(), searchReq.SessionID, query, services.DefaultContextSearchLimit)
+		if err != nil {
+			if utils.IsValidationError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			s.logger.Error().Err(err).Msg("Failed to search context buffer")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search memories"})
+			return
+		}
+	}
 
 	// Log the search activity only if it's not a wildcard query
 	if query != "*" && query != "" {
@@ -150,6 +282,16 @@ func (s *Server) searchMemoriesHandler(c *gin.Context) {
 			"use_semantic_search":  useSemanticSearch,
 			"results_count":        len(memories),
 		}
+		if searchReq.MetadataQuery != "" {
+			details["metadata_query"] = searchReq.MetadataQuery
+		}
+		if searchReq.SearchMode != "" {
+			details["search_mode"] = searchReq.SearchMode
+		}
+		if len(searchReq.Tags) > 0 {
+			details["tags"] = searchReq.Tags
+			details["tag_match"] = searchReq.TagMatch
+		}
 		
 		// Log search activity asynchronously with proper error handling
 		go func() {
@@ -173,193 +315,1083 @@ func (s *Server) searchMemoriesHandler(c *gin.Context) {
 	}
 
 	response := mcp.SearchMemoriesResponse{
-		Memories: memories,
-		Count:    len(memories),
+		Memories:     memories,
+		Count:        len(memories),
+		TotalCount:   page.TotalCount,
+		NextCursor:   page.NextCursor,
+		RefineCursor: page.RefineCursor,
+		Context:      contextMatches,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// deleteMemoryHandler godoc
-// @Summary Delete a memory
-// @Description Delete a memory by its ID
+// parseTagsQuery splits tags given as repeated and/or comma-separated query values
+func parseTagsQuery(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// memoryProvenanceHandler godoc
+// @Summary Get memory provenance
+// @Description Trace a memory created by consolidation or merge back to the memories it was derived from, including whether each source has since changed or been deleted
 // @Tags memories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path string true "Memory ID"
-// @Success 200 {object} mcp.DeleteMemoryResponse
+// @Success 200 {object} services.Provenance
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /memories/{id} [delete]
-func (s *Server) deleteMemoryHandler(c *gin.Context) {
-	// Get user from context
+// @Router /memories/{id}/provenance [get]
+func (s *Server) memoryProvenanceHandler(c *gin.Context) {
 	user, exists := getUserFromContext(c)
 	if !exists || user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
 		return
 	}
 
-	// Create user-scoped memory service
-	userMemoryService := s.createScopedMemoryService(user.ID)
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
 
-	delReq := &services.DeleteMemoryRequest{
-		ID: uint(id),
-	}
-	err = userMemoryService.DeleteMemory(c.Request.Context(), delReq)
+	provenance, err := userMemoryService.Provenance(c.Request.Context(), uint(id))
 	if err != nil {
-		// Check if it's a NotFoundError
-		var notFoundErr *utils.NotFoundError
-		if errors.As(err, &notFoundErr) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
 			return
 		}
-		s.logger.Error().Err(err).Msg("Failed to delete memory")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete memory"})
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to get memory provenance")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory provenance"})
 		return
 	}
 
-	// Log the deletion activity
-	details := map[string]interface{}{
-		"memory_id": uint(id),
+	c.JSON(http.StatusOK, provenance)
+}
+
+// memoryHistoryHandler godoc
+// @Summary Get memory history
+// @Description Get a memory's current version and the versions it replaced, newest first. A revision is recorded whenever
+// @Description a store (including an update_key match) or an update changes the memory's content, type or category.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Memory ID"
+// @Success 200 {object} services.MemoryHistory
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/{id}/history [get]
+func (s *Server) memoryHistoryHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
 	}
-	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityMemoryDeleted, details, c.ClientIP(), c.GetHeader("User-Agent"))
 
-	response := mcp.DeleteMemoryResponse{
-		Success: true,
-		Message: "Memory deleted successfully",
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
 
-// basicMemoryStatsHandler - deprecated, kept for compatibility
-func (s *Server) basicMemoryStatsHandler(c *gin.Context) {
-	stats, err := s.memoryService.GetMemoryStats(c.Request.Context())
+	history, err := userMemoryService.GetMemoryHistory(c.Request.Context(), uint(id))
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get memory stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory statistics"})
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to get memory history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory history"})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	c.JSON(http.StatusOK, history)
 }
 
-// enhancedMemoryStatsHandler godoc
-// @Summary Get enhanced memory statistics
-// @Description Get comprehensive statistics about stored memories including search patterns and growth trends
+// MemoryAccessLogResponse lists who accessed a memory, newest first
+type MemoryAccessLogResponse struct {
+	MemoryID uint                     `json:"memory_id" example:"42"`
+	Entries  []models.MemoryAccessLog `json:"entries"`
+	Count    int                      `json:"count" example:"3"`
+}
+
+// memoryAccessLogHandler godoc
+// @Summary Get memory access log
+// @Description Get the audit trail of a memory: each read, update and delete made through MCP or the HTTP API, with the
+// @Description user, action, source and timestamp, newest first. Entries are kept after the memory is deleted.
 // @Tags memories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} map[string]interface{}
+// @Param id path string true "Memory ID"
+// @Param action query string false "read, update or delete"
+// @Param since query string false "Only entries at or after this RFC 3339 time"
+// @Param limit query int false "Maximum entries to return (default 100, max 1000)"
+// @Success 200 {object} MemoryAccessLogResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /memories/stats [get]
-func (s *Server) enhancedMemoryStatsHandler(c *gin.Context) {
-	// Get user from context
+// @Router /memories/{id}/access-log [get]
+func (s *Server) memoryAccessLogHandler(c *gin.Context) {
 	user, exists := getUserFromContext(c)
 	if !exists || user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
-	ctx := c.Request.Context()
-	
-	// Create user-scoped memory service
-	userMemoryService := s.createScopedMemoryService(user.ID)
-
-	// Get basic memory stats
-	basicStats, err := userMemoryService.GetMemoryStats(ctx)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get basic memory stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory statistics"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
 		return
 	}
-	
-	// Get search statistics for this user
-	userIDPtr := &user.ID
-	searchStats, err := s.activityService.GetSearchStats(ctx, userIDPtr)
+
+	req := services.AccessLogRequest{Action: c.Query("action")}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if req.Limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected an RFC 3339 time"})
+			return
+		}
+		req.Since = &since
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	entries, err := userMemoryService.MemoryAccessLog(c.Request.Context(), uint(id), req)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get search stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search statistics"})
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to get memory access log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory access log"})
 		return
 	}
-	
-	// Get memory growth stats for the last 7 days
-	growthStats, err := s.activityService.GetMemoryGrowthStats(ctx, userIDPtr)
+
+	c.JSON(http.StatusOK, MemoryAccessLogResponse{
+		MemoryID: uint(id),
+		Entries:  entries,
+		Count:    len(entries),
+	})
+}
+
+// memoryTimelineHandler godoc
+// @Summary Get memory timeline
+// @Description Get everything that happened to a memory, oldest first: its creation and the client that stored it, each edit
+// @Description with what it changed, its latest access, feedback, links, consolidation, due date and support access.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Memory ID"
+// @Param types query string false "Comma-separated event types: created, edited, accessed, feedback, linked, derived, due, support_access"
+// @Param q query string false "Keep only events whose summary or details contain this text"
+// @Success 200 {object} services.MemoryTimeline
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/{id}/timeline [get]
+func (s *Server) memoryTimelineHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get memory growth stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory growth statistics"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
 		return
 	}
-	
-	// Combine all statistics
-	enhancedStats := map[string]interface{}{
-		"basic_stats":    basicStats,
-		"search_stats":   searchStats,
-		"growth_stats":   growthStats,
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	timeline, err := userMemoryService.MemoryTimeline(c.Request.Context(), uint(id), services.TimelineRequest{
+		Types: parseTagsQuery(c.QueryArray("types")),
+		Query: c.Query("q"),
+	})
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to get memory timeline")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory timeline"})
+		return
 	}
-	
-	c.JSON(http.StatusOK, enhancedStats)
+
+	c.JSON(http.StatusOK, timeline)
 }
 
-// userActivityStatsHandler godoc
-// @Summary Get user activity statistics
-// @Description Get comprehensive activity statistics for the authenticated user
-// @Tags users
+// memoryNeighborsHandler godoc
+// @Summary Get a memory's nearest neighbors
+// @Description Debug semantic search: list the memories whose embeddings are closest to this one, with cosine distance,
+// @Description similarity, shared tags and whether each reaches the configured similarity threshold. Does not count as an access.
+// @Tags memories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} map[string]interface{}
+// @Param id path string true "Memory ID"
+// @Param k query int false "Number of neighbors (default 10, max 100)"
+// @Success 200 {object} services.NeighborReport
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /users/activity-stats [get]
-func (s *Server) userActivityStatsHandler(c *gin.Context) {
-	// Get user from context (set by auth middleware)
+// @Router /memories/{id}/neighbors [get]
+func (s *Server) memoryNeighborsHandler(c *gin.Context) {
 	user, exists := getUserFromContext(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
-	
-	stats, err := s.activityService.GetUserActivityStats(c.Request.Context(), user.ID)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get user activity stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user activity statistics"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
 		return
 	}
-	
-	c.JSON(http.StatusOK, stats)
+
+	k := 0
+	if kStr := c.Query("k"); kStr != "" {
+		if k, err = strconv.Atoi(kStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid k"})
+			return
+		}
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	report, err := userMemoryService.NearestNeighbors(c.Request.Context(), uint(id), k)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("memory_id", uint(id)).Msg("Failed to find nearest neighbors")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find nearest neighbors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
 
-// systemPerformanceStatsHandler godoc
-// @Summary Get system performance statistics
-// @Description Get system-wide performance metrics and health indicators
-// @Tags system
+// recallRecentHandler godoc
+// @Summary Recall recently used memories
+// @Description List the memories searches and lookups returned most recently (by=recent) or most often (by=frequent).
+// @Description Memories never returned are left out, and recalling does not count as an access.
+// @Tags memories
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} map[string]interface{}
+// @Param by query string false "recent (default) or frequent"
+// @Param category query string false "Filter by category"
+// @Param type query string false "Filter by type"
+// @Param limit query int false "Maximum memories to return (default 10, max 100)"
+// @Success 200 {object} mcp.RecallRecentResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /system/performance [get]
-func (s *Server) systemPerformanceStatsHandler(c *gin.Context) {
-	stats, err := s.activityService.GetPerformanceStats(c.Request.Context())
+// @Router /memories/recent [get]
+func (s *Server) recallRecentHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+	by := c.DefaultQuery("by", services.RecallByRecent)
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	memories, err := userMemoryService.RecallRecent(c.Request.Context(), services.RecallRequest{
+		By:       by,
+		Category: c.Query("category"),
+		Type:     c.Query("type"),
+		Limit:    limit,
+	})
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Failed to get system performance stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get system performance statistics"})
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to recall recent memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recall recent memories"})
 		return
 	}
-	
-	c.JSON(http.StatusOK, stats)
-}
\ No newline at end of file
+	if memories == nil {
+		memories = []*models.Memory{}
+	}
+
+	c.JSON(http.StatusOK, mcp.RecallRecentResponse{
+		Success:  true,
+		By:       by,
+		Memories: memories,
+		Count:    len(memories),
+	})
+}
+
+// deleteMemoryHandler godoc
+// @Summary Delete a memory
+// @Description Delete a memory by its ID
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Memory ID"
+// @Param confirm query string false "Confirmation token, required to delete a critical memory"
+// @Success 200 {object} mcp.DeleteMemoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} mcp.DeleteMemoryResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/{id} [delete]
+func (s *Server) deleteMemoryHandler(c *gin.Context) {
+	// Get user from context
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	// Create user-scoped memory service
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	delReq := &services.DeleteMemoryRequest{
+		ID:      uint(id),
+		Confirm: c.Query("confirm"),
+	}
+	err = userMemoryService.DeleteMemory(c.Request.Context(), delReq)
+	if err != nil {
+		// Check if it's a NotFoundError
+		var notFoundErr *utils.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "memory not found"})
+			return
+		}
+		var confirmErr *services.ConfirmationRequiredError
+		if errors.As(err, &confirmErr) {
+			c.JSON(http.StatusConflict, mcp.DeleteMemoryResponse{
+				Success:           false,
+				Error:             err.Error(),
+				ConfirmationToken: confirmErr.Token,
+			})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to delete memory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete memory"})
+		return
+	}
+
+	// Log the deletion activity
+	details := map[string]interface{}{
+		"memory_id": uint(id),
+	}
+	go s.activityService.LogActivity(c.Request.Context(), user.ID, models.ActivityMemoryDeleted, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	response := mcp.DeleteMemoryResponse{
+		Success: true,
+		Message: "Memory deleted successfully",
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// updateMemoriesHandler godoc
+// @Summary Update memories in bulk
+// @Description Update up to 100 memories by ID in one request, each with only the fields to change. Each update succeeds
+// @Description or fails on its own and is reported in the results in request order. With atomic=true every update is
+// @Description applied in one transaction: if any fails, none are applied and rolled_back is set.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body mcp.UpdateMemoriesBulkRequest true "Updates to apply"
+// @Success 200 {object} services.BulkUpdateResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories [patch]
+func (s *Server) updateMemoriesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req mcp.UpdateMemoriesBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.BulkUpdate(c.Request.Context(), req.ServiceRequest())
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to update memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// deleteMemoriesHandler godoc
+// @Summary Delete memories by filter
+// @Description Delete every memory matching the filters. At least one filter is required. With dryRun=true nothing is deleted; the response counts the matching memories, lists a sample and carries the confirm token the delete itself needs. Critical memories are skipped unless includeCritical=true.
+// @Tags memories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param category query string false "Category to delete"
+// @Param type query string false "Type to delete"
+// @Param tags query string false "Comma-separated tags"
+// @Param tagMatch query string false "any (default) or all"
+// @Param query query string false "Text the content contains, ignoring case"
+// @Param createdAfter query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param createdBefore query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param updatedAfter query string false "Updated at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updatedBefore query string false "Updated before (RFC 3339 or YYYY-MM-DD)"
+// @Param includeCritical query bool false "Also delete critical memories"
+// @Param dryRun query bool false "Preview the delete"
+// @Param confirm query string false "Token from a dry run with the same filters"
+// @Success 200 {object} services.BulkDeleteResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories [delete]
+func (s *Server) deleteMemoriesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	category := c.Query("category")
+	if category != "" && !models.IsValidCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category. Must be one of: personal, project, business"})
+		return
+	}
+	memoryType := c.Query("type")
+	if memoryType != "" && !models.IsValidType(memoryType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Must be one of: fact, conversation, context, preference"})
+		return
+	}
+
+	var dateRange services.DateRange
+	for _, bound := range []struct {
+		field string
+		dest  **time.Time
+	}{
+		{"createdAfter", &dateRange.CreatedAfter},
+		{"createdBefore", &dateRange.CreatedBefore},
+		{"updatedAfter", &dateRange.UpdatedAfter},
+		{"updatedBefore", &dateRange.UpdatedBefore},
+	} {
+		parsed, err := services.ParseDateBound(bound.field, c.Query(bound.field))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*bound.dest = parsed
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.DeleteMatching(c.Request.Context(), services.BulkDeleteRequest{
+		Category:        category,
+		Type:            memoryType,
+		Tags:            parseTagsQuery(c.QueryArray("tags")),
+		TagMatch:        c.Query("tagMatch"),
+		Query:           c.Query("query"),
+		DateRange:       dateRange,
+		IncludeCritical: c.Query("includeCritical") == "true",
+		DryRun:          c.Query("dryRun") == "true",
+		Confirm:         c.Query("confirm"),
+	})
+	if err != nil {
+		var confirmErr *services.BulkDeleteConfirmationError
+		if errors.As(err, &confirmErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   err.Error(),
+				"matched": confirmErr.Matched,
+				"confirm": confirmErr.Token,
+			})
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to delete memories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// basicMemoryStatsHandler - deprecated, kept for compatibility
+func (s *Server) basicMemoryStatsHandler(c *gin.Context) {
+	stats, err := s.memoryService.GetMemoryStats(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get memory stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// enhancedMemoryStatsHandler godoc
+// @Summary Get enhanced memory statistics
+// @Description Get comprehensive statistics about stored memories including search patterns and growth trends.
+// @Description Counts come from counters kept by the database; exact=true recounts the memories first.
+// @Tags memories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param exact query bool false "Recount memories instead of trusting the counters"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/stats [get]
+func (s *Server) enhancedMemoryStatsHandler(c *gin.Context) {
+	// Get user from context
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	
+	// Create user-scoped memory service
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	// Rebuild the counters on demand, in case they have drifted
+	if c.Query("exact") == "true" {
+		if _, err := userMemoryService.RecountMemories(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to recount memories")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recount memories"})
+			return
+		}
+	}
+
+	// Get basic memory stats
+	basicStats, err := userMemoryService.GetMemoryStats(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get basic memory stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory statistics"})
+		return
+	}
+	
+	// Get search statistics for this user
+	userIDPtr := &user.ID
+	searchStats, err := s.activityService.GetSearchStats(ctx, userIDPtr)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get search stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search statistics"})
+		return
+	}
+	
+	// Get memory growth stats for the last 7 days
+	growthStats, err := s.activityService.GetMemoryGrowthStats(ctx, userIDPtr)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get memory growth stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory growth statistics"})
+		return
+	}
+	
+	// Combine all statistics
+	enhancedStats := map[string]interface{}{
+		"basic_stats":    basicStats,
+		"search_stats":   searchStats,
+		"growth_stats":   growthStats,
+	}
+	
+	c.JSON(http.StatusOK, enhancedStats)
+}
+
+// userActivityStatsHandler godoc
+// @Summary Get user activity statistics
+// @Description Get comprehensive activity statistics for the authenticated user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/activity-stats [get]
+func (s *Server) userActivityStatsHandler(c *gin.Context) {
+	// Get user from context (set by auth middleware)
+	user, exists := getUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	
+	stats, err := s.activityService.GetUserActivityStats(c.Request.Context(), user.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get user activity stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user activity statistics"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// trendFrom reads the days query parameter and returns the first day a trend
+// covers; ok is false once the error response is written
+func trendFrom(c *gin.Context) (from time.Time, ok bool) {
+	days := 90
+	if daysStr := c.Query("days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil || days < 1 || days > services.MaxTrendDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(services.MaxTrendDays)})
+			return time.Time{}, false
+		}
+	}
+	return time.Now().UTC().AddDate(0, 0, -days), true
+}
+
+// userActivityTrendsHandler godoc
+// @Summary Get user activity trends
+// @Description Get the authenticated user's daily activity counts by type, oldest first. Trends come from daily rollups, so they cover days whose raw activity logs were already pruned; a day appears once it has been rolled up and days without activity are left out.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Param days query int false "Days to cover (default 90, max 730)"
+// @Success 200 {array} services.ActivityTrendDay
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/activity-trends [get]
+func (s *Server) userActivityTrendsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	from, ok := trendFrom(c)
+	if !ok {
+		return
+	}
+
+	trend, err := s.activityService.ActivityTrend(c.Request.Context(), &user.ID, from)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get user activity trends")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activity trends"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
+// EvictionPolicyRequest sets the user's eviction policy; an empty policy reverts to the server default
+type EvictionPolicyRequest struct {
+	Policy string `json:"policy" example:"least_accessed"`
+}
+
+// EvictionPolicyResponse reports the eviction policy in effect for the user
+type EvictionPolicyResponse struct {
+	Policy      string `json:"policy" example:"oldest_first"`
+	MemoryLimit int    `json:"memory_limit" example:"1000"`
+}
+
+// getEvictionPolicyHandler godoc
+// @Summary Get eviction policy
+// @Description Get the policy applied when the user reaches the memory limit
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} EvictionPolicyResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /users/eviction-policy [get]
+func (s *Server) getEvictionPolicyHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	c.JSON(http.StatusOK, EvictionPolicyResponse{
+		Policy:      userMemoryService.EvictionPolicy(c.Request.Context()),
+		MemoryLimit: userMemoryService.MemoryLimit(c.Request.Context()),
+	})
+}
+
+// setEvictionPolicyHandler godoc
+// @Summary Set eviction policy
+// @Description Choose what happens at the memory limit: oldest_first, least_accessed, least_recently_used, lowest_priority or reject_new
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body EvictionPolicyRequest true "Eviction policy"
+// @Success 200 {object} EvictionPolicyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/eviction-policy [put]
+func (s *Server) setEvictionPolicyHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req EvictionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	if err := userMemoryService.SetEvictionPolicy(c.Request.Context(), req.Policy); err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to set eviction policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set eviction policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, EvictionPolicyResponse{
+		Policy:      userMemoryService.EvictionPolicy(c.Request.Context()),
+		MemoryLimit: userMemoryService.MemoryLimit(c.Request.Context()),
+	})
+}
+
+// systemPerformanceStatsHandler godoc
+// @Summary Get system performance statistics
+// @Description Get system-wide performance metrics and health indicators, including rate limiting counters
+// @Tags system
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/performance [get]
+func (s *Server) systemPerformanceStatsHandler(c *gin.Context) {
+	stats, err := s.activityService.GetPerformanceStats(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get system performance stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get system performance statistics"})
+		return
+	}
+	stats["rate_limit"] = s.rateLimitStats()
+	
+	c.JSON(http.StatusOK, stats)
+}
+
+// systemPerformanceTrendsHandler godoc
+// @Summary Get system performance trends
+// @Description Get daily request counts, 5xx errors and response times, oldest first. Trends come from daily rollups, so they cover days whose raw performance metrics were already pruned.
+// @Tags system
+// @Produce json
+// @Security ApiKeyAuth
+// @Param days query int false "Days to cover (default 90, max 730)"
+// @Success 200 {array} services.PerformanceTrendDay
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/performance/trends [get]
+func (s *Server) systemPerformanceTrendsHandler(c *gin.Context) {
+	from, ok := trendFrom(c)
+	if !ok {
+		return
+	}
+
+	trend, err := s.activityService.PerformanceTrend(c.Request.Context(), from)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get system performance trends")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get system performance trends"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
+// IncognitoRequest starts incognito mode; duration is a Go duration such as "30m"
+type IncognitoRequest struct {
+	Duration string `json:"duration" example:"30m"`
+}
+
+// getIncognitoHandler godoc
+// @Summary Get incognito status
+// @Description Report whether incognito mode is on and when it ends
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.IncognitoStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/incognito [get]
+func (s *Server) getIncognitoHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	status, err := userMemoryService.IncognitoStatus(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get incognito status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incognito status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// startIncognitoHandler godoc
+// @Summary Start incognito mode
+// @Description Stop remembering for a while. Nothing is stored or auto-detected until the duration (default 1h, at most 24h) ends or incognito mode is stopped.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body IncognitoRequest false "Incognito duration"
+// @Success 200 {object} services.IncognitoStatus
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/incognito [post]
+func (s *Server) startIncognitoHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req IncognitoRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	duration, err := mcp.ParseIncognitoDuration(req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	status, err := userMemoryService.StartIncognito(c.Request.Context(), duration)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to start incognito mode")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start incognito mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// stopIncognitoHandler godoc
+// @Summary Stop incognito mode
+// @Description Resume remembering immediately
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.IncognitoStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/incognito [delete]
+func (s *Server) stopIncognitoHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	status, err := userMemoryService.StopIncognito(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to stop incognito mode")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop incognito mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// OpenAIKeyRequest sets the user's own OpenAI API key
+type OpenAIKeyRequest struct {
+	APIKey string `json:"api_key" binding:"required" example:"sk-proj-..."`
+}
+
+// getOpenAIKeyHandler godoc
+// @Summary Get OpenAI key status
+// @Description Report whether the user has their own OpenAI API key. The key itself is never returned.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.OpenAIKeyStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/openai-key [get]
+func (s *Server) getOpenAIKeyHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	status, err := userMemoryService.OpenAIKeyStatus(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get OpenAI key status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get OpenAI key status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// setOpenAIKeyHandler godoc
+// @Summary Set OpenAI key
+// @Description Use your own OpenAI API key for your embeddings. The key is checked with OpenAI, stored encrypted, and its usage is not counted against the server's budgets.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body OpenAIKeyRequest true "OpenAI API key"
+// @Success 200 {object} services.OpenAIKeyStatus
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/openai-key [put]
+func (s *Server) setOpenAIKeyHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req OpenAIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	status, err := userMemoryService.SetOpenAIKey(c.Request.Context(), req.APIKey)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to set OpenAI key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set OpenAI key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// deleteOpenAIKeyHandler godoc
+// @Summary Remove OpenAI key
+// @Description Remove the user's own OpenAI API key and go back to the server's key
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.OpenAIKeyStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/openai-key [delete]
+func (s *Server) deleteOpenAIKeyHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	if err := userMemoryService.DeleteOpenAIKey(c.Request.Context()); err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to remove OpenAI key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove OpenAI key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, &services.OpenAIKeyStatus{})
+}
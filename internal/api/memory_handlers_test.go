@@ -134,9 +134,11 @@ func TestMemoryEndpoints(t *testing.T) {
 		var response map[string]interface{}
 		err := json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Contains(t, response, "total_count")
-		assert.Contains(t, response, "by_type")
-		assert.Contains(t, response, "by_category")
+		basicStats, ok := response["basic_stats"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, float64(1), basicStats["total_count"])
+		assert.Contains(t, basicStats, "by_type")
+		assert.Contains(t, basicStats, "by_category")
 	})
 
 	t.Run("delete memory", func(t *testing.T) {
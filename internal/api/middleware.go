@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
 )
 
 const (
@@ -14,23 +18,56 @@ const (
 	authTypeAPIKey = "apikey"
 	userContextKey = "user"
 	authTypeKey    = "auth_type"
+	apiKeyKey      = "api_key"
+	// roleClaimKey holds the role claim of a bearer token
+	roleClaimKey = "role_claim"
+	// tokenIDKey and tokenExpiryKey hold the jti and expiry of a bearer token, so
+	// logout can revoke it
+	tokenIDKey     = "token_id"
+	tokenExpiryKey = "token_expiry"
+	// workspaceContextKey holds the workspace the request runs in; see
+	// workspaceMiddleware
+	workspaceContextKey = "workspace_id"
 )
 
+// restrictedKeyRoutes are the only routes backup API keys may call, with the
+// permission each requires. Everything else, including the MCP endpoint and key
+// management, is denied to them.
+var restrictedKeyRoutes = map[string]string{
+	"GET /api/v1/memories/export": models.PermissionMemoryExport,
+	"GET /api/v1/memories/stats":  models.PermissionMemoryStats,
+}
+
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check for API Key in header
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey != "" {
 			apiKeyObj, err := s.authService.ValidateAPIKey(apiKey)
+			if errors.Is(err, ErrUserDisabled) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+				c.Abort()
+				return
+			}
 			if err != nil {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 				c.Abort()
 				return
 			}
-			
+
+			if detector := s.activityService.AnomalyDetector(); detector != nil {
+				go detector.ObserveAPIKeyUse(context.Background(), apiKeyObj, c.ClientIP(), time.Now())
+			}
+
 			c.Set(userContextKey, &apiKeyObj.User)
 			c.Set(authTypeKey, authTypeAPIKey)
-			c.Set("api_key", apiKeyObj)
+			c.Set(apiKeyKey, apiKeyObj)
+
+			if !apiKeyAllowed(apiKeyObj, c.Request.Method, c.FullPath()) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key is not permitted to use this endpoint"})
+				c.Abort()
+				return
+			}
 			c.Next()
 			return
 		}
@@ -72,6 +109,22 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 				return
 			}
 
+			tokenID, _ := claims["jti"].(string)
+			if tokenID != "" {
+				revoked, err := s.authService.IsAccessTokenRevoked(tokenID)
+				if err != nil {
+					s.logger.Error().Err(err).Msg("Failed to check token revocation")
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+					c.Abort()
+					return
+				}
+				if revoked {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
+					c.Abort()
+					return
+				}
+			}
+
 			// Get user from database
 			var user models.User
 			if err := s.db.DB().First(&user, uint(userID)).Error; err != nil {
@@ -80,8 +133,29 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 				return
 			}
 
+			if user.IsDisabled() {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+				c.Abort()
+				return
+			}
+
+			// Tokens issued before a password change or disable are no longer valid
+			if version, _ := claims["ver"].(float64); int(version) != user.TokenVersion {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
+				c.Abort()
+				return
+			}
+
+			role, _ := claims["role"].(string)
 			c.Set(userContextKey, &user)
 			c.Set(authTypeKey, authTypeBearer)
+			c.Set(roleClaimKey, role)
+			if tokenID != "" {
+				c.Set(tokenIDKey, tokenID)
+				if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+					c.Set(tokenExpiryKey, exp.Time)
+				}
+			}
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -91,12 +165,112 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// apiKeyAllowed reports whether the key may call the route. Standard keys may call
+// every route their user can; backup keys only those in restrictedKeyRoutes. Keys
+// of any other type, including ones whose type was never recorded, are refused.
+func apiKeyAllowed(apiKey *models.APIKey, method, route string) bool {
+	switch apiKey.KeyType {
+	case models.APIKeyTypeStandard:
+		return true
+	case models.APIKeyTypeBackup:
+		permission, ok := restrictedKeyRoutes[method+" "+route]
+		return ok && apiKey.HasPermission(permission)
+	default:
+		return false
+	}
+}
+
+// adminMiddleware restricts a route group to users with the admin role signed in
+// with a bearer token. API keys are refused, so a key an admin made for their own
+// memories is not also an admin credential. The token must carry the admin role
+// claim, so one issued before the user became an admin doesn't grant admin
+// access, and the stored role is checked too so one issued before they stopped
+// being an admin doesn't either. It must run after authMiddleware.
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if getAuthType(c) != authTypeBearer {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin endpoints require a bearer token; API keys are not accepted"})
+			c.Abort()
+			return
+		}
+		user, exists := getUserFromContext(c)
+		if !exists || user == nil || !user.IsAdmin() || c.GetString(roleClaimKey) != models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// connectionGrace is how long a connection stays open past its request's
+// deadline, so the handler can still write the timeout error
+var connectionGrace = 5 * time.Second
+
+// connectionTimeout is the read and write timeout of a connection whose request
+// has the given deadline; zero leaves it unbounded
+func connectionTimeout(deadline time.Duration) time.Duration {
+	if deadline <= 0 {
+		return 0
+	}
+	return deadline + connectionGrace
+}
+
+// timeoutMiddleware gives each request the deadline configured for its route in
+// timeouts, so handlers and the queries they run stop once it passes. Routes
+// allowed more or less time than the default also have their connection's read
+// and write deadlines moved to match, since the server sets them for the default.
+func (s *Server) timeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() != "" {
+			s.setConnectionDeadline(c)
+		}
+		timeout := s.config.Timeouts.Route(c.Request.Method, c.FullPath())
+		if timeout <= 0 || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// setConnectionDeadline moves the connection deadlines of a request whose route
+// is allowed a different time than the server's default
+func (s *Server) setConnectionDeadline(c *gin.Context) {
+	timeout := s.config.Timeouts.Connection(c.Request.Method, c.FullPath())
+	if timeout == s.config.Timeouts.Default {
+		return
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(connectionTimeout(timeout))
+	}
+	controller := http.NewResponseController(c.Writer)
+	for _, set := range []func(time.Time) error{controller.SetReadDeadline, controller.SetWriteDeadline} {
+		if err := set(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			s.logger.Warn().Err(err).Str("route", c.FullPath()).Msg("Failed to set connection deadline")
+		}
+	}
+}
+
+// accessSourceMiddleware marks the memory access a request makes as the HTTP
+// API's in the access audit log; the MCP endpoint marks its own
+func accessSourceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(services.WithAccessSource(c.Request.Context(), services.AccessSourceHTTP))
+		c.Next()
+	}
+}
+
 func getUserFromContext(c *gin.Context) (*models.User, bool) {
 	user, exists := c.Get(userContextKey)
 	if !exists {
 		return nil, false
 	}
-	
+
 	u, ok := user.(*models.User)
 	return u, ok
 }
@@ -105,4 +279,4 @@ func getAuthType(c *gin.Context) string {
 	authType, _ := c.Get(authTypeKey)
 	t, _ := authType.(string)
 	return t
-}
\ No newline at end of file
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestTimeoutMiddleware_ConnectionDeadlines(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	grace := connectionGrace
+	connectionGrace = 0
+	t.Cleanup(func() { connectionGrace = grace })
+
+	server.config.Timeouts = config.Timeouts{
+		Default: 200 * time.Millisecond,
+		Routes:  map[string]time.Duration{"GET /slow-export": 2 * time.Second},
+	}
+	slow := func(c *gin.Context) {
+		time.Sleep(500 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	}
+	server.router.GET("/slow-export", slow)
+	server.router.GET("/slow-default", slow)
+
+	httpServer := server.newHTTPServer("")
+	assert.Equal(t, 200*time.Millisecond, httpServer.WriteTimeout)
+	listener := httptest.NewUnstartedServer(httpServer.Handler)
+	listener.Config = httpServer
+	listener.Start()
+	defer listener.Close()
+
+	resp, err := http.Get(listener.URL + "/slow-export")
+	require.NoError(t, err, "a route allowed longer than the default keeps its connection")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = http.Get(listener.URL + "/slow-default")
+	assert.Error(t, err, "other routes are cut off at the default")
+}
+
+func TestAPIKeyAllowed(t *testing.T) {
+	key := func(keyType string) *models.APIKey {
+		apiKey := &models.APIKey{KeyType: keyType}
+		apiKey.SetPermissions(models.APIKeyTypePermissions[models.APIKeyTypeBackup])
+		return apiKey
+	}
+
+	assert.True(t, apiKeyAllowed(key(models.APIKeyTypeStandard), http.MethodDelete, "/api/v1/memories/:id"))
+
+	backup := key(models.APIKeyTypeBackup)
+	assert.True(t, apiKeyAllowed(backup, http.MethodGet, "/api/v1/memories/export"))
+	assert.True(t, apiKeyAllowed(backup, http.MethodGet, "/api/v1/memories/stats"))
+	assert.False(t, apiKeyAllowed(backup, http.MethodGet, "/api/v1/memories"))
+	assert.False(t, apiKeyAllowed(backup, http.MethodPost, "/mcp"))
+
+	for _, keyType := range []string{"", "admin", "Standard"} {
+		assert.False(t, apiKeyAllowed(key(keyType), http.MethodGet, "/api/v1/memories/export"), "type %q is refused", keyType)
+	}
+}
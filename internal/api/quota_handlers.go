@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// QuotaExceededResponse is returned with 429 when a store would take the user
+// over their memory limit
+type QuotaExceededResponse struct {
+	Error string `json:"error" example:"memory limit of 1000 reached and the eviction policy is reject_new: delete memories before storing new ones"`
+	Code  string `json:"code" example:"quota_exceeded"`
+	Limit int    `json:"limit,omitempty" example:"1000"`
+}
+
+// respondQuotaExceeded answers 429 if err is a memory limit error, reporting
+// whether it did
+func respondQuotaExceeded(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrMemoryLimitReached) {
+		return false
+	}
+
+	response := QuotaExceededResponse{Error: err.Error(), Code: "quota_exceeded"}
+	var limitErr *services.MemoryLimitError
+	if errors.As(err, &limitErr) {
+		response.Limit = limitErr.Limit
+	}
+	c.JSON(http.StatusTooManyRequests, response)
+	return true
+}
+
+// getQuotaHandler godoc
+// @Summary Get memory quota
+// @Description Get the user's memory usage against their limit. The limit is the one an admin set for the user,
+// @Description else their plan's, else the server default; 0 means unlimited.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.QuotaUsage
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/quota [get]
+func (s *Server) getQuotaHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	quota, err := s.workspaceMemoryService(c, user.ID).Quota(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get quota")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get quota"})
+		return
+	}
+	quota.RefreshWarning()
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// SetQuotaRequest assigns a user a plan and optionally a memory limit of their own
+type SetQuotaRequest struct {
+	// Plan is one of the configured memory.plans; empty uses the server default
+	Plan string `json:"plan" example:"pro"`
+	// MemoryLimit overrides the plan's limit, 0 meaning unlimited; null uses the plan's
+	MemoryLimit *int `json:"memory_limit" example:"5000"`
+}
+
+// adminSetQuotaHandler godoc
+// @Summary Set a user's memory quota
+// @Description Assign a user a plan from memory.plans and optionally a memory limit of their own, which takes
+// @Description precedence over the plan's. Memories over a lowered limit are evicted on the user's next store.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body SetQuotaRequest true "Plan and limit"
+// @Success 200 {object} services.QuotaUsage
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/quota [put]
+func (s *Server) adminSetQuotaHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SetQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.createScopedMemoryService(id)
+	if err := userMemoryService.SetQuota(c.Request.Context(), req.Plan, req.MemoryLimit); err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", id).Msg("Failed to set quota")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set quota"})
+		return
+	}
+	s.logger.Info().Uint("admin_id", admin.ID).Uint("user_id", id).Str("plan", req.Plan).Msg("User quota changed")
+
+	quota, err := userMemoryService.Quota(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", id).Msg("Failed to get quota")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get quota"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
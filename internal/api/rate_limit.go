@@ -0,0 +1,197 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled are forgotten
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitStats counts the requests a limiter has allowed and refused
+type RateLimitStats struct {
+	Rate    float64 `json:"rate"`
+	Burst   int     `json:"burst"`
+	Allowed int64   `json:"allowed"`
+	Limited int64   `json:"limited"`
+	// Clients is how many API keys or IPs currently have a partly used bucket
+	Clients int `json:"clients"`
+}
+
+// tokenBucket holds a client's remaining tokens as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token-bucket limiter keyed by client. Each client's bucket
+// holds up to burst tokens and refills at rate tokens per second.
+type rateLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	allowed   int64
+	limited   int64
+	now       func() time.Time
+}
+
+// newRateLimiter creates a limiter, or returns nil when rate is 0
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(float64(l.burst), bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		l.allowed++
+		return true, 0
+	}
+	l.limited++
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep forgets buckets that have had time to refill, which behave exactly like
+// new ones. Callers hold l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// stats returns the limiter's counters
+func (l *rateLimiter) stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimitStats{
+		Rate:    l.rate,
+		Burst:   l.burst,
+		Allowed: l.allowed,
+		Limited: l.limited,
+		Clients: len(l.buckets),
+	}
+}
+
+// newRateLimiters creates the per-IP and per-API-key limiters; either is nil when
+// its limit is off
+func newRateLimiters(cfg config.RateLimit) (ip, apiKey *rateLimiter) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return newRateLimiter(cfg.IPRate, cfg.IPBurst), newRateLimiter(cfg.APIKeyRate, cfg.APIKeyBurst)
+}
+
+// ipRateLimitMiddleware limits requests per client IP. It runs before
+// authentication so unauthenticated clients are limited too; health checks are
+// not limited.
+func (s *Server) ipRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.ipLimiter == nil || c.Request.URL.Path == "/health" {
+			c.Next()
+			return
+		}
+		if ok, wait := s.ipLimiter.allow(c.ClientIP()); !ok {
+			s.rejectRateLimited(c, wait, "ip")
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKeyRateLimitMiddleware limits requests per API key. It runs after
+// authentication; requests authenticated with a bearer token are only limited by IP.
+func (s *Server) apiKeyRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.apiKeyLimiter == nil || getAuthType(c) != authTypeAPIKey {
+			c.Next()
+			return
+		}
+		apiKey, exists := c.Get(apiKeyKey)
+		if !exists {
+			c.Next()
+			return
+		}
+		key := strconv.FormatUint(uint64(apiKey.(*models.APIKey).ID), 10)
+		if ok, wait := s.apiKeyLimiter.allow(key); !ok {
+			s.rejectRateLimited(c, wait, "api_key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rejectRateLimited answers 429 with a Retry-After header in whole seconds
+func (s *Server) rejectRateLimited(c *gin.Context, wait time.Duration, scope string) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	s.logger.Debug().
+		Str("scope", scope).
+		Str("ip", c.ClientIP()).
+		Str("path", c.Request.URL.Path).
+		Int("retry_after", retryAfter).
+		Msg("Rate limit exceeded")
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Rate limit exceeded",
+		"retry_after": retryAfter,
+	})
+}
+
+// rateLimitStats reports the limiters' counters for the performance stats
+func (s *Server) rateLimitStats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"enabled": s.ipLimiter != nil || s.apiKeyLimiter != nil,
+	}
+	if s.ipLimiter != nil {
+		stats["ip"] = s.ipLimiter.stats()
+	}
+	if s.apiKeyLimiter != nil {
+		stats["api_key"] = s.apiKeyLimiter.stats()
+	}
+	return stats
+}
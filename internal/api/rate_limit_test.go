@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.allow("client")
+		require.True(t, ok, "request %d is within the burst", i)
+	}
+	ok, wait := limiter.allow("client")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	ok, _ = limiter.allow("other")
+	assert.True(t, ok, "clients have their own buckets")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("client")
+	assert.True(t, ok, "the bucket refills at the rate")
+	ok, _ = limiter.allow("client")
+	assert.False(t, ok)
+
+	stats := limiter.stats()
+	assert.Equal(t, int64(5), stats.Allowed)
+	assert.Equal(t, int64(2), stats.Limited)
+	assert.Equal(t, 2, stats.Clients)
+
+	// Buckets that have refilled are forgotten
+	now = now.Add(rateLimitSweepInterval)
+	ok, _ = limiter.allow("client")
+	assert.True(t, ok)
+	assert.Equal(t, 1, limiter.stats().Clients)
+
+	assert.Nil(t, newRateLimiter(0, 10), "a rate of 0 turns the limit off")
+}
+
+func TestIPRateLimitMiddleware(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.ipLimiter = newRateLimiter(1, 2)
+
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/memories", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("forwarded addresses are ignored by default", func(t *testing.T) {
+		for i, forwardedFor := range []string{"198.51.100.1", "198.51.100.2"} {
+			assert.Equal(t, http.StatusUnauthorized, request("203.0.113.5:4000", forwardedFor).Code, "request %d", i)
+		}
+		rec := request("203.0.113.5:4000", "198.51.100.3")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code, "rotating X-Forwarded-For does not get a new bucket")
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("trusted proxies give the client address", func(t *testing.T) {
+		require.NoError(t, server.router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusUnauthorized, request("10.1.2.3:4000", "198.51.100.20").Code, "request %d", i)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, request("10.1.2.3:4000", "198.51.100.20").Code)
+		assert.Equal(t, http.StatusUnauthorized, request("10.1.2.3:4000", "198.51.100.21").Code, "another client behind the proxy")
+	})
+
+	t.Run("health checks are not limited", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = "203.0.113.5:4000"
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, req)
+			assert.NotEqual(t, http.StatusTooManyRequests, rec.Code)
+		}
+	})
+}
+
+func TestAPIKeyRateLimitMiddleware(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.apiKeyLimiter = newRateLimiter(1, 1)
+
+	user, err := server.authService.RegisterUser("test@example.com", "password123")
+	require.NoError(t, err)
+	first, err := server.authService.GenerateAPIKey(user.ID, "first", nil)
+	require.NoError(t, err)
+	second, err := server.authService.GenerateAPIKey(user.ID, "second", nil)
+	require.NoError(t, err)
+
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/keys", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, request(first.Key))
+	assert.Equal(t, http.StatusTooManyRequests, request(first.Key))
+	assert.Equal(t, http.StatusOK, request(second.Key), "each key has its own bucket")
+}
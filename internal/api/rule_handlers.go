@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// listRulesHandler godoc
+// @Summary List categorization rules
+// @Description Get the authenticated user's categorization rules
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.CategorizationRule
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rules [get]
+func (s *Server) listRulesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	rules, err := userMemoryService.ListCategorizationRules(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list categorization rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list categorization rules"})
+		return
+	}
+
+	if rules == nil {
+		rules = []models.CategorizationRule{}
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// saveRuleHandler godoc
+// @Summary Save a categorization rule
+// @Description Create a rule filing memories that mention a phrase under a category and tags, or replace an existing one with the same name. New memories are filed by enabled rules as they are stored; use /rules/apply for existing ones.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body services.CategorizationRuleSpec true "Categorization rule"
+// @Success 200 {object} models.CategorizationRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rules [post]
+func (s *Server) saveRuleHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req services.CategorizationRuleSpec
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	rule, err := userMemoryService.SaveCategorizationRule(c.Request.Context(), req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to save categorization rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save categorization rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// deleteRuleHandler godoc
+// @Summary Delete a categorization rule
+// @Description Delete a categorization rule by ID. Memories it already changed keep their category and tags.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Rule ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rules/{id} [delete]
+func (s *Server) deleteRuleHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	if err := userMemoryService.DeleteCategorizationRule(c.Request.Context(), uint(id)); err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "categorization rule not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to delete categorization rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete categorization rule"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// applyRulesHandler godoc
+// @Summary Apply categorization rules to existing memories
+// @Description Run every enabled rule, or the one given by rule_id, over the user's existing memories and update those whose category or tags change. With preview set nothing is changed and the response lists what would be.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body services.ApplyRulesRequest false "Rules to apply"
+// @Success 200 {object} services.ApplyRulesResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rules/apply [post]
+func (s *Server) applyRulesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req services.ApplyRulesRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.ApplyCategorizationRules(c.Request.Context(), req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "categorization rule not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to apply categorization rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply categorization rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,6 +17,7 @@ import (
 	"github.com/rs/zerolog"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Server struct {
@@ -23,15 +27,33 @@ type Server struct {
 	memoryService  *services.MemoryService
 	authService    *AuthService
 	activityService *services.ActivityService
+	supportService *services.SupportService
 	logger         zerolog.Logger
 	httpServer     *http.Server
+	ipLimiter      *rateLimiter
+	apiKeyLimiter  *rateLimiter
+	// mcpClients holds the client profile each user's MCP client matched when it
+	// last initialized, by user ID
+	mcpClients sync.Map
+	// mcpWorkspaces holds the workspace each user's MCP client selected when it
+	// last initialized, by user ID
+	mcpWorkspaces sync.Map
 }
 
 func NewServer(cfg *config.Config, db *database.Database, memoryService *services.MemoryService, activityService *services.ActivityService, logger zerolog.Logger) (*Server, error) {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
+	// Only proxies we run may say who the client is; anyone else could rotate
+	// X-Forwarded-For to dodge per-IP rate limits and anomaly checks
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 	router.Use(gin.Recovery())
+	if cfg.Tracing.Enabled {
+		// Spans are named by route and continue traces started by the caller
+		router.Use(otelgin.Middleware("remember-me-http"))
+	}
 	router.Use(LoggerMiddleware(logger))
 	
 	// Configure CORS
@@ -43,7 +65,7 @@ func NewServer(cfg *config.Config, db *database.Database, memoryService *service
 		corsConfig.AllowOrigins = []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:5174", "http://127.0.0.1:3000", "http://127.0.0.1:5173", "http://127.0.0.1:5174"}
 	}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Requested-With"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Requested-With", supportTokenHeader}
 	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Type"}
 	corsConfig.AllowCredentials = true
 	corsConfig.MaxAge = 12 * time.Hour
@@ -51,6 +73,8 @@ func NewServer(cfg *config.Config, db *database.Database, memoryService *service
 	router.Use(cors.New(corsConfig))
 
 	authService := NewAuthService(db, logger)
+	supportService := services.NewSupportService(db.DB(), memoryService.GetEncryptionService(), logger)
+	ipLimiter, apiKeyLimiter := newRateLimiters(cfg.HTTP.RateLimit)
 
 	server := &Server{
 		router:         router,
@@ -59,11 +83,17 @@ func NewServer(cfg *config.Config, db *database.Database, memoryService *service
 		memoryService:  memoryService,
 		authService:    authService,
 		activityService: activityService,
+		supportService: supportService,
 		logger:         logger,
+		ipLimiter:      ipLimiter,
+		apiKeyLimiter:  apiKeyLimiter,
 	}
 
 	// Add performance tracking middleware
 	router.Use(server.PerformanceMiddleware())
+	router.Use(server.ipRateLimitMiddleware())
+	router.Use(server.timeoutMiddleware())
+	router.Use(accessSourceMiddleware())
 
 	server.setupRoutes()
 
@@ -85,11 +115,13 @@ func (s *Server) setupRoutes() {
 		{
 			auth.POST("/register", s.registerHandler)
 			auth.POST("/login", s.loginHandler)
+			auth.POST("/refresh", s.refreshHandler)
+			auth.POST("/logout", s.authMiddleware(), s.logoutHandler)
 		}
 
 		// Protected endpoints
 		protected := v1.Group("")
-		protected.Use(s.authMiddleware())
+		protected.Use(s.authMiddleware(), s.apiKeyRateLimitMiddleware(), s.workspaceMiddleware())
 		{
 			// API Key management
 			keys := protected.Group("/keys")
@@ -104,20 +136,150 @@ func (s *Server) setupRoutes() {
 			{
 				memories.POST("", s.storeMemoryHandler)
 				memories.GET("", s.searchMemoriesHandler)
+				memories.PATCH("", s.updateMemoriesHandler)
+				memories.DELETE("", s.deleteMemoriesHandler)
 				memories.DELETE("/:id", s.deleteMemoryHandler)
 				memories.GET("/stats", s.enhancedMemoryStatsHandler)
+				memories.GET("/recent", s.recallRecentHandler)
+				memories.GET("/:id/provenance", s.memoryProvenanceHandler)
+				memories.GET("/:id/history", s.memoryHistoryHandler)
+				memories.GET("/:id/access-log", s.memoryAccessLogHandler)
+				memories.GET("/:id/timeline", s.memoryTimelineHandler)
+				memories.GET("/:id/neighbors", s.memoryNeighborsHandler)
+				memories.GET("/:id/attachments", s.listAttachmentsHandler)
+				memories.POST("/:id/attachments", s.addAttachmentHandler)
+
+				// Capture memories from a conversation transcript
+				memories.POST("/process", s.processContentHandler)
+
+				// Merge related memories into summaries
+				memories.POST("/consolidate", s.consolidateMemoriesHandler)
+
+				// Portable archives for moving memories between servers
+				memories.GET("/export", s.exportMemoriesHandler)
+				memories.POST("/import", s.importMemoriesHandler)
+
+				// Point-in-time snapshots
+				memories.POST("/snapshots", s.createSnapshotHandler)
+				memories.GET("/snapshots", s.listSnapshotsHandler)
+				memories.POST("/snapshots/:id/restore", s.restoreSnapshotHandler)
+				memories.DELETE("/snapshots/:id", s.deleteSnapshotHandler)
+			}
+
+			// Quick capture for editor and IDE plugins
+			protected.POST("/capture", s.captureHandler)
+			protected.POST("/capture/voice", s.voiceMemoHandler)
+			protected.GET("/attachments/:id", s.getAttachmentHandler)
+			protected.DELETE("/attachments/:id", s.deleteAttachmentHandler)
+
+			// Short-term conversation context buffer
+			protected.POST("/context", s.appendContextHandler)
+			protected.GET("/context", s.getContextHandler)
+
+			// Separate spaces of memories within the account
+			workspaces := protected.Group("/workspaces")
+			{
+				workspaces.GET("", s.listWorkspacesHandler)
+				workspaces.POST("", s.createWorkspaceHandler)
+				workspaces.DELETE("/:name", s.deleteWorkspaceHandler)
 			}
 
-			// User activity statistics
+			// Working sessions
+			sessions := protected.Group("/sessions")
+			{
+				sessions.GET("", s.listSessionsHandler)
+				sessions.POST("", s.startSessionHandler)
+				sessions.POST("/end", s.endSessionHandler)
+				sessions.DELETE("/:id/memories", s.purgeSessionHandler)
+			}
+
+			// Saved searches
+			searches := protected.Group("/searches")
+			{
+				searches.GET("", s.listSavedSearchesHandler)
+				searches.POST("", s.saveSearchHandler)
+				searches.GET("/:id/run", s.runSavedSearchHandler)
+				searches.DELETE("/:id", s.deleteSavedSearchHandler)
+			}
+
+			// Categorization rules
+			rules := protected.Group("/rules")
+			{
+				rules.GET("", s.listRulesHandler)
+				rules.POST("", s.saveRuleHandler)
+				rules.POST("/apply", s.applyRulesHandler)
+				rules.DELETE("/:id", s.deleteRuleHandler)
+			}
+
+			// Maintenance agent reports and their review
+			maintenance := protected.Group("/maintenance")
+			{
+				maintenance.GET("/reports", s.listMaintenanceReportsHandler)
+				maintenance.POST("/reports", s.runMaintenanceHandler)
+				maintenance.GET("/reports/:id", s.getMaintenanceReportHandler)
+				maintenance.POST("/reports/:id/review", s.reviewMaintenanceReportHandler)
+			}
+
+			// Portable configuration bundles
+			configGroup := protected.Group("/config")
+			{
+				configGroup.GET("/export", s.exportConfigHandler)
+				configGroup.POST("/import", s.importConfigHandler)
+			}
+
+			// User activity statistics and preferences
 			users := protected.Group("/users")
 			{
 				users.GET("/activity-stats", s.userActivityStatsHandler)
+				users.GET("/activity-trends", s.userActivityTrendsHandler)
+				users.GET("/eviction-policy", s.getEvictionPolicyHandler)
+				users.PUT("/eviction-policy", s.setEvictionPolicyHandler)
+				users.GET("/quota", s.getQuotaHandler)
+				users.GET("/incognito", s.getIncognitoHandler)
+				users.POST("/incognito", s.startIncognitoHandler)
+				users.DELETE("/incognito", s.stopIncognitoHandler)
+				users.GET("/maintenance", s.getMaintenanceSettingsHandler)
+				users.PUT("/maintenance", s.setMaintenanceSettingsHandler)
+				users.GET("/openai-key", s.getOpenAIKeyHandler)
+				users.PUT("/openai-key", s.setOpenAIKeyHandler)
+				users.DELETE("/openai-key", s.deleteOpenAIKeyHandler)
+
+				// Account data export and deletion
+				users.GET("/me/export", s.exportAccountHandler)
+				users.DELETE("/me", s.deleteAccountHandler)
+
+				// Support access consent
+				users.POST("/support-access", s.grantSupportAccessHandler)
+				users.GET("/support-access", s.listSupportAccessHandler)
+				users.DELETE("/support-access/:id", s.revokeSupportAccessHandler)
 			}
 
 			// System performance statistics
 			system := protected.Group("/system")
 			{
 				system.GET("/performance", s.systemPerformanceStatsHandler)
+				system.GET("/performance/trends", s.systemPerformanceTrendsHandler)
+			}
+
+			// Admin-only endpoints
+			admin := protected.Group("/admin")
+			admin.Use(s.adminMiddleware())
+			{
+				admin.GET("/overview", s.adminOverviewHandler)
+				admin.GET("/memories/lookup", s.adminLookupMemoryHandler)
+				admin.GET("/alerts", s.listAlertsHandler)
+				admin.POST("/alerts/:id/acknowledge", s.acknowledgeAlertHandler)
+				admin.GET("/users", s.listUsersHandler)
+				admin.GET("/users/:id", s.getUserHandler)
+				admin.POST("/users/:id/disable", s.disableUserHandler)
+				admin.POST("/users/:id/enable", s.enableUserHandler)
+				admin.POST("/users/:id/reset-password", s.resetPasswordHandler)
+				admin.PUT("/users/:id/role", s.setUserRoleHandler)
+				admin.PUT("/users/:id/quota", s.adminSetQuotaHandler)
+				admin.GET("/users/:id/search", s.adminImpersonateSearchHandler)
+				admin.GET("/storage", s.storageReportHandler)
+				admin.GET("/vector-index", s.vectorIndexHandler)
+				admin.POST("/vector-index/rebuild", s.rebuildVectorIndexHandler)
 			}
 		}
 		
@@ -127,19 +289,28 @@ func (s *Server) setupRoutes() {
 }
 
 func (s *Server) Start(port int) error {
-	addr := fmt.Sprintf(":%d", port)
-	s.httpServer = &http.Server{
-		Addr:           addr,
-		Handler:        s.router,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
-	}
+	addr := net.JoinHostPort(s.config.HTTP.Host, strconv.Itoa(port))
+	s.httpServer = s.newHTTPServer(addr)
 
 	s.logger.Info().Str("address", addr).Msg("Starting HTTP server")
 	return s.httpServer.ListenAndServe()
 }
 
+// newHTTPServer returns the server listening on addr. Connections are bounded by
+// the default route deadline; timeoutMiddleware extends them for routes that are
+// allowed longer.
+func (s *Server) newHTTPServer(addr string) *http.Server {
+	timeout := connectionTimeout(s.config.Timeouts.Default)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout,
+		MaxHeaderBytes:    1 << 20,
+	}
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.httpServer == nil {
 		return nil
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// StartSessionRequest names a working session
+type StartSessionRequest struct {
+	SessionID string `json:"session_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// EndSessionRequest says whether to delete the session's memories when ending it
+type EndSessionRequest struct {
+	Purge bool `json:"purge,omitempty"`
+}
+
+// listSessionsHandler godoc
+// @Summary List working sessions
+// @Description List the user's working sessions, most recently started first, with how many memories each captured
+// @Tags sessions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum sessions to return (default and max 50)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions [get]
+func (s *Server) listSessionsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	sessions, err := userMemoryService.ListSessions(c.Request.Context(), limit)
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to list sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	active, err := userMemoryService.ActiveSession(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to get active session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "active": active})
+}
+
+// startSessionHandler godoc
+// @Summary Start a working session
+// @Description Open a working session, ending any session still open. Memories stored until it ends are captured in it.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body StartSessionRequest false "Session ID (default: generated) and name"
+// @Success 201 {object} models.MemorySession
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "A session with this ID already exists"
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions [post]
+func (s *Server) startSessionHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req StartSessionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	session, err := userMemoryService.StartSession(c.Request.Context(), req.SessionID, req.Name)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsConflictError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to start session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// endSessionHandler godoc
+// @Summary End the working session
+// @Description End the open working session. With purge its memories are deleted too, apart from critical ones.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body EndSessionRequest false "Whether to purge the session's memories"
+// @Success 200 {object} services.EndSessionResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "No session is open"
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/end [post]
+func (s *Server) endSessionHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req EndSessionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.EndSession(c.Request.Context(), req.Purge)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No session is open"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to end session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// purgeSessionHandler godoc
+// @Summary Delete a session's memories
+// @Description Delete the memories captured in a working session (current for the open one). Critical memories are kept and counted.
+// @Tags sessions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} services.SessionPurge
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "No session is open"
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/{id}/memories [delete]
+func (s *Server) purgeSessionHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	purge, err := userMemoryService.PurgeSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No session is open"})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to purge session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, purge)
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+type CreateSnapshotRequest struct {
+	Name        string `json:"name" binding:"required" example:"before-import"`
+	Description string `json:"description,omitempty" example:"State before importing notes from Obsidian"`
+}
+
+type RestoreSnapshotResponse struct {
+	Success  bool                   `json:"success"`
+	Snapshot *models.MemorySnapshot `json:"snapshot"`
+	Restored int                    `json:"restored"`
+}
+
+// createSnapshotHandler godoc
+// @Summary Create a memory snapshot
+// @Description Save a named point-in-time copy of all memories for later restore
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateSnapshotRequest true "Snapshot details"
+// @Success 201 {object} models.MemorySnapshot
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/snapshots [post]
+func (s *Server) createSnapshotHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	snapshot, err := userMemoryService.CreateSnapshot(c.Request.Context(), req.Name, req.Description)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to create snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create snapshot"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"snapshot_id":  snapshot.ID,
+		"name":         snapshot.Name,
+		"memory_count": snapshot.MemoryCount,
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivitySnapshotCreated, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// listSnapshotsHandler godoc
+// @Summary List memory snapshots
+// @Description Get all snapshots for the authenticated user, newest first
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.MemorySnapshot
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/snapshots [get]
+func (s *Server) listSnapshotsHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	snapshots, err := userMemoryService.ListSnapshots(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list snapshots")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list snapshots"})
+		return
+	}
+
+	if snapshots == nil {
+		snapshots = []models.MemorySnapshot{}
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// restoreSnapshotHandler godoc
+// @Summary Restore a memory snapshot
+// @Description Replace all current memories with the contents of a snapshot
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Snapshot ID"
+// @Param allow_cross_region query bool false "Allow restoring a snapshot taken in a different residency region"
+// @Success 200 {object} RestoreSnapshotResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/snapshots/{id}/restore [post]
+func (s *Server) restoreSnapshotHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	allowCrossRegion := c.Query("allow_cross_region") == "true"
+
+	snapshot, err := userMemoryService.RestoreSnapshot(c.Request.Context(), uint(id), allowCrossRegion)
+	if err != nil {
+		var notFoundErr *utils.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "snapshot not found"})
+			return
+		}
+		if errors.Is(err, services.ErrCrossRegion) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to restore snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore snapshot"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"snapshot_id":  snapshot.ID,
+		"name":         snapshot.Name,
+		"memory_count": snapshot.MemoryCount,
+		"region":       snapshot.Region,
+	}
+	if allowCrossRegion {
+		details["allow_cross_region"] = true
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivitySnapshotRestored, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusOK, RestoreSnapshotResponse{
+		Success:  true,
+		Snapshot: snapshot,
+		Restored: snapshot.MemoryCount,
+	})
+}
+
+// deleteSnapshotHandler godoc
+// @Summary Delete a memory snapshot
+// @Description Delete a snapshot without touching current memories
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Snapshot ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /memories/snapshots/{id} [delete]
+func (s *Server) deleteSnapshotHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	if err := userMemoryService.DeleteSnapshot(c.Request.Context(), uint(id)); err != nil {
+		var notFoundErr *utils.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "snapshot not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to delete snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete snapshot"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
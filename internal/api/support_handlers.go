@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// supportTokenHeader carries a user's support-access token on admin lookups
+const supportTokenHeader = "X-Support-Token"
+
+type GrantSupportAccessRequest struct {
+	DurationMinutes int    `json:"duration_minutes,omitempty" example:"60"`
+	Reason          string `json:"reason,omitempty" example:"Ticket #1234: missing memory"`
+}
+
+type GrantSupportAccessResponse struct {
+	Grant *models.SupportAccessGrant `json:"grant"`
+	Token string                     `json:"token"`
+}
+
+// grantSupportAccessHandler godoc
+// @Summary Grant temporary support access
+// @Description Issue a time-limited token that lets support staff view the content of your memories. The token is shown only once.
+// @Tags support
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body GrantSupportAccessRequest false "Grant details"
+// @Success 201 {object} GrantSupportAccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/support-access [post]
+func (s *Server) grantSupportAccessHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req GrantSupportAccessRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	grant, token, err := s.supportService.GrantAccess(c.Request.Context(), user.ID, duration, req.Reason)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to grant support access")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant support access"})
+		return
+	}
+
+	details := map[string]interface{}{
+		"grant_id":   grant.ID,
+		"expires_at": grant.ExpiresAt,
+		"reason":     grant.Reason,
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivitySupportAccessGranted, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusCreated, GrantSupportAccessResponse{
+		Grant: grant,
+		Token: token,
+	})
+}
+
+// listSupportAccessHandler godoc
+// @Summary List support access grants
+// @Description Get all support access grants issued by the authenticated user
+// @Tags support
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.SupportAccessGrant
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/support-access [get]
+func (s *Server) listSupportAccessHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	grants, err := s.supportService.ListGrants(c.Request.Context(), user.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to list support access grants")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list support access grants"})
+		return
+	}
+
+	if grants == nil {
+		grants = []models.SupportAccessGrant{}
+	}
+
+	c.JSON(http.StatusOK, grants)
+}
+
+// revokeSupportAccessHandler godoc
+// @Summary Revoke support access
+// @Description Immediately invalidate a support access grant
+// @Tags support
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Grant ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/support-access/{id} [delete]
+func (s *Server) revokeSupportAccessHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid grant ID"})
+		return
+	}
+
+	if err := s.supportService.RevokeGrant(c.Request.Context(), user.ID, uint(id)); err != nil {
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "support access grant not found"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to revoke support access")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke support access"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminLookupMemoryHandler godoc
+// @Summary Look up a memory across all users (admin)
+// @Description Find a memory by ID or SHA-256 content hash. Returns existence, owner and timestamps only; content is included when a valid X-Support-Token from the owner is supplied.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id query int false "Memory ID"
+// @Param hash query string false "Hex SHA-256 of the memory content"
+// @Param X-Support-Token header string false "Support access token issued by the memory owner"
+// @Success 200 {object} services.MemoryLookupResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/memories/lookup [get]
+func (s *Server) adminLookupMemoryHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	req := &services.MemoryLookupRequest{
+		ContentHash:  c.Query("hash"),
+		SupportToken: c.GetHeader(supportTokenHeader),
+	}
+	if idStr := c.Query("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+			return
+		}
+		req.MemoryID = uint(id)
+	}
+
+	result, err := s.supportService.LookupMemory(services.WithSupportActor(c.Request.Context(), admin.ID), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSupportToken) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to look up memory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up memory"})
+		return
+	}
+
+	s.logger.Info().
+		Uint("admin_id", admin.ID).
+		Uint("memory_id", req.MemoryID).
+		Bool("hash_lookup", req.ContentHash != "").
+		Bool("support_token", req.SupportToken != "").
+		Int("matches", len(result.Matches)).
+		Msg("Admin memory lookup")
+
+	// Tell owners whenever their content was actually shown to support
+	for _, match := range result.Matches {
+		if !match.ContentRevealed {
+			continue
+		}
+		details := map[string]interface{}{
+			"memory_id": match.MemoryID,
+			"admin_id":  admin.ID,
+		}
+		go s.activityService.LogActivity(context.Background(), match.OwnerID, models.ActivitySupportAccessUsed, details, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ImpersonatedSearchResponse is the result of a search run as another user
+type ImpersonatedSearchResponse struct {
+	UserID         uint             `json:"user_id"`
+	GrantID        uint             `json:"grant_id"`
+	GrantExpiresAt time.Time        `json:"grant_expires_at"`
+	ReadOnly       bool             `json:"read_only"`
+	Memories       []*models.Memory `json:"memories"`
+	Count          int              `json:"count"`
+}
+
+// adminImpersonateSearchHandler godoc
+// @Summary Search a user's memories as that user (admin)
+// @Description Run a read-only search over a user's memories exactly as they would see it, to debug recall problems they reported. Requires an X-Support-Token the user issued; access ends when the grant expires or is revoked. Nothing about the user's memories changes, and every search is audit-logged and shown in the user's activity log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param query query string true "Search query"
+// @Param category query string false "Filter by category"
+// @Param type query string false "Filter by type"
+// @Param searchMode query string false "keyword, semantic or hybrid"
+// @Param limit query int false "Maximum results (default 20, max 100)"
+// @Param X-Support-Token header string true "Support access token issued by the user"
+// @Success 200 {object} ImpersonatedSearchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/search [get]
+func (s *Server) adminImpersonateSearchHandler(c *gin.Context) {
+	admin, exists := getUserFromContext(c)
+	if !exists || admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	userID := uint(id)
+
+	query := c.Query("query")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter is required"})
+		return
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsedLimit
+	}
+
+	grant, err := s.supportService.AuthorizeImpersonation(c.Request.Context(), userID, c.GetHeader(supportTokenHeader))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSupportToken) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "a valid support access token issued by this user is required"})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to authorize impersonation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize impersonation"})
+		return
+	}
+
+	ctx := services.WithSupportActor(c.Request.Context(), admin.ID)
+	memories, err := s.createScopedMemoryService(userID).Search(ctx, services.SearchRequest{
+		Query:             query,
+		Category:          c.Query("category"),
+		Type:              c.Query("type"),
+		Limit:             limit,
+		UseSemanticSearch: true,
+		Mode:              c.Query("searchMode"),
+		ReadOnly:          true,
+	})
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to run impersonated search")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search memories"})
+		return
+	}
+
+	s.logger.Warn().
+		Uint("admin_id", admin.ID).
+		Uint("user_id", userID).
+		Uint("grant_id", grant.ID).
+		Time("grant_expires_at", grant.ExpiresAt).
+		Str("query", query).
+		Int("results", len(memories)).
+		Msg("IMPERSONATION: admin searched memories as user")
+
+	details := map[string]interface{}{
+		"admin_id": admin.ID,
+		"grant_id": grant.ID,
+		"query":    query,
+		"results":  len(memories),
+	}
+	go s.activityService.LogActivity(context.Background(), userID, models.ActivitySupportImpersonation, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	if memories == nil {
+		memories = []*models.Memory{}
+	}
+	c.JSON(http.StatusOK, ImpersonatedSearchResponse{
+		UserID:         userID,
+		GrantID:        grant.ID,
+		GrantExpiresAt: grant.ExpiresAt,
+		ReadOnly:       true,
+		Memories:       memories,
+		Count:          len(memories),
+	})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// voiceMemoFormMemory is the memory multipart voice memo forms are parsed in
+// before spilling to disk
+const voiceMemoFormMemory = 1 << 20
+
+// voiceMemoHandler godoc
+// @Summary Capture a memory by voice
+// @Description Transcribe a short voice memo (at most 10MB of flac, m4a, mp3, mp4, mpeg, mpga, oga, ogg, wav or webm) with
+// @Description the configured transcription provider and capture the transcript like POST /capture: memory detection picks
+// @Description the type, category and update key unless given, and memos already remembered are skipped. The recording is
+// @Description attached to the memory it created or updated. Send a multipart form with the recording in the audio field,
+// @Description or the raw recording as the body with an audio/* Content-Type (options then go in the query string).
+// @Tags memories
+// @Accept multipart/form-data
+// @Accept audio/mpeg
+// @Produce json
+// @Security ApiKeyAuth
+// @Param audio formData file false "Recording (multipart requests)"
+// @Param filename query string false "Recording file name, for raw bodies whose Content-Type is not specific"
+// @Param language query string false "ISO-639-1 language hint, e.g. en"
+// @Param type query string false "Memory type; detected when omitted"
+// @Param category query string false "Memory category; detected when omitted"
+// @Param tags query string false "Comma-separated tags"
+// @Success 200 {object} services.VoiceMemoResult "Updated or skipped"
+// @Success 201 {object} services.VoiceMemoResult "Created"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} QuotaExceededResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /capture/voice [post]
+func (s *Server) voiceMemoHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Allow some room over the limit for the multipart framing and form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxVoiceMemoBytes+voiceMemoFormMemory)
+	req, err := readVoiceMemo(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("voice memo must be at most %d bytes", services.MaxVoiceMemoBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userMemoryService := s.workspaceMemoryService(c, user.ID)
+
+	result, err := userMemoryService.CaptureVoiceMemo(c.Request.Context(), *req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrTranscriptionDisabled) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrIncognito) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Msg("Failed to capture voice memo")
+		if errors.Is(err, services.ErrTranscriptionFailed) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to transcribe voice memo"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture voice memo"})
+		return
+	}
+
+	if result.Action == services.CaptureSkipped {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	details := map[string]interface{}{
+		"memory_id": result.Memory.ID,
+		"category":  result.Memory.Category,
+		"type":      result.Memory.Type,
+		"action":    result.Action,
+		"source":    "voice",
+	}
+	go s.activityService.LogActivity(context.Background(), user.ID, models.ActivityMemoryStored, details, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	status := http.StatusOK
+	if result.Action == services.CaptureCreated {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
+
+// readVoiceMemo reads a voice memo sent as a multipart form or as the raw body.
+// Options are read from the form, falling back to the query string.
+func readVoiceMemo(c *gin.Context) (*services.VoiceMemoRequest, error) {
+	option := func(name string) string {
+		if value, ok := c.GetPostForm(name); ok {
+			return value
+		}
+		return c.Query(name)
+	}
+
+	req := &services.VoiceMemoRequest{}
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.Request.ParseMultipartForm(voiceMemoFormMemory); err != nil {
+			return nil, err
+		}
+		file, header, err := c.Request.FormFile("audio")
+		if err != nil {
+			return nil, fmt.Errorf("audio file is required")
+		}
+		defer file.Close()
+
+		if req.Audio, err = io.ReadAll(file); err != nil {
+			return nil, err
+		}
+		req.Filename = header.Filename
+		req.ContentType = header.Header.Get("Content-Type")
+	} else {
+		var err error
+		if req.Audio, err = io.ReadAll(c.Request.Body); err != nil {
+			return nil, err
+		}
+		req.ContentType = c.ContentType()
+	}
+	if filename := option("filename"); filename != "" {
+		req.Filename = filename
+	}
+
+	req.Language = option("language")
+	req.Type = option("type")
+	req.Category = option("category")
+	for _, tag := range strings.Split(option("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			req.Tags = append(req.Tags, tag)
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// workspaceHeader selects the workspace a request runs in, by name
+const workspaceHeader = "X-Workspace"
+
+// CreateWorkspaceRequest names a new workspace
+type CreateWorkspaceRequest struct {
+	Name        string `json:"name" binding:"required" example:"work"`
+	Description string `json:"description,omitempty" example:"Memories about my job"`
+}
+
+// workspaceMiddleware picks the workspace a request runs in: the one named by the
+// X-Workspace header, else the default workspace of the API key used, else the
+// default workspace. An unknown workspace is refused rather than falling back,
+// so memories never land in the wrong one.
+func (s *Server) workspaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := getUserFromContext(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if name := c.GetHeader(workspaceHeader); name != "" {
+			workspaceID, err := s.createScopedMemoryService(user.ID).ResolveWorkspace(c.Request.Context(), name)
+			if err != nil {
+				if utils.IsNotFoundError(err) {
+					c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				} else {
+					s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to resolve workspace")
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve workspace"})
+				}
+				c.Abort()
+				return
+			}
+			c.Set(workspaceContextKey, workspaceID)
+		} else if apiKey, ok := c.Get(apiKeyKey); ok {
+			if key, ok := apiKey.(*models.APIKey); ok {
+				c.Set(workspaceContextKey, key.WorkspaceID)
+			}
+		}
+		c.Next()
+	}
+}
+
+// requestWorkspace returns the workspace the request selected, if it selected one
+func requestWorkspace(c *gin.Context) (uint, bool) {
+	value, ok := c.Get(workspaceContextKey)
+	if !ok {
+		return 0, false
+	}
+	workspaceID, ok := value.(uint)
+	return workspaceID, ok
+}
+
+// workspaceMemoryService returns the user's memory service confined to the
+// workspace the request runs in; see workspaceMiddleware
+func (s *Server) workspaceMemoryService(c *gin.Context, userID uint) *services.MemoryService {
+	workspaceID, _ := requestWorkspace(c)
+	return s.createScopedMemoryService(userID).InWorkspace(workspaceID)
+}
+
+// listWorkspacesHandler godoc
+// @Summary List workspaces
+// @Description List the user's workspaces with how many memories each holds, starting with the default workspace every user has
+// @Tags workspaces
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces [get]
+func (s *Server) listWorkspacesHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	workspaces, err := s.createScopedMemoryService(user.ID).ListWorkspaces(c.Request.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to list workspaces")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+		return
+	}
+
+	workspaceID, _ := requestWorkspace(c)
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces, "current_workspace_id": workspaceID})
+}
+
+// createWorkspaceHandler godoc
+// @Summary Create a workspace
+// @Description Create a separate space of memories, selected with the X-Workspace header, an API key's default workspace or the MCP initialize capability
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateWorkspaceRequest true "Workspace name and description"
+// @Success 201 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "A workspace with this name already exists"
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces [post]
+func (s *Server) createWorkspaceHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := s.createScopedMemoryService(user.ID).CreateWorkspace(c.Request.Context(), req.Name, req.Description)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsConflictError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to create workspace")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// deleteWorkspaceHandler godoc
+// @Summary Delete a workspace
+// @Description Delete an empty workspace. API keys defaulting to it fall back to the default workspace.
+// @Tags workspaces
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "Workspace name"
+// @Success 204
+// @Failure 400 {object} ErrorResponse "The workspace still holds memories, or is the default workspace"
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /workspaces/{name} [delete]
+func (s *Server) deleteWorkspaceHandler(c *gin.Context) {
+	user, exists := getUserFromContext(c)
+	if !exists || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := s.createScopedMemoryService(user.ID).DeleteWorkspace(c.Request.Context(), c.Param("name")); err != nil {
+		if utils.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.logger.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to delete workspace")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workspace"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -2,19 +2,59 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
 )
 
+// residencyRegionPattern restricts region tags to simple identifiers such as "eu-west-1"
+var residencyRegionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,31}$`)
+
 // Config represents the main application configuration
 type Config struct {
 	Database   Database   `json:"database" mapstructure:"database"`
 	OpenAI     OpenAI     `json:"openai" mapstructure:"openai"`
+	Embedding  Embedding  `json:"embedding" mapstructure:"embedding"`
 	Memory     Memory     `json:"memory" mapstructure:"memory"`
 	Server     Server     `json:"server" mapstructure:"server"`
 	JWT        JWT        `json:"jwt" mapstructure:"jwt"`
 	HTTP       HTTP       `json:"http" mapstructure:"http"`
 	Encryption Encryption `json:"encryption" mapstructure:"encryption"`
+	Alerts     Alerts     `json:"alerts" mapstructure:"alerts"`
+	GeoIP      GeoIP      `json:"geoip" mapstructure:"geoip"`
+	Residency  Residency  `json:"residency" mapstructure:"residency"`
+	Audit      Audit      `json:"audit" mapstructure:"audit"`
+	Moderation Moderation `json:"moderation" mapstructure:"moderation"`
+	DualWrite  DualWrite  `json:"dual_write" mapstructure:"dual_write"`
+	LLM        LLM        `json:"llm" mapstructure:"llm"`
+	Extraction Extraction `json:"extraction" mapstructure:"extraction"`
+	// Transcription turns voice memos into text
+	Transcription Transcription `json:"transcription" mapstructure:"transcription"`
+	// Attachments limits the files kept with memories and where they are stored
+	Attachments Attachments `json:"attachments" mapstructure:"attachments"`
+	// Backup dumps every user's memories on a schedule for cmd/restore to rebuild from
+	Backup Backup `json:"backup" mapstructure:"backup"`
+
+	EmbeddingBackfill EmbeddingBackfill `json:"embedding_backfill" mapstructure:"embedding_backfill"`
+	Migrations        Migrations        `json:"migrations" mapstructure:"migrations"`
+	// Maintenance runs the agent proposing clean-ups of opted-in users' memories
+	Maintenance Maintenance `json:"maintenance" mapstructure:"maintenance"`
+	// Consolidation merges clusters of related memories into summaries
+	Consolidation Consolidation `json:"consolidation" mapstructure:"consolidation"`
+	// SearchCache keeps recent query embeddings and search results in memory
+	SearchCache SearchCache `json:"search_cache" mapstructure:"search_cache"`
+	// Retention prunes old activity logs and performance metrics
+	Retention Retention `json:"retention" mapstructure:"retention"`
+	// Timeouts bounds how long HTTP routes, MCP tools and service writes may take
+	Timeouts Timeouts `json:"timeouts" mapstructure:"timeouts"`
+	// ClientProfiles adapt MCP responses to the client that connected
+	ClientProfiles []ClientProfile `json:"client_profiles" mapstructure:"client_profiles"`
+	// Tracing exports OpenTelemetry traces of requests, tool calls and queries
+	Tracing Tracing `json:"tracing" mapstructure:"tracing"`
 }
 
 // Database represents database configuration
@@ -29,6 +69,23 @@ type Database struct {
 	MaxIdleConns    int           `json:"max_idle_conns" mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
+	// VectorIndex configures the pgvector index on memory embeddings
+	VectorIndex VectorIndex `json:"vector_index" mapstructure:"vector_index"`
+}
+
+// VectorIndex selects and tunes the pgvector index semantic search uses
+type VectorIndex struct {
+	// Type is hnsw, ivfflat or none to leave the index alone
+	Type string `json:"type" mapstructure:"type"`
+	// M and EfConstruction shape an hnsw index when it is built; EfSearch is how
+	// many candidates each query considers
+	M              int `json:"m" mapstructure:"m"`
+	EfConstruction int `json:"ef_construction" mapstructure:"ef_construction"`
+	EfSearch       int `json:"ef_search" mapstructure:"ef_search"`
+	// Lists is how many clusters an ivfflat index is built with; Probes is how
+	// many of them each query visits
+	Lists  int `json:"lists" mapstructure:"lists"`
+	Probes int `json:"probes" mapstructure:"probes"`
 }
 
 // OpenAI represents OpenAI API configuration
@@ -37,35 +94,581 @@ type OpenAI struct {
 	Model      string        `json:"model" mapstructure:"model"`
 	MaxRetries int           `json:"max_retries" mapstructure:"max_retries"`
 	Timeout    time.Duration `json:"timeout" mapstructure:"timeout"`
+	// BatchSize caps how many memory embeddings are coalesced into one request,
+	// and BatchWindow is how long a request waits for others to join it
+	BatchSize   int           `json:"batch_size" mapstructure:"batch_size"`
+	BatchWindow time.Duration `json:"batch_window" mapstructure:"batch_window"`
+}
+
+// MaxEmbeddingDimensions is the size of the embedding column; smaller vectors are
+// zero-padded to fit
+const MaxEmbeddingDimensions = 1536
+
+// Embedding selects the embedding provider. The openai provider is configured by
+// the openai section; the others are configured here.
+type Embedding struct {
+	// Provider is openai, ollama, voyage, cohere, onnx or mock
+	Provider string `json:"provider" mapstructure:"provider"`
+	// BaseURL replaces the provider's default endpoint, e.g. a remote Ollama host
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// Model defaults to the provider's usual embedding model
+	Model string `json:"model" mapstructure:"model"`
+	// Dimensions is the vector size the model returns; 0 accepts any size up to
+	// MaxEmbeddingDimensions
+	Dimensions int `json:"dimensions" mapstructure:"dimensions"`
+	// APIKey authenticates with hosted providers (voyage and cohere)
+	APIKey string `json:"api_key" mapstructure:"api_key"`
+	// Document controls what is embedded for each memory
+	Document EmbeddingDocument `json:"document" mapstructure:"document"`
+	// Cache keeps the embedding generated for each text, keyed by model and a
+	// hash of the text, so storing the same text again or repeating a search does
+	// not call the provider
+	Cache bool `json:"cache" mapstructure:"cache"`
+}
+
+// EmbeddingDocument composes the text embedded for a memory from its fields, so
+// a query can match a project named only in a tag. Queries are embedded as-is.
+type EmbeddingDocument struct {
+	// Template is a Go text/template over .Content, .Tags, .Category and .Type,
+	// e.g. "{{.Content}} Tags: {{join .Tags \", \"}}". Empty embeds the content.
+	Template string `json:"template" mapstructure:"template"`
+	// Weights, when set, embed content, tags, category and type separately and
+	// average the vectors with these weights; fields without a weight are left
+	// out. The template is then ignored.
+	Weights map[string]float64 `json:"weights" mapstructure:"weights"`
 }
 
 // Memory represents memory-related configuration
 type Memory struct {
 	MaxMemories         int     `json:"max_memories" mapstructure:"max_memories"`
 	SimilarityThreshold float64 `json:"similarity_threshold" mapstructure:"similarity_threshold"`
+	// PriorityBoosts is added to a memory's relevance score per priority level
+	// (low, medium, high, critical) when ranking search results
+	PriorityBoosts map[string]float64 `json:"priority_boosts" mapstructure:"priority_boosts"`
+	// EvictionPolicy is the default for what happens at MaxMemories (oldest_first,
+	// least_accessed, least_recently_used, lowest_priority or reject_new, or the
+	// aliases oldest, lowest_access_count and least_recently_accessed); users may
+	// override it
+	EvictionPolicy string `json:"eviction_policy" mapstructure:"eviction_policy"`
+	// ContextBufferTTL is how long conversation turns appended to a session's
+	// short-term buffer are kept
+	ContextBufferTTL time.Duration `json:"context_buffer_ttl" mapstructure:"context_buffer_ttl"`
+	// DuplicateAction is what a store does when its content is semantically close
+	// to an existing memory: merge, update or reject. Empty catches only exact
+	// duplicates; requests may override it.
+	DuplicateAction string `json:"duplicate_action" mapstructure:"duplicate_action"`
+	// DuplicateThreshold is the embedding similarity from which memories count as
+	// near-duplicates
+	DuplicateThreshold float64 `json:"duplicate_threshold" mapstructure:"duplicate_threshold"`
+	// FeedbackWeight is the most that helpful or irrelevant feedback from
+	// feedback_memory can add to or take from a memory's relevance. Zero ignores
+	// feedback when ranking.
+	FeedbackWeight float64 `json:"feedback_weight" mapstructure:"feedback_weight"`
+	// Plans maps plan names to their memory limit, 0 meaning unlimited. Users on
+	// a plan get its limit instead of MaxMemories unless they have their own.
+	Plans map[string]int `json:"plans" mapstructure:"plans"`
+	// ExactCounts counts memories on every stats read and limit check instead of
+	// reading the counters kept by database triggers
+	ExactCounts bool `json:"exact_counts" mapstructure:"exact_counts"`
+	// NormalizeDetected tidies automatically detected memories before storing
+	// them: filler words are stripped, whitespace collapsed and sentences
+	// capitalized. The text as said is kept in the memory's metadata.
+	NormalizeDetected bool `json:"normalize_detected" mapstructure:"normalize_detected"`
+	// FillerWords are the words and phrases stripped when normalizing, matched
+	// as whole words ignoring case. Empty uses the built-in list.
+	FillerWords []string `json:"filler_words" mapstructure:"filler_words"`
+	// MaxContentLength is the most characters a memory's content may hold; longer
+	// stores and updates are rejected. Zero means no limit.
+	MaxContentLength int `json:"max_content_length" mapstructure:"max_content_length"`
+	// ChunkSize is the most characters embedded together. Longer content is split
+	// into chunks embedded on their own, so searches match text anywhere in it.
+	// Zero embeds only the start of long content.
+	ChunkSize int `json:"chunk_size" mapstructure:"chunk_size"`
 }
 
 // Server represents server configuration
 type Server struct {
 	LogLevel string `json:"log_level" mapstructure:"log_level"`
 	Debug    bool   `json:"debug" mapstructure:"debug"`
+	// WarmUp primes the embedding client, vector index and search statements at
+	// startup so the first semantic search is not slower than the rest
+	WarmUp bool `json:"warm_up" mapstructure:"warm_up"`
+}
+
+// Timeouts represents the deadlines given to requests. A duration of zero means no
+// deadline.
+type Timeouts struct {
+	// Default bounds every HTTP route and MCP tool without an entry of its own
+	Default time.Duration `json:"default" mapstructure:"default"`
+	// Routes maps an HTTP route, written as the method and the path it was
+	// registered with (e.g. "POST /api/v1/memories/import"), to its deadline. The
+	// MCP endpoint is bounded per tool instead unless it is listed here.
+	Routes map[string]time.Duration `json:"routes" mapstructure:"routes"`
+	// Tools maps an MCP tool name (e.g. store_memories_bulk) to its deadline
+	Tools map[string]time.Duration `json:"tools" mapstructure:"tools"`
+	// Write bounds the database writes the memory service finishes after the
+	// request that started them has returned or been cancelled, such as storing
+	// an embedding generated in the background
+	Write time.Duration `json:"write" mapstructure:"write"`
+}
+
+// ClientProfile shapes MCP responses for the clients matching it, by the name and
+// version they send when initializing. The first matching profile applies;
+// clients matching none get the defaults.
+type ClientProfile struct {
+	// Name matches the client name ignoring case; a trailing * matches a prefix
+	Name string `json:"name" mapstructure:"name"`
+	// MinVersion (inclusive) and MaxVersion (exclusive) bound the client versions
+	// matched, compared as dotted numbers. Either may be empty.
+	MinVersion string `json:"min_version" mapstructure:"min_version"`
+	MaxVersion string `json:"max_version" mapstructure:"max_version"`
+	// DefaultLimit is the number of results returned when the client does not ask
+	// for a number; MaxLimit caps what it may ask for. Zero keeps the default.
+	DefaultLimit int `json:"default_limit" mapstructure:"default_limit"`
+	MaxLimit     int `json:"max_limit" mapstructure:"max_limit"`
+	// Compact leaves metadata out of returned memories and shortens their content
+	// to MaxContentLength characters when that is set
+	Compact          bool `json:"compact" mapstructure:"compact"`
+	MaxContentLength int  `json:"max_content_length" mapstructure:"max_content_length"`
+	// Schemas is "full" (the default) or "minimal", which leaves the descriptions
+	// of optional parameters out of tool schemas
+	Schemas string `json:"schemas" mapstructure:"schemas"`
+	// Annotations lists tools with hints on whether they are read-only,
+	// destructive or idempotent, for clients that understand them
+	Annotations bool `json:"annotations" mapstructure:"annotations"`
+	// Format is the result format used when a tool call does not choose one:
+	// "text" (JSON, the default), "json" (an embedded JSON resource) or "markdown"
+	Format string `json:"format" mapstructure:"format"`
+}
+
+// Tracing configures OpenTelemetry tracing. Spans cover MCP tool calls, HTTP
+// requests, embedding provider calls and database queries, and are exported over
+// OTLP/HTTP.
+type Tracing struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Endpoint is the collector's host:port (e.g. localhost:4318). When empty the
+	// standard OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
+	// Insecure sends spans over plain HTTP instead of HTTPS
+	Insecure bool `json:"insecure" mapstructure:"insecure"`
+	// SampleRatio is the share of traces recorded, from 0 to 1. Traces started by
+	// a caller that sampled them are always recorded.
+	SampleRatio float64 `json:"sample_ratio" mapstructure:"sample_ratio"`
+	// ServiceName names this process in traces; it defaults to the binary's role
+	ServiceName string `json:"service_name" mapstructure:"service_name"`
+}
+
+// MCPRoute is the HTTP route of the MCP endpoint, whose deadline comes from the
+// tool called rather than from Timeouts.Default
+const MCPRoute = "POST /api/v1/mcp"
+
+// Route returns the deadline of an HTTP route given by method and registered path.
+// Routes are matched ignoring case, as keys read from config files are lowercased.
+func (t Timeouts) Route(method, path string) time.Duration {
+	route := method + " " + path
+	for key, timeout := range t.Routes {
+		if strings.EqualFold(key, route) {
+			return timeout
+		}
+	}
+	if route == MCPRoute {
+		return 0
+	}
+	return t.Default
+}
+
+// Connection returns how long a request to an HTTP route may take to be read and
+// answered: the route's deadline, or for the MCP endpoint the longest deadline of
+// any tool. Zero means unbounded.
+func (t Timeouts) Connection(method, path string) time.Duration {
+	timeout := t.Route(method, path)
+	if timeout > 0 || !strings.EqualFold(method+" "+path, MCPRoute) {
+		return timeout
+	}
+	if t.Default <= 0 {
+		return 0
+	}
+	longest := t.Default
+	for _, tool := range t.Tools {
+		if tool <= 0 {
+			return 0
+		}
+		longest = max(longest, tool)
+	}
+	return longest
+}
+
+// Tool returns the deadline of an MCP tool
+func (t Timeouts) Tool(name string) time.Duration {
+	if timeout, ok := t.Tools[name]; ok {
+		return timeout
+	}
+	return t.Default
 }
 
 // JWT represents JWT configuration
 type JWT struct {
 	Secret string `json:"secret" mapstructure:"secret"`
+	// AccessTTL is how long an access token is valid
+	AccessTTL time.Duration `json:"access_ttl" mapstructure:"access_ttl"`
+	// RefreshTTL is how long a refresh token can be exchanged for a new access token
+	RefreshTTL time.Duration `json:"refresh_ttl" mapstructure:"refresh_ttl"`
 }
 
-// HTTP represents HTTP server configuration  
+// HTTP represents HTTP server configuration
 type HTTP struct {
-	Port         int      `json:"port" mapstructure:"port"`
-	AllowOrigins []string `json:"allow_origins" mapstructure:"allow_origins"`
+	// Host is the address the server listens on; empty listens on all interfaces
+	Host         string    `json:"host" mapstructure:"host"`
+	Port         int       `json:"port" mapstructure:"port"`
+	AllowOrigins []string  `json:"allow_origins" mapstructure:"allow_origins"`
+	RateLimit    RateLimit `json:"rate_limit" mapstructure:"rate_limit"`
+	// TrustedProxies lists the IPs and CIDR ranges of reverse proxies whose
+	// X-Forwarded-For header gives the client IP. With none, the header is
+	// ignored and the connection's address is the client IP.
+	TrustedProxies []string `json:"trusted_proxies" mapstructure:"trusted_proxies"`
+}
+
+// RateLimit configures token-bucket rate limiting of the HTTP API. Rates are
+// requests per second and bursts are how many requests a client may make at once;
+// a rate of 0 turns that limit off.
+type RateLimit struct {
+	Enabled     bool    `json:"enabled" mapstructure:"enabled"`
+	APIKeyRate  float64 `json:"api_key_rate" mapstructure:"api_key_rate"`
+	APIKeyBurst int     `json:"api_key_burst" mapstructure:"api_key_burst"`
+	IPRate      float64 `json:"ip_rate" mapstructure:"ip_rate"`
+	IPBurst     int     `json:"ip_burst" mapstructure:"ip_burst"`
 }
 
 // Encryption represents encryption configuration
 type Encryption struct {
+	// MasterKey is the base64 master key, or its ciphertext when KMS is set
 	MasterKey string `json:"master_key" mapstructure:"master_key"`
-	Enabled   bool   `json:"enabled" mapstructure:"enabled"`
+	// PreviousKeys are master keys rotated away from, newest first; data still
+	// encrypted with them is decrypted until cmd/rotate-key has moved it
+	PreviousKeys []string `json:"previous_keys" mapstructure:"previous_keys"`
+	Enabled      bool     `json:"enabled" mapstructure:"enabled"`
+	// KMS decrypts the master keys at startup, so only their ciphertexts are
+	// configured
+	KMS KMS `json:"kms" mapstructure:"kms"`
+}
+
+// KMS selects the key management service holding the key that encrypts the
+// master keys
+type KMS struct {
+	// Provider is aws, gcp or vault; empty means the master keys are configured
+	// in plaintext
+	Provider string `json:"provider" mapstructure:"provider"`
+	// KeyID names the key: an AWS key ID, ARN or alias, a Google Cloud resource
+	// name (projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>), or a Vault
+	// transit key name
+	KeyID string `json:"key_id" mapstructure:"key_id"`
+	// Endpoint overrides the service's URL; for vault it is the Vault address
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
+	// Region is the AWS region; empty uses the AWS SDK's default from the
+	// environment or shared config
+	Region string `json:"region" mapstructure:"region"`
+	// AccessKeyID, SecretAccessKey and SessionToken are static AWS credentials.
+	// Without them the AWS SDK's default credential chain is used: environment
+	// variables, shared config and credentials files, web identity tokens, and
+	// container or instance roles.
+	AccessKeyID     string `json:"access_key_id" mapstructure:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" mapstructure:"secret_access_key"`
+	SessionToken    string `json:"session_token" mapstructure:"session_token"`
+	// Token is a Google Cloud access token or a Vault token. Without one, gcp
+	// fetches tokens from the instance metadata server.
+	Token string `json:"token" mapstructure:"token"`
+	// Mount is the path the Vault transit engine is mounted at
+	Mount string `json:"mount" mapstructure:"mount"`
+	// Timeout bounds each request
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// Alerts represents anomaly detection and alert delivery configuration
+type Alerts struct {
+	Enabled              bool          `json:"enabled" mapstructure:"enabled"`
+	WebhookURL           string        `json:"webhook_url" mapstructure:"webhook_url"`
+	Email                AlertEmail    `json:"email" mapstructure:"email"`
+	DeleteSpikeThreshold int           `json:"delete_spike_threshold" mapstructure:"delete_spike_threshold"`
+	DeleteSpikeWindow    time.Duration `json:"delete_spike_window" mapstructure:"delete_spike_window"`
+	APIKeyLearningUses   int64         `json:"api_key_learning_uses" mapstructure:"api_key_learning_uses"`
+}
+
+// AlertEmail represents SMTP settings for alert emails
+type AlertEmail struct {
+	SMTPHost string   `json:"smtp_host" mapstructure:"smtp_host"`
+	SMTPPort int      `json:"smtp_port" mapstructure:"smtp_port"`
+	Username string   `json:"username" mapstructure:"username"`
+	Password string   `json:"password" mapstructure:"password"`
+	From     string   `json:"from" mapstructure:"from"`
+	To       []string `json:"to" mapstructure:"to"`
+}
+
+// Migrations represents how versioned migrations that rewrite data are protected
+type Migrations struct {
+	// RequireBackupConfirmation skips the automatic copies of rewritten tables,
+	// which can be too large in production, and refuses to run those migrations
+	// unless the server is started with --i-have-a-backup
+	RequireBackupConfirmation bool `json:"require_backup_confirmation" mapstructure:"require_backup_confirmation"`
+}
+
+// EmbeddingBackfill represents the background worker that retries missing embeddings
+type EmbeddingBackfill struct {
+	Enabled     bool          `json:"enabled" mapstructure:"enabled"`
+	Interval    time.Duration `json:"interval" mapstructure:"interval"`
+	BatchSize   int           `json:"batch_size" mapstructure:"batch_size"`
+	MaxAttempts int           `json:"max_attempts" mapstructure:"max_attempts"`
+	MaxBackoff  time.Duration `json:"max_backoff" mapstructure:"max_backoff"`
+}
+
+// Maintenance configures the maintenance agent, which periodically reviews the
+// memories of users who opted in and proposes merging duplicates, resolving
+// conflicts, pruning stale memories and refiling ones categorization rules match.
+// Nothing changes until the user approves it.
+type Maintenance struct {
+	// Enabled runs the scheduled agent; users still opt in, and may ask for a
+	// review either way
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Interval is how often each opted-in user's memories are reviewed
+	Interval time.Duration `json:"interval" mapstructure:"interval"`
+	// StaleAfter is how long a low or medium priority memory may go unrecalled
+	// before pruning it is proposed
+	StaleAfter time.Duration `json:"stale_after" mapstructure:"stale_after"`
+	// MaxActions caps the actions one review proposes
+	MaxActions int `json:"max_actions" mapstructure:"max_actions"`
+}
+
+// Consolidation configures merging clusters of related memories, found by
+// embedding similarity, into summary memories written by an OpenAI chat model.
+// The originals are kept and marked as superseded by the summary. It needs
+// openai.api_key; the consolidate_memories tool works whether or not the
+// scheduled run is enabled.
+type Consolidation struct {
+	// Enabled consolidates every user's memories on a schedule
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Interval is how often the scheduled run happens
+	Interval time.Duration `json:"interval" mapstructure:"interval"`
+	// Model is the chat model that writes the summaries
+	Model string `json:"model" mapstructure:"model"`
+	// Timeout bounds each summary request
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+	// Similarity is how similar two memories' embeddings must be to cluster them
+	Similarity float64 `json:"similarity" mapstructure:"similarity"`
+	// MinClusterSize and MaxClusterSize bound the memories folded into a summary
+	MinClusterSize int `json:"min_cluster_size" mapstructure:"min_cluster_size"`
+	MaxClusterSize int `json:"max_cluster_size" mapstructure:"max_cluster_size"`
+	// MaxClusters caps the clusters consolidated per user and run
+	MaxClusters int `json:"max_clusters" mapstructure:"max_clusters"`
+}
+
+// SearchCache configures the in-memory cache in front of semantic and hybrid
+// search. Query embeddings are kept by model and query, and the memories each
+// search found are kept until they expire or the user's memories change. Each
+// process has its own cache.
+type SearchCache struct {
+	// Enabled turns the cache on
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Embeddings is how many query embeddings are kept; 0 keeps none
+	Embeddings int `json:"embeddings" mapstructure:"embeddings"`
+	// Results is how many search results are kept; 0 keeps none
+	Results int `json:"results" mapstructure:"results"`
+	// ResultTTL is how long search results are served from the cache; 0 keeps
+	// none
+	ResultTTL time.Duration `json:"result_ttl" mapstructure:"result_ttl"`
+}
+
+// Retention configures how long raw activity logs and performance metrics are
+// kept. Every finished day is rolled up into daily counts before its rows are
+// pruned, so activity and performance trends outlast the raw rows.
+type Retention struct {
+	// Enabled rolls up and prunes on a schedule
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Interval is how often the scheduled run happens
+	Interval time.Duration `json:"interval" mapstructure:"interval"`
+	// ActivityLogs is how long activity logs are kept; 0 keeps them forever
+	ActivityLogs time.Duration `json:"activity_logs" mapstructure:"activity_logs"`
+	// PerformanceMetrics is how long performance metrics are kept; 0 keeps them
+	// forever
+	PerformanceMetrics time.Duration `json:"performance_metrics" mapstructure:"performance_metrics"`
+}
+
+// GeoIP represents IP geolocation configuration
+type GeoIP struct {
+	// DatabasePath points to a local MaxMind GeoLite2/GeoIP2 City or Country .mmdb file
+	DatabasePath string `json:"database_path" mapstructure:"database_path"`
+}
+
+// Residency represents data residency configuration
+type Residency struct {
+	// Region tags the deployment, e.g. "eu-west". Exports and snapshots record it and
+	// restores from a different region are refused unless explicitly overridden.
+	Region string `json:"region" mapstructure:"region"`
+}
+
+// Audit represents the audit trail of access to memory content
+type Audit struct {
+	// MemoryAccess records each read, update and delete of a memory made through
+	// MCP or the HTTP API, queryable per memory
+	MemoryAccess bool `json:"memory_access" mapstructure:"memory_access"`
+}
+
+// Moderation represents the content moderation hook run before memories are stored
+type Moderation struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Provider is "openai" (the OpenAI moderation API) or "rules" (local regex rules)
+	Provider string `json:"provider" mapstructure:"provider"`
+	// Model is the OpenAI moderation model
+	Model string `json:"model" mapstructure:"model"`
+	// Actions maps a moderation category to block, flag or encrypt. Flagged
+	// categories without an action are ignored.
+	Actions map[string]string `json:"actions" mapstructure:"actions"`
+	// Rules maps a category to the regular expressions that flag it (rules provider)
+	Rules map[string][]string `json:"rules" mapstructure:"rules"`
+	// FailOpen stores content unmoderated when the provider errors instead of rejecting it
+	FailOpen bool `json:"fail_open" mapstructure:"fail_open"`
+}
+
+// LLM represents settings shared by the optional language-model features
+// (extraction, consolidation, ask and rerank)
+type LLM struct {
+	// DailyBudgetUSD caps the spend of all users per UTC day, and
+	// UserDailyBudgetUSD the spend of each user; zero means no limit. Features
+	// fall back to their non-LLM behaviour once a budget is spent.
+	DailyBudgetUSD     float64 `json:"daily_budget_usd" mapstructure:"daily_budget_usd"`
+	UserDailyBudgetUSD float64 `json:"user_daily_budget_usd" mapstructure:"user_daily_budget_usd"`
+	// InputCostPerMillion and OutputCostPerMillion price tokens in USD per
+	// million, used to turn recorded usage into cost
+	InputCostPerMillion  float64 `json:"input_cost_per_million" mapstructure:"input_cost_per_million"`
+	OutputCostPerMillion float64 `json:"output_cost_per_million" mapstructure:"output_cost_per_million"`
+	// EmbeddingCostPerMillion prices embedding tokens, for usage attribution
+	EmbeddingCostPerMillion float64 `json:"embedding_cost_per_million" mapstructure:"embedding_cost_per_million"`
+}
+
+// Extraction selects how memories are found in free text such as conversation
+// transcripts
+type Extraction struct {
+	// Engine is "regex" (the built-in patterns) or "llm" (an OpenAI chat model,
+	// falling back to the patterns when it fails or the LLM budget is spent)
+	Engine string `json:"engine" mapstructure:"engine"`
+	// Model is the chat model of the llm engine
+	Model string `json:"model" mapstructure:"model"`
+	// Timeout bounds each llm extraction request
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// Transcription selects the speech-to-text service voice memos are transcribed with
+type Transcription struct {
+	// Provider is "none" (voice memos are rejected), "openai" (the OpenAI
+	// transcription API, using openai.api_key) or "whisper" (a self-hosted server
+	// with the same API, such as faster-whisper-server, at BaseURL)
+	Provider string `json:"provider" mapstructure:"provider"`
+	// Model is the transcription model, e.g. whisper-1
+	Model string `json:"model" mapstructure:"model"`
+	// BaseURL is the whisper server, e.g. http://localhost:8000
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// APIKey authenticates with a whisper server that requires it
+	APIKey string `json:"api_key" mapstructure:"api_key"`
+	// Timeout bounds each transcription request
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// Attachments configures the files and images kept with memories
+type Attachments struct {
+	// Storage is "database" (kept in the attachments table) or "s3" (kept in the
+	// S3-compatible bucket configured under S3, with metadata in the database)
+	Storage string `json:"storage" mapstructure:"storage"`
+	// MaxSize is the largest attachment accepted, in bytes
+	MaxSize int64 `json:"max_size" mapstructure:"max_size"`
+	// MaxPerMemory is the most attachments a memory may have (0 for no limit)
+	MaxPerMemory int `json:"max_per_memory" mapstructure:"max_per_memory"`
+	// S3 is the bucket attachments are stored in with storage "s3"
+	S3 ObjectStorage `json:"s3" mapstructure:"s3"`
+}
+
+// Backup configures scheduled backups of every user's memories
+type Backup struct {
+	// Enabled runs backups on the schedule
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Schedule is a five-field cron expression in UTC, e.g. "0 3 * * *" for 03:00
+	// every day, or a shortcut such as @daily
+	Schedule string `json:"schedule" mapstructure:"schedule"`
+	// Destination is "local" (files under Path), "s3" or "gcs" (objects in the
+	// bucket configured under S3; gcs defaults the endpoint to Google Cloud Storage)
+	Destination string `json:"destination" mapstructure:"destination"`
+	// Path is the directory local backups are written to
+	Path string `json:"path" mapstructure:"path"`
+	// S3 is the bucket backups are uploaded to with destination "s3" or "gcs"
+	S3 ObjectStorage `json:"s3" mapstructure:"s3"`
+	// Encrypt seals each backup with EncryptionKey, or with the encryption master
+	// key when EncryptionKey is empty
+	Encrypt       bool   `json:"encrypt" mapstructure:"encrypt"`
+	EncryptionKey string `json:"encryption_key" mapstructure:"encryption_key"`
+	// IncludeEmbeddings stores each memory's embedding so a restore can skip
+	// re-embedding
+	IncludeEmbeddings bool `json:"include_embeddings" mapstructure:"include_embeddings"`
+	// Keep is how many backups are kept, the oldest being deleted (0 keeps all)
+	Keep int `json:"keep" mapstructure:"keep"`
+}
+
+// GCSEndpoint is the S3-compatible endpoint of Google Cloud Storage
+const GCSEndpoint = "https://storage.googleapis.com"
+
+// Bucket returns the bucket backups are uploaded to, with the Google Cloud
+// Storage endpoint filled in for destination "gcs"
+func (b Backup) Bucket() ObjectStorage {
+	bucket := b.S3
+	if b.Destination == "gcs" {
+		if bucket.Endpoint == "" {
+			bucket.Endpoint = GCSEndpoint
+		}
+		if bucket.Region == "" || bucket.Region == "us-east-1" {
+			bucket.Region = "auto"
+		}
+	}
+	return bucket
+}
+
+// ObjectStorage is a bucket in Amazon S3 or a service with the same API, such as
+// MinIO, Cloudflare R2 or Google Cloud Storage with HMAC keys
+type ObjectStorage struct {
+	// Endpoint is the service's URL, e.g. https://s3.amazonaws.com or
+	// https://storage.googleapis.com
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
+	// Region signs requests; services without regions accept us-east-1 or auto
+	Region string `json:"region" mapstructure:"region"`
+	Bucket string `json:"bucket" mapstructure:"bucket"`
+	// Prefix is prepended to every object key, e.g. remember-me/
+	Prefix          string `json:"prefix" mapstructure:"prefix"`
+	AccessKeyID     string `json:"access_key_id" mapstructure:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" mapstructure:"secret_access_key"`
+	// PathStyle addresses the bucket in the path (endpoint/bucket/key) rather than
+	// the host (bucket.endpoint/key), as MinIO usually requires
+	PathStyle bool `json:"path_style" mapstructure:"path_style"`
+	// Timeout bounds each request
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// Validate checks that a bucket is configured; name identifies it in errors
+func (o ObjectStorage) Validate(name string) error {
+	if o.Endpoint == "" || o.Bucket == "" {
+		return fmt.Errorf("%s endpoint and bucket are required", name)
+	}
+	if _, err := url.Parse(o.Endpoint); err != nil {
+		return fmt.Errorf("invalid %s endpoint: %w", name, err)
+	}
+	if o.AccessKeyID == "" || o.SecretAccessKey == "" {
+		return fmt.Errorf("%s access key ID and secret access key are required", name)
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("%s timeout must be positive", name)
+	}
+	return nil
+}
+
+// DualWrite represents the migration assist mode that mirrors memory writes onto a
+// second database, so a deployment can move to a new Postgres without downtime
+type DualWrite struct {
+	Enabled bool     `json:"enabled" mapstructure:"enabled"`
+	Target  Database `json:"target" mapstructure:"target"`
 }
 
 // NewDefault returns a Config instance with default values
@@ -82,31 +685,179 @@ func NewDefault() *Config {
 			MaxIdleConns:    10,
 			ConnMaxLifetime: 5 * time.Minute,
 			ConnMaxIdleTime: 1 * time.Minute,
+			VectorIndex: VectorIndex{
+				Type:           "hnsw",
+				M:              16,
+				EfConstruction: 64,
+				EfSearch:       40,
+				Lists:          100,
+				Probes:         1,
+			},
 		},
 		OpenAI: OpenAI{
-			APIKey:     "",
-			Model:      "text-embedding-3-small",
-			MaxRetries: 3,
-			Timeout:    30 * time.Second,
+			APIKey:      "",
+			Model:       "text-embedding-3-small",
+			MaxRetries:  3,
+			Timeout:     30 * time.Second,
+			BatchSize:   100,
+			BatchWindow: 50 * time.Millisecond,
+		},
+		Embedding: Embedding{
+			Provider: "openai",
+			Cache:    true,
 		},
 		Memory: Memory{
 			MaxMemories:         1000,
 			SimilarityThreshold: 0.7,
+			PriorityBoosts: map[string]float64{
+				"low":      0,
+				"medium":   0.02,
+				"high":     0.05,
+				"critical": 0.1,
+			},
+			EvictionPolicy:     "oldest_first",
+			ContextBufferTTL:   30 * time.Minute,
+			DuplicateThreshold: 0.95,
+			FeedbackWeight:     0.05,
+			MaxContentLength:   20000,
+			ChunkSize:          2000,
 		},
 		Server: Server{
 			LogLevel: "info",
 			Debug:    false,
 		},
 		JWT: JWT{
-			Secret: "change-me-in-production",
+			Secret:     "change-me-in-production",
+			AccessTTL:  24 * time.Hour,
+			RefreshTTL: 30 * 24 * time.Hour,
 		},
 		HTTP: HTTP{
-			Port: 8082,
+			Port:         8082,
 			AllowOrigins: []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:5174"},
+			RateLimit: RateLimit{
+				Enabled:     true,
+				APIKeyRate:  10,
+				APIKeyBurst: 20,
+				IPRate:      20,
+				IPBurst:     40,
+			},
 		},
 		Encryption: Encryption{
 			MasterKey: "",
 			Enabled:   false,
+			KMS: KMS{
+				Mount:   "transit",
+				Timeout: 10 * time.Second,
+			},
+		},
+		Alerts: Alerts{
+			Enabled:              false,
+			DeleteSpikeThreshold: 20,
+			DeleteSpikeWindow:    10 * time.Minute,
+			APIKeyLearningUses:   50,
+			Email: AlertEmail{
+				SMTPPort: 587,
+			},
+		},
+		Moderation: Moderation{
+			Enabled:  false,
+			Provider: "rules",
+			Model:    "omni-moderation-latest",
+		},
+		LLM: LLM{
+			InputCostPerMillion:     0.15,
+			OutputCostPerMillion:    0.60,
+			EmbeddingCostPerMillion: 0.02,
+		},
+		Extraction: Extraction{
+			Engine:  "regex",
+			Model:   "gpt-4o-mini",
+			Timeout: 20 * time.Second,
+		},
+		Transcription: Transcription{
+			Provider: "none",
+			Model:    "whisper-1",
+			Timeout:  time.Minute,
+		},
+		Attachments: Attachments{
+			Storage:      "database",
+			MaxSize:      5 << 20,
+			MaxPerMemory: 10,
+			S3: ObjectStorage{
+				Region:  "us-east-1",
+				Timeout: 30 * time.Second,
+			},
+		},
+		EmbeddingBackfill: EmbeddingBackfill{
+			Enabled:     true,
+			Interval:    time.Minute,
+			BatchSize:   50,
+			MaxAttempts: 10,
+			MaxBackoff:  6 * time.Hour,
+		},
+		Maintenance: Maintenance{
+			Enabled:    true,
+			Interval:   24 * time.Hour,
+			StaleAfter: 180 * 24 * time.Hour,
+			MaxActions: 100,
+		},
+		Consolidation: Consolidation{
+			Enabled:        false,
+			Interval:       24 * time.Hour,
+			Model:          "gpt-4o-mini",
+			Timeout:        30 * time.Second,
+			Similarity:     0.85,
+			MinClusterSize: 3,
+			MaxClusterSize: 10,
+			MaxClusters:    10,
+		},
+		SearchCache: SearchCache{
+			Enabled:    true,
+			Embeddings: 1000,
+			Results:    1000,
+			ResultTTL:  5 * time.Minute,
+		},
+		Audit: Audit{
+			MemoryAccess: true,
+		},
+		Retention: Retention{
+			Enabled:            true,
+			Interval:           24 * time.Hour,
+			ActivityLogs:       90 * 24 * time.Hour,
+			PerformanceMetrics: 90 * 24 * time.Hour,
+		},
+		Timeouts: Timeouts{
+			Default: 30 * time.Second,
+			Routes: map[string]time.Duration{
+				"POST /api/v1/memories/import":      5 * time.Minute,
+				"PATCH /api/v1/memories":            2 * time.Minute,
+				"GET /api/v1/memories/export":       5 * time.Minute,
+				"POST /api/v1/memories/process":     2 * time.Minute,
+				"POST /api/v1/memories/consolidate": 5 * time.Minute,
+				"POST /api/v1/capture/voice":        2 * time.Minute,
+				"GET /api/v1/memories/stats":        10 * time.Second,
+			},
+			Tools: map[string]time.Duration{
+				"store_memories_bulk":  5 * time.Minute,
+				"update_memories_bulk": 2 * time.Minute,
+				"export_memories":      5 * time.Minute,
+				"import_memories":      5 * time.Minute,
+				"process_content":      2 * time.Minute,
+				"consolidate_memories": 5 * time.Minute,
+			},
+			Write: 30 * time.Second,
+		},
+		Tracing: Tracing{
+			SampleRatio: 1,
+		},
+		Backup: Backup{
+			Enabled:           false,
+			Schedule:          "0 3 * * *",
+			Destination:       "local",
+			Path:              "./backups",
+			S3:                ObjectStorage{Region: "us-east-1", Timeout: 5 * time.Minute},
+			IncludeEmbeddings: true,
+			Keep:              7,
 		},
 	}
 }
@@ -135,6 +886,15 @@ func (c *Config) Validate() error {
 	if c.Database.MaxIdleConns > c.Database.MaxConnections {
 		return fmt.Errorf("max idle connections cannot exceed max connections")
 	}
+	switch c.Database.VectorIndex.Type {
+	case "", "hnsw", "ivfflat", "none":
+	default:
+		return fmt.Errorf("invalid vector index type: %s", c.Database.VectorIndex.Type)
+	}
+	if c.Database.VectorIndex.M < 0 || c.Database.VectorIndex.EfConstruction < 0 || c.Database.VectorIndex.EfSearch < 0 ||
+		c.Database.VectorIndex.Lists < 0 || c.Database.VectorIndex.Probes < 0 {
+		return fmt.Errorf("vector index parameters cannot be negative")
+	}
 
 	// OpenAI validation - API key is optional, will use mock if not provided
 	if c.OpenAI.Model == "" {
@@ -147,6 +907,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	// Embedding validation - providers are checked when the service is created
+	if c.Embedding.Dimensions < 0 || c.Embedding.Dimensions > MaxEmbeddingDimensions {
+		return fmt.Errorf("embedding dimensions must be between 0 and %d", MaxEmbeddingDimensions)
+	}
+	if weights := c.Embedding.Document.Weights; len(weights) > 0 {
+		total := 0.0
+		for field, weight := range weights {
+			switch field {
+			case "content", "tags", "category", "type":
+			default:
+				return fmt.Errorf("invalid field in embedding document weights: %s", field)
+			}
+			if weight < 0 {
+				return fmt.Errorf("embedding document weight for %s cannot be negative", field)
+			}
+			total += weight
+		}
+		if total == 0 {
+			return fmt.Errorf("embedding document weights must include a positive weight")
+		}
+	}
+
 	// Memory validation
 	if c.Memory.MaxMemories <= 0 {
 		return fmt.Errorf("max memories must be greater than 0")
@@ -154,6 +936,49 @@ func (c *Config) Validate() error {
 	if c.Memory.SimilarityThreshold < 0 || c.Memory.SimilarityThreshold > 1 {
 		return fmt.Errorf("similarity threshold must be between 0 and 1")
 	}
+	for priority, boost := range c.Memory.PriorityBoosts {
+		switch priority {
+		case "low", "medium", "high", "critical":
+		default:
+			return fmt.Errorf("invalid priority in priority boosts: %s", priority)
+		}
+		if boost < -1 || boost > 1 {
+			return fmt.Errorf("priority boost for %s must be between -1 and 1", priority)
+		}
+	}
+	switch c.Memory.EvictionPolicy {
+	case "", "oldest_first", "least_accessed", "least_recently_used", "lowest_priority", "reject_new",
+		"oldest", "lowest_access_count", "least_recently_accessed":
+	default:
+		return fmt.Errorf("invalid eviction policy: %s", c.Memory.EvictionPolicy)
+	}
+	switch c.Memory.DuplicateAction {
+	case "", "merge", "update", "reject":
+	default:
+		return fmt.Errorf("invalid duplicate action: %s", c.Memory.DuplicateAction)
+	}
+	if c.Memory.DuplicateThreshold < 0 || c.Memory.DuplicateThreshold > 1 {
+		return fmt.Errorf("duplicate threshold must be between 0 and 1")
+	}
+	if c.Memory.FeedbackWeight < 0 || c.Memory.FeedbackWeight > 1 {
+		return fmt.Errorf("feedback weight must be between 0 and 1")
+	}
+	for plan, limit := range c.Memory.Plans {
+		if limit < 0 {
+			return fmt.Errorf("memory limit for plan %s must not be negative", plan)
+		}
+	}
+	if c.Memory.MaxContentLength < 0 {
+		return fmt.Errorf("max content length must not be negative")
+	}
+	if c.Memory.ChunkSize < 0 || (c.Memory.ChunkSize > 0 && c.Memory.ChunkSize < 100) {
+		return fmt.Errorf("chunk size must be 0 or at least 100 characters")
+	}
+	for _, filler := range c.Memory.FillerWords {
+		if strings.TrimSpace(filler) == "" {
+			return fmt.Errorf("filler words must not be empty")
+		}
+	}
 
 	// Server validation
 	validLogLevels := map[string]bool{
@@ -171,16 +996,313 @@ func (c *Config) Validate() error {
 	if c.JWT.Secret == "" {
 		return fmt.Errorf("JWT secret cannot be empty")
 	}
+	if c.JWT.AccessTTL < 0 || c.JWT.RefreshTTL < 0 {
+		return fmt.Errorf("JWT token lifetimes must not be negative")
+	}
 
 	// HTTP validation
 	if c.HTTP.Port <= 0 || c.HTTP.Port > 65535 {
 		return fmt.Errorf("HTTP port must be between 1 and 65535")
 	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trusted proxy %q must be an IP address or CIDR range", proxy)
+		}
+	}
+	if rl := c.HTTP.RateLimit; rl.Enabled {
+		if rl.APIKeyRate < 0 || rl.IPRate < 0 {
+			return fmt.Errorf("rate limits cannot be negative")
+		}
+		if (rl.APIKeyRate > 0 && rl.APIKeyBurst < 1) || (rl.IPRate > 0 && rl.IPBurst < 1) {
+			return fmt.Errorf("rate limit bursts must be at least 1")
+		}
+	}
 
 	// Encryption validation
 	if c.Encryption.Enabled && c.Encryption.MasterKey == "" {
 		return fmt.Errorf("encryption master key is required when encryption is enabled")
 	}
+	switch c.Encryption.KMS.Provider {
+	case "":
+		if c.Encryption.MasterKey != "" && len(c.Encryption.PreviousKeys) > 0 {
+			if _, err := utils.NewEncryptionService(c.Encryption.MasterKey, c.Encryption.PreviousKeys...); err != nil {
+				return fmt.Errorf("invalid encryption keys: %w", err)
+			}
+		}
+	case "aws", "gcp", "vault":
+		kms := c.Encryption.KMS
+		if kms.Timeout <= 0 {
+			return fmt.Errorf("KMS timeout must be positive")
+		}
+		switch {
+		case kms.Provider != "aws" && kms.KeyID == "":
+			return fmt.Errorf("KMS key ID is required for %s", kms.Provider)
+		case kms.Provider == "aws" && (kms.AccessKeyID == "") != (kms.SecretAccessKey == ""):
+			return fmt.Errorf("KMS access key ID and secret access key must be set together for aws")
+		case kms.Provider == "vault" && (kms.Endpoint == "" || kms.Token == ""):
+			return fmt.Errorf("KMS endpoint and token are required for vault")
+		}
+	default:
+		return fmt.Errorf("invalid KMS provider: %s", c.Encryption.KMS.Provider)
+	}
+
+	// Residency validation
+	if c.Residency.Region != "" && !residencyRegionPattern.MatchString(c.Residency.Region) {
+		return fmt.Errorf("residency region must be 1-32 letters, digits or hyphens: %s", c.Residency.Region)
+	}
+
+	// Alerts validation
+	if c.Alerts.Enabled {
+		if c.Alerts.DeleteSpikeThreshold <= 0 {
+			return fmt.Errorf("alert delete spike threshold must be greater than 0")
+		}
+		if c.Alerts.DeleteSpikeWindow <= 0 {
+			return fmt.Errorf("alert delete spike window must be positive")
+		}
+		if c.Alerts.Email.SMTPHost != "" && (c.Alerts.Email.From == "" || len(c.Alerts.Email.To) == 0) {
+			return fmt.Errorf("alert email requires from and to addresses")
+		}
+	}
+
+	// Embedding backfill validation
+	if c.EmbeddingBackfill.Enabled {
+		if c.EmbeddingBackfill.Interval <= 0 {
+			return fmt.Errorf("embedding backfill interval must be positive")
+		}
+		if c.EmbeddingBackfill.BatchSize <= 0 {
+			return fmt.Errorf("embedding backfill batch size must be greater than 0")
+		}
+		if c.EmbeddingBackfill.MaxAttempts <= 0 {
+			return fmt.Errorf("embedding backfill max attempts must be greater than 0")
+		}
+	}
+
+	// Maintenance validation
+	if c.Maintenance.Enabled && c.Maintenance.Interval <= 0 {
+		return fmt.Errorf("maintenance interval must be positive")
+	}
+	if c.Maintenance.StaleAfter < 0 {
+		return fmt.Errorf("maintenance stale_after must not be negative")
+	}
+	if c.Maintenance.MaxActions < 0 {
+		return fmt.Errorf("maintenance max actions must not be negative")
+	}
+
+	// Consolidation validation
+	if c.Consolidation.Enabled {
+		if c.OpenAI.APIKey == "" {
+			return fmt.Errorf("OpenAI API key is required for scheduled consolidation")
+		}
+		if c.Consolidation.Interval <= 0 {
+			return fmt.Errorf("consolidation interval must be positive")
+		}
+	}
+	if c.Consolidation.Similarity < 0 || c.Consolidation.Similarity > 1 {
+		return fmt.Errorf("consolidation similarity must be between 0 and 1")
+	}
+	if c.Consolidation.MinClusterSize != 0 && c.Consolidation.MinClusterSize < 2 {
+		return fmt.Errorf("consolidation min cluster size must be at least 2")
+	}
+	if c.Consolidation.MaxClusterSize != 0 && c.Consolidation.MaxClusterSize < c.Consolidation.MinClusterSize {
+		return fmt.Errorf("consolidation max cluster size must not be below the min cluster size")
+	}
+	if c.Consolidation.MaxClusters < 0 {
+		return fmt.Errorf("consolidation max clusters must not be negative")
+	}
+
+	// Search cache validation
+	if c.SearchCache.Embeddings < 0 || c.SearchCache.Results < 0 {
+		return fmt.Errorf("search cache sizes must not be negative")
+	}
+	if c.SearchCache.ResultTTL < 0 {
+		return fmt.Errorf("search cache result_ttl must not be negative")
+	}
+
+	// Retention validation
+	if c.Retention.Enabled && c.Retention.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	// Search stats count the current month from the activity logs
+	if c.Retention.ActivityLogs != 0 && c.Retention.ActivityLogs < 31*24*time.Hour {
+		return fmt.Errorf("retention activity_logs must be 0 or at least 31 days")
+	}
+	if c.Retention.PerformanceMetrics != 0 && c.Retention.PerformanceMetrics < 24*time.Hour {
+		return fmt.Errorf("retention performance_metrics must be 0 or at least 24h")
+	}
+
+	// Dual write validation
+	if c.DualWrite.Enabled {
+		if c.DualWrite.Target.Host == "" || c.DualWrite.Target.DBName == "" {
+			return fmt.Errorf("dual write target host and database name are required")
+		}
+		if c.DualWrite.Target.Host == c.Database.Host && c.DualWrite.Target.Port == c.Database.Port && c.DualWrite.Target.DBName == c.Database.DBName {
+			return fmt.Errorf("dual write target must differ from the primary database")
+		}
+	}
+
+	// Moderation validation
+	if c.Moderation.Enabled {
+		switch c.Moderation.Provider {
+		case "openai":
+			if c.OpenAI.APIKey == "" {
+				return fmt.Errorf("OpenAI API key is required for the openai moderation provider")
+			}
+		case "rules":
+			if len(c.Moderation.Rules) == 0 {
+				return fmt.Errorf("moderation rules are required for the rules moderation provider")
+			}
+			for category, patterns := range c.Moderation.Rules {
+				for _, pattern := range patterns {
+					if _, err := regexp.Compile(pattern); err != nil {
+						return fmt.Errorf("invalid moderation rule for %s: %w", category, err)
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("invalid moderation provider: %s", c.Moderation.Provider)
+		}
+		for category, action := range c.Moderation.Actions {
+			switch action {
+			case "block", "flag":
+			case "encrypt":
+				if c.Encryption.MasterKey == "" {
+					return fmt.Errorf("encryption master key is required to encrypt moderated category %s", category)
+				}
+			default:
+				return fmt.Errorf("invalid moderation action for %s: %s", category, action)
+			}
+		}
+	}
+
+	// Extraction validation
+	switch c.Extraction.Engine {
+	case "regex":
+	case "llm":
+		if c.OpenAI.APIKey == "" {
+			return fmt.Errorf("OpenAI API key is required for the llm extraction engine")
+		}
+		if c.Extraction.Model == "" {
+			return fmt.Errorf("extraction model is required for the llm extraction engine")
+		}
+		if c.Extraction.Timeout <= 0 {
+			return fmt.Errorf("extraction timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid extraction engine: %s", c.Extraction.Engine)
+	}
+
+	// Transcription validation
+	switch c.Transcription.Provider {
+	case "", "none":
+	case "openai", "whisper":
+		if c.Transcription.Provider == "openai" && c.OpenAI.APIKey == "" {
+			return fmt.Errorf("OpenAI API key is required for openai transcription")
+		}
+		if c.Transcription.Provider == "whisper" && c.Transcription.BaseURL == "" {
+			return fmt.Errorf("transcription base URL is required for whisper transcription")
+		}
+		if c.Transcription.Model == "" {
+			return fmt.Errorf("transcription model is required")
+		}
+		if c.Transcription.Timeout <= 0 {
+			return fmt.Errorf("transcription timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid transcription provider: %s", c.Transcription.Provider)
+	}
+
+	// Attachments validation
+	if c.Attachments.MaxSize <= 0 {
+		return fmt.Errorf("attachment max size must be positive")
+	}
+	if c.Attachments.MaxPerMemory < 0 {
+		return fmt.Errorf("attachments per memory cannot be negative")
+	}
+	switch c.Attachments.Storage {
+	case "", "database":
+	case "s3":
+		if err := c.Attachments.S3.Validate("attachments S3"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid attachment storage: %s", c.Attachments.Storage)
+	}
+
+	// Backup validation
+	if c.Backup.Enabled {
+		if _, err := utils.ParseCronSchedule(c.Backup.Schedule); err != nil {
+			return fmt.Errorf("invalid backup schedule: %w", err)
+		}
+		switch c.Backup.Destination {
+		case "local":
+			if c.Backup.Path == "" {
+				return fmt.Errorf("backup path is required for local backups")
+			}
+		case "s3", "gcs":
+			if err := c.Backup.Bucket().Validate("backup " + c.Backup.Destination); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid backup destination: %s", c.Backup.Destination)
+		}
+		if c.Backup.Encrypt && c.Backup.EncryptionKey == "" && c.Encryption.MasterKey == "" {
+			return fmt.Errorf("backup encryption key or encryption master key is required when backup encryption is enabled")
+		}
+		if c.Backup.Keep < 0 {
+			return fmt.Errorf("backups kept cannot be negative")
+		}
+	}
+
+	// LLM budget validation
+	if c.LLM.DailyBudgetUSD < 0 || c.LLM.UserDailyBudgetUSD < 0 {
+		return fmt.Errorf("LLM budgets cannot be negative")
+	}
+	if c.LLM.InputCostPerMillion < 0 || c.LLM.OutputCostPerMillion < 0 || c.LLM.EmbeddingCostPerMillion < 0 {
+		return fmt.Errorf("LLM token costs cannot be negative")
+	}
+
+	// Timeout validation - zero means no deadline
+	if c.Timeouts.Default < 0 || c.Timeouts.Write < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
+	for route, timeout := range c.Timeouts.Routes {
+		if len(strings.Fields(route)) != 2 {
+			return fmt.Errorf("invalid route in timeouts: %q must be a method and a path", route)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("timeout for route %s cannot be negative", route)
+		}
+	}
+	for tool, timeout := range c.Timeouts.Tools {
+		if timeout < 0 {
+			return fmt.Errorf("timeout for tool %s cannot be negative", tool)
+		}
+	}
+
+	// Tracing validation
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+
+	// Client profile validation
+	for i, profile := range c.ClientProfiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			return fmt.Errorf("client profile %d needs a name", i)
+		}
+		if profile.DefaultLimit < 0 || profile.MaxLimit < 0 || profile.MaxContentLength < 0 {
+			return fmt.Errorf("limits of client profile %s cannot be negative", profile.Name)
+		}
+		switch profile.Schemas {
+		case "", "full", "minimal":
+		default:
+			return fmt.Errorf("invalid schemas for client profile %s: %s (must be full or minimal)", profile.Name, profile.Schemas)
+		}
+		switch profile.Format {
+		case "", "text", "json", "markdown":
+		default:
+			return fmt.Errorf("invalid format for client profile %s: %s (must be text, json or markdown)", profile.Name, profile.Format)
+		}
+	}
 
 	return nil
 }
@@ -208,4 +1330,4 @@ func (c *Config) DatabaseURL() string {
 	}
 
 	return u.String()
-}
\ No newline at end of file
+}
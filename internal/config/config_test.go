@@ -221,6 +221,128 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "similarity threshold must be between 0 and 1",
 		},
+		{
+			name: "Invalid eviction policy",
+			config: Config{
+				Database: Database{
+					Host:           "localhost",
+					Port:           5432,
+					User:           "test",
+					DBName:         "test",
+					MaxConnections: 25,
+				},
+				OpenAI: OpenAI{
+					APIKey:  "test-key",
+					Model:   "text-embedding-3-small",
+					Timeout: 30 * time.Second,
+				},
+				Memory: Memory{
+					MaxMemories:    1000,
+					EvictionPolicy: "newest_first",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid eviction policy",
+		},
+		{
+			name: "Moderation encrypt action without master key",
+			config: Config{
+				Database: Database{
+					Host:           "localhost",
+					Port:           5432,
+					User:           "test",
+					DBName:         "test",
+					MaxConnections: 25,
+				},
+				OpenAI: OpenAI{
+					APIKey:  "test-key",
+					Model:   "text-embedding-3-small",
+					Timeout: 30 * time.Second,
+				},
+				Memory: Memory{
+					MaxMemories: 1000,
+				},
+				Server: Server{LogLevel: "info"},
+				JWT:    JWT{Secret: "secret"},
+				HTTP:   HTTP{Port: 8082},
+				Moderation: Moderation{
+					Enabled:  true,
+					Provider: "rules",
+					Rules:    map[string][]string{"secrets": {`(?i)password`}},
+					Actions:  map[string]string{"secrets": "encrypt"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "encryption master key is required",
+		},
+		{
+			name: "Trusted proxies",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.HTTP.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10", "::1"}
+				return c
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "Invalid trusted proxy",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.HTTP.TrustedProxies = []string{"load-balancer"}
+				return c
+			}(),
+			wantErr: true,
+			errMsg:  "trusted proxy",
+		},
+		{
+			name: "Unknown KMS provider",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.Encryption.KMS.Provider = "hsm"
+				return c
+			}(),
+			wantErr: true,
+			errMsg:  "invalid KMS provider",
+		},
+		{
+			name: "AWS KMS with the default credential chain",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.Encryption.KMS.Provider = "aws"
+				c.Encryption.KMS.KeyID = "alias/remember-me"
+				return c
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "AWS KMS with half a static key",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.Encryption.KMS.Provider = "aws"
+				c.Encryption.KMS.AccessKeyID = "AKID"
+				return c
+			}(),
+			wantErr: true,
+			errMsg:  "must be set together",
+		},
+		{
+			name: "Vault KMS without token",
+			config: func() Config {
+				c := *NewDefault()
+				c.OpenAI.APIKey = "test-api-key"
+				c.Encryption.KMS.Provider = "vault"
+				c.Encryption.KMS.KeyID = "remember-me"
+				c.Encryption.KMS.Endpoint = "https://vault.example.com"
+				return c
+			}(),
+			wantErr: true,
+			errMsg:  "KMS endpoint and token are required for vault",
+		},
 	}
 
 	for _, tt := range tests {
@@ -521,4 +643,38 @@ func TestNewDefault(t *testing.T) {
 	// Default config should validate (API key is optional)
 	err := config.Validate()
 	assert.NoError(t, err)
-}
\ No newline at end of file
+}
+func TestTimeouts(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "timeouts.yaml")
+	configContent := `
+jwt:
+  secret: test-secret
+timeouts:
+  default: 20s
+  routes:
+    "POST /api/v1/memories/import": 10m
+    "GET /api/v1/memories/:id/history": 0
+  tools:
+    store_memories_bulk: 3m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	timeouts := config.Timeouts
+
+	assert.Equal(t, 10*time.Minute, timeouts.Route("POST", "/api/v1/memories/import"))
+	assert.Zero(t, timeouts.Route("GET", "/api/v1/memories/:id/history"))
+	assert.Equal(t, 20*time.Second, timeouts.Route("GET", "/api/v1/memories"))
+	assert.Zero(t, timeouts.Route("POST", "/api/v1/mcp"), "the MCP endpoint is bounded per tool")
+	assert.Equal(t, 3*time.Minute, timeouts.Tool("store_memories_bulk"))
+	assert.Equal(t, 20*time.Second, timeouts.Tool("process_content"), "configured tools replace the defaults")
+	assert.Equal(t, 30*time.Second, timeouts.Write)
+
+	assert.Equal(t, 10*time.Minute, timeouts.Connection("POST", "/api/v1/memories/import"))
+	assert.Equal(t, 20*time.Second, timeouts.Connection("GET", "/api/v1/memories"))
+	assert.Equal(t, 3*time.Minute, timeouts.Connection("POST", "/api/v1/mcp"), "the MCP endpoint allows the slowest tool")
+
+	config.Timeouts.Routes["/api/v1/memories"] = time.Second
+	assert.Error(t, config.Validate())
+}
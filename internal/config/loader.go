@@ -75,6 +75,24 @@ func LoadConfig(configPath string) (*Config, error) {
 		fmt.Printf("DEBUG: Set http.allow_origins to %v\n", originList)
 	}
 
+	// Handle trusted proxies as comma-separated list
+	if proxies := os.Getenv("REMEMBER_ME_HTTP_TRUSTED_PROXIES"); proxies != "" {
+		proxyList := strings.Split(proxies, ",")
+		for i := range proxyList {
+			proxyList[i] = strings.TrimSpace(proxyList[i])
+		}
+		v.Set("http.trusted_proxies", proxyList)
+	}
+
+	// Handle previous encryption master keys as comma-separated list
+	if keys := os.Getenv("ENCRYPTION_PREVIOUS_KEYS"); keys != "" {
+		keyList := strings.Split(keys, ",")
+		for i := range keyList {
+			keyList[i] = strings.TrimSpace(keyList[i])
+		}
+		v.Set("encryption.previous_keys", keyList)
+	}
+
 	// Unmarshal configuration
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
@@ -104,29 +122,197 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.max_idle_conns", 5)
 	v.SetDefault("database.conn_max_lifetime", "1h")
 	v.SetDefault("database.conn_max_idle_time", "10m")
+	v.SetDefault("database.vector_index.type", "hnsw")
+	v.SetDefault("database.vector_index.m", 16)
+	v.SetDefault("database.vector_index.ef_construction", 64)
+	v.SetDefault("database.vector_index.ef_search", 40)
+	v.SetDefault("database.vector_index.lists", 100)
+	v.SetDefault("database.vector_index.probes", 1)
 
 	// OpenAI defaults
 	v.SetDefault("openai.model", "text-embedding-3-small")
 	v.SetDefault("openai.max_retries", 3)
 	v.SetDefault("openai.timeout", 30)
+	v.SetDefault("openai.batch_size", 100)
+	v.SetDefault("openai.batch_window", "50ms")
+
+	// Embedding provider defaults
+	v.SetDefault("embedding.provider", "openai")
+	v.SetDefault("embedding.dimensions", 0)
+	v.SetDefault("embedding.cache", true)
 
 	// Memory defaults
 	v.SetDefault("memory.max_memories", 1000)
 	v.SetDefault("memory.similarity_threshold", 0.7)
+	v.SetDefault("memory.priority_boosts", map[string]float64{
+		"low":      0,
+		"medium":   0.02,
+		"high":     0.05,
+		"critical": 0.1,
+	})
+	v.SetDefault("memory.eviction_policy", "oldest_first")
+	v.SetDefault("memory.context_buffer_ttl", "30m")
+	v.SetDefault("memory.feedback_weight", 0.05)
+	v.SetDefault("memory.exact_counts", false)
+	v.SetDefault("memory.normalize_detected", false)
+	v.SetDefault("memory.max_content_length", 20000)
+	v.SetDefault("memory.chunk_size", 2000)
 
 	// Server defaults
 	v.SetDefault("server.log_level", "info")
 	v.SetDefault("server.debug", false)
+	v.SetDefault("server.warm_up", false)
 	
 	// JWT defaults
 	v.SetDefault("jwt.secret", "")
+	v.SetDefault("jwt.access_ttl", "24h")
+	v.SetDefault("jwt.refresh_ttl", "720h")
 	
 	// HTTP defaults
 	v.SetDefault("http.port", 8082)
+	v.SetDefault("http.rate_limit.enabled", true)
+	v.SetDefault("http.rate_limit.api_key_rate", 10)
+	v.SetDefault("http.rate_limit.api_key_burst", 20)
+	v.SetDefault("http.rate_limit.ip_rate", 20)
+	v.SetDefault("http.rate_limit.ip_burst", 40)
 	
 	// Encryption defaults
 	v.SetDefault("encryption.enabled", false)
 	v.SetDefault("encryption.master_key", "")
+	v.SetDefault("encryption.previous_keys", []string{})
+	v.SetDefault("encryption.kms.provider", "")
+	v.SetDefault("encryption.kms.key_id", "")
+	v.SetDefault("encryption.kms.endpoint", "")
+	v.SetDefault("encryption.kms.region", "")
+	v.SetDefault("encryption.kms.access_key_id", "")
+	v.SetDefault("encryption.kms.secret_access_key", "")
+	v.SetDefault("encryption.kms.session_token", "")
+	v.SetDefault("encryption.kms.token", "")
+	v.SetDefault("encryption.kms.mount", "transit")
+	v.SetDefault("encryption.kms.timeout", "10s")
+
+	// Alert defaults
+	v.SetDefault("alerts.enabled", false)
+	v.SetDefault("alerts.delete_spike_threshold", 20)
+	v.SetDefault("alerts.delete_spike_window", "10m")
+	v.SetDefault("alerts.api_key_learning_uses", 50)
+	v.SetDefault("alerts.email.smtp_port", 587)
+
+	// Embedding backfill defaults
+	v.SetDefault("embedding_backfill.enabled", true)
+	v.SetDefault("embedding_backfill.interval", "1m")
+	v.SetDefault("embedding_backfill.batch_size", 50)
+	v.SetDefault("embedding_backfill.max_attempts", 10)
+	v.SetDefault("embedding_backfill.max_backoff", "6h")
+
+	// Maintenance agent defaults
+	v.SetDefault("maintenance.enabled", true)
+	v.SetDefault("maintenance.interval", "24h")
+	v.SetDefault("maintenance.stale_after", "4320h")
+	v.SetDefault("maintenance.max_actions", 100)
+
+	// Consolidation defaults: on demand only
+	v.SetDefault("consolidation.enabled", false)
+	v.SetDefault("consolidation.interval", "24h")
+	v.SetDefault("consolidation.model", "gpt-4o-mini")
+	v.SetDefault("consolidation.timeout", "30s")
+	v.SetDefault("consolidation.similarity", 0.85)
+	v.SetDefault("consolidation.min_cluster_size", 3)
+	v.SetDefault("consolidation.max_cluster_size", 10)
+	v.SetDefault("consolidation.max_clusters", 10)
+
+	// Search cache defaults
+	v.SetDefault("search_cache.enabled", true)
+	v.SetDefault("search_cache.embeddings", 1000)
+	v.SetDefault("search_cache.results", 1000)
+	v.SetDefault("search_cache.result_ttl", "5m")
+
+	// Audit defaults: record memory access
+	v.SetDefault("audit.memory_access", true)
+
+	// Retention defaults: 90 days of raw activity, rolled up daily
+	v.SetDefault("retention.enabled", true)
+	v.SetDefault("retention.interval", "24h")
+	v.SetDefault("retention.activity_logs", "2160h")
+	v.SetDefault("retention.performance_metrics", "2160h")
+
+	// Migration defaults: back up rewritten tables automatically
+	v.SetDefault("migrations.require_backup_confirmation", false)
+
+	// Dual write defaults
+	v.SetDefault("dual_write.enabled", false)
+	v.SetDefault("dual_write.target.port", 5432)
+	v.SetDefault("dual_write.target.sslmode", "disable")
+	v.SetDefault("dual_write.target.max_connections", 10)
+	v.SetDefault("dual_write.target.max_idle_conns", 2)
+	v.SetDefault("dual_write.target.conn_max_lifetime", "1h")
+	v.SetDefault("dual_write.target.conn_max_idle_time", "10m")
+
+	// Moderation defaults
+	v.SetDefault("moderation.enabled", false)
+	v.SetDefault("moderation.provider", "rules")
+	v.SetDefault("moderation.model", "omni-moderation-latest")
+
+	// Extraction defaults: the built-in patterns
+	v.SetDefault("extraction.engine", "regex")
+	v.SetDefault("extraction.model", "gpt-4o-mini")
+	v.SetDefault("extraction.timeout", "20s")
+
+	// Transcription defaults: voice memos disabled
+	v.SetDefault("transcription.provider", "none")
+	v.SetDefault("transcription.model", "whisper-1")
+	v.SetDefault("transcription.timeout", "60s")
+
+	// Attachment defaults: up to 10 files of 5MB per memory, kept in the database
+	v.SetDefault("attachments.storage", "database")
+	v.SetDefault("attachments.max_size", 5<<20)
+	v.SetDefault("attachments.max_per_memory", 10)
+	v.SetDefault("attachments.s3.region", "us-east-1")
+	v.SetDefault("attachments.s3.timeout", "30s")
+
+	// Backup defaults: off; once enabled, daily at 03:00 UTC to ./backups, keeping 7
+	v.SetDefault("backup.enabled", false)
+	v.SetDefault("backup.schedule", "0 3 * * *")
+	v.SetDefault("backup.destination", "local")
+	v.SetDefault("backup.path", "./backups")
+	v.SetDefault("backup.s3.region", "us-east-1")
+	v.SetDefault("backup.s3.timeout", "5m")
+	v.SetDefault("backup.encrypt", false)
+	v.SetDefault("backup.include_embeddings", true)
+	v.SetDefault("backup.keep", 7)
+
+	// LLM defaults: no budgets, gpt-4o-mini pricing
+	v.SetDefault("llm.daily_budget_usd", 0)
+	v.SetDefault("llm.user_daily_budget_usd", 0)
+	v.SetDefault("llm.input_cost_per_million", 0.15)
+	v.SetDefault("llm.output_cost_per_million", 0.60)
+	v.SetDefault("llm.embedding_cost_per_million", 0.02)
+
+	// Timeout defaults: longer deadlines for bulk work, shorter for stats
+	v.SetDefault("timeouts.default", "30s")
+	v.SetDefault("timeouts.routes", map[string]string{
+		"POST /api/v1/memories/import":      "5m",
+		"PATCH /api/v1/memories":            "2m",
+		"GET /api/v1/memories/export":       "5m",
+		"POST /api/v1/memories/process":     "2m",
+		"POST /api/v1/memories/consolidate": "5m",
+		"POST /api/v1/capture/voice":        "2m",
+		"GET /api/v1/memories/stats":        "10s",
+	})
+	v.SetDefault("timeouts.tools", map[string]string{
+		"store_memories_bulk":  "5m",
+		"update_memories_bulk": "2m",
+		"export_memories":      "5m",
+		"import_memories":      "5m",
+		"process_content":      "2m",
+		"consolidate_memories": "5m",
+	})
+	v.SetDefault("timeouts.write", "30s")
+
+	// Tracing defaults: off, every trace sampled once enabled
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.insecure", false)
+	v.SetDefault("tracing.sample_ratio", 1.0)
 }
 
 // bindEnvVars binds specific environment variables to configuration keys
@@ -134,27 +320,104 @@ func bindEnvVars(v *viper.Viper) {
 	// OpenAI API key can be set via OPENAI_API_KEY or REMEMBER_ME_OPENAI_API_KEY
 	v.BindEnv("openai.api_key", "OPENAI_API_KEY", "REMEMBER_ME_OPENAI_API_KEY")
 
+	// Embedding provider
+	v.BindEnv("embedding.provider", "EMBEDDING_PROVIDER", "REMEMBER_ME_EMBEDDING_PROVIDER")
+	v.BindEnv("embedding.base_url", "EMBEDDING_BASE_URL", "REMEMBER_ME_EMBEDDING_BASE_URL")
+	v.BindEnv("embedding.model", "EMBEDDING_MODEL", "REMEMBER_ME_EMBEDDING_MODEL")
+	v.BindEnv("embedding.api_key", "EMBEDDING_API_KEY", "REMEMBER_ME_EMBEDDING_API_KEY")
+	v.BindEnv("embedding.cache", "EMBEDDING_CACHE", "REMEMBER_ME_EMBEDDING_CACHE")
+	v.BindEnv("search_cache.enabled", "SEARCH_CACHE", "REMEMBER_ME_SEARCH_CACHE")
+
+	// Attachment bucket credentials can come from the standard AWS variables
+	v.BindEnv("attachments.s3.access_key_id", "REMEMBER_ME_ATTACHMENTS_S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID")
+	v.BindEnv("attachments.s3.secret_access_key", "REMEMBER_ME_ATTACHMENTS_S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY")
+
+	// Backup bucket credentials and key are kept out of config files
+	v.BindEnv("backup.s3.access_key_id", "REMEMBER_ME_BACKUP_S3_ACCESS_KEY_ID")
+	v.BindEnv("backup.s3.secret_access_key", "REMEMBER_ME_BACKUP_S3_SECRET_ACCESS_KEY")
+	v.BindEnv("backup.encryption_key", "REMEMBER_ME_BACKUP_ENCRYPTION_KEY")
+
+	// Tracing can be switched on and pointed at a collector from the environment
+	v.BindEnv("tracing.enabled", "TRACING_ENABLED", "REMEMBER_ME_TRACING_ENABLED")
+	v.BindEnv("tracing.endpoint", "TRACING_ENDPOINT", "REMEMBER_ME_TRACING_ENDPOINT")
+
 	// Log level can be set via LOG_LEVEL or REMEMBER_ME_SERVER_LOG_LEVEL
 	v.BindEnv("server.log_level", "LOG_LEVEL", "REMEMBER_ME_SERVER_LOG_LEVEL")
 
 	// Memory limit can be set via MEMORY_LIMIT or REMEMBER_ME_MEMORY_MAX_MEMORIES
 	v.BindEnv("memory.max_memories", "MEMORY_LIMIT", "REMEMBER_ME_MEMORY_MAX_MEMORIES")
 
+	// pgvector index type
+	v.BindEnv("database.vector_index.type", "VECTOR_INDEX_TYPE", "REMEMBER_ME_DATABASE_VECTOR_INDEX_TYPE")
+
+	// Default eviction policy at the memory limit
+	v.BindEnv("memory.eviction_policy", "MEMORY_EVICTION_POLICY", "REMEMBER_ME_MEMORY_EVICTION_POLICY")
+
 	// Debug mode
 	v.BindEnv("server.debug", "DEBUG", "REMEMBER_ME_SERVER_DEBUG")
 	
+	// Startup warm-up
+	v.BindEnv("server.warm_up", "WARM_UP", "REMEMBER_ME_SERVER_WARM_UP")
+	
 	// JWT secret
 	v.BindEnv("jwt.secret", "JWT_SECRET", "REMEMBER_ME_JWT_SECRET")
 	
-	// HTTP port
+	// HTTP listen address
+	v.BindEnv("http.host", "HTTP_HOST", "REMEMBER_ME_HTTP_HOST")
 	v.BindEnv("http.port", "HTTP_PORT", "REMEMBER_ME_HTTP_PORT")
 	
 	// CORS allowed origins
 	v.BindEnv("http.allow_origins", "CORS_ALLOWED_ORIGINS", "REMEMBER_ME_HTTP_ALLOW_ORIGINS")
+
+	// Rate limiting
+	v.BindEnv("http.rate_limit.enabled", "RATE_LIMIT_ENABLED", "REMEMBER_ME_HTTP_RATE_LIMIT_ENABLED")
 	
 	// Encryption settings
 	v.BindEnv("encryption.enabled", "ENCRYPTION_ENABLED", "REMEMBER_ME_ENCRYPTION_ENABLED")
 	v.BindEnv("encryption.master_key", "ENCRYPTION_MASTER_KEY", "REMEMBER_ME_ENCRYPTION_MASTER_KEY")
+	v.BindEnv("encryption.kms.provider", "ENCRYPTION_KMS_PROVIDER", "REMEMBER_ME_ENCRYPTION_KMS_PROVIDER")
+	v.BindEnv("encryption.kms.key_id", "ENCRYPTION_KMS_KEY_ID", "REMEMBER_ME_ENCRYPTION_KMS_KEY_ID")
+	v.BindEnv("encryption.kms.endpoint", "ENCRYPTION_KMS_ENDPOINT", "VAULT_ADDR", "REMEMBER_ME_ENCRYPTION_KMS_ENDPOINT")
+	v.BindEnv("encryption.kms.region", "ENCRYPTION_KMS_REGION", "AWS_REGION", "REMEMBER_ME_ENCRYPTION_KMS_REGION")
+	// The AWS_* credential variables are left to the AWS SDK's credential chain
+	v.BindEnv("encryption.kms.access_key_id", "REMEMBER_ME_ENCRYPTION_KMS_ACCESS_KEY_ID")
+	v.BindEnv("encryption.kms.secret_access_key", "REMEMBER_ME_ENCRYPTION_KMS_SECRET_ACCESS_KEY")
+	v.BindEnv("encryption.kms.session_token", "REMEMBER_ME_ENCRYPTION_KMS_SESSION_TOKEN")
+	v.BindEnv("encryption.kms.token", "ENCRYPTION_KMS_TOKEN", "VAULT_TOKEN", "REMEMBER_ME_ENCRYPTION_KMS_TOKEN")
+	
+	// Alert settings
+	v.BindEnv("alerts.enabled", "ALERTS_ENABLED", "REMEMBER_ME_ALERTS_ENABLED")
+	v.BindEnv("alerts.webhook_url", "ALERTS_WEBHOOK_URL", "REMEMBER_ME_ALERTS_WEBHOOK_URL")
+	v.BindEnv("alerts.email.password", "ALERTS_SMTP_PASSWORD", "REMEMBER_ME_ALERTS_EMAIL_PASSWORD")
+	
+	// Embedding backfill settings
+	v.BindEnv("embedding_backfill.enabled", "EMBEDDING_BACKFILL_ENABLED", "REMEMBER_ME_EMBEDDING_BACKFILL_ENABLED")
+	v.BindEnv("embedding_backfill.interval", "EMBEDDING_BACKFILL_INTERVAL", "REMEMBER_ME_EMBEDDING_BACKFILL_INTERVAL")
+	
+	// Migration backups
+	v.BindEnv("migrations.require_backup_confirmation", "MIGRATIONS_REQUIRE_BACKUP_CONFIRMATION", "REMEMBER_ME_MIGRATIONS_REQUIRE_BACKUP_CONFIRMATION")
+	
+	// GeoIP database
+	v.BindEnv("geoip.database_path", "GEOIP_DATABASE_PATH", "REMEMBER_ME_GEOIP_DATABASE_PATH")
+	
+	// Data residency
+	v.BindEnv("residency.region", "RESIDENCY_REGION", "REMEMBER_ME_RESIDENCY_REGION")
+
+	// Audit
+	v.BindEnv("audit.memory_access", "AUDIT_MEMORY_ACCESS")
+	
+	// Dual write migration target
+	v.BindEnv("dual_write.enabled", "DUAL_WRITE_ENABLED", "REMEMBER_ME_DUAL_WRITE_ENABLED")
+	v.BindEnv("dual_write.target.host", "DUAL_WRITE_TARGET_HOST", "REMEMBER_ME_DUAL_WRITE_TARGET_HOST")
+	v.BindEnv("dual_write.target.password", "DUAL_WRITE_TARGET_PASSWORD", "REMEMBER_ME_DUAL_WRITE_TARGET_PASSWORD")
+	
+	// Content moderation
+	v.BindEnv("moderation.enabled", "MODERATION_ENABLED", "REMEMBER_ME_MODERATION_ENABLED")
+	v.BindEnv("moderation.provider", "MODERATION_PROVIDER", "REMEMBER_ME_MODERATION_PROVIDER")
+
+	// Memory extraction
+	v.BindEnv("extraction.engine", "EXTRACTION_ENGINE", "REMEMBER_ME_EXTRACTION_ENGINE")
+	v.BindEnv("extraction.model", "EXTRACTION_MODEL", "REMEMBER_ME_EXTRACTION_MODEL")
 }
 
 // parseDatabaseURL parses a PostgreSQL connection URL and sets individual database config values
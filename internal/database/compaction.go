@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// toastThreshold is roughly the stored size above which Postgres moves a value
+	// out of line into the table's TOAST relation
+	toastThreshold = 2000
+
+	// vacuumDeadRatio is the share of dead rows from which VACUUM is suggested
+	vacuumDeadRatio = 0.2
+	// vacuumMinDeadTuples keeps small tables from triggering suggestions
+	vacuumMinDeadTuples = 1000
+	// vacuumFullDeadRatio is the share of dead rows from which rewriting the
+	// table to return space to the operating system is suggested
+	vacuumFullDeadRatio = 0.5
+	// vacuumFullMinBytes keeps VACUUM FULL, which locks the table, for tables
+	// where the space is worth it
+	vacuumFullMinBytes = 64 << 20
+)
+
+// CompactionReport describes how much space the memory store takes and what
+// maintenance would reclaim some of it
+type CompactionReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Dialect is the database the report was built on. Table and index sizes and
+	// dead tuples are only reported for postgres.
+	Dialect string              `json:"dialect"`
+	Tables  []TableStorage      `json:"tables"`
+	Indexes []IndexStorage      `json:"indexes"`
+	Users   []UserStorage       `json:"users"`
+	Actions []MaintenanceAction `json:"actions"`
+}
+
+// TableStorage is the size and bloat of one table
+type TableStorage struct {
+	Table string `json:"table"`
+	// TotalBytes includes the table, its TOAST relation and its indexes
+	TotalBytes int64   `json:"total_bytes"`
+	TableBytes int64   `json:"table_bytes"`
+	ToastBytes int64   `json:"toast_bytes"`
+	IndexBytes int64   `json:"index_bytes"`
+	LiveTuples int64   `json:"live_tuples"`
+	DeadTuples int64   `json:"dead_tuples"`
+	DeadRatio  float64 `json:"dead_ratio"`
+	// LastVacuum is the latest manual or automatic vacuum
+	LastVacuum *time.Time `json:"last_vacuum,omitempty"`
+}
+
+// IndexStorage is the size of one index
+type IndexStorage struct {
+	Index string `json:"index"`
+	Table string `json:"table"`
+	Bytes int64  `json:"bytes"`
+	Scans int64  `json:"scans"`
+	// Vector is set for pgvector (hnsw or ivfflat) indexes
+	Vector bool `json:"vector"`
+}
+
+// UserStorage estimates the space one user's memories take. Sizes are of the
+// stored, possibly compressed, values; ToastBytes is the part of them large
+// enough to be stored out of line.
+type UserStorage struct {
+	UserID         uint  `json:"user_id"`
+	Memories       int64 `json:"memories"`
+	ContentBytes   int64 `json:"content_bytes"`
+	MetadataBytes  int64 `json:"metadata_bytes"`
+	EmbeddingBytes int64 `json:"embedding_bytes"`
+	ToastBytes     int64 `json:"toast_bytes"`
+	TotalBytes     int64 `json:"total_bytes"`
+}
+
+// MaintenanceAction is a statement suggested by a compaction report
+type MaintenanceAction struct {
+	Statement string `json:"statement"`
+	Reason    string `json:"reason"`
+	// Locking statements block reads and writes on the table while they run, so
+	// should only be run in a maintenance window
+	Locking bool `json:"locking"`
+}
+
+// MaintenanceResult is the outcome of running one maintenance action
+type MaintenanceResult struct {
+	MaintenanceAction
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// BuildCompactionReport reports table, TOAST, index and vector sizes with dead
+// tuple estimates, the topUsers users whose memories take the most space, and the
+// VACUUM and REINDEX statements worth running
+func BuildCompactionReport(ctx context.Context, db *gorm.DB, topUsers int) (*CompactionReport, error) {
+	report := &CompactionReport{
+		GeneratedAt: time.Now().UTC(),
+		Dialect:     db.Dialector.Name(),
+		Tables:      []TableStorage{},
+		Indexes:     []IndexStorage{},
+	}
+
+	if report.Dialect == "postgres" {
+		if err := db.WithContext(ctx).Raw(`
+			SELECT s.relname AS "table",
+				pg_total_relation_size(s.relid) AS total_bytes,
+				pg_relation_size(s.relid) AS table_bytes,
+				CASE WHEN c.reltoastrelid = 0 THEN 0 ELSE pg_total_relation_size(c.reltoastrelid) END AS toast_bytes,
+				pg_indexes_size(s.relid) AS index_bytes,
+				s.n_live_tup AS live_tuples,
+				s.n_dead_tup AS dead_tuples,
+				GREATEST(s.last_vacuum, s.last_autovacuum) AS last_vacuum
+			FROM pg_stat_user_tables s
+			JOIN pg_class c ON c.oid = s.relid
+			ORDER BY total_bytes DESC
+		`).Scan(&report.Tables).Error; err != nil {
+			return nil, fmt.Errorf("failed to read table sizes: %w", err)
+		}
+		for i := range report.Tables {
+			table := &report.Tables[i]
+			if rows := table.LiveTuples + table.DeadTuples; rows > 0 {
+				table.DeadRatio = float64(table.DeadTuples) / float64(rows)
+			}
+		}
+
+		if err := db.WithContext(ctx).Raw(`
+			SELECT i.indexrelname AS "index",
+				i.relname AS "table",
+				pg_relation_size(i.indexrelid) AS bytes,
+				i.idx_scan AS scans,
+				x.indexdef ~* 'USING (hnsw|ivfflat)' AS vector
+			FROM pg_stat_user_indexes i
+			JOIN pg_indexes x ON x.schemaname = i.schemaname AND x.indexname = i.indexrelname
+			ORDER BY bytes DESC
+		`).Scan(&report.Indexes).Error; err != nil {
+			return nil, fmt.Errorf("failed to read index sizes: %w", err)
+		}
+	}
+
+	users, err := userStorage(ctx, db, topUsers)
+	if err != nil {
+		return nil, err
+	}
+	report.Users = users
+	report.Actions = SuggestMaintenance(report.Tables, report.Indexes)
+	return report, nil
+}
+
+// userStorage sums the stored size of each user's memories, largest first
+func userStorage(ctx context.Context, db *gorm.DB, limit int) ([]UserStorage, error) {
+	size := "LENGTH"
+	if db.Dialector.Name() == "postgres" {
+		size = "pg_column_size"
+	}
+	toasted := func(column string) string {
+		return fmt.Sprintf("COALESCE(SUM(CASE WHEN %[1]s(%[2]s) > %[3]d THEN %[1]s(%[2]s) ELSE 0 END), 0)", size, column, toastThreshold)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT user_id,
+			COUNT(*) AS memories,
+			COALESCE(SUM(%[1]s(content)), 0) + COALESCE(SUM(%[1]s(encrypted_content)), 0) AS content_bytes,
+			COALESCE(SUM(%[1]s(metadata)), 0) AS metadata_bytes,
+			COALESCE(SUM(%[1]s(embedding)), 0) AS embedding_bytes,
+			%[2]s + %[3]s + %[4]s + %[5]s AS toast_bytes
+		FROM memories
+		GROUP BY user_id
+	`, size, toasted("content"), toasted("encrypted_content"), toasted("metadata"), toasted("embedding"))
+
+	var users []UserStorage
+	if err := db.WithContext(ctx).Raw(query).Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to read per-user sizes: %w", err)
+	}
+	for i := range users {
+		users[i].TotalBytes = users[i].ContentBytes + users[i].MetadataBytes + users[i].EmbeddingBytes
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].TotalBytes != users[j].TotalBytes {
+			return users[i].TotalBytes > users[j].TotalBytes
+		}
+		return users[i].UserID < users[j].UserID
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	if users == nil {
+		users = []UserStorage{}
+	}
+	return users, nil
+}
+
+// SuggestMaintenance returns the statements worth running for the given tables
+// and indexes: VACUUM (ANALYZE) for tables with many dead rows, VACUUM FULL when
+// most of a large table is dead, and REINDEX for vector indexes on such tables,
+// since hnsw and ivfflat indexes keep the space of deleted rows
+func SuggestMaintenance(tables []TableStorage, indexes []IndexStorage) []MaintenanceAction {
+	actions := []MaintenanceAction{}
+	bloated := make(map[string]TableStorage)
+	for _, table := range tables {
+		if table.DeadTuples < vacuumMinDeadTuples || table.DeadRatio < vacuumDeadRatio {
+			continue
+		}
+		bloated[table.Table] = table
+
+		reason := fmt.Sprintf("%d dead rows (%.0f%%)", table.DeadTuples, table.DeadRatio*100)
+		actions = append(actions, MaintenanceAction{
+			Statement: "VACUUM (ANALYZE) " + quoteIdentifier(table.Table),
+			Reason:    reason + "; makes their space reusable",
+		})
+		if table.DeadRatio >= vacuumFullDeadRatio && table.TotalBytes >= vacuumFullMinBytes {
+			actions = append(actions, MaintenanceAction{
+				Statement: "VACUUM FULL " + quoteIdentifier(table.Table),
+				Reason:    reason + "; rewrites the table to return the space to the operating system",
+				Locking:   true,
+			})
+		}
+	}
+
+	for _, index := range indexes {
+		table, ok := bloated[index.Table]
+		if !index.Vector || !ok {
+			continue
+		}
+		actions = append(actions, MaintenanceAction{
+			Statement: "REINDEX INDEX CONCURRENTLY " + quoteIdentifier(index.Index),
+			Reason:    fmt.Sprintf("vector index on %s, which has %.0f%% dead rows; vector indexes keep the space of deleted rows", table.Table, table.DeadRatio*100),
+		})
+	}
+	return actions
+}
+
+// RunMaintenance runs the actions in order, continuing past failures. VACUUM and
+// REINDEX CONCURRENTLY can't run in a transaction, so each runs on its own.
+func RunMaintenance(ctx context.Context, db *gorm.DB, actions []MaintenanceAction) []MaintenanceResult {
+	results := make([]MaintenanceResult, 0, len(actions))
+	for _, action := range actions {
+		start := time.Now()
+		result := MaintenanceResult{MaintenanceAction: action}
+		if err := db.WithContext(ctx).Exec(action.Statement).Error; err != nil {
+			result.Error = err.Error()
+		}
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+// quoteIdentifier quotes a table or index name for use in a statement
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCompactionReport_Users(t *testing.T) {
+	db := openDualWriteTestDB(t, "compaction.db")
+	require.NoError(t, db.Create(newDualWriteMemory(1, "short")).Error)
+	require.NoError(t, db.Create(newDualWriteMemory(2, strings.Repeat("long ", 1000))).Error)
+	require.NoError(t, db.Create(newDualWriteMemory(2, "another")).Error)
+
+	report, err := BuildCompactionReport(context.Background(), db, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", report.Dialect)
+	assert.Empty(t, report.Tables)
+	assert.Empty(t, report.Actions)
+
+	// The user with the most data comes first
+	require.Len(t, report.Users, 2)
+	assert.Equal(t, uint(2), report.Users[0].UserID)
+	assert.Equal(t, int64(2), report.Users[0].Memories)
+	assert.Equal(t, int64(5007), report.Users[0].ContentBytes)
+	assert.Equal(t, int64(5000), report.Users[0].ToastBytes)
+	assert.Equal(t, uint(1), report.Users[1].UserID)
+	assert.Zero(t, report.Users[1].ToastBytes)
+
+	report, err = BuildCompactionReport(context.Background(), db, 1)
+	require.NoError(t, err)
+	assert.Len(t, report.Users, 1)
+}
+
+func TestSuggestMaintenance(t *testing.T) {
+	tables := []TableStorage{
+		{Table: "memories", TotalBytes: 512 << 20, LiveTuples: 40000, DeadTuples: 60000, DeadRatio: 0.6},
+		{Table: "activity_logs", TotalBytes: 8 << 20, LiveTuples: 7000, DeadTuples: 3000, DeadRatio: 0.3},
+		{Table: "users", TotalBytes: 1 << 20, LiveTuples: 10, DeadTuples: 90, DeadRatio: 0.9},
+	}
+	indexes := []IndexStorage{
+		{Index: "idx_memories_embedding", Table: "memories", Vector: true},
+		{Index: "idx_memories_tags", Table: "memories"},
+	}
+
+	actions := SuggestMaintenance(tables, indexes)
+	statements := make([]string, len(actions))
+	for i, action := range actions {
+		statements[i] = action.Statement
+	}
+	assert.Equal(t, []string{
+		`VACUUM (ANALYZE) "memories"`,
+		`VACUUM FULL "memories"`,
+		`VACUUM (ANALYZE) "activity_logs"`,
+		`REINDEX INDEX CONCURRENTLY "idx_memories_embedding"`,
+	}, statements)
+	assert.True(t, actions[1].Locking)
+	assert.False(t, actions[3].Locking)
+}
@@ -8,9 +8,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
 )
 
 // Database manages the database connection and operations
@@ -27,6 +30,39 @@ func NewDatabase(config map[string]interface{}) *Database {
 	}
 }
 
+// Open connects to the database described by cfg and checks its health
+func Open(cfg config.Database, logLevel string) (*Database, error) {
+	db := NewDatabase(map[string]interface{}{
+		"host":               cfg.Host,
+		"port":               cfg.Port,
+		"user":               cfg.User,
+		"password":           cfg.Password,
+		"dbname":             cfg.DBName,
+		"sslmode":            cfg.SSLMode,
+		"max_open_conns":     cfg.MaxConnections,
+		"max_idle_conns":     cfg.MaxIdleConns,
+		"conn_max_lifetime":  cfg.ConnMaxLifetime,
+		"conn_max_idle_time": cfg.ConnMaxIdleTime,
+		"vector_ef_search":   cfg.VectorIndex.EfSearch,
+		"vector_probes":      cfg.VectorIndex.Probes,
+		"log_level":          logLevel,
+	})
+
+	if err := db.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.Health(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database health check failed: %w", err)
+	}
+
+	return db, nil
+}
+
 // Connect establishes a connection to the PostgreSQL database with retry logic
 func (d *Database) Connect() error {
 	d.mu.Lock()
@@ -65,6 +101,17 @@ func (d *Database) Connect() error {
 		return fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
 	}
 
+	if err := d.db.Use(NewWorkspaceScope()); err != nil {
+		return fmt.Errorf("failed to enable workspace scoping: %w", err)
+	}
+
+	// Trace queries without their arguments, which hold memory content
+	if tracing, _ := d.config["tracing"].(bool); tracing {
+		if err := d.db.Use(otelgorm.NewPlugin(otelgorm.WithoutQueryVariables(), otelgorm.WithoutMetrics())); err != nil {
+			return fmt.Errorf("failed to enable query tracing: %w", err)
+		}
+	}
+
 	// Configure connection pool
 	sqlDB, err := d.db.DB()
 	if err != nil {
@@ -125,7 +172,10 @@ func (d *Database) Health(ctx context.Context) error {
 		return fmt.Errorf("database ping failed: %w", err)
 	}
 
-	// Check pgvector extension
+	// Check pgvector extension; SQLite (see OpenInMemory) has none
+	if d.db.Dialector.Name() != "postgres" {
+		return nil
+	}
 	var result int
 	err = d.db.WithContext(ctx).Raw("SELECT 1 FROM pg_extension WHERE extname = 'vector'").Scan(&result).Error
 	if err != nil {
@@ -181,8 +231,21 @@ func (d *Database) buildDSN() string {
 	sslmode := d.getConfigString("sslmode", "disable")
 	timezone := d.getConfigString("timezone", "UTC")
 
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
 		host, port, user, password, dbname, sslmode, timezone)
+
+	// Query-time pgvector tuning is set on every connection
+	var options []string
+	if efSearch := d.getConfigInt("vector_ef_search", 0); efSearch > 0 {
+		options = append(options, fmt.Sprintf("-c hnsw.ef_search=%d", efSearch))
+	}
+	if probes := d.getConfigInt("vector_probes", 0); probes > 0 {
+		options = append(options, fmt.Sprintf("-c ivfflat.probes=%d", probes))
+	}
+	if len(options) > 0 {
+		dsn += fmt.Sprintf(" options='%s'", strings.Join(options, " "))
+	}
+	return dsn
 }
 
 // enablePgVector enables the pgvector extension
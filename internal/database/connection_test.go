@@ -57,6 +57,14 @@ func TestDatabase_buildDSN(t *testing.T) {
 			},
 			expected: "host=db.example.com port=5433 user=dbuser password= dbname=mydb sslmode=disable TimeZone=UTC",
 		},
+		{
+			name: "Vector search tuning",
+			config: map[string]interface{}{
+				"vector_ef_search": 100,
+				"vector_probes":    10,
+			},
+			expected: "host=localhost port=5432 user=postgres password= dbname=remember_me sslmode=disable TimeZone=UTC options='-c hnsw.ef_search=100 -c ivfflat.probes=10'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// memoryCountersPostgres keeps memory_counters current as memories are written.
+// Updates that leave the counted columns alone return early, so saving a memory
+// only touches its counters when it moves between categories or types or gains
+// or loses its embedding. The trigger passes columns rather than the row, as
+// rows of a partitioned memories table have their partition's type.
+var memoryCountersPostgres = []string{`
+	CREATE OR REPLACE FUNCTION memory_counters_bump(uid BIGINT, dim TEXT, val TEXT, delta BIGINT) RETURNS void AS $$
+	BEGIN
+		INSERT INTO memory_counters (user_id, dimension, value, count) VALUES (uid, dim, val, delta)
+		ON CONFLICT (user_id, dimension, value) DO UPDATE SET count = memory_counters.count + EXCLUDED.count;
+	END
+	$$ LANGUAGE plpgsql`,
+	`CREATE OR REPLACE FUNCTION memory_counters_apply(uid BIGINT, cat TEXT, typ TEXT, embedded BOOLEAN, delta BIGINT) RETURNS void AS $$
+	BEGIN
+		PERFORM memory_counters_bump(uid, 'total', '', delta);
+		PERFORM memory_counters_bump(uid, 'category', cat, delta);
+		PERFORM memory_counters_bump(uid, 'type', typ, delta);
+		IF embedded THEN
+			PERFORM memory_counters_bump(uid, 'embedded', '', delta);
+		END IF;
+	END
+	$$ LANGUAGE plpgsql`,
+	`CREATE OR REPLACE FUNCTION memory_counters_trigger() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'UPDATE'
+			AND OLD.user_id = NEW.user_id
+			AND OLD.category = NEW.category
+			AND OLD.type = NEW.type
+			AND (OLD.embedding IS NULL) = (NEW.embedding IS NULL) THEN
+			RETURN NULL;
+		END IF;
+		IF TG_OP IN ('UPDATE', 'DELETE') THEN
+			PERFORM memory_counters_apply(OLD.user_id, OLD.category, OLD.type, OLD.embedding IS NOT NULL, -1);
+		END IF;
+		IF TG_OP IN ('INSERT', 'UPDATE') THEN
+			PERFORM memory_counters_apply(NEW.user_id, NEW.category, NEW.type, NEW.embedding IS NOT NULL, 1);
+		END IF;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP FUNCTION IF EXISTS memory_counters_apply(memories, BIGINT)`,
+	`DROP TRIGGER IF EXISTS memories_counters ON memories`,
+	`CREATE TRIGGER memories_counters
+		AFTER INSERT OR DELETE OR UPDATE OF user_id, category, type, embedding ON memories
+		FOR EACH ROW EXECUTE FUNCTION memory_counters_trigger()`,
+}
+
+// sqliteCounterUpserts renders the statements adding delta to the counters of
+// the row (NEW or OLD) inside a SQLite trigger
+func sqliteCounterUpserts(row string, delta int) string {
+	upsert := func(dimension, value, where string) string {
+		return fmt.Sprintf(
+			"INSERT INTO memory_counters (user_id, dimension, value, count) SELECT %[1]s.user_id, '%[2]s', %[3]s, %[4]d WHERE %[5]s "+
+				"ON CONFLICT (user_id, dimension, value) DO UPDATE SET count = count + %[4]d;",
+			row, dimension, value, delta, where,
+		)
+	}
+	return strings.Join([]string{
+		upsert(models.CounterTotal, "''", "1"),
+		upsert(models.CounterCategory, row+".category", "1"),
+		upsert(models.CounterType, row+".type", "1"),
+		upsert(models.CounterEmbedded, "''", row+".embedding IS NOT NULL"),
+	}, "\n")
+}
+
+// memoryCountersSQLite returns the SQLite triggers keeping memory_counters current
+func memoryCountersSQLite() []string {
+	return []string{
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS memories_counters_insert AFTER INSERT ON memories BEGIN
+			%s
+		END`, sqliteCounterUpserts("NEW", 1)),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS memories_counters_delete AFTER DELETE ON memories BEGIN
+			%s
+		END`, sqliteCounterUpserts("OLD", -1)),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS memories_counters_update AFTER UPDATE OF user_id, category, type, embedding ON memories BEGIN
+			%s
+			%s
+		END`, sqliteCounterUpserts("OLD", -1), sqliteCounterUpserts("NEW", 1)),
+	}
+}
+
+// InstallMemoryCounters creates the triggers that keep memory_counters current and
+// recounts every user's memories, so counters are exact from the start even for
+// memories written before the triggers existed
+func InstallMemoryCounters(db *gorm.DB) error {
+	statements := memoryCountersPostgres
+	if db.Dialector.Name() == "sqlite" {
+		statements = memoryCountersSQLite()
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create memory counter triggers: %w", err)
+		}
+	}
+
+	return RecountMemories(context.Background(), db, 0)
+}
+
+// RecountMemories replaces the counters of a user, or of every user when userID
+// is 0, with exact counts from the memories table
+func RecountMemories(ctx context.Context, db *gorm.DB, userID uint) error {
+	scope, args := "", []interface{}{}
+	if userID != 0 {
+		scope, args = " WHERE user_id = ?", []interface{}{userID}
+	}
+	embeddedScope := " WHERE embedding IS NOT NULL"
+	if userID != 0 {
+		embeddedScope += " AND user_id = ?"
+	}
+
+	recount := fmt.Sprintf(`
+		INSERT INTO memory_counters (user_id, dimension, value, count)
+		SELECT user_id, '%[1]s', '', COUNT(*) FROM memories%[5]s GROUP BY user_id
+		UNION ALL
+		SELECT user_id, '%[2]s', category, COUNT(*) FROM memories%[5]s GROUP BY user_id, category
+		UNION ALL
+		SELECT user_id, '%[3]s', type, COUNT(*) FROM memories%[5]s GROUP BY user_id, type
+		UNION ALL
+		SELECT user_id, '%[4]s', '', COUNT(*) FROM memories%[6]s GROUP BY user_id
+	`, models.CounterTotal, models.CounterCategory, models.CounterType, models.CounterEmbedded, scope, embeddedScope)
+	var recountArgs []interface{}
+	for i := 0; i < 4; i++ {
+		recountArgs = append(recountArgs, args...)
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Hold off writers so no trigger runs between the delete and the recount
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("LOCK TABLE memories IN SHARE MODE").Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM memory_counters"+scope, args...).Error; err != nil {
+			return err
+		}
+		return tx.Exec(recount, recountArgs...).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to recount memories: %w", err)
+	}
+	return nil
+}
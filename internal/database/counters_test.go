@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// counters returns a user's counters keyed by dimension and value
+func counters(t *testing.T, db *gorm.DB, userID uint) map[string]int64 {
+	var rows []models.MemoryCounter
+	require.NoError(t, db.Where("user_id = ?", userID).Find(&rows).Error)
+	result := make(map[string]int64)
+	for _, row := range rows {
+		result[row.Dimension+":"+row.Value] = row.Count
+	}
+	return result
+}
+
+func TestMemoryCounters(t *testing.T) {
+	database, err := OpenInMemory("silent")
+	require.NoError(t, err)
+	defer database.Close()
+	db := database.DB()
+
+	create := func(category, memType string) *models.Memory {
+		memory := &models.Memory{UserID: SystemUserID, Type: memType, Category: category, Content: category + " " + memType}
+		require.NoError(t, db.Omit("embedding", "tags").Create(memory).Error)
+		return memory
+	}
+	first := create(models.CategoryPersonal, models.TypeFact)
+	create(models.CategoryPersonal, models.TypePreference)
+	create(models.CategoryProject, models.TypeFact)
+
+	got := counters(t, db, SystemUserID)
+	assert.Equal(t, int64(3), got["total:"])
+	assert.Equal(t, int64(2), got["category:personal"])
+	assert.Equal(t, int64(1), got["category:project"])
+	assert.Equal(t, int64(2), got["type:fact"])
+	assert.Zero(t, got["embedded:"])
+
+	// Moving a memory between categories and embedding it moves its counts
+	require.NoError(t, db.Exec("UPDATE memories SET category = ?, embedding = ? WHERE id = ?",
+		models.CategoryBusiness, []byte{1}, first.ID).Error)
+	got = counters(t, db, SystemUserID)
+	assert.Equal(t, int64(3), got["total:"])
+	assert.Equal(t, int64(1), got["category:personal"])
+	assert.Equal(t, int64(1), got["category:business"])
+	assert.Equal(t, int64(1), got["embedded:"])
+
+	require.NoError(t, db.Delete(&models.Memory{}, first.ID).Error)
+	got = counters(t, db, SystemUserID)
+	assert.Equal(t, int64(2), got["total:"])
+	assert.Zero(t, got["category:business"])
+	assert.Zero(t, got["embedded:"])
+
+	// Recounting repairs counters that drifted
+	require.NoError(t, db.Model(&models.MemoryCounter{}).Where("user_id = ?", SystemUserID).Update("count", 99).Error)
+	require.NoError(t, RecountMemories(context.Background(), db, SystemUserID))
+	got = counters(t, db, SystemUserID)
+	assert.Equal(t, int64(2), got["total:"])
+	assert.Equal(t, int64(1), got["category:personal"])
+	assert.Equal(t, int64(1), got["category:project"])
+	assert.Zero(t, got["category:business"])
+}
@@ -0,0 +1,486 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// dualWriteIDsKey carries the IDs an update or delete will touch from the before
+// callback to the after callback
+const dualWriteIDsKey = "dual_write:ids"
+
+// rawMemoryWritePattern spots raw SQL that writes to the memories table
+var rawMemoryWritePattern = regexp.MustCompile(`(?is)^\s*(insert\s+into|update|delete\s+from)\s+"?memories"?\b`)
+
+// DualWriter is a GORM plugin that mirrors every write to the memories table onto a
+// second database, so a deployment can move to a new Postgres without downtime:
+// enable dual writes, backfill, verify, then cut over.
+//
+// Writes are mirrored by ID: the affected rows are re-read from the primary and
+// upserted into (or deleted from) the target, together with the users they belong
+// to. Other tables are not mirrored. A failed mirror never fails the primary write;
+// it is logged and counted, and VerifyMemories will report the drift.
+// Raw SQL writes (such as snapshot restores) cannot be mirrored by ID and are only
+// logged; RepairMemories resyncs the affected users.
+type DualWriter struct {
+	target   *gorm.DB
+	logger   zerolog.Logger
+	failures atomic.Int64
+}
+
+// NewDualWriter creates a dual-write plugin mirroring onto target
+func NewDualWriter(target *gorm.DB, logger zerolog.Logger) *DualWriter {
+	return &DualWriter{
+		target: target,
+		logger: logger.With().Str("component", "dual_write").Logger(),
+	}
+}
+
+// Name implements gorm.Plugin
+func (w *DualWriter) Name() string {
+	return "dual_write"
+}
+
+// Initialize implements gorm.Plugin by registering the mirroring callbacks
+func (w *DualWriter) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("dual_write:after_create", w.afterCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("dual_write:before_update", w.captureIDs); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("dual_write:after_update", w.afterWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("dual_write:before_delete", w.captureIDs); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("dual_write:after_delete", w.afterWrite); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("dual_write:after_raw", w.afterRaw)
+}
+
+// Failures returns how many writes could not be mirrored since startup
+func (w *DualWriter) Failures() int64 {
+	return w.failures.Load()
+}
+
+// mirrored reports whether the statement targets the memories table
+func (w *DualWriter) mirrored(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Table == "memories"
+}
+
+func (w *DualWriter) afterCreate(db *gorm.DB) {
+	if !w.mirrored(db) {
+		return
+	}
+	w.mirror(db, primaryKeys(db.Statement))
+}
+
+// captureIDs records which rows an update or delete is about to touch, as deleted
+// rows can no longer be found afterwards
+func (w *DualWriter) captureIDs(db *gorm.DB) {
+	if !w.mirrored(db) {
+		return
+	}
+
+	ids := primaryKeys(db.Statement)
+	if where, ok := db.Statement.Clauses["WHERE"]; ok && where.Expression != nil {
+		var matched []uint
+		if err := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Memory{}).
+			Clauses(where.Expression).
+			Pluck("id", &matched).Error; err != nil {
+			w.failed(err, "failed to capture rows for dual write")
+			return
+		}
+		ids = append(ids, matched...)
+	}
+	db.Statement.Settings.Store(dualWriteIDsKey, ids)
+}
+
+func (w *DualWriter) afterWrite(db *gorm.DB) {
+	if !w.mirrored(db) {
+		return
+	}
+	ids, _ := db.Statement.Settings.Load(dualWriteIDsKey)
+	if ids, ok := ids.([]uint); ok {
+		w.mirror(db, ids)
+	}
+}
+
+func (w *DualWriter) afterRaw(db *gorm.DB) {
+	if db.Error != nil || !rawMemoryWritePattern.MatchString(db.Statement.SQL.String()) {
+		return
+	}
+	w.failures.Add(1)
+	w.logger.Warn().
+		Str("sql", db.Statement.SQL.String()).
+		Msg("raw write to memories was not mirrored; run migrate-db -verify -repair before cutover")
+}
+
+// mirror copies the current state of the given rows to the target. It reads through
+// the statement's connection so rows written inside a transaction are visible.
+// The session is initialized up front: otherwise chaining WithContext onto it
+// would clone the in-flight statement, table and all.
+func (w *DualWriter) mirror(db *gorm.DB, ids []uint) {
+	if len(ids) == 0 {
+		return
+	}
+	source := db.Session(&gorm.Session{NewDB: true, Initialized: true})
+	if err := copyMemories(db.Statement.Context, source, w.target, ids); err != nil {
+		w.failed(err, "failed to mirror memories to target database")
+	}
+}
+
+func (w *DualWriter) failed(err error, msg string) {
+	w.failures.Add(1)
+	w.logger.Error().Err(err).Msg(msg)
+}
+
+// primaryKeys returns the non-zero IDs of the statement's model or destination
+func primaryKeys(stmt *gorm.Statement) []uint {
+	if stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+
+	var ids []uint
+	collect := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		if rv.Kind() != reflect.Struct {
+			return
+		}
+		if value, zero := field.ValueOf(stmt.Context, rv); !zero {
+			if id, ok := value.(uint); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			collect(stmt.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		collect(stmt.ReflectValue)
+	}
+	return ids
+}
+
+// schemaCache caches parsed model schemas for upsert column lists
+var schemaCache sync.Map
+
+// upsertColumns lists the columns of a model an upsert overwrites: everything but
+// the primary key and the skipped columns
+func upsertColumns(db *gorm.DB, model interface{}, skip ...string) ([]string, error) {
+	s, err := schema.Parse(model, &schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, name := range s.DBNames {
+		if name == s.PrioritizedPrimaryField.DBName || slices.Contains(skip, name) {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// copyUsers upserts the given users into the target, as memories reference them
+func copyUsers(ctx context.Context, source, target *gorm.DB, ids []uint) error {
+	columns, err := upsertColumns(target, &models.User{})
+	if err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := source.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return fmt.Errorf("read source users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return target.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&users).Error
+}
+
+// copyMemories makes the target's rows for the given IDs match the source: rows
+// are upserted with their original IDs and timestamps, and rows missing from the
+// source are deleted. The embedding is copied separately because pgvector cannot
+// scan NULL embeddings.
+func copyMemories(ctx context.Context, source, target *gorm.DB, ids []uint) error {
+	columns, err := upsertColumns(target, &models.Memory{}, "embedding")
+	if err != nil {
+		return err
+	}
+
+	var memories []models.Memory
+	if err := source.WithContext(ctx).Omit("embedding").Where("id IN ?", ids).Find(&memories).Error; err != nil {
+		return fmt.Errorf("read source rows: %w", err)
+	}
+
+	var embeddings []struct {
+		ID        uint
+		Embedding pgvector.Vector
+	}
+	if err := source.WithContext(ctx).Model(&models.Memory{}).
+		Select("id, embedding").
+		Where("id IN ? AND embedding IS NOT NULL", ids).
+		Scan(&embeddings).Error; err != nil {
+		return fmt.Errorf("read source embeddings: %w", err)
+	}
+
+	target = target.WithContext(ctx).Session(&gorm.Session{SkipHooks: true})
+	return target.Transaction(func(tx *gorm.DB) error {
+		found := make(map[uint]bool, len(memories))
+		if len(memories) > 0 {
+			userIDs := make([]uint, 0, 1)
+			for _, memory := range memories {
+				if !slices.Contains(userIDs, memory.UserID) {
+					userIDs = append(userIDs, memory.UserID)
+				}
+			}
+			if err := copyUsers(ctx, source, tx, userIDs); err != nil {
+				return fmt.Errorf("copy users: %w", err)
+			}
+
+			if err := tx.Omit("embedding").Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns(columns),
+			}).Create(&memories).Error; err != nil {
+				return fmt.Errorf("upsert target rows: %w", err)
+			}
+			for _, memory := range memories {
+				found[memory.ID] = true
+			}
+		}
+
+		embedded := make(map[uint]bool, len(embeddings))
+		for _, row := range embeddings {
+			embedded[row.ID] = true
+			if err := tx.Table("memories").Where("id = ?", row.ID).UpdateColumn("embedding", row.Embedding).Error; err != nil {
+				return fmt.Errorf("copy embedding: %w", err)
+			}
+		}
+
+		var missing, unembedded []uint
+		for _, id := range ids {
+			switch {
+			case !found[id]:
+				missing = append(missing, id)
+			case !embedded[id]:
+				unembedded = append(unembedded, id)
+			}
+		}
+		if len(unembedded) > 0 {
+			if err := tx.Table("memories").Where("id IN ?", unembedded).UpdateColumn("embedding", gorm.Expr("NULL")).Error; err != nil {
+				return fmt.Errorf("clear embeddings: %w", err)
+			}
+		}
+		if len(missing) > 0 {
+			if err := tx.Where("id IN ?", missing).Delete(&models.Memory{}).Error; err != nil {
+				return fmt.Errorf("delete target rows: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// BackfillMemories copies every memory from source to target in batches of IDs.
+// It is idempotent, so it can run while dual writes are enabled.
+func BackfillMemories(ctx context.Context, source, target *gorm.DB, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	copied := 0
+	var lastID uint
+	for {
+		var ids []uint
+		if err := source.WithContext(ctx).Model(&models.Memory{}).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return copied, fmt.Errorf("list source rows: %w", err)
+		}
+		if len(ids) == 0 {
+			return copied, nil
+		}
+
+		if err := copyMemories(ctx, source, target, ids); err != nil {
+			return copied, err
+		}
+		copied += len(ids)
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// MemoryDiff describes a user whose memories differ between the databases
+type MemoryDiff struct {
+	UserID     uint   `json:"user_id"`
+	SourceRows int64  `json:"source_rows"`
+	TargetRows int64  `json:"target_rows"`
+	SourceHash string `json:"source_hash"`
+	TargetHash string `json:"target_hash"`
+}
+
+// VerifyReport compares the memories of the source and target databases
+type VerifyReport struct {
+	SourceRows int64        `json:"source_rows"`
+	TargetRows int64        `json:"target_rows"`
+	Users      int          `json:"users"`
+	Mismatched []MemoryDiff `json:"mismatched,omitempty"`
+}
+
+// Match reports whether both databases hold the same memories
+func (r *VerifyReport) Match() bool {
+	return r.SourceRows == r.TargetRows && len(r.Mismatched) == 0
+}
+
+// userDigest accumulates a per-user row count and hash
+type userDigest struct {
+	rows int64
+	hash []byte
+}
+
+// VerifyMemories compares per-user row counts and hashes of each memory's ID,
+// content hash, priority, access count, update time and whether it has an embedding
+func VerifyMemories(ctx context.Context, source, target *gorm.DB) (*VerifyReport, error) {
+	sourceDigests, err := digestMemories(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("digest source: %w", err)
+	}
+	targetDigests, err := digestMemories(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("digest target: %w", err)
+	}
+
+	users := make(map[uint]bool)
+	report := &VerifyReport{}
+	for userID, digest := range sourceDigests {
+		users[userID] = true
+		report.SourceRows += digest.rows
+	}
+	for userID, digest := range targetDigests {
+		users[userID] = true
+		report.TargetRows += digest.rows
+	}
+	report.Users = len(users)
+
+	for userID := range users {
+		src, dst := sourceDigests[userID], targetDigests[userID]
+		diff := MemoryDiff{UserID: userID}
+		if src != nil {
+			diff.SourceRows, diff.SourceHash = src.rows, hex.EncodeToString(src.hash)
+		}
+		if dst != nil {
+			diff.TargetRows, diff.TargetHash = dst.rows, hex.EncodeToString(dst.hash)
+		}
+		if diff.SourceRows != diff.TargetRows || diff.SourceHash != diff.TargetHash {
+			report.Mismatched = append(report.Mismatched, diff)
+		}
+	}
+	sort.Slice(report.Mismatched, func(i, j int) bool {
+		return report.Mismatched[i].UserID < report.Mismatched[j].UserID
+	})
+	return report, nil
+}
+
+// digestMemories hashes every memory row per user, reading in ID order
+func digestMemories(ctx context.Context, db *gorm.DB) (map[uint]*userDigest, error) {
+	type digestRow struct {
+		ID           uint
+		UserID       uint
+		ContentHash  string
+		Priority     string
+		AccessCount  int64
+		UpdatedAt    time.Time
+		HasEmbedding bool
+	}
+
+	hashers := make(map[uint]*userDigest)
+	running := make(map[uint][]byte)
+	rows, err := db.WithContext(ctx).Model(&models.Memory{}).
+		Select("id, user_id, content_hash, priority, access_count, updated_at, embedding IS NOT NULL AS has_embedding").
+		Order("id ASC").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row digestRow
+		if err := db.ScanRows(rows, &row); err != nil {
+			return nil, err
+		}
+		line := fmt.Sprintf("%d|%s|%s|%d|%d|%t", row.ID, row.ContentHash, row.Priority, row.AccessCount, row.UpdatedAt.UTC().UnixMicro(), row.HasEmbedding)
+		sum := sha256.Sum256(append(running[row.UserID], line...))
+		running[row.UserID] = sum[:]
+
+		digest, ok := hashers[row.UserID]
+		if !ok {
+			digest = &userDigest{}
+			hashers[row.UserID] = digest
+		}
+		digest.rows++
+		digest.hash = running[row.UserID]
+	}
+	return hashers, rows.Err()
+}
+
+// RepairMemories resyncs the memories of the given users from source to target,
+// including deleting target rows the source no longer has
+func RepairMemories(ctx context.Context, source, target *gorm.DB, userIDs []uint) error {
+	for _, userID := range userIDs {
+		var ids []uint
+		if err := source.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("list source rows for user %d: %w", userID, err)
+		}
+		var targetIDs []uint
+		if err := target.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", userID).Pluck("id", &targetIDs).Error; err != nil {
+			return fmt.Errorf("list target rows for user %d: %w", userID, err)
+		}
+		if all := append(ids, targetIDs...); len(all) > 0 {
+			if err := copyMemories(ctx, source, target, all); err != nil {
+				return fmt.Errorf("repair user %d: %w", userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SyncMemorySequence moves the target's ID sequence past the highest copied ID.
+// Rows are copied with explicit IDs, which does not advance the sequence, so this
+// must run before the target starts taking writes of its own.
+func SyncMemorySequence(ctx context.Context, target *gorm.DB) error {
+	return target.WithContext(ctx).Exec(
+		"SELECT setval(pg_get_serial_sequence('memories', 'id'), COALESCE((SELECT MAX(id) FROM memories), 0) + 1, false)",
+	).Error
+}
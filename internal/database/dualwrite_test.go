@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// openDualWriteTestDB opens a file-backed sqlite database with the memories schema
+// used by the service tests, which has no vector type
+func openDualWriteTestDB(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	require.NoError(t, db.Exec(`
+		CREATE TABLE memories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 1,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			type TEXT NOT NULL,
+			category TEXT NOT NULL,
+			content TEXT NOT NULL,
+			encrypted_content TEXT,
+			is_encrypted BOOLEAN DEFAULT FALSE,
+			priority TEXT DEFAULT 'medium',
+			update_key TEXT,
+			content_hash TEXT,
+			content_index TEXT,
+			keyword_index TEXT,
+			access_count INTEGER NOT NULL DEFAULT 0,
+			last_accessed_at DATETIME,
+			session_id TEXT,
+			embedding BLOB,
+			tags TEXT,
+			metadata TEXT,
+			created_at DATETIME,
+			updated_at DATETIME
+		)
+	`).Error)
+	return db
+}
+
+func newDualWriteMemory(userID uint, content string) *models.Memory {
+	return &models.Memory{
+		UserID:      userID,
+		Type:        models.TypeFact,
+		Category:    models.CategoryPersonal,
+		Content:     content,
+		ContentHash: models.HashContent(content),
+		Priority:    models.PriorityMedium,
+	}
+}
+
+func TestDualWriter_MirrorsWrites(t *testing.T) {
+	ctx := context.Background()
+	primary := openDualWriteTestDB(t, "primary.db")
+	target := openDualWriteTestDB(t, "target.db")
+
+	writer := NewDualWriter(target, zerolog.Nop())
+	require.NoError(t, primary.Use(writer))
+
+	require.NoError(t, primary.Create(&models.User{ID: 2, Email: "a@example.com", Password: "x"}).Error)
+	first := newDualWriteMemory(2, "first")
+	second := newDualWriteMemory(2, "second")
+	require.NoError(t, primary.Omit("embedding").Create(first).Error)
+	require.NoError(t, primary.Omit("embedding").Create(second).Error)
+
+	// Updates by model and by condition are both mirrored
+	first.Priority = models.PriorityHigh
+	require.NoError(t, primary.Omit("embedding").Save(first).Error)
+	require.NoError(t, primary.Model(&models.Memory{}).Where("id IN ?", []uint{first.ID, second.ID}).
+		UpdateColumn("access_count", gorm.Expr("access_count + 1")).Error)
+
+	require.NoError(t, primary.Delete(&models.Memory{}, second.ID).Error)
+
+	var mirrored []models.Memory
+	require.NoError(t, target.Omit("embedding").Find(&mirrored).Error)
+	require.Len(t, mirrored, 1)
+	assert.Equal(t, first.ID, mirrored[0].ID)
+	assert.Equal(t, models.PriorityHigh, mirrored[0].Priority)
+	assert.Equal(t, int64(1), mirrored[0].AccessCount)
+
+	var user models.User
+	require.NoError(t, target.First(&user, 2).Error)
+	assert.Equal(t, "a@example.com", user.Email)
+
+	report, err := VerifyMemories(ctx, primary, target)
+	require.NoError(t, err)
+	assert.True(t, report.Match())
+	assert.Zero(t, writer.Failures())
+}
+
+func TestBackfillVerifyRepair(t *testing.T) {
+	ctx := context.Background()
+	primary := openDualWriteTestDB(t, "primary.db")
+	target := openDualWriteTestDB(t, "target.db")
+
+	require.NoError(t, primary.Create(&models.User{ID: 2, Email: "a@example.com", Password: "x"}).Error)
+	require.NoError(t, primary.Create(&models.User{ID: 3, Email: "b@example.com", Password: "x"}).Error)
+	for _, memory := range []*models.Memory{
+		newDualWriteMemory(2, "one"),
+		newDualWriteMemory(2, "two"),
+		newDualWriteMemory(3, "three"),
+	} {
+		require.NoError(t, primary.Omit("embedding").Create(memory).Error)
+	}
+
+	report, err := VerifyMemories(ctx, primary, target)
+	require.NoError(t, err)
+	assert.False(t, report.Match())
+	assert.Len(t, report.Mismatched, 2)
+
+	copied, err := BackfillMemories(ctx, primary, target, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+
+	report, err = VerifyMemories(ctx, primary, target)
+	require.NoError(t, err)
+	assert.True(t, report.Match())
+	assert.Equal(t, int64(3), report.TargetRows)
+
+	// Drift in the target is detected per user and repaired
+	require.NoError(t, target.Exec("DELETE FROM memories WHERE user_id = 3").Error)
+	require.NoError(t, target.Exec("UPDATE memories SET priority = 'low' WHERE content = 'one'").Error)
+
+	report, err = VerifyMemories(ctx, primary, target)
+	require.NoError(t, err)
+	require.Len(t, report.Mismatched, 2)
+	assert.Equal(t, int64(0), report.Mismatched[1].TargetRows)
+
+	require.NoError(t, RepairMemories(ctx, primary, target, []uint{2, 3}))
+	report, err = VerifyMemories(ctx, primary, target)
+	require.NoError(t, err)
+	assert.True(t, report.Match())
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// EncryptedColumn is a column holding utils.EncryptedData as JSON
+type EncryptedColumn struct {
+	Table  string
+	Column string
+	// UserID selects the owning user of rows that may be encrypted with the
+	// user's data key rather than the master key
+	UserID string
+}
+
+// EncryptedColumns lists every column encrypted with the master key or a user's
+// data key
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "users", Column: "encryption_key"},
+	{Table: "users", Column: "openai_key"},
+	{Table: "memories", Column: "encrypted_content", UserID: "user_id"},
+	{Table: "memory_revisions", Column: "encrypted_content", UserID: "user_id"},
+	{
+		Table:  "memory_snapshot_items",
+		Column: "encrypted_content",
+		UserID: "(SELECT user_id FROM memory_snapshots WHERE memory_snapshots.id = memory_snapshot_items.snapshot_id)",
+	},
+	{Table: "context_turns", Column: "encrypted_content", UserID: "user_id"},
+}
+
+// KeyRotationProgress counts the rows of one column seen so far
+type KeyRotationProgress struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// LastID is the highest row ID processed
+	LastID  uint `json:"last_id"`
+	Scanned int  `json:"scanned"`
+	Rotated int  `json:"rotated"`
+	// AlreadyRotated rows were encrypted with the new key by an earlier run
+	AlreadyRotated int `json:"already_rotated"`
+	// UserKeyed rows are encrypted with their user's data key, which moves to the
+	// new master key with users.encryption_key
+	UserKeyed int `json:"user_keyed"`
+	// Failed rows could be decrypted with none of the keys and were left untouched
+	Failed int `json:"failed"`
+}
+
+// KeyRotationOptions controls RotateEncryptionKey
+type KeyRotationOptions struct {
+	BatchSize int
+	// DryRun counts the rows that would be rotated without writing them
+	DryRun bool
+	// Progress, when set, is called after each batch
+	Progress func(KeyRotationProgress)
+}
+
+// RotateEncryptionKey moves every encrypted column from the old master keys to the
+// new one. Each batch is written in its own transaction, and only the data keys are
+// re-encrypted; see utils.EncryptionService.RewrapField. Rows already under the new
+// key are skipped, so an interrupted rotation is resumed by running it again.
+// Writers using the old key must be stopped while it runs, unless from and to are
+// the same service holding the old keys as previous keys, which the writers
+// share.
+func RotateEncryptionKey(ctx context.Context, db *gorm.DB, from, to *utils.EncryptionService, opts KeyRotationOptions) ([]KeyRotationProgress, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	keys := &rotationUserKeys{db: db, from: from, to: to, keys: make(map[uint]*utils.EncryptionService)}
+
+	var report []KeyRotationProgress
+	for _, column := range EncryptedColumns {
+		if !db.Migrator().HasTable(column.Table) {
+			continue
+		}
+		progress, err := rotateColumn(ctx, db, column, keys, opts)
+		report = append(report, progress)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func rotateColumn(ctx context.Context, db *gorm.DB, column EncryptedColumn, keys *rotationUserKeys, opts KeyRotationOptions) (KeyRotationProgress, error) {
+	progress := KeyRotationProgress{Table: column.Table, Column: column.Column}
+	from, to := keys.from, keys.to
+
+	userID := "0"
+	if column.UserID != "" {
+		userID = column.UserID
+	}
+
+	for {
+		var rows []struct {
+			ID     uint
+			Data   []byte
+			UserID uint
+		}
+		if err := db.WithContext(ctx).Table(column.Table).
+			Select("id, "+column.Column+" AS data, "+userID+" AS user_id").
+			Where(column.Column+" IS NOT NULL AND id > ?", progress.LastID).
+			Order("id ASC").
+			Limit(opts.BatchSize).
+			Scan(&rows).Error; err != nil {
+			return progress, fmt.Errorf("read %s: %w", column.Table, err)
+		}
+		if len(rows) == 0 {
+			return progress, nil
+		}
+
+		updates := make(map[uint]json.RawMessage, len(rows))
+		for _, row := range rows {
+			progress.Scanned++
+			progress.LastID = row.ID
+
+			var data utils.EncryptedData
+			if err := json.Unmarshal(row.Data, &data); err != nil || data.EncryptedKey == "" {
+				progress.Failed++
+				continue
+			}
+			if to.UsesCurrentKey(&data) {
+				progress.AlreadyRotated++
+				continue
+			}
+
+			rewrapped, err := from.RewrapField(&data, to)
+			if err != nil {
+				if row.UserID != 0 && keys.owns(ctx, row.UserID, &data) {
+					progress.UserKeyed++
+				} else {
+					progress.Failed++
+				}
+				continue
+			}
+			encoded, err := json.Marshal(rewrapped)
+			if err != nil {
+				return progress, fmt.Errorf("encode %s %d: %w", column.Table, row.ID, err)
+			}
+			updates[row.ID] = encoded
+		}
+
+		if !opts.DryRun && len(updates) > 0 {
+			if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for id, encoded := range updates {
+					if err := tx.Table(column.Table).Where("id = ?", id).UpdateColumn(column.Column, encoded).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return progress, fmt.Errorf("update %s: %w", column.Table, err)
+			}
+		}
+		progress.Rotated += len(updates)
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+}
+
+// rotationUserKeys caches user data keys, which are wrapped by either the old or
+// the new master key depending on whether users.encryption_key has been rotated
+type rotationUserKeys struct {
+	db       *gorm.DB
+	from, to *utils.EncryptionService
+	keys     map[uint]*utils.EncryptionService
+}
+
+// owns reports whether data is encrypted with the user's data key
+func (k *rotationUserKeys) owns(ctx context.Context, userID uint, data *utils.EncryptedData) bool {
+	key, ok := k.keys[userID]
+	if !ok {
+		if wrapped, err := loadUserDataKey(ctx, k.db, userID); err == nil && wrapped != nil {
+			if key, err = k.to.UnwrapKey(wrapped); err != nil {
+				key, _ = k.from.UnwrapKey(wrapped)
+			}
+		}
+		k.keys[userID] = key
+	}
+	return key != nil && key.OwnsField(data)
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func newTestEncryptionService(t *testing.T) *utils.EncryptionService {
+	key, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	service, err := utils.NewEncryptionService(key)
+	require.NoError(t, err)
+	return service
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	ctx := context.Background()
+	db := openDualWriteTestDB(t, "rotate.db")
+	oldKey := newTestEncryptionService(t)
+	newKey := newTestEncryptionService(t)
+
+	for _, content := range []string{"one", "two", "three"} {
+		encrypted, err := oldKey.EncryptField(content)
+		require.NoError(t, err)
+		encoded, err := json.Marshal(encrypted)
+		require.NoError(t, err)
+
+		memory := newDualWriteMemory(1, "[encrypted]")
+		memory.IsEncrypted = true
+		memory.EncryptedContent = encoded
+		require.NoError(t, db.Omit("embedding").Create(memory).Error)
+	}
+	require.NoError(t, db.Omit("embedding").Create(newDualWriteMemory(1, "plain")).Error)
+
+	decryptAll := func(service *utils.EncryptionService) []string {
+		var memories []models.Memory
+		require.NoError(t, db.Omit("embedding").Where("is_encrypted = ?", true).Order("id").Find(&memories).Error)
+		var contents []string
+		for _, memory := range memories {
+			var data utils.EncryptedData
+			require.NoError(t, json.Unmarshal(memory.EncryptedContent, &data))
+			content, err := service.DecryptField(&data)
+			require.NoError(t, err)
+			contents = append(contents, content)
+		}
+		return contents
+	}
+
+	// A dry run counts without writing
+	report, err := RotateEncryptionKey(ctx, db, oldKey, newKey, KeyRotationOptions{BatchSize: 2, DryRun: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, report)
+	assert.Equal(t, "memories", report[2].Table)
+	assert.Equal(t, 3, report[2].Rotated)
+	assert.Equal(t, []string{"one", "two", "three"}, decryptAll(oldKey))
+
+	batches := 0
+	report, err = RotateEncryptionKey(ctx, db, oldKey, newKey, KeyRotationOptions{
+		BatchSize: 2,
+		Progress:  func(KeyRotationProgress) { batches++ },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report[2].Rotated)
+	assert.Zero(t, report[2].Failed)
+	assert.Equal(t, 2, batches)
+	assert.Equal(t, []string{"one", "two", "three"}, decryptAll(newKey))
+
+	// Running again resumes by skipping rows already under the new key
+	report, err = RotateEncryptionKey(ctx, db, oldKey, newKey, KeyRotationOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, report[2].Rotated)
+	assert.Equal(t, 3, report[2].AlreadyRotated)
+
+	// Content under a user's data key stays as it is; the user's key moves to the
+	// new master key
+	require.NoError(t, db.Create(&models.User{ID: 2, Email: "a@example.com", Password: "x"}).Error)
+	userKey, err := UserDataKey(ctx, db, newKey, 2)
+	require.NoError(t, err)
+	encrypted, err := userKey.EncryptField("four")
+	require.NoError(t, err)
+	encoded, err := json.Marshal(encrypted)
+	require.NoError(t, err)
+	memory := newDualWriteMemory(2, "[encrypted]")
+	memory.EncryptedContent = encoded
+	require.NoError(t, db.Omit("embedding").Create(memory).Error)
+
+	newerKey := newTestEncryptionService(t)
+	report, err = RotateEncryptionKey(ctx, db, newKey, newerKey, KeyRotationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "users", report[0].Table)
+	assert.Equal(t, 1, report[0].Rotated)
+	assert.Equal(t, 3, report[2].Rotated)
+	assert.Equal(t, 1, report[2].UserKeyed)
+	assert.Zero(t, report[2].Failed)
+
+	userKey, err = UserDataKey(ctx, db, newerKey, 2)
+	require.NoError(t, err)
+	content, err := userKey.DecryptField(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "four", content)
+	newKey = newerKey
+
+	// Data under neither key is reported and left alone
+	report, err = RotateEncryptionKey(ctx, db, newTestEncryptionService(t), newTestEncryptionService(t), KeyRotationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report[2].Failed)
+	assert.Equal(t, 1, report[0].Failed)
+	assert.Equal(t, []string{"one", "two", "three"}, decryptAll(newKey))
+}
+
+func TestRotateEncryptionKey_PreviousKeys(t *testing.T) {
+	ctx := context.Background()
+	db := openDualWriteTestDB(t, "rotate-previous.db")
+	oldKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	newKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	oldService, err := utils.NewEncryptionService(oldKey)
+	require.NoError(t, err)
+	service, err := utils.NewEncryptionService(newKey, oldKey)
+	require.NoError(t, err)
+
+	// Servers holding both keys write with the new one while the old data remains
+	for _, encryption := range []*utils.EncryptionService{oldService, service} {
+		encrypted, err := encryption.EncryptField("secret")
+		require.NoError(t, err)
+		encoded, err := json.Marshal(encrypted)
+		require.NoError(t, err)
+		memory := newDualWriteMemory(1, "[encrypted]")
+		memory.IsEncrypted = true
+		memory.EncryptedContent = encoded
+		require.NoError(t, db.Omit("embedding").Create(memory).Error)
+	}
+
+	report, err := RotateEncryptionKey(ctx, db, service, service, KeyRotationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "memories", report[2].Table)
+	assert.Equal(t, 1, report[2].Rotated)
+	assert.Equal(t, 1, report[2].AlreadyRotated)
+	assert.Zero(t, report[2].Failed)
+
+	// Once rotated, the previous key can be dropped
+	current, err := utils.NewEncryptionService(newKey)
+	require.NoError(t, err)
+	var memories []models.Memory
+	require.NoError(t, db.Omit("embedding").Where("is_encrypted = ?", true).Find(&memories).Error)
+	require.Len(t, memories, 2)
+	for _, memory := range memories {
+		var data utils.EncryptedData
+		require.NoError(t, json.Unmarshal(memory.EncryptedContent, &data))
+		assert.Equal(t, current.KeyID(), data.KeyID)
+		content, err := current.DecryptField(&data)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", content)
+	}
+}
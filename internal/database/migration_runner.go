@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/ksred/remember-me-mcp/internal/models"
@@ -19,6 +22,30 @@ type Migration struct {
 	Version string
 	Name    string
 	Run     MigrationFunc
+	// Tables lists the tables the migration rewrites data in, which are backed up
+	// before it runs. Schema-only migrations leave it empty.
+	Tables []string
+}
+
+// ErrBackupRequired is returned when migrations that rewrite data are pending and
+// the runner requires the operator to confirm they have a backup
+var ErrBackupRequired = errors.New("a backup is required before running these migrations")
+
+// backupTablePrefix names the copies taken before data-rewriting migrations
+const backupTablePrefix = "migration_backup_"
+
+// identifierPattern restricts the table names used in backup statements
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// rowsAffectedKey carries the running migration's row counter in its context
+type rowsAffectedKey struct{}
+
+// AddRowsAffected reports rows changed by the running migration, for the
+// migration audit
+func AddRowsAffected(ctx context.Context, rows int64) {
+	if counter, ok := ctx.Value(rowsAffectedKey{}).(*int64); ok {
+		*counter += rows
+	}
 }
 
 // MigrationRunner handles running database migrations
@@ -26,6 +53,10 @@ type MigrationRunner struct {
 	db         *gorm.DB
 	logger     zerolog.Logger
 	migrations []Migration
+	// requireBackupConfirmation refuses data-rewriting migrations unless
+	// backupConfirmed; backupConfirmed also skips the automatic backup
+	requireBackupConfirmation bool
+	backupConfirmed           bool
 }
 
 // NewMigrationRunner creates a new migration runner
@@ -42,10 +73,20 @@ func (r *MigrationRunner) Register(migration Migration) {
 	r.migrations = append(r.migrations, migration)
 }
 
+// SetBackupPolicy chooses how migrations that rewrite data are protected. By
+// default the runner copies the tables they rewrite first. With
+// requireConfirmation, as in production where those copies can be too large, it
+// refuses to run them unless confirmed says the operator has taken their own
+// backup. confirmed also skips the automatic copies.
+func (r *MigrationRunner) SetBackupPolicy(requireConfirmation, confirmed bool) {
+	r.requireBackupConfirmation = requireConfirmation
+	r.backupConfirmed = confirmed
+}
+
 // Run executes all pending migrations
 func (r *MigrationRunner) Run(ctx context.Context) error {
-	// Ensure migrations table exists
-	if err := r.db.AutoMigrate(&models.Migration{}); err != nil {
+	// Ensure migrations tables exist
+	if err := r.db.AutoMigrate(&models.Migration{}, &models.MigrationAudit{}); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
@@ -65,6 +106,20 @@ func (r *MigrationRunner) Run(ctx context.Context) error {
 		appliedMap[v] = true
 	}
 
+	// Refuse up front, before anything runs, when a backup must be confirmed
+	if r.requireBackupConfirmation && !r.backupConfirmed {
+		var unprotected []string
+		for _, migration := range r.migrations {
+			if !appliedMap[migration.Version] && len(migration.Tables) > 0 {
+				unprotected = append(unprotected, fmt.Sprintf("%s (%s)", migration.Version, strings.Join(migration.Tables, ", ")))
+			}
+		}
+		if len(unprotected) > 0 {
+			return fmt.Errorf("%w: pending migrations rewrite data: %s; back up the database and restart with --i-have-a-backup",
+				ErrBackupRequired, strings.Join(unprotected, "; "))
+		}
+	}
+
 	// Run pending migrations
 	for _, migration := range r.migrations {
 		if appliedMap[migration.Version] {
@@ -80,44 +135,147 @@ func (r *MigrationRunner) Run(ctx context.Context) error {
 			Str("name", migration.Name).
 			Msg("Running migration")
 
-		// Start transaction
-		tx := r.db.Begin()
-		if tx.Error != nil {
-			return fmt.Errorf("failed to start transaction: %w", tx.Error)
-		}
-
-		// Run migration
-		if err := migration.Run(ctx, tx, r.logger); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("migration %s failed: %w", migration.Version, err)
-		}
-
-		// Record migration
-		record := &models.Migration{
+		audit := &models.MigrationAudit{
 			Version:   migration.Version,
 			Name:      migration.Name,
-			AppliedAt: time.Now(),
+			StartedAt: time.Now(),
+			Tables:    strings.Join(migration.Tables, ","),
 		}
-
-		if err := tx.Create(record).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+		err := r.run(ctx, migration, audit)
+		audit.DurationMS = time.Since(audit.StartedAt).Milliseconds()
+		audit.Status = models.MigrationSucceeded
+		if err != nil {
+			audit.Status = models.MigrationFailed
+			audit.Error = err.Error()
 		}
-
-		// Commit transaction
-		if err := tx.Commit().Error; err != nil {
-			return fmt.Errorf("failed to commit migration %s: %w", migration.Version, err)
+		if auditErr := r.db.Create(audit).Error; auditErr != nil {
+			r.logger.Error().Err(auditErr).Str("version", migration.Version).Msg("Failed to record migration audit")
+		}
+		if err != nil {
+			return err
 		}
 
 		r.logger.Info().
 			Str("version", migration.Version).
 			Str("name", migration.Name).
+			Int64("duration_ms", audit.DurationMS).
+			Int64("rows_affected", audit.RowsAffected).
 			Msg("Migration completed successfully")
 	}
 
 	return nil
 }
 
+// run backs up the tables a migration rewrites, then applies it in a transaction,
+// filling in the audit as it goes
+func (r *MigrationRunner) run(ctx context.Context, migration Migration, audit *models.MigrationAudit) error {
+	if len(migration.Tables) > 0 {
+		rows, err := r.countRows(ctx, migration.Tables)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Version, err)
+		}
+		audit.TableRows = rows
+
+		if r.backupConfirmed {
+			audit.BackupSkipped = true
+			r.logger.Warn().
+				Str("version", migration.Version).
+				Strs("tables", migration.Tables).
+				Msg("Skipping pre-migration backup, operator confirmed a backup exists")
+		} else {
+			backups, err := r.backupTables(ctx, migration)
+			if err != nil {
+				return fmt.Errorf("migration %s: pre-migration backup failed: %w", migration.Version, err)
+			}
+			audit.BackupTables = strings.Join(backups, ",")
+		}
+	}
+
+	// Start transaction
+	tx := r.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	// Run migration
+	var rowsAffected int64
+	if err := migration.Run(context.WithValue(ctx, rowsAffectedKey{}, &rowsAffected), tx, r.logger); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %s failed: %w", migration.Version, err)
+	}
+	audit.RowsAffected = rowsAffected
+
+	// Record migration
+	record := &models.Migration{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		AppliedAt: time.Now(),
+	}
+
+	if err := tx.Create(record).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// countRows returns the total rows in the tables
+func (r *MigrationRunner) countRows(ctx context.Context, tables []string) (int64, error) {
+	var total int64
+	for _, table := range tables {
+		if !identifierPattern.MatchString(table) {
+			return 0, fmt.Errorf("invalid table name %q", table)
+		}
+		var rows int64
+		if err := r.db.WithContext(ctx).Table(table).Count(&rows).Error; err != nil {
+			return 0, fmt.Errorf("failed to count rows in %s: %w", table, err)
+		}
+		total += rows
+	}
+	return total, nil
+}
+
+// backupTables copies each table the migration rewrites into
+// migration_backup_<version>_<table> in the same database, replacing a copy left
+// by an earlier failed attempt, which rolled back and so matches the data now.
+// Restore by copying the rows back; drop the copies once the migration is trusted.
+func (r *MigrationRunner) backupTables(ctx context.Context, migration Migration) ([]string, error) {
+	version := strings.ToLower(strings.Map(func(c rune) rune {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			return c
+		}
+		return '_'
+	}, migration.Version))
+
+	db := r.db.WithContext(ctx)
+	backups := make([]string, 0, len(migration.Tables))
+	for _, table := range migration.Tables {
+		backup := backupTablePrefix + version + "_" + table
+		if !identifierPattern.MatchString(table) || !identifierPattern.MatchString(backup) {
+			return backups, fmt.Errorf("invalid table name %q", table)
+		}
+		if err := db.Exec("DROP TABLE IF EXISTS " + backup).Error; err != nil {
+			return backups, fmt.Errorf("failed to replace %s: %w", backup, err)
+		}
+		if err := db.Exec("CREATE TABLE " + backup + " AS SELECT * FROM " + table).Error; err != nil {
+			return backups, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		backups = append(backups, backup)
+
+		r.logger.Info().
+			Str("version", migration.Version).
+			Str("table", table).
+			Str("backup", backup).
+			Msg("Backed up table before migration")
+	}
+	return backups, nil
+}
+
 // GetPendingMigrations returns a list of migrations that haven't been applied yet
 func (r *MigrationRunner) GetPendingMigrations() ([]Migration, error) {
 	// Get applied migrations
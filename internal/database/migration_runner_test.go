@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrations.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL)").Error)
+	require.NoError(t, db.Exec("INSERT INTO notes (id, body) VALUES (1, 'one'), (2, 'two'), (3, 'three')").Error)
+	return db
+}
+
+// uppercaseNotes rewrites every note, reporting the rows it changed
+func uppercaseNotes(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	result := db.Exec("UPDATE notes SET body = UPPER(body)")
+	AddRowsAffected(ctx, result.RowsAffected)
+	return result.Error
+}
+
+func lastAudit(t *testing.T, db *gorm.DB) models.MigrationAudit {
+	var audit models.MigrationAudit
+	require.NoError(t, db.Order("id DESC").First(&audit).Error)
+	return audit
+}
+
+func TestMigrationRunner_BacksUpRewrittenTables(t *testing.T) {
+	db := openMigrationTestDB(t)
+	runner := NewMigrationRunner(db, zerolog.Nop())
+	runner.Register(Migration{Version: "20250101_001", Name: "uppercase_notes", Run: uppercaseNotes, Tables: []string{"notes"}})
+
+	require.NoError(t, runner.Run(context.Background()))
+
+	var bodies []string
+	require.NoError(t, db.Table("notes").Order("id").Pluck("body", &bodies).Error)
+	assert.Equal(t, []string{"ONE", "TWO", "THREE"}, bodies)
+
+	// The backup holds the rows as they were before the migration
+	require.NoError(t, db.Table("migration_backup_20250101_001_notes").Order("id").Pluck("body", &bodies).Error)
+	assert.Equal(t, []string{"one", "two", "three"}, bodies)
+
+	audit := lastAudit(t, db)
+	assert.Equal(t, models.MigrationSucceeded, audit.Status)
+	assert.Equal(t, "notes", audit.Tables)
+	assert.Equal(t, int64(3), audit.TableRows)
+	assert.Equal(t, int64(3), audit.RowsAffected)
+	assert.Equal(t, "migration_backup_20250101_001_notes", audit.BackupTables)
+	assert.False(t, audit.BackupSkipped)
+}
+
+func TestMigrationRunner_RequiresBackupConfirmation(t *testing.T) {
+	db := openMigrationTestDB(t)
+	schemaOnlyRan := false
+	register := func(runner *MigrationRunner) {
+		runner.Register(Migration{Version: "20250101_001", Name: "schema_only", Run: func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+			schemaOnlyRan = true
+			return nil
+		}})
+		runner.Register(Migration{Version: "20250101_002", Name: "uppercase_notes", Run: uppercaseNotes, Tables: []string{"notes"}})
+	}
+
+	runner := NewMigrationRunner(db, zerolog.Nop())
+	runner.SetBackupPolicy(true, false)
+	register(runner)
+
+	// Nothing runs until the operator confirms a backup
+	err := runner.Run(context.Background())
+	assert.True(t, errors.Is(err, ErrBackupRequired))
+	assert.ErrorContains(t, err, "20250101_002 (notes)")
+	assert.False(t, schemaOnlyRan)
+
+	runner = NewMigrationRunner(db, zerolog.Nop())
+	runner.SetBackupPolicy(true, true)
+	register(runner)
+	require.NoError(t, runner.Run(context.Background()))
+	assert.True(t, schemaOnlyRan)
+
+	audit := lastAudit(t, db)
+	assert.Equal(t, "20250101_002", audit.Version)
+	assert.True(t, audit.BackupSkipped)
+	assert.Empty(t, audit.BackupTables)
+	assert.False(t, db.Migrator().HasTable("migration_backup_20250101_002_notes"))
+}
+
+func TestMigrationRunner_AuditsFailures(t *testing.T) {
+	db := openMigrationTestDB(t)
+	runner := NewMigrationRunner(db, zerolog.Nop())
+	runner.Register(Migration{
+		Version: "20250101_001",
+		Name:    "broken",
+		Tables:  []string{"notes"},
+		Run: func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+			if err := uppercaseNotes(ctx, db, logger); err != nil {
+				return err
+			}
+			return errors.New("halfway failure")
+		},
+	})
+
+	err := runner.Run(context.Background())
+	assert.ErrorContains(t, err, "halfway failure")
+
+	// The transaction rolled back and the attempt is audited
+	var bodies []string
+	require.NoError(t, db.Table("notes").Order("id").Pluck("body", &bodies).Error)
+	assert.Equal(t, []string{"one", "two", "three"}, bodies)
+
+	audit := lastAudit(t, db)
+	assert.Equal(t, models.MigrationFailed, audit.Status)
+	assert.Contains(t, audit.Error, "halfway failure")
+
+	var applied int64
+	require.NoError(t, db.Model(&models.Migration{}).Count(&applied).Error)
+	assert.Zero(t, applied)
+}
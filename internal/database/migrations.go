@@ -12,17 +12,61 @@ import (
 // SystemUserID is the reserved user ID for local MCP operations
 const SystemUserID = 1
 
-// RunMigrations runs all database migrations
-func RunMigrations(db *gorm.DB) error {
-	// Run auto-migrations for all models
-	if err := db.AutoMigrate(
+// schemaModels returns every model with a table, in the order they are migrated
+func schemaModels() []interface{} {
+	return []interface{}{
 		&models.User{},
 		&models.APIKey{},
 		&models.Memory{},
 		&models.ActivityLog{},
 		&models.PerformanceMetric{},
+		&models.ActivityRollup{},
+		&models.PerformanceRollup{},
 		&models.Migration{},
-	); err != nil {
+		&models.MemorySnapshot{},
+		&models.MemorySnapshotItem{},
+		&models.SavedSearch{},
+		&models.Workspace{},
+		&models.SupportAccessGrant{},
+		&models.Alert{},
+		&models.EmbeddingJob{},
+		&models.MemoryProvenance{},
+		&models.MemoryRevision{},
+		&models.MemoryAccessLog{},
+		&models.ContextTurn{},
+		&models.LLMUsage{},
+		&models.MemoryFeedback{},
+		&models.MemoryLink{},
+		&models.MemoryChunk{},
+		&models.Attachment{},
+		&models.MemoryCounter{},
+		&models.MemorySession{},
+		&models.EmbeddingCacheEntry{},
+		&models.CategorizationRule{},
+		&models.ReembedRun{},
+		&models.MaintenanceReport{},
+		&models.MaintenanceAction{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Confirmation{},
+	}
+}
+
+// RunMigrations runs all database migrations
+func RunMigrations(db *gorm.DB) error {
+	// A partitioned memories table cannot be the target of foreign keys; deletes
+	// cascade through a trigger instead (see PartitionMemories)
+	partitioned, err := MemoriesPartitioned(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	if partitioned {
+		db = db.Session(&gorm.Session{})
+		db.DisableForeignKeyConstraintWhenMigrating = true
+	}
+
+	// Run auto-migrations for all models
+	if err := db.AutoMigrate(schemaModels()...); err != nil {
 		return fmt.Errorf("failed to run auto-migrations: %w", err)
 	}
 
@@ -40,6 +84,80 @@ func RunMigrations(db *gorm.DB) error {
 		return fmt.Errorf("failed to create composite index: %w", err)
 	}
 
+	// Composite index serving statements scoped to a workspace
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_user_workspace
+		ON memories(user_id, workspace_id)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create workspace index: %w", err)
+	}
+
+	// Partial index serving session filters and purges
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_user_session
+		ON memories(user_id, session_id)
+		WHERE session_id IS NOT NULL AND session_id <> ''
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create session index: %w", err)
+	}
+
+	// Partial index serving recall_recent
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_user_last_accessed
+		ON memories(user_id, last_accessed_at DESC)
+		WHERE last_accessed_at IS NOT NULL
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create last accessed index: %w", err)
+	}
+
+	// GIN index serving metadata containment (@>) and JSONPath (@@) queries
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_metadata_path
+		ON memories USING GIN (metadata jsonb_path_ops)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create metadata index: %w", err)
+	}
+
+	// GIN index serving tag overlap (&&) and containment (@>) filters
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_tags
+		ON memories USING GIN (tags)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create tags index: %w", err)
+	}
+
+	// Lexemes of the content for keyword and hybrid search, kept current by
+	// Postgres. Adding the column fills it for existing rows, rewriting the table.
+	if err := db.Exec(`
+		ALTER TABLE memories ADD COLUMN IF NOT EXISTS content_tsv tsvector
+		GENERATED ALWAYS AS (to_tsvector('english', coalesce(content, ''))) STORED
+	`).Error; err != nil {
+		return fmt.Errorf("failed to add full-text column: %w", err)
+	}
+
+	// GIN index serving full-text search
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_memories_content_tsv
+		ON memories USING GIN (content_tsv)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create full-text index: %w", err)
+	}
+
+	// The expression index searches used before the column existed
+	if err := db.Exec("DROP INDEX IF EXISTS idx_memories_content_fts").Error; err != nil {
+		return fmt.Errorf("failed to drop old full-text index: %w", err)
+	}
+
+	// The embedding cache index from before entries were kept per user
+	if err := db.Exec("DROP INDEX IF EXISTS idx_embedding_cache_model_hash").Error; err != nil {
+		return fmt.Errorf("failed to drop old embedding cache index: %w", err)
+	}
+
+	// Triggers keeping per-user counts current, so counting skips the table scan
+	if err := InstallMemoryCounters(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/models"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 	"github.com/rs/zerolog"
@@ -107,6 +108,7 @@ func EncryptExistingMemories(encryptionService *utils.EncryptionService) func(ct
 		logger.Info().
 			Int("total_encrypted", totalEncrypted).
 			Msg("Completed encryption of existing memories")
+		database.AddRowsAffected(ctx, int64(totalEncrypted))
 
 		return nil
 	}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// BackfillContentHash computes content_hash for memories stored before the column existed.
+// Encrypted memories are decrypted in memory to hash their plaintext; they are skipped
+// when no encryption service is available.
+func BackfillContentHash(encryptionService *utils.EncryptionService) func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	return func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+		logger.Info().Msg("Backfilling content hashes for existing memories")
+
+		var totalHashed, totalSkipped int
+		batchSize := 100
+		var lastID uint
+
+		for {
+			var memories []models.Memory
+
+			// Only select the columns we need; embeddings may be NULL
+			if err := db.Model(&models.Memory{}).
+				Select("id", "content", "encrypted_content", "is_encrypted").
+				Where("id > ?", lastID).
+				Where("content_hash IS NULL OR content_hash = ''").
+				Order("id ASC").
+				Limit(batchSize).
+				Find(&memories).Error; err != nil {
+				return fmt.Errorf("failed to fetch memories: %w", err)
+			}
+
+			if len(memories) == 0 {
+				break
+			}
+
+			for _, memory := range memories {
+				lastID = memory.ID
+
+				content := memory.Content
+				if memory.IsEncrypted && len(memory.EncryptedContent) > 0 {
+					if encryptionService == nil {
+						totalSkipped++
+						continue
+					}
+
+					var encryptedData utils.EncryptedData
+					if err := json.Unmarshal(memory.EncryptedContent, &encryptedData); err != nil {
+						logger.Error().Err(err).Uint("id", memory.ID).Msg("Failed to unmarshal encrypted data, skipping")
+						totalSkipped++
+						continue
+					}
+
+					decrypted, err := encryptionService.DecryptField(&encryptedData)
+					if err != nil {
+						logger.Error().Err(err).Uint("id", memory.ID).Msg("Failed to decrypt memory, skipping")
+						totalSkipped++
+						continue
+					}
+					content = decrypted
+				}
+
+				if err := db.Exec(
+					"UPDATE memories SET content_hash = ? WHERE id = ?",
+					models.HashContent(content), memory.ID,
+				).Error; err != nil {
+					return fmt.Errorf("failed to update memory %d: %w", memory.ID, err)
+				}
+				totalHashed++
+			}
+
+			if len(memories) < batchSize {
+				break
+			}
+		}
+
+		logger.Info().
+			Int("total_hashed", totalHashed).
+			Int("total_skipped", totalSkipped).
+			Msg("Completed content hash backfill")
+		database.AddRowsAffected(ctx, int64(totalHashed))
+
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// MoveToUserDataKeys re-encrypts content encrypted with the master key under its
+// user's own data key. Only the data keys are re-encrypted; see
+// utils.EncryptionService.RewrapField.
+func MoveToUserDataKeys(encryptionService *utils.EncryptionService) func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	return func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+		// Skip if encryption service is not available
+		if encryptionService == nil {
+			logger.Warn().Msg("Encryption service not available, skipping per-user key migration")
+			return nil
+		}
+
+		userKeys := make(map[uint]*utils.EncryptionService)
+		var totalMoved int
+
+		for _, column := range database.EncryptedColumns {
+			if column.UserID == "" || !db.Migrator().HasTable(column.Table) {
+				continue
+			}
+
+			var lastID uint
+			for {
+				var rows []struct {
+					ID     uint
+					Data   []byte
+					UserID uint
+				}
+				if err := db.Table(column.Table).
+					Select("id, "+column.Column+" AS data, "+column.UserID+" AS user_id").
+					Where(column.Column+" IS NOT NULL AND id > ?", lastID).
+					Order("id ASC").
+					Limit(100).
+					Scan(&rows).Error; err != nil {
+					return fmt.Errorf("failed to fetch %s: %w", column.Table, err)
+				}
+				if len(rows) == 0 {
+					break
+				}
+
+				for _, row := range rows {
+					lastID = row.ID
+
+					var data utils.EncryptedData
+					if err := json.Unmarshal(row.Data, &data); err != nil || !encryptionService.OwnsField(&data) {
+						continue
+					}
+
+					userKey, ok := userKeys[row.UserID]
+					if !ok {
+						var err error
+						userKey, err = database.UserDataKey(ctx, db, encryptionService, row.UserID)
+						if errors.Is(err, gorm.ErrRecordNotFound) {
+							// Orphaned rows stay under the master key
+							userKey = nil
+						} else if err != nil {
+							return fmt.Errorf("failed to get data key for user %d: %w", row.UserID, err)
+						}
+						userKeys[row.UserID] = userKey
+					}
+					if userKey == nil {
+						continue
+					}
+
+					rewrapped, err := encryptionService.RewrapField(&data, userKey)
+					if err != nil {
+						return fmt.Errorf("failed to re-encrypt %s %d: %w", column.Table, row.ID, err)
+					}
+					encoded, err := json.Marshal(rewrapped)
+					if err != nil {
+						return fmt.Errorf("failed to marshal encrypted data: %w", err)
+					}
+					if err := db.Table(column.Table).Where("id = ?", row.ID).
+						UpdateColumn(column.Column, json.RawMessage(encoded)).Error; err != nil {
+						return fmt.Errorf("failed to update %s %d: %w", column.Table, row.ID, err)
+					}
+					totalMoved++
+				}
+			}
+
+			logger.Info().Str("table", column.Table).Msg("Moved encrypted content to per-user keys")
+		}
+
+		logger.Info().
+			Int("total_moved", totalMoved).
+			Int("users", len(userKeys)).
+			Msg("Completed per-user key migration")
+		database.AddRowsAffected(ctx, int64(totalMoved))
+
+		return nil
+	}
+}
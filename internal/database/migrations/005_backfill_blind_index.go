@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// BackfillBlindIndex computes the blind indexes of memories encrypted before they
+// existed, so exact duplicates and keyword searches find them. Content is
+// decrypted in memory and indexed with its user's data key, as new memories are.
+func BackfillBlindIndex(encryptionService *utils.EncryptionService) func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	return func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+		if encryptionService == nil {
+			logger.Warn().Msg("Encryption service not available, skipping blind index backfill")
+			return nil
+		}
+
+		logger.Info().Msg("Backfilling blind indexes for encrypted memories")
+
+		userKeys := make(map[uint]*utils.EncryptionService)
+		var totalIndexed, totalSkipped int
+		var lastID uint
+
+		for {
+			var memories []models.Memory
+			if err := db.Model(&models.Memory{}).
+				Select("id", "user_id", "encrypted_content").
+				Where("id > ? AND is_encrypted = ?", lastID, true).
+				Where("content_index IS NULL OR content_index = ''").
+				Order("id ASC").
+				Limit(100).
+				Find(&memories).Error; err != nil {
+				return fmt.Errorf("failed to fetch memories: %w", err)
+			}
+			if len(memories) == 0 {
+				break
+			}
+
+			for _, memory := range memories {
+				lastID = memory.ID
+
+				userKey, ok := userKeys[memory.UserID]
+				if !ok {
+					var err error
+					userKey, err = database.UserDataKey(ctx, db, encryptionService, memory.UserID)
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						userKey = encryptionService
+					} else if err != nil {
+						return fmt.Errorf("failed to get data key for user %d: %w", memory.UserID, err)
+					}
+					userKeys[memory.UserID] = userKey
+				}
+
+				var encryptedData utils.EncryptedData
+				if err := json.Unmarshal(memory.EncryptedContent, &encryptedData); err != nil {
+					logger.Error().Err(err).Uint("id", memory.ID).Msg("Failed to unmarshal encrypted data, skipping")
+					totalSkipped++
+					continue
+				}
+				decryptWith := userKey
+				if !userKey.OwnsField(&encryptedData) {
+					decryptWith = encryptionService
+				}
+				aad := models.MemoryContentAAD(memory.UserID, memory.ID)
+				if encryptedData.EnvelopeVersion() == utils.EnvelopeV2 {
+					aad = models.OwnerContentAAD(memory.UserID)
+				}
+				content, err := decryptWith.DecryptFieldWithAAD(&encryptedData, aad)
+				if err != nil {
+					logger.Error().Err(err).Uint("id", memory.ID).Msg("Failed to decrypt memory, skipping")
+					totalSkipped++
+					continue
+				}
+
+				index, err := userKey.BlindIndex(memory.UserID)
+				if err != nil {
+					return fmt.Errorf("failed to derive blind index for user %d: %w", memory.UserID, err)
+				}
+				if err := db.Exec(
+					"UPDATE memories SET content_index = ?, keyword_index = ? WHERE id = ?",
+					index.Content(content), index.Keywords(content), memory.ID,
+				).Error; err != nil {
+					return fmt.Errorf("failed to update memory %d: %w", memory.ID, err)
+				}
+				totalIndexed++
+			}
+		}
+
+		logger.Info().
+			Int("total_indexed", totalIndexed).
+			Int("total_skipped", totalSkipped).
+			Msg("Completed blind index backfill")
+		database.AddRowsAffected(ctx, int64(totalIndexed))
+
+		return nil
+	}
+}
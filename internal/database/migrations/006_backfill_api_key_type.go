@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// BackfillAPIKeyType records the type of API keys created before it had its own
+// column, from the permissions they were created with. Keys whose permissions
+// match no type are left without one, so they are refused rather than given full
+// access.
+func BackfillAPIKeyType(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	logger.Info().Msg("Backfilling API key types")
+
+	var keys []models.APIKey
+	if err := db.Unscoped().Model(&models.APIKey{}).
+		Select("id", "permissions").
+		Where("key_type IS NULL OR key_type = ''").
+		Find(&keys).Error; err != nil {
+		return fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	var totalTyped, totalUnknown int
+	for _, key := range keys {
+		keyType := models.APIKeyTypeForPermissions(key.GetPermissions())
+		if keyType == "" {
+			logger.Warn().Uint("id", key.ID).Str("permissions", key.Permissions).Msg("API key matches no key type and will be refused")
+			totalUnknown++
+			continue
+		}
+		if err := db.Exec("UPDATE api_keys SET key_type = ? WHERE id = ?", keyType, key.ID).Error; err != nil {
+			return fmt.Errorf("failed to update API key %d: %w", key.ID, err)
+		}
+		totalTyped++
+	}
+
+	logger.Info().
+		Int("total_typed", totalTyped).
+		Int("total_unknown", totalUnknown).
+		Msg("Completed API key type backfill")
+	database.AddRowsAffected(ctx, int64(totalTyped))
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// hashedContentTables are the tables holding a content hash, with the query
+// reading a batch of their rows after a given ID along with the owner and memory
+// each row's content is bound to
+var hashedContentTables = []struct {
+	name  string
+	query string
+}{
+	{"memories", `
+		SELECT id, user_id, id AS memory_id, content, encrypted_content, is_encrypted
+		FROM memories WHERE id > ? ORDER BY id LIMIT 100`},
+	{"memory_revisions", `
+		SELECT id, user_id, memory_id, content, encrypted_content, is_encrypted
+		FROM memory_revisions WHERE id > ? ORDER BY id LIMIT 100`},
+	{"memory_snapshot_items", `
+		SELECT i.id, s.user_id, i.memory_id, i.content, i.encrypted_content, i.is_encrypted
+		FROM memory_snapshot_items i JOIN memory_snapshots s ON s.id = i.snapshot_id
+		WHERE i.id > ? ORDER BY i.id LIMIT 100`},
+}
+
+// hashedContentRow is a row of one of hashedContentTables
+type hashedContentRow struct {
+	ID               uint
+	UserID           uint
+	MemoryID         uint
+	Content          string
+	EncryptedContent []byte
+	IsEncrypted      bool
+}
+
+// KeyContentHash replaces the plain SHA-256 content hashes of memories, their
+// revisions and snapshot copies with hashes keyed by each user's blind index, as
+// content is hashed once encryption is on, so a guessed text can no longer be
+// confirmed against them. Provenance recorded against a source's plain hash is
+// moved to the keyed one, and the embedding cache, keyed the same way, is
+// emptied. Without encryption hashes stay as they are.
+func KeyContentHash(encryptionService *utils.EncryptionService) func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	return func(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+		if encryptionService == nil {
+			logger.Info().Msg("Encryption service not available, content hashes stay unkeyed")
+			return nil
+		}
+
+		logger.Info().Msg("Keying content hashes")
+
+		userKeys := make(map[uint]*utils.EncryptionService)
+		userKey := func(userID uint) (*utils.EncryptionService, error) {
+			if key, ok := userKeys[userID]; ok {
+				return key, nil
+			}
+			key, err := database.UserDataKey(ctx, db, encryptionService, userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				key = encryptionService
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get data key for user %d: %w", userID, err)
+			}
+			userKeys[userID] = key
+			return key, nil
+		}
+
+		var totalKeyed, totalSkipped int
+		for _, table := range hashedContentTables {
+			var lastID uint
+			for {
+				var rows []hashedContentRow
+				if err := db.Raw(table.query, lastID).Scan(&rows).Error; err != nil {
+					return fmt.Errorf("failed to fetch %s: %w", table.name, err)
+				}
+				if len(rows) == 0 {
+					break
+				}
+
+				for _, row := range rows {
+					lastID = row.ID
+
+					key, err := userKey(row.UserID)
+					if err != nil {
+						return err
+					}
+					content, err := hashedContentPlaintext(encryptionService, key, &row)
+					if err != nil {
+						logger.Error().Err(err).Str("table", table.name).Uint("id", row.ID).Msg("Failed to decrypt content, skipping")
+						totalSkipped++
+						continue
+					}
+					index, err := key.BlindIndex(row.UserID)
+					if err != nil {
+						return fmt.Errorf("failed to derive blind index for user %d: %w", row.UserID, err)
+					}
+					hash := index.Content(content)
+
+					if err := db.Exec("UPDATE "+table.name+" SET content_hash = ? WHERE id = ?", hash, row.ID).Error; err != nil {
+						return fmt.Errorf("failed to update %s %d: %w", table.name, row.ID, err)
+					}
+					if table.name != "memory_snapshot_items" {
+						if err := db.Exec(
+							"UPDATE memory_provenance SET source_content_hash = ? WHERE source_memory_id = ? AND source_content_hash = ?",
+							hash, row.MemoryID, models.HashContent(content),
+						).Error; err != nil {
+							return fmt.Errorf("failed to update provenance of memory %d: %w", row.MemoryID, err)
+						}
+					}
+					totalKeyed++
+				}
+			}
+		}
+
+		if err := db.Exec("DELETE FROM embedding_cache").Error; err != nil {
+			return fmt.Errorf("failed to clear embedding cache: %w", err)
+		}
+
+		logger.Info().
+			Int("total_keyed", totalKeyed).
+			Int("total_skipped", totalSkipped).
+			Msg("Completed keying content hashes")
+		database.AddRowsAffected(ctx, int64(totalKeyed))
+
+		return nil
+	}
+}
+
+// hashedContentPlaintext returns a row's content, decrypting it with the user's
+// data key or, for content from before per-user keys, the master key
+func hashedContentPlaintext(master, userKey *utils.EncryptionService, row *hashedContentRow) (string, error) {
+	if !row.IsEncrypted {
+		return row.Content, nil
+	}
+
+	var encryptedData utils.EncryptedData
+	if err := json.Unmarshal(row.EncryptedContent, &encryptedData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	}
+	decryptWith := userKey
+	if !userKey.OwnsField(&encryptedData) {
+		decryptWith = master
+	}
+	aad := models.MemoryContentAAD(row.UserID, row.MemoryID)
+	if encryptedData.EnvelopeVersion() == utils.EnvelopeV2 {
+		aad = models.OwnerContentAAD(row.UserID)
+	}
+	return decryptWith.DecryptFieldWithAAD(&encryptedData, aad)
+}
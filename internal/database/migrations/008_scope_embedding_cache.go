@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// ScopeEmbeddingCache removes the embedding cache entries cached before entries
+// were kept per user. They belong to no user, so no lookup can reach them and
+// they would only count towards the deployment-wide cache stats. The embeddings
+// are generated again as users store and search.
+func ScopeEmbeddingCache(ctx context.Context, db *gorm.DB, logger zerolog.Logger) error {
+	logger.Info().Msg("Removing embedding cache entries without a user")
+
+	result := db.Exec("DELETE FROM embedding_cache WHERE user_id = 0")
+	if result.Error != nil {
+		return fmt.Errorf("failed to clear embedding cache: %w", result.Error)
+	}
+
+	logger.Info().
+		Int64("total_removed", result.RowsAffected).
+		Msg("Completed embedding cache scoping")
+	database.AddRowsAffected(ctx, result.RowsAffected)
+
+	return nil
+}
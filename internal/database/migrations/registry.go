@@ -17,6 +17,43 @@ func GetMigrations(encryptionService *utils.EncryptionService) []database.Migrat
 			Version: "20240101_002", 
 			Name:    "encrypt_existing_memories",
 			Run:     EncryptExistingMemories(encryptionService),
+			Tables:  []string{"memories"},
+		},
+		{
+			Version: "20240101_003",
+			Name:    "backfill_content_hash",
+			Run:     BackfillContentHash(encryptionService),
+			Tables:  []string{"memories"},
+		},
+		{
+			Version: "20240101_004",
+			Name:    "move_to_user_data_keys",
+			Run:     MoveToUserDataKeys(encryptionService),
+			Tables:  []string{"memories", "memory_revisions", "memory_snapshot_items", "context_turns"},
+		},
+		{
+			Version: "20240101_005",
+			Name:    "backfill_blind_index",
+			Run:     BackfillBlindIndex(encryptionService),
+			Tables:  []string{"memories"},
+		},
+		{
+			Version: "20240101_006",
+			Name:    "backfill_api_key_type",
+			Run:     BackfillAPIKeyType,
+			Tables:  []string{"api_keys"},
+		},
+		{
+			Version: "20240101_007",
+			Name:    "key_content_hash",
+			Run:     KeyContentHash(encryptionService),
+			Tables:  []string{"memories", "memory_revisions", "memory_snapshot_items", "memory_provenance", "embedding_cache"},
+		},
+		{
+			Version: "20240101_008",
+			Name:    "scope_embedding_cache",
+			Run:     ScopeEmbeddingCache,
+			Tables:  []string{"embedding_cache"},
 		},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,702 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+const (
+	// PartitionStagingTable receives the copy of memories while partitioning
+	PartitionStagingTable = "memories_partitioned"
+	// UnpartitionedMemoriesTable is the original memories table once partitioning
+	// has switched over, kept until DropUnpartitionedMemories removes it
+	UnpartitionedMemoriesTable = "memories_unpartitioned"
+
+	// partitionMirrorTrigger copies writes to memories onto the staging table
+	partitionMirrorTrigger = "memories_partition_mirror"
+	// cascadeDeleteTrigger replaces the foreign keys referencing memories, which a
+	// partitioned table cannot have
+	cascadeDeleteTrigger = "memories_cascade_delete"
+
+	// minPartitionServerVersion is PostgreSQL 14, the first to reindex
+	// partitioned indexes concurrently
+	minPartitionServerVersion = 140000
+	// maxIdentifierLength is PostgreSQL's limit on names
+	maxIdentifierLength = 63
+)
+
+// indexDefinitionPattern picks apart the statement pg_get_indexdef returns
+var indexDefinitionPattern = regexp.MustCompile(`^CREATE INDEX (\S+) ON (?:ONLY )?\S+ USING `)
+
+// PartitionOptions controls partitioning the memories table
+type PartitionOptions struct {
+	// Partitions is how many hash partitions of user_id to create
+	Partitions int
+	// BatchSize is how many memories are copied per statement
+	BatchSize int
+	// Pause is the rest between batches, leaving room for the live workload
+	Pause time.Duration
+	// LockTimeout bounds the wait for the exclusive lock the switch takes; the
+	// switch fails rather than queue every other query behind it
+	LockTimeout time.Duration
+	// Progress, when set, is called after each batch
+	Progress func(PartitionProgress)
+}
+
+// withDefaults fills in unset options
+func (o PartitionOptions) withDefaults() PartitionOptions {
+	if o.Partitions <= 0 {
+		o.Partitions = 16
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 5000
+	}
+	if o.LockTimeout <= 0 {
+		o.LockTimeout = 10 * time.Second
+	}
+	return o
+}
+
+// PartitionProgress reports how far the copy into the staging table has got
+type PartitionProgress struct {
+	Copied int64 `json:"copied"`
+	LastID uint  `json:"last_id"`
+	// MaxID is the last memory the copy covers; later ones are mirrored
+	MaxID uint `json:"max_id"`
+}
+
+// MemoryPartition is one hash partition of the memories table
+type MemoryPartition struct {
+	Name string `json:"name"`
+	// Rows is the planner's estimate
+	Rows  int64 `json:"rows"`
+	Bytes int64 `json:"bytes"`
+}
+
+// PartitionStatus describes how the memories table is partitioned
+type PartitionStatus struct {
+	Partitioned bool `json:"partitioned"`
+	// Migrating is set while memories are being copied into the staging table
+	Migrating bool `json:"migrating"`
+	// UnpartitionedKept is set while the original table is kept after the switch
+	UnpartitionedKept bool              `json:"unpartitioned_kept"`
+	Partitions        []MemoryPartition `json:"partitions"`
+}
+
+// MemoriesPartitioned reports whether the memories table is partitioned. Only
+// postgres tables can be.
+func MemoriesPartitioned(ctx context.Context, db *gorm.DB) (bool, error) {
+	if db.Dialector.Name() != "postgres" {
+		return false, nil
+	}
+	var partitioned bool
+	if err := db.WithContext(ctx).Raw(
+		"SELECT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = to_regclass('memories'))",
+	).Scan(&partitioned).Error; err != nil {
+		return false, fmt.Errorf("failed to check memories partitioning: %w", err)
+	}
+	return partitioned, nil
+}
+
+// InspectPartitioning reports whether memories are partitioned, or being
+// partitioned, and the size of each partition
+func InspectPartitioning(ctx context.Context, db *gorm.DB) (*PartitionStatus, error) {
+	status := &PartitionStatus{Partitions: []MemoryPartition{}}
+	if db.Dialector.Name() != "postgres" {
+		return status, nil
+	}
+
+	partitioned, err := MemoriesPartitioned(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	status.Partitioned = partitioned
+	if status.Migrating, err = tableExists(ctx, db, PartitionStagingTable); err != nil {
+		return nil, err
+	}
+	if status.UnpartitionedKept, err = tableExists(ctx, db, UnpartitionedMemoriesTable); err != nil {
+		return nil, err
+	}
+
+	parent := "memories"
+	if !partitioned {
+		if !status.Migrating {
+			return status, nil
+		}
+		parent = PartitionStagingTable
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT c.relname AS name, GREATEST(c.reltuples, 0)::bigint AS rows,
+			pg_total_relation_size(c.oid) AS bytes
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass(?)
+		ORDER BY length(c.relname), c.relname
+	`, parent).Scan(&status.Partitions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list memory partitions: %w", err)
+	}
+	return status, nil
+}
+
+// PartitionMemories turns the memories table into one hash-partitioned on
+// user_id while the server keeps running. Every memory query names its user, so
+// PostgreSQL reads that user's partition alone, and each partition has its own
+// smaller vector index; queries across users scan the partitions in parallel.
+//
+// It creates a partitioned staging table with the same columns and indexes, and
+// a trigger mirroring every write to memories onto it. It then copies the
+// existing memories a batch at a time and finally, holding an exclusive lock
+// for the few seconds it takes to check both tables match, swaps the tables.
+// The original is kept as memories_unpartitioned until
+// DropUnpartitionedMemories removes it.
+//
+// A partitioned table cannot be the target of foreign keys, so those of the
+// tables referencing memories are replaced by a trigger deleting their rows
+// along with the memory, and later migrations create no foreign keys.
+//
+// Interrupted runs resume: the staging table is kept and copying starts over,
+// skipping rows already copied.
+func PartitionMemories(ctx context.Context, db *gorm.DB, opts PartitionOptions, logger zerolog.Logger) (*PartitionStatus, error) {
+	if db.Dialector.Name() != "postgres" {
+		return nil, fmt.Errorf("partitioning requires postgres, not %s", db.Dialector.Name())
+	}
+	opts = opts.withDefaults()
+	db = db.WithContext(ctx)
+
+	var version int
+	if err := db.Raw("SELECT current_setting('server_version_num')::int").Scan(&version).Error; err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	if version < minPartitionServerVersion {
+		return nil, fmt.Errorf("partitioning requires PostgreSQL 14 or later, not %d", version)
+	}
+
+	partitioned, err := MemoriesPartitioned(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if partitioned {
+		logger.Info().Msg("Memories are already partitioned")
+		return InspectPartitioning(ctx, db)
+	}
+
+	if err := preparePartitionedMemories(ctx, db, opts, logger); err != nil {
+		return nil, err
+	}
+	if err := copyIntoPartitions(ctx, db, opts, logger); err != nil {
+		return nil, err
+	}
+	if err := switchToPartitions(ctx, db, opts, logger); err != nil {
+		return nil, err
+	}
+	if err := db.Exec("ANALYZE memories").Error; err != nil {
+		logger.Warn().Err(err).Msg("Failed to analyze partitioned memories")
+	}
+	return InspectPartitioning(ctx, db)
+}
+
+// DropUnpartitionedMemories drops the original memories table kept after
+// partitioning, once the partitioned table has proven itself
+func DropUnpartitionedMemories(ctx context.Context, db *gorm.DB) error {
+	partitioned, err := MemoriesPartitioned(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !partitioned {
+		return errors.New("memories are not partitioned; the original table is still in use")
+	}
+	if err := db.WithContext(ctx).Exec("DROP TABLE IF EXISTS " + quoteIdentifier(UnpartitionedMemoriesTable)).Error; err != nil {
+		return fmt.Errorf("failed to drop %s: %w", UnpartitionedMemoriesTable, err)
+	}
+	return nil
+}
+
+// memoryIndex is an index of the memories table
+type memoryIndex struct {
+	Name       string
+	Definition string
+	Primary    bool
+	Unique     bool
+}
+
+// memoryForeignKey is a foreign key of another table referencing memories
+type memoryForeignKey struct {
+	Name   string
+	Table  string
+	Column string
+	// OnDelete is pg_constraint.confdeltype: c cascades, n sets null
+	OnDelete string
+}
+
+// preparePartitionedMemories creates the staging table, its partitions and
+// indexes, and the trigger mirroring writes onto it. Indexes are built while the
+// table is empty, so copying maintains them without ever blocking writes.
+func preparePartitionedMemories(ctx context.Context, db *gorm.DB, opts PartitionOptions, logger zerolog.Logger) error {
+	exists, err := tableExists(ctx, db, PartitionStagingTable)
+	if err != nil {
+		return err
+	}
+	if exists {
+		logger.Info().Msg("Resuming partitioning into the existing staging table")
+		return nil
+	}
+
+	var sequence *string
+	if err := db.Raw("SELECT pg_get_serial_sequence('memories', 'id')").Scan(&sequence).Error; err != nil {
+		return fmt.Errorf("failed to find the memory ID sequence: %w", err)
+	}
+	if sequence == nil {
+		return errors.New("memories.id has no sequence to carry over to the partitioned table")
+	}
+
+	indexes, err := memoryIndexes(ctx, db, "memories")
+	if err != nil {
+		return err
+	}
+	columns, err := memoryColumns(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	staging := quoteIdentifier(PartitionStagingTable)
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (LIKE memories INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING GENERATED INCLUDING STORAGE INCLUDING COMMENTS) PARTITION BY HASH (user_id)", staging),
+		fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY (id, user_id)", staging, quoteIdentifier(PartitionStagingTable+"_pkey")),
+	}
+	for i := 0; i < opts.Partitions; i++ {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE %s PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+			quoteIdentifier(fmt.Sprintf("memories_p%d", i)), staging, opts.Partitions, i,
+		))
+	}
+
+	// Foreign keys of memories itself, such as the one to users, carry over
+	var foreignKeys []struct {
+		Name       string
+		Definition string
+	}
+	if err := db.Raw(`
+		SELECT conname AS name, pg_get_constraintdef(oid) AS definition
+		FROM pg_constraint
+		WHERE conrelid = 'memories'::regclass AND contype = 'f'
+	`).Scan(&foreignKeys).Error; err != nil {
+		return fmt.Errorf("failed to read memory foreign keys: %w", err)
+	}
+	for _, fk := range foreignKeys {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+			staging, quoteIdentifier(fk.Name), fk.Definition))
+	}
+
+	for _, index := range indexes {
+		if index.Primary {
+			continue
+		}
+		if index.Unique {
+			return fmt.Errorf("unique index %s cannot be partitioned by user; drop it first", index.Name)
+		}
+		statement, err := stagingIndexStatement(index)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, statement)
+	}
+	statements = append(statements, partitionMirrorStatements(columns)...)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s: %w", firstLine(statement), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create partitioned memories table: %w", err)
+	}
+
+	logger.Info().
+		Int("partitions", opts.Partitions).
+		Int("indexes", len(indexes)).
+		Msg("Created partitioned memories table; writes are now mirrored onto it")
+	return nil
+}
+
+// copyIntoPartitions copies existing memories into the staging table in batches
+// of IDs. Memories after the last one present when copying starts are written
+// by the mirror trigger, which was in place before it started.
+func copyIntoPartitions(ctx context.Context, db *gorm.DB, opts PartitionOptions, logger zerolog.Logger) error {
+	columns, err := memoryColumns(ctx, db)
+	if err != nil {
+		return err
+	}
+	list := quotedColumnList(columns)
+
+	progress := PartitionProgress{}
+	if err := db.Raw("SELECT COALESCE(MAX(id), 0) FROM memories").Scan(&progress.MaxID).Error; err != nil {
+		return fmt.Errorf("failed to find the last memory: %w", err)
+	}
+
+	for progress.LastID < progress.MaxID {
+		// The end of the batch is the BatchSize-th ID after the last one copied,
+		// so gaps in the IDs don't make batches empty
+		var upper []uint
+		if err := db.Raw("SELECT id FROM memories WHERE id > ? AND id <= ? ORDER BY id OFFSET ? LIMIT 1",
+			progress.LastID, progress.MaxID, opts.BatchSize-1).Scan(&upper).Error; err != nil {
+			return fmt.Errorf("failed to find the next batch of memories: %w", err)
+		}
+		end := progress.MaxID
+		if len(upper) > 0 {
+			end = upper[0]
+		}
+
+		result := db.Exec(fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM memories WHERE id > ? AND id <= ? ON CONFLICT DO NOTHING",
+			quoteIdentifier(PartitionStagingTable), list, list,
+		), progress.LastID, end)
+		if result.Error != nil {
+			return fmt.Errorf("failed to copy memories %d to %d: %w", progress.LastID+1, end, result.Error)
+		}
+		progress.Copied += result.RowsAffected
+		progress.LastID = end
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		if opts.Pause > 0 && progress.LastID < progress.MaxID {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+
+	logger.Info().
+		Int64("copied", progress.Copied).
+		Uint("max_id", progress.MaxID).
+		Msg("Copied memories into partitions")
+	return nil
+}
+
+// switchToPartitions swaps the staging table in for memories. Under an exclusive
+// lock it removes rows the copy resurrected after concurrent deletes, checks
+// both tables hold the same memories, and renames tables, indexes and
+// constraints so the partitioned table takes the original's place.
+func switchToPartitions(ctx context.Context, db *gorm.DB, opts PartitionOptions, logger zerolog.Logger) error {
+	start := time.Now()
+	staging := quoteIdentifier(PartitionStagingTable)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d", opts.LockTimeout.Milliseconds())).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("LOCK TABLE memories IN ACCESS EXCLUSIVE MODE").Error; err != nil {
+			return fmt.Errorf("failed to lock memories: %w", err)
+		}
+
+		if err := tx.Exec(fmt.Sprintf(
+			"DELETE FROM %s p WHERE NOT EXISTS (SELECT 1 FROM memories m WHERE m.id = p.id AND m.user_id = p.user_id)",
+			staging,
+		)).Error; err != nil {
+			return fmt.Errorf("failed to remove deleted memories from partitions: %w", err)
+		}
+		var counts struct {
+			Original    int64
+			Partitioned int64
+		}
+		if err := tx.Raw(fmt.Sprintf(
+			"SELECT (SELECT COUNT(*) FROM memories) AS original, (SELECT COUNT(*) FROM %s) AS partitioned", staging,
+		)).Scan(&counts).Error; err != nil {
+			return fmt.Errorf("failed to count memories: %w", err)
+		}
+		if counts.Original != counts.Partitioned {
+			return fmt.Errorf("partitions hold %d memories but memories holds %d; run again to recopy",
+				counts.Partitioned, counts.Original)
+		}
+
+		var added []string
+		if err := tx.Raw(`
+			SELECT column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'memories'
+			EXCEPT
+			SELECT column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ?
+		`, PartitionStagingTable).Scan(&added).Error; err != nil {
+			return fmt.Errorf("failed to compare memory columns: %w", err)
+		}
+		if len(added) > 0 {
+			return fmt.Errorf("memories gained columns %s since partitioning began; drop %s and start again",
+				strings.Join(added, ", "), PartitionStagingTable)
+		}
+
+		originalIndexes, err := memoryIndexes(ctx, tx, "memories")
+		if err != nil {
+			return err
+		}
+		stagingIndexes, err := memoryIndexes(ctx, tx, PartitionStagingTable)
+		if err != nil {
+			return err
+		}
+		foreignKeys, err := referencingForeignKeys(ctx, tx)
+		if err != nil {
+			return err
+		}
+		var sequence string
+		if err := tx.Raw("SELECT pg_get_serial_sequence('memories', 'id')").Scan(&sequence).Error; err != nil {
+			return fmt.Errorf("failed to find the memory ID sequence: %w", err)
+		}
+
+		statements := []string{
+			fmt.Sprintf("DROP TRIGGER %s ON memories", quoteIdentifier(partitionMirrorTrigger)),
+			fmt.Sprintf("DROP FUNCTION %s()", quoteIdentifier(partitionMirrorTrigger)),
+			"DROP TRIGGER IF EXISTS memories_counters ON memories",
+		}
+		// Indexes added to memories since the staging table was created are built
+		// now, under the lock
+		staged := make(map[string]bool, len(stagingIndexes))
+		for _, index := range stagingIndexes {
+			staged[index.Name] = true
+		}
+		for _, index := range originalIndexes {
+			if index.Primary || staged[stagingIndexName(index.Name)] {
+				continue
+			}
+			statement, err := stagingIndexStatement(index)
+			if err != nil {
+				return err
+			}
+			statements = append(statements, statement)
+		}
+		for _, fk := range foreignKeys {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s",
+				quoteIdentifier(fk.Table), quoteIdentifier(fk.Name)))
+		}
+		statements = append(statements,
+			fmt.Sprintf("ALTER TABLE memories RENAME TO %s", quoteIdentifier(UnpartitionedMemoriesTable)),
+		)
+		// Index names are unique across the schema, so the original's give way
+		for _, index := range originalIndexes {
+			old := quoteIdentifier(truncateIdentifier("u_" + index.Name))
+			if index.Primary {
+				statements = append(statements,
+					fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s",
+						quoteIdentifier(UnpartitionedMemoriesTable), quoteIdentifier(index.Name), old),
+					fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s",
+						staging, quoteIdentifier(PartitionStagingTable+"_pkey"), quoteIdentifier(index.Name)),
+				)
+				continue
+			}
+			statements = append(statements,
+				fmt.Sprintf("ALTER INDEX %s RENAME TO %s", quoteIdentifier(index.Name), old),
+				fmt.Sprintf("ALTER INDEX %s RENAME TO %s", quoteIdentifier(stagingIndexName(index.Name)), quoteIdentifier(index.Name)),
+			)
+		}
+		statements = append(statements,
+			fmt.Sprintf("ALTER TABLE %s RENAME TO memories", staging),
+			fmt.Sprintf("ALTER SEQUENCE %s OWNED BY memories.id", sequence),
+		)
+		statements = append(statements, cascadeDeleteStatements(foreignKeys)...)
+		statements = append(statements, memoryCountersPostgres...)
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s: %w", firstLine(statement), err)
+			}
+		}
+
+		for _, fk := range foreignKeys {
+			if fk.OnDelete != "c" && fk.OnDelete != "n" {
+				logger.Warn().
+					Str("table", fk.Table).
+					Str("constraint", fk.Name).
+					Msg("Dropped a foreign key to memories that neither cascades nor sets null; it is no longer enforced")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to switch to partitioned memories: %w", err)
+	}
+
+	logger.Info().
+		Dur("locked_for", time.Since(start)).
+		Str("original", UnpartitionedMemoriesTable).
+		Msg("Switched to partitioned memories; the original table is kept until dropped")
+	return nil
+}
+
+// memoryIndexes lists the indexes of a table
+func memoryIndexes(ctx context.Context, db *gorm.DB, table string) ([]memoryIndex, error) {
+	var indexes []memoryIndex
+	if err := db.WithContext(ctx).Raw(`
+		SELECT c.relname AS name, pg_get_indexdef(i.indexrelid) AS definition,
+			i.indisprimary AS "primary", i.indisunique AS "unique"
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE i.indrelid = to_regclass(?)
+		ORDER BY c.relname
+	`, table).Scan(&indexes).Error; err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+	return indexes, nil
+}
+
+// memoryColumns lists the columns of memories in order, leaving out generated
+// ones such as content_tsv, which cannot be written and are computed on copy
+func memoryColumns(ctx context.Context, db *gorm.DB) ([]string, error) {
+	var columns []string
+	if err := db.WithContext(ctx).Raw(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'memories' AND is_generated = 'NEVER'
+		ORDER BY ordinal_position
+	`).Scan(&columns).Error; err != nil {
+		return nil, fmt.Errorf("failed to read memory columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("memories table not found")
+	}
+	return columns, nil
+}
+
+// referencingForeignKeys lists the foreign keys of other tables referencing
+// memories
+func referencingForeignKeys(ctx context.Context, db *gorm.DB) ([]memoryForeignKey, error) {
+	var foreignKeys []memoryForeignKey
+	if err := db.WithContext(ctx).Raw(`
+		SELECT con.conname AS name, cls.relname AS "table", att.attname AS "column",
+			con.confdeltype AS on_delete
+		FROM pg_constraint con
+		JOIN pg_class cls ON cls.oid = con.conrelid
+		JOIN pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = con.conkey[1]
+		WHERE con.contype = 'f' AND con.confrelid = 'memories'::regclass AND con.conrelid <> con.confrelid
+		ORDER BY cls.relname, con.conname
+	`).Scan(&foreignKeys).Error; err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys to memories: %w", err)
+	}
+	return foreignKeys, nil
+}
+
+// tableExists reports whether a table exists in the current schema
+func tableExists(ctx context.Context, db *gorm.DB, table string) (bool, error) {
+	var exists bool
+	if err := db.WithContext(ctx).Raw("SELECT to_regclass(?) IS NOT NULL", table).Scan(&exists).Error; err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, err)
+	}
+	return exists, nil
+}
+
+// stagingIndexName is the name an index of memories has on the staging table
+// until the switch
+func stagingIndexName(name string) string {
+	return truncateIdentifier("p_" + name)
+}
+
+// stagingIndexStatement rewrites an index of memories to build the same index on
+// the staging table
+func stagingIndexStatement(index memoryIndex) (string, error) {
+	match := indexDefinitionPattern.FindStringIndex(index.Definition)
+	if match == nil {
+		return "", fmt.Errorf("cannot copy index %s: unexpected definition %q", index.Name, index.Definition)
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s USING %s",
+		quoteIdentifier(stagingIndexName(index.Name)),
+		quoteIdentifier(PartitionStagingTable),
+		index.Definition[match[1]:],
+	), nil
+}
+
+// partitionMirrorStatements create the trigger copying every write to memories
+// onto the staging table. Updates that move a memory to another user move it to
+// that user's partition.
+func partitionMirrorStatements(columns []string) []string {
+	values := make([]string, len(columns))
+	updates := make([]string, 0, len(columns))
+	for i, column := range columns {
+		quoted := quoteIdentifier(column)
+		values[i] = "NEW." + quoted
+		if column != "id" && column != "user_id" {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted))
+		}
+	}
+	staging := quoteIdentifier(PartitionStagingTable)
+	trigger := quoteIdentifier(partitionMirrorTrigger)
+
+	return []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id <> NEW.user_id) THEN
+			DELETE FROM %[2]s WHERE id = OLD.id AND user_id = OLD.user_id;
+		END IF;
+		IF TG_OP IN ('INSERT', 'UPDATE') THEN
+			INSERT INTO %[2]s (%[3]s) VALUES (%[4]s)
+			ON CONFLICT (id, user_id) DO UPDATE SET %[5]s;
+		END IF;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`, trigger, staging, quotedColumnList(columns), strings.Join(values, ", "), strings.Join(updates, ", ")),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON memories", trigger),
+		fmt.Sprintf("CREATE TRIGGER %[1]s AFTER INSERT OR UPDATE OR DELETE ON memories FOR EACH ROW EXECUTE FUNCTION %[1]s()", trigger),
+	}
+}
+
+// cascadeDeleteStatements create the trigger doing what the foreign keys to
+// memories did when a memory is deleted: deleting the rows referencing it, or
+// clearing their reference
+func cascadeDeleteStatements(foreignKeys []memoryForeignKey) []string {
+	var actions []string
+	for _, fk := range foreignKeys {
+		switch fk.OnDelete {
+		case "c":
+			actions = append(actions, fmt.Sprintf("DELETE FROM %s WHERE %s = OLD.id;",
+				quoteIdentifier(fk.Table), quoteIdentifier(fk.Column)))
+		case "n":
+			actions = append(actions, fmt.Sprintf("UPDATE %[1]s SET %[2]s = NULL WHERE %[2]s = OLD.id;",
+				quoteIdentifier(fk.Table), quoteIdentifier(fk.Column)))
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	trigger := quoteIdentifier(cascadeDeleteTrigger)
+	return []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+	BEGIN
+		%s
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`, trigger, strings.Join(actions, "\n\t\t")),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON memories", trigger),
+		fmt.Sprintf("CREATE TRIGGER %[1]s AFTER DELETE ON memories FOR EACH ROW EXECUTE FUNCTION %[1]s()", trigger),
+	}
+}
+
+// quotedColumnList joins quoted column names for a column list
+func quotedColumnList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// truncateIdentifier shortens a name to PostgreSQL's limit
+func truncateIdentifier(name string) string {
+	if len(name) > maxIdentifierLength {
+		return name[:maxIdentifierLength]
+	}
+	return name
+}
+
+// firstLine returns the first line of a statement, for error messages
+func firstLine(statement string) string {
+	line, _, _ := strings.Cut(statement, "\n")
+	return line
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStagingIndexStatement(t *testing.T) {
+	statement, err := stagingIndexStatement(memoryIndex{
+		Name:       "idx_memories_user_session",
+		Definition: "CREATE INDEX idx_memories_user_session ON public.memories USING btree (user_id, session_id) WHERE ((session_id IS NOT NULL) AND ((session_id)::text <> ''::text))",
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`CREATE INDEX "p_idx_memories_user_session" ON "memories_partitioned" USING btree (user_id, session_id) WHERE ((session_id IS NOT NULL) AND ((session_id)::text <> ''::text))`,
+		statement)
+
+	statement, err = stagingIndexStatement(memoryIndex{
+		Name:       VectorIndexName,
+		Definition: "CREATE INDEX idx_memories_embedding ON public.memories USING hnsw (embedding vector_cosine_ops) WITH (m='16', ef_construction='64')",
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`CREATE INDEX "p_idx_memories_embedding" ON "memories_partitioned" USING hnsw (embedding vector_cosine_ops) WITH (m='16', ef_construction='64')`,
+		statement)
+
+	_, err = stagingIndexStatement(memoryIndex{Name: "odd", Definition: "CREATE UNIQUE INDEX odd ON memories USING btree (id)"})
+	assert.Error(t, err)
+}
+
+func TestPartitionMirrorStatements(t *testing.T) {
+	statements := partitionMirrorStatements([]string{"id", "user_id", "content"})
+	require.Len(t, statements, 3)
+	assert.Contains(t, statements[0], `INSERT INTO "memories_partitioned" ("id", "user_id", "content") VALUES (NEW."id", NEW."user_id", NEW."content")`)
+	assert.Contains(t, statements[0], `ON CONFLICT (id, user_id) DO UPDATE SET "content" = EXCLUDED."content";`)
+	assert.Contains(t, statements[0], `DELETE FROM "memories_partitioned" WHERE id = OLD.id AND user_id = OLD.user_id;`)
+	assert.Equal(t,
+		`CREATE TRIGGER "memories_partition_mirror" AFTER INSERT OR UPDATE OR DELETE ON memories FOR EACH ROW EXECUTE FUNCTION "memories_partition_mirror"()`,
+		statements[2])
+}
+
+func TestCascadeDeleteStatements(t *testing.T) {
+	assert.Empty(t, cascadeDeleteStatements(nil))
+
+	statements := cascadeDeleteStatements([]memoryForeignKey{
+		{Name: "fk_memory_revisions_memory", Table: "memory_revisions", Column: "memory_id", OnDelete: "c"},
+		{Name: "fk_attachments_memory", Table: "attachments", Column: "memory_id", OnDelete: "n"},
+		{Name: "fk_other", Table: "other", Column: "memory_id", OnDelete: "a"},
+	})
+	require.Len(t, statements, 3)
+	assert.Contains(t, statements[0], `DELETE FROM "memory_revisions" WHERE "memory_id" = OLD.id;`)
+	assert.Contains(t, statements[0], `UPDATE "attachments" SET "memory_id" = NULL WHERE "memory_id" = OLD.id;`)
+	assert.NotContains(t, statements[0], `"other"`)
+	assert.Contains(t, statements[2], "AFTER DELETE ON memories")
+}
+
+func TestStagingIndexName(t *testing.T) {
+	assert.Equal(t, "p_idx_memories_tags", stagingIndexName("idx_memories_tags"))
+	long := "idx_memories_" + strings.Repeat("x", 60)
+	assert.Len(t, stagingIndexName(long), maxIdentifierLength)
+}
+
+func TestPartitioning_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openDualWriteTestDB(t, "partitioning.db")
+
+	// Only postgres tables can be partitioned
+	partitioned, err := MemoriesPartitioned(ctx, db)
+	require.NoError(t, err)
+	assert.False(t, partitioned)
+
+	status, err := InspectPartitioning(ctx, db)
+	require.NoError(t, err)
+	assert.False(t, status.Partitioned)
+	assert.Empty(t, status.Partitions)
+
+	_, err = PartitionMemories(ctx, db, PartitionOptions{}, zerolog.New(nil).Level(zerolog.Disabled))
+	assert.Error(t, err)
+}
+
+func TestPartitionOptionsDefaults(t *testing.T) {
+	opts := PartitionOptions{}.withDefaults()
+	assert.Equal(t, 16, opts.Partitions)
+	assert.Equal(t, 5000, opts.BatchSize)
+	assert.Positive(t, opts.LockTimeout)
+}
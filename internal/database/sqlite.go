@@ -0,0 +1,97 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// memoriesTableSQLite creates the memories table without pgvector types.
+// Embeddings are stored as opaque blobs and tags as text.
+const memoriesTableSQLite = `
+	CREATE TABLE IF NOT EXISTS memories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL DEFAULT 1,
+		workspace_id INTEGER NOT NULL DEFAULT 0,
+		type TEXT NOT NULL,
+		category TEXT NOT NULL,
+		content TEXT NOT NULL,
+		encrypted_content TEXT,
+		is_encrypted BOOLEAN DEFAULT FALSE,
+		priority TEXT DEFAULT 'medium',
+		update_key TEXT,
+		content_hash TEXT,
+		content_index TEXT,
+		keyword_index TEXT,
+		access_count INTEGER NOT NULL DEFAULT 0,
+		last_accessed_at DATETIME,
+		session_id TEXT,
+		embedding BLOB,
+		tags TEXT,
+		metadata TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
+	)
+`
+
+// OpenInMemory opens an in-memory SQLite database with the application schema,
+// for running the server without Postgres. Data is lost when the process exits.
+// SQLite has no vector type, so searches fall back to keyword matching.
+func OpenInMemory(logLevel string) (*Database, error) {
+	db := NewDatabase(map[string]interface{}{
+		"log_level": logLevel,
+	})
+
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(db.getLogLevel()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+	if err := gormDB.Use(NewWorkspaceScope()); err != nil {
+		return nil, fmt.Errorf("failed to enable workspace scoping: %w", err)
+	}
+
+	// Each connection to :memory: gets its own empty database, so keep exactly one
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+	db.SetDB(gormDB)
+
+	if err := gormDB.Exec(memoriesTableSQLite).Error; err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create memories table: %w", err)
+	}
+
+	var tables []interface{}
+	for _, model := range schemaModels() {
+		if _, ok := model.(*models.Memory); !ok {
+			tables = append(tables, model)
+		}
+	}
+	if err := gormDB.AutoMigrate(tables...); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run auto-migrations: %w", err)
+	}
+
+	if err := InstallMemoryCounters(gormDB); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := createSystemUser(gormDB); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create system user: %w", err)
+	}
+
+	return db, nil
+}
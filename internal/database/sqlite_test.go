@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestOpenInMemory(t *testing.T) {
+	db, err := OpenInMemory("silent")
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Health(context.Background()))
+
+	var system models.User
+	require.NoError(t, db.DB().First(&system, SystemUserID).Error)
+
+	// Writes are visible to later queries, which share the one connection
+	memory := &models.Memory{UserID: SystemUserID, Type: models.TypeFact, Category: models.CategoryPersonal, Content: "in memory"}
+	require.NoError(t, db.DB().Omit("embedding", "tags").Create(memory).Error)
+	var count int64
+	require.NoError(t, db.DB().Model(&models.Memory{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Each call opens a separate database
+	other, err := OpenInMemory("silent")
+	require.NoError(t, err)
+	defer other.Close()
+	require.NoError(t, other.DB().Model(&models.Memory{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
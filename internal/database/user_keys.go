@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// UserDataKey returns the encryption service for a user's own data key. Each
+// user's memories are encrypted with their own key, so a leaked key exposes only
+// that user. The key is created on first use and stored in users.encryption_key,
+// encrypted with the master key. It returns gorm.ErrRecordNotFound when the user
+// does not exist.
+func UserDataKey(ctx context.Context, db *gorm.DB, master *utils.EncryptionService, userID uint) (*utils.EncryptionService, error) {
+	wrapped, err := loadUserDataKey(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapped == nil {
+		created, err := master.NewWrappedKey()
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(created)
+		if err != nil {
+			return nil, err
+		}
+		result := db.WithContext(ctx).Table("users").
+			Where("id = ? AND encryption_key IS NULL", userID).
+			UpdateColumn("encryption_key", json.RawMessage(encoded))
+		if result.Error != nil {
+			return nil, fmt.Errorf("store user data key: %w", result.Error)
+		}
+		wrapped = created
+
+		// Another request created the key first
+		if result.RowsAffected == 0 {
+			if wrapped, err = loadUserDataKey(ctx, db, userID); err != nil {
+				return nil, err
+			}
+			if wrapped == nil {
+				return nil, fmt.Errorf("user %d data key was not stored", userID)
+			}
+		}
+	}
+
+	return master.UnwrapKey(wrapped)
+}
+
+// loadUserDataKey reads a user's wrapped data key, which is nil until created
+func loadUserDataKey(ctx context.Context, db *gorm.DB, userID uint) (*utils.EncryptedData, error) {
+	var row struct {
+		EncryptionKey []byte
+	}
+	result := db.WithContext(ctx).Table("users").
+		Select("encryption_key").
+		Where("id = ?", userID).
+		Limit(1).
+		Scan(&row)
+	if result.Error != nil {
+		return nil, fmt.Errorf("load user data key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if len(row.EncryptionKey) == 0 {
+		return nil, nil
+	}
+
+	var wrapped utils.EncryptedData
+	if err := json.Unmarshal(row.EncryptionKey, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode user data key: %w", err)
+	}
+	return &wrapped, nil
+}
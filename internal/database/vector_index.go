@@ -0,0 +1,306 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+// VectorIndexName is the pgvector index on memory embeddings
+const VectorIndexName = "idx_memories_embedding"
+
+// Vector index types
+const (
+	// VectorIndexHNSW builds a graph index: slower to build, better recall
+	VectorIndexHNSW = "hnsw"
+	// VectorIndexIVFFlat clusters embeddings into lists: quicker to build, but the
+	// clusters are fixed at build time and drift as memories are added
+	VectorIndexIVFFlat = "ivfflat"
+	// VectorIndexNone leaves any existing index alone
+	VectorIndexNone = "none"
+)
+
+// VectorIndexOptions are the build parameters of the vector index
+type VectorIndexOptions struct {
+	Type           string
+	M              int
+	EfConstruction int
+	Lists          int
+}
+
+// VectorIndexOptionsFromConfig returns the build parameters configured for the
+// vector index, with pgvector's defaults for unset values
+func VectorIndexOptionsFromConfig(cfg config.VectorIndex) VectorIndexOptions {
+	opts := VectorIndexOptions{
+		Type:           cfg.Type,
+		M:              cfg.M,
+		EfConstruction: cfg.EfConstruction,
+		Lists:          cfg.Lists,
+	}
+	if opts.Type == "" {
+		opts.Type = VectorIndexHNSW
+	}
+	if opts.M <= 0 {
+		opts.M = 16
+	}
+	if opts.EfConstruction <= 0 {
+		opts.EfConstruction = 64
+	}
+	if opts.Lists <= 0 {
+		opts.Lists = 100
+	}
+	return opts
+}
+
+// storageParameters returns the index's WITH parameters as pg_class.reloptions
+// lists them
+func (o VectorIndexOptions) storageParameters() []string {
+	switch o.Type {
+	case VectorIndexHNSW:
+		return []string{fmt.Sprintf("m=%d", o.M), fmt.Sprintf("ef_construction=%d", o.EfConstruction)}
+	case VectorIndexIVFFlat:
+		return []string{fmt.Sprintf("lists=%d", o.Lists)}
+	default:
+		return nil
+	}
+}
+
+// createStatement builds the statement creating the index without blocking writes
+func (o VectorIndexOptions) createStatement() string {
+	return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON memories USING %s (embedding vector_cosine_ops) WITH (%s)",
+		VectorIndexName, o.Type, strings.Join(o.storageParameters(), ", "))
+}
+
+// VectorIndexStatus describes the vector index as it is and as it is configured
+type VectorIndexStatus struct {
+	// Configured is the index type the configuration asks for
+	Configured string `json:"configured"`
+	Exists     bool   `json:"exists"`
+	// Type and Parameters are those the existing index was built with
+	Type       string   `json:"type,omitempty"`
+	Parameters []string `json:"parameters,omitempty"`
+	// Valid is false when a concurrent build failed part way
+	Valid bool  `json:"valid"`
+	Bytes int64 `json:"bytes"`
+	// UpToDate is set when the existing index matches the configuration
+	UpToDate bool `json:"up_to_date"`
+	// UsedByQueries reports whether the planner chooses the index for a nearest
+	// neighbour query; it is unknown until a memory has an embedding
+	UsedByQueries *bool `json:"used_by_queries,omitempty"`
+}
+
+// InspectVectorIndex reports the state of the vector index. Only postgres has one.
+func InspectVectorIndex(ctx context.Context, db *gorm.DB, opts VectorIndexOptions) (*VectorIndexStatus, error) {
+	status := &VectorIndexStatus{Configured: opts.Type}
+	if db.Dialector.Name() != "postgres" {
+		return status, nil
+	}
+
+	var rows []struct {
+		Type       string
+		Parameters pq.StringArray
+		Valid      bool
+		Bytes      int64
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT am.amname AS type, c.reloptions AS parameters, i.indisvalid AS valid,
+			(SELECT COALESCE(SUM(pg_relation_size(relid)), 0) FROM pg_partition_tree(c.oid))::bigint AS bytes
+		FROM pg_class c
+		JOIN pg_am am ON am.oid = c.relam
+		JOIN pg_index i ON i.indexrelid = c.oid
+		WHERE c.relname = ?
+	`, VectorIndexName).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to inspect vector index: %w", err)
+	}
+	if len(rows) > 0 {
+		status.Exists = true
+		status.Type = rows[0].Type
+		status.Parameters = rows[0].Parameters
+		status.Valid = rows[0].Valid
+		status.Bytes = rows[0].Bytes
+		status.UpToDate = status.Valid && status.Type == opts.Type &&
+			slices.Equal(sortedCopy(status.Parameters), sortedCopy(opts.storageParameters()))
+	}
+
+	used, err := vectorIndexUsed(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	status.UsedByQueries = used
+	return status, nil
+}
+
+// EnsureVectorIndex creates the configured vector index, rebuilding it when its
+// type or parameters have changed or an earlier build failed, and logs whether
+// nearest neighbour queries use it. Builds run concurrently, so writes continue
+// meanwhile, but a large table takes a while. Partitioned tables can't build
+// indexes concurrently, so on those writes wait for the build.
+func EnsureVectorIndex(ctx context.Context, db *gorm.DB, opts VectorIndexOptions, logger zerolog.Logger) error {
+	if db.Dialector.Name() != "postgres" || opts.Type == VectorIndexNone {
+		return nil
+	}
+
+	status, err := InspectVectorIndex(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+	if !status.UpToDate {
+		partitioned, err := MemoriesPartitioned(ctx, db)
+		if err != nil {
+			return err
+		}
+		drop, create := "DROP INDEX CONCURRENTLY IF EXISTS "+quoteIdentifier(VectorIndexName), opts.createStatement()
+		if partitioned {
+			drop = strings.Replace(drop, " CONCURRENTLY", "", 1)
+			create = strings.Replace(create, " CONCURRENTLY", "", 1)
+		}
+
+		if status.Exists {
+			logger.Warn().
+				Str("type", status.Type).
+				Strs("parameters", status.Parameters).
+				Bool("valid", status.Valid).
+				Str("configured", opts.Type).
+				Strs("configured_parameters", opts.storageParameters()).
+				Msg("Vector index differs from the configuration, rebuilding it")
+			if err := db.WithContext(ctx).Exec(drop).Error; err != nil {
+				return fmt.Errorf("failed to drop vector index: %w", err)
+			}
+		}
+
+		start := time.Now()
+		if err := db.WithContext(ctx).Exec(create).Error; err != nil {
+			return fmt.Errorf("failed to create vector index: %w", err)
+		}
+		logger.Info().
+			Str("type", opts.Type).
+			Strs("parameters", opts.storageParameters()).
+			Dur("duration", time.Since(start)).
+			Msg("Created vector index")
+
+		if status, err = InspectVectorIndex(ctx, db, opts); err != nil {
+			return err
+		}
+	}
+
+	logVectorIndexUse(logger, status)
+	return nil
+}
+
+// RebuildVectorIndex rebuilds the vector index and refreshes the planner's
+// statistics, as is worth doing after a bulk import: an ivfflat index keeps the
+// clusters it was built with, and both types keep the space of deleted rows. A
+// missing or outdated index is created as configured instead.
+func RebuildVectorIndex(ctx context.Context, db *gorm.DB, opts VectorIndexOptions, logger zerolog.Logger) ([]MaintenanceResult, *VectorIndexStatus, error) {
+	if db.Dialector.Name() != "postgres" {
+		return nil, nil, fmt.Errorf("vector indexes require postgres, not %s", db.Dialector.Name())
+	}
+
+	status, err := InspectVectorIndex(ctx, db, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []MaintenanceResult
+	if status.Exists && (status.UpToDate || opts.Type == VectorIndexNone) {
+		results = RunMaintenance(ctx, db, []MaintenanceAction{{
+			Statement: "REINDEX INDEX CONCURRENTLY " + quoteIdentifier(VectorIndexName),
+			Reason:    "rebuild the vector index from the current embeddings",
+		}})
+	} else if opts.Type != VectorIndexNone {
+		start := time.Now()
+		if err := EnsureVectorIndex(ctx, db, opts, logger); err != nil {
+			return nil, nil, err
+		}
+		results = append(results, MaintenanceResult{
+			MaintenanceAction: MaintenanceAction{Statement: opts.createStatement(), Reason: "create the configured vector index"},
+			Duration:          time.Since(start),
+		})
+	}
+	results = append(results, RunMaintenance(ctx, db, []MaintenanceAction{{
+		Statement: "ANALYZE memories",
+		Reason:    "refresh the statistics the planner uses to choose the vector index",
+	}})...)
+
+	if status, err = InspectVectorIndex(ctx, db, opts); err != nil {
+		return nil, nil, err
+	}
+	logVectorIndexUse(logger, status)
+	return results, status, nil
+}
+
+// vectorIndexUsed explains a nearest neighbour query like semantic search's and
+// reports whether its plan reads the vector index, or nil when no memory has an
+// embedding to query with
+func vectorIndexUsed(ctx context.Context, db *gorm.DB) (*bool, error) {
+	var probes []struct {
+		UserID    uint
+		Embedding string
+	}
+	if err := db.WithContext(ctx).Raw(
+		"SELECT user_id, embedding::text AS embedding FROM memories WHERE embedding IS NOT NULL LIMIT 1",
+	).Scan(&probes).Error; err != nil {
+		return nil, fmt.Errorf("failed to read a probe embedding: %w", err)
+	}
+	if len(probes) == 0 {
+		return nil, nil
+	}
+
+	var plan []string
+	if err := db.WithContext(ctx).Raw(
+		"EXPLAIN SELECT id FROM memories WHERE user_id = ? AND embedding IS NOT NULL ORDER BY embedding <=> ?::vector LIMIT 10",
+		probes[0].UserID, probes[0].Embedding,
+	).Scan(&plan).Error; err != nil {
+		return nil, fmt.Errorf("failed to explain vector query: %w", err)
+	}
+
+	// A partitioned index is read through the indexes of its partitions
+	var names []string
+	if err := db.WithContext(ctx).Raw(
+		"SELECT c.relname FROM pg_partition_tree(to_regclass(?)) t JOIN pg_class c ON c.oid = t.relid",
+		VectorIndexName,
+	).Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list vector index partitions: %w", err)
+	}
+	text := strings.Join(plan, "\n")
+	used := strings.Contains(text, VectorIndexName)
+	for _, name := range names {
+		used = used || strings.Contains(text, name)
+	}
+	return &used, nil
+}
+
+// logVectorIndexUse logs whether nearest neighbour queries use the vector index
+func logVectorIndexUse(logger zerolog.Logger, status *VectorIndexStatus) {
+	event := logger.Info()
+	if status.UsedByQueries != nil && !*status.UsedByQueries {
+		event = logger.Warn()
+	}
+	event = event.Bool("exists", status.Exists).Str("type", status.Type).Int64("bytes", status.Bytes)
+	if status.UsedByQueries != nil {
+		event = event.Bool("used_by_queries", *status.UsedByQueries)
+	}
+	switch {
+	case status.UsedByQueries == nil:
+		event.Msg("Vector index ready; no embeddings yet to check query plans with")
+	case *status.UsedByQueries:
+		event.Msg("Vector index is used by similarity queries")
+	default:
+		event.Msg("Vector index is not used by similarity queries; the planner prefers a scan, which is expected while tables are small")
+	}
+}
+
+// sortedCopy returns a sorted copy of values
+func sortedCopy(values []string) []string {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+func TestVectorIndexOptions(t *testing.T) {
+	opts := VectorIndexOptionsFromConfig(config.VectorIndex{})
+	assert.Equal(t, VectorIndexOptions{Type: VectorIndexHNSW, M: 16, EfConstruction: 64, Lists: 100}, opts)
+	assert.Equal(t,
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_memories_embedding ON memories USING hnsw (embedding vector_cosine_ops) WITH (m=16, ef_construction=64)",
+		opts.createStatement())
+
+	opts = VectorIndexOptionsFromConfig(config.VectorIndex{Type: VectorIndexIVFFlat, Lists: 500})
+	assert.Equal(t,
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_memories_embedding ON memories USING ivfflat (embedding vector_cosine_ops) WITH (lists=500)",
+		opts.createStatement())
+	assert.Equal(t, []string{"lists=500"}, opts.storageParameters())
+}
+
+func TestVectorIndex_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openDualWriteTestDB(t, "vector_index.db")
+	opts := VectorIndexOptionsFromConfig(config.VectorIndex{})
+
+	// Only postgres has a vector index
+	status, err := InspectVectorIndex(ctx, db, opts)
+	require.NoError(t, err)
+	assert.Equal(t, VectorIndexHNSW, status.Configured)
+	assert.False(t, status.Exists)
+	assert.Nil(t, status.UsedByQueries)
+
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	require.NoError(t, EnsureVectorIndex(ctx, db, opts, logger))
+	_, _, err = RebuildVectorIndex(ctx, db, opts, logger)
+	assert.Error(t, err)
+}
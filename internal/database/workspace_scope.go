@@ -0,0 +1,129 @@
+package database
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// workspaceKey is the session setting holding the workspace ID statements on the
+// memories table are scoped to
+const workspaceKey = "workspace:id"
+
+// WorkspaceScope is a GORM plugin that confines statements on the memories table
+// to one workspace: queries, counts, updates and deletes only match the
+// workspace's memories, and created memories are placed in it. A session is
+// scoped with InWorkspace; unscoped sessions see every workspace, as workers
+// running across the whole account need.
+//
+// Raw SQL is left alone, so raw statements on memories filter on workspace_id
+// themselves.
+type WorkspaceScope struct{}
+
+// NewWorkspaceScope creates the workspace scoping plugin
+func NewWorkspaceScope() *WorkspaceScope {
+	return &WorkspaceScope{}
+}
+
+// Name implements gorm.Plugin
+func (w *WorkspaceScope) Name() string {
+	return "workspace_scope"
+}
+
+// Initialize implements gorm.Plugin by registering the scoping callbacks
+func (w *WorkspaceScope) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("workspace_scope:create", w.assign); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("workspace_scope:query", w.filter); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("workspace_scope:row", w.filter); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("workspace_scope:update", w.filter); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("workspace_scope:delete", w.filter)
+}
+
+// InWorkspace returns a session whose statements on memories are scoped to the
+// workspace
+func InWorkspace(db *gorm.DB, workspaceID uint) *gorm.DB {
+	return db.Set(workspaceKey, workspaceID).Session(&gorm.Session{})
+}
+
+// AllWorkspaces returns a session whose statements on memories see every
+// workspace, lifting the scope of InWorkspace
+func AllWorkspaces(db *gorm.DB) *gorm.DB {
+	return db.Set(workspaceKey, nil).Session(&gorm.Session{})
+}
+
+// Workspace returns the workspace a session is scoped to, if any
+func Workspace(db *gorm.DB) (uint, bool) {
+	value, _ := db.Get(workspaceKey)
+	workspaceID, ok := value.(uint)
+	return workspaceID, ok
+}
+
+// scoped returns the workspace a statement on memories is scoped to. Statements
+// carrying their own SQL are raw and not scoped.
+func (w *WorkspaceScope) scoped(db *gorm.DB) (uint, bool) {
+	if db.Error != nil || db.Statement.Table != "memories" || db.Statement.SQL.Len() > 0 {
+		return 0, false
+	}
+	return Workspace(db)
+}
+
+// filter adds the workspace to the statement's conditions. Existing conditions
+// are grouped first, so an OR among them cannot escape the workspace.
+func (w *WorkspaceScope) filter(db *gorm.DB) {
+	workspaceID, ok := w.scoped(db)
+	if !ok {
+		return
+	}
+
+	condition := clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "workspace_id"}, Value: workspaceID}
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		where = clause.Clause{Name: "WHERE"}
+	}
+	exprs := []clause.Expression{condition}
+	if existing, ok := where.Expression.(clause.Where); ok && len(existing.Exprs) > 0 {
+		exprs = []clause.Expression{clause.And(existing.Exprs...), condition}
+	}
+	where.Expression = clause.Where{Exprs: exprs}
+	db.Statement.Clauses["WHERE"] = where
+}
+
+// assign places the memories being created in the statement's workspace
+func (w *WorkspaceScope) assign(db *gorm.DB) {
+	workspaceID, ok := w.scoped(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("WorkspaceID")
+	if field == nil {
+		return
+	}
+
+	set := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		if rv.Kind() != reflect.Struct {
+			return
+		}
+		if err := field.Set(db.Statement.Context, rv, workspaceID); err != nil {
+			db.AddError(err)
+		}
+	}
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			set(db.Statement.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		set(db.Statement.ReflectValue)
+	}
+}
@@ -0,0 +1,107 @@
+// Package geoip resolves IP addresses to coarse locations using a local MaxMind
+// database (GeoLite2/GeoIP2 City or Country, .mmdb format). Lookups are served
+// entirely from memory; no data leaves the host.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the coarse geo data attached to activity records
+type Location struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// Reader looks up IP addresses in an MMDB database held in memory
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+// locationRecord is the part of a City or Country database record we read
+type locationRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Open loads an MMDB database from disk
+func Open(path string) (*Reader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geoip database: %w", err)
+	}
+	return FromBytes(buffer)
+}
+
+// FromBytes parses an MMDB database from memory
+func FromBytes(buffer []byte) (*Reader, error) {
+	db, err := maxminddb.FromBytes(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geoip database: %w", err)
+	}
+	return &Reader{db: db}, nil
+}
+
+// DatabaseType returns the database type from the metadata, e.g. "GeoLite2-City"
+func (r *Reader) DatabaseType() string {
+	return r.db.Metadata.DatabaseType
+}
+
+// Lookup returns the location of an IP address, or nil if it is not in the database
+func (r *Reader) Lookup(ip net.IP) (*Location, error) {
+	if ip == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	// An IPv4 database holds no IPv6 addresses
+	if ip.To4() == nil && r.db.Metadata.IPVersion == 4 {
+		return nil, nil
+	}
+
+	var record locationRecord
+	if err := r.db.Lookup(ip, &record); err != nil {
+		return nil, fmt.Errorf("geoip lookup failed: %w", err)
+	}
+
+	loc := &Location{
+		CountryCode: record.Country.ISOCode,
+		Country:     record.Country.Names["en"],
+		City:        record.City.Names["en"],
+	}
+	if loc.CountryCode == "" && loc.City == "" {
+		return nil, nil
+	}
+	return loc, nil
+}
+
+// LookupString parses and looks up an IP address string. Unparseable addresses
+// and addresses missing from the database both yield nil.
+func (r *Reader) LookupString(ip string) *Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+	loc, err := r.Lookup(parsed)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Country returns the ISO country code for an IP address, or "" when unknown
+func (r *Reader) Country(ip string) string {
+	if loc := r.LookupString(ip); loc != nil {
+		return loc.CountryCode
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metadataStartMarker precedes the metadata section at the end of an MMDB file
+var metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparatorSize is the number of zero bytes between the search tree and data section
+const dataSectionSeparatorSize = 16
+
+// MMDB data section types written by encodeValue
+const (
+	typePointer = 1
+	typeString  = 2
+	typeMap     = 7
+	typeUint64  = 9
+)
+
+// rawValue is written to the data section as is
+type rawValue []byte
+
+// testNetwork maps a CIDR to the record stored for it
+type testNetwork struct {
+	cidr   string
+	record interface{}
+}
+
+// buildTestDB writes a minimal MMDB file with a 24-bit record size search tree
+func buildTestDB(t *testing.T, ipVersion int, networks []testNetwork) []byte {
+	type node struct{ children [2]int } // -1 empty, >=0 node index, <=-2 data index
+	nodes := []node{{children: [2]int{-1, -1}}}
+	var records []interface{}
+
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		require.NoError(t, err)
+		ones, _ := ipNet.Mask.Size()
+
+		ip := ipNet.IP
+		if v4 := ip.To4(); v4 != nil && ipVersion == 6 {
+			// IPv4 networks live under ::/96 in IPv6 trees
+			ip = append(make(net.IP, 12), v4...)
+			ones += 96
+		} else if v4 != nil {
+			ip = v4
+		}
+
+		records = append(records, n.record)
+		dataIndex := -2 - (len(records) - 1)
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+			if i == ones-1 {
+				nodes[current].children[bit] = dataIndex
+				break
+			}
+			next := nodes[current].children[bit]
+			if next < 0 {
+				nodes = append(nodes, node{children: [2]int{-1, -1}})
+				next = len(nodes) - 1
+				nodes[current].children[bit] = next
+			}
+			current = next
+		}
+	}
+
+	// Encode the data section, remembering where each record starts
+	var data bytes.Buffer
+	offsets := make([]int, len(records))
+	for i, record := range records {
+		offsets[i] = data.Len()
+		encodeValue(&data, record)
+	}
+
+	nodeCount := len(nodes)
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for _, child := range n.children {
+			var value int
+			switch {
+			case child == -1:
+				value = nodeCount
+			case child >= 0:
+				value = child
+			default:
+				value = nodeCount + dataSectionSeparatorSize + offsets[-child-2]
+			}
+			out.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	out.Write(make([]byte, dataSectionSeparatorSize))
+	out.Write(data.Bytes())
+	out.Write(metadataStartMarker)
+	encodeValue(&out, map[string]interface{}{
+		"node_count":                  uint64(nodeCount),
+		"record_size":                 uint64(24),
+		"ip_version":                  uint64(ipVersion),
+		"database_type":               "Test-City",
+		"binary_format_major_version": uint64(2),
+	})
+
+	return out.Bytes()
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) {
+	switch value := v.(type) {
+	case rawValue:
+		buf.Write(value)
+	case string:
+		writeControl(buf, typeString, len(value))
+		buf.WriteString(value)
+	case uint64:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], value)
+		trimmed := bytes.TrimLeft(b[:], "\x00")
+		writeControl(buf, typeUint64, len(trimmed))
+		buf.Write(trimmed)
+	case map[string]interface{}:
+		writeControl(buf, typeMap, len(value))
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encodeValue(buf, k)
+			encodeValue(buf, value[k])
+		}
+	}
+}
+
+func writeControl(buf *bytes.Buffer, dataType, size int) {
+	var ctrl byte
+	extended := dataType > 7
+	if !extended {
+		ctrl = byte(dataType << 5)
+	}
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+		buf.WriteByte(ctrl)
+		if extended {
+			buf.WriteByte(byte(dataType - 7))
+		}
+	default:
+		ctrl |= 29
+		buf.WriteByte(ctrl)
+		if extended {
+			buf.WriteByte(byte(dataType - 7))
+		}
+		buf.WriteByte(byte(size - 29))
+	}
+}
+
+func cityRecord(code, country, city string) map[string]interface{} {
+	record := map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": code,
+			"names":    map[string]interface{}{"en": country},
+		},
+	}
+	if city != "" {
+		record["city"] = map[string]interface{}{
+			"names": map[string]interface{}{"en": city},
+		}
+	}
+	return record
+}
+
+func TestReader_IPv4Database(t *testing.T) {
+	db := buildTestDB(t, 4, []testNetwork{
+		{"81.2.69.0/24", cityRecord("GB", "United Kingdom", "London")},
+		{"175.16.199.0/24", cityRecord("CN", "China", "")},
+	})
+
+	reader, err := FromBytes(db)
+	require.NoError(t, err)
+	assert.Equal(t, "Test-City", reader.DatabaseType())
+
+	t.Run("City match", func(t *testing.T) {
+		loc := reader.LookupString("81.2.69.160")
+		require.NotNil(t, loc)
+		assert.Equal(t, &Location{CountryCode: "GB", Country: "United Kingdom", City: "London"}, loc)
+	})
+
+	t.Run("Country only match", func(t *testing.T) {
+		loc := reader.LookupString("175.16.199.1")
+		require.NotNil(t, loc)
+		assert.Equal(t, "CN", loc.CountryCode)
+		assert.Empty(t, loc.City)
+		assert.Equal(t, "CN", reader.Country("175.16.199.1"))
+	})
+
+	t.Run("Unknown and invalid addresses", func(t *testing.T) {
+		assert.Nil(t, reader.LookupString("10.0.0.1"))
+		assert.Nil(t, reader.LookupString("not-an-ip"))
+		assert.Nil(t, reader.LookupString("2001:db8::1"))
+		assert.Empty(t, reader.Country("10.0.0.1"))
+	})
+}
+
+func TestReader_IPv6Database(t *testing.T) {
+	db := buildTestDB(t, 6, []testNetwork{
+		{"81.2.69.0/24", cityRecord("GB", "United Kingdom", "London")},
+		{"2001:db8::/32", cityRecord("SE", "Sweden", "Stockholm")},
+	})
+
+	reader, err := FromBytes(db)
+	require.NoError(t, err)
+
+	assert.Equal(t, "GB", reader.Country("81.2.69.1"))
+	assert.Equal(t, "SE", reader.Country("2001:db8::42"))
+	assert.Empty(t, reader.Country("2001:db9::1"))
+}
+
+func TestFromBytes_Invalid(t *testing.T) {
+	_, err := FromBytes([]byte("not a database"))
+	assert.Error(t, err)
+}
+
+func TestReader_CorruptData(t *testing.T) {
+	// A pointer to itself must fail the lookup rather than recurse forever. The
+	// record is the only one, so its country value follows the map's control
+	// byte and the encoded "country" key.
+	selfPointer := rawValue{typePointer << 5, byte(1 + 1 + len("country"))}
+	db := buildTestDB(t, 4, []testNetwork{
+		{"81.2.69.0/24", map[string]interface{}{"country": selfPointer}},
+	})
+
+	reader, err := FromBytes(db)
+	require.NoError(t, err)
+
+	_, err = reader.Lookup(net.ParseIP("81.2.69.1"))
+	assert.Error(t, err)
+	assert.Nil(t, reader.LookupString("81.2.69.1"))
+}
@@ -0,0 +1,242 @@
+// Package lifecycle starts and stops a process's long-running parts in
+// dependency order. Each part registers start and stop hooks; parts start after
+// their dependencies and stop before them, each phase bounded by a timeout and
+// logged.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultTimeout bounds each start and stop hook unless overridden with Timeout
+const DefaultTimeout = 10 * time.Second
+
+// StartFunc starts a part and returns once it is running. The context stays
+// valid until the part is stopped, so background work may keep using it.
+type StartFunc func(ctx context.Context) error
+
+// StopFunc stops a part, returning once it has finished or the context is done
+type StopFunc func(ctx context.Context) error
+
+// Option configures a registered hook
+type Option func(*hook)
+
+// DependsOn starts the hook after the named hooks, and stops it before them
+func DependsOn(names ...string) Option {
+	return func(h *hook) {
+		h.dependsOn = append(h.dependsOn, names...)
+	}
+}
+
+// Timeout bounds the hook's start and stop instead of DefaultTimeout
+func Timeout(timeout time.Duration) Option {
+	return func(h *hook) {
+		h.timeout = timeout
+	}
+}
+
+// Background returns hooks running fn in a goroutine until the part is stopped.
+// Stopping cancels fn's context and waits for it to return, so work in progress
+// can finish.
+func Background(fn func(ctx context.Context)) (StartFunc, StopFunc) {
+	done := make(chan struct{})
+	start := func(ctx context.Context) error {
+		go func() {
+			defer close(done)
+			fn(ctx)
+		}()
+		return nil
+	}
+	stop := func(ctx context.Context) error {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return start, stop
+}
+
+type hook struct {
+	name      string
+	start     StartFunc
+	stop      StopFunc
+	dependsOn []string
+	timeout   time.Duration
+	// cancel ends the context the hook was started with
+	cancel context.CancelFunc
+}
+
+// Manager runs registered hooks in dependency order
+type Manager struct {
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	hooks   []*hook
+	byName  map[string]*hook
+	started []*hook
+}
+
+// New creates an empty lifecycle manager
+func New(logger zerolog.Logger) *Manager {
+	return &Manager{
+		logger: logger.With().Str("component", "lifecycle").Logger(),
+		byName: make(map[string]*hook),
+	}
+}
+
+// Register adds a part. Either hook may be nil: parts with only a stop hook are
+// already running, such as an open database connection, and parts with only a
+// start hook stop when their context is cancelled. Hooks with no dependency
+// between them start in registration order.
+func (m *Manager) Register(name string, start StartFunc, stop StopFunc, opts ...Option) {
+	h := &hook{name: name, start: start, stop: stop, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.byName[name]; exists {
+		panic(fmt.Sprintf("lifecycle: hook %q registered twice", name))
+	}
+	m.hooks = append(m.hooks, h)
+	m.byName[name] = h
+}
+
+// Start runs the start hooks in dependency order. If one fails, the parts already
+// started are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	order, err := m.order()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, h := range order {
+		runCtx, cancel := context.WithCancel(ctx)
+		h.cancel = cancel
+
+		if h.start != nil {
+			began := time.Now()
+			m.logger.Info().Str("hook", h.name).Msg("Starting")
+			if err := runWithTimeout(runCtx, h.timeout, h.start); err != nil {
+				cancel()
+				m.logger.Error().Err(err).Str("hook", h.name).Dur("duration", time.Since(began)).Msg("Failed to start")
+				if stopErr := m.Stop(context.Background()); stopErr != nil {
+					m.logger.Error().Err(stopErr).Msg("Failed to stop after a failed start")
+				}
+				return fmt.Errorf("start %s: %w", h.name, err)
+			}
+			m.logger.Info().Str("hook", h.name).Dur("duration", time.Since(began)).Msg("Started")
+		}
+
+		m.mu.Lock()
+		m.started = append(m.started, h)
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started parts in reverse start order, so each
+// part stops before the parts it depends on. A hook that fails or times out is
+// logged and the rest still run; the errors are returned together. ctx bounds
+// the whole shutdown.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		h := started[i]
+		h.cancel()
+		if h.stop == nil {
+			continue
+		}
+
+		began := time.Now()
+		m.logger.Info().Str("hook", h.name).Msg("Stopping")
+		stopCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		err := runWithTimeout(stopCtx, h.timeout, h.stop)
+		cancel()
+		if err != nil {
+			m.logger.Error().Err(err).Str("hook", h.name).Dur("duration", time.Since(began)).Msg("Failed to stop")
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.name, err))
+			continue
+		}
+		m.logger.Info().Str("hook", h.name).Dur("duration", time.Since(began)).Msg("Stopped")
+	}
+	return errors.Join(errs...)
+}
+
+// order sorts the hooks so each comes after its dependencies, keeping
+// registration order otherwise
+func (m *Manager) order() ([]*hook, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(m.hooks))
+	order := make([]*hook, 0, len(m.hooks))
+
+	var visit func(h *hook, path []string) error
+	visit = func(h *hook, path []string) error {
+		switch state[h.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle: %v", append(path, h.name))
+		}
+		state[h.name] = visiting
+		for _, name := range h.dependsOn {
+			dependency, ok := m.byName[name]
+			if !ok {
+				return fmt.Errorf("lifecycle: %s depends on unregistered hook %s", h.name, name)
+			}
+			if err := visit(dependency, append(path, h.name)); err != nil {
+				return err
+			}
+		}
+		state[h.name] = visited
+		order = append(order, h)
+		return nil
+	}
+
+	for _, h := range m.hooks {
+		if err := visit(h, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// runWithTimeout runs fn, giving up once the timeout passes or ctx is done. fn
+// is left running when it ignores its context.
+func runWithTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	done := make(chan error, 1)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("timed out after %s", timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
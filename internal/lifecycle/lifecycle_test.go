@@ -0,0 +1,118 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder registers hooks that note when they run
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) start(name string) StartFunc {
+	return func(context.Context) error {
+		r.events = append(r.events, "start "+name)
+		return nil
+	}
+}
+
+func (r *recorder) stop(name string) StopFunc {
+	return func(context.Context) error {
+		r.events = append(r.events, "stop "+name)
+		return nil
+	}
+}
+
+func TestManager_DependencyOrder(t *testing.T) {
+	r := &recorder{}
+	m := New(zerolog.Nop())
+	m.Register("server", r.start("server"), r.stop("server"), DependsOn("cache", "database"))
+	m.Register("cache", r.start("cache"), r.stop("cache"), DependsOn("database"))
+	m.Register("database", r.start("database"), r.stop("database"))
+	m.Register("metrics", r.start("metrics"), r.stop("metrics"))
+
+	require.NoError(t, m.Start(context.Background()))
+	require.NoError(t, m.Stop(context.Background()))
+
+	assert.Equal(t, []string{
+		"start database", "start cache", "start server", "start metrics",
+		"stop metrics", "stop server", "stop cache", "stop database",
+	}, r.events)
+
+	// Stopping twice is harmless
+	require.NoError(t, m.Stop(context.Background()))
+	assert.Len(t, r.events, 8)
+}
+
+func TestManager_InvalidDependencies(t *testing.T) {
+	m := New(zerolog.Nop())
+	m.Register("a", nil, nil, DependsOn("b"))
+	m.Register("b", nil, nil, DependsOn("a"))
+	err := m.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cycle")
+
+	m = New(zerolog.Nop())
+	m.Register("a", nil, nil, DependsOn("missing"))
+	err = m.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unregistered hook missing")
+
+	assert.Panics(t, func() { m.Register("a", nil, nil) })
+}
+
+func TestManager_FailedStartStopsStartedParts(t *testing.T) {
+	r := &recorder{}
+	m := New(zerolog.Nop())
+	m.Register("database", r.start("database"), r.stop("database"))
+	m.Register("server", func(context.Context) error {
+		return errors.New("port in use")
+	}, r.stop("server"), DependsOn("database"))
+	m.Register("worker", r.start("worker"), r.stop("worker"), DependsOn("server"))
+
+	err := m.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "start server: port in use")
+	assert.Equal(t, []string{"start database", "stop database"}, r.events)
+}
+
+func TestManager_StopTimeout(t *testing.T) {
+	r := &recorder{}
+	m := New(zerolog.Nop())
+	m.Register("database", nil, r.stop("database"))
+	m.Register("stuck", nil, func(context.Context) error {
+		select {}
+	}, DependsOn("database"), Timeout(20*time.Millisecond))
+
+	require.NoError(t, m.Start(context.Background()))
+	err := m.Stop(context.Background())
+
+	// The stuck hook is reported and the database still closes
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stop stuck: timed out")
+	assert.Equal(t, []string{"stop database"}, r.events)
+}
+
+func TestBackground(t *testing.T) {
+	finished := false
+	start, stop := Background(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+	})
+
+	m := New(zerolog.Nop())
+	m.Register("worker", start, stop)
+	require.NoError(t, m.Start(context.Background()))
+	require.NoError(t, m.Stop(context.Background()))
+
+	// Stop cancels the worker and waits for it to return
+	assert.True(t, finished)
+}
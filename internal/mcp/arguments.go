@@ -0,0 +1,666 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tool arguments are produced by language models, which do not always respect the
+// JSON types in the tool schema: IDs arrive as "42" and flags as "true". The request
+// types below decode these fields leniently so such calls succeed instead of failing
+// with a type error.
+
+// UnmarshalJSON accepts a string-encoded limit, offset and semantic search flag, a
+// metadata query given either as a string or as a JSON object, and tags given as
+// an array or a comma-separated string
+func (r *SearchMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias SearchMemoriesRequest
+	aux := struct {
+		*alias
+		Limit             json.RawMessage `json:"limit"`
+		Offset            json.RawMessage `json:"offset"`
+		UseSemanticSearch json.RawMessage `json:"useSemanticSearch"`
+		MetadataQuery     json.RawMessage `json:"metadataQuery"`
+		Tags              json.RawMessage `json:"tags"`
+		IncludeRelated    json.RawMessage `json:"includeRelated"`
+		RelatedDepth      json.RawMessage `json:"relatedDepth"`
+		WithinIDs         json.RawMessage `json:"withinIds"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	limit, err := parseLenientInt(aux.Limit, "limit")
+	if err != nil {
+		return err
+	}
+	offset, err := parseLenientInt(aux.Offset, "offset")
+	if err != nil {
+		return err
+	}
+	semantic, err := parseLenientBool(aux.UseSemanticSearch, "useSemanticSearch")
+	if err != nil {
+		return err
+	}
+
+	metadataQuery, err := parseMetadataQueryArgument(aux.MetadataQuery)
+	if err != nil {
+		return err
+	}
+
+	tags, err := parseStringListArgument(aux.Tags, "tags")
+	if err != nil {
+		return err
+	}
+
+	includeRelated, err := parseLenientBool(aux.IncludeRelated, "includeRelated")
+	if err != nil {
+		return err
+	}
+	relatedDepth, err := parseLenientInt(aux.RelatedDepth, "relatedDepth")
+	if err != nil {
+		return err
+	}
+	withinIDs, err := parseIDListArgument(aux.WithinIDs, "withinIds")
+	if err != nil {
+		return err
+	}
+
+	r.Limit = limit
+	r.Offset = offset
+	r.UseSemanticSearch = semantic
+	r.MetadataQuery = metadataQuery
+	r.Tags = tags
+	r.IncludeRelated = includeRelated
+	r.RelatedDepth = relatedDepth
+	r.WithinIDs = withinIDs
+	r.TagMatch = strings.ToLower(strings.TrimSpace(r.TagMatch))
+	r.SearchMode = strings.ToLower(strings.TrimSpace(r.SearchMode))
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID
+func (r *UpdateMemoryRequest) UnmarshalJSON(data []byte) error {
+	type alias UpdateMemoryRequest
+	aux := struct {
+		*alias
+		ID json.RawMessage `json:"id"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID and a confirmation token in any
+// scalar form
+func (r *DeleteMemoryRequest) UnmarshalJSON(data []byte) error {
+	type alias DeleteMemoryRequest
+	aux := struct {
+		*alias
+		ID      json.RawMessage `json:"id"`
+		Confirm json.RawMessage `json:"confirm"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+	confirm, err := lenientScalar(aux.Confirm)
+	if err != nil {
+		return fmt.Errorf("confirm: %w", err)
+	}
+
+	r.ID = id
+	r.Confirm = confirm
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID
+func (r *GetMemoryRequest) UnmarshalJSON(data []byte) error {
+	type alias GetMemoryRequest
+	aux := struct {
+		*alias
+		ID json.RawMessage `json:"id"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID
+func (r *MemoryHistoryRequest) UnmarshalJSON(data []byte) error {
+	type alias MemoryHistoryRequest
+	aux := struct {
+		*alias
+		ID json.RawMessage `json:"id"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID
+func (r *FeedbackMemoryRequest) UnmarshalJSON(data []byte) error {
+	type alias FeedbackMemoryRequest
+	aux := struct {
+		*alias
+		ID json.RawMessage `json:"id"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// UnmarshalJSON accepts string-encoded memory IDs
+func (r *LinkMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias LinkMemoriesRequest
+	aux := struct {
+		*alias
+		SourceID json.RawMessage `json:"sourceId"`
+		TargetID json.RawMessage `json:"targetId"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	sourceID, err := parseLenientUint(aux.SourceID, "sourceId")
+	if err != nil {
+		return err
+	}
+	targetID, err := parseLenientUint(aux.TargetID, "targetId")
+	if err != nil {
+		return err
+	}
+
+	r.SourceID = sourceID
+	r.TargetID = targetID
+	r.Relation = strings.ToLower(strings.TrimSpace(r.Relation))
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded memory ID and depth, and relations given
+// as an array or a comma-separated string
+func (r *GetRelatedMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias GetRelatedMemoriesRequest
+	aux := struct {
+		*alias
+		ID        json.RawMessage `json:"id"`
+		Depth     json.RawMessage `json:"depth"`
+		Relations json.RawMessage `json:"relations"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	id, err := parseLenientUint(aux.ID, "id")
+	if err != nil {
+		return err
+	}
+	depth, err := parseLenientInt(aux.Depth, "depth")
+	if err != nil {
+		return err
+	}
+	relations, err := parseStringListArgument(aux.Relations, "relations")
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	r.Depth = depth
+	r.Relations = relations
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded confidence and dry run flag
+func (r *ProcessContentRequest) UnmarshalJSON(data []byte) error {
+	type alias ProcessContentRequest
+	aux := struct {
+		*alias
+		MinConfidence json.RawMessage `json:"minConfidence"`
+		DryRun        json.RawMessage `json:"dryRun"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	minConfidence, err := parseLenientFloat(aux.MinConfidence, "minConfidence")
+	if err != nil {
+		return err
+	}
+	dryRun, err := parseLenientBool(aux.DryRun, "dryRun")
+	if err != nil {
+		return err
+	}
+
+	r.MinConfidence = minConfidence
+	r.DryRun = dryRun
+	return nil
+}
+
+// UnmarshalJSON accepts anonymize as a boolean, a string or a list of PII kinds,
+// and anonymize_terms as an array or a comma-separated string
+func (r *ExportMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias ExportMemoriesRequest
+	aux := struct {
+		*alias
+		Anonymize      json.RawMessage `json:"anonymize"`
+		AnonymizeTerms json.RawMessage `json:"anonymize_terms"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var anonymize string
+	if trimmed := bytes.TrimSpace(aux.Anonymize); len(trimmed) > 0 && trimmed[0] == '[' {
+		kinds, err := parseStringListArgument(trimmed, "anonymize")
+		if err != nil {
+			return err
+		}
+		anonymize = strings.Join(kinds, ",")
+	} else {
+		value, err := lenientScalar(aux.Anonymize)
+		if err != nil {
+			return fmt.Errorf("anonymize: %w", err)
+		}
+		anonymize = value
+	}
+	terms, err := parseStringListArgument(aux.AnonymizeTerms, "anonymize_terms")
+	if err != nil {
+		return err
+	}
+
+	r.Anonymize = anonymize
+	r.AnonymizeTerms = terms
+	return nil
+}
+
+// UnmarshalJSON accepts a duration given as a string or as a number of minutes
+func (r *IncognitoRequest) UnmarshalJSON(data []byte) error {
+	type alias IncognitoRequest
+	aux := struct {
+		*alias
+		Duration json.RawMessage `json:"duration"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	duration, err := lenientScalar(aux.Duration)
+	if err != nil {
+		return fmt.Errorf("duration: %w", err)
+	}
+
+	r.Action = strings.ToLower(strings.TrimSpace(r.Action))
+	r.Duration = duration
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded limit
+func (r *RecallRecentRequest) UnmarshalJSON(data []byte) error {
+	type alias RecallRecentRequest
+	aux := struct {
+		*alias
+		Limit json.RawMessage `json:"limit"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	limit, err := parseLenientInt(aux.Limit, "limit")
+	if err != nil {
+		return err
+	}
+
+	r.Limit = limit
+	return nil
+}
+
+// UnmarshalJSON accepts memory IDs given as strings or a comma-separated string,
+// and a string-encoded cluster limit and dry run flag
+func (r *ConsolidateMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias ConsolidateMemoriesRequest
+	aux := struct {
+		*alias
+		MemoryIDs   json.RawMessage `json:"memoryIds"`
+		MaxClusters json.RawMessage `json:"maxClusters"`
+		DryRun      json.RawMessage `json:"dryRun"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	memoryIDs, err := parseIDListArgument(aux.MemoryIDs, "memoryIds")
+	if err != nil {
+		return err
+	}
+	maxClusters, err := parseLenientInt(aux.MaxClusters, "maxClusters")
+	if err != nil {
+		return err
+	}
+	dryRun, err := parseLenientBool(aux.DryRun, "dryRun")
+	if err != nil {
+		return err
+	}
+
+	r.MemoryIDs = memoryIDs
+	r.MaxClusters = maxClusters
+	r.DryRun = dryRun
+	return nil
+}
+
+// UnmarshalJSON accepts string-encoded flags, tags given as an array or a
+// comma-separated string, and a confirmation token in any scalar form
+func (r *DeleteMemoriesRequest) UnmarshalJSON(data []byte) error {
+	type alias DeleteMemoriesRequest
+	aux := struct {
+		*alias
+		Tags            json.RawMessage `json:"tags"`
+		IncludeCritical json.RawMessage `json:"includeCritical"`
+		DryRun          json.RawMessage `json:"dryRun"`
+		Confirm         json.RawMessage `json:"confirm"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	tags, err := parseStringListArgument(aux.Tags, "tags")
+	if err != nil {
+		return err
+	}
+	includeCritical, err := parseLenientBool(aux.IncludeCritical, "includeCritical")
+	if err != nil {
+		return err
+	}
+	dryRun, err := parseLenientBool(aux.DryRun, "dryRun")
+	if err != nil {
+		return err
+	}
+	confirm, err := lenientScalar(aux.Confirm)
+	if err != nil {
+		return fmt.Errorf("confirm: %w", err)
+	}
+
+	r.Tags = tags
+	r.IncludeCritical = includeCritical
+	r.DryRun = dryRun
+	r.Confirm = confirm
+	return nil
+}
+
+// UnmarshalJSON accepts a string-encoded atomic flag. Each update decodes its own
+// memory ID leniently.
+func (r *UpdateMemoriesBulkRequest) UnmarshalJSON(data []byte) error {
+	type alias UpdateMemoriesBulkRequest
+	aux := struct {
+		*alias
+		Atomic json.RawMessage `json:"atomic"`
+	}{alias: (*alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	atomic, err := parseLenientBool(aux.Atomic, "atomic")
+	if err != nil {
+		return err
+	}
+
+	r.Atomic = atomic
+	return nil
+}
+
+// lenientScalar returns the textual value of a JSON number or string, with strings
+// unquoted and trimmed. Missing and null values yield "".
+func lenientScalar(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(s), nil
+	}
+
+	return string(raw), nil
+}
+
+// parseLenientInt decodes an integer given as a JSON number or numeric string.
+// Whole-valued floats such as 10.0 are accepted.
+func parseLenientInt(raw json.RawMessage, field string) (int, error) {
+	value, err := lenientScalar(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 0); err == nil {
+		return int(n), nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, fmt.Errorf("%s must be an integer, got %s", field, value)
+	}
+	return int(f), nil
+}
+
+// parseLenientUint decodes a non-negative ID given as a JSON number or numeric string
+func parseLenientUint(raw json.RawMessage, field string) (uint, error) {
+	n, err := parseLenientInt(raw, field)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %d", field, n)
+	}
+	return uint(n), nil
+}
+
+// parseLenientFloat decodes a number given as a JSON number or numeric string
+func parseLenientFloat(raw json.RawMessage, field string) (float64, error) {
+	value, err := lenientScalar(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%s must be a number, got %s", field, value)
+	}
+	return f, nil
+}
+
+// parseLenientBool decodes a boolean given as true/false, "true"/"false", or 1/0
+// in either numeric or string form
+func parseLenientBool(raw json.RawMessage, field string) (bool, error) {
+	value, err := lenientScalar(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", field, err)
+	}
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(strings.ToLower(value))
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %s", field, value)
+	}
+	return b, nil
+}
+
+// parseMetadataQueryArgument returns a metadata query passed as a string as-is and
+// one passed as a JSON object as its JSON text
+func parseMetadataQueryArgument(raw json.RawMessage) (string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return "", nil
+	}
+
+	switch trimmed[0] {
+	case '{':
+		return string(trimmed), nil
+	case '"':
+		var query string
+		if err := json.Unmarshal(trimmed, &query); err != nil {
+			return "", fmt.Errorf("metadataQuery: %w", err)
+		}
+		return query, nil
+	default:
+		return "", fmt.Errorf("metadataQuery must be a string or a JSON object")
+	}
+}
+
+// parseIDListArgument decodes memory IDs given as a JSON array of numbers or
+// numeric strings, or as a single comma-separated string. An empty array decodes
+// to an empty, non-nil list.
+func parseIDListArgument(raw json.RawMessage, field string) ([]uint, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+
+	var items []json.RawMessage
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+	case '"':
+		var list string
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		for _, item := range strings.Split(list, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, json.RawMessage(item))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s must be an array of IDs or a comma-separated string", field)
+	}
+
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		id, err := parseLenientUint(item, field)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseStringListArgument decodes a list such as tags given as a JSON array of
+// strings or as a single comma-separated string
+func parseStringListArgument(raw json.RawMessage, field string) ([]string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var items []string
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return items, nil
+	case '"':
+		var list string
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		var items []string
+		for _, item := range strings.Split(list, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%s must be an array of strings or a comma-separated string", field)
+	}
+}
+
+// ParseIncognitoDuration parses an incognito duration given as a Go duration such
+// as "30m" or as a bare number of minutes. An empty value yields 0, which means the
+// default duration.
+func ParseIncognitoDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if minutes, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(minutes * float64(time.Minute)), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("duration must be a number of minutes or a duration such as 30m or 2h, got %s", value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateMemoryRequest_LenientID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    uint
+		wantErr bool
+	}{
+		{"Number", `{"id": 42, "content": "x"}`, 42, false},
+		{"String", `{"id": "42", "content": "x"}`, 42, false},
+		{"Padded string", `{"id": " 42 ", "content": "x"}`, 42, false},
+		{"Whole float", `{"id": 42.0, "content": "x"}`, 42, false},
+		{"Missing", `{"content": "x"}`, 0, false},
+		{"Null", `{"id": null, "content": "x"}`, 0, false},
+		{"Fractional", `{"id": 4.5}`, 0, true},
+		{"Negative", `{"id": "-1"}`, 0, true},
+		{"Not a number", `{"id": "abc"}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdateMemoryRequest
+			err := json.Unmarshal([]byte(tt.input), &req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, req.ID)
+		})
+	}
+}
+
+func TestUpdateMemoryRequest_KeepsOtherFields(t *testing.T) {
+	var req UpdateMemoryRequest
+	input := `{"id": "7", "content": "new", "tags": ["a"], "priority": "high", "metadata": {"k": "v"}}`
+	require.NoError(t, json.Unmarshal([]byte(input), &req))
+
+	assert.Equal(t, uint(7), req.ID)
+	assert.Equal(t, "new", req.Content)
+	assert.Equal(t, []string{"a"}, req.Tags)
+	assert.Equal(t, "high", req.Priority)
+	assert.Equal(t, "v", req.Metadata["k"])
+}
+
+func TestDeleteMemoryRequest_LenientID(t *testing.T) {
+	var req DeleteMemoryRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "15"}`), &req))
+	assert.Equal(t, uint(15), req.ID)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 16}`), &req))
+	assert.Equal(t, uint(16), req.ID)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"id": true}`), &req))
+}
+
+func TestSearchMemoriesRequest_LenientFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantLimit    int
+		wantSemantic bool
+		wantErr      bool
+	}{
+		{"Native types", `{"query": "q", "limit": 5, "useSemanticSearch": true}`, 5, true, false},
+		{"Strings", `{"query": "q", "limit": "5", "useSemanticSearch": "true"}`, 5, true, false},
+		{"String false", `{"query": "q", "useSemanticSearch": "false"}`, 0, false, false},
+		{"Numeric flag", `{"query": "q", "useSemanticSearch": 1}`, 0, true, false},
+		{"Upper case flag", `{"query": "q", "useSemanticSearch": "TRUE"}`, 0, true, false},
+		{"Empty string", `{"query": "q", "limit": "", "useSemanticSearch": ""}`, 0, false, false},
+		{"Invalid flag", `{"query": "q", "useSemanticSearch": "maybe"}`, 0, false, true},
+		{"Invalid limit", `{"query": "q", "limit": "ten"}`, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req SearchMemoriesRequest
+			err := json.Unmarshal([]byte(tt.input), &req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "q", req.Query)
+			assert.Equal(t, tt.wantLimit, req.Limit)
+			assert.Equal(t, tt.wantSemantic, req.UseSemanticSearch)
+		})
+	}
+}
+
+func TestSearchMemoriesRequest_RoundTrip(t *testing.T) {
+	original := SearchMemoriesRequest{Query: "q", Category: "personal", Limit: 3, UseSemanticSearch: true}
+	data, err := original.ToJSON()
+	require.NoError(t, err)
+
+	var decoded SearchMemoriesRequest
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, decoded)
+}
+
+func TestSearchMemoriesRequest_MetadataQuery(t *testing.T) {
+	var req SearchMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "metadataQuery": {"source": "slack"}}`), &req))
+	assert.Equal(t, `{"source": "slack"}`, req.MetadataQuery)
+
+	req = SearchMemoriesRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "metadataQuery": "$.scores[0] > 0.9"}`), &req))
+	assert.Equal(t, "$.scores[0] > 0.9", req.MetadataQuery)
+
+	req = SearchMemoriesRequest{}
+	assert.Error(t, json.Unmarshal([]byte(`{"query": "x", "metadataQuery": 5}`), &req))
+}
+
+func TestSearchMemoriesRequest_Pagination(t *testing.T) {
+	var req SearchMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "offset": "20", "cursor": "eyJvIjoyMH0"}`), &req))
+	assert.Equal(t, 20, req.Offset)
+	assert.Equal(t, "eyJvIjoyMH0", req.Cursor)
+}
+
+func TestSearchMemoriesRequest_Tags(t *testing.T) {
+	var req SearchMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "tags": ["work", "urgent"], "tagMatch": " ALL "}`), &req))
+	assert.Equal(t, []string{"work", "urgent"}, req.Tags)
+	assert.Equal(t, "all", req.TagMatch)
+
+	req = SearchMemoriesRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "tags": "work, urgent,"}`), &req))
+	assert.Equal(t, []string{"work", "urgent"}, req.Tags)
+
+	req = SearchMemoriesRequest{}
+	assert.Error(t, json.Unmarshal([]byte(`{"query": "x", "tags": 5}`), &req))
+}
+
+func TestLinkRequests_Lenient(t *testing.T) {
+	var link LinkMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"sourceId": "7", "targetId": 3, "relation": " Supersedes "}`), &link))
+	assert.Equal(t, LinkMemoriesRequest{SourceID: 7, TargetID: 3, Relation: "supersedes"}, link)
+
+	var related GetRelatedMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "7", "depth": "2", "relations": "supersedes, part_of_project"}`), &related))
+	assert.Equal(t, uint(7), related.ID)
+	assert.Equal(t, 2, related.Depth)
+	assert.Equal(t, []string{"supersedes", "part_of_project"}, related.Relations)
+
+	var search SearchMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"query": "x", "includeRelated": "true", "relatedDepth": "2"}`), &search))
+	assert.True(t, search.IncludeRelated)
+	assert.Equal(t, 2, search.RelatedDepth)
+}
+
+func TestProcessContentRequest_Lenient(t *testing.T) {
+	var req ProcessContentRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"content": "I prefer tea", "minConfidence": "0.8", "dryRun": "true"}`), &req))
+	assert.Equal(t, ProcessContentRequest{Content: "I prefer tea", MinConfidence: 0.8, DryRun: true}, req)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"content": "x", "minConfidence": "high"}`), &req))
+}
+
+func TestIncognitoRequest_Duration(t *testing.T) {
+	var req IncognitoRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"action": " Start ", "duration": 45}`), &req))
+	assert.Equal(t, IncognitoActionStart, req.Action)
+	assert.Equal(t, "45", req.Duration)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"45", 45 * time.Minute},
+		{"1.5", 90 * time.Second},
+		{"2h", 2 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		duration, err := ParseIncognitoDuration(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, duration, tt.value)
+	}
+
+	_, err := ParseIncognitoDuration("a while")
+	assert.Error(t, err)
+}
+
+func TestExportMemoriesRequest_Anonymize(t *testing.T) {
+	var req ExportMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"anonymize": true, "anonymize_terms": "Falcon, Osprey"}`), &req))
+	assert.Equal(t, ExportMemoriesRequest{Anonymize: "true", AnonymizeTerms: []string{"Falcon", "Osprey"}}, req)
+
+	req = ExportMemoriesRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"anonymize": ["emails", "names"], "include_embeddings": false}`), &req))
+	assert.Equal(t, "emails,names", req.Anonymize)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"anonymize_terms": 5}`), &req))
+}
+
+func TestRecallRecentRequest_Lenient(t *testing.T) {
+	var req RecallRecentRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"by": "accessed", "limit": "5"}`), &req))
+	assert.Equal(t, RecallRecentRequest{By: "accessed", Limit: 5}, req)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"limit": "five"}`), &req))
+}
+
+func TestConsolidateMemoriesRequest_Lenient(t *testing.T) {
+	var req ConsolidateMemoriesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"memoryIds": ["3", 4], "maxClusters": "2", "dryRun": "true"}`), &req))
+	assert.Equal(t, ConsolidateMemoriesRequest{MemoryIDs: []uint{3, 4}, MaxClusters: 2, DryRun: true}, req)
+
+	req = ConsolidateMemoriesRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"memoryIds": "3, 4"}`), &req))
+	assert.Equal(t, []uint{3, 4}, req.MemoryIDs)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"dryRun": "maybe"}`), &req))
+}
+
+func TestDeleteMemoriesRequest_Lenient(t *testing.T) {
+	var req DeleteMemoriesRequest
+	input := `{"category": "work", "tags": "old, stale", "includeCritical": "false", "dryRun": 1, "confirm": 1234}`
+	require.NoError(t, json.Unmarshal([]byte(input), &req))
+	assert.Equal(t, DeleteMemoriesRequest{
+		Category: "work",
+		Tags:     []string{"old", "stale"},
+		DryRun:   true,
+		Confirm:  "1234",
+	}, req)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"includeCritical": "yes please"}`), &req))
+}
+
+func TestUpdateMemoriesBulkRequest_Lenient(t *testing.T) {
+	var req UpdateMemoriesBulkRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"updates": [{"id": "7", "content": "new"}], "atomic": "true"}`), &req))
+	assert.True(t, req.Atomic)
+	require.Len(t, req.Updates, 1)
+	assert.Equal(t, uint(7), req.Updates[0].ID)
+	assert.Equal(t, "new", req.Updates[0].Content)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"updates": [], "atomic": "sometimes"}`), &req))
+}
@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"mime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// attachmentURIPrefix starts the resource URI of every attachment
+const attachmentURIPrefix = "memory://attachments/"
+
+// AttachmentResourceTemplate is the resource template every attachment is read
+// through, at the URI given with it in attachment listings
+func AttachmentResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(attachmentURIPrefix+"{id}", "Memory Attachment",
+		mcp.WithTemplateDescription("A file or image attached to a memory, as text for text files and base64 otherwise"),
+	)
+}
+
+// IsAttachmentURI reports whether a resource URI names an attachment
+func IsAttachmentURI(uri string) bool {
+	return strings.HasPrefix(uri, attachmentURIPrefix)
+}
+
+// ReadAttachment returns the contents of the attachment a resource URI names:
+// text files as text and anything else as a base64 blob
+func ReadAttachment(ctx context.Context, memoryService *services.MemoryService, uri string) ([]mcp.ResourceContents, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(uri, attachmentURIPrefix), 10, 32)
+	if err != nil || !IsAttachmentURI(uri) {
+		return nil, utils.WrapNotFoundError("attachment", uri)
+	}
+	attachment, err := memoryService.GetAttachment(ctx, uint(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if isTextType(attachment.ContentType) && utf8.Valid(attachment.Data) {
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      uri,
+				MIMEType: attachment.ContentType,
+				Text:     string(attachment.Data),
+			},
+		}, nil
+	}
+	return []mcp.ResourceContents{
+		mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: attachment.ContentType,
+			Blob:     base64.StdEncoding.EncodeToString(attachment.Data),
+		},
+	}, nil
+}
+
+// isTextType reports whether a content type is text a client can show as-is
+func isTextType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", mediaType == "application/xml",
+		strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
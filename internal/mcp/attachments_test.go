@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestReadAttachment(t *testing.T) {
+	ctx := context.Background()
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t, &models.Attachment{}), nil, zerolog.Nop(), nil)
+	memory, err := memoryService.Store(ctx, services.StoreRequest{Content: "Trip itinerary", Category: models.CategoryPersonal, Type: models.TypeContext})
+	require.NoError(t, err)
+
+	notes, err := memoryService.AddAttachment(ctx, memory.ID, services.AttachmentRequest{Data: []byte("Fly out Monday"), ContentType: "text/plain"})
+	require.NoError(t, err)
+	assert.Equal(t, "memory://attachments/"+strconv.FormatUint(uint64(notes.ID), 10), notes.URI)
+	contents, err := ReadAttachment(ctx, memoryService, notes.URI)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	text := contents[0].(mcpgo.TextResourceContents)
+	assert.Equal(t, "Fly out Monday", text.Text)
+	assert.Equal(t, "text/plain", text.MIMEType)
+
+	ticket := []byte{0x25, 0x50, 0x44, 0x46, 0x2d, 0x00, 0xff}
+	pdf, err := memoryService.AddAttachment(ctx, memory.ID, services.AttachmentRequest{Data: ticket, ContentType: "application/pdf"})
+	require.NoError(t, err)
+	contents, err = ReadAttachment(ctx, memoryService, pdf.URI)
+	require.NoError(t, err)
+	blob := contents[0].(mcpgo.BlobResourceContents)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(ticket), blob.Blob)
+	assert.Equal(t, "application/pdf", blob.MIMEType)
+
+	_, err = ReadAttachment(ctx, memoryService, "memory://attachments/999")
+	assert.True(t, utils.IsNotFoundError(err))
+	_, err = ReadAttachment(ctx, memoryService, "memory://attachments/notes")
+	assert.True(t, utils.IsNotFoundError(err))
+}
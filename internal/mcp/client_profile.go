@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// ClientProfile is the response shaping chosen for a connected client; see
+// config.ClientProfile
+type ClientProfile struct {
+	config.ClientProfile
+	// ClientName and ClientVersion are what the client sent when initializing
+	ClientName    string
+	ClientVersion string
+}
+
+// MatchClientProfile returns the first profile matching the client's name and
+// version, or nil when none does
+func MatchClientProfile(profiles []config.ClientProfile, name, version string) *ClientProfile {
+	for _, profile := range profiles {
+		if !matchClientName(profile.Name, name) {
+			continue
+		}
+		if profile.MinVersion != "" && compareVersions(version, profile.MinVersion) < 0 {
+			continue
+		}
+		if profile.MaxVersion != "" && compareVersions(version, profile.MaxVersion) >= 0 {
+			continue
+		}
+		return &ClientProfile{ClientProfile: profile, ClientName: name, ClientVersion: version}
+	}
+	return nil
+}
+
+// matchClientName matches a client name against a profile name, ignoring case;
+// a trailing * matches a prefix
+func matchClientName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// compareVersions compares dotted version numbers numerically, ignoring a leading
+// v and anything after a - or +. Missing parts count as zero.
+func compareVersions(a, b string) int {
+	parse := func(version string) []int {
+		version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+		if i := strings.IndexAny(version, "-+"); i >= 0 {
+			version = version[:i]
+		}
+		var parts []int
+		for _, part := range strings.Split(version, ".") {
+			n, _ := strconv.Atoi(part)
+			parts = append(parts, n)
+		}
+		return parts
+	}
+	pa, pb := parse(a), parse(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+type clientProfileKey struct{}
+
+// WithClientProfile returns a context carrying the profile of the client a
+// request came from
+func WithClientProfile(ctx context.Context, profile *ClientProfile) context.Context {
+	if profile == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientProfileKey{}, profile)
+}
+
+// clientProfileFrom returns the client profile carried by the context, or nil
+func clientProfileFrom(ctx context.Context) *ClientProfile {
+	profile, _ := ctx.Value(clientProfileKey{}).(*ClientProfile)
+	return profile
+}
+
+// limit applies the profile's default and maximum to a requested number of
+// results, returning fallback when neither the client nor the profile sets one
+func (p *ClientProfile) limit(requested, fallback int) int {
+	if requested <= 0 {
+		requested = fallback
+		if p != nil && p.DefaultLimit > 0 {
+			requested = p.DefaultLimit
+		}
+	}
+	if p != nil && p.MaxLimit > 0 && requested > p.MaxLimit {
+		requested = p.MaxLimit
+	}
+	return requested
+}
+
+// shapeMemories compacts returned memories for clients with a compact profile.
+// The memories are copied, not modified.
+func (p *ClientProfile) shapeMemories(memories []*models.Memory) []*models.Memory {
+	if p == nil || !p.Compact {
+		return memories
+	}
+	shaped := make([]*models.Memory, len(memories))
+	for i, memory := range memories {
+		compact := *memory
+		compact.Metadata = nil
+		if p.MaxContentLength > 0 {
+			compact.Content = truncateRunes(compact.Content, p.MaxContentLength)
+		}
+		shaped[i] = &compact
+	}
+	return shaped
+}
+
+// truncateRunes shortens text to at most n characters, marking the cut with an
+// ellipsis
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	if n <= 1 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// toolHints are the behaviour hints listed for clients whose profile asks for
+// annotations
+var toolHints = map[string]mcp.ToolAnnotation{
+	"store_memory":         {Title: "Store memory", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+	"store_memories_bulk":  {Title: "Store memories", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+	"update_memory":        {Title: "Update memory", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(true), IdempotentHint: mcp.ToBoolPtr(true)},
+	"update_memories_bulk": {Title: "Update memories", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(true), IdempotentHint: mcp.ToBoolPtr(true)},
+	"get_memory":           {Title: "Get memory", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"export_memories":      {Title: "Export memories", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"import_memories":      {Title: "Import memories", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(false)},
+	"search_memories":      {Title: "Search memories", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"delete_memory":        {Title: "Delete memory", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(true), IdempotentHint: mcp.ToBoolPtr(true)},
+	"incognito":            {Title: "Incognito mode", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+	"start_session":        {Title: "Start session", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(false)},
+	"end_session":          {Title: "End session", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+	"memory_history":       {Title: "Memory history", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"append_context":       {Title: "Append context", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(false)},
+	"feedback_memory":      {Title: "Memory feedback", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(false)},
+	"link_memories":        {Title: "Link memories", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+	"get_related_memories": {Title: "Related memories", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"recall_recent":        {Title: "Recall recent memories", ReadOnlyHint: mcp.ToBoolPtr(true)},
+	"process_content":      {Title: "Process content", ReadOnlyHint: mcp.ToBoolPtr(false), DestructiveHint: mcp.ToBoolPtr(false), IdempotentHint: mcp.ToBoolPtr(true)},
+}
+
+// ShapeTools adapts a tool list to the client: minimal schemas drop the
+// descriptions of optional parameters, and annotations add behaviour hints.
+// Tools are copied, so the registered definitions are left alone.
+func (p *ClientProfile) ShapeTools(tools []mcp.Tool) []mcp.Tool {
+	if p == nil || (p.Schemas != "minimal" && !p.Annotations) {
+		return tools
+	}
+	shaped := make([]mcp.Tool, len(tools))
+	for i, tool := range tools {
+		if p.Schemas == "minimal" {
+			tool.InputSchema.Properties = minimalProperties(tool.InputSchema.Properties, tool.InputSchema.Required)
+		}
+		if p.Annotations {
+			if hints, ok := toolHints[tool.Name]; ok {
+				tool.Annotations = hints
+			}
+		}
+		shaped[i] = tool
+	}
+	return shaped
+}
+
+// minimalProperties copies a schema's properties without the descriptions of
+// those not required
+func minimalProperties(properties map[string]interface{}, required []string) map[string]interface{} {
+	minimal := make(map[string]interface{}, len(properties))
+	for name, property := range properties {
+		schema, ok := property.(map[string]interface{})
+		if !ok || slices.Contains(required, name) {
+			minimal[name] = property
+			continue
+		}
+		trimmed := make(map[string]interface{}, len(schema))
+		for key, value := range schema {
+			if key != "description" {
+				trimmed[key] = value
+			}
+		}
+		minimal[name] = trimmed
+	}
+	return minimal
+}
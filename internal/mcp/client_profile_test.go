@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestMatchClientProfile(t *testing.T) {
+	profiles := []config.ClientProfile{
+		{Name: "tiny-*", MinVersion: "1.2", MaxVersion: "2.0", MaxLimit: 5},
+		{Name: "Claude-AI", Annotations: true},
+	}
+
+	tests := []struct {
+		name    string
+		client  string
+		version string
+		want    string
+	}{
+		{"PrefixInRange", "tiny-client", "1.5.3", "tiny-*"},
+		{"MinVersionInclusive", "tiny-client", "v1.2.0", "tiny-*"},
+		{"BelowMinVersion", "tiny-client", "1.1.9", ""},
+		{"MaxVersionExclusive", "tiny-client", "2.0", ""},
+		{"PreReleaseSuffix", "tiny-client", "1.10-beta", "tiny-*"},
+		{"CaseInsensitive", "claude-ai", "0.1", "Claude-AI"},
+		{"NoMatch", "other", "1.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := MatchClientProfile(profiles, tt.client, tt.version)
+			if tt.want == "" {
+				assert.Nil(t, profile)
+				return
+			}
+			require.NotNil(t, profile)
+			assert.Equal(t, tt.want, profile.Name)
+			assert.Equal(t, tt.client, profile.ClientName)
+			assert.Equal(t, tt.version, profile.ClientVersion)
+		})
+	}
+}
+
+func TestClientProfile_Limit(t *testing.T) {
+	var none *ClientProfile
+	assert.Equal(t, 10, none.limit(0, 10))
+	assert.Equal(t, 50, none.limit(50, 10))
+
+	profile := &ClientProfile{ClientProfile: config.ClientProfile{DefaultLimit: 3, MaxLimit: 8}}
+	assert.Equal(t, 3, profile.limit(0, 10))
+	assert.Equal(t, 5, profile.limit(5, 10))
+	assert.Equal(t, 8, profile.limit(50, 10))
+}
+
+func TestClientProfile_ShapeMemories(t *testing.T) {
+	memories := []*models.Memory{{ID: 1, Content: "héllo wörld", Metadata: []byte(`{"a":1}`)}}
+
+	var none *ClientProfile
+	assert.Same(t, memories[0], none.shapeMemories(memories)[0])
+
+	profile := &ClientProfile{ClientProfile: config.ClientProfile{Compact: true, MaxContentLength: 5}}
+	shaped := profile.shapeMemories(memories)
+	require.Len(t, shaped, 1)
+	assert.Equal(t, "héll…", shaped[0].Content)
+	assert.Nil(t, shaped[0].Metadata)
+	assert.Equal(t, "héllo wörld", memories[0].Content, "original memory should be untouched")
+	assert.NotNil(t, memories[0].Metadata)
+}
+
+func TestClientProfile_ShapeTools(t *testing.T) {
+	tools := []mcp.Tool{
+		mcp.NewTool("search_memories",
+			mcp.WithDescription("Search memories"),
+			mcp.WithString("query", mcp.Required(), mcp.Description("What to search for")),
+			mcp.WithNumber("limit", mcp.Description("Most results to return")),
+		),
+	}
+
+	profile := &ClientProfile{ClientProfile: config.ClientProfile{Schemas: "minimal", Annotations: true}}
+	shaped := profile.ShapeTools(tools)
+	require.Len(t, shaped, 1)
+
+	properties := shaped[0].InputSchema.Properties
+	assert.Equal(t, "What to search for", properties["query"].(map[string]interface{})["description"])
+	assert.NotContains(t, properties["limit"], "description")
+	assert.Equal(t, "number", properties["limit"].(map[string]interface{})["type"])
+	require.NotNil(t, shaped[0].Annotations.ReadOnlyHint)
+	assert.True(t, *shaped[0].Annotations.ReadOnlyHint)
+
+	assert.Contains(t, tools[0].InputSchema.Properties["limit"], "description", "registered tool should be untouched")
+}
+
+func TestClientProfile_Context(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, clientProfileFrom(ctx))
+	assert.Equal(t, ctx, WithClientProfile(ctx, nil))
+
+	profile := &ClientProfile{ClientProfile: config.ClientProfile{Name: "x"}}
+	assert.Same(t, profile, clientProfileFrom(WithClientProfile(ctx, profile)))
+}
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// ToRPCError maps a tool failure to a JSON-RPC error. The memory service's domain
+// errors get their own codes and data; everything else goes through
+// utils.ToMCPError.
+func ToRPCError(err error) *utils.MCPError {
+	var limitErr *services.MemoryLimitError
+	var blockedErr *services.ContentBlockedError
+	var confirmErr *services.ConfirmationRequiredError
+	var bulkConfirmErr *services.BulkDeleteConfirmationError
+	var residencyErr *services.ResidencyError
+	var incognitoErr *services.IncognitoError
+	var budgetErr *services.LLMBudgetError
+	var duplicateErr *services.DuplicateMemoryError
+
+	switch {
+	case errors.As(err, &limitErr):
+		return utils.NewMCPError(utils.MCPCodeQuotaExceeded, "quota_exceeded", err.Error(), map[string]interface{}{
+			"limit": limitErr.Limit,
+		})
+	case errors.Is(err, services.ErrMemoryLimitReached):
+		return utils.NewMCPError(utils.MCPCodeQuotaExceeded, "quota_exceeded", err.Error(), nil)
+
+	case errors.As(err, &blockedErr):
+		return utils.NewMCPError(utils.MCPCodeInvalidParams, "content_blocked", err.Error(), map[string]interface{}{
+			"categories": blockedErr.Categories,
+		})
+
+	case errors.As(err, &confirmErr):
+		return utils.NewMCPError(utils.MCPCodeConflict, "confirmation_required", err.Error(), map[string]interface{}{
+			"memory_id":          confirmErr.MemoryID,
+			"confirmation_token": confirmErr.Token,
+		})
+
+	case errors.As(err, &bulkConfirmErr):
+		return utils.NewMCPError(utils.MCPCodeConflict, "confirmation_required", err.Error(), map[string]interface{}{
+			"matched":            bulkConfirmErr.Matched,
+			"confirmation_token": bulkConfirmErr.Token,
+		})
+
+	case errors.As(err, &residencyErr):
+		return utils.NewMCPError(utils.MCPCodeConflict, "cross_region", err.Error(), map[string]interface{}{
+			"source_region": residencyErr.SourceRegion,
+			"target_region": residencyErr.TargetRegion,
+		})
+
+	case errors.As(err, &incognitoErr):
+		return utils.NewMCPError(utils.MCPCodeConflict, "incognito", err.Error(), map[string]interface{}{
+			"incognito_until": incognitoErr.Until,
+		})
+
+	case errors.As(err, &budgetErr):
+		return utils.NewMCPError(utils.MCPCodeQuotaExceeded, "llm_budget_exceeded", err.Error(), map[string]interface{}{
+			"scope":      budgetErr.Scope,
+			"budget_usd": budgetErr.BudgetUSD,
+		})
+
+	case errors.Is(err, services.ErrConsolidationDisabled):
+		return utils.NewMCPError(utils.MCPCodeInternalError, "consolidation_disabled", err.Error(), nil)
+
+	case errors.As(err, &duplicateErr):
+		return utils.NewMCPError(utils.MCPCodeConflict, "duplicate", err.Error(), map[string]interface{}{
+			"memory":     duplicateErr.Memory,
+			"similarity": duplicateErr.Similarity,
+		})
+	}
+
+	return utils.ToMCPError(err)
+}
+
+// invalidParams reports a malformed or invalid tool argument
+func invalidParams(format string, args ...interface{}) *utils.MCPError {
+	return utils.NewMCPError(utils.MCPCodeInvalidParams, "validation", fmt.Sprintf(format, args...), nil)
+}
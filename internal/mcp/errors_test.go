@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestToRPCError(t *testing.T) {
+	until := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantType string
+		wantData map[string]interface{}
+	}{
+		{"Validation", utils.InvalidFieldError("tagMatch", "must be any or all"), utils.MCPCodeInvalidParams, "validation",
+			map[string]interface{}{"field": "tagMatch"}},
+		{"Not found", fmt.Errorf("update: %w", utils.WrapNotFoundError("memory", "7")), utils.MCPCodeNotFound, "not_found",
+			map[string]interface{}{"resource": "memory", "id": "7"}},
+		{"Memory limit", &services.MemoryLimitError{Limit: 100}, utils.MCPCodeQuotaExceeded, "quota_exceeded",
+			map[string]interface{}{"limit": 100}},
+		{"Content blocked", &services.ContentBlockedError{Categories: []string{"credentials"}}, utils.MCPCodeInvalidParams, "content_blocked",
+			map[string]interface{}{"categories": []string{"credentials"}}},
+		{"Confirmation required", &services.ConfirmationRequiredError{MemoryID: 3, Token: "abc"}, utils.MCPCodeConflict, "confirmation_required",
+			map[string]interface{}{"memory_id": uint(3), "confirmation_token": "abc"}},
+		{"Bulk delete confirmation required", &services.BulkDeleteConfirmationError{Matched: 12, Token: "abc"}, utils.MCPCodeConflict, "confirmation_required",
+			map[string]interface{}{"matched": 12, "confirmation_token": "abc"}},
+		{"Incognito", &services.IncognitoError{Until: until}, utils.MCPCodeConflict, "incognito",
+			map[string]interface{}{"incognito_until": until}},
+		{"Database", utils.WrapDatabaseError("search memories", fmt.Errorf("connection refused")), utils.MCPCodeInternalError, "internal", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcErr := ToRPCError(tt.err)
+			assert.Equal(t, tt.wantCode, rpcErr.Code)
+			assert.Equal(t, tt.wantType, rpcErr.Data["type"])
+			for key, value := range tt.wantData {
+				assert.Equal(t, value, rpcErr.Data[key], key)
+			}
+		})
+	}
+}
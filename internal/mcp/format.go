@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Formats a tool result can be returned in, chosen with the format argument every
+// tool takes or by the client profile
+const (
+	// FormatText returns the result as JSON in a text content item, the default
+	FormatText = "text"
+	// FormatJSON returns the result as an embedded application/json resource, and
+	// as structured content over HTTP
+	FormatJSON = "json"
+	// FormatMarkdown returns a compact markdown summary of the result
+	FormatMarkdown = "markdown"
+)
+
+// formatArgument is the schema of the format argument added to every tool
+var formatArgument = map[string]interface{}{
+	"type":        "string",
+	"description": "How to return the result: text (JSON, the default), json (a structured application/json resource) or markdown (a compact summary)",
+	"enum":        []string{FormatText, FormatJSON, FormatMarkdown},
+}
+
+// WithFormatArgument adds the format argument to each tool's schema. Tools are
+// copied, so the registered definitions are left alone.
+func WithFormatArgument(tools []mcp.Tool) []mcp.Tool {
+	shaped := make([]mcp.Tool, len(tools))
+	for i, tool := range tools {
+		properties := make(map[string]interface{}, len(tool.InputSchema.Properties)+1)
+		maps.Copy(properties, tool.InputSchema.Properties)
+		properties["format"] = formatArgument
+		tool.InputSchema.Properties = properties
+		shaped[i] = tool
+	}
+	return shaped
+}
+
+// RequestedFormat returns the result format a tool call's arguments ask for,
+// falling back to the client profile's and then to text
+func RequestedFormat(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args map[string]json.RawMessage
+	// Arguments that are not an object are left for the tool to reject
+	_ = json.Unmarshal(arguments, &args)
+	format, err := lenientScalar(args["format"])
+	if err != nil {
+		return "", utils.InvalidFieldError("format", "must be text, json or markdown")
+	}
+	format = strings.ToLower(format)
+	if format == "" {
+		if profile := clientProfileFrom(ctx); profile != nil && profile.Format != "" {
+			return profile.Format, nil
+		}
+		return FormatText, nil
+	}
+	switch format {
+	case FormatText, FormatJSON, FormatMarkdown:
+		return format, nil
+	default:
+		return "", utils.InvalidFieldError("format", "must be text, json or markdown")
+	}
+}
+
+// ResultURI is the URI of the resource a tool's JSON result is embedded as
+func ResultURI(tool string) string {
+	return "memory://results/" + tool
+}
+
+// ToolResult renders a tool's result in a format
+func ToolResult(tool string, result interface{}, format string) (*mcp.CallToolResult, error) {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.NewEmbeddedResource(mcp.TextResourceContents{
+					URI:      ResultURI(tool),
+					MIMEType: "application/json",
+					Text:     string(resultJSON),
+				}),
+			},
+		}, nil
+	case FormatMarkdown:
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(resultJSON))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		return mcp.NewToolResultText(Markdown(value)), nil
+	default:
+		return mcp.NewToolResultText(string(resultJSON)), nil
+	}
+}
+
+// Markdown summarizes a decoded JSON result. Messages lead, other fields follow
+// as bullets, and memories, context turns and related memories are listed one
+// line each. Success flags and empty fields are left out.
+func Markdown(value interface{}) string {
+	var b strings.Builder
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		b.WriteString(inline(value))
+	} else {
+		for _, key := range []string{"message", "error"} {
+			if text, ok := fields[key].(string); ok && text != "" {
+				fmt.Fprintf(&b, "%s\n\n", text)
+			}
+		}
+		writeFields(&b, "", fields)
+	}
+	if b.Len() == 0 {
+		return "Done."
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// writeFields writes an object's scalar fields as bullets, then its lists of
+// memories, then its nested objects; prefix names the object within the result
+func writeFields(b *strings.Builder, prefix string, fields map[string]interface{}) {
+	keys := slices.Sorted(maps.Keys(fields))
+	var lists, objects []string
+	for _, key := range keys {
+		value := fields[key]
+		if isEmpty(value) || (prefix == "" && (key == "success" || key == "message" || key == "error")) {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if isMemory(v) {
+				lists = append(lists, key)
+			} else {
+				objects = append(objects, key)
+			}
+		case []interface{}:
+			if isScalarList(v) {
+				fmt.Fprintf(b, "- **%s%s**: %s\n", prefix, key, inline(v))
+			} else {
+				lists = append(lists, key)
+			}
+		default:
+			fmt.Fprintf(b, "- **%s%s**: %s\n", prefix, key, inline(v))
+		}
+	}
+
+	for _, key := range lists {
+		items, ok := fields[key].([]interface{})
+		if !ok {
+			items = []interface{}{fields[key]}
+			fmt.Fprintf(b, "\n**%s%s**:\n", prefix, key)
+		} else {
+			fmt.Fprintf(b, "\n**%s%s** (%d):\n", prefix, key, len(items))
+		}
+		for _, item := range items {
+			fmt.Fprintf(b, "- %s\n", itemLine(item))
+		}
+	}
+
+	for _, key := range objects {
+		writeFields(b, prefix+key+".", fields[key].(map[string]interface{}))
+	}
+}
+
+// itemLine renders one list item: a memory or context turn as its ID, kind and
+// content, a related memory with how it is linked, and anything else inline
+func itemLine(item interface{}) string {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return inline(item)
+	}
+	if memory, ok := fields["memory"].(map[string]interface{}); ok && isMemory(memory) {
+		line := memoryLine(memory)
+		if relation, ok := fields["relation"].(string); ok && relation != "" {
+			line += fmt.Sprintf(" ← %s %v of #%v", relation, fields["direction"], fields["from_id"])
+		}
+		return line
+	}
+	if isMemory(fields) {
+		return memoryLine(fields)
+	}
+	return inline(fields)
+}
+
+// memoryLine renders a memory on one line
+func memoryLine(memory map[string]interface{}) string {
+	var kind []string
+	for _, key := range []string{"type", "category", "role"} {
+		if value, ok := memory[key].(string); ok && value != "" {
+			kind = append(kind, value)
+		}
+	}
+	line := fmt.Sprintf("#%v", memory["id"])
+	if len(kind) > 0 {
+		line += " [" + strings.Join(kind, "/") + "]"
+	}
+	content, _ := memory["content"].(string)
+	line += " " + strings.Join(strings.Fields(content), " ")
+
+	var details []string
+	if tags, ok := memory["tags"].([]interface{}); ok && len(tags) > 0 {
+		details = append(details, "tags: "+inline(tags))
+	}
+	for _, key := range []string{"similarity", "score"} {
+		switch value := memory[key].(type) {
+		case json.Number:
+			if f, err := value.Float64(); err == nil {
+				details = append(details, fmt.Sprintf("%s %.2f", key, f))
+			}
+		case float64:
+			details = append(details, fmt.Sprintf("%s %.2f", key, value))
+		}
+	}
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, "; ") + ")"
+	}
+	return line
+}
+
+// isMemory reports whether an object is a memory or context turn
+func isMemory(fields map[string]interface{}) bool {
+	_, hasID := fields["id"]
+	_, hasContent := fields["content"].(string)
+	return hasID && hasContent
+}
+
+// isScalarList reports whether every item of a list is a scalar
+func isScalarList(items []interface{}) bool {
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// isEmpty reports whether a value has nothing worth showing
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// inline renders a value on one line: lists of scalars comma-separated, objects
+// as their non-empty fields, and anything else as compact JSON
+func inline(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case []interface{}:
+		if isScalarList(v) {
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = inline(item)
+			}
+			return strings.Join(parts, ", ")
+		}
+	case map[string]interface{}:
+		var parts []string
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if !isEmpty(v[key]) {
+				parts = append(parts, key+": "+inline(v[key]))
+			}
+		}
+		return strings.Join(parts, ", ")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestRequestedFormat(t *testing.T) {
+	ctx := context.Background()
+
+	format, err := RequestedFormat(ctx, json.RawMessage(`{"query": "tea"}`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+
+	format, err = RequestedFormat(ctx, json.RawMessage(`{"format": " Markdown "}`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatMarkdown, format)
+
+	profile := &ClientProfile{ClientProfile: config.ClientProfile{Format: FormatJSON}}
+	format, err = RequestedFormat(WithClientProfile(ctx, profile), json.RawMessage(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format, "the profile's format applies when the call gives none")
+
+	format, err = RequestedFormat(WithClientProfile(ctx, profile), json.RawMessage(`{"format": "text"}`))
+	require.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+
+	_, err = RequestedFormat(ctx, json.RawMessage(`{"format": "yaml"}`))
+	var validationErr *utils.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestToolResult(t *testing.T) {
+	similarity := 0.912
+	result := SearchMemoriesResponse{
+		Memories: []*models.Memory{
+			{ID: 7, Type: models.TypePreference, Category: models.CategoryPersonal, Content: "likes green\ntea", Tags: []string{"drinks", "tea"}, Similarity: &similarity},
+			{ID: 9, Type: models.TypeFact, Category: models.CategoryProject, Content: "deploys on Tuesdays"},
+		},
+		Count:      2,
+		TotalCount: 5,
+		NextCursor: "abc",
+	}
+
+	t.Run("text", func(t *testing.T) {
+		toolResult, err := ToolResult("search_memories", result, FormatText)
+		require.NoError(t, err)
+		require.Len(t, toolResult.Content, 1)
+		text := toolResult.Content[0].(mcpgo.TextContent).Text
+		expected, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), text)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		toolResult, err := ToolResult("search_memories", result, FormatJSON)
+		require.NoError(t, err)
+		require.Len(t, toolResult.Content, 1)
+		embedded := toolResult.Content[0].(mcpgo.EmbeddedResource)
+		assert.Equal(t, "resource", embedded.Type)
+		resource := embedded.Resource.(mcpgo.TextResourceContents)
+		assert.Equal(t, "memory://results/search_memories", resource.URI)
+		assert.Equal(t, "application/json", resource.MIMEType)
+
+		var decoded SearchMemoriesResponse
+		require.NoError(t, json.Unmarshal([]byte(resource.Text), &decoded))
+		assert.Equal(t, 2, decoded.Count)
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		toolResult, err := ToolResult("search_memories", result, FormatMarkdown)
+		require.NoError(t, err)
+		text := toolResult.Content[0].(mcpgo.TextContent).Text
+		assert.Equal(t, "- **count**: 2\n"+
+			"- **next_cursor**: abc\n"+
+			"- **total_count**: 5\n"+
+			"\n**memories** (2):\n"+
+			"- #7 [preference/personal] likes green tea (tags: drinks, tea; similarity 0.91)\n"+
+			"- #9 [fact/project] deploys on Tuesdays", text)
+	})
+}
+
+func TestMarkdown(t *testing.T) {
+	decode := func(v interface{}) interface{} {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		var decoded interface{}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		return decoded
+	}
+
+	assert.Equal(t, "Memory deleted", Markdown(decode(DeleteMemoryResponse{Success: true, Message: "Memory deleted"})))
+	assert.Equal(t, "Done.", Markdown(decode(map[string]interface{}{"success": true})))
+
+	related := GetRelatedMemoriesResponse{
+		Success:  true,
+		MemoryID: 3,
+		Related: []services.RelatedMemory{
+			{Memory: &models.Memory{ID: 4, Type: models.TypeFact, Category: models.CategoryProject, Content: "uses Postgres"}, FromID: 3, Relation: "supports", Direction: "outgoing", Depth: 1},
+		},
+		Count: 1,
+	}
+	assert.Equal(t, "- **count**: 1\n"+
+		"- **memory_id**: 3\n"+
+		"\n**related** (1):\n"+
+		"- #4 [fact/project] uses Postgres ← supports outgoing of #3", Markdown(decode(related)))
+
+	nested := map[string]interface{}{
+		"quota":  map[string]interface{}{"memories_used": 3, "memory_limit": 0},
+		"memory": map[string]interface{}{"id": 1, "type": "fact", "category": "personal", "content": "hi"},
+	}
+	assert.Equal(t, "**memory**:\n"+
+		"- #1 [fact/personal] hi\n"+
+		"- **quota.memories_used**: 3\n"+
+		"- **quota.memory_limit**: 0", Markdown(decode(nested)))
+}
+
+func TestWithFormatArgument(t *testing.T) {
+	tools := []mcpgo.Tool{mcpgo.NewTool("get_memory", mcpgo.WithNumber("id", mcpgo.Required()))}
+
+	shaped := WithFormatArgument(tools)
+	require.Len(t, shaped, 1)
+	assert.Contains(t, shaped[0].InputSchema.Properties, "id")
+	format := shaped[0].InputSchema.Properties["format"].(map[string]interface{})
+	assert.Equal(t, []string{"text", "json", "markdown"}, format["enum"])
+	assert.NotContains(t, tools[0].InputSchema.Properties, "format", "registered tool should be untouched")
+}
+
+func TestServer_ToolResultFormat(t *testing.T) {
+	ctx := context.Background()
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), nil)
+	s, err := NewServer(memoryService, zerolog.Nop())
+	require.NoError(t, err)
+
+	call := func(arguments string) map[string]interface{} {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"store_memory","arguments":` + arguments + `}}`
+		data, err := json.Marshal(s.mcpServer.HandleMessage(ctx, json.RawMessage(message)))
+		require.NoError(t, err)
+		var response struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(data, &response))
+		return response.Result
+	}
+
+	result := call(`{"type": "fact", "category": "personal", "content": "likes green tea", "format": "markdown"}`)
+	content := result["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "text", content["type"])
+	assert.Contains(t, content["text"], "[fact/personal] likes green tea")
+
+	result = call(`{"type": "fact", "category": "personal", "content": "drinks coffee", "format": "json"}`)
+	content = result["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "resource", content["type"])
+	assert.Equal(t, "memory://results/store_memory", content["resource"].(map[string]interface{})["uri"])
+
+	result = call(`{"type": "fact", "category": "personal", "content": "drinks coffee", "format": "xml"}`)
+	assert.Equal(t, true, result["isError"])
+}
@@ -3,7 +3,11 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -14,16 +18,40 @@ import (
 
 // Handler manages MCP tool handlers
 type Handler struct {
-	memoryService *services.MemoryService
-	logger        zerolog.Logger
+	// service is the memory service tool calls run against; it is replaced by one
+	// confined to a workspace when the client selects one (see UseWorkspace)
+	service atomic.Pointer[services.MemoryService]
+	logger  zerolog.Logger
 }
 
-// NewHandler creates a new MCP handler
+// NewHandler creates a new MCP handler. Tool calls run in the default workspace
+// unless memoryService is already confined to one.
 func NewHandler(memoryService *services.MemoryService, logger zerolog.Logger) *Handler {
-	return &Handler{
-		memoryService: memoryService,
-		logger:        logger,
+	if _, ok := memoryService.Workspace(); !ok {
+		memoryService = memoryService.InWorkspace(0)
 	}
+	h := &Handler{
+		logger: logger,
+	}
+	h.service.Store(memoryService)
+	return h
+}
+
+// memoryService returns the memory service tool calls run against
+func (h *Handler) memoryService() *services.MemoryService {
+	return h.service.Load()
+}
+
+// UseWorkspace confines the handler's tool calls to the named workspace of the
+// user; an empty name or "default" selects the default workspace
+func (h *Handler) UseWorkspace(ctx context.Context, name string) (uint, error) {
+	memoryService := h.memoryService()
+	workspaceID, err := memoryService.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	h.service.Store(memoryService.InWorkspace(workspaceID))
+	return workspaceID, nil
 }
 
 // StoreMemoryRequest represents the request structure for storing memory
@@ -33,6 +61,12 @@ type StoreMemoryRequest struct {
 	Content  string                 `json:"content"`
 	Tags     []string               `json:"tags,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// OnDuplicate is what to do when the memory is a near-duplicate of an
+	// existing one: merge, update, reject or allow (default: configured)
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+	// SessionID captures the memory in this working session instead of the one
+	// opened with start_session
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // SearchMemoriesRequest represents the request structure for searching memories
@@ -42,6 +76,78 @@ type SearchMemoriesRequest struct {
 	Type              string `json:"type,omitempty"`
 	Limit             int    `json:"limit,omitempty"`
 	UseSemanticSearch bool   `json:"useSemanticSearch,omitempty"`
+	// MetadataQuery is a JSON object matched by containment or a JSONPath expression
+	MetadataQuery string `json:"metadataQuery,omitempty"`
+	// Tags keeps memories with any (TagMatch "any", the default) or all of the tags
+	Tags     []string `json:"tags,omitempty"`
+	TagMatch string   `json:"tagMatch,omitempty"`
+	// SearchMode is keyword, semantic or hybrid; it overrides UseSemanticSearch
+	SearchMode string `json:"searchMode,omitempty"`
+	// Offset skips results; Cursor continues from a previous page's nextCursor
+	Offset int    `json:"offset,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	// IncludeContext also searches the session's short-term conversation buffer
+	IncludeContext bool   `json:"includeContext,omitempty"`
+	SessionID      string `json:"sessionId,omitempty"`
+	// IncludeRelated adds the memories linked to the results, following links up
+	// to RelatedDepth (default 1)
+	IncludeRelated bool `json:"includeRelated,omitempty"`
+	RelatedDepth   int  `json:"relatedDepth,omitempty"`
+	// WithinIDs searches only these memories, and RefineCursor only the memories
+	// of a previous response's refine_cursor, to drill into earlier results
+	WithinIDs    []uint `json:"withinIds,omitempty"`
+	RefineCursor string `json:"refineCursor,omitempty"`
+	// CreatedAfter, CreatedBefore, UpdatedAfter and UpdatedBefore scope the search
+	// by time. Each is an RFC 3339 timestamp or a YYYY-MM-DD date; after bounds are
+	// inclusive and before bounds exclusive.
+	CreatedAfter  string `json:"createdAfter,omitempty"`
+	CreatedBefore string `json:"createdBefore,omitempty"`
+	UpdatedAfter  string `json:"updatedAfter,omitempty"`
+	UpdatedBefore string `json:"updatedBefore,omitempty"`
+	// Priorities keeps memories of any of the priorities and MinPriority those at
+	// least as important
+	Priorities  []string `json:"priorities,omitempty"`
+	MinPriority string   `json:"minPriority,omitempty"`
+	// Session keeps memories captured in a working session, "current" being the
+	// open one. It is unrelated to SessionID, which names a context buffer.
+	Session string `json:"session,omitempty"`
+}
+
+// dateRange parses the request's time bounds
+func (r *SearchMemoriesRequest) dateRange() (services.DateRange, error) {
+	return parseDateRange(r.CreatedAfter, r.CreatedBefore, r.UpdatedAfter, r.UpdatedBefore)
+}
+
+// parseDateRange parses the createdAfter, createdBefore, updatedAfter and
+// updatedBefore arguments of a tool
+func parseDateRange(createdAfter, createdBefore, updatedAfter, updatedBefore string) (services.DateRange, error) {
+	var dateRange services.DateRange
+	bounds := []struct {
+		field string
+		value string
+		dest  **time.Time
+	}{
+		{"createdAfter", createdAfter, &dateRange.CreatedAfter},
+		{"createdBefore", createdBefore, &dateRange.CreatedBefore},
+		{"updatedAfter", updatedAfter, &dateRange.UpdatedAfter},
+		{"updatedBefore", updatedBefore, &dateRange.UpdatedBefore},
+	}
+	for _, bound := range bounds {
+		parsed, err := services.ParseDateBound(bound.field, bound.value)
+		if err != nil {
+			return dateRange, err
+		}
+		*bound.dest = parsed
+	}
+	return dateRange, nil
+}
+
+// AppendContextRequest represents the request structure for adding a turn to the
+// short-term conversation buffer
+type AppendContextRequest struct {
+	Content   string `json:"content"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
 }
 
 // UpdateMemoryRequest represents the request structure for updating memory
@@ -55,9 +161,87 @@ type UpdateMemoryRequest struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// liftMetadataTags moves tags given in metadata, as older clients send them, to
+// Tags when no tags were given directly
+func (r *UpdateMemoryRequest) liftMetadataTags() {
+	if len(r.Tags) > 0 || r.Metadata == nil {
+		return
+	}
+	if tagsInterface, exists := r.Metadata["tags"]; exists {
+		switch tags := tagsInterface.(type) {
+		case []interface{}:
+			// Convert []interface{} to []string
+			for _, tag := range tags {
+				if tagStr, ok := tag.(string); ok {
+					r.Tags = append(r.Tags, tagStr)
+				}
+			}
+		case []string:
+			// Direct assignment if already []string
+			r.Tags = tags
+		}
+		// Remove tags from metadata to avoid duplication
+		delete(r.Metadata, "tags")
+	}
+}
+
+// UpdateMemoriesBulkRequest represents the request structure for updating
+// several memories at once
+type UpdateMemoriesBulkRequest struct {
+	Updates []UpdateMemoryRequest `json:"updates"`
+	// Atomic applies every update or, if any fails, none
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+// ServiceRequest converts the request for the memory service
+func (r *UpdateMemoriesBulkRequest) ServiceRequest() services.BulkUpdateRequest {
+	updates := make([]services.BulkUpdateItem, len(r.Updates))
+	for i := range r.Updates {
+		update := &r.Updates[i]
+		update.liftMetadataTags()
+		updates[i] = services.BulkUpdateItem{
+			ID: update.ID,
+			UpdateRequest: services.UpdateRequest{
+				Content:  update.Content,
+				Category: update.Category,
+				Type:     update.Type,
+				Priority: update.Priority,
+				Tags:     update.Tags,
+				Metadata: update.Metadata,
+			},
+		}
+	}
+	return services.BulkUpdateRequest{Updates: updates, Atomic: r.Atomic}
+}
+
+// GetMemoryRequest represents the request structure for getting one memory
+type GetMemoryRequest struct {
+	ID uint `json:"id"`
+}
+
 // DeleteMemoryRequest represents the request structure for deleting memory
 type DeleteMemoryRequest struct {
-	ID uint `json:"id"`
+	ID      uint   `json:"id"`
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// DeleteMemoriesRequest represents the request structure for deleting the
+// memories matching filters
+type DeleteMemoriesRequest struct {
+	Category      string   `json:"category,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	TagMatch      string   `json:"tagMatch,omitempty"`
+	Query         string   `json:"query,omitempty"`
+	CreatedAfter  string   `json:"createdAfter,omitempty"`
+	CreatedBefore string   `json:"createdBefore,omitempty"`
+	UpdatedAfter  string   `json:"updatedAfter,omitempty"`
+	UpdatedBefore string   `json:"updatedBefore,omitempty"`
+	// IncludeCritical deletes matching critical memories too
+	IncludeCritical bool `json:"includeCritical,omitempty"`
+	// DryRun previews the delete and returns the token that confirms it
+	DryRun  bool   `json:"dryRun,omitempty"`
+	Confirm string `json:"confirm,omitempty"`
 }
 
 // Response structures
@@ -66,14 +250,45 @@ type DeleteMemoryRequest struct {
 type StoreMemoryResponse struct {
 	Success bool           `json:"success"`
 	Memory  *models.Memory `json:"memory,omitempty"`
-	Error   string         `json:"error,omitempty"`
+	// Action is created for a new memory, updated when an existing one matched by
+	// update key, content or similarity was overwritten, or merged when the
+	// memory was folded into a near-duplicate
+	Action string `json:"action,omitempty"`
+	// MatchedBy is how the existing memory was found: update_key, content or
+	// near_duplicate
+	MatchedBy string `json:"matched_by,omitempty"`
+	// PreviousContent is what an updated memory said before this store
+	PreviousContent string               `json:"previous_content,omitempty"`
+	Quota           *services.QuotaUsage `json:"quota,omitempty"`
+	// Duplicate is set when the memory was merged into or updated a
+	// near-duplicate, which is then the memory returned
+	Duplicate *services.DuplicateMatch `json:"duplicate,omitempty"`
+	Error     string                   `json:"error,omitempty"`
 }
 
 // SearchMemoriesResponse represents the response after searching memories
 type SearchMemoriesResponse struct {
 	Memories []*models.Memory `json:"memories"`
 	Count    int              `json:"count"`
-	Error    string           `json:"error,omitempty"`
+	// TotalCount is how many memories match across all pages
+	TotalCount int64 `json:"total_count"`
+	// NextCursor fetches the next page; it is omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// RefineCursor searches within this page's memories when passed as refineCursor
+	RefineCursor string `json:"refine_cursor,omitempty"`
+	// Context holds matching turns from the short-term buffer when requested
+	Context []services.ContextMatch `json:"context,omitempty"`
+	// Related holds the memories linked to the results when requested
+	Related []services.RelatedMemory `json:"related,omitempty"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// AppendContextResponse represents the response after buffering a turn
+type AppendContextResponse struct {
+	Success bool                `json:"success"`
+	Turn    *models.ContextTurn `json:"turn,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Error   string              `json:"error,omitempty"`
 }
 
 // UpdateMemoryResponse represents the response after updating a memory
@@ -88,6 +303,8 @@ type DeleteMemoryResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// ConfirmationToken is set when deleting a critical memory needs confirmation
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
 }
 
 // StoreMemoriesBulkRequest represents the request structure for bulk storing memories
@@ -101,6 +318,7 @@ type StoreMemoriesBulkResponse struct {
 	Stored    int                    `json:"stored"`
 	Failed    int                    `json:"failed"`
 	Memories  []*models.Memory       `json:"memories,omitempty"`
+	Quota     *services.QuotaUsage   `json:"quota,omitempty"`
 	Errors    []string               `json:"errors,omitempty"`
 }
 
@@ -130,6 +348,7 @@ func (h *Handler) HandleStoreMemoriesBulk(ctx context.Context, params json.RawMe
 	// Process each memory
 	var storedMemories []*models.Memory
 	var errors []string
+	var lastQuota *services.QuotaUsage
 	successCount := 0
 	failureCount := 0
 
@@ -179,14 +398,21 @@ func (h *Handler) HandleStoreMemoriesBulk(ctx context.Context, params json.RawMe
 			UpdateKey: "",
 			Tags:      memReq.Tags,
 			Metadata:  memReq.Metadata,
+			SessionID: memReq.SessionID,
 		}
 
-		memory, err := h.memoryService.Store(ctx, storeReq)
+		memory, quota, err := h.memoryService().StoreWithQuota(ctx, storeReq)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("memory[%d]: %v", i, err))
 			failureCount++
 			continue
 		}
+		if quota != nil {
+			if lastQuota != nil {
+				quota.Evicted += lastQuota.Evicted
+			}
+			lastQuota = quota
+		}
 
 		// Create response memory without embedding
 		responseMemory := &models.Memory{
@@ -212,11 +438,16 @@ func (h *Handler) HandleStoreMemoriesBulk(ctx context.Context, params json.RawMe
 		Int("failed", failureCount).
 		Msg("bulk store memories completed")
 
+	if lastQuota != nil {
+		lastQuota.RefreshWarning()
+	}
+
 	return StoreMemoriesBulkResponse{
 		Success:  failureCount == 0,
 		Stored:   successCount,
 		Failed:   failureCount,
 		Memories: storedMemories,
+		Quota:    lastQuota,
 		Errors:   errors,
 	}, nil
 }
@@ -232,10 +463,7 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 	// Check if params are empty
 	if len(params) == 0 {
 		h.logger.Error().Msg("empty params received in HandleStoreMemory")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   "empty request body",
-		}, nil
+		return nil, invalidParams("empty request body")
 	}
 
 	// Parse request
@@ -245,35 +473,23 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 			Err(err).
 			Str("params_string", string(params)).
 			Msg("failed to parse store memory request")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid request format: %v", err),
-		}, nil
+		return nil, invalidParams("invalid request format: %v", err)
 	}
 
 	// Validate request
 	if req.Content == "" {
 		h.logger.Warn().Msg("store memory request missing content")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   "content is required",
-		}, nil
+		return nil, invalidParams("content is required")
 	}
 	
 	if req.Type == "" {
 		h.logger.Warn().Msg("store memory request missing type")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   "type is required (must be one of: fact, conversation, context, preference)",
-		}, nil
+		return nil, invalidParams("type is required (must be one of: fact, conversation, context, preference)")
 	}
 	
 	if req.Category == "" {
 		h.logger.Warn().Msg("store memory request missing category")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   "category is required (must be one of: personal, project, business)",
-		}, nil
+		return nil, invalidParams("category is required (must be one of: personal, project, business)")
 	}
 
 	// Check if tags are provided in metadata for backward compatibility
@@ -298,22 +514,16 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 
 	if !models.IsValidType(req.Type) {
 		h.logger.Warn().Str("type", req.Type).Msg("invalid memory type")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type),
-		}, nil
+		return nil, invalidParams("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type)
 	}
 
 	if !models.IsValidCategory(req.Category) {
 		h.logger.Warn().Str("category", req.Category).Msg("invalid memory category")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid memory category '%s': must be one of personal, project, or business", req.Category),
-		}, nil
+		return nil, invalidParams("invalid memory category '%s': must be one of personal, project, or business", req.Category)
 	}
 
 	// First try automatic pattern detection
-	autoMemories, err := h.memoryService.ProcessContentForMemory(ctx, req.Content)
+	autoMemories, err := h.memoryService().ProcessContentForMemory(ctx, req.Content)
 	if err != nil {
 		h.logger.Warn().Err(err).Msg("automatic pattern detection failed")
 	}
@@ -324,13 +534,15 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 		// Use detected memory as base but allow manual override
 		detected := autoMemories[0]
 		storeReq = services.StoreRequest{
-			Content:   req.Content,
-			Category:  req.Category,  // Manual override
-			Type:      req.Type,      // Manual override
-			Priority:  detected.Priority,
-			UpdateKey: detected.UpdateKey,
-			Tags:      req.Tags,
-			Metadata:  req.Metadata,
+			Content:     req.Content,
+			Category:    req.Category,  // Manual override
+			Type:        req.Type,      // Manual override
+			Priority:    detected.Priority,
+			UpdateKey:   detected.UpdateKey,
+			Tags:        req.Tags,
+			Metadata:    req.Metadata,
+			OnDuplicate: req.OnDuplicate,
+			SessionID:   req.SessionID,
 		}
 		
 		h.logger.Info().
@@ -340,26 +552,37 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 	} else {
 		// No automatic detection, use manual input
 		storeReq = services.StoreRequest{
-			Content:   req.Content,
-			Category:  req.Category,
-			Type:      req.Type,
-			Priority:  "medium", // Default priority
-			UpdateKey: "",       // No update key
-			Tags:      req.Tags,
-			Metadata:  req.Metadata,
+			Content:     req.Content,
+			Category:    req.Category,
+			Type:        req.Type,
+			Priority:    "medium", // Default priority
+			UpdateKey:   "",       // No update key
+			Tags:        req.Tags,
+			Metadata:    req.Metadata,
+			OnDuplicate: req.OnDuplicate,
+			SessionID:   req.SessionID,
 		}
 	}
 
 	// Call memory service
-	memory, err := h.memoryService.Store(ctx, storeReq)
+	result, err := h.memoryService().StoreWithResult(ctx, storeReq)
 
 	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to store memory")
-		return StoreMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to store memory: %v", err),
-		}, nil
+		rpcErr := ToRPCError(err)
+		switch {
+		case errors.Is(err, services.ErrMemoryLimitReached):
+			// Not a failure of the server: report the usage so the client can prune
+			if quota, quotaErr := h.memoryService().Quota(ctx); quotaErr == nil {
+				quota.RefreshWarning()
+				rpcErr.Data["quota"] = quota
+			}
+		case errors.Is(err, services.ErrContentBlocked), errors.Is(err, services.ErrDuplicateMemory):
+		default:
+			h.logger.Error().Err(err).Msg("failed to store memory")
+		}
+		return nil, rpcErr
 	}
+	memory := result.Memory
 
 	h.logger.Info().
 		Uint("id", memory.ID).
@@ -382,8 +605,13 @@ func (h *Handler) HandleStoreMemory(ctx context.Context, params json.RawMessage)
 	}
 	
 	return StoreMemoryResponse{
-		Success: true,
-		Memory:  responseMemory,
+		Success:         true,
+		Memory:          responseMemory,
+		Action:          result.Action,
+		MatchedBy:       result.MatchedBy,
+		PreviousContent: result.PreviousContent,
+		Quota:           result.Quota,
+		Duplicate:       result.Duplicate,
 	}, nil
 }
 
@@ -395,57 +623,92 @@ func (h *Handler) HandleSearchMemories(ctx context.Context, params json.RawMessa
 	var req SearchMemoriesRequest
 	if err := json.Unmarshal(params, &req); err != nil {
 		h.logger.Error().Err(err).Msg("failed to parse search memories request")
-		return SearchMemoriesResponse{
-			Memories: []*models.Memory{},
-			Count:    0,
-			Error:    fmt.Sprintf("invalid request format: %v", err),
-		}, nil
+		return nil, invalidParams("invalid request format: %v", err)
 	}
 
 	// Validate request
 	if req.Type != "" && !models.IsValidType(req.Type) {
 		h.logger.Warn().Str("type", req.Type).Msg("invalid memory type")
-		return SearchMemoriesResponse{
-			Memories: []*models.Memory{},
-			Count:    0,
-			Error:    fmt.Sprintf("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type),
-		}, nil
+		return nil, invalidParams("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type)
 	}
 
 	if req.Category != "" && !models.IsValidCategory(req.Category) {
 		h.logger.Warn().Str("category", req.Category).Msg("invalid memory category")
-		return SearchMemoriesResponse{
-			Memories: []*models.Memory{},
-			Count:    0,
-			Error:    fmt.Sprintf("invalid memory category '%s': must be one of personal, project, or business", req.Category),
-		}, nil
+		return nil, invalidParams("invalid memory category '%s': must be one of personal, project, or business", req.Category)
+	}
+
+	if req.TagMatch != "" && req.TagMatch != services.TagMatchAny && req.TagMatch != services.TagMatchAll {
+		h.logger.Warn().Str("tag_match", req.TagMatch).Msg("invalid tag match mode")
+		return nil, invalidParams("invalid tagMatch '%s': must be any or all", req.TagMatch)
+	}
+
+	if req.SearchMode != "" && !services.IsValidSearchMode(req.SearchMode) {
+		h.logger.Warn().Str("search_mode", req.SearchMode).Msg("invalid search mode")
+		return nil, invalidParams("invalid searchMode '%s': must be keyword, semantic or hybrid", req.SearchMode)
 	}
 
-	// Set default limit if not provided
-	if req.Limit <= 0 {
-		req.Limit = 100
+	dateRange, err := req.dateRange()
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("invalid date range")
+		return nil, ToRPCError(err)
 	}
 
+	// Set default limit if not provided, within what the client's profile allows
+	profile := clientProfileFrom(ctx)
+	req.Limit = profile.limit(req.Limit, 100)
+
 	// Default to semantic search when we have a query (this is why we have embeddings!)
 	// This is the entire point of having vector search
 	useSemanticSearch := req.Query != ""
 
 	// Call memory service
-	memories, err := h.memoryService.Search(ctx, services.SearchRequest{
+	page, err := h.memoryService().SearchPage(ctx, services.SearchRequest{
 		Query:             req.Query,
 		Category:          req.Category,
 		Type:              req.Type,
 		Limit:             req.Limit,
 		UseSemanticSearch: useSemanticSearch,
-	})
+		MetadataQuery:     req.MetadataQuery,
+		Tags:              req.Tags,
+		TagMatch:          req.TagMatch,
+		Mode:              req.SearchMode,
+		Offset:            req.Offset,
+		WithinIDs:         req.WithinIDs,
+		RefineCursor:      req.RefineCursor,
+		DateRange:         dateRange,
+		PriorityFilter: services.PriorityFilter{
+			Priorities:  req.Priorities,
+			MinPriority: req.MinPriority,
+		},
+		Session: req.Session,
+	}, req.Cursor)
 
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to search memories")
-		return SearchMemoriesResponse{
-			Memories: []*models.Memory{},
-			Count:    0,
-			Error:    fmt.Sprintf("failed to search memories: %v", err),
-		}, nil
+		return nil, ToRPCError(err)
+	}
+	memories := page.Memories
+
+	var contextMatches []services.ContextMatch
+	if req.IncludeContext {
+		contextMatches, err = h.memoryService().SearchContext(ctx, req.SessionID, req.Query, services.DefaultContextSearchLimit)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to search context buffer")
+			return nil, ToRPCError(err)
+		}
+	}
+
+	var related []services.RelatedMemory
+	if req.IncludeRelated && len(memories) > 0 {
+		ids := make([]uint, len(memories))
+		for i, memory := range memories {
+			ids[i] = memory.ID
+		}
+		related, err = h.memoryService().RelatedMemories(ctx, ids, services.RelatedRequest{Depth: req.RelatedDepth})
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to follow memory links")
+			return nil, ToRPCError(err)
+		}
 	}
 
 	// Ensure we return an empty array instead of nil
@@ -475,12 +738,49 @@ func (h *Handler) HandleSearchMemories(ctx context.Context, params json.RawMessa
 		Str("query", req.Query).
 		Str("category", req.Category).
 		Str("type", req.Type).
+		Strs("tags", req.Tags).
 		Bool("semantic", useSemanticSearch).
+		Str("search_mode", req.SearchMode).
 		Msg("successfully searched memories")
 
 	return SearchMemoriesResponse{
-		Memories: responseMemories,
-		Count:    len(responseMemories),
+		Memories:     profile.shapeMemories(responseMemories),
+		Count:        len(responseMemories),
+		TotalCount:   page.TotalCount,
+		NextCursor:   page.NextCursor,
+		RefineCursor: page.RefineCursor,
+		Context:      contextMatches,
+		Related:      related,
+	}, nil
+}
+
+// HandleAppendContext handles the append context MCP tool call
+func (h *Handler) HandleAppendContext(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().Msg("handleAppendContext called")
+
+	var req AppendContextRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse append context request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.Content == "" {
+		return nil, invalidParams("content is required")
+	}
+
+	turn, err := h.memoryService().AppendContext(ctx, services.AppendContextRequest{
+		SessionID: req.SessionID,
+		Role:      req.Role,
+		Content:   req.Content,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to append context")
+		return nil, ToRPCError(err)
+	}
+
+	return AppendContextResponse{
+		Success: true,
+		Turn:    turn,
+		Message: fmt.Sprintf("Added to session %q until %s", turn.SessionID, turn.ExpiresAt.UTC().Format(time.RFC3339)),
 	}, nil
 }
 
@@ -492,60 +792,31 @@ func (h *Handler) HandleUpdateMemory(ctx context.Context, params json.RawMessage
 	var req UpdateMemoryRequest
 	if err := json.Unmarshal(params, &req); err != nil {
 		h.logger.Error().Err(err).Msg("failed to parse update memory request")
-		return UpdateMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid request format: %v", err),
-		}, nil
+		return nil, invalidParams("invalid request format: %v", err)
 	}
 
 	// Validate request
 	if req.ID == 0 {
 		h.logger.Warn().Msg("update memory request missing ID")
-		return UpdateMemoryResponse{
-			Success: false,
-			Error:   "memory ID is required",
-		}, nil
+		return nil, invalidParams("memory ID is required")
 	}
 
 	// Check if tags are provided in metadata for backward compatibility
-	if len(req.Tags) == 0 && req.Metadata != nil {
-		if tagsInterface, exists := req.Metadata["tags"]; exists {
-			switch tags := tagsInterface.(type) {
-			case []interface{}:
-				// Convert []interface{} to []string
-				for _, tag := range tags {
-					if tagStr, ok := tag.(string); ok {
-						req.Tags = append(req.Tags, tagStr)
-					}
-				}
-			case []string:
-				// Direct assignment if already []string
-				req.Tags = tags
-			}
-			// Remove tags from metadata to avoid duplication
-			delete(req.Metadata, "tags")
-		}
-	}
+	req.liftMetadataTags()
 
 	// Validate fields if provided
 	if req.Type != "" && !models.IsValidType(req.Type) {
 		h.logger.Warn().Str("type", req.Type).Msg("invalid memory type")
-		return UpdateMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type),
-		}, nil
+		return nil, invalidParams("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type)
 	}
 
 	if req.Category != "" && !models.IsValidCategory(req.Category) {
 		h.logger.Warn().Str("category", req.Category).Msg("invalid memory category")
-		return UpdateMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid memory category '%s': must be one of personal, project, or business", req.Category),
-		}, nil
+		return nil, invalidParams("invalid memory category '%s': must be one of personal, project, or business", req.Category)
 	}
 
 	// Call memory service
-	memory, err := h.memoryService.Update(ctx, req.ID, services.UpdateRequest{
+	memory, err := h.memoryService().Update(ctx, req.ID, services.UpdateRequest{
 		Content:  req.Content,
 		Category: req.Category,
 		Type:     req.Type,
@@ -555,20 +826,12 @@ func (h *Handler) HandleUpdateMemory(ctx context.Context, params json.RawMessage
 	})
 
 	if err != nil {
-		// Check if it's a not found error
 		if utils.IsNotFoundError(err) {
 			h.logger.Warn().Uint("id", req.ID).Msg("memory not found")
-			return UpdateMemoryResponse{
-				Success: false,
-				Error:   fmt.Sprintf("memory with ID %d not found", req.ID),
-			}, nil
+		} else {
+			h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to update memory")
 		}
-
-		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to update memory")
-		return UpdateMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to update memory: %v", err),
-		}, nil
+		return nil, ToRPCError(err)
 	}
 
 	h.logger.Info().
@@ -595,6 +858,53 @@ func (h *Handler) HandleUpdateMemory(ctx context.Context, params json.RawMessage
 	}, nil
 }
 
+// HandleUpdateMemoriesBulk handles the bulk update memories MCP tool call. Each
+// update succeeds or fails on its own unless the request is atomic.
+func (h *Handler) HandleUpdateMemoriesBulk(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleUpdateMemoriesBulk called")
+
+	var req UpdateMemoriesBulkRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse bulk update memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	result, err := h.memoryService().BulkUpdate(ctx, req.ServiceRequest())
+	if err != nil {
+		if !utils.IsValidationError(err) {
+			h.logger.Error().Err(err).Msg("failed to bulk update memories")
+		}
+		return nil, ToRPCError(err)
+	}
+
+	return result, nil
+}
+
+// HandleGetMemory handles the get memory MCP tool call
+func (h *Handler) HandleGetMemory(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleGetMemory called")
+
+	var req GetMemoryRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse get memory request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.ID == 0 {
+		return nil, invalidParams("memory ID is required")
+	}
+
+	memory, err := h.memoryService().GetByID(ctx, req.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to get memory")
+		return nil, ToRPCError(err)
+	}
+
+	return GetMemoryResponse{
+		Success: true,
+		Memory:  memory,
+	}, nil
+}
+
 // HandleDeleteMemory handles the delete memory MCP tool call
 func (h *Handler) HandleDeleteMemory(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	h.logger.Debug().RawJSON("params", params).Msg("handleDeleteMemory called")
@@ -603,38 +913,29 @@ func (h *Handler) HandleDeleteMemory(ctx context.Context, params json.RawMessage
 	var req DeleteMemoryRequest
 	if err := json.Unmarshal(params, &req); err != nil {
 		h.logger.Error().Err(err).Msg("failed to parse delete memory request")
-		return DeleteMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid request format: %v", err),
-		}, nil
+		return nil, invalidParams("invalid request format: %v", err)
 	}
 
 	// Validate request
 	if req.ID == 0 {
 		h.logger.Warn().Msg("delete memory request missing ID")
-		return DeleteMemoryResponse{
-			Success: false,
-			Error:   "memory ID is required",
-		}, nil
+		return nil, invalidParams("memory ID is required")
 	}
 
 	// Call memory service
-	err := h.memoryService.Delete(ctx, req.ID)
+	err := h.memoryService().DeleteConfirmed(ctx, req.ID, req.Confirm)
 	if err != nil {
-		// Check if it's a not found error
-		if utils.IsNotFoundError(err) {
+		rpcErr := ToRPCError(err)
+		switch {
+		case errors.Is(err, services.ErrConfirmationRequired):
+			h.logger.Warn().Uint("id", req.ID).Msg("critical memory delete needs confirmation")
+			rpcErr.Message += ". Ask the user to confirm before retrying."
+		case utils.IsNotFoundError(err):
 			h.logger.Warn().Uint("id", req.ID).Msg("memory not found")
-			return DeleteMemoryResponse{
-				Success: false,
-				Error:   fmt.Sprintf("memory with ID %d not found", req.ID),
-			}, nil
+		default:
+			h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to delete memory")
 		}
-
-		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to delete memory")
-		return DeleteMemoryResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to delete memory: %v", err),
-		}, nil
+		return nil, rpcErr
 	}
 
 	h.logger.Info().
@@ -647,6 +948,618 @@ func (h *Handler) HandleDeleteMemory(ctx context.Context, params json.RawMessage
 	}, nil
 }
 
+// HandleDeleteMemories handles the delete memories MCP tool call
+func (h *Handler) HandleDeleteMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleDeleteMemories called")
+
+	var req DeleteMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse delete memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	if req.Type != "" && !models.IsValidType(req.Type) {
+		return nil, invalidParams("invalid memory type '%s': must be one of fact, conversation, context, or preference", req.Type)
+	}
+	if req.Category != "" && !models.IsValidCategory(req.Category) {
+		return nil, invalidParams("invalid memory category '%s': must be one of personal, project, or business", req.Category)
+	}
+	if req.TagMatch != "" && req.TagMatch != services.TagMatchAny && req.TagMatch != services.TagMatchAll {
+		return nil, invalidParams("invalid tagMatch '%s': must be any or all", req.TagMatch)
+	}
+	dateRange, err := parseDateRange(req.CreatedAfter, req.CreatedBefore, req.UpdatedAfter, req.UpdatedBefore)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("invalid date range")
+		return nil, ToRPCError(err)
+	}
+
+	result, err := h.memoryService().DeleteMatching(ctx, services.BulkDeleteRequest{
+		Category:        req.Category,
+		Type:            req.Type,
+		Tags:            req.Tags,
+		TagMatch:        req.TagMatch,
+		Query:           req.Query,
+		DateRange:       dateRange,
+		IncludeCritical: req.IncludeCritical,
+		DryRun:          req.DryRun,
+		Confirm:         req.Confirm,
+	})
+	if err != nil {
+		rpcErr := ToRPCError(err)
+		if errors.Is(err, services.ErrBulkDeleteConfirmationRequired) {
+			h.logger.Warn().Msg("bulk delete needs confirmation")
+			rpcErr.Message += ". Show the user what would be deleted and ask them to confirm before retrying."
+		} else if !utils.IsValidationError(err) {
+			h.logger.Error().Err(err).Msg("failed to delete memories")
+		}
+		return nil, rpcErr
+	}
+
+	return result, nil
+}
+
+// ExportMemoriesRequest represents the request structure for exporting memories
+type ExportMemoriesRequest struct {
+	IncludeEmbeddings bool `json:"include_embeddings,omitempty"`
+	KeepEncrypted     bool `json:"keep_encrypted,omitempty"`
+	// Anonymize is "true" or a comma-separated list of the PII kinds to replace
+	Anonymize      string   `json:"anonymize,omitempty"`
+	AnonymizeTerms []string `json:"anonymize_terms,omitempty"`
+}
+
+// ExportMemoriesResponse represents the response after exporting memories
+type ExportMemoriesResponse struct {
+	Success bool                    `json:"success"`
+	Archive *services.MemoryArchive `json:"archive,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// ImportMemoriesRequest represents the request structure for importing memories
+type ImportMemoriesRequest struct {
+	Archive          *services.MemoryArchive `json:"archive"`
+	AllowCrossRegion bool                    `json:"allow_cross_region,omitempty"`
+}
+
+// ImportMemoriesResponse represents the response after importing memories
+type ImportMemoriesResponse struct {
+	Success bool                           `json:"success"`
+	Result  *services.ImportMemoriesResult `json:"result,omitempty"`
+	Error   string                         `json:"error,omitempty"`
+}
+
+// HandleExportMemories handles the export memories MCP tool call
+func (h *Handler) HandleExportMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleExportMemories called")
+
+	var req ExportMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse export memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	anonymize, err := services.ParseAnonymizeOptions(req.Anonymize, strings.Join(req.AnonymizeTerms, ","))
+	if err != nil {
+		return nil, ToRPCError(err)
+	}
+
+	archive, err := h.memoryService().ExportMemoriesWithOptions(ctx, services.ExportOptions{
+		IncludeEmbeddings: req.IncludeEmbeddings,
+		KeepEncrypted:     req.KeepEncrypted,
+		Anonymize:         anonymize,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to export memories")
+		return nil, ToRPCError(err)
+	}
+
+	return ExportMemoriesResponse{
+		Success: true,
+		Archive: archive,
+	}, nil
+}
+
+// HandleImportMemories handles the import memories MCP tool call
+func (h *Handler) HandleImportMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().Int("params_length", len(params)).Msg("handleImportMemories called")
+
+	var req ImportMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse import memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	if req.Archive == nil {
+		return nil, invalidParams("archive is required")
+	}
+
+	result, err := h.memoryService().ImportMemories(ctx, req.Archive, req.AllowCrossRegion)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to import memories")
+		return nil, ToRPCError(err)
+	}
+
+	h.logger.Info().
+		Int("created", result.Created).
+		Int("skipped", result.Skipped).
+		Int("embeddings_restored", result.EmbeddingsRestored).
+		Msg("successfully imported memories")
+
+	return ImportMemoriesResponse{
+		Success: true,
+		Result:  result,
+	}, nil
+}
+
+// Incognito actions
+const (
+	IncognitoActionStart  = "start"
+	IncognitoActionStop   = "stop"
+	IncognitoActionStatus = "status"
+)
+
+// IncognitoRequest represents the request structure for the incognito tool
+type IncognitoRequest struct {
+	Action string `json:"action"`
+	// Duration is a Go duration such as "30m" or "2h", or a number of minutes
+	Duration string `json:"duration,omitempty"`
+}
+
+// IncognitoResponse reports the incognito status after the action
+type IncognitoResponse struct {
+	Success bool                      `json:"success"`
+	Message string                    `json:"message"`
+	Status  *services.IncognitoStatus `json:"status"`
+}
+
+// HandleIncognito handles the incognito MCP tool call
+func (h *Handler) HandleIncognito(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleIncognito called")
+
+	var req IncognitoRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse incognito request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	var status *services.IncognitoStatus
+	var err error
+	switch req.Action {
+	case IncognitoActionStart:
+		duration, parseErr := ParseIncognitoDuration(req.Duration)
+		if parseErr != nil {
+			return nil, invalidParams("%v", parseErr)
+		}
+		status, err = h.memoryService().StartIncognito(ctx, duration)
+	case IncognitoActionStop:
+		status, err = h.memoryService().StopIncognito(ctx)
+	case IncognitoActionStatus, "":
+		status, err = h.memoryService().IncognitoStatus(ctx)
+	default:
+		return nil, invalidParams("invalid action: %s (must be start, stop or status)", req.Action)
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("action", req.Action).Msg("failed to change incognito mode")
+		return nil, ToRPCError(err)
+	}
+
+	message := "Incognito mode is off. Memories are being remembered."
+	if status.Active {
+		message = fmt.Sprintf("Incognito mode is on until %s. Nothing is remembered until then or until it is stopped.",
+			status.Until.UTC().Format(time.RFC3339))
+	}
+
+	return IncognitoResponse{
+		Success: true,
+		Message: message,
+		Status:  status,
+	}, nil
+}
+
+// StartSessionRequest represents the request structure for the start_session tool
+type StartSessionRequest struct {
+	// SessionID names the session, e.g. the client's conversation ID; one is
+	// generated when empty
+	SessionID string `json:"sessionId,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// EndSessionRequest represents the request structure for the end_session tool
+type EndSessionRequest struct {
+	// Purge deletes the memories captured in the session, apart from critical ones
+	Purge bool `json:"purge,omitempty"`
+}
+
+// SessionResponse reports a working session after it was started or ended
+type SessionResponse struct {
+	Success bool                   `json:"success"`
+	Message string                 `json:"message"`
+	Session interface{}            `json:"session"`
+	Purge   *services.SessionPurge `json:"purge,omitempty"`
+}
+
+// HandleStartSession handles the start_session MCP tool call
+func (h *Handler) HandleStartSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleStartSession called")
+
+	var req StartSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse start session request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	session, err := h.memoryService().StartSession(ctx, req.SessionID, req.Name)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to start session")
+		return nil, ToRPCError(err)
+	}
+
+	return SessionResponse{
+		Success: true,
+		Message: fmt.Sprintf("Session %s started. Memories stored until end_session are captured in it.", session.SessionID),
+		Session: session,
+	}, nil
+}
+
+// HandleEndSession handles the end_session MCP tool call
+func (h *Handler) HandleEndSession(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleEndSession called")
+
+	var req EndSessionRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse end session request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+
+	result, err := h.memoryService().EndSession(ctx, req.Purge)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to end session")
+		return nil, ToRPCError(err)
+	}
+
+	message := fmt.Sprintf("Session %s ended with %d memories.", result.Session.SessionID, result.Session.MemoryCount)
+	if result.Purge != nil {
+		message = fmt.Sprintf("Session %s ended and %d of its memories were deleted.", result.Session.SessionID, result.Purge.Deleted)
+		if result.Purge.KeptCritical > 0 {
+			message += fmt.Sprintf(" %d critical memories were kept.", result.Purge.KeptCritical)
+		}
+	}
+
+	return SessionResponse{
+		Success: true,
+		Message: message,
+		Session: result.Session,
+		Purge:   result.Purge,
+	}, nil
+}
+
+// MemoryHistoryRequest represents the request structure for getting a memory's history
+type MemoryHistoryRequest struct {
+	ID uint `json:"id"`
+}
+
+// GetMemoryResponse represents the response with a single memory
+type GetMemoryResponse struct {
+	Success bool           `json:"success"`
+	Memory  *models.Memory `json:"memory,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// MemoryHistoryResponse represents the response with a memory's history
+type MemoryHistoryResponse struct {
+	Success bool                    `json:"success"`
+	History *services.MemoryHistory `json:"history,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// HandleMemoryHistory handles the memory history MCP tool call
+func (h *Handler) HandleMemoryHistory(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleMemoryHistory called")
+
+	var req MemoryHistoryRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse memory history request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.ID == 0 {
+		return nil, invalidParams("memory ID is required")
+	}
+
+	history, err := h.memoryService().GetMemoryHistory(ctx, req.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to get memory history")
+		return nil, ToRPCError(err)
+	}
+
+	return MemoryHistoryResponse{
+		Success: true,
+		History: history,
+	}, nil
+}
+
+// FeedbackMemoryRequest represents the request structure for rating a memory
+// returned by a search
+type FeedbackMemoryRequest struct {
+	ID       uint   `json:"id"`
+	Query    string `json:"query"`
+	Feedback string `json:"feedback"`
+}
+
+// FeedbackMemoryResponse represents the response after recording feedback
+type FeedbackMemoryResponse struct {
+	Success  bool                            `json:"success"`
+	Feedback *services.MemoryFeedbackSummary `json:"feedback,omitempty"`
+	Message  string                          `json:"message,omitempty"`
+}
+
+// HandleFeedbackMemory handles the feedback memory MCP tool call
+func (h *Handler) HandleFeedbackMemory(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleFeedbackMemory called")
+
+	var req FeedbackMemoryRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse feedback memory request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.ID == 0 {
+		return nil, invalidParams("memory ID is required")
+	}
+
+	summary, err := h.memoryService().RecordFeedback(ctx, req.ID, req.Query, req.Feedback)
+	if err != nil {
+		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to record memory feedback")
+		return nil, ToRPCError(err)
+	}
+
+	return FeedbackMemoryResponse{
+		Success:  true,
+		Feedback: summary,
+		Message:  fmt.Sprintf("Recorded memory %d as %s", req.ID, req.Feedback),
+	}, nil
+}
+
+// LinkMemoriesRequest represents the request structure for linking two memories
+type LinkMemoriesRequest struct {
+	SourceID uint   `json:"sourceId"`
+	TargetID uint   `json:"targetId"`
+	Relation string `json:"relation"`
+}
+
+// LinkMemoriesResponse represents the response after linking memories
+type LinkMemoriesResponse struct {
+	Success bool               `json:"success"`
+	Link    *models.MemoryLink `json:"link,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+// GetRelatedMemoriesRequest represents the request structure for following a
+// memory's links
+type GetRelatedMemoriesRequest struct {
+	ID        uint     `json:"id"`
+	Depth     int      `json:"depth,omitempty"`
+	Relations []string `json:"relations,omitempty"`
+}
+
+// GetRelatedMemoriesResponse represents the response with a memory's related memories
+type GetRelatedMemoriesResponse struct {
+	Success  bool                     `json:"success"`
+	MemoryID uint                     `json:"memory_id"`
+	Related  []services.RelatedMemory `json:"related"`
+	Count    int                      `json:"count"`
+}
+
+// HandleLinkMemories handles the link memories MCP tool call
+func (h *Handler) HandleLinkMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleLinkMemories called")
+
+	var req LinkMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse link memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.SourceID == 0 || req.TargetID == 0 {
+		return nil, invalidParams("sourceId and targetId are required")
+	}
+
+	link, err := h.memoryService().LinkMemories(ctx, req.SourceID, req.TargetID, req.Relation)
+	if err != nil {
+		h.logger.Error().Err(err).Uint("source_id", req.SourceID).Uint("target_id", req.TargetID).Msg("failed to link memories")
+		return nil, ToRPCError(err)
+	}
+
+	return LinkMemoriesResponse{
+		Success: true,
+		Link:    link,
+		Message: fmt.Sprintf("Memory %d %s memory %d", link.SourceID, link.Relation, link.TargetID),
+	}, nil
+}
+
+// HandleGetRelatedMemories handles the get related memories MCP tool call
+func (h *Handler) HandleGetRelatedMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleGetRelatedMemories called")
+
+	var req GetRelatedMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse get related memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.ID == 0 {
+		return nil, invalidParams("memory ID is required")
+	}
+
+	related, err := h.memoryService().GetRelatedMemories(ctx, req.ID, services.RelatedRequest{
+		Depth:     req.Depth,
+		Relations: req.Relations,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Uint("id", req.ID).Msg("failed to get related memories")
+		return nil, ToRPCError(err)
+	}
+
+	return GetRelatedMemoriesResponse{
+		Success:  true,
+		MemoryID: req.ID,
+		Related:  related,
+		Count:    len(related),
+	}, nil
+}
+
+// RecallRecentRequest represents the request structure for recalling recently
+// used memories
+type RecallRecentRequest struct {
+	By       string `json:"by,omitempty"`
+	Category string `json:"category,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// RecallRecentResponse represents the response with recently used memories
+type RecallRecentResponse struct {
+	Success  bool             `json:"success"`
+	By       string           `json:"by"`
+	Memories []*models.Memory `json:"memories"`
+	Count    int              `json:"count"`
+}
+
+// HandleRecallRecent handles the recall recent MCP tool call
+func (h *Handler) HandleRecallRecent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleRecallRecent called")
+
+	var req RecallRecentRequest
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &req); err != nil {
+			h.logger.Error().Err(err).Msg("failed to parse recall recent request")
+			return nil, invalidParams("invalid request format: %v", err)
+		}
+	}
+	if req.By == "" {
+		req.By = services.RecallByRecent
+	}
+	profile := clientProfileFrom(ctx)
+
+	memories, err := h.memoryService().RecallRecent(ctx, services.RecallRequest{
+		By:       req.By,
+		Category: req.Category,
+		Type:     req.Type,
+		Limit:    profile.limit(req.Limit, 0),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Str("by", req.By).Msg("failed to recall recent memories")
+		return nil, ToRPCError(err)
+	}
+	if memories == nil {
+		memories = []*models.Memory{}
+	}
+
+	return RecallRecentResponse{
+		Success:  true,
+		By:       req.By,
+		Memories: profile.shapeMemories(memories),
+		Count:    len(memories),
+	}, nil
+}
+
+// ProcessContentRequest represents the request structure for scanning a
+// conversation transcript for memories
+type ProcessContentRequest struct {
+	Content       string  `json:"content"`
+	MinConfidence float64 `json:"minConfidence,omitempty"`
+	DryRun        bool    `json:"dryRun,omitempty"`
+}
+
+// ProcessContentResponse represents the response with the memories captured from
+// a transcript
+type ProcessContentResponse struct {
+	Success bool `json:"success"`
+	*services.ProcessContentResult
+	Count   int    `json:"count"`
+	Message string `json:"message,omitempty"`
+}
+
+// HandleProcessContent handles the process content MCP tool call
+func (h *Handler) HandleProcessContent(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().Int("params_size", len(params)).Msg("handleProcessContent called")
+
+	var req ProcessContentRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse process content request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.Content == "" {
+		return nil, invalidParams("content is required")
+	}
+
+	result, err := h.memoryService().ProcessContent(ctx, services.ProcessContentRequest{
+		Content:       req.Content,
+		MinConfidence: req.MinConfidence,
+		DryRun:        req.DryRun,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to process content")
+		return nil, ToRPCError(err)
+	}
+
+	message := fmt.Sprintf("Captured %d memories from %d sentences", len(result.Captured), result.Sentences)
+	if result.DryRun {
+		message = fmt.Sprintf("Would capture %d memories from %d sentences", len(result.Captured), result.Sentences)
+	}
+	return ProcessContentResponse{
+		Success:              true,
+		ProcessContentResult: result,
+		Count:                len(result.Captured),
+		Message:              message,
+	}, nil
+}
+
+// ConsolidateMemoriesRequest represents the request structure for consolidating
+// related memories
+type ConsolidateMemoriesRequest struct {
+	MemoryIDs   []uint `json:"memoryIds,omitempty"`
+	MaxClusters int    `json:"maxClusters,omitempty"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+}
+
+// ConsolidateMemoriesResponse represents the response with the clusters found and
+// the summaries written
+type ConsolidateMemoriesResponse struct {
+	Success bool `json:"success"`
+	*services.ConsolidateResult
+	Message string `json:"message,omitempty"`
+}
+
+// HandleConsolidateMemories handles the consolidate memories MCP tool call
+func (h *Handler) HandleConsolidateMemories(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	h.logger.Debug().RawJSON("params", params).Msg("handleConsolidateMemories called")
+
+	var req ConsolidateMemoriesRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		h.logger.Error().Err(err).Msg("failed to parse consolidate memories request")
+		return nil, invalidParams("invalid request format: %v", err)
+	}
+	if req.MaxClusters < 0 {
+		return nil, invalidParams("maxClusters must not be negative")
+	}
+
+	result, err := h.memoryService().Consolidate(ctx, services.ConsolidateRequest{
+		MemoryIDs:   req.MemoryIDs,
+		MaxClusters: req.MaxClusters,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to consolidate memories")
+		return nil, ToRPCError(err)
+	}
+
+	message := fmt.Sprintf("Consolidated %d of %d clusters", result.Consolidated, len(result.Clusters))
+	if result.DryRun {
+		message = fmt.Sprintf("Found %d clusters to consolidate", len(result.Clusters))
+	}
+	return ConsolidateMemoriesResponse{
+		Success:           true,
+		ConsolidateResult: result,
+		Message:           message,
+	}, nil
+}
+
 // ToJSON methods for request types
 
 // ToJSON converts the request to JSON
@@ -0,0 +1,224 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+)
+
+// Prompt names
+const (
+	PromptStoreFact     = "store_fact"
+	PromptRecallContext = "recall_context"
+	PromptWeeklyReview  = "weekly_review"
+	PromptDedupeReview  = "dedupe_review"
+)
+
+const (
+	defaultRecallContextLimit = 10
+	maxRecallContextLimit     = 50
+	defaultReviewDays         = 7
+	maxReviewDays             = 90
+	// maxReviewMemories caps the memories a weekly review lists
+	maxReviewMemories        = 200
+	defaultDedupeReviewLimit = 20
+	maxDedupeReviewLimit     = 100
+)
+
+// Prompts returns the MCP prompts. Apart from store_fact they are filled in from
+// the user's memories when requested.
+func Prompts() []mcp.Prompt {
+	return []mcp.Prompt{
+		{
+			Name:        PromptStoreFact,
+			Description: "Template for storing a factual memory",
+			Arguments: []mcp.PromptArgument{
+				{Name: "fact", Description: "The fact to store", Required: true},
+				{Name: "category", Description: "Category for the fact", Required: false},
+			},
+		},
+		{
+			Name:        PromptRecallContext,
+			Description: "Bring the memories relevant to a topic into the conversation as background",
+			Arguments: []mcp.PromptArgument{
+				{Name: "topic", Description: "What the conversation is about", Required: true},
+				{Name: "limit", Description: fmt.Sprintf("Most memories to include (default: %d, max: %d)", defaultRecallContextLimit, maxRecallContextLimit)},
+			},
+		},
+		{
+			Name:        PromptWeeklyReview,
+			Description: "Summarize the memories stored recently: decisions, new preferences and open items",
+			Arguments: []mcp.PromptArgument{
+				{Name: "days", Description: fmt.Sprintf("How many days back to review (default: %d, max: %d)", defaultReviewDays, maxReviewDays)},
+			},
+		},
+		{
+			Name:        PromptDedupeReview,
+			Description: "Go through memories that repeat another one and decide which to merge or delete",
+			Arguments: []mcp.PromptArgument{
+				{Name: "limit", Description: fmt.Sprintf("Most duplicate pairs to review (default: %d, max: %d)", defaultDedupeReviewLimit, maxDedupeReviewLimit)},
+			},
+		},
+	}
+}
+
+// GetPrompt fills in a prompt from the memories of the service's user
+func GetPrompt(ctx context.Context, memoryService *services.MemoryService, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	switch name {
+	case PromptStoreFact:
+		return storeFactPrompt(args), nil
+	case PromptRecallContext:
+		return recallContextPrompt(ctx, memoryService, args)
+	case PromptWeeklyReview:
+		return weeklyReviewPrompt(ctx, memoryService, args)
+	case PromptDedupeReview:
+		return dedupeReviewPrompt(ctx, memoryService, args)
+	default:
+		return nil, invalidParams("unknown prompt: %s", name)
+	}
+}
+
+func storeFactPrompt(args map[string]string) *mcp.GetPromptResult {
+	category := "personal"
+	if c, ok := args["category"]; ok {
+		category = c
+	}
+	return userPrompt("", fmt.Sprintf("Store this fact in the %s category: %s", category, args["fact"]))
+}
+
+func recallContextPrompt(ctx context.Context, memoryService *services.MemoryService, args map[string]string) (*mcp.GetPromptResult, error) {
+	topic := strings.TrimSpace(args["topic"])
+	if topic == "" {
+		return nil, invalidParams("topic is required")
+	}
+	limit, err := promptIntArgument(args, "limit", defaultRecallContextLimit, maxRecallContextLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	memories, err := memoryService.Search(ctx, services.SearchRequest{
+		Query: topic,
+		Limit: limit,
+		Mode:  services.SearchModeHybrid,
+	})
+	if err != nil {
+		return nil, ToRPCError(err)
+	}
+
+	var text strings.Builder
+	if len(memories) == 0 {
+		fmt.Fprintf(&text, "I have no stored memories about %q. Ask me for any background you need.", topic)
+	} else {
+		fmt.Fprintf(&text, "Here is what you remember about %q. Use it as background for what follows, and prefer newer memories where they disagree.\n\n", topic)
+		writePromptMemories(&text, memories)
+	}
+	return userPrompt(fmt.Sprintf("Memories about %s", topic), text.String()), nil
+}
+
+func weeklyReviewPrompt(ctx context.Context, memoryService *services.MemoryService, args map[string]string) (*mcp.GetPromptResult, error) {
+	days, err := promptIntArgument(args, "days", defaultReviewDays, maxReviewDays)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	memories, err := memoryService.List(ctx, services.ListRequest{
+		Limit:     maxReviewMemories,
+		DateRange: services.DateRange{CreatedAfter: &since},
+	})
+	if err != nil {
+		return nil, ToRPCError(err)
+	}
+
+	var text strings.Builder
+	if len(memories) == 0 {
+		fmt.Fprintf(&text, "No memories were stored in the last %d days.", days)
+	} else {
+		fmt.Fprintf(&text, "Summarize what I stored in the last %d days. Group related memories, call out decisions, new preferences and anything still open, and point out memories that look outdated or contradict each other.\n\n", days)
+		fmt.Fprintf(&text, "Memories stored since %s (%d", since.UTC().Format(time.DateOnly), len(memories))
+		if len(memories) == maxReviewMemories {
+			text.WriteString(", the most recent only")
+		}
+		text.WriteString("):\n")
+		writePromptMemories(&text, memories)
+	}
+	return userPrompt(fmt.Sprintf("Review of the last %d days", days), text.String()), nil
+}
+
+func dedupeReviewPrompt(ctx context.Context, memoryService *services.MemoryService, args map[string]string) (*mcp.GetPromptResult, error) {
+	limit, err := promptIntArgument(args, "limit", defaultDedupeReviewLimit, maxDedupeReviewLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := memoryService.FindDuplicates(ctx, limit)
+	if err != nil {
+		return nil, ToRPCError(err)
+	}
+
+	var text strings.Builder
+	if len(pairs) == 0 {
+		text.WriteString("No duplicate memories were found.")
+	} else {
+		text.WriteString("These memories look like duplicates. For each pair, say whether they really are the same; if so, suggest which to keep and what to carry over, and once I confirm, delete the duplicate with delete_memory or fold it in with update_memory.\n\n")
+		for i, pair := range pairs {
+			fmt.Fprintf(&text, "%d. Similarity %.2f\n", i+1, pair.Similarity)
+			fmt.Fprintf(&text, "   keep:      %s\n", formatPromptMemory(pair.Keep))
+			fmt.Fprintf(&text, "   duplicate: %s\n", formatPromptMemory(pair.Duplicate))
+		}
+	}
+	return userPrompt("Duplicate memories to review", text.String()), nil
+}
+
+// userPrompt is a prompt of a single user message
+func userPrompt(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []mcp.PromptMessage{
+			{
+				Role: mcp.RoleUser,
+				Content: mcp.TextContent{
+					Type: "text",
+					Text: text,
+				},
+			},
+		},
+	}
+}
+
+// promptIntArgument parses an optional positive integer argument, capped at max
+func promptIntArgument(args map[string]string, name string, fallback, max int) (int, error) {
+	raw := strings.TrimSpace(args[name])
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		return 0, invalidParams("%s must be a positive integer", name)
+	}
+	return min(value, max), nil
+}
+
+func writePromptMemories(text *strings.Builder, memories []*models.Memory) {
+	for _, memory := range memories {
+		fmt.Fprintf(text, "- %s\n", formatPromptMemory(memory))
+	}
+}
+
+// formatPromptMemory renders a memory on one line with its ID, so the model can
+// refer to it in tool calls
+func formatPromptMemory(memory *models.Memory) string {
+	line := fmt.Sprintf("[#%d %s/%s, %s] %s", memory.ID, memory.Category, memory.Type,
+		memory.CreatedAt.UTC().Format(time.DateOnly), strings.Join(strings.Fields(memory.Content), " "))
+	if len(memory.Tags) > 0 {
+		line += fmt.Sprintf(" (tags: %s)", strings.Join(memory.Tags, ", "))
+	}
+	return line
+}
@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// promptText returns the text of a single-message prompt
+func promptText(t *testing.T, result *mcpgo.GetPromptResult) string {
+	t.Helper()
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, mcpgo.RoleUser, result.Messages[0].Role)
+	content, ok := result.Messages[0].Content.(mcpgo.TextContent)
+	require.True(t, ok)
+	return content.Text
+}
+
+func TestServer_RegistersPrompts(t *testing.T) {
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), nil)
+	s, err := NewServer(memoryService, zerolog.Nop())
+	require.NoError(t, err)
+
+	response := s.mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`))
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var listed struct {
+		Result struct {
+			Prompts []struct {
+				Name string `json:"name"`
+			} `json:"prompts"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &listed))
+
+	var names []string
+	for _, prompt := range listed.Result.Prompts {
+		names = append(names, prompt.Name)
+	}
+	assert.ElementsMatch(t, []string{PromptStoreFact, PromptRecallContext, PromptWeeklyReview, PromptDedupeReview}, names)
+}
+
+func TestGetPrompt(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.SQLiteDB(t, &models.MemoryFeedback{})
+	memoryService := services.NewMemoryService(db, nil, zerolog.Nop(), nil)
+
+	deploys := testutil.NewMemory().Content("Deploys go out on Tuesdays").Category(models.CategoryProject).Create(t, db)
+	testutil.NewMemory().Content("Prefers tea over coffee").CreatedAt(time.Now().AddDate(0, 0, -30)).Create(t, db)
+	original := testutil.NewMemory().Content("Lives in Lisbon").CreatedAt(time.Now().AddDate(0, 0, -40)).Create(t, db)
+	duplicate := testutil.NewMemory().Content("Lives in Lisbon").CreatedAt(time.Now().AddDate(0, 0, -2)).Create(t, db)
+
+	t.Run("store_fact", func(t *testing.T) {
+		result, err := GetPrompt(ctx, memoryService, PromptStoreFact, map[string]string{"fact": "Likes jazz"})
+		require.NoError(t, err)
+		assert.Equal(t, "Store this fact in the personal category: Likes jazz", promptText(t, result))
+	})
+
+	t.Run("recall_context", func(t *testing.T) {
+		result, err := GetPrompt(ctx, memoryService, PromptRecallContext, map[string]string{"topic": "deploys"})
+		require.NoError(t, err)
+		text := promptText(t, result)
+		assert.Contains(t, text, "Deploys go out on Tuesdays")
+		assert.Contains(t, text, "#"+memoryIDString(deploys.ID)+" project/fact")
+		assert.NotContains(t, text, "tea")
+
+		result, err = GetPrompt(ctx, memoryService, PromptRecallContext, map[string]string{"topic": "sailing"})
+		require.NoError(t, err)
+		assert.Contains(t, promptText(t, result), "no stored memories")
+
+		_, err = GetPrompt(ctx, memoryService, PromptRecallContext, map[string]string{})
+		var rpcErr *utils.MCPError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, utils.MCPCodeInvalidParams, rpcErr.Code)
+	})
+
+	t.Run("weekly_review", func(t *testing.T) {
+		result, err := GetPrompt(ctx, memoryService, PromptWeeklyReview, nil)
+		require.NoError(t, err)
+		text := promptText(t, result)
+		assert.Contains(t, text, "last 7 days")
+		assert.Contains(t, text, "Deploys go out on Tuesdays")
+		assert.Contains(t, text, "#"+memoryIDString(duplicate.ID))
+		assert.NotContains(t, text, "tea")
+
+		result, err = GetPrompt(ctx, memoryService, PromptWeeklyReview, map[string]string{"days": "35"})
+		require.NoError(t, err)
+		assert.Contains(t, promptText(t, result), "tea")
+
+		_, err = GetPrompt(ctx, memoryService, PromptWeeklyReview, map[string]string{"days": "-1"})
+		var rpcErr *utils.MCPError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, utils.MCPCodeInvalidParams, rpcErr.Code)
+	})
+
+	t.Run("dedupe_review", func(t *testing.T) {
+		result, err := GetPrompt(ctx, memoryService, PromptDedupeReview, nil)
+		require.NoError(t, err)
+		text := promptText(t, result)
+		assert.Contains(t, text, "keep:      [#"+memoryIDString(original.ID))
+		assert.Contains(t, text, "duplicate: [#"+memoryIDString(duplicate.ID))
+		assert.NotContains(t, text, "Deploys")
+	})
+
+	t.Run("unknown prompt", func(t *testing.T) {
+		_, err := GetPrompt(ctx, memoryService, "nope", nil)
+		var rpcErr *utils.MCPError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, utils.MCPCodeInvalidParams, rpcErr.Code)
+	})
+}
+
+func memoryIDString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
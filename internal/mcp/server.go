@@ -3,13 +3,17 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog"
 
+	"github.com/ksred/remember-me-mcp/internal/config"
 	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
 )
 
 // Server wraps the MCP server with our application logic
@@ -17,26 +21,50 @@ type Server struct {
 	mcpServer *server.MCPServer
 	handler   *Handler
 	logger    zerolog.Logger
+	timeouts  config.Timeouts
+	profiles  []config.ClientProfile
+	// client is the profile of the client that initialized the session, if any
+	// profile matched it
+	client atomic.Pointer[ClientProfile]
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTimeouts bounds each tool call by the deadline configured for its tool
+func WithTimeouts(timeouts config.Timeouts) Option {
+	return func(s *Server) {
+		s.timeouts = timeouts
+	}
+}
+
+// WithClientProfiles adapts responses to the client that initializes the session
+func WithClientProfiles(profiles []config.ClientProfile) Option {
+	return func(s *Server) {
+		s.profiles = profiles
+	}
 }
 
 // NewServer creates a new MCP server instance
-func NewServer(memoryService *services.MemoryService, logger zerolog.Logger) (*Server, error) {
+func NewServer(memoryService *services.MemoryService, logger zerolog.Logger, opts ...Option) (*Server, error) {
+	s := &Server{
+		handler: NewHandler(memoryService, logger),
+		logger:  logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	// Create the MCP server
-	mcpServer := server.NewMCPServer(
+	s.mcpServer = server.NewMCPServer(
 		"remember-me",
 		"1.0.0",
 		server.WithLogging(),
+		server.WithToolHandlerMiddleware(toolTracingMiddleware),
+		server.WithToolHandlerMiddleware(s.toolTimeoutMiddleware),
+		server.WithHooks(s.clientProfileHooks()),
 	)
 
-	// Create handler
-	handler := NewHandler(memoryService, logger)
-
-	s := &Server{
-		mcpServer: mcpServer,
-		handler:   handler,
-		logger:    logger,
-	}
-
 	// Register handlers
 	s.registerTools()
 	s.registerResources()
@@ -82,10 +110,239 @@ func (s *Server) registerTools() {
 					"type":        "object",
 					"description": "Optional metadata for the memory",
 				},
+				"on_duplicate": map[string]interface{}{
+					"type":        "string",
+					"description": "What to do when the memory is nearly the same as an existing one: merge into it, update it, reject the store, or allow a new memory (default: server setting)",
+					"enum":        []string{"merge", "update", "reject", "allow"},
+				},
+				"session_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Working session to capture the memory in (default: the session opened with start_session, if any)",
+				},
 			},
 			Required: []string{"type", "category", "content"},
 		},
-	}, s.createStoreMemoryHandler())
+	}, s.createToolHandler("store_memory", s.handler.HandleStoreMemory))
+
+	// Bulk store tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "store_memories_bulk",
+		Description: "Store multiple memories at once. Use when the user wants to remember multiple things in a single request.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"memories": map[string]interface{}{
+					"type":        "array",
+					"description": "Array of memories to store",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"type": map[string]interface{}{
+								"type":        "string",
+								"description": "Type of memory: fact, conversation, context, or preference",
+								"enum":        []string{"fact", "conversation", "context", "preference"},
+							},
+							"category": map[string]interface{}{
+								"type":        "string",
+								"description": "Category of memory: personal, project, or business",
+								"enum":        []string{"personal", "project", "business"},
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "The content of the memory to store",
+							},
+							"tags": map[string]interface{}{
+								"type":        "array",
+								"description": "Optional tags to categorize the memory",
+								"items": map[string]interface{}{
+									"type": "string",
+								},
+							},
+							"metadata": map[string]interface{}{
+								"type":        "object",
+								"description": "Optional metadata for the memory",
+							},
+						},
+						"required": []string{"type", "category", "content"},
+					},
+				},
+			},
+			Required: []string{"memories"},
+		},
+	}, s.createToolHandler("store_memories_bulk", s.handler.HandleStoreMemoriesBulk))
+
+	// Update memory tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "update_memory",
+		Description: "Update an existing memory by ID. Provide only the fields you want to update.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory to update",
+					"minimum":     1,
+				},
+				"type": map[string]interface{}{
+					"type":        "string",
+					"description": "Type of memory: fact, conversation, context, or preference",
+					"enum":        []string{"fact", "conversation", "context", "preference"},
+				},
+				"category": map[string]interface{}{
+					"type":        "string",
+					"description": "Category of memory: personal, project, or business",
+					"enum":        []string{"personal", "project", "business"},
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The new content of the memory",
+				},
+				"priority": map[string]interface{}{
+					"type":        "string",
+					"description": "Priority level: low, medium, high, or critical. Higher priority memories rank above lower priority ones in search results.",
+					"enum":        []string{"low", "medium", "high", "critical"},
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Tags to categorize the memory",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"metadata": map[string]interface{}{
+					"type":        "object",
+					"description": "Metadata for the memory",
+				},
+			},
+			Required: []string{"id"},
+		},
+	}, s.createToolHandler("update_memory", s.handler.HandleUpdateMemory))
+
+	// Bulk update tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "update_memories_bulk",
+		Description: "Update several existing memories at once by ID, for example to re-tag or re-categorize a set found by a search. Provide only the fields to change for each. Each update succeeds or fails on its own and is reported in the results; set atomic to apply all of them or none.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"updates": map[string]interface{}{
+					"type":        "array",
+					"description": "Updates to apply, at most 100",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"id": map[string]interface{}{
+								"type":        "integer",
+								"description": "ID of the memory to update",
+								"minimum":     1,
+							},
+							"type": map[string]interface{}{
+								"type":        "string",
+								"description": "Type of memory: fact, conversation, context, or preference",
+								"enum":        []string{"fact", "conversation", "context", "preference"},
+							},
+							"category": map[string]interface{}{
+								"type":        "string",
+								"description": "Category of memory: personal, project, or business",
+								"enum":        []string{"personal", "project", "business"},
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "The new content of the memory",
+							},
+							"priority": map[string]interface{}{
+								"type":        "string",
+								"description": "Priority level: low, medium, high, or critical",
+								"enum":        []string{"low", "medium", "high", "critical"},
+							},
+							"tags": map[string]interface{}{
+								"type":        "array",
+								"description": "Tags to categorize the memory",
+								"items": map[string]interface{}{
+									"type": "string",
+								},
+							},
+							"metadata": map[string]interface{}{
+								"type":        "object",
+								"description": "Metadata for the memory",
+							},
+						},
+						"required": []string{"id"},
+					},
+				},
+				"atomic": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Apply every update in one transaction, so if any fails none are applied (default false)",
+				},
+			},
+			Required: []string{"updates"},
+		},
+	}, s.createToolHandler("update_memories_bulk", s.handler.HandleUpdateMemoriesBulk))
+
+	// Get memory tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "get_memory",
+		Description: "Get one memory by ID with its tags, metadata, priority and timestamps. Use when you have a memory's ID, for example from a search, and need the complete record.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory",
+					"minimum":     1,
+				},
+			},
+			Required: []string{"id"},
+		},
+	}, s.createToolHandler("get_memory", s.handler.HandleGetMemory))
+
+	// Export tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "export_memories",
+		Description: "Export all of the user's memories as a portable archive, for backing up or moving to another server. Only use when the user asks for an export.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"include_embeddings": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include each memory's embedding (base64 float32 array with model name) so a server using the same model can skip re-embedding (default: false)",
+				},
+				"keep_encrypted": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Export encrypted memories as their encrypted payload instead of decrypted content; only a server sharing this server's encryption key can import them (default: false)",
+				},
+				"anonymize": map[string]interface{}{
+					"type":        "string",
+					"description": "Replace personal information with stable pseudonyms so the export can be shared: \"true\" for all, or a comma-separated list of emails, phones, names, companies",
+				},
+				"anonymize_terms": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Further words or phrases to pseudonymize, such as project names",
+				},
+			},
+		},
+	}, s.createToolHandler("export_memories", s.handler.HandleExportMemories))
+
+	// Import tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "import_memories",
+		Description: "Import a memory archive produced by export_memories. Memories the user already has are skipped; embeddings are reused when they come from the same model.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"archive": map[string]interface{}{
+					"type":        "object",
+					"description": "The archive returned by export_memories",
+				},
+				"allow_cross_region": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Allow importing an archive exported from a different residency region",
+				},
+			},
+			Required: []string{"archive"},
+		},
+	}, s.createToolHandler("import_memories", s.handler.HandleImportMemories))
 
 	// Search memories tool
 	s.mcpServer.AddTool(mcp.Tool{
@@ -118,15 +375,102 @@ func (s *Server) registerTools() {
 					"type":        "boolean",
 					"description": "Use semantic search (default: true)",
 				},
+				"metadataQuery": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter on memory metadata. Either a JSON object matched by containment, e.g. {\"source\":\"slack\"}, or a JSONPath expression starting with $, e.g. $.scores[0] > 0.9 or $.ticket (matches memories where the path exists). At most 1024 characters.",
+					"maxLength":   1024,
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"description": "Filter by tags. Returns memories with any of the tags, or all of them when tagMatch is all",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+				"tagMatch": map[string]interface{}{
+					"type":        "string",
+					"description": "How tags are matched: any (default) or all",
+					"enum":        []string{"any", "all"},
+				},
+				"searchMode": map[string]interface{}{
+					"type":        "string",
+					"description": "keyword (substring match), semantic (embedding similarity, the default when a query is given) or hybrid (full-text and semantic results fused by reciprocal rank; best for names and exact terms)",
+					"enum":        []string{"keyword", "semantic", "hybrid"},
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of results to skip (default: 0)",
+					"minimum":     0,
+					"maximum":     10000,
+				},
+				"cursor": map[string]interface{}{
+					"type":        "string",
+					"description": "next_cursor from a previous response, to fetch the next page with the same query and filters",
+				},
+				"includeContext": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also search the session's short-term conversation buffer; matching turns are returned in context",
+				},
+				"sessionId": map[string]interface{}{
+					"type":        "string",
+					"description": "Session whose buffer includeContext searches (default: default)",
+				},
+				"includeRelated": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also return the memories linked to the results (see link_memories) in related, so context chains can be followed",
+				},
+				"relatedDepth": map[string]interface{}{
+					"type":        "integer",
+					"description": "How many links to follow from the results with includeRelated (default: 1, max: 3)",
+					"minimum":     1,
+					"maximum":     3,
+				},
+				"withinIds": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "integer"},
+					"description": "Only search these memory IDs, to drill into earlier results",
+				},
+				"refineCursor": map[string]interface{}{
+					"type":        "string",
+					"description": "refine_cursor from a previous search response; only its memories are searched",
+				},
+				"createdAfter": map[string]interface{}{
+					"type":        "string",
+					"description": "Only memories created at or after this time: an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC), e.g. the first day of last month",
+				},
+				"createdBefore": map[string]interface{}{
+					"type":        "string",
+					"description": "Only memories created before this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"updatedAfter": map[string]interface{}{
+					"type":        "string",
+					"description": "Only memories last updated at or after this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"updatedBefore": map[string]interface{}{
+					"type":        "string",
+					"description": "Only memories last updated before this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"priorities": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high", "critical"}},
+					"description": "Only memories of these priorities",
+				},
+				"session": map[string]interface{}{
+					"type":        "string",
+					"description": "Only memories captured in this working session; current means the open one",
+				},
+				"minPriority": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"low", "medium", "high", "critical"},
+					"description": "Only memories of at least this priority, e.g. high for high and critical memories",
+				},
 			},
 			Required: []string{"query"},
 		},
-	}, s.createSearchMemoriesHandler())
+	}, s.createToolHandler("search_memories", s.handler.HandleSearchMemories))
 
 	// Delete memory tool
 	s.mcpServer.AddTool(mcp.Tool{
 		Name:        "delete_memory",
-		Description: "Delete a memory by ID",
+		Description: "Delete a memory by ID. Deleting a critical memory requires a second call with the single-use confirmation token returned by the first; only make it once the user agrees.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
@@ -135,12 +479,336 @@ func (s *Server) registerTools() {
 					"description": "ID of the memory to delete",
 					"minimum":     1,
 				},
+				"confirm": map[string]interface{}{
+					"type":        "string",
+					"description": "Confirmation token for deleting a critical memory. Only pass the token returned by a previous delete attempt, and only after the user has explicitly agreed to the deletion.",
+				},
 			},
 			Required: []string{"id"},
 		},
-	}, s.createDeleteMemoryHandler())
+	}, s.createToolHandler("delete_memory", s.handler.HandleDeleteMemory))
 
-	s.logger.Info().Int("count", 3).Msg("Registered MCP tools")
+	// Bulk delete tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "delete_memories",
+		Description: "Delete every memory matching filters, e.g. all memories about an old project. Always call with dryRun first: it returns how many memories match, a sample of them and a confirmation token. Show the user what would be deleted and only repeat the call with that token as confirm once they agree. Critical memories are skipped unless includeCritical is set.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"category": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"personal", "project", "business"},
+					"description": "Only delete memories of this category",
+				},
+				"type": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"fact", "conversation", "context", "preference"},
+					"description": "Only delete memories of this type",
+				},
+				"tags": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Only delete memories with any of these tags (all of them with tagMatch all)",
+				},
+				"tagMatch": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"any", "all"},
+					"description": "Whether memories need any (default) or all of the tags",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Only delete memories whose content contains this text, ignoring case",
+				},
+				"createdAfter": map[string]interface{}{
+					"type":        "string",
+					"description": "Only delete memories created at or after this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"createdBefore": map[string]interface{}{
+					"type":        "string",
+					"description": "Only delete memories created before this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"updatedAfter": map[string]interface{}{
+					"type":        "string",
+					"description": "Only delete memories updated at or after this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"updatedBefore": map[string]interface{}{
+					"type":        "string",
+					"description": "Only delete memories updated before this time (RFC 3339 or YYYY-MM-DD)",
+				},
+				"includeCritical": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also delete matching critical memories (default: false)",
+				},
+				"dryRun": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Count the matching memories and return a confirmation token without deleting anything",
+				},
+				"confirm": map[string]interface{}{
+					"type":        "string",
+					"description": "Confirmation token from a dry run with the same filters. Only pass it after the user has explicitly agreed to the deletion.",
+				},
+			},
+		},
+	}, s.createToolHandler("delete_memories", s.handler.HandleDeleteMemories))
+
+	// Incognito tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "incognito",
+		Description: "Pause remembering. Use when the user says 'go incognito', 'don't remember this', 'off the record' or similar. While incognito is on nothing is stored or auto-detected, and it turns off automatically when the duration ends. Tell the user when it will end.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"action": map[string]interface{}{
+					"type":        "string",
+					"description": "start turns incognito mode on, stop turns it off, status reports whether it is on and when it ends",
+					"enum":        []string{"start", "stop", "status"},
+				},
+				"duration": map[string]interface{}{
+					"type":        "string",
+					"description": "How long incognito mode lasts when starting, e.g. 30m or 2h, or a number of minutes (default 1h, at most 24h). It turns off by itself afterwards.",
+				},
+			},
+			Required: []string{"action"},
+		},
+	}, s.createToolHandler("incognito", s.handler.HandleIncognito))
+
+	// Working session tools
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "start_session",
+		Description: "Start a working session. Memories stored until end_session is called are captured in it, so they can later be recalled together (search with session \"current\" or the session ID) or purged together. Starting a session ends any session still open.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sessionId": map[string]interface{}{
+					"type":        "string",
+					"description": "ID for the session, e.g. the conversation ID (default: generated)",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "What the session is about, e.g. 'Q3 planning'",
+				},
+			},
+		},
+	}, s.createToolHandler("start_session", s.handler.HandleStartSession))
+
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "end_session",
+		Description: "End the working session started with start_session. With purge, the memories captured in it are deleted too, apart from critical ones; only purge when the user asks to forget the session.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"purge": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Delete the memories captured in the session (default false)",
+				},
+			},
+		},
+	}, s.createToolHandler("end_session", s.handler.HandleEndSession))
+
+	// Memory history tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "memory_history",
+		Description: "Show how a memory changed over time: its current version and the earlier versions it replaced, newest first. Use when the user asks what they used to prefer, when something changed, or how a remembered fact evolved.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory",
+					"minimum":     1,
+				},
+			},
+			Required: []string{"id"},
+		},
+	}, s.createToolHandler("memory_history", s.handler.HandleMemoryHistory))
+
+	// Append context tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "append_context",
+		Description: "Add a recent conversation turn to the session's short-term buffer. Buffered turns are working memory between tool calls: they expire after a short time and are never stored as memories, so use this for conversational context that is useful now but not worth remembering. Search with includeContext to find them.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The conversation turn to buffer",
+				},
+				"role": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"user", "assistant"},
+					"description": "Who said it (default: user)",
+				},
+				"sessionId": map[string]interface{}{
+					"type":        "string",
+					"description": "Conversation session the turn belongs to (default: default)",
+				},
+			},
+			Required: []string{"content"},
+		},
+	}, s.createToolHandler("append_context", s.handler.HandleAppendContext))
+
+	// Feedback tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "feedback_memory",
+		Description: "Tell the memory system whether a memory returned by search_memories was helpful or irrelevant for the query. Search learns from this: helpful memories rank higher in later searches and irrelevant ones lower. Use when the user confirms or dismisses a recalled memory, or when a result clearly did not answer the query.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory returned by the search",
+					"minimum":     1,
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "The search query the memory was returned for",
+				},
+				"feedback": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"helpful", "irrelevant"},
+					"description": "Whether the memory helped answer the query",
+				},
+			},
+			Required: []string{"id", "query", "feedback"},
+		},
+	}, s.createToolHandler("feedback_memory", s.handler.HandleFeedbackMemory))
+
+	// Memory link tools
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "link_memories",
+		Description: "Relate two memories so related context can be followed later: supersedes when the source replaces an outdated target, related_to for memories about the same thing, part_of_project when the source belongs to the project the target describes. Use when storing a memory that updates, extends or belongs with one you already found.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sourceId": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory the link starts from",
+					"minimum":     1,
+				},
+				"targetId": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory the link points to",
+					"minimum":     1,
+				},
+				"relation": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"supersedes", "related_to", "part_of_project"},
+					"description": "How the source relates to the target",
+				},
+			},
+			Required: []string{"sourceId", "targetId", "relation"},
+		},
+	}, s.createToolHandler("link_memories", s.handler.HandleLinkMemories))
+
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "get_related_memories",
+		Description: "Follow a memory's links in both directions to find the memories related to it: what superseded it, what it is part of and what else belongs with it. Use to gather the full context around a memory found by search.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "integer",
+					"description": "ID of the memory",
+					"minimum":     1,
+				},
+				"depth": map[string]interface{}{
+					"type":        "integer",
+					"description": "How many links to follow (default: 1, max: 3)",
+					"minimum":     1,
+					"maximum":     3,
+				},
+				"relations": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": []string{"supersedes", "related_to", "part_of_project"}},
+					"description": "Only follow these relations (default: all)",
+				},
+			},
+			Required: []string{"id"},
+		},
+	}, s.createToolHandler("get_related_memories", s.handler.HandleGetRelatedMemories))
+
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "recall_recent",
+		Description: "Recall the memories used lately: the ones searches and lookups returned most recently, or most often. Memories never returned are left out. Use at the start of a conversation to pick up where the user left off without a query.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"by": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"recent", "frequent"},
+					"description": "recent for the memories used most recently (default), frequent for the ones used most often",
+				},
+				"category": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"personal", "project", "business"},
+					"description": "Only recall memories of this category",
+				},
+				"type": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"fact", "conversation", "context", "preference"},
+					"description": "Only recall memories of this type",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum memories to return (default: 10, max: 100)",
+					"minimum":     1,
+					"maximum":     100,
+				},
+			},
+		},
+	}, s.createToolHandler("recall_recent", s.handler.HandleRecallRecent))
+
+	// Transcript capture tool
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "process_content",
+		Description: "Scan a conversation transcript for things worth remembering and store them. Each sentence the user said is checked for preferences, personal facts, decisions and explicit remember requests; lines labelled Assistant: or System: are ignored. Returns what was captured with confidence scores, skipping memories that already exist and updating ones with the same subject. Use at the end of a conversation, or with dryRun to preview.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The conversation transcript, optionally with User: and Assistant: labels on each turn",
+				},
+				"minConfidence": map[string]interface{}{
+					"type":        "number",
+					"description": "Detection confidence a sentence needs to be captured (default: 0.5)",
+					"minimum":     0,
+					"maximum":     1,
+				},
+				"dryRun": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Report what would be captured without storing anything (default: false)",
+				},
+			},
+			Required: []string{"content"},
+		},
+	}, s.createToolHandler("process_content", s.handler.HandleProcessContent))
+
+	s.mcpServer.AddTool(mcp.Tool{
+		Name:        "consolidate_memories",
+		Description: "Merge related memories into one summary memory written by a language model. Without memoryIds, clusters of similar memories are found automatically; with them, exactly those memories are merged. Each summary supersedes its originals, which are kept and can be traced from the summary's provenance. Use dryRun first to show the user what would be merged.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"memoryIds": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "integer"},
+					"description": "Merge exactly these memories (at least two) instead of finding clusters",
+				},
+				"maxClusters": map[string]interface{}{
+					"type":        "integer",
+					"description": "Most clusters to merge (default: 10)",
+					"minimum":     1,
+				},
+				"dryRun": map[string]interface{}{
+					"type":        "boolean",
+					"description": "List the clusters with their memories without merging anything",
+				},
+			},
+		},
+	}, s.createToolHandler("consolidate_memories", s.handler.HandleConsolidateMemories))
+
+	s.logger.Info().Int("count", 20).Msg("Registered MCP tools")
 }
 
 // registerResources registers MCP resources
@@ -153,207 +821,108 @@ func (s *Server) registerResources() {
 		MIMEType:    "application/json",
 	}, s.createMemoryStatsHandler())
 
-	s.logger.Info().Int("count", 1).Msg("Registered MCP resources")
+	// Every attachment can be read through its URI
+	s.mcpServer.AddResourceTemplate(AttachmentResourceTemplate(), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return ReadAttachment(ctx, s.handler.memoryService(), request.Params.URI)
+	})
+
+	s.logger.Info().Int("count", 2).Msg("Registered MCP resources")
 }
 
 // registerPrompts registers MCP prompts
 func (s *Server) registerPrompts() {
-	// Example prompt for memory storage
-	s.mcpServer.AddPrompt(mcp.Prompt{
-		Name:        "store_fact",
-		Description: "Template for storing a factual memory",
-		Arguments: []mcp.PromptArgument{
-			{
-				Name:        "fact",
-				Description: "The fact to store",
-				Required:    true,
-			},
-			{
-				Name:        "category",
-				Description: "Category for the fact",
-				Required:    false,
-			},
-		},
-	}, s.createStoreFactHandler())
+	prompts := Prompts()
+	for _, prompt := range prompts {
+		s.mcpServer.AddPrompt(prompt, s.createPromptHandler(prompt.Name))
+	}
 
-	s.logger.Info().Int("count", 1).Msg("Registered MCP prompts")
+	s.logger.Info().Int("count", len(prompts)).Msg("Registered MCP prompts")
 }
 
-// Handler creation functions for MCP tools
-
-func (s *Server) createStoreMemoryHandler() server.ToolHandlerFunc {
+// toolTracingMiddleware records a span for each tool call. Calls that return an
+// error result are marked failed like calls that return an error.
+func toolTracingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		s.logger.Debug().Msg("Store memory tool handler called")
-		
-		// Convert arguments to JSON for the handler
-		jsonData, err := json.Marshal(request.GetArguments())
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to parse arguments: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+		ctx, span := tracing.StartToolCall(ctx, request.Params.Name)
+		result, err := next(ctx, request)
+		failure := err
+		if failure == nil && result != nil && result.IsError {
+			failure = errors.New("tool returned an error result")
 		}
-
-		// Call the existing handler
-		result, err := s.handler.HandleStoreMemory(ctx, jsonData)
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Error: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
-		}
-
-		// Convert result to JSON string
-		response := result.(StoreMemoryResponse)
-		resultJSON, err := response.ToJSON()
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to marshal result: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
-		}
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				mcp.TextContent{
-					Type: "text",
-					Text: string(resultJSON),
-				},
-			},
-		}, nil
+		tracing.End(span, failure)
+		return result, err
 	}
 }
 
-func (s *Server) createSearchMemoriesHandler() server.ToolHandlerFunc {
+// toolTimeoutMiddleware gives each tool call the deadline configured for its tool
+// and the profile of the client, and marks the memory access it makes as MCP's
+func (s *Server) toolTimeoutMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Convert arguments to JSON for the handler
-		jsonData, err := json.Marshal(request.GetArguments())
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to parse arguments: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
-		}
-
-		// Call the existing handler
-		result, err := s.handler.HandleSearchMemories(ctx, jsonData)
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Error: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+		if timeout := s.timeouts.Tool(request.Params.Name); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
 		}
+		ctx = services.WithAccessSource(ctx, services.AccessSourceMCP)
+		return next(WithClientProfile(ctx, s.client.Load()), request)
+	}
+}
 
-		// Convert result to JSON string
-		response := result.(SearchMemoriesResponse)
-		resultJSON, err := response.ToJSON()
-		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to marshal result: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+// clientProfileHooks records the profile of the client that initializes the
+// session and shapes the tool list for it. The workspace the client selects is
+// applied before the initialize is answered.
+func (s *Server) clientProfileHooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnRequestInitialization(s.selectWorkspace)
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		client := message.Params.ClientInfo
+		profile := MatchClientProfile(s.profiles, client.Name, client.Version)
+		s.client.Store(profile)
+		event := s.logger.Info().Str("client", client.Name).Str("client_version", client.Version)
+		if profile != nil {
+			event = event.Str("profile", profile.Name)
 		}
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				mcp.TextContent{
-					Type: "text",
-					Text: string(resultJSON),
-				},
-			},
-		}, nil
-	}
+		event.Msg("MCP client initialized")
+	})
+	hooks.AddAfterListTools(func(ctx context.Context, id any, message *mcp.ListToolsRequest, result *mcp.ListToolsResult) {
+		result.Tools = s.client.Load().ShapeTools(WithFormatArgument(result.Tools))
+	})
+	return hooks
 }
 
-func (s *Server) createDeleteMemoryHandler() server.ToolHandlerFunc {
+// createToolHandler adapts a Handler method to an MCP tool handler, rendering
+// the result in the requested format and returning errors as tool results so
+// the client can show them
+func (s *Server) createToolHandler(name string, handle func(context.Context, json.RawMessage) (interface{}, error)) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Convert arguments to JSON for the handler
+		s.logger.Debug().Str("tool", name).Msg("Tool handler called")
+
 		jsonData, err := json.Marshal(request.GetArguments())
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to parse arguments: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse arguments: %v", err)), nil
 		}
 
-		// Call the existing handler
-		result, err := s.handler.HandleDeleteMemory(ctx, jsonData)
+		format, err := RequestedFormat(ctx, jsonData)
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Error: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
 		}
 
-		// Convert result to JSON string
-		response := result.(DeleteMemoryResponse)
-		resultJSON, err := response.ToJSON()
+		result, err := handle(ctx, jsonData)
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Failed to marshal result: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
 		}
 
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				mcp.TextContent{
-					Type: "text",
-					Text: string(resultJSON),
-				},
-			},
-		}, nil
+		toolResult, err := ToolResult(name, result, format)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+		return toolResult, nil
 	}
 }
 
 func (s *Server) createMemoryStatsHandler() server.ResourceHandlerFunc {
 	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		stats, err := s.handler.memoryService.GetMemoryStats(ctx)
+		stats, err := s.handler.memoryService().GetMemoryStats(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -373,28 +942,15 @@ func (s *Server) createMemoryStatsHandler() server.ResourceHandlerFunc {
 	}
 }
 
-func (s *Server) createStoreFactHandler() server.PromptHandlerFunc {
+// createPromptHandler fills in a prompt from the user's memories
+func (s *Server) createPromptHandler(name string) server.PromptHandlerFunc {
 	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
-		fact := ""
-		category := "personal"
-
-		if f, ok := request.Params.Arguments["fact"]; ok {
-			fact = f
-		}
-		if c, ok := request.Params.Arguments["category"]; ok {
-			category = c
+		ctx = services.WithAccessSource(ctx, services.AccessSourceMCP)
+		result, err := GetPrompt(ctx, s.handler.memoryService(), name, request.Params.Arguments)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("prompt", name).Msg("Failed to get prompt")
+			return nil, err
 		}
-
-		return &mcp.GetPromptResult{
-			Messages: []mcp.PromptMessage{
-				{
-					Role: "user",
-					Content: mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Store this fact in the %s category: %s", category, fact),
-					},
-				},
-			},
-		}, nil
+		return result, nil
 	}
-}
\ No newline at end of file
+}
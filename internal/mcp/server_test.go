@@ -1,10 +1,22 @@
 package mcp
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
 )
 
 func TestStoreMemoryRequest_Structure(t *testing.T) {
@@ -81,4 +93,114 @@ func TestMemoryResponse_ToJSON(t *testing.T) {
 	assert.Contains(t, jsonString, "\"success\":true")
 	assert.Contains(t, jsonString, "\"message\":\"Success\"")
 	assert.Contains(t, jsonString, "\"id\":1")
-}
\ No newline at end of file
+}
+func TestServer_RegistersFullToolSet(t *testing.T) {
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), nil)
+	s, err := NewServer(memoryService, zerolog.Nop())
+	require.NoError(t, err)
+
+	response := s.mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var listed struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &listed))
+
+	var names []string
+	for _, tool := range listed.Result.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"store_memory", "store_memories_bulk", "search_memories", "update_memory", "update_memories_bulk", "get_memory",
+		"delete_memory", "delete_memories", "export_memories", "import_memories", "incognito", "start_session", "end_session", "memory_history",
+		"append_context", "feedback_memory", "link_memories", "get_related_memories",
+		"recall_recent", "process_content", "consolidate_memories",
+	}, names)
+}
+
+func TestServer_ToolTimeouts(t *testing.T) {
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), nil)
+	s, err := NewServer(memoryService, zerolog.Nop(), WithTimeouts(config.Timeouts{
+		Default: time.Minute,
+		Tools:   map[string]time.Duration{"store_memories_bulk": 10 * time.Minute, "get_memory": 0},
+	}))
+	require.NoError(t, err)
+
+	deadlines := make(map[string]time.Duration)
+	record := s.toolTimeoutMiddleware(func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			deadlines[request.Params.Name] = time.Until(deadline)
+		}
+		return nil, nil
+	})
+	for _, name := range []string{"store_memories_bulk", "search_memories", "get_memory"} {
+		request := mcpgo.CallToolRequest{}
+		request.Params.Name = name
+		_, err := record(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	assert.InDelta(t, 10*time.Minute, deadlines["store_memories_bulk"], float64(time.Second))
+	assert.InDelta(t, time.Minute, deadlines["search_memories"], float64(time.Second))
+	assert.NotContains(t, deadlines, "get_memory")
+}
+
+func TestHandleGetMemory(t *testing.T) {
+	ctx := context.Background()
+	memoryService := services.NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), nil)
+	handler := NewHandler(memoryService, zerolog.Nop())
+
+	stored, err := memoryService.Store(ctx, services.StoreRequest{
+		Content:  "Deploys go out on Tuesdays",
+		Type:     models.TypeFact,
+		Category: models.CategoryProject,
+		Priority: models.PriorityHigh,
+		Metadata: map[string]interface{}{"team": "platform"},
+	})
+	require.NoError(t, err)
+
+	// IDs sent as strings are accepted like the other tools
+	result, err := handler.HandleGetMemory(ctx, json.RawMessage(fmt.Sprintf(`{"id": "%d"}`, stored.ID)))
+	require.NoError(t, err)
+	response := result.(GetMemoryResponse)
+	require.True(t, response.Success)
+	assert.Equal(t, "Deploys go out on Tuesdays", response.Memory.Content)
+	assert.Equal(t, models.PriorityHigh, response.Memory.Priority)
+	assert.JSONEq(t, `{"team": "platform"}`, string(response.Memory.Metadata))
+	assert.False(t, response.Memory.CreatedAt.IsZero())
+
+	_, err = handler.HandleGetMemory(ctx, json.RawMessage(`{"id": 9999}`))
+	var rpcErr *utils.MCPError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, utils.MCPCodeNotFound, rpcErr.Code)
+
+	_, err = handler.HandleGetMemory(ctx, json.RawMessage(`{}`))
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, utils.MCPCodeInvalidParams, rpcErr.Code)
+}
+
+func TestHandleSearchMemories_DateRange(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.SQLiteDB(t, &models.MemoryFeedback{})
+	handler := NewHandler(services.NewMemoryService(db, nil, zerolog.Nop(), nil), zerolog.Nop())
+
+	testutil.NewMemory().Content("budget approved").CreatedAt(time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)).Create(t, db)
+	march := testutil.NewMemory().Content("budget cut").CreatedAt(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)).Create(t, db)
+
+	result, err := handler.HandleSearchMemories(ctx, json.RawMessage(`{"query": "budget", "createdAfter": "2025-03-01", "createdBefore": "2025-04-01T00:00:00Z"}`))
+	require.NoError(t, err)
+	response := result.(SearchMemoriesResponse)
+	require.Len(t, response.Memories, 1)
+	assert.Equal(t, march.ID, response.Memories[0].ID)
+
+	_, err = handler.HandleSearchMemories(ctx, json.RawMessage(`{"query": "budget", "updatedBefore": "yesterday"}`))
+	var rpcErr *utils.MCPError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, utils.MCPCodeInvalidParams, rpcErr.Code)
+}
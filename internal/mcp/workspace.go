@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// WorkspaceCapability is the experimental client capability selecting the
+// workspace a session runs in, sent when initializing:
+//
+//	{"capabilities": {"experimental": {"workspace": "work"}}}
+//
+// Without it the session runs in the default workspace.
+const WorkspaceCapability = "workspace"
+
+// RequestedWorkspace returns the name of the workspace a client's experimental
+// capabilities select, or "" for the default workspace
+func RequestedWorkspace(experimental map[string]any) (string, error) {
+	value, ok := experimental[WorkspaceCapability]
+	if !ok || value == nil {
+		return "", nil
+	}
+	name, ok := value.(string)
+	if !ok {
+		return "", utils.InvalidFieldError("capabilities.experimental.workspace", "must be a workspace name")
+	}
+	return name, nil
+}
+
+// selectWorkspace confines the session to the workspace an initialize request
+// asks for. An unknown workspace fails the initialize, rather than letting the
+// session read and write another workspace's memories.
+func (s *Server) selectWorkspace(ctx context.Context, id any, message any) error {
+	raw, ok := message.(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	var request mcp.InitializeRequest
+	if err := json.Unmarshal(raw, &request); err != nil || request.Method != string(mcp.MethodInitialize) {
+		return nil
+	}
+
+	name, err := RequestedWorkspace(request.Params.Capabilities.Experimental)
+	if err != nil {
+		return err
+	}
+	workspaceID, err := s.handler.UseWorkspace(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to select workspace: %w", err)
+	}
+	if name != "" {
+		s.logger.Info().Str("workspace", name).Uint("workspace_id", workspaceID).Msg("MCP session workspace selected")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestRequestedWorkspace(t *testing.T) {
+	name, err := RequestedWorkspace(nil)
+	require.NoError(t, err)
+	assert.Empty(t, name)
+
+	name, err = RequestedWorkspace(map[string]any{"sampling": map[string]any{}})
+	require.NoError(t, err)
+	assert.Empty(t, name)
+
+	name, err = RequestedWorkspace(map[string]any{WorkspaceCapability: "work"})
+	require.NoError(t, err)
+	assert.Equal(t, "work", name)
+
+	_, err = RequestedWorkspace(map[string]any{WorkspaceCapability: 7})
+	assert.True(t, utils.IsValidationError(err))
+}
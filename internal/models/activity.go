@@ -2,21 +2,24 @@ package models
 
 import (
 	"encoding/json"
-	"time"
 	"gorm.io/gorm"
+	"time"
 )
 
 // ActivityLog represents user activity tracking
 type ActivityLog struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	UserID    uint           `gorm:"not null;index" json:"user_id"`
-	User      User           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
-	Type      string         `gorm:"not null;index" json:"type"` // memory_stored, memory_search, memory_deleted, api_key_created, login
-	Details   json.RawMessage `gorm:"type:jsonb" json:"details,omitempty" swaggertype:"object"`
-	IPAddress string         `gorm:"type:inet" json:"ip_address,omitempty"`
-	UserAgent string         `gorm:"type:text" json:"user_agent,omitempty"`
-	CreatedAt time.Time      `gorm:"index" json:"timestamp"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	UserID      uint            `gorm:"not null;index" json:"user_id"`
+	User        User            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Type        string          `gorm:"not null;index" json:"type"` // memory_stored, memory_search, memory_deleted, api_key_created, login
+	Details     json.RawMessage `gorm:"type:jsonb" json:"details,omitempty" swaggertype:"object"`
+	IPAddress   string          `gorm:"type:inet" json:"ip_address,omitempty"`
+	UserAgent   string          `gorm:"type:text" json:"user_agent,omitempty"`
+	CountryCode string          `gorm:"size:2;index" json:"country_code,omitempty"`
+	Country     string          `json:"country,omitempty"`
+	City        string          `json:"city,omitempty"`
+	CreatedAt   time.Time       `gorm:"index" json:"timestamp"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
 // GetDetailsMap unmarshals the Details JSON into a map
@@ -24,7 +27,7 @@ func (a *ActivityLog) GetDetailsMap() (map[string]interface{}, error) {
 	if a.Details == nil || len(a.Details) == 0 {
 		return nil, nil
 	}
-	
+
 	var details map[string]interface{}
 	if err := json.Unmarshal(a.Details, &details); err != nil {
 		return nil, err
@@ -38,7 +41,7 @@ func (a *ActivityLog) SetDetailsFromMap(details map[string]interface{}) error {
 		a.Details = nil
 		return nil
 	}
-	
+
 	data, err := json.Marshal(details)
 	if err != nil {
 		return err
@@ -68,10 +71,41 @@ func (PerformanceMetric) TableName() string {
 
 // Activity type constants
 const (
-	ActivityMemoryStored  = "memory_stored"
-	ActivityMemorySearch  = "memory_search"
-	ActivityMemoryDeleted = "memory_deleted"
-	ActivityAPIKeyCreated = "api_key_created"
-	ActivityAPIKeyDeleted = "api_key_deleted"
-	ActivityLogin         = "login"
-)
\ No newline at end of file
+	ActivityMemoryStored     = "memory_stored"
+	ActivityMemorySearch     = "memory_search"
+	ActivityMemoryDeleted    = "memory_deleted"
+	ActivityMemoriesDeleted  = "memories_deleted"
+	ActivityAPIKeyCreated    = "api_key_created"
+	ActivityAPIKeyDeleted    = "api_key_deleted"
+	ActivityLogin            = "login"
+	ActivityLogout           = "logout"
+	ActivitySnapshotCreated  = "snapshot_created"
+	ActivitySnapshotRestored = "snapshot_restored"
+	ActivityConfigImported   = "config_imported"
+	ActivityMemoryEvicted    = "memory_evicted"
+	ActivityMemoriesExported = "memories_exported"
+	ActivityMemoriesImported = "memories_imported"
+	ActivityContentModerated = "content_moderated"
+	// ActivityMemoriesRecategorized records categorization rules run over
+	// existing memories
+	ActivityMemoriesRecategorized = "memories_recategorized"
+	// ActivityMemoriesConsolidated records clusters of memories merged into
+	// summary memories
+	ActivityMemoriesConsolidated = "memories_consolidated"
+
+	ActivitySupportAccessGranted = "support_access_granted"
+	ActivitySupportAccessUsed    = "support_access_used"
+	// ActivitySupportImpersonation records an admin searching a user's memories
+	// as that user under a support grant
+	ActivitySupportImpersonation = "support_impersonation"
+
+	// ActivityAccountChanged records an admin disabling, enabling, resetting the
+	// password of or changing the role of an account
+	ActivityAccountChanged = "account_changed"
+	// ActivityAccountExported records the user downloading all their account data
+	ActivityAccountExported = "account_exported"
+
+	// ActivityMaintenanceReviewed records the user approving or rejecting actions
+	// the maintenance agent proposed
+	ActivityMaintenanceReviewed = "maintenance_reviewed"
+)
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ActivityRollup counts one user's activity of one type on one UTC day. Days are
+// rolled up once they are over, so trends outlast the raw activity logs the
+// retention policy prunes.
+type ActivityRollup struct {
+	ID     uint      `gorm:"primaryKey" json:"-"`
+	Day    time.Time `gorm:"type:date;not null;uniqueIndex:idx_activity_rollups_day_user_type" json:"day"`
+	UserID uint      `gorm:"not null;uniqueIndex:idx_activity_rollups_day_user_type;index" json:"user_id"`
+	Type   string    `gorm:"not null;uniqueIndex:idx_activity_rollups_day_user_type" json:"type"`
+	Count  int64     `gorm:"not null;default:0" json:"count"`
+}
+
+// TableName ensures consistent table naming
+func (ActivityRollup) TableName() string {
+	return "activity_rollups"
+}
+
+// PerformanceRollup summarizes the requests to one endpoint on one UTC day, kept
+// after the raw performance metrics are pruned
+type PerformanceRollup struct {
+	ID       uint      `gorm:"primaryKey" json:"-"`
+	Day      time.Time `gorm:"type:date;not null;uniqueIndex:idx_performance_rollups_day_endpoint" json:"day"`
+	Endpoint string    `gorm:"not null;uniqueIndex:idx_performance_rollups_day_endpoint" json:"endpoint"`
+	Method   string    `gorm:"not null;uniqueIndex:idx_performance_rollups_day_endpoint" json:"method"`
+	Requests int64     `gorm:"not null;default:0" json:"requests"`
+	// Errors counts responses with a 5xx status
+	Errors          int64 `gorm:"not null;default:0" json:"errors"`
+	TotalDurationMs int64 `gorm:"not null;default:0" json:"total_duration_ms"`
+	MaxDurationMs   int   `gorm:"not null;default:0" json:"max_duration_ms"`
+}
+
+// TableName ensures consistent table naming
+func (PerformanceRollup) TableName() string {
+	return "performance_rollups"
+}
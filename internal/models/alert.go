@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Alert is a suspicious-activity finding raised by the anomaly detector
+type Alert struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	UserID         uint            `gorm:"not null;index" json:"user_id"`
+	Rule           string          `gorm:"not null;index" json:"rule"`
+	Severity       string          `gorm:"not null" json:"severity"`
+	Message        string          `gorm:"type:text;not null" json:"message"`
+	Details        json.RawMessage `gorm:"type:jsonb" json:"details,omitempty" swaggertype:"object"`
+	AcknowledgedAt *time.Time      `gorm:"index" json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint           `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+// Anomaly detection rules
+const (
+	AlertRuleDeleteSpike    = "delete_spike"
+	AlertRuleNewCountry     = "new_country"
+	AlertRuleAPIKeyOffHours = "api_key_off_hours"
+)
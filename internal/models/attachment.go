@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Attachment is a file kept with a memory, such as an image or the recording a
+// voice memo was transcribed from. Its data is kept in the row or, when Storage
+// names an object store, under StorageKey there. With encryption enabled the data
+// is encrypted like memory content and Data is empty. Attachments are deleted with
+// their memory.
+type Attachment struct {
+	ID          uint    `gorm:"primaryKey" json:"id"`
+	UserID      uint    `gorm:"not null;index" json:"user_id"`
+	MemoryID    uint    `gorm:"not null;index" json:"memory_id"`
+	Memory      *Memory `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	Filename    string  `gorm:"size:255" json:"filename"`
+	ContentType string  `gorm:"size:100;not null" json:"content_type"`
+	Size        int64   `gorm:"not null" json:"size"`
+	SHA256      string  `gorm:"size:64" json:"sha256"`
+	// Metadata holds what the uploader described the file with, and the width and
+	// height of images
+	Metadata json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty" swaggertype:"object"`
+	// Storage is empty for data kept in the row, or the object store holding it
+	Storage       string          `gorm:"size:20" json:"storage,omitempty"`
+	StorageKey    string          `gorm:"size:255" json:"-"`
+	Data          []byte          `gorm:"type:bytea" json:"-" swaggerignore:"true"`
+	EncryptedData json.RawMessage `gorm:"type:jsonb" json:"-" swaggerignore:"true"`
+	IsEncrypted   bool            `gorm:"default:false" json:"-"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// URI is the MCP resource the attachment can be read as
+	URI string `gorm:"-" json:"uri,omitempty"`
+}
+
+// TableName ensures consistent table naming
+func (Attachment) TableName() string {
+	return "attachments"
+}
+
+// AttachmentURI is the MCP resource URI of an attachment
+func AttachmentURI(id uint) string {
+	return fmt.Sprintf("memory://attachments/%d", id)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken lets a client get a new access token without logging in again.
+// Only a hash of the token is stored. Each refresh replaces the token used with a
+// new one, so a revoked token coming back means it was stolen.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive reports whether the token can still be exchanged
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedToken blacklists an access token, by its jti claim, until it would have
+// expired anyway
+type RevokedToken struct {
+	TokenID   string    `gorm:"primaryKey;size:64" json:"token_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
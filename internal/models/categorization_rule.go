@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CategorizationRule files memories mentioning a phrase under a category and
+// tags. Enabled rules are applied when memories are stored and can be run over
+// existing memories.
+type CategorizationRule struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_categorization_rules_user_name" json:"user_id"`
+	Name   string `gorm:"not null;uniqueIndex:idx_categorization_rules_user_name" json:"name"`
+	// Match is the phrase memory content must contain, ignoring case
+	Match string `gorm:"type:text;not null" json:"match"`
+	// Category, when set, replaces the memory's category
+	Category string `json:"category,omitempty"`
+	// Tags are added to the memory's tags
+	Tags      pq.StringArray `gorm:"type:text[]" json:"tags" swaggertype:"array,string"`
+	Enabled   bool           `gorm:"not null" json:"enabled"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (CategorizationRule) TableName() string {
+	return "categorization_rules"
+}
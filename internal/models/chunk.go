@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// MemoryChunk is a part of a long memory after its first, embedded on its own so
+// searches match text far into the memory. The memory keeps the whole content and
+// its own embedding covers the first part; chunks hold only their place in the
+// content, and a search matching one returns the whole memory. Chunks are
+// replaced whenever the memory is embedded and deleted with it.
+type MemoryChunk struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	UserID   uint    `gorm:"not null;index" json:"user_id"`
+	MemoryID uint    `gorm:"not null;uniqueIndex:idx_memory_chunks_position" json:"memory_id"`
+	Memory   *Memory `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	// Position orders the chunks from 1; position 0 is the memory's own embedding
+	Position int `gorm:"not null;uniqueIndex:idx_memory_chunks_position" json:"position"`
+	// Start and Length place the chunk in the memory's content, in characters
+	Start     int             `gorm:"not null" json:"start"`
+	Length    int             `gorm:"not null" json:"length"`
+	Embedding pgvector.Vector `gorm:"type:vector(1536)" json:"-" swaggerignore:"true"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryChunk) TableName() string {
+	return "memory_chunks"
+}
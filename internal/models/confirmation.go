@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Confirmation is a single-use token confirming a destructive action. Only its
+// hash is stored, along with a fingerprint of what the action would affect, so a
+// token stops working once it is used, expires, or the target changes.
+type Confirmation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"-"`
+	Action      string    `gorm:"not null;size:32" json:"action"`
+	Fingerprint string    `gorm:"not null;size:64" json:"-"`
+	TokenHash   string    `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (Confirmation) TableName() string {
+	return "confirmations"
+}
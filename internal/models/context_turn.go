@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Conversation roles for context turns
+const (
+	ContextRoleUser      = "user"
+	ContextRoleAssistant = "assistant"
+)
+
+// ContextTurn is one recent conversation turn in a session's short-term buffer.
+// Turns expire on their own and are never promoted to memories.
+type ContextTurn struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	UserID           uint            `gorm:"not null;index:idx_context_turns_session" json:"-"`
+	SessionID        string          `gorm:"not null;size:128;index:idx_context_turns_session" json:"session_id"`
+	Role             string          `gorm:"not null;size:16" json:"role"`
+	Content          string          `gorm:"type:text;not null" json:"content"`
+	EncryptedContent json.RawMessage `gorm:"type:jsonb" json:"-" swaggerignore:"true"`
+	IsEncrypted      bool            `gorm:"default:false" json:"-"`
+	ExpiresAt        time.Time       `gorm:"not null;index" json:"expires_at"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (ContextTurn) TableName() string {
+	return "context_turns"
+}
+
+// IsValidContextRole checks if the role is valid
+func IsValidContextRole(role string) bool {
+	return role == ContextRoleUser || role == ContextRoleAssistant
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// EmbeddingCacheEntry is an embedding generated for a text by a model, kept so
+// the same user storing the same text again reuses it instead of asking the
+// provider. Entries are keyed by the text's content hash, which is keyed per user
+// where content is encrypted, so the text itself is not kept and cannot be
+// confirmed by guessing.
+type EmbeddingCacheEntry struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     uint      `gorm:"not null;default:0;uniqueIndex:idx_embedding_cache_user_model_hash" json:"user_id"`
+	Model      string    `gorm:"not null;size:255;uniqueIndex:idx_embedding_cache_user_model_hash" json:"model"`
+	TextHash   string    `gorm:"not null;size:64;uniqueIndex:idx_embedding_cache_user_model_hash" json:"text_hash"`
+	Dimensions int       `gorm:"not null" json:"dimensions"`
+	Vector     []byte    `gorm:"not null" json:"-"`
+	Hits       int64     `gorm:"not null;default:0" json:"hits"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `gorm:"index" json:"last_used_at"`
+}
+
+// TableName ensures consistent table naming
+func (EmbeddingCacheEntry) TableName() string {
+	return "embedding_cache"
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// EmbeddingJob tracks a memory whose embedding still needs to be generated so the
+// work survives process restarts and embedding provider failures
+type EmbeddingJob struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	MemoryID      uint      `gorm:"not null;uniqueIndex" json:"memory_id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	Status        string    `gorm:"not null;default:'pending';index" json:"status"`
+	Attempts      int       `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     string    `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (EmbeddingJob) TableName() string {
+	return "embedding_jobs"
+}
+
+// Embedding job statuses
+const (
+	EmbeddingJobPending = "pending"
+	EmbeddingJobFailed  = "failed"
+)
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// MemoryFeedback records whether a memory returned for a query was helpful or
+// irrelevant. Search learns a ranking boost for each memory from its feedback.
+type MemoryFeedback struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	UserID   uint    `gorm:"not null;index" json:"user_id"`
+	MemoryID uint    `gorm:"not null;index" json:"memory_id"`
+	Memory   *Memory `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	// Query is the search the memory was returned for, normalized to lower case
+	Query     string    `gorm:"type:text;not null" json:"query"`
+	Helpful   bool      `gorm:"not null" json:"helpful"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryFeedback) TableName() string {
+	return "memory_feedback"
+}
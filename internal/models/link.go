@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// MemoryLink relates one memory to another, such as a newer fact superseding an
+// older one. Links are directed from source to target and deleted with either
+// memory.
+type MemoryLink struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	SourceID  uint      `gorm:"not null;uniqueIndex:idx_memory_links_edge" json:"source_id"`
+	Source    *Memory   `gorm:"foreignKey:SourceID;constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	TargetID  uint      `gorm:"not null;index;uniqueIndex:idx_memory_links_edge" json:"target_id"`
+	Target    *Memory   `gorm:"foreignKey:TargetID;constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	Relation  string    `gorm:"not null;size:32;uniqueIndex:idx_memory_links_edge" json:"relation"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryLink) TableName() string {
+	return "memory_links"
+}
+
+// Link relations
+const (
+	// LinkSupersedes marks the source as replacing the target
+	LinkSupersedes = "supersedes"
+	// LinkRelatedTo loosely associates two memories
+	LinkRelatedTo = "related_to"
+	// LinkPartOfProject places the source under a memory describing a project
+	LinkPartOfProject = "part_of_project"
+)
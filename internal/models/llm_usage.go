@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Whose API key paid for usage
+const (
+	KeySourceServer = "server"
+	KeySourceUser   = "user"
+)
+
+// LLMUsage totals a user's language-model usage for one feature and key source on
+// one UTC day
+type LLMUsage struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_llm_usage_user_feature_day" json:"user_id"`
+	Feature      string    `gorm:"not null;size:32;uniqueIndex:idx_llm_usage_user_feature_day" json:"feature"`
+	KeySource    string    `gorm:"not null;size:16;default:'server';uniqueIndex:idx_llm_usage_user_feature_day" json:"key_source"`
+	Day          string    `gorm:"not null;size:10;uniqueIndex:idx_llm_usage_user_feature_day;index" json:"day"`
+	Requests     int64     `gorm:"not null;default:0" json:"requests"`
+	InputTokens  int64     `gorm:"not null;default:0" json:"input_tokens"`
+	OutputTokens int64     `gorm:"not null;default:0" json:"output_tokens"`
+	CostUSD      float64   `gorm:"not null;default:0" json:"cost_usd"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaintenanceReport is one review of a user's memories by the maintenance agent.
+// It proposes actions, none of which is applied until the user approves it.
+type MaintenanceReport struct {
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// Trigger is scheduled for reviews the agent ran on its own and manual for
+	// ones the user asked for
+	Trigger string `gorm:"size:16;not null" json:"trigger"`
+	// Scanned is how many memories the review looked at
+	Scanned   int                 `gorm:"not null;default:0" json:"scanned"`
+	Actions   []MaintenanceAction `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE" json:"actions,omitempty"`
+	CreatedAt time.Time           `gorm:"index" json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MaintenanceReport) TableName() string {
+	return "maintenance_reports"
+}
+
+// MaintenanceAction is a change the maintenance agent proposes to one memory
+type MaintenanceAction struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	ReportID uint   `gorm:"not null;index" json:"report_id"`
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Kind     string `gorm:"size:32;not null" json:"kind"`
+	// MemoryID is the memory the action changes or deletes
+	MemoryID uint `gorm:"not null;index" json:"memory_id"`
+	// KeepID is the memory kept in its place, for merges and conflicts
+	KeepID *uint  `json:"keep_id,omitempty"`
+	Reason string `gorm:"type:text" json:"reason"`
+	// Similarity is how close a near-duplicate is to the memory kept
+	Similarity float64 `json:"similarity,omitempty"`
+	// Category and AddTags are the changes a recategorization makes
+	Category string         `gorm:"size:32" json:"category,omitempty"`
+	AddTags  pq.StringArray `gorm:"type:text[]" json:"add_tags,omitempty" swaggertype:"array,string"`
+	// Status is proposed until the user reviews the action
+	Status    string     `gorm:"size:16;not null;index" json:"status"`
+	Error     string     `gorm:"type:text" json:"error,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MaintenanceAction) TableName() string {
+	return "maintenance_actions"
+}
+
+// Maintenance action kinds
+const (
+	// MaintenanceMergeDuplicate folds a duplicate into the memory kept and deletes it
+	MaintenanceMergeDuplicate = "merge_duplicate"
+	// MaintenanceResolveConflict deletes a memory a newer one contradicts
+	MaintenanceResolveConflict = "resolve_conflict"
+	// MaintenancePruneStale deletes a memory that has gone unused
+	MaintenancePruneStale = "prune_stale"
+	// MaintenanceRecategorize files a memory under the category and tags of the
+	// categorization rules it matches
+	MaintenanceRecategorize = "recategorize"
+)
+
+// Maintenance action statuses
+const (
+	MaintenanceProposed = "proposed"
+	MaintenanceApplied  = "applied"
+	MaintenanceRejected = "rejected"
+	MaintenanceFailed   = "failed"
+	// MaintenanceExpired marks proposals a later report replaced
+	MaintenanceExpired = "expired"
+)
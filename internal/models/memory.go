@@ -1,8 +1,11 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,23 +15,37 @@ import (
 
 // Memory represents a stored memory item in the database
 type Memory struct {
-	ID              uint              `gorm:"primaryKey" json:"id"`
-	UserID          uint              `gorm:"not null;index;default:1" json:"user_id"`
-	Type            string            `gorm:"index;not null" json:"type"`
-	Category        string            `gorm:"index;not null" json:"category"`
-	Content         string            `gorm:"type:text;not null" json:"content"`
-	EncryptedContent json.RawMessage  `gorm:"type:jsonb" json:"-" swaggerignore:"true"` // Stores encrypted content data
-	IsEncrypted     bool              `gorm:"default:false" json:"is_encrypted"`
-	Priority        string            `gorm:"index;default:'medium'" json:"priority"`
-	UpdateKey       string            `gorm:"index" json:"update_key,omitempty"`
-	Embedding       pgvector.Vector   `gorm:"type:vector(1536);default:null" json:"-" swaggerignore:"true"`
-	Tags            pq.StringArray    `gorm:"type:text[]" json:"tags" swaggertype:"array,string"`
-	Metadata        json.RawMessage   `gorm:"type:jsonb" json:"metadata,omitempty" swaggertype:"object"`
-	CreatedAt       time.Time         `json:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at"`
-	
+	ID     uint `gorm:"primaryKey" json:"id"`
+	UserID uint `gorm:"not null;index;default:1" json:"user_id"`
+	// WorkspaceID is the workspace holding the memory; 0 is the default workspace
+	WorkspaceID      uint            `gorm:"not null;default:0" json:"workspace_id"`
+	Type             string          `gorm:"index;not null" json:"type"`
+	Category         string          `gorm:"index;not null" json:"category"`
+	Content          string          `gorm:"type:text;not null" json:"content"`
+	EncryptedContent json.RawMessage `gorm:"type:jsonb" json:"-" swaggerignore:"true"` // Stores encrypted content data
+	IsEncrypted      bool            `gorm:"default:false" json:"is_encrypted"`
+	Priority         string          `gorm:"index;default:'medium'" json:"priority"`
+	UpdateKey        string          `gorm:"index" json:"update_key,omitempty"`
+	ContentHash      string          `gorm:"index;size:64" json:"-"` // hash of the plaintext content, keyed per user where content is encrypted
+	// ContentIndex and KeywordIndex are blind indexes of encrypted content: keyed
+	// hashes of the whole plaintext, and of each of its words, space-separated
+	ContentIndex   string          `gorm:"index;size:64" json:"-"`
+	KeywordIndex   string          `gorm:"type:text" json:"-"`
+	Embedding      pgvector.Vector `gorm:"type:vector(1536);default:null" json:"-" swaggerignore:"true"`
+	Tags           pq.StringArray  `gorm:"type:text[]" json:"tags" swaggertype:"array,string"`
+	Metadata       json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty" swaggertype:"object"`
+	AccessCount    int64           `gorm:"not null;default:0" json:"access_count"`
+	LastAccessedAt *time.Time      `json:"last_accessed_at,omitempty"`
+	// SessionID is the working session the memory was captured in, if any
+	SessionID string `gorm:"size:128" json:"session_id,omitempty"`
+	// Similarity is the cosine similarity to the query, set on semantic search
+	// results only; it is not stored
+	Similarity *float64  `gorm:"-" json:"similarity,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
 	// Associations
-	User            *User             `gorm:"foreignKey:UserID" json:"-" swaggerignore:"true"`
+	User *User `gorm:"foreignKey:UserID" json:"-" swaggerignore:"true"`
 }
 
 // Valid memory types
@@ -46,6 +63,14 @@ const (
 	CategoryBusiness = "business"
 )
 
+// Valid memory priorities
+const (
+	PriorityLow      = "low"
+	PriorityMedium   = "medium"
+	PriorityHigh     = "high"
+	PriorityCritical = "critical"
+)
+
 // TableName ensures consistent table naming
 func (Memory) TableName() string {
 	return "memories"
@@ -87,6 +112,28 @@ func (m *Memory) BeforeUpdate(tx *gorm.DB) error {
 	return m.Validate()
 }
 
+// HashContent returns the hex SHA-256 digest used for ContentHash where content
+// is not encrypted; encrypted deployments key it per user instead
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryContentAAD is the additional authenticated data binding a memory's
+// encrypted content to its owner and to the memory itself, so the ciphertext
+// cannot be moved to another user's memory or to another of the owner's
+// memories. Content not stored on a memory, such as a context turn, has memory
+// ID 0.
+func MemoryContentAAD(userID, memoryID uint) []byte {
+	return []byte(fmt.Sprintf("remember-me:memory-content:user:%d:memory:%d", userID, memoryID))
+}
+
+// OwnerContentAAD is the additional authenticated data of content encrypted in
+// a version 2 envelope, which bound it to its owner only
+func OwnerContentAAD(userID uint) []byte {
+	return []byte(fmt.Sprintf("remember-me:memory-content:user:%d", userID))
+}
+
 // IsValidType checks if a given type string is valid
 func IsValidType(t string) bool {
 	switch t {
@@ -105,4 +152,14 @@ func IsValidCategory(c string) bool {
 	default:
 		return false
 	}
-}
\ No newline at end of file
+}
+
+// IsValidPriority checks if a given priority string is valid
+func IsValidPriority(p string) bool {
+	switch p {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityCritical:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// MemoryAccessLog records one read, update or delete of a memory's content made
+// through MCP or the HTTP API, for users who must account for every access.
+// Entries outlive the memory, so the history of a deleted memory is kept.
+type MemoryAccessLog struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	UserID   uint `gorm:"not null;index:idx_memory_access_logs_memory,priority:1" json:"user_id"`
+	MemoryID uint `gorm:"not null;index:idx_memory_access_logs_memory,priority:2" json:"memory_id"`
+	// Action is read, update or delete
+	Action string `gorm:"size:16;not null" json:"action"`
+	// Source is the front end the access came through: mcp or http
+	Source string `gorm:"size:16;not null" json:"source"`
+	// ActorID is the user who made the access: the owner, or the admin acting
+	// under a support grant. It is 0 for entries recorded before actors were.
+	ActorID uint `gorm:"index" json:"actor_id"`
+	// ActorRole is owner or support
+	ActorRole string    `gorm:"size:16;not null;default:owner" json:"actor_role"`
+	CreatedAt time.Time `gorm:"index" json:"timestamp"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryAccessLog) TableName() string {
+	return "memory_access_logs"
+}
+
+// Memory access actions
+const (
+	MemoryAccessRead   = "read"
+	MemoryAccessUpdate = "update"
+	MemoryAccessDelete = "delete"
+)
+
+// Memory access actor roles
+const (
+	MemoryAccessOwner   = "owner"
+	MemoryAccessSupport = "support"
+)
@@ -0,0 +1,25 @@
+package models
+
+// Dimensions of MemoryCounter
+const (
+	CounterTotal    = "total"
+	CounterCategory = "category"
+	CounterType     = "type"
+	CounterEmbedded = "embedded"
+)
+
+// MemoryCounter is how many memories a user has overall (CounterTotal), in one
+// category or of one type (Value names it), or with an embedding
+// (CounterEmbedded). Triggers on memories keep the counters current, so counting
+// does not scan the memories table.
+type MemoryCounter struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Dimension string `gorm:"primaryKey;size:16" json:"dimension"`
+	Value     string `gorm:"primaryKey;size:64" json:"value"`
+	Count     int64  `gorm:"not null;default:0" json:"count"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryCounter) TableName() string {
+	return "memory_counters"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Migration audit outcomes
+const (
+	MigrationSucceeded = "succeeded"
+	MigrationFailed    = "failed"
+)
+
+// MigrationAudit records each attempt to run a versioned migration, including
+// failed ones, with how long it took and how much data it touched
+type MigrationAudit struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Version    string    `gorm:"not null;size:64;index" json:"version"`
+	Name       string    `gorm:"not null" json:"name"`
+	Status     string    `gorm:"not null;size:16" json:"status"`
+	StartedAt  time.Time `gorm:"not null" json:"started_at"`
+	DurationMS int64     `gorm:"not null;default:0" json:"duration_ms"`
+	// Tables lists the tables the migration rewrites, comma separated, and
+	// TableRows is how many rows they held when it started
+	Tables    string `json:"tables,omitempty"`
+	TableRows int64  `gorm:"not null;default:0" json:"table_rows"`
+	// RowsAffected is the number of rows the migration reported changing
+	RowsAffected int64 `gorm:"not null;default:0" json:"rows_affected"`
+	// BackupTables lists the copies taken before the migration ran; BackupSkipped
+	// is set when the operator confirmed they had their own backup instead
+	BackupTables  string `json:"backup_tables,omitempty"`
+	BackupSkipped bool   `gorm:"not null;default:false" json:"backup_skipped"`
+	Error         string `gorm:"type:text" json:"error,omitempty"`
+}
+
+// TableName returns the table name for GORM
+func (MigrationAudit) TableName() string {
+	return "migration_audit"
+}
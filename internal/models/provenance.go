@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MemoryProvenance links a memory created by consolidation or merge to one of the
+// memories it was derived from. Rows are kept when the source is later changed or
+// deleted so the origin of a derived memory can always be traced.
+type MemoryProvenance struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	UserID         uint   `gorm:"not null;index" json:"user_id"`
+	MemoryID       uint   `gorm:"not null;index" json:"memory_id"`
+	SourceMemoryID uint   `gorm:"not null;index" json:"source_memory_id"`
+	Method         string `gorm:"not null;size:32" json:"method"`
+	// SourceContentHash and SourceVersion identify the version of the source that was used
+	SourceContentHash string    `gorm:"size:64" json:"-"`
+	SourceVersion     time.Time `json:"source_version"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryProvenance) TableName() string {
+	return "memory_provenance"
+}
+
+// Provenance methods
+const (
+	ProvenanceMethodConsolidation = "consolidation"
+	ProvenanceMethodMerge         = "merge"
+)
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ReembedRun tracks regenerating every memory's embedding with a new embedding
+// model. Memories are processed in ID order and LastMemoryID records how far a
+// run got, so an interrupted run resumes where it stopped.
+type ReembedRun struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Model        string     `gorm:"not null;index" json:"model"`
+	Status       string     `gorm:"not null;index" json:"status"`
+	LastMemoryID uint       `gorm:"not null;default:0" json:"last_memory_id"`
+	Total        int64      `gorm:"not null;default:0" json:"total"`
+	Processed    int64      `gorm:"not null;default:0" json:"processed"`
+	Skipped      int64      `gorm:"not null;default:0" json:"skipped"`
+	LastError    string     `gorm:"type:text" json:"last_error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (ReembedRun) TableName() string {
+	return "reembed_runs"
+}
+
+// Re-embedding run statuses
+const (
+	ReembedRunning   = "running"
+	ReembedCompleted = "completed"
+)
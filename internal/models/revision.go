@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MemoryRevision is a previous version of a memory, saved when Store or Update
+// overwrites its content, type or category. Content is kept in the same form as
+// the memory held it, so encrypted memories have encrypted revisions.
+type MemoryRevision struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	UserID           uint            `gorm:"not null;index" json:"user_id"`
+	MemoryID         uint            `gorm:"not null;index" json:"memory_id"`
+	Memory           *Memory         `gorm:"constraint:OnDelete:CASCADE" json:"-" swaggerignore:"true"`
+	Content          string          `gorm:"type:text;not null" json:"content"`
+	EncryptedContent json.RawMessage `gorm:"type:jsonb" json:"-" swaggerignore:"true"`
+	IsEncrypted      bool            `gorm:"default:false" json:"-"`
+	ContentHash      string          `gorm:"size:64" json:"-"`
+	Type             string          `gorm:"not null" json:"type"`
+	Category         string          `gorm:"not null" json:"category"`
+	Priority         string          `json:"priority"`
+	// ValidFrom is when this version was written and CreatedAt when it was replaced
+	ValidFrom time.Time `json:"valid_from"`
+	CreatedAt time.Time `json:"replaced_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemoryRevision) TableName() string {
+	return "memory_revisions"
+}
+
+// NewMemoryRevision captures a memory's current stored version before it is
+// overwritten
+func NewMemoryRevision(memory *Memory) *MemoryRevision {
+	return &MemoryRevision{
+		UserID:           memory.UserID,
+		MemoryID:         memory.ID,
+		Content:          memory.Content,
+		EncryptedContent: memory.EncryptedContent,
+		IsEncrypted:      memory.IsEncrypted,
+		ContentHash:      memory.ContentHash,
+		Type:             memory.Type,
+		Category:         memory.Category,
+		Priority:         memory.Priority,
+		ValidFrom:        memory.UpdatedAt,
+	}
+}
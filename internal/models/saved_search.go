@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch is a named, reusable set of search parameters owned by a user
+type SavedSearch struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	UserID            uint      `gorm:"not null;uniqueIndex:idx_saved_searches_user_name" json:"user_id"`
+	Name              string    `gorm:"not null;uniqueIndex:idx_saved_searches_user_name" json:"name"`
+	Query             string    `gorm:"type:text;not null" json:"query"`
+	Category          string    `json:"category,omitempty"`
+	Type              string    `json:"type,omitempty"`
+	Limit             int       `json:"limit,omitempty"`
+	UseSemanticSearch bool      `json:"use_semantic_search"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// MemorySession is a working session during which memories are captured, so
+// they can later be recalled or purged together. A user has at most one open
+// session, and memories stored while it is open are tagged with its SessionID.
+type MemorySession struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_memory_sessions_user_session" json:"-"`
+	SessionID string     `gorm:"not null;size:128;uniqueIndex:idx_memory_sessions_user_session" json:"session_id"`
+	Name      string     `gorm:"size:255" json:"name,omitempty"`
+	StartedAt time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt   *time.Time `gorm:"index" json:"ended_at,omitempty"`
+}
+
+// TableName ensures consistent table naming
+func (MemorySession) TableName() string {
+	return "memory_sessions"
+}
+
+// IsOpen reports whether the session has not been ended
+func (s *MemorySession) IsOpen() bool {
+	return s.EndedAt == nil
+}
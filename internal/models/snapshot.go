@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+)
+
+// MemorySnapshot represents a named point-in-time copy of a user's memories
+type MemorySnapshot struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	Name        string    `gorm:"not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	MemoryCount int       `gorm:"not null;default:0" json:"memory_count"`
+	Region      string    `gorm:"size:32" json:"region,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemorySnapshot) TableName() string {
+	return "memory_snapshots"
+}
+
+// MemorySnapshotItem is the logical copy of a single memory row inside a snapshot.
+// MemoryID keeps the original primary key so a restore brings memories back under
+// the same IDs clients may already hold.
+type MemorySnapshotItem struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	SnapshotID       uint            `gorm:"not null;index" json:"snapshot_id"`
+	MemoryID         uint            `gorm:"not null" json:"memory_id"`
+	WorkspaceID      uint            `gorm:"not null;default:0" json:"workspace_id"`
+	Type             string          `gorm:"not null" json:"type"`
+	Category         string          `gorm:"not null" json:"category"`
+	Content          string          `gorm:"type:text;not null" json:"content"`
+	EncryptedContent json.RawMessage `gorm:"type:jsonb" json:"-"`
+	IsEncrypted      bool            `gorm:"default:false" json:"is_encrypted"`
+	Priority         string          `json:"priority"`
+	UpdateKey        string          `json:"update_key,omitempty"`
+	ContentHash      string          `json:"-"`
+	ContentIndex     string          `json:"-"`
+	KeywordIndex     string          `gorm:"type:text" json:"-"`
+	Embedding        pgvector.Vector `gorm:"type:vector(1536);default:null" json:"-"`
+	Tags             pq.StringArray  `gorm:"type:text[]" json:"tags"`
+	Metadata         json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty"`
+	AccessCount      int64           `gorm:"not null;default:0" json:"access_count"`
+	LastAccessedAt   *time.Time      `json:"last_accessed_at,omitempty"`
+	SessionID        string          `gorm:"size:128" json:"session_id,omitempty"`
+	MemoryCreatedAt  time.Time       `json:"memory_created_at"`
+	MemoryUpdatedAt  time.Time       `json:"memory_updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (MemorySnapshotItem) TableName() string {
+	return "memory_snapshot_items"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// SupportAccessGrant is a user's temporary consent for support staff to view
+// the content of their memories. Only a hash of the token is stored; the
+// plaintext token is shown to the user once and handed to support out of band.
+type SupportAccessGrant struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	Reason    string     `gorm:"type:text" json:"reason,omitempty"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName ensures consistent table naming
+func (SupportAccessGrant) TableName() string {
+	return "support_access_grants"
+}
+
+// IsActive reports whether the grant can still be used
+func (g *SupportAccessGrant) IsActive(now time.Time) bool {
+	return g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
@@ -1,19 +1,68 @@
 package models
 
 import (
+	"encoding/json"
+	"gorm.io/gorm"
 	"strings"
 	"time"
-	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	APIKeys   []APIKey       `gorm:"foreignKey:UserID" json:"-"`
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Email    string `gorm:"uniqueIndex;not null" json:"email"`
+	Password string `gorm:"not null" json:"-"`
+	Role     string `gorm:"not null;default:'user'" json:"role"`
+	// EvictionPolicy overrides the deployment default for what happens at the memory limit
+	EvictionPolicy string `gorm:"size:32" json:"eviction_policy,omitempty"`
+	// Plan selects the user's memory limit from memory.plans; empty uses the
+	// deployment's max_memories
+	Plan string `gorm:"size:32" json:"plan,omitempty"`
+	// MemoryLimit overrides the plan's memory limit for this user; 0 is unlimited
+	MemoryLimit *int `json:"memory_limit,omitempty"`
+	// DisabledAt is when an admin disabled the account; disabled users can't log
+	// in or use their API keys
+	DisabledAt *time.Time `gorm:"index" json:"disabled_at,omitempty"`
+	// TokenVersion is raised when the user's password changes or the account is
+	// disabled; access tokens carrying an older version are rejected
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
+	// IncognitoUntil suspends remembering anything for the user until this time
+	IncognitoUntil *time.Time `json:"incognito_until,omitempty"`
+	// MaintenanceEnabled opts the user in to scheduled reviews by the
+	// maintenance agent
+	MaintenanceEnabled bool `gorm:"not null;default:false" json:"maintenance_enabled,omitempty"`
+	// OpenAIKey is the user's own OpenAI API key, encrypted with the master key,
+	// used for their embedding and LLM calls instead of the server's key
+	OpenAIKey      json.RawMessage `gorm:"column:openai_key;type:jsonb" json:"-"`
+	OpenAIKeyHint  string          `gorm:"column:openai_key_hint;size:16" json:"-"`
+	OpenAIKeySetAt *time.Time      `gorm:"column:openai_key_set_at" json:"-"`
+	// EncryptionKey is the key encrypting the user's memories, itself encrypted
+	// with the master key; see database.UserDataKey
+	EncryptionKey json.RawMessage `gorm:"type:jsonb" json:"-"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt  `gorm:"index" json:"-"`
+	APIKeys       []APIKey        `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user may use admin-only endpoints
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsDisabled reports whether an admin has disabled the account
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// IsValidRole checks if a role is known
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
 }
 
 type APIKey struct {
@@ -26,11 +75,39 @@ type APIKey struct {
 	ExpiresAt   *time.Time     `json:"expires_at"`
 	IsActive    bool           `gorm:"default:true;index" json:"is_active"`
 	Permissions string         `gorm:"type:text" json:"-"`
+	KeyType     string         `gorm:"size:16" json:"-"`            // standard or backup; keys of any other type are refused
+	UsageHours  int            `gorm:"not null;default:0" json:"-"` // bitmask of UTC hours the key has been used in
+	UsageCount  int64          `gorm:"not null;default:0" json:"-"`
+	WorkspaceID uint           `gorm:"not null;default:0" json:"workspace_id"` // workspace requests default to; 0 is the default workspace
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// API key permissions
+const (
+	PermissionMemoryRead   = "memory:read"
+	PermissionMemoryWrite  = "memory:write"
+	PermissionMemoryDelete = "memory:delete"
+	PermissionMemoryExport = "memory:export"
+	PermissionMemoryStats  = "memory:stats"
+)
+
+// API key types
+const (
+	// APIKeyTypeStandard keys have full access to the user's memories
+	APIKeyTypeStandard = "standard"
+	// APIKeyTypeBackup keys are read-only and limited to export and stats, for
+	// automated off-site backups
+	APIKeyTypeBackup = "backup"
+)
+
+// APIKeyTypePermissions maps each key type to the permissions it is created with
+var APIKeyTypePermissions = map[string][]string{
+	APIKeyTypeStandard: {PermissionMemoryRead, PermissionMemoryWrite, PermissionMemoryDelete},
+	APIKeyTypeBackup:   {PermissionMemoryExport, PermissionMemoryStats},
+}
+
 // GetPermissions returns the permissions as a slice
 func (a *APIKey) GetPermissions() []string {
 	if a.Permissions == "" {
@@ -42,4 +119,26 @@ func (a *APIKey) GetPermissions() []string {
 // SetPermissions sets the permissions from a slice
 func (a *APIKey) SetPermissions(perms []string) {
 	a.Permissions = strings.Join(perms, ",")
-}
\ No newline at end of file
+}
+
+// HasPermission reports whether the key grants the permission
+func (a *APIKey) HasPermission(permission string) bool {
+	for _, p := range a.GetPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyTypeForPermissions returns the type created with exactly these
+// permissions, or "" when no type matches. It classifies keys stored before the
+// type had its own column.
+func APIKeyTypeForPermissions(permissions []string) string {
+	for keyType, typePermissions := range APIKeyTypePermissions {
+		if strings.Join(typePermissions, ",") == strings.Join(permissions, ",") {
+			return keyType
+		}
+	}
+	return ""
+}
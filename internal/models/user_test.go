@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyTypeForPermissions(t *testing.T) {
+	standard := &APIKey{}
+	standard.SetPermissions(APIKeyTypePermissions[APIKeyTypeStandard])
+	assert.Equal(t, APIKeyTypeStandard, APIKeyTypeForPermissions(standard.GetPermissions()))
+	assert.True(t, standard.HasPermission(PermissionMemoryDelete))
+	assert.False(t, standard.HasPermission(PermissionMemoryExport))
+
+	backup := &APIKey{}
+	backup.SetPermissions(APIKeyTypePermissions[APIKeyTypeBackup])
+	assert.Equal(t, APIKeyTypeBackup, APIKeyTypeForPermissions(backup.GetPermissions()))
+	assert.True(t, backup.HasPermission(PermissionMemoryExport))
+	assert.False(t, backup.HasPermission(PermissionMemoryWrite))
+
+	// Permissions matching no type give no type, rather than full access
+	assert.Empty(t, APIKeyTypeForPermissions(nil))
+	assert.Empty(t, APIKeyTypeForPermissions([]string{PermissionMemoryRead}))
+	assert.Empty(t, APIKeyTypeForPermissions([]string{PermissionMemoryExport, PermissionMemoryStats, PermissionMemoryWrite}))
+}
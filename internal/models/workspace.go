@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultWorkspace names the workspace every user has without creating it. It has
+// no row; its memories carry workspace ID 0.
+const DefaultWorkspace = "default"
+
+// Workspace is a separate space of memories within a user's account, such as
+// "work", "personal" or one per client. Searches, lists and duplicate checks only
+// see the memories of the workspace they run in.
+type Workspace struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_workspaces_user_name" json:"user_id"`
+	Name        string    `gorm:"not null;size:64;uniqueIndex:idx_workspaces_user_name" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName ensures consistent table naming
+func (Workspace) TableName() string {
+	return "workspaces"
+}
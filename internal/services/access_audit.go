@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Sources of memory access recorded in the access audit log
+const (
+	AccessSourceMCP  = "mcp"
+	AccessSourceHTTP = "http"
+)
+
+const (
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+)
+
+type accessSourceKey struct{}
+
+type accessActorKey struct{}
+
+// accessActor is who makes the access a context carries, when it is not the owner
+type accessActor struct {
+	id   uint
+	role string
+}
+
+// WithAccessSource returns a context whose memory reads, updates and deletes are
+// recorded in the access audit log as coming from source. Work done without one,
+// such as backups and maintenance, is not recorded.
+func WithAccessSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, accessSourceKey{}, source)
+}
+
+// AccessSourceFromContext returns the source set by WithAccessSource, or "" when
+// there is none
+func AccessSourceFromContext(ctx context.Context) string {
+	source, _ := ctx.Value(accessSourceKey{}).(string)
+	return source
+}
+
+// WithSupportActor returns a context whose memory access is recorded in the
+// access audit log as support access by the admin with the given ID, rather than
+// as the owner's own
+func WithSupportActor(ctx context.Context, adminID uint) context.Context {
+	return context.WithValue(ctx, accessActorKey{}, accessActor{id: adminID, role: models.MemoryAccessSupport})
+}
+
+// newAccessLog returns the audit entry for an action on a memory of userID made
+// with ctx
+func newAccessLog(ctx context.Context, userID, memoryID uint, action, source string) models.MemoryAccessLog {
+	entry := models.MemoryAccessLog{
+		UserID:    userID,
+		MemoryID:  memoryID,
+		Action:    action,
+		Source:    source,
+		ActorID:   userID,
+		ActorRole: models.MemoryAccessOwner,
+	}
+	if actor, ok := ctx.Value(accessActorKey{}).(accessActor); ok {
+		entry.ActorID = actor.id
+		entry.ActorRole = actor.role
+	}
+	return entry
+}
+
+// AccessLogRequest selects entries of a memory's access history
+type AccessLogRequest struct {
+	// Action keeps only read, update or delete entries when set
+	Action string
+	// Since keeps only entries recorded at or after it when set
+	Since *time.Time
+	Limit int
+}
+
+// auditAccess records an action on the memories with the given IDs when the
+// context carries an access source. Support access is recorded even with the
+// audit log turned off. A failure to record is logged and does not fail the access.
+func (s *MemoryService) auditAccess(ctx context.Context, action string, ids []uint) {
+	source := AccessSourceFromContext(ctx)
+	if source == "" || len(ids) == 0 {
+		return
+	}
+	_, support := ctx.Value(accessActorKey{}).(accessActor)
+	if enabled, ok := s.config["access_audit"].(bool); ok && !enabled && !support {
+		return
+	}
+
+	entries := make([]models.MemoryAccessLog, len(ids))
+	for i, id := range ids {
+		entries[i] = newAccessLog(ctx, s.userID, id, action, source)
+	}
+	if err := s.db.WithContext(ctx).CreateInBatches(entries, 500).Error; err != nil {
+		s.logger.Warn().Err(err).Str("action", action).Int("count", len(ids)).Msg("failed to record memory access audit")
+	}
+}
+
+// auditReads records that memories were returned to a client
+func (s *MemoryService) auditReads(ctx context.Context, memories []*models.Memory) {
+	if len(memories) == 0 {
+		return
+	}
+	ids := make([]uint, len(memories))
+	for i, memory := range memories {
+		ids[i] = memory.ID
+	}
+	s.auditAccess(ctx, models.MemoryAccessRead, ids)
+}
+
+// MemoryAccessLog returns who read, updated or deleted a memory, through which
+// front end and whether as the owner or as support, newest first. History is kept
+// after the memory is deleted.
+func (s *MemoryService) MemoryAccessLog(ctx context.Context, memoryID uint, req AccessLogRequest) ([]models.MemoryAccessLog, error) {
+	switch req.Action {
+	case "", models.MemoryAccessRead, models.MemoryAccessUpdate, models.MemoryAccessDelete:
+	default:
+		return nil, utils.InvalidFieldError("action", "must be read, update or delete")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAccessLogLimit
+	}
+	if limit > maxAccessLogLimit {
+		limit = maxAccessLogLimit
+	}
+
+	query := s.db.WithContext(ctx).Where("user_id = ? AND memory_id = ?", s.userID, memoryID)
+	if req.Action != "" {
+		query = query.Where("action = ?", req.Action)
+	}
+	if req.Since != nil {
+		query = query.Where("created_at >= ?", *req.Since)
+	}
+
+	entries := []models.MemoryAccessLog{}
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		s.logger.Error().Err(err).Uint("memory_id", memoryID).Msg("failed to get memory access log")
+		return nil, utils.WrapDatabaseError("get memory access log", err)
+	}
+	return entries, nil
+}
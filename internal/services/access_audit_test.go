@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func setupAuditedMemoryService(t *testing.T, config map[string]interface{}) *MemoryService {
+	t.Helper()
+	service := setupMemoryService(t, config)
+	require.NoError(t, service.db.AutoMigrate(&models.MemoryAccessLog{}))
+	return service
+}
+
+func TestMemoryService_AuditsMemoryAccess(t *testing.T) {
+	service := setupAuditedMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "Bank PIN hint is a birthday")
+	other, _ := storeTestMemory(t, service, "Prefers window seats")
+
+	httpCtx := WithAccessSource(context.Background(), AccessSourceHTTP)
+	mcpCtx := WithAccessSource(context.Background(), AccessSourceMCP)
+
+	_, err := service.GetByID(httpCtx, memory.ID)
+	require.NoError(t, err)
+	_, err = service.Search(mcpCtx, SearchRequest{Query: "PIN", Limit: 10})
+	require.NoError(t, err)
+	_, err = service.Update(mcpCtx, memory.ID, UpdateRequest{Content: "Bank PIN hint is an anniversary"})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(httpCtx, memory.ID))
+
+	entries, err := service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4, "history is kept after the memory is deleted")
+	var actions, sources []string
+	for _, entry := range entries {
+		assert.Equal(t, service.userID, entry.UserID)
+		assert.Equal(t, memory.ID, entry.MemoryID)
+		actions = append(actions, entry.Action)
+		sources = append(sources, entry.Source)
+	}
+	assert.Equal(t, []string{models.MemoryAccessDelete, models.MemoryAccessUpdate, models.MemoryAccessRead, models.MemoryAccessRead}, actions)
+	assert.Equal(t, []string{AccessSourceHTTP, AccessSourceMCP, AccessSourceMCP, AccessSourceHTTP}, sources)
+
+	reads, err := service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{Action: models.MemoryAccessRead, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, reads, 1)
+	assert.Equal(t, AccessSourceMCP, reads[0].Source)
+
+	future := time.Now().Add(time.Hour)
+	recent, err := service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{Since: &future})
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	untouched, err := service.MemoryAccessLog(context.Background(), other.ID, AccessLogRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, untouched, "the search did not return the other memory")
+
+	_, err = service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{Action: "export"})
+	assert.Error(t, err)
+}
+
+func TestMemoryService_AuditsSupportAccess(t *testing.T) {
+	// Support access is recorded even where the owner turned the audit log off
+	service := setupAuditedMemoryService(t, map[string]interface{}{"access_audit": false})
+	memory, _ := storeTestMemory(t, service, "Bank PIN hint is a birthday")
+	ctx := WithAccessSource(context.Background(), AccessSourceHTTP)
+
+	_, err := service.GetByID(ctx, memory.ID)
+	require.NoError(t, err)
+	_, err = service.Search(WithSupportActor(ctx, 42), SearchRequest{Query: "PIN", Limit: 10, ReadOnly: true})
+	require.NoError(t, err)
+
+	entries, err := service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, service.userID, entries[0].UserID)
+	assert.Equal(t, uint(42), entries[0].ActorID)
+	assert.Equal(t, models.MemoryAccessSupport, entries[0].ActorRole)
+
+	service.config["access_audit"] = true
+	_, err = service.GetByID(ctx, memory.ID)
+	require.NoError(t, err)
+	entries, err = service.MemoryAccessLog(context.Background(), memory.ID, AccessLogRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, service.userID, entries[0].ActorID, "the owner's own access")
+	assert.Equal(t, models.MemoryAccessOwner, entries[0].ActorRole)
+}
+
+func TestMemoryService_AccessAuditNeedsSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("internal access is not recorded", func(t *testing.T) {
+		service := setupAuditedMemoryService(t, nil)
+		memory, _ := storeTestMemory(t, service, "Allergic to penicillin")
+
+		_, err := service.GetByID(ctx, memory.ID)
+		require.NoError(t, err)
+		_, err = service.List(ctx, ListRequest{})
+		require.NoError(t, err)
+
+		entries, err := service.MemoryAccessLog(ctx, memory.ID, AccessLogRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		service := setupAuditedMemoryService(t, map[string]interface{}{"access_audit": false})
+		memory, _ := storeTestMemory(t, service, "Allergic to penicillin")
+
+		_, err := service.GetByID(WithAccessSource(ctx, AccessSourceHTTP), memory.ID)
+		require.NoError(t, err)
+
+		entries, err := service.MemoryAccessLog(ctx, memory.ID, AccessLogRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
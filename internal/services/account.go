@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// AccountExportVersion is the current format version of account exports
+const AccountExportVersion = 1
+
+// ErrAccountDeletionConfirmationRequired is returned when an account deletion
+// does not carry a token issued for the same data
+var ErrAccountDeletionConfirmationRequired = errors.New("confirmation required to delete the account")
+
+// AccountDeletionConfirmationError carries the single-use token the caller must
+// send back to delete the account, and what would be deleted with it
+type AccountDeletionConfirmationError struct {
+	Memories int64
+	Token    string
+}
+
+func (e *AccountDeletionConfirmationError) Error() string {
+	return fmt.Sprintf("deleting the account removes all its data, including %d memories; repeat the delete with confirm=%q to proceed", e.Memories, e.Token)
+}
+
+func (e *AccountDeletionConfirmationError) Unwrap() error {
+	return ErrAccountDeletionConfirmationRequired
+}
+
+// AccountExport is everything the deployment holds about a user: the account,
+// each workspace's memories as an archive that can be imported elsewhere, earlier
+// versions of memories, and the account's keys, settings, activity and memory
+// access history. Content is decrypted, so store exports securely. Secrets such
+// as API keys and passwords are left out.
+type AccountExport struct {
+	Version             int                         `json:"version"`
+	ExportedAt          time.Time                   `json:"exported_at"`
+	Account             *models.User                `json:"account"`
+	Workspaces          []AccountWorkspace          `json:"workspaces"`
+	Revisions           []models.MemoryRevision     `json:"revisions"`
+	APIKeys             []models.APIKey             `json:"api_keys"`
+	Sessions            []models.MemorySession      `json:"sessions"`
+	SavedSearches       []models.SavedSearch        `json:"saved_searches"`
+	CategorizationRules []models.CategorizationRule `json:"categorization_rules"`
+	Snapshots           []models.MemorySnapshot     `json:"snapshots"`
+	SupportAccess       []models.SupportAccessGrant `json:"support_access"`
+	LLMUsage            []models.LLMUsage           `json:"llm_usage"`
+	Activity            []models.ActivityLog        `json:"activity"`
+	MemoryAccess        []models.MemoryAccessLog    `json:"memory_access"`
+}
+
+// AccountWorkspace is a workspace in an account export with its memories
+type AccountWorkspace struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	CreatedAt   *time.Time     `json:"created_at,omitempty"`
+	Memories    *MemoryArchive `json:"memories"`
+}
+
+// AccountDeletionResult reports how many rows deleting an account removed, by table
+type AccountDeletionResult struct {
+	Deleted map[string]int64 `json:"deleted"`
+}
+
+// accountTable is a table holding a user's rows, deleted with the account
+type accountTable struct {
+	name  string
+	model interface{}
+}
+
+// accountTables are the tables deleting an account clears, rows referring to
+// others first. Memories take their embeddings with them.
+var accountTables = []accountTable{
+	{"maintenance_actions", &models.MaintenanceAction{}},
+	{"maintenance_reports", &models.MaintenanceReport{}},
+	{"attachments", &models.Attachment{}},
+	{"memory_feedback", &models.MemoryFeedback{}},
+	{"memory_links", &models.MemoryLink{}},
+	{"memory_chunks", &models.MemoryChunk{}},
+	{"memory_revisions", &models.MemoryRevision{}},
+	{"memory_provenance", &models.MemoryProvenance{}},
+	{"embedding_jobs", &models.EmbeddingJob{}},
+	{"context_turns", &models.ContextTurn{}},
+	{"memories", &models.Memory{}},
+	{"memory_counters", &models.MemoryCounter{}},
+	{"memory_sessions", &models.MemorySession{}},
+	{"workspaces", &models.Workspace{}},
+	{"saved_searches", &models.SavedSearch{}},
+	{"categorization_rules", &models.CategorizationRule{}},
+	{"llm_usage", &models.LLMUsage{}},
+	{"confirmations", &models.Confirmation{}},
+	{"alerts", &models.Alert{}},
+	{"support_access_grants", &models.SupportAccessGrant{}},
+	{"memory_access_logs", &models.MemoryAccessLog{}},
+	{"activity_logs", &models.ActivityLog{}},
+	{"activity_rollups", &models.ActivityRollup{}},
+	{"performance_metrics", &models.PerformanceMetric{}},
+	{"refresh_tokens", &models.RefreshToken{}},
+	{"revoked_tokens", &models.RevokedToken{}},
+	{"api_keys", &models.APIKey{}},
+}
+
+// ExportAccount collects everything held about the user into one archive, across
+// all workspaces
+func (s *MemoryService) ExportAccount(ctx context.Context) (*AccountExport, error) {
+	db := database.AllWorkspaces(s.db).WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, s.userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+		}
+		return nil, utils.WrapDatabaseError("get user", err)
+	}
+
+	export := &AccountExport{
+		Version:    AccountExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Account:    &user,
+	}
+
+	workspaces, err := s.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, workspace := range workspaces {
+		archive, err := s.InWorkspace(workspace.ID).ExportMemoriesWithOptions(ctx, ExportOptions{IncludeEmbeddings: true})
+		if err != nil {
+			return nil, err
+		}
+		exported := AccountWorkspace{Name: workspace.Name, Description: workspace.Description, Memories: archive}
+		if workspace.ID != 0 {
+			createdAt := workspace.CreatedAt
+			exported.CreatedAt = &createdAt
+		}
+		export.Workspaces = append(export.Workspaces, exported)
+	}
+
+	for _, rows := range []struct {
+		name string
+		dest interface{}
+	}{
+		{"revisions", &export.Revisions},
+		{"API keys", &export.APIKeys},
+		{"sessions", &export.Sessions},
+		{"saved searches", &export.SavedSearches},
+		{"categorization rules", &export.CategorizationRules},
+		{"snapshots", &export.Snapshots},
+		{"support access grants", &export.SupportAccess},
+		{"LLM usage", &export.LLMUsage},
+		{"activity", &export.Activity},
+		{"memory access", &export.MemoryAccess},
+	} {
+		if err := db.Where("user_id = ?", s.userID).Order("id").Find(rows.dest).Error; err != nil {
+			s.logger.Error().Err(err).Str("data", rows.name).Msg("failed to export account data")
+			return nil, utils.WrapDatabaseError("export "+rows.name, err)
+		}
+	}
+
+	for i := range export.Revisions {
+		revision := &export.Revisions[i]
+		if !revision.IsEncrypted {
+			continue
+		}
+		version := &models.Memory{ID: revision.MemoryID, IsEncrypted: true, EncryptedContent: revision.EncryptedContent}
+		if err := s.decryptContent(version); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", revision.ID, err)
+		}
+		revision.Content = version.Content
+	}
+	for i := range export.APIKeys {
+		export.APIKeys[i].Key = ""
+	}
+
+	s.logger.Info().Int("workspace_count", len(export.Workspaces)).Msg("exported account")
+	return export, nil
+}
+
+// DeleteAccount permanently deletes the user's account with all its data: memories
+// and their embeddings, attachments and history, activity logs and their rollups,
+// performance metrics, API keys and settings, in one transaction. Without confirm it deletes
+// nothing and returns an AccountDeletionConfirmationError carrying the token that
+// confirms it; the token only holds while the user's memories stay the same, and
+// is used up and expires like every confirmation token. The system account cannot
+// be deleted.
+func (s *MemoryService) DeleteAccount(ctx context.Context, confirm string) (*AccountDeletionResult, error) {
+	if s.userID == database.SystemUserID {
+		return nil, utils.InvalidFieldError("user", "the system account cannot be deleted")
+	}
+	db := database.AllWorkspaces(s.db).WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, s.userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+		}
+		return nil, utils.WrapDatabaseError("get user", err)
+	}
+
+	var memories struct {
+		Count  int64
+		LastID uint
+	}
+	if err := db.Model(&models.Memory{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS last_id").
+		Where("user_id = ?", s.userID).
+		Scan(&memories).Error; err != nil {
+		return nil, utils.WrapDatabaseError("count memories", err)
+	}
+	fingerprint := confirmationFingerprint(user.Email, user.CreatedAt.UnixNano(), memories.Count, memories.LastID)
+	token, err := s.confirm(ctx, ConfirmDeleteAccount, fingerprint, confirm)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		return nil, &AccountDeletionConfirmationError{Memories: memories.Count, Token: token}
+	}
+
+	attachmentKeys := s.attachmentObjectKeys(ctx)
+	result := &AccountDeletionResult{Deleted: make(map[string]int64, len(accountTables)+2)}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		snapshots := tx.Model(&models.MemorySnapshot{}).Select("id").Where("user_id = ?", s.userID)
+		deleted := tx.Where("snapshot_id IN (?)", snapshots).Delete(&models.MemorySnapshotItem{})
+		if deleted.Error != nil {
+			return utils.WrapDatabaseError("delete memory_snapshot_items", deleted.Error)
+		}
+		result.Deleted["memory_snapshot_items"] = deleted.RowsAffected
+		if deleted = tx.Where("user_id = ?", s.userID).Delete(&models.MemorySnapshot{}); deleted.Error != nil {
+			return utils.WrapDatabaseError("delete memory_snapshots", deleted.Error)
+		}
+		result.Deleted["memory_snapshots"] = deleted.RowsAffected
+
+		for _, table := range accountTables {
+			deleted := tx.Where("user_id = ?", s.userID).Delete(table.model)
+			if deleted.Error != nil {
+				return utils.WrapDatabaseError("delete "+table.name, deleted.Error)
+			}
+			result.Deleted[table.name] = deleted.RowsAffected
+		}
+
+		if deleted = tx.Delete(&models.User{}, s.userID); deleted.Error != nil {
+			return utils.WrapDatabaseError("delete user", deleted.Error)
+		}
+		result.Deleted["users"] = deleted.RowsAffected
+		return nil
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to delete account")
+		return nil, err
+	}
+	s.removeAttachmentObjects(ctx, attachmentKeys)
+
+	// Activity logs went with the account, so the deletion is only logged here
+	s.logger.Info().Int64("memories", result.Deleted["memories"]).Msg("deleted account")
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// setupAccountDB creates a database with every table an account holds rows in
+func setupAccountDB(t *testing.T) *gorm.DB {
+	tables := []interface{}{&models.User{}, &models.MemorySnapshot{}, &models.MemorySnapshotItem{}}
+	for _, table := range accountTables {
+		switch table.model.(type) {
+		case *models.Memory, *models.MemoryChunk:
+			// SQLiteDB creates these without pgvector types
+		default:
+			tables = append(tables, table.model)
+		}
+	}
+	return testutil.SQLiteDB(t, tables...)
+}
+
+func TestMemoryService_ExportAccount(t *testing.T) {
+	ctx := context.Background()
+	db := setupAccountDB(t)
+	user := testutil.NewUser().ID(2).Create(t, db)
+	testutil.NewAPIKey(user.ID).Name("laptop").Create(t, db)
+	service := NewMemoryServiceWithUser(db, nil, zerolog.Nop(), nil, user.ID)
+
+	work, err := service.CreateWorkspace(ctx, "work", "my job")
+	require.NoError(t, err)
+	storeTestMemory(t, service.InWorkspace(0), "allergic to peanuts")
+	memory, _ := storeTestMemory(t, service.InWorkspace(work.ID), "team lead is Sam")
+	_, err = service.InWorkspace(work.ID).Update(ctx, memory.ID, UpdateRequest{Content: "team lead is Alex"})
+	require.NoError(t, err)
+	_, err = service.SaveSearch(ctx, SavedSearchSpec{Name: "allergies", Query: "allergic"})
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.ActivityLog{UserID: user.ID, Type: models.ActivityLogin}).Error)
+
+	export, err := service.ExportAccount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, AccountExportVersion, export.Version)
+	assert.Equal(t, user.Email, export.Account.Email)
+
+	require.Len(t, export.Workspaces, 2)
+	assert.Equal(t, models.DefaultWorkspace, export.Workspaces[0].Name)
+	require.Len(t, export.Workspaces[0].Memories.Memories, 1)
+	assert.Equal(t, "allergic to peanuts", export.Workspaces[0].Memories.Memories[0].Content)
+	assert.Equal(t, "work", export.Workspaces[1].Name)
+	require.Len(t, export.Workspaces[1].Memories.Memories, 1)
+	assert.Equal(t, "team lead is Alex", export.Workspaces[1].Memories.Memories[0].Content)
+
+	require.Len(t, export.Revisions, 1)
+	assert.Equal(t, "team lead is Sam", export.Revisions[0].Content)
+	require.Len(t, export.APIKeys, 1)
+	assert.Equal(t, "laptop", export.APIKeys[0].Name)
+	assert.Empty(t, export.APIKeys[0].Key, "API keys are secrets and left out")
+	require.Len(t, export.SavedSearches, 1)
+	require.Len(t, export.Activity, 1)
+	assert.Equal(t, models.ActivityLogin, export.Activity[0].Type)
+}
+
+func TestMemoryService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	db := setupAccountDB(t)
+	user := testutil.NewUser().ID(2).Create(t, db)
+	other := testutil.NewUser().ID(3).Create(t, db)
+	testutil.NewAPIKey(user.ID).Create(t, db)
+	testutil.NewAPIKey(other.ID).Create(t, db)
+
+	service := NewMemoryServiceWithUser(db, nil, zerolog.Nop(), nil, user.ID)
+	otherService := NewMemoryServiceWithUser(db, nil, zerolog.Nop(), nil, other.ID)
+	work, err := service.CreateWorkspace(ctx, "work", "")
+	require.NoError(t, err)
+	storeTestMemory(t, service.InWorkspace(0), "allergic to peanuts")
+	storeTestMemory(t, service.InWorkspace(work.ID), "team lead is Sam")
+	kept, _ := storeTestMemory(t, otherService, "likes hiking")
+	_, err = service.CreateSnapshot(ctx, "before", "")
+	require.NoError(t, err)
+
+	// The first attempt deletes nothing and returns the confirmation token
+	_, err = service.DeleteAccount(ctx, "")
+	var confirmErr *AccountDeletionConfirmationError
+	require.True(t, errors.As(err, &confirmErr))
+	assert.ErrorIs(t, err, ErrAccountDeletionConfirmationRequired)
+	assert.Equal(t, int64(2), confirmErr.Memories)
+
+	_, err = service.DeleteAccount(ctx, "wrong")
+	assert.ErrorIs(t, err, ErrAccountDeletionConfirmationRequired)
+
+	result, err := service.DeleteAccount(ctx, confirmErr.Token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Deleted["memories"])
+	assert.Equal(t, int64(1), result.Deleted["api_keys"])
+	assert.Equal(t, int64(1), result.Deleted["workspaces"])
+	assert.Equal(t, int64(1), result.Deleted["memory_snapshots"])
+	assert.Equal(t, int64(2), result.Deleted["memory_snapshot_items"])
+	assert.Equal(t, int64(1), result.Deleted["users"])
+
+	for _, table := range []string{"memories", "api_keys", "activity_logs", "workspaces", "memory_snapshots"} {
+		var remaining int64
+		require.NoError(t, db.Table(table).Where("user_id = ?", user.ID).Count(&remaining).Error, table)
+		assert.Zero(t, remaining, table)
+	}
+	var users int64
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&users).Error)
+	assert.Zero(t, users)
+
+	// Other accounts are untouched
+	got, err := otherService.GetByID(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "likes hiking", got.Content)
+	var keys int64
+	require.NoError(t, db.Model(&models.APIKey{}).Where("user_id = ?", other.ID).Count(&keys).Error)
+	assert.Equal(t, int64(1), keys)
+
+	_, err = service.DeleteAccount(ctx, confirmErr.Token)
+	assert.True(t, utils.IsNotFoundError(err))
+}
+
+func TestMemoryService_DeleteAccount_SystemUser(t *testing.T) {
+	service := NewMemoryService(setupAccountDB(t), nil, zerolog.Nop(), nil)
+	_, err := service.DeleteAccount(context.Background(), "")
+	assert.True(t, utils.IsValidationError(err))
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+const (
+	// rollupSettle is how long after a day ends it is rolled up, so activity
+	// logged in the background around midnight is still counted
+	rollupSettle = time.Hour
+	// pruneBatchSize is how many rows each prune statement deletes
+	pruneBatchSize = 5000
+	// MaxTrendDays caps the days a trend covers
+	MaxTrendDays = 730
+)
+
+// ActivityRetentionConfig bounds how long raw activity logs and performance
+// metrics are kept. Every finished day is rolled up into daily counts first, so
+// trends outlast the raw rows.
+type ActivityRetentionConfig struct {
+	// Interval is how often days are rolled up and old rows pruned
+	Interval time.Duration
+	// ActivityLogs is how long activity logs are kept; 0 keeps them forever
+	ActivityLogs time.Duration
+	// PerformanceMetrics is how long performance metrics are kept; 0 keeps them
+	// forever
+	PerformanceMetrics time.Duration
+}
+
+// DefaultActivityRetentionConfig returns the default retention: 90 days of both,
+// checked daily
+func DefaultActivityRetentionConfig() ActivityRetentionConfig {
+	return ActivityRetentionConfig{
+		Interval:           24 * time.Hour,
+		ActivityLogs:       90 * 24 * time.Hour,
+		PerformanceMetrics: 90 * 24 * time.Hour,
+	}
+}
+
+// RetentionResult reports what one retention run rolled up and pruned
+type RetentionResult struct {
+	ActivityDays             int   `json:"activity_days"`
+	PerformanceDays          int   `json:"performance_days"`
+	ActivityLogsPruned       int64 `json:"activity_logs_pruned"`
+	PerformanceMetricsPruned int64 `json:"performance_metrics_pruned"`
+}
+
+// ActivityTrendDay is one day of activity counts by type
+type ActivityTrendDay struct {
+	Date   string           `json:"date"`
+	Total  int64            `json:"total"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+// PerformanceTrendDay is one day of request counts and latency
+type PerformanceTrendDay struct {
+	Date          string `json:"date"`
+	Requests      int64  `json:"requests"`
+	Errors        int64  `json:"errors"`
+	AvgDurationMs int64  `json:"avg_duration_ms"`
+	MaxDurationMs int    `json:"max_duration_ms"`
+}
+
+// utcDay returns the start of the UTC day t falls in
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ApplyRetention rolls up every finished day not rolled up yet, then deletes the
+// activity logs and performance metrics older than the configured retention.
+// Only rolled-up days are pruned, so nothing is lost from the trends.
+func (s *ActivityService) ApplyRetention(ctx context.Context, config ActivityRetentionConfig, now time.Time) (*RetentionResult, error) {
+	// Days before rolledUp are over and, once the rollups below succeed, counted
+	rolledUp := utcDay(now.Add(-rollupSettle))
+	result := &RetentionResult{}
+
+	var err error
+	if result.ActivityDays, err = s.rollUpDays(ctx, &models.ActivityRollup{}, &models.ActivityLog{}, rolledUp, s.rollUpActivity); err != nil {
+		return nil, fmt.Errorf("failed to roll up activity: %w", err)
+	}
+	if result.PerformanceDays, err = s.rollUpDays(ctx, &models.PerformanceRollup{}, &models.PerformanceMetric{}, rolledUp, s.rollUpPerformance); err != nil {
+		return nil, fmt.Errorf("failed to roll up performance metrics: %w", err)
+	}
+
+	if config.ActivityLogs > 0 {
+		cutoff := utcDay(now.Add(-config.ActivityLogs))
+		if cutoff.After(rolledUp) {
+			cutoff = rolledUp
+		}
+		if result.ActivityLogsPruned, err = s.prune(ctx, &models.ActivityLog{}, cutoff); err != nil {
+			return nil, fmt.Errorf("failed to prune activity logs: %w", err)
+		}
+	}
+	if config.PerformanceMetrics > 0 {
+		cutoff := utcDay(now.Add(-config.PerformanceMetrics))
+		if cutoff.After(rolledUp) {
+			cutoff = rolledUp
+		}
+		if result.PerformanceMetricsPruned, err = s.prune(ctx, &models.PerformanceMetric{}, cutoff); err != nil {
+			return nil, fmt.Errorf("failed to prune performance metrics: %w", err)
+		}
+	}
+
+	if result.ActivityLogsPruned > 0 || result.PerformanceMetricsPruned > 0 {
+		s.logger.Info().
+			Int64("activity_logs", result.ActivityLogsPruned).
+			Int64("performance_metrics", result.PerformanceMetricsPruned).
+			Msg("Pruned old activity")
+	}
+	return result, nil
+}
+
+// rollUpDays rolls up each day from that of the oldest raw row after the latest
+// rollup until end. Each day is rolled up in its own transaction, so a day is
+// counted exactly once even when a run is interrupted.
+func (s *ActivityService) rollUpDays(ctx context.Context, rollup, raw interface{}, end time.Time, rollUpDay func(tx *gorm.DB, day time.Time) error) (int, error) {
+	db := s.db.WithContext(ctx)
+
+	var latest struct{ Day time.Time }
+	query := db.Model(rollup).Select("day").Order("day DESC").Limit(1).Scan(&latest)
+	if query.Error != nil {
+		return 0, query.Error
+	}
+	// Days without activity leave no rollup, so skip straight to the next raw row
+	oldestQuery := db.Unscoped().Model(raw).Select("created_at").Order("created_at").Limit(1)
+	if query.RowsAffected > 0 {
+		oldestQuery = oldestQuery.Where("created_at >= ?", utcDay(latest.Day).AddDate(0, 0, 1))
+	}
+	var oldest struct{ CreatedAt time.Time }
+	query = oldestQuery.Scan(&oldest)
+	if query.Error != nil {
+		return 0, query.Error
+	}
+	if query.RowsAffected == 0 {
+		return 0, nil
+	}
+	day := utcDay(oldest.CreatedAt)
+
+	days := 0
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return days, err
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return rollUpDay(tx, day)
+		}); err != nil {
+			return days, fmt.Errorf("%s: %w", day.Format(time.DateOnly), err)
+		}
+		days++
+	}
+	return days, nil
+}
+
+// rollUpActivity counts a day's activity logs by user and type
+func (s *ActivityService) rollUpActivity(tx *gorm.DB, day time.Time) error {
+	var rollups []models.ActivityRollup
+	if err := tx.Unscoped().Model(&models.ActivityLog{}).
+		Select("user_id, type, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", day, day.AddDate(0, 0, 1)).
+		Group("user_id, type").
+		Scan(&rollups).Error; err != nil {
+		return err
+	}
+	if len(rollups) == 0 {
+		return nil
+	}
+	for i := range rollups {
+		rollups[i].Day = day
+	}
+	return tx.CreateInBatches(&rollups, 500).Error
+}
+
+// rollUpPerformance summarizes a day's performance metrics by endpoint
+func (s *ActivityService) rollUpPerformance(tx *gorm.DB, day time.Time) error {
+	var rollups []models.PerformanceRollup
+	if err := tx.Model(&models.PerformanceMetric{}).
+		Select("endpoint, method, COUNT(*) AS requests, "+
+			"SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS errors, "+
+			"COALESCE(SUM(duration_ms), 0) AS total_duration_ms, "+
+			"COALESCE(MAX(duration_ms), 0) AS max_duration_ms").
+		Where("created_at >= ? AND created_at < ?", day, day.AddDate(0, 0, 1)).
+		Group("endpoint, method").
+		Scan(&rollups).Error; err != nil {
+		return err
+	}
+	if len(rollups) == 0 {
+		return nil
+	}
+	for i := range rollups {
+		rollups[i].Day = day
+	}
+	return tx.CreateInBatches(&rollups, 500).Error
+}
+
+// prune deletes the rows of model created before cutoff, in batches so no single
+// statement holds locks on a large table for long
+func (s *ActivityService) prune(ctx context.Context, model interface{}, cutoff time.Time) (int64, error) {
+	db := s.db.WithContext(ctx).Unscoped().Session(&gorm.Session{})
+	var pruned int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+		batch := db.Model(model).Select("id").Where("created_at < ?", cutoff).Order("id").Limit(pruneBatchSize)
+		deleted := db.Where("id IN (?)", batch).Delete(model)
+		if deleted.Error != nil {
+			return pruned, deleted.Error
+		}
+		pruned += deleted.RowsAffected
+		if deleted.RowsAffected < pruneBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// ActivityTrend returns the daily activity counts rolled up since from, oldest
+// first, for one user or, with a nil userID, everyone. Days without activity are
+// left out; the current day appears once it has been rolled up.
+func (s *ActivityService) ActivityTrend(ctx context.Context, userID *uint, from time.Time) ([]ActivityTrendDay, error) {
+	query := s.db.WithContext(ctx).Model(&models.ActivityRollup{}).
+		Select("day, type, SUM(count) AS count").
+		Where("day >= ?", utcDay(from))
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	var rows []struct {
+		Day   time.Time
+		Type  string
+		Count int64
+	}
+	if err := query.Group("day, type").Order("day, type").Scan(&rows).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get activity trend")
+		return nil, err
+	}
+
+	trend := []ActivityTrendDay{}
+	for _, row := range rows {
+		date := row.Day.UTC().Format(time.DateOnly)
+		if len(trend) == 0 || trend[len(trend)-1].Date != date {
+			trend = append(trend, ActivityTrendDay{Date: date, Counts: make(map[string]int64)})
+		}
+		day := &trend[len(trend)-1]
+		day.Counts[row.Type] += row.Count
+		day.Total += row.Count
+	}
+	return trend, nil
+}
+
+// PerformanceTrend returns the daily request counts and latency rolled up since
+// from, oldest first
+func (s *ActivityService) PerformanceTrend(ctx context.Context, from time.Time) ([]PerformanceTrendDay, error) {
+	var rows []struct {
+		Day             time.Time
+		Requests        int64
+		Errors          int64
+		TotalDurationMs int64
+		MaxDurationMs   int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.PerformanceRollup{}).
+		Select("day, SUM(requests) AS requests, SUM(errors) AS errors, "+
+			"SUM(total_duration_ms) AS total_duration_ms, MAX(max_duration_ms) AS max_duration_ms").
+		Where("day >= ?", utcDay(from)).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error; err != nil {
+		s.logger.Error().Err(err).Msg("Failed to get performance trend")
+		return nil, err
+	}
+
+	trend := make([]PerformanceTrendDay, 0, len(rows))
+	for _, row := range rows {
+		day := PerformanceTrendDay{
+			Date:          row.Day.UTC().Format(time.DateOnly),
+			Requests:      row.Requests,
+			Errors:        row.Errors,
+			MaxDurationMs: row.MaxDurationMs,
+		}
+		if row.Requests > 0 {
+			day.AvgDurationMs = row.TotalDurationMs / row.Requests
+		}
+		trend = append(trend, day)
+	}
+	return trend, nil
+}
+
+// ActivityRetentionWorker periodically rolls up finished days of activity and
+// prunes the raw rows past their retention
+type ActivityRetentionWorker struct {
+	service *ActivityService
+	config  ActivityRetentionConfig
+}
+
+// NewActivityRetentionWorker creates a retention worker for the activity service
+func NewActivityRetentionWorker(service *ActivityService, config ActivityRetentionConfig) *ActivityRetentionWorker {
+	return &ActivityRetentionWorker{
+		service: service,
+		config:  config,
+	}
+}
+
+// Start runs the worker until the context is cancelled, applying the retention
+// once straight away
+func (w *ActivityRetentionWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.service.ApplyRetention(ctx, w.config, time.Now()); err != nil && ctx.Err() == nil {
+			w.service.logger.Error().Err(err).Msg("Activity retention run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
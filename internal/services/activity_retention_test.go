@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+)
+
+// setupRetentionDB creates a database with the activity tables and their rollups
+func setupRetentionDB(t *testing.T) *gorm.DB {
+	db := testutil.SQLiteDB(t, &models.User{}, &models.ActivityLog{}, &models.PerformanceMetric{}, &models.ActivityRollup{}, &models.PerformanceRollup{})
+	// The legacy response_time column is not migrated but is still written
+	require.NoError(t, db.Exec(`ALTER TABLE performance_metrics ADD COLUMN response_time INTEGER NOT NULL DEFAULT 0`).Error)
+	return db
+}
+
+func TestActivityService_ApplyRetention(t *testing.T) {
+	ctx := context.Background()
+	db := setupRetentionDB(t)
+	service := NewActivityService(db, zerolog.Nop())
+
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -120)
+	recent := now.AddDate(0, 0, -10)
+	user := uint(2)
+	for _, activity := range []models.ActivityLog{
+		{UserID: user, Type: models.ActivityMemorySearch, CreatedAt: old},
+		{UserID: user, Type: models.ActivityMemorySearch, CreatedAt: old.Add(time.Hour)},
+		{UserID: user, Type: models.ActivityMemoryStored, CreatedAt: old},
+		{UserID: 3, Type: models.ActivityMemorySearch, CreatedAt: old},
+		{UserID: user, Type: models.ActivityMemorySearch, CreatedAt: recent},
+		{UserID: user, Type: models.ActivityLogin, CreatedAt: now},
+	} {
+		require.NoError(t, db.Create(&activity).Error)
+	}
+	for _, metric := range []models.PerformanceMetric{
+		{Endpoint: "/api/v1/memories", Method: "GET", DurationMs: 10, StatusCode: 200, CreatedAt: old},
+		{Endpoint: "/api/v1/memories", Method: "GET", DurationMs: 30, StatusCode: 500, CreatedAt: old},
+		{Endpoint: "/api/v1/memories", Method: "GET", DurationMs: 20, StatusCode: 200, CreatedAt: recent},
+	} {
+		require.NoError(t, db.Create(&metric).Error)
+	}
+
+	config := ActivityRetentionConfig{ActivityLogs: 90 * 24 * time.Hour, PerformanceMetrics: 90 * 24 * time.Hour}
+	result, err := service.ApplyRetention(ctx, config, now)
+	require.NoError(t, err)
+	assert.Equal(t, 120, result.ActivityDays, "every finished day since the oldest log is rolled up")
+	assert.Equal(t, 120, result.PerformanceDays)
+	assert.Equal(t, int64(4), result.ActivityLogsPruned)
+	assert.Equal(t, int64(2), result.PerformanceMetricsPruned)
+
+	var remaining int64
+	require.NoError(t, db.Model(&models.ActivityLog{}).Count(&remaining).Error)
+	assert.Equal(t, int64(2), remaining)
+
+	// Trends keep the pruned days
+	trend, err := service.ActivityTrend(ctx, &user, old)
+	require.NoError(t, err)
+	require.Len(t, trend, 2, "today is not rolled up yet")
+	assert.Equal(t, old.Format(time.DateOnly), trend[0].Date)
+	assert.Equal(t, int64(3), trend[0].Total)
+	assert.Equal(t, int64(2), trend[0].Counts[models.ActivityMemorySearch])
+	assert.Equal(t, recent.Format(time.DateOnly), trend[1].Date)
+	assert.Equal(t, int64(1), trend[1].Total)
+
+	everyone, err := service.ActivityTrend(ctx, nil, old)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), everyone[0].Total)
+
+	performance, err := service.PerformanceTrend(ctx, old)
+	require.NoError(t, err)
+	require.Len(t, performance, 2)
+	assert.Equal(t, int64(2), performance[0].Requests)
+	assert.Equal(t, int64(1), performance[0].Errors)
+	assert.Equal(t, int64(20), performance[0].AvgDurationMs)
+	assert.Equal(t, 30, performance[0].MaxDurationMs)
+
+	// A second run the next day only rolls up the day that finished since
+	result, err = service.ApplyRetention(ctx, config, now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ActivityDays)
+	assert.Zero(t, result.ActivityLogsPruned)
+	trend, err = service.ActivityTrend(ctx, &user, old)
+	require.NoError(t, err)
+	require.Len(t, trend, 3)
+	assert.Equal(t, int64(3), trend[0].Total, "days are counted once")
+	assert.Equal(t, int64(1), trend[2].Counts[models.ActivityLogin])
+}
+
+func TestActivityService_ApplyRetention_KeepsForever(t *testing.T) {
+	db := setupRetentionDB(t)
+	service := NewActivityService(db, zerolog.Nop())
+	now := time.Now().UTC()
+	require.NoError(t, db.Create(&models.ActivityLog{UserID: 2, Type: models.ActivityLogin, CreatedAt: now.AddDate(-2, 0, 0)}).Error)
+
+	result, err := service.ApplyRetention(context.Background(), ActivityRetentionConfig{}, now)
+	require.NoError(t, err)
+	assert.Zero(t, result.ActivityLogsPruned)
+	assert.Positive(t, result.ActivityDays)
+}
@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ksred/remember-me-mcp/internal/geoip"
 	"github.com/ksred/remember-me-mcp/internal/models"
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 )
 
 type ActivityService struct {
-	db     *gorm.DB
-	logger zerolog.Logger
+	db       *gorm.DB
+	logger   zerolog.Logger
+	detector *AnomalyDetector
+	geo      GeoLocator
+}
+
+// GeoLocator resolves an IP address to a coarse location, returning nil when unknown
+type GeoLocator interface {
+	LookupString(ip string) *geoip.Location
 }
 
 func NewActivityService(db *gorm.DB, logger zerolog.Logger) *ActivityService {
@@ -23,6 +31,21 @@ func NewActivityService(db *gorm.DB, logger zerolog.Logger) *ActivityService {
 	}
 }
 
+// SetAnomalyDetector enables anomaly checks on every logged activity
+func (s *ActivityService) SetAnomalyDetector(detector *AnomalyDetector) {
+	s.detector = detector
+}
+
+// SetGeoLocator enables geo enrichment of logged activity
+func (s *ActivityService) SetGeoLocator(geo GeoLocator) {
+	s.geo = geo
+}
+
+// AnomalyDetector returns the configured anomaly detector, or nil if detection is disabled
+func (s *ActivityService) AnomalyDetector() *AnomalyDetector {
+	return s.detector
+}
+
 // LogActivity logs user activity
 func (s *ActivityService) LogActivity(ctx context.Context, userID uint, activityType string, details map[string]interface{}, ipAddress, userAgent string) error {
 	activity := &models.ActivityLog{
@@ -33,6 +56,14 @@ func (s *ActivityService) LogActivity(ctx context.Context, userID uint, activity
 		CreatedAt: time.Now(),
 	}
 
+	if s.geo != nil && ipAddress != "" {
+		if loc := s.geo.LookupString(ipAddress); loc != nil {
+			activity.CountryCode = loc.CountryCode
+			activity.Country = loc.Country
+			activity.City = loc.City
+		}
+	}
+
 	// Set details using the new method
 	if err := activity.SetDetailsFromMap(details); err != nil {
 		s.logger.Error().Err(err).Msg("Failed to marshal activity details")
@@ -44,6 +75,10 @@ func (s *ActivityService) LogActivity(ctx context.Context, userID uint, activity
 		return err
 	}
 
+	if s.detector != nil {
+		s.detector.CheckActivity(ctx, activity)
+	}
+
 	return nil
 }
 
@@ -278,6 +313,13 @@ func (s *ActivityService) getRecentActivity(ctx context.Context, userID uint, li
 		if activity.UserAgent != "" {
 			result["user_agent"] = activity.UserAgent
 		}
+		if activity.CountryCode != "" {
+			result["country_code"] = activity.CountryCode
+			result["country"] = activity.Country
+		}
+		if activity.City != "" {
+			result["city"] = activity.City
+		}
 
 		results[i] = result
 	}
@@ -336,6 +378,120 @@ func (s *ActivityService) getActivityDescription(activity models.ActivityLog) st
 	case models.ActivityLogin:
 		return "Logged in"
 	
+	case models.ActivitySnapshotCreated:
+		if details != nil {
+			if name, ok := details["name"].(string); ok {
+				return fmt.Sprintf("Created memory snapshot: %s", name)
+			}
+		}
+		return "Created memory snapshot"
+	
+	case models.ActivitySnapshotRestored:
+		if details != nil {
+			if name, ok := details["name"].(string); ok {
+				return fmt.Sprintf("Restored memory snapshot: %s", name)
+			}
+		}
+		return "Restored memory snapshot"
+	
+	case models.ActivityConfigImported:
+		return "Imported configuration bundle"
+	
+	case models.ActivityMemoryEvicted:
+		if details != nil {
+			if count, ok := details["count"].(float64); ok {
+				return fmt.Sprintf("%d memories evicted at the memory limit", int(count))
+			}
+		}
+		return "Memories evicted at the memory limit"
+	
+	case models.ActivityMemoriesExported:
+		return "Exported memories"
+	
+	case models.ActivityMemoriesImported:
+		if details != nil {
+			if created, ok := details["created"].(float64); ok {
+				return fmt.Sprintf("Imported %d memories", int(created))
+			}
+		}
+		return "Imported memories"
+	
+	case models.ActivityMemoriesDeleted:
+		if details != nil {
+			if deleted, ok := details["deleted"].(float64); ok {
+				return fmt.Sprintf("Deleted %d memories matching filters", int(deleted))
+			}
+		}
+		return "Deleted memories matching filters"
+	
+	case models.ActivityMemoriesConsolidated:
+		if details != nil {
+			if consolidated, ok := details["consolidated"].(float64); ok {
+				return fmt.Sprintf("Consolidated %d clusters of related memories", int(consolidated))
+			}
+		}
+		return "Consolidated related memories"
+	
+	case models.ActivityMemoriesRecategorized:
+		if details != nil {
+			if changed, ok := details["changed"].(float64); ok {
+				return fmt.Sprintf("Categorization rules changed %d memories", int(changed))
+			}
+		}
+		return "Categorization rules changed memories"
+	
+	case models.ActivityContentModerated:
+		if details != nil {
+			if action, ok := details["action"].(string); ok {
+				switch action {
+				case ModerationBlock:
+					return "Memory blocked by content moderation"
+				case ModerationEncrypt:
+					return "Memory encrypted by content moderation"
+				}
+			}
+		}
+		return "Memory flagged by content moderation"
+	
+	case models.ActivitySupportAccessGranted:
+		return "Granted temporary support access"
+	
+	case models.ActivitySupportAccessUsed:
+		if details != nil {
+			if memoryID, ok := details["memory_id"].(float64); ok {
+				return fmt.Sprintf("Support viewed memory #%d", int(memoryID))
+			}
+		}
+		return "Support viewed a memory"
+
+	case models.ActivitySupportImpersonation:
+		if details != nil {
+			if query, ok := details["query"].(string); ok && query != "" {
+				return fmt.Sprintf("Support searched your memories for %q", truncateString(query, 50))
+			}
+		}
+		return "Support searched your memories"
+	
+	case models.ActivityAccountChanged:
+		if details != nil {
+			switch details["change"] {
+			case "disabled":
+				return "Account disabled by an admin"
+			case "enabled":
+				return "Account re-enabled by an admin"
+			case "password_reset":
+				return "Password reset by an admin"
+			case "role":
+				if role, ok := details["role"].(string); ok {
+					return fmt.Sprintf("Role changed to %s by an admin", role)
+				}
+			}
+		}
+		return "Account changed by an admin"
+
+	case models.ActivityAccountExported:
+		return "Downloaded all account data"
+
 	default:
 		return fmt.Sprintf("Performed %s action", activity.Type)
 	}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// AnomalyDetectorConfig tunes the anomaly detection rules
+type AnomalyDetectorConfig struct {
+	// DeleteSpikeThreshold is the number of deletes within DeleteSpikeWindow that raises an alert
+	DeleteSpikeThreshold int
+	DeleteSpikeWindow    time.Duration
+	// APIKeyLearningUses is how many uses a key needs before off-hours usage is flagged
+	APIKeyLearningUses int64
+	// CountryHistory is how far back known countries are considered
+	CountryHistory time.Duration
+	// DedupeWindow suppresses repeat alerts for the same user and rule
+	DedupeWindow time.Duration
+}
+
+// DefaultAnomalyDetectorConfig returns the default detection thresholds
+func DefaultAnomalyDetectorConfig() AnomalyDetectorConfig {
+	return AnomalyDetectorConfig{
+		DeleteSpikeThreshold: 20,
+		DeleteSpikeWindow:    10 * time.Minute,
+		APIKeyLearningUses:   50,
+		CountryHistory:       90 * 24 * time.Hour,
+		DedupeWindow:         time.Hour,
+	}
+}
+
+// AnomalyDetector watches activity for suspicious patterns, stores alerts and
+// sends them through the configured notifier
+type AnomalyDetector struct {
+	db       *gorm.DB
+	notifier Notifier
+	config   AnomalyDetectorConfig
+	logger   zerolog.Logger
+}
+
+// NewAnomalyDetector creates a new AnomalyDetector. The notifier is optional. The
+// new-country rule relies on activity being geo-enriched and is inert otherwise.
+func NewAnomalyDetector(db *gorm.DB, notifier Notifier, config AnomalyDetectorConfig, logger zerolog.Logger) *AnomalyDetector {
+	return &AnomalyDetector{
+		db:       db,
+		notifier: notifier,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// CheckActivity evaluates the rules that apply to a freshly logged activity
+func (d *AnomalyDetector) CheckActivity(ctx context.Context, activity *models.ActivityLog) {
+	switch activity.Type {
+	case models.ActivityMemoryDeleted:
+		d.checkDeleteSpike(ctx, activity)
+	case models.ActivityMemorySearch, models.ActivityLogin:
+		d.checkNewCountry(ctx, activity)
+	}
+}
+
+// ObserveAPIKeyUse records the hour a key was used in and flags use outside the
+// hours it has historically been used once enough history has been collected
+func (d *AnomalyDetector) ObserveAPIKeyUse(ctx context.Context, key *models.APIKey, ip string, at time.Time) {
+	hour := at.UTC().Hour()
+	bit := 1 << hour
+
+	if key.UsageCount >= d.config.APIKeyLearningUses && key.UsageHours&bit == 0 {
+		d.raise(ctx, &models.Alert{
+			UserID:   key.UserID,
+			Rule:     models.AlertRuleAPIKeyOffHours,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("API key %q used at %02d:00 UTC, outside its usual hours", key.Name, hour),
+		}, map[string]interface{}{
+			"api_key_id": key.ID,
+			"name":       key.Name,
+			"hour_utc":   hour,
+			"ip_address": ip,
+		})
+	}
+
+	if err := d.db.WithContext(ctx).Exec(
+		"UPDATE api_keys SET usage_hours = usage_hours | ?, usage_count = usage_count + 1 WHERE id = ?",
+		bit, key.ID,
+	).Error; err != nil {
+		d.logger.Error().Err(err).Uint("api_key_id", key.ID).Msg("failed to record API key usage")
+	}
+}
+
+// checkDeleteSpike flags a burst of deletes within the configured window
+func (d *AnomalyDetector) checkDeleteSpike(ctx context.Context, activity *models.ActivityLog) {
+	var count int64
+	since := time.Now().Add(-d.config.DeleteSpikeWindow)
+	if err := d.db.WithContext(ctx).Model(&models.ActivityLog{}).
+		Where("user_id = ? AND type = ? AND created_at >= ?", activity.UserID, models.ActivityMemoryDeleted, since).
+		Count(&count).Error; err != nil {
+		d.logger.Error().Err(err).Msg("failed to count recent deletes")
+		return
+	}
+
+	if count < int64(d.config.DeleteSpikeThreshold) {
+		return
+	}
+
+	d.raise(ctx, &models.Alert{
+		UserID:   activity.UserID,
+		Rule:     models.AlertRuleDeleteSpike,
+		Severity: SeverityCritical,
+		Message:  fmt.Sprintf("%d memories deleted in the last %s", count, d.config.DeleteSpikeWindow),
+	}, map[string]interface{}{
+		"deletes":    count,
+		"window":     d.config.DeleteSpikeWindow.String(),
+		"ip_address": activity.IPAddress,
+	})
+}
+
+// checkNewCountry flags activity from a country the user has not been seen in before
+func (d *AnomalyDetector) checkNewCountry(ctx context.Context, activity *models.ActivityLog) {
+	country := activity.CountryCode
+	if country == "" {
+		return
+	}
+
+	var known []string
+	if err := d.db.WithContext(ctx).Model(&models.ActivityLog{}).
+		Distinct("country_code").
+		Where("user_id = ? AND id <> ? AND created_at >= ? AND country_code IS NOT NULL AND country_code <> ''",
+			activity.UserID, activity.ID, time.Now().Add(-d.config.CountryHistory)).
+		Pluck("country_code", &known).Error; err != nil {
+		d.logger.Error().Err(err).Msg("failed to load previous activity countries")
+		return
+	}
+
+	// Without any history there is nothing to compare against
+	if len(known) == 0 {
+		return
+	}
+	for _, c := range known {
+		if c == country {
+			return
+		}
+	}
+
+	sort.Strings(known)
+
+	d.raise(ctx, &models.Alert{
+		UserID:   activity.UserID,
+		Rule:     models.AlertRuleNewCountry,
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("Activity from new country %s", country),
+	}, map[string]interface{}{
+		"country":         country,
+		"known_countries": known,
+		"ip_address":      activity.IPAddress,
+		"activity_type":   activity.Type,
+	})
+}
+
+// raise stores an alert and notifies, unless an unacknowledged alert for the same
+// user and rule was raised within the dedupe window
+func (d *AnomalyDetector) raise(ctx context.Context, alert *models.Alert, details map[string]interface{}) {
+	var recent int64
+	if err := d.db.WithContext(ctx).Model(&models.Alert{}).
+		Where("user_id = ? AND rule = ? AND acknowledged_at IS NULL AND created_at >= ?",
+			alert.UserID, alert.Rule, time.Now().Add(-d.config.DedupeWindow)).
+		Count(&recent).Error; err != nil {
+		d.logger.Error().Err(err).Msg("failed to check for duplicate alerts")
+		return
+	}
+	if recent > 0 {
+		return
+	}
+
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err == nil {
+			alert.Details = data
+		}
+	}
+
+	if err := d.db.WithContext(ctx).Create(alert).Error; err != nil {
+		d.logger.Error().Err(err).Str("rule", alert.Rule).Msg("failed to store alert")
+		return
+	}
+
+	d.logger.Warn().
+		Uint("alert_id", alert.ID).
+		Uint("user_id", alert.UserID).
+		Str("rule", alert.Rule).
+		Msg(alert.Message)
+
+	if d.notifier == nil {
+		return
+	}
+
+	notification := &Notification{
+		Event:     "alert." + alert.Rule,
+		Severity:  alert.Severity,
+		Subject:   alert.Message,
+		Message:   fmt.Sprintf("Anomaly detected for user %d: %s", alert.UserID, alert.Message),
+		UserID:    alert.UserID,
+		Data:      details,
+		Timestamp: alert.CreatedAt,
+	}
+	if err := d.notifier.Notify(ctx, notification); err != nil {
+		d.logger.Error().Err(err).Uint("alert_id", alert.ID).Msg("failed to deliver alert notification")
+	}
+}
+
+// ListAlerts returns alerts, newest first. Acknowledged alerts are only included when requested.
+func (d *AnomalyDetector) ListAlerts(ctx context.Context, includeAcknowledged bool, limit int) ([]models.Alert, error) {
+	query := d.db.WithContext(ctx).Order("created_at DESC")
+	if !includeAcknowledged {
+		query = query.Where("acknowledged_at IS NULL")
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var alerts []models.Alert
+	if err := query.Find(&alerts).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list alerts", err)
+	}
+
+	return alerts, nil
+}
+
+// CountOpenAlerts returns the number of unacknowledged alerts
+func (d *AnomalyDetector) CountOpenAlerts(ctx context.Context) (int64, error) {
+	var count int64
+	if err := d.db.WithContext(ctx).Model(&models.Alert{}).
+		Where("acknowledged_at IS NULL").
+		Count(&count).Error; err != nil {
+		return 0, utils.WrapDatabaseError("count alerts", err)
+	}
+
+	return count, nil
+}
+
+// AcknowledgeAlert marks an alert as handled by an admin
+func (d *AnomalyDetector) AcknowledgeAlert(ctx context.Context, alertID, adminID uint) error {
+	result := d.db.WithContext(ctx).Model(&models.Alert{}).
+		Where("id = ? AND acknowledged_at IS NULL", alertID).
+		Updates(map[string]interface{}{
+			"acknowledged_at": time.Now(),
+			"acknowledged_by": adminID,
+		})
+	if result.Error != nil {
+		return utils.WrapDatabaseError("acknowledge alert", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("alert", fmt.Sprintf("%d", alertID))
+	}
+
+	return nil
+}
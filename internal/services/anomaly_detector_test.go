@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/geoip"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []*Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+type staticGeoLocator map[string]string
+
+func (g staticGeoLocator) LookupString(ip string) *geoip.Location {
+	if code, ok := g[ip]; ok {
+		return &geoip.Location{CountryCode: code}
+	}
+	return nil
+}
+
+func setupAnomalyDetector(t *testing.T, geo GeoLocator) (*ActivityService, *AnomalyDetector, *recordingNotifier, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.ActivityLog{}, &models.APIKey{}, &models.Alert{}))
+
+	log := zerolog.New(nil).Level(zerolog.Disabled)
+	config := DefaultAnomalyDetectorConfig()
+	config.DeleteSpikeThreshold = 3
+	config.APIKeyLearningUses = 2
+
+	notifier := &recordingNotifier{}
+	detector := NewAnomalyDetector(db, notifier, config, log)
+	activity := NewActivityService(db, log)
+	activity.SetAnomalyDetector(detector)
+	if geo != nil {
+		activity.SetGeoLocator(geo)
+	}
+
+	return activity, detector, notifier, db
+}
+
+func TestAnomalyDetector_DeleteSpike(t *testing.T) {
+	ctx := context.Background()
+	activity, detector, notifier, _ := setupAnomalyDetector(t, nil)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemoryDeleted, nil, "10.0.0.1", "test"))
+	}
+	alerts, err := detector.ListAlerts(ctx, false, 0)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	// The third delete crosses the threshold; further deletes are deduplicated
+	for i := 0; i < 3; i++ {
+		require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemoryDeleted, nil, "10.0.0.1", "test"))
+	}
+	alerts, err = detector.ListAlerts(ctx, false, 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, models.AlertRuleDeleteSpike, alerts[0].Rule)
+	assert.Len(t, notifier.notifications, 1)
+
+	// Acknowledging allows a new alert to be raised
+	require.NoError(t, detector.AcknowledgeAlert(ctx, alerts[0].ID, 1))
+	require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemoryDeleted, nil, "10.0.0.1", "test"))
+	count, err := detector.CountOpenAlerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAnomalyDetector_NewCountry(t *testing.T) {
+	ctx := context.Background()
+	geo := staticGeoLocator{"1.1.1.1": "GB", "2.2.2.2": "GB", "3.3.3.3": "BR"}
+	activity, detector, _, _ := setupAnomalyDetector(t, geo)
+
+	// The first ever activity has nothing to compare against
+	require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemorySearch, nil, "1.1.1.1", "test"))
+	require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemorySearch, nil, "2.2.2.2", "test"))
+
+	alerts, err := detector.ListAlerts(ctx, false, 0)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	require.NoError(t, activity.LogActivity(ctx, 2, models.ActivityMemorySearch, nil, "3.3.3.3", "test"))
+	alerts, err = detector.ListAlerts(ctx, false, 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, models.AlertRuleNewCountry, alerts[0].Rule)
+	assert.Contains(t, alerts[0].Message, "BR")
+}
+
+func TestAnomalyDetector_APIKeyOffHours(t *testing.T) {
+	ctx := context.Background()
+	_, detector, _, db := setupAnomalyDetector(t, nil)
+
+	key := &models.APIKey{UserID: 2, Key: "k", Name: "laptop", IsActive: true}
+	require.NoError(t, db.Create(key).Error)
+
+	morning := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		detector.ObserveAPIKeyUse(ctx, key, "10.0.0.1", morning)
+		require.NoError(t, db.First(key, key.ID).Error)
+	}
+	assert.Equal(t, int64(2), key.UsageCount)
+	assert.Equal(t, 1<<9, key.UsageHours)
+
+	// Known hour after learning: no alert
+	detector.ObserveAPIKeyUse(ctx, key, "10.0.0.1", morning)
+	require.NoError(t, db.First(key, key.ID).Error)
+	count, err := detector.CountOpenAlerts(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// New hour after learning: alert
+	detector.ObserveAPIKeyUse(ctx, key, "10.0.0.1", morning.Add(-6*time.Hour))
+	alerts, err := detector.ListAlerts(ctx, false, 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, models.AlertRuleAPIKeyOffHours, alerts[0].Rule)
+}
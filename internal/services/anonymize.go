@@ -0,0 +1,292 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Kinds of personal information an anonymized export replaces
+const (
+	PIIEmails    = "emails"
+	PIIPhones    = "phones"
+	PIINames     = "names"
+	PIICompanies = "companies"
+)
+
+// PIIKinds lists every kind of personal information that can be anonymized
+var PIIKinds = []string{PIIEmails, PIIPhones, PIINames, PIICompanies}
+
+var (
+	piiEmail = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+	// piiPhone matches ten-digit phone numbers with an optional country code; the
+	// 3-3-4 grouping keeps dates and versions from matching
+	piiPhone = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`)
+
+	// piiNamePatterns capture the names people are introduced by
+	piiNamePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i:my name is|call me|named|called)\s+([A-Z][a-z]+(?:\s+[A-Z][a-z]+){0,2})`),
+		regexp.MustCompile(`(?i:my|our)\s+(?i:wife|husband|partner|son|daughter|boss|manager|colleague|coworker|friend|brother|sister|mother|father|mom|dad|mum|client|doctor),?\s+([A-Z][a-z]+(?:\s+[A-Z][a-z]+)?)`),
+	}
+	// piiCompanyPatterns capture the companies people work with and names with a
+	// company suffix
+	piiCompanyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i:work(?:s|ed|ing)?\s+(?:at|for)|employed\s+by|joined|job\s+at|interview(?:ing|ed)?\s+(?:at|with))\s+([A-Z][\w&-]*(?:\s+[A-Z][\w&-]*){0,3})`),
+		regexp.MustCompile(`\b((?:[A-Z][\w&-]*\s+)+(?:Inc|LLC|Ltd|Corp|Corporation|GmbH|PLC))\b`),
+	}
+)
+
+// AnonymizeOptions selects the personal information an export replaces
+type AnonymizeOptions struct {
+	// Kinds are the kinds of PII to replace; nil replaces all of them, and an
+	// empty list only the Terms
+	Kinds []string
+	// Terms are further words or phrases to replace, such as project codenames,
+	// matched without regard to case
+	Terms []string
+}
+
+// ParsePIIKinds parses a comma-separated list of PII kinds. "true" or "all"
+// selects every kind.
+func ParsePIIKinds(value string) ([]string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "true" || value == "all" {
+		return nil, nil
+	}
+
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !isPIIKind(kind) {
+			return nil, utils.InvalidFieldError("anonymize", "must be true or a list of: "+strings.Join(PIIKinds, ", "))
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// ParseAnonymizeOptions parses an anonymize setting, "true", "all" or a list of
+// PII kinds, and a comma-separated list of further terms to replace. It returns
+// nil when neither asks for anonymization.
+func ParseAnonymizeOptions(value, terms string) (*AnonymizeOptions, error) {
+	value = strings.TrimSpace(value)
+	if (value == "" || strings.EqualFold(value, "false")) && strings.TrimSpace(terms) == "" {
+		return nil, nil
+	}
+
+	opts := &AnonymizeOptions{}
+	if value != "" && !strings.EqualFold(value, "false") {
+		kinds, err := ParsePIIKinds(value)
+		if err != nil {
+			return nil, err
+		}
+		opts.Kinds = kinds
+	} else {
+		// Only the given terms are replaced
+		opts.Kinds = []string{}
+	}
+	for _, term := range strings.Split(terms, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			opts.Terms = append(opts.Terms, term)
+		}
+	}
+	return opts, nil
+}
+
+func isPIIKind(kind string) bool {
+	for _, k := range PIIKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Anonymizer replaces personal information with pseudonyms. The same value always
+// gets the same pseudonym, so an anonymized export still shows which memories
+// talk about the same person or company. Names and companies are only recognised
+// where they are introduced ("my manager Alice", "I work at Acme"), so every
+// memory is passed to Collect before any is anonymized; mentions elsewhere are
+// then replaced too.
+type Anonymizer struct {
+	kinds      map[string]bool
+	pseudonyms map[string]string
+	counts     map[string]int
+	terms      []string
+	pattern    *regexp.Regexp
+}
+
+// NewAnonymizer creates an anonymizer for the given options
+func NewAnonymizer(opts AnonymizeOptions) (*Anonymizer, error) {
+	a := &Anonymizer{
+		kinds:      make(map[string]bool),
+		pseudonyms: make(map[string]string),
+		counts:     make(map[string]int),
+	}
+	kinds := opts.Kinds
+	if kinds == nil {
+		kinds = PIIKinds
+	}
+	for _, kind := range kinds {
+		if !isPIIKind(kind) {
+			return nil, utils.InvalidFieldError("anonymize", "unknown kind "+kind)
+		}
+		a.kinds[kind] = true
+	}
+	for _, term := range opts.Terms {
+		if term = strings.TrimSpace(term); term != "" {
+			a.addTerm(term, "Redacted")
+		}
+	}
+	return a, nil
+}
+
+// Collect records the names and companies introduced in text
+func (a *Anonymizer) Collect(text string) {
+	if a.kinds[PIINames] {
+		for _, pattern := range piiNamePatterns {
+			for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+				a.addTerm(match[1], "Person")
+			}
+		}
+	}
+	if a.kinds[PIICompanies] {
+		for _, pattern := range piiCompanyPatterns {
+			for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+				a.addTerm(match[1], "Company")
+			}
+		}
+	}
+}
+
+// addTerm gives term the next pseudonym with the given prefix, unless it has one
+func (a *Anonymizer) addTerm(term, prefix string) {
+	key := strings.ToLower(term)
+	if _, ok := a.pseudonyms[key]; ok {
+		return
+	}
+	a.counts[prefix]++
+	a.pseudonyms[key] = fmt.Sprintf("%s %d", prefix, a.counts[prefix])
+	a.terms = append(a.terms, term)
+	a.pattern = nil
+}
+
+// Anonymize returns text with its personal information replaced
+func (a *Anonymizer) Anonymize(text string) string {
+	if a.kinds[PIIEmails] {
+		text = piiEmail.ReplaceAllStringFunc(text, func(email string) string {
+			return a.pseudonym("email:"+strings.ToLower(email), func(n int) string {
+				return fmt.Sprintf("user%d@example.com", n)
+			})
+		})
+	}
+	if a.kinds[PIIPhones] {
+		text = piiPhone.ReplaceAllStringFunc(text, func(phone string) string {
+			return a.pseudonym("phone:"+strings.Map(keepDigits, phone), func(n int) string {
+				return fmt.Sprintf("555-%04d", 100+n)
+			})
+		})
+	}
+
+	if len(a.terms) == 0 {
+		return text
+	}
+	if a.pattern == nil {
+		a.pattern = termsPattern(a.terms)
+	}
+	return a.pattern.ReplaceAllStringFunc(text, func(term string) string {
+		return a.pseudonyms[strings.ToLower(term)]
+	})
+}
+
+// pseudonym returns the pseudonym recorded under key, making one with next if
+// there is none
+func (a *Anonymizer) pseudonym(key string, next func(n int) string) string {
+	if pseudonym, ok := a.pseudonyms[key]; ok {
+		return pseudonym
+	}
+	kind, _, _ := strings.Cut(key, ":")
+	a.counts[kind]++
+	a.pseudonyms[key] = next(a.counts[kind])
+	return a.pseudonyms[key]
+}
+
+// AnonymizeMemory replaces the personal information in an archived memory's
+// content, update key, tags and metadata strings
+func (a *Anonymizer) AnonymizeMemory(memory *ArchivedMemory) {
+	memory.Content = a.Anonymize(memory.Content)
+	memory.UpdateKey = a.Anonymize(memory.UpdateKey)
+	for i, tag := range memory.Tags {
+		memory.Tags[i] = a.Anonymize(tag)
+	}
+
+	if len(memory.Metadata) == 0 {
+		return
+	}
+	var metadata interface{}
+	if err := json.Unmarshal(memory.Metadata, &metadata); err != nil {
+		// Metadata that can't be read can't be checked either
+		memory.Metadata = nil
+		return
+	}
+	if data, err := json.Marshal(a.anonymizeValue(metadata)); err == nil {
+		memory.Metadata = data
+	} else {
+		memory.Metadata = nil
+	}
+}
+
+// anonymizeValue anonymizes the strings in a decoded JSON value
+func (a *Anonymizer) anonymizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return a.Anonymize(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = a.anonymizeValue(v[i])
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = a.anonymizeValue(item)
+		}
+	}
+	return value
+}
+
+// termsPattern matches any of terms as whole words, case-insensitively, trying
+// longer terms first so "Acme Corp" is not replaced as "Acme"
+func termsPattern(terms []string) *regexp.Regexp {
+	sorted := append([]string(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	alternatives := make([]string, len(sorted))
+	for i, term := range sorted {
+		quoted := regexp.QuoteMeta(term)
+		if isWordByte(term[0]) {
+			quoted = `\b` + quoted
+		}
+		if isWordByte(term[len(term)-1]) {
+			quoted += `\b`
+		}
+		alternatives[i] = quoted
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func keepDigits(r rune) rune {
+	if r >= '0' && r <= '9' {
+		return r
+	}
+	return -1
+}
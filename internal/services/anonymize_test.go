@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestAnonymizer(t *testing.T) {
+	anonymizer, err := NewAnonymizer(AnonymizeOptions{Terms: []string{"Project Falcon"}})
+	require.NoError(t, err)
+
+	texts := []string{
+		"Alice Smith reviews every release on Project Falcon",
+		"My manager Alice Smith prefers async updates",
+		"I work at Acme and my colleague Bob sits next to me",
+		"Initech Inc is our biggest client",
+	}
+	for _, text := range texts {
+		anonymizer.Collect(text)
+	}
+
+	// Names seen in one memory are replaced wherever they appear
+	assert.Equal(t, "Person 1 reviews every release on Redacted 1", anonymizer.Anonymize(texts[0]))
+	assert.Equal(t, "My manager Person 1 prefers async updates", anonymizer.Anonymize(texts[1]))
+	assert.Equal(t, "I work at Company 1 and my colleague Person 2 sits next to me", anonymizer.Anonymize(texts[2]))
+	assert.Equal(t, "Company 2 is our biggest client", anonymizer.Anonymize(texts[3]))
+	assert.Equal(t, "Person 2 moved from Company 1 to Company 2", anonymizer.Anonymize("Bob moved from acme to Initech Inc"))
+
+	// Emails and phone numbers keep their pseudonym; dates are left alone
+	assert.Equal(t, "Mail user1@example.com or call 555-0101 before 2024-05-01",
+		anonymizer.Anonymize("Mail alice@acme.com or call (415) 555-2671 before 2024-05-01"))
+	assert.Equal(t, "user1@example.com, user2@example.com, 555-0101",
+		anonymizer.Anonymize("Alice@Acme.com, bob@acme.com, 415-555-2671"))
+}
+
+func TestAnonymizer_Kinds(t *testing.T) {
+	anonymizer, err := NewAnonymizer(AnonymizeOptions{Kinds: []string{PIIEmails}})
+	require.NoError(t, err)
+	anonymizer.Collect("My name is Carol and I work at Globex")
+	assert.Equal(t, "Carol (user1@example.com) works at Globex",
+		anonymizer.Anonymize("Carol (carol@globex.com) works at Globex"))
+
+	kinds, err := ParsePIIKinds(" Names, phones ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{PIINames, PIIPhones}, kinds)
+	_, err = ParsePIIKinds("names,addresses")
+	assert.True(t, utils.IsValidationError(err))
+
+	opts, err := ParseAnonymizeOptions("false", "")
+	require.NoError(t, err)
+	assert.Nil(t, opts)
+	opts, err = ParseAnonymizeOptions("", "Falcon, Osprey")
+	require.NoError(t, err)
+	assert.Equal(t, &AnonymizeOptions{Kinds: []string{}, Terms: []string{"Falcon", "Osprey"}}, opts)
+}
+
+func TestMemoryService_ExportAnonymized(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	for _, req := range []StoreRequest{
+		{Content: "Dana is in charge of the Q3 roadmap", Type: models.TypeFact, Category: models.CategoryProject, Tags: []string{"dana"}},
+		{Content: "My boss Dana prefers email at dana@example.org", Type: models.TypePreference, Category: models.CategoryBusiness,
+			Metadata: map[string]interface{}{"owner": "Dana", "count": 2}},
+	} {
+		_, err := service.Store(ctx, req)
+		require.NoError(t, err)
+	}
+
+	archive, err := service.ExportMemoriesWithOptions(ctx, ExportOptions{Anonymize: &AnonymizeOptions{}})
+	require.NoError(t, err)
+	assert.True(t, archive.Anonymized)
+	require.Len(t, archive.Memories, 2)
+	assert.Equal(t, "Person 1 is in charge of the Q3 roadmap", archive.Memories[0].Content)
+	assert.Equal(t, []string{"Person 1"}, archive.Memories[0].Tags)
+	assert.Equal(t, "My boss Person 1 prefers email at user1@example.com", archive.Memories[1].Content)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(archive.Memories[1].Metadata, &metadata))
+	assert.Equal(t, "Person 1", metadata["owner"])
+	assert.EqualValues(t, 2, metadata["count"])
+
+	// Embeddings and encrypted payloads would carry the original content
+	_, err = service.ExportMemoriesWithOptions(ctx, ExportOptions{Anonymize: &AnonymizeOptions{}, IncludeEmbeddings: true})
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.ExportMemoriesWithOptions(ctx, ExportOptions{Anonymize: &AnonymizeOptions{}, KeepEncrypted: true})
+	assert.True(t, utils.IsValidationError(err))
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Archive formats accepted by export and import
+const (
+	ArchiveFormatJSON  = "json"
+	ArchiveFormatJSONL = "jsonl"
+)
+
+// maxArchiveLineSize bounds a single JSONL line; a memory with an embedding and
+// large metadata fits comfortably
+const maxArchiveLineSize = 16 << 20
+
+// archiveHeader is the first line of a JSONL archive
+type archiveHeader struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Region     string    `json:"region,omitempty"`
+	Anonymized bool      `json:"anonymized,omitempty"`
+}
+
+// IsValidArchiveFormat checks if a given archive format is supported
+func IsValidArchiveFormat(format string) bool {
+	return format == ArchiveFormatJSON || format == ArchiveFormatJSONL
+}
+
+// WriteArchiveJSONL writes an archive as JSON lines: a header line with the
+// version, export time and region, then one line per memory. Large stores can be
+// streamed and split without holding a single JSON document.
+func WriteArchiveJSONL(w io.Writer, archive *MemoryArchive) error {
+	writer := NewArchiveJSONLWriter(w, archive)
+	for i := range archive.Memories {
+		if err := writer.Write(&archive.Memories[i]); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// ArchiveJSONLWriter writes a JSONL archive one memory at a time, for exports
+// streamed as they are read. The header line is written with the first memory, or
+// on Close when there are none.
+type ArchiveJSONLWriter struct {
+	encoder *json.Encoder
+	header  archiveHeader
+	written int
+	started bool
+}
+
+// NewArchiveJSONLWriter returns a writer for an archive with the version, export
+// time and region of archive; its memories are not written
+func NewArchiveJSONLWriter(w io.Writer, archive *MemoryArchive) *ArchiveJSONLWriter {
+	return &ArchiveJSONLWriter{
+		encoder: json.NewEncoder(w),
+		header: archiveHeader{
+			Version:    archive.Version,
+			ExportedAt: archive.ExportedAt,
+			Region:     archive.Region,
+			Anonymized: archive.Anonymized,
+		},
+	}
+}
+
+// Started reports whether anything has been written yet
+func (a *ArchiveJSONLWriter) Started() bool {
+	return a.started
+}
+
+// Write adds a memory to the archive
+func (a *ArchiveJSONLWriter) Write(memory *ArchivedMemory) error {
+	if err := a.start(); err != nil {
+		return err
+	}
+	if err := a.encoder.Encode(memory); err != nil {
+		return fmt.Errorf("write memory %d: %w", a.written, err)
+	}
+	a.written++
+	return nil
+}
+
+// Close finishes the archive, writing the header if no memory was written
+func (a *ArchiveJSONLWriter) Close() error {
+	return a.start()
+}
+
+// start writes the header line once
+func (a *ArchiveJSONLWriter) start() error {
+	if a.started {
+		return nil
+	}
+	a.started = true
+	if err := a.encoder.Encode(a.header); err != nil {
+		return fmt.Errorf("write archive header: %w", err)
+	}
+	return nil
+}
+
+// ReadArchiveJSONL reads an archive written by WriteArchiveJSONL. Blank lines are
+// ignored.
+func ReadArchiveJSONL(r io.Reader) (*MemoryArchive, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxArchiveLineSize)
+
+	var archive *MemoryArchive
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		if archive == nil {
+			var header archiveHeader
+			if err := json.Unmarshal(data, &header); err != nil {
+				return nil, utils.WrapValidationError("archive", fmt.Sprintf("line %d: invalid header: %v", line, err))
+			}
+			archive = &MemoryArchive{
+				Version:    header.Version,
+				ExportedAt: header.ExportedAt,
+				Region:     header.Region,
+				Anonymized: header.Anonymized,
+				Memories:   []ArchivedMemory{},
+			}
+			continue
+		}
+
+		var memory ArchivedMemory
+		if err := json.Unmarshal(data, &memory); err != nil {
+			return nil, utils.WrapValidationError("archive", fmt.Sprintf("line %d: invalid memory: %v", line, err))
+		}
+		archive.Memories = append(archive.Memories, memory)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, utils.WrapValidationError("archive", fmt.Sprintf("line %d: %v", line+1, err))
+	}
+	if archive == nil {
+		return nil, utils.WrapValidationError("archive", "archive is empty")
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// drainRequeueTime is how long before a drain's deadline embeddings still in
+// flight are cancelled, leaving them time to be requeued before the database closes
+const drainRequeueTime = 2 * time.Second
+
+// errDraining is recorded on embedding jobs for memories stored while draining
+var errDraining = errors.New("embedding deferred: shutting down")
+
+// AsyncEmbeddings tracks the embeddings memory services generate in the background
+// after a store, so shutdown can wait for them instead of killing them mid-flight.
+// One is shared by every memory service, like the embedding batcher.
+type AsyncEmbeddings struct {
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+	inFlight atomic.Int64
+
+	// ctx is cancelled when a drain runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewAsyncEmbeddings creates a tracker for background embeddings
+func NewAsyncEmbeddings(logger zerolog.Logger) *AsyncEmbeddings {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AsyncEmbeddings{
+		logger: logger.With().Str("service", "async_embeddings").Logger(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// InFlight returns how many embeddings are being generated in the background
+func (a *AsyncEmbeddings) InFlight() int {
+	return int(a.inFlight.Load())
+}
+
+// start runs fn in the background with a context cancelled if draining runs out
+// of time. It returns false without running fn once draining has begun.
+func (a *AsyncEmbeddings) start(fn func(ctx context.Context)) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.draining {
+		return false
+	}
+	a.wg.Add(1)
+	a.inFlight.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer a.inFlight.Add(-1)
+		fn(a.ctx)
+	}()
+	return true
+}
+
+// Drain stops embeddings from starting in the background and waits for those in
+// flight to finish. Ones still running shortly before ctx's deadline are
+// cancelled, which queues them as embedding jobs for the backfill worker. An error
+// is returned only when ctx ended before they were all accounted for.
+func (a *AsyncEmbeddings) Drain(ctx context.Context) error {
+	a.mu.Lock()
+	a.draining = true
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	waitCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline.Add(-drainRequeueTime))
+		defer cancel()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-waitCtx.Done():
+	}
+
+	a.logger.Warn().Int("in_flight", a.InFlight()).Msg("requeueing embeddings that did not finish before shutdown")
+	a.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetAsyncEmbeddings returns the tracker of the service's background embeddings
+func (s *MemoryService) GetAsyncEmbeddings() *AsyncEmbeddings {
+	return s.asyncEmbeddings
+}
+
+// Drain waits for embeddings being generated in the background to finish, or
+// requeues them for the backfill worker if ctx ends first; see
+// AsyncEmbeddings.Drain. Memories stored afterwards are queued for the backfill
+// worker instead of being embedded.
+func (s *MemoryService) Drain(ctx context.Context) error {
+	return s.asyncEmbeddings.Drain(ctx)
+}
+
+// embedAsync generates a memory's embedding in the background, or queues it for
+// the backfill worker once draining has begun
+func (s *MemoryService) embedAsync(memoryID uint, fields EmbeddingFields) {
+	started := s.asyncEmbeddings.start(func(ctx context.Context) {
+		s.generateEmbeddingAsync(ctx, memoryID, fields)
+	})
+	if !started {
+		s.enqueueEmbeddingJob(context.Background(), memoryID, errDraining)
+	}
+}
+
+// asyncEmbeddingsFrom returns the shared tracker in config, or a new one for a
+// service of its own
+func asyncEmbeddingsFrom(config map[string]interface{}, logger zerolog.Logger) *AsyncEmbeddings {
+	if tracker, ok := config["async_embeddings"].(*AsyncEmbeddings); ok {
+		return tracker
+	}
+	return NewAsyncEmbeddings(logger)
+}
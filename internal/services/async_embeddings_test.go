@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// blockingEmbeddingService never answers, returning only once the caller gives up
+type blockingEmbeddingService struct{}
+
+func (blockingEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAsyncEmbeddings_DrainWaitsForInFlight(t *testing.T) {
+	tracker := NewAsyncEmbeddings(zerolog.Nop())
+
+	var finished atomic.Bool
+	require.True(t, tracker.start(func(ctx context.Context) {
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}))
+	assert.Equal(t, 1, tracker.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, tracker.Drain(ctx))
+	assert.True(t, finished.Load())
+	assert.Zero(t, tracker.InFlight())
+
+	// Nothing starts once draining has begun
+	assert.False(t, tracker.start(func(ctx context.Context) {}))
+}
+
+func TestAsyncEmbeddings_DrainCancelsBeforeDeadline(t *testing.T) {
+	tracker := NewAsyncEmbeddings(zerolog.Nop())
+
+	var cancelled atomic.Bool
+	tracker.start(func(ctx context.Context) {
+		<-ctx.Done()
+		cancelled.Store(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainRequeueTime+100*time.Millisecond)
+	defer cancel()
+	require.NoError(t, tracker.Drain(ctx))
+	assert.True(t, cancelled.Load())
+	assert.NoError(t, ctx.Err(), "in-flight work should be cancelled before the deadline")
+}
+
+func TestMemoryService_DrainRequeuesEmbeddings(t *testing.T) {
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.EmbeddingJob{}))
+	service.embedding = blockingEmbeddingService{}
+
+	// An embedding that cannot finish in time is queued for the backfill worker
+	inFlight, _ := storeTestMemory(t, service, "embedding in flight at shutdown")
+	ctx, cancel := context.WithTimeout(context.Background(), drainRequeueTime+200*time.Millisecond)
+	defer cancel()
+	require.NoError(t, service.Drain(ctx))
+
+	var job models.EmbeddingJob
+	require.NoError(t, service.db.Where("memory_id = ?", inFlight.ID).First(&job).Error)
+	assert.Equal(t, models.EmbeddingJobPending, job.Status)
+	assert.Contains(t, job.LastError, "context canceled")
+
+	// Memories stored while draining are queued without being embedded
+	late, _ := storeTestMemory(t, service, "stored during shutdown")
+	var lateJob models.EmbeddingJob
+	require.NoError(t, service.db.Where("memory_id = ?", late.ID).First(&lateJob).Error)
+	assert.Equal(t, errDraining.Error(), lateJob.LastError)
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register image formats for DecodeConfig
+	_ "image/jpeg" // register image formats for DecodeConfig
+	_ "image/png"  // register image formats for DecodeConfig
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Attachment limits applied when they are not configured
+const (
+	// DefaultMaxAttachmentSize is the largest attachment accepted, in bytes
+	DefaultMaxAttachmentSize = 5 << 20
+	// DefaultMaxAttachmentsPerMemory is the most attachments a memory may have
+	DefaultMaxAttachmentsPerMemory = 10
+)
+
+// AttachmentRequest is a file to attach to a memory
+type AttachmentRequest struct {
+	Data     []byte
+	Filename string
+	// ContentType is detected from the data when empty or application/octet-stream
+	ContentType string
+	// Metadata describes the file; images also get their width and height
+	Metadata map[string]interface{}
+}
+
+// NewAttachmentStoreFromConfig builds the object store attachments are kept in,
+// or returns nil when they are kept in the database
+func NewAttachmentStoreFromConfig(cfg *config.Config) (ObjectStore, error) {
+	switch cfg.Attachments.Storage {
+	case "", "database":
+		return nil, nil
+	case "s3":
+		return NewS3ObjectStore(cfg.Attachments.S3)
+	default:
+		return nil, fmt.Errorf("invalid attachment storage: %s", cfg.Attachments.Storage)
+	}
+}
+
+// GetAttachmentStore returns the object store attachments are kept in, or nil
+// when they are kept in the database
+func (s *MemoryService) GetAttachmentStore() ObjectStore {
+	if store, ok := s.config["attachment_store"].(ObjectStore); ok {
+		return store
+	}
+	return nil
+}
+
+// maxAttachmentSize returns the largest attachment accepted, in bytes
+func (s *MemoryService) maxAttachmentSize() int64 {
+	switch size := s.config["attachment_max_size"].(type) {
+	case int64:
+		if size > 0 {
+			return size
+		}
+	case int:
+		if size > 0 {
+			return int64(size)
+		}
+	}
+	return DefaultMaxAttachmentSize
+}
+
+// maxAttachmentsPerMemory returns the most attachments a memory may have, 0
+// meaning no limit
+func (s *MemoryService) maxAttachmentsPerMemory() int {
+	if limit, ok := s.config["attachment_max_per_memory"].(int); ok && limit >= 0 {
+		return limit
+	}
+	return DefaultMaxAttachmentsPerMemory
+}
+
+// AddAttachment attaches a file to one of the user's memories, within the
+// configured size and count limits
+func (s *MemoryService) AddAttachment(ctx context.Context, memoryID uint, req AttachmentRequest) (*models.Attachment, error) {
+	if len(req.Data) == 0 {
+		return nil, utils.RequiredFieldError("file")
+	}
+	if limit := s.maxAttachmentSize(); int64(len(req.Data)) > limit {
+		return nil, utils.InvalidFieldError("file", fmt.Sprintf("must be at most %d bytes", limit))
+	}
+	filename := filepath.Base(strings.TrimSpace(req.Filename))
+	if filename == "." || filename == "/" {
+		filename = ""
+	}
+	if len(filename) > 255 {
+		return nil, utils.InvalidFieldError("filename", "must be at most 255 characters")
+	}
+
+	contentType := req.ContentType
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mime.FormatMediaType(mediaType, params)
+	} else {
+		contentType = ""
+	}
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(req.Data)
+	}
+	if len(contentType) > 100 {
+		return nil, utils.InvalidFieldError("content_type", "must be at most 100 characters")
+	}
+
+	metadata := make(map[string]interface{}, len(req.Metadata)+2)
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		if img, _, err := image.DecodeConfig(bytes.NewReader(req.Data)); err == nil {
+			metadata["width"] = img.Width
+			metadata["height"] = img.Height
+		}
+	}
+
+	if _, err := s.GetByID(ctx, memoryID); err != nil {
+		return nil, err
+	}
+	if limit := s.maxAttachmentsPerMemory(); limit > 0 {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Attachment{}).
+			Where("user_id = ? AND memory_id = ?", s.userID, memoryID).
+			Count(&count).Error; err != nil {
+			return nil, utils.WrapDatabaseError("count attachments", err)
+		}
+		if count >= int64(limit) {
+			return nil, utils.InvalidFieldError("file", fmt.Sprintf("memory already has the most attachments allowed (%d)", limit))
+		}
+	}
+
+	if filename == "" {
+		filename = "attachment" + attachmentExtension(contentType)
+	}
+	return s.attach(ctx, memoryID, filename, contentType, req.Data, metadata)
+}
+
+// attachmentExtensions are the extensions unnamed uploads of common types get,
+// where the system's MIME table lists several
+var attachmentExtensions = map[string]string{
+	"application/octet-stream": ".bin",
+	"image/jpeg":               ".jpg",
+	"text/plain":               ".txt",
+}
+
+// attachmentExtension returns the file extension for a content type, or none
+func attachmentExtension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if ext, ok := attachmentExtensions[mediaType]; ok {
+		return ext
+	}
+	if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
+
+// attach stores a file with one of the user's memories, encrypted when encryption
+// is enabled, in the attachment store if one is configured
+func (s *MemoryService) attach(ctx context.Context, memoryID uint, filename, contentType string, data []byte, metadata map[string]interface{}) (*models.Attachment, error) {
+	sum := sha256.Sum256(data)
+	attachment := &models.Attachment{
+		UserID:      s.userID,
+		MemoryID:    memoryID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Data:        data,
+	}
+	if len(metadata) > 0 {
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, utils.WrapValidationError("metadata", "invalid metadata format")
+		}
+		attachment.Metadata = metadataJSON
+	}
+
+	if s.encryption != nil {
+		dataKey, err := s.dataKey(s.userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load data key: %w", err)
+		}
+		encrypted, err := dataKey.EncryptField(base64.StdEncoding.EncodeToString(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt attachment: %w", err)
+		}
+		if attachment.EncryptedData, err = json.Marshal(encrypted); err != nil {
+			return nil, fmt.Errorf("failed to marshal encrypted data: %w", err)
+		}
+		attachment.IsEncrypted = true
+		attachment.Data = nil
+	}
+
+	// An object store holds the data, encrypted or not, and the row only its key
+	store := s.GetAttachmentStore()
+	if store != nil {
+		attachment.Storage = store.Name()
+		attachment.StorageKey = attachmentKey(s.userID)
+		object, objectType := attachment.Data, contentType
+		if attachment.IsEncrypted {
+			object, objectType = attachment.EncryptedData, "application/json"
+		}
+		if err := store.Put(ctx, attachment.StorageKey, object, objectType); err != nil {
+			s.logger.Error().Err(err).Uint("memory_id", memoryID).Str("storage", store.Name()).Msg("failed to upload attachment")
+			return nil, fmt.Errorf("failed to upload attachment: %w", err)
+		}
+		attachment.Data = nil
+		attachment.EncryptedData = nil
+	}
+
+	if err := s.db.WithContext(ctx).Create(attachment).Error; err != nil {
+		s.logger.Error().Err(err).Uint("memory_id", memoryID).Msg("failed to store attachment")
+		if store != nil {
+			s.removeAttachmentObjects(ctx, []string{attachment.StorageKey})
+		}
+		return nil, utils.WrapDatabaseError("store attachment", err)
+	}
+	attachment.Data = nil
+	attachment.EncryptedData = nil
+	attachment.URI = models.AttachmentURI(attachment.ID)
+	return attachment, nil
+}
+
+// attachmentKey returns a new object key for one of a user's attachments
+func attachmentKey(userID uint) string {
+	random := make([]byte, 16)
+	_, _ = rand.Read(random)
+	return fmt.Sprintf("attachments/%d/%s", userID, hex.EncodeToString(random))
+}
+
+// ListAttachments returns the attachments of one of the user's memories, without
+// their data
+func (s *MemoryService) ListAttachments(ctx context.Context, memoryID uint) ([]models.Attachment, error) {
+	if _, err := s.GetByID(ctx, memoryID); err != nil {
+		return nil, err
+	}
+
+	attachments := []models.Attachment{}
+	if err := s.db.WithContext(ctx).
+		Omit("data", "encrypted_data").
+		Where("user_id = ? AND memory_id = ?", s.userID, memoryID).
+		Order("id").
+		Find(&attachments).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list attachments", err)
+	}
+	for i := range attachments {
+		attachments[i].URI = models.AttachmentURI(attachments[i].ID)
+	}
+	return attachments, nil
+}
+
+// GetAttachment returns one of the user's attachments with its data decrypted
+func (s *MemoryService) GetAttachment(ctx context.Context, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, s.userID).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapNotFoundError("attachment", fmt.Sprintf("%d", id))
+		}
+		return nil, utils.WrapDatabaseError("get attachment", err)
+	}
+	attachment.URI = models.AttachmentURI(attachment.ID)
+
+	if attachment.StorageKey != "" {
+		store := s.GetAttachmentStore()
+		if store == nil || store.Name() != attachment.Storage {
+			return nil, fmt.Errorf("attachment is kept in %s storage, which is not configured", attachment.Storage)
+		}
+		object, err := store.Get(ctx, attachment.StorageKey)
+		if err != nil {
+			s.logger.Error().Err(err).Uint("attachment_id", id).Str("storage", store.Name()).Msg("failed to download attachment")
+			return nil, fmt.Errorf("failed to download attachment: %w", err)
+		}
+		if attachment.IsEncrypted {
+			attachment.EncryptedData = object
+		} else {
+			attachment.Data = object
+		}
+	}
+	if !attachment.IsEncrypted {
+		return &attachment, nil
+	}
+
+	var encrypted utils.EncryptedData
+	if err := json.Unmarshal(attachment.EncryptedData, &encrypted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	}
+	if s.encryption == nil {
+		return nil, fmt.Errorf("attachment is encrypted but encryption service is not available")
+	}
+	encryption, err := s.dataKey(attachment.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+	if encryption != s.encryption && !encryption.OwnsField(&encrypted) {
+		encryption = s.encryption
+	}
+	encoded, err := encryption.DecryptField(&encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt attachment: %w", err)
+	}
+	if attachment.Data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return nil, fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	attachment.EncryptedData = nil
+	return &attachment, nil
+}
+
+// DeleteAttachment deletes one of the user's attachments and its stored object
+func (s *MemoryService) DeleteAttachment(ctx context.Context, id uint) error {
+	keys := s.attachmentObjectKeys(ctx, "id = ?", id)
+	deleted := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, s.userID).Delete(&models.Attachment{})
+	if deleted.Error != nil {
+		s.logger.Error().Err(deleted.Error).Uint("attachment_id", id).Msg("failed to delete attachment")
+		return utils.WrapDatabaseError("delete attachment", deleted.Error)
+	}
+	if deleted.RowsAffected == 0 {
+		return utils.WrapNotFoundError("attachment", fmt.Sprintf("%d", id))
+	}
+	s.removeAttachmentObjects(ctx, keys)
+	return nil
+}
+
+// attachmentObjectKeys returns the object keys of the user's attachments matching
+// conds that are kept in an object store. Rows go with their memories, so the
+// keys are read before memories are deleted and their objects removed after.
+func (s *MemoryService) attachmentObjectKeys(ctx context.Context, conds ...interface{}) []string {
+	if s.GetAttachmentStore() == nil {
+		return nil
+	}
+	query := s.db.WithContext(ctx).Model(&models.Attachment{}).
+		Where("user_id = ? AND storage_key <> ''", s.userID)
+	if len(conds) > 0 {
+		query = query.Where(conds[0], conds[1:]...)
+	}
+	var keys []string
+	if err := query.Pluck("storage_key", &keys).Error; err != nil {
+		s.logger.Warn().Err(err).Msg("failed to find stored attachments")
+		return nil
+	}
+	return keys
+}
+
+// removeAttachmentObjects deletes the stored objects of deleted attachments.
+// Failures are logged and leave the object behind.
+func (s *MemoryService) removeAttachmentObjects(ctx context.Context, keys []string) {
+	store := s.GetAttachmentStore()
+	if store == nil || len(keys) == 0 {
+		return
+	}
+	// Finish removing objects even if the caller gives up
+	ctx, cancel := s.detachedContext(ctx)
+	defer cancel()
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			s.logger.Warn().Err(err).Str("key", key).Str("storage", store.Name()).Msg("failed to remove attachment object")
+		}
+	}
+}
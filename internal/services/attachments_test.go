@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_AddAttachment(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{"attachment_max_size": 1024, "attachment_max_per_memory": 2})
+	require.NoError(t, service.db.AutoMigrate(&models.Attachment{}))
+	memory, err := service.Store(ctx, StoreRequest{Content: "The office floor plan", Category: models.CategoryBusiness, Type: models.TypeContext})
+	require.NoError(t, err)
+
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 3))))
+	attachment, err := service.AddAttachment(ctx, memory.ID, AttachmentRequest{
+		Data:     img.Bytes(),
+		Filename: "../plans/floor.png",
+		Metadata: map[string]interface{}{"caption": "second floor"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "floor.png", attachment.Filename)
+	assert.Equal(t, "image/png", attachment.ContentType, "the content type is detected")
+	assert.Equal(t, models.AttachmentURI(attachment.ID), attachment.URI)
+	assert.Empty(t, attachment.Storage)
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(attachment.Metadata, &metadata))
+	assert.Equal(t, map[string]interface{}{"caption": "second floor", "width": float64(4), "height": float64(3)}, metadata)
+
+	stored, err := service.GetAttachment(ctx, attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, img.Bytes(), stored.Data)
+
+	notes, err := service.AddAttachment(ctx, memory.ID, AttachmentRequest{Data: []byte("desk by the window"), ContentType: "text/plain; charset=utf-8"})
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", notes.ContentType)
+	assert.Equal(t, "attachment.txt", notes.Filename)
+
+	_, err = service.AddAttachment(ctx, memory.ID, AttachmentRequest{Data: []byte("one too many")})
+	assert.True(t, utils.IsValidationError(err), "the memory has the most attachments allowed")
+
+	_, err = service.AddAttachment(ctx, memory.ID, AttachmentRequest{Data: bytes.Repeat([]byte("x"), 1025)})
+	assert.True(t, utils.IsValidationError(err), "the file is over the size limit")
+
+	_, err = service.AddAttachment(ctx, memory.ID, AttachmentRequest{})
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = service.AddAttachment(ctx, memory.ID+100, AttachmentRequest{Data: []byte("data")})
+	assert.True(t, utils.IsNotFoundError(err))
+
+	require.NoError(t, service.DeleteAttachment(ctx, notes.ID))
+	attachments, err := service.ListAttachments(ctx, memory.ID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, attachment.URI, attachments[0].URI)
+	assert.True(t, utils.IsNotFoundError(service.DeleteAttachment(ctx, notes.ID)))
+}
+
+func TestMemoryService_AttachmentObjectStore(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+
+	for name, config := range map[string]map[string]interface{}{
+		"plain":     {},
+		"encrypted": {"encryption_service": encryption},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newMemoryObjectStore()
+			config["attachment_store"] = store
+			service := setupMemoryService(t, config)
+			require.NoError(t, service.db.AutoMigrate(&models.Attachment{}))
+
+			memory, err := service.Store(ctx, StoreRequest{Content: "Receipt for the new laptop", Category: models.CategoryBusiness, Type: models.TypeFact})
+			require.NoError(t, err)
+			attachment, err := service.AddAttachment(ctx, memory.ID, AttachmentRequest{Data: []byte("receipt"), Filename: "receipt.txt"})
+			require.NoError(t, err)
+			assert.Equal(t, "memory", attachment.Storage)
+
+			var row models.Attachment
+			require.NoError(t, service.db.First(&row, attachment.ID).Error)
+			assert.Empty(t, row.Data, "the data is kept in the object store")
+			assert.Empty(t, row.EncryptedData)
+			require.Len(t, store.keys(), 1)
+			object, err := store.Get(ctx, row.StorageKey)
+			require.NoError(t, err)
+			assert.Equal(t, name == "plain", string(object) == "receipt")
+
+			stored, err := service.GetAttachment(ctx, attachment.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("receipt"), stored.Data)
+
+			// Deleting the memory removes the object along with the row
+			require.NoError(t, service.Delete(ctx, memory.ID))
+			assert.Empty(t, store.keys())
+		})
+	}
+}
+
+func TestMemoryService_BulkDeleteRemovesAttachmentObjects(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryObjectStore()
+	service := setupMemoryService(t, map[string]interface{}{"attachment_store": store})
+	require.NoError(t, service.db.AutoMigrate(&models.Attachment{}))
+
+	for _, content := range []string{"Photo of the whiteboard", "Photo of the kitchen"} {
+		memory, err := service.Store(ctx, StoreRequest{Content: content, Category: models.CategoryPersonal, Type: models.TypeContext})
+		require.NoError(t, err)
+		_, err = service.AddAttachment(ctx, memory.ID, AttachmentRequest{Data: []byte(content)})
+		require.NoError(t, err)
+	}
+	require.Len(t, store.keys(), 2)
+
+	preview, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "photo of", DryRun: true})
+	require.NoError(t, err)
+	_, err = service.DeleteMatching(ctx, BulkDeleteRequest{Query: "photo of", Confirm: preview.Confirm})
+	require.NoError(t, err)
+	assert.Empty(t, store.keys())
+}
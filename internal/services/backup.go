@@ -0,0 +1,729 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// BackupVersion is the current format version of backups
+const BackupVersion = 1
+
+// BackupKeyPrefix is where backups are kept in their store
+const BackupKeyPrefix = "backups/"
+
+// Backup file suffixes: a gzipped JSONL stream, sealed when encrypted
+const (
+	backupSuffix          = ".jsonl.gz"
+	encryptedBackupSuffix = ".jsonl.gz.enc"
+)
+
+// encryptedBackupMagic starts an encrypted backup, ahead of the salt, the nonce
+// and the sealed gzip stream
+var encryptedBackupMagic = []byte("RMBACKUP-ENC1\n")
+
+// backupKeyInfo derives the key sealing a backup from the backup encryption key
+var backupKeyInfo = []byte("remember-me backup")
+
+// BackupRecord is one line of a backup. Exactly one field is set. The header
+// comes first, then each user followed by the memories of their default
+// workspace, each further workspace followed by its memories, and the user's API
+// keys.
+type BackupRecord struct {
+	Header    *BackupHeader    `json:"header,omitempty"`
+	User      *BackupUser      `json:"user,omitempty"`
+	Workspace *BackupWorkspace `json:"workspace,omitempty"`
+	Memory    *ArchivedMemory  `json:"memory,omitempty"`
+	APIKey    *BackupAPIKey    `json:"api_key,omitempty"`
+}
+
+// BackupHeader describes a backup
+type BackupHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Region    string    `json:"region,omitempty"`
+	// EmbeddingModel produced the embeddings in the backup, if it has any
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+}
+
+// BackupUser is an account in a backup. Users are matched by email on restore;
+// the password hash is kept so they can log in to the restored deployment.
+type BackupUser struct {
+	Email              string     `json:"email"`
+	PasswordHash       string     `json:"password_hash"`
+	Role               string     `json:"role"`
+	Plan               string     `json:"plan,omitempty"`
+	MemoryLimit        *int       `json:"memory_limit,omitempty"`
+	EvictionPolicy     string     `json:"eviction_policy,omitempty"`
+	MaintenanceEnabled bool       `json:"maintenance_enabled,omitempty"`
+	DisabledAt         *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// BackupWorkspace is a workspace in a backup; the memories after it belong to it
+type BackupWorkspace struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BackupAPIKey is an API key in a backup
+type BackupAPIKey struct {
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	Permissions string     `json:"permissions,omitempty"`
+	Type        string     `json:"type,omitempty"` // empty in backups made before keys recorded a type
+	IsActive    bool       `json:"is_active"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Workspace names the workspace requests default to; empty is the default
+	Workspace string    `json:"workspace,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupOptions controls what a backup contains
+type BackupOptions struct {
+	// IncludeEmbeddings adds each memory's embedding so a restore can skip
+	// re-embedding
+	IncludeEmbeddings bool
+}
+
+// BackupResult summarises a backup
+type BackupResult struct {
+	Users      int `json:"users"`
+	Workspaces int `json:"workspaces"`
+	Memories   int `json:"memories"`
+	APIKeys    int `json:"api_keys"`
+}
+
+// RestoreOptions controls a restore
+type RestoreOptions struct {
+	// Encryption opens encrypted backups
+	Encryption *utils.EncryptionService
+	// AllowCrossRegion restores a backup taken in another residency region
+	AllowCrossRegion bool
+}
+
+// RestoreResult summarises a restore
+type RestoreResult struct {
+	Users        int `json:"users"`
+	UsersCreated int `json:"users_created"`
+	Workspaces   int `json:"workspaces"`
+	APIKeys      int `json:"api_keys"`
+	ImportMemoriesResult
+}
+
+// NewBackupStoreFromConfig builds the store backups are written to
+func NewBackupStoreFromConfig(cfg config.Backup) (ObjectStore, error) {
+	switch cfg.Destination {
+	case "", "local":
+		return NewLocalObjectStore(cfg.Path)
+	case "s3", "gcs":
+		return NewS3ObjectStore(cfg.Bucket())
+	default:
+		return nil, fmt.Errorf("invalid backup destination: %s", cfg.Destination)
+	}
+}
+
+// NewBackupEncryptionFromConfig returns the key backups are sealed and opened
+// with: the backup encryption key, or the encryption master key when there is
+// none. It returns nil when neither is configured.
+func NewBackupEncryptionFromConfig(cfg *config.Config) (*utils.EncryptionService, error) {
+	key := cfg.Backup.EncryptionKey
+	if key == "" {
+		// Backups sealed before a master key rotation open with the previous keys
+		if cfg.Encryption.MasterKey == "" {
+			return nil, nil
+		}
+		return NewEncryptionServiceFromConfig(cfg.Encryption)
+	}
+	return utils.NewEncryptionService(key)
+}
+
+// WriteBackup writes every user's accounts, workspaces, memories and API keys to
+// w as JSON lines. Encrypted memories keep their encrypted payload, so restoring
+// them needs the same encryption master key.
+func (s *MemoryService) WriteBackup(ctx context.Context, w io.Writer, opts BackupOptions) (*BackupResult, error) {
+	encoder := json.NewEncoder(w)
+	header := &BackupHeader{
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Region:    s.ResidencyRegion(),
+	}
+	if opts.IncludeEmbeddings {
+		header.EmbeddingModel = s.EmbeddingModel()
+	}
+	if err := encoder.Encode(BackupRecord{Header: header}); err != nil {
+		return nil, fmt.Errorf("write backup header: %w", err)
+	}
+
+	result := &BackupResult{}
+	exportOptions := ExportOptions{IncludeEmbeddings: opts.IncludeEmbeddings, KeepEncrypted: true}
+	var users []models.User
+	err := s.db.WithContext(ctx).Order("id").FindInBatches(&users, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range users {
+			user := &users[i]
+			if err := encoder.Encode(BackupRecord{User: backupUser(user)}); err != nil {
+				return fmt.Errorf("write user %d: %w", user.ID, err)
+			}
+			result.Users++
+
+			var workspaces []models.Workspace
+			if err := s.db.WithContext(ctx).Where("user_id = ?", user.ID).Order("id").Find(&workspaces).Error; err != nil {
+				return utils.WrapDatabaseError("backup workspaces", err)
+			}
+			userService := s.forUser(user.ID)
+			streamWorkspace := func(workspaceID uint) error {
+				count, err := userService.InWorkspace(workspaceID).StreamMemories(ctx, exportOptions, func(memory *ArchivedMemory) error {
+					return encoder.Encode(BackupRecord{Memory: memory})
+				})
+				result.Memories += count
+				if err != nil {
+					return fmt.Errorf("backup memories of user %d: %w", user.ID, err)
+				}
+				return nil
+			}
+
+			if err := streamWorkspace(0); err != nil {
+				return err
+			}
+			workspaceNames := map[uint]string{}
+			for _, workspace := range workspaces {
+				workspaceNames[workspace.ID] = workspace.Name
+				if err := encoder.Encode(BackupRecord{Workspace: &BackupWorkspace{
+					Name:        workspace.Name,
+					Description: workspace.Description,
+					CreatedAt:   workspace.CreatedAt,
+				}}); err != nil {
+					return fmt.Errorf("write workspace %d: %w", workspace.ID, err)
+				}
+				result.Workspaces++
+				if err := streamWorkspace(workspace.ID); err != nil {
+					return err
+				}
+			}
+
+			var keys []models.APIKey
+			if err := s.db.WithContext(ctx).Where("user_id = ?", user.ID).Order("id").Find(&keys).Error; err != nil {
+				return utils.WrapDatabaseError("backup API keys", err)
+			}
+			for _, key := range keys {
+				if err := encoder.Encode(BackupRecord{APIKey: &BackupAPIKey{
+					Key:         key.Key,
+					Name:        key.Name,
+					Permissions: key.Permissions,
+					Type:        key.KeyType,
+					IsActive:    key.IsActive,
+					ExpiresAt:   key.ExpiresAt,
+					Workspace:   workspaceNames[key.WorkspaceID],
+					CreatedAt:   key.CreatedAt,
+				}}); err != nil {
+					return fmt.Errorf("write API key %d: %w", key.ID, err)
+				}
+				result.APIKeys++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// backupUser converts an account for a backup
+func backupUser(user *models.User) *BackupUser {
+	return &BackupUser{
+		Email:              user.Email,
+		PasswordHash:       user.Password,
+		Role:               user.Role,
+		Plan:               user.Plan,
+		MemoryLimit:        user.MemoryLimit,
+		EvictionPolicy:     user.EvictionPolicy,
+		MaintenanceEnabled: user.MaintenanceEnabled,
+		DisabledAt:         user.DisabledAt,
+		CreatedAt:          user.CreatedAt,
+	}
+}
+
+// RestoreBackup rebuilds accounts, workspaces, memories and API keys from a
+// backup written by WriteBackup, gzipped and possibly sealed with SealBackup.
+// Users, workspaces and API keys that already exist are reused rather than
+// replaced, and memories are imported as ImportMemories does, so restoring the
+// same backup twice adds nothing. Embeddings in the backup are restored when this
+// deployment embeds with the same model; other memories are queued for embedding.
+func (s *MemoryService) RestoreBackup(ctx context.Context, r io.Reader, opts RestoreOptions) (*RestoreResult, error) {
+	reader, err := openBackup(r, opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxArchiveLineSize)
+
+	result := &RestoreResult{}
+	var (
+		header    *BackupHeader
+		user      *models.User
+		target    *MemoryService
+		pending   []ArchivedMemory
+		workspace = map[string]uint{}
+	)
+	// flush imports the memories read since the last flush
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		imported, err := target.ImportMemories(ctx, &MemoryArchive{
+			Version:  MemoryArchiveVersion,
+			Region:   header.Region,
+			Memories: pending,
+		}, true)
+		if err != nil {
+			return fmt.Errorf("restore memories of %s: %w", user.Email, err)
+		}
+		result.Created += imported.Created
+		result.Skipped += imported.Skipped
+		result.EmbeddingsRestored += imported.EmbeddingsRestored
+		result.EmbeddingsQueued += imported.EmbeddingsQueued
+		pending = nil
+		return nil
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		var record BackupRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return result, utils.WrapValidationError("backup", fmt.Sprintf("line %d: %v", line, err))
+		}
+
+		switch {
+		case header == nil:
+			if record.Header == nil {
+				return result, utils.WrapValidationError("backup", "missing header")
+			}
+			header = record.Header
+			if header.Version < 1 || header.Version > BackupVersion {
+				return result, utils.InvalidFieldError("version", fmt.Sprintf("unsupported backup version %d", header.Version))
+			}
+			if err := CheckResidency("backup", header.Region, s.ResidencyRegion(), opts.AllowCrossRegion); err != nil {
+				return result, err
+			}
+
+		case record.User != nil:
+			if err := flush(); err != nil {
+				return result, err
+			}
+			created := false
+			if user, created, err = s.restoreUser(ctx, record.User); err != nil {
+				return result, err
+			}
+			result.Users++
+			if created {
+				result.UsersCreated++
+			}
+			target = s.forUser(user.ID).InWorkspace(0)
+			workspace = map[string]uint{"": 0}
+
+		case user == nil:
+			return result, utils.WrapValidationError("backup", fmt.Sprintf("line %d: record before the first user", line))
+
+		case record.Workspace != nil:
+			if err := flush(); err != nil {
+				return result, err
+			}
+			id, err := s.restoreWorkspace(ctx, user.ID, record.Workspace)
+			if err != nil {
+				return result, err
+			}
+			result.Workspaces++
+			workspace[record.Workspace.Name] = id
+			target = s.forUser(user.ID).InWorkspace(id)
+
+		case record.Memory != nil:
+			pending = append(pending, *record.Memory)
+			if len(pending) >= exportBatchSize {
+				if err := flush(); err != nil {
+					return result, err
+				}
+			}
+
+		case record.APIKey != nil:
+			if err := flush(); err != nil {
+				return result, err
+			}
+			restored, err := s.restoreAPIKey(ctx, user.ID, workspace[record.APIKey.Workspace], record.APIKey)
+			if err != nil {
+				return result, err
+			}
+			if restored {
+				result.APIKeys++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, utils.WrapValidationError("backup", fmt.Sprintf("line %d: %v", line+1, err))
+	}
+	if header == nil {
+		return result, utils.WrapValidationError("backup", "backup is empty")
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	s.logger.Info().
+		Int("users", result.Users).
+		Int("users_created", result.UsersCreated).
+		Int("memories_created", result.Created).
+		Int("embeddings_restored", result.EmbeddingsRestored).
+		Msg("restored backup")
+	return result, nil
+}
+
+// restoreUser finds the account with the backed up user's email, creating it if
+// there is none
+func (s *MemoryService) restoreUser(ctx context.Context, backedUp *BackupUser) (*models.User, bool, error) {
+	if backedUp.Email == "" || backedUp.PasswordHash == "" {
+		return nil, false, utils.WrapValidationError("backup", "user without an email or password hash")
+	}
+	var user models.User
+	err := s.db.WithContext(ctx).Where("email = ?", backedUp.Email).First(&user).Error
+	if err == nil {
+		return &user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, utils.WrapDatabaseError("restore user", err)
+	}
+
+	role := backedUp.Role
+	if !models.IsValidRole(role) {
+		role = models.RoleUser
+	}
+	user = models.User{
+		Email:              backedUp.Email,
+		Password:           backedUp.PasswordHash,
+		Role:               role,
+		Plan:               backedUp.Plan,
+		MemoryLimit:        backedUp.MemoryLimit,
+		EvictionPolicy:     backedUp.EvictionPolicy,
+		MaintenanceEnabled: backedUp.MaintenanceEnabled,
+		DisabledAt:         backedUp.DisabledAt,
+		CreatedAt:          backedUp.CreatedAt,
+	}
+	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+		return nil, false, utils.WrapDatabaseError("restore user", err)
+	}
+	return &user, true, nil
+}
+
+// restoreWorkspace returns the ID of the user's workspace with the backed up
+// workspace's name, creating it if there is none
+func (s *MemoryService) restoreWorkspace(ctx context.Context, userID uint, backedUp *BackupWorkspace) (uint, error) {
+	workspace := models.Workspace{
+		UserID:      userID,
+		Name:        backedUp.Name,
+		Description: backedUp.Description,
+		CreatedAt:   backedUp.CreatedAt,
+	}
+	if err := s.db.WithContext(ctx).
+		Where(models.Workspace{UserID: userID, Name: backedUp.Name}).
+		FirstOrCreate(&workspace).Error; err != nil {
+		return 0, utils.WrapDatabaseError("restore workspace", err)
+	}
+	return workspace.ID, nil
+}
+
+// restoreAPIKey recreates a backed up API key unless a key with its value
+// exists, reporting whether it was created
+func (s *MemoryService) restoreAPIKey(ctx context.Context, userID, workspaceID uint, backedUp *BackupAPIKey) (bool, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Unscoped().Model(&models.APIKey{}).Where("key = ?", backedUp.Key).Count(&count).Error; err != nil {
+		return false, utils.WrapDatabaseError("restore API key", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	keyType := backedUp.Type
+	if keyType == "" {
+		keyType = models.APIKeyTypeForPermissions(strings.Split(backedUp.Permissions, ","))
+	}
+	key := models.APIKey{
+		UserID:      userID,
+		Key:         backedUp.Key,
+		Name:        backedUp.Name,
+		Permissions: backedUp.Permissions,
+		KeyType:     keyType,
+		IsActive:    backedUp.IsActive,
+		ExpiresAt:   backedUp.ExpiresAt,
+		WorkspaceID: workspaceID,
+		CreatedAt:   backedUp.CreatedAt,
+	}
+	if err := s.db.WithContext(ctx).Create(&key).Error; err != nil {
+		return false, utils.WrapDatabaseError("restore API key", err)
+	}
+	// Create leaves a false IsActive to the column default
+	if !backedUp.IsActive {
+		if err := s.db.WithContext(ctx).Model(&key).Update("is_active", false).Error; err != nil {
+			return false, utils.WrapDatabaseError("restore API key", err)
+		}
+	}
+	return true, nil
+}
+
+// SealBackup encrypts a backup with a key derived from encryption
+func SealBackup(encryption *utils.EncryptionService, backup []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := backupCipher(encryption, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := make([]byte, 0, len(encryptedBackupMagic)+len(salt)+len(nonce)+len(backup)+gcm.Overhead())
+	sealed = append(sealed, encryptedBackupMagic...)
+	sealed = append(sealed, salt...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, backup, encryptedBackupMagic), nil
+}
+
+// openBackup returns the JSON lines of a gzipped backup, opening it first if it
+// was sealed with SealBackup
+func openBackup(r io.Reader, encryption *utils.EncryptionService) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(encryptedBackupMagic))
+	if bytes.Equal(magic, encryptedBackupMagic) {
+		if encryption == nil {
+			return nil, utils.WrapValidationError("backup", "backup is encrypted and no backup encryption key is configured")
+		}
+		sealed, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		sealed = sealed[len(encryptedBackupMagic):]
+		if len(sealed) < 16 {
+			return nil, utils.WrapValidationError("backup", "encrypted backup is truncated")
+		}
+		keys, err := encryption.DeriveKeys(sealed[:16], backupKeyInfo)
+		if err != nil {
+			return nil, err
+		}
+		sealed = sealed[16:]
+		var plain []byte
+		for _, key := range keys {
+			gcm, err := newBackupCipher(key)
+			if err != nil {
+				return nil, err
+			}
+			if len(sealed) < gcm.NonceSize() {
+				return nil, utils.WrapValidationError("backup", "encrypted backup is truncated")
+			}
+			if plain, err = gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], encryptedBackupMagic); err == nil {
+				break
+			}
+		}
+		if plain == nil {
+			return nil, utils.WrapValidationError("backup", "backup cannot be decrypted with this key")
+		}
+		buffered = bufio.NewReader(bytes.NewReader(plain))
+	}
+
+	reader, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, utils.WrapValidationError("backup", fmt.Sprintf("not a gzipped backup: %v", err))
+	}
+	return reader, nil
+}
+
+// backupCipher returns the AES-GCM cipher sealing a backup with the given salt
+func backupCipher(encryption *utils.EncryptionService, salt []byte) (cipher.AEAD, error) {
+	key, err := encryption.DeriveKey(salt, backupKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	return newBackupCipher(key)
+}
+
+// newBackupCipher returns the AES-GCM cipher for a derived backup key
+func newBackupCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// BackupWorkerConfig controls scheduled backups
+type BackupWorkerConfig struct {
+	// Schedule is when backups are taken, in UTC
+	Schedule *utils.CronSchedule
+	// Store is where backups are written
+	Store ObjectStore
+	// Encryption seals backups when set
+	Encryption        *utils.EncryptionService
+	IncludeEmbeddings bool
+	// Keep is how many backups are kept, the oldest being deleted (0 keeps all)
+	Keep int
+}
+
+// NewBackupWorkerConfig builds the backup worker's settings from the backup
+// configuration
+func NewBackupWorkerConfig(cfg *config.Config) (BackupWorkerConfig, error) {
+	schedule, err := utils.ParseCronSchedule(cfg.Backup.Schedule)
+	if err != nil {
+		return BackupWorkerConfig{}, err
+	}
+	store, err := NewBackupStoreFromConfig(cfg.Backup)
+	if err != nil {
+		return BackupWorkerConfig{}, err
+	}
+	workerConfig := BackupWorkerConfig{
+		Schedule:          schedule,
+		Store:             store,
+		IncludeEmbeddings: cfg.Backup.IncludeEmbeddings,
+		Keep:              cfg.Backup.Keep,
+	}
+	if cfg.Backup.Encrypt {
+		if workerConfig.Encryption, err = NewBackupEncryptionFromConfig(cfg); err != nil {
+			return BackupWorkerConfig{}, fmt.Errorf("invalid backup encryption key: %w", err)
+		}
+	}
+	return workerConfig, nil
+}
+
+// BackupWorker takes a backup of every user's memories on a cron schedule
+type BackupWorker struct {
+	service *MemoryService
+	config  BackupWorkerConfig
+}
+
+// NewBackupWorker creates a backup worker using the service's database
+func NewBackupWorker(service *MemoryService, config BackupWorkerConfig) *BackupWorker {
+	return &BackupWorker{
+		service: service,
+		config:  config,
+	}
+}
+
+// Start runs the worker until the context is cancelled, taking a backup at each
+// scheduled time
+func (w *BackupWorker) Start(ctx context.Context) {
+	for {
+		next := w.config.Schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			w.service.logger.Error().Str("schedule", w.config.Schedule.String()).Msg("backup schedule never runs")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.service.logger.Error().Err(err).Msg("backup failed")
+		}
+	}
+}
+
+// RunOnce takes a backup, writes it to the store and deletes backups past the
+// number kept, returning the new backup's key
+func (w *BackupWorker) RunOnce(ctx context.Context) (string, error) {
+	started := time.Now().UTC()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	result, err := w.service.WriteBackup(ctx, gz, BackupOptions{IncludeEmbeddings: w.config.IncludeEmbeddings})
+	if err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress backup: %w", err)
+	}
+
+	data, suffix, contentType := buf.Bytes(), backupSuffix, "application/gzip"
+	if w.config.Encryption != nil {
+		if data, err = SealBackup(w.config.Encryption, data); err != nil {
+			return "", fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		suffix, contentType = encryptedBackupSuffix, "application/octet-stream"
+	}
+
+	key := BackupKeyPrefix + "remember-me-" + started.Format("20060102T150405Z") + suffix
+	if err := w.config.Store.Put(ctx, key, data, contentType); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	w.service.logger.Info().
+		Str("key", key).
+		Str("store", w.config.Store.Name()).
+		Int("bytes", len(data)).
+		Int("users", result.Users).
+		Int("memories", result.Memories).
+		Dur("duration", time.Since(started)).
+		Msg("backup completed")
+
+	if w.config.Keep > 0 {
+		if err := w.prune(ctx); err != nil {
+			w.service.logger.Warn().Err(err).Msg("failed to delete old backups")
+		}
+	}
+	return key, nil
+}
+
+// prune deletes all but the newest backups kept
+func (w *BackupWorker) prune(ctx context.Context) error {
+	backups, err := ListBackups(ctx, w.config.Store)
+	if err != nil {
+		return err
+	}
+	for len(backups) > w.config.Keep {
+		if err := w.config.Store.Delete(ctx, backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// ListBackups returns the keys of the backups in a store, oldest first
+func ListBackups(ctx context.Context, store ObjectStore) ([]string, error) {
+	keys, err := store.List(ctx, BackupKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, backupSuffix) || strings.HasSuffix(key, encryptedBackupSuffix) {
+			backups = append(backups, key)
+		}
+	}
+	return backups, nil
+}
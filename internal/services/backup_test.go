@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestBackupWorker_BackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+	config := map[string]interface{}{"encryption_service": encryption}
+
+	// Migrations create the system user in every database
+	db := setupAccountDB(t)
+	testutil.NewUser().ID(1).Email("system@remember-me.local").Create(t, db)
+	alice := testutil.NewUser().ID(2).Email("alice@example.com").Create(t, db)
+	bob := testutil.NewUser().ID(3).Email("bob@example.com").Create(t, db)
+	aliceService := NewMemoryServiceWithUser(db, nil, zerolog.Nop(), config, alice.ID)
+	work, err := aliceService.CreateWorkspace(ctx, "work", "my job")
+	require.NoError(t, err)
+	storeTestMemory(t, aliceService.InWorkspace(0), "allergic to peanuts")
+	storeTestMemory(t, aliceService.InWorkspace(work.ID), "team lead is Sam")
+	storeTestMemory(t, NewMemoryServiceWithUser(db, nil, zerolog.Nop(), config, bob.ID), "likes green tea")
+	aliceKey := testutil.NewAPIKey(alice.ID).Name("work laptop").Create(t, db)
+	require.NoError(t, db.Model(aliceKey).Update("workspace_id", work.ID).Error)
+	bobKey := testutil.NewAPIKey(bob.ID).Inactive().Create(t, db)
+
+	store := newMemoryObjectStore()
+	require.NoError(t, store.Put(ctx, "backups/remember-me-20200101T000000Z.jsonl.gz", []byte("old"), ""))
+	require.NoError(t, store.Put(ctx, "backups/README.txt", []byte("not a backup"), ""))
+	worker := NewBackupWorker(NewMemoryService(db, nil, zerolog.Nop(), config), BackupWorkerConfig{
+		Store:      store,
+		Encryption: encryption,
+		Keep:       1,
+	})
+	key, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Regexp(t, `^backups/remember-me-\d{8}T\d{6}Z\.jsonl\.gz\.enc$`, key)
+	backups, err := ListBackups(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{key}, backups, "the older backup is deleted")
+	_, err = store.Get(ctx, "backups/README.txt")
+	assert.NoError(t, err, "other objects are left alone")
+
+	backup, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	restoreDB := setupAccountDB(t)
+	testutil.NewUser().ID(1).Email("system@remember-me.local").Create(t, restoreDB)
+	restorer := NewMemoryService(restoreDB, nil, zerolog.Nop(), config)
+	_, err = restorer.RestoreBackup(ctx, bytes.NewReader(backup), RestoreOptions{})
+	assert.True(t, utils.IsValidationError(err), "an encrypted backup needs the key")
+
+	result, err := restorer.RestoreBackup(ctx, bytes.NewReader(backup), RestoreOptions{Encryption: encryption})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Users)
+	assert.Equal(t, 2, result.UsersCreated, "the system user already exists")
+	assert.Equal(t, 1, result.Workspaces)
+	assert.Equal(t, 2, result.APIKeys)
+	assert.Equal(t, 3, result.Created)
+
+	var restoredAlice models.User
+	require.NoError(t, restoreDB.Where("email = ?", "alice@example.com").First(&restoredAlice).Error)
+	assert.Equal(t, alice.Password, restoredAlice.Password)
+	restoredService := NewMemoryServiceWithUser(restoreDB, nil, zerolog.Nop(), config, restoredAlice.ID)
+	workspaces, err := restoredService.ListWorkspaces(ctx)
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+	restoredWork := workspaces[1]
+	assert.Equal(t, "work", restoredWork.Name)
+
+	memories, err := restoredService.InWorkspace(restoredWork.ID).List(ctx, ListRequest{})
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	assert.Equal(t, "team lead is Sam", memories[0].Content)
+	assert.True(t, memories[0].IsEncrypted, "restored memories are encrypted again")
+
+	var restoredKey models.APIKey
+	require.NoError(t, restoreDB.Where("key = ?", aliceKey.Key).First(&restoredKey).Error)
+	assert.Equal(t, restoredAlice.ID, restoredKey.UserID)
+	assert.Equal(t, restoredWork.ID, restoredKey.WorkspaceID)
+	var restoredInactive models.APIKey
+	require.NoError(t, restoreDB.Where("key = ?", bobKey.Key).First(&restoredInactive).Error)
+	assert.False(t, restoredInactive.IsActive)
+
+	// Restoring again adds nothing
+	result, err = restorer.RestoreBackup(ctx, bytes.NewReader(backup), RestoreOptions{Encryption: encryption})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.UsersCreated)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 3, result.Skipped)
+	assert.Equal(t, 0, result.APIKeys)
+}
+
+func TestMemoryService_RestoreBackup_Invalid(t *testing.T) {
+	ctx := context.Background()
+	service := NewMemoryService(setupAccountDB(t), nil, zerolog.Nop(), nil)
+
+	_, err := service.RestoreBackup(ctx, bytes.NewReader([]byte("not a backup")), RestoreOptions{})
+	assert.True(t, utils.IsValidationError(err))
+
+	otherKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	other, err := utils.NewEncryptionService(otherKey)
+	require.NoError(t, err)
+	sealed, err := SealBackup(other, []byte("backup"))
+	require.NoError(t, err)
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+	_, err = service.RestoreBackup(ctx, bytes.NewReader(sealed), RestoreOptions{Encryption: encryption})
+	assert.True(t, utils.IsValidationError(err), "the backup was sealed with another key")
+}
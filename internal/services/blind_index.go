@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Encrypted memories keep "[encrypted]" in place of their content, so they are
+// matched through blind indexes instead: keyed hashes of the plaintext computed
+// when it is encrypted (see utils.BlindIndex). Exact duplicates are found by the
+// content index, and keyword search finds encrypted memories containing every
+// word of the query through the keyword index. Words match exactly, ignoring
+// case, without the stemming of full-text search.
+
+// contentBlindIndex returns the blind index of the service user's encrypted
+// content, or nil when nothing is encrypted
+func (s *MemoryService) contentBlindIndex() *utils.BlindIndex {
+	var encryption *utils.EncryptionService
+	if s.encryption != nil {
+		dataKey, err := s.dataKey(s.userID)
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("failed to load data key for blind index")
+			return nil
+		}
+		encryption = dataKey
+	} else if hook := s.GetModerationHook(); hook != nil {
+		encryption = hook.encryption
+	}
+	if encryption == nil {
+		return nil
+	}
+
+	index, err := encryption.BlindIndex(s.userID)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to derive blind index")
+		return nil
+	}
+	return index
+}
+
+// setBlindIndexes records the blind indexes of a memory's plaintext content
+// before it is encrypted
+func setBlindIndexes(encryption *utils.EncryptionService, memory *models.Memory, ownerID uint) error {
+	index, err := encryption.BlindIndex(ownerID)
+	if err != nil {
+		return err
+	}
+	memory.ContentIndex = index.Content(memory.Content)
+	memory.KeywordIndex = index.Keywords(memory.Content)
+	return nil
+}
+
+// clearBlindIndexes drops the blind indexes along with a memory's encrypted
+// content, when new plaintext replaces it
+func clearBlindIndexes(memory *models.Memory) {
+	memory.ContentIndex = ""
+	memory.KeywordIndex = ""
+}
+
+// blindKeywordCondition returns the condition matching encrypted memories that
+// contain every word of terms, or an empty condition when there are none
+func blindKeywordCondition(index *utils.BlindIndex, terms string) (string, []interface{}) {
+	words := index.Words(terms)
+	if len(words) == 0 {
+		return "", nil
+	}
+
+	conditions := make([]string, 0, len(words)+1)
+	args := make([]interface{}, 0, len(words)+1)
+	conditions = append(conditions, "is_encrypted = ?")
+	args = append(args, true)
+	for _, word := range words {
+		conditions = append(conditions, "keyword_index LIKE ?")
+		args = append(args, "% "+word+" %")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// contentCondition narrows a query to memories whose content is exactly content,
+// encrypted or not
+func (s *MemoryService) contentCondition(query *gorm.DB, content string) *gorm.DB {
+	if index := s.contentBlindIndex(); index != nil {
+		return query.Where("(content = ? OR content_index = ?)", content, index.Content(content))
+	}
+	return query.Where("content = ?", content)
+}
+
+// contentHash returns the hash kept in content_hash, which finds exact
+// duplicates. Where content is encrypted it is the content blind index of the
+// user's data key, so it cannot be checked against guessed texts and differs
+// between users; otherwise it is models.HashContent, as the content itself is
+// stored in plaintext.
+func (s *MemoryService) contentHash(content string) (string, error) {
+	if s.encryption == nil {
+		return models.HashContent(content), nil
+	}
+	dataKey, err := s.dataKey(s.userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load data key: %w", err)
+	}
+	index, err := dataKey.BlindIndex(s.userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive blind index: %w", err)
+	}
+	return index.Content(content), nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestBlindIndex_EncryptedMemories(t *testing.T) {
+	ctx := context.Background()
+	encryption := newTestEncryption(t)
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	for _, id := range []uint{2, 3} {
+		require.NoError(t, db.Create(&models.User{ID: id, Email: string(rune('a'+id)) + "@example.com", Password: "x"}).Error)
+	}
+	config := map[string]interface{}{"encryption_service": encryption}
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	alice := NewMemoryServiceWithUser(db, nil, logger, config, 2)
+	bob := NewMemoryServiceWithUser(db, nil, logger, config, 3)
+
+	stored, _ := storeTestMemory(t, alice, "The Apollo launch is on Friday")
+	storeTestMemory(t, alice, "Lunch with the design team")
+	bobs, _ := storeTestMemory(t, bob, "The Apollo launch is on Friday")
+
+	var row models.Memory
+	require.NoError(t, db.Omit("embedding", "tags").First(&row, stored.ID).Error)
+	assert.Equal(t, "[encrypted]", row.Content)
+	assert.Len(t, row.ContentIndex, 64)
+	assert.NotContains(t, row.KeywordIndex, "apollo")
+
+	t.Run("indexes are keyed per user", func(t *testing.T) {
+		var other models.Memory
+		require.NoError(t, db.Omit("embedding", "tags").First(&other, bobs.ID).Error)
+		assert.NotEqual(t, row.ContentIndex, other.ContentIndex)
+		assert.NotEqual(t, row.KeywordIndex, other.KeywordIndex)
+	})
+
+	t.Run("content hashes are keyed per user", func(t *testing.T) {
+		var other models.Memory
+		require.NoError(t, db.Omit("embedding", "tags").First(&other, bobs.ID).Error)
+		assert.NotEqual(t, models.HashContent("The Apollo launch is on Friday"), row.ContentHash,
+			"a guessed text's plain hash does not match")
+		assert.NotEqual(t, row.ContentHash, other.ContentHash)
+	})
+
+	t.Run("exact duplicates update the memory", func(t *testing.T) {
+		again, _ := storeTestMemory(t, alice, "The Apollo launch is on Friday")
+		assert.Equal(t, stored.ID, again.ID)
+
+		count, err := alice.Count(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, count)
+	})
+
+	t.Run("keyword search matches every word", func(t *testing.T) {
+		results, err := alice.Search(ctx, SearchRequest{Query: "friday APOLLO"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, stored.ID, results[0].ID)
+		assert.Equal(t, "The Apollo launch is on Friday", results[0].Content)
+
+		results, err = alice.Search(ctx, SearchRequest{Query: "apollo design"})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		// Partial words do not match
+		results, err = alice.Search(ctx, SearchRequest{Query: "apol"})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("updates keep the indexes current", func(t *testing.T) {
+		_, err := alice.Update(ctx, stored.ID, UpdateRequest{Content: "The Apollo launch moved to Monday"})
+		require.NoError(t, err)
+		results, err := alice.Search(ctx, SearchRequest{Query: "friday"})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		// Changing other fields leaves the encrypted content alone
+		_, err = alice.Update(ctx, stored.ID, UpdateRequest{Priority: models.PriorityHigh})
+		require.NoError(t, err)
+		results, err = alice.Search(ctx, SearchRequest{Query: "monday"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "The Apollo launch moved to Monday", results[0].Content)
+	})
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// maxBulkDelete caps the memories one bulk delete removes
+	maxBulkDelete = 10000
+	// bulkDeleteSampleSize is how many of the matching memories a preview lists
+	bulkDeleteSampleSize = 20
+	// bulkDeleteBatchSize is how many memories each delete statement removes
+	bulkDeleteBatchSize = 500
+)
+
+// ErrBulkDeleteConfirmationRequired is returned when a bulk delete does not carry
+// a token issued for the same memories
+var ErrBulkDeleteConfirmationRequired = errors.New("confirmation required to delete memories in bulk")
+
+// BulkDeleteConfirmationError carries the single-use token the caller must send
+// back to delete the memories the filters match
+type BulkDeleteConfirmationError struct {
+	Matched int
+	Token   string
+}
+
+func (e *BulkDeleteConfirmationError) Error() string {
+	return fmt.Sprintf("%d memories match; repeat the delete with confirm=%q to delete them", e.Matched, e.Token)
+}
+
+func (e *BulkDeleteConfirmationError) Unwrap() error {
+	return ErrBulkDeleteConfirmationRequired
+}
+
+// BulkDeleteRequest selects memories to delete by filter. At least one filter is
+// required, so an empty request never deletes everything.
+type BulkDeleteRequest struct {
+	Category string
+	Type     string
+	Tags     []string
+	TagMatch string
+	// Query keeps memories whose content contains it, ignoring case
+	Query string
+	// DateRange keeps memories created or updated within its bounds
+	DateRange
+	// IncludeCritical deletes matching critical memories too; they are skipped
+	// otherwise
+	IncludeCritical bool
+	// DryRun reports what would be deleted, with the token confirming it
+	DryRun bool
+	// Confirm is a token issued by a dry run or refused delete over the same
+	// memories
+	Confirm string
+}
+
+// BulkDeleteResult reports what a bulk delete removed or, for a dry run, would
+type BulkDeleteResult struct {
+	DryRun  bool `json:"dry_run"`
+	Matched int  `json:"matched"`
+	Deleted int  `json:"deleted"`
+	// SkippedCritical counts matching critical memories left alone
+	SkippedCritical int `json:"skipped_critical,omitempty"`
+	// Sample lists some of the memories a dry run would delete
+	Sample []*models.Memory `json:"sample,omitempty"`
+	// Confirm is the token that deletes exactly the memories a dry run matched
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// hasFilter reports whether the request narrows the memories at all
+func (r *BulkDeleteRequest) hasFilter() bool {
+	return r.Category != "" || r.Type != "" || len(r.Tags) > 0 || strings.TrimSpace(r.Query) != "" || !r.DateRange.IsZero()
+}
+
+// bulkDeleteCandidate is a memory a bulk delete matched
+type bulkDeleteCandidate struct {
+	ID        uint
+	Priority  string
+	UpdatedAt time.Time
+}
+
+// DeleteMatching deletes the memories matching the request's filters. A dry run
+// counts them and returns a confirmation token; the delete itself only goes ahead
+// with that token, and only while the same memories still match unchanged, so
+// nothing added or edited after the preview is deleted unseen. Tokens are single
+// use and expire; see confirm.
+func (s *MemoryService) DeleteMatching(ctx context.Context, req BulkDeleteRequest) (*BulkDeleteResult, error) {
+	if !req.hasFilter() {
+		return nil, utils.InvalidFieldError("filters", "give at least one of category, type, tags, query or a date range")
+	}
+
+	query, err := s.listQuery(ctx, ListRequest{
+		Category:  req.Category,
+		Type:      req.Type,
+		Tags:      req.Tags,
+		TagMatch:  req.TagMatch,
+		DateRange: req.DateRange,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if term := strings.TrimSpace(req.Query); term != "" {
+		query = query.Where("LOWER(content) LIKE ?", "%"+strings.ToLower(term)+"%")
+	}
+
+	var candidates []bulkDeleteCandidate
+	if err := query.Select("id, priority, updated_at").Order("id").Limit(maxBulkDelete + 1).Scan(&candidates).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to find memories to delete")
+		return nil, utils.WrapDatabaseError("find memories to delete", err)
+	}
+	if len(candidates) > maxBulkDelete {
+		return nil, utils.InvalidFieldError("filters", fmt.Sprintf("more than %d memories match; narrow the filters", maxBulkDelete))
+	}
+
+	result := &BulkDeleteResult{DryRun: req.DryRun}
+	ids := make([]uint, 0, len(candidates))
+	var critical []uint
+	fingerprint := make([]interface{}, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Priority == models.PriorityCritical {
+			if !req.IncludeCritical {
+				result.SkippedCritical++
+				continue
+			}
+			critical = append(critical, candidate.ID)
+		}
+		ids = append(ids, candidate.ID)
+		fingerprint = append(fingerprint, fmt.Sprintf("%d@%d", candidate.ID, candidate.UpdatedAt.UnixNano()))
+	}
+	result.Matched = len(ids)
+	matched := confirmationFingerprint(fingerprint...)
+
+	if req.DryRun {
+		if result.Confirm, err = s.confirm(ctx, ConfirmBulkDelete, matched, ""); err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			sample := ids[:min(len(ids), bulkDeleteSampleSize)]
+			if result.Sample, err = s.List(ctx, ListRequest{WithinIDs: sample, Limit: len(sample)}); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	token, err := s.confirm(ctx, ConfirmBulkDelete, matched, req.Confirm)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		return nil, &BulkDeleteConfirmationError{Matched: len(ids), Token: token}
+	}
+
+	// Critical memories are loaded before they go, for their notifications
+	var criticalMemories []*models.Memory
+	if len(critical) > 0 {
+		if err := s.db.WithContext(ctx).Select("id", "type", "category").
+			Where("user_id = ? AND id IN ?", s.userID, critical).
+			Find(&criticalMemories).Error; err != nil {
+			return nil, utils.WrapDatabaseError("load critical memories", err)
+		}
+	}
+
+	for start := 0; start < len(ids); start += bulkDeleteBatchSize {
+		batch := ids[start:min(start+bulkDeleteBatchSize, len(ids))]
+		attachmentKeys := s.attachmentObjectKeys(ctx, "memory_id IN ?", batch)
+		deleted := s.db.WithContext(ctx).Where("user_id = ? AND id IN ?", s.userID, batch).Delete(&models.Memory{})
+		if deleted.Error != nil {
+			s.logger.Error().Err(deleted.Error).Int("deleted", result.Deleted).Msg("failed to delete memories")
+			return nil, utils.WrapDatabaseError("delete memories", deleted.Error)
+		}
+		result.Deleted += int(deleted.RowsAffected)
+		s.removeAttachmentObjects(ctx, attachmentKeys)
+		s.auditAccess(ctx, models.MemoryAccessDelete, batch)
+	}
+
+	for _, memory := range criticalMemories {
+		s.notifyCriticalChange(EventCriticalMemoryDeleted, memory, nil)
+	}
+
+	s.logActivity(ctx, models.ActivityMemoriesDeleted, map[string]interface{}{
+		"deleted":          result.Deleted,
+		"skipped_critical": result.SkippedCritical,
+	})
+	s.logger.Info().
+		Int("deleted", result.Deleted).
+		Int("skipped_critical", result.SkippedCritical).
+		Msg("deleted memories matching filters")
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_DeleteMatching(t *testing.T) {
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	service := setupMemoryService(t, map[string]interface{}{"notifier": notifier})
+
+	store := func(content, category string, priority string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{
+			Content:  content,
+			Category: category,
+			Type:     models.TypeFact,
+			Priority: priority,
+		})
+		require.NoError(t, err)
+		return memory
+	}
+	store("Apollo deploys run on Fridays", models.CategoryProject, "")
+	store("Apollo uses Postgres 15", models.CategoryProject, "")
+	critical := store("Apollo production credentials live in the vault", models.CategoryProject, models.PriorityCritical)
+	kept := store("Likes green tea", models.CategoryPersonal, "")
+
+	t.Run("requires a filter", func(t *testing.T) {
+		_, err := service.DeleteMatching(ctx, BulkDeleteRequest{DryRun: true})
+		assert.True(t, utils.IsValidationError(err))
+	})
+
+	t.Run("dry run previews without deleting", func(t *testing.T) {
+		result, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "apollo", DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 2, result.Matched)
+		assert.Equal(t, 1, result.SkippedCritical)
+		assert.Zero(t, result.Deleted)
+		assert.Len(t, result.Sample, 2)
+		assert.NotEmpty(t, result.Confirm)
+
+		count, err := service.Count(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 4, count)
+	})
+
+	t.Run("delete needs the preview token", func(t *testing.T) {
+		_, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "apollo", Confirm: "deadbeef"})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrBulkDeleteConfirmationRequired))
+
+		var confirmErr *BulkDeleteConfirmationError
+		require.True(t, errors.As(err, &confirmErr))
+		assert.Equal(t, 2, confirmErr.Matched)
+	})
+
+	t.Run("a token goes stale when the matches change", func(t *testing.T) {
+		preview, err := service.DeleteMatching(ctx, BulkDeleteRequest{Category: models.CategoryProject, DryRun: true})
+		require.NoError(t, err)
+
+		extra := store("Apollo staging is at staging.apollo.dev", models.CategoryProject, "")
+		_, err = service.DeleteMatching(ctx, BulkDeleteRequest{Category: models.CategoryProject, Confirm: preview.Confirm})
+		assert.True(t, errors.Is(err, ErrBulkDeleteConfirmationRequired))
+		require.NoError(t, service.Delete(ctx, extra.ID))
+	})
+
+	t.Run("confirmed delete skips critical memories", func(t *testing.T) {
+		preview, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "apollo", DryRun: true})
+		require.NoError(t, err)
+
+		result, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "apollo", Confirm: preview.Confirm})
+		require.NoError(t, err)
+		assert.False(t, result.DryRun)
+		assert.Equal(t, 2, result.Deleted)
+		assert.Equal(t, 1, result.SkippedCritical)
+
+		_, err = service.GetByID(ctx, critical.ID)
+		assert.NoError(t, err)
+		_, err = service.GetByID(ctx, kept.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, notifier.events())
+	})
+
+	t.Run("critical memories go when included", func(t *testing.T) {
+		req := BulkDeleteRequest{Category: models.CategoryProject, IncludeCritical: true, DryRun: true}
+		preview, err := service.DeleteMatching(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, preview.Matched)
+
+		req.DryRun, req.Confirm = false, preview.Confirm
+		result, err := service.DeleteMatching(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Deleted)
+
+		_, err = service.GetByID(ctx, critical.ID)
+		assert.True(t, utils.IsNotFoundError(err))
+		assert.Eventually(t, func() bool {
+			events := notifier.events()
+			return len(events) == 1 && events[0] == EventCriticalMemoryDeleted
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("nothing matching deletes nothing", func(t *testing.T) {
+		result, err := service.DeleteMatching(ctx, BulkDeleteRequest{Query: "apollo"})
+		require.NoError(t, err)
+		assert.Zero(t, result.Matched)
+		assert.Zero(t, result.Deleted)
+
+		_, err = service.GetByID(ctx, kept.ID)
+		assert.NoError(t, err)
+	})
+}
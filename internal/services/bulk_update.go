@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// MaxBulkUpdate caps the memories one bulk update changes
+const MaxBulkUpdate = 100
+
+// BulkUpdateItem is the update of one memory in a bulk update. Fields left empty
+// are unchanged, as in Update.
+type BulkUpdateItem struct {
+	ID uint
+	UpdateRequest
+}
+
+// BulkUpdateRequest updates several memories at once
+type BulkUpdateRequest struct {
+	Updates []BulkUpdateItem
+	// Atomic applies every update in one transaction: if any fails, none are
+	// applied. Otherwise each is applied on its own and failures are reported.
+	Atomic bool
+}
+
+// BulkUpdateResult reports a bulk update, with one result per requested update
+// in request order
+type BulkUpdateResult struct {
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+	// RolledBack is set when an atomic update failed and nothing was applied
+	RolledBack bool                   `json:"rolled_back,omitempty"`
+	Results    []BulkUpdateItemResult `json:"results"`
+}
+
+// BulkUpdateItemResult reports the update of one memory
+type BulkUpdateItemResult struct {
+	ID      uint           `json:"id"`
+	Success bool           `json:"success"`
+	Memory  *models.Memory `json:"memory,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Errors reported for the updates an atomic bulk update did not apply because
+// another one failed
+const (
+	bulkUpdateRolledBack   = "rolled back: another update in the batch failed"
+	bulkUpdateNotAttempted = "not attempted: an earlier update in the batch failed"
+)
+
+// BulkUpdate applies updates to several memories. An item that fails does not
+// fail the request; its error is reported in its result. Only a request that is
+// empty or too large is refused as a whole.
+func (s *MemoryService) BulkUpdate(ctx context.Context, req BulkUpdateRequest) (*BulkUpdateResult, error) {
+	if len(req.Updates) == 0 {
+		return nil, utils.InvalidFieldError("updates", "is required and cannot be empty")
+	}
+	if len(req.Updates) > MaxBulkUpdate {
+		return nil, utils.InvalidFieldError("updates", fmt.Sprintf("cannot contain more than %d updates", MaxBulkUpdate))
+	}
+
+	// Finish the writes even if the caller gives up
+	dbCtx, cancel := s.detachedContext(ctx)
+	defer cancel()
+
+	result := &BulkUpdateResult{Results: make([]BulkUpdateItemResult, len(req.Updates))}
+	for i, item := range req.Updates {
+		result.Results[i].ID = item.ID
+	}
+
+	if req.Atomic {
+		s.bulkUpdateAtomic(ctx, dbCtx, req.Updates, result)
+	} else {
+		for i, item := range req.Updates {
+			update, err := s.bulkUpdateItem(ctx, dbCtx, item)
+			if err != nil {
+				result.Results[i].Error = err.Error()
+				continue
+			}
+			result.Results[i].Success = true
+			result.Results[i].Memory = s.finishUpdate(update)
+		}
+	}
+
+	for _, item := range result.Results {
+		if item.Success {
+			result.Updated++
+		} else {
+			result.Failed++
+		}
+	}
+	s.logger.Info().
+		Int("updated", result.Updated).
+		Int("failed", result.Failed).
+		Bool("atomic", req.Atomic).
+		Msg("bulk updated memories")
+	return result, nil
+}
+
+// bulkUpdateAtomic applies the updates in one transaction, stopping at the first
+// failure. Follow-up work only runs once the transaction commits.
+func (s *MemoryService) bulkUpdateAtomic(ctx, dbCtx context.Context, items []BulkUpdateItem, result *BulkUpdateResult) {
+	updates := make([]*writtenUpdate, 0, len(items))
+	failed := -1
+	var failure error
+	err := s.transaction(dbCtx, func(tx *gorm.DB) error {
+		txService := s.inTransaction(tx)
+		for i, item := range items {
+			update, err := txService.bulkUpdateItem(ctx, dbCtx, item)
+			if err != nil {
+				failed, failure = i, err
+				return err
+			}
+			updates = append(updates, update)
+		}
+		return nil
+	})
+
+	if err != nil {
+		result.RolledBack = true
+		for i := range result.Results {
+			switch {
+			case i < failed:
+				result.Results[i].Error = bulkUpdateRolledBack
+			case i == failed:
+				result.Results[i].Error = failure.Error()
+			case failed >= 0:
+				result.Results[i].Error = bulkUpdateNotAttempted
+			default:
+				// The commit itself failed
+				result.Results[i].Error = utils.WrapDatabaseError("update memories", err).Error()
+			}
+		}
+		return
+	}
+
+	for i, update := range updates {
+		result.Results[i].Success = true
+		result.Results[i].Memory = s.finishUpdate(update)
+	}
+}
+
+// bulkUpdateItem validates and saves one update of a bulk update
+func (s *MemoryService) bulkUpdateItem(ctx, dbCtx context.Context, item BulkUpdateItem) (*writtenUpdate, error) {
+	if item.ID == 0 {
+		return nil, utils.InvalidFieldError("id", "is required")
+	}
+	if item.Type != "" && !models.IsValidType(item.Type) {
+		return nil, utils.InvalidFieldError("type", "must be one of fact, conversation, context, or preference")
+	}
+	if item.Category != "" && !models.IsValidCategory(item.Category) {
+		return nil, utils.InvalidFieldError("category", "must be one of personal, project, or business")
+	}
+	if item.Priority != "" && !models.IsValidPriority(item.Priority) {
+		return nil, utils.InvalidFieldError("priority", "must be one of low, medium, high, or critical")
+	}
+	return s.writeUpdate(ctx, dbCtx, item.ID, item.UpdateRequest)
+}
+
+// inTransaction returns a copy of the service whose statements run in tx
+func (s *MemoryService) inTransaction(tx *gorm.DB) *MemoryService {
+	return &MemoryService{
+		db:              tx,
+		embedding:       s.embedding,
+		encryption:      s.encryption,
+		logger:          s.logger,
+		config:          s.config,
+		userID:          s.userID,
+		asyncEmbeddings: s.asyncEmbeddings,
+	}
+}
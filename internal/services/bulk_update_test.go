@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_BulkUpdate(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	first, _ := storeTestMemory(t, service, "Uses Postgres at work")
+	second, _ := storeTestMemory(t, service, "Deploys on Fridays")
+
+	result, err := service.BulkUpdate(ctx, BulkUpdateRequest{Updates: []BulkUpdateItem{
+		{ID: first.ID, UpdateRequest: UpdateRequest{Category: models.CategoryBusiness, Tags: []string{"work"}}},
+		{ID: 9999, UpdateRequest: UpdateRequest{Priority: models.PriorityHigh}},
+		{ID: second.ID, UpdateRequest: UpdateRequest{Type: "opinion"}},
+		{ID: second.ID, UpdateRequest: UpdateRequest{Content: "Never deploys on Fridays"}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 2, result.Failed)
+	assert.False(t, result.RolledBack)
+	require.Len(t, result.Results, 4)
+
+	assert.True(t, result.Results[0].Success)
+	assert.Equal(t, models.CategoryBusiness, result.Results[0].Memory.Category)
+	assert.Equal(t, []string{"work"}, []string(result.Results[0].Memory.Tags))
+	assert.False(t, result.Results[1].Success)
+	assert.Contains(t, result.Results[1].Error, "not found")
+	assert.False(t, result.Results[2].Success)
+	assert.Contains(t, result.Results[2].Error, "type")
+	assert.True(t, result.Results[3].Success)
+	assert.Equal(t, "Never deploys on Fridays", result.Results[3].Memory.Content)
+
+	updated, err := service.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CategoryBusiness, updated.Category)
+}
+
+func TestMemoryService_BulkUpdateAtomic(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	first, _ := storeTestMemory(t, service, "Favourite language is Go")
+	second, _ := storeTestMemory(t, service, "Favourite database is Postgres")
+	third, _ := storeTestMemory(t, service, "Favourite editor is vim")
+
+	t.Run("a failure applies nothing", func(t *testing.T) {
+		result, err := service.BulkUpdate(ctx, BulkUpdateRequest{Atomic: true, Updates: []BulkUpdateItem{
+			{ID: first.ID, UpdateRequest: UpdateRequest{Content: "Favourite language is Rust"}},
+			{ID: second.ID, UpdateRequest: UpdateRequest{Priority: "urgent"}},
+			{ID: third.ID, UpdateRequest: UpdateRequest{Priority: models.PriorityHigh}},
+		}})
+		require.NoError(t, err)
+		assert.True(t, result.RolledBack)
+		assert.Equal(t, 0, result.Updated)
+		assert.Equal(t, 3, result.Failed)
+		assert.Contains(t, result.Results[0].Error, "rolled back")
+		assert.Contains(t, result.Results[1].Error, "priority")
+		assert.Contains(t, result.Results[2].Error, "not attempted")
+
+		unchanged, err := service.GetByID(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Favourite language is Go", unchanged.Content)
+
+		history, err := service.GetMemoryHistory(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history.Revisions, "the revision is rolled back with the update")
+	})
+
+	t.Run("all succeed", func(t *testing.T) {
+		result, err := service.BulkUpdate(ctx, BulkUpdateRequest{Atomic: true, Updates: []BulkUpdateItem{
+			{ID: first.ID, UpdateRequest: UpdateRequest{Content: "Favourite language is Rust"}},
+			{ID: second.ID, UpdateRequest: UpdateRequest{Priority: models.PriorityHigh}},
+		}})
+		require.NoError(t, err)
+		assert.False(t, result.RolledBack)
+		assert.Equal(t, 2, result.Updated)
+		assert.Equal(t, "Favourite language is Rust", result.Results[0].Memory.Content)
+
+		updated, err := service.GetByID(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PriorityHigh, updated.Priority)
+	})
+}
+
+func TestMemoryService_BulkUpdateLimits(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	_, err := service.BulkUpdate(ctx, BulkUpdateRequest{})
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = service.BulkUpdate(ctx, BulkUpdateRequest{Updates: make([]BulkUpdateItem, MaxBulkUpdate+1)})
+	assert.True(t, utils.IsValidationError(err))
+}
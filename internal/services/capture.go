@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Capture outcomes
+const (
+	CaptureCreated = "created"
+	CaptureUpdated = "updated"
+	CaptureSkipped = "skipped"
+)
+
+// Ways a capture can match an existing memory
+const (
+	CaptureMatchContent    = "content"
+	CaptureMatchUpdateKey  = "update_key"
+	CaptureMatchSimilarity = "similarity"
+)
+
+const (
+	// maxCaptureLength bounds captured text; editor selections beyond this are
+	// better stored as attachments than as memories
+	maxCaptureLength = 10000
+	// captureDuplicateSimilarity is the cosine similarity above which a
+	// capture is treated as something already remembered
+	captureDuplicateSimilarity = 0.95
+)
+
+// CaptureContext describes where in an editor text was captured
+type CaptureContext struct {
+	File     string `json:"file,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// CaptureRequest is raw text captured by an editor or IDE plugin. Type and
+// category are detected from the text unless given.
+type CaptureRequest struct {
+	Text     string
+	Context  CaptureContext
+	Type     string
+	Category string
+	Tags     []string
+}
+
+// CaptureResult reports what a capture did. When the capture matched an existing
+// memory, Existing is that memory as it was before the capture, so plugins can
+// show it as already remembered.
+type CaptureResult struct {
+	Action     string         `json:"action"`
+	Memory     *models.Memory `json:"memory,omitempty"`
+	Existing   *models.Memory `json:"existing,omitempty"`
+	MatchedBy  string         `json:"matched_by,omitempty"`
+	Similarity float64        `json:"similarity,omitempty"`
+	// Detected is true when memory detection recognised the text
+	Detected bool `json:"detected"`
+}
+
+// Capture stores text captured in an editor after running memory detection and
+// deduplication. Text the user already has, exactly or as a near-identical
+// memory, is skipped; text sharing a detected update key updates that memory.
+func (s *MemoryService) Capture(ctx context.Context, req CaptureRequest) (*CaptureResult, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, utils.RequiredFieldError("text")
+	}
+	if len(text) > maxCaptureLength {
+		return nil, utils.InvalidFieldError("text", "must be at most 10000 characters")
+	}
+	if req.Type != "" && !models.IsValidType(req.Type) {
+		return nil, utils.InvalidFieldError("type", "must be one of: fact, conversation, context, preference")
+	}
+	if req.Category != "" && !models.IsValidCategory(req.Category) {
+		return nil, utils.InvalidFieldError("category", "must be one of: personal, project, business")
+	}
+
+	storeReq, detected := captureStoreRequest(text, req)
+	return s.storeCaptured(ctx, text, storeReq, &CaptureResult{Detected: detected})
+}
+
+// storeCaptured stores text unless the user already has it, filling in result:
+// an exact or near-identical memory is skipped and a memory with the same update
+// key is updated
+func (s *MemoryService) storeCaptured(ctx context.Context, text string, storeReq StoreRequest, result *CaptureResult) (*CaptureResult, error) {
+	hash, err := s.contentHash(text)
+	if err != nil {
+		return nil, utils.WrapDatabaseError("hash content", err)
+	}
+	existing, err := s.findByContentHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		result.Action = CaptureSkipped
+		result.MatchedBy = CaptureMatchContent
+		result.Existing = existing
+		result.Memory = existing
+		return result, nil
+	}
+
+	if storeReq.UpdateKey != "" {
+		previous, err := s.findByUpdateKey(ctx, storeReq.UpdateKey)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapDatabaseError("check for existing memory", err)
+		}
+		if previous != nil {
+			if err := s.decryptContent(previous); err != nil {
+				s.logger.Warn().Err(err).Uint("id", previous.ID).Msg("failed to decrypt memory content")
+			}
+			memory, err := s.Store(ctx, storeReq)
+			if err != nil {
+				return nil, err
+			}
+			result.Action = CaptureUpdated
+			result.MatchedBy = CaptureMatchUpdateKey
+			result.Existing = previous
+			result.Memory = memory
+			return result, nil
+		}
+	}
+
+	if similar, similarity := s.findNearDuplicate(ctx, text); similar != nil {
+		result.Action = CaptureSkipped
+		result.MatchedBy = CaptureMatchSimilarity
+		result.Similarity = similarity
+		result.Existing = similar
+		result.Memory = similar
+		return result, nil
+	}
+
+	memory, err := s.Store(ctx, storeReq)
+	if err != nil {
+		return nil, err
+	}
+	result.Action = CaptureCreated
+	result.Memory = memory
+	return result, nil
+}
+
+// captureStoreRequest builds the memory to store for captured text, using the most
+// confident detection when there is one. Captures from a repository default to
+// project memories.
+func captureStoreRequest(text string, req CaptureRequest) (StoreRequest, bool) {
+	storeReq := StoreRequest{
+		Content:  text,
+		Type:     models.TypeContext,
+		Category: models.CategoryPersonal,
+		Priority: models.PriorityMedium,
+		Tags:     req.Tags,
+	}
+	if req.Context.Repo != "" {
+		storeReq.Category = models.CategoryProject
+	}
+
+	metadata := map[string]interface{}{"source": "capture"}
+	best := bestDetection(DetectMemoryPatterns(text), defaultMinConfidence)
+	if best != nil {
+		storeReq.Type = best.Type
+		storeReq.Category = best.Category
+		storeReq.Priority = best.Priority.String()
+		storeReq.UpdateKey = best.UpdateKey
+		metadata["auto_detected"] = true
+		metadata["confidence"] = best.Confidence
+		best.annotate(metadata)
+	}
+
+	if req.Type != "" {
+		storeReq.Type = req.Type
+	}
+	if req.Category != "" {
+		storeReq.Category = req.Category
+	}
+	if req.Context.File != "" {
+		metadata["file"] = req.Context.File
+	}
+	if req.Context.Repo != "" {
+		metadata["repo"] = req.Context.Repo
+	}
+	if req.Context.Language != "" {
+		metadata["language"] = req.Context.Language
+	}
+	storeReq.Metadata = metadata
+
+	return storeReq, best != nil
+}
+
+// findByContentHash finds the user's memory with the given content hash (see
+// contentHash), which matches encrypted memories too, or returns nil if there is
+// none
+func (s *MemoryService) findByContentHash(ctx context.Context, hash string) (*models.Memory, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ? AND content_hash = ?", s.userID, hash)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+
+	var memory models.Memory
+	if err := query.First(&memory).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, utils.WrapDatabaseError("check for duplicate memory", err)
+	}
+	if err := s.decryptContent(&memory); err != nil {
+		s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+	}
+	return &memory, nil
+}
+
+// findNearDuplicate returns the user's memory most similar to text when it is
+// similar enough to count as the same memory. The check is best effort: without
+// embeddings it finds nothing.
+func (s *MemoryService) findNearDuplicate(ctx context.Context, text string) (*models.Memory, float64) {
+	id, similarity, err := s.nearestMemory(ctx, EmbeddingFields{Content: text})
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to check captured text for near duplicates")
+		return nil, 0
+	}
+	if id == 0 || similarity < captureDuplicateSimilarity {
+		return nil, 0
+	}
+
+	memory, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, 0
+	}
+	return memory, similarity
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_Capture(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	editor := CaptureContext{File: "cmd/main.go", Repo: "remember-me-mcp", Language: "go"}
+
+	created, err := service.Capture(ctx, CaptureRequest{Text: "  retries use exponential backoff  ", Context: editor})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureCreated, created.Action)
+	assert.False(t, created.Detected)
+	assert.Nil(t, created.Existing)
+	assert.Equal(t, "retries use exponential backoff", created.Memory.Content)
+	assert.Equal(t, models.CategoryProject, created.Memory.Category)
+	assert.Equal(t, models.TypeContext, created.Memory.Type)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(created.Memory.Metadata, &metadata))
+	assert.Equal(t, "capture", metadata["source"])
+	assert.Equal(t, "go", metadata["language"])
+	assert.Equal(t, "cmd/main.go", metadata["file"])
+
+	// Capturing the same text again is reported as already remembered
+	skipped, err := service.Capture(ctx, CaptureRequest{Text: "retries use exponential backoff"})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureSkipped, skipped.Action)
+	assert.Equal(t, CaptureMatchContent, skipped.MatchedBy)
+	require.NotNil(t, skipped.Existing)
+	assert.Equal(t, created.Memory.ID, skipped.Existing.ID)
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryService_CaptureUpdatesByDetectedKey(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	first, err := service.Capture(ctx, CaptureRequest{Text: "My editor is vim"})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureCreated, first.Action)
+	assert.True(t, first.Detected)
+	assert.Equal(t, models.TypeFact, first.Memory.Type)
+	assert.Equal(t, models.CategoryPersonal, first.Memory.Category)
+
+	second, err := service.Capture(ctx, CaptureRequest{Text: "My editor is helix"})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureUpdated, second.Action)
+	assert.Equal(t, CaptureMatchUpdateKey, second.MatchedBy)
+	require.NotNil(t, second.Existing)
+	assert.Equal(t, "My editor is vim", second.Existing.Content)
+	assert.Equal(t, first.Memory.ID, second.Memory.ID)
+	assert.Equal(t, "My editor is helix", second.Memory.Content)
+}
+
+func TestMemoryService_CaptureValidation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for _, req := range []CaptureRequest{
+		{Text: "   "},
+		{Text: "note", Type: "bogus"},
+		{Text: "note", Category: "bogus"},
+	} {
+		_, err := service.Capture(ctx, req)
+		assert.True(t, utils.IsValidationError(err), "request %+v", req)
+	}
+}
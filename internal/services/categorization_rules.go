@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// ruleBatchSize is how many memories a rule run reads at a time
+	ruleBatchSize = 200
+	// maxListedRuleChanges caps the changes a rule run lists; the counts cover all
+	maxListedRuleChanges = 500
+)
+
+// CategorizationRuleSpec describes a categorization rule to create or replace
+type CategorizationRuleSpec struct {
+	Name     string   `json:"name"`
+	Match    string   `json:"match"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// Validate checks that a rule spec is usable
+func (spec *CategorizationRuleSpec) Validate() error {
+	if strings.TrimSpace(spec.Name) == "" {
+		return utils.RequiredFieldError("name")
+	}
+	if strings.TrimSpace(spec.Match) == "" {
+		return utils.RequiredFieldError("match")
+	}
+	if spec.Category != "" && !models.IsValidCategory(spec.Category) {
+		return utils.InvalidFieldError("category", "must be personal, project or business")
+	}
+	if spec.Category == "" && len(spec.Tags) == 0 {
+		return utils.InvalidFieldError("category", "a rule needs a category or tags to apply")
+	}
+	for _, tag := range spec.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return utils.InvalidFieldError("tags", "tags cannot be empty")
+		}
+	}
+	return nil
+}
+
+// ApplyRulesRequest selects the rules to run over existing memories
+type ApplyRulesRequest struct {
+	// RuleID runs a single rule, enabled or not; zero runs every enabled rule
+	RuleID uint `json:"rule_id,omitempty"`
+	// Preview reports what would change without changing anything
+	Preview bool `json:"preview,omitempty"`
+}
+
+// RuleChange is the change rules make to one memory
+type RuleChange struct {
+	MemoryID     uint     `json:"memory_id"`
+	Content      string   `json:"content"`
+	Rules        []string `json:"rules"`
+	FromCategory string   `json:"from_category"`
+	ToCategory   string   `json:"to_category"`
+	AddedTags    []string `json:"added_tags,omitempty"`
+}
+
+// ApplyRulesResult summarises a rule run over existing memories
+type ApplyRulesResult struct {
+	Preview bool `json:"preview"`
+	Scanned int  `json:"scanned"`
+	// Changed counts the memories the rules change (or would, in a preview)
+	Changed int `json:"changed"`
+	// Changes lists the first changes; Truncated is set when there were more
+	Changes   []RuleChange `json:"changes"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// ListCategorizationRules returns the user's categorization rules ordered by name
+func (s *MemoryService) ListCategorizationRules(ctx context.Context) ([]models.CategorizationRule, error) {
+	var rules []models.CategorizationRule
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("name ASC").
+		Find(&rules).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to list categorization rules")
+		return nil, utils.WrapDatabaseError("list categorization rules", err)
+	}
+
+	return rules, nil
+}
+
+// GetCategorizationRule loads a categorization rule owned by the user
+func (s *MemoryService) GetCategorizationRule(ctx context.Context, id uint) (*models.CategorizationRule, error) {
+	var rule models.CategorizationRule
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, s.userID).
+		First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.WrapNotFoundError("categorization rule", fmt.Sprintf("%d", id))
+		}
+		return nil, utils.WrapDatabaseError("find categorization rule", err)
+	}
+
+	return &rule, nil
+}
+
+// SaveCategorizationRule creates a rule, or replaces an existing one with the
+// same name. Saving a rule does not touch existing memories; see
+// ApplyCategorizationRules.
+func (s *MemoryService) SaveCategorizationRule(ctx context.Context, spec CategorizationRuleSpec) (*models.CategorizationRule, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var rule *models.CategorizationRule
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		rule, _, err = s.upsertCategorizationRule(tx, spec)
+		return err
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("name", spec.Name).Msg("failed to save categorization rule")
+		return nil, utils.WrapDatabaseError("save categorization rule", err)
+	}
+
+	return rule, nil
+}
+
+// upsertCategorizationRule writes a rule by name within a transaction and reports
+// whether a new row was created
+func (s *MemoryService) upsertCategorizationRule(tx *gorm.DB, spec CategorizationRuleSpec) (*models.CategorizationRule, bool, error) {
+	var rule models.CategorizationRule
+	err := tx.Where("user_id = ? AND name = ?", s.userID, spec.Name).First(&rule).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+	created := err == gorm.ErrRecordNotFound
+
+	rule.UserID = s.userID
+	rule.Name = spec.Name
+	rule.Match = strings.TrimSpace(spec.Match)
+	rule.Category = spec.Category
+	rule.Tags = spec.Tags
+	rule.Enabled = spec.Enabled == nil || *spec.Enabled
+	if err := tx.Save(&rule).Error; err != nil {
+		return nil, false, err
+	}
+
+	return &rule, created, nil
+}
+
+// DeleteCategorizationRule removes a categorization rule. Memories it already
+// changed keep their category and tags.
+func (s *MemoryService) DeleteCategorizationRule(ctx context.Context, id uint) error {
+	rule, err := s.GetCategorizationRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(rule).Error; err != nil {
+		s.logger.Error().Err(err).Uint("rule_id", id).Msg("failed to delete categorization rule")
+		return utils.WrapDatabaseError("delete categorization rule", err)
+	}
+
+	return nil
+}
+
+// enabledCategorizationRules returns the user's enabled rules in the order they
+// apply: oldest first
+func (s *MemoryService) enabledCategorizationRules(ctx context.Context) ([]models.CategorizationRule, error) {
+	var rules []models.CategorizationRule
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND enabled = ?", s.userID, true).
+		Order("id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// applyCategorizationRules files a memory being stored under the category and
+// tags of the rules its content matches. A rule that cannot be loaded leaves the
+// request as it is rather than failing the store.
+func (s *MemoryService) applyCategorizationRules(ctx context.Context, req *StoreRequest) {
+	rules, err := s.enabledCategorizationRules(ctx)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to load categorization rules, storing memory as given")
+		return
+	}
+
+	category, tags, matched := evaluateRules(rules, req.Content, req.Category, req.Tags)
+	if len(matched) == 0 {
+		return
+	}
+	s.logger.Debug().
+		Strs("rules", matched).
+		Str("from_category", req.Category).
+		Str("to_category", category).
+		Msg("categorization rules matched new memory")
+	req.Category = category
+	req.Tags = tags
+}
+
+// evaluateRules returns the category and tags a memory gets from the rules its
+// content matches, and the names of those rules. The last matching rule with a
+// category decides it; tags of every matching rule are added.
+func evaluateRules(rules []models.CategorizationRule, content, category string, tags []string) (string, []string, []string) {
+	var matched []string
+	lower := strings.ToLower(content)
+	for _, rule := range rules {
+		if !strings.Contains(lower, strings.ToLower(rule.Match)) {
+			continue
+		}
+		matched = append(matched, rule.Name)
+		if rule.Category != "" {
+			category = rule.Category
+		}
+		for _, tag := range rule.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(slices.Clip(tags), tag)
+			}
+		}
+	}
+	return category, tags, matched
+}
+
+// ApplyCategorizationRules runs rules over the user's existing memories, a batch
+// at a time, and updates the memories whose category or tags they change. Each
+// update keeps a revision, like any other edit. With Preview set nothing is
+// written and the result shows what would change.
+func (s *MemoryService) ApplyCategorizationRules(ctx context.Context, req ApplyRulesRequest) (*ApplyRulesResult, error) {
+	var rules []models.CategorizationRule
+	if req.RuleID != 0 {
+		rule, err := s.GetCategorizationRule(ctx, req.RuleID)
+		if err != nil {
+			return nil, err
+		}
+		rules = []models.CategorizationRule{*rule}
+	} else {
+		var err error
+		if rules, err = s.enabledCategorizationRules(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("failed to load categorization rules")
+			return nil, utils.WrapDatabaseError("load categorization rules", err)
+		}
+	}
+
+	result := &ApplyRulesResult{Preview: req.Preview, Changes: []RuleChange{}}
+	if len(rules) == 0 {
+		return result, nil
+	}
+
+	var lastID uint
+	for {
+		var memories []*models.Memory
+		if err := s.db.WithContext(ctx).
+			Omit("embedding").
+			Where("user_id = ? AND id > ?", s.userID, lastID).
+			Order("id ASC").
+			Limit(ruleBatchSize).
+			Find(&memories).Error; err != nil {
+			s.logger.Error().Err(err).Msg("failed to read memories for categorization rules")
+			return nil, utils.WrapDatabaseError("read memories", err)
+		}
+		if len(memories) == 0 {
+			break
+		}
+		lastID = memories[len(memories)-1].ID
+
+		for _, memory := range memories {
+			result.Scanned++
+			if err := s.decryptContent(memory); err != nil {
+				s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("skipping memory whose content cannot be decrypted")
+				continue
+			}
+
+			category, tags, matched := evaluateRules(rules, memory.Content, memory.Category, memory.Tags)
+			added := len(tags) - len(memory.Tags)
+			if len(matched) == 0 || (category == memory.Category && added == 0) {
+				continue
+			}
+
+			if !req.Preview {
+				if _, err := s.Update(ctx, memory.ID, UpdateRequest{Category: category, Tags: tags}); err != nil {
+					return nil, err
+				}
+			}
+
+			result.Changed++
+			if len(result.Changes) == maxListedRuleChanges {
+				result.Truncated = true
+				continue
+			}
+			result.Changes = append(result.Changes, RuleChange{
+				MemoryID:     memory.ID,
+				Content:      truncateString(memory.Content, 200),
+				Rules:        matched,
+				FromCategory: memory.Category,
+				ToCategory:   category,
+				AddedTags:    tags[len(memory.Tags):],
+			})
+		}
+	}
+
+	s.logger.Info().
+		Bool("preview", req.Preview).
+		Int("rules", len(rules)).
+		Int("scanned", result.Scanned).
+		Int("changed", result.Changed).
+		Msg("applied categorization rules")
+
+	if !req.Preview && result.Changed > 0 {
+		s.logActivity(ctx, models.ActivityMemoriesRecategorized, map[string]interface{}{
+			"rules":   len(rules),
+			"changed": result.Changed,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestCategorizationRules(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("validation", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		for _, spec := range []CategorizationRuleSpec{
+			{Match: "acme", Category: models.CategoryBusiness},
+			{Name: "acme", Category: models.CategoryBusiness},
+			{Name: "acme", Match: "acme"},
+			{Name: "acme", Match: "acme", Category: "work"},
+			{Name: "acme", Match: "acme", Tags: []string{" "}},
+		} {
+			_, err := service.SaveCategorizationRule(ctx, spec)
+			assert.True(t, utils.IsValidationError(err), "%+v", spec)
+		}
+	})
+
+	t.Run("applied on store", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		_, err := service.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+			Name: "acme", Match: "Acme Corp", Category: models.CategoryBusiness, Tags: []string{"acme"},
+		})
+		require.NoError(t, err)
+
+		memory, err := service.Store(ctx, StoreRequest{
+			Content: "Lunch with the ACME CORP team on Friday", Category: models.CategoryPersonal,
+			Type: models.TypeFact, Tags: []string{"lunch"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.CategoryBusiness, memory.Category)
+		assert.Equal(t, []string{"lunch", "acme"}, []string(memory.Tags))
+
+		memory, err = service.Store(ctx, StoreRequest{
+			Content: "Prefers oat milk", Category: models.CategoryPersonal, Type: models.TypePreference,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.CategoryPersonal, memory.Category)
+		assert.Empty(t, memory.Tags)
+	})
+
+	t.Run("disabled rules are not applied on store", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		disabled := false
+		_, err := service.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+			Name: "acme", Match: "acme", Category: models.CategoryBusiness, Enabled: &disabled,
+		})
+		require.NoError(t, err)
+
+		memory, err := service.Store(ctx, StoreRequest{Content: "Acme invoice", Category: models.CategoryPersonal, Type: models.TypeFact})
+		require.NoError(t, err)
+		assert.Equal(t, models.CategoryPersonal, memory.Category)
+	})
+
+	t.Run("preview and apply to existing memories", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		acme, err := service.Store(ctx, StoreRequest{Content: "Acme Corp renewal is in March", Category: models.CategoryPersonal, Type: models.TypeFact})
+		require.NoError(t, err)
+		tagged, err := service.Store(ctx, StoreRequest{
+			Content: "Acme Corp contact is Dana", Category: models.CategoryBusiness, Type: models.TypeFact, Tags: []string{"acme"},
+		})
+		require.NoError(t, err)
+		_, err = service.Store(ctx, StoreRequest{Content: "Likes hiking", Category: models.CategoryPersonal, Type: models.TypePreference})
+		require.NoError(t, err)
+
+		rule, err := service.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+			Name: "acme", Match: "acme corp", Category: models.CategoryBusiness, Tags: []string{"acme"},
+		})
+		require.NoError(t, err)
+
+		preview, err := service.ApplyCategorizationRules(ctx, ApplyRulesRequest{Preview: true})
+		require.NoError(t, err)
+		assert.True(t, preview.Preview)
+		assert.Equal(t, 3, preview.Scanned)
+		assert.Equal(t, 1, preview.Changed)
+		require.Len(t, preview.Changes, 1)
+		assert.Equal(t, RuleChange{
+			MemoryID: acme.ID, Content: acme.Content, Rules: []string{"acme"},
+			FromCategory: models.CategoryPersonal, ToCategory: models.CategoryBusiness, AddedTags: []string{"acme"},
+		}, preview.Changes[0])
+
+		unchanged, err := service.GetByID(ctx, acme.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.CategoryPersonal, unchanged.Category)
+
+		applied, err := service.ApplyCategorizationRules(ctx, ApplyRulesRequest{RuleID: rule.ID})
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied.Changed)
+
+		var changed models.Memory
+		require.NoError(t, service.db.Omit("embedding").First(&changed, acme.ID).Error)
+		assert.Equal(t, models.CategoryBusiness, changed.Category)
+		assert.Equal(t, []string{"acme"}, []string(changed.Tags))
+		history, err := service.GetMemoryHistory(ctx, acme.ID)
+		require.NoError(t, err)
+		assert.Len(t, history.Revisions, 1)
+
+		again, err := service.ApplyCategorizationRules(ctx, ApplyRulesRequest{})
+		require.NoError(t, err)
+		assert.Zero(t, again.Changed)
+
+		var untouched models.Memory
+		require.NoError(t, service.db.Omit("embedding").First(&untouched, tagged.ID).Error)
+		assert.Equal(t, []string{"acme"}, []string(untouched.Tags))
+	})
+
+	t.Run("rules are per user", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		rule, err := service.SaveCategorizationRule(ctx, CategorizationRuleSpec{Name: "acme", Match: "acme", Tags: []string{"acme"}})
+		require.NoError(t, err)
+
+		other := NewMemoryServiceWithUser(service.db, nil, service.logger, service.config, 2)
+		_, err = other.ApplyCategorizationRules(ctx, ApplyRulesRequest{RuleID: rule.ID})
+		assert.True(t, utils.IsNotFoundError(err))
+		assert.True(t, utils.IsNotFoundError(other.DeleteCategorizationRule(ctx, rule.ID)))
+
+		require.NoError(t, service.DeleteCategorizationRule(ctx, rule.ID))
+		rules, err := service.ListCategorizationRules(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Content limits applied when they are not configured
+const (
+	// DefaultMaxContentLength is the most characters a memory may hold
+	DefaultMaxContentLength = 20000
+	// DefaultChunkSize is the most characters embedded together; longer content
+	// is embedded in chunks of about this size
+	DefaultChunkSize = 2000
+)
+
+// maxContentLength returns the most characters a memory may hold, 0 meaning no
+// limit
+func (s *MemoryService) maxContentLength() int {
+	if length, ok := s.config["max_content_length"].(int); ok && length >= 0 {
+		return length
+	}
+	return DefaultMaxContentLength
+}
+
+// chunkSize returns the most characters embedded together, 0 meaning content is
+// never chunked
+func (s *MemoryService) chunkSize() int {
+	if size, ok := s.config["chunk_size"].(int); ok && size >= 0 {
+		return size
+	}
+	return DefaultChunkSize
+}
+
+// checkContentLength rejects content longer than the configured limit
+func (s *MemoryService) checkContentLength(content string) error {
+	limit := s.maxContentLength()
+	if limit <= 0 {
+		return nil
+	}
+	if length := utf8.RuneCountInString(content); length > limit {
+		return utils.InvalidFieldError("content", fmt.Sprintf("is %d characters, more than the limit of %d", length, limit))
+	}
+	return nil
+}
+
+// contentChunk is a part of a memory's content embedded on its own
+type contentChunk struct {
+	Start  int
+	Length int
+	Text   string
+}
+
+// chunkContent splits content longer than the chunk size into chunks, ending each
+// at a paragraph, sentence or word break where one falls in its second half. It
+// returns nil when the content fits in one chunk.
+func (s *MemoryService) chunkContent(content string) []contentChunk {
+	size := s.chunkSize()
+	if size <= 0 || utf8.RuneCountInString(content) <= size {
+		return nil
+	}
+
+	runes := []rune(content)
+	var chunks []contentChunk
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			end = chunkBreak(runes, start, end)
+		}
+		chunks = append(chunks, contentChunk{
+			Start:  start,
+			Length: end - start,
+			Text:   strings.TrimSpace(string(runes[start:end])),
+		})
+		start = end
+	}
+	return chunks
+}
+
+// chunkBreak returns where a chunk running from start to at most end should
+// stop: after the last paragraph break in its second half, or else the last
+// sentence or line end, or else the last space. Without any it stops at end.
+func chunkBreak(runes []rune, start, end int) int {
+	floor := start + (end-start)/2
+	breaks := []func(i int) bool{
+		func(i int) bool { return i >= 2 && runes[i-1] == '\n' && runes[i-2] == '\n' },
+		func(i int) bool {
+			return runes[i-1] == '\n' || (i >= 2 && unicode.IsSpace(runes[i-1]) && strings.ContainsRune(".!?", runes[i-2]))
+		},
+		func(i int) bool { return unicode.IsSpace(runes[i-1]) },
+	}
+	for _, isBreak := range breaks {
+		for i := end; i > floor; i-- {
+			if isBreak(i) {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// leadChunk returns the part of content the memory's own embedding covers: the
+// first chunk of long content, or all of it
+func (s *MemoryService) leadChunk(content string) string {
+	if chunks := s.chunkContent(content); len(chunks) > 0 {
+		return chunks[0].Text
+	}
+	return content
+}
+
+// embedChunks embeds the chunks of long content after the first, which the
+// memory's own embedding covers. Content that fits in one chunk has none.
+func (s *MemoryService) embedChunks(ctx context.Context, embedder EmbeddingService, memoryID, userID uint, content string) ([]models.MemoryChunk, error) {
+	chunks := s.chunkContent(content)
+	if len(chunks) < 2 {
+		return nil, nil
+	}
+	chunks = chunks[1:]
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	vectors, err := s.cachedEmbedder(embedder).GenerateEmbeddings(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed chunks: %w", err)
+	}
+	if len(vectors) != len(chunks) {
+		return nil, fmt.Errorf("expected %d chunk embeddings, got %d", len(chunks), len(vectors))
+	}
+
+	rows := make([]models.MemoryChunk, len(chunks))
+	for i, chunk := range chunks {
+		rows[i] = models.MemoryChunk{
+			UserID:    userID,
+			MemoryID:  memoryID,
+			Position:  i + 1,
+			Start:     chunk.Start,
+			Length:    chunk.Length,
+			Embedding: pgvector.NewVector(vectors[i]),
+		}
+	}
+	return rows, nil
+}
+
+// saveEmbedding stores a memory's embedding and replaces its chunks in one
+// transaction, so an embedded memory always has the chunks of its content
+func (s *MemoryService) saveEmbedding(ctx context.Context, memoryID, userID uint, embedding []float32, chunks []models.MemoryChunk) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := memoryRow(tx.Model(&models.Memory{}), memoryID, userID).
+			UpdateColumn("embedding", pgvector.NewVector(embedding)).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("memory_id = ?", memoryID).Delete(&models.MemoryChunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.Create(&chunks).Error
+	})
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// longContent returns paragraphs of numbered sentences, about 60 characters each
+func longContent(paragraphs, sentences int) string {
+	var b strings.Builder
+	for p := 0; p < paragraphs; p++ {
+		if p > 0 {
+			b.WriteString("\n\n")
+		}
+		for i := 0; i < sentences; i++ {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString("Paragraph " + string(rune('A'+p)) + " says something worth keeping here.")
+		}
+	}
+	return b.String()
+}
+
+func TestMemoryService_ChunkContent(t *testing.T) {
+	service := NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), map[string]interface{}{"chunk_size": 300})
+
+	assert.Nil(t, service.chunkContent("short enough"))
+
+	content := longContent(4, 4)
+	chunks := service.chunkContent(content)
+	require.Greater(t, len(chunks), 1)
+
+	runes := []rune(content)
+	next := 0
+	for _, chunk := range chunks {
+		assert.Equal(t, next, chunk.Start, "chunks cover the content without gaps")
+		assert.LessOrEqual(t, chunk.Length, 300)
+		assert.Equal(t, strings.TrimSpace(string(runes[chunk.Start:chunk.Start+chunk.Length])), chunk.Text)
+		next = chunk.Start + chunk.Length
+	}
+	assert.Equal(t, len(runes), next)
+	assert.True(t, strings.HasSuffix(chunks[0].Text, "here."), "chunks end at a sentence")
+	assert.Equal(t, chunks[0].Text, service.leadChunk(content))
+
+	// Without any break the chunk is cut at the size
+	unbroken := strings.Repeat("x", 700)
+	chunks = service.chunkContent(unbroken)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, 300, chunks[0].Length)
+	assert.Equal(t, 100, chunks[2].Length)
+
+	disabled := NewMemoryService(testutil.SQLiteDB(t), nil, zerolog.Nop(), map[string]interface{}{"chunk_size": 0})
+	assert.Nil(t, disabled.chunkContent(content))
+	assert.Equal(t, content, disabled.leadChunk(content))
+}
+
+func TestMemoryService_MaxContentLength(t *testing.T) {
+	ctx := context.Background()
+	service := NewMemoryService(testutil.SQLiteDB(t, &models.MemoryRevision{}), nil, zerolog.Nop(), map[string]interface{}{"max_content_length": 10})
+
+	_, err := service.Store(ctx, StoreRequest{Content: "héllo wörld!", Category: models.CategoryPersonal, Type: models.TypeFact})
+	var validationErr *utils.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "content", validationErr.Field)
+	assert.Contains(t, validationErr.Message, "12 characters, more than the limit of 10")
+
+	memory, err := service.Store(ctx, StoreRequest{Content: "héllo wörl", Category: models.CategoryPersonal, Type: models.TypeFact})
+	require.NoError(t, err, "the limit counts characters, not bytes")
+
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Content: "much too long now"})
+	require.ErrorAs(t, err, &validationErr)
+
+	unlimited := NewMemoryService(service.db, nil, zerolog.Nop(), map[string]interface{}{"max_content_length": 0})
+	_, err = unlimited.Store(ctx, StoreRequest{Content: strings.Repeat("a", DefaultMaxContentLength+1), Category: models.CategoryPersonal, Type: models.TypeFact})
+	assert.NoError(t, err)
+}
+
+func TestMemoryService_EmbedsChunks(t *testing.T) {
+	ctx := context.Background()
+	provider := &textRecordingEmbeddingService{}
+	db := testutil.SQLiteDB(t, &models.MemoryRevision{})
+	service := NewMemoryService(db, provider, zerolog.Nop(), map[string]interface{}{"chunk_size": 300})
+
+	content := longContent(4, 4)
+	chunks := service.chunkContent(content)
+	require.Greater(t, len(chunks), 2)
+
+	embedded := func() bool { return service.GetAsyncEmbeddings().InFlight() == 0 }
+
+	memory, err := service.Store(ctx, StoreRequest{Content: content, Category: models.CategoryProject, Type: models.TypeContext})
+	require.NoError(t, err)
+	require.Eventually(t, embedded, time.Second, 10*time.Millisecond)
+
+	var texts []string
+	for _, chunk := range chunks {
+		texts = append(texts, chunk.Text)
+	}
+	assert.ElementsMatch(t, texts, provider.texts, "each chunk is embedded once, the first as the memory's own")
+
+	var stored []models.MemoryChunk
+	require.NoError(t, db.Order("position").Find(&stored, "memory_id = ?", memory.ID).Error)
+	require.Len(t, stored, len(chunks)-1)
+	for i, chunk := range stored {
+		assert.Equal(t, i+1, chunk.Position)
+		assert.Equal(t, chunks[i+1].Start, chunk.Start)
+		assert.Equal(t, chunks[i+1].Length, chunk.Length)
+		assert.Equal(t, memory.UserID, chunk.UserID)
+	}
+
+	// The memory keeps its whole content
+	loaded, err := service.GetByID(ctx, memory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, content, loaded.Content)
+	assert.Equal(t, utf8.RuneCountInString(content), stored[len(stored)-1].Start+stored[len(stored)-1].Length)
+
+	// Shortened content leaves no chunks behind
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Content: "Now a short note."})
+	require.NoError(t, err)
+	require.Eventually(t, embedded, time.Second, 10*time.Millisecond)
+	var count int64
+	require.NoError(t, db.Model(&models.MemoryChunk{}).Where("memory_id = ?", memory.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestMemoryService_SemanticSearch_MatchesChunks(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.PostgresDB(t)
+	service := NewMemoryService(db, &MockEmbeddingService{}, zerolog.Nop(), map[string]interface{}{"chunk_size": 300})
+
+	content := longContent(4, 4)
+	chunks := service.chunkContent(content)
+	require.Greater(t, len(chunks), 2)
+
+	long, err := service.Store(ctx, StoreRequest{Content: content, Category: models.CategoryProject, Type: models.TypeContext})
+	require.NoError(t, err)
+	_, err = service.Store(ctx, StoreRequest{Content: "An unrelated short memory", Category: models.CategoryProject, Type: models.TypeFact})
+	require.NoError(t, err)
+	require.NoError(t, service.Drain(ctx))
+
+	// The mock embeds identical text identically, so the last chunk's text matches
+	// only that chunk
+	memories, err := service.SearchSemantic(ctx, SearchRequest{Query: chunks[len(chunks)-1].Text, Limit: 5})
+	require.NoError(t, err)
+	require.NotEmpty(t, memories)
+	assert.Equal(t, long.ID, memories[0].ID)
+	assert.Equal(t, content, memories[0].Content, "the whole memory is returned")
+	require.NotNil(t, memories[0].Similarity)
+	assert.InDelta(t, 1, *memories[0].Similarity, 0.001)
+	for _, memory := range memories[1:] {
+		assert.NotEqual(t, long.ID, memory.ID, "a memory is returned once")
+	}
+}
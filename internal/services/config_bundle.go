@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// ConfigBundleVersion is the current format version of exported configuration
+// bundles. Version 1 bundles hold only saved searches and are still accepted.
+const ConfigBundleVersion = 2
+
+// ConfigBundle is a portable export of a user's configuration artifacts.
+// Bundles carry no database IDs or user IDs so they can be imported into
+// another account or another deployment. Region records the residency region
+// of the exporting deployment.
+type ConfigBundle struct {
+	Version             int                      `json:"version"`
+	ExportedAt          time.Time                `json:"exported_at"`
+	Region              string                   `json:"region,omitempty"`
+	SavedSearches       []SavedSearchSpec        `json:"saved_searches"`
+	CategorizationRules []CategorizationRuleSpec `json:"categorization_rules"`
+	Workspaces          []WorkspaceSpec          `json:"workspaces"`
+	Settings            *ConfigSettings          `json:"settings,omitempty"`
+}
+
+// WorkspaceSpec describes a workspace without its memories
+type WorkspaceSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigSettings are the account settings a user chooses for themselves. Limits
+// and plans an admin sets are not part of a bundle.
+type ConfigSettings struct {
+	EvictionPolicy     string `json:"eviction_policy,omitempty"`
+	MaintenanceEnabled bool   `json:"maintenance_enabled"`
+}
+
+// SavedSearchSpec describes a saved search independently of where it is stored
+type SavedSearchSpec struct {
+	Name              string `json:"name"`
+	Query             string `json:"query"`
+	Category          string `json:"category,omitempty"`
+	Type              string `json:"type,omitempty"`
+	Limit             int    `json:"limit,omitempty"`
+	UseSemanticSearch bool   `json:"use_semantic_search"`
+}
+
+// ImportConfigResult summarises what an import changed
+type ImportConfigResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// Validate checks that a saved search spec is usable
+func (spec *SavedSearchSpec) Validate() error {
+	if strings.TrimSpace(spec.Name) == "" {
+		return utils.RequiredFieldError("name")
+	}
+	if strings.TrimSpace(spec.Query) == "" {
+		return utils.RequiredFieldError("query")
+	}
+	if spec.Limit < 0 || spec.Limit > 1000 {
+		return utils.InvalidFieldError("limit", "must be between 0 and 1000")
+	}
+	return nil
+}
+
+// Validate checks that a workspace spec is usable
+func (spec *WorkspaceSpec) Validate() error {
+	if err := validateWorkspaceName(spec.Name); err != nil {
+		return err
+	}
+	if strings.EqualFold(spec.Name, models.DefaultWorkspace) {
+		return utils.InvalidFieldError("name", fmt.Sprintf("%q is the workspace every user has", models.DefaultWorkspace))
+	}
+	return nil
+}
+
+// Validate checks that the settings are usable
+func (settings *ConfigSettings) Validate() error {
+	if settings.EvictionPolicy != "" && !IsValidEvictionPolicy(settings.EvictionPolicy) {
+		return utils.InvalidFieldError("eviction_policy", fmt.Sprintf("unknown eviction policy %q", settings.EvictionPolicy))
+	}
+	return nil
+}
+
+// SearchRequest converts the spec into a search request
+func (spec *SavedSearchSpec) SearchRequest() *SearchMemoriesRequest {
+	limit := spec.Limit
+	if limit == 0 {
+		limit = 100
+	}
+	return &SearchMemoriesRequest{
+		Query:             spec.Query,
+		Category:          spec.Category,
+		Type:              spec.Type,
+		Limit:             limit,
+		UseSemanticSearch: spec.UseSemanticSearch,
+	}
+}
+
+// NewSavedSearchSpec builds a portable spec from a stored saved search
+func NewSavedSearchSpec(search *models.SavedSearch) SavedSearchSpec {
+	return SavedSearchSpec{
+		Name:              search.Name,
+		Query:             search.Query,
+		Category:          search.Category,
+		Type:              search.Type,
+		Limit:             search.Limit,
+		UseSemanticSearch: search.UseSemanticSearch,
+	}
+}
+
+// ListSavedSearches returns the user's saved searches ordered by name
+func (s *MemoryService) ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("name ASC").
+		Find(&searches).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to list saved searches")
+		return nil, utils.WrapDatabaseError("list saved searches", err)
+	}
+
+	return searches, nil
+}
+
+// GetSavedSearch loads a saved search owned by the user
+func (s *MemoryService) GetSavedSearch(ctx context.Context, id uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, s.userID).
+		First(&search).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.WrapNotFoundError("saved search", fmt.Sprintf("%d", id))
+		}
+		return nil, utils.WrapDatabaseError("find saved search", err)
+	}
+
+	return &search, nil
+}
+
+// SaveSearch creates a saved search, or replaces the parameters of an existing
+// one with the same name
+func (s *MemoryService) SaveSearch(ctx context.Context, spec SavedSearchSpec) (*models.SavedSearch, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var search *models.SavedSearch
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		search, _, err = s.upsertSavedSearch(tx, spec)
+		return err
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("name", spec.Name).Msg("failed to save search")
+		return nil, utils.WrapDatabaseError("save search", err)
+	}
+
+	return search, nil
+}
+
+// DeleteSavedSearch removes a saved search
+func (s *MemoryService) DeleteSavedSearch(ctx context.Context, id uint) error {
+	search, err := s.GetSavedSearch(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(search).Error; err != nil {
+		s.logger.Error().Err(err).Uint("saved_search_id", id).Msg("failed to delete saved search")
+		return utils.WrapDatabaseError("delete saved search", err)
+	}
+
+	return nil
+}
+
+// NewCategorizationRuleSpec builds a portable spec from a stored categorization rule
+func NewCategorizationRuleSpec(rule *models.CategorizationRule) CategorizationRuleSpec {
+	enabled := rule.Enabled
+	return CategorizationRuleSpec{
+		Name:     rule.Name,
+		Match:    rule.Match,
+		Category: rule.Category,
+		Tags:     rule.Tags,
+		Enabled:  &enabled,
+	}
+}
+
+// ExportConfigBundle collects the user's configuration artifacts into a portable bundle
+func (s *MemoryService) ExportConfigBundle(ctx context.Context) (*ConfigBundle, error) {
+	searches, err := s.ListSavedSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.ListCategorizationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []models.Workspace
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("name ASC").
+		Find(&workspaces).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list workspaces", err)
+	}
+	settings, err := s.configSettings(s.db.WithContext(ctx))
+	if err != nil {
+		return nil, utils.WrapDatabaseError("load settings", err)
+	}
+
+	bundle := &ConfigBundle{
+		Version:             ConfigBundleVersion,
+		ExportedAt:          time.Now().UTC(),
+		Region:              s.ResidencyRegion(),
+		SavedSearches:       make([]SavedSearchSpec, 0, len(searches)),
+		CategorizationRules: make([]CategorizationRuleSpec, 0, len(rules)),
+		Workspaces:          make([]WorkspaceSpec, 0, len(workspaces)),
+		Settings:            settings,
+	}
+	for i := range searches {
+		bundle.SavedSearches = append(bundle.SavedSearches, NewSavedSearchSpec(&searches[i]))
+	}
+	for i := range rules {
+		bundle.CategorizationRules = append(bundle.CategorizationRules, NewCategorizationRuleSpec(&rules[i]))
+	}
+	for _, workspace := range workspaces {
+		bundle.Workspaces = append(bundle.Workspaces, WorkspaceSpec{Name: workspace.Name, Description: workspace.Description})
+	}
+
+	return bundle, nil
+}
+
+// configSettings loads the user's settings, or nil when the user has no account
+// row, as the system user of local MCP mode may not
+func (s *MemoryService) configSettings(db *gorm.DB) (*ConfigSettings, error) {
+	var user models.User
+	err := db.Select("id", "eviction_policy", "maintenance_enabled").First(&user, s.userID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigSettings{EvictionPolicy: user.EvictionPolicy, MaintenanceEnabled: user.MaintenanceEnabled}, nil
+}
+
+// ImportConfigBundle applies a bundle to the user's configuration. Artifacts are
+// matched by name; existing ones are only replaced when overwrite is set, otherwise
+// they are skipped. Settings count as one artifact that exists once the user has
+// changed any of them. The import is all-or-nothing. Bundles exported from a
+// different residency region are refused unless allowCrossRegion is set.
+func (s *MemoryService) ImportConfigBundle(ctx context.Context, bundle *ConfigBundle, overwrite, allowCrossRegion bool) (*ImportConfigResult, error) {
+	if bundle == nil {
+		return nil, utils.RequiredFieldError("bundle")
+	}
+	if bundle.Version < 1 || bundle.Version > ConfigBundleVersion {
+		return nil, utils.InvalidFieldError("version", fmt.Sprintf("unsupported bundle version %d", bundle.Version))
+	}
+	if err := CheckResidency("config bundle", bundle.Region, s.ResidencyRegion(), allowCrossRegion); err != nil {
+		return nil, err
+	}
+
+	if err := bundle.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &ImportConfigResult{}
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		for _, spec := range bundle.SavedSearches {
+			if err := s.importNamed(tx, &models.SavedSearch{}, spec.Name, overwrite, result, func() (bool, error) {
+				_, created, err := s.upsertSavedSearch(tx, spec)
+				return created, err
+			}); err != nil {
+				return err
+			}
+		}
+		for _, spec := range bundle.CategorizationRules {
+			if err := s.importNamed(tx, &models.CategorizationRule{}, spec.Name, overwrite, result, func() (bool, error) {
+				_, created, err := s.upsertCategorizationRule(tx, spec)
+				return created, err
+			}); err != nil {
+				return err
+			}
+		}
+		for _, spec := range bundle.Workspaces {
+			if err := s.importNamed(tx, &models.Workspace{}, spec.Name, overwrite, result, func() (bool, error) {
+				return s.upsertWorkspace(tx, spec)
+			}); err != nil {
+				return err
+			}
+		}
+		if bundle.Settings != nil {
+			return s.importSettings(tx, bundle.Settings, overwrite, result)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to import config bundle")
+		return nil, utils.WrapDatabaseError("import config bundle", err)
+	}
+
+	s.logger.Info().
+		Int("created", result.Created).
+		Int("updated", result.Updated).
+		Int("skipped", result.Skipped).
+		Msg("imported config bundle")
+
+	return result, nil
+}
+
+// upsertSavedSearch writes a saved search by name within a transaction and reports
+// whether a new row was created
+func (s *MemoryService) upsertSavedSearch(tx *gorm.DB, spec SavedSearchSpec) (*models.SavedSearch, bool, error) {
+	var search models.SavedSearch
+	err := tx.Where("user_id = ? AND name = ?", s.userID, spec.Name).First(&search).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+	created := err == gorm.ErrRecordNotFound
+
+	search.UserID = s.userID
+	search.Name = spec.Name
+	search.Query = spec.Query
+	search.Category = spec.Category
+	search.Type = spec.Type
+	search.Limit = spec.Limit
+	search.UseSemanticSearch = spec.UseSemanticSearch
+
+	if err := tx.Save(&search).Error; err != nil {
+		return nil, false, err
+	}
+
+	return &search, created, nil
+}
+
+// validate checks every artifact in the bundle and that names are unique within
+// each kind
+func (bundle *ConfigBundle) validate() error {
+	searches := make(map[string]bool, len(bundle.SavedSearches))
+	for i := range bundle.SavedSearches {
+		spec := &bundle.SavedSearches[i]
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("saved search %d: %w", i, err)
+		}
+		if searches[spec.Name] {
+			return utils.InvalidFieldError("saved_searches", fmt.Sprintf("duplicate name %q", spec.Name))
+		}
+		searches[spec.Name] = true
+	}
+
+	rules := make(map[string]bool, len(bundle.CategorizationRules))
+	for i := range bundle.CategorizationRules {
+		spec := &bundle.CategorizationRules[i]
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("categorization rule %d: %w", i, err)
+		}
+		if rules[spec.Name] {
+			return utils.InvalidFieldError("categorization_rules", fmt.Sprintf("duplicate name %q", spec.Name))
+		}
+		rules[spec.Name] = true
+	}
+
+	workspaces := make(map[string]bool, len(bundle.Workspaces))
+	for i := range bundle.Workspaces {
+		spec := &bundle.Workspaces[i]
+		spec.Name = strings.TrimSpace(spec.Name)
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("workspace %d: %w", i, err)
+		}
+		if workspaces[spec.Name] {
+			return utils.InvalidFieldError("workspaces", fmt.Sprintf("duplicate name %q", spec.Name))
+		}
+		workspaces[spec.Name] = true
+	}
+
+	if bundle.Settings != nil {
+		if err := bundle.Settings.Validate(); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// importNamed imports one named artifact within a transaction: it is skipped when
+// the user already has one of that name and overwrite is not set, and otherwise
+// written by upsert, which reports whether it created a new row
+func (s *MemoryService) importNamed(tx *gorm.DB, model interface{}, name string, overwrite bool, result *ImportConfigResult, upsert func() (bool, error)) error {
+	if !overwrite {
+		var count int64
+		if err := tx.Model(model).
+			Where("user_id = ? AND name = ?", s.userID, name).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			result.Skipped++
+			return nil
+		}
+	}
+
+	created, err := upsert()
+	if err != nil {
+		return err
+	}
+	if created {
+		result.Created++
+	} else {
+		result.Updated++
+	}
+	return nil
+}
+
+// upsertWorkspace writes a workspace by name within a transaction and reports
+// whether a new row was created
+func (s *MemoryService) upsertWorkspace(tx *gorm.DB, spec WorkspaceSpec) (bool, error) {
+	var workspace models.Workspace
+	err := tx.Where("user_id = ? AND name = ?", s.userID, spec.Name).First(&workspace).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	created := err == gorm.ErrRecordNotFound
+
+	workspace.UserID = s.userID
+	workspace.Name = spec.Name
+	workspace.Description = strings.TrimSpace(spec.Description)
+	if err := tx.Save(&workspace).Error; err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// importSettings applies the bundle's settings within a transaction. They replace
+// settings the user has changed only when overwrite is set; users without an
+// account row have no settings to change, so they are skipped.
+func (s *MemoryService) importSettings(tx *gorm.DB, settings *ConfigSettings, overwrite bool, result *ImportConfigResult) error {
+	current, err := s.configSettings(tx)
+	if err != nil {
+		return err
+	}
+	if current == nil || (!overwrite && *current != (ConfigSettings{})) {
+		result.Skipped++
+		return nil
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", s.userID).Updates(map[string]interface{}{
+		"eviction_policy":     CanonicalEvictionPolicy(settings.EvictionPolicy),
+		"maintenance_enabled": settings.MaintenanceEnabled,
+	}).Error; err != nil {
+		return err
+	}
+	result.Updated++
+	return nil
+}
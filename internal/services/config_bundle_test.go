@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// setupConfigService creates a memory service with the configuration tables migrated
+func setupConfigService(t *testing.T) *MemoryService {
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.SavedSearch{}, &models.Workspace{}, &models.User{}, &models.MaintenanceReport{}))
+	return service
+}
+
+func TestMemoryService_SaveSearch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates and replaces by name", func(t *testing.T) {
+		service := setupConfigService(t)
+
+		first, err := service.SaveSearch(ctx, SavedSearchSpec{Name: "work", Query: "deadline", Category: models.CategoryProject})
+		require.NoError(t, err)
+		assert.NotZero(t, first.ID)
+
+		second, err := service.SaveSearch(ctx, SavedSearchSpec{Name: "work", Query: "meeting", Limit: 20})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, "meeting", second.Query)
+		assert.Equal(t, 20, second.Limit)
+
+		searches, err := service.ListSavedSearches(ctx)
+		require.NoError(t, err)
+		assert.Len(t, searches, 1)
+	})
+
+	t.Run("Rejects invalid specs", func(t *testing.T) {
+		service := setupConfigService(t)
+
+		_, err := service.SaveSearch(ctx, SavedSearchSpec{Query: "missing name"})
+		assert.True(t, utils.IsValidationError(err))
+
+		_, err = service.SaveSearch(ctx, SavedSearchSpec{Name: "big", Query: "x", Limit: 5000})
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
+
+func TestMemoryService_ConfigBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	source := setupConfigService(t)
+	_, err := source.SaveSearch(ctx, SavedSearchSpec{Name: "prefs", Query: "*", Type: models.TypePreference})
+	require.NoError(t, err)
+	_, err = source.SaveSearch(ctx, SavedSearchSpec{Name: "semantic", Query: "travel plans", UseSemanticSearch: true})
+	require.NoError(t, err)
+
+	bundle, err := source.ExportConfigBundle(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ConfigBundleVersion, bundle.Version)
+	require.Len(t, bundle.SavedSearches, 2)
+
+	t.Run("Imports into empty account", func(t *testing.T) {
+		target := setupConfigService(t)
+
+		result, err := target.ImportConfigBundle(ctx, bundle, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+
+		exported, err := target.ExportConfigBundle(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, bundle.SavedSearches, exported.SavedSearches)
+	})
+
+	t.Run("Skips or overwrites existing names", func(t *testing.T) {
+		target := setupConfigService(t)
+		_, err := target.SaveSearch(ctx, SavedSearchSpec{Name: "prefs", Query: "local"})
+		require.NoError(t, err)
+
+		result, err := target.ImportConfigBundle(ctx, bundle, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Created: 1, Skipped: 1}, result)
+
+		result, err = target.ImportConfigBundle(ctx, bundle, true, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Updated: 2}, result)
+
+		searches, err := target.ListSavedSearches(ctx)
+		require.NoError(t, err)
+		require.Len(t, searches, 2)
+		assert.Equal(t, "*", searches[0].Query)
+	})
+
+	t.Run("Rejects unsupported versions and duplicates", func(t *testing.T) {
+		target := setupConfigService(t)
+
+		_, err := target.ImportConfigBundle(ctx, &ConfigBundle{Version: ConfigBundleVersion + 1}, false, false)
+		assert.True(t, utils.IsValidationError(err))
+
+		dup := &ConfigBundle{
+			Version: ConfigBundleVersion,
+			SavedSearches: []SavedSearchSpec{
+				{Name: "a", Query: "x"},
+				{Name: "a", Query: "y"},
+			},
+		}
+		_, err = target.ImportConfigBundle(ctx, dup, false, false)
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
+
+func TestMemoryService_ConfigBundleArtifacts(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *MemoryService {
+		service := setupConfigService(t)
+		testutil.NewUser().ID(service.userID).Create(t, service.db)
+		return service
+	}
+
+	source := setup(t)
+	_, err := source.SaveSearch(ctx, SavedSearchSpec{Name: "prefs", Query: "*"})
+	require.NoError(t, err)
+	disabled := false
+	_, err = source.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+		Name: "acme", Match: "Acme Corp", Category: models.CategoryBusiness, Tags: []string{"acme"},
+	})
+	require.NoError(t, err)
+	_, err = source.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+		Name: "paused", Match: "draft", Tags: []string{"draft"}, Enabled: &disabled,
+	})
+	require.NoError(t, err)
+	_, err = source.CreateWorkspace(ctx, "clients", "one per client")
+	require.NoError(t, err)
+	require.NoError(t, source.SetEvictionPolicy(ctx, EvictionLeastAccessed))
+	_, err = source.SetMaintenanceEnabled(ctx, true)
+	require.NoError(t, err)
+
+	bundle, err := source.ExportConfigBundle(ctx)
+	require.NoError(t, err)
+	require.Len(t, bundle.CategorizationRules, 2)
+	assert.False(t, *bundle.CategorizationRules[1].Enabled)
+	assert.Equal(t, []WorkspaceSpec{{Name: "clients", Description: "one per client"}}, bundle.Workspaces)
+	assert.Equal(t, &ConfigSettings{EvictionPolicy: EvictionLeastAccessed, MaintenanceEnabled: true}, bundle.Settings)
+
+	t.Run("Imports every artifact", func(t *testing.T) {
+		target := setup(t)
+
+		result, err := target.ImportConfigBundle(ctx, bundle, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Created: 4, Updated: 1}, result)
+
+		exported, err := target.ExportConfigBundle(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, bundle.CategorizationRules, exported.CategorizationRules)
+		assert.Equal(t, bundle.Workspaces, exported.Workspaces)
+		assert.Equal(t, bundle.Settings, exported.Settings)
+	})
+
+	t.Run("Keeps changed settings unless overwriting", func(t *testing.T) {
+		target := setup(t)
+		require.NoError(t, target.SetEvictionPolicy(ctx, EvictionRejectNew))
+		_, err := target.CreateWorkspace(ctx, "clients", "mine")
+		require.NoError(t, err)
+
+		result, err := target.ImportConfigBundle(ctx, bundle, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Created: 3, Skipped: 2}, result)
+		assert.Equal(t, EvictionRejectNew, target.EvictionPolicy(ctx))
+
+		result, err = target.ImportConfigBundle(ctx, bundle, true, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Updated: 5}, result)
+		assert.Equal(t, EvictionLeastAccessed, target.EvictionPolicy(ctx))
+		exported, err := target.ExportConfigBundle(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, bundle.Workspaces, exported.Workspaces)
+	})
+
+	t.Run("Accepts version 1 bundles", func(t *testing.T) {
+		target := setup(t)
+
+		result, err := target.ImportConfigBundle(ctx, &ConfigBundle{
+			Version:       1,
+			SavedSearches: []SavedSearchSpec{{Name: "old", Query: "x"}},
+		}, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, &ImportConfigResult{Created: 1}, result)
+	})
+
+	t.Run("Rejects invalid artifacts", func(t *testing.T) {
+		target := setup(t)
+
+		for _, invalid := range []*ConfigBundle{
+			{Version: ConfigBundleVersion, CategorizationRules: []CategorizationRuleSpec{{Name: "a", Match: "x"}}},
+			{Version: ConfigBundleVersion, CategorizationRules: []CategorizationRuleSpec{
+				{Name: "a", Match: "x", Tags: []string{"t"}},
+				{Name: "a", Match: "y", Tags: []string{"t"}},
+			}},
+			{Version: ConfigBundleVersion, Workspaces: []WorkspaceSpec{{Name: models.DefaultWorkspace}}},
+			{Version: ConfigBundleVersion, Workspaces: []WorkspaceSpec{{Name: "bad name"}}},
+			{Version: ConfigBundleVersion, Settings: &ConfigSettings{EvictionPolicy: "random"}},
+		} {
+			_, err := target.ImportConfigBundle(ctx, invalid, false, false)
+			assert.True(t, utils.IsValidationError(err), "%+v", invalid)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Actions that need a confirmation token before they go ahead
+const (
+	ConfirmDeleteCritical = "delete_critical"
+	ConfirmBulkDelete     = "bulk_delete"
+	ConfirmDeleteAccount  = "delete_account"
+)
+
+// confirmationTTL is how long an issued confirmation token stays usable
+const confirmationTTL = 5 * time.Minute
+
+// Confirmation tokens guard against accidental destructive calls: the first
+// attempt is refused with a token, and only a second call carrying it goes ahead.
+// The token is handed to whoever made the attempt, so it proves the caller meant
+// it, not that a person approved it. Tokens are random, stored hashed, used up by
+// the confirming call and expire after confirmationTTL; each is bound to a
+// fingerprint of what the action affects, so a change in between voids it.
+
+// confirmationFingerprint hashes the parts describing what an action affects
+func confirmationFingerprint(parts ...interface{}) string {
+	hash := sha256.New()
+	for i, part := range parts {
+		if i > 0 {
+			hash.Write([]byte{':'})
+		}
+		fmt.Fprint(hash, part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// hashConfirmationToken returns the stored form of a confirmation token
+func hashConfirmationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// confirm uses up token if it was issued to the user for the action and
+// fingerprint and has not expired. Otherwise it issues a fresh token and returns
+// it, so the caller can refuse the action and hand the token back.
+func (s *MemoryService) confirm(ctx context.Context, action, fingerprint, token string) (string, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	if token = strings.TrimSpace(token); token != "" {
+		used := db.Where("user_id = ? AND action = ? AND fingerprint = ? AND token_hash = ? AND expires_at > ?",
+			s.userID, action, fingerprint, hashConfirmationToken(token), now).
+			Delete(&models.Confirmation{})
+		if used.Error != nil {
+			return "", utils.WrapDatabaseError("check confirmation", used.Error)
+		}
+		if used.RowsAffected > 0 {
+			return "", nil
+		}
+	}
+
+	if err := db.Where("user_id = ? AND expires_at <= ?", s.userID, now).Delete(&models.Confirmation{}).Error; err != nil {
+		s.logger.Warn().Err(err).Msg("failed to remove expired confirmations")
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	issued := hex.EncodeToString(random)
+	if err := db.Create(&models.Confirmation{
+		UserID:      s.userID,
+		Action:      action,
+		Fingerprint: fingerprint,
+		TokenHash:   hashConfirmationToken(issued),
+		ExpiresAt:   now.Add(confirmationTTL),
+	}).Error; err != nil {
+		return "", utils.WrapDatabaseError("issue confirmation", err)
+	}
+	return issued, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestMemoryService_Confirm(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, service.userID+1)
+	fingerprint := confirmationFingerprint(7, "abc")
+
+	token, err := service.confirm(ctx, ConfirmDeleteCritical, fingerprint, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	var stored models.Confirmation
+	require.NoError(t, service.db.First(&stored).Error)
+	assert.NotEqual(t, token, stored.TokenHash, "only the hash is stored")
+
+	refused := func(svc *MemoryService, action, fingerprint, token string) bool {
+		issued, err := svc.confirm(ctx, action, fingerprint, token)
+		require.NoError(t, err)
+		return issued != ""
+	}
+	assert.True(t, refused(service, ConfirmBulkDelete, fingerprint, token), "tokens are bound to their action")
+	assert.True(t, refused(service, ConfirmDeleteCritical, confirmationFingerprint(7, "abd"), token), "and to what the action affects")
+	assert.True(t, refused(other, ConfirmDeleteCritical, fingerprint, token), "and to the user")
+
+	assert.False(t, refused(service, ConfirmDeleteCritical, fingerprint, " "+token+" "))
+	assert.True(t, refused(service, ConfirmDeleteCritical, fingerprint, token), "tokens are single use")
+
+	expiring, err := service.confirm(ctx, ConfirmDeleteAccount, fingerprint, "")
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&models.Confirmation{}).
+		Where("token_hash = ?", hashConfirmationToken(expiring)).
+		Update("expires_at", time.Now().Add(-time.Second)).Error)
+	assert.True(t, refused(service, ConfirmDeleteAccount, fingerprint, expiring), "expired tokens are refused")
+
+	var expired int64
+	require.NoError(t, service.db.Model(&models.Confirmation{}).Where("expires_at <= ?", time.Now()).Count(&expired).Error)
+	assert.Zero(t, expired, "expired tokens are cleared when new ones are issued")
+}
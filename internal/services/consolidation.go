@@ -0,0 +1,572 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// DefaultConsolidationSimilarity is how similar two memories' embeddings must
+	// be for them to fall in the same cluster
+	DefaultConsolidationSimilarity = 0.85
+	// DefaultConsolidationMinCluster is the fewest memories worth consolidating
+	DefaultConsolidationMinCluster = 3
+	// DefaultConsolidationMaxCluster caps the memories folded into one summary
+	DefaultConsolidationMaxCluster = 10
+	// DefaultConsolidationMaxClusters caps the clusters one run consolidates
+	DefaultConsolidationMaxClusters = 10
+	// maxConsolidationEdges bounds the neighbor pairs clustering considers
+	maxConsolidationEdges = 5000
+)
+
+// ErrConsolidationDisabled is returned when consolidation is asked for but no
+// summarizer is configured
+var ErrConsolidationDisabled = errors.New("memory consolidation is disabled: no language model is configured")
+
+// Summary is what a MemorySummarizer made of a cluster of memories
+type Summary struct {
+	Content  string
+	Type     string
+	Category string
+	// InputTokens and OutputTokens are the language-model usage
+	InputTokens  int
+	OutputTokens int
+}
+
+// MemorySummarizer merges related memories into one
+type MemorySummarizer interface {
+	Summarize(ctx context.Context, memories []*models.Memory) (*Summary, error)
+}
+
+// consolidationPrompt tells the model how to merge memories and how to answer
+const consolidationPrompt = `You merge related long-term memories about a user into one.
+You get a numbered list of memories, oldest first. Return a JSON object with:
+- "content": one self-contained memory keeping every distinct detail of the
+  list. Where memories disagree, the newer one wins. Do not invent anything.
+- "type": fact, preference, context or conversation
+- "category": personal, project or business
+Never include passwords, keys, tokens or other secrets.`
+
+// LLMSummarizer merges memories with an OpenAI chat model
+type LLMSummarizer struct {
+	apiKey   string
+	model    string
+	endpoint string
+	client   *http.Client
+}
+
+// NewLLMSummarizer creates a summarizer backed by the OpenAI chat completions API
+func NewLLMSummarizer(apiKey, model string, timeout time.Duration) *LLMSummarizer {
+	return &LLMSummarizer{
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: "https://api.openai.com/v1/chat/completions",
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// NewMemorySummarizerFromConfig builds the summarizer consolidation uses, or
+// returns nil when there is no OpenAI key to run it with
+func NewMemorySummarizerFromConfig(cfg *config.Config, logger zerolog.Logger) MemorySummarizer {
+	if cfg.OpenAI.APIKey == "" {
+		return nil
+	}
+	logger.Info().Str("model", cfg.Consolidation.Model).Msg("Memory consolidation available")
+	return NewLLMSummarizer(cfg.OpenAI.APIKey, cfg.Consolidation.Model, cfg.Consolidation.Timeout)
+}
+
+// withAPIKey returns a copy of the summarizer that sends requests with another key
+func (m *LLMSummarizer) withAPIKey(apiKey string) *LLMSummarizer {
+	copied := *m
+	copied.apiKey = apiKey
+	return &copied
+}
+
+// Summarize asks the model for one memory covering all of the given ones. An
+// unknown type or category is left empty for the caller to choose.
+func (m *LLMSummarizer) Summarize(ctx context.Context, memories []*models.Memory) (*Summary, error) {
+	var list strings.Builder
+	for i, memory := range memories {
+		fmt.Fprintf(&list, "%d. [%s, %s/%s] %s\n", i+1, memory.CreatedAt.UTC().Format(time.DateOnly),
+			memory.Category, memory.Type, strings.Join(strings.Fields(memory.Content), " "))
+	}
+
+	completion, err := chatCompletion(ctx, m.client, m.endpoint, m.apiKey, m.model, consolidationPrompt, list.String())
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{
+		InputTokens:  completion.InputTokens,
+		OutputTokens: completion.OutputTokens,
+	}
+
+	var answer struct {
+		Content  string `json:"content"`
+		Type     string `json:"type"`
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal([]byte(completion.Content), &answer); err != nil {
+		return summary, fmt.Errorf("failed to parse summary: %w", err)
+	}
+	summary.Content = strings.TrimSpace(answer.Content)
+	if summary.Content == "" {
+		return summary, fmt.Errorf("summary is empty")
+	}
+	if memoryType := strings.ToLower(answer.Type); models.IsValidType(memoryType) {
+		summary.Type = memoryType
+	}
+	if category := strings.ToLower(answer.Category); models.IsValidCategory(category) {
+		summary.Category = category
+	}
+	return summary, nil
+}
+
+// GetMemorySummarizer returns the configured summarizer, or nil when
+// consolidation is unavailable
+func (s *MemoryService) GetMemorySummarizer() MemorySummarizer {
+	summarizer, _ := s.config["memory_summarizer"].(MemorySummarizer)
+	return summarizer
+}
+
+// consolidationSimilarity returns the configured clustering threshold
+func (s *MemoryService) consolidationSimilarity() float64 {
+	if similarity, ok := s.config["consolidation_similarity"].(float64); ok && similarity > 0 {
+		return similarity
+	}
+	return DefaultConsolidationSimilarity
+}
+
+// consolidationClusterSizes returns the configured smallest and largest cluster
+func (s *MemoryService) consolidationClusterSizes() (int, int) {
+	minSize, maxSize := DefaultConsolidationMinCluster, DefaultConsolidationMaxCluster
+	if size, ok := s.config["consolidation_min_cluster"].(int); ok && size >= 2 {
+		minSize = size
+	}
+	if size, ok := s.config["consolidation_max_cluster"].(int); ok && size > 0 {
+		maxSize = size
+	}
+	return minSize, max(minSize, maxSize)
+}
+
+// ConsolidateRequest selects the memories to consolidate
+type ConsolidateRequest struct {
+	// MemoryIDs consolidates exactly these memories into one; when empty,
+	// clusters of similar memories are found
+	MemoryIDs []uint
+	// MaxClusters caps the clusters consolidated
+	MaxClusters int
+	// DryRun lists the clusters without summarizing anything
+	DryRun bool
+}
+
+// ConsolidationCluster is a group of related memories and, once consolidated, the
+// summary replacing them
+type ConsolidationCluster struct {
+	MemoryIDs []uint `json:"memory_ids"`
+	// Similarity is the lowest similarity that joined the cluster; 0 for memories
+	// chosen by ID
+	Similarity float64          `json:"similarity,omitempty"`
+	Memories   []*models.Memory `json:"memories,omitempty"`
+	Summary    *models.Memory   `json:"summary,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// ConsolidateResult reports the clusters found and what became of them
+type ConsolidateResult struct {
+	DryRun       bool                   `json:"dry_run"`
+	Clusters     []ConsolidationCluster `json:"clusters"`
+	Consolidated int                    `json:"consolidated"`
+}
+
+// Consolidate merges clusters of related memories into summary memories. Each
+// summary is written by the language model, records the memories it came from as
+// its provenance and supersedes them with a link; the originals are kept, so the
+// maintenance agent can later propose pruning them. Memories already superseded
+// are not clustered again. Clusters are found by embedding similarity, which
+// needs postgres; on other databases only memories chosen by ID are consolidated.
+func (s *MemoryService) Consolidate(ctx context.Context, req ConsolidateRequest) (*ConsolidateResult, error) {
+	if req.MaxClusters <= 0 {
+		req.MaxClusters = DefaultConsolidationMaxClusters
+	}
+	summarizer := s.GetMemorySummarizer()
+	if summarizer == nil && !req.DryRun {
+		return nil, ErrConsolidationDisabled
+	}
+
+	var clusters []ConsolidationCluster
+	if len(req.MemoryIDs) > 0 {
+		ids := uniqueIDs(req.MemoryIDs)
+		if len(ids) < 2 {
+			return nil, utils.InvalidFieldError("memory_ids", "give at least two memories to consolidate")
+		}
+		if _, maxSize := s.consolidationClusterSizes(); len(ids) > maxSize {
+			return nil, utils.InvalidFieldError("memory_ids", fmt.Sprintf("at most %d memories can be consolidated at once", maxSize))
+		}
+		clusters = []ConsolidationCluster{{MemoryIDs: ids}}
+	} else {
+		found, err := s.findConsolidationClusters(ctx, req.MaxClusters)
+		if err != nil {
+			return nil, err
+		}
+		clusters = found
+	}
+
+	result := &ConsolidateResult{DryRun: req.DryRun, Clusters: clusters}
+	for i := range result.Clusters {
+		cluster := &result.Clusters[i]
+		memories, err := s.List(ctx, ListRequest{WithinIDs: cluster.MemoryIDs, Limit: len(cluster.MemoryIDs)})
+		if err != nil {
+			return nil, err
+		}
+		// Oldest first, so the summarizer lets newer memories win
+		slices.Reverse(memories)
+		if len(memories) != len(cluster.MemoryIDs) {
+			if len(req.MemoryIDs) > 0 {
+				return nil, utils.WrapNotFoundError("memory", idList(cluster.MemoryIDs))
+			}
+			// Deleted since the clusters were found
+			cluster.Error = "some of the memories no longer exist"
+			continue
+		}
+		if req.DryRun {
+			cluster.Memories = memories
+			continue
+		}
+
+		summary, err := s.consolidateCluster(ctx, summarizer, memories)
+		if err != nil {
+			if len(req.MemoryIDs) > 0 || (errors.Is(err, ErrLLMBudgetExceeded) && result.Consolidated == 0) {
+				return nil, err
+			}
+			if errors.Is(err, ErrLLMBudgetExceeded) {
+				cluster.Error = err.Error()
+				break
+			}
+			s.logger.Warn().Err(err).Uints("memory_ids", cluster.MemoryIDs).Msg("failed to consolidate memories")
+			cluster.Error = err.Error()
+			continue
+		}
+		cluster.Summary = summary
+		result.Consolidated++
+	}
+
+	if result.Consolidated > 0 {
+		s.logActivity(ctx, models.ActivityMemoriesConsolidated, map[string]interface{}{
+			"consolidated": result.Consolidated,
+		})
+	}
+	return result, nil
+}
+
+// consolidateCluster summarizes memories, oldest first, into a new memory that
+// supersedes them
+func (s *MemoryService) consolidateCluster(ctx context.Context, summarizer MemorySummarizer, memories []*models.Memory) (*models.Memory, error) {
+	if err := s.CheckLLMBudget(ctx, LLMFeatureConsolidation); err != nil {
+		return nil, err
+	}
+	if llm, ok := summarizer.(*LLMSummarizer); ok {
+		if apiKey := s.userOpenAIKey(ctx); apiKey != "" {
+			summarizer = llm.withAPIKey(apiKey)
+		}
+	}
+
+	summary, err := summarizer.Summarize(ctx, memories)
+	if summary != nil && (summary.InputTokens > 0 || summary.OutputTokens > 0) {
+		if _, usageErr := s.RecordLLMUsage(ctx, LLMFeatureConsolidation, summary.InputTokens, summary.OutputTokens); usageErr != nil {
+			s.logger.Warn().Err(usageErr).Msg("failed to record consolidation usage")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize memories: %w", err)
+	}
+	// The model is told not to, but secrets must never be stored
+	if containsSensitiveInfo(summary.Content) {
+		return nil, fmt.Errorf("summary contains sensitive information")
+	}
+
+	req := StoreRequest{
+		Content:     summary.Content,
+		Type:        summary.Type,
+		Category:    summary.Category,
+		Priority:    models.PriorityLow,
+		OnDuplicate: DuplicateAllow,
+	}
+	ids := make([]uint, len(memories))
+	types, categories := map[string]int{}, map[string]int{}
+	for i, memory := range memories {
+		ids[i] = memory.ID
+		types[memory.Type]++
+		categories[memory.Category]++
+		if priorityRank(memory.Priority) > priorityRank(req.Priority) {
+			req.Priority = memory.Priority
+		}
+		for _, tag := range memory.Tags {
+			if !slices.Contains(req.Tags, tag) {
+				req.Tags = append(req.Tags, tag)
+			}
+		}
+	}
+	if req.Type == "" {
+		req.Type = mostCommon(types)
+	}
+	if req.Category == "" {
+		req.Category = mostCommon(categories)
+	}
+
+	// The summary joins its memories' workspace, also when a worker running over
+	// the whole account consolidates them
+	store := s
+	if _, ok := s.Workspace(); !ok {
+		store = s.InWorkspace(memories[0].WorkspaceID)
+	}
+	consolidated, err := store.StoreDerived(ctx, req, models.ProvenanceMethodConsolidation, ids)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]models.MemoryLink, len(ids))
+	for i, id := range ids {
+		links[i] = models.MemoryLink{UserID: s.userID, SourceID: consolidated.ID, TargetID: id, Relation: models.LinkSupersedes}
+	}
+	if err := s.db.WithContext(ctx).Create(&links).Error; err != nil {
+		s.logger.Error().Err(err).Uint("memory_id", consolidated.ID).Msg("failed to link consolidated memories")
+		return nil, utils.WrapDatabaseError("link consolidated memories", err)
+	}
+
+	s.logger.Info().
+		Uint("memory_id", consolidated.ID).
+		Uints("sources", ids).
+		Msg("consolidated memories")
+	return consolidated, nil
+}
+
+// consolidationEdge is a pair of memories whose embeddings are similar
+type consolidationEdge struct {
+	ID         uint
+	NeighborID uint
+	Similarity float64
+}
+
+// findConsolidationClusters finds up to limit clusters of similar memories that
+// are not yet superseded
+func (s *MemoryService) findConsolidationClusters(ctx context.Context, limit int) ([]ConsolidationCluster, error) {
+	// The sqlite schema used in tests has no vector type
+	if s.db.Dialector.Name() == "sqlite" {
+		return []ConsolidationCluster{}, nil
+	}
+
+	minSize, maxSize := s.consolidationClusterSizes()
+	// Clusters never span workspaces
+	workspaceFilter, args := "", []interface{}{models.LinkSupersedes, maxSize - 1, s.userID}
+	if workspaceID, ok := s.Workspace(); ok {
+		workspaceFilter = " AND m.workspace_id = ?"
+		args = append(args, workspaceID)
+	}
+	args = append(args, s.consolidationSimilarity(), models.LinkSupersedes, maxConsolidationEdges)
+
+	var edges []consolidationEdge
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT m.id, n.id AS neighbor_id, n.similarity
+		FROM memories m
+		CROSS JOIN LATERAL (
+			SELECT o.id, 1 - (o.embedding <=> m.embedding) AS similarity
+			FROM memories o
+			WHERE o.user_id = m.user_id AND o.workspace_id = m.workspace_id AND o.id <> m.id AND o.embedding IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM memory_links l WHERE l.target_id = o.id AND l.relation = ?)
+			ORDER BY o.embedding <=> m.embedding
+			LIMIT ?
+		) n
+		WHERE m.user_id = ?`+workspaceFilter+` AND m.embedding IS NOT NULL AND n.similarity >= ?
+			AND NOT EXISTS (SELECT 1 FROM memory_links l WHERE l.target_id = m.id AND l.relation = ?)
+		LIMIT ?
+	`, args...).
+		Scan(&edges).Error; err != nil {
+		return nil, utils.WrapDatabaseError("find similar memories", err)
+	}
+
+	clusters := clusterEdges(edges, minSize, maxSize)
+	if len(clusters) > limit {
+		clusters = clusters[:limit]
+	}
+	return clusters, nil
+}
+
+// clusterEdges groups memories joined by similar pairs, most similar pairs first,
+// never letting a cluster grow past maxSize. It returns the clusters of at least
+// minSize memories, largest and then most similar first.
+func clusterEdges(edges []consolidationEdge, minSize, maxSize int) []ConsolidationCluster {
+	edges = slices.Clone(edges)
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].Similarity > edges[j].Similarity
+	})
+
+	parent := map[uint]uint{}
+	members := map[uint][]uint{}
+	similarity := map[uint]float64{}
+	var find func(id uint) uint
+	find = func(id uint) uint {
+		if _, ok := parent[id]; !ok {
+			parent[id] = id
+			members[id] = []uint{id}
+		}
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	for _, edge := range edges {
+		a, b := find(edge.ID), find(edge.NeighborID)
+		if a == b || len(members[a])+len(members[b]) > maxSize {
+			continue
+		}
+		parent[b] = a
+		members[a] = append(members[a], members[b]...)
+		delete(members, b)
+		// Edges come most similar first, so the latest join is the weakest
+		similarity[a] = edge.Similarity
+		delete(similarity, b)
+	}
+
+	clusters := []ConsolidationCluster{}
+	for root, ids := range members {
+		if len(ids) < minSize {
+			continue
+		}
+		slices.Sort(ids)
+		clusters = append(clusters, ConsolidationCluster{MemoryIDs: ids, Similarity: similarity[root]})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].MemoryIDs) != len(clusters[j].MemoryIDs) {
+			return len(clusters[i].MemoryIDs) > len(clusters[j].MemoryIDs)
+		}
+		if clusters[i].Similarity != clusters[j].Similarity {
+			return clusters[i].Similarity > clusters[j].Similarity
+		}
+		return clusters[i].MemoryIDs[0] < clusters[j].MemoryIDs[0]
+	})
+	return clusters
+}
+
+// mostCommon returns the most frequent key, the alphabetically first on a tie
+func mostCommon(counts map[string]int) string {
+	best := ""
+	for key, count := range counts {
+		if count > counts[best] || (count == counts[best] && key < best) {
+			best = key
+		}
+	}
+	return best
+}
+
+// idList renders memory IDs for error messages
+func idList(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ConsolidationWorkerConfig tunes the scheduled consolidation
+type ConsolidationWorkerConfig struct {
+	// Interval is how often every user's memories are consolidated
+	Interval time.Duration
+	// MaxClusters caps the clusters consolidated per user and run
+	MaxClusters int
+}
+
+// DefaultConsolidationWorkerConfig returns the default consolidation settings
+func DefaultConsolidationWorkerConfig() ConsolidationWorkerConfig {
+	return ConsolidationWorkerConfig{
+		Interval:    24 * time.Hour,
+		MaxClusters: DefaultConsolidationMaxClusters,
+	}
+}
+
+// ConsolidationWorker periodically consolidates the memories of every active user
+// with enough embedded memories to form a cluster
+type ConsolidationWorker struct {
+	service *MemoryService
+	config  ConsolidationWorkerConfig
+}
+
+// NewConsolidationWorker creates a consolidation worker using the service's
+// database, summarizer and configuration
+func NewConsolidationWorker(service *MemoryService, config ConsolidationWorkerConfig) *ConsolidationWorker {
+	return &ConsolidationWorker{
+		service: service,
+		config:  config,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *ConsolidationWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.service.logger.Error().Err(err).Msg("consolidation run failed")
+		}
+	}
+}
+
+// RunOnce consolidates each eligible user's memories, returning how many summary
+// memories were created. A user whose consolidation fails is logged and skipped;
+// the run stops once the global LLM budget is spent.
+func (w *ConsolidationWorker) RunOnce(ctx context.Context) (int, error) {
+	if w.service.GetMemorySummarizer() == nil {
+		return 0, ErrConsolidationDisabled
+	}
+
+	minSize, _ := w.service.consolidationClusterSizes()
+	var userIDs []uint
+	if err := w.service.db.WithContext(ctx).Model(&models.User{}).
+		Where("disabled_at IS NULL").
+		Where("(SELECT COUNT(*) FROM memories m WHERE m.user_id = users.id AND m.embedding IS NOT NULL) >= ?", minSize).
+		Order("id").
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find users to consolidate: %w", err)
+	}
+
+	consolidated := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := w.service.forUser(userID).Consolidate(ctx, ConsolidateRequest{MaxClusters: w.config.MaxClusters})
+		if err != nil {
+			var budgetErr *LLMBudgetError
+			if errors.As(err, &budgetErr) && budgetErr.Scope == LLMBudgetScopeGlobal {
+				w.service.logger.Warn().Msg("LLM budget spent, stopping consolidation")
+				break
+			}
+			w.service.logger.Warn().Err(err).Uint("user_id", userID).Msg("consolidation failed")
+			continue
+		}
+		consolidated += result.Consolidated
+	}
+	if consolidated > 0 {
+		w.service.logger.Info().Int("consolidated", consolidated).Int("users", len(userIDs)).Msg("consolidated memories")
+	}
+	return consolidated, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// stubSummarizer joins the memories it is given, recording each call
+type stubSummarizer struct {
+	calls [][]uint
+	err   error
+}
+
+func (s *stubSummarizer) Summarize(ctx context.Context, memories []*models.Memory) (*Summary, error) {
+	ids := make([]uint, len(memories))
+	contents := make([]string, len(memories))
+	for i, memory := range memories {
+		ids[i] = memory.ID
+		contents[i] = memory.Content
+	}
+	s.calls = append(s.calls, ids)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &Summary{Content: "Summary: " + strings.Join(contents, " "), InputTokens: 100, OutputTokens: 20}, nil
+}
+
+func TestClusterEdges(t *testing.T) {
+	edges := []consolidationEdge{
+		{ID: 1, NeighborID: 2, Similarity: 0.95},
+		{ID: 2, NeighborID: 3, Similarity: 0.90},
+		{ID: 3, NeighborID: 4, Similarity: 0.86},
+		{ID: 2, NeighborID: 1, Similarity: 0.95},
+		{ID: 10, NeighborID: 11, Similarity: 0.99},
+		{ID: 11, NeighborID: 12, Similarity: 0.97},
+		{ID: 20, NeighborID: 21, Similarity: 0.99},
+	}
+
+	clusters := clusterEdges(edges, 3, 10)
+	require.Len(t, clusters, 2)
+	assert.Equal(t, []uint{1, 2, 3, 4}, clusters[0].MemoryIDs)
+	assert.Equal(t, 0.86, clusters[0].Similarity)
+	assert.Equal(t, []uint{10, 11, 12}, clusters[1].MemoryIDs)
+	assert.Equal(t, 0.97, clusters[1].Similarity)
+
+	// Clusters stop growing at the maximum size, weakest joins first to go
+	clusters = clusterEdges(edges, 2, 3)
+	require.Len(t, clusters, 3)
+	assert.Equal(t, []uint{10, 11, 12}, clusters[0].MemoryIDs)
+	assert.Equal(t, []uint{1, 2, 3}, clusters[1].MemoryIDs)
+	assert.Equal(t, 0.90, clusters[1].Similarity)
+	assert.Equal(t, []uint{20, 21}, clusters[2].MemoryIDs)
+
+	assert.Empty(t, clusterEdges(nil, 3, 10))
+}
+
+func TestLLMSummarizer_Summarize(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []map[string]string `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Messages, 2)
+		prompt = req.Messages[1]["content"]
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{
+				"content": `{"content": "Deploys Apollo on Fridays with Postgres 16", "type": "FACT", "category": "office"}`,
+			}}},
+			"usage": map[string]int{"prompt_tokens": 300, "completion_tokens": 40},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	summarizer := NewLLMSummarizer("sk-test", "gpt-4o-mini", 5*time.Second)
+	summarizer.endpoint = server.URL
+
+	day := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	summary, err := summarizer.Summarize(context.Background(), []*models.Memory{
+		{Content: "Apollo deploys on Fridays", Category: models.CategoryProject, Type: models.TypeFact, CreatedAt: day},
+		{Content: "Apollo moved to\nPostgres 16", Category: models.CategoryProject, Type: models.TypeFact, CreatedAt: day.AddDate(0, 1, 0)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1. [2025-03-01, project/fact] Apollo deploys on Fridays\n2. [2025-04-01, project/fact] Apollo moved to Postgres 16\n", prompt)
+	assert.Equal(t, "Deploys Apollo on Fridays with Postgres 16", summary.Content)
+	assert.Equal(t, models.TypeFact, summary.Type)
+	assert.Empty(t, summary.Category, "unknown categories are left for the caller")
+	assert.Equal(t, 300, summary.InputTokens)
+	assert.Equal(t, 40, summary.OutputTokens)
+}
+
+func TestMemoryService_Consolidate(t *testing.T) {
+	ctx := context.Background()
+	summarizer := &stubSummarizer{}
+	service := setupMemoryService(t, map[string]interface{}{"memory_summarizer": summarizer})
+	require.NoError(t, service.db.AutoMigrate(&models.MemoryProvenance{}, &models.ActivityLog{}))
+
+	store := func(content, category, priority string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{
+			Content:  content,
+			Category: category,
+			Type:     models.TypeFact,
+			Priority: priority,
+		})
+		require.NoError(t, err)
+		return memory
+	}
+	first := store("Apollo deploys on Fridays", models.CategoryProject, "")
+	second := store("Apollo uses Postgres 16", models.CategoryProject, models.PriorityHigh)
+	third := store("Apollo's on-call rota is weekly", models.CategoryBusiness, "")
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := service.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{first.ID, first.ID}})
+		assert.True(t, utils.IsValidationError(err))
+
+		_, err = service.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{first.ID, 9999}})
+		assert.True(t, utils.IsNotFoundError(err))
+		assert.Empty(t, summarizer.calls)
+	})
+
+	t.Run("dry run lists the memories", func(t *testing.T) {
+		result, err := service.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{third.ID, first.ID, second.ID}, DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		require.Len(t, result.Clusters, 1)
+		assert.Len(t, result.Clusters[0].Memories, 3)
+		assert.Nil(t, result.Clusters[0].Summary)
+		assert.Zero(t, result.Consolidated)
+		assert.Empty(t, summarizer.calls)
+	})
+
+	t.Run("without a summarizer", func(t *testing.T) {
+		plain := NewMemoryService(service.db, nil, service.logger, map[string]interface{}{})
+		_, err := plain.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{first.ID, second.ID}})
+		assert.True(t, errors.Is(err, ErrConsolidationDisabled))
+	})
+
+	t.Run("consolidates into a summary superseding the originals", func(t *testing.T) {
+		result, err := service.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{third.ID, first.ID, second.ID}})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Consolidated)
+		require.Len(t, summarizer.calls, 1)
+		assert.Equal(t, []uint{first.ID, second.ID, third.ID}, summarizer.calls[0], "oldest first")
+
+		summary := result.Clusters[0].Summary
+		require.NotNil(t, summary)
+		assert.Equal(t, "Summary: Apollo deploys on Fridays Apollo uses Postgres 16 Apollo's on-call rota is weekly", summary.Content)
+		assert.Equal(t, models.CategoryProject, summary.Category, "the most common category")
+		assert.Equal(t, models.TypeFact, summary.Type)
+		assert.Equal(t, models.PriorityHigh, summary.Priority, "the highest priority")
+
+		provenance, err := service.Provenance(ctx, summary.ID)
+		require.NoError(t, err)
+		assert.True(t, provenance.Derived)
+		require.Len(t, provenance.Sources, 3)
+		assert.Equal(t, models.ProvenanceMethodConsolidation, provenance.Sources[0].Method)
+
+		related, err := service.GetRelatedMemories(ctx, summary.ID, RelatedRequest{Relations: []string{models.LinkSupersedes}})
+		require.NoError(t, err)
+		assert.Len(t, related, 3)
+
+		// The originals are kept
+		_, err = service.GetByID(ctx, first.ID)
+		assert.NoError(t, err)
+
+		status, err := service.LLMBudgetStatus(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, status.ByFeature[LLMFeatureConsolidation], 0.0)
+	})
+
+	t.Run("summarizer failures are returned", func(t *testing.T) {
+		summarizer.err = errors.New("model unavailable")
+		defer func() { summarizer.err = nil }()
+
+		_, err := service.Consolidate(ctx, ConsolidateRequest{MemoryIDs: []uint{first.ID, second.ID}})
+		assert.ErrorContains(t, err, "model unavailable")
+	})
+
+	t.Run("clusters need embeddings", func(t *testing.T) {
+		// The sqlite schema used in tests has no vector type, so none are found
+		result, err := service.Consolidate(ctx, ConsolidateRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, result.Clusters)
+	})
+}
+
+func TestConsolidationWorker_RunOnce(t *testing.T) {
+	ctx := context.Background()
+
+	service := setupMemoryService(t, nil)
+	_, err := NewConsolidationWorker(service, DefaultConsolidationWorkerConfig()).RunOnce(ctx)
+	assert.True(t, errors.Is(err, ErrConsolidationDisabled))
+
+	service = setupMemoryService(t, map[string]interface{}{"memory_summarizer": &stubSummarizer{}})
+	require.NoError(t, service.db.AutoMigrate(&models.User{}))
+	require.NoError(t, service.db.Create(&models.User{ID: 1, Email: "user@example.com", Password: "x"}).Error)
+
+	consolidated, err := NewConsolidationWorker(service, DefaultConsolidationWorkerConfig()).RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, consolidated)
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// DefaultContextBufferTTL is how long a conversation turn stays in the buffer
+	// when context_buffer_ttl is not configured
+	DefaultContextBufferTTL = 30 * time.Minute
+	// DefaultContextSessionID is the session used when a client does not name one
+	DefaultContextSessionID = "default"
+	// DefaultContextSearchLimit caps the buffer hits blended into a search
+	DefaultContextSearchLimit = 5
+
+	// maxContextTurnsPerSession bounds a session's buffer; the oldest turns are
+	// dropped first
+	maxContextTurnsPerSession = 50
+	// maxContextTurnLength bounds a single turn
+	maxContextTurnLength      = 4000
+	maxContextSessionIDLength = 128
+)
+
+// AppendContextRequest adds a conversation turn to a session's buffer
+type AppendContextRequest struct {
+	SessionID string
+	Role      string
+	Content   string
+}
+
+// ContextMatch is a buffered turn matching a search, scored by the fraction of
+// query terms it contains
+type ContextMatch struct {
+	models.ContextTurn
+	Score float64 `json:"score"`
+}
+
+// contextBufferTTL returns the configured lifetime of buffered turns
+func (s *MemoryService) contextBufferTTL() time.Duration {
+	if ttl, ok := s.config["context_buffer_ttl"].(time.Duration); ok && ttl > 0 {
+		return ttl
+	}
+	return DefaultContextBufferTTL
+}
+
+// AppendContext adds a turn to the session's short-term buffer. Buffered turns
+// give clients working memory between tool calls without storing chit-chat as
+// memories: they expire after the buffer TTL and sessions keep only their most
+// recent turns. Nothing is buffered while incognito.
+func (s *MemoryService) AppendContext(ctx context.Context, req AppendContextRequest) (*models.ContextTurn, error) {
+	sessionID, err := normalizeContextSession(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, utils.RequiredFieldError("content")
+	}
+	if len(content) > maxContextTurnLength {
+		return nil, utils.InvalidFieldError("content", fmt.Sprintf("must be at most %d characters", maxContextTurnLength))
+	}
+	role := req.Role
+	if role == "" {
+		role = models.ContextRoleUser
+	}
+	if !models.IsValidContextRole(role) {
+		return nil, utils.InvalidFieldError("role", "must be one of: user, assistant")
+	}
+	if err := s.checkIncognito(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	turn := &models.ContextTurn{
+		UserID:    s.userID,
+		SessionID: sessionID,
+		Role:      role,
+		Content:   content,
+		ExpiresAt: now.Add(s.contextBufferTTL()),
+	}
+	if s.encryption != nil {
+		encrypted := &models.Memory{Content: content}
+		if err := s.encryptContent(encrypted); err != nil {
+			return nil, err
+		}
+		turn.Content = encrypted.Content
+		turn.EncryptedContent = encrypted.EncryptedContent
+		turn.IsEncrypted = true
+	}
+
+	err = s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expires_at <= ?", s.userID, now).
+			Delete(&models.ContextTurn{}).Error; err != nil {
+			return fmt.Errorf("prune expired turns: %w", err)
+		}
+		if err := tx.Create(turn).Error; err != nil {
+			return err
+		}
+
+		keep := tx.Model(&models.ContextTurn{}).
+			Select("id").
+			Where("user_id = ? AND session_id = ?", s.userID, sessionID).
+			Order("id DESC").
+			Limit(maxContextTurnsPerSession)
+		if err := tx.Where("user_id = ? AND session_id = ? AND id NOT IN (?)", s.userID, sessionID, keep).
+			Delete(&models.ContextTurn{}).Error; err != nil {
+			return fmt.Errorf("trim session buffer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, utils.WrapDatabaseError("append context", err)
+	}
+
+	turn.Content = content
+	return turn, nil
+}
+
+// ContextTurns returns the session's unexpired turns, oldest first
+func (s *MemoryService) ContextTurns(ctx context.Context, sessionID string) ([]models.ContextTurn, error) {
+	sessionID, err := normalizeContextSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var turns []models.ContextTurn
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND session_id = ? AND expires_at > ?", s.userID, sessionID, time.Now()).
+		Order("id ASC").
+		Find(&turns).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get context", err)
+	}
+
+	for i := range turns {
+		turn := &turns[i]
+		if !turn.IsEncrypted {
+			continue
+		}
+		decrypted := &models.Memory{IsEncrypted: true, EncryptedContent: turn.EncryptedContent}
+		if err := s.decryptContent(decrypted); err != nil {
+			s.logger.Warn().Err(err).Uint("turn_id", turn.ID).Msg("failed to decrypt context turn")
+			continue
+		}
+		turn.Content = decrypted.Content
+	}
+	return turns, nil
+}
+
+// SearchContext returns the session's buffered turns matching the query, best
+// matches first and newer turns breaking ties. Buffers are small and may be
+// encrypted, so turns are matched in memory rather than by the database. An
+// empty or wildcard query returns the most recent turns.
+func (s *MemoryService) SearchContext(ctx context.Context, sessionID, query string, limit int) ([]ContextMatch, error) {
+	turns, err := s.ContextTurns(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultContextSearchLimit
+	}
+
+	terms := contextTerms(query)
+	matches := make([]ContextMatch, 0, len(turns))
+	for _, turn := range turns {
+		score := 1.0
+		if len(terms) > 0 {
+			score = contextScore(turn.Content, terms)
+		}
+		if score > 0 {
+			matches = append(matches, ContextMatch{ContextTurn: turn, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID > matches[j].ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// normalizeContextSession validates a session ID, defaulting an empty one
+func normalizeContextSession(sessionID string) (string, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return DefaultContextSessionID, nil
+	}
+	if len(sessionID) > maxContextSessionIDLength {
+		return "", utils.InvalidFieldError("session_id", fmt.Sprintf("must be at most %d characters", maxContextSessionIDLength))
+	}
+	return sessionID, nil
+}
+
+// contextTerms splits a query into distinct lowercase terms
+func contextTerms(query string) []string {
+	if query == "*" {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// contextScore is the fraction of terms found in content
+func contextScore(content string, terms []string) float64 {
+	content = strings.ToLower(content)
+	found := 0
+	for _, term := range terms {
+		if strings.Contains(content, term) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(terms))
+}
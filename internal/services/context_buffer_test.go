@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func appendTestContext(t *testing.T, service *MemoryService, sessionID, content string) *models.ContextTurn {
+	turn, err := service.AppendContext(context.Background(), AppendContextRequest{
+		SessionID: sessionID,
+		Content:   content,
+	})
+	require.NoError(t, err)
+	return turn
+}
+
+func TestContextBuffer_AppendAndSearch(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	appendTestContext(t, service, "s1", "We are debugging the payment webhook")
+	appendTestContext(t, service, "s1", "The webhook fails with a 502 from the proxy")
+	appendTestContext(t, service, "s1", "Lunch was great")
+	appendTestContext(t, service, "s2", "Payment webhook in another session")
+
+	matches, err := service.SearchContext(ctx, "s1", "payment webhook", 0)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "We are debugging the payment webhook", matches[0].Content)
+	assert.Equal(t, 1.0, matches[0].Score)
+	assert.Equal(t, "The webhook fails with a 502 from the proxy", matches[1].Content)
+	assert.Equal(t, 0.5, matches[1].Score)
+
+	// Memories are untouched by the buffer
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestContextBuffer_WildcardReturnsRecentTurns(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for i := 0; i < 3; i++ {
+		appendTestContext(t, service, "", fmt.Sprintf("turn %d", i))
+	}
+
+	matches, err := service.SearchContext(ctx, DefaultContextSessionID, "*", 2)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "turn 2", matches[0].Content)
+	assert.Equal(t, "turn 1", matches[1].Content)
+}
+
+func TestContextBuffer_Expiry(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{"context_buffer_ttl": time.Minute})
+
+	old := appendTestContext(t, service, "s1", "stale turn")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), old.ExpiresAt, 5*time.Second)
+	require.NoError(t, service.db.Model(&models.ContextTurn{}).Where("id = ?", old.ID).
+		Update("expires_at", time.Now().Add(-time.Second)).Error)
+
+	turns, err := service.ContextTurns(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, turns)
+
+	// Appending prunes expired turns
+	appendTestContext(t, service, "s1", "fresh turn")
+	var stored int64
+	require.NoError(t, service.db.Model(&models.ContextTurn{}).Count(&stored).Error)
+	assert.Equal(t, int64(1), stored)
+}
+
+func TestContextBuffer_KeepsRecentTurnsPerSession(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for i := 0; i < maxContextTurnsPerSession+5; i++ {
+		appendTestContext(t, service, "s1", fmt.Sprintf("turn %d", i))
+	}
+
+	turns, err := service.ContextTurns(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, turns, maxContextTurnsPerSession)
+	assert.Equal(t, "turn 5", turns[0].Content)
+}
+
+func TestContextBuffer_Validation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	_, err := service.AppendContext(ctx, AppendContextRequest{Content: "  "})
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = service.AppendContext(ctx, AppendContextRequest{Content: "hi", Role: "system"})
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestContextBuffer_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+	service := setupMemoryService(t, map[string]interface{}{"encryption_service": encryption})
+
+	turn := appendTestContext(t, service, "s1", "secret plans for friday")
+	assert.Equal(t, "secret plans for friday", turn.Content)
+
+	var stored models.ContextTurn
+	require.NoError(t, service.db.First(&stored, turn.ID).Error)
+	assert.True(t, stored.IsEncrypted)
+	assert.NotContains(t, stored.Content, "secret")
+
+	matches, err := service.SearchContext(ctx, "s1", "friday", 0)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "secret plans for friday", matches[0].Content)
+}
+
+func TestContextBuffer_RefusedWhileIncognito(t *testing.T) {
+	ctx := context.Background()
+	service := setupIncognitoService(t)
+
+	_, err := service.StartIncognito(ctx, 30*time.Minute)
+	require.NoError(t, err)
+
+	_, err = service.AppendContext(ctx, AppendContextRequest{Content: "hello"})
+	assert.ErrorIs(t, err, ErrIncognito)
+}
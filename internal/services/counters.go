@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Where memory counts come from
+const (
+	CountSourceCounters = "counters"
+	CountSourceExact    = "exact"
+)
+
+// MemoryCounts is how many memories a user has, overall and broken down
+type MemoryCounts struct {
+	Total          int64            `json:"total"`
+	ByCategory     map[string]int64 `json:"by_category"`
+	ByType         map[string]int64 `json:"by_type"`
+	WithEmbeddings int64            `json:"with_embeddings"`
+	// Source is counters when read from the materialized counters and exact when
+	// the memories were counted
+	Source string `json:"source"`
+}
+
+// exactCounts reports whether counts must come from scanning memories, as set by
+// exact_counts
+func (s *MemoryService) exactCounts() bool {
+	exact, _ := s.config["exact_counts"].(bool)
+	return exact
+}
+
+// MemoryCounts returns the user's memory counts from the counters the database
+// triggers keep, falling back to counting memories when there are none yet or
+// exact_counts is set
+func (s *MemoryService) MemoryCounts(ctx context.Context) (*MemoryCounts, error) {
+	if !s.exactCounts() {
+		counts, err := s.materializedCounts(ctx)
+		if err != nil {
+			s.logger.Debug().Err(err).Msg("memory counters unavailable, counting memories")
+		} else if counts != nil {
+			return counts, nil
+		}
+	}
+	return s.ExactMemoryCounts(ctx)
+}
+
+// materializedCounts reads the user's counters, or returns nil when there are none
+func (s *MemoryService) materializedCounts(ctx context.Context) (*MemoryCounts, error) {
+	var counters []models.MemoryCounter
+	if err := s.db.WithContext(ctx).Where("user_id = ?", s.userID).Find(&counters).Error; err != nil {
+		return nil, err
+	}
+	if len(counters) == 0 {
+		return nil, nil
+	}
+
+	counts := newMemoryCounts(CountSourceCounters)
+	for _, counter := range counters {
+		switch counter.Dimension {
+		case models.CounterTotal:
+			counts.Total = counter.Count
+		case models.CounterCategory:
+			counts.ByCategory[counter.Value] = counter.Count
+		case models.CounterType:
+			counts.ByType[counter.Value] = counter.Count
+		case models.CounterEmbedded:
+			counts.WithEmbeddings = counter.Count
+		}
+	}
+	return counts, nil
+}
+
+// ExactMemoryCounts counts the user's memories across all workspaces, bypassing
+// the counters
+func (s *MemoryService) ExactMemoryCounts(ctx context.Context) (*MemoryCounts, error) {
+	counts := newMemoryCounts(CountSourceExact)
+
+	var groups []struct {
+		Category string
+		Type     string
+		Count    int64
+		Embedded int64
+	}
+	if err := database.AllWorkspaces(s.db).WithContext(ctx).Model(&models.Memory{}).
+		Select("category, type, COUNT(*) AS count, COUNT(embedding) AS embedded").
+		Where("user_id = ?", s.userID).
+		Group("category, type").
+		Scan(&groups).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to count memories")
+		return nil, utils.WrapDatabaseError("count memories", err)
+	}
+
+	for _, group := range groups {
+		counts.Total += group.Count
+		counts.ByCategory[group.Category] += group.Count
+		counts.ByType[group.Type] += group.Count
+		counts.WithEmbeddings += group.Embedded
+	}
+	return counts, nil
+}
+
+// RecountMemories rebuilds the user's counters from their memories, for when the
+// counters are suspected to have drifted
+func (s *MemoryService) RecountMemories(ctx context.Context) (*MemoryCounts, error) {
+	if err := database.RecountMemories(ctx, s.db, s.userID); err != nil {
+		s.logger.Error().Err(err).Msg("failed to recount memories")
+		return nil, utils.WrapDatabaseError("recount memories", err)
+	}
+	return s.MemoryCounts(ctx)
+}
+
+// newMemoryCounts returns empty counts with every category and type present
+func newMemoryCounts(source string) *MemoryCounts {
+	counts := &MemoryCounts{
+		ByCategory: make(map[string]int64),
+		ByType:     make(map[string]int64),
+		Source:     source,
+	}
+	for _, category := range []string{models.CategoryPersonal, models.CategoryProject, models.CategoryBusiness} {
+		counts.ByCategory[category] = 0
+	}
+	for _, memType := range []string{models.TypeFact, models.TypeConversation, models.TypeContext, models.TypePreference} {
+		counts.ByType[memType] = 0
+	}
+	return counts
+}
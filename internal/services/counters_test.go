@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestMemoryService_MemoryCounts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads the counters kept by triggers", func(t *testing.T) {
+		db, err := database.OpenInMemory("silent")
+		require.NoError(t, err)
+		defer db.Close()
+		service := NewMemoryService(db.DB(), nil, zerolog.Nop(), nil)
+
+		storeTestMemory(t, service, "first")
+		_, err = service.Store(ctx, StoreRequest{Content: "second", Category: models.CategoryProject, Type: models.TypeContext})
+		require.NoError(t, err)
+
+		counts, err := service.MemoryCounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, CountSourceCounters, counts.Source)
+		assert.Equal(t, int64(2), counts.Total)
+		assert.Equal(t, int64(1), counts.ByCategory[models.CategoryProject])
+		assert.Equal(t, int64(1), counts.ByType[models.TypeContext])
+		assert.Zero(t, counts.ByCategory[models.CategoryBusiness])
+
+		exact, err := service.ExactMemoryCounts(ctx)
+		require.NoError(t, err)
+		exact.Source = CountSourceCounters
+		assert.Equal(t, counts, exact)
+
+		stats, err := service.GetMemoryStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), stats["total_count"])
+		assert.Equal(t, CountSourceCounters, stats["counts_source"])
+	})
+
+	t.Run("counts memories without counters", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		storeTestMemory(t, service, "first")
+
+		counts, err := service.MemoryCounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, CountSourceExact, counts.Source)
+		assert.Equal(t, int64(1), counts.Total)
+	})
+
+	t.Run("exact_counts bypasses the counters", func(t *testing.T) {
+		db, err := database.OpenInMemory("silent")
+		require.NoError(t, err)
+		defer db.Close()
+		service := NewMemoryService(db.DB(), nil, zerolog.Nop(), map[string]interface{}{"exact_counts": true})
+		storeTestMemory(t, service, "first")
+
+		count, err := service.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		counts, err := service.MemoryCounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, CountSourceExact, counts.Source)
+	})
+}
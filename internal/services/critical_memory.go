@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// Critical memory events delivered through the notifier
+const (
+	EventCriticalMemoryUpdated = "memory.critical.updated"
+	EventCriticalMemoryDeleted = "memory.critical.deleted"
+)
+
+// ErrConfirmationRequired is returned when a critical memory is deleted without
+// a confirmation token issued for it
+var ErrConfirmationRequired = errors.New("confirmation required to delete a critical memory")
+
+// ConfirmationRequiredError carries the single-use token the caller must send back
+// to confirm deleting a critical memory
+type ConfirmationRequiredError struct {
+	MemoryID uint
+	Token    string
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("memory %d is marked critical; repeat the delete with confirm=%q to proceed", e.MemoryID, e.Token)
+}
+
+func (e *ConfirmationRequiredError) Unwrap() error {
+	return ErrConfirmationRequired
+}
+
+// checkDeleteConfirmation enforces the confirmation step for critical memories.
+// The token only confirms the memory as it was when the token was issued.
+func (s *MemoryService) checkDeleteConfirmation(ctx context.Context, memory *models.Memory, confirm string) error {
+	if memory.Priority != models.PriorityCritical {
+		return nil
+	}
+
+	fingerprint := confirmationFingerprint(memory.ID, memory.ContentHash, memory.UpdatedAt.UnixNano())
+	token, err := s.confirm(ctx, ConfirmDeleteCritical, fingerprint, confirm)
+	if err != nil || token == "" {
+		return err
+	}
+	return &ConfirmationRequiredError{MemoryID: memory.ID, Token: token}
+}
+
+// GetNotifier returns the notifier used for critical memory events, if any
+func (s *MemoryService) GetNotifier() Notifier {
+	notifier, _ := s.config["notifier"].(Notifier)
+	return notifier
+}
+
+// notifyCriticalChange sends an immediate notification when a critical memory is
+// updated or deleted. Delivery happens in the background so slow webhooks do not
+// hold up the caller.
+func (s *MemoryService) notifyCriticalChange(event string, memory *models.Memory, changes map[string]interface{}) {
+	notifier := s.GetNotifier()
+	if notifier == nil {
+		return
+	}
+
+	action := "updated"
+	severity := SeverityWarning
+	if event == EventCriticalMemoryDeleted {
+		action = "deleted"
+		severity = SeverityCritical
+	}
+
+	data := map[string]interface{}{
+		"memory_id": memory.ID,
+		"type":      memory.Type,
+		"category":  memory.Category,
+	}
+	if len(changes) > 0 {
+		data["changes"] = changes
+	}
+
+	notification := &Notification{
+		Event:     event,
+		Severity:  severity,
+		Subject:   fmt.Sprintf("Critical memory %d %s", memory.ID, action),
+		Message:   fmt.Sprintf("Memory %d, marked critical, was %s for user %d.", memory.ID, action, s.userID),
+		UserID:    s.userID,
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+	}
+
+	go func() {
+		ctx, cancel := s.detachedContext(context.Background())
+		defer cancel()
+		if err := notifier.Notify(ctx, notification); err != nil {
+			s.logger.Error().Err(err).Uint("memory_id", memory.ID).Str("event", event).Msg("failed to deliver critical memory notification")
+		}
+	}()
+}
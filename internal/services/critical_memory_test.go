@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func (n *recordingNotifier) events() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	events := make([]string, 0, len(n.notifications))
+	for _, notification := range n.notifications {
+		events = append(events, notification.Event)
+	}
+	return events
+}
+
+func TestMemoryService_CriticalMemoryDelete(t *testing.T) {
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	service := setupMemoryService(t, map[string]interface{}{"notifier": notifier})
+
+	memory, err := service.Store(ctx, StoreRequest{
+		Content:  "Production database password rotates on the 1st",
+		Category: models.CategoryProject,
+		Type:     models.TypeFact,
+		Priority: models.PriorityCritical,
+	})
+	require.NoError(t, err)
+
+	// A plain delete is refused and hands back a confirmation token
+	err = service.Delete(ctx, memory.ID)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrConfirmationRequired))
+
+	var confirmErr *ConfirmationRequiredError
+	require.True(t, errors.As(err, &confirmErr))
+	assert.Len(t, confirmErr.Token, 16)
+
+	// A wrong token is refused as well
+	err = service.DeleteConfirmed(ctx, memory.ID, "deadbeef")
+	assert.True(t, errors.Is(err, ErrConfirmationRequired))
+	assert.Empty(t, notifier.events())
+
+	// The wrong token was refused with a fresh one; the first still holds
+	require.NoError(t, service.DeleteConfirmed(ctx, memory.ID, confirmErr.Token))
+	_, err = service.GetByID(ctx, memory.ID)
+	assert.True(t, utils.IsNotFoundError(err))
+
+	assert.Eventually(t, func() bool {
+		events := notifier.events()
+		return len(events) == 1 && events[0] == EventCriticalMemoryDeleted
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMemoryService_CriticalMemoryUpdateNotifies(t *testing.T) {
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	service := setupMemoryService(t, map[string]interface{}{"notifier": notifier})
+
+	critical, err := service.Store(ctx, StoreRequest{
+		Content:  "Allergic to penicillin",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+		Priority: models.PriorityCritical,
+	})
+	require.NoError(t, err)
+
+	ordinary, err := service.Store(ctx, StoreRequest{
+		Content:  "Likes green tea",
+		Category: models.CategoryPersonal,
+		Type:     models.TypePreference,
+	})
+	require.NoError(t, err)
+
+	_, err = service.Update(ctx, ordinary.ID, UpdateRequest{Content: "Likes oolong tea"})
+	require.NoError(t, err)
+
+	// Downgrading a critical memory is itself a change worth reporting
+	_, err = service.Update(ctx, critical.ID, UpdateRequest{Priority: models.PriorityLow})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		events := notifier.events()
+		return len(events) == 1 && events[0] == EventCriticalMemoryUpdated
+	}, time.Second, 10*time.Millisecond)
+
+	notifier.mu.Lock()
+	changes := notifier.notifications[0].Data["changes"].(map[string]interface{})
+	notifier.mu.Unlock()
+	assert.Equal(t, map[string]string{"from": models.PriorityCritical, "to": models.PriorityLow}, changes["priority"])
+
+	// No longer critical, so a plain delete now succeeds
+	require.NoError(t, service.Delete(ctx, critical.ID))
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// userDataKey returns the encryption service for a user's own data key, or the
+// master key's when the user has no row (as for the local system user before it
+// is created)
+func userDataKey(ctx context.Context, db *gorm.DB, master *utils.EncryptionService, userID uint) (*utils.EncryptionService, error) {
+	key, err := database.UserDataKey(ctx, db, master, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return master, nil
+	}
+	return key, err
+}
+
+// dataKey returns the encryption service for a user's memories, cached for the
+// lifetime of the service
+func (s *MemoryService) dataKey(userID uint) (*utils.EncryptionService, error) {
+	if userID == 0 {
+		userID = s.userID
+	}
+	if key, ok := s.dataKeys.Load(userID); ok {
+		return key.(*utils.EncryptionService), nil
+	}
+
+	key, err := userDataKey(context.Background(), s.db, s.encryption, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.dataKeys.Store(userID, key)
+	return key, nil
+}
+
+// decryptWithDataKey decrypts content encrypted with the user's data key, or with
+// the master key as content was before per-user keys
+func decryptWithDataKey(master, dataKey *utils.EncryptionService, memory *models.Memory, ownerID uint) error {
+	var encryptedData utils.EncryptedData
+	if err := json.Unmarshal(memory.EncryptedContent, &encryptedData); err != nil {
+		return fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	}
+
+	encryption := dataKey
+	if dataKey != master && !dataKey.OwnsField(&encryptedData) {
+		encryption = master
+	}
+	return decryptMemoryContent(encryption, memory, ownerID)
+}
+
+// exportableContent returns a memory's encrypted content re-wrapped under the
+// master key, so another deployment sharing the master key can import it
+func (s *MemoryService) exportableContent(memory *models.Memory) (json.RawMessage, error) {
+	var encryptedData utils.EncryptedData
+	if err := json.Unmarshal(memory.EncryptedContent, &encryptedData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	}
+	if encryptedData.EnvelopeVersion() != utils.EnvelopeV1 {
+		return s.portableContent(memory)
+	}
+	if s.encryption == nil || s.encryption.OwnsField(&encryptedData) {
+		return memory.EncryptedContent, nil
+	}
+
+	dataKey, err := s.dataKey(memory.UserID)
+	if err != nil {
+		return nil, err
+	}
+	rewrapped, err := dataKey.RewrapField(&encryptedData, s.encryption)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rewrapped)
+}
+
+// portableContent re-encrypts content that is bound to its owner without the
+// binding, under the master key, as the user importing it has a different ID
+func (s *MemoryService) portableContent(memory *models.Memory) (json.RawMessage, error) {
+	encryption := s.encryption
+	if encryption == nil {
+		if hook := s.GetModerationHook(); hook != nil {
+			encryption = hook.encryption
+		}
+	}
+	if encryption == nil {
+		return nil, fmt.Errorf("content is encrypted but encryption service is not available")
+	}
+
+	plain := *memory
+	if err := s.decryptContent(&plain); err != nil {
+		return nil, err
+	}
+	encryptedData, err := encryption.EncryptField(plain.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt content: %w", err)
+	}
+	return json.Marshal(encryptedData)
+}
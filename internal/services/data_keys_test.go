@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func encryptedPayload(t *testing.T, memory *models.Memory) *utils.EncryptedData {
+	var data utils.EncryptedData
+	require.NoError(t, json.Unmarshal(memory.EncryptedContent, &data))
+	return &data
+}
+
+func TestDataKeys_PerUser(t *testing.T) {
+	ctx := context.Background()
+	encryption := newTestEncryption(t)
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	for _, id := range []uint{2, 3} {
+		require.NoError(t, db.Create(&models.User{ID: id, Email: string(rune('a'+id)) + "@example.com", Password: "x"}).Error)
+	}
+	config := map[string]interface{}{"encryption_service": encryption}
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	alice := NewMemoryServiceWithUser(db, nil, logger, config, 2)
+	bob := NewMemoryServiceWithUser(db, nil, logger, config, 3)
+
+	aliceMemory, _ := storeTestMemory(t, alice, "alice's secret")
+	bobMemory, _ := storeTestMemory(t, bob, "bob's secret")
+
+	var stored []models.Memory
+	require.NoError(t, db.Omit("embedding", "tags").Order("id").Find(&stored).Error)
+	require.Len(t, stored, 2)
+
+	// Content is under each user's own key, not the master key
+	aliceKey, err := alice.dataKey(2)
+	require.NoError(t, err)
+	bobKey, err := bob.dataKey(3)
+	require.NoError(t, err)
+	assert.False(t, encryption.OwnsField(encryptedPayload(t, &stored[0])))
+	assert.True(t, aliceKey.OwnsField(encryptedPayload(t, &stored[0])))
+	assert.False(t, aliceKey.OwnsField(encryptedPayload(t, &stored[1])))
+	assert.True(t, bobKey.OwnsField(encryptedPayload(t, &stored[1])))
+
+	// The key is stored wrapped by the master key and reused
+	var user models.User
+	require.NoError(t, db.First(&user, 2).Error)
+	require.NotEmpty(t, user.EncryptionKey)
+	fresh := NewMemoryServiceWithUser(db, nil, logger, config, 2)
+	got, err := fresh.GetByID(ctx, aliceMemory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice's secret", got.Content)
+
+	_, err = fresh.GetByID(ctx, bobMemory.ID)
+	assert.True(t, utils.IsNotFoundError(err))
+
+	// Content encrypted before per-user keys still decrypts
+	legacy := &models.Memory{UserID: 2, Type: models.TypeFact, Category: models.CategoryPersonal, Content: "legacy secret"}
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return fresh.createMemory(tx, legacy, encryption, nil)
+	}))
+	got, err = fresh.GetByID(ctx, legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy secret", got.Content)
+
+	// Encrypted exports are portable to servers sharing the master key
+	archive, err := alice.ExportMemoriesWithOptions(ctx, ExportOptions{KeepEncrypted: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, archive.Memories)
+	for _, archived := range archive.Memories {
+		var data utils.EncryptedData
+		require.NoError(t, json.Unmarshal(archived.EncryptedContent, &data))
+		assert.True(t, encryption.OwnsField(&data))
+	}
+}
+
+func newTestEncryption(t *testing.T) *utils.EncryptionService {
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+	return encryption
+}
+
+func TestEncryptedContent_Envelope(t *testing.T) {
+	ctx := context.Background()
+	encryption := newTestEncryption(t)
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	for _, id := range []uint{2, 3} {
+		require.NoError(t, db.Create(&models.User{ID: id, Email: string(rune('a'+id)) + "@example.com", Password: "x"}).Error)
+	}
+	config := map[string]interface{}{"encryption_service": encryption}
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	alice := NewMemoryServiceWithUser(db, nil, logger, config, 2)
+	bob := NewMemoryServiceWithUser(db, nil, logger, config, 3)
+
+	// New content is bound to its owner
+	stored, _ := storeTestMemory(t, alice, "alice's secret")
+	var row models.Memory
+	require.NoError(t, db.Omit("embedding", "tags").First(&row, stored.ID).Error)
+	assert.Equal(t, utils.CurrentEnvelopeVersion, encryptedPayload(t, &row).EnvelopeVersion())
+
+	// An envelope moved to another user's memory does not decrypt there, even when
+	// sealed with the master key both users fall back to
+	swapped := &models.Memory{UserID: 2, Content: "master-key secret"}
+	require.NoError(t, encryptMemoryContent(encryption, swapped, 2))
+	target, _ := storeTestMemory(t, bob, "bob's secret")
+	require.NoError(t, db.Model(&models.Memory{}).Where("id = ?", target.ID).
+		UpdateColumn("encrypted_content", swapped.EncryptedContent).Error)
+	got, err := bob.GetByID(ctx, target.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "master-key secret", got.Content)
+
+	// Nor does one moved to another of the owner's memories
+	other, _ := storeTestMemory(t, alice, "alice's other secret")
+	require.NoError(t, db.Model(&models.Memory{}).Where("id = ?", other.ID).
+		UpdateColumn("encrypted_content", row.EncryptedContent).Error)
+	got, err = alice.GetByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "alice's secret", got.Content)
+	require.NoError(t, db.Delete(&models.Memory{}, other.ID).Error)
+
+	// Legacy envelopes still decrypt and are upgraded on read
+	aliceKey, err := alice.dataKey(2)
+	require.NoError(t, err)
+	legacyData, err := aliceKey.EncryptField("legacy secret")
+	require.NoError(t, err)
+	legacyJSON, err := json.Marshal(legacyData)
+	require.NoError(t, err)
+	legacy, _ := storeTestMemory(t, alice, "to be replaced")
+	require.NoError(t, db.Model(&models.Memory{}).Where("id = ?", legacy.ID).
+		UpdateColumn("encrypted_content", legacyJSON).Error)
+
+	got, err = alice.GetByID(ctx, legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy secret", got.Content)
+	var upgraded models.Memory
+	require.NoError(t, db.Omit("embedding", "tags").First(&upgraded, legacy.ID).Error)
+	assert.False(t, encryptedPayload(t, &upgraded).NeedsUpgrade())
+
+	got, err = alice.GetByID(ctx, legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy secret", got.Content)
+
+	// Version 2 envelopes, bound to the owner only, still decrypt and are upgraded
+	ownerBound, err := aliceKey.EncryptFieldWithAAD("owner-bound secret", models.OwnerContentAAD(2))
+	require.NoError(t, err)
+	ownerBound.Version = utils.EnvelopeV2
+	ownerBoundJSON, err := json.Marshal(ownerBound)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.Memory{}).Where("id = ?", legacy.ID).
+		UpdateColumn("encrypted_content", ownerBoundJSON).Error)
+	got, err = alice.GetByID(ctx, legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "owner-bound secret", got.Content)
+	require.NoError(t, db.Omit("embedding", "tags").First(&upgraded, legacy.ID).Error)
+	assert.Equal(t, utils.CurrentEnvelopeVersion, encryptedPayload(t, &upgraded).EnvelopeVersion())
+
+	// Bound content is exported without the binding so another user can import it
+	archive, err := alice.ExportMemoriesWithOptions(ctx, ExportOptions{KeepEncrypted: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, archive.Memories)
+	for _, archived := range archive.Memories {
+		var data utils.EncryptedData
+		require.NoError(t, json.Unmarshal(archived.EncryptedContent, &data))
+		assert.Equal(t, utils.EnvelopeV1, data.EnvelopeVersion())
+		require.NoError(t, bob.decryptArchivedContent(&archived))
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// dateOnlyLayout is the date form ParseDateBound accepts besides RFC 3339
+const dateOnlyLayout = "2006-01-02"
+
+// DateRange scopes a search to memories created or last updated within bounds.
+// Every bound is optional. After bounds are inclusive and before bounds exclusive,
+// so consecutive ranges such as one month and the next do not overlap.
+type DateRange struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+// ParseDateBound parses a date filter given as an RFC 3339 timestamp or a
+// YYYY-MM-DD date, which means midnight UTC. An empty value is no bound.
+func ParseDateBound(field, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return &parsed, nil
+	}
+	if parsed, err := time.Parse(dateOnlyLayout, value); err == nil {
+		return &parsed, nil
+	}
+	return nil, utils.InvalidFieldError(field, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// IsZero reports whether the range has no bounds
+func (r DateRange) IsZero() bool {
+	return r.CreatedAfter == nil && r.CreatedBefore == nil && r.UpdatedAfter == nil && r.UpdatedBefore == nil
+}
+
+// validate rejects ranges that cannot match anything
+func (r DateRange) validate() error {
+	if r.CreatedAfter != nil && r.CreatedBefore != nil && !r.CreatedAfter.Before(*r.CreatedBefore) {
+		return utils.InvalidFieldError("createdBefore", "must be later than createdAfter")
+	}
+	if r.UpdatedAfter != nil && r.UpdatedBefore != nil && !r.UpdatedAfter.Before(*r.UpdatedBefore) {
+		return utils.InvalidFieldError("updatedBefore", "must be later than updatedAfter")
+	}
+	return nil
+}
+
+// dateCondition is one bound of a DateRange as a SQL comparison
+type dateCondition struct {
+	column   string
+	operator string
+	value    time.Time
+}
+
+// conditions returns the range's bounds as SQL comparisons
+func (r DateRange) conditions() []dateCondition {
+	var conditions []dateCondition
+	add := func(column, operator string, bound *time.Time) {
+		if bound != nil {
+			conditions = append(conditions, dateCondition{column: column, operator: operator, value: bound.UTC()})
+		}
+	}
+	add("created_at", ">=", r.CreatedAfter)
+	add("created_at", "<", r.CreatedBefore)
+	add("updated_at", ">=", r.UpdatedAfter)
+	add("updated_at", "<", r.UpdatedBefore)
+	return conditions
+}
+
+// apply adds the range's bounds to a query
+func (r DateRange) apply(query *gorm.DB) *gorm.DB {
+	for _, condition := range r.conditions() {
+		query = query.Where(fmt.Sprintf("%s %s ?", condition.column, condition.operator), condition.value)
+	}
+	return query
+}
+
+// appendSQL writes the range's bounds to filters as WHERE conditions with numbered
+// placeholders and returns args with their values appended
+func (r DateRange) appendSQL(filters *strings.Builder, args []interface{}) []interface{} {
+	for _, condition := range r.conditions() {
+		args = append(args, condition.value)
+		fmt.Fprintf(filters, " AND %s %s $%d", condition.column, condition.operator, len(args))
+	}
+	return args
+}
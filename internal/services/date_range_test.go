@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestParseDateBound(t *testing.T) {
+	bound, err := ParseDateBound("createdAfter", "2025-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *bound)
+
+	bound, err = ParseDateBound("createdAfter", "2025-03-01T09:30:00+02:00")
+	require.NoError(t, err)
+	assert.True(t, bound.Equal(time.Date(2025, 3, 1, 7, 30, 0, 0, time.UTC)))
+
+	bound, err = ParseDateBound("createdAfter", " ")
+	require.NoError(t, err)
+	assert.Nil(t, bound)
+
+	_, err = ParseDateBound("createdAfter", "last month")
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMemoryService_SearchDateRange(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	february := time.Date(2025, 2, 14, 12, 0, 0, 0, time.UTC)
+	march := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 2, 12, 0, 0, 0, time.UTC)
+	testutil.NewMemory().Content("project kickoff").Category(models.CategoryProject).CreatedAt(february).Create(t, service.db)
+	inMarch := testutil.NewMemory().Content("project deadline moved").Category(models.CategoryProject).CreatedAt(march).Create(t, service.db)
+	testutil.NewMemory().Content("project shipped").Category(models.CategoryProject).CreatedAt(april).Create(t, service.db)
+
+	marchOnly := DateRange{
+		CreatedAfter:  timePtr(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)),
+		CreatedBefore: timePtr(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	t.Run("keyword search", func(t *testing.T) {
+		memories, err := service.Search(ctx, SearchRequest{Query: "project", DateRange: marchOnly})
+		require.NoError(t, err)
+		require.Len(t, memories, 1)
+		assert.Equal(t, inMarch.ID, memories[0].ID)
+	})
+
+	t.Run("listing with a page count", func(t *testing.T) {
+		page, err := service.SearchPage(ctx, SearchRequest{Query: "*", DateRange: DateRange{UpdatedAfter: marchOnly.CreatedAfter}}, "")
+		require.NoError(t, err)
+		assert.Len(t, page.Memories, 2)
+		assert.Equal(t, int64(2), page.TotalCount)
+	})
+
+	t.Run("empty range is rejected", func(t *testing.T) {
+		_, err := service.Search(ctx, SearchRequest{Query: "project", DateRange: DateRange{
+			CreatedAfter:  marchOnly.CreatedBefore,
+			CreatedBefore: marchOnly.CreatedAfter,
+		}})
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MetadataDueAt is the metadata key holding the date a detected memory refers
+// to, such as "next Friday" in "remember that the report is due next Friday".
+// It is an RFC 3339 timestamp at midnight UTC.
+const MetadataDueAt = "due_at"
+
+// notADate marks day phrases that contain a relative date word without meaning
+// a date, such as Spanish "la mañana" (the morning) as opposed to "mañana"
+const notADate = -1
+
+// dueUnit is a duration unit of a relative date such as "in two weeks"
+type dueUnit int
+
+const (
+	unitDay dueUnit = iota
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+// dateLocale holds the words one language uses for relative dates
+type dateLocale struct {
+	// days are phrases for a day relative to today: 0 today, 1 tomorrow and so on
+	days map[string]int
+	// in precedes a duration: "in two weeks"
+	in []string
+	// next marks the coming weekday or unit, before or after it: "next Friday",
+	// "vendredi prochain", "next week"
+	next []string
+	// on marks a weekday as a date without meaning "next": "on Friday"
+	on       []string
+	weekdays map[string]time.Weekday
+	units    map[string]dueUnit
+	numbers  map[string]int
+}
+
+// dateLocales are the languages ParseRelativeDate understands: English, Spanish,
+// French, German, Dutch and Afrikaans. Accented words are also listed without
+// their accents, which are often dropped when typing, unless that spelling is a
+// common word elsewhere, as Afrikaans "more" is in English.
+var dateLocales = []dateLocale{
+	{ // English
+		days:     map[string]int{"today": 0, "tonight": 0, "tomorrow": 1, "day after tomorrow": 2},
+		in:       []string{"in", "within"},
+		next:     []string{"next", "coming"},
+		on:       []string{"on", "by", "this", "until", "before"},
+		weekdays: weekdayNames([7]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}, nil),
+		units: map[string]dueUnit{
+			"day": unitDay, "days": unitDay, "week": unitWeek, "weeks": unitWeek,
+			"fortnight": unitWeek, "month": unitMonth, "months": unitMonth, "year": unitYear, "years": unitYear,
+		},
+		numbers: map[string]int{
+			"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+			"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "couple": 2, "few": 3,
+		},
+	},
+	{ // Spanish
+		days: map[string]int{
+			"hoy": 0, "mañana": 1, "manana": 1, "pasado mañana": 2, "pasado manana": 2,
+			"la mañana": notADate, "la manana": notADate,
+		},
+		in:   []string{"en", "dentro"},
+		next: []string{"próximo", "proximo", "próxima", "proxima", "siguiente"},
+		on:   []string{"el", "este", "para", "hasta"},
+		weekdays: weekdayNames([7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+			map[string]time.Weekday{"miercoles": time.Wednesday, "sabado": time.Saturday}),
+		units: map[string]dueUnit{
+			"día": unitDay, "días": unitDay, "dia": unitDay, "dias": unitDay, "semana": unitWeek, "semanas": unitWeek,
+			"mes": unitMonth, "meses": unitMonth, "año": unitYear, "años": unitYear,
+		},
+		numbers: map[string]int{
+			"un": 1, "una": 1, "uno": 1, "dos": 2, "tres": 3, "cuatro": 4, "cinco": 5, "seis": 6,
+			"siete": 7, "ocho": 8, "nueve": 9, "diez": 10, "once": 11, "doce": 12,
+		},
+	},
+	{ // French
+		days:     map[string]int{"aujourd'hui": 0, "ce soir": 0, "demain": 1, "après demain": 2, "apres demain": 2},
+		in:       []string{"dans", "d'ici"},
+		next:     []string{"prochain", "prochaine"},
+		on:       []string{"ce", "avant", "jusqu'à"},
+		weekdays: weekdayNames([7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"}, nil),
+		units: map[string]dueUnit{
+			"jour": unitDay, "jours": unitDay, "semaine": unitWeek, "semaines": unitWeek,
+			"mois": unitMonth, "an": unitYear, "ans": unitYear, "année": unitYear, "années": unitYear,
+		},
+		numbers: map[string]int{
+			"un": 1, "une": 1, "deux": 2, "trois": 3, "quatre": 4, "cinq": 5, "six": 6,
+			"sept": 7, "huit": 8, "neuf": 9, "dix": 10, "onze": 11, "douze": 12, "quinze": 15,
+		},
+	},
+	{ // German
+		days: map[string]int{
+			"heute": 0, "morgen": 1, "übermorgen": 2, "uebermorgen": 2,
+			"guten morgen": notADate, "am morgen": notADate, "jeden morgen": notADate,
+		},
+		in:   []string{"in", "binnen"},
+		next: []string{"nächsten", "nächste", "nächster", "naechsten", "naechste", "kommenden", "kommende"},
+		on:   []string{"am", "bis", "diesen"},
+		weekdays: weekdayNames([7]string{"sonntag", "montag", "dienstag", "mittwoch", "donnerstag", "freitag", "samstag"},
+			map[string]time.Weekday{"sonnabend": time.Saturday}),
+		units: map[string]dueUnit{
+			"tag": unitDay, "tagen": unitDay, "tage": unitDay, "woche": unitWeek, "wochen": unitWeek,
+			"monat": unitMonth, "monaten": unitMonth, "jahr": unitYear, "jahren": unitYear,
+		},
+		numbers: map[string]int{
+			"einem": 1, "einer": 1, "ein": 1, "eine": 1, "zwei": 2, "drei": 3, "vier": 4, "fünf": 5, "sechs": 6,
+			"sieben": 7, "acht": 8, "neun": 9, "zehn": 10, "elf": 11, "zwölf": 12,
+		},
+	},
+	{ // Dutch
+		days:     map[string]int{"vandaag": 0, "vanavond": 0, "morgen": 1, "overmorgen": 2, "goede morgen": notADate},
+		in:       []string{"over", "binnen"},
+		next:     []string{"volgende", "komende", "aanstaande"},
+		on:       []string{"op", "voor", "tot"},
+		weekdays: weekdayNames([7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"}, nil),
+		units: map[string]dueUnit{
+			"dag": unitDay, "dagen": unitDay, "week": unitWeek, "weken": unitWeek,
+			"maand": unitMonth, "maanden": unitMonth, "jaar": unitYear, "jaren": unitYear,
+		},
+		numbers: map[string]int{
+			"een": 1, "één": 1, "twee": 2, "drie": 3, "vier": 4, "vijf": 5, "zes": 6,
+			"zeven": 7, "acht": 8, "negen": 9, "tien": 10, "elf": 11, "twaalf": 12,
+		},
+	},
+	{ // Afrikaans
+		days:     map[string]int{"vandag": 0, "vanaand": 0, "môre": 1, "oormôre": 2, "oormore": 2},
+		in:       []string{"oor", "binne"},
+		next:     []string{"volgende", "aanstaande"},
+		on:       []string{"op", "voor", "teen"},
+		weekdays: weekdayNames([7]string{"sondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrydag", "saterdag"}, nil),
+		units: map[string]dueUnit{
+			"dag": unitDay, "dae": unitDay, "week": unitWeek, "weke": unitWeek,
+			"maand": unitMonth, "maande": unitMonth, "jaar": unitYear, "jare": unitYear,
+		},
+		numbers: map[string]int{
+			"n": 1, "een": 1, "twee": 2, "drie": 3, "vier": 4, "vyf": 5, "ses": 6,
+			"sewe": 7, "agt": 8, "nege": 9, "tien": 10, "elf": 11, "twaalf": 12,
+		},
+	},
+}
+
+// weekdayNames maps the names of the weekdays, given from Sunday to Saturday,
+// to their weekdays, along with any alternative spellings
+func weekdayNames(names [7]string, alternatives map[string]time.Weekday) map[string]time.Weekday {
+	weekdays := make(map[string]time.Weekday, len(names)+len(alternatives))
+	for i, name := range names {
+		weekdays[name] = time.Weekday(i)
+	}
+	for name, weekday := range alternatives {
+		weekdays[name] = weekday
+	}
+	return weekdays
+}
+
+// ParseRelativeDate finds the first relative date in text, such as "tomorrow",
+// "next Friday", "in two weeks", "vendredi prochain" or "môre", and returns the
+// day it names as midnight UTC counted from now. It returns nil when text names
+// no date. A weekday alone is a date only with a word such as "next" or "on".
+func ParseRelativeDate(text string, now time.Time) *time.Time {
+	tokens := dateTokens(text)
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	for i := 0; i < len(tokens); i++ {
+		if skip := skipNotADate(tokens, i); skip > 0 {
+			i += skip - 1
+			continue
+		}
+		for _, locale := range dateLocales {
+			if due, ok := locale.match(tokens, i, today); ok {
+				return &due
+			}
+		}
+	}
+	return nil
+}
+
+// skipNotADate returns how many tokens at i form a phrase that only looks like a
+// date, or 0
+func skipNotADate(tokens []string, i int) int {
+	for _, locale := range dateLocales {
+		for phrase, days := range locale.days {
+			if days == notADate && hasPhrase(tokens, i, phrase) {
+				return len(strings.Fields(phrase))
+			}
+		}
+	}
+	return 0
+}
+
+// match reports the date named by the tokens starting at i in this locale
+func (l dateLocale) match(tokens []string, i int, today time.Time) (time.Time, bool) {
+	for _, phrase := range l.dayPhrases() {
+		if l.days[phrase] != notADate && hasPhrase(tokens, i, phrase) {
+			return today.AddDate(0, 0, l.days[phrase]), true
+		}
+	}
+
+	token := tokens[i]
+	if slices.Contains(l.in, token) && i+2 < len(tokens) {
+		if n, ok := l.number(tokens[i+1]); ok {
+			if unit, ok := l.units[tokens[i+2]]; ok {
+				return addUnits(today, unit, n), true
+			}
+		}
+	}
+
+	if i+1 < len(tokens) {
+		qualifier, subject := token, tokens[i+1]
+		if slices.Contains(l.next, subject) {
+			qualifier, subject = subject, token
+		}
+		if slices.Contains(l.next, qualifier) {
+			if unit, ok := l.units[subject]; ok {
+				return addUnits(today, unit, 1), true
+			}
+		}
+		if slices.Contains(l.next, qualifier) || slices.Contains(l.on, qualifier) {
+			if weekday, ok := l.weekdays[subject]; ok {
+				return nextWeekday(today, weekday), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayPhrases returns the locale's day phrases, longest first, so "day after
+// tomorrow" is found before "tomorrow"
+func (l dateLocale) dayPhrases() []string {
+	phrases := make([]string, 0, len(l.days))
+	for phrase := range l.days {
+		phrases = append(phrases, phrase)
+	}
+	sort.Slice(phrases, func(i, j int) bool {
+		if wi, wj := len(strings.Fields(phrases[i])), len(strings.Fields(phrases[j])); wi != wj {
+			return wi > wj
+		}
+		return phrases[i] < phrases[j]
+	})
+	return phrases
+}
+
+// number parses a count written as digits or as one of the locale's words
+func (l dateLocale) number(token string) (int, bool) {
+	if n, err := strconv.Atoi(token); err == nil && n > 0 && n <= 366 {
+		return n, true
+	}
+	n, ok := l.numbers[token]
+	return n, ok
+}
+
+// addUnits adds n units to day
+func addUnits(day time.Time, unit dueUnit, n int) time.Time {
+	switch unit {
+	case unitWeek:
+		return day.AddDate(0, 0, 7*n)
+	case unitMonth:
+		return day.AddDate(0, n, 0)
+	case unitYear:
+		return day.AddDate(n, 0, 0)
+	default:
+		return day.AddDate(0, 0, n)
+	}
+}
+
+// nextWeekday returns the first day after today falling on weekday
+func nextWeekday(today time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// hasPhrase reports whether the tokens starting at i spell phrase
+func hasPhrase(tokens []string, i int, phrase string) bool {
+	words := strings.Fields(phrase)
+	if i+len(words) > len(tokens) {
+		return false
+	}
+	for j, word := range words {
+		if tokens[i+j] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// dateTokens lowercases text and splits it into words. Apostrophes stay inside
+// words such as "aujourd'hui" but Afrikaans "'n" becomes "n".
+func dateTokens(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "’", "'")
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	words := tokens[:0]
+	for _, token := range tokens {
+		if token = strings.Trim(token, "'"); token != "" {
+			words = append(words, token)
+		}
+	}
+	return words
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRelativeDate(t *testing.T) {
+	// A Wednesday afternoon
+	now := time.Date(2025, 3, 12, 15, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d int) *time.Time {
+		return timePtr(time.Date(2025, month, d, 0, 0, 0, 0, time.UTC))
+	}
+
+	tests := []struct {
+		text string
+		want *time.Time
+	}{
+		{"remember that the report is due tomorrow", day(3, 13)},
+		{"the demo is the day after tomorrow", day(3, 14)},
+		{"remember that the invoice is due next Friday", day(3, 14)},
+		{"we ship on wednesday", day(3, 19)},
+		{"renew the domain in two weeks", day(3, 26)},
+		{"renew the domain in 3 days", day(3, 15)},
+		{"the lease ends in a month", day(4, 12)},
+		{"the offsite is next week", day(3, 19)},
+		{"la entrega es mañana", day(3, 13)},
+		{"la reunión es el próximo viernes", day(3, 14)},
+		{"recordar pagar en dos semanas", day(3, 26)},
+		{"le rendez-vous est vendredi prochain", day(3, 14)},
+		{"la livraison arrive après-demain", day(3, 14)},
+		{"die Abgabe ist in zwei Wochen", day(3, 26)},
+		{"das Treffen ist nächsten Montag", day(3, 17)},
+		{"de deadline is overmorgen", day(3, 14)},
+		{"die vergadering is môre", day(3, 13)},
+		{"betaal die huur oor 'n week", day(3, 19)},
+		{"I run every Friday", nil},
+		{"I need more time", nil},
+		{"me gusta correr por la mañana", nil},
+		{"my editor is helix", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseRelativeDate(tt.text, now))
+		})
+	}
+}
+
+func TestDetectMemoryPatterns_DueAt(t *testing.T) {
+	detected := DetectMemoryPatterns("remember that the tax return is due tomorrow")
+	require.NotEmpty(t, detected)
+	require.NotNil(t, detected[0].DueAt)
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	assert.Equal(t, tomorrow, *detected[0].DueAt)
+
+	service := setupMemoryService(t, nil)
+	stored, err := service.ProcessContentForMemory(context.Background(), "remember that the tax return is due tomorrow")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(stored[0].Metadata, &metadata))
+	assert.Equal(t, tomorrow.Format(time.RFC3339), metadata[MetadataDueAt])
+
+	detected = DetectMemoryPatterns("i prefer tabs over spaces")
+	require.NotEmpty(t, detected)
+	assert.Nil(t, detected[0].DueAt)
+}
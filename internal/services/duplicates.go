@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// What a store does with a near-duplicate of an existing memory
+const (
+	// DuplicateMerge keeps the existing memory, adding the new tags and metadata
+	// and raising its priority
+	DuplicateMerge = "merge"
+	// DuplicateUpdate replaces the existing memory's content, as a store with the
+	// same update key would
+	DuplicateUpdate = "update"
+	// DuplicateReject refuses the store
+	DuplicateReject = "reject"
+	// DuplicateAllow stores the memory regardless
+	DuplicateAllow = "allow"
+)
+
+// defaultDuplicateThreshold applies when duplicate_threshold is not configured
+const defaultDuplicateThreshold = 0.95
+
+// ErrDuplicateMemory is returned when a store is rejected as a near-duplicate
+var ErrDuplicateMemory = errors.New("memory is a near-duplicate of an existing memory")
+
+// DuplicateMemoryError reports a store rejected as a near-duplicate, with the
+// memory it matched
+type DuplicateMemoryError struct {
+	Memory     *models.Memory
+	Similarity float64
+}
+
+func (e *DuplicateMemoryError) Error() string {
+	return fmt.Sprintf("memory is a near-duplicate of memory %d (similarity %.3f)", e.Memory.ID, e.Similarity)
+}
+
+func (e *DuplicateMemoryError) Unwrap() error {
+	return ErrDuplicateMemory
+}
+
+// DuplicateMatch describes the existing memory a store was merged into or
+// updated, in place of creating a new one
+type DuplicateMatch struct {
+	MemoryID   uint    `json:"memory_id"`
+	Similarity float64 `json:"similarity"`
+	Action     string  `json:"action"`
+}
+
+// duplicateAction returns what to do with near-duplicates for a request, or ""
+// when they are not checked
+func (s *MemoryService) duplicateAction(req StoreRequest) (string, error) {
+	action := req.OnDuplicate
+	if action == "" {
+		action, _ = s.config["duplicate_action"].(string)
+	}
+	switch action {
+	case "", DuplicateAllow:
+		return "", nil
+	case DuplicateMerge, DuplicateUpdate, DuplicateReject:
+		return action, nil
+	default:
+		return "", utils.InvalidFieldError("on_duplicate", "must be merge, update, reject or allow")
+	}
+}
+
+// duplicateThreshold returns the configured near-duplicate similarity threshold
+func (s *MemoryService) duplicateThreshold() float64 {
+	if threshold, ok := s.config["duplicate_threshold"].(float64); ok && threshold > 0 {
+		return threshold
+	}
+	return defaultDuplicateThreshold
+}
+
+// nearestMemory returns the user's embedded memory most similar to a memory with
+// the given fields, and their cosine similarity. It finds nothing without
+// embeddings, and when the fields cannot be embedded, so a provider outage never
+// blocks a store.
+func (s *MemoryService) nearestMemory(ctx context.Context, fields EmbeddingFields) (uint, float64, error) {
+	// The sqlite schema used in tests has no vector type
+	if s.embedding == nil || s.db.Dialector.Name() == "sqlite" {
+		return 0, 0, nil
+	}
+
+	embedding, err := s.embedMemory(ctx, s.embedderFor(ctx, s.userID, false), fields)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to embed memory, skipping similarity check")
+		return 0, 0, nil
+	}
+
+	var match struct {
+		ID         uint
+		Similarity float64
+	}
+	var filters strings.Builder
+	args := s.workspaceFilter(&filters, []interface{}{pgvector.NewVector(embedding), s.userID})
+	if err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT id, 1 - (embedding <=> $1) AS similarity
+		FROM memories
+		WHERE user_id = $2 AND embedding IS NOT NULL%s
+		ORDER BY embedding <=> $1
+		LIMIT 1
+	`, filters.String()), args...).Scan(&match).Error; err != nil {
+		return 0, 0, err
+	}
+	return match.ID, match.Similarity, nil
+}
+
+// resolveNearDuplicate applies the near-duplicate action to a store that matched
+// no memory exactly, recording any match in the outcome. It returns the memory the
+// store should update for the update action, or the merged memory for merge.
+func (s *MemoryService) resolveNearDuplicate(ctx context.Context, req StoreRequest, outcome *storeOutcome) (*models.Memory, error) {
+	action, err := s.duplicateAction(req)
+	if err != nil || action == "" {
+		return nil, err
+	}
+
+	id, similarity, err := s.nearestMemory(ctx, EmbeddingFields{
+		Content:  req.Content,
+		Tags:     req.Tags,
+		Category: req.Category,
+		Type:     req.Type,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to check for near-duplicate memory")
+		return nil, utils.WrapDatabaseError("check for near-duplicate memory", err)
+	}
+	if id == 0 || similarity < s.duplicateThreshold() {
+		return nil, nil
+	}
+
+	var memory *models.Memory
+	switch action {
+	case DuplicateReject:
+		return nil, s.rejectDuplicate(ctx, id, similarity)
+	case DuplicateMerge:
+		memory, err = s.mergeDuplicate(ctx, id, req)
+	default:
+		memory, err = s.loadForUpdate(ctx, id)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || utils.IsNotFoundError(err) {
+		// Deleted since the check; store as a new memory
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Uint("id", id).Str("action", action).Msg("failed to resolve near-duplicate memory")
+		return nil, utils.WrapDatabaseError("resolve near-duplicate memory", err)
+	}
+
+	s.logger.Info().
+		Uint("id", id).
+		Float64("similarity", similarity).
+		Str("action", action).
+		Msg("store matched a near-duplicate memory")
+	outcome.duplicate = &DuplicateMatch{MemoryID: id, Similarity: similarity, Action: action}
+	return memory, nil
+}
+
+// loadForUpdate loads one of the user's memories as stored, without decrypting it
+func (s *MemoryService) loadForUpdate(ctx context.Context, id uint) (*models.Memory, error) {
+	query := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, s.userID)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+
+	var memory models.Memory
+	if err := query.First(&memory).Error; err != nil {
+		return nil, err
+	}
+	return &memory, nil
+}
+
+// mergeDuplicate folds a store into the near-duplicate it matched: the existing
+// content is kept, the new tags and metadata are added and the higher priority
+// wins
+func (s *MemoryService) mergeDuplicate(ctx context.Context, id uint, req StoreRequest) (*models.Memory, error) {
+	memory, err := s.loadForNeighbors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := append([]string(nil), memory.Tags...)
+	for _, tag := range req.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	metadata := map[string]interface{}{}
+	if len(memory.Metadata) > 0 {
+		if err := json.Unmarshal(memory.Metadata, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+	}
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, utils.WrapValidationError("metadata", "invalid metadata format")
+	}
+
+	priority := memory.Priority
+	if priorityRank(req.Priority) > priorityRank(priority) {
+		priority = req.Priority
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"tags":       pq.StringArray(tags),
+		"metadata":   json.RawMessage(metadataJSON),
+		"priority":   priority,
+		"updated_at": now,
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("id = ? AND user_id = ?", id, s.userID).
+		UpdateColumns(updates).Error; err != nil {
+		return nil, err
+	}
+
+	memory.Tags = tags
+	memory.Metadata = json.RawMessage(metadataJSON)
+	memory.Priority = priority
+	memory.UpdatedAt = now
+	return memory, nil
+}
+
+// rejectDuplicate returns the error refusing a store that matched a memory
+func (s *MemoryService) rejectDuplicate(ctx context.Context, id uint, similarity float64) error {
+	memory, err := s.loadForNeighbors(ctx, id)
+	if utils.IsNotFoundError(err) {
+		// Deleted since the check; nothing to conflict with
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &DuplicateMemoryError{Memory: memory, Similarity: similarity}
+}
+
+// priorityRank orders priorities from low to critical
+func priorityRank(priority string) int {
+	switch priority {
+	case models.PriorityLow:
+		return 1
+	case models.PriorityMedium:
+		return 2
+	case models.PriorityHigh:
+		return 3
+	case models.PriorityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestDuplicateAction(t *testing.T) {
+	service := setupMemoryService(t, nil)
+	action, err := service.duplicateAction(StoreRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, action)
+
+	service = setupMemoryService(t, map[string]interface{}{"duplicate_action": DuplicateReject})
+	action, err = service.duplicateAction(StoreRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateReject, action)
+
+	// Requests override the configured action, including turning the check off
+	action, err = service.duplicateAction(StoreRequest{OnDuplicate: DuplicateMerge})
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateMerge, action)
+	action, err = service.duplicateAction(StoreRequest{OnDuplicate: DuplicateAllow})
+	require.NoError(t, err)
+	assert.Empty(t, action)
+
+	_, err = service.duplicateAction(StoreRequest{OnDuplicate: "ignore"})
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMergeDuplicate(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, err := service.Store(ctx, StoreRequest{
+		Content:  "I drink green tea every morning",
+		Category: models.CategoryPersonal,
+		Type:     models.TypePreference,
+		Priority: models.PriorityMedium,
+		Metadata: map[string]interface{}{"source": "chat"},
+	})
+	require.NoError(t, err)
+
+	merged, err := service.mergeDuplicate(ctx, memory.ID, StoreRequest{
+		Content:  "Every morning I drink green tea",
+		Priority: models.PriorityHigh,
+		Metadata: map[string]interface{}{"confidence": 0.9},
+	})
+	require.NoError(t, err)
+
+	// The existing content is kept, metadata is combined and the priority raised
+	assert.Equal(t, memory.ID, merged.ID)
+	assert.Equal(t, "I drink green tea every morning", merged.Content)
+	assert.Equal(t, models.PriorityHigh, merged.Priority)
+
+	stored, err := service.GetByID(ctx, memory.ID)
+	require.NoError(t, err)
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(stored.Metadata, &metadata))
+	assert.Equal(t, map[string]interface{}{"source": "chat", "confidence": 0.9}, metadata)
+	assert.Equal(t, models.PriorityHigh, stored.Priority)
+
+	// A lower priority never demotes the memory
+	merged, err = service.mergeDuplicate(ctx, memory.ID, StoreRequest{Priority: models.PriorityLow})
+	require.NoError(t, err)
+	assert.Equal(t, models.PriorityHigh, merged.Priority)
+}
+
+func TestRejectDuplicate(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "My flight to Lisbon leaves at 9am")
+
+	err := service.rejectDuplicate(ctx, memory.ID, 0.97)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrDuplicateMemory))
+	var duplicateErr *DuplicateMemoryError
+	require.True(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, memory.ID, duplicateErr.Memory.ID)
+	assert.Equal(t, "My flight to Lisbon leaves at 9am", duplicateErr.Memory.Content)
+
+	// A match deleted since the check no longer conflicts
+	assert.NoError(t, service.rejectDuplicate(ctx, memory.ID+100, 0.97))
+}
+
+func TestStore_NearDuplicateCheckNeedsEmbeddings(t *testing.T) {
+	// Without embeddings there is nothing to compare, so stores go ahead
+	service := setupMemoryService(t, map[string]interface{}{"duplicate_action": DuplicateReject})
+	first, _ := storeTestMemory(t, service, "The office wifi password is on the fridge")
+
+	result, err := service.StoreWithResult(context.Background(), StoreRequest{
+		Content:  "The wifi password for the office is on the fridge",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, result.Memory.ID)
+	assert.Nil(t, result.Duplicate)
+}
@@ -16,6 +16,9 @@ const (
 type EmbeddingService interface {
 	// GenerateEmbedding generates an embedding vector for the given text
 	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	// GenerateEmbeddings generates embedding vectors for several texts at once,
+	// in the same order as the texts
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
 }
 
 // MockEmbeddingService is a mock implementation of EmbeddingService for testing
@@ -26,6 +29,11 @@ func NewMockEmbeddingService() *MockEmbeddingService {
 	return &MockEmbeddingService{}
 }
 
+// GetModel returns the name reported for mock embeddings
+func (m *MockEmbeddingService) GetModel() string {
+	return "mock"
+}
+
 // GenerateEmbedding generates a deterministic embedding based on text hash
 func (m *MockEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if text == "" {
@@ -83,6 +91,19 @@ func (m *MockEmbeddingService) GenerateEmbedding(ctx context.Context, text strin
 	return embedding, nil
 }
 
+// GenerateEmbeddings generates deterministic embeddings for each text
+func (m *MockEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := m.GenerateEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
 // Ensure MockEmbeddingService implements EmbeddingService
 var _ EmbeddingService = (*MockEmbeddingService)(nil)
 
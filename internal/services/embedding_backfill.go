@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// EmbeddingBackfillConfig tunes the embedding backfill worker
+type EmbeddingBackfillConfig struct {
+	// Interval is how often the worker scans for missing embeddings
+	Interval time.Duration
+	// BatchSize caps how many jobs are enqueued and processed per run
+	BatchSize int
+	// MaxAttempts is how many failures a job tolerates before it is marked failed
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failure; it doubles on each retry
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Grace leaves freshly written memories to the inline embedding goroutine
+	Grace time.Duration
+}
+
+// DefaultEmbeddingBackfillConfig returns the default backfill settings
+func DefaultEmbeddingBackfillConfig() EmbeddingBackfillConfig {
+	return EmbeddingBackfillConfig{
+		Interval:    time.Minute,
+		BatchSize:   50,
+		MaxAttempts: 10,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		Grace:       5 * time.Minute,
+	}
+}
+
+// EmbeddingBackfillReport summarises a single backfill run
+type EmbeddingBackfillReport struct {
+	Enqueued  int64
+	Succeeded int
+	Retried   int
+	Failed    int
+}
+
+// EmbeddingBackfillWorker periodically generates embeddings for memories that are
+// missing one, retrying failures with exponential backoff. Jobs are stored in the
+// embedding_jobs table so they survive restarts. It runs across all users.
+type EmbeddingBackfillWorker struct {
+	service *MemoryService
+	config  EmbeddingBackfillConfig
+}
+
+// NewEmbeddingBackfillWorker creates a worker that uses the service's database,
+// embedding and encryption services
+func NewEmbeddingBackfillWorker(service *MemoryService, config EmbeddingBackfillConfig) *EmbeddingBackfillWorker {
+	return &EmbeddingBackfillWorker{
+		service: service,
+		config:  config,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *EmbeddingBackfillWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.service.logger.Error().Err(err).Msg("embedding backfill run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce enqueues memories that are missing embeddings and processes the jobs
+// that are due
+func (w *EmbeddingBackfillWorker) RunOnce(ctx context.Context) (*EmbeddingBackfillReport, error) {
+	report := &EmbeddingBackfillReport{}
+	if w.service.embedding == nil {
+		return report, nil
+	}
+
+	enqueued, err := w.enqueueMissing(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Enqueued = enqueued
+
+	var jobs []models.EmbeddingJob
+	if err := w.service.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.EmbeddingJobPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(w.config.BatchSize).
+		Find(&jobs).Error; err != nil {
+		return report, fmt.Errorf("failed to load embedding jobs: %w", err)
+	}
+
+	for i := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		job := &jobs[i]
+		if err := w.process(ctx, job); err != nil {
+			if failed := w.recordFailure(ctx, job, err); failed {
+				report.Failed++
+			} else {
+				report.Retried++
+			}
+			continue
+		}
+		report.Succeeded++
+	}
+
+	if report.Enqueued > 0 || len(jobs) > 0 {
+		w.service.logger.Info().
+			Int64("enqueued", report.Enqueued).
+			Int("succeeded", report.Succeeded).
+			Int("retried", report.Retried).
+			Int("failed", report.Failed).
+			Msg("embedding backfill run completed")
+	}
+	return report, nil
+}
+
+// enqueueMissing creates jobs for memories without an embedding that have no job yet
+func (w *EmbeddingBackfillWorker) enqueueMissing(ctx context.Context) (int64, error) {
+	now := time.Now()
+	result := w.service.db.WithContext(ctx).Exec(`
+		INSERT INTO embedding_jobs (memory_id, user_id, status, attempts, next_attempt_at, created_at, updated_at)
+		SELECT m.id, m.user_id, ?, 0, ?, ?, ?
+		FROM memories m
+		WHERE m.embedding IS NULL
+			AND m.updated_at < ?
+			AND NOT EXISTS (SELECT 1 FROM embedding_jobs j WHERE j.memory_id = m.id)
+		ORDER BY m.id
+		LIMIT ?`,
+		models.EmbeddingJobPending, now, now, now, now.Add(-w.config.Grace), w.config.BatchSize,
+	)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to enqueue embedding jobs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// process generates and stores the embedding for a job's memory. Jobs whose memory
+// was deleted or already embedded are dropped.
+func (w *EmbeddingBackfillWorker) process(ctx context.Context, job *models.EmbeddingJob) error {
+	db := w.service.db.WithContext(ctx)
+
+	// Tags are part of the embedded document when a composer includes them
+	omit := []string{"embedding"}
+	if db.Dialector.Name() == "sqlite" {
+		omit = append(omit, "tags")
+	}
+
+	var memory models.Memory
+	err := memoryRow(db.Omit(omit...), job.MemoryID, job.UserID).
+		Where("embedding IS NULL").
+		First(&memory).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Delete(job).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load memory: %w", err)
+	}
+
+	if err := w.service.decryptContent(&memory); err != nil {
+		return err
+	}
+
+	embedder := w.service.embedderFor(ctx, memory.UserID, false)
+	embedding, err := w.service.embedMemory(ctx, embedder, embeddingFieldsOf(&memory, memory.Content))
+	if err != nil {
+		return err
+	}
+	chunks, err := w.service.embedChunks(ctx, embedder, memory.ID, memory.UserID, memory.Content)
+	if err != nil {
+		return err
+	}
+
+	if err := w.service.saveEmbedding(ctx, memory.ID, memory.UserID, embedding, chunks); err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+
+	return db.Delete(job).Error
+}
+
+// recordFailure schedules the next attempt, or marks the job failed once it has
+// used up its attempts. It reports whether the job is now failed.
+func (w *EmbeddingBackfillWorker) recordFailure(ctx context.Context, job *models.EmbeddingJob, cause error) bool {
+	job.Attempts++
+	job.LastError = cause.Error()
+	failed := job.Attempts >= w.config.MaxAttempts
+	if failed {
+		job.Status = models.EmbeddingJobFailed
+	} else {
+		job.NextAttemptAt = time.Now().Add(w.backoff(job.Attempts))
+	}
+
+	w.service.logger.Warn().Err(cause).
+		Uint("memory_id", job.MemoryID).
+		Int("attempts", job.Attempts).
+		Bool("failed", failed).
+		Msg("embedding backfill attempt failed")
+
+	if err := w.service.db.WithContext(ctx).Save(job).Error; err != nil {
+		w.service.logger.Error().Err(err).Uint("memory_id", job.MemoryID).Msg("failed to update embedding job")
+	}
+	return failed
+}
+
+// backoff returns the delay before the given retry, doubling from BaseBackoff up
+// to MaxBackoff
+func (w *EmbeddingBackfillWorker) backoff(attempts int) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= w.config.MaxBackoff {
+			return w.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// enqueueEmbeddingJob records a failed inline embedding so the backfill worker
+// retries it
+func (s *MemoryService) enqueueEmbeddingJob(ctx context.Context, memoryID uint, cause error) {
+	job := &models.EmbeddingJob{
+		MemoryID:      memoryID,
+		UserID:        s.userID,
+		Status:        models.EmbeddingJobPending,
+		NextAttemptAt: time.Now(),
+		LastError:     cause.Error(),
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(job).Error; err != nil {
+		s.logger.Error().Err(err).Uint("memory_id", memoryID).Msg("failed to enqueue embedding job")
+	}
+}
+
+// EmbeddingBackfillProgress reports how many of the user's memories are still
+// waiting for an embedding and the state of their backfill jobs
+func (s *MemoryService) EmbeddingBackfillProgress(ctx context.Context) (map[string]interface{}, error) {
+	db := s.db.WithContext(ctx)
+
+	var missing int64
+	if err := db.Model(&models.Memory{}).
+		Where("user_id = ? AND embedding IS NULL", s.userID).
+		Count(&missing).Error; err != nil {
+		return nil, fmt.Errorf("failed to count memories without embeddings: %w", err)
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&models.EmbeddingJob{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", s.userID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count embedding jobs: %w", err)
+	}
+
+	progress := map[string]interface{}{
+		"missing":                  missing,
+		models.EmbeddingJobPending: int64(0),
+		models.EmbeddingJobFailed:  int64(0),
+	}
+	for _, row := range rows {
+		progress[row.Status] = row.Count
+	}
+
+	var next models.EmbeddingJob
+	err := db.Where("user_id = ? AND status = ?", s.userID, models.EmbeddingJobPending).
+		Order("next_attempt_at ASC").
+		Limit(1).
+		Find(&next).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load next embedding job: %w", err)
+	}
+	if next.ID != 0 {
+		progress["next_attempt_at"] = next.NextAttemptAt
+	}
+
+	return progress, nil
+}
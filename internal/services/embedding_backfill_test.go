@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// failingEmbeddingService always fails to generate embeddings
+type failingEmbeddingService struct{}
+
+func (failingEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func (failingEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestEmbeddingBackfillWorker_Backoff(t *testing.T) {
+	worker := NewEmbeddingBackfillWorker(nil, EmbeddingBackfillConfig{
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  5 * time.Minute,
+	})
+
+	assert.Equal(t, 30*time.Second, worker.backoff(1))
+	assert.Equal(t, time.Minute, worker.backoff(2))
+	assert.Equal(t, 4*time.Minute, worker.backoff(4))
+	assert.Equal(t, 5*time.Minute, worker.backoff(5))
+	assert.Equal(t, 5*time.Minute, worker.backoff(20))
+}
+
+func TestEmbeddingBackfillWorker_RetriesUntilEmbedded(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.EmbeddingJob{}))
+	memory, _ := storeTestMemory(t, service, "waiting for an embedding")
+
+	config := DefaultEmbeddingBackfillConfig()
+	config.Grace = 0
+	config.MaxAttempts = 2
+	worker := NewEmbeddingBackfillWorker(service, config)
+
+	// A failing provider leaves the job queued with a backoff
+	service.embedding = failingEmbeddingService{}
+	report, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Enqueued)
+	assert.Equal(t, 1, report.Retried)
+
+	var job models.EmbeddingJob
+	require.NoError(t, service.db.Where("memory_id = ?", memory.ID).First(&job).Error)
+	assert.Equal(t, models.EmbeddingJobPending, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "provider unavailable", job.LastError)
+	assert.True(t, job.NextAttemptAt.After(time.Now()))
+
+	progress, err := service.EmbeddingBackfillProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), progress["missing"])
+	assert.Equal(t, int64(1), progress[models.EmbeddingJobPending])
+
+	// Jobs that are not yet due are skipped
+	report, err = worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Enqueued)
+	assert.Zero(t, report.Retried)
+
+	// Once due and the provider recovers the embedding is stored and the job removed
+	service.embedding = NewMockEmbeddingService()
+	require.NoError(t, service.db.Model(&job).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	report, err = worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Succeeded)
+
+	var jobs int64
+	service.db.Model(&models.EmbeddingJob{}).Count(&jobs)
+	assert.Zero(t, jobs)
+
+	var missing int64
+	service.db.Model(&models.Memory{}).Where("embedding IS NULL").Count(&missing)
+	assert.Zero(t, missing)
+}
+
+func TestEmbeddingBackfillWorker_MarksFailedAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.EmbeddingJob{}))
+	storeTestMemory(t, service, "never embeds")
+
+	config := DefaultEmbeddingBackfillConfig()
+	config.Grace = 0
+	config.MaxAttempts = 1
+	worker := NewEmbeddingBackfillWorker(service, config)
+	service.embedding = failingEmbeddingService{}
+
+	report, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+
+	progress, err := service.EmbeddingBackfillProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), progress[models.EmbeddingJobFailed])
+	assert.Equal(t, int64(0), progress[models.EmbeddingJobPending])
+
+	// Failed jobs are neither retried nor re-enqueued
+	report, err = worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Enqueued)
+	assert.Zero(t, report.Failed)
+}
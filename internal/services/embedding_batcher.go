@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultEmbeddingBatchSize is the most texts sent in one coalesced request
+	DefaultEmbeddingBatchSize = 100
+	// DefaultEmbeddingBatchWindow is how long the first text in a batch waits for
+	// others to join it
+	DefaultEmbeddingBatchWindow = 50 * time.Millisecond
+	// embeddingBatchTimeout bounds a flush, which runs detached from any caller
+	embeddingBatchTimeout = 2 * time.Minute
+)
+
+// Ensure EmbeddingBatcher implements EmbeddingService
+var _ EmbeddingService = (*EmbeddingBatcher)(nil)
+
+// EmbeddingBatcher coalesces embedding requests made close together into single
+// GenerateEmbeddings calls. Stores embed their memory in the background, so a bulk
+// store of N memories becomes one provider request instead of N. A batch is sent
+// when it is full or when its window ends, whichever comes first.
+//
+// One batcher is shared by every memory service so requests from concurrent
+// stores, including other users', coalesce too.
+type EmbeddingBatcher struct {
+	embedding EmbeddingService
+	batchSize int
+	window    time.Duration
+	logger    zerolog.Logger
+
+	mu      sync.Mutex
+	pending []*pendingEmbedding
+	timer   *time.Timer
+}
+
+// pendingEmbedding is a text waiting for its batch to be sent
+type pendingEmbedding struct {
+	text      string
+	done      chan struct{}
+	embedding []float32
+	err       error
+}
+
+// NewEmbeddingBatcher creates a batcher in front of an embedding service. Zero
+// values use DefaultEmbeddingBatchSize and DefaultEmbeddingBatchWindow.
+func NewEmbeddingBatcher(embedding EmbeddingService, batchSize int, window time.Duration, logger zerolog.Logger) *EmbeddingBatcher {
+	if batchSize <= 0 {
+		batchSize = DefaultEmbeddingBatchSize
+	}
+	if window <= 0 {
+		window = DefaultEmbeddingBatchWindow
+	}
+	return &EmbeddingBatcher{
+		embedding: embedding,
+		batchSize: batchSize,
+		window:    window,
+		logger:    logger.With().Str("service", "embedding_batcher").Logger(),
+	}
+}
+
+// GetModel returns the model of the underlying embedding service, if it reports one
+func (b *EmbeddingBatcher) GetModel() string {
+	if namer, ok := b.embedding.(interface{ GetModel() string }); ok {
+		return namer.GetModel()
+	}
+	return ""
+}
+
+// GenerateEmbedding queues the text and waits for the batch it joins to be sent
+func (b *EmbeddingBatcher) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	pending := &pendingEmbedding{text: text, done: make(chan struct{})}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, pending)
+	if len(b.pending) >= b.batchSize {
+		batch := b.takeLocked()
+		b.mu.Unlock()
+		go b.send(batch)
+	} else {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.window, b.flush)
+		}
+		b.mu.Unlock()
+	}
+
+	select {
+	case <-pending.done:
+		return pending.embedding, pending.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GenerateEmbeddings passes texts that are already batched straight through
+func (b *EmbeddingBatcher) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return b.embedding.GenerateEmbeddings(ctx, texts)
+}
+
+// flush sends whatever is pending; it runs when a batch window ends
+func (b *EmbeddingBatcher) flush() {
+	b.mu.Lock()
+	batch := b.takeLocked()
+	b.mu.Unlock()
+	b.send(batch)
+}
+
+// takeLocked removes and returns the pending batch. The caller holds b.mu.
+func (b *EmbeddingBatcher) takeLocked() []*pendingEmbedding {
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+// send embeds a batch with one request and hands each waiter its result
+func (b *EmbeddingBatcher) send(batch []*pendingEmbedding) {
+	if len(batch) == 0 {
+		return
+	}
+
+	texts := make([]string, len(batch))
+	for i, pending := range batch {
+		texts[i] = pending.text
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), embeddingBatchTimeout)
+	defer cancel()
+
+	start := time.Now()
+	embeddings, err := b.embedding.GenerateEmbeddings(ctx, texts)
+	if err == nil && len(embeddings) != len(batch) {
+		err = fmt.Errorf("expected %d embeddings, got %d", len(batch), len(embeddings))
+	}
+	if err != nil {
+		b.logger.Warn().Err(err).Int("batch_size", len(batch)).Msg("failed to generate batched embeddings")
+	} else {
+		b.logger.Debug().Int("batch_size", len(batch)).Dur("duration", time.Since(start)).Msg("generated batched embeddings")
+	}
+
+	for i, pending := range batch {
+		if err != nil {
+			pending.err = err
+		} else {
+			pending.embedding = embeddings[i]
+		}
+		close(pending.done)
+	}
+}
+
+// GetEmbeddingBatcher returns the shared embedding batcher, if one is configured
+func (s *MemoryService) GetEmbeddingBatcher() *EmbeddingBatcher {
+	batcher, _ := s.config["embedding_batcher"].(*EmbeddingBatcher)
+	return batcher
+}
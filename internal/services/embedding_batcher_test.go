@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbeddingService records the batches it is asked to embed
+type countingEmbeddingService struct {
+	MockEmbeddingService
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (c *countingEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	c.mu.Lock()
+	c.batches = append(c.batches, append([]string(nil), texts...))
+	c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.MockEmbeddingService.GenerateEmbeddings(ctx, texts)
+}
+
+func (c *countingEmbeddingService) batchSizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sizes := make([]int, len(c.batches))
+	for i, batch := range c.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+// embedConcurrently embeds n distinct texts from separate goroutines, as
+// concurrent stores do
+func embedConcurrently(t *testing.T, batcher *EmbeddingBatcher, n int) ([][]float32, []error) {
+	embeddings := make([][]float32, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			embeddings[i], errs[i] = batcher.GenerateEmbedding(context.Background(), fmt.Sprintf("memory %d", i))
+		}(i)
+	}
+	wg.Wait()
+	return embeddings, errs
+}
+
+func TestEmbeddingBatcher_CoalescesWithinWindow(t *testing.T) {
+	provider := &countingEmbeddingService{}
+	batcher := NewEmbeddingBatcher(provider, 100, 100*time.Millisecond, zerolog.Nop())
+
+	embeddings, errs := embedConcurrently(t, batcher, 10)
+
+	assert.Equal(t, []int{10}, provider.batchSizes())
+	mock := NewMockEmbeddingService()
+	for i := range embeddings {
+		require.NoError(t, errs[i])
+		want, err := mock.GenerateEmbedding(context.Background(), fmt.Sprintf("memory %d", i))
+		require.NoError(t, err)
+		assert.Equal(t, want, embeddings[i], "embedding %d went to the wrong caller", i)
+	}
+}
+
+func TestEmbeddingBatcher_SendsFullBatchesImmediately(t *testing.T) {
+	provider := &countingEmbeddingService{}
+	batcher := NewEmbeddingBatcher(provider, 4, time.Hour, zerolog.Nop())
+
+	_, errs := embedConcurrently(t, batcher, 8)
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{4, 4}, provider.batchSizes())
+}
+
+func TestEmbeddingBatcher_ReportsErrorsToEveryCaller(t *testing.T) {
+	provider := &countingEmbeddingService{err: errors.New("provider unavailable")}
+	batcher := NewEmbeddingBatcher(provider, 100, 20*time.Millisecond, zerolog.Nop())
+
+	_, errs := embedConcurrently(t, batcher, 3)
+
+	for _, err := range errs {
+		assert.EqualError(t, err, "provider unavailable")
+	}
+}
+
+func TestEmbeddingBatcher_CallerCancellation(t *testing.T) {
+	batcher := NewEmbeddingBatcher(&countingEmbeddingService{}, 100, time.Hour, zerolog.Nop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := batcher.GenerateEmbedding(ctx, "never sent")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
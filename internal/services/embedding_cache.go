@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// embeddingCacheEnabled reports whether memory and query embeddings are looked up
+// in the embedding cache before asking the provider. The cache is on unless
+// embedding_cache is set to false.
+func (s *MemoryService) embeddingCacheEnabled() bool {
+	enabled, ok := s.config["embedding_cache"].(bool)
+	return !ok || enabled
+}
+
+// EmbeddingCacheStats reports how much the embedding cache saved. Each entry cost
+// one provider call; each hit is a call saved.
+type EmbeddingCacheStats struct {
+	Enabled bool  `json:"enabled"`
+	Entries int64 `json:"entries"`
+	Hits    int64 `json:"hits"`
+	// HitRate is the share of embeddings answered from the cache
+	HitRate float64 `json:"hit_rate"`
+}
+
+// EmbeddingCacheStats returns the size and hits of the user's embedding cache
+// entries
+func (s *MemoryService) EmbeddingCacheStats(ctx context.Context) (*EmbeddingCacheStats, error) {
+	return s.embeddingCacheStats(ctx, s.db.WithContext(ctx).Where("user_id = ?", s.userID))
+}
+
+// AllUsersEmbeddingCacheStats returns the size and hits of the embedding cache
+// across every user. It is for administrators only.
+func (s *MemoryService) AllUsersEmbeddingCacheStats(ctx context.Context) (*EmbeddingCacheStats, error) {
+	return s.embeddingCacheStats(ctx, s.db.WithContext(ctx))
+}
+
+// embeddingCacheStats totals the embedding cache entries matched by query
+func (s *MemoryService) embeddingCacheStats(ctx context.Context, query *gorm.DB) (*EmbeddingCacheStats, error) {
+	stats := &EmbeddingCacheStats{Enabled: s.embeddingCacheEnabled()}
+	var totals struct {
+		Entries int64
+		Hits    int64
+	}
+	if err := query.Model(&models.EmbeddingCacheEntry{}).
+		Select("COUNT(*) AS entries, COALESCE(SUM(hits), 0) AS hits").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	stats.Entries, stats.Hits = totals.Entries, totals.Hits
+	if lookups := totals.Entries + totals.Hits; lookups > 0 {
+		stats.HitRate = float64(totals.Hits) / float64(lookups)
+	}
+	return stats, nil
+}
+
+// cachedEmbedder puts the embedding cache in front of an embedder. Embeddings are
+// cached per user and model, so nothing is cached when the model is unknown.
+func (s *MemoryService) cachedEmbedder(embedder EmbeddingService) EmbeddingService {
+	model := s.EmbeddingModel()
+	if !s.embeddingCacheEnabled() || model == "" {
+		return embedder
+	}
+	return &cachingEmbedder{EmbeddingService: embedder, service: s, model: model}
+}
+
+// cachingEmbedder answers embedding requests from the embedding cache and only
+// sends the texts it has not seen to the wrapped embedder. Idempotent retries,
+// re-imports and the duplicate check followed by the store's own embedding then
+// cost one provider call per distinct text. Cache failures fall back to the
+// provider, so the cache can never fail a store.
+type cachingEmbedder struct {
+	EmbeddingService
+	service *MemoryService
+	model   string
+}
+
+// GenerateEmbedding returns the cached embedding of text or generates and caches it
+func (e *cachingEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if cached := e.lookup(ctx, []string{text}); cached[0] != nil {
+		return cached[0], nil
+	}
+
+	embedding, err := e.EmbeddingService.GenerateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	e.store(ctx, []string{text}, [][]float32{embedding})
+	return embedding, nil
+}
+
+// GenerateEmbeddings returns the cached embeddings and generates the rest in a
+// single request, sending each distinct text once
+func (e *cachingEmbedder) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := e.lookup(ctx, texts)
+
+	var missing []string
+	missingAt := make(map[string][]int)
+	for i, embedding := range embeddings {
+		if embedding != nil {
+			continue
+		}
+		if _, ok := missingAt[texts[i]]; !ok {
+			missing = append(missing, texts[i])
+		}
+		missingAt[texts[i]] = append(missingAt[texts[i]], i)
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	generated, err := e.EmbeddingService.GenerateEmbeddings(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(generated) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(generated))
+	}
+	for i, text := range missing {
+		for _, at := range missingAt[text] {
+			embeddings[at] = generated[i]
+		}
+	}
+	e.store(ctx, missing, generated)
+	return embeddings, nil
+}
+
+// lookup returns the cached embedding of each text, or nil where there is none
+func (e *cachingEmbedder) lookup(ctx context.Context, texts []string) [][]float32 {
+	embeddings := make([][]float32, len(texts))
+	hashes := make([]string, len(texts))
+	for i, text := range texts {
+		hash, err := e.service.contentHash(text)
+		if err != nil {
+			e.service.logger.Debug().Err(err).Msg("failed to hash text for the embedding cache, generating embeddings")
+			return embeddings
+		}
+		hashes[i] = hash
+	}
+
+	var entries []models.EmbeddingCacheEntry
+	if err := e.service.db.WithContext(ctx).
+		Where("user_id = ? AND model = ? AND text_hash IN ?", e.service.userID, e.model, hashes).
+		Find(&entries).Error; err != nil {
+		e.service.logger.Debug().Err(err).Msg("embedding cache lookup failed, generating embeddings")
+		return embeddings
+	}
+	if len(entries) == 0 {
+		return embeddings
+	}
+
+	byHash := make(map[string][]float32, len(entries))
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		vector, err := decodeVector(entry.Vector, entry.Dimensions)
+		if err != nil {
+			e.service.logger.Warn().Err(err).Uint("entry_id", entry.ID).Msg("ignoring corrupt embedding cache entry")
+			continue
+		}
+		byHash[entry.TextHash] = vector
+		ids = append(ids, entry.ID)
+	}
+	for i, hash := range hashes {
+		embeddings[i] = byHash[hash]
+	}
+
+	if len(ids) > 0 {
+		if err := e.service.db.WithContext(ctx).Model(&models.EmbeddingCacheEntry{}).
+			Where("id IN ?", ids).
+			UpdateColumns(map[string]interface{}{
+				"hits":         gorm.Expr("hits + 1"),
+				"last_used_at": time.Now().UTC(),
+			}).Error; err != nil {
+			e.service.logger.Debug().Err(err).Msg("failed to record embedding cache hits")
+		}
+		e.service.logger.Debug().Int("hits", len(ids)).Int("texts", len(texts)).Str("model", e.model).Msg("embedding cache hit")
+	}
+	return embeddings
+}
+
+// store caches newly generated embeddings. A text cached concurrently by another
+// store keeps its first entry.
+func (e *cachingEmbedder) store(ctx context.Context, texts []string, embeddings [][]float32) {
+	now := time.Now().UTC()
+	entries := make([]models.EmbeddingCacheEntry, 0, len(texts))
+	for i, text := range texts {
+		if len(embeddings[i]) == 0 {
+			continue
+		}
+		hash, err := e.service.contentHash(text)
+		if err != nil {
+			e.service.logger.Debug().Err(err).Msg("failed to hash text for the embedding cache")
+			return
+		}
+		entries = append(entries, models.EmbeddingCacheEntry{
+			UserID:     e.service.userID,
+			Model:      e.model,
+			TextHash:   hash,
+			Dimensions: len(embeddings[i]),
+			Vector:     encodeVector(embeddings[i]),
+			CreatedAt:  now,
+			LastUsedAt: now,
+		})
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := e.service.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entries).Error; err != nil {
+		e.service.logger.Debug().Err(err).Msg("failed to cache embeddings")
+	}
+}
+
+// encodeVector packs an embedding as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeVector unpacks an embedding packed by encodeVector, checking it against
+// the expected dimensions
+func decodeVector(buf []byte, dimensions int) ([]float32, error) {
+	if len(buf) != dimensions*4 {
+		return nil, fmt.Errorf("embedding has %d bytes, expected %d for %d dimensions", len(buf), dimensions*4, dimensions)
+	}
+	vector := make([]float32, dimensions)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vector, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+)
+
+// textRecordingEmbeddingService records every text sent to the provider
+type textRecordingEmbeddingService struct {
+	MockEmbeddingService
+	texts []string
+}
+
+func (r *textRecordingEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	r.texts = append(r.texts, text)
+	return r.MockEmbeddingService.GenerateEmbedding(ctx, text)
+}
+
+func (r *textRecordingEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	r.texts = append(r.texts, texts...)
+	return r.MockEmbeddingService.GenerateEmbeddings(ctx, texts)
+}
+
+func TestEmbeddingCache(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T, config map[string]interface{}) (*MemoryService, *textRecordingEmbeddingService) {
+		provider := &textRecordingEmbeddingService{}
+		db := testutil.SQLiteDB(t, &models.EmbeddingCacheEntry{})
+		return NewMemoryService(db, provider, zerolog.Nop(), config), provider
+	}
+
+	t.Run("repeated text is embedded once", func(t *testing.T) {
+		service, provider := setup(t, nil)
+		fields := EmbeddingFields{Content: "prefers tabs over spaces"}
+
+		first, err := service.embedMemory(ctx, service.embedding, fields)
+		require.NoError(t, err)
+		second, err := service.embedMemory(ctx, service.embedding, fields)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, []string{"prefers tabs over spaces"}, provider.texts)
+
+		var entry models.EmbeddingCacheEntry
+		require.NoError(t, service.db.First(&entry).Error)
+		assert.Equal(t, "mock", entry.Model)
+		assert.Equal(t, models.HashContent("prefers tabs over spaces"), entry.TextHash)
+		assert.Equal(t, int64(1), entry.Hits)
+	})
+
+	t.Run("batches only send texts not cached", func(t *testing.T) {
+		service, provider := setup(t, nil)
+		embedder := service.cachedEmbedder(service.embedding)
+		_, err := embedder.GenerateEmbedding(ctx, "seen before")
+		require.NoError(t, err)
+
+		embeddings, err := embedder.GenerateEmbeddings(ctx, []string{"new text", "seen before", "new text"})
+		require.NoError(t, err)
+		require.Len(t, embeddings, 3)
+		assert.Equal(t, embeddings[0], embeddings[2])
+		assert.Equal(t, []string{"seen before", "new text"}, provider.texts)
+
+		var count int64
+		require.NoError(t, service.db.Model(&models.EmbeddingCacheEntry{}).Count(&count).Error)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("repeated search queries are embedded once", func(t *testing.T) {
+		service, provider := setup(t, nil)
+		for i := 0; i < 3; i++ {
+			_, err := service.queryEmbedding(ctx, "what tea do I like")
+			require.NoError(t, err)
+		}
+		_, err := service.embedMemory(ctx, service.embedding, EmbeddingFields{Content: "what tea do I like"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"what tea do I like"}, provider.texts, "queries and memories share the cache")
+
+		stats, err := service.EmbeddingCacheStats(ctx)
+		require.NoError(t, err)
+		assert.True(t, stats.Enabled)
+		assert.Equal(t, int64(1), stats.Entries)
+		assert.Equal(t, int64(3), stats.Hits)
+		assert.Equal(t, 0.75, stats.HitRate)
+	})
+
+	t.Run("entries are keyed per user where content is encrypted", func(t *testing.T) {
+		provider := &textRecordingEmbeddingService{}
+		db := testutil.SQLiteDB(t, &models.EmbeddingCacheEntry{}, &models.User{})
+		for _, id := range []uint{2, 3} {
+			require.NoError(t, db.Create(&models.User{ID: id, Email: string(rune('a'+id)) + "@example.com", Password: "x"}).Error)
+		}
+		config := map[string]interface{}{"encryption_service": newTestEncryption(t)}
+		alice := NewMemoryServiceWithUser(db, provider, zerolog.Nop(), config, 2)
+		bob := NewMemoryServiceWithUser(db, provider, zerolog.Nop(), config, 3)
+
+		fields := EmbeddingFields{Content: "prefers tabs over spaces"}
+		for _, service := range []*MemoryService{alice, alice, bob} {
+			_, err := service.embedMemory(ctx, service.embedding, fields)
+			require.NoError(t, err)
+		}
+		assert.Len(t, provider.texts, 2, "each user's text is embedded once")
+
+		var hashes []string
+		require.NoError(t, db.Model(&models.EmbeddingCacheEntry{}).Pluck("text_hash", &hashes).Error)
+		assert.Len(t, hashes, 2)
+		assert.NotContains(t, hashes, models.HashContent("prefers tabs over spaces"))
+	})
+
+	t.Run("stats only count the user's own entries", func(t *testing.T) {
+		provider := &textRecordingEmbeddingService{}
+		db := testutil.SQLiteDB(t, &models.EmbeddingCacheEntry{})
+		alice := NewMemoryServiceWithUser(db, provider, zerolog.Nop(), nil, 2)
+		bob := NewMemoryServiceWithUser(db, provider, zerolog.Nop(), nil, 3)
+
+		fields := EmbeddingFields{Content: "prefers tabs over spaces"}
+		_, err := bob.embedMemory(ctx, bob.embedding, fields)
+		require.NoError(t, err)
+		before, err := bob.EmbeddingCacheStats(ctx)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err := alice.embedMemory(ctx, alice.embedding, fields)
+			require.NoError(t, err)
+		}
+		assert.Len(t, provider.texts, 2, "users do not share entries, even without encryption")
+
+		after, err := bob.EmbeddingCacheStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+		assert.Equal(t, int64(1), after.Entries)
+		assert.Equal(t, int64(0), after.Hits)
+
+		own, err := alice.EmbeddingCacheStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), own.Entries)
+		assert.Equal(t, int64(2), own.Hits)
+
+		all, err := alice.AllUsersEmbeddingCacheStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), all.Entries)
+		assert.Equal(t, int64(2), all.Hits)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		service, provider := setup(t, map[string]interface{}{"embedding_cache": false})
+		fields := EmbeddingFields{Content: "prefers tabs over spaces"}
+		for i := 0; i < 2; i++ {
+			_, err := service.embedMemory(ctx, service.embedding, fields)
+			require.NoError(t, err)
+		}
+		assert.Len(t, provider.texts, 2)
+	})
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// EmbeddingFields are the parts of a memory available to an embedding document
+type EmbeddingFields struct {
+	Content  string
+	Tags     []string
+	Category string
+	Type     string
+}
+
+// embeddingFieldsOf returns a memory's fields, with content passed separately as
+// the memory may already hold the encrypted marker
+func embeddingFieldsOf(memory *models.Memory, content string) EmbeddingFields {
+	return EmbeddingFields{
+		Content:  content,
+		Tags:     append([]string(nil), memory.Tags...),
+		Category: memory.Category,
+		Type:     memory.Type,
+	}
+}
+
+// embeddingPart is a text to embed and its weight in the memory's vector
+type embeddingPart struct {
+	Text   string
+	Weight float64
+}
+
+// EmbeddingComposer builds the text embedded for each memory; see
+// config.EmbeddingDocument. A nil composer embeds the content alone.
+type EmbeddingComposer struct {
+	template *template.Template
+	weights  map[string]float64
+}
+
+// NewEmbeddingComposer parses the document configuration. It returns nil when the
+// content is embedded alone, as by default.
+func NewEmbeddingComposer(cfg config.EmbeddingDocument) (*EmbeddingComposer, error) {
+	if len(cfg.Weights) > 0 {
+		return &EmbeddingComposer{weights: cfg.Weights}, nil
+	}
+	if strings.TrimSpace(cfg.Template) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("embedding_document").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Option("missingkey=error").
+		Parse(cfg.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedding document template: %w", err)
+	}
+	// Catch references to fields that do not exist before the first store
+	if err := tmpl.Execute(&strings.Builder{}, EmbeddingFields{}); err != nil {
+		return nil, fmt.Errorf("invalid embedding document template: %w", err)
+	}
+	return &EmbeddingComposer{template: tmpl}, nil
+}
+
+// parts returns the texts to embed for a memory. Fields that are empty are left
+// out, and the content is always embedded if nothing else is.
+func (c *EmbeddingComposer) parts(fields EmbeddingFields) []embeddingPart {
+	contentOnly := []embeddingPart{{Text: fields.Content, Weight: 1}}
+	if c == nil {
+		return contentOnly
+	}
+
+	if c.template != nil {
+		var document strings.Builder
+		if err := c.template.Execute(&document, fields); err != nil || strings.TrimSpace(document.String()) == "" {
+			return contentOnly
+		}
+		return []embeddingPart{{Text: document.String(), Weight: 1}}
+	}
+
+	texts := map[string]string{
+		"content":  fields.Content,
+		"tags":     strings.Join(fields.Tags, ", "),
+		"category": fields.Category,
+		"type":     fields.Type,
+	}
+	names := make([]string, 0, len(c.weights))
+	for name := range c.weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []embeddingPart
+	for _, name := range names {
+		if weight := c.weights[name]; weight > 0 && strings.TrimSpace(texts[name]) != "" {
+			parts = append(parts, embeddingPart{Text: texts[name], Weight: weight})
+		}
+	}
+	if len(parts) == 0 {
+		return contentOnly
+	}
+	return parts
+}
+
+// GetEmbeddingComposer returns the composer building embedded documents, or nil
+// when only content is embedded
+func (s *MemoryService) GetEmbeddingComposer() *EmbeddingComposer {
+	composer, _ := s.config["embedding_composer"].(*EmbeddingComposer)
+	return composer
+}
+
+// embedMemory generates a memory's embedding from its composed document, reusing
+// cached embeddings of texts embedded before. Only the first chunk of long
+// content is part of the document; embedChunks covers the rest.
+func (s *MemoryService) embedMemory(ctx context.Context, embedder EmbeddingService, fields EmbeddingFields) ([]float32, error) {
+	fields.Content = s.leadChunk(fields.Content)
+	return embedParts(ctx, s.cachedEmbedder(embedder), s.GetEmbeddingComposer().parts(fields))
+}
+
+// embedParts embeds each part and combines the vectors into their weighted
+// average, normalised to unit length. A single part is embedded as-is.
+func embedParts(ctx context.Context, embedder EmbeddingService, parts []embeddingPart) ([]float32, error) {
+	if len(parts) == 1 {
+		return embedder.GenerateEmbedding(ctx, parts[0].Text)
+	}
+
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.Text
+	}
+	vectors, err := embedder.GenerateEmbeddings(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(parts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(parts), len(vectors))
+	}
+
+	combined := make([]float64, len(vectors[0]))
+	for i, vector := range vectors {
+		if len(vector) != len(combined) {
+			return nil, fmt.Errorf("embeddings have different dimensions")
+		}
+		norm := vectorNorm(vector)
+		if norm == 0 {
+			continue
+		}
+		for j, value := range vector {
+			combined[j] += parts[i].Weight * float64(value) / norm
+		}
+	}
+
+	norm := 0.0
+	for _, value := range combined {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, len(combined))
+	for j, value := range combined {
+		if norm > 0 {
+			value /= norm
+		}
+		result[j] = float32(value)
+	}
+	return result, nil
+}
+
+func vectorNorm(vector []float32) float64 {
+	norm := 0.0
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	return math.Sqrt(norm)
+}
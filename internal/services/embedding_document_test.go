@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+// axisEmbeddingService embeds each text as a fixed vector so combinations can be
+// checked exactly
+type axisEmbeddingService struct {
+	vectors map[string][]float32
+	texts   []string
+}
+
+func (a *axisEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	a.texts = append(a.texts, text)
+	return a.vectors[text], nil
+}
+
+func (a *axisEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = a.GenerateEmbedding(ctx, text)
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingComposer_ContentOnlyByDefault(t *testing.T) {
+	composer, err := NewEmbeddingComposer(config.EmbeddingDocument{})
+	require.NoError(t, err)
+	assert.Nil(t, composer)
+
+	parts := composer.parts(EmbeddingFields{Content: "likes tea", Tags: []string{"drinks"}})
+	assert.Equal(t, []embeddingPart{{Text: "likes tea", Weight: 1}}, parts)
+}
+
+func TestEmbeddingComposer_Template(t *testing.T) {
+	composer, err := NewEmbeddingComposer(config.EmbeddingDocument{
+		Template: `{{.Content}} | {{join .Tags ", "}} | {{.Category}}`,
+	})
+	require.NoError(t, err)
+
+	parts := composer.parts(EmbeddingFields{Content: "likes tea", Tags: []string{"drinks", "uk"}, Category: "personal"})
+	assert.Equal(t, []embeddingPart{{Text: "likes tea | drinks, uk | personal", Weight: 1}}, parts)
+
+	_, err = NewEmbeddingComposer(config.EmbeddingDocument{Template: "{{.Content"})
+	assert.Error(t, err)
+	_, err = NewEmbeddingComposer(config.EmbeddingDocument{Template: "{{.Summary}}"})
+	assert.Error(t, err)
+}
+
+func TestEmbeddingComposer_WeightedFields(t *testing.T) {
+	ctx := context.Background()
+	composer, err := NewEmbeddingComposer(config.EmbeddingDocument{
+		Weights: map[string]float64{"content": 3, "tags": 1, "category": 1},
+	})
+	require.NoError(t, err)
+
+	// Empty fields are left out rather than embedded as blank text
+	parts := composer.parts(EmbeddingFields{Content: "likes tea", Tags: []string{"drinks"}})
+	assert.Equal(t, []embeddingPart{{Text: "likes tea", Weight: 3}, {Text: "drinks", Weight: 1}}, parts)
+
+	embedder := &axisEmbeddingService{vectors: map[string][]float32{
+		"likes tea": {2, 0},
+		"drinks":    {0, 5},
+		"personal":  {1, 1},
+	}}
+	vector, err := embedParts(ctx, embedder, parts)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.9487, vector[0], 0.0001)
+	assert.InDelta(t, 0.3162, vector[1], 0.0001)
+	assert.InDelta(t, 1, vectorNorm(vector), 0.0001)
+
+	// The service embeds stored memories through its composer
+	svc := &MemoryService{config: map[string]interface{}{"embedding_composer": composer}}
+	embedder.texts = nil
+	_, err = svc.embedMemory(ctx, embedder, EmbeddingFields{Content: "likes tea", Category: "personal"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"likes tea", "personal"}, embedder.texts)
+}
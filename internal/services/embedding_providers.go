@@ -0,0 +1,420 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
+)
+
+// EmbeddingProviderFactory creates an embedding service from the configuration
+type EmbeddingProviderFactory func(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error)
+
+var (
+	embeddingProvidersMu sync.RWMutex
+	embeddingProviders   = map[string]EmbeddingProviderFactory{
+		"openai": newOpenAIProvider,
+		"ollama": newOllamaProvider,
+		"voyage": newVoyageProvider,
+		"cohere": newCohereProvider,
+		"onnx":   newONNXProvider,
+		"mock": func(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+			return NewMockEmbeddingService(), nil
+		},
+	}
+)
+
+// RegisterEmbeddingProvider makes a provider selectable with embedding.provider,
+// replacing any provider registered under the same name
+func RegisterEmbeddingProvider(name string, factory EmbeddingProviderFactory) {
+	embeddingProvidersMu.Lock()
+	defer embeddingProvidersMu.Unlock()
+	embeddingProviders[strings.ToLower(name)] = factory
+}
+
+// EmbeddingProviders returns the registered provider names, sorted
+func EmbeddingProviders() []string {
+	embeddingProvidersMu.RLock()
+	defer embeddingProvidersMu.RUnlock()
+	names := make([]string, 0, len(embeddingProviders))
+	for name := range embeddingProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEmbeddingServiceFromConfig creates the embedding service selected by
+// embedding.provider, defaulting to OpenAI
+func NewEmbeddingServiceFromConfig(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	name := strings.ToLower(cfg.Embedding.Provider)
+	if name == "" {
+		name = "openai"
+	}
+
+	embeddingProvidersMu.RLock()
+	factory, ok := embeddingProviders[name]
+	embeddingProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("invalid embedding provider: %s (available: %s)", cfg.Embedding.Provider, strings.Join(EmbeddingProviders(), ", "))
+	}
+	return factory(cfg, logger)
+}
+
+// newOpenAIProvider creates the OpenAI service from the openai section. Without an
+// API key it falls back to mock embeddings, as the server always has.
+func newOpenAIProvider(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	if cfg.OpenAI.APIKey == "" {
+		logger.Warn().Msg("No OpenAI API key provided, using mock embedding service")
+		return NewMockEmbeddingService(), nil
+	}
+
+	openAIConfig := cfg.OpenAI
+	if cfg.Embedding.Model != "" {
+		openAIConfig.Model = cfg.Embedding.Model
+	}
+	service, err := NewOpenAIEmbeddingService(&openAIConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	service.baseURL = strings.TrimSuffix(cfg.Embedding.BaseURL, "/")
+	service.dimensions = cfg.Embedding.Dimensions
+	return service, nil
+}
+
+// newOllamaProvider embeds with a local Ollama server
+func newOllamaProvider(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	return newHTTPEmbeddingProvider(cfg, logger, httpEmbeddingAPI{
+		name:      "ollama",
+		baseURL:   "http://localhost:11434",
+		path:      "/api/embed",
+		model:     "nomic-embed-text",
+		maxInputs: 512,
+		encode: func(model string, texts []string) interface{} {
+			return map[string]interface{}{"model": model, "input": texts}
+		},
+		decode: func(body []byte) ([][]float64, error) {
+			var response struct {
+				Embeddings [][]float64 `json:"embeddings"`
+			}
+			err := json.Unmarshal(body, &response)
+			return response.Embeddings, err
+		},
+	})
+}
+
+// newVoyageProvider embeds with the Voyage AI API
+func newVoyageProvider(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	return newHTTPEmbeddingProvider(cfg, logger, httpEmbeddingAPI{
+		name:       "voyage",
+		baseURL:    "https://api.voyageai.com",
+		path:       "/v1/embeddings",
+		model:      "voyage-3",
+		maxInputs:  128,
+		requireKey: true,
+		encode: func(model string, texts []string) interface{} {
+			return map[string]interface{}{"model": model, "input": texts}
+		},
+		decode: decodeIndexedEmbeddings,
+	})
+}
+
+// newCohereProvider embeds with the Cohere API. Memories and queries are both
+// embedded as documents so they stay in one space.
+func newCohereProvider(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	return newHTTPEmbeddingProvider(cfg, logger, httpEmbeddingAPI{
+		name:       "cohere",
+		baseURL:    "https://api.cohere.com",
+		path:       "/v2/embed",
+		model:      "embed-english-v3.0",
+		maxInputs:  96,
+		requireKey: true,
+		encode: func(model string, texts []string) interface{} {
+			return map[string]interface{}{
+				"model":           model,
+				"texts":           texts,
+				"input_type":      "search_document",
+				"embedding_types": []string{"float"},
+			}
+		},
+		decode: func(body []byte) ([][]float64, error) {
+			var response struct {
+				Embeddings struct {
+					Float [][]float64 `json:"float"`
+				} `json:"embeddings"`
+			}
+			err := json.Unmarshal(body, &response)
+			return response.Embeddings.Float, err
+		},
+	})
+}
+
+// newONNXProvider embeds with a local ONNX model served by Hugging Face
+// text-embeddings-inference, which serves a single model
+func newONNXProvider(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+	return newHTTPEmbeddingProvider(cfg, logger, httpEmbeddingAPI{
+		name:      "onnx",
+		baseURL:   "http://localhost:8080",
+		path:      "/embed",
+		model:     "local",
+		maxInputs: 32,
+		encode: func(model string, texts []string) interface{} {
+			return map[string]interface{}{"inputs": texts}
+		},
+		decode: func(body []byte) ([][]float64, error) {
+			var embeddings [][]float64
+			err := json.Unmarshal(body, &embeddings)
+			return embeddings, err
+		},
+	})
+}
+
+// decodeIndexedEmbeddings reads an OpenAI-style response whose results carry the
+// index of their input
+func decodeIndexedEmbeddings(body []byte) ([][]float64, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float64, len(response.Data))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(embeddings) || embeddings[data.Index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings, nil
+}
+
+// httpEmbeddingAPI describes a provider's embeddings endpoint
+type httpEmbeddingAPI struct {
+	name    string
+	baseURL string
+	path    string
+	// model is the default when embedding.model is not set
+	model string
+	// maxInputs is the most texts sent in one request
+	maxInputs  int
+	requireKey bool
+	encode     func(model string, texts []string) interface{}
+	decode     func(body []byte) ([][]float64, error)
+}
+
+// httpEmbeddingProvider is an embedding service for providers reached over a
+// simple JSON API
+type httpEmbeddingProvider struct {
+	api        httpEmbeddingAPI
+	url        string
+	model      string
+	apiKey     string
+	dimensions int
+	maxRetries int
+	client     *http.Client
+	logger     zerolog.Logger
+}
+
+func newHTTPEmbeddingProvider(cfg *config.Config, logger zerolog.Logger, api httpEmbeddingAPI) (*httpEmbeddingProvider, error) {
+	if api.requireKey && cfg.Embedding.APIKey == "" {
+		return nil, fmt.Errorf("embedding API key is required for the %s provider", api.name)
+	}
+
+	baseURL := api.baseURL
+	if cfg.Embedding.BaseURL != "" {
+		baseURL = strings.TrimSuffix(cfg.Embedding.BaseURL, "/")
+	}
+	model := api.model
+	if cfg.Embedding.Model != "" {
+		model = cfg.Embedding.Model
+	}
+	maxRetries := cfg.OpenAI.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	logger.Info().Str("provider", api.name).Str("model", model).Str("url", baseURL+api.path).Msg("Creating embedding service")
+	return &httpEmbeddingProvider{
+		api:        api,
+		url:        baseURL + api.path,
+		model:      model,
+		apiKey:     cfg.Embedding.APIKey,
+		dimensions: cfg.Embedding.Dimensions,
+		maxRetries: maxRetries,
+		client:     &http.Client{Timeout: 60 * time.Second},
+		logger:     logger.With().Str("service", api.name+"_embedding").Logger(),
+	}, nil
+}
+
+// GetModel returns the provider and model, e.g. "ollama:nomic-embed-text"
+func (p *httpEmbeddingProvider) GetModel() string {
+	return p.api.name + ":" + p.model
+}
+
+// GenerateEmbedding generates the embedding for a single text
+func (p *httpEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings in requests of up to the provider's
+// input limit, returning them in the same order as the texts
+func (p *httpEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts []string) (results [][]float32, err error) {
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("text %d cannot be empty", i)
+		}
+	}
+
+	ctx, span := tracing.StartEmbedding(ctx, p.api.name, p.model, len(texts))
+	defer func() { tracing.End(span, err) }()
+
+	results = make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += p.api.maxInputs {
+		end := start + p.api.maxInputs
+		if end > len(texts) {
+			end = len(texts)
+		}
+		embeddings, err := p.generateWithRetry(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, embeddings...)
+	}
+	return results, nil
+}
+
+// generateWithRetry makes one request, retrying rate limits, server errors and
+// network failures with exponential backoff
+func (p *httpEmbeddingProvider) generateWithRetry(ctx context.Context, texts []string) ([][]float32, error) {
+	var lastErr error
+	for attempt := 0; attempt < p.maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt-1)) * time.Second
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		embeddings, err := p.generate(ctx, texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+		p.logger.Warn().Err(err).Int("attempt", attempt+1).Msg("Failed to generate embeddings")
+
+		var statusErr *embeddingStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return nil, fmt.Errorf("non-retryable error: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", p.maxRetries, lastErr)
+}
+
+// generate makes a single embeddings request
+func (p *httpEmbeddingProvider) generate(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(p.api.encode(p.model, texts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &embeddingStatusError{provider: p.api.name, status: resp.StatusCode, body: string(body)}
+	}
+
+	embeddings, err := p.api.decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+
+	results := make([][]float32, len(embeddings))
+	for i, embedding := range embeddings {
+		if results[i], err = fitEmbedding(embedding, p.dimensions); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// embeddingStatusError is an unsuccessful response from a provider
+type embeddingStatusError struct {
+	provider string
+	status   int
+	body     string
+}
+
+func (e *embeddingStatusError) Error() string {
+	return fmt.Sprintf("%s request failed with status %d: %s", e.provider, e.status, e.body)
+}
+
+func (e *embeddingStatusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// fitEmbedding converts an embedding to float32 and zero-pads it to
+// EmbeddingDimension, the size of the embedding column. Padding with zeros leaves
+// cosine similarity unchanged. When dimensions is set the embedding must have
+// exactly that many.
+func fitEmbedding(embedding []float64, dimensions int) ([]float32, error) {
+	if dimensions > 0 && len(embedding) != dimensions {
+		return nil, fmt.Errorf("expected an embedding of %d dimensions, got %d", dimensions, len(embedding))
+	}
+	if len(embedding) == 0 || len(embedding) > EmbeddingDimension {
+		return nil, fmt.Errorf("embedding of %d dimensions does not fit the %d-dimension embedding column", len(embedding), EmbeddingDimension)
+	}
+
+	result := make([]float32, EmbeddingDimension)
+	for i, v := range embedding {
+		result[i] = float32(v)
+	}
+	return result, nil
+}
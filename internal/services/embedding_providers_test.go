@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+func providerConfig(provider, baseURL string) *config.Config {
+	cfg := config.NewDefault()
+	cfg.Embedding.Provider = provider
+	cfg.Embedding.BaseURL = baseURL
+	cfg.Embedding.APIKey = "test-key"
+	cfg.OpenAI.MaxRetries = 1
+	return cfg
+}
+
+func TestEmbeddingProviders_Requests(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		respond  func(t *testing.T, body map[string]interface{}) interface{}
+	}{
+		{
+			provider: "ollama",
+			path:     "/api/embed",
+			respond: func(t *testing.T, body map[string]interface{}) interface{} {
+				assert.Equal(t, "nomic-embed-text", body["model"])
+				assert.Len(t, body["input"], 2)
+				return map[string]interface{}{"embeddings": [][]float64{{1, 0}, {0, 1}}}
+			},
+		},
+		{
+			provider: "voyage",
+			path:     "/v1/embeddings",
+			respond: func(t *testing.T, body map[string]interface{}) interface{} {
+				assert.Equal(t, "voyage-3", body["model"])
+				// Results may come back out of order
+				return map[string]interface{}{"data": []map[string]interface{}{
+					{"index": 1, "embedding": []float64{0, 1}},
+					{"index": 0, "embedding": []float64{1, 0}},
+				}}
+			},
+		},
+		{
+			provider: "cohere",
+			path:     "/v2/embed",
+			respond: func(t *testing.T, body map[string]interface{}) interface{} {
+				assert.Equal(t, "embed-english-v3.0", body["model"])
+				assert.Equal(t, "search_document", body["input_type"])
+				assert.Len(t, body["texts"], 2)
+				return map[string]interface{}{"embeddings": map[string]interface{}{"float": [][]float64{{1, 0}, {0, 1}}}}
+			},
+		},
+		{
+			provider: "onnx",
+			path:     "/embed",
+			respond: func(t *testing.T, body map[string]interface{}) interface{} {
+				assert.Len(t, body["inputs"], 2)
+				return [][]float64{{1, 0}, {0, 1}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.path, r.URL.Path)
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				require.NoError(t, json.NewEncoder(w).Encode(tt.respond(t, body)))
+			}))
+			defer server.Close()
+
+			service, err := NewEmbeddingServiceFromConfig(providerConfig(tt.provider, server.URL), zerolog.Nop())
+			require.NoError(t, err)
+
+			embeddings, err := service.GenerateEmbeddings(context.Background(), []string{"first", "second"})
+			require.NoError(t, err)
+			require.Len(t, embeddings, 2)
+			for _, embedding := range embeddings {
+				assert.Len(t, embedding, EmbeddingDimension)
+			}
+			assert.Equal(t, []float32{1, 0, 0}, embeddings[0][:3])
+			assert.Equal(t, []float32{0, 1, 0}, embeddings[1][:3])
+		})
+	}
+}
+
+func TestEmbeddingProviders_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float64{{1, 2, 3}}}))
+	}))
+	defer server.Close()
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		cfg := providerConfig("ollama", server.URL)
+		cfg.Embedding.Dimensions = 768
+		service, err := NewEmbeddingServiceFromConfig(cfg, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = service.GenerateEmbedding(context.Background(), "text")
+		assert.ErrorContains(t, err, "expected an embedding of 768 dimensions")
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		requests := 0
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			http.Error(w, "bad model", http.StatusBadRequest)
+		}))
+		defer failing.Close()
+
+		cfg := providerConfig("ollama", failing.URL)
+		cfg.OpenAI.MaxRetries = 3
+		service, err := NewEmbeddingServiceFromConfig(cfg, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = service.GenerateEmbedding(context.Background(), "text")
+		assert.ErrorContains(t, err, "status 400")
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := NewEmbeddingServiceFromConfig(providerConfig("word2vec", ""), zerolog.Nop())
+		assert.ErrorContains(t, err, "invalid embedding provider")
+	})
+
+	t.Run("hosted provider without key", func(t *testing.T) {
+		cfg := providerConfig("cohere", "")
+		cfg.Embedding.APIKey = ""
+		_, err := NewEmbeddingServiceFromConfig(cfg, zerolog.Nop())
+		assert.ErrorContains(t, err, "API key is required")
+	})
+}
+
+func TestEmbeddingProviders_Registry(t *testing.T) {
+	// OpenAI without a key keeps falling back to mock embeddings
+	service, err := NewEmbeddingServiceFromConfig(config.NewDefault(), zerolog.Nop())
+	require.NoError(t, err)
+	assert.IsType(t, &MockEmbeddingService{}, service)
+
+	RegisterEmbeddingProvider("Custom", func(cfg *config.Config, logger zerolog.Logger) (EmbeddingService, error) {
+		return NewMockEmbeddingService(), nil
+	})
+	t.Cleanup(func() {
+		embeddingProvidersMu.Lock()
+		delete(embeddingProviders, "custom")
+		embeddingProvidersMu.Unlock()
+	})
+	assert.Contains(t, EmbeddingProviders(), "custom")
+
+	service, err = NewEmbeddingServiceFromConfig(providerConfig("custom", ""), zerolog.Nop())
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Eviction policies decide what happens when a user reaches the memory limit
+const (
+	// EvictionOldestFirst removes the oldest memories (the historical behaviour)
+	EvictionOldestFirst = "oldest_first"
+	// EvictionLeastAccessed removes the memories recalled least often
+	EvictionLeastAccessed = "least_accessed"
+	// EvictionLeastRecentlyUsed removes the memories recalled longest ago, counting
+	// memories never recalled from when they were stored
+	EvictionLeastRecentlyUsed = "least_recently_used"
+	// EvictionLowestPriority removes low priority memories first, oldest first within a priority
+	EvictionLowestPriority = "lowest_priority"
+	// EvictionRejectNew keeps existing memories and refuses to store new ones
+	EvictionRejectNew = "reject_new"
+)
+
+// evictionPolicyAliases maps alternative policy names onto the policies above
+var evictionPolicyAliases = map[string]string{
+	"oldest":                  EvictionOldestFirst,
+	"lowest_access_count":     EvictionLeastAccessed,
+	"least_recently_accessed": EvictionLeastRecentlyUsed,
+}
+
+// EventMemoriesEvicted is the notification event sent when memories are evicted
+const EventMemoriesEvicted = "memory.evicted"
+
+// ErrMemoryLimitReached is returned when storing a new memory under the reject_new policy
+var ErrMemoryLimitReached = errors.New("memory limit reached")
+
+// MemoryLimitError reports a store refused because the user is at their memory limit
+type MemoryLimitError struct {
+	Limit int
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("memory limit of %d reached and the eviction policy is %s: delete memories before storing new ones",
+		e.Limit, EvictionRejectNew)
+}
+
+func (e *MemoryLimitError) Unwrap() error {
+	return ErrMemoryLimitReached
+}
+
+// CanonicalEvictionPolicy resolves an alias such as oldest or
+// least_recently_accessed to the policy it names; other values are returned as is
+func CanonicalEvictionPolicy(policy string) string {
+	if canonical, ok := evictionPolicyAliases[policy]; ok {
+		return canonical
+	}
+	return policy
+}
+
+// IsValidEvictionPolicy checks if a given eviction policy, or an alias of one, is known
+func IsValidEvictionPolicy(policy string) bool {
+	switch CanonicalEvictionPolicy(policy) {
+	case EvictionOldestFirst, EvictionLeastAccessed, EvictionLeastRecentlyUsed, EvictionLowestPriority, EvictionRejectNew:
+		return true
+	default:
+		return false
+	}
+}
+
+// evictionOrder returns the ORDER BY clause selecting eviction candidates first
+func evictionOrder(policy string) string {
+	switch policy {
+	case EvictionLeastAccessed:
+		return "access_count ASC, COALESCE(last_accessed_at, created_at) ASC, id ASC"
+	case EvictionLeastRecentlyUsed:
+		return "COALESCE(last_accessed_at, created_at) ASC, id ASC"
+	case EvictionLowestPriority:
+		return "CASE priority WHEN 'low' THEN 0 WHEN 'medium' THEN 1 WHEN 'high' THEN 2 WHEN 'critical' THEN 3 ELSE 1 END ASC, created_at ASC, id ASC"
+	default:
+		return "created_at ASC, id ASC"
+	}
+}
+
+// EvictionPolicy returns the user's eviction policy, falling back to the deployment
+// default and then to oldest-first
+func (s *MemoryService) EvictionPolicy(ctx context.Context) string {
+	var policies []string
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Pluck("eviction_policy", &policies).Error; err != nil {
+		s.logger.Debug().Err(err).Msg("failed to load user eviction policy, using default")
+	} else if len(policies) > 0 && IsValidEvictionPolicy(policies[0]) {
+		return CanonicalEvictionPolicy(policies[0])
+	}
+
+	if policy, ok := s.config["eviction_policy"].(string); ok && IsValidEvictionPolicy(policy) {
+		return CanonicalEvictionPolicy(policy)
+	}
+	return EvictionOldestFirst
+}
+
+// SetEvictionPolicy stores the user's eviction policy, resolving aliases. An empty
+// policy reverts to the deployment default.
+func (s *MemoryService) SetEvictionPolicy(ctx context.Context, policy string) error {
+	if policy != "" && !IsValidEvictionPolicy(policy) {
+		return utils.InvalidFieldError("eviction_policy",
+			fmt.Sprintf("must be one of %s, %s, %s, %s or %s", EvictionOldestFirst, EvictionLeastAccessed,
+				EvictionLeastRecentlyUsed, EvictionLowestPriority, EvictionRejectNew))
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Update("eviction_policy", CanonicalEvictionPolicy(policy))
+	if result.Error != nil {
+		s.logger.Error().Err(result.Error).Msg("failed to set eviction policy")
+		return utils.WrapDatabaseError("set eviction policy", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+	}
+
+	return nil
+}
+
+// checkCapacity refuses a new memory when the user is at the limit under reject_new
+func (s *MemoryService) checkCapacity(ctx context.Context) error {
+	limit := s.MemoryLimit(ctx)
+	if limit == 0 || s.EvictionPolicy(ctx) != EvictionRejectNew {
+		return nil
+	}
+
+	count, err := s.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return &MemoryLimitError{Limit: limit}
+	}
+	return nil
+}
+
+// reportEvictions records evicted memories in the activity log and notifies the user
+func (s *MemoryService) reportEvictions(ctx context.Context, policy string, limit int, evicted []models.Memory) {
+	ids := make([]uint, 0, len(evicted))
+	for _, memory := range evicted {
+		ids = append(ids, memory.ID)
+	}
+
+	details := map[string]interface{}{
+		"count":      len(ids),
+		"memory_ids": ids,
+		"policy":     policy,
+		"limit":      limit,
+	}
+
+	s.logActivity(ctx, models.ActivityMemoryEvicted, details)
+
+	notifier := s.GetNotifier()
+	if notifier == nil {
+		return
+	}
+
+	notification := &Notification{
+		Event:     EventMemoriesEvicted,
+		Severity:  SeverityWarning,
+		Subject:   fmt.Sprintf("%d memories evicted", len(ids)),
+		Message:   fmt.Sprintf("User %d reached the memory limit of %d; %d memories were evicted using the %s policy.", s.userID, limit, len(ids), policy),
+		UserID:    s.userID,
+		Data:      details,
+		Timestamp: time.Now().UTC(),
+	}
+
+	go func() {
+		ctx, cancel := s.detachedContext(context.Background())
+		defer cancel()
+		if err := notifier.Notify(ctx, notification); err != nil {
+			s.logger.Error().Err(err).Msg("failed to deliver eviction notification")
+		}
+	}()
+}
+
+// recordAccess bumps the access statistics of memories returned to a client, which
+// feed the least_accessed and least_recently_used eviction policies and RecallRecent
+func (s *MemoryService) recordAccess(ctx context.Context, memories []*models.Memory) {
+	if len(memories) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(memories))
+	for _, memory := range memories {
+		ids = append(ids, memory.ID)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("user_id = ? AND id IN ?", s.userID, ids).
+		UpdateColumns(map[string]interface{}{
+			"access_count":     gorm.Expr("access_count + 1"),
+			"last_accessed_at": time.Now(),
+		}).Error; err != nil {
+		s.logger.Warn().Err(err).Msg("failed to record memory access")
+	}
+}
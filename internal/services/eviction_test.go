@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func setupEvictionService(t *testing.T, config map[string]interface{}, policy string) (*MemoryService, *recordingNotifier) {
+	service := setupMemoryService(t, config)
+	require.NoError(t, service.db.AutoMigrate(&models.User{}, &models.ActivityLog{}))
+	require.NoError(t, service.db.Create(&models.User{ID: 1, Email: "user@example.com", Password: "x"}).Error)
+	require.NoError(t, service.SetEvictionPolicy(context.Background(), policy))
+
+	notifier := &recordingNotifier{}
+	service.config["notifier"] = Notifier(notifier)
+	return service, notifier
+}
+
+func storePriorityMemory(t *testing.T, service *MemoryService, content, priority string) *models.Memory {
+	memory, err := service.Store(context.Background(), StoreRequest{
+		Content:  content,
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+		Priority: priority,
+	})
+	require.NoError(t, err)
+	return memory
+}
+
+func TestEvictionPolicy_Resolution(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupEvictionService(t, map[string]interface{}{"eviction_policy": EvictionLeastAccessed}, "")
+
+	// Without a user override the deployment default applies
+	assert.Equal(t, EvictionLeastAccessed, service.EvictionPolicy(ctx))
+
+	require.NoError(t, service.SetEvictionPolicy(ctx, EvictionRejectNew))
+	assert.Equal(t, EvictionRejectNew, service.EvictionPolicy(ctx))
+
+	assert.Error(t, service.SetEvictionPolicy(ctx, "random"))
+
+	// Aliases resolve to the policy they name
+	require.NoError(t, service.SetEvictionPolicy(ctx, "least_recently_accessed"))
+	assert.Equal(t, EvictionLeastRecentlyUsed, service.EvictionPolicy(ctx))
+
+	service, _ = setupEvictionService(t, map[string]interface{}{"eviction_policy": "lowest_access_count"}, "")
+	assert.Equal(t, EvictionLeastAccessed, service.EvictionPolicy(ctx))
+}
+
+func TestEnforceMemoryLimit_LowestPriority(t *testing.T) {
+	ctx := context.Background()
+	service, notifier := setupEvictionService(t, map[string]interface{}{"memory_limit": 2}, EvictionLowestPriority)
+
+	high := storePriorityMemory(t, service, "high priority", models.PriorityHigh)
+	low := storePriorityMemory(t, service, "low priority", models.PriorityLow)
+	storePriorityMemory(t, service, "medium priority", models.PriorityMedium)
+
+	_, err := service.GetByID(ctx, low.ID)
+	assert.Error(t, err)
+	_, err = service.GetByID(ctx, high.ID)
+	assert.NoError(t, err)
+
+	var activities []models.ActivityLog
+	require.NoError(t, service.db.Where("type = ?", models.ActivityMemoryEvicted).Find(&activities).Error)
+	require.Len(t, activities, 1)
+	details, err := activities[0].GetDetailsMap()
+	require.NoError(t, err)
+	assert.Equal(t, EvictionLowestPriority, details["policy"])
+
+	assert.Eventually(t, func() bool {
+		events := notifier.events()
+		return len(events) == 1 && events[0] == EventMemoriesEvicted
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEnforceMemoryLimit_LeastAccessed(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupEvictionService(t, map[string]interface{}{"memory_limit": 2}, EvictionLeastAccessed)
+
+	first := storePriorityMemory(t, service, "recalled often", models.PriorityMedium)
+	second := storePriorityMemory(t, service, "never recalled", models.PriorityMedium)
+	_, err := service.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+
+	storePriorityMemory(t, service, "newest", models.PriorityMedium)
+
+	_, err = service.GetByID(ctx, second.ID)
+	assert.Error(t, err)
+	_, err = service.GetByID(ctx, first.ID)
+	assert.NoError(t, err)
+}
+
+func TestEnforceMemoryLimit_LeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupEvictionService(t, map[string]interface{}{"memory_limit": 2}, EvictionLeastRecentlyUsed)
+
+	lately := storePriorityMemory(t, service, "recalled once, just now", models.PriorityMedium)
+	often := storePriorityMemory(t, service, "recalled often, a while ago", models.PriorityMedium)
+	setAccess(t, service, often, 5, time.Now().UTC().Add(-time.Hour))
+	setAccess(t, service, lately, 1, time.Now().UTC())
+
+	storePriorityMemory(t, service, "newest", models.PriorityMedium)
+
+	// oldest_first and least_accessed would both have evicted the memory recalled once
+	_, err := service.GetByID(ctx, often.ID)
+	assert.Error(t, err)
+	_, err = service.GetByID(ctx, lately.ID)
+	assert.NoError(t, err)
+}
+
+func TestEnforceMemoryLimit_RejectNew(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupEvictionService(t, map[string]interface{}{"memory_limit": 1}, EvictionRejectNew)
+
+	existing := storePriorityMemory(t, service, "kept", models.PriorityLow)
+
+	_, err := service.Store(ctx, StoreRequest{
+		Content:  "rejected",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+		Priority: models.PriorityHigh,
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrMemoryLimitReached))
+
+	// Updating an existing memory is still allowed at the limit
+	_, err = service.Store(ctx, StoreRequest{
+		Content:  "kept",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+		Priority: models.PriorityHigh,
+	})
+	assert.NoError(t, err)
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = service.GetByID(ctx, existing.ID)
+	assert.NoError(t, err)
+}
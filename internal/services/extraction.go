@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// Extraction engines
+const (
+	ExtractionEngineRegex = "regex"
+	ExtractionEngineLLM   = "llm"
+)
+
+// maxExtractedMemories bounds the memories one extraction request may return
+const maxExtractedMemories = 50
+
+// Extraction is what a MemoryExtractor found in a text
+type Extraction struct {
+	Memories []DetectedMemory
+	// InputTokens and OutputTokens are the language-model usage, if any
+	InputTokens  int
+	OutputTokens int
+}
+
+// MemoryExtractor finds the memories worth storing in free text. The regex engine
+// runs the built-in patterns; the llm engine asks a chat model.
+type MemoryExtractor interface {
+	// Name identifies the engine, e.g. "regex" or "llm"
+	Name() string
+	Extract(ctx context.Context, text string) (*Extraction, error)
+}
+
+// RegexExtractor extracts memories with the built-in patterns of
+// DetectMemoryPatterns, one per sentence
+type RegexExtractor struct{}
+
+// Name identifies the engine
+func (RegexExtractor) Name() string {
+	return ExtractionEngineRegex
+}
+
+// Extract returns the most confident detection of each sentence of text
+func (RegexExtractor) Extract(ctx context.Context, text string) (*Extraction, error) {
+	extraction := &Extraction{}
+	for _, line := range strings.Split(text, "\n") {
+		for _, sentence := range splitSentences(line) {
+			if best := bestDetection(DetectMemoryPatterns(sentence), 0); best != nil {
+				extraction.Memories = append(extraction.Memories, *best)
+			}
+		}
+	}
+	return extraction, nil
+}
+
+// extractionPrompt tells the model what to extract and how to answer
+const extractionPrompt = `You extract long-term memories about the user from what they wrote.
+Return a JSON object {"memories": [...]} where each memory has:
+- "content": one self-contained statement, in the user's words where possible
+- "type": fact, preference, context or conversation
+- "category": personal, project or business
+- "priority": low, medium, high or critical
+- "update_key": a short stable key for the subject, such as "preference:editor" or
+  "work:company", shared by statements that replace each other; "" if none
+- "confidence": 0 to 1, how sure you are that the user wants this remembered
+Only include things worth remembering in later conversations: preferences, facts
+about the user and their work, decisions and explicit requests to remember.
+Never include passwords, keys, tokens or other secrets. Return {"memories": []}
+when there is nothing to remember.`
+
+// LLMExtractor extracts memories with an OpenAI chat model
+type LLMExtractor struct {
+	apiKey   string
+	model    string
+	endpoint string
+	client   *http.Client
+}
+
+// NewLLMExtractor creates an extractor backed by the OpenAI chat completions API
+func NewLLMExtractor(apiKey, model string, timeout time.Duration) *LLMExtractor {
+	return &LLMExtractor{
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: "https://api.openai.com/v1/chat/completions",
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// NewMemoryExtractorFromConfig builds the configured extractor, or returns nil
+// for the regex engine, which the memory service uses without configuration
+func NewMemoryExtractorFromConfig(cfg *config.Config, logger zerolog.Logger) (MemoryExtractor, error) {
+	switch cfg.Extraction.Engine {
+	case "", ExtractionEngineRegex:
+		return nil, nil
+	case ExtractionEngineLLM:
+		logger.Info().Str("model", cfg.Extraction.Model).Msg("LLM memory extraction enabled")
+		return NewLLMExtractor(cfg.OpenAI.APIKey, cfg.Extraction.Model, cfg.Extraction.Timeout), nil
+	default:
+		return nil, fmt.Errorf("invalid extraction engine: %s", cfg.Extraction.Engine)
+	}
+}
+
+// Name identifies the engine
+func (e *LLMExtractor) Name() string {
+	return ExtractionEngineLLM
+}
+
+// withAPIKey returns a copy of the extractor that sends requests with another key
+func (e *LLMExtractor) withAPIKey(apiKey string) *LLMExtractor {
+	copied := *e
+	copied.apiKey = apiKey
+	return &copied
+}
+
+// Extract asks the model for the memories in text. Memories with an unknown type
+// or category are dropped, and priorities and confidences are normalized.
+func (e *LLMExtractor) Extract(ctx context.Context, text string) (*Extraction, error) {
+	completion, err := chatCompletion(ctx, e.client, e.endpoint, e.apiKey, e.model, extractionPrompt, text)
+	if err != nil {
+		return nil, err
+	}
+
+	extraction := &Extraction{
+		InputTokens:  completion.InputTokens,
+		OutputTokens: completion.OutputTokens,
+	}
+	memories, err := parseExtractedMemories(completion.Content)
+	if err != nil {
+		return extraction, err
+	}
+	extraction.Memories = memories
+	return extraction, nil
+}
+
+// chatCompletionResult is the answer of a chat model with its token usage
+type chatCompletionResult struct {
+	Content      string
+	InputTokens  int
+	OutputTokens int
+}
+
+// chatCompletion sends a system and a user message to an OpenAI chat model and
+// returns its JSON answer
+func chatCompletion(ctx context.Context, client *http.Client, endpoint, apiKey, model, system, user string) (*chatCompletionResult, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no completion returned")
+	}
+
+	return &chatCompletionResult{
+		Content:      response.Choices[0].Message.Content,
+		InputTokens:  response.Usage.PromptTokens,
+		OutputTokens: response.Usage.CompletionTokens,
+	}, nil
+}
+
+// parseExtractedMemories decodes the model's answer, keeping the memories that
+// can be stored
+func parseExtractedMemories(content string) ([]DetectedMemory, error) {
+	var answer struct {
+		Memories []struct {
+			Content    string  `json:"content"`
+			Type       string  `json:"type"`
+			Category   string  `json:"category"`
+			Priority   string  `json:"priority"`
+			UpdateKey  string  `json:"update_key"`
+			Confidence float64 `json:"confidence"`
+		} `json:"memories"`
+	}
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return nil, fmt.Errorf("failed to parse extracted memories: %w", err)
+	}
+
+	var memories []DetectedMemory
+	for _, extracted := range answer.Memories {
+		content := strings.TrimSpace(extracted.Content)
+		memoryType := strings.ToLower(extracted.Type)
+		category := strings.ToLower(extracted.Category)
+		if content == "" || !models.IsValidType(memoryType) || !models.IsValidCategory(category) {
+			continue
+		}
+		// The model is told not to, but secrets must never be stored
+		if containsSensitiveInfo(content) {
+			continue
+		}
+
+		confidence := extracted.Confidence
+		if confidence <= 0 || confidence > 1 {
+			confidence = defaultMinConfidence
+		}
+		memories = append(memories, DetectedMemory{
+			Content:    content,
+			Type:       memoryType,
+			Category:   category,
+			Priority:   parseMemoryPriority(extracted.Priority),
+			UpdateKey:  strings.ToLower(strings.TrimSpace(extracted.UpdateKey)),
+			Confidence: confidence,
+			DueAt:      ParseRelativeDate(content, time.Now()),
+		})
+		if len(memories) == maxExtractedMemories {
+			break
+		}
+	}
+	return memories, nil
+}
+
+// parseMemoryPriority returns the priority named by p, or medium
+func parseMemoryPriority(p string) MemoryPriority {
+	switch strings.ToLower(strings.TrimSpace(p)) {
+	case "low":
+		return LowPriority
+	case "high":
+		return HighPriority
+	case "critical":
+		return CriticalPriority
+	default:
+		return MediumPriority
+	}
+}
+
+// GetMemoryExtractor returns the configured memory extractor; without one the
+// built-in patterns are used
+func (s *MemoryService) GetMemoryExtractor() MemoryExtractor {
+	if extractor, ok := s.config["memory_extractor"].(MemoryExtractor); ok {
+		return extractor
+	}
+	return RegexExtractor{}
+}
+
+// extractMemories finds the memories in text with the configured extractor. A
+// language-model extractor uses the user's own OpenAI key when they have one and
+// counts against the LLM budget; when the budget is spent or the request fails,
+// the built-in patterns are used instead. It returns the engine that ran.
+func (s *MemoryService) extractMemories(ctx context.Context, text string) ([]DetectedMemory, string) {
+	extractor := s.GetMemoryExtractor()
+	if llm, ok := extractor.(*LLMExtractor); ok {
+		if apiKey := s.userOpenAIKey(ctx); apiKey != "" {
+			extractor = llm.withAPIKey(apiKey)
+		}
+	}
+
+	if extractor.Name() != ExtractionEngineRegex {
+		if err := s.CheckLLMBudget(ctx, LLMFeatureExtraction); err == nil {
+			extraction, err := extractor.Extract(ctx, text)
+			if extraction != nil && (extraction.InputTokens > 0 || extraction.OutputTokens > 0) {
+				if _, usageErr := s.RecordLLMUsage(ctx, LLMFeatureExtraction, extraction.InputTokens, extraction.OutputTokens); usageErr != nil {
+					s.logger.Warn().Err(usageErr).Msg("failed to record extraction usage")
+				}
+			}
+			if err == nil {
+				return extraction.Memories, extractor.Name()
+			}
+			s.logger.Warn().Err(err).Str("engine", extractor.Name()).Msg("memory extraction failed, using patterns")
+		}
+	}
+
+	extraction, _ := RegexExtractor{}.Extract(ctx, text)
+	return extraction.Memories, ExtractionEngineRegex
+}
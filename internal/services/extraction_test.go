@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// newTestLLMExtractor returns an extractor whose chat model always answers with
+// answer, counting the requests it gets
+func newTestLLMExtractor(t *testing.T, answer string, requests *int) *LLMExtractor {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		var req struct {
+			Model          string            `json:"model"`
+			ResponseFormat map[string]string `json:"response_format"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "gpt-4o-mini", req.Model)
+		assert.Equal(t, "json_object", req.ResponseFormat["type"])
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": answer}}},
+			"usage":   map[string]int{"prompt_tokens": 1000, "completion_tokens": 200},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	extractor := NewLLMExtractor("sk-test", "gpt-4o-mini", 5*time.Second)
+	extractor.endpoint = server.URL
+	return extractor
+}
+
+func TestRegexExtractor(t *testing.T) {
+	extraction, err := RegexExtractor{}.Extract(context.Background(), "Hi. I prefer tea over coffee.\nMy editor is vim")
+	require.NoError(t, err)
+	require.Len(t, extraction.Memories, 2)
+	assert.Equal(t, "I prefer tea over coffee.", extraction.Memories[0].Content)
+	assert.Equal(t, models.TypePreference, extraction.Memories[0].Type)
+	assert.Equal(t, "My editor is vim", extraction.Memories[1].Content)
+	assert.Zero(t, extraction.InputTokens)
+}
+
+func TestLLMExtractor_Extract(t *testing.T) {
+	requests := 0
+	extractor := newTestLLMExtractor(t, `{"memories": [
+		{"content": "Prefers green tea in the afternoon", "type": "preference", "category": "personal", "priority": "HIGH", "update_key": "preference:Tea", "confidence": 0.8},
+		{"content": "Their database password is hunter2", "type": "fact", "category": "personal"},
+		{"content": "Unknown type", "type": "opinion", "category": "personal"},
+		{"content": "Works at Acme", "type": "fact", "category": "business", "confidence": 7}
+	]}`, &requests)
+
+	extraction, err := extractor.Extract(context.Background(), "I drink green tea in the afternoon, and I work at Acme.")
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 1000, extraction.InputTokens)
+	assert.Equal(t, 200, extraction.OutputTokens)
+
+	// Secrets and unknown types are dropped; priorities, keys and out-of-range
+	// confidences are normalized
+	require.Len(t, extraction.Memories, 2)
+	assert.Equal(t, DetectedMemory{
+		Content:    "Prefers green tea in the afternoon",
+		Type:       models.TypePreference,
+		Category:   models.CategoryPersonal,
+		Priority:   HighPriority,
+		UpdateKey:  "preference:tea",
+		Confidence: 0.8,
+	}, extraction.Memories[0])
+	assert.Equal(t, MediumPriority, extraction.Memories[1].Priority)
+	assert.Equal(t, defaultMinConfidence, extraction.Memories[1].Confidence)
+
+	_, err = newTestLLMExtractor(t, "not json", &requests).Extract(context.Background(), "x")
+	assert.Error(t, err)
+}
+
+func TestMemoryService_ProcessContentWithLLM(t *testing.T) {
+	ctx := context.Background()
+	requests := 0
+	extractor := newTestLLMExtractor(t, `{"memories": [
+		{"content": "Prefers Go for backend services", "type": "preference", "category": "project", "confidence": 0.9}
+	]}`, &requests)
+	budget := &LLMBudget{UserDailyUSD: 0.001, InputCostPerMillion: 1}
+	service := setupMemoryService(t, map[string]interface{}{"memory_extractor": extractor, "llm_budget": budget})
+
+	result, err := service.ProcessContent(ctx, ProcessContentRequest{Content: "User: Go is what I'd pick for backends.\nAssistant: Great."})
+	require.NoError(t, err)
+	assert.Equal(t, ExtractionEngineLLM, result.Engine)
+	require.Len(t, result.Captured, 1)
+	assert.Equal(t, "Prefers Go for backend services", result.Captured[0].Memory.Content)
+	assert.Equal(t, models.CategoryProject, result.Captured[0].Memory.Category)
+
+	status, err := service.LLMBudgetStatus(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.001, status.ByFeature[LLMFeatureExtraction], 1e-9)
+
+	// With the budget spent the patterns are used instead
+	result, err = service.ProcessContent(ctx, ProcessContentRequest{Content: "I prefer tabs over spaces."})
+	require.NoError(t, err)
+	assert.Equal(t, ExtractionEngineRegex, result.Engine)
+	assert.Equal(t, 1, requests)
+	require.Len(t, result.Captured, 1)
+	assert.Equal(t, "I prefer tabs over spaces.", result.Captured[0].Sentence)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Feedback on a memory returned for a query
+const (
+	FeedbackHelpful    = "helpful"
+	FeedbackIrrelevant = "irrelevant"
+)
+
+// defaultFeedbackWeight applies when feedback_weight is not configured. It is the
+// most feedback can add to or take from a memory's relevance, comparable to the
+// high priority boost.
+const defaultFeedbackWeight = 0.05
+
+// feedbackSmoothing damps the boost of memories with few votes, so a single vote
+// moves a memory a third of the way to the full weight
+const feedbackSmoothing = 2
+
+// topFeedbackMemories bounds the memories listed in feedback stats
+const topFeedbackMemories = 10
+
+// MemoryFeedbackSummary is the feedback a memory has received and the ranking
+// boost learned from it
+type MemoryFeedbackSummary struct {
+	MemoryID   uint    `json:"memory_id"`
+	Helpful    int64   `json:"helpful"`
+	Irrelevant int64   `json:"irrelevant"`
+	Boost      float64 `json:"boost"`
+}
+
+// FeedbackStats summarizes a user's feedback for the dashboard
+type FeedbackStats struct {
+	Helpful       int64 `json:"helpful"`
+	Irrelevant    int64 `json:"irrelevant"`
+	MemoriesRated int64 `json:"memories_rated"`
+	// Memories lists the memories with the most feedback
+	Memories []MemoryFeedbackSummary `json:"memories"`
+}
+
+// feedbackWeight returns the configured maximum feedback boost. Zero turns the
+// boost off.
+func (s *MemoryService) feedbackWeight() float64 {
+	if weight, ok := s.config["feedback_weight"].(float64); ok && weight >= 0 {
+		return weight
+	}
+	return defaultFeedbackWeight
+}
+
+// feedbackBoost is the ranking boost for a memory's net feedback: weight times
+// (helpful - irrelevant) / (votes + feedbackSmoothing), between -weight and weight
+func feedbackBoost(weight float64, helpful, irrelevant int64) float64 {
+	return weight * float64(helpful-irrelevant) / float64(helpful+irrelevant+feedbackSmoothing)
+}
+
+// feedbackBoostSQL renders feedbackBoost as a correlated subquery for the memory
+// whose ID is in the given column, or "0" when the boost is off
+func (s *MemoryService) feedbackBoostSQL(idColumn string) string {
+	weight := s.feedbackWeight()
+	if weight == 0 {
+		return "0"
+	}
+	return fmt.Sprintf(
+		"COALESCE((SELECT %s * SUM(CASE WHEN f.helpful THEN 1.0 ELSE -1.0 END) / (COUNT(*) + %d) FROM memory_feedback f WHERE f.memory_id = %s), 0)",
+		formatBoost(weight), feedbackSmoothing, idColumn,
+	)
+}
+
+// RecordFeedback marks a memory returned for a query as helpful or irrelevant.
+// A new vote for the same memory and query replaces the previous one, so
+// repeating a search cannot inflate the boost.
+func (s *MemoryService) RecordFeedback(ctx context.Context, memoryID uint, query, feedback string) (*MemoryFeedbackSummary, error) {
+	var helpful bool
+	switch feedback {
+	case FeedbackHelpful:
+		helpful = true
+	case FeedbackIrrelevant:
+	default:
+		return nil, utils.InvalidFieldError("feedback", "must be helpful or irrelevant")
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, utils.RequiredFieldError("query")
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("id = ? AND user_id = ?", memoryID, s.userID).
+		Count(&count).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory by id", err)
+	}
+	if count == 0 {
+		return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d", memoryID))
+	}
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("memory_id = ? AND user_id = ? AND query = ?", memoryID, s.userID, query).
+			Delete(&models.MemoryFeedback{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MemoryFeedback{
+			UserID:   s.userID,
+			MemoryID: memoryID,
+			Query:    query,
+			Helpful:  helpful,
+		}).Error
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Uint("id", memoryID).Msg("failed to record memory feedback")
+		return nil, utils.WrapDatabaseError("record memory feedback", err)
+	}
+
+	summaries, err := s.feedbackSummaries(s.db.WithContext(ctx).Where("memory_id = ?", memoryID))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info().
+		Uint("id", memoryID).
+		Str("feedback", feedback).
+		Float64("boost", summaries[0].Boost).
+		Msg("recorded memory feedback")
+	return &summaries[0], nil
+}
+
+// GetFeedbackStats returns the user's feedback totals and the memories with the
+// most feedback
+func (s *MemoryService) GetFeedbackStats(ctx context.Context) (*FeedbackStats, error) {
+	var totals struct {
+		Helpful       int64
+		Irrelevant    int64
+		MemoriesRated int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.MemoryFeedback{}).
+		Select(`COALESCE(SUM(CASE WHEN helpful THEN 1 ELSE 0 END), 0) AS helpful,
+			COALESCE(SUM(CASE WHEN helpful THEN 0 ELSE 1 END), 0) AS irrelevant,
+			COUNT(DISTINCT memory_id) AS memories_rated`).
+		Where("user_id = ?", s.userID).
+		Scan(&totals).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get feedback stats", err)
+	}
+
+	memories, err := s.feedbackSummaries(s.db.WithContext(ctx).
+		Order("COUNT(*) DESC").
+		Order("memory_id").
+		Limit(topFeedbackMemories))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FeedbackStats{
+		Helpful:       totals.Helpful,
+		Irrelevant:    totals.Irrelevant,
+		MemoriesRated: totals.MemoriesRated,
+		Memories:      memories,
+	}, nil
+}
+
+// feedbackSummaries counts the user's feedback per memory, narrowed by scope
+func (s *MemoryService) feedbackSummaries(scope *gorm.DB) ([]MemoryFeedbackSummary, error) {
+	var summaries []MemoryFeedbackSummary
+	if err := scope.Model(&models.MemoryFeedback{}).
+		Select(`memory_id,
+			SUM(CASE WHEN helpful THEN 1 ELSE 0 END) AS helpful,
+			SUM(CASE WHEN helpful THEN 0 ELSE 1 END) AS irrelevant`).
+		Where("user_id = ?", s.userID).
+		Group("memory_id").
+		Scan(&summaries).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory feedback", err)
+	}
+
+	weight := s.feedbackWeight()
+	for i := range summaries {
+		summaries[i].Boost = feedbackBoost(weight, summaries[i].Helpful, summaries[i].Irrelevant)
+	}
+	return summaries, nil
+}
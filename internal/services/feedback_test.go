@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestFeedbackBoost(t *testing.T) {
+	assert.Equal(t, 0.0, feedbackBoost(0.05, 0, 0))
+	assert.InDelta(t, 0.05/3, feedbackBoost(0.05, 1, 0), 1e-9)
+	assert.InDelta(t, -0.05/3, feedbackBoost(0.05, 0, 1), 1e-9)
+	// Many votes approach, but never pass, the full weight
+	assert.InDelta(t, 0.05*100/102, feedbackBoost(0.05, 100, 0), 1e-9)
+}
+
+func TestRecordFeedback(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "The staging database lives in eu-west-1")
+
+	summary, err := service.RecordFeedback(ctx, memory.ID, "Where is staging?", FeedbackHelpful)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Helpful)
+	assert.Greater(t, summary.Boost, 0.0)
+
+	// A second vote for the same query replaces the first
+	summary, err = service.RecordFeedback(ctx, memory.ID, "where is staging?", FeedbackIrrelevant)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), summary.Helpful)
+	assert.Equal(t, int64(1), summary.Irrelevant)
+	assert.Less(t, summary.Boost, 0.0)
+
+	_, err = service.RecordFeedback(ctx, memory.ID, "staging", "meh")
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.RecordFeedback(ctx, memory.ID, " ", FeedbackHelpful)
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.RecordFeedback(ctx, memory.ID+100, "staging", FeedbackHelpful)
+	assert.True(t, utils.IsNotFoundError(err))
+}
+
+func TestSearch_FeedbackReranksKeywordMatches(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	older, _ := storeTestMemory(t, service, "Deploys run from the release branch")
+	newer, _ := storeTestMemory(t, service, "Deploys are frozen on Fridays")
+
+	results, err := service.Search(ctx, SearchRequest{Query: "deploys"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, newer.ID, results[0].ID)
+
+	_, err = service.RecordFeedback(ctx, older.ID, "deploys", FeedbackHelpful)
+	require.NoError(t, err)
+	_, err = service.RecordFeedback(ctx, newer.ID, "deploys", FeedbackIrrelevant)
+	require.NoError(t, err)
+
+	results, err = service.Search(ctx, SearchRequest{Query: "deploys"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, older.ID, results[0].ID)
+
+	// With no weight, feedback is recorded but ignored when ranking
+	service.config["feedback_weight"] = 0.0
+	results, err = service.Search(ctx, SearchRequest{Query: "deploys"})
+	require.NoError(t, err)
+	assert.Equal(t, newer.ID, results[0].ID)
+}
+
+func TestGetFeedbackStats(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	first, _ := storeTestMemory(t, service, "Standup is at 9:30")
+	second, _ := storeTestMemory(t, service, "Retro is every other Thursday")
+
+	for _, query := range []string{"standup", "meeting times"} {
+		_, err := service.RecordFeedback(ctx, first.ID, query, FeedbackHelpful)
+		require.NoError(t, err)
+	}
+	_, err := service.RecordFeedback(ctx, second.ID, "standup", FeedbackIrrelevant)
+	require.NoError(t, err)
+
+	// Other users' feedback is not counted
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, 2)
+	otherMemory, err := other.Store(ctx, StoreRequest{Content: "Standup is at 10", Category: models.CategoryProject, Type: models.TypeFact})
+	require.NoError(t, err)
+	_, err = other.RecordFeedback(ctx, otherMemory.ID, "standup", FeedbackHelpful)
+	require.NoError(t, err)
+
+	stats, err := service.GetFeedbackStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Helpful)
+	assert.Equal(t, int64(1), stats.Irrelevant)
+	assert.Equal(t, int64(2), stats.MemoriesRated)
+	require.Len(t, stats.Memories, 2)
+	assert.Equal(t, first.ID, stats.Memories[0].MemoryID)
+	assert.Equal(t, int64(2), stats.Memories[0].Helpful)
+
+	memoryStats, err := service.GetMemoryStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, stats, memoryStats["feedback"])
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Full-text search runs against memories.content_tsv, a tsvector column generated
+// from the content and indexed with GIN (see database.RunMigrations). Queries are
+// parsed with websearch_to_tsquery, so they take the syntax of web search boxes:
+// "quoted phrases", or between alternatives, and -excluded words.
+const (
+	// contentSearchVector is the column holding the content's lexemes
+	contentSearchVector = "content_tsv"
+	// fullTextQuery parses a search query into a tsquery
+	fullTextQuery = "websearch_to_tsquery('english', ?)"
+)
+
+// fullTextSearch reports whether keyword search runs against the full-text
+// index. SQLite, used in tests, has none and matches substrings instead.
+func (s *MemoryService) fullTextSearch() bool {
+	return s.db.Dialector.Name() == "postgres"
+}
+
+// keywordCondition narrows a query to memories matching the search terms.
+// Encrypted memories match through their keyword index.
+func (s *MemoryService) keywordCondition(query *gorm.DB, terms string) *gorm.DB {
+	condition, args := "LOWER(content) LIKE ?", []interface{}{fmt.Sprintf("%%%s%%", strings.ToLower(terms))}
+	if s.fullTextSearch() {
+		condition, args = contentSearchVector+" @@ "+fullTextQuery, []interface{}{terms}
+	}
+	if index := s.contentBlindIndex(); index != nil {
+		if blind, blindArgs := blindKeywordCondition(index, terms); blind != "" {
+			condition = "((" + condition + ") OR (" + blind + "))"
+			args = append(args, blindArgs...)
+		}
+	}
+	return query.Where(condition, args...)
+}
+
+// keywordOrder ranks keyword matches by ts_rank plus the priority and feedback
+// boosts, as semantic search adds them to similarity. Substring matches carry no
+// rank, so there the boosts alone order them.
+func (s *MemoryService) keywordOrder(query *gorm.DB, terms string) *gorm.DB {
+	boosts := s.priorityBoosts().sqlExpression("priority") + " + " + s.feedbackBoostSQL("memories.id")
+	if !s.fullTextSearch() {
+		return query.Order(boosts + " DESC")
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank(" + contentSearchVector + ", " + fullTextQuery + ") + " + boosts + " DESC",
+		Vars:               []interface{}{terms},
+		WithoutParentheses: true,
+	}})
+}
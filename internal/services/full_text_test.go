@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+)
+
+func TestMemoryService_Search_FullText(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.PostgresDB(t)
+	service := NewMemoryService(db, nil, zerolog.New(nil).Level(zerolog.Disabled), map[string]interface{}{})
+
+	store := func(content string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{Content: content, Category: models.CategoryProject, Type: models.TypeFact})
+		require.NoError(t, err)
+		return memory
+	}
+	deploys := store("Deploys of the payments service run on Fridays")
+	staging := store("The payments service deploys to staging first")
+	store("Invoices are emailed monthly")
+
+	search := func(query string) []uint {
+		memories, err := service.Search(ctx, SearchRequest{Query: query})
+		require.NoError(t, err)
+		ids := make([]uint, len(memories))
+		for i, memory := range memories {
+			ids[i] = memory.ID
+		}
+		return ids
+	}
+
+	t.Run("matches stemmed words in any order", func(t *testing.T) {
+		assert.ElementsMatch(t, []uint{deploys.ID, staging.ID}, search("payment deploying"))
+	})
+
+	t.Run("takes web search syntax", func(t *testing.T) {
+		assert.Equal(t, []uint{deploys.ID}, search(`"payments service" -staging`))
+		assert.ElementsMatch(t, []uint{deploys.ID, staging.ID}, search("fridays or staging"))
+	})
+
+	t.Run("ranks better matches first", func(t *testing.T) {
+		assert.Equal(t, []uint{staging.ID, deploys.ID}, search("staging deploys or payments"))
+	})
+
+	t.Run("every memory is indexed and counted alike", func(t *testing.T) {
+		var indexed int64
+		require.NoError(t, db.Model(&models.Memory{}).Where("content_tsv IS NOT NULL").Count(&indexed).Error)
+		assert.EqualValues(t, 3, indexed)
+
+		total, err := service.countSearchMatches(ctx, SearchRequest{Query: "payment"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, total)
+	})
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pgvector/pgvector-go"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Search modes
+const (
+	// SearchModeKeyword matches the query's words against the content's full-text
+	// index
+	SearchModeKeyword = "keyword"
+	// SearchModeSemantic ranks memories by embedding similarity
+	SearchModeSemantic = "semantic"
+	// SearchModeHybrid fuses full-text and embedding rankings
+	SearchModeHybrid = "hybrid"
+)
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion. 60 is the value
+// from the original RRF paper and works well without tuning.
+const rrfK = 60
+
+// IsValidSearchMode checks if a given search mode is known
+func IsValidSearchMode(mode string) bool {
+	switch mode {
+	case SearchModeKeyword, SearchModeSemantic, SearchModeHybrid:
+		return true
+	default:
+		return false
+	}
+}
+
+// searchMode returns the request's search mode, defaulting from UseSemanticSearch
+func (r SearchRequest) searchMode() (string, error) {
+	if r.Mode == "" {
+		if r.UseSemanticSearch {
+			return SearchModeSemantic, nil
+		}
+		return SearchModeKeyword, nil
+	}
+	if !IsValidSearchMode(r.Mode) {
+		return "", utils.InvalidFieldError("searchMode", "must be keyword, semantic or hybrid")
+	}
+	return r.Mode, nil
+}
+
+// keywordOnly returns a copy of the request that runs as a keyword search
+func (r SearchRequest) keywordOnly() SearchRequest {
+	r.Mode = SearchModeKeyword
+	r.UseSemanticSearch = false
+	return r
+}
+
+// SearchHybrid runs a full-text search and a vector search for the query and merges
+// them with reciprocal rank fusion, so a memory that ranks well in either list (or
+// moderately in both) comes out on top. Exact terms that embeddings blur, such as
+// names and identifiers, are caught by the full-text side.
+func (s *MemoryService) SearchHybrid(ctx context.Context, req SearchRequest) ([]*models.Memory, error) {
+	// The sqlite schema used in tests has neither full-text search nor vectors
+	if s.db.Dialector.Name() != "postgres" || s.embedding == nil {
+		return s.Search(ctx, req.keywordOnly())
+	}
+
+	// Repeated searches are answered from the search cache
+	cached, cacheResults := s.cachedSearch(ctx, SearchModeHybrid, req)
+	if cached != nil {
+		return cached, nil
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	// Later pages fuse a wider window so they line up with the earlier ones
+	window := limit + req.Offset
+	candidates := window * priorityCandidateFactor
+
+	fullText, err := s.fullTextCandidates(ctx, req, candidates)
+	if err != nil {
+		s.logger.Error().Err(err).Str("query", req.Query).Msg("failed to perform full-text search")
+		return nil, err
+	}
+
+	var semantic []*models.Memory
+	queryEmbedding, embedErr := s.queryEmbedding(ctx, req.Query)
+	if embedErr != nil {
+		// Full-text results alone are still useful
+		s.logger.Warn().Err(embedErr).Msg("failed to generate query embedding, using full-text results only")
+	} else {
+		filters, args, err := s.searchFilters(req, []interface{}{pgvector.NewVector(queryEmbedding), s.userID, candidates})
+		if err != nil {
+			return nil, err
+		}
+		if semantic, err = s.scanScored(ctx, s.semanticSearchSQL(filters, window, 0), args); err != nil {
+			s.logger.Error().Err(err).Str("query", req.Query).Msg("failed to perform semantic search")
+			return nil, utils.WrapDatabaseError("semantic search", err)
+		}
+	}
+
+	memories := fuseRankings(fullText, semantic)
+	if len(memories) > req.Offset {
+		memories = memories[req.Offset:]
+	} else {
+		memories = nil
+	}
+	if len(memories) > limit {
+		memories = memories[:limit]
+	}
+
+	s.logger.Info().
+		Str("query", req.Query).
+		Int("full_text_results", len(fullText)).
+		Int("semantic_results", len(semantic)).
+		Int("results_count", len(memories)).
+		Msg("Hybrid search completed")
+
+	// Without the semantic side the results are incomplete, so they are not cached
+	if cacheResults != nil && embedErr == nil {
+		cacheResults(memories)
+	}
+
+	for _, memory := range memories {
+		if err := s.decryptContent(memory); err != nil {
+			s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+		}
+	}
+
+	if !req.ReadOnly {
+		s.recordAccess(ctx, memories)
+	}
+	s.auditReads(ctx, memories)
+
+	return memories, nil
+}
+
+// fullTextCandidates returns up to limit memories matching the query's terms,
+// best ts_rank first
+func (s *MemoryService) fullTextCandidates(ctx context.Context, req SearchRequest, limit int) ([]*models.Memory, error) {
+	filters, args, err := s.searchFilters(req, []interface{}{req.Query, s.userID, limit})
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT *, ts_rank(content_tsv, websearch_to_tsquery('english', $1)) AS rank
+		FROM memories
+		WHERE user_id = $2 AND content_tsv @@ websearch_to_tsquery('english', $1)%s
+		ORDER BY rank DESC
+		LIMIT $3
+	`, filters)
+
+	var memories []*models.Memory
+	if err := s.db.WithContext(ctx).Raw(sql, args...).Scan(&memories).Error; err != nil {
+		return nil, utils.WrapDatabaseError("full-text search", err)
+	}
+	return memories, nil
+}
+
+// fuseRankings merges ranked lists with reciprocal rank fusion: each memory scores
+// the sum of 1/(rrfK + rank) over the lists it appears in. Ties keep the order in
+// which memories were first seen. A memory keeps the similarity of whichever
+// list has one.
+func fuseRankings(rankings ...[]*models.Memory) []*models.Memory {
+	scores := make(map[uint]float64)
+	seen := make(map[uint]*models.Memory)
+	var fused []*models.Memory
+	for _, ranking := range rankings {
+		for rank, memory := range ranking {
+			if first, ok := seen[memory.ID]; !ok {
+				seen[memory.ID] = memory
+				fused = append(fused, memory)
+			} else if first.Similarity == nil {
+				first.Similarity = memory.Similarity
+			}
+			scores[memory.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return scores[fused[i].ID] > scores[fused[j].ID]
+	})
+	return fused
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func memoriesWithIDs(ids ...uint) []*models.Memory {
+	memories := make([]*models.Memory, len(ids))
+	for i, id := range ids {
+		memories[i] = &models.Memory{ID: id}
+	}
+	return memories
+}
+
+func memoryIDs(memories []*models.Memory) []uint {
+	ids := make([]uint, len(memories))
+	for i, memory := range memories {
+		ids[i] = memory.ID
+	}
+	return ids
+}
+
+func TestFuseRankings(t *testing.T) {
+	fullText := memoriesWithIDs(1, 2, 3)
+	semantic := memoriesWithIDs(4, 3, 1)
+
+	// 1 and 3 appear in both lists and beat the single-list leaders; 1 ranks
+	// higher on average so it comes first
+	fused := fuseRankings(fullText, semantic)
+	assert.Equal(t, []uint{1, 3, 4, 2}, memoryIDs(fused))
+
+	assert.Equal(t, []uint{4, 3, 1}, memoryIDs(fuseRankings(nil, semantic)))
+	assert.Empty(t, fuseRankings(nil, nil))
+}
+
+func TestFuseRankings_KeepsSimilarity(t *testing.T) {
+	similarity := 0.82
+	fullText := memoriesWithIDs(1, 2)
+	semantic := []*models.Memory{{ID: 1, Similarity: &similarity}}
+
+	fused := fuseRankings(fullText, semantic)
+	assert.Equal(t, []uint{1, 2}, memoryIDs(fused))
+	if assert.NotNil(t, fused[0].Similarity) {
+		assert.Equal(t, 0.82, *fused[0].Similarity)
+	}
+	assert.Nil(t, fused[1].Similarity)
+}
+
+func TestSemanticSearchSQL_AppliesThreshold(t *testing.T) {
+	svc := &MemoryService{config: map[string]interface{}{"similarity_threshold": 0.75}}
+	assert.Contains(t, svc.semanticSearchSQL("", 10, 0), "WHERE similarity >= 0.75")
+
+	svc.config = map[string]interface{}{}
+	assert.Contains(t, svc.semanticSearchSQL("", 10, 0), "WHERE similarity >= 0.3")
+}
+
+func TestSearchRequest_SearchMode(t *testing.T) {
+	mode, err := SearchRequest{UseSemanticSearch: true}.searchMode()
+	assert.NoError(t, err)
+	assert.Equal(t, SearchModeSemantic, mode)
+
+	mode, err = SearchRequest{UseSemanticSearch: true, Mode: SearchModeHybrid}.searchMode()
+	assert.NoError(t, err)
+	assert.Equal(t, SearchModeHybrid, mode)
+
+	mode, err = SearchRequest{}.searchMode()
+	assert.NoError(t, err)
+	assert.Equal(t, SearchModeKeyword, mode)
+
+	_, err = SearchRequest{Mode: "fuzzy"}.searchMode()
+	assert.True(t, utils.IsValidationError(err))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// DefaultIncognitoDuration is used when incognito mode is turned on without a duration
+	DefaultIncognitoDuration = time.Hour
+	// MaxIncognitoDuration caps a single incognito window so it cannot be left on by accident
+	MaxIncognitoDuration = 24 * time.Hour
+)
+
+// ErrIncognito is returned when storing a memory while incognito mode is on
+var ErrIncognito = errors.New("incognito mode is on")
+
+// IncognitoError reports a store refused because the user is in incognito mode
+type IncognitoError struct {
+	Until time.Time
+}
+
+func (e *IncognitoError) Error() string {
+	return fmt.Sprintf("incognito mode is on until %s: nothing is being remembered", e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *IncognitoError) Unwrap() error {
+	return ErrIncognito
+}
+
+// IncognitoStatus reports whether incognito mode is on and when it expires
+type IncognitoStatus struct {
+	Active bool       `json:"active"`
+	Until  *time.Time `json:"until,omitempty"`
+	// RemainingSeconds is the time left in the window, rounded down
+	RemainingSeconds int64 `json:"remaining_seconds,omitempty"`
+}
+
+// newIncognitoStatus builds the status for an expiry time; expired windows are inactive
+func newIncognitoStatus(until *time.Time, now time.Time) *IncognitoStatus {
+	if until == nil || !until.After(now) {
+		return &IncognitoStatus{}
+	}
+	return &IncognitoStatus{
+		Active:           true,
+		Until:            until,
+		RemainingSeconds: int64(until.Sub(now) / time.Second),
+	}
+}
+
+// IncognitoStatus returns the user's incognito status. Windows expire on their own;
+// nothing needs to run to turn them off.
+func (s *MemoryService) IncognitoStatus(ctx context.Context) (*IncognitoStatus, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).
+		Select("id", "incognito_until").
+		Where("id = ?", s.userID).
+		Limit(1).
+		Find(&users).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get incognito status", err)
+	}
+	if len(users) == 0 {
+		return &IncognitoStatus{}, nil
+	}
+	return newIncognitoStatus(users[0].IncognitoUntil, time.Now()), nil
+}
+
+// StartIncognito turns incognito mode on for the given duration, replacing any
+// current window. A zero duration uses DefaultIncognitoDuration.
+func (s *MemoryService) StartIncognito(ctx context.Context, duration time.Duration) (*IncognitoStatus, error) {
+	if duration == 0 {
+		duration = DefaultIncognitoDuration
+	}
+	if duration < time.Minute || duration > MaxIncognitoDuration {
+		return nil, utils.InvalidFieldError("duration",
+			fmt.Sprintf("must be between 1m and %s", MaxIncognitoDuration))
+	}
+
+	now := time.Now()
+	until := now.Add(duration)
+	if err := s.setIncognitoUntil(ctx, &until); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Time("until", until).Msg("incognito mode started")
+	return newIncognitoStatus(&until, now), nil
+}
+
+// StopIncognito turns incognito mode off immediately
+func (s *MemoryService) StopIncognito(ctx context.Context) (*IncognitoStatus, error) {
+	if err := s.setIncognitoUntil(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Msg("incognito mode stopped")
+	return &IncognitoStatus{}, nil
+}
+
+// setIncognitoUntil stores the end of the user's incognito window
+func (s *MemoryService) setIncognitoUntil(ctx context.Context, until *time.Time) error {
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Update("incognito_until", until)
+	if result.Error != nil {
+		s.logger.Error().Err(result.Error).Msg("failed to set incognito mode")
+		return utils.WrapDatabaseError("set incognito mode", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+	}
+	return nil
+}
+
+// checkIncognito refuses to remember anything while incognito mode is on
+func (s *MemoryService) checkIncognito(ctx context.Context) error {
+	status, err := s.IncognitoStatus(ctx)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to load incognito status, assuming it is off")
+		return nil
+	}
+	if status.Active {
+		return &IncognitoError{Until: *status.Until}
+	}
+	return nil
+}
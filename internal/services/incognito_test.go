@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func setupIncognitoService(t *testing.T) *MemoryService {
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.User{}))
+	require.NoError(t, service.db.Create(&models.User{ID: 1, Email: "user@example.com", Password: "x"}).Error)
+	return service
+}
+
+func TestIncognito_BlocksStorage(t *testing.T) {
+	ctx := context.Background()
+	service := setupIncognitoService(t)
+
+	status, err := service.StartIncognito(ctx, 30*time.Minute)
+	require.NoError(t, err)
+	assert.True(t, status.Active)
+	assert.InDelta(t, (30 * time.Minute).Seconds(), status.RemainingSeconds, 2)
+
+	_, err = service.Store(ctx, StoreRequest{
+		Content:  "remember that I am planning a surprise party",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+	})
+	assert.True(t, errors.Is(err, ErrIncognito))
+
+	detected, err := service.ProcessContentForMemory(ctx, "remember that I am planning a surprise party")
+	require.NoError(t, err)
+	assert.Empty(t, detected)
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Stopping resumes storage straight away
+	status, err = service.StopIncognito(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Active)
+
+	_, err = service.Store(ctx, StoreRequest{
+		Content:  "remembered again",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+	})
+	assert.NoError(t, err)
+}
+
+func TestIncognito_Expires(t *testing.T) {
+	ctx := context.Background()
+	service := setupIncognitoService(t)
+
+	_, err := service.StartIncognito(ctx, 0)
+	require.NoError(t, err)
+	status, err := service.IncognitoStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Active)
+	assert.WithinDuration(t, time.Now().Add(DefaultIncognitoDuration), *status.Until, 5*time.Second)
+
+	// A window in the past is reported as off without anything turning it off
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, service.setIncognitoUntil(ctx, &past))
+	status, err = service.IncognitoStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Active)
+	assert.Nil(t, status.Until)
+}
+
+func TestIncognito_DurationLimits(t *testing.T) {
+	service := setupIncognitoService(t)
+
+	_, err := service.StartIncognito(context.Background(), 30*time.Second)
+	assert.Error(t, err)
+	_, err = service.StartIncognito(context.Background(), MaxIncognitoDuration+time.Minute)
+	assert.Error(t, err)
+}
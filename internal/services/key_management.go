@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// NewKeyManagerFromConfig returns the configured key management service, or nil
+// when the master keys are configured in plaintext
+func NewKeyManagerFromConfig(cfg config.KMS) (utils.KeyManager, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "aws":
+		return utils.NewAWSKMS(context.Background(), cfg.Endpoint, cfg.Region, cfg.KeyID, utils.AWSCredentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+		}, cfg.Timeout)
+	case "gcp":
+		return utils.NewGCPKMS(cfg.Endpoint, cfg.KeyID, cfg.Token, cfg.Timeout), nil
+	case "vault":
+		return utils.NewVaultTransit(cfg.Endpoint, cfg.Mount, cfg.KeyID, cfg.Token, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown KMS provider: %s", cfg.Provider)
+	}
+}
+
+// NewEncryptionServiceFromConfig creates the encryption service for the
+// configured master keys, decrypting them with the key management service first
+// when one is configured
+func NewEncryptionServiceFromConfig(cfg config.Encryption) (*utils.EncryptionService, error) {
+	kms, err := NewKeyManagerFromConfig(cfg.KMS)
+	if err != nil {
+		return nil, err
+	}
+	if kms == nil {
+		return utils.NewEncryptionService(cfg.MasterKey, cfg.PreviousKeys...)
+	}
+	// Each request is bounded by the KMS timeout
+	return utils.NewEncryptionServiceWithKMS(context.Background(), kms, cfg.MasterKey, cfg.PreviousKeys...)
+}
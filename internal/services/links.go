@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxLinkDepth bounds how many links are followed from a memory
+const maxLinkDepth = 3
+
+// maxRelatedMemories bounds the memories returned by one traversal
+const maxRelatedMemories = 50
+
+// Directions of a link relative to the memory it was followed from
+const (
+	LinkOutgoing = "outgoing"
+	LinkIncoming = "incoming"
+)
+
+// IsValidLinkRelation checks if a given link relation is known
+func IsValidLinkRelation(relation string) bool {
+	switch relation {
+	case models.LinkSupersedes, models.LinkRelatedTo, models.LinkPartOfProject:
+		return true
+	default:
+		return false
+	}
+}
+
+// RelatedMemory is a memory reached by following links
+type RelatedMemory struct {
+	Memory *models.Memory `json:"memory"`
+	// FromID is the memory the link was followed from
+	FromID   uint   `json:"from_id"`
+	Relation string `json:"relation"`
+	// Direction is outgoing when FromID is the link's source and incoming when it
+	// is the target: a memory reached by an incoming supersedes link replaces FromID
+	Direction string `json:"direction"`
+	// Depth is how many links away from the starting memories it is
+	Depth int `json:"depth"`
+}
+
+// RelatedRequest selects which links to follow
+type RelatedRequest struct {
+	// Depth is how many links to follow, from 1 (the default) to maxLinkDepth
+	Depth int
+	// Relations limits the links followed; empty follows all
+	Relations []string
+}
+
+// validate normalizes the depth and checks the relations
+func (r *RelatedRequest) validate() error {
+	if r.Depth <= 0 {
+		r.Depth = 1
+	}
+	if r.Depth > maxLinkDepth {
+		return utils.InvalidFieldError("depth", fmt.Sprintf("must be at most %d", maxLinkDepth))
+	}
+	for _, relation := range r.Relations {
+		if !IsValidLinkRelation(relation) {
+			return utils.InvalidFieldError("relation", "must be supersedes, related_to or part_of_project")
+		}
+	}
+	return nil
+}
+
+// LinkMemories records that source relates to target. Linking the same memories
+// with the same relation again returns the existing link.
+func (s *MemoryService) LinkMemories(ctx context.Context, sourceID, targetID uint, relation string) (*models.MemoryLink, error) {
+	if !IsValidLinkRelation(relation) {
+		return nil, utils.InvalidFieldError("relation", "must be supersedes, related_to or part_of_project")
+	}
+	if sourceID == targetID {
+		return nil, utils.WrapValidationError("target_id", "a memory cannot be linked to itself")
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("id IN ? AND user_id = ?", []uint{sourceID, targetID}, s.userID).
+		Count(&count).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory", err)
+	}
+	if count != 2 {
+		return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d or %d", sourceID, targetID))
+	}
+
+	link := models.MemoryLink{
+		UserID:   s.userID,
+		SourceID: sourceID,
+		TargetID: targetID,
+		Relation: relation,
+	}
+	if err := s.db.WithContext(ctx).
+		Where("source_id = ? AND target_id = ? AND relation = ?", sourceID, targetID, relation).
+		FirstOrCreate(&link).Error; err != nil {
+		s.logger.Error().Err(err).Uint("source_id", sourceID).Uint("target_id", targetID).Msg("failed to link memories")
+		return nil, utils.WrapDatabaseError("link memories", err)
+	}
+
+	s.logger.Info().
+		Uint("source_id", sourceID).
+		Uint("target_id", targetID).
+		Str("relation", relation).
+		Msg("linked memories")
+	return &link, nil
+}
+
+// GetRelatedMemories returns the memories linked to a memory, following links in
+// both directions up to the requested depth
+func (s *MemoryService) GetRelatedMemories(ctx context.Context, memoryID uint, req RelatedRequest) ([]RelatedMemory, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("id = ? AND user_id = ?", memoryID, s.userID).
+		Count(&count).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory", err)
+	}
+	if count == 0 {
+		return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d", memoryID))
+	}
+
+	return s.RelatedMemories(ctx, []uint{memoryID}, req)
+}
+
+// RelatedMemories follows links breadth-first from the given memories, returning
+// each memory reached once, nearest first. The starting memories themselves are
+// left out, so search results can be extended with the context around them.
+func (s *MemoryService) RelatedMemories(ctx context.Context, memoryIDs []uint, req RelatedRequest) ([]RelatedMemory, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	visited := make(map[uint]bool, len(memoryIDs))
+	for _, id := range memoryIDs {
+		visited[id] = true
+	}
+	frontier := uniqueIDs(memoryIDs)
+	related := []RelatedMemory{}
+
+	for depth := 1; depth <= req.Depth && len(frontier) > 0 && len(related) < maxRelatedMemories; depth++ {
+		query := s.db.WithContext(ctx).
+			Where("user_id = ? AND (source_id IN ? OR target_id IN ?)", s.userID, frontier, frontier)
+		if len(req.Relations) > 0 {
+			query = query.Where("relation IN ?", req.Relations)
+		}
+		var links []models.MemoryLink
+		if err := query.Order("id ASC").Find(&links).Error; err != nil {
+			return nil, utils.WrapDatabaseError("get memory links", err)
+		}
+
+		inFrontier := make(map[uint]bool, len(frontier))
+		for _, id := range frontier {
+			inFrontier[id] = true
+		}
+		var reached []RelatedMemory
+		var ids []uint
+		for _, link := range links {
+			next := RelatedMemory{Relation: link.Relation, Depth: depth}
+			var id uint
+			switch {
+			case inFrontier[link.SourceID] && !visited[link.TargetID]:
+				next.FromID, id, next.Direction = link.SourceID, link.TargetID, LinkOutgoing
+			case inFrontier[link.TargetID] && !visited[link.SourceID]:
+				next.FromID, id, next.Direction = link.TargetID, link.SourceID, LinkIncoming
+			default:
+				continue
+			}
+			visited[id] = true
+			reached = append(reached, next)
+			ids = append(ids, id)
+		}
+
+		memories, err := s.linkedMemories(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for i, next := range reached {
+			memory, ok := memories[ids[i]]
+			if !ok {
+				// Deleted without its links
+				continue
+			}
+			next.Memory = memory
+			related = append(related, next)
+			frontier = append(frontier, memory.ID)
+			if len(related) == maxRelatedMemories {
+				break
+			}
+		}
+	}
+
+	return related, nil
+}
+
+// linkedMemories loads and decrypts the user's memories reached by a traversal step
+func (s *MemoryService) linkedMemories(ctx context.Context, ids []uint) (map[uint]*models.Memory, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var memories []models.Memory
+	query := s.db.WithContext(ctx).Where("id IN ? AND user_id = ?", ids, s.userID)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+	if err := query.Find(&memories).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get linked memories", err)
+	}
+
+	byID := make(map[uint]*models.Memory, len(memories))
+	for i := range memories {
+		memory := &memories[i]
+		if err := s.decryptContent(memory); err != nil {
+			s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt linked memory content")
+		}
+		byID[memory.ID] = memory
+	}
+	return byID, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestLinkMemories(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	oldPlan, _ := storeTestMemory(t, service, "We deploy with Heroku")
+	newPlan, _ := storeTestMemory(t, service, "We deploy with Fly.io")
+
+	link, err := service.LinkMemories(ctx, newPlan.ID, oldPlan.ID, models.LinkSupersedes)
+	require.NoError(t, err)
+	assert.Equal(t, newPlan.ID, link.SourceID)
+	assert.Equal(t, oldPlan.ID, link.TargetID)
+
+	// Linking again returns the same link
+	again, err := service.LinkMemories(ctx, newPlan.ID, oldPlan.ID, models.LinkSupersedes)
+	require.NoError(t, err)
+	assert.Equal(t, link.ID, again.ID)
+
+	_, err = service.LinkMemories(ctx, newPlan.ID, oldPlan.ID, "replaces")
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.LinkMemories(ctx, newPlan.ID, newPlan.ID, models.LinkRelatedTo)
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.LinkMemories(ctx, newPlan.ID, oldPlan.ID+100, models.LinkRelatedTo)
+	assert.True(t, utils.IsNotFoundError(err))
+}
+
+func TestGetRelatedMemories(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	project, _ := storeTestMemory(t, service, "Project Atlas: the billing rewrite")
+	oldPlan, _ := storeTestMemory(t, service, "Atlas launches in March")
+	newPlan, _ := storeTestMemory(t, service, "Atlas launch moved to May")
+	owner, _ := storeTestMemory(t, service, "Priya owns the Atlas rollout")
+
+	for _, link := range []struct {
+		source, target uint
+		relation       string
+	}{
+		{newPlan.ID, oldPlan.ID, models.LinkSupersedes},
+		{newPlan.ID, project.ID, models.LinkPartOfProject},
+		{owner.ID, project.ID, models.LinkPartOfProject},
+	} {
+		_, err := service.LinkMemories(ctx, link.source, link.target, link.relation)
+		require.NoError(t, err)
+	}
+
+	// One link away from the old plan is the plan that superseded it
+	related, err := service.GetRelatedMemories(ctx, oldPlan.ID, RelatedRequest{})
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	assert.Equal(t, newPlan.ID, related[0].Memory.ID)
+	assert.Equal(t, "Atlas launch moved to May", related[0].Memory.Content)
+	assert.Equal(t, models.LinkSupersedes, related[0].Relation)
+	assert.Equal(t, LinkIncoming, related[0].Direction)
+
+	// Following further reaches the project and the rest of it, each once
+	related, err = service.GetRelatedMemories(ctx, oldPlan.ID, RelatedRequest{Depth: 3})
+	require.NoError(t, err)
+	require.Len(t, related, 3)
+	assert.Equal(t, []uint{newPlan.ID, project.ID, owner.ID},
+		[]uint{related[0].Memory.ID, related[1].Memory.ID, related[2].Memory.ID})
+	assert.Equal(t, []int{1, 2, 3}, []int{related[0].Depth, related[1].Depth, related[2].Depth})
+	assert.Equal(t, project.ID, related[2].FromID)
+
+	// Relations limit which links are followed
+	related, err = service.GetRelatedMemories(ctx, newPlan.ID, RelatedRequest{Relations: []string{models.LinkPartOfProject}})
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	assert.Equal(t, project.ID, related[0].Memory.ID)
+	assert.Equal(t, LinkOutgoing, related[0].Direction)
+
+	// Starting memories are left out, as search results extended with their links
+	related, err = service.RelatedMemories(ctx, []uint{newPlan.ID, project.ID}, RelatedRequest{})
+	require.NoError(t, err)
+	require.Len(t, related, 2)
+	assert.ElementsMatch(t, []uint{oldPlan.ID, owner.ID}, []uint{related[0].Memory.ID, related[1].Memory.ID})
+
+	// Links to deleted memories are skipped
+	require.NoError(t, service.Delete(ctx, newPlan.ID))
+	related, err = service.GetRelatedMemories(ctx, oldPlan.ID, RelatedRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, related)
+
+	_, err = service.GetRelatedMemories(ctx, oldPlan.ID, RelatedRequest{Depth: maxLinkDepth + 1})
+	assert.True(t, utils.IsValidationError(err))
+	_, err = service.GetRelatedMemories(ctx, owner.ID+100, RelatedRequest{})
+	assert.True(t, utils.IsNotFoundError(err))
+}
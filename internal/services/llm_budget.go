@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Language-model features, whose usage is recorded separately
+const (
+	LLMFeatureExtraction    = "extraction"
+	LLMFeatureConsolidation = "consolidation"
+	LLMFeatureAsk           = "ask"
+	LLMFeatureRerank        = "rerank"
+	// LLMFeatureEmbedding records embedding usage for attribution. Embeddings are
+	// not optional, so they do not count against the budgets.
+	LLMFeatureEmbedding = "embedding"
+)
+
+// Budget scopes
+const (
+	LLMBudgetScopeUser   = "user"
+	LLMBudgetScopeGlobal = "global"
+)
+
+// ErrLLMBudgetExceeded is returned when a daily language-model budget is spent
+var ErrLLMBudgetExceeded = errors.New("language-model budget exceeded")
+
+// LLMBudgetError reports which daily budget is spent
+type LLMBudgetError struct {
+	Scope     string
+	SpentUSD  float64
+	BudgetUSD float64
+}
+
+func (e *LLMBudgetError) Error() string {
+	return fmt.Sprintf("daily %s language-model budget of $%.2f is spent ($%.4f used)", e.Scope, e.BudgetUSD, e.SpentUSD)
+}
+
+func (e *LLMBudgetError) Unwrap() error {
+	return ErrLLMBudgetExceeded
+}
+
+// LLMBudget holds the daily language-model budgets and token prices. Budgets of
+// zero are unlimited and only count spend on the server's key. One budget is
+// shared by every memory service.
+type LLMBudget struct {
+	DailyUSD                float64
+	UserDailyUSD            float64
+	InputCostPerMillion     float64
+	OutputCostPerMillion    float64
+	EmbeddingCostPerMillion float64
+}
+
+// NewLLMBudgetFromConfig creates the budget from the llm configuration
+func NewLLMBudgetFromConfig(cfg *config.Config) *LLMBudget {
+	return &LLMBudget{
+		DailyUSD:                cfg.LLM.DailyBudgetUSD,
+		UserDailyUSD:            cfg.LLM.UserDailyBudgetUSD,
+		InputCostPerMillion:     cfg.LLM.InputCostPerMillion,
+		OutputCostPerMillion:    cfg.LLM.OutputCostPerMillion,
+		EmbeddingCostPerMillion: cfg.LLM.EmbeddingCostPerMillion,
+	}
+}
+
+// Cost returns the USD cost of a request with the given token counts
+func (b *LLMBudget) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*b.InputCostPerMillion + float64(outputTokens)*b.OutputCostPerMillion) / 1e6
+}
+
+// EmbeddingCost returns the USD cost of embedding the given number of tokens
+func (b *LLMBudget) EmbeddingCost(tokens int) float64 {
+	return float64(tokens) * b.EmbeddingCostPerMillion / 1e6
+}
+
+// LLMBudgetStatus reports today's language-model spend on the server's key
+// against the budgets. Budgets of zero are unlimited.
+type LLMBudgetStatus struct {
+	Day             string             `json:"day"`
+	UserSpentUSD    float64            `json:"user_spent_usd"`
+	UserBudgetUSD   float64            `json:"user_budget_usd"`
+	GlobalSpentUSD  float64            `json:"global_spent_usd"`
+	GlobalBudgetUSD float64            `json:"global_budget_usd"`
+	Requests        int64              `json:"requests"`
+	ByFeature       map[string]float64 `json:"by_feature"`
+	// Exhausted is true when LLM features are falling back; ExhaustedScope says
+	// which budget is spent
+	Exhausted      bool   `json:"exhausted"`
+	ExhaustedScope string `json:"exhausted_scope,omitempty"`
+}
+
+// GetLLMBudget returns the shared language-model budget. Without one there are no
+// limits and usage is priced at the default rates.
+func (s *MemoryService) GetLLMBudget() *LLMBudget {
+	if budget, ok := s.config["llm_budget"].(*LLMBudget); ok {
+		return budget
+	}
+	return NewLLMBudgetFromConfig(config.NewDefault())
+}
+
+// CheckLLMBudget returns an *LLMBudgetError when the user's or the global daily
+// budget is spent. LLM features call it before each request and use their
+// non-LLM fallback when it fails. Users with their own API key are not limited.
+// Errors reading usage are logged and allow the request, so an accounting problem
+// does not turn features off.
+func (s *MemoryService) CheckLLMBudget(ctx context.Context, feature string) error {
+	budget := s.GetLLMBudget()
+	if budget.DailyUSD <= 0 && budget.UserDailyUSD <= 0 {
+		return nil
+	}
+	if s.userOpenAIKey(ctx) != "" {
+		return nil
+	}
+
+	status, err := s.LLMBudgetStatus(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Str("feature", feature).Msg("failed to check LLM budget, allowing request")
+		return nil
+	}
+	if !status.Exhausted {
+		return nil
+	}
+
+	budgetErr := &LLMBudgetError{Scope: status.ExhaustedScope, SpentUSD: status.UserSpentUSD, BudgetUSD: status.UserBudgetUSD}
+	if status.ExhaustedScope == LLMBudgetScopeGlobal {
+		budgetErr.SpentUSD = status.GlobalSpentUSD
+		budgetErr.BudgetUSD = status.GlobalBudgetUSD
+	}
+	s.logger.Warn().
+		Uint("user_id", s.userID).
+		Str("feature", feature).
+		Str("scope", budgetErr.Scope).
+		Float64("spent_usd", budgetErr.SpentUSD).
+		Float64("budget_usd", budgetErr.BudgetUSD).
+		Msg("LLM budget exceeded, using non-LLM fallback")
+	return budgetErr
+}
+
+// RecordLLMUsage adds a request's token usage to today's totals for the user and
+// feature, returning its cost in USD. Usage is attributed to the user's own key
+// when they have one.
+func (s *MemoryService) RecordLLMUsage(ctx context.Context, feature string, inputTokens, outputTokens int) (float64, error) {
+	cost := s.GetLLMBudget().Cost(inputTokens, outputTokens)
+	return cost, s.recordUsage(ctx, s.userID, feature, s.keySource(ctx), inputTokens, outputTokens, cost)
+}
+
+// recordUsage adds to today's usage totals for a user, feature and key source
+func (s *MemoryService) recordUsage(ctx context.Context, userID uint, feature, keySource string, inputTokens, outputTokens int, cost float64) error {
+	now := time.Now()
+
+	usage := &models.LLMUsage{
+		UserID:       userID,
+		Feature:      feature,
+		KeySource:    keySource,
+		Day:          llmDay(now),
+		Requests:     1,
+		InputTokens:  int64(inputTokens),
+		OutputTokens: int64(outputTokens),
+		CostUSD:      cost,
+	}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "feature"}, {Name: "key_source"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":      gorm.Expr("llm_usage.requests + 1"),
+			"input_tokens":  gorm.Expr("llm_usage.input_tokens + ?", inputTokens),
+			"output_tokens": gorm.Expr("llm_usage.output_tokens + ?", outputTokens),
+			"cost_usd":      gorm.Expr("llm_usage.cost_usd + ?", cost),
+			"updated_at":    now,
+		}),
+	}).Create(usage).Error
+	if err != nil {
+		return utils.WrapDatabaseError("record LLM usage", err)
+	}
+	return nil
+}
+
+// LLMBudgetStatus returns today's language-model spend for the user and overall
+func (s *MemoryService) LLMBudgetStatus(ctx context.Context) (*LLMBudgetStatus, error) {
+	budget := s.GetLLMBudget()
+	status := &LLMBudgetStatus{
+		Day:             llmDay(time.Now()),
+		UserBudgetUSD:   budget.UserDailyUSD,
+		GlobalBudgetUSD: budget.DailyUSD,
+		ByFeature:       make(map[string]float64),
+	}
+
+	var usage []models.LLMUsage
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND day = ? AND key_source = ? AND feature <> ?", s.userID, status.Day, models.KeySourceServer, LLMFeatureEmbedding).
+		Find(&usage).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get LLM usage", err)
+	}
+	for _, u := range usage {
+		status.UserSpentUSD += u.CostUSD
+		status.Requests += u.Requests
+		status.ByFeature[u.Feature] += u.CostUSD
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.LLMUsage{}).
+		Where("day = ? AND key_source = ? AND feature <> ?", status.Day, models.KeySourceServer, LLMFeatureEmbedding).
+		Select("COALESCE(SUM(cost_usd), 0)").
+		Scan(&status.GlobalSpentUSD).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get LLM usage", err)
+	}
+
+	switch {
+	case budget.UserDailyUSD > 0 && status.UserSpentUSD >= budget.UserDailyUSD:
+		status.Exhausted = true
+		status.ExhaustedScope = LLMBudgetScopeUser
+	case budget.DailyUSD > 0 && status.GlobalSpentUSD >= budget.DailyUSD:
+		status.Exhausted = true
+		status.ExhaustedScope = LLMBudgetScopeGlobal
+	}
+	return status, nil
+}
+
+// llmDay is the UTC day budgets are counted in
+func llmDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLMBudget_RecordUsage(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{
+		"llm_budget": &LLMBudget{InputCostPerMillion: 1, OutputCostPerMillion: 2},
+	})
+
+	cost, err := service.RecordLLMUsage(ctx, LLMFeatureExtraction, 1000, 500)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.002, cost, 1e-9)
+	_, err = service.RecordLLMUsage(ctx, LLMFeatureExtraction, 1000, 500)
+	require.NoError(t, err)
+	_, err = service.RecordLLMUsage(ctx, LLMFeatureRerank, 1000000, 0)
+	require.NoError(t, err)
+
+	status, err := service.LLMBudgetStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.Requests)
+	assert.InDelta(t, 1.004, status.UserSpentUSD, 1e-9)
+	assert.InDelta(t, 0.004, status.ByFeature[LLMFeatureExtraction], 1e-9)
+	assert.InDelta(t, 1.0, status.ByFeature[LLMFeatureRerank], 1e-9)
+	assert.False(t, status.Exhausted)
+
+	// No budgets means no limits
+	assert.NoError(t, service.CheckLLMBudget(ctx, LLMFeatureAsk))
+}
+
+func TestLLMBudget_UserBudget(t *testing.T) {
+	ctx := context.Background()
+	budget := &LLMBudget{UserDailyUSD: 0.01, InputCostPerMillion: 10}
+	service := setupMemoryService(t, map[string]interface{}{"llm_budget": budget})
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, map[string]interface{}{"llm_budget": budget}, 2)
+
+	require.NoError(t, service.CheckLLMBudget(ctx, LLMFeatureExtraction))
+	_, err := service.RecordLLMUsage(ctx, LLMFeatureExtraction, 1000, 0)
+	require.NoError(t, err)
+
+	err = service.CheckLLMBudget(ctx, LLMFeatureExtraction)
+	require.ErrorIs(t, err, ErrLLMBudgetExceeded)
+	var budgetErr *LLMBudgetError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, LLMBudgetScopeUser, budgetErr.Scope)
+
+	// Other users have budgets of their own
+	assert.NoError(t, other.CheckLLMBudget(ctx, LLMFeatureExtraction))
+
+	stats, err := service.GetMemoryStats(ctx)
+	require.NoError(t, err)
+	status := stats["llm_budget"].(*LLMBudgetStatus)
+	assert.True(t, status.Exhausted)
+	assert.Equal(t, LLMBudgetScopeUser, status.ExhaustedScope)
+}
+
+func TestLLMBudget_GlobalBudget(t *testing.T) {
+	ctx := context.Background()
+	budget := &LLMBudget{DailyUSD: 0.01, InputCostPerMillion: 10}
+	service := setupMemoryService(t, map[string]interface{}{"llm_budget": budget})
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, map[string]interface{}{"llm_budget": budget}, 2)
+
+	_, err := other.RecordLLMUsage(ctx, LLMFeatureConsolidation, 1000, 0)
+	require.NoError(t, err)
+
+	var budgetErr *LLMBudgetError
+	require.ErrorAs(t, service.CheckLLMBudget(ctx, LLMFeatureAsk), &budgetErr)
+	assert.Equal(t, LLMBudgetScopeGlobal, budgetErr.Scope)
+	assert.InDelta(t, 0.01, budgetErr.SpentUSD, 1e-9)
+}
@@ -0,0 +1,725 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// DefaultMaintenanceStaleAfter is how long a low or medium priority memory may
+	// go unused before the maintenance agent proposes pruning it
+	DefaultMaintenanceStaleAfter = 180 * 24 * time.Hour
+	// DefaultMaintenanceMaxActions caps the actions one report proposes
+	DefaultMaintenanceMaxActions = 100
+	// maxListedMaintenanceReports caps the reports listed, newest first
+	maxListedMaintenanceReports = 50
+)
+
+// What started a maintenance review
+const (
+	MaintenanceTriggerScheduled = "scheduled"
+	MaintenanceTriggerManual    = "manual"
+)
+
+// MaintenanceSettings reports whether the user has opted in to scheduled reviews
+// by the maintenance agent
+type MaintenanceSettings struct {
+	Enabled bool `json:"enabled"`
+	// LastReportAt is when the user's memories were last reviewed
+	LastReportAt *time.Time `json:"last_report_at,omitempty"`
+}
+
+// MaintenanceReview approves and rejects actions of a maintenance report by ID.
+// Actions in neither list stay proposed.
+type MaintenanceReview struct {
+	Approve []uint `json:"approve"`
+	Reject  []uint `json:"reject"`
+}
+
+// Validate checks that the review decides something and nothing twice
+func (r *MaintenanceReview) Validate() error {
+	if len(r.Approve) == 0 && len(r.Reject) == 0 {
+		return utils.InvalidFieldError("approve", "approve or reject at least one action")
+	}
+	for _, id := range r.Approve {
+		if slices.Contains(r.Reject, id) {
+			return utils.InvalidFieldError("reject", fmt.Sprintf("action %d is also approved", id))
+		}
+	}
+	return nil
+}
+
+// maintenanceProposals collects a review's actions, proposing at most one per
+// memory and never removing a memory another action keeps
+type maintenanceProposals struct {
+	actions []models.MaintenanceAction
+	// claimed are memories an action changes; kept are ones kept in place of another
+	claimed map[uint]bool
+	kept    map[uint]bool
+	max     int
+}
+
+func newMaintenanceProposals(max int) *maintenanceProposals {
+	return &maintenanceProposals{claimed: map[uint]bool{}, kept: map[uint]bool{}, max: max}
+}
+
+// full reports whether no more actions fit in the report
+func (p *maintenanceProposals) full() bool {
+	return len(p.actions) >= p.max
+}
+
+// add proposes an action unless the report is full or it touches a memory an
+// earlier action already does. It reports whether the action was added.
+func (p *maintenanceProposals) add(action models.MaintenanceAction) bool {
+	if p.full() || p.claimed[action.MemoryID] || p.kept[action.MemoryID] {
+		return false
+	}
+	if action.KeepID != nil {
+		if p.claimed[*action.KeepID] {
+			return false
+		}
+		p.kept[*action.KeepID] = true
+	}
+	p.claimed[action.MemoryID] = true
+	p.actions = append(p.actions, action)
+	return true
+}
+
+// maintenanceCandidate is a memory a detector considers acting on
+type maintenanceCandidate struct {
+	ID             uint
+	WorkspaceID    uint
+	Priority       string
+	UpdateKey      string
+	ContentHash    string
+	LastAccessedAt *time.Time
+	CreatedAt      time.Time
+}
+
+// maintenanceStaleAfter returns the configured idle time before pruning is proposed
+func (s *MemoryService) maintenanceStaleAfter() time.Duration {
+	if staleAfter, ok := s.config["maintenance_stale_after"].(time.Duration); ok && staleAfter > 0 {
+		return staleAfter
+	}
+	return DefaultMaintenanceStaleAfter
+}
+
+// maintenanceMaxActions returns the configured cap on actions per report
+func (s *MemoryService) maintenanceMaxActions() int {
+	if max, ok := s.config["maintenance_max_actions"].(int); ok && max > 0 {
+		return max
+	}
+	return DefaultMaintenanceMaxActions
+}
+
+// MaintenanceSettings returns whether the user has opted in to scheduled reviews
+// and when the last review ran
+func (s *MemoryService) MaintenanceSettings(ctx context.Context) (*MaintenanceSettings, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "maintenance_enabled").First(&user, s.userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+		}
+		return nil, utils.WrapDatabaseError("load maintenance settings", err)
+	}
+	settings := &MaintenanceSettings{Enabled: user.MaintenanceEnabled}
+
+	var last models.MaintenanceReport
+	err := s.db.WithContext(ctx).Select("created_at").Where("user_id = ?", s.userID).Order("id DESC").Take(&last).Error
+	switch {
+	case err == nil:
+		settings.LastReportAt = &last.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, utils.WrapDatabaseError("load maintenance reports", err)
+	}
+	return settings, nil
+}
+
+// SetMaintenanceEnabled opts the user in to or out of scheduled reviews. Reviews
+// the user asks for run either way.
+func (s *MemoryService) SetMaintenanceEnabled(ctx context.Context, enabled bool) (*MaintenanceSettings, error) {
+	result := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", s.userID).Update("maintenance_enabled", enabled)
+	if result.Error != nil {
+		return nil, utils.WrapDatabaseError("update maintenance settings", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+	}
+	return s.MaintenanceSettings(ctx)
+}
+
+// RunMaintenance reviews the user's memories and records a report of proposed
+// actions: duplicates to merge, memories a newer one contradicts, memories that
+// have gone unused and memories categorization rules would refile. Nothing is
+// changed until actions are approved with ReviewMaintenanceReport. Actions still
+// proposed by earlier reports expire, since this one supersedes them.
+func (s *MemoryService) RunMaintenance(ctx context.Context, trigger string) (*models.MaintenanceReport, error) {
+	proposals := newMaintenanceProposals(s.maintenanceMaxActions())
+
+	// Conflicts go first so the memory a newer one contradicts is deleted rather
+	// than merged or pruned
+	detectors := []func(context.Context, *maintenanceProposals) error{
+		s.proposeConflictResolutions,
+		s.proposeDuplicateMerges,
+		s.proposeRecategorizations,
+		s.proposeStalePrunes,
+	}
+	for _, detect := range detectors {
+		if proposals.full() {
+			break
+		}
+		if err := detect(ctx, proposals); err != nil {
+			s.logger.Error().Err(err).Msg("failed to review memories for maintenance")
+			return nil, utils.WrapDatabaseError("review memories", err)
+		}
+	}
+
+	scanned, err := s.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.MaintenanceReport{
+		UserID:  s.userID,
+		Trigger: trigger,
+		Scanned: int(scanned),
+		Actions: proposals.actions,
+	}
+	for i := range report.Actions {
+		report.Actions[i].UserID = s.userID
+		report.Actions[i].Status = models.MaintenanceProposed
+	}
+	err = s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MaintenanceAction{}).
+			Where("user_id = ? AND status = ?", s.userID, models.MaintenanceProposed).
+			Update("status", models.MaintenanceExpired).Error; err != nil {
+			return err
+		}
+		return tx.Create(report).Error
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to save maintenance report")
+		return nil, utils.WrapDatabaseError("save maintenance report", err)
+	}
+
+	s.logger.Info().
+		Uint("report_id", report.ID).
+		Str("trigger", trigger).
+		Int("scanned", report.Scanned).
+		Int("actions", len(report.Actions)).
+		Msg("reviewed memories for maintenance")
+	return report, nil
+}
+
+// proposeConflictResolutions proposes deleting memories a newer one contradicts:
+// older memories sharing an update key with a newer one in their workspace, and
+// memories another supersedes. Critical memories are left alone.
+func (s *MemoryService) proposeConflictResolutions(ctx context.Context, p *maintenanceProposals) error {
+	db := s.db.WithContext(ctx)
+
+	var candidates []maintenanceCandidate
+	if err := db.Model(&models.Memory{}).
+		Select("id, workspace_id, priority, update_key").
+		Where("user_id = ? AND update_key IN (?)", s.userID,
+			db.Model(&models.Memory{}).
+				Select("update_key").
+				Where("user_id = ? AND update_key IS NOT NULL AND update_key <> ''", s.userID).
+				Group("update_key").
+				Having("COUNT(*) > 1")).
+		Order("workspace_id, update_key, updated_at DESC, id DESC").
+		Scan(&candidates).Error; err != nil {
+		return err
+	}
+	var current *maintenanceCandidate
+	for i := range candidates {
+		candidate := &candidates[i]
+		if current == nil || current.WorkspaceID != candidate.WorkspaceID || current.UpdateKey != candidate.UpdateKey {
+			current = candidate
+			continue
+		}
+		if candidate.Priority == models.PriorityCritical {
+			continue
+		}
+		keep := current.ID
+		p.add(models.MaintenanceAction{
+			Kind:     models.MaintenanceResolveConflict,
+			MemoryID: candidate.ID,
+			KeepID:   &keep,
+			Reason:   fmt.Sprintf("Memory %d is newer and has the same update key (%s)", keep, candidate.UpdateKey),
+		})
+	}
+
+	var superseded []struct {
+		SourceID uint
+		TargetID uint
+	}
+	if err := db.Table("memory_links AS l").
+		Select("l.source_id, l.target_id").
+		Joins("JOIN memories AS t ON t.id = l.target_id AND t.user_id = l.user_id").
+		Joins("JOIN memories AS src ON src.id = l.source_id AND src.user_id = l.user_id").
+		Where("l.user_id = ? AND l.relation = ? AND t.priority <> ?", s.userID, models.LinkSupersedes, models.PriorityCritical).
+		Order("l.target_id").
+		Scan(&superseded).Error; err != nil {
+		return err
+	}
+	for _, link := range superseded {
+		keep := link.SourceID
+		p.add(models.MaintenanceAction{
+			Kind:     models.MaintenanceResolveConflict,
+			MemoryID: link.TargetID,
+			KeepID:   &keep,
+			Reason:   fmt.Sprintf("Memory %d supersedes it", keep),
+		})
+	}
+	return nil
+}
+
+// proposeDuplicateMerges proposes folding duplicates into the memory they repeat
+// in the same workspace: memories with identical content, and on postgres ones
+// whose embeddings are at least as similar as the duplicate threshold. The oldest
+// memory of the highest priority is kept.
+func (s *MemoryService) proposeDuplicateMerges(ctx context.Context, p *maintenanceProposals) error {
+	db := s.db.WithContext(ctx)
+
+	var candidates []maintenanceCandidate
+	if err := db.Model(&models.Memory{}).
+		Select("id, workspace_id, priority, content_hash").
+		Where("user_id = ? AND content_hash IN (?)", s.userID,
+			db.Model(&models.Memory{}).
+				Select("content_hash").
+				Where("user_id = ? AND content_hash IS NOT NULL AND content_hash <> ''", s.userID).
+				Group("content_hash").
+				Having("COUNT(*) > 1")).
+		Order("workspace_id, content_hash, id").
+		Scan(&candidates).Error; err != nil {
+		return err
+	}
+	for start := 0; start < len(candidates); {
+		end := start + 1
+		for end < len(candidates) && candidates[end].WorkspaceID == candidates[start].WorkspaceID &&
+			candidates[end].ContentHash == candidates[start].ContentHash {
+			end++
+		}
+		group := candidates[start:end]
+		start = end
+
+		keep := group[0]
+		for _, candidate := range group[1:] {
+			if priorityRank(candidate.Priority) > priorityRank(keep.Priority) {
+				keep = candidate
+			}
+		}
+		for _, candidate := range group {
+			if candidate.ID == keep.ID || candidate.Priority == models.PriorityCritical {
+				continue
+			}
+			keepID := keep.ID
+			p.add(models.MaintenanceAction{
+				Kind:       models.MaintenanceMergeDuplicate,
+				MemoryID:   candidate.ID,
+				KeepID:     &keepID,
+				Reason:     fmt.Sprintf("Same content as memory %d", keepID),
+				Similarity: 1,
+			})
+		}
+	}
+
+	// The sqlite schema used in tests has no vector type
+	if s.db.Dialector.Name() == "sqlite" || p.full() {
+		return nil
+	}
+	var near []struct {
+		ID         uint
+		Priority   string
+		KeepID     uint
+		Similarity float64
+	}
+	workspaceFilter, args := "", []interface{}{s.userID}
+	if workspaceID, ok := s.Workspace(); ok {
+		workspaceFilter = " AND m.workspace_id = ?"
+		args = append(args, workspaceID)
+	}
+	args = append(args, s.duplicateThreshold(), p.max)
+	if err := db.Raw(`
+		SELECT m.id, m.priority, n.id AS keep_id, n.similarity
+		FROM memories m
+		CROSS JOIN LATERAL (
+			SELECT o.id, 1 - (o.embedding <=> m.embedding) AS similarity
+			FROM memories o
+			WHERE o.user_id = m.user_id AND o.workspace_id = m.workspace_id AND o.id < m.id AND o.embedding IS NOT NULL
+			ORDER BY o.embedding <=> m.embedding
+			LIMIT 1
+		) n
+		WHERE m.user_id = ?`+workspaceFilter+` AND m.embedding IS NOT NULL AND n.similarity >= ?
+		ORDER BY m.id
+		LIMIT ?
+	`, args...).Scan(&near).Error; err != nil {
+		return err
+	}
+	for _, match := range near {
+		if match.Priority == models.PriorityCritical {
+			continue
+		}
+		keep := match.KeepID
+		p.add(models.MaintenanceAction{
+			Kind:       models.MaintenanceMergeDuplicate,
+			MemoryID:   match.ID,
+			KeepID:     &keep,
+			Reason:     fmt.Sprintf("Near-duplicate of memory %d", keep),
+			Similarity: match.Similarity,
+		})
+	}
+	return nil
+}
+
+// DuplicatePair is a memory that repeats another one
+type DuplicatePair struct {
+	Duplicate *models.Memory `json:"duplicate"`
+	// Keep is the memory a merge would keep
+	Keep       *models.Memory `json:"keep"`
+	Similarity float64        `json:"similarity"`
+}
+
+// FindDuplicates returns up to limit memories that repeat another, found the way
+// the maintenance agent finds them. Nothing is changed or recorded.
+func (s *MemoryService) FindDuplicates(ctx context.Context, limit int) ([]DuplicatePair, error) {
+	if limit <= 0 {
+		limit = DefaultMaintenanceMaxActions
+	}
+	proposals := newMaintenanceProposals(limit)
+	if err := s.proposeDuplicateMerges(ctx, proposals); err != nil {
+		return nil, utils.WrapDatabaseError("find duplicates", err)
+	}
+	if len(proposals.actions) == 0 {
+		return []DuplicatePair{}, nil
+	}
+
+	ids := make([]uint, 0, 2*len(proposals.actions))
+	for _, action := range proposals.actions {
+		ids = append(ids, action.MemoryID, *action.KeepID)
+	}
+	memories, err := s.List(ctx, ListRequest{WithinIDs: ids, Limit: len(ids)})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Memory, len(memories))
+	for _, memory := range memories {
+		byID[memory.ID] = memory
+	}
+
+	pairs := make([]DuplicatePair, 0, len(proposals.actions))
+	for _, action := range proposals.actions {
+		duplicate, keep := byID[action.MemoryID], byID[*action.KeepID]
+		if duplicate == nil || keep == nil {
+			continue
+		}
+		pairs = append(pairs, DuplicatePair{Duplicate: duplicate, Keep: keep, Similarity: action.Similarity})
+	}
+	return pairs, nil
+}
+
+// proposeRecategorizations proposes filing memories under the category and tags
+// of the enabled categorization rules they match
+func (s *MemoryService) proposeRecategorizations(ctx context.Context, p *maintenanceProposals) error {
+	preview, err := s.ApplyCategorizationRules(ctx, ApplyRulesRequest{Preview: true})
+	if err != nil {
+		return err
+	}
+	for _, change := range preview.Changes {
+		action := models.MaintenanceAction{
+			Kind:     models.MaintenanceRecategorize,
+			MemoryID: change.MemoryID,
+			AddTags:  change.AddedTags,
+			Reason:   fmt.Sprintf("Matches categorization rules: %s", strings.Join(change.Rules, ", ")),
+		}
+		if change.ToCategory != change.FromCategory {
+			action.Category = change.ToCategory
+		}
+		p.add(action)
+	}
+	return nil
+}
+
+// proposeStalePrunes proposes deleting low and medium priority memories that
+// have not been recalled, or were never recalled, for longer than the configured
+// idle time. Preferences are kept, since they stay true without being recalled.
+func (s *MemoryService) proposeStalePrunes(ctx context.Context, p *maintenanceProposals) error {
+	cutoff := time.Now().Add(-s.maintenanceStaleAfter())
+
+	var candidates []maintenanceCandidate
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Select("id, priority, last_accessed_at, created_at").
+		Where("user_id = ? AND priority IN ? AND type <> ?", s.userID,
+			[]string{models.PriorityLow, models.PriorityMedium}, models.TypePreference).
+		Where("COALESCE(last_accessed_at, created_at) < ?", cutoff).
+		Order("COALESCE(last_accessed_at, created_at), id").
+		Limit(p.max + len(p.claimed)).
+		Scan(&candidates).Error; err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		reason := fmt.Sprintf("Never recalled since it was stored on %s", candidate.CreatedAt.UTC().Format(time.DateOnly))
+		if candidate.LastAccessedAt != nil {
+			reason = fmt.Sprintf("Not recalled since %s", candidate.LastAccessedAt.UTC().Format(time.DateOnly))
+		}
+		p.add(models.MaintenanceAction{
+			Kind:     models.MaintenancePruneStale,
+			MemoryID: candidate.ID,
+			Reason:   reason,
+		})
+	}
+	return nil
+}
+
+// ListMaintenanceReports returns the user's latest maintenance reports, newest
+// first, without their actions
+func (s *MemoryService) ListMaintenanceReports(ctx context.Context) ([]models.MaintenanceReport, error) {
+	reports := []models.MaintenanceReport{}
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("id DESC").
+		Limit(maxListedMaintenanceReports).
+		Find(&reports).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list maintenance reports", err)
+	}
+	return reports, nil
+}
+
+// GetMaintenanceReport loads one of the user's maintenance reports with its actions
+func (s *MemoryService) GetMaintenanceReport(ctx context.Context, id uint) (*models.MaintenanceReport, error) {
+	var report models.MaintenanceReport
+	err := s.db.WithContext(ctx).
+		Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ? AND user_id = ?", id, s.userID).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.WrapNotFoundError("maintenance report", fmt.Sprintf("%d", id))
+	}
+	if err != nil {
+		return nil, utils.WrapDatabaseError("get maintenance report", err)
+	}
+	return &report, nil
+}
+
+// ReviewMaintenanceReport applies the approved actions of a report and rejects
+// the others given. Only proposed actions can be decided. An approved action
+// that cannot be applied, say because its memory was deleted since, is marked
+// failed with the reason and the rest still apply.
+func (s *MemoryService) ReviewMaintenanceReport(ctx context.Context, id uint, review MaintenanceReview) (*models.MaintenanceReport, error) {
+	if err := review.Validate(); err != nil {
+		return nil, err
+	}
+	report, err := s.GetMaintenanceReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*models.MaintenanceAction, len(report.Actions))
+	for i := range report.Actions {
+		byID[report.Actions[i].ID] = &report.Actions[i]
+	}
+	for _, actionID := range slices.Concat(review.Approve, review.Reject) {
+		action, ok := byID[actionID]
+		if !ok {
+			return nil, utils.InvalidFieldError("approve", fmt.Sprintf("action %d is not part of report %d", actionID, id))
+		}
+		if action.Status != models.MaintenanceProposed {
+			return nil, utils.InvalidFieldError("approve", fmt.Sprintf("action %d is already %s", actionID, action.Status))
+		}
+	}
+
+	counts := map[string]int{}
+	now := time.Now()
+	decide := func(action *models.MaintenanceAction, status string, cause error) error {
+		action.Status = status
+		action.DecidedAt = &now
+		if cause != nil {
+			action.Error = cause.Error()
+		}
+		counts[status]++
+		return s.db.WithContext(ctx).Model(action).Updates(map[string]interface{}{
+			"status":     action.Status,
+			"error":      action.Error,
+			"decided_at": action.DecidedAt,
+		}).Error
+	}
+	for _, actionID := range review.Reject {
+		if err := decide(byID[actionID], models.MaintenanceRejected, nil); err != nil {
+			return nil, utils.WrapDatabaseError("update maintenance action", err)
+		}
+	}
+	for _, actionID := range review.Approve {
+		action := byID[actionID]
+		status := models.MaintenanceApplied
+		cause := s.applyMaintenanceAction(ctx, action)
+		if cause != nil {
+			s.logger.Warn().Err(cause).Uint("action_id", action.ID).Str("kind", action.Kind).Msg("failed to apply maintenance action")
+			status = models.MaintenanceFailed
+		}
+		if err := decide(action, status, cause); err != nil {
+			return nil, utils.WrapDatabaseError("update maintenance action", err)
+		}
+	}
+
+	s.logActivity(ctx, models.ActivityMaintenanceReviewed, map[string]interface{}{
+		"report_id": report.ID,
+		"applied":   counts[models.MaintenanceApplied],
+		"failed":    counts[models.MaintenanceFailed],
+		"rejected":  counts[models.MaintenanceRejected],
+	})
+	return report, nil
+}
+
+// applyMaintenanceAction makes the change an approved action proposes
+func (s *MemoryService) applyMaintenanceAction(ctx context.Context, action *models.MaintenanceAction) error {
+	switch action.Kind {
+	case models.MaintenanceMergeDuplicate:
+		duplicate, err := s.loadForNeighbors(ctx, action.MemoryID)
+		if err != nil {
+			return err
+		}
+		metadata := map[string]interface{}{}
+		if len(duplicate.Metadata) > 0 {
+			if err := json.Unmarshal(duplicate.Metadata, &metadata); err != nil {
+				return fmt.Errorf("failed to parse metadata: %w", err)
+			}
+		}
+		if _, err := s.mergeDuplicate(ctx, *action.KeepID, StoreRequest{
+			Tags:     duplicate.Tags,
+			Metadata: metadata,
+			Priority: duplicate.Priority,
+		}); err != nil {
+			return err
+		}
+		return s.Delete(ctx, action.MemoryID)
+
+	case models.MaintenanceResolveConflict:
+		// The memory kept must still be there to take over
+		if _, err := s.loadForNeighbors(ctx, *action.KeepID); err != nil {
+			return err
+		}
+		return s.Delete(ctx, action.MemoryID)
+
+	case models.MaintenancePruneStale:
+		return s.Delete(ctx, action.MemoryID)
+
+	case models.MaintenanceRecategorize:
+		memory, err := s.loadForNeighbors(ctx, action.MemoryID)
+		if err != nil {
+			return err
+		}
+		tags := append([]string(nil), memory.Tags...)
+		for _, tag := range action.AddTags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		_, err = s.Update(ctx, action.MemoryID, UpdateRequest{Category: action.Category, Tags: tags})
+		return err
+
+	default:
+		return fmt.Errorf("unknown maintenance action %q", action.Kind)
+	}
+}
+
+// MaintenanceWorkerConfig tunes the scheduled maintenance agent
+type MaintenanceWorkerConfig struct {
+	// Interval is how often each opted-in user's memories are reviewed
+	Interval time.Duration
+	// CheckEvery is how often the worker looks for users due a review
+	CheckEvery time.Duration
+	// BatchSize caps the users reviewed per check
+	BatchSize int
+}
+
+// DefaultMaintenanceWorkerConfig returns the default maintenance agent settings
+func DefaultMaintenanceWorkerConfig() MaintenanceWorkerConfig {
+	return MaintenanceWorkerConfig{
+		Interval:   24 * time.Hour,
+		CheckEvery: 10 * time.Minute,
+		BatchSize:  20,
+	}
+}
+
+// MaintenanceWorker is the maintenance agent: it periodically reviews the
+// memories of users who opted in, leaving a report of proposed actions for each
+// to approve. It runs across all users and never changes a memory itself.
+type MaintenanceWorker struct {
+	service *MemoryService
+	config  MaintenanceWorkerConfig
+}
+
+// NewMaintenanceWorker creates a maintenance agent using the service's database
+// and configuration
+func NewMaintenanceWorker(service *MemoryService, config MaintenanceWorkerConfig) *MaintenanceWorker {
+	return &MaintenanceWorker{
+		service: service,
+		config:  config,
+	}
+}
+
+// Start runs the worker until the context is cancelled
+func (w *MaintenanceWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.CheckEvery)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.service.logger.Error().Err(err).Msg("maintenance run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce reviews the memories of opted-in users whose last review is older than
+// the interval, returning how many were reviewed. A review that fails is logged
+// and retried on the next run.
+func (w *MaintenanceWorker) RunOnce(ctx context.Context) (int, error) {
+	var userIDs []uint
+	if err := w.service.db.WithContext(ctx).Model(&models.User{}).
+		Where("maintenance_enabled = ? AND disabled_at IS NULL", true).
+		Where("NOT EXISTS (SELECT 1 FROM maintenance_reports r WHERE r.user_id = users.id AND r.created_at > ?)",
+			time.Now().Add(-w.config.Interval)).
+		Order("id").
+		Limit(w.config.BatchSize).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find users due a maintenance review: %w", err)
+	}
+
+	reviewed := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, err := w.service.forUser(userID).RunMaintenance(ctx, MaintenanceTriggerScheduled); err != nil {
+			w.service.logger.Warn().Err(err).Uint("user_id", userID).Msg("maintenance review failed")
+			continue
+		}
+		reviewed++
+	}
+	return reviewed, nil
+}
+
+// forUser returns a service sharing this one's dependencies whose memories are
+// the given user's
+func (s *MemoryService) forUser(userID uint) *MemoryService {
+	if userID <= 1 {
+		return NewMemoryService(s.db, s.embedding, s.logger, s.config)
+	}
+	return NewMemoryServiceWithUser(s.db, s.embedding, s.logger, s.config, userID)
+}
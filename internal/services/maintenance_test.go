@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// setupMaintenanceService creates a memory service for the system user with the
+// tables the maintenance agent uses
+func setupMaintenanceService(t *testing.T, config map[string]interface{}) *MemoryService {
+	service := setupMemoryService(t, config)
+	require.NoError(t, service.db.AutoMigrate(&models.User{}, &models.MaintenanceReport{}, &models.MaintenanceAction{}, &models.ActivityLog{}))
+	require.NoError(t, service.db.Create(&models.User{ID: 1, Email: "user@example.com", Password: "x"}).Error)
+	return service
+}
+
+// copyTestMemory inserts a copy of a memory directly, bypassing the duplicate
+// checks and update keys of Store
+func copyTestMemory(t *testing.T, service *MemoryService, memory *models.Memory, updateKey string) *models.Memory {
+	clone := *memory
+	clone.ID = 0
+	clone.UpdateKey = updateKey
+	clone.CreatedAt = time.Time{}
+	clone.UpdatedAt = time.Time{}
+	require.NoError(t, service.db.Omit("embedding").Create(&clone).Error)
+	return &clone
+}
+
+// testMemoryTags reads a memory's tags, which the sqlite test schema stores as text
+func testMemoryTags(t *testing.T, service *MemoryService, id uint) []string {
+	var tags pq.StringArray
+	require.NoError(t, service.db.Raw("SELECT tags FROM memories WHERE id = ?", id).Row().Scan(&tags))
+	return tags
+}
+
+// actionFor returns the action a report proposes for a memory
+func actionFor(t *testing.T, report *models.MaintenanceReport, memoryID uint) models.MaintenanceAction {
+	for _, action := range report.Actions {
+		if action.MemoryID == memoryID {
+			return action
+		}
+	}
+	t.Fatalf("no action proposed for memory %d", memoryID)
+	return models.MaintenanceAction{}
+}
+
+func TestMemoryService_MaintenanceSettings(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, nil)
+
+	settings, err := service.MaintenanceSettings(ctx)
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.Nil(t, settings.LastReportAt)
+
+	settings, err = service.SetMaintenanceEnabled(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, settings.Enabled)
+
+	_, err = service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	settings, err = service.MaintenanceSettings(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, settings.LastReportAt)
+
+	_, err = service.forUser(42).SetMaintenanceEnabled(ctx, true)
+	assert.True(t, utils.IsNotFoundError(err))
+}
+
+func TestMemoryService_RunMaintenance(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, map[string]interface{}{"maintenance_stale_after": time.Hour})
+
+	original, _ := storeTestMemory(t, service, "Prefers window seats on long flights")
+	duplicate := copyTestMemory(t, service, original, "")
+
+	home, err := service.Store(ctx, StoreRequest{
+		Content: "Lives in Porto", Category: models.CategoryPersonal, Type: models.TypeFact, UpdateKey: "home",
+	})
+	require.NoError(t, err)
+	moved := copyTestMemory(t, service, home, "home")
+	moved.Content = "Lives in Lisbon"
+	require.NoError(t, service.db.Model(moved).Updates(map[string]interface{}{
+		"content": moved.Content, "updated_at": time.Now().Add(time.Minute),
+	}).Error)
+
+	stale, _ := storeTestMemory(t, service, "Parked on level 3")
+	require.NoError(t, service.db.Model(&models.Memory{}).Where("id = ?", stale.ID).
+		UpdateColumn("created_at", time.Now().Add(-2*time.Hour)).Error)
+
+	acme, _ := storeTestMemory(t, service, "Acme renewal is due in March")
+	_, err = service.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+		Name: "acme", Match: "acme", Category: models.CategoryBusiness, Tags: []string{"acme"},
+	})
+	require.NoError(t, err)
+
+	report, err := service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	assert.Equal(t, MaintenanceTriggerManual, report.Trigger)
+	assert.Equal(t, 6, report.Scanned)
+	require.Len(t, report.Actions, 4)
+
+	merge := actionFor(t, report, duplicate.ID)
+	assert.Equal(t, models.MaintenanceMergeDuplicate, merge.Kind)
+	assert.Equal(t, original.ID, *merge.KeepID)
+	assert.Equal(t, 1.0, merge.Similarity)
+
+	conflict := actionFor(t, report, home.ID)
+	assert.Equal(t, models.MaintenanceResolveConflict, conflict.Kind)
+	assert.Equal(t, moved.ID, *conflict.KeepID)
+
+	prune := actionFor(t, report, stale.ID)
+	assert.Equal(t, models.MaintenancePruneStale, prune.Kind)
+
+	recategorize := actionFor(t, report, acme.ID)
+	assert.Equal(t, models.MaintenanceRecategorize, recategorize.Kind)
+	assert.Equal(t, models.CategoryBusiness, recategorize.Category)
+	assert.Equal(t, []string{"acme"}, []string(recategorize.AddTags))
+
+	for _, action := range report.Actions {
+		assert.Equal(t, models.MaintenanceProposed, action.Status)
+	}
+
+	// Nothing changes until actions are approved
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+
+	// A later review expires proposals nobody decided on
+	_, err = service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	earlier, err := service.GetMaintenanceReport(ctx, report.ID)
+	require.NoError(t, err)
+	for _, action := range earlier.Actions {
+		assert.Equal(t, models.MaintenanceExpired, action.Status)
+	}
+
+	reports, err := service.ListMaintenanceReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Greater(t, reports[0].ID, reports[1].ID)
+}
+
+func TestMemoryService_MaintenanceLeavesCriticalMemories(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, nil)
+
+	original, err := service.Store(ctx, StoreRequest{
+		Content: "Allergic to penicillin", Category: models.CategoryPersonal,
+		Type: models.TypeFact, Priority: models.PriorityCritical,
+	})
+	require.NoError(t, err)
+	copyTestMemory(t, service, original, "")
+
+	report, err := service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	assert.Empty(t, report.Actions)
+}
+
+func TestMemoryService_ReviewMaintenanceReport(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, map[string]interface{}{"maintenance_stale_after": time.Hour})
+
+	original, _ := storeTestMemory(t, service, "Prefers window seats on long flights")
+	duplicate := copyTestMemory(t, service, original, "")
+
+	stale, _ := storeTestMemory(t, service, "Parked on level 3")
+	require.NoError(t, service.db.Model(&models.Memory{}).Where("id = ?", stale.ID).
+		UpdateColumn("created_at", time.Now().Add(-2*time.Hour)).Error)
+
+	acme, _ := storeTestMemory(t, service, "Acme renewal is due in March")
+	_, err := service.SaveCategorizationRule(ctx, CategorizationRuleSpec{
+		Name: "acme", Match: "acme", Category: models.CategoryBusiness, Tags: []string{"acme"},
+	})
+	require.NoError(t, err)
+
+	report, err := service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	merge := actionFor(t, report, duplicate.ID)
+	prune := actionFor(t, report, stale.ID)
+	recategorize := actionFor(t, report, acme.ID)
+
+	t.Run("validation", func(t *testing.T) {
+		for _, review := range []MaintenanceReview{
+			{},
+			{Approve: []uint{merge.ID}, Reject: []uint{merge.ID}},
+			{Approve: []uint{9999}},
+		} {
+			_, err := service.ReviewMaintenanceReport(ctx, report.ID, review)
+			assert.True(t, utils.IsValidationError(err), "%+v", review)
+		}
+
+		_, err := service.ReviewMaintenanceReport(ctx, 9999, MaintenanceReview{Approve: []uint{merge.ID}})
+		assert.True(t, utils.IsNotFoundError(err))
+	})
+
+	t.Run("approve and reject", func(t *testing.T) {
+		reviewed, err := service.ReviewMaintenanceReport(ctx, report.ID, MaintenanceReview{
+			Approve: []uint{merge.ID, recategorize.ID},
+			Reject:  []uint{prune.ID},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, models.MaintenanceApplied, actionFor(t, reviewed, duplicate.ID).Status)
+		assert.Equal(t, models.MaintenanceApplied, actionFor(t, reviewed, acme.ID).Status)
+		assert.Equal(t, models.MaintenanceRejected, actionFor(t, reviewed, stale.ID).Status)
+
+		// The duplicate is folded into the memory kept
+		_, err = service.GetByID(ctx, duplicate.ID)
+		assert.True(t, utils.IsNotFoundError(err))
+		_, err = service.GetByID(ctx, original.ID)
+		assert.NoError(t, err)
+
+		refiled, err := service.GetByID(ctx, acme.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.CategoryBusiness, refiled.Category)
+		assert.Contains(t, testMemoryTags(t, service, acme.ID), "acme")
+
+		// Rejected memories stay
+		_, err = service.GetByID(ctx, stale.ID)
+		assert.NoError(t, err)
+
+		var activity models.ActivityLog
+		require.NoError(t, service.db.Where("type = ?", models.ActivityMaintenanceReviewed).First(&activity).Error)
+	})
+
+	t.Run("decided actions cannot be decided again", func(t *testing.T) {
+		_, err := service.ReviewMaintenanceReport(ctx, report.ID, MaintenanceReview{Approve: []uint{prune.ID}})
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
+
+func TestMemoryService_ReviewMaintenanceMarksFailures(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, map[string]interface{}{"maintenance_stale_after": time.Hour})
+
+	stale, _ := storeTestMemory(t, service, "Parked on level 3")
+	require.NoError(t, service.db.Model(&models.Memory{}).Where("id = ?", stale.ID).
+		UpdateColumn("created_at", time.Now().Add(-2*time.Hour)).Error)
+
+	report, err := service.RunMaintenance(ctx, MaintenanceTriggerManual)
+	require.NoError(t, err)
+	prune := actionFor(t, report, stale.ID)
+
+	// The memory went away before the review
+	require.NoError(t, service.Delete(ctx, stale.ID))
+
+	reviewed, err := service.ReviewMaintenanceReport(ctx, report.ID, MaintenanceReview{Approve: []uint{prune.ID}})
+	require.NoError(t, err)
+	failed := actionFor(t, reviewed, stale.ID)
+	assert.Equal(t, models.MaintenanceFailed, failed.Status)
+	assert.NotEmpty(t, failed.Error)
+	assert.NotNil(t, failed.DecidedAt)
+}
+
+func TestMaintenanceWorker_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	service := setupMaintenanceService(t, nil)
+	require.NoError(t, service.db.Create(&models.User{ID: 2, Email: "other@example.com", Password: "x"}).Error)
+	_, err := service.SetMaintenanceEnabled(ctx, true)
+	require.NoError(t, err)
+
+	worker := NewMaintenanceWorker(service, DefaultMaintenanceWorkerConfig())
+	reviewed, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reviewed, "only opted-in users are reviewed")
+
+	settings, err := service.MaintenanceSettings(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, settings.LastReportAt)
+
+	// Users reviewed within the interval are not due again
+	reviewed, err = worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, reviewed)
+
+	reports, err := service.ListMaintenanceReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, MaintenanceTriggerScheduled, reports[0].Trigger)
+}
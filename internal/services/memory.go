@@ -4,13 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/pgvector/pgvector-go"
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 
+	"github.com/ksred/remember-me-mcp/internal/database"
 	"github.com/ksred/remember-me-mcp/internal/models"
 	"github.com/ksred/remember-me-mcp/internal/utils"
 )
@@ -23,6 +25,9 @@ type MemoryService struct {
 	logger     zerolog.Logger
 	config     map[string]interface{}
 	userID     uint // User ID for scoping memories (0 means no scoping)
+	dataKeys   sync.Map // User ID to *utils.EncryptionService; see dataKey
+	// asyncEmbeddings tracks embeddings generated after a store; see Drain
+	asyncEmbeddings *AsyncEmbeddings
 }
 
 // NewMemoryService creates a new instance of MemoryService for local MCP mode
@@ -39,12 +44,13 @@ func NewMemoryService(db *gorm.DB, embedding EmbeddingService, logger zerolog.Lo
 	}
 	
 	return &MemoryService{
-		db:         db,
-		embedding:  embedding,
-		encryption: encryption,
-		logger:     logger,
-		config:     config,
-		userID:     1, // System user for local MCP mode
+		db:              db,
+		embedding:       embedding,
+		encryption:      encryption,
+		logger:          logger,
+		config:          config,
+		userID:          1, // System user for local MCP mode
+		asyncEmbeddings: asyncEmbeddingsFrom(config, logger),
 	}
 }
 
@@ -68,12 +74,13 @@ func NewMemoryServiceWithUser(db *gorm.DB, embedding EmbeddingService, logger ze
 	}
 	
 	return &MemoryService{
-		db:         db,
-		embedding:  embedding,
-		encryption: encryption,
-		logger:     logger,
-		config:     config,
-		userID:     userID,
+		db:              db,
+		embedding:       embedding,
+		encryption:      encryption,
+		logger:          logger,
+		config:          config,
+		userID:          userID,
+		asyncEmbeddings: asyncEmbeddingsFrom(config, logger),
 	}
 }
 
@@ -86,6 +93,12 @@ type StoreRequest struct {
 	UpdateKey string
 	Tags     []string
 	Metadata map[string]interface{}
+	// OnDuplicate overrides the configured action for near-duplicates: merge,
+	// update, reject or allow
+	OnDuplicate string
+	// SessionID captures the memory in a working session instead of the open
+	// one; see StartSession
+	SessionID string
 }
 
 // SearchRequest represents a request to search memories
@@ -95,6 +108,32 @@ type SearchRequest struct {
 	Type              string
 	Limit             int
 	UseSemanticSearch bool
+	// MetadataQuery filters on metadata; see ParseMetadataQuery
+	MetadataQuery string
+	// Tags keeps memories with any (TagMatch "any", the default) or all ("all") of
+	// the tags; see ParseTagFilter
+	Tags     []string
+	TagMatch string
+	// Mode is keyword, semantic or hybrid. When empty, UseSemanticSearch picks
+	// between semantic and keyword.
+	Mode string
+	// Offset skips that many results; see SearchPage for cursor pagination
+	Offset int
+	// WithinIDs, when not nil, searches only these memories, so a follow-up search
+	// can drill into earlier results. RefineCursor does the same with the
+	// refine cursor of a previous search's page.
+	WithinIDs    []uint
+	RefineCursor string
+	// DateRange keeps memories created or updated within its bounds
+	DateRange
+	// PriorityFilter keeps memories of the given priorities
+	PriorityFilter
+	// Session keeps memories captured in a working session; CurrentSession
+	// means the open one
+	Session string
+	// ReadOnly leaves the results' access statistics untouched, for searches run
+	// on the user's behalf rather than by the user
+	ReadOnly bool
 }
 
 // UpdateRequest represents a request to update a memory
@@ -109,6 +148,12 @@ type UpdateRequest struct {
 
 // ProcessContentForMemory automatically detects and stores memories from content
 func (s *MemoryService) ProcessContentForMemory(ctx context.Context, content string) ([]*models.Memory, error) {
+	// Nothing is detected or stored while incognito
+	if err := s.checkIncognito(ctx); err != nil {
+		s.logger.Debug().Msg("skipping memory detection in incognito mode")
+		return nil, nil
+	}
+	
 	// Detect memory patterns
 	detectedMemories := DetectMemoryPatterns(content)
 	
@@ -132,6 +177,8 @@ func (s *MemoryService) ProcessContentForMemory(ctx context.Context, content str
 				"pattern_type":  detected.Type,
 			},
 		}
+		detected.annotate(req.Metadata)
+		req.Content = s.normalizeDetected(req.Content, req.Metadata)
 		
 		memory, err := s.Store(ctx, req)
 		if err != nil {
@@ -147,20 +194,141 @@ func (s *MemoryService) ProcessContentForMemory(ctx context.Context, content str
 
 // Store creates or updates a memory
 func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Memory, error) {
+	memory, _, err := s.store(ctx, req)
+	return memory, err
+}
+
+// What a store did with the memory it was given
+const (
+	// StoreCreated means a new memory was created
+	StoreCreated = "created"
+	// StoreUpdated means an existing memory was overwritten with the new one
+	StoreUpdated = "updated"
+	// StoreMerged means the memory was folded into a near-duplicate, whose
+	// content was kept
+	StoreMerged = "merged"
+)
+
+// How a store found the existing memory it updated or merged into
+const (
+	MatchedByUpdateKey     = "update_key"
+	MatchedByContent       = "content"
+	MatchedByNearDuplicate = "near_duplicate"
+)
+
+// StoreResult is a stored memory with the user's quota usage afterwards
+type StoreResult struct {
+	Memory *models.Memory
+	// Quota is nil when usage could not be computed
+	Quota *QuotaUsage
+	// Duplicate is set when the store was merged into or updated a near-duplicate,
+	// which is then the memory returned
+	Duplicate *DuplicateMatch
+	// Action is StoreCreated, StoreUpdated or StoreMerged
+	Action string
+	// MatchedBy is how the existing memory was found when one was updated or
+	// merged into
+	MatchedBy string
+	// PreviousContent is what an updated memory said before, when the store
+	// changed it
+	PreviousContent string
+}
+
+// newStoreResult reports a store's memory and outcome, without quota usage
+func newStoreResult(memory *models.Memory, outcome storeOutcome) *StoreResult {
+	return &StoreResult{
+		Memory:          memory,
+		Duplicate:       outcome.duplicate,
+		Action:          outcome.action,
+		MatchedBy:       outcome.matchedBy,
+		PreviousContent: outcome.previousContent,
+	}
+}
+
+// StoreWithQuota creates or updates a memory and reports the user's quota usage
+// afterwards, including how many old memories were evicted to make room
+func (s *MemoryService) StoreWithQuota(ctx context.Context, req StoreRequest) (*models.Memory, *QuotaUsage, error) {
+	result, err := s.StoreWithResult(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Memory, result.Quota, nil
+}
+
+// StoreWithResult creates or updates a memory and reports the user's quota usage
+// and any near-duplicate the store was folded into
+func (s *MemoryService) StoreWithResult(ctx context.Context, req StoreRequest) (*StoreResult, error) {
+	memory, outcome, err := s.store(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := newStoreResult(memory, outcome)
+
+	quota, err := s.Quota(ctx)
+	if err != nil {
+		// The memory is stored; quota reporting is best effort
+		s.logger.Warn().Err(err).Msg("failed to compute quota usage")
+		return result, nil
+	}
+	quota.Evicted = outcome.evicted
+	quota.RefreshWarning()
+	result.Quota = quota
+
+	return result, nil
+}
+
+// storeOutcome is what a store did besides saving the memory
+type storeOutcome struct {
+	// evicted is the number of old memories evicted to make room
+	evicted int
+	// duplicate is set when the store was folded into a near-duplicate
+	duplicate *DuplicateMatch
+	// action, matchedBy and previousContent are reported in StoreResult
+	action          string
+	matchedBy       string
+	previousContent string
+}
+
+// store creates or updates a memory and reports what else it did
+func (s *MemoryService) store(ctx context.Context, req StoreRequest) (*models.Memory, storeOutcome, error) {
+	var outcome storeOutcome
+
 	// Validate input
 	if req.Content == "" {
-		return nil, utils.WrapValidationError("", "content cannot be empty")
+		return nil, outcome, utils.WrapValidationError("", "content cannot be empty")
+	}
+	if err := s.checkContentLength(req.Content); err != nil {
+		return nil, outcome, err
+	}
+
+	if err := s.checkIncognito(ctx); err != nil {
+		return nil, outcome, err
+	}
+
+	// The user's categorization rules file the memory before it is written
+	s.applyCategorizationRules(ctx, &req)
+
+	// Moderate before anything is written
+	decision, err := s.moderate(ctx, req.Content)
+	if err != nil {
+		return nil, outcome, err
+	}
+	contentHash, err := s.contentHash(req.Content)
+	if err != nil {
+		return nil, outcome, utils.WrapDatabaseError("hash content", err)
 	}
 
 	var existing *models.Memory
-	var err error
 
 	// Check for existing memory using UpdateKey first (for intelligent updates)
 	if req.UpdateKey != "" {
 		existing, err = s.findByUpdateKey(ctx, req.UpdateKey)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			s.logger.Error().Err(err).Msg("failed to check for existing memory by update key")
-			return nil, utils.WrapDatabaseError("check for existing memory", err)
+			return nil, outcome, utils.WrapDatabaseError("check for existing memory", err)
+		}
+		if existing != nil {
+			outcome.matchedBy = MatchedByUpdateKey
 		}
 	}
 
@@ -169,7 +337,24 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 		existing, err = s.findByContent(ctx, req.Content)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			s.logger.Error().Err(err).Msg("failed to check for duplicate memory")
-			return nil, utils.WrapDatabaseError("check for duplicate memory", err)
+			return nil, outcome, utils.WrapDatabaseError("check for duplicate memory", err)
+		}
+		if existing != nil {
+			outcome.matchedBy = MatchedByContent
+		}
+	}
+
+	// If not an exact duplicate, check for near-duplicates by embedding similarity
+	if existing == nil {
+		if existing, err = s.resolveNearDuplicate(ctx, req, &outcome); err != nil {
+			return nil, outcome, err
+		}
+		if existing != nil {
+			outcome.matchedBy = MatchedByNearDuplicate
+		}
+		if outcome.duplicate != nil && outcome.duplicate.Action == DuplicateMerge {
+			outcome.action = StoreMerged
+			return existing, outcome, nil
 		}
 	}
 
@@ -178,12 +363,27 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 		s.logger.Info().
 			Uint("id", existing.ID).
 			Str("update_key", req.UpdateKey).
+			Str("matched_by", outcome.matchedBy).
 			Msg("updating existing memory")
 			
 		// Store original content for embedding generation
 		originalContent := req.Content
+		revision := revisionFor(existing, contentHash, req.Type, req.Category)
+
+		// Report the content being replaced, so the caller can say what changed
+		outcome.action = StoreUpdated
+		previous := *existing
+		if err := s.decryptContent(&previous); err != nil {
+			s.logger.Warn().Err(err).Uint("id", existing.ID).Msg("failed to decrypt replaced content")
+		} else if previous.Content != req.Content {
+			outcome.previousContent = previous.Content
+		}
 		
 		existing.Content = req.Content
+		existing.ContentHash = contentHash
+		existing.IsEncrypted = false
+		existing.EncryptedContent = nil
+		clearBlindIndexes(existing)
 		existing.Category = req.Category
 		existing.Type = req.Type
 		existing.Priority = req.Priority
@@ -193,7 +393,7 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 		if req.Metadata != nil {
 			metadataJSON, err := json.Marshal(req.Metadata)
 			if err != nil {
-				return nil, utils.WrapValidationError("metadata", "invalid metadata format")
+				return nil, outcome, utils.WrapValidationError("metadata", "invalid metadata format")
 			}
 			existing.Metadata = json.RawMessage(metadataJSON)
 		}
@@ -201,28 +401,32 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 		// Encrypt content if encryption is enabled
 		if err := s.encryptContent(existing); err != nil {
 			s.logger.Error().Err(err).Msg("failed to encrypt content")
-			return nil, utils.WrapDatabaseError("encrypt content", err)
+			return nil, outcome, utils.WrapDatabaseError("encrypt content", err)
+		}
+		if err := s.applyModeration(existing, decision); err != nil {
+			s.logger.Error().Err(err).Msg("failed to apply moderation decision")
+			return nil, outcome, utils.WrapDatabaseError("apply moderation", err)
 		}
 		
 		// Skip embedding generation for updates too - do it asynchronously
 		// This prevents MCP timeout issues from affecting memory updates
 		
-		// Create a new context with a longer timeout to avoid cancellation
-		dbCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		// Finish the update even if the caller gives up
+		dbCtx, cancel := s.detachedContext(ctx)
 		defer cancel()
 		
-		// Update memory without touching embedding field
-		updateErr := s.db.WithContext(dbCtx).Omit("embedding").Save(existing).Error
+		// Update memory without touching embedding field, keeping the replaced version
+		updateErr := s.saveWithRevision(dbCtx, existing, revision)
 		
 		if updateErr != nil {
 			s.logger.Error().Err(updateErr).Msg("failed to update memory")
-			return nil, utils.WrapDatabaseError("update memory", updateErr)
+			return nil, outcome, utils.WrapDatabaseError("update memory", updateErr)
 		}
 		
 		// Generate embedding asynchronously after updating the memory
 		// Use original content for embedding, not encrypted content
 		if s.embedding != nil {
-			go s.generateEmbeddingAsync(existing.ID, originalContent)
+			s.embedAsync(existing.ID, embeddingFieldsOf(existing, originalContent))
 		}
 		
 		// Decrypt content before returning if it was encrypted
@@ -231,7 +435,18 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 			// Don't fail the operation, just return with encrypted marker
 		}
 		
-		return existing, nil
+		return existing, outcome, nil
+	}
+
+	// Under the reject_new eviction policy a full account refuses new memories
+	if err := s.checkCapacity(ctx); err != nil {
+		return nil, outcome, err
+	}
+
+	// New memories are captured in the named or open working session
+	sessionID, err := s.captureSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, outcome, err
 	}
 
 	// Store original content for embedding generation
@@ -239,13 +454,15 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 	
 	// Create new memory
 	memory := &models.Memory{
-		UserID:    s.userID,
-		Content:   req.Content,
-		Category:  req.Category,
-		Type:      req.Type,
-		Priority:  req.Priority,
-		UpdateKey: req.UpdateKey,
-		Tags:      req.Tags,
+		UserID:      s.userID,
+		Content:     req.Content,
+		ContentHash: contentHash,
+		Category:    req.Category,
+		Type:        req.Type,
+		Priority:    req.Priority,
+		UpdateKey:   req.UpdateKey,
+		Tags:        req.Tags,
+		SessionID:   sessionID,
 	}
 	
 	s.logger.Debug().Msg("Creating new memory - will generate embedding asynchronously")
@@ -253,35 +470,41 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 	if req.Metadata != nil {
 		metadataJSON, err := json.Marshal(req.Metadata)
 		if err != nil {
-			return nil, utils.WrapValidationError("metadata", "invalid metadata format")
+			return nil, outcome, utils.WrapValidationError("metadata", "invalid metadata format")
 		}
 		memory.Metadata = json.RawMessage(metadataJSON)
 	}
 	
-	// Encrypt content if encryption is enabled
-	if err := s.encryptContent(memory); err != nil {
+	// Content is encrypted once the memory has an ID, with the key loaded here
+	encryption, err := s.contentEncryption(memory)
+	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to encrypt content")
-		return nil, utils.WrapDatabaseError("encrypt content", err)
+		return nil, outcome, utils.WrapDatabaseError("encrypt content", err)
 	}
 
 	// Skip embedding generation for now - we'll do it asynchronously after storing
 	// This prevents MCP timeout issues from affecting memory storage
 
 	// Create the memory record
-	// Create a new context with a longer timeout to avoid cancellation
-	dbCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Finish the write even if the caller gives up
+	dbCtx, cancel := s.detachedContext(ctx)
 	defer cancel()
 	
 	// Create memory without embedding first
-	createErr := s.db.WithContext(dbCtx).Omit("embedding").Create(memory).Error
+	createErr := s.transaction(dbCtx, func(tx *gorm.DB) error {
+		return s.createMemory(tx, memory, encryption, decision)
+	})
 	
 	if createErr != nil {
 		s.logger.Error().Err(createErr).Msg("failed to create memory")
-		return nil, utils.WrapDatabaseError("create memory", createErr)
+		return nil, outcome, utils.WrapDatabaseError("create memory", createErr)
 	}
 
+	outcome.action = StoreCreated
+
 	// Enforce memory limit if configured
-	if err := s.enforceMemoryLimit(ctx); err != nil {
+	outcome.evicted, err = s.enforceMemoryLimit(ctx)
+	if err != nil {
 		s.logger.Warn().Err(err).Msg("failed to enforce memory limit")
 		// Don't fail the operation, just log the warning
 	}
@@ -297,7 +520,7 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 	// Generate embedding asynchronously after storing the memory
 	// Use original content for embedding, not encrypted content
 	if s.embedding != nil {
-		go s.generateEmbeddingAsync(memory.ID, originalContent)
+		s.embedAsync(memory.ID, embeddingFieldsOf(memory, originalContent))
 	}
 	
 	// Decrypt content before returning if it was encrypted
@@ -306,15 +529,51 @@ func (s *MemoryService) Store(ctx context.Context, req StoreRequest) (*models.Me
 		// Don't fail the operation, just return with encrypted marker
 	}
 
-	return memory, nil
+	return memory, outcome, nil
 }
 
 // Update updates an existing memory by ID
 func (s *MemoryService) Update(ctx context.Context, id uint, req UpdateRequest) (*models.Memory, error) {
-	// Create a new context with a longer timeout to avoid cancellation
-	dbCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Finish the write even if the caller gives up
+	dbCtx, cancel := s.detachedContext(ctx)
 	defer cancel()
 
+	update, err := s.writeUpdate(ctx, dbCtx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishUpdate(update), nil
+}
+
+// writtenUpdate is an update saved to the database whose follow-up work,
+// re-embedding and critical-change notifications, has not run yet
+type writtenUpdate struct {
+	memory          *models.Memory
+	originalContent string
+	reembed         bool
+	wasCritical     bool
+	changes         map[string]interface{}
+}
+
+// writeUpdate moderates and saves an update to a memory. ctx bounds moderation
+// and dbCtx the database work.
+func (s *MemoryService) writeUpdate(ctx, dbCtx context.Context, id uint, req UpdateRequest) (*writtenUpdate, error) {
+	// Moderate new content before anything is written
+	var decision *ModerationDecision
+	var contentHash string
+	if req.Content != "" {
+		if err := s.checkContentLength(req.Content); err != nil {
+			return nil, err
+		}
+		var err error
+		if decision, err = s.moderate(ctx, req.Content); err != nil {
+			return nil, err
+		}
+		if contentHash, err = s.contentHash(req.Content); err != nil {
+			return nil, utils.WrapDatabaseError("hash content", err)
+		}
+	}
+
 	// Find the memory by ID
 	var memory models.Memory
 	if err := s.db.WithContext(dbCtx).Where("id = ? AND user_id = ?", id, s.userID).First(&memory).Error; err != nil {
@@ -326,10 +585,41 @@ func (s *MemoryService) Update(ctx context.Context, id uint, req UpdateRequest)
 
 	// Store original content for embedding generation
 	originalContent := memory.Content
+	if req.Content == "" && memory.IsEncrypted {
+		plain := memory
+		if err := s.decryptContent(&plain); err == nil {
+			originalContent = plain.Content
+		}
+	}
+	wasCritical := memory.Priority == models.PriorityCritical
+	revision := revisionFor(&memory, contentHash, req.Type, req.Category)
+	changes := make(map[string]interface{})
+	if contentHash != "" && contentHash != memory.ContentHash {
+		changes["content"] = true
+	}
+	if req.Category != "" && req.Category != memory.Category {
+		changes["category"] = map[string]string{"from": memory.Category, "to": req.Category}
+	}
+	if req.Type != "" && req.Type != memory.Type {
+		changes["type"] = map[string]string{"from": memory.Type, "to": req.Type}
+	}
+	if req.Priority != "" && req.Priority != memory.Priority {
+		changes["priority"] = map[string]string{"from": memory.Priority, "to": req.Priority}
+	}
+	if req.Tags != nil {
+		changes["tags"] = true
+	}
+	if req.Metadata != nil {
+		changes["metadata"] = true
+	}
 
 	// Update fields if provided (only update non-empty values)
 	if req.Content != "" {
 		memory.Content = req.Content
+		memory.ContentHash = contentHash
+		memory.IsEncrypted = false
+		memory.EncryptedContent = nil
+		clearBlindIndexes(&memory)
 		originalContent = req.Content // Use new content for embedding
 	}
 	if req.Category != "" {
@@ -358,55 +648,97 @@ func (s *MemoryService) Update(ctx context.Context, id uint, req UpdateRequest)
 		s.logger.Error().Err(err).Msg("failed to encrypt content")
 		return nil, utils.WrapDatabaseError("encrypt content", err)
 	}
+	if err := s.applyModeration(&memory, decision); err != nil {
+		s.logger.Error().Err(err).Msg("failed to apply moderation decision")
+		return nil, utils.WrapDatabaseError("apply moderation", err)
+	}
 
-	// Update memory without touching embedding field initially
-	updateErr := s.db.WithContext(dbCtx).Omit("embedding").Save(&memory).Error
+	// Update memory without touching embedding field initially, keeping the
+	// replaced version
+	updateErr := s.saveWithRevision(dbCtx, &memory, revision)
 	if updateErr != nil {
 		s.logger.Error().Err(updateErr).Msg("failed to update memory")
 		return nil, utils.WrapDatabaseError("update memory", updateErr)
 	}
 
-	// Generate new embedding asynchronously if content changed
-	if req.Content != "" && s.embedding != nil {
-		go s.generateEmbeddingAsync(memory.ID, originalContent)
+	// Re-embed if content changed, or a field the embedded document includes
+	reembed := req.Content != "" ||
+		(s.GetEmbeddingComposer() != nil && (changes["tags"] != nil || changes["category"] != nil || changes["type"] != nil))
+
+	return &writtenUpdate{
+		memory:          &memory,
+		originalContent: originalContent,
+		reembed:         reembed,
+		wasCritical:     wasCritical,
+		changes:         changes,
+	}, nil
+}
+
+// finishUpdate runs the follow-up work of a saved update and returns the updated
+// memory with its content decrypted
+func (s *MemoryService) finishUpdate(update *writtenUpdate) *models.Memory {
+	memory := update.memory
+
+	// Generate new embedding asynchronously
+	if update.reembed && s.embedding != nil {
+		s.embedAsync(memory.ID, embeddingFieldsOf(memory, update.originalContent))
 	}
 
 	s.logger.Info().
 		Uint("id", memory.ID).
 		Msg("successfully updated memory")
 
+	if update.wasCritical {
+		s.notifyCriticalChange(EventCriticalMemoryUpdated, memory, update.changes)
+	}
+
 	// Decrypt content before returning if it was encrypted
-	if err := s.decryptContent(&memory); err != nil {
+	if err := s.decryptContent(memory); err != nil {
 		s.logger.Warn().Err(err).Msg("failed to decrypt content for response")
 		// Don't fail the operation, just return with encrypted marker
 	}
 
-	return &memory, nil
+	return memory
+}
+
+// memoryRow narrows a memories query to one memory. Its owner is added when
+// known, so a partitioned memories table is read in that user's partition alone.
+func memoryRow(db *gorm.DB, id, userID uint) *gorm.DB {
+	db = db.Where("id = ?", id)
+	if userID != 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	return db
 }
 
-// generateEmbeddingAsync generates embedding for a memory asynchronously
-func (s *MemoryService) generateEmbeddingAsync(memoryID uint, content string) {
+// generateEmbeddingAsync generates embedding for a memory asynchronously. A
+// cancelled ctx queues the memory for the backfill worker.
+func (s *MemoryService) generateEmbeddingAsync(ctx context.Context, memoryID uint, fields EmbeddingFields) {
 	s.logger.Debug().Uint("memory_id", memoryID).Msg("starting async embedding generation")
 	
-	// Use the same approach as the successful startup validation
-	// Don't pass any context from the caller - create completely fresh one
-	embedding, err := s.embedding.GenerateEmbedding(context.Background(), content)
+	// Don't pass any context from the caller; ctx only ends when shutdown
+	// cannot wait. Memories stored together share one request through the batcher.
+	embedder := s.embedderFor(ctx, s.userID, true)
+	embedding, err := s.embedMemory(ctx, embedder, fields)
+	var chunks []models.MemoryChunk
+	if err == nil {
+		chunks, err = s.embedChunks(ctx, embedder, memoryID, s.userID, fields.Content)
+	}
 	if err != nil {
 		s.logger.Warn().Err(err).Uint("memory_id", memoryID).Msg("failed to generate embedding asynchronously")
+		s.enqueueEmbeddingJob(context.Background(), memoryID, err)
 		return
 	}
 	
-	// Update the memory with the embedding
-	updateCtx, updateCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Update the memory with the embedding and its chunks
+	updateCtx, updateCancel := s.detachedContext(context.Background())
 	defer updateCancel()
 	
-	err = s.db.WithContext(updateCtx).
-		Model(&models.Memory{}).
-		Where("id = ?", memoryID).
-		UpdateColumn("embedding", pgvector.NewVector(embedding)).Error
+	err = s.saveEmbedding(updateCtx, memoryID, s.userID, embedding, chunks)
 	
 	if err != nil {
 		s.logger.Error().Err(err).Uint("memory_id", memoryID).Msg("failed to update memory with embedding")
+		s.enqueueEmbeddingJob(context.Background(), memoryID, err)
 		return
 	}
 	
@@ -415,36 +747,85 @@ func (s *MemoryService) generateEmbeddingAsync(memoryID uint, content string) {
 
 // Search searches memories based on the provided criteria
 func (s *MemoryService) Search(ctx context.Context, req SearchRequest) ([]*models.Memory, error) {
-	// Handle wildcard query - return all memories
+	mode, err := req.searchMode()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOffset(req.Offset); err != nil {
+		return nil, err
+	}
+	if err := req.resolveScope(); err != nil {
+		return nil, err
+	}
+	if req.Session, err = s.resolveSession(ctx, req.Session); err != nil {
+		return nil, err
+	}
+
+	// A wildcard or empty query lists memories instead of searching
 	if req.Query == "*" || req.Query == "" {
-		req.Query = ""
-		req.UseSemanticSearch = false
+		return s.List(ctx, req.listRequest())
 	}
 	
-	// Use semantic search if requested and embedding service is available
-	if req.UseSemanticSearch && s.embedding != nil && req.Query != "" {
-		return s.SearchSemantic(ctx, req)
+	metadataQuery, err := s.parseMetadataQuery(req.MetadataQuery)
+	if err != nil {
+		return nil, err
+	}
+	tagFilter, err := s.parseTagFilter(req.Tags, req.TagMatch)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.DateRange.validate(); err != nil {
+		return nil, err
+	}
+	if err := req.PriorityFilter.validate(); err != nil {
+		return nil, err
+	}
+
+	// Use semantic or hybrid search if requested and embedding service is available
+	if s.embedding != nil {
+		switch mode {
+		case SearchModeSemantic:
+			return s.SearchSemantic(ctx, req)
+		case SearchModeHybrid:
+			return s.SearchHybrid(ctx, req)
+		}
 	}
 
 	// Fall back to keyword search
 	query := s.db.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", s.userID)
 
-	// Apply keyword search if query is provided (and not wildcard)
-	if req.Query != "" && req.Query != "*" {
-		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(req.Query))
-		query = query.Where("LOWER(content) LIKE ?", searchTerm)
+	// Filter by metadata if provided
+	if metadataQuery != nil {
+		query = query.Where(metadataQuery.condition("?"), metadataQuery.value())
 	}
 
-	// Filter by category if provided
-	if req.Category != "" {
-		query = query.Where("category = ?", req.Category)
+	// Filter by tags if provided
+	if tagFilter != nil {
+		query = query.Where(tagFilter.condition("?"), tagFilter.value())
 	}
 
-	// Filter by type if provided
-	if req.Type != "" {
-		query = query.Where("type = ?", req.Type)
+	// Search only within earlier results if scoped
+	if req.WithinIDs != nil {
+		query = query.Where("id IN ?", req.WithinIDs)
 	}
 
+	// Filter by creation and update time if provided
+	query = req.DateRange.apply(query)
+
+	// Filter by priority if provided
+	query = req.PriorityFilter.apply(query)
+
+	// Filter by working session if provided
+	if req.Session != "" {
+		query = query.Where("session_id = ?", req.Session)
+	}
+
+	// Apply keyword search
+	query = s.keywordCondition(query, req.Query)
+
+	// Filter by category and type if provided
+	query = filterMemories(query, req.Category, req.Type)
+
 	// Apply limit
 	if req.Limit > 0 {
 		query = query.Limit(req.Limit)
@@ -452,9 +833,15 @@ func (s *MemoryService) Search(ctx context.Context, req SearchRequest) ([]*model
 		// Default limit to prevent returning too many results
 		query = query.Limit(100)
 	}
+	if req.Offset > 0 {
+		query = query.Offset(req.Offset)
+	}
 
-	// Order by created_at descending (newest first)
-	query = query.Order("created_at DESC")
+	// Rank by relevance with the priority and feedback boosts, and then newest
+	// first, like List
+	query = s.keywordOrder(query, req.Query).
+		Order("created_at DESC").
+		Order("id DESC")
 
 	var memories []*models.Memory
 	if err := query.Omit("embedding", "tags").Find(&memories).Error; err != nil {
@@ -470,6 +857,11 @@ func (s *MemoryService) Search(ctx context.Context, req SearchRequest) ([]*model
 		}
 	}
 
+	if !req.ReadOnly {
+		s.recordAccess(ctx, memories)
+	}
+	s.auditReads(ctx, memories)
+
 	return memories, nil
 }
 
@@ -479,27 +871,25 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 		return nil, fmt.Errorf("embedding service not available")
 	}
 
+	// Repeated searches are answered from the search cache
+	cached, cacheResults := s.cachedSearch(ctx, SearchModeSemantic, req)
+	if cached != nil {
+		return cached, nil
+	}
+
 	// Generate embedding for the search query
-	queryEmbedding, err := s.embedding.GenerateEmbedding(ctx, req.Query)
+	queryEmbedding, err := s.queryEmbedding(ctx, req.Query)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to generate query embedding")
 		// Fall back to keyword search
-		req.UseSemanticSearch = false
-		return s.Search(ctx, req)
+		return s.Search(ctx, req.keywordOnly())
 	}
 
 	// Build the query
 	query := s.db.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", s.userID)
 
-	// Apply category filter if provided
-	if req.Category != "" {
-		query = query.Where("category = ?", req.Category)
-	}
-
-	// Apply type filter if provided
-	if req.Type != "" {
-		query = query.Where("type = ?", req.Type)
-	}
+	// Apply category and type filters if provided
+	query = filterMemories(query, req.Category, req.Type)
 
 	// Apply limit
 	limit := req.Limit
@@ -512,15 +902,10 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 	
 	// For SQLite in tests, fall back to regular search
 	if s.db.Dialector.Name() == "sqlite" {
-		req.UseSemanticSearch = false
-		return s.Search(ctx, req)
+		return s.Search(ctx, req.keywordOnly())
 	}
 
-	// Get similarity threshold from config - use a lower default for now
-	similarityThreshold := 0.3 // lowered significantly from 0.7
-	if threshold, ok := s.config["similarity_threshold"].(float64); ok && threshold > 0 {
-		similarityThreshold = threshold
-	}
+	similarityThreshold := s.similarityThreshold()
 	
 	s.logger.Info().
 		Float64("similarity_threshold", similarityThreshold).
@@ -530,9 +915,7 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 
 	// First, check if we have any memories with embeddings
 	var totalCount int64
-	s.db.WithContext(ctx).Model(&models.Memory{}).
-		Where("user_id = ? AND embedding IS NOT NULL", s.userID).
-		Count(&totalCount)
+	s.countEmbedded(ctx, s.userID, &totalCount)
 	
 	s.logger.Info().
 		Int64("memories_with_embeddings", totalCount).
@@ -543,44 +926,15 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 		return []*models.Memory{}, nil
 	}
 
-	// Simple semantic search query using pgvector
-	// Calculate similarity and order by it
-	// Using raw SQL for the order clause to ensure proper syntax
-	sql := fmt.Sprintf(`
-		SELECT *, (1 - (embedding <=> $1)) as similarity 
-		FROM memories 
-		WHERE user_id = $2 AND embedding IS NOT NULL
-		%s %s
-		ORDER BY embedding <=> $1
-		LIMIT $3
-	`, 
-		func() string {
-			if req.Category != "" {
-				return "AND category = $4"
-			}
-			return ""
-		}(),
-		func() string {
-			if req.Type != "" {
-				if req.Category != "" {
-					return "AND type = $5"
-				}
-				return "AND type = $4"
-			}
-			return ""
-		}(),
-	)
-	
-	args := []interface{}{pgvector.NewVector(queryEmbedding), s.userID, limit}
-	if req.Category != "" {
-		args = append(args, req.Category)
-	}
-	if req.Type != "" {
-		args = append(args, req.Type)
+	// Optional filters are appended after the fixed $1-$3 arguments
+	filters, args, err := s.searchFilters(req, []interface{}{pgvector.NewVector(queryEmbedding), s.userID, limit})
+	if err != nil {
+		return nil, err
 	}
-	
-	err = s.db.WithContext(ctx).Raw(sql, args...).Scan(&memories).Error
 
+	sql := s.semanticSearchSQL(filters, limit, req.Offset)
+	
+	memories, err = s.scanScored(ctx, sql, args)
 	if err != nil {
 		s.logger.Error().
 			Err(err).
@@ -592,6 +946,9 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 	s.logger.Info().
 		Int("results_count", len(memories)).
 		Msg("Semantic search completed")
+	if cacheResults != nil {
+		cacheResults(memories)
+	}
 	
 	// Decrypt content for each memory
 	for _, memory := range memories {
@@ -601,6 +958,11 @@ func (s *MemoryService) SearchSemantic(ctx context.Context, req SearchRequest) (
 		}
 	}
 
+	if !req.ReadOnly {
+		s.recordAccess(ctx, memories)
+	}
+	s.auditReads(ctx, memories)
+
 	return memories, nil
 }
 
@@ -612,8 +974,137 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// Delete deletes a memory by ID
+// searchFilters appends the request's optional filters to args, which holds the
+// statement's fixed arguments, and returns the SQL to add to its WHERE clause
+func (s *MemoryService) searchFilters(req SearchRequest, args []interface{}) (string, []interface{}, error) {
+	var filters strings.Builder
+	args = s.workspaceFilter(&filters, args)
+	if req.Category != "" {
+		args = append(args, req.Category)
+		fmt.Fprintf(&filters, " AND category = $%d", len(args))
+	}
+	if req.Type != "" {
+		args = append(args, req.Type)
+		fmt.Fprintf(&filters, " AND type = $%d", len(args))
+	}
+	if metadataQuery, err := s.parseMetadataQuery(req.MetadataQuery); err != nil {
+		return "", nil, err
+	} else if metadataQuery != nil {
+		args = append(args, metadataQuery.value())
+		fmt.Fprintf(&filters, " AND %s", metadataQuery.condition(fmt.Sprintf("$%d", len(args))))
+	}
+	if tagFilter, err := s.parseTagFilter(req.Tags, req.TagMatch); err != nil {
+		return "", nil, err
+	} else if tagFilter != nil {
+		args = append(args, tagFilter.value())
+		fmt.Fprintf(&filters, " AND %s", tagFilter.condition(fmt.Sprintf("$%d", len(args))))
+	}
+	if req.WithinIDs != nil {
+		args = append(args, withinArray(req.WithinIDs))
+		fmt.Fprintf(&filters, " AND id = ANY($%d)", len(args))
+	}
+	if req.Session != "" {
+		args = append(args, req.Session)
+		fmt.Fprintf(&filters, " AND session_id = $%d", len(args))
+	}
+	if err := req.DateRange.validate(); err != nil {
+		return "", nil, err
+	}
+	args = req.DateRange.appendSQL(&filters, args)
+	if err := req.PriorityFilter.validate(); err != nil {
+		return "", nil, err
+	}
+	args = req.PriorityFilter.appendSQL(&filters, args)
+	return filters.String(), args, nil
+}
+
+// semanticSearchSQL builds the pgvector search statement. The nearest candidates are
+// fetched by distance (keeping the query index-friendly) and then re-ranked by
+// similarity plus the priority boost, so a critical memory can overtake a slightly
+// closer low priority one. The boost learned from feedback is added the same way.
+// Candidates less similar than the similarity threshold are left out, boosted or not.
+// Later pages widen the candidate set so they are ranked
+// consistently with the first. WarmUp prepares the same statement text.
+//
+// Long memories are also matched by their nearest chunks, taking the similarity
+// of the best one when it beats the memory's own embedding.
+func (s *MemoryService) semanticSearchSQL(filters string, limit, offset int) string {
+	return fmt.Sprintf(`
+		SELECT * FROM (
+			SELECT DISTINCT ON (id) * FROM (
+				(SELECT *, (1 - (embedding <=> $1)) as similarity 
+				FROM memories 
+				WHERE user_id = $2 AND embedding IS NOT NULL%[1]s
+				ORDER BY embedding <=> $1
+				LIMIT %[2]d)
+				UNION ALL
+				(SELECT memories.*, chunks.similarity
+				FROM memories JOIN (
+					SELECT memory_id, MAX(1 - (embedding <=> $1)) as similarity
+					FROM (
+						SELECT memory_id, embedding FROM memory_chunks
+						WHERE user_id = $2
+						ORDER BY embedding <=> $1
+						LIMIT %[2]d
+					) nearest_chunks
+					GROUP BY memory_id
+				) chunks ON chunks.memory_id = memories.id
+				WHERE user_id = $2%[1]s)
+			) matches
+			ORDER BY id, similarity DESC
+		) candidates
+		WHERE similarity >= %[3]s
+		ORDER BY similarity + %[4]s + %[5]s DESC, id DESC
+		LIMIT $3 OFFSET %[6]d
+	`,
+		filters,
+		(limit+offset)*priorityCandidateFactor,
+		strconv.FormatFloat(s.similarityThreshold(), 'f', -1, 64),
+		s.priorityBoosts().sqlExpression("priority"),
+		s.feedbackBoostSQL("candidates.id"),
+		offset,
+	)
+}
+
+// scoredMemory is a semantic search row: a memory and its similarity to the query
+type scoredMemory struct {
+	models.Memory
+	Score float64 `gorm:"column:similarity"`
+}
+
+// scanScored runs a semantic search statement and returns its memories with
+// their similarity set
+func (s *MemoryService) scanScored(ctx context.Context, sql string, args []interface{}) ([]*models.Memory, error) {
+	var rows []scoredMemory
+	if err := s.db.WithContext(ctx).Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	memories := make([]*models.Memory, len(rows))
+	for i := range rows {
+		memory := &rows[i].Memory
+		memory.Similarity = &rows[i].Score
+		memories[i] = memory
+	}
+	return memories, nil
+}
+
+// countEmbedded counts a user's memories that have an embedding
+func (s *MemoryService) countEmbedded(ctx context.Context, userID uint, count *int64) error {
+	return s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("user_id = ? AND embedding IS NOT NULL", userID).
+		Count(count).Error
+}
+
+// Delete deletes a memory by ID. Critical memories are refused with a
+// ConfirmationRequiredError; use DeleteConfirmed to delete them.
 func (s *MemoryService) Delete(ctx context.Context, id uint) error {
+	return s.DeleteConfirmed(ctx, id, "")
+}
+
+// DeleteConfirmed deletes a memory by ID. Deleting a critical memory requires confirm
+// to be the token returned in the ConfirmationRequiredError of a previous attempt;
+// see confirm.
+func (s *MemoryService) DeleteConfirmed(ctx context.Context, id uint, confirm string) error {
 	// Check if memory exists and belongs to the user
 	var memory models.Memory
 	query := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, s.userID)
@@ -631,24 +1122,36 @@ func (s *MemoryService) Delete(ctx context.Context, id uint) error {
 		return utils.WrapDatabaseError("find memory", err)
 	}
 
-	// Delete the memory
-	if err := s.db.WithContext(ctx).Delete(&memory).Error; err != nil {
+	if err := s.checkDeleteConfirmation(ctx, &memory, confirm); err != nil {
+		s.logger.Warn().Uint("id", id).Msg("refused to delete critical memory without confirmation")
+		return err
+	}
+
+	// Delete the memory, then the stored objects of its attachments
+	attachmentKeys := s.attachmentObjectKeys(ctx, "memory_id = ?", memory.ID)
+	if err := s.db.WithContext(ctx).Where("user_id = ?", memory.UserID).Delete(&memory).Error; err != nil {
 		s.logger.Error().Err(err).Msg("failed to delete memory")
 		return utils.WrapDatabaseError("delete memory", err)
 	}
+	s.removeAttachmentObjects(ctx, attachmentKeys)
+	s.auditAccess(ctx, models.MemoryAccessDelete, []uint{memory.ID})
+
+	if memory.Priority == models.PriorityCritical {
+		s.notifyCriticalChange(EventCriticalMemoryDeleted, &memory, nil)
+	}
 
 	return nil
 }
 
-// Count returns the total number of memories for the user
+// Count returns the total number of memories for the user, read from the
+// materialized counters when available; see MemoryCounts
 func (s *MemoryService) Count(ctx context.Context) (int64, error) {
-	var count int64
-	if err := s.db.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", s.userID).Count(&count).Error; err != nil {
-		s.logger.Error().Err(err).Msg("failed to count memories")
-		return 0, utils.WrapDatabaseError("count memories", err)
+	counts, err := s.MemoryCounts(ctx)
+	if err != nil {
+		return 0, err
 	}
 
-	return count, nil
+	return counts.Total, nil
 }
 
 // GetByID retrieves a memory by its ID for the user
@@ -675,16 +1178,20 @@ func (s *MemoryService) GetByID(ctx context.Context, id uint) (*models.Memory, e
 		// Don't fail the operation, return with encrypted marker
 	}
 
+	s.recordAccess(ctx, []*models.Memory{&memory})
+	s.auditAccess(ctx, models.MemoryAccessRead, []uint{memory.ID})
+
 	return &memory, nil
 }
 
 // findByContent finds a memory with the exact same content for the user
 func (s *MemoryService) findByContent(ctx context.Context, content string) (*models.Memory, error) {
 	var memory models.Memory
-	// Create a new context with a longer timeout to avoid cancellation
-	dbCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Look up under the write deadline, as the store it is part of is finished
+	// even if the caller gives up
+	dbCtx, cancel := s.detachedContext(ctx)
 	defer cancel()
-	query := s.db.WithContext(dbCtx).Where("content = ? AND user_id = ?", content, s.userID)
+	query := s.contentCondition(s.db.WithContext(dbCtx).Where("user_id = ?", s.userID), content)
 	
 	// For SQLite, omit fields that cause issues
 	if s.db.Dialector.Name() == "sqlite" {
@@ -701,8 +1208,9 @@ func (s *MemoryService) findByContent(ctx context.Context, content string) (*mod
 // findByUpdateKey finds a memory with the same update key (for intelligent updates) for the user
 func (s *MemoryService) findByUpdateKey(ctx context.Context, updateKey string) (*models.Memory, error) {
 	var memory models.Memory
-	// Create a new context with a longer timeout to avoid cancellation
-	dbCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Look up under the write deadline, as the store it is part of is finished
+	// even if the caller gives up
+	dbCtx, cancel := s.detachedContext(ctx)
 	defer cancel()
 	query := s.db.WithContext(dbCtx).Where("update_key = ? AND user_id = ?", updateKey, s.userID)
 	
@@ -718,13 +1226,14 @@ func (s *MemoryService) findByUpdateKey(ctx context.Context, updateKey string) (
 	return &memory, nil
 }
 
-// enforceMemoryLimit deletes oldest memories if over the configured limit
-func (s *MemoryService) enforceMemoryLimit(ctx context.Context) error {
+// defaultMemoryLimit returns the configured per-user memory limit, or 0 when
+// unlimited; see MemoryLimit for the limit that applies to the user
+func (s *MemoryService) defaultMemoryLimit() int {
 	// Get memory limit from config
 	limitInterface, exists := s.config["memory_limit"]
 	if !exists {
 		// No limit configured
-		return nil
+		return 0
 	}
 
 	limit, ok := limitInterface.(int)
@@ -734,144 +1243,221 @@ func (s *MemoryService) enforceMemoryLimit(ctx context.Context) error {
 			limit = int(limitFloat)
 		} else {
 			s.logger.Warn().Interface("memory_limit", limitInterface).Msg("invalid memory_limit configuration")
-			return nil
+			return 0
 		}
 	}
 
-	if limit <= 0 {
-		// No limit or invalid limit
-		return nil
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// enforceMemoryLimit deletes memories chosen by the user's eviction policy if over
+// the configured limit and returns how many were deleted. Critical memories are
+// never evicted.
+func (s *MemoryService) enforceMemoryLimit(ctx context.Context) (int, error) {
+	limit := s.MemoryLimit(ctx)
+	if limit == 0 {
+		// No limit configured
+		return 0, nil
 	}
 
 	// Count current memories
 	count, err := s.Count(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if count <= int64(limit) {
 		// Within limit
-		return nil
+		return 0, nil
+	}
+
+	policy := s.EvictionPolicy(ctx)
+	if policy == EvictionRejectNew {
+		// Nothing is evicted; new memories are refused before they are stored
+		return 0, nil
 	}
 
 	// Calculate how many to delete
 	toDelete := int(count) - limit
 
-	// Find and delete oldest memories
-	var oldestMemories []models.Memory
-	query := s.db.WithContext(ctx).Order("created_at ASC").Limit(toDelete)
+	// Find the memories the policy evicts first. The limit covers the whole
+	// account, so they may come from any workspace.
+	db := database.AllWorkspaces(s.db)
+	var candidates []models.Memory
+	query := db.WithContext(ctx).
+		Where("user_id = ? AND priority <> ?", s.userID, models.PriorityCritical).
+		Order(evictionOrder(policy)).
+		Limit(toDelete)
 	
 	// For SQLite, omit fields that cause issues
 	if s.db.Dialector.Name() == "sqlite" {
 		query = query.Omit("embedding", "tags")
 	}
 	
-	if err := query.Find(&oldestMemories).Error; err != nil {
-		return fmt.Errorf("failed to find oldest memories: %w", err)
+	if err := query.Find(&candidates).Error; err != nil {
+		return 0, fmt.Errorf("failed to find memories to evict: %w", err)
 	}
 
-	// Delete the oldest memories
-	for _, memory := range oldestMemories {
-		if err := s.db.WithContext(ctx).Delete(&memory).Error; err != nil {
-			s.logger.Error().Err(err).Uint("id", memory.ID).Msg("failed to delete old memory")
+	// Delete the selected memories
+	var evicted []models.Memory
+	for _, memory := range candidates {
+		attachmentKeys := s.attachmentObjectKeys(ctx, "memory_id = ?", memory.ID)
+		if err := db.WithContext(ctx).Where("user_id = ?", s.userID).Delete(&memory).Error; err != nil {
+			s.logger.Error().Err(err).Uint("id", memory.ID).Msg("failed to evict memory")
 			// Continue deleting others
+			continue
 		}
+		s.removeAttachmentObjects(ctx, attachmentKeys)
+		s.auditAccess(ctx, models.MemoryAccessDelete, []uint{memory.ID})
+		evicted = append(evicted, memory)
 	}
 
 	s.logger.Info().
-		Int("deleted", toDelete).
+		Int("deleted", len(evicted)).
 		Int("limit", limit).
+		Str("policy", policy).
 		Msg("enforced memory limit")
 
-	return nil
+	if len(evicted) > 0 {
+		s.reportEvictions(ctx, policy, limit, evicted)
+	}
+
+	return len(evicted), nil
 }
 
 // StoreMemory stores a memory using the standard request/response types
 func (s *MemoryService) StoreMemory(ctx context.Context, req *StoreMemoryRequest) (*models.Memory, error) {
+	result, err := s.StoreMemoryWithResult(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return result.Memory, nil
+}
+
+// StoreMemoryWithResult stores a memory using the standard request type and
+// reports whether a new memory was created or an existing one updated. Quota
+// usage is not reported.
+func (s *MemoryService) StoreMemoryWithResult(ctx context.Context, req *StoreMemoryRequest) (*StoreResult, error) {
 	storeReq := StoreRequest{
-		Content:  req.Content,
-		Category: req.Category,
-		Type:     req.Type,
-		Metadata: req.Metadata,
+		Content:     req.Content,
+		Category:    req.Category,
+		Type:        req.Type,
+		Tags:        req.Tags,
+		Metadata:    req.Metadata,
+		OnDuplicate: req.OnDuplicate,
+		SessionID:   req.SessionID,
 	}
 	
-	memory, err := s.Store(ctx, storeReq)
+	memory, outcome, err := s.store(ctx, storeReq)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Set tags if provided
-	if len(req.Tags) > 0 {
-		memory.Tags = req.Tags
-		if err := s.db.WithContext(ctx).Save(memory).Error; err != nil {
-			s.logger.Error().Err(err).Msg("failed to save memory tags")
-			return nil, utils.WrapDatabaseError("save memory tags", err)
-		}
-	}
-	
-	return memory, nil
+	return newStoreResult(memory, outcome), nil
 }
 
 // SearchMemories searches memories using the standard request/response types
 func (s *MemoryService) SearchMemories(ctx context.Context, req *SearchMemoriesRequest) ([]*models.Memory, error) {
-	searchReq := SearchRequest{
-		Query:             req.Query,
-		Category:          req.Category,
-		Type:              req.Type,
-		Limit:             req.Limit,
-		UseSemanticSearch: req.UseSemanticSearch,
+	return s.Search(ctx, req.searchRequest())
+}
+
+// SearchMemoriesPage searches memories using the standard request type and returns
+// one page of results with the total count and the cursor for the next page
+func (s *MemoryService) SearchMemoriesPage(ctx context.Context, req *SearchMemoriesRequest) (*SearchPage, error) {
+	return s.SearchPage(ctx, req.searchRequest(), req.Cursor)
+}
+
+// searchRequest converts the standard request type to a SearchRequest
+func (r *SearchMemoriesRequest) searchRequest() SearchRequest {
+	return SearchRequest{
+		Query:             r.Query,
+		Category:          r.Category,
+		Type:              r.Type,
+		Limit:             r.Limit,
+		UseSemanticSearch: r.UseSemanticSearch,
+		MetadataQuery:     r.MetadataQuery,
+		Tags:              r.Tags,
+		TagMatch:          r.TagMatch,
+		Mode:              r.SearchMode,
+		Offset:            r.Offset,
+		WithinIDs:         r.WithinIDs,
+		RefineCursor:      r.RefineCursor,
+		DateRange: DateRange{
+			CreatedAfter:  r.CreatedAfter,
+			CreatedBefore: r.CreatedBefore,
+			UpdatedAfter:  r.UpdatedAfter,
+			UpdatedBefore: r.UpdatedBefore,
+		},
+		PriorityFilter: PriorityFilter{
+			Priorities:  r.Priorities,
+			MinPriority: r.MinPriority,
+		},
+		Session: r.Session,
 	}
-	
-	return s.Search(ctx, searchReq)
 }
 
 // DeleteMemory deletes a memory using the standard request/response types
 func (s *MemoryService) DeleteMemory(ctx context.Context, req *DeleteMemoryRequest) error {
-	return s.Delete(ctx, req.ID)
+	return s.DeleteConfirmed(ctx, req.ID, req.Confirm)
 }
 
 // GetMemoryStats returns statistics about stored memories
 func (s *MemoryService) GetMemoryStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 	
-	// Get total count
-	totalCount, err := s.Count(ctx)
+	// Get counts from the materialized counters rather than scanning memories
+	counts, err := s.MemoryCounts(ctx)
 	if err != nil {
 		return nil, err
 	}
-	stats["total_count"] = totalCount
+	stats["total_count"] = counts.Total
+	stats["by_category"] = counts.ByCategory
+	stats["by_type"] = counts.ByType
+	stats["with_embeddings"] = counts.WithEmbeddings
+	stats["without_embeddings"] = counts.Total - counts.WithEmbeddings
+	stats["counts_source"] = counts.Source
 	
-	// Get count by category
-	categoryStats := make(map[string]int64)
-	for _, category := range []string{models.CategoryPersonal, models.CategoryProject, models.CategoryBusiness} {
-		var count int64
-		if err := s.db.WithContext(ctx).Model(&models.Memory{}).Where("category = ? AND user_id = ?", category, s.userID).Count(&count).Error; err != nil {
-			s.logger.Error().Err(err).Str("category", category).Msg("failed to count memories by category")
-			continue
-		}
-		categoryStats[category] = count
+	// Report incognito mode so clients can show it
+	if incognito, err := s.IncognitoStatus(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to get incognito status")
+	} else {
+		stats["incognito"] = incognito
 	}
-	stats["by_category"] = categoryStats
 	
-	// Get count by type
-	typeStats := make(map[string]int64)
-	for _, memType := range []string{models.TypeFact, models.TypeConversation, models.TypeContext, models.TypePreference} {
-		var count int64
-		if err := s.db.WithContext(ctx).Model(&models.Memory{}).Where("type = ? AND user_id = ?", memType, s.userID).Count(&count).Error; err != nil {
-			s.logger.Error().Err(err).Str("type", memType).Msg("failed to count memories by type")
-			continue
-		}
-		typeStats[memType] = count
+	// Get embedding backfill progress
+	if progress, err := s.EmbeddingBackfillProgress(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to get embedding backfill progress")
+	} else {
+		stats["embedding_backfill"] = progress
+	}
+	
+	// Report language-model spend so clients can show when LLM features fall back
+	if budget, err := s.LLMBudgetStatus(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to get LLM budget status")
+	} else {
+		stats["llm_budget"] = budget
+	}
+	
+	// Report search feedback so the dashboard can show what ranking has learned
+	if feedback, err := s.GetFeedbackStats(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to get feedback stats")
+	} else {
+		stats["feedback"] = feedback
 	}
-	stats["by_type"] = typeStats
 	
-	// Get embedding stats
-	var embeddingCount int64
-	if err := s.db.WithContext(ctx).Model(&models.Memory{}).Where("embedding IS NOT NULL AND user_id = ?", s.userID).Count(&embeddingCount).Error; err != nil {
-		s.logger.Error().Err(err).Msg("failed to count memories with embeddings")
+	// Report the provider calls the embedding cache saved
+	if embeddingCache, err := s.EmbeddingCacheStats(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to get embedding cache stats")
 	} else {
-		stats["with_embeddings"] = embeddingCount
-		stats["without_embeddings"] = totalCount - embeddingCount
+		stats["embedding_cache"] = embeddingCache
+	}
+	
+	// Report how often searches were answered from the search cache
+	if cache := s.GetSearchCache(); cache != nil {
+		stats["search_cache"] = cache.Stats()
 	}
 	
 	return stats, nil
@@ -887,14 +1473,97 @@ func (s *MemoryService) GetEncryptionService() *utils.EncryptionService {
 	return s.encryption
 }
 
-// encryptContent encrypts the content field if encryption is enabled
+// encryptContent encrypts the content field if encryption is enabled and it is
+// not encrypted already
 func (s *MemoryService) encryptContent(memory *models.Memory) error {
-	if s.encryption == nil || memory.Content == "" {
+	if s.encryption == nil || memory.Content == "" || memory.IsEncrypted {
 		return nil
 	}
 	
+	dataKey, err := s.contentEncryption(memory)
+	if err != nil {
+		return err
+	}
+	return encryptMemoryContent(dataKey, memory, s.contentOwner(memory))
+}
+
+// contentEncryption returns the key encrypting the memory's content, its owner's
+// data key, or nil when the deployment does not encrypt content
+func (s *MemoryService) contentEncryption(memory *models.Memory) (*utils.EncryptionService, error) {
+	if s.encryption == nil {
+		return nil, nil
+	}
+	dataKey, err := s.dataKey(memory.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// createMemory inserts a new memory, encrypting its content with encryption
+// unless it is nil and applying the moderation decision. Encrypted content is
+// bound to the memory's ID, which only exists once the row does, so such a
+// memory is inserted with the encrypted marker and its ciphertext written in the
+// same transaction; the plaintext never reaches the row. encryption must be
+// loaded before tx is opened, as loading a data key may write one.
+func (s *MemoryService) createMemory(tx *gorm.DB, memory *models.Memory, encryption *utils.EncryptionService, decision *ModerationDecision) error {
+	seal := func() error {
+		if encryption != nil {
+			if err := encryptMemoryContent(encryption, memory, s.contentOwner(memory)); err != nil {
+				return err
+			}
+		}
+		return s.applyModeration(memory, decision)
+	}
+
+	if encryption == nil && (decision == nil || decision.Action != ModerationEncrypt) {
+		if err := seal(); err != nil {
+			return err
+		}
+		return tx.Omit("embedding").Create(memory).Error
+	}
+
+	content := memory.Content
+	memory.Content = encryptedContentMarker
+	if err := tx.Omit("embedding").Create(memory).Error; err != nil {
+		return err
+	}
+	memory.Content = content
+	if err := seal(); err != nil {
+		return err
+	}
+	return tx.Model(memory).UpdateColumns(map[string]interface{}{
+		"content":           memory.Content,
+		"encrypted_content": memory.EncryptedContent,
+		"is_encrypted":      memory.IsEncrypted,
+		"content_index":     memory.ContentIndex,
+		"keyword_index":     memory.KeywordIndex,
+		"metadata":          memory.Metadata,
+	}).Error
+}
+
+// contentOwner returns the user a memory's content is encrypted for. Memories
+// built only to carry content, such as revisions and context turns, belong to the
+// service's user.
+func (s *MemoryService) contentOwner(memory *models.Memory) uint {
+	if memory.UserID != 0 {
+		return memory.UserID
+	}
+	return s.userID
+}
+
+// encryptedContentMarker stands in for the content of encrypted memories
+const encryptedContentMarker = "[encrypted]"
+
+// encryptMemoryContent replaces the content with the encrypted marker, bound to
+// the owner's user ID and the memory's ID, keeping blind indexes of the plaintext
+func encryptMemoryContent(encryption *utils.EncryptionService, memory *models.Memory, ownerID uint) error {
+	if err := setBlindIndexes(encryption, memory, ownerID); err != nil {
+		return fmt.Errorf("failed to index content: %w", err)
+	}
+
 	// Encrypt the content
-	encryptedData, err := s.encryption.EncryptField(memory.Content)
+	encryptedData, err := encryption.EncryptFieldWithAAD(memory.Content, models.MemoryContentAAD(ownerID, memory.ID))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt content: %w", err)
 	}
@@ -908,7 +1577,7 @@ func (s *MemoryService) encryptContent(memory *models.Memory) error {
 	memory.EncryptedContent = encryptedJSON
 	memory.IsEncrypted = true
 	// Clear the plain text content
-	memory.Content = "[encrypted]"
+	memory.Content = encryptedContentMarker
 	
 	return nil
 }
@@ -919,7 +1588,69 @@ func (s *MemoryService) decryptContent(memory *models.Memory) error {
 		return nil
 	}
 	
+	// Content encrypted by moderation uses the hook's service when deployment-wide
+	// encryption is off
 	if s.encryption == nil {
+		var encryption *utils.EncryptionService
+		if hook := s.GetModerationHook(); hook != nil {
+			encryption = hook.encryption
+		}
+		if err := decryptMemoryContent(encryption, memory, s.contentOwner(memory)); err != nil {
+			return err
+		}
+		s.upgradeEncryptedContent(encryption, memory)
+		return nil
+	}
+	
+	dataKey, err := s.dataKey(memory.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load data key: %w", err)
+	}
+	if err := decryptWithDataKey(s.encryption, dataKey, memory, s.contentOwner(memory)); err != nil {
+		return err
+	}
+	s.upgradeEncryptedContent(dataKey, memory)
+	return nil
+}
+
+// upgradeEncryptedContent re-encrypts a stored memory whose content is still in
+// an older envelope, so rows move to the current format as they are read. The row
+// is only rewritten if it has not changed since it was read; on failure it keeps
+// the old envelope, which still decrypts.
+func (s *MemoryService) upgradeEncryptedContent(encryption *utils.EncryptionService, memory *models.Memory) {
+	if memory.ID == 0 || memory.UpdatedAt.IsZero() {
+		return
+	}
+	var encryptedData utils.EncryptedData
+	if err := json.Unmarshal(memory.EncryptedContent, &encryptedData); err != nil || !encryptedData.NeedsUpgrade() {
+		return
+	}
+
+	owner := s.contentOwner(memory)
+	upgraded := &models.Memory{ID: memory.ID, Content: memory.Content}
+	if err := encryptMemoryContent(encryption, upgraded, owner); err != nil {
+		s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to upgrade encrypted content")
+		return
+	}
+
+	result := s.db.Model(&models.Memory{}).
+		Where("id = ? AND user_id = ? AND updated_at = ?", memory.ID, owner, memory.UpdatedAt).
+		UpdateColumn("encrypted_content", upgraded.EncryptedContent)
+	if result.Error != nil {
+		s.logger.Warn().Err(result.Error).Uint("id", memory.ID).Msg("failed to upgrade encrypted content")
+		return
+	}
+	if result.RowsAffected > 0 {
+		memory.EncryptedContent = upgraded.EncryptedContent
+		s.logger.Debug().Uint("id", memory.ID).Int("version", utils.CurrentEnvelopeVersion).Msg("upgraded encrypted content envelope")
+	}
+}
+
+// decryptMemoryContent replaces the encrypted marker with the decrypted content.
+// Content in a current envelope only decrypts for the owner and memory it was
+// encrypted for.
+func decryptMemoryContent(encryption *utils.EncryptionService, memory *models.Memory, ownerID uint) error {
+	if encryption == nil {
 		return fmt.Errorf("content is encrypted but encryption service is not available")
 	}
 	
@@ -930,7 +1661,7 @@ func (s *MemoryService) decryptContent(memory *models.Memory) error {
 	}
 	
 	// Decrypt the content
-	decrypted, err := s.encryption.DecryptField(&encryptedData)
+	decrypted, err := encryption.DecryptFieldWithAAD(&encryptedData, memoryContentAAD(&encryptedData, ownerID, memory.ID))
 	if err != nil {
 		return fmt.Errorf("failed to decrypt content: %w", err)
 	}
@@ -938,4 +1669,29 @@ func (s *MemoryService) decryptContent(memory *models.Memory) error {
 	memory.Content = decrypted
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// memoryContentAAD returns the additional authenticated data the envelope was
+// sealed with. Version 2 envelopes were bound to the owner only, as the memory's
+// ID was not known when its content was encrypted.
+func memoryContentAAD(encryptedData *utils.EncryptedData, ownerID, memoryID uint) []byte {
+	if encryptedData.EnvelopeVersion() == utils.EnvelopeV2 {
+		return models.OwnerContentAAD(ownerID)
+	}
+	return models.MemoryContentAAD(ownerID, memoryID)
+}
+
+// logActivity records an activity for the service's user. Failures are logged but
+// never fail the calling operation.
+func (s *MemoryService) logActivity(ctx context.Context, activityType string, details map[string]interface{}) {
+	activity := &models.ActivityLog{
+		UserID: s.userID,
+		Type:   activityType,
+	}
+	if data, err := json.Marshal(details); err == nil {
+		activity.Details = data
+	}
+	if err := s.db.WithContext(ctx).Create(activity).Error; err != nil {
+		s.logger.Warn().Err(err).Str("type", activityType).Msg("failed to record activity")
+	}
+}
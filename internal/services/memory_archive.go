@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// MemoryArchiveVersion is the current format version of memory export archives
+const MemoryArchiveVersion = 1
+
+// MemoryArchive is a portable export of a user's memories. Like config bundles,
+// archives carry no database or user IDs so they can be imported into another
+// account or deployment. Content is exported decrypted by default, since the
+// importing deployment will usually not share the exporting one's encryption key.
+type MemoryArchive struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Region     string    `json:"region,omitempty"`
+	// Anonymized is set when personal information was replaced with pseudonyms
+	Anonymized bool             `json:"anonymized,omitempty"`
+	Memories   []ArchivedMemory `json:"memories"`
+}
+
+// ArchivedMemory is a single memory in an export archive. Memories exported with
+// KeepEncrypted carry their encrypted payload instead of content; only a
+// deployment with the same master key can import them.
+type ArchivedMemory struct {
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Content  string `json:"content,omitempty"`
+	// EncryptedContent is the encrypted payload in place of Content
+	EncryptedContent json.RawMessage    `json:"encrypted_content,omitempty" swaggertype:"object"`
+	Priority         string             `json:"priority,omitempty"`
+	UpdateKey        string             `json:"update_key,omitempty"`
+	SessionID        string             `json:"session_id,omitempty"`
+	Tags             []string           `json:"tags,omitempty"`
+	Metadata         json.RawMessage    `json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	Embedding        *ArchivedEmbedding `json:"embedding,omitempty"`
+}
+
+// ArchivedEmbedding is an embedding vector together with the model that produced it.
+// Vector holds the float32 components, little-endian, base64 encoded.
+type ArchivedEmbedding struct {
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	Vector     string `json:"vector"`
+}
+
+// ImportMemoriesResult summarises what a memory import changed
+type ImportMemoriesResult struct {
+	Created int `json:"created"`
+	// Skipped counts memories whose content the user already has
+	Skipped int `json:"skipped"`
+	// EmbeddingsRestored counts memories whose archived embedding was reused as-is
+	EmbeddingsRestored int `json:"embeddings_restored"`
+	// EmbeddingsQueued counts memories queued for embedding generation because the
+	// archive had no embedding for them or it came from a different model
+	EmbeddingsQueued int `json:"embeddings_queued"`
+}
+
+// embeddingModelNamer is implemented by embedding services that can name their model
+type embeddingModelNamer interface {
+	GetModel() string
+}
+
+// NewArchivedEmbedding encodes an embedding vector for an export archive
+func NewArchivedEmbedding(model string, vector []float32) *ArchivedEmbedding {
+	return &ArchivedEmbedding{
+		Model:      model,
+		Dimensions: len(vector),
+		Vector:     base64.StdEncoding.EncodeToString(encodeVector(vector)),
+	}
+}
+
+// Decode returns the embedding vector, checking it against the declared dimensions
+func (e *ArchivedEmbedding) Decode() ([]float32, error) {
+	buf, err := base64.StdEncoding.DecodeString(e.Vector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedding encoding: %w", err)
+	}
+	return decodeVector(buf, e.Dimensions)
+}
+
+// EmbeddingModel returns the name of the model this deployment embeds with, or an
+// empty string when it is unknown
+func (s *MemoryService) EmbeddingModel() string {
+	if namer, ok := s.embedding.(embeddingModelNamer); ok {
+		return namer.GetModel()
+	}
+	return ""
+}
+
+// exportBatchSize is how many memories an export reads from the database at once
+const exportBatchSize = 500
+
+// ExportOptions controls what an export archive contains
+type ExportOptions struct {
+	// IncludeEmbeddings adds each memory's embedding so the importing deployment can
+	// skip re-embedding
+	IncludeEmbeddings bool
+	// KeepEncrypted exports encrypted memories as their encrypted payload rather than
+	// decrypted content, for backups and moves between servers sharing a master key
+	KeepEncrypted bool
+	// Filter limits the export to matching memories; the zero value exports all
+	Filter ExportFilter
+	// Anonymize, when set, replaces personal information with stable pseudonyms;
+	// see Anonymizer
+	Anonymize *AnonymizeOptions
+}
+
+// ExportFilter selects the memories to export with the filters of a keyword search
+type ExportFilter struct {
+	// Query keeps memories whose content contains it, ignoring case
+	Query         string
+	Category      string
+	Type          string
+	MetadataQuery string
+	Tags          []string
+	TagMatch      string
+}
+
+// IsSet reports whether the filter selects anything less than every memory
+func (f ExportFilter) IsSet() bool {
+	query := strings.TrimSpace(f.Query)
+	return (query != "" && query != "*") || f.Category != "" || f.Type != "" || f.MetadataQuery != "" || len(f.Tags) > 0
+}
+
+// ExportMemories collects the user's memories into a portable archive, optionally
+// including their embeddings so the importing deployment can skip re-embedding
+func (s *MemoryService) ExportMemories(ctx context.Context, includeEmbeddings bool) (*MemoryArchive, error) {
+	return s.ExportMemoriesWithOptions(ctx, ExportOptions{IncludeEmbeddings: includeEmbeddings})
+}
+
+// ExportMemoriesWithOptions collects the user's memories into a portable archive
+func (s *MemoryService) ExportMemoriesWithOptions(ctx context.Context, opts ExportOptions) (*MemoryArchive, error) {
+	archive := s.NewArchive()
+	archive.Anonymized = opts.Anonymize != nil
+	if _, err := s.StreamMemories(ctx, opts, func(archived *ArchivedMemory) error {
+		archive.Memories = append(archive.Memories, *archived)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// NewArchive returns an empty archive stamped with this deployment's region
+func (s *MemoryService) NewArchive() *MemoryArchive {
+	return &MemoryArchive{
+		Version:    MemoryArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Region:     s.ResidencyRegion(),
+		Memories:   []ArchivedMemory{},
+	}
+}
+
+// StreamMemories passes the user's memories matching the export filter to fn one
+// at a time, oldest first, reading them in batches so exports of any size run in
+// bounded memory. The filter is checked before fn is first called, and streaming
+// stops at the first error fn returns. It returns how many memories were passed.
+//
+// Anonymized exports read the memories twice: once to find the names and
+// companies to replace, so each gets the same pseudonym wherever it appears, and
+// once to export them.
+func (s *MemoryService) StreamMemories(ctx context.Context, opts ExportOptions, fn func(*ArchivedMemory) error) (int, error) {
+	filter := opts.Filter
+	query, err := s.listQuery(ctx, ListRequest{
+		Category:      filter.Category,
+		Type:          filter.Type,
+		MetadataQuery: filter.MetadataQuery,
+		Tags:          filter.Tags,
+		TagMatch:      filter.TagMatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if text := strings.TrimSpace(filter.Query); text != "" && text != "*" {
+		query = query.Where("LOWER(content) LIKE ?", "%"+strings.ToLower(text)+"%")
+	}
+
+	var anonymizer *Anonymizer
+	if opts.Anonymize != nil {
+		if opts.KeepEncrypted {
+			return 0, utils.InvalidFieldError("anonymize", "cannot be combined with keep_encrypted")
+		}
+		if opts.IncludeEmbeddings {
+			// Embeddings are computed from the original content and would leak it
+			return 0, utils.InvalidFieldError("anonymize", "cannot be combined with include_embeddings")
+		}
+		if anonymizer, err = NewAnonymizer(*opts.Anonymize); err != nil {
+			return 0, err
+		}
+		if err := s.exportBatches(ctx, query, func(memories []*models.Memory) error {
+			for _, memory := range memories {
+				if err := s.decryptContent(memory); err != nil {
+					return fmt.Errorf("memory %d: %w", memory.ID, err)
+				}
+				anonymizer.Collect(memory.Content)
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	}
+	model := s.EmbeddingModel()
+
+	exported, embedded := 0, 0
+	err = s.exportBatches(ctx, query, func(memories []*models.Memory) error {
+		s.auditReads(ctx, memories)
+		var embeddings map[uint][]float32
+		if opts.IncludeEmbeddings {
+			ids := make([]uint, len(memories))
+			for i, memory := range memories {
+				ids[i] = memory.ID
+			}
+			var err error
+			if embeddings, err = s.loadEmbeddings(ctx, ids); err != nil {
+				s.logger.Error().Err(err).Msg("failed to load embeddings for export")
+				return utils.WrapDatabaseError("export embeddings", err)
+			}
+		}
+
+		for _, memory := range memories {
+			archived, err := s.archiveMemory(memory, opts.KeepEncrypted)
+			if err != nil {
+				return err
+			}
+			if vector, ok := embeddings[memory.ID]; ok {
+				archived.Embedding = NewArchivedEmbedding(model, vector)
+				embedded++
+			}
+			if anonymizer != nil {
+				anonymizer.AnonymizeMemory(archived)
+			}
+			if err := fn(archived); err != nil {
+				return err
+			}
+			exported++
+		}
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	s.logger.Info().
+		Int("memory_count", exported).
+		Int("embedding_count", embedded).
+		Bool("anonymized", anonymizer != nil).
+		Msg("exported memories")
+
+	return exported, nil
+}
+
+// exportBatches passes the memories selected by query to fn in batches, oldest
+// first. Paging by position is not thrown off by memories added meanwhile.
+func (s *MemoryService) exportBatches(ctx context.Context, query *gorm.DB, fn func([]*models.Memory) error) error {
+	var after *ListPosition
+	for {
+		batch := query.Session(&gorm.Session{}).Omit("embedding")
+		if after != nil {
+			batch = batch.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+		var memories []*models.Memory
+		if err := batch.Order("created_at ASC, id ASC").Limit(exportBatchSize).Find(&memories).Error; err != nil {
+			s.logger.Error().Err(err).Msg("failed to load memories for export")
+			return utils.WrapDatabaseError("export memories", err)
+		}
+		if len(memories) == 0 {
+			return nil
+		}
+		if err := fn(memories); err != nil {
+			return err
+		}
+
+		last := memories[len(memories)-1]
+		after = &ListPosition{CreatedAt: last.CreatedAt, ID: last.ID}
+		if len(memories) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// archiveMemory converts a memory for an export archive, decrypting its content
+// unless keepEncrypted is set and it is encrypted
+func (s *MemoryService) archiveMemory(memory *models.Memory, keepEncrypted bool) (*ArchivedMemory, error) {
+	keepEncrypted = keepEncrypted && memory.IsEncrypted && len(memory.EncryptedContent) > 0
+	if !keepEncrypted {
+		if err := s.decryptContent(memory); err != nil {
+			return nil, fmt.Errorf("memory %d: %w", memory.ID, err)
+		}
+	}
+
+	archived := &ArchivedMemory{
+		Type:      memory.Type,
+		Category:  memory.Category,
+		Content:   memory.Content,
+		Priority:  memory.Priority,
+		UpdateKey: memory.UpdateKey,
+		SessionID: memory.SessionID,
+		Tags:      memory.Tags,
+		Metadata:  memory.Metadata,
+		CreatedAt: memory.CreatedAt,
+		UpdatedAt: memory.UpdatedAt,
+	}
+	if keepEncrypted {
+		encryptedContent, err := s.exportableContent(memory)
+		if err != nil {
+			return nil, fmt.Errorf("memory %d: %w", memory.ID, err)
+		}
+		archived.Content = ""
+		archived.EncryptedContent = encryptedContent
+	}
+	return archived, nil
+}
+
+// loadEmbeddings returns the stored embeddings of the given memories keyed by
+// memory ID. Only rows with an embedding are read, as pgvector cannot scan NULL
+// into a vector.
+func (s *MemoryService) loadEmbeddings(ctx context.Context, ids []uint) (map[uint][]float32, error) {
+	embeddings := make(map[uint][]float32)
+
+	// The sqlite schema used in tests has no vector type
+	if s.db.Dialector.Name() == "sqlite" {
+		return embeddings, nil
+	}
+
+	var rows []struct {
+		ID        uint
+		Embedding pgvector.Vector
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Select("id, embedding").
+		Where("user_id = ? AND id IN ? AND embedding IS NOT NULL", s.userID, ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		embeddings[row.ID] = row.Embedding.Slice()
+	}
+	return embeddings, nil
+}
+
+// ImportMemories adds the memories of an archive to the user's store. Memories the
+// user already has (by content) are skipped. Archived embeddings are reused when
+// they were produced by the model this deployment uses; everything else is queued
+// for embedding generation. The import is all-or-nothing.
+func (s *MemoryService) ImportMemories(ctx context.Context, archive *MemoryArchive, allowCrossRegion bool) (*ImportMemoriesResult, error) {
+	if archive == nil {
+		return nil, utils.RequiredFieldError("archive")
+	}
+	if archive.Version < 1 || archive.Version > MemoryArchiveVersion {
+		return nil, utils.InvalidFieldError("version", fmt.Sprintf("unsupported archive version %d", archive.Version))
+	}
+	if err := CheckResidency("memory archive", archive.Region, s.ResidencyRegion(), allowCrossRegion); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(archive.Memories))
+	decisions := make([]*ModerationDecision, len(archive.Memories))
+	for i := range archive.Memories {
+		archived := &archive.Memories[i]
+		if err := s.decryptArchivedContent(archived); err != nil {
+			return nil, fmt.Errorf("memory %d: %w", i, err)
+		}
+		if archived.Content == "" {
+			return nil, fmt.Errorf("memory %d: %w", i, utils.RequiredFieldError("content"))
+		}
+		if !models.IsValidType(archived.Type) {
+			return nil, fmt.Errorf("memory %d: %w", i, utils.InvalidFieldError("type", archived.Type))
+		}
+		if !models.IsValidCategory(archived.Category) {
+			return nil, fmt.Errorf("memory %d: %w", i, utils.InvalidFieldError("category", archived.Category))
+		}
+		if archived.Priority != "" && !models.IsValidPriority(archived.Priority) {
+			return nil, fmt.Errorf("memory %d: %w", i, utils.InvalidFieldError("priority", archived.Priority))
+		}
+		if err := s.checkContentLength(archived.Content); err != nil {
+			return nil, fmt.Errorf("memory %d: %w", i, err)
+		}
+		decision, err := s.moderate(ctx, archived.Content)
+		if err != nil {
+			return nil, fmt.Errorf("memory %d: %w", i, err)
+		}
+		decisions[i] = decision
+		// Archives hold no chunks, so long memories are embedded afresh
+		if s.canRestoreEmbedding(archived.Embedding) && s.chunkContent(archived.Content) == nil {
+			vector, err := archived.Embedding.Decode()
+			if err != nil {
+				return nil, fmt.Errorf("memory %d: %w", i, utils.InvalidFieldError("embedding", err.Error()))
+			}
+			vectors[i] = vector
+		}
+	}
+
+	result := &ImportMemoriesResult{}
+	var pending []*models.Memory
+	var pendingContent []string
+
+	encryption, err := s.contentEncryption(&models.Memory{UserID: s.userID})
+	if err != nil {
+		return nil, utils.WrapDatabaseError("import memories", err)
+	}
+
+	err = s.transaction(ctx, func(tx *gorm.DB) error {
+		for i := range archive.Memories {
+			archived := &archive.Memories[i]
+			hash, err := s.contentHash(archived.Content)
+			if err != nil {
+				return err
+			}
+
+			var count int64
+			if err := tx.Model(&models.Memory{}).
+				Where("user_id = ? AND content_hash = ?", s.userID, hash).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				result.Skipped++
+				continue
+			}
+
+			priority := archived.Priority
+			if priority == "" {
+				priority = models.PriorityMedium
+			}
+			memory := &models.Memory{
+				UserID:      s.userID,
+				Type:        archived.Type,
+				Category:    archived.Category,
+				Content:     archived.Content,
+				ContentHash: hash,
+				Priority:    priority,
+				UpdateKey:   archived.UpdateKey,
+				SessionID:   archived.SessionID,
+				Tags:        archived.Tags,
+				Metadata:    archived.Metadata,
+				CreatedAt:   archived.CreatedAt,
+				UpdatedAt:   archived.UpdatedAt,
+			}
+			if err := s.createMemory(tx, memory, encryption, nil); err != nil {
+				return err
+			}
+			result.Created++
+
+			if vectors[i] != nil {
+				if err := tx.Model(memory).Where("user_id = ?", memory.UserID).UpdateColumn("embedding", pgvector.NewVector(vectors[i])).Error; err != nil {
+					return err
+				}
+				result.EmbeddingsRestored++
+				continue
+			}
+
+			pending = append(pending, memory)
+			pendingContent = append(pendingContent, archived.Content)
+		}
+
+		if limit := s.MemoryLimit(ctx); limit > 0 && s.EvictionPolicy(ctx) == EvictionRejectNew {
+			var total int64
+			if err := tx.Model(&models.Memory{}).Where("user_id = ?", s.userID).Count(&total).Error; err != nil {
+				return err
+			}
+			if total > int64(limit) {
+				return &MemoryLimitError{Limit: limit}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrMemoryLimitReached) {
+			return nil, err
+		}
+		s.logger.Error().Err(err).Msg("failed to import memories")
+		return nil, utils.WrapDatabaseError("import memories", err)
+	}
+
+	// Generate embeddings for the rest in the background, as a normal store would
+	if s.embedding != nil {
+		for i, memory := range pending {
+			s.embedAsync(memory.ID, embeddingFieldsOf(memory, pendingContent[i]))
+		}
+		result.EmbeddingsQueued = len(pending)
+	}
+
+	if _, err := s.enforceMemoryLimit(ctx); err != nil {
+		s.logger.Warn().Err(err).Msg("failed to enforce memory limit after import")
+	}
+
+	s.logger.Info().
+		Int("created", result.Created).
+		Int("skipped", result.Skipped).
+		Int("embeddings_restored", result.EmbeddingsRestored).
+		Int("embeddings_queued", result.EmbeddingsQueued).
+		Msg("imported memories")
+
+	return result, nil
+}
+
+// decryptArchivedContent replaces an archived encrypted payload with its content.
+// The payload is re-encrypted on import if this deployment encrypts content.
+func (s *MemoryService) decryptArchivedContent(archived *ArchivedMemory) error {
+	if len(archived.EncryptedContent) == 0 {
+		return nil
+	}
+
+	memory := &models.Memory{IsEncrypted: true, EncryptedContent: archived.EncryptedContent}
+	if err := s.decryptContent(memory); err != nil {
+		return utils.InvalidFieldError("encrypted_content", "cannot be decrypted with this server's encryption key")
+	}
+	archived.Content = memory.Content
+	archived.EncryptedContent = nil
+	return nil
+}
+
+// canRestoreEmbedding reports whether an archived embedding can be stored as-is,
+// which requires it to come from the model this deployment embeds with
+func (s *MemoryService) canRestoreEmbedding(embedding *ArchivedEmbedding) bool {
+	if embedding == nil || embedding.Dimensions != EmbeddingDimension {
+		return false
+	}
+	model := s.EmbeddingModel()
+	return model != "" && embedding.Model == model
+}
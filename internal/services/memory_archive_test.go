@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestArchivedEmbedding_RoundTrip(t *testing.T) {
+	vector := []float32{0.25, -1, 3.5e-7, 0}
+
+	embedding := NewArchivedEmbedding("text-embedding-3-small", vector)
+	assert.Equal(t, 4, embedding.Dimensions)
+
+	decoded, err := embedding.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, vector, decoded)
+
+	embedding.Dimensions = 5
+	_, err = embedding.Decode()
+	assert.Error(t, err)
+}
+
+func TestMemoryService_ExportImportMemories(t *testing.T) {
+	ctx := context.Background()
+	source := setupMemoryService(t, nil)
+	storeTestMemory(t, source, "exported fact")
+	storeTestMemory(t, source, "already known")
+
+	archive, err := source.ExportMemories(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, archive.Memories, 2)
+	assert.Equal(t, MemoryArchiveVersion, archive.Version)
+	assert.Equal(t, "exported fact", archive.Memories[0].Content)
+
+	target := NewMemoryServiceWithUser(source.db, nil, zerolog.New(nil).Level(zerolog.Disabled), nil, 2)
+	storeTestMemory(t, target, "already known")
+
+	result, err := target.ImportMemories(ctx, archive, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Skipped)
+
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestMemoryService_ImportMemoriesEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	service.embedding = NewMockEmbeddingService()
+
+	vector, err := service.embedding.GenerateEmbedding(ctx, "same model")
+	require.NoError(t, err)
+
+	archive := &MemoryArchive{
+		Version: MemoryArchiveVersion,
+		Memories: []ArchivedMemory{
+			{
+				Type:      models.TypeFact,
+				Category:  models.CategoryPersonal,
+				Content:   "same model",
+				Embedding: NewArchivedEmbedding("mock", vector),
+			},
+			{
+				Type:      models.TypeFact,
+				Category:  models.CategoryPersonal,
+				Content:   "different model",
+				Embedding: NewArchivedEmbedding("another-model", vector),
+			},
+		},
+	}
+
+	result, err := service.ImportMemories(ctx, archive, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 1, result.EmbeddingsRestored)
+	assert.Equal(t, 1, result.EmbeddingsQueued)
+}
+
+func TestMemoryService_ImportMemoriesValidation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{"residency_region": "eu-west-1"})
+
+	_, err := service.ImportMemories(ctx, &MemoryArchive{Version: 99}, false)
+	assert.Error(t, err)
+
+	_, err = service.ImportMemories(ctx, &MemoryArchive{Version: 1, Region: "us-east-1"}, false)
+	assert.True(t, errors.Is(err, ErrCrossRegion))
+
+	_, err = service.ImportMemories(ctx, &MemoryArchive{
+		Version:  1,
+		Memories: []ArchivedMemory{{Type: "bogus", Category: models.CategoryPersonal, Content: "x"}},
+	}, false)
+	assert.Error(t, err)
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestArchiveJSONL_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	storeTestMemory(t, service, "first line")
+	storeTestMemory(t, service, "second line")
+
+	archive, err := service.ExportMemories(ctx, false)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchiveJSONL(&buf, archive))
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+
+	decoded, err := ReadArchiveJSONL(strings.NewReader(buf.String() + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, archive.Version, decoded.Version)
+	require.Len(t, decoded.Memories, 2)
+	assert.Equal(t, archive.Memories[0].Content, decoded.Memories[0].Content)
+
+	_, err = ReadArchiveJSONL(strings.NewReader(""))
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = ReadArchiveJSONL(strings.NewReader(`{"version":1}` + "\nnot json\n"))
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMemoryService_ExportKeepEncrypted(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+
+	source := setupMemoryService(t, map[string]interface{}{"encryption_service": encryption})
+	storeTestMemory(t, source, "encrypted secret")
+
+	archive, err := source.ExportMemoriesWithOptions(ctx, ExportOptions{KeepEncrypted: true})
+	require.NoError(t, err)
+	require.Len(t, archive.Memories, 1)
+	assert.Empty(t, archive.Memories[0].Content)
+	assert.NotEmpty(t, archive.Memories[0].EncryptedContent)
+
+	// A server with another key cannot read the payload
+	otherKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	otherEncryption, err := utils.NewEncryptionService(otherKey)
+	require.NoError(t, err)
+	other := NewMemoryServiceWithUser(source.db, nil, zerolog.New(nil).Level(zerolog.Disabled),
+		map[string]interface{}{"encryption_service": otherEncryption}, 3)
+	_, err = other.ImportMemories(ctx, archive, false)
+	assert.True(t, utils.IsValidationError(err))
+
+	target := NewMemoryServiceWithUser(source.db, nil, zerolog.New(nil).Level(zerolog.Disabled),
+		map[string]interface{}{"encryption_service": encryption}, 2)
+	result, err := target.ImportMemories(ctx, archive, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+
+	memories, err := target.List(ctx, ListRequest{})
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	assert.Equal(t, "encrypted secret", memories[0].Content)
+}
+
+func TestMemoryService_StreamMemoriesFiltered(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	for _, req := range []StoreRequest{
+		{Content: "Blood pressure was 120/80", Type: models.TypeFact, Category: models.CategoryPersonal},
+		{Content: "Health insurance renews in March", Type: models.TypeFact, Category: models.CategoryBusiness},
+		{Content: "Dentist appointment next week", Type: models.TypeContext, Category: models.CategoryPersonal},
+	} {
+		_, err := service.Store(ctx, req)
+		require.NoError(t, err)
+	}
+
+	var contents []string
+	count, err := service.StreamMemories(ctx, ExportOptions{Filter: ExportFilter{Query: "HEALTH"}}, func(memory *ArchivedMemory) error {
+		contents = append(contents, memory.Content)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"Health insurance renews in March"}, contents)
+
+	archive, err := service.ExportMemoriesWithOptions(ctx, ExportOptions{Filter: ExportFilter{Category: models.CategoryPersonal, Type: models.TypeContext}})
+	require.NoError(t, err)
+	require.Len(t, archive.Memories, 1)
+	assert.Equal(t, "Dentist appointment next week", archive.Memories[0].Content)
+
+	// Filters are checked before anything is streamed
+	called := false
+	_, err = service.StreamMemories(ctx, ExportOptions{Filter: ExportFilter{TagMatch: "most"}}, func(*ArchivedMemory) error {
+		called = true
+		return nil
+	})
+	assert.True(t, utils.IsValidationError(err))
+	assert.False(t, called)
+
+	// Errors from the callback stop the stream
+	stop := errors.New("stop")
+	count, err = service.StreamMemories(ctx, ExportOptions{}, func(*ArchivedMemory) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.Zero(t, count)
+}
+
+func TestMemoryService_StreamMemoriesBatches(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	// Memories created in the same instant still page by ID
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	memories := make([]models.Memory, exportBatchSize+2)
+	for i := range memories {
+		memories[i] = models.Memory{
+			UserID:    service.userID,
+			Type:      models.TypeFact,
+			Category:  models.CategoryPersonal,
+			Content:   fmt.Sprintf("note %d", i),
+			Priority:  models.PriorityMedium,
+			CreatedAt: createdAt,
+		}
+	}
+	require.NoError(t, service.db.CreateInBatches(memories, 100).Error)
+
+	seen := make(map[string]bool)
+	count, err := service.StreamMemories(ctx, ExportOptions{}, func(memory *ArchivedMemory) error {
+		seen[memory.Content] = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(memories), count)
+	assert.Len(t, seen, len(memories))
+}
+
+func TestArchiveJSONLWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewArchiveJSONLWriter(&buf, &MemoryArchive{Version: MemoryArchiveVersion, Region: "eu"})
+	assert.False(t, writer.Started())
+	require.NoError(t, writer.Close())
+	assert.True(t, writer.Started())
+
+	archive, err := ReadArchiveJSONL(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "eu", archive.Region)
+	assert.Empty(t, archive.Memories)
+}
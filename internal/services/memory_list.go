@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// defaultListLimit caps listings that do not set a limit
+const defaultListLimit = 100
+
+// ListRequest selects memories without a query. Search turns the wildcard query
+// "*" and the empty query into a ListRequest with the same filters.
+type ListRequest struct {
+	Category      string
+	Type          string
+	MetadataQuery string
+	Tags          []string
+	TagMatch      string
+	Limit         int
+	Offset        int
+	// After continues a listing after the given memory; it takes precedence over Offset
+	After *ListPosition
+	// WithinIDs, when not nil, lists only these memories
+	WithinIDs []uint
+	// DateRange keeps memories created or updated within its bounds
+	DateRange
+	// PriorityFilter keeps memories of the given priorities
+	PriorityFilter
+	// Session keeps memories captured in a working session; CurrentSession
+	// means the open one
+	Session string
+}
+
+// ListPosition is a memory's place in the newest-first listing order
+type ListPosition struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// List returns the user's memories matching the filters, newest first. Memories
+// created in the same instant are ordered by ID so pages are stable. Listing is
+// not recall, so unlike Search it does not count as an access; otherwise listing
+// everything would reset the least_accessed eviction order.
+func (s *MemoryService) List(ctx context.Context, req ListRequest) ([]*models.Memory, error) {
+	if err := validateOffset(req.Offset); err != nil {
+		return nil, err
+	}
+	query, err := s.listQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	// Keyset pagination is not thrown off by memories added while paging
+	if req.After != nil {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			req.After.CreatedAt, req.After.CreatedAt, req.After.ID)
+	} else if req.Offset > 0 {
+		query = query.Offset(req.Offset)
+	}
+
+	// For SQLite, omit fields that cause issues
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+
+	var memories []*models.Memory
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&memories).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to list memories")
+		return nil, utils.WrapDatabaseError("list memories", err)
+	}
+
+	for _, memory := range memories {
+		if err := s.decryptContent(memory); err != nil {
+			s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+		}
+	}
+	s.auditReads(ctx, memories)
+
+	return memories, nil
+}
+
+// listQuery selects the user's memories matching the listing filters
+func (s *MemoryService) listQuery(ctx context.Context, req ListRequest) (*gorm.DB, error) {
+	metadataQuery, err := s.parseMetadataQuery(req.MetadataQuery)
+	if err != nil {
+		return nil, err
+	}
+	tagFilter, err := s.parseTagFilter(req.Tags, req.TagMatch)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.DateRange.validate(); err != nil {
+		return nil, err
+	}
+	if err := req.PriorityFilter.validate(); err != nil {
+		return nil, err
+	}
+	session, err := s.resolveSession(ctx, req.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Memory{}).Where("user_id = ?", s.userID)
+	query = filterMemories(query, req.Category, req.Type)
+	if metadataQuery != nil {
+		query = query.Where(metadataQuery.condition("?"), metadataQuery.value())
+	}
+	if tagFilter != nil {
+		query = query.Where(tagFilter.condition("?"), tagFilter.value())
+	}
+	if req.WithinIDs != nil {
+		query = query.Where("id IN ?", req.WithinIDs)
+	}
+	query = req.DateRange.apply(query)
+	query = req.PriorityFilter.apply(query)
+	if session != "" {
+		query = query.Where("session_id = ?", session)
+	}
+	return query, nil
+}
+
+// filterMemories applies the optional category and type filters
+func filterMemories(query *gorm.DB, category, memoryType string) *gorm.DB {
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if memoryType != "" {
+		query = query.Where("type = ?", memoryType)
+	}
+	return query
+}
+
+// listRequest returns the listing a wildcard search runs
+func (r SearchRequest) listRequest() ListRequest {
+	return ListRequest{
+		Category:       r.Category,
+		Type:           r.Type,
+		MetadataQuery:  r.MetadataQuery,
+		Tags:           r.Tags,
+		TagMatch:       r.TagMatch,
+		Limit:          r.Limit,
+		Offset:         r.Offset,
+		WithinIDs:      r.WithinIDs,
+		DateRange:      r.DateRange,
+		PriorityFilter: r.PriorityFilter,
+		Session:        r.Session,
+	}
+}
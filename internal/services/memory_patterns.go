@@ -3,6 +3,7 @@ package services
 import (
 	"regexp"
 	"strings"
+	"time"
 )
 
 // MemoryPattern represents a pattern for automatic memory detection
@@ -220,6 +221,7 @@ type DetectedMemory struct {
 	Priority   MemoryPriority
 	UpdateKey  string // Key for deduplication/updates
 	Confidence float64
+	DueAt      *time.Time // Date the content refers to, such as "next Friday"
 }
 
 // DetectMemoryPatterns automatically detects memory-worthy content
@@ -231,6 +233,8 @@ func DetectMemoryPatterns(content string) []DetectedMemory {
 		return detected // Return empty if sensitive
 	}
 
+	dueAt := ParseRelativeDate(content, time.Now())
+
 	// Check against all memory patterns
 	for _, pattern := range memoryPatterns {
 		if pattern.Pattern.MatchString(content) {
@@ -241,6 +245,7 @@ func DetectMemoryPatterns(content string) []DetectedMemory {
 				Priority:   pattern.Priority,
 				UpdateKey:  pattern.KeyExtract(content),
 				Confidence: calculateConfidence(content, pattern),
+				DueAt:      dueAt,
 			}
 			detected = append(detected, memory)
 		}
@@ -249,6 +254,14 @@ func DetectMemoryPatterns(content string) []DetectedMemory {
 	return detected
 }
 
+// annotate adds what was detected beyond the memory's type and category to its
+// metadata
+func (d *DetectedMemory) annotate(metadata map[string]interface{}) {
+	if d.DueAt != nil {
+		metadata[MetadataDueAt] = d.DueAt.Format(time.RFC3339)
+	}
+}
+
 // containsSensitiveInfo checks if content contains sensitive information
 func containsSensitiveInfo(content string) bool {
 	for _, pattern := range sensitivePatterns {
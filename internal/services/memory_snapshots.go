@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// snapshotColumns are the memory columns a snapshot keeps, named the same in
+// memories and memory_snapshot_items. The ID and timestamps are mapped separately.
+var snapshotColumns = strings.Join(snapshotColumnNames, ", ")
+
+var snapshotColumnNames = []string{
+	"workspace_id", "type", "category", "content", "encrypted_content", "is_encrypted",
+	"priority", "update_key", "content_hash", "content_index", "keyword_index", "embedding",
+	"tags", "metadata", "access_count", "last_accessed_at", "session_id",
+}
+
+// snapshotRestoreAssignments sets every snapshot column of a memory from the
+// snapshot item aliased i
+func snapshotRestoreAssignments() string {
+	assignments := make([]string, 0, len(snapshotColumnNames)+2)
+	for _, column := range snapshotColumnNames {
+		assignments = append(assignments, column+" = i."+column)
+	}
+	assignments = append(assignments, "created_at = i.memory_created_at", "updated_at = i.memory_updated_at")
+	return strings.Join(assignments, ", ")
+}
+
+// CreateSnapshot copies the user's current memories into a named snapshot.
+// The copy happens inside the database so embeddings and encrypted payloads
+// are preserved byte-for-byte without a round trip through the service.
+func (s *MemoryService) CreateSnapshot(ctx context.Context, name, description string) (*models.MemorySnapshot, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, utils.RequiredFieldError("name")
+	}
+
+	snapshot := &models.MemorySnapshot{
+		UserID:      s.userID,
+		Name:        name,
+		Description: description,
+		Region:      s.ResidencyRegion(),
+	}
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO memory_snapshot_items
+				(snapshot_id, memory_id, `+snapshotColumns+`, memory_created_at, memory_updated_at)
+			SELECT ?, id, `+snapshotColumns+`, created_at, updated_at
+			FROM memories
+			WHERE user_id = ?
+		`, snapshot.ID, s.userID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		snapshot.MemoryCount = int(result.RowsAffected)
+		return tx.Model(snapshot).Update("memory_count", snapshot.MemoryCount).Error
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("name", name).Msg("failed to create memory snapshot")
+		return nil, utils.WrapDatabaseError("create snapshot", err)
+	}
+
+	s.logger.Info().
+		Uint("snapshot_id", snapshot.ID).
+		Int("memory_count", snapshot.MemoryCount).
+		Msg("created memory snapshot")
+
+	return snapshot, nil
+}
+
+// ListSnapshots returns the user's snapshots, newest first
+func (s *MemoryService) ListSnapshots(ctx context.Context) ([]models.MemorySnapshot, error) {
+	var snapshots []models.MemorySnapshot
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("created_at DESC").
+		Find(&snapshots).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to list memory snapshots")
+		return nil, utils.WrapDatabaseError("list snapshots", err)
+	}
+
+	return snapshots, nil
+}
+
+// RestoreSnapshot returns the user's memories to their state in a snapshot.
+// Memories created after the snapshot are removed, memories in it are reset to
+// their snapshotted content, and memories deleted since are brought back under
+// their original IDs. Memories that survive the restore keep their revisions,
+// links, feedback, chunks and attachments. Snapshots taken in a different
+// residency region are refused unless allowCrossRegion is set.
+func (s *MemoryService) RestoreSnapshot(ctx context.Context, snapshotID uint, allowCrossRegion bool) (*models.MemorySnapshot, error) {
+	snapshot, err := s.findSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckResidency("snapshot", snapshot.Region, s.ResidencyRegion(), allowCrossRegion); err != nil {
+		return nil, err
+	}
+
+	// Only memories missing from the snapshot are deleted. Their attachments go
+	// with them, so the stored objects are found first and removed after commit.
+	snapshotted := s.db.Model(&models.MemorySnapshotItem{}).Select("memory_id").Where("snapshot_id = ?", snapshot.ID)
+	removedIDs := s.db.Model(&models.Memory{}).Select("id").Where("user_id = ? AND id NOT IN (?)", s.userID, snapshotted)
+	attachmentKeys := s.attachmentObjectKeys(ctx, "memory_id IN (?)", removedIDs)
+
+	var removed int64
+	err = s.transaction(ctx, func(tx *gorm.DB) error {
+		deleted := tx.Where("user_id = ? AND id NOT IN (?)", s.userID, snapshotted).Delete(&models.Memory{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		removed = deleted.RowsAffected
+
+		if err := tx.Exec(`
+			UPDATE memories SET `+snapshotRestoreAssignments()+`
+			FROM memory_snapshot_items AS i
+			WHERE i.snapshot_id = ? AND memories.id = i.memory_id AND memories.user_id = ?
+		`, snapshot.ID, s.userID).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			INSERT INTO memories
+				(id, user_id, `+snapshotColumns+`, created_at, updated_at)
+			SELECT memory_id, ?, `+snapshotColumns+`, memory_created_at, memory_updated_at
+			FROM memory_snapshot_items
+			WHERE snapshot_id = ? AND memory_id NOT IN (SELECT id FROM memories WHERE user_id = ?)
+		`, s.userID, snapshot.ID, s.userID).Error
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Uint("snapshot_id", snapshotID).Msg("failed to restore memory snapshot")
+		return nil, utils.WrapDatabaseError("restore snapshot", err)
+	}
+	s.removeAttachmentObjects(ctx, attachmentKeys)
+
+	s.logger.Info().
+		Uint("snapshot_id", snapshot.ID).
+		Int("memory_count", snapshot.MemoryCount).
+		Int64("removed", removed).
+		Msg("restored memory snapshot")
+
+	return snapshot, nil
+}
+
+// DeleteSnapshot removes a snapshot and its items
+func (s *MemoryService) DeleteSnapshot(ctx context.Context, snapshotID uint) error {
+	snapshot, err := s.findSnapshot(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	err = s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("snapshot_id = ?", snapshot.ID).Delete(&models.MemorySnapshotItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(snapshot).Error
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Uint("snapshot_id", snapshotID).Msg("failed to delete memory snapshot")
+		return utils.WrapDatabaseError("delete snapshot", err)
+	}
+
+	return nil
+}
+
+// findSnapshot loads a snapshot owned by the user
+func (s *MemoryService) findSnapshot(ctx context.Context, snapshotID uint) (*models.MemorySnapshot, error) {
+	var snapshot models.MemorySnapshot
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", snapshotID, s.userID).
+		First(&snapshot).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.WrapNotFoundError("snapshot", fmt.Sprintf("%d", snapshotID))
+		}
+		return nil, utils.WrapDatabaseError("find snapshot", err)
+	}
+
+	return &snapshot, nil
+}
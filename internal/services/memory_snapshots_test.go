@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func setupSnapshotService(t *testing.T, config map[string]interface{}) *MemoryService {
+	t.Helper()
+	service := setupMemoryService(t, config)
+	require.NoError(t, service.db.AutoMigrate(&models.User{}, &models.MemorySnapshot{}, &models.MemorySnapshotItem{}, &models.Attachment{}))
+	for _, id := range []uint{service.userID, service.userID + 1} {
+		testutil.NewUser().ID(id).Create(t, service.db)
+	}
+	// Enforce the cascading foreign keys a restore must not trip
+	require.NoError(t, service.db.Exec("PRAGMA foreign_keys = ON").Error)
+	return service
+}
+
+func TestMemoryService_RestoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryObjectStore()
+	service := setupSnapshotService(t, map[string]interface{}{"attachment_store": store})
+
+	kept, err := service.Store(ctx, StoreRequest{
+		Content:   "Favourite editor is vim",
+		Category:  models.CategoryPersonal,
+		Type:      models.TypePreference,
+		SessionID: "onboarding",
+	})
+	require.NoError(t, err)
+	_, err = service.Update(ctx, kept.ID, UpdateRequest{Content: "Favourite editor is emacs"})
+	require.NoError(t, err)
+	_, err = service.AddAttachment(ctx, kept.ID, AttachmentRequest{Data: []byte("dotfiles"), Filename: "init.el"})
+	require.NoError(t, err)
+	_, err = service.GetByID(WithAccessSource(ctx, AccessSourceHTTP), kept.ID)
+	require.NoError(t, err)
+	deleted, _ := storeTestMemory(t, service, "Lives in Lisbon")
+	var before models.Memory
+	require.NoError(t, service.db.Omit("embedding", "tags").First(&before, kept.ID).Error)
+	require.NotZero(t, before.AccessCount)
+
+	snapshot, err := service.CreateSnapshot(ctx, "before cleanup", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, snapshot.MemoryCount)
+
+	_, err = service.Update(ctx, kept.ID, UpdateRequest{Content: "Favourite editor is helix"})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(ctx, deleted.ID))
+	added, _ := storeTestMemory(t, service, "Moved to Porto")
+	_, err = service.AddAttachment(ctx, added.ID, AttachmentRequest{Data: []byte("lease"), Filename: "lease.txt"})
+	require.NoError(t, err)
+	require.Len(t, store.keys(), 2)
+
+	_, err = service.RestoreSnapshot(ctx, snapshot.ID, false)
+	require.NoError(t, err)
+
+	var row models.Memory
+	require.NoError(t, service.db.Omit("embedding", "tags").First(&row, kept.ID).Error)
+	assert.Equal(t, before.AccessCount, row.AccessCount, "access statistics are restored")
+	assert.Equal(t, "onboarding", row.SessionID)
+
+	restored, err := service.GetByID(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Favourite editor is emacs", restored.Content)
+
+	back, err := service.GetByID(ctx, deleted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Lives in Lisbon", back.Content)
+
+	_, err = service.GetByID(ctx, added.ID)
+	assert.True(t, utils.IsNotFoundError(err), "memories created after the snapshot are removed")
+
+	// Rows hanging off a memory in the snapshot survive the restore
+	history, err := service.GetMemoryHistory(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.Len(t, history.Revisions, 2)
+	attachments, err := service.ListAttachments(ctx, kept.ID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Len(t, store.keys(), 1, "the removed memory's attachment object is deleted")
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestMemoryService_RestoreSnapshotScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	service := setupSnapshotService(t, nil)
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, service.userID+1)
+
+	storeTestMemory(t, service, "Allergic to peanuts")
+	snapshot, err := service.CreateSnapshot(ctx, "mine", "")
+	require.NoError(t, err)
+	theirs, _ := storeTestMemory(t, other, "Likes hiking")
+
+	_, err = other.RestoreSnapshot(ctx, snapshot.ID, false)
+	assert.True(t, utils.IsNotFoundError(err))
+
+	_, err = service.RestoreSnapshot(ctx, snapshot.ID, false)
+	require.NoError(t, err)
+	got, err := other.GetByID(ctx, theirs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Likes hiking", got.Content)
+}
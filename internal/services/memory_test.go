@@ -9,44 +9,15 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 
 	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/testutil"
 )
 
 // setupTestDB creates an in-memory SQLite database for testing
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	require.NoError(t, err)
-
-	// Create table manually without pgvector fields for SQLite compatibility
-	err = db.Exec(`
-		CREATE TABLE memories (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			type TEXT NOT NULL,
-			category TEXT NOT NULL,
-			content TEXT NOT NULL,
-			embedding BLOB,
-			tags TEXT,
-			metadata TEXT,
-			created_at DATETIME,
-			updated_at DATETIME
-		)
-	`).Error
-	require.NoError(t, err)
-
-	// Create indexes
-	err = db.Exec(`CREATE INDEX idx_memories_type ON memories(type)`).Error
-	require.NoError(t, err)
-	
-	err = db.Exec(`CREATE INDEX idx_memories_category ON memories(category)`).Error
-	require.NoError(t, err)
-
-	return db
+	return testutil.SQLiteDB(t, &models.MemoryRevision{}, &models.ContextTurn{}, &models.LLMUsage{}, &models.MemoryFeedback{}, &models.MemoryLink{}, &models.MemorySession{}, &models.CategorizationRule{}, &models.Confirmation{})
 }
 
 // setupMemoryService creates a test memory service with an in-memory database
@@ -223,6 +194,67 @@ func TestMemoryService_Store(t *testing.T) {
 	})
 }
 
+func TestMemoryService_StoreReportsAction(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	req := StoreRequest{
+		Content:   "Favourite editor is vim",
+		Type:      models.TypePreference,
+		Category:  models.CategoryPersonal,
+		UpdateKey: "favourite_editor",
+	}
+
+	created, err := service.StoreWithResult(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, StoreCreated, created.Action)
+	assert.Empty(t, created.MatchedBy)
+	assert.Empty(t, created.PreviousContent)
+
+	req.Content = "Favourite editor is helix"
+	updated, err := service.StoreWithResult(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, created.Memory.ID, updated.Memory.ID)
+	assert.Equal(t, StoreUpdated, updated.Action)
+	assert.Equal(t, MatchedByUpdateKey, updated.MatchedBy)
+	assert.Equal(t, "Favourite editor is vim", updated.PreviousContent)
+
+	// Storing the same content again changes nothing worth reporting
+	repeated, err := service.StoreWithResult(ctx, StoreRequest{
+		Content:  "Favourite editor is helix",
+		Type:     models.TypePreference,
+		Category: models.CategoryPersonal,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, created.Memory.ID, repeated.Memory.ID)
+	assert.Equal(t, StoreUpdated, repeated.Action)
+	assert.Equal(t, MatchedByContent, repeated.MatchedBy)
+	assert.Empty(t, repeated.PreviousContent)
+}
+
+func TestMemoryService_StoreMemoryWithTagsKeepsContentEncrypted(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	require.NoError(t, db.Create(&models.User{ID: 2, Email: "b@example.com", Password: "x"}).Error)
+	config := map[string]interface{}{"encryption_service": newTestEncryption(t)}
+	service := NewMemoryServiceWithUser(db, nil, zerolog.New(nil).Level(zerolog.Disabled), config, 2)
+
+	result, err := service.StoreMemoryWithResult(ctx, &StoreMemoryRequest{
+		Content:  "top secret plaintext",
+		Type:     models.TypeFact,
+		Category: models.CategoryPersonal,
+		Tags:     []string{"secret"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "top secret plaintext", result.Memory.Content)
+	assert.Equal(t, []string{"secret"}, []string(result.Memory.Tags))
+
+	var row models.Memory
+	require.NoError(t, db.Omit("embedding", "tags").First(&row, result.Memory.ID).Error)
+	assert.True(t, row.IsEncrypted)
+	assert.Equal(t, "[encrypted]", row.Content)
+}
+
 func TestMemoryService_Search(t *testing.T) {
 	ctx := context.Background()
 
@@ -690,4 +722,64 @@ func TestMemoryService_ComplexMetadata(t *testing.T) {
 	context, ok := retrievedMetadata["context"].(map[string]interface{})
 	assert.True(t, ok)
 	assert.Equal(t, "abc-123", context["session_id"])
-}
\ No newline at end of file
+}
+func TestMemoryService_ListIsolation(t *testing.T) {
+	ctx := context.Background()
+	base := setupMemoryService(t, nil)
+	silent := zerolog.New(nil).Level(zerolog.Disabled)
+	alice := NewMemoryServiceWithUser(base.db, nil, silent, map[string]interface{}{"memory_limit": 2}, 2)
+	bob := NewMemoryServiceWithUser(base.db, nil, silent, nil, 3)
+
+	store := func(service *MemoryService, content, category string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{Content: content, Category: category, Type: models.TypeFact})
+		require.NoError(t, err)
+		return memory
+	}
+	for i := 0; i < 3; i++ {
+		store(bob, fmt.Sprintf("bob %d", i), models.CategoryPersonal)
+	}
+	store(alice, "alice personal", models.CategoryPersonal)
+	store(alice, "alice project", models.CategoryProject)
+
+	t.Run("Wildcard search lists only the user's memories, newest first", func(t *testing.T) {
+		for _, query := range []string{"*", ""} {
+			memories, err := alice.Search(ctx, SearchRequest{Query: query})
+			require.NoError(t, err)
+			require.Len(t, memories, 2)
+			assert.Equal(t, "alice project", memories[0].Content)
+			assert.Equal(t, "alice personal", memories[1].Content)
+		}
+
+		memories, err := bob.Search(ctx, SearchRequest{Query: "*", Category: models.CategoryPersonal, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, memories, 2)
+		assert.Equal(t, []string{"bob 2", "bob 1"}, []string{memories[0].Content, memories[1].Content})
+	})
+
+	t.Run("Filters apply to listings", func(t *testing.T) {
+		memories, err := alice.List(ctx, ListRequest{Category: models.CategoryProject})
+		require.NoError(t, err)
+		require.Len(t, memories, 1)
+		assert.Equal(t, "alice project", memories[0].Content)
+	})
+
+	t.Run("Listing does not record access", func(t *testing.T) {
+		_, err := bob.List(ctx, ListRequest{})
+		require.NoError(t, err)
+
+		var accessed int64
+		require.NoError(t, base.db.Model(&models.Memory{}).Where("access_count > 0").Count(&accessed).Error)
+		assert.Zero(t, accessed)
+	})
+
+	t.Run("Eviction only touches the user's memories", func(t *testing.T) {
+		store(alice, "alice newest", models.CategoryPersonal)
+
+		count, err := alice.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		count, err = bob.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+}
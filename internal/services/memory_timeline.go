@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Timeline event types
+const (
+	TimelineCreated       = "created"
+	TimelineEdited        = "edited"
+	TimelineAccessed      = "accessed"
+	TimelineFeedback      = "feedback"
+	TimelineLinked        = "linked"
+	TimelineDerived       = "derived"
+	TimelineDue           = "due"
+	TimelineSupportAccess = "support_access"
+)
+
+// timelineEventTypes lists the event types in the order they are documented
+var timelineEventTypes = []string{
+	TimelineCreated, TimelineEdited, TimelineAccessed, TimelineFeedback,
+	TimelineLinked, TimelineDerived, TimelineDue, TimelineSupportAccess,
+}
+
+// maxTimelineActivities bounds the activity log entries read for one memory
+const maxTimelineActivities = 500
+
+// TimelineEvent is one thing that happened to a memory
+type TimelineEvent struct {
+	At      time.Time `json:"at"`
+	Type    string    `json:"type"`
+	Summary string    `json:"summary"`
+	// Source says who or what caused the event when that is known, such as the
+	// client that stored the memory or the method that derived it
+	Source  string                 `json:"source,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// MemoryTimeline is a memory's events, oldest first
+type MemoryTimeline struct {
+	MemoryID uint            `json:"memory_id"`
+	Events   []TimelineEvent `json:"events"`
+}
+
+// TimelineRequest narrows a memory's timeline
+type TimelineRequest struct {
+	// Types keeps only events of these types; empty keeps all
+	Types []string
+	// Query keeps only events whose summary or details contain it, ignoring case
+	Query string
+}
+
+// Validate checks the requested event types
+func (r *TimelineRequest) Validate() error {
+	for _, eventType := range r.Types {
+		if !slices.Contains(timelineEventTypes, eventType) {
+			return utils.InvalidFieldError("types", "must be among "+strings.Join(timelineEventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// MemoryTimeline merges a memory's creation, edits, accesses, feedback, links,
+// provenance, due date and support access into one chronological view, to answer
+// when a fact changed and what changed it. Edits come from the revisions kept
+// when content, type or category change; accesses only from the memory's
+// counters, so just the latest access is listed.
+func (s *MemoryService) MemoryTimeline(ctx context.Context, memoryID uint, req TimelineRequest) (*MemoryTimeline, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	history, err := s.GetMemoryHistory(ctx, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	memory := history.Current
+
+	var events []TimelineEvent
+	events = append(events, TimelineEvent{
+		At:      memory.CreatedAt,
+		Type:    TimelineCreated,
+		Summary: fmt.Sprintf("Stored as a %s %s memory", memory.Category, memory.Type),
+	})
+	events = append(events, editEvents(memory, history.Revisions)...)
+
+	if memory.LastAccessedAt != nil {
+		events = append(events, TimelineEvent{
+			At:      *memory.LastAccessedAt,
+			Type:    TimelineAccessed,
+			Summary: fmt.Sprintf("Last returned to a client, %d times in all", memory.AccessCount),
+			Details: map[string]interface{}{"access_count": memory.AccessCount},
+		})
+	}
+
+	if dueAt, ok := timelineDueAt(memory); ok {
+		events = append(events, TimelineEvent{
+			At:      dueAt,
+			Type:    TimelineDue,
+			Summary: "Due date mentioned in the memory",
+		})
+	}
+
+	related, err := s.timelineRelatedEvents(ctx, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, related...)
+
+	activities, err := s.timelineActivities(ctx, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, activity := range activities {
+		source := activitySource(activity)
+		switch activity.Type {
+		case models.ActivityMemoryStored:
+			// The log entry of the store says where the memory came from
+			events[0].Source = source
+		case models.ActivitySupportAccessUsed:
+			events = append(events, TimelineEvent{
+				At:      activity.CreatedAt,
+				Type:    TimelineSupportAccess,
+				Summary: "Content shown to support",
+				Source:  source,
+			})
+		}
+	}
+
+	// Events at the same moment keep the order they were gathered in, so a
+	// memory's creation comes before anything recorded with it
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+
+	timeline := &MemoryTimeline{MemoryID: memoryID, Events: []TimelineEvent{}}
+	query := strings.ToLower(strings.TrimSpace(req.Query))
+	for _, event := range events {
+		if len(req.Types) > 0 && !slices.Contains(req.Types, event.Type) {
+			continue
+		}
+		if query != "" && !event.matches(query) {
+			continue
+		}
+		timeline.Events = append(timeline.Events, event)
+	}
+	return timeline, nil
+}
+
+// matches reports whether the event's summary, source or details contain the
+// lower-cased query
+func (e *TimelineEvent) matches(query string) bool {
+	text := e.Summary + "\n" + e.Source
+	if len(e.Details) > 0 {
+		if details, err := json.Marshal(e.Details); err == nil {
+			text += "\n" + string(details)
+		}
+	}
+	return strings.Contains(strings.ToLower(text), query)
+}
+
+// editEvents describes each revision as the edit that replaced it, comparing the
+// version it kept with the one that followed
+func editEvents(memory *models.Memory, revisions []models.MemoryRevision) []TimelineEvent {
+	events := make([]TimelineEvent, 0, len(revisions))
+	// Revisions come newest first; each was replaced by the one before it in the
+	// list, and the newest by the current version
+	next := models.MemoryRevision{
+		Content:  memory.Content,
+		Type:     memory.Type,
+		Category: memory.Category,
+		Priority: memory.Priority,
+	}
+	for _, revision := range revisions {
+		var changes []string
+		details := map[string]interface{}{"revision_id": revision.ID}
+		if revision.Content != next.Content {
+			changes = append(changes, "content")
+			details["previous_content"] = revision.Content
+			details["content"] = next.Content
+		}
+		for _, field := range []struct{ name, from, to string }{
+			{"type", revision.Type, next.Type},
+			{"category", revision.Category, next.Category},
+			{"priority", revision.Priority, next.Priority},
+		} {
+			if field.from != field.to {
+				changes = append(changes, fmt.Sprintf("%s from %s to %s", field.name, field.from, field.to))
+			}
+		}
+
+		summary := "Rewritten with the same content"
+		if len(changes) > 0 {
+			summary = "Changed " + strings.Join(changes, ", ")
+		}
+		events = append(events, TimelineEvent{
+			At:      revision.CreatedAt,
+			Type:    TimelineEdited,
+			Summary: summary,
+			Details: details,
+		})
+		next = revision
+	}
+	return events
+}
+
+// timelineDueAt returns the due date detected in the memory, if any
+func timelineDueAt(memory *models.Memory) (time.Time, bool) {
+	if len(memory.Metadata) == 0 {
+		return time.Time{}, false
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(memory.Metadata, &metadata); err != nil {
+		return time.Time{}, false
+	}
+	value, _ := metadata[MetadataDueAt].(string)
+	dueAt, err := time.Parse(time.RFC3339, value)
+	return dueAt, err == nil
+}
+
+// timelineRelatedEvents lists the feedback, links and provenance recorded for a
+// memory
+func (s *MemoryService) timelineRelatedEvents(ctx context.Context, memoryID uint) ([]TimelineEvent, error) {
+	db := s.db.WithContext(ctx)
+	var events []TimelineEvent
+
+	var feedback []models.MemoryFeedback
+	if err := db.Where("memory_id = ? AND user_id = ?", memoryID, s.userID).
+		Order("id ASC").
+		Find(&feedback).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory feedback", err)
+	}
+	for _, vote := range feedback {
+		verdict := FeedbackIrrelevant
+		if vote.Helpful {
+			verdict = FeedbackHelpful
+		}
+		events = append(events, TimelineEvent{
+			At:      vote.CreatedAt,
+			Type:    TimelineFeedback,
+			Summary: fmt.Sprintf("Marked %s for %q", verdict, vote.Query),
+			Details: map[string]interface{}{"query": vote.Query, "helpful": vote.Helpful},
+		})
+	}
+
+	var links []models.MemoryLink
+	if err := db.Where("user_id = ? AND (source_id = ? OR target_id = ?)", s.userID, memoryID, memoryID).
+		Order("id ASC").
+		Find(&links).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory links", err)
+	}
+	for _, link := range links {
+		summary := fmt.Sprintf("Linked: %s memory %d", link.Relation, link.TargetID)
+		other := link.TargetID
+		if link.TargetID == memoryID {
+			summary = fmt.Sprintf("Linked: memory %d %s this one", link.SourceID, link.Relation)
+			other = link.SourceID
+		}
+		events = append(events, TimelineEvent{
+			At:      link.CreatedAt,
+			Type:    TimelineLinked,
+			Summary: summary,
+			Details: map[string]interface{}{"relation": link.Relation, "memory_id": other},
+		})
+	}
+
+	var provenance []models.MemoryProvenance
+	if err := db.Where("user_id = ? AND (memory_id = ? OR source_memory_id = ?)", s.userID, memoryID, memoryID).
+		Order("id ASC").
+		Find(&provenance).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory provenance", err)
+	}
+	for _, row := range provenance {
+		summary := fmt.Sprintf("Built by %s from memory %d", row.Method, row.SourceMemoryID)
+		other := row.SourceMemoryID
+		if row.SourceMemoryID == memoryID {
+			summary = fmt.Sprintf("Used by %s to build memory %d", row.Method, row.MemoryID)
+			other = row.MemoryID
+		}
+		events = append(events, TimelineEvent{
+			At:      row.CreatedAt,
+			Type:    TimelineDerived,
+			Summary: summary,
+			Source:  row.Method,
+			Details: map[string]interface{}{"memory_id": other},
+		})
+	}
+
+	return events, nil
+}
+
+// timelineActivities reads the activity log entries that name the memory
+func (s *MemoryService) timelineActivities(ctx context.Context, memoryID uint) ([]models.ActivityLog, error) {
+	db := s.db.WithContext(ctx)
+	condition := "details->>'memory_id' = ?"
+	var id interface{} = fmt.Sprintf("%d", memoryID)
+	if db.Dialector.Name() == "sqlite" {
+		condition = "json_extract(details, '$.memory_id') = ?"
+		id = memoryID
+	}
+
+	var activities []models.ActivityLog
+	if err := db.Where("user_id = ? AND type IN ?", s.userID, []string{models.ActivityMemoryStored, models.ActivitySupportAccessUsed}).
+		Where(condition, id).
+		Order("id ASC").
+		Limit(maxTimelineActivities).
+		Find(&activities).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory activity", err)
+	}
+	return activities, nil
+}
+
+// activitySource describes where an activity came from: its client and location
+func activitySource(activity models.ActivityLog) string {
+	var parts []string
+	if activity.UserAgent != "" {
+		parts = append(parts, activity.UserAgent)
+	}
+	if activity.IPAddress != "" {
+		parts = append(parts, activity.IPAddress)
+	}
+	if activity.City != "" && activity.Country != "" {
+		parts = append(parts, activity.City+", "+activity.Country)
+	} else if activity.Country != "" {
+		parts = append(parts, activity.Country)
+	}
+	return strings.Join(parts, " from ")
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func setupTimelineService(t *testing.T) *MemoryService {
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.MemoryProvenance{}, &models.ActivityLog{}))
+	return service
+}
+
+func timelineTypes(timeline *MemoryTimeline) []string {
+	var types []string
+	for _, event := range timeline.Events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestMemoryTimeline_MergesHistory(t *testing.T) {
+	ctx := context.Background()
+	service := setupTimelineService(t)
+	memory, _ := storeTestMemory(t, service, "Deploys happen on Tuesdays")
+	other, _ := storeTestMemory(t, service, "Release checklist")
+
+	details, err := json.Marshal(map[string]interface{}{"memory_id": memory.ID})
+	require.NoError(t, err)
+	require.NoError(t, service.db.Create(&models.ActivityLog{
+		UserID:    memory.UserID,
+		Type:      models.ActivityMemoryStored,
+		Details:   details,
+		UserAgent: "claude-desktop",
+	}).Error)
+
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Content: "Deploys happen on Thursdays"})
+	require.NoError(t, err)
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Category: models.CategoryBusiness})
+	require.NoError(t, err)
+	_, err = service.LinkMemories(ctx, other.ID, memory.ID, models.LinkRelatedTo)
+	require.NoError(t, err)
+	_, err = service.RecordFeedback(ctx, memory.ID, "deploy day", FeedbackHelpful)
+	require.NoError(t, err)
+
+	timeline, err := service.MemoryTimeline(ctx, memory.ID, TimelineRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, memory.ID, timeline.MemoryID)
+	assert.Equal(t, []string{TimelineCreated, TimelineEdited, TimelineEdited, TimelineLinked, TimelineFeedback}, timelineTypes(timeline))
+	assert.Equal(t, "claude-desktop", timeline.Events[0].Source)
+
+	// The first edit changed the content, the second the category
+	assert.Equal(t, "Changed content", timeline.Events[1].Summary)
+	assert.Equal(t, "Deploys happen on Tuesdays", timeline.Events[1].Details["previous_content"])
+	assert.Equal(t, "Deploys happen on Thursdays", timeline.Events[1].Details["content"])
+	assert.Contains(t, timeline.Events[2].Summary, "category from personal to business")
+	assert.Equal(t, other.ID, timeline.Events[3].Details["memory_id"])
+}
+
+func TestMemoryTimeline_Filters(t *testing.T) {
+	ctx := context.Background()
+	service := setupTimelineService(t)
+	memory, _ := storeTestMemory(t, service, "Standup is at 9am")
+
+	_, err := service.Update(ctx, memory.ID, UpdateRequest{Content: "Standup is at 10am"})
+	require.NoError(t, err)
+	_, err = service.RecordFeedback(ctx, memory.ID, "standup time", FeedbackIrrelevant)
+	require.NoError(t, err)
+
+	timeline, err := service.MemoryTimeline(ctx, memory.ID, TimelineRequest{Types: []string{TimelineEdited}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{TimelineEdited}, timelineTypes(timeline))
+
+	timeline, err = service.MemoryTimeline(ctx, memory.ID, TimelineRequest{Query: "9AM"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{TimelineEdited}, timelineTypes(timeline))
+
+	timeline, err = service.MemoryTimeline(ctx, memory.ID, TimelineRequest{Query: "nothing like this"})
+	require.NoError(t, err)
+	assert.Empty(t, timeline.Events)
+
+	_, err = service.MemoryTimeline(ctx, memory.ID, TimelineRequest{Types: []string{"shared"}})
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMemoryTimeline_ScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	service := setupTimelineService(t)
+	memory, _ := storeTestMemory(t, service, "Private note")
+
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, memory.UserID+1)
+	_, err := other.MemoryTimeline(ctx, memory.ID, TimelineRequest{})
+	assert.True(t, utils.IsNotFoundError(err))
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxMetadataQueryLength bounds metadata queries so they stay cheap to plan
+const maxMetadataQueryLength = 1024
+
+// jsonPathPredicateTokens mark a JSONPath expression as a predicate rather than a
+// plain path. Plain paths are wrapped in exists() so both forms use the @@ operator.
+var jsonPathPredicateTokens = []string{"==", "!=", "<>", "<", ">", "like_regex", "starts with", "exists", "is unknown", "&&", "||"}
+
+// MetadataQuery filters memories on their JSONB metadata. Two forms are accepted:
+//
+//   - a JSON object, matched by containment: {"source":"slack"}
+//   - a JSONPath expression: $.scores[0] > 0.9, or a bare path such as $.source,
+//     which matches memories where the path exists
+//
+// Both translate to operators supported by the GIN jsonb_path_ops index on metadata.
+type MetadataQuery struct {
+	containment string
+	jsonPath    string
+}
+
+// ParseMetadataQuery validates a metadata query. An empty query yields nil.
+func ParseMetadataQuery(raw string) (*MetadataQuery, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if len(raw) > maxMetadataQueryLength {
+		return nil, utils.InvalidFieldError("metadataQuery", fmt.Sprintf("must be at most %d characters", maxMetadataQueryLength))
+	}
+
+	switch raw[0] {
+	case '{':
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			return nil, utils.InvalidFieldError("metadataQuery", fmt.Sprintf("invalid JSON object: %v", err))
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(raw)); err != nil {
+			return nil, utils.InvalidFieldError("metadataQuery", fmt.Sprintf("invalid JSON object: %v", err))
+		}
+		return &MetadataQuery{containment: compact.String()}, nil
+
+	case '$':
+		if err := checkJSONPathBalance(raw); err != nil {
+			return nil, utils.InvalidFieldError("metadataQuery", err.Error())
+		}
+		if !isJSONPathPredicate(raw) {
+			raw = "exists(" + raw + ")"
+		}
+		return &MetadataQuery{jsonPath: raw}, nil
+
+	default:
+		return nil, utils.InvalidFieldError("metadataQuery", "must be a JSON object or a JSONPath expression starting with $")
+	}
+}
+
+// parseMetadataQuery parses a search's metadata query, which needs PostgreSQL's
+// JSONB operators
+func (s *MemoryService) parseMetadataQuery(raw string) (*MetadataQuery, error) {
+	metadataQuery, err := ParseMetadataQuery(raw)
+	if err != nil || metadataQuery == nil {
+		return metadataQuery, err
+	}
+	if s.db.Dialector.Name() != "postgres" {
+		return nil, fmt.Errorf("metadata queries are only supported on PostgreSQL")
+	}
+	return metadataQuery, nil
+}
+
+// condition returns the SQL condition for the query using the given placeholder
+// for its single argument
+func (q *MetadataQuery) condition(placeholder string) string {
+	if q.containment != "" {
+		return fmt.Sprintf("metadata @> CAST(%s AS jsonb)", placeholder)
+	}
+	return fmt.Sprintf("metadata @@ CAST(%s AS jsonpath)", placeholder)
+}
+
+// value returns the argument bound to the condition's placeholder
+func (q *MetadataQuery) value() string {
+	if q.containment != "" {
+		return q.containment
+	}
+	return q.jsonPath
+}
+
+// checkJSONPathBalance catches unbalanced brackets and quotes early so clients get
+// a clear error instead of a database syntax error
+func checkJSONPathBalance(path string) error {
+	var stack []rune
+	inString := false
+	escaped := false
+
+	for _, r := range path {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch r {
+		case '"':
+			inString = true
+		case '(', '[':
+			stack = append(stack, r)
+		case ')', ']':
+			want := '('
+			if r == ']' {
+				want = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != want {
+				return fmt.Errorf("unbalanced %q in JSONPath expression", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if inString {
+		return fmt.Errorf("unterminated string in JSONPath expression")
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %q in JSONPath expression", stack[len(stack)-1])
+	}
+	return nil
+}
+
+// isJSONPathPredicate reports whether the expression contains a comparison or
+// logical operator outside string literals. Filter expressions such as
+// $.tags[*] ? (@ == "go") select items rather than evaluate to a boolean, so they
+// are treated as paths.
+func isJSONPathPredicate(path string) bool {
+	var outside strings.Builder
+	inString := false
+	escaped := false
+	for _, r := range path {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			continue
+		}
+		if r == '"' {
+			inString = true
+			continue
+		}
+		outside.WriteRune(r)
+	}
+
+	code := outside.String()
+	if strings.Contains(code, "?") {
+		return false
+	}
+	for _, token := range jsonPathPredicateTokens {
+		if strings.Contains(code, token) {
+			return true
+		}
+	}
+	return false
+}
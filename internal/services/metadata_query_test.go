@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestParseMetadataQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		wantCondition string
+		wantValue     string
+	}{
+		{"Containment", ` {"source": "slack"} `, "metadata @> CAST(? AS jsonb)", `{"source":"slack"}`},
+		{"Predicate", `$.scores[0] > 0.9`, "metadata @@ CAST(? AS jsonpath)", `$.scores[0] > 0.9`},
+		{"Bare path", `$.ticket`, "metadata @@ CAST(? AS jsonpath)", `exists($.ticket)`},
+		{"Operator inside string", `$.note ? (@ starts with "a>b")`, "metadata @@ CAST(? AS jsonpath)", `exists($.note ? (@ starts with "a>b"))`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseMetadataQuery(tt.input)
+			require.NoError(t, err)
+			require.NotNil(t, query)
+			assert.Equal(t, tt.wantCondition, query.condition("?"))
+			assert.Equal(t, tt.wantValue, query.value())
+		})
+	}
+}
+
+func TestParseMetadataQuery_Invalid(t *testing.T) {
+	query, err := ParseMetadataQuery("  ")
+	assert.NoError(t, err)
+	assert.Nil(t, query)
+
+	for _, input := range []string{
+		`source = slack`,
+		`{"source": }`,
+		`["slack"]`,
+		`$.scores[0 > 0.9`,
+		`$.name == "unterminated`,
+		`$.a)`,
+	} {
+		_, err := ParseMetadataQuery(input)
+		assert.True(t, utils.IsValidationError(err), input)
+	}
+}
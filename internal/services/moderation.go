@@ -0,0 +1,364 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Moderation actions a deployment can configure per category
+const (
+	// ModerationBlock refuses to store the content
+	ModerationBlock = "block"
+	// ModerationFlag stores the content and records the decision
+	ModerationFlag = "flag"
+	// ModerationEncrypt stores the content encrypted, even when encryption is off
+	ModerationEncrypt = "encrypt"
+)
+
+// moderationMetadataKey is the metadata key moderation decisions are recorded under
+const moderationMetadataKey = "moderation"
+
+// ErrContentBlocked is returned when moderation refuses to store content
+var ErrContentBlocked = errors.New("content blocked by moderation")
+
+// ContentBlockedError names the categories that caused content to be blocked
+type ContentBlockedError struct {
+	Categories []string
+}
+
+func (e *ContentBlockedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrContentBlocked.Error(), strings.Join(e.Categories, ", "))
+}
+
+func (e *ContentBlockedError) Unwrap() error {
+	return ErrContentBlocked
+}
+
+// ModerationResult lists the categories a moderator flagged content for
+type ModerationResult struct {
+	Categories []string
+}
+
+// Moderator classifies content before it is stored
+type Moderator interface {
+	Name() string
+	Moderate(ctx context.Context, content string) (*ModerationResult, error)
+}
+
+// ModerationDecision is the outcome of moderating content. It is recorded in the
+// memory's metadata and in the activity log.
+type ModerationDecision struct {
+	Action     string    `json:"action"`
+	Categories []string  `json:"categories"`
+	Provider   string    `json:"provider"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// RuleModerator flags content matching locally configured regular expressions
+type RuleModerator struct {
+	rules map[string][]*regexp.Regexp
+}
+
+// NewRuleModerator compiles the patterns for each category
+func NewRuleModerator(rules map[string][]string) (*RuleModerator, error) {
+	compiled := make(map[string][]*regexp.Regexp, len(rules))
+	for category, patterns := range rules {
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid moderation rule for %s: %w", category, err)
+			}
+			compiled[category] = append(compiled[category], re)
+		}
+	}
+	return &RuleModerator{rules: compiled}, nil
+}
+
+// Name identifies the moderator in recorded decisions
+func (m *RuleModerator) Name() string {
+	return "rules"
+}
+
+// Moderate flags every category with a matching pattern
+func (m *RuleModerator) Moderate(ctx context.Context, content string) (*ModerationResult, error) {
+	result := &ModerationResult{}
+	for category, patterns := range m.rules {
+		for _, re := range patterns {
+			if re.MatchString(content) {
+				result.Categories = append(result.Categories, category)
+				break
+			}
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
+
+// OpenAIModerator classifies content with the OpenAI moderation API
+type OpenAIModerator struct {
+	apiKey   string
+	model    string
+	endpoint string
+	client   *http.Client
+}
+
+// NewOpenAIModerator creates a moderator backed by the OpenAI moderation API
+func NewOpenAIModerator(apiKey, model string) *OpenAIModerator {
+	return &OpenAIModerator{
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: "https://api.openai.com/v1/moderations",
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name identifies the moderator in recorded decisions
+func (m *OpenAIModerator) Name() string {
+	return "openai"
+}
+
+// Moderate returns the categories OpenAI flagged, e.g. "harassment" or "self-harm"
+func (m *OpenAIModerator) Moderate(ctx context.Context, content string) (*ModerationResult, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": m.model,
+		"input": content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Results []struct {
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.Results) == 0 {
+		return nil, fmt.Errorf("no moderation results returned")
+	}
+
+	result := &ModerationResult{}
+	for category, flagged := range response.Results[0].Categories {
+		if flagged {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
+
+// ModerationHook runs a moderator before memories are stored and maps the flagged
+// categories to the actions the deployment owner configured
+type ModerationHook struct {
+	moderator Moderator
+	actions   map[string]string
+	failOpen  bool
+	// encryption encrypts content for the encrypt action when deployment-wide
+	// encryption is disabled
+	encryption *utils.EncryptionService
+}
+
+// NewModerationHook creates a moderation hook. The encryption service is only needed
+// when an encrypt action is configured.
+func NewModerationHook(moderator Moderator, actions map[string]string, failOpen bool, encryption *utils.EncryptionService) *ModerationHook {
+	return &ModerationHook{
+		moderator:  moderator,
+		actions:    actions,
+		failOpen:   failOpen,
+		encryption: encryption,
+	}
+}
+
+// NewModerationHookFromConfig builds the moderation hook, or returns nil when
+// moderation is disabled
+func NewModerationHookFromConfig(cfg *config.Config, logger zerolog.Logger) (*ModerationHook, error) {
+	if !cfg.Moderation.Enabled {
+		return nil, nil
+	}
+
+	var moderator Moderator
+	switch cfg.Moderation.Provider {
+	case "openai":
+		moderator = NewOpenAIModerator(cfg.OpenAI.APIKey, cfg.Moderation.Model)
+	case "rules":
+		ruleModerator, err := NewRuleModerator(cfg.Moderation.Rules)
+		if err != nil {
+			return nil, err
+		}
+		moderator = ruleModerator
+	default:
+		return nil, fmt.Errorf("invalid moderation provider: %s", cfg.Moderation.Provider)
+	}
+
+	var encryption *utils.EncryptionService
+	for _, action := range cfg.Moderation.Actions {
+		if action != ModerationEncrypt {
+			continue
+		}
+		var err error
+		if encryption, err = NewEncryptionServiceFromConfig(cfg.Encryption); err != nil {
+			return nil, fmt.Errorf("failed to create moderation encryption service: %w", err)
+		}
+		break
+	}
+
+	logger.Info().
+		Str("provider", moderator.Name()).
+		Int("actions", len(cfg.Moderation.Actions)).
+		Msg("Content moderation enabled")
+
+	return NewModerationHook(moderator, cfg.Moderation.Actions, cfg.Moderation.FailOpen, encryption), nil
+}
+
+// Evaluate moderates content and returns the strongest configured action among the
+// flagged categories (block, then encrypt, then flag). It returns nil when no flagged
+// category has an action.
+func (h *ModerationHook) Evaluate(ctx context.Context, content string) (*ModerationDecision, error) {
+	result, err := h.moderator.Moderate(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &ModerationDecision{
+		Provider:  h.moderator.Name(),
+		CheckedAt: time.Now().UTC(),
+	}
+	for _, category := range result.Categories {
+		action, ok := h.actions[category]
+		if !ok {
+			continue
+		}
+		decision.Categories = append(decision.Categories, category)
+		if moderationActionRank(action) > moderationActionRank(decision.Action) {
+			decision.Action = action
+		}
+	}
+	if decision.Action == "" {
+		return nil, nil
+	}
+	return decision, nil
+}
+
+// moderationActionRank orders actions by severity
+func moderationActionRank(action string) int {
+	switch action {
+	case ModerationBlock:
+		return 3
+	case ModerationEncrypt:
+		return 2
+	case ModerationFlag:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// GetModerationHook returns the content moderation hook, if any
+func (s *MemoryService) GetModerationHook() *ModerationHook {
+	hook, _ := s.config["moderation"].(*ModerationHook)
+	return hook
+}
+
+// moderate runs the moderation hook on content about to be stored. Blocked content
+// yields a ContentBlockedError; other decisions are returned for the caller to apply.
+// Every decision is recorded in the activity log.
+func (s *MemoryService) moderate(ctx context.Context, content string) (*ModerationDecision, error) {
+	hook := s.GetModerationHook()
+	if hook == nil {
+		return nil, nil
+	}
+
+	decision, err := hook.Evaluate(ctx, content)
+	if err != nil {
+		if hook.failOpen {
+			s.logger.Warn().Err(err).Msg("content moderation failed, storing unmoderated")
+			return nil, nil
+		}
+		s.logger.Error().Err(err).Msg("content moderation failed")
+		return nil, fmt.Errorf("content moderation unavailable: %w", err)
+	}
+	if decision == nil {
+		return nil, nil
+	}
+
+	s.logActivity(ctx, models.ActivityContentModerated, map[string]interface{}{
+		"action":     decision.Action,
+		"categories": decision.Categories,
+		"provider":   decision.Provider,
+	})
+
+	if decision.Action == ModerationBlock {
+		s.logger.Info().Strs("categories", decision.Categories).Msg("content blocked by moderation")
+		return nil, &ContentBlockedError{Categories: decision.Categories}
+	}
+	return decision, nil
+}
+
+// applyModeration records the decision in the memory's metadata and encrypts the
+// content when the decision requires it. Content must not yet be encrypted by the
+// deployment-wide encryption service.
+func (s *MemoryService) applyModeration(memory *models.Memory, decision *ModerationDecision) error {
+	if decision == nil {
+		return nil
+	}
+
+	metadata := make(map[string]interface{})
+	if len(memory.Metadata) > 0 {
+		if err := json.Unmarshal(memory.Metadata, &metadata); err != nil {
+			return utils.WrapValidationError("metadata", "invalid metadata format")
+		}
+	}
+	metadata[moderationMetadataKey] = decision
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	memory.Metadata = json.RawMessage(metadataJSON)
+
+	if decision.Action != ModerationEncrypt || memory.IsEncrypted {
+		return nil
+	}
+	encryption := s.GetModerationHook().encryption
+	if encryption == nil {
+		return fmt.Errorf("moderation requires encryption but no encryption service is configured")
+	}
+	return encryptMemoryContent(encryption, memory, s.contentOwner(memory))
+}
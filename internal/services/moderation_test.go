@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func setupModerationService(t *testing.T, actions map[string]string) *MemoryService {
+	moderator, err := NewRuleModerator(map[string][]string{
+		"credentials": {`(?i)password\s*[:=]`},
+		"health":      {`(?i)diagnos(is|ed)`},
+		"profanity":   {`(?i)\bdarn\b`},
+	})
+	require.NoError(t, err)
+
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+
+	service := setupMemoryService(t, map[string]interface{}{
+		"moderation": NewModerationHook(moderator, actions, false, encryption),
+	})
+	require.NoError(t, service.db.AutoMigrate(&models.ActivityLog{}))
+	return service
+}
+
+func TestModerationHook_Evaluate(t *testing.T) {
+	moderator, err := NewRuleModerator(map[string][]string{
+		"credentials": {`password`},
+		"profanity":   {`darn`},
+		"ignored":     {`password`},
+	})
+	require.NoError(t, err)
+	hook := NewModerationHook(moderator, map[string]string{
+		"credentials": ModerationEncrypt,
+		"profanity":   ModerationFlag,
+	}, false, nil)
+
+	decision, err := hook.Evaluate(context.Background(), "darn, my password")
+	require.NoError(t, err)
+	require.NotNil(t, decision)
+	assert.Equal(t, ModerationEncrypt, decision.Action)
+	assert.Equal(t, []string{"credentials", "profanity"}, decision.Categories)
+	assert.Equal(t, "rules", decision.Provider)
+
+	decision, err = hook.Evaluate(context.Background(), "nothing to see")
+	require.NoError(t, err)
+	assert.Nil(t, decision)
+
+	_, err = NewRuleModerator(map[string][]string{"bad": {`(`}})
+	assert.Error(t, err)
+}
+
+func TestMemoryService_ModerationBlock(t *testing.T) {
+	ctx := context.Background()
+	service := setupModerationService(t, map[string]string{"credentials": ModerationBlock})
+
+	_, err := service.Store(ctx, StoreRequest{
+		Content:  "Database password: hunter2",
+		Category: models.CategoryProject,
+		Type:     models.TypeFact,
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrContentBlocked))
+
+	var blocked *ContentBlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, []string{"credentials"}, blocked.Categories)
+
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	var activities []models.ActivityLog
+	require.NoError(t, service.db.Where("type = ?", models.ActivityContentModerated).Find(&activities).Error)
+	require.Len(t, activities, 1)
+	details, err := activities[0].GetDetailsMap()
+	require.NoError(t, err)
+	assert.Equal(t, ModerationBlock, details["action"])
+}
+
+func TestMemoryService_ModerationFlagAndEncrypt(t *testing.T) {
+	ctx := context.Background()
+	service := setupModerationService(t, map[string]string{
+		"health":    ModerationEncrypt,
+		"profanity": ModerationFlag,
+	})
+
+	flagged, err := service.Store(ctx, StoreRequest{
+		Content:  "Darn, the build broke again",
+		Category: models.CategoryProject,
+		Type:     models.TypeFact,
+		Metadata: map[string]interface{}{"source": "chat"},
+	})
+	require.NoError(t, err)
+	assert.False(t, flagged.IsEncrypted)
+
+	var metadata map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(flagged.Metadata, &metadata))
+	assert.JSONEq(t, `"chat"`, string(metadata["source"]))
+	var decision ModerationDecision
+	require.NoError(t, json.Unmarshal(metadata["moderation"], &decision))
+	assert.Equal(t, ModerationFlag, decision.Action)
+	assert.Equal(t, []string{"profanity"}, decision.Categories)
+
+	// Deployment-wide encryption is off, but the health category is forced encrypted
+	encrypted, err := service.Store(ctx, StoreRequest{
+		Content:  "Diagnosed with asthma in 2019",
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+	})
+	require.NoError(t, err)
+	assert.True(t, encrypted.IsEncrypted)
+	assert.Equal(t, "Diagnosed with asthma in 2019", encrypted.Content)
+
+	var stored models.Memory
+	require.NoError(t, service.db.Omit("embedding", "tags").First(&stored, encrypted.ID).Error)
+	assert.Equal(t, "[encrypted]", stored.Content)
+
+	fetched, err := service.GetByID(ctx, encrypted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Diagnosed with asthma in 2019", fetched.Content)
+
+	// Clean content is stored without a moderation record
+	clean, err := service.Store(ctx, StoreRequest{
+		Content:  "Prefers tabs over spaces",
+		Category: models.CategoryPersonal,
+		Type:     models.TypePreference,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, clean.Metadata)
+
+	var moderated int64
+	require.NoError(t, service.db.Model(&models.ActivityLog{}).Where("type = ?", models.ActivityContentModerated).Count(&moderated).Error)
+	assert.Equal(t, int64(2), moderated)
+}
+
+type failingModerator struct{}
+
+func (failingModerator) Name() string { return "failing" }
+
+func (failingModerator) Moderate(ctx context.Context, content string) (*ModerationResult, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestMemoryService_ModerationFailure(t *testing.T) {
+	ctx := context.Background()
+	req := StoreRequest{Content: "anything", Category: models.CategoryProject, Type: models.TypeFact}
+
+	closed := setupMemoryService(t, map[string]interface{}{
+		"moderation": NewModerationHook(failingModerator{}, nil, false, nil),
+	})
+	_, err := closed.Store(ctx, req)
+	assert.Error(t, err)
+
+	open := setupMemoryService(t, map[string]interface{}{
+		"moderation": NewModerationHook(failingModerator{}, nil, true, nil),
+	})
+	_, err = open.Store(ctx, req)
+	assert.NoError(t, err)
+}
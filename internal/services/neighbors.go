@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	defaultNeighborCount = 10
+	maxNeighborCount     = 100
+	// defaultSimilarityThreshold applies when similarity_threshold is not configured
+	defaultSimilarityThreshold = 0.3
+)
+
+// Neighbor is a memory close to another in embedding space
+type Neighbor struct {
+	Memory *models.Memory `json:"memory"`
+	// Distance is the cosine distance between the embeddings, from 0 to 2
+	Distance   float64  `json:"distance"`
+	Similarity float64  `json:"similarity"`
+	SharedTags []string `json:"shared_tags"`
+	// AboveThreshold is true when the similarity reaches the configured threshold
+	AboveThreshold bool `json:"above_threshold"`
+}
+
+// NeighborReport lists a memory's nearest neighbors, closest first
+type NeighborReport struct {
+	Memory              *models.Memory `json:"memory"`
+	SimilarityThreshold float64        `json:"similarity_threshold"`
+	Neighbors           []Neighbor     `json:"neighbors"`
+}
+
+// similarityThreshold returns the configured similarity threshold
+func (s *MemoryService) similarityThreshold() float64 {
+	if threshold, ok := s.config["similarity_threshold"].(float64); ok && threshold > 0 {
+		return threshold
+	}
+	return defaultSimilarityThreshold
+}
+
+// NearestNeighbors returns the k memories whose embeddings are closest to the given
+// memory's, with their distances and the tags they share with it. It is a
+// diagnostic for tuning the similarity threshold, so it does not count as an
+// access of any of the memories.
+func (s *MemoryService) NearestNeighbors(ctx context.Context, id uint, k int) (*NeighborReport, error) {
+	if k <= 0 {
+		k = defaultNeighborCount
+	}
+	if k > maxNeighborCount {
+		return nil, utils.InvalidFieldError("k", fmt.Sprintf("must be at most %d", maxNeighborCount))
+	}
+
+	memory, err := s.loadForNeighbors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedded int64
+	if err := memoryRow(s.db.WithContext(ctx).Model(&models.Memory{}), id, memory.UserID).
+		Where("embedding IS NOT NULL").
+		Count(&embedded).Error; err != nil {
+		return nil, utils.WrapDatabaseError("check memory embedding", err)
+	}
+	if embedded == 0 {
+		return nil, utils.InvalidFieldError("id", "memory has no embedding yet")
+	}
+
+	report := &NeighborReport{
+		Memory:              memory,
+		SimilarityThreshold: s.similarityThreshold(),
+		Neighbors:           []Neighbor{},
+	}
+
+	// The sqlite schema used in tests has no vector type
+	if s.db.Dialector.Name() == "sqlite" {
+		return report, nil
+	}
+
+	var rows []struct {
+		ID       uint
+		Distance float64
+	}
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT m.id, m.embedding <=> source.embedding AS distance
+		FROM memories m, (SELECT embedding, workspace_id FROM memories WHERE id = $1) source
+		WHERE m.user_id = $2 AND m.workspace_id = source.workspace_id AND m.id <> $1 AND m.embedding IS NOT NULL
+		ORDER BY m.embedding <=> source.embedding
+		LIMIT $3
+	`, id, s.userID, k).Scan(&rows).Error; err != nil {
+		return nil, utils.WrapDatabaseError("find nearest neighbors", err)
+	}
+
+	for _, row := range rows {
+		neighbor, err := s.loadForNeighbors(ctx, row.ID)
+		if err != nil {
+			// Deleted since the search ran
+			continue
+		}
+		similarity := 1 - row.Distance
+		report.Neighbors = append(report.Neighbors, Neighbor{
+			Memory:         neighbor,
+			Distance:       row.Distance,
+			Similarity:     similarity,
+			SharedTags:     sharedTags(memory.Tags, neighbor.Tags),
+			AboveThreshold: similarity >= report.SimilarityThreshold,
+		})
+	}
+	return report, nil
+}
+
+// loadForNeighbors loads and decrypts one of the user's memories without its
+// embedding and without recording an access
+func (s *MemoryService) loadForNeighbors(ctx context.Context, id uint) (*models.Memory, error) {
+	query := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, s.userID)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+
+	var memory models.Memory
+	if err := query.First(&memory).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d", id))
+		}
+		return nil, utils.WrapDatabaseError("get memory by id", err)
+	}
+	if err := s.decryptContent(&memory); err != nil {
+		s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+	}
+	return &memory, nil
+}
+
+// sharedTags returns the tags present in both lists, in the order of the first
+func sharedTags(a, b []string) []string {
+	shared := []string{}
+	for _, tag := range a {
+		if slices.Contains(b, tag) && !slices.Contains(shared, tag) {
+			shared = append(shared, tag)
+		}
+	}
+	return shared
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestNearestNeighbors(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{"similarity_threshold": 0.8})
+	memory, _ := storeTestMemory(t, service, "prefers dark mode")
+
+	_, err := service.NearestNeighbors(ctx, memory.ID+100, 5)
+	assert.True(t, utils.IsNotFoundError(err))
+
+	_, err = service.NearestNeighbors(ctx, memory.ID, 5)
+	assert.True(t, utils.IsValidationError(err))
+	assert.ErrorContains(t, err, "no embedding")
+
+	_, err = service.NearestNeighbors(ctx, memory.ID, maxNeighborCount+1)
+	assert.True(t, utils.IsValidationError(err))
+
+	// Other users' memories are not visible
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, 2)
+	_, err = other.NearestNeighbors(ctx, memory.ID, 5)
+	assert.True(t, utils.IsNotFoundError(err))
+
+	require.NoError(t, service.db.Exec("UPDATE memories SET embedding = 'stub' WHERE id = ?", memory.ID).Error)
+	report, err := service.NearestNeighbors(ctx, memory.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, memory.ID, report.Memory.ID)
+	assert.Equal(t, "prefers dark mode", report.Memory.Content)
+	assert.Equal(t, 0.8, report.SimilarityThreshold)
+	assert.Empty(t, report.Neighbors)
+}
+
+func TestSharedTags(t *testing.T) {
+	assert.Equal(t, []string{"ui", "work"}, sharedTags([]string{"ui", "work", "ui", "home"}, []string{"work", "ui"}))
+	assert.Empty(t, sharedTags([]string{"ui"}, nil))
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MetadataOriginalContent is the metadata key holding a detected memory's text as
+// it was said, when normalizing changed it
+const MetadataOriginalContent = "original_content"
+
+// defaultFillerWords are the disfluencies stripped wherever they appear when no
+// filler words are configured
+var defaultFillerWords = []string{"um", "umm", "uh", "uhh", "uhm", "er", "erm", "hmm"}
+
+// setOffFillers are stripped only when set off by commas or opening a sentence,
+// since elsewhere they carry meaning: "like, I prefer tabs" but "I like tabs"
+const setOffFillers = `like|basically|actually|literally|anyway|so|well|you\s+know|i\s+mean`
+
+var (
+	defaultFillerPattern = fillerPattern(defaultFillerWords)
+
+	// leadInPattern matches the instruction opening a sentence said to be
+	// remembered: "remember that", "please note", "don't forget that"
+	leadInPattern   = regexp.MustCompile(`(?i)^(?:please\s+)?(?:remember|note|don't\s+forget|do\s+not\s+forget)(?:\s+that\b\s*[,:]?|\s*[,:])\s*`)
+	leadingSetOff   = regexp.MustCompile(`(?i)^(?:` + setOffFillers + `)\s*,\s*`)
+	sentenceSetOff  = regexp.MustCompile(`(?i)([.!?]\s+)(?:` + setOffFillers + `)\s*,\s*`)
+	middleSetOff    = regexp.MustCompile(`(?i),\s*(?:` + setOffFillers + `)\s*,`)
+	trailingSetOff  = regexp.MustCompile(`(?i),\s*(?:` + setOffFillers + `)\s*([.!?]|$)`)
+	spaceRun        = regexp.MustCompile(`\s+`)
+	spaceBeforeMark = regexp.MustCompile(`\s+([,.;:!?])`)
+	commaRun        = regexp.MustCompile(`,(?:\s*,)+`)
+	commaBeforeStop = regexp.MustCompile(`[,;:]\s*([.!?])`)
+	loneI           = regexp.MustCompile(`(^|\s)i('|\s|,|$)`)
+	sentenceStart   = regexp.MustCompile(`[.!?]\s+\p{Ll}`)
+)
+
+// fillerPattern matches any of fillers as whole words ignoring case, along with a
+// comma following it
+func fillerPattern(fillers []string) *regexp.Regexp {
+	alternatives := make([]string, 0, len(fillers))
+	for _, filler := range fillers {
+		words := strings.Fields(filler)
+		if len(words) == 0 {
+			continue
+		}
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		alternatives = append(alternatives, strings.Join(words, `\s+`))
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b\s*,?`)
+}
+
+// normalizeContent tidies detected text for storing: fillers are stripped, as is
+// an instruction to remember opening it, whitespace is collapsed and sentences
+// start with a capital. fillers replaces the default list when not empty. Text
+// that would be left empty is returned unchanged.
+func normalizeContent(content string, fillers []string) string {
+	pattern := defaultFillerPattern
+	if len(fillers) > 0 {
+		pattern = fillerPattern(fillers)
+	}
+
+	text := content
+	if pattern != nil {
+		text = pattern.ReplaceAllString(text, " ")
+	}
+	text = tidyPunctuation(text)
+
+	// Lead-ins and set-off fillers may follow one another: "remember that, like,"
+	for {
+		stripped := leadInPattern.ReplaceAllString(text, "")
+		stripped = leadingSetOff.ReplaceAllString(stripped, "")
+		stripped = strings.TrimLeft(stripped, " ,;:-")
+		if stripped == text {
+			break
+		}
+		text = stripped
+	}
+	text = sentenceSetOff.ReplaceAllString(text, "$1")
+	text = middleSetOff.ReplaceAllString(text, " ")
+	text = trailingSetOff.ReplaceAllString(text, "$1")
+	text = tidyPunctuation(text)
+
+	if strings.Trim(text, " .,;:!?-") == "" {
+		return content
+	}
+	return sentenceCase(text)
+}
+
+// tidyPunctuation collapses whitespace and the stray punctuation left where words
+// were removed
+func tidyPunctuation(text string) string {
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = spaceBeforeMark.ReplaceAllString(text, "$1")
+	text = commaRun.ReplaceAllString(text, ",")
+	text = commaBeforeStop.ReplaceAllString(text, "$1")
+	text = strings.TrimLeft(text, " ,;:.-")
+	return strings.TrimRight(text, " ,;:-")
+}
+
+// sentenceCase capitalizes the first letter of each sentence and the pronoun "i",
+// leaving the rest of the text as written
+func sentenceCase(text string) string {
+	text = loneI.ReplaceAllString(text, "${1}I${2}")
+	text = sentenceStart.ReplaceAllStringFunc(text, func(match string) string {
+		r, size := utf8.DecodeLastRuneInString(match)
+		return match[:len(match)-size] + string(unicode.ToUpper(r))
+	})
+	r, size := utf8.DecodeRuneInString(text)
+	if unicode.IsLower(r) {
+		text = string(unicode.ToUpper(r)) + text[size:]
+	}
+	return text
+}
+
+// normalizeDetected returns detected content as it should be stored: normalized
+// when normalize_detected is configured, in which case the text as said is kept
+// in metadata if that changed it
+func (s *MemoryService) normalizeDetected(content string, metadata map[string]interface{}) string {
+	if enabled, _ := s.config["normalize_detected"].(bool); !enabled {
+		return content
+	}
+	fillers, _ := s.config["filler_words"].([]string)
+	normalized := normalizeContent(content, fillers)
+	if normalized != content {
+		metadata[MetadataOriginalContent] = content
+	}
+	return normalized
+}
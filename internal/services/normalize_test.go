@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fillers []string
+		want    string
+	}{
+		{"fillers and lead-in", "um, remember that, like, i prefer   tabs over spaces", nil, "I prefer tabs over spaces"},
+		{"filler mid-sentence", "My editor is uh vim", nil, "My editor is vim"},
+		{"set-off filler", "I use Go, basically, for everything.", nil, "I use Go for everything."},
+		{"trailing set-off filler", "i work at Acme, you know.", nil, "I work at Acme."},
+		{"meaningful words kept", "I like tabs. so I use them", nil, "I like tabs. So I use them"},
+		{"lead-in needs that", "remember to call Sam", nil, "Remember to call Sam"},
+		{"sentences capitalized", "note: my deadline is friday. it moved", nil, "My deadline is friday. It moved"},
+		{"configured fillers", "I think my favourite city is Lisbon", []string{"I think"}, "My favourite city is Lisbon"},
+		{"configured fillers replace defaults", "um my city is Lisbon", []string{"I think"}, "Um my city is Lisbon"},
+		{"nothing left", "um, uh", nil, "um, uh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeContent(tt.content, tt.fillers))
+		})
+	}
+}
+
+func TestMemoryService_NormalizeDetected(t *testing.T) {
+	ctx := context.Background()
+	said := "um, remember that, like, I prefer tabs over spaces"
+
+	// Detected memories are stored as said unless normalizing is configured
+	service := setupMemoryService(t, nil)
+	memories, err := service.ProcessContentForMemory(ctx, said)
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	assert.Equal(t, said, memories[0].Content)
+
+	service = setupMemoryService(t, map[string]interface{}{"normalize_detected": true})
+	memories, err = service.ProcessContentForMemory(ctx, said)
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	assert.Equal(t, "I prefer tabs over spaces", memories[0].Content)
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(memories[0].Metadata, &metadata))
+	assert.Equal(t, said, metadata[MetadataOriginalContent])
+
+	// Transcripts are normalized sentence by sentence
+	result, err := service.ProcessContent(ctx, ProcessContentRequest{Content: "User: uh, my editor is vim."})
+	require.NoError(t, err)
+	require.Len(t, result.Captured, 1)
+	assert.Equal(t, "uh, my editor is vim.", result.Captured[0].Sentence)
+	require.NotNil(t, result.Captured[0].Memory)
+	assert.Equal(t, "My editor is vim.", result.Captured[0].Memory.Content)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+// Notification severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is an operator- or user-facing event delivered through a Notifier
+type Notification struct {
+	Event     string                 `json:"event"`
+	Severity  string                 `json:"severity"`
+	Subject   string                 `json:"subject"`
+	Message   string                 `json:"message"`
+	UserID    uint                   `json:"user_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Notifier delivers notifications to an external channel
+type Notifier interface {
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+// MultiNotifier fans a notification out to several notifiers
+type MultiNotifier []Notifier
+
+// Notify delivers to every notifier and returns the combined errors
+func (m MultiNotifier) Notify(ctx context.Context, notification *Notification) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct {
+	logger zerolog.Logger
+}
+
+// NewLogNotifier creates a notifier that only logs
+func NewLogNotifier(logger zerolog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify logs the notification
+func (n *LogNotifier) Notify(ctx context.Context, notification *Notification) error {
+	n.logger.Warn().
+		Str("event", notification.Event).
+		Str("severity", notification.Severity).
+		Uint("user_id", notification.UserID).
+		Interface("data", notification.Data).
+		Msg(notification.Subject)
+	return nil
+}
+
+// WebhookNotifier POSTs notifications as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify sends the notification to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// EmailNotifier sends notifications as plain-text email over SMTP
+type EmailNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewEmailNotifier creates an SMTP email notifier
+func NewEmailNotifier(host string, port int, username, password, from string, to []string) *EmailNotifier {
+	return &EmailNotifier{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}
+}
+
+// Notify emails the notification to all recipients
+func (n *EmailNotifier) Notify(ctx context.Context, notification *Notification) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: [remember-me %s] %s\r\n", notification.Severity, notification.Subject)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(notification.Message)
+	msg.WriteString("\r\n")
+	if len(notification.Data) > 0 {
+		data, _ := json.MarshalIndent(notification.Data, "", "  ")
+		msg.WriteString("\r\n")
+		msg.Write(data)
+		msg.WriteString("\r\n")
+	}
+
+	addr := fmt.Sprintf("%s:%d", n.host, n.port)
+	if err := smtp.SendMail(addr, auth, n.from, n.to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// NewNotifierFromConfig builds the notifier for alerts and critical memory events:
+// always the log, plus the webhook and email channels when configured
+func NewNotifierFromConfig(cfg *config.Config, logger zerolog.Logger) Notifier {
+	notifiers := MultiNotifier{NewLogNotifier(logger)}
+
+	if cfg.Alerts.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.Alerts.WebhookURL))
+	}
+
+	if email := cfg.Alerts.Email; email.SMTPHost != "" {
+		notifiers = append(notifiers, NewEmailNotifier(
+			email.SMTPHost, email.SMTPPort, email.Username, email.Password, email.From, email.To,
+		))
+	}
+
+	return notifiers
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+// ErrObjectNotFound is returned for objects missing from an object store
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore keeps blobs outside the database, addressed by key
+type ObjectStore interface {
+	// Name identifies the store, e.g. "s3"
+	Name() string
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns ErrObjectNotFound for a missing object
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete succeeds for a missing object
+	Delete(ctx context.Context, key string) error
+	// List returns the keys starting with prefix, in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// LocalObjectStore keeps objects as files under a directory, for deployments
+// without a bucket
+type LocalObjectStore struct {
+	dir string
+}
+
+// NewLocalObjectStore creates a store in dir, creating the directory if needed
+func NewLocalObjectStore(dir string) (*LocalObjectStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create object directory: %w", err)
+	}
+	return &LocalObjectStore{dir: dir}, nil
+}
+
+// Name identifies the store
+func (l *LocalObjectStore) Name() string {
+	return "local"
+}
+
+// Put writes an object, replacing any with the same key. The file is written
+// under a temporary name and renamed, so a partly written object is never seen.
+func (l *LocalObjectStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get reads an object
+func (l *LocalObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete removes an object
+func (l *LocalObjectStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the keys starting with prefix
+func (l *LocalObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(l.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// path returns the file an object is kept in, refusing keys that would leave
+// the directory
+func (l *LocalObjectStore) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid object key: %s", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// S3ObjectStore keeps objects in a bucket of Amazon S3 or a service with the
+// same API, signing requests with AWS Signature Version 4
+type S3ObjectStore struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	prefix          string
+	accessKeyID     string
+	secretAccessKey string
+	pathStyle       bool
+	client          *http.Client
+	signer          *v4.Signer
+	now             func() time.Time
+}
+
+// NewS3ObjectStore creates a store for the configured bucket
+func NewS3ObjectStore(cfg config.ObjectStorage) (*S3ObjectStore, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint: %s", cfg.Endpoint)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3ObjectStore{
+		endpoint:        endpoint,
+		region:          region,
+		bucket:          cfg.Bucket,
+		prefix:          cfg.Prefix,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		pathStyle:       cfg.PathStyle,
+		client:          &http.Client{Timeout: cfg.Timeout},
+		signer:          v4.NewSigner(),
+		now:             time.Now,
+	}, nil
+}
+
+// Name identifies the store
+func (s *S3ObjectStore) Name() string {
+	return "s3"
+}
+
+// Put uploads an object, replacing any with the same key
+func (s *S3ObjectStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	_, err := s.do(ctx, http.MethodPut, key, data, header)
+	return err
+}
+
+// Get downloads an object
+func (s *S3ObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, nil)
+}
+
+// List returns the keys starting with prefix, following ListObjectsV2 pages
+func (s *S3ObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u := s.bucketURL()
+		u.RawQuery = encodeQuery(query)
+		data, err := s.send(ctx, http.MethodGet, u, "list "+prefix, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse object listing: %w", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(object.Key, s.prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes an object
+func (s *S3ObjectStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+// bucketURL addresses the bucket itself
+func (s *S3ObjectStore) bucketURL() *url.URL {
+	u := *s.endpoint
+	path := "/"
+	if s.pathStyle {
+		path = "/" + s.bucket + "/"
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return &u
+}
+
+// objectURL addresses an object in the bucket
+func (s *S3ObjectStore) objectURL(key string) *url.URL {
+	u := s.bucketURL()
+	u.Path += s.prefix + key
+	return u
+}
+
+// do sends a signed request for an object and returns the response body
+func (s *S3ObjectStore) do(ctx context.Context, method, key string, body []byte, header http.Header) ([]byte, error) {
+	return s.send(ctx, method, s.objectURL(key), key, body, header)
+}
+
+// send sends a signed request; what names its target in errors
+func (s *S3ObjectStore) send(ctx context.Context, method string, u *url.URL, what string, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object storage request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	sum := sha256.Sum256(body)
+	if err := s.sign(req, hex.EncodeToString(sum[:]), s.now()); err != nil {
+		return nil, fmt.Errorf("failed to sign object storage request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object storage request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object storage response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, what)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("object storage returned %d for %s %s: %s", resp.StatusCode, method, what, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// sign adds a Signature Version 4 Authorization header. payloadHash is the hex
+// SHA-256 of the request body.
+func (s *S3ObjectStore) sign(req *http.Request, payloadHash string, now time.Time) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{AccessKeyID: s.accessKeyID, SecretAccessKey: s.secretAccessKey}
+	// S3 signs object keys as sent rather than escaping them again
+	return s.signer.SignHTTP(req.Context(), credentials, req, payloadHash, "s3", s.region, now, func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+}
+
+// encodeQuery encodes a query string as Signature Version 4 canonicalises it,
+// sorted by key with spaces as %20
+func encodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
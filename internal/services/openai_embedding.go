@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/tracing"
 	"github.com/rs/zerolog"
 	"github.com/sashabaranov/go-openai"
 )
@@ -22,6 +23,11 @@ type OpenAIEmbeddingService struct {
 	client *openai.Client
 	config *config.OpenAI
 	logger zerolog.Logger
+	// baseURL replaces https://api.openai.com/v1 when set
+	baseURL string
+	// dimensions, when set, asks text-embedding-3 models for shorter vectors,
+	// which are zero-padded to fit the embedding column
+	dimensions int
 }
 
 // NewOpenAIEmbeddingService creates a new OpenAI embedding service
@@ -60,12 +66,28 @@ func (s *OpenAIEmbeddingService) validateAPIKeyAsync() {
 	}
 }
 
+// maxEmbeddingInputs is the most inputs OpenAI accepts in one embeddings request
+const maxEmbeddingInputs = 2048
+
 // generateEmbeddingDirect makes a direct HTTP request to OpenAI API
 func (s *OpenAIEmbeddingService) generateEmbeddingDirect(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := s.generateEmbeddingsDirect(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// generateEmbeddingsDirect embeds all texts in a single request to the OpenAI API,
+// returning the embeddings in the same order as the texts
+func (s *OpenAIEmbeddingService) generateEmbeddingsDirect(ctx context.Context, texts []string) ([][]float32, error) {
 	// Create HTTP request
 	reqBody := map[string]interface{}{
 		"model": s.config.Model,
-		"input": []string{text},
+		"input": texts,
+	}
+	if s.dimensions > 0 {
+		reqBody["dimensions"] = s.dimensions
 	}
 	
 	jsonData, err := json.Marshal(reqBody)
@@ -73,7 +95,7 @@ func (s *OpenAIEmbeddingService) generateEmbeddingDirect(ctx context.Context, te
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.embeddingsURL(), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -102,6 +124,7 @@ func (s *OpenAIEmbeddingService) generateEmbeddingDirect(ctx context.Context, te
 	
 	var response struct {
 		Data []struct {
+			Index     int       `json:"index"`
 			Embedding []float64 `json:"embedding"`
 		} `json:"data"`
 	}
@@ -110,18 +133,40 @@ func (s *OpenAIEmbeddingService) generateEmbeddingDirect(ctx context.Context, te
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 	
-	if len(response.Data) == 0 {
-		return nil, fmt.Errorf("no embeddings returned")
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
 	}
 	
-	// Convert to float32
-	embedding := response.Data[0].Embedding
-	result := make([]float32, len(embedding))
-	for i, v := range embedding {
-		result[i] = float32(v)
+	// Results carry the index of their input; convert to float32 in input order
+	results := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) || results[data.Index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		if s.dimensions > 0 {
+			embedding, err := fitEmbedding(data.Embedding, s.dimensions)
+			if err != nil {
+				return nil, err
+			}
+			results[data.Index] = embedding
+			continue
+		}
+		embedding := make([]float32, len(data.Embedding))
+		for i, v := range data.Embedding {
+			embedding[i] = float32(v)
+		}
+		results[data.Index] = embedding
 	}
 	
-	return result, nil
+	return results, nil
+}
+
+// embeddingsURL returns the embeddings endpoint, overridable for tests
+func (s *OpenAIEmbeddingService) embeddingsURL() string {
+	if s.baseURL != "" {
+		return s.baseURL + "/embeddings"
+	}
+	return "https://api.openai.com/v1/embeddings"
 }
 
 // GenerateEmbedding generates embeddings for the given text using OpenAI API
@@ -130,12 +175,48 @@ func (s *OpenAIEmbeddingService) GenerateEmbedding(ctx context.Context, text str
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings for several texts with one OpenAI
+// request per 2048 texts, returning them in the same order as the texts
+func (s *OpenAIEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) (results [][]float32, err error) {
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("text %d cannot be empty", i)
+		}
+	}
+
+	_, span := tracing.StartEmbedding(ctx, "openai", s.config.Model, len(texts))
+	defer func() { tracing.End(span, err) }()
+
+	results = make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingInputs {
+		end := start + maxEmbeddingInputs
+		if end > len(texts) {
+			end = len(texts)
+		}
+		embeddings, err := s.generateWithRetry(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, embeddings...)
+	}
+	return results, nil
+}
+
+// generateWithRetry makes one embeddings request, retrying with exponential backoff
+func (s *OpenAIEmbeddingService) generateWithRetry(texts []string) ([][]float32, error) {
 	// Use direct HTTP approach to avoid any OpenAI client context issues
 	s.logger.Debug().
 		Str("model", s.config.Model).
-		Int("text_length", len(text)).
+		Int("inputs", len(texts)).
 		Dur("config_timeout", s.config.Timeout).
-		Msg("Generating embedding with direct HTTP")
+		Msg("Generating embeddings with direct HTTP")
 
 	// Force a longer timeout - ignore config timeout which might be too short
 	timeout := 60 * time.Second
@@ -171,7 +252,7 @@ func (s *OpenAIEmbeddingService) GenerateEmbedding(ctx context.Context, text str
 			Msg("Making direct HTTP call to OpenAI API")
 
 		start := time.Now()
-		result, err := s.generateEmbeddingDirect(freshCtx, text)
+		results, err := s.generateEmbeddingsDirect(freshCtx, texts)
 		duration := time.Since(start)
 		if err != nil {
 			lastErr = err
@@ -179,7 +260,7 @@ func (s *OpenAIEmbeddingService) GenerateEmbedding(ctx context.Context, text str
 				Err(err).
 				Int("attempt", attempt+1).
 				Dur("duration", duration).
-				Msg("Failed to generate embedding")
+				Msg("Failed to generate embeddings")
 			
 			// Check if error is retryable
 			if !isRetryableError(err) {
@@ -190,12 +271,12 @@ func (s *OpenAIEmbeddingService) GenerateEmbedding(ctx context.Context, text str
 
 		// Log success
 		s.logger.Debug().
-			Int("dimensions", len(result)).
+			Int("inputs", len(results)).
 			Int("attempts", attempt+1).
 			Dur("duration", duration).
-			Msg("Successfully generated embedding")
+			Msg("Successfully generated embeddings")
 
-		return result, nil
+		return results, nil
 	}
 
 	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -73,6 +76,44 @@ func TestOpenAIEmbeddingService_GenerateEmbedding(t *testing.T) {
 	// and would be run separately from unit tests
 }
 
+func TestOpenAIEmbeddingService_GenerateEmbeddings(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		// Answer out of order; results are matched to inputs by index
+		type datum struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		var data []datum
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			data = append(data, datum{Index: i, Embedding: []float64{float64(len(body.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	service := &OpenAIEmbeddingService{
+		config:  &config.OpenAI{APIKey: "test", Model: "text-embedding-3-small", MaxRetries: 1},
+		logger:  zerolog.Nop(),
+		baseURL: server.URL,
+	}
+
+	embeddings, err := service.GenerateEmbeddings(context.Background(), []string{"a", "bbb", "cc"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, [][]float32{{1}, {3}, {2}}, embeddings)
+
+	_, err = service.GenerateEmbeddings(context.Background(), []string{"a", ""})
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
 func TestMockEmbeddingService_GenerateEmbedding(t *testing.T) {
 	service := NewMockEmbeddingService()
 	ctx := context.Background()
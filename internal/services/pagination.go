@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxSearchOffset bounds offset pagination. Ranked searches re-rank every earlier
+// page to produce a later one, so deep pages get expensive; narrow the filters instead.
+const maxSearchOffset = 10000
+
+// SearchPage is one page of search results
+type SearchPage struct {
+	Memories []*models.Memory
+	// TotalCount is how many memories match. For semantic and hybrid searches every
+	// memory with an embedding is a candidate, so it is an upper bound.
+	TotalCount int64
+	// NextCursor fetches the following page; it is empty on the last page
+	NextCursor string
+	// RefineCursor scopes a follow-up search to this page's memories; it is empty
+	// when the page is
+	RefineCursor string
+}
+
+// pageCursor is the decoded form of a page cursor. Listings continue after a
+// position; ranked searches continue at an offset.
+type pageCursor struct {
+	Offset    int        `json:"o,omitempty"`
+	CreatedAt *time.Time `json:"t,omitempty"`
+	ID        uint       `json:"i,omitempty"`
+}
+
+// encode returns the opaque cursor string
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor returned by a previous page
+func decodeCursor(cursor string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(cursor))
+	if err != nil {
+		return nil, utils.InvalidFieldError("cursor", "is not a valid cursor")
+	}
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, utils.InvalidFieldError("cursor", "is not a valid cursor")
+	}
+	if (decoded.CreatedAt == nil) != (decoded.ID == 0) {
+		return nil, utils.InvalidFieldError("cursor", "is not a valid cursor")
+	}
+	if err := validateOffset(decoded.Offset); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// validateOffset rejects negative and excessively deep offsets
+func validateOffset(offset int) error {
+	if offset < 0 || offset > maxSearchOffset {
+		return utils.InvalidFieldError("offset", fmt.Sprintf("must be between 0 and %d", maxSearchOffset))
+	}
+	return nil
+}
+
+// SearchPage runs a search and returns one page of results with the total count
+// and a cursor for the next page. A cursor from a previous page takes precedence
+// over req.Offset and must be used with the same query and filters. Wildcard
+// listings page by position, so memories stored while paging do not shift later
+// pages; ranked searches page by offset.
+func (s *MemoryService) SearchPage(ctx context.Context, req SearchRequest, cursor string) (*SearchPage, error) {
+	if _, err := req.searchMode(); err != nil {
+		return nil, err
+	}
+	if err := validateOffset(req.Offset); err != nil {
+		return nil, err
+	}
+	if err := req.resolveScope(); err != nil {
+		return nil, err
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultListLimit
+	}
+
+	var position *pageCursor
+	if cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		position = decoded
+		req.Offset = decoded.Offset
+	}
+
+	if req.Query == "*" || req.Query == "" {
+		page, err := s.listPage(ctx, req.listRequest(), position)
+		if err != nil {
+			return nil, err
+		}
+		page.RefineCursor = refineCursor(page.Memories)
+		return page, nil
+	}
+
+	memories, err := s.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.countSearchMatches(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchPage{Memories: memories, TotalCount: total, RefineCursor: refineCursor(memories)}
+	next := req.Offset + len(memories)
+	if len(memories) == req.Limit && int64(next) < total && next <= maxSearchOffset {
+		page.NextCursor = pageCursor{Offset: next}.encode()
+	}
+	return page, nil
+}
+
+// listPage returns one page of a listing. One extra memory is fetched to tell
+// whether another page follows.
+func (s *MemoryService) listPage(ctx context.Context, req ListRequest, position *pageCursor) (*SearchPage, error) {
+	if position != nil && position.CreatedAt != nil {
+		req.After = &ListPosition{CreatedAt: *position.CreatedAt, ID: position.ID}
+	}
+
+	limit := req.Limit
+	req.Limit = limit + 1
+	memories, err := s.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := s.listQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, utils.WrapDatabaseError("count memories", err)
+	}
+
+	page := &SearchPage{Memories: memories, TotalCount: total}
+	if len(memories) > limit {
+		page.Memories = memories[:limit]
+		last := page.Memories[limit-1]
+		page.NextCursor = pageCursor{CreatedAt: &last.CreatedAt, ID: last.ID}.encode()
+	}
+	return page, nil
+}
+
+// countSearchMatches counts the memories a search could return, following the same
+// fallbacks as Search
+func (s *MemoryService) countSearchMatches(ctx context.Context, req SearchRequest) (int64, error) {
+	query, err := s.listQuery(ctx, req.listRequest())
+	if err != nil {
+		return 0, err
+	}
+
+	mode, _ := req.searchMode()
+	if s.embedding == nil || s.db.Dialector.Name() != "postgres" {
+		mode = SearchModeKeyword
+	}
+	switch mode {
+	case SearchModeSemantic:
+		query = query.Where("embedding IS NOT NULL")
+	case SearchModeHybrid:
+		query = query.Where("(embedding IS NOT NULL OR "+contentSearchVector+" @@ "+fullTextQuery+")", req.Query)
+	default:
+		query = s.keywordCondition(query, req.Query)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, utils.WrapDatabaseError("count search results", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectPages(t *testing.T, service *MemoryService, req SearchRequest) ([]uint, int64) {
+	var ids []uint
+	var total int64
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := service.SearchPage(context.Background(), req, cursor)
+		require.NoError(t, err)
+		total = page.TotalCount
+		for _, memory := range page.Memories {
+			ids = append(ids, memory.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, total
+		}
+		cursor = page.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil, 0
+}
+
+func TestSearchPage_ListingCursor(t *testing.T) {
+	service := setupMemoryService(t, nil)
+	for i := 0; i < 5; i++ {
+		storeTestMemory(t, service, fmt.Sprintf("listed memory %d", i))
+	}
+
+	ids, total := collectPages(t, service, SearchRequest{Query: "*", Limit: 2})
+	assert.Equal(t, int64(5), total)
+	require.Len(t, ids, 5)
+
+	// Newest first with no repeats
+	seen := make(map[uint]bool)
+	for i, id := range ids {
+		assert.False(t, seen[id])
+		seen[id] = true
+		if i > 0 {
+			assert.Greater(t, ids[i-1], id)
+		}
+	}
+}
+
+func TestSearchPage_ListingCursorIgnoresNewMemories(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	for i := 0; i < 4; i++ {
+		storeTestMemory(t, service, fmt.Sprintf("listed memory %d", i))
+	}
+
+	first, err := service.SearchPage(ctx, SearchRequest{Query: "*", Limit: 2}, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, first.NextCursor)
+
+	// A memory stored between pages does not shift the second page
+	storeTestMemory(t, service, "stored while paging")
+	second, err := service.SearchPage(ctx, SearchRequest{Query: "*", Limit: 2}, first.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, second.Memories, 2)
+	assert.Less(t, second.Memories[0].ID, first.Memories[1].ID)
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestSearchPage_KeywordOffset(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	for i := 0; i < 5; i++ {
+		storeTestMemory(t, service, fmt.Sprintf("coffee note %d", i))
+	}
+	storeTestMemory(t, service, "unrelated")
+
+	ids, total := collectPages(t, service, SearchRequest{Query: "coffee", Limit: 2})
+	assert.Equal(t, int64(5), total)
+	assert.Len(t, ids, 5)
+
+	page, err := service.SearchPage(ctx, SearchRequest{Query: "coffee", Limit: 2, Offset: 4}, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Memories, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestSearchPage_Validation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	_, err := service.SearchPage(ctx, SearchRequest{Query: "x"}, "not a cursor!")
+	assert.Error(t, err)
+	_, err = service.SearchPage(ctx, SearchRequest{Query: "x", Offset: -1}, "")
+	assert.Error(t, err)
+	_, err = service.SearchPage(ctx, SearchRequest{Query: "x", Offset: maxSearchOffset + 1}, "")
+	assert.Error(t, err)
+}
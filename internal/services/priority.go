@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// priorityCandidateFactor controls how many nearest neighbours semantic search
+// considers per requested result before re-ranking with priority boosts
+const priorityCandidateFactor = 3
+
+// PriorityBoosts maps memory priority levels to the score added to a memory's
+// relevance when ranking search results. Keyword matches are all equally relevant,
+// so there the boost decides the order; for semantic search it is added to the
+// cosine similarity, letting a critical memory outrank a slightly closer low
+// priority one.
+type PriorityBoosts map[string]float64
+
+// DefaultPriorityBoosts returns the boosts used when none are configured
+func DefaultPriorityBoosts() PriorityBoosts {
+	return PriorityBoosts{
+		models.PriorityLow:      0,
+		models.PriorityMedium:   0.02,
+		models.PriorityHigh:     0.05,
+		models.PriorityCritical: 0.1,
+	}
+}
+
+// Boost returns the boost for a priority. Memories without a priority are ranked as medium.
+func (b PriorityBoosts) Boost(priority string) float64 {
+	if priority == "" {
+		priority = models.PriorityMedium
+	}
+	return b[priority]
+}
+
+// sqlExpression renders the boosts as a CASE expression over the given column.
+// Only validated priority names and formatted floats are interpolated.
+func (b PriorityBoosts) sqlExpression(column string) string {
+	priorities := make([]string, 0, len(b))
+	for priority := range b {
+		if models.IsValidPriority(priority) {
+			priorities = append(priorities, priority)
+		}
+	}
+	sort.Strings(priorities)
+
+	var expr strings.Builder
+	expr.WriteString("CASE ")
+	expr.WriteString(column)
+	for _, priority := range priorities {
+		fmt.Fprintf(&expr, " WHEN '%s' THEN %s", priority, formatBoost(b[priority]))
+	}
+	fmt.Fprintf(&expr, " ELSE %s END", formatBoost(b.Boost(models.PriorityMedium)))
+
+	return expr.String()
+}
+
+func formatBoost(boost float64) string {
+	return strconv.FormatFloat(boost, 'f', -1, 64)
+}
+
+// priorityBoosts returns the configured priority boosts, falling back to the defaults
+func (s *MemoryService) priorityBoosts() PriorityBoosts {
+	switch boosts := s.config["priority_boosts"].(type) {
+	case PriorityBoosts:
+		if len(boosts) > 0 {
+			return boosts
+		}
+	case map[string]float64:
+		if len(boosts) > 0 {
+			return PriorityBoosts(boosts)
+		}
+	}
+	return DefaultPriorityBoosts()
+}
+
+// PriorityFilter scopes a search to memories of some priorities. Priorities keeps
+// the listed priorities and MinPriority those at least as important; when both
+// are set a memory must satisfy both. Memories without a priority count as medium.
+type PriorityFilter struct {
+	Priorities  []string
+	MinPriority string
+}
+
+// IsZero reports whether the filter keeps every priority
+func (f PriorityFilter) IsZero() bool {
+	return len(f.Priorities) == 0 && f.MinPriority == ""
+}
+
+// allowed returns the priority column values the filter keeps, or nil when it
+// keeps every priority
+func (f PriorityFilter) allowed() ([]string, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	for _, priority := range f.Priorities {
+		if !models.IsValidPriority(priority) {
+			return nil, utils.InvalidFieldError("priorities", fmt.Sprintf("unknown priority %q: must be low, medium, high or critical", priority))
+		}
+	}
+	if f.MinPriority != "" && !models.IsValidPriority(f.MinPriority) {
+		return nil, utils.InvalidFieldError("minPriority", fmt.Sprintf("unknown priority %q: must be low, medium, high or critical", f.MinPriority))
+	}
+
+	var allowed []string
+	for _, priority := range []string{models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityCritical} {
+		if len(f.Priorities) > 0 && !slices.Contains(f.Priorities, priority) {
+			continue
+		}
+		if f.MinPriority != "" && priorityRank(priority) < priorityRank(f.MinPriority) {
+			continue
+		}
+		allowed = append(allowed, priority)
+		if priority == models.PriorityMedium {
+			allowed = append(allowed, "")
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, utils.InvalidFieldError("priorities", "no listed priority is at least minPriority")
+	}
+	return allowed, nil
+}
+
+// validate rejects unknown priorities and filters that cannot match anything
+func (f PriorityFilter) validate() error {
+	_, err := f.allowed()
+	return err
+}
+
+// apply adds the filter to a query
+func (f PriorityFilter) apply(query *gorm.DB) *gorm.DB {
+	if allowed, _ := f.allowed(); allowed != nil {
+		query = query.Where("priority IN ?", allowed)
+	}
+	return query
+}
+
+// appendSQL writes the filter to filters as a WHERE condition with a numbered
+// placeholder and returns args with its value appended
+func (f PriorityFilter) appendSQL(filters *strings.Builder, args []interface{}) []interface{} {
+	if allowed, _ := f.allowed(); allowed != nil {
+		args = append(args, pq.StringArray(allowed))
+		fmt.Fprintf(filters, " AND priority = ANY($%d)", len(args))
+	}
+	return args
+}
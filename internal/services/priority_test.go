@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestPriorityBoosts_Boost(t *testing.T) {
+	boosts := DefaultPriorityBoosts()
+
+	assert.Equal(t, 0.1, boosts.Boost(models.PriorityCritical))
+	assert.Equal(t, boosts.Boost(models.PriorityMedium), boosts.Boost(""))
+	assert.Equal(t, 0.0, boosts.Boost("unknown"))
+}
+
+func TestPriorityBoosts_SQLExpression(t *testing.T) {
+	boosts := PriorityBoosts{
+		models.PriorityCritical: 0.25,
+		models.PriorityLow:      -0.1,
+		"bogus' OR 1=1 --":      1,
+	}
+
+	assert.Equal(t,
+		"CASE priority WHEN 'critical' THEN 0.25 WHEN 'low' THEN -0.1 ELSE 0 END",
+		boosts.sqlExpression("priority"))
+}
+
+func TestMemoryService_SearchRanksByPriority(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for _, m := range []struct{ content, priority string }{
+		{"deploy notes: low", models.PriorityLow},
+		{"deploy notes: critical", models.PriorityCritical},
+		{"deploy notes: medium", models.PriorityMedium},
+		{"deploy notes: high", models.PriorityHigh},
+	} {
+		_, err := service.Store(ctx, StoreRequest{
+			Content:  m.content,
+			Category: models.CategoryProject,
+			Type:     models.TypeFact,
+			Priority: m.priority,
+		})
+		require.NoError(t, err)
+	}
+
+	results, err := service.Search(ctx, SearchRequest{Query: "deploy"})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	var order []string
+	for _, memory := range results {
+		order = append(order, memory.Priority)
+	}
+	assert.Equal(t, []string{models.PriorityCritical, models.PriorityHigh, models.PriorityMedium, models.PriorityLow}, order)
+
+	t.Run("Configured boosts override the defaults", func(t *testing.T) {
+		service.config["priority_boosts"] = map[string]float64{models.PriorityLow: 1}
+
+		results, err := service.Search(ctx, SearchRequest{Query: "deploy", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, models.PriorityLow, results[0].Priority)
+	})
+}
+
+func TestMemoryService_SearchFiltersByPriority(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for _, priority := range []string{models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityCritical} {
+		_, err := service.Store(ctx, StoreRequest{
+			Content:  "release checklist: " + priority,
+			Category: models.CategoryProject,
+			Type:     models.TypeFact,
+			Priority: priority,
+		})
+		require.NoError(t, err)
+	}
+	priorities := func(memories []*models.Memory) []string {
+		var result []string
+		for _, memory := range memories {
+			result = append(result, memory.Priority)
+		}
+		return result
+	}
+
+	t.Run("minimum priority", func(t *testing.T) {
+		results, err := service.Search(ctx, SearchRequest{Query: "release", PriorityFilter: PriorityFilter{MinPriority: models.PriorityHigh}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.PriorityCritical, models.PriorityHigh}, priorities(results))
+	})
+
+	t.Run("listed priorities when listing", func(t *testing.T) {
+		results, err := service.Search(ctx, SearchRequest{Query: "*", PriorityFilter: PriorityFilter{Priorities: []string{models.PriorityLow, models.PriorityMedium}}})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{models.PriorityLow, models.PriorityMedium}, priorities(results))
+	})
+
+	t.Run("both must match", func(t *testing.T) {
+		filter := PriorityFilter{Priorities: []string{models.PriorityLow, models.PriorityHigh}, MinPriority: models.PriorityMedium}
+		results, err := service.Search(ctx, SearchRequest{Query: "release", PriorityFilter: filter})
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.PriorityHigh}, priorities(results))
+	})
+
+	t.Run("invalid filters are rejected", func(t *testing.T) {
+		_, err := service.Search(ctx, SearchRequest{Query: "release", PriorityFilter: PriorityFilter{MinPriority: "urgent"}})
+		assert.True(t, utils.IsValidationError(err))
+
+		_, err = service.Search(ctx, SearchRequest{Query: "release", PriorityFilter: PriorityFilter{Priorities: []string{models.PriorityLow}, MinPriority: models.PriorityHigh}})
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
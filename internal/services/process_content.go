@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// CaptureDetected is the action reported for a memory found by a dry run
+const CaptureDetected = "detected"
+
+const (
+	// maxProcessContentLength bounds a transcript scanned in one call
+	maxProcessContentLength = 100000
+	// defaultMinConfidence is the detection confidence below which a sentence is
+	// not captured, as for memories detected on store
+	defaultMinConfidence = 0.5
+)
+
+var (
+	// sentenceEnd matches the punctuation and space ending a sentence
+	sentenceEnd = regexp.MustCompile(`[.!?]+\s+`)
+	// speakerLabel matches the start of a transcript turn such as "User: ..."
+	speakerLabel = regexp.MustCompile(`(?i)^\s*(user|human|me|assistant|ai|bot|system)\s*:\s*`)
+)
+
+// ProcessContentRequest is a conversation transcript to scan for memories
+type ProcessContentRequest struct {
+	Content string
+	// MinConfidence is the detection confidence a sentence needs to be captured,
+	// from 0 to 1; zero uses defaultMinConfidence
+	MinConfidence float64
+	// DryRun reports what would be captured without storing anything
+	DryRun bool
+}
+
+// CapturedMemory is a memory found in a transcript
+type CapturedMemory struct {
+	// Sentence is the sentence the regex engine recognised, or the statement the
+	// llm engine extracted
+	Sentence   string  `json:"sentence"`
+	Type       string  `json:"type"`
+	Category   string  `json:"category"`
+	Priority   string  `json:"priority"`
+	Confidence float64 `json:"confidence"`
+	// DueAt is the date the sentence refers to, such as "next Friday", which is
+	// stored as the memory's due_at metadata
+	DueAt *time.Time `json:"due_at,omitempty"`
+	// Action is created, updated or skipped as for a capture, or detected on a
+	// dry run
+	Action    string         `json:"action"`
+	MatchedBy string         `json:"matched_by,omitempty"`
+	Memory    *models.Memory `json:"memory,omitempty"`
+}
+
+// ProcessContentResult reports what scanning a transcript captured
+type ProcessContentResult struct {
+	// Sentences is how many of the user's sentences were scanned
+	Sentences int              `json:"sentences"`
+	Captured  []CapturedMemory `json:"captured"`
+	DryRun    bool             `json:"dry_run"`
+	// Engine is the extraction engine that ran: regex, or llm when configured
+	// and it succeeded
+	Engine string `json:"engine"`
+}
+
+// ProcessContent scans a conversation transcript with the configured extraction
+// engine and stores what it finds with enough confidence. Lines labelled as the
+// assistant or system, and the lines following them up to the next label, are
+// not scanned, so only what the user said is remembered. Memories the user
+// already has are skipped and ones sharing an update key update that memory, as
+// for a capture.
+func (s *MemoryService) ProcessContent(ctx context.Context, req ProcessContentRequest) (*ProcessContentResult, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, utils.RequiredFieldError("content")
+	}
+	if len(content) > maxProcessContentLength {
+		return nil, utils.InvalidFieldError("content", "must be at most 100000 characters")
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 1 {
+		return nil, utils.InvalidFieldError("min_confidence", "must be between 0 and 1")
+	}
+	minConfidence := req.MinConfidence
+	if minConfidence == 0 {
+		minConfidence = defaultMinConfidence
+	}
+	if !req.DryRun {
+		if err := s.checkIncognito(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sentences := transcriptSentences(content)
+	result := &ProcessContentResult{
+		Sentences: len(sentences),
+		Captured:  []CapturedMemory{},
+		DryRun:    req.DryRun,
+		Engine:    ExtractionEngineRegex,
+	}
+	if len(sentences) == 0 {
+		return result, nil
+	}
+
+	var detected []DetectedMemory
+	detected, result.Engine = s.extractMemories(ctx, strings.Join(sentences, "\n"))
+	for _, detection := range detected {
+		if detection.Confidence < minConfidence {
+			continue
+		}
+		sentence := detection.Content
+		captured := CapturedMemory{
+			Sentence:   sentence,
+			Type:       detection.Type,
+			Category:   detection.Category,
+			Priority:   detection.Priority.String(),
+			Confidence: detection.Confidence,
+			DueAt:      detection.DueAt,
+			Action:     CaptureDetected,
+		}
+		if req.DryRun {
+			result.Captured = append(result.Captured, captured)
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"auto_detected": true,
+			"confidence":    detection.Confidence,
+			"pattern_type":  detection.Type,
+			"source":        "transcript",
+		}
+		detection.annotate(metadata)
+		normalized := s.normalizeDetected(sentence, metadata)
+		stored, err := s.storeCaptured(ctx, normalized, StoreRequest{
+			Content:   normalized,
+			Type:      detection.Type,
+			Category:  detection.Category,
+			Priority:  detection.Priority.String(),
+			UpdateKey: detection.UpdateKey,
+			Metadata:  metadata,
+		}, &CaptureResult{Detected: true})
+		if err != nil {
+			if errors.Is(err, ErrContentBlocked) || utils.IsValidationError(err) {
+				s.logger.Warn().Err(err).Msg("skipping sentence that could not be stored")
+				continue
+			}
+			return nil, err
+		}
+		captured.Action = stored.Action
+		captured.MatchedBy = stored.MatchedBy
+		captured.Memory = stored.Memory
+		result.Captured = append(result.Captured, captured)
+	}
+
+	s.logger.Info().
+		Int("sentences", result.Sentences).
+		Int("captured", len(result.Captured)).
+		Bool("dry_run", req.DryRun).
+		Msg("processed content for memories")
+	return result, nil
+}
+
+// bestDetection returns the most confident detection with at least minConfidence,
+// or nil if there is none
+func bestDetection(detections []DetectedMemory, minConfidence float64) *DetectedMemory {
+	var best *DetectedMemory
+	for i := range detections {
+		if detections[i].Confidence >= minConfidence && (best == nil || detections[i].Confidence > best.Confidence) {
+			best = &detections[i]
+		}
+	}
+	return best
+}
+
+// transcriptSentences splits a transcript into the user's sentences. Unlabelled
+// lines belong to the previous label's speaker, and to the user before any label.
+func transcriptSentences(content string) []string {
+	var sentences []string
+	fromUser := true
+	for _, line := range strings.Split(content, "\n") {
+		if label := speakerLabel.FindStringSubmatch(line); label != nil {
+			switch strings.ToLower(label[1]) {
+			case "user", "human", "me":
+				fromUser = true
+			default:
+				fromUser = false
+			}
+			line = line[len(label[0]):]
+		}
+		if !fromUser {
+			continue
+		}
+
+		sentences = append(sentences, splitSentences(line)...)
+	}
+	return sentences
+}
+
+// splitSentences splits a line of text after sentence punctuation
+func splitSentences(line string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range sentenceEnd.FindAllStringIndex(line, -1) {
+		if sentence := strings.TrimSpace(line[start:end[1]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end[1]
+	}
+	if sentence := strings.TrimSpace(line[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
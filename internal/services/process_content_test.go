@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const testTranscript = `User: Hi there. I prefer Go over Python for services. My editor is vim!
+Assistant: Noted. I prefer Rust myself.
+I live in the cloud.
+User: Maybe my favourite city is Lisbon.
+Also, I work at Acme.`
+
+func TestTranscriptSentences(t *testing.T) {
+	assert.Equal(t, []string{
+		"Hi there.",
+		"I prefer Go over Python for services.",
+		"My editor is vim!",
+		"Maybe my favourite city is Lisbon.",
+		"Also, I work at Acme.",
+	}, transcriptSentences(testTranscript))
+}
+
+func TestMemoryService_ProcessContent(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	// A dry run reports what would be captured without storing it
+	preview, err := service.ProcessContent(ctx, ProcessContentRequest{Content: testTranscript, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 5, preview.Sentences)
+	require.Len(t, preview.Captured, 4)
+	assert.Equal(t, CaptureDetected, preview.Captured[0].Action)
+	count, err := service.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Raising the confidence leaves out the hedged sentence
+	result, err := service.ProcessContent(ctx, ProcessContentRequest{Content: testTranscript, MinConfidence: 0.6})
+	require.NoError(t, err)
+	require.Len(t, result.Captured, 3)
+	preference := result.Captured[0]
+	assert.Equal(t, "I prefer Go over Python for services.", preference.Sentence)
+	assert.Equal(t, models.TypePreference, preference.Type)
+	assert.Equal(t, 0.9, preference.Confidence)
+	assert.Equal(t, CaptureCreated, preference.Action)
+	require.NotNil(t, preference.Memory)
+	assert.Equal(t, preference.Sentence, preference.Memory.Content)
+
+	// Scanning again skips what is already remembered and updates by key
+	result, err = service.ProcessContent(ctx, ProcessContentRequest{Content: "I prefer Go over Python for services. My editor is helix."})
+	require.NoError(t, err)
+	require.Len(t, result.Captured, 2)
+	assert.Equal(t, CaptureSkipped, result.Captured[0].Action)
+	assert.Equal(t, CaptureUpdated, result.Captured[1].Action)
+	assert.Equal(t, CaptureMatchUpdateKey, result.Captured[1].MatchedBy)
+
+	count, err = service.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestMemoryService_ProcessContentValidation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	for _, req := range []ProcessContentRequest{
+		{Content: "  "},
+		{Content: "I prefer tea", MinConfidence: 1.5},
+		{Content: "I prefer tea", MinConfidence: -0.1},
+	} {
+		_, err := service.ProcessContent(ctx, req)
+		assert.True(t, utils.IsValidationError(err), "request %+v", req)
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxProvenanceDepth bounds how many generations of derived memories are followed
+const maxProvenanceDepth = 10
+
+// IsValidProvenanceMethod checks if a given provenance method is known
+func IsValidProvenanceMethod(method string) bool {
+	switch method {
+	case models.ProvenanceMethodConsolidation, models.ProvenanceMethodMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProvenanceSource is one memory a derived memory was built from
+type ProvenanceSource struct {
+	MemoryID uint   `json:"memory_id"`
+	Method   string `json:"method"`
+	// Version is the source's last update time when it was used
+	Version time.Time `json:"version"`
+	// Exists is false once the source has been deleted
+	Exists bool `json:"exists"`
+	// Modified is true when the source has changed since it was used
+	Modified bool           `json:"modified"`
+	Memory   *models.Memory `json:"memory,omitempty"`
+	// Sources lists the source's own sources when it was itself derived
+	Sources []ProvenanceSource `json:"sources,omitempty"`
+}
+
+// Provenance is the chain of memories a memory was derived from
+type Provenance struct {
+	MemoryID uint               `json:"memory_id"`
+	Derived  bool               `json:"derived"`
+	Sources  []ProvenanceSource `json:"sources"`
+}
+
+// StoreDerived stores a memory created from other memories by consolidation or
+// merge and records which versions of the sources it came from
+func (s *MemoryService) StoreDerived(ctx context.Context, req StoreRequest, method string, sourceIDs []uint) (*models.Memory, error) {
+	if !IsValidProvenanceMethod(method) {
+		return nil, utils.InvalidFieldError("method",
+			fmt.Sprintf("must be %s or %s", models.ProvenanceMethodConsolidation, models.ProvenanceMethodMerge))
+	}
+	if len(sourceIDs) == 0 {
+		return nil, utils.WrapValidationError("source_ids", "at least one source memory is required")
+	}
+
+	var sources []models.Memory
+	if err := s.db.WithContext(ctx).
+		Select("id", "content_hash", "updated_at").
+		Where("id IN ? AND user_id = ?", sourceIDs, s.userID).
+		Find(&sources).Error; err != nil {
+		return nil, utils.WrapDatabaseError("load source memories", err)
+	}
+	if len(sources) != len(uniqueIDs(sourceIDs)) {
+		return nil, utils.WrapValidationError("source_ids", "source memories must exist and belong to the user")
+	}
+
+	memory, err := s.Store(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]models.MemoryProvenance, 0, len(sources))
+	for _, source := range sources {
+		if source.ID == memory.ID {
+			continue
+		}
+		rows = append(rows, models.MemoryProvenance{
+			UserID:            s.userID,
+			MemoryID:          memory.ID,
+			SourceMemoryID:    source.ID,
+			Method:            method,
+			SourceContentHash: source.ContentHash,
+			SourceVersion:     source.UpdatedAt,
+		})
+	}
+	if len(rows) > 0 {
+		if err := s.db.WithContext(ctx).Create(&rows).Error; err != nil {
+			s.logger.Error().Err(err).Uint("memory_id", memory.ID).Msg("failed to record memory provenance")
+			return nil, utils.WrapDatabaseError("record memory provenance", err)
+		}
+	}
+
+	return memory, nil
+}
+
+// Provenance returns the chain of memories the given memory was derived from,
+// following derived sources back to the originals
+func (s *MemoryService) Provenance(ctx context.Context, memoryID uint) (*Provenance, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("id = ? AND user_id = ?", memoryID, s.userID).
+		Count(&count).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory", err)
+	}
+	if count == 0 {
+		return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d", memoryID))
+	}
+
+	visited := map[uint]bool{memoryID: true}
+	sources, err := s.provenanceSources(ctx, memoryID, visited, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provenance{
+		MemoryID: memoryID,
+		Derived:  len(sources) > 0,
+		Sources:  sources,
+	}, nil
+}
+
+// provenanceSources loads the sources of a memory and, recursively, their sources
+func (s *MemoryService) provenanceSources(ctx context.Context, memoryID uint, visited map[uint]bool, depth int) ([]ProvenanceSource, error) {
+	var rows []models.MemoryProvenance
+	if err := s.db.WithContext(ctx).
+		Where("memory_id = ? AND user_id = ?", memoryID, s.userID).
+		Order("source_memory_id ASC").
+		Find(&rows).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory provenance", err)
+	}
+	if len(rows) == 0 {
+		return []ProvenanceSource{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.SourceMemoryID
+	}
+	var memories []models.Memory
+	query := s.db.WithContext(ctx).Where("id IN ? AND user_id = ?", ids, s.userID)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+	if err := query.Find(&memories).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get source memories", err)
+	}
+	byID := make(map[uint]*models.Memory, len(memories))
+	for i := range memories {
+		byID[memories[i].ID] = &memories[i]
+	}
+
+	sources := make([]ProvenanceSource, 0, len(rows))
+	for _, row := range rows {
+		source := ProvenanceSource{
+			MemoryID: row.SourceMemoryID,
+			Method:   row.Method,
+			Version:  row.SourceVersion,
+		}
+		if memory, ok := byID[row.SourceMemoryID]; ok {
+			source.Exists = true
+			source.Modified = memory.ContentHash != row.SourceContentHash
+			if err := s.decryptContent(memory); err != nil {
+				s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt source memory content")
+			}
+			source.Memory = memory
+		}
+
+		// Guard against cycles and runaway chains
+		if !visited[row.SourceMemoryID] && depth < maxProvenanceDepth {
+			visited[row.SourceMemoryID] = true
+			parents, err := s.provenanceSources(ctx, row.SourceMemoryID, visited, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if len(parents) > 0 {
+				source.Sources = parents
+			}
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// uniqueIDs returns the distinct IDs in order of first appearance
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func derivedRequest(content string) StoreRequest {
+	return StoreRequest{
+		Content:  content,
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+	}
+}
+
+func TestProvenance_TracesChain(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.MemoryProvenance{}))
+
+	first, _ := storeTestMemory(t, service, "likes espresso")
+	second, _ := storeTestMemory(t, service, "drinks coffee every morning")
+	third, _ := storeTestMemory(t, service, "avoids tea")
+
+	merged, err := service.StoreDerived(ctx, derivedRequest("drinks espresso every morning"),
+		models.ProvenanceMethodMerge, []uint{first.ID, second.ID})
+	require.NoError(t, err)
+	summary, err := service.StoreDerived(ctx, derivedRequest("coffee person, not a tea person"),
+		models.ProvenanceMethodConsolidation, []uint{merged.ID, third.ID})
+	require.NoError(t, err)
+
+	// Sources changed or deleted after derivation are still traced
+	_, err = service.Update(ctx, first.ID, UpdateRequest{Content: "likes flat whites"})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(ctx, second.ID))
+
+	provenance, err := service.Provenance(ctx, summary.ID)
+	require.NoError(t, err)
+	assert.True(t, provenance.Derived)
+	require.Len(t, provenance.Sources, 2)
+
+	// Sources are listed by memory ID, so the original comes before the merge
+	assert.Equal(t, third.ID, provenance.Sources[0].MemoryID)
+	assert.Empty(t, provenance.Sources[0].Sources)
+
+	mergedSource := provenance.Sources[1]
+	assert.Equal(t, merged.ID, mergedSource.MemoryID)
+	assert.Equal(t, models.ProvenanceMethodConsolidation, mergedSource.Method)
+	assert.True(t, mergedSource.Exists)
+	assert.False(t, mergedSource.Modified)
+	require.Len(t, mergedSource.Sources, 2)
+
+	assert.Equal(t, first.ID, mergedSource.Sources[0].MemoryID)
+	assert.Equal(t, models.ProvenanceMethodMerge, mergedSource.Sources[0].Method)
+	assert.True(t, mergedSource.Sources[0].Exists)
+	assert.True(t, mergedSource.Sources[0].Modified)
+	assert.Equal(t, "likes flat whites", mergedSource.Sources[0].Memory.Content)
+
+	assert.Equal(t, second.ID, mergedSource.Sources[1].MemoryID)
+	assert.False(t, mergedSource.Sources[1].Exists)
+	assert.Nil(t, mergedSource.Sources[1].Memory)
+
+	// Original memories have no provenance
+	original, err := service.Provenance(ctx, third.ID)
+	require.NoError(t, err)
+	assert.False(t, original.Derived)
+	assert.Empty(t, original.Sources)
+}
+
+func TestProvenance_Validation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	require.NoError(t, service.db.AutoMigrate(&models.MemoryProvenance{}))
+	source, _ := storeTestMemory(t, service, "source")
+
+	_, err := service.StoreDerived(ctx, derivedRequest("derived"), "summarize", []uint{source.ID})
+	assert.Error(t, err)
+	_, err = service.StoreDerived(ctx, derivedRequest("derived"), models.ProvenanceMethodMerge, nil)
+	assert.Error(t, err)
+
+	// Sources must belong to the user
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, 2)
+	_, err = other.StoreDerived(ctx, derivedRequest("derived"), models.ProvenanceMethodMerge, []uint{source.ID})
+	assert.Error(t, err)
+
+	_, err = other.Provenance(ctx, source.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// quotaWarningPercent is the usage level at which store responses start warning
+const quotaWarningPercent = 90
+
+// embeddingBytes is the storage taken by one 1536-dimension float32 embedding
+const embeddingBytes = 1536 * 4
+
+// QuotaUsage describes how much of their memory allowance a user is using
+type QuotaUsage struct {
+	MemoriesUsed int64 `json:"memories_used"`
+	// MemoryLimit is the maximum number of memories kept; 0 means unlimited
+	MemoryLimit int     `json:"memory_limit"`
+	UsedPercent float64 `json:"used_percent"`
+	// ApproxBytes estimates storage for content, metadata and embeddings
+	ApproxBytes int64 `json:"approx_bytes"`
+	// Evicted is the number of memories removed by the operation to stay within the limit
+	Evicted int `json:"evicted"`
+	// EvictionPolicy decides which memories are removed, or whether new ones are rejected, at the limit
+	EvictionPolicy string `json:"eviction_policy"`
+	Warning        string `json:"warning,omitempty"`
+}
+
+// Quota reports the user's current memory usage against the configured limit,
+// across all of their workspaces
+func (s *MemoryService) Quota(ctx context.Context) (*QuotaUsage, error) {
+	var usage struct {
+		Count          int64
+		ContentBytes   int64
+		WithEmbeddings int64
+	}
+
+	if err := database.AllWorkspaces(s.db).WithContext(ctx).Model(&models.Memory{}).
+		Select(`COUNT(*) AS count,
+			COALESCE(SUM(LENGTH(content) + COALESCE(LENGTH(CAST(encrypted_content AS TEXT)), 0) + COALESCE(LENGTH(CAST(metadata AS TEXT)), 0)), 0) AS content_bytes,
+			COUNT(embedding) AS with_embeddings`).
+		Where("user_id = ?", s.userID).
+		Scan(&usage).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to compute memory usage")
+		return nil, utils.WrapDatabaseError("compute memory usage", err)
+	}
+
+	quota := &QuotaUsage{
+		MemoriesUsed:   usage.Count,
+		MemoryLimit:    s.MemoryLimit(ctx),
+		ApproxBytes:    usage.ContentBytes + usage.WithEmbeddings*embeddingBytes,
+		EvictionPolicy: s.EvictionPolicy(ctx),
+	}
+	if quota.MemoryLimit > 0 {
+		quota.UsedPercent = float64(quota.MemoriesUsed) * 100 / float64(quota.MemoryLimit)
+	}
+
+	return quota, nil
+}
+
+// MemoryLimit returns the user's memory limit, or 0 when unlimited: the limit an
+// admin set for the user, else their plan's, else the deployment default
+func (s *MemoryService) MemoryLimit(ctx context.Context) int {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Select("plan", "memory_limit").
+		Where("id = ?", s.userID).
+		Limit(1).
+		Find(&users).Error; err != nil {
+		s.logger.Debug().Err(err).Msg("failed to load user quota, using default")
+	} else if len(users) > 0 {
+		if limit := users[0].MemoryLimit; limit != nil {
+			if *limit < 0 {
+				return 0
+			}
+			return *limit
+		}
+		if limit, ok := s.PlanLimits()[users[0].Plan]; ok && users[0].Plan != "" {
+			return limit
+		}
+	}
+	return s.defaultMemoryLimit()
+}
+
+// PlanLimits returns the memory limit of each configured plan, 0 meaning unlimited
+func (s *MemoryService) PlanLimits() map[string]int {
+	limits, _ := s.config["plan_limits"].(map[string]int)
+	return limits
+}
+
+// SetQuota assigns the user a plan and a memory limit of their own. An empty plan
+// reverts to the deployment default and a nil limit to the plan's. Memories over
+// a lowered limit are evicted on the next store, as the user's policy decides.
+func (s *MemoryService) SetQuota(ctx context.Context, plan string, limit *int) error {
+	if _, ok := s.PlanLimits()[plan]; plan != "" && !ok {
+		return utils.InvalidFieldError("plan", "is not a configured plan")
+	}
+	if limit != nil && *limit < 0 {
+		return utils.InvalidFieldError("memory_limit", "must not be negative")
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Updates(map[string]interface{}{"plan": plan, "memory_limit": limit})
+	if result.Error != nil {
+		s.logger.Error().Err(result.Error).Msg("failed to set quota")
+		return utils.WrapDatabaseError("set quota", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+	}
+
+	s.logger.Info().Str("plan", plan).Interface("memory_limit", limit).Msg("quota updated")
+	return nil
+}
+
+// RefreshWarning sets Warning to a message for the user when usage is high or
+// memories were evicted, and clears it otherwise
+func (q *QuotaUsage) RefreshWarning() {
+	q.Warning = q.warning()
+}
+
+func (q *QuotaUsage) warning() string {
+	if q.MemoryLimit == 0 {
+		return ""
+	}
+
+	if q.Evicted > 0 {
+		return fmt.Sprintf("Memory limit of %d reached: %d memories were removed to make room (%s policy). Consider pruning memories you no longer need.",
+			q.MemoryLimit, q.Evicted, q.EvictionPolicy)
+	}
+
+	if q.UsedPercent >= quotaWarningPercent {
+		consequence := "memories are removed according to the " + q.EvictionPolicy + " policy"
+		if q.EvictionPolicy == EvictionRejectNew {
+			consequence = "new memories are rejected"
+		}
+		return fmt.Sprintf("You are at %.0f%% of your memory limit (%d of %d). When the limit is reached %s; consider pruning memories you no longer need.",
+			q.UsedPercent, q.MemoriesUsed, q.MemoryLimit, consequence)
+	}
+
+	return ""
+}
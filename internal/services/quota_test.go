@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func storeTestMemory(t *testing.T, service *MemoryService, content string) (*models.Memory, *QuotaUsage) {
+	memory, quota, err := service.StoreWithQuota(context.Background(), StoreRequest{
+		Content:  content,
+		Category: models.CategoryPersonal,
+		Type:     models.TypeFact,
+		Priority: models.PriorityMedium,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, quota)
+	return memory, quota
+}
+
+func TestMemoryService_StoreWithQuota(t *testing.T) {
+	service := setupMemoryService(t, map[string]interface{}{"memory_limit": 10})
+
+	for i := 0; i < 8; i++ {
+		_, quota := storeTestMemory(t, service, fmt.Sprintf("memory %d", i))
+		assert.Empty(t, quota.Warning)
+	}
+
+	_, quota := storeTestMemory(t, service, "memory 8")
+	assert.Equal(t, int64(9), quota.MemoriesUsed)
+	assert.Equal(t, 10, quota.MemoryLimit)
+	assert.Equal(t, 90.0, quota.UsedPercent)
+	assert.Positive(t, quota.ApproxBytes)
+	assert.Zero(t, quota.Evicted)
+	assert.Contains(t, quota.Warning, "90% of your memory limit")
+
+	storeTestMemory(t, service, "memory 9")
+	_, quota = storeTestMemory(t, service, "memory 10")
+	assert.Equal(t, int64(10), quota.MemoriesUsed)
+	assert.Equal(t, 1, quota.Evicted)
+	assert.Contains(t, quota.Warning, "1 memories were removed")
+}
+
+func TestMemoryService_QuotaUnlimited(t *testing.T) {
+	service := setupMemoryService(t, nil)
+
+	_, quota := storeTestMemory(t, service, "no limit configured")
+	assert.Equal(t, int64(1), quota.MemoriesUsed)
+	assert.Zero(t, quota.MemoryLimit)
+	assert.Zero(t, quota.UsedPercent)
+	assert.Empty(t, quota.Warning)
+}
+
+func TestMemoryService_EvictionIsScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	config := map[string]interface{}{"memory_limit": 2}
+	first := setupMemoryService(t, config)
+	second := NewMemoryServiceWithUser(first.db, nil, zerolog.New(nil).Level(zerolog.Disabled), config, 2)
+
+	storeTestMemory(t, first, "first user memory")
+	storeTestMemory(t, second, "second user memory 1")
+	storeTestMemory(t, second, "second user memory 2")
+	_, quota := storeTestMemory(t, second, "second user memory 3")
+	assert.Equal(t, 1, quota.Evicted)
+
+	count, err := first.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryService_MemoryLimitPlans(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupEvictionService(t, map[string]interface{}{
+		"memory_limit": 3,
+		"plan_limits":  map[string]int{"pro": 5, "unlimited": 0},
+	}, EvictionRejectNew)
+	require.NoError(t, service.db.Create(&models.User{ID: 2, Email: "other@example.com", Password: "x"}).Error)
+	other := NewMemoryServiceWithUser(service.db, nil, zerolog.Nop(), service.config, 2)
+
+	// Another user's memories don't count towards the limit
+	for i := 0; i < 4; i++ {
+		storeTestMemory(t, other, fmt.Sprintf("other user memory %d", i))
+	}
+	for i := 0; i < 3; i++ {
+		storeTestMemory(t, service, fmt.Sprintf("memory %d", i))
+	}
+	assert.Equal(t, 3, service.MemoryLimit(ctx))
+	_, err := service.Store(ctx, StoreRequest{Content: "one too many", Category: models.CategoryPersonal, Type: models.TypeFact})
+	assert.ErrorIs(t, err, ErrMemoryLimitReached)
+
+	// A plan raises the limit, and the user's own limit takes precedence over it
+	require.NoError(t, service.SetQuota(ctx, "pro", nil))
+	assert.Equal(t, 5, service.MemoryLimit(ctx))
+	storeTestMemory(t, service, "memory 3")
+
+	limit := 0
+	require.NoError(t, service.SetQuota(ctx, "pro", &limit))
+	assert.Equal(t, 0, service.MemoryLimit(ctx))
+	quota, err := service.Quota(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, quota.MemoryLimit)
+
+	require.NoError(t, service.SetQuota(ctx, "", nil))
+	assert.Equal(t, 3, service.MemoryLimit(ctx))
+	assert.Equal(t, 3, other.MemoryLimit(ctx))
+
+	assert.Error(t, service.SetQuota(ctx, "enterprise", nil))
+	limit = -1
+	assert.Error(t, service.SetQuota(ctx, "", &limit))
+}
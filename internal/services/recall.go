@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Recall orders for RecallRecent
+const (
+	// RecallByRecent returns the memories accessed most recently first
+	RecallByRecent = "recent"
+	// RecallByFrequent returns the memories accessed most often first
+	RecallByFrequent = "frequent"
+)
+
+const (
+	defaultRecallLimit = 10
+	maxRecallLimit     = 100
+)
+
+// RecallRequest selects the memories a client used lately
+type RecallRequest struct {
+	// By is RecallByRecent (the default) or RecallByFrequent
+	By       string
+	Category string
+	Type     string
+	Limit    int
+}
+
+// RecallRecent returns the user's memories that searches and lookups have
+// returned, most recently or most frequently accessed first. Memories never
+// accessed are left out. Recalling does not itself count as an access, so
+// asking what was used lately does not change the answer.
+func (s *MemoryService) RecallRecent(ctx context.Context, req RecallRequest) ([]*models.Memory, error) {
+	var order []string
+	switch req.By {
+	case "", RecallByRecent:
+		order = []string{"last_accessed_at DESC", "id DESC"}
+	case RecallByFrequent:
+		order = []string{"access_count DESC", "last_accessed_at DESC", "id DESC"}
+	default:
+		return nil, utils.InvalidFieldError("by", "must be recent or frequent")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+	if limit > maxRecallLimit {
+		limit = maxRecallLimit
+	}
+
+	query, err := s.listQuery(ctx, ListRequest{Category: req.Category, Type: req.Type})
+	if err != nil {
+		return nil, err
+	}
+	query = query.Where("last_accessed_at IS NOT NULL AND access_count > 0")
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+	for _, column := range order {
+		query = query.Order(column)
+	}
+
+	var memories []*models.Memory
+	if err := query.Limit(limit).Find(&memories).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to recall recent memories")
+		return nil, utils.WrapDatabaseError("recall recent memories", err)
+	}
+
+	for _, memory := range memories {
+		if err := s.decryptContent(memory); err != nil {
+			s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+		}
+	}
+	s.auditReads(ctx, memories)
+
+	return memories, nil
+}
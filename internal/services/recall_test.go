@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// setAccess gives a memory fixed access statistics
+func setAccess(t *testing.T, service *MemoryService, memory *models.Memory, count int64, at time.Time) {
+	require.NoError(t, service.db.Model(&models.Memory{}).Where("id = ?", memory.ID).
+		UpdateColumns(map[string]interface{}{"access_count": count, "last_accessed_at": at}).Error)
+}
+
+func TestMemoryService_RecallRecent(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	now := time.Now().UTC()
+
+	often := storePriorityMemory(t, service, "recalled often, a while ago", models.PriorityMedium)
+	lately := storePriorityMemory(t, service, "recalled once, just now", models.PriorityMedium)
+	storePriorityMemory(t, service, "never recalled", models.PriorityMedium)
+	setAccess(t, service, often, 5, now.Add(-time.Hour))
+	setAccess(t, service, lately, 1, now)
+
+	recent, err := service.RecallRecent(ctx, RecallRequest{})
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, lately.ID, recent[0].ID)
+	assert.Equal(t, often.ID, recent[1].ID)
+
+	frequent, err := service.RecallRecent(ctx, RecallRequest{By: RecallByFrequent, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, frequent, 1)
+	assert.Equal(t, often.ID, frequent[0].ID)
+
+	// Recalling is not an access
+	again, err := service.RecallRecent(ctx, RecallRequest{By: RecallByFrequent})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), again[0].AccessCount)
+
+	none, err := service.RecallRecent(ctx, RecallRequest{Category: models.CategoryBusiness})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	_, err = service.RecallRecent(ctx, RecallRequest{By: "oldest"})
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMemoryService_Search_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	storePriorityMemory(t, service, "deploys go out on tuesdays", models.PriorityMedium)
+
+	results, err := service.Search(ctx, SearchRequest{Query: "deploys", ReadOnly: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	recent, err := service.RecallRecent(ctx, RecallRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	_, err = service.Search(ctx, SearchRequest{Query: "deploys"})
+	require.NoError(t, err)
+	recent, err = service.RecallRecent(ctx, RecallRequest{})
+	require.NoError(t, err)
+	assert.Len(t, recent, 1)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// ReembedOptions controls a re-embedding run
+type ReembedOptions struct {
+	// BatchSize is how many memories are read and checkpointed at a time
+	BatchSize int
+	// Restart starts over instead of resuming an unfinished run for the model
+	Restart bool
+	// Progress, when set, is called after each batch
+	Progress func(run *models.ReembedRun)
+}
+
+// ReembedAll regenerates the embedding of every memory, across all users, with
+// the service's embedding model, as is needed after switching models: vectors
+// from different models cannot be compared, and a model with other dimensions
+// leaves old vectors unusable. Progress is checkpointed in reembed_runs after
+// every batch; calling it again for the same model resumes an unfinished run.
+//
+// Run it with the server already on the new model, so memories written meanwhile
+// get new embeddings too. Until the run completes searches compare new and old
+// vectors and rank less well. Memories whose content cannot be read are skipped
+// and counted; a provider error stops the run so it can be resumed later.
+func (s *MemoryService) ReembedAll(ctx context.Context, opts ReembedOptions) (*models.ReembedRun, error) {
+	if s.embedding == nil {
+		return nil, errors.New("no embedding service configured")
+	}
+	model := s.EmbeddingModel()
+	if model == "" {
+		return nil, errors.New("the embedding service does not name its model")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	run, err := s.reembedRun(ctx, model, opts.Restart)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info().
+		Uint("run_id", run.ID).
+		Str("model", model).
+		Uint("last_memory_id", run.LastMemoryID).
+		Int64("processed", run.Processed).
+		Int64("total", run.Total).
+		Msg("re-embedding memories")
+
+	db := s.db.WithContext(ctx)
+	// Tags are part of the embedded document when a composer includes them
+	omit := []string{"embedding"}
+	if db.Dialector.Name() == "sqlite" {
+		omit = append(omit, "tags")
+	}
+
+	for {
+		var memories []models.Memory
+		if err := db.Omit(omit...).
+			Where("id > ?", run.LastMemoryID).
+			Order("id ASC").
+			Limit(opts.BatchSize).
+			Find(&memories).Error; err != nil {
+			return run, s.stopReembedRun(ctx, run, fmt.Errorf("failed to load memories: %w", err))
+		}
+		if len(memories) == 0 {
+			break
+		}
+
+		for i := range memories {
+			memory := &memories[i]
+			if err := s.decryptContent(memory); err != nil {
+				s.logger.Warn().Err(err).Uint("memory_id", memory.ID).Msg("skipping memory whose content cannot be decrypted")
+				run.Skipped++
+				run.LastMemoryID = memory.ID
+				continue
+			}
+
+			embedder := s.embedderFor(ctx, memory.UserID, false)
+			embedding, err := s.embedMemory(ctx, embedder, embeddingFieldsOf(memory, memory.Content))
+			if err != nil {
+				return run, s.stopReembedRun(ctx, run, fmt.Errorf("failed to embed memory %d: %w", memory.ID, err))
+			}
+			chunks, err := s.embedChunks(ctx, embedder, memory.ID, memory.UserID, memory.Content)
+			if err != nil {
+				return run, s.stopReembedRun(ctx, run, fmt.Errorf("failed to embed memory %d: %w", memory.ID, err))
+			}
+			if err := s.saveEmbedding(ctx, memory.ID, memory.UserID, embedding, chunks); err != nil {
+				return run, s.stopReembedRun(ctx, run, fmt.Errorf("failed to store embedding of memory %d: %w", memory.ID, err))
+			}
+			run.Processed++
+			run.LastMemoryID = memory.ID
+		}
+
+		if err := db.Save(run).Error; err != nil {
+			return run, fmt.Errorf("failed to record re-embedding progress: %w", err)
+		}
+		if opts.Progress != nil {
+			opts.Progress(run)
+		}
+	}
+
+	now := time.Now().UTC()
+	run.Status = models.ReembedCompleted
+	run.CompletedAt = &now
+	run.LastError = ""
+	if err := db.Save(run).Error; err != nil {
+		return run, fmt.Errorf("failed to record re-embedding progress: %w", err)
+	}
+
+	s.logger.Info().
+		Uint("run_id", run.ID).
+		Str("model", model).
+		Int64("processed", run.Processed).
+		Int64("skipped", run.Skipped).
+		Msg("re-embedded memories")
+	return run, nil
+}
+
+// ReembedRuns returns the re-embedding runs, newest first
+func (s *MemoryService) ReembedRuns(ctx context.Context) ([]models.ReembedRun, error) {
+	var runs []models.ReembedRun
+	if err := s.db.WithContext(ctx).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list re-embedding runs: %w", err)
+	}
+	return runs, nil
+}
+
+// reembedRun returns the unfinished run for the model, or starts a new one
+func (s *MemoryService) reembedRun(ctx context.Context, model string, restart bool) (*models.ReembedRun, error) {
+	db := s.db.WithContext(ctx)
+
+	var run models.ReembedRun
+	if !restart {
+		err := db.Where("model = ? AND status = ?", model, models.ReembedRunning).
+			Order("id DESC").
+			First(&run).Error
+		if err == nil {
+			return &run, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load re-embedding run: %w", err)
+		}
+	}
+
+	var total int64
+	if err := db.Model(&models.Memory{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count memories: %w", err)
+	}
+	run = models.ReembedRun{
+		Model:     model,
+		Status:    models.ReembedRunning,
+		Total:     total,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := db.Create(&run).Error; err != nil {
+		return nil, fmt.Errorf("failed to start re-embedding run: %w", err)
+	}
+	return &run, nil
+}
+
+// stopReembedRun checkpoints an interrupted run with its error so it can resume
+func (s *MemoryService) stopReembedRun(ctx context.Context, run *models.ReembedRun, cause error) error {
+	run.LastError = cause.Error()
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Save(run).Error; err != nil {
+		s.logger.Error().Err(err).Uint("run_id", run.ID).Msg("failed to record re-embedding progress")
+	}
+	return cause
+}
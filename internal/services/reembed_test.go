@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// outageEmbeddingService embeds like the mock until its budget of calls runs out
+type outageEmbeddingService struct {
+	*MockEmbeddingService
+	remaining int
+}
+
+func (e *outageEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if e.remaining == 0 {
+		return nil, errors.New("provider unavailable")
+	}
+	e.remaining--
+	return e.MockEmbeddingService.GenerateEmbedding(ctx, text)
+}
+
+func TestMemoryService_ReembedAll(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, map[string]interface{}{"embedding_cache": false})
+	require.NoError(t, service.db.AutoMigrate(&models.ReembedRun{}))
+	for i := 0; i < 5; i++ {
+		storeTestMemory(t, service, fmt.Sprintf("memory %d", i))
+	}
+
+	// The provider fails part way through the second batch
+	service.embedding = &outageEmbeddingService{MockEmbeddingService: NewMockEmbeddingService(), remaining: 3}
+	var batches int
+	run, err := service.ReembedAll(ctx, ReembedOptions{BatchSize: 2, Progress: func(*models.ReembedRun) { batches++ }})
+	require.Error(t, err)
+	assert.Equal(t, 1, batches)
+	assert.Equal(t, models.ReembedRunning, run.Status)
+	assert.Equal(t, int64(5), run.Total)
+	assert.Equal(t, int64(3), run.Processed)
+	assert.Contains(t, run.LastError, "provider unavailable")
+
+	var embedded int64
+	service.db.Model(&models.Memory{}).Where("embedding IS NOT NULL").Count(&embedded)
+	assert.Equal(t, int64(3), embedded)
+
+	// Once the provider recovers the run resumes where it stopped
+	service.embedding = NewMockEmbeddingService()
+	resumed, err := service.ReembedAll(ctx, ReembedOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, resumed.ID)
+	assert.Equal(t, models.ReembedCompleted, resumed.Status)
+	assert.Equal(t, int64(5), resumed.Processed)
+	assert.Empty(t, resumed.LastError)
+	assert.NotNil(t, resumed.CompletedAt)
+
+	service.db.Model(&models.Memory{}).Where("embedding IS NOT NULL").Count(&embedded)
+	assert.Equal(t, int64(5), embedded)
+
+	// A completed run is not resumed
+	again, err := service.ReembedAll(ctx, ReembedOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, run.ID, again.ID)
+	assert.Equal(t, int64(5), again.Processed)
+
+	runs, err := service.ReembedRuns(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, again.ID, runs[0].ID)
+}
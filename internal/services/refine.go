@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxWithinIDs bounds the memories a search can be scoped to
+const maxWithinIDs = 1000
+
+// refineScope is the decoded form of a refine cursor: the memories a previous
+// search returned
+type refineScope struct {
+	IDs []uint `json:"w"`
+}
+
+// refineCursor returns a cursor scoping a follow-up search to the given memories,
+// or "" when there are none
+func refineCursor(memories []*models.Memory) string {
+	if len(memories) == 0 {
+		return ""
+	}
+	scope := refineScope{IDs: make([]uint, len(memories))}
+	for i, memory := range memories {
+		scope.IDs[i] = memory.ID
+	}
+	data, _ := json.Marshal(scope)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRefineCursor parses a refine cursor returned by a previous search
+func decodeRefineCursor(cursor string) ([]uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(cursor))
+	if err != nil {
+		return nil, utils.InvalidFieldError("refineCursor", "is not a valid refine cursor")
+	}
+	var scope refineScope
+	if err := json.Unmarshal(data, &scope); err != nil || scope.IDs == nil {
+		return nil, utils.InvalidFieldError("refineCursor", "is not a valid refine cursor")
+	}
+	return scope.IDs, nil
+}
+
+// resolveScope turns a refine cursor into WithinIDs, keeping only the memories in
+// both when the request has both, and checks the scope's size. A scope is only a
+// filter: memories of other users in it are still never returned.
+func (r *SearchRequest) resolveScope() error {
+	if r.RefineCursor != "" {
+		ids, err := decodeRefineCursor(r.RefineCursor)
+		if err != nil {
+			return err
+		}
+		if r.WithinIDs != nil {
+			ids = intersectIDs(r.WithinIDs, ids)
+		}
+		r.WithinIDs = ids
+		r.RefineCursor = ""
+	}
+	if len(r.WithinIDs) > maxWithinIDs {
+		return utils.InvalidFieldError("withinIds", fmt.Sprintf("must list at most %d memories", maxWithinIDs))
+	}
+	return nil
+}
+
+// withinArray returns the scope as a Postgres array for raw queries
+func withinArray(ids []uint) interface{} {
+	array := make(pq.Int64Array, len(ids))
+	for i, id := range ids {
+		array[i] = int64(id)
+	}
+	return array
+}
+
+// intersectIDs returns the IDs of a that are also in b, in the order of a. The
+// result is never nil, so an empty intersection still scopes a search.
+func intersectIDs(a, b []uint) []uint {
+	inB := make(map[uint]bool, len(b))
+	for _, id := range b {
+		inB[id] = true
+	}
+	both := []uint{}
+	for _, id := range a {
+		if inB[id] {
+			both = append(both, id)
+		}
+	}
+	return both
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestSearchPage_RefineWithinResults(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	store := func(content, category string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{Content: content, Category: category, Type: models.TypeFact})
+		require.NoError(t, err)
+		return memory
+	}
+	launch := store("Atlas project deadline is the end of May", models.CategoryProject)
+	store("Atlas project uses Postgres", models.CategoryProject)
+	store("Passport renewal deadline is in June", models.CategoryPersonal)
+
+	projects, err := service.SearchPage(ctx, SearchRequest{Query: "project", Category: models.CategoryProject}, "")
+	require.NoError(t, err)
+	require.Len(t, projects.Memories, 2)
+	require.NotEmpty(t, projects.RefineCursor)
+
+	// Within those project memories, which mention deadlines?
+	page, err := service.SearchPage(ctx, SearchRequest{Query: "deadline", RefineCursor: projects.RefineCursor}, "")
+	require.NoError(t, err)
+	require.Len(t, page.Memories, 1)
+	assert.Equal(t, launch.ID, page.Memories[0].ID)
+	assert.Equal(t, int64(1), page.TotalCount)
+
+	// Explicit IDs work the same way, and with a cursor only IDs in both are searched
+	memories, err := service.Search(ctx, SearchRequest{Query: "deadline", WithinIDs: []uint{launch.ID}})
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	memories, err = service.Search(ctx, SearchRequest{Query: "deadline", WithinIDs: []uint{launch.ID + 100}, RefineCursor: projects.RefineCursor})
+	require.NoError(t, err)
+	assert.Empty(t, memories)
+
+	// Wildcard listings are scoped too
+	page, err = service.SearchPage(ctx, SearchRequest{Query: "*", RefineCursor: projects.RefineCursor}, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Memories, 2)
+	assert.Equal(t, int64(2), page.TotalCount)
+
+	// An empty scope matches nothing rather than everything
+	memories, err = service.Search(ctx, SearchRequest{Query: "deadline", WithinIDs: []uint{}})
+	require.NoError(t, err)
+	assert.Empty(t, memories)
+}
+
+func TestSearch_RefineValidation(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	_, err := service.Search(ctx, SearchRequest{Query: "x", RefineCursor: "not a cursor"})
+	assert.True(t, utils.IsValidationError(err))
+
+	// A pagination cursor is not a refine cursor
+	_, err = service.Search(ctx, SearchRequest{Query: "x", RefineCursor: pageCursor{Offset: 10}.encode()})
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = service.Search(ctx, SearchRequest{Query: "x", WithinIDs: make([]uint, maxWithinIDs+1)})
+	assert.True(t, utils.IsValidationError(err))
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCrossRegion is returned when data tagged with one residency region is
+// restored into a deployment tagged with another
+var ErrCrossRegion = errors.New("cross-region restore blocked")
+
+// ResidencyError describes a blocked cross-region restore
+type ResidencyError struct {
+	Artifact     string
+	SourceRegion string
+	TargetRegion string
+}
+
+func (e *ResidencyError) Error() string {
+	return fmt.Sprintf("%s was created in region %q and cannot be restored into region %q without allow_cross_region",
+		e.Artifact, e.SourceRegion, e.TargetRegion)
+}
+
+func (e *ResidencyError) Unwrap() error {
+	return ErrCrossRegion
+}
+
+// NormalizeRegion canonicalises a residency region tag
+func NormalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
+
+// CheckResidency reports whether data from sourceRegion may be restored into
+// targetRegion. Untagged data and untagged deployments are not restricted, so
+// the check only applies once both sides carry a region.
+func CheckResidency(artifact, sourceRegion, targetRegion string, allowCrossRegion bool) error {
+	source := NormalizeRegion(sourceRegion)
+	target := NormalizeRegion(targetRegion)
+
+	if source == "" || target == "" || source == target || allowCrossRegion {
+		return nil
+	}
+
+	return &ResidencyError{
+		Artifact:     artifact,
+		SourceRegion: source,
+		TargetRegion: target,
+	}
+}
+
+// ResidencyRegion returns the residency region the deployment is tagged with, if any
+func (s *MemoryService) ResidencyRegion() string {
+	if region, ok := s.config["residency_region"].(string); ok {
+		return NormalizeRegion(region)
+	}
+	return ""
+}
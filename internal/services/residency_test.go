@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestCheckResidency(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		target  string
+		allow   bool
+		blocked bool
+	}{
+		{"Same region", "eu-west", "eu-west", false, false},
+		{"Same region different case", " EU-West ", "eu-west", false, false},
+		{"Untagged source", "", "eu-west", false, false},
+		{"Untagged deployment", "us-east", "", false, false},
+		{"Different regions", "us-east", "eu-west", false, true},
+		{"Different regions with override", "us-east", "eu-west", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckResidency("snapshot", tt.source, tt.target, tt.allow)
+			if !tt.blocked {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrCrossRegion))
+
+			var residencyErr *ResidencyError
+			require.True(t, errors.As(err, &residencyErr))
+			assert.Equal(t, "us-east", residencyErr.SourceRegion)
+			assert.Equal(t, "eu-west", residencyErr.TargetRegion)
+		})
+	}
+}
+
+func TestMemoryService_ConfigBundleResidency(t *testing.T) {
+	ctx := context.Background()
+
+	source := setupMemoryService(t, map[string]interface{}{"residency_region": "US-East"})
+	require.NoError(t, source.db.AutoMigrate(&models.SavedSearch{}, &models.Workspace{}, &models.User{}))
+	_, err := source.SaveSearch(ctx, SavedSearchSpec{Name: "work", Query: "deadline"})
+	require.NoError(t, err)
+
+	bundle, err := source.ExportConfigBundle(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "us-east", bundle.Region)
+
+	target := setupMemoryService(t, map[string]interface{}{"residency_region": "eu-west"})
+	require.NoError(t, target.db.AutoMigrate(&models.SavedSearch{}, &models.Workspace{}, &models.User{}))
+
+	_, err = target.ImportConfigBundle(ctx, bundle, false, false)
+	assert.True(t, errors.Is(err, ErrCrossRegion))
+
+	result, err := target.ImportConfigBundle(ctx, bundle, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+}
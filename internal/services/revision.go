@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// maxRevisionsPerMemory bounds the history kept for one memory; the oldest
+// revisions are dropped first
+const maxRevisionsPerMemory = 50
+
+// MemoryHistory is a memory's current version and the versions it replaced
+type MemoryHistory struct {
+	MemoryID uint           `json:"memory_id"`
+	Current  *models.Memory `json:"current"`
+	// Revisions lists previous versions, newest first
+	Revisions []models.MemoryRevision `json:"revisions"`
+}
+
+// revisionFor returns the revision to record before memory is overwritten with the
+// given values, or nil when the content, type and category stay the same. New
+// content is given by its content hash. Empty values leave a field unchanged.
+func revisionFor(memory *models.Memory, contentHash, memoryType, category string) *models.MemoryRevision {
+	changed := (contentHash != "" && contentHash != memory.ContentHash) ||
+		(memoryType != "" && memoryType != memory.Type) ||
+		(category != "" && category != memory.Category)
+	if !changed {
+		return nil
+	}
+	return models.NewMemoryRevision(memory)
+}
+
+// saveWithRevision saves an existing memory, recording the revision it replaces in
+// the same transaction when there is one
+func (s *MemoryService) saveWithRevision(ctx context.Context, memory *models.Memory, revision *models.MemoryRevision) error {
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if revision != nil {
+			if err := tx.Create(revision).Error; err != nil {
+				return fmt.Errorf("record revision: %w", err)
+			}
+		}
+
+		if err := tx.Omit("embedding").Where("user_id = ?", memory.UserID).Save(memory).Error; err != nil {
+			return err
+		}
+
+		if revision != nil {
+			keep := tx.Model(&models.MemoryRevision{}).
+				Select("id").
+				Where("memory_id = ?", memory.ID).
+				Order("id DESC").
+				Limit(maxRevisionsPerMemory)
+			if err := tx.Where("memory_id = ? AND id NOT IN (?)", memory.ID, keep).
+				Delete(&models.MemoryRevision{}).Error; err != nil {
+				return fmt.Errorf("prune revisions: %w", err)
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		s.auditAccess(ctx, models.MemoryAccessUpdate, []uint{memory.ID})
+	}
+	return err
+}
+
+// GetMemoryHistory returns a memory with the versions it replaced, newest first,
+// so users can see how a remembered fact evolved
+func (s *MemoryService) GetMemoryHistory(ctx context.Context, memoryID uint) (*MemoryHistory, error) {
+	query := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", memoryID, s.userID)
+	if s.db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding", "tags")
+	} else {
+		query = query.Omit("embedding")
+	}
+
+	var memory models.Memory
+	if err := query.First(&memory).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.WrapNotFoundError("memory", fmt.Sprintf("%d", memoryID))
+		}
+		return nil, utils.WrapDatabaseError("get memory", err)
+	}
+	if err := s.decryptContent(&memory); err != nil {
+		s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+	}
+
+	var revisions []models.MemoryRevision
+	if err := s.db.WithContext(ctx).
+		Where("memory_id = ? AND user_id = ?", memoryID, s.userID).
+		Order("id DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get memory history", err)
+	}
+
+	for i := range revisions {
+		revision := &revisions[i]
+		if !revision.IsEncrypted {
+			continue
+		}
+		version := &models.Memory{ID: revision.MemoryID, IsEncrypted: true, EncryptedContent: revision.EncryptedContent}
+		if err := s.decryptContent(version); err != nil {
+			s.logger.Warn().Err(err).Uint("revision_id", revision.ID).Msg("failed to decrypt revision content")
+			continue
+		}
+		revision.Content = version.Content
+	}
+	s.auditAccess(ctx, models.MemoryAccessRead, []uint{memoryID})
+
+	return &MemoryHistory{
+		MemoryID:  memoryID,
+		Current:   &memory,
+		Revisions: revisions,
+	}, nil
+}
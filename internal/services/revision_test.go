@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestMemoryService_UpdateRecordsRevision(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "Prefers dark mode")
+
+	_, err := service.Update(ctx, memory.ID, UpdateRequest{Content: "Prefers light mode"})
+	require.NoError(t, err)
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Content: "Prefers high contrast mode"})
+	require.NoError(t, err)
+
+	history, err := service.GetMemoryHistory(ctx, memory.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Prefers high contrast mode", history.Current.Content)
+	require.Len(t, history.Revisions, 2)
+	assert.Equal(t, "Prefers light mode", history.Revisions[0].Content)
+	assert.Equal(t, "Prefers dark mode", history.Revisions[1].Content)
+	assert.Equal(t, memory.ID, history.Revisions[0].MemoryID)
+}
+
+func TestMemoryService_UpdateWithoutChangeRecordsNoRevision(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "Works on the billing service")
+
+	_, err := service.Update(ctx, memory.ID, UpdateRequest{Priority: models.PriorityHigh})
+	require.NoError(t, err)
+	_, err = service.Update(ctx, memory.ID, UpdateRequest{Content: "Works on the billing service"})
+	require.NoError(t, err)
+
+	history, err := service.GetMemoryHistory(ctx, memory.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history.Revisions)
+}
+
+func TestMemoryService_StoreWithUpdateKeyRecordsRevision(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+
+	first, err := service.Store(ctx, StoreRequest{
+		Content:   "Favourite editor is vim",
+		Type:      models.TypePreference,
+		Category:  models.CategoryPersonal,
+		UpdateKey: "favourite_editor",
+	})
+	require.NoError(t, err)
+
+	second, err := service.Store(ctx, StoreRequest{
+		Content:   "Favourite editor is helix",
+		Type:      models.TypePreference,
+		Category:  models.CategoryPersonal,
+		UpdateKey: "favourite_editor",
+	})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, second.ID)
+
+	history, err := service.GetMemoryHistory(ctx, first.ID)
+	require.NoError(t, err)
+	require.Len(t, history.Revisions, 1)
+	assert.Equal(t, "Favourite editor is vim", history.Revisions[0].Content)
+	assert.Equal(t, models.TypePreference, history.Revisions[0].Type)
+}
+
+func TestMemoryService_GetMemoryHistoryScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	service := setupMemoryService(t, nil)
+	memory, _ := storeTestMemory(t, service, "Lives in Lisbon")
+
+	other := NewMemoryServiceWithUser(service.db, nil, service.logger, nil, memory.UserID+1)
+	_, err := other.GetMemoryHistory(ctx, memory.ID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
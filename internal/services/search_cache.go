@@ -0,0 +1,628 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// searchCacheTables are the tables whose writes change search results: memories
+// themselves and the feedback that boosts their ranking
+var searchCacheTables = map[string]bool{
+	"memories":        true,
+	"memory_feedback": true,
+}
+
+// accessColumns are written when memories are recalled, which leaves search
+// results as they are
+var accessColumns = map[string]bool{
+	"access_count":     true,
+	"last_accessed_at": true,
+}
+
+// rawSearchWritePattern spots raw SQL that writes to the tables in searchCacheTables
+var rawSearchWritePattern = regexp.MustCompile(`(?is)^\s*(insert\s+into|update|delete\s+from)\s+"?(memories|memory_feedback)"?\b`)
+
+// SearchCacheConfig sizes the search cache
+type SearchCacheConfig struct {
+	// Embeddings is how many query embeddings are kept; 0 keeps none
+	Embeddings int
+	// Results is how many result lists are kept; 0 keeps none
+	Results int
+	// ResultTTL is how long a result list is served; 0 keeps none
+	ResultTTL time.Duration
+}
+
+// DefaultSearchCacheConfig returns the default sizes: 1000 query embeddings and
+// 1000 result lists served for five minutes
+func DefaultSearchCacheConfig() SearchCacheConfig {
+	return SearchCacheConfig{
+		Embeddings: 1000,
+		Results:    1000,
+		ResultTTL:  5 * time.Minute,
+	}
+}
+
+// SearchCacheStats reports how often the search cache answered
+type SearchCacheStats struct {
+	EmbeddingHits   int64 `json:"embedding_hits"`
+	EmbeddingMisses int64 `json:"embedding_misses"`
+	ResultHits      int64 `json:"result_hits"`
+	ResultMisses    int64 `json:"result_misses"`
+	// Invalidations counts the writes that made cached results stale
+	Invalidations int64 `json:"invalidations"`
+	// Embeddings and Results are how many entries are cached now
+	Embeddings int `json:"embeddings"`
+	Results    int `json:"results"`
+}
+
+// SearchCache keeps recent semantic and hybrid searches in memory: the embedding
+// of each query, so repeating a query does not call the embedding provider
+// again, and the IDs each search found, so repeating a search does not run the
+// vector query again. It is shared by every memory service of a process.
+//
+// Result lists expire after the configured TTL and are dropped as soon as the
+// user's memories change. The cache is also a GORM plugin that watches writes to
+// memories and feedback for that; writes it cannot trace to a user drop every
+// user's results. Writes made in a transaction are dropped again once it
+// commits, see MemoryService.transaction.
+type SearchCache struct {
+	config SearchCacheConfig
+
+	mu         sync.Mutex
+	embeddings *lruCache
+	results    *lruCache
+	// generations counts the writes to each user's memories and epoch the writes
+	// traced to no user; results cached in an earlier generation are stale
+	generations map[uint]uint64
+	epoch       uint64
+	// pending collects the invalidations of the transactions being watched, by
+	// the connection they run on
+	pending map[gorm.ConnPool]*pendingInvalidations
+
+	embeddingHits   atomic.Int64
+	embeddingMisses atomic.Int64
+	resultHits      atomic.Int64
+	resultMisses    atomic.Int64
+	invalidations   atomic.Int64
+}
+
+// searchGeneration is the state of a user's memories a result list was found in
+type searchGeneration struct {
+	user  uint64
+	epoch uint64
+}
+
+// cachedHit is one memory of a cached result list
+type cachedHit struct {
+	ID         uint
+	Similarity *float64
+}
+
+// cachedResults is a cached result list
+type cachedResults struct {
+	hits       []cachedHit
+	generation searchGeneration
+	expires    time.Time
+}
+
+// NewSearchCache creates a search cache
+func NewSearchCache(config SearchCacheConfig) *SearchCache {
+	return &SearchCache{
+		config:      config,
+		embeddings:  newLRUCache(config.Embeddings),
+		results:     newLRUCache(config.Results),
+		generations: make(map[uint]uint64),
+		pending:     make(map[gorm.ConnPool]*pendingInvalidations),
+	}
+}
+
+// NewSearchCacheFromConfig creates the search cache from the search_cache
+// configuration, or returns nil when it is disabled
+func NewSearchCacheFromConfig(cfg *config.Config) *SearchCache {
+	if !cfg.SearchCache.Enabled {
+		return nil
+	}
+	return NewSearchCache(SearchCacheConfig{
+		Embeddings: cfg.SearchCache.Embeddings,
+		Results:    cfg.SearchCache.Results,
+		ResultTTL:  cfg.SearchCache.ResultTTL,
+	})
+}
+
+// Name implements gorm.Plugin
+func (c *SearchCache) Name() string {
+	return "search_cache"
+}
+
+// Initialize implements gorm.Plugin by registering the invalidation callbacks
+func (c *SearchCache) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("search_cache:create", c.afterWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("search_cache:update", c.afterWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("search_cache:delete", c.afterWrite); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("search_cache:raw", c.afterRaw)
+}
+
+// Stats returns the cache's hit counts and size
+func (c *SearchCache) Stats() SearchCacheStats {
+	c.mu.Lock()
+	embeddings, results := c.embeddings.len(), c.results.len()
+	c.mu.Unlock()
+
+	return SearchCacheStats{
+		EmbeddingHits:   c.embeddingHits.Load(),
+		EmbeddingMisses: c.embeddingMisses.Load(),
+		ResultHits:      c.resultHits.Load(),
+		ResultMisses:    c.resultMisses.Load(),
+		Invalidations:   c.invalidations.Load(),
+		Embeddings:      embeddings,
+		Results:         results,
+	}
+}
+
+// Invalidate drops the cached results of the user's searches
+func (c *SearchCache) Invalidate(userID uint) {
+	c.mu.Lock()
+	c.generations[userID]++
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+}
+
+// InvalidateAll drops the cached results of every user's searches
+func (c *SearchCache) InvalidateAll() {
+	c.mu.Lock()
+	c.epoch++
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+}
+
+// embedding returns the cached embedding of a query
+func (c *SearchCache) embedding(model, query string) ([]float32, bool) {
+	c.mu.Lock()
+	value, ok := c.embeddings.get(embeddingKey(model, query))
+	c.mu.Unlock()
+
+	if !ok {
+		c.embeddingMisses.Add(1)
+		return nil, false
+	}
+	c.embeddingHits.Add(1)
+	return value.([]float32), true
+}
+
+// storeEmbedding caches the embedding of a query
+func (c *SearchCache) storeEmbedding(model, query string, embedding []float32) {
+	c.mu.Lock()
+	c.embeddings.add(embeddingKey(model, query), embedding)
+	c.mu.Unlock()
+}
+
+// cachedSearch returns the result list cached under key if it is still fresh.
+// Otherwise it returns the generation to store the search's results with, read
+// before searching so a write made meanwhile leaves them stale.
+func (c *SearchCache) cachedSearch(key string, userID uint) ([]cachedHit, searchGeneration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	generation := searchGeneration{user: c.generations[userID], epoch: c.epoch}
+	if value, ok := c.results.get(key); ok {
+		cached := value.(*cachedResults)
+		if cached.generation == generation && time.Now().Before(cached.expires) {
+			c.resultHits.Add(1)
+			return cached.hits, generation, true
+		}
+		c.results.remove(key)
+	}
+	c.resultMisses.Add(1)
+	return nil, generation, false
+}
+
+// storeSearch caches a search's results found in generation
+func (c *SearchCache) storeSearch(key string, generation searchGeneration, memories []*models.Memory) {
+	if c.config.ResultTTL <= 0 {
+		return
+	}
+	hits := make([]cachedHit, len(memories))
+	for i, memory := range memories {
+		hits[i] = cachedHit{ID: memory.ID, Similarity: memory.Similarity}
+	}
+
+	c.mu.Lock()
+	c.results.add(key, &cachedResults{
+		hits:       hits,
+		generation: generation,
+		expires:    time.Now().Add(c.config.ResultTTL),
+	})
+	c.mu.Unlock()
+}
+
+// afterWrite invalidates the results of the users whose memories or feedback a
+// statement wrote. Recording that memories were recalled is not a change.
+func (c *SearchCache) afterWrite(db *gorm.DB) {
+	if db.Error != nil || db.RowsAffected == 0 || !searchCacheTables[db.Statement.Table] || accessOnly(db.Statement) {
+		return
+	}
+	c.invalidateWrite(db.Statement.ConnPool, writtenUsers(db.Statement))
+}
+
+// afterRaw invalidates every user's results after raw SQL writing to memories or
+// feedback, as it cannot be traced to a user
+func (c *SearchCache) afterRaw(db *gorm.DB) {
+	if db.Error != nil || !rawSearchWritePattern.MatchString(db.Statement.SQL.String()) {
+		return
+	}
+	c.invalidateWrite(db.Statement.ConnPool, nil)
+}
+
+// invalidateWrite invalidates the results of the users a write touched, or of
+// every user's when it touched none that can be told. A write made in a watched
+// transaction is invalidated again once the transaction commits.
+func (c *SearchCache) invalidateWrite(pool gorm.ConnPool, users []uint) {
+	c.mu.Lock()
+	if pending, ok := c.pending[pool]; ok {
+		pending.add(users)
+	}
+	c.mu.Unlock()
+
+	if len(users) == 0 {
+		c.InvalidateAll()
+		return
+	}
+	for _, userID := range users {
+		c.Invalidate(userID)
+	}
+}
+
+// watchTransaction collects the invalidations of the writes made in the
+// transaction running on pool. Until it commits, searches still find the rows
+// as they were and may cache them after the writes' own invalidation, so the
+// returned function, called once the transaction ends, repeats them if it
+// committed. A transaction nested in a watched one is left to the outer one.
+func (c *SearchCache) watchTransaction(pool gorm.ConnPool) func(committed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, nested := c.pending[pool]; nested {
+		return func(bool) {}
+	}
+	pending := &pendingInvalidations{users: make(map[uint]bool)}
+	c.pending[pool] = pending
+
+	return func(committed bool) {
+		c.mu.Lock()
+		delete(c.pending, pool)
+		c.mu.Unlock()
+
+		if !committed {
+			return
+		}
+		if pending.all {
+			c.InvalidateAll()
+			return
+		}
+		for userID := range pending.users {
+			c.Invalidate(userID)
+		}
+	}
+}
+
+// pendingInvalidations are the invalidations of a watched transaction's writes
+type pendingInvalidations struct {
+	users map[uint]bool
+	all   bool
+}
+
+// add records the invalidation of the users' results, or of every user's when
+// there are none
+func (p *pendingInvalidations) add(users []uint) {
+	if len(users) == 0 {
+		p.all = true
+		return
+	}
+	for _, userID := range users {
+		p.users[userID] = true
+	}
+}
+
+// accessOnly reports whether an update only sets the access statistics
+func accessOnly(stmt *gorm.Statement) bool {
+	columns, ok := stmt.Dest.(map[string]interface{})
+	if !ok || len(columns) == 0 {
+		return false
+	}
+	for column := range columns {
+		if !accessColumns[column] {
+			return false
+		}
+	}
+	return true
+}
+
+// writtenUsers returns the users a write touched: those of the rows it created
+// or saved, or else the user its conditions are filtered to. It returns nil
+// when the user cannot be told.
+func writtenUsers(stmt *gorm.Statement) []uint {
+	var users []uint
+	if stmt.Schema != nil {
+		if field := stmt.Schema.LookUpField("UserID"); field != nil {
+			collect := func(rv reflect.Value) {
+				rv = reflect.Indirect(rv)
+				if rv.Kind() != reflect.Struct {
+					return
+				}
+				if value, zero := field.ValueOf(stmt.Context, rv); !zero {
+					if userID, ok := value.(uint); ok {
+						users = append(users, userID)
+					}
+				}
+			}
+			switch stmt.ReflectValue.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < stmt.ReflectValue.Len(); i++ {
+					collect(stmt.ReflectValue.Index(i))
+				}
+			case reflect.Struct:
+				collect(stmt.ReflectValue)
+			}
+		}
+	}
+	if len(users) > 0 {
+		return users
+	}
+
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok {
+		if userID, ok := userCondition(where.Exprs); ok {
+			return []uint{userID}
+		}
+	}
+	return nil
+}
+
+// userCondition finds the user_id a list of ANDed conditions requires
+func userCondition(exprs []clause.Expression) (uint, bool) {
+	for _, expr := range exprs {
+		switch e := expr.(type) {
+		case clause.Expr:
+			at := strings.Index(e.SQL, "user_id = ?")
+			if at < 0 || (at > 0 && !strings.ContainsAny(e.SQL[at-1:at], " (")) {
+				continue
+			}
+			if n := strings.Count(e.SQL[:at], "?"); n < len(e.Vars) {
+				if userID, ok := e.Vars[n].(uint); ok {
+					return userID, true
+				}
+			}
+		case clause.Eq:
+			if column, ok := e.Column.(string); ok && column == "user_id" {
+				if userID, ok := e.Value.(uint); ok {
+					return userID, true
+				}
+			}
+		case clause.AndConditions:
+			if userID, ok := userCondition(e.Exprs); ok {
+				return userID, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// embeddingKey keys a query embedding by model and the query's hash, so queries
+// are not kept in memory
+func embeddingKey(model, query string) string {
+	return model + ":" + models.HashContent(query)
+}
+
+// GetSearchCache returns the shared search cache, if one is configured
+func (s *MemoryService) GetSearchCache() *SearchCache {
+	cache, _ := s.config["search_cache"].(*SearchCache)
+	return cache
+}
+
+// transaction runs fn in a transaction, invalidating the search results its
+// writes made stale again once it commits
+func (s *MemoryService) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	cache := s.GetSearchCache()
+	if cache == nil {
+		return s.db.WithContext(ctx).Transaction(fn)
+	}
+
+	var done func(committed bool)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		done = cache.watchTransaction(tx.Statement.ConnPool)
+		return fn(tx)
+	})
+	if done != nil {
+		done(err == nil)
+	}
+	return err
+}
+
+// queryEmbedding embeds a search query, answering repeated queries from the
+// search cache and then the embedding cache
+func (s *MemoryService) queryEmbedding(ctx context.Context, query string) ([]float32, error) {
+	cache := s.GetSearchCache()
+	model := s.EmbeddingModel()
+	if cache != nil && model != "" {
+		if embedding, ok := cache.embedding(model, query); ok {
+			return embedding, nil
+		}
+	}
+
+	embedding, err := s.cachedEmbedder(s.embedderFor(ctx, s.userID, false)).GenerateEmbedding(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil && model != "" {
+		cache.storeEmbedding(model, query, embedding)
+	}
+	return embedding, nil
+}
+
+// cachedSearch answers a search from the search cache, loading the memories it
+// found last time. On a miss it returns the function caching the search's
+// results instead, which is nil when there is no cache.
+func (s *MemoryService) cachedSearch(ctx context.Context, mode string, req SearchRequest) ([]*models.Memory, func([]*models.Memory)) {
+	cache := s.GetSearchCache()
+	if cache == nil {
+		return nil, nil
+	}
+	key, err := s.searchKey(mode, req)
+	if err != nil {
+		s.logger.Debug().Err(err).Msg("search not cacheable")
+		return nil, nil
+	}
+
+	hits, generation, ok := cache.cachedSearch(key, s.userID)
+	store := func(memories []*models.Memory) {
+		cache.storeSearch(key, generation, memories)
+	}
+	if !ok {
+		return nil, store
+	}
+
+	memories, err := s.loadHits(ctx, hits)
+	if err != nil {
+		s.logger.Debug().Err(err).Msg("failed to load cached search results, searching again")
+		return nil, store
+	}
+	for _, memory := range memories {
+		if err := s.decryptContent(memory); err != nil {
+			s.logger.Warn().Err(err).Uint("id", memory.ID).Msg("failed to decrypt memory content")
+		}
+	}
+	if !req.ReadOnly {
+		s.recordAccess(ctx, memories)
+	}
+	s.auditReads(ctx, memories)
+	s.logger.Debug().Str("mode", mode).Int("results_count", len(memories)).Msg("search answered from cache")
+	return memories, nil
+}
+
+// searchKey keys a search by user, workspace, mode and request
+func (s *MemoryService) searchKey(mode string, req SearchRequest) (string, error) {
+	req.ReadOnly = false
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	workspace := "all"
+	if workspaceID, ok := database.Workspace(s.db); ok {
+		workspace = fmt.Sprint(workspaceID)
+	}
+	return fmt.Sprintf("%d:%s:%s:%s", s.userID, workspace, mode, models.HashContent(string(encoded))), nil
+}
+
+// loadHits loads the memories of a cached result list in its order, leaving out
+// any deleted since
+func (s *MemoryService) loadHits(ctx context.Context, hits []cachedHit) ([]*models.Memory, error) {
+	memories := make([]*models.Memory, 0, len(hits))
+	if len(hits) == 0 {
+		return memories, nil
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var found []*models.Memory
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND id IN ?", s.userID, ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Memory, len(found))
+	for _, memory := range found {
+		byID[memory.ID] = memory
+	}
+	for _, hit := range hits {
+		if memory, ok := byID[hit.ID]; ok {
+			memory.Similarity = hit.Similarity
+			memories = append(memories, memory)
+		}
+	}
+	return memories, nil
+}
+
+// lruCache is a fixed-size map evicting the least recently used entry; callers
+// synchronize access
+type lruCache struct {
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// lruEntry is an entry of an lruCache
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// newLRUCache creates an LRU cache holding up to capacity entries
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the value under key, marking it as recently used
+func (c *lruCache) get(key string) (interface{}, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+// add stores value under key, evicting the least recently used entry when full
+func (c *lruCache) add(key string, value interface{}) {
+	if c.capacity <= 0 {
+		return
+	}
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// remove drops the entry under key
+func (c *lruCache) remove(key string) {
+	if element, ok := c.items[key]; ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+}
+
+// len returns how many entries are cached
+func (c *lruCache) len() int {
+	return c.order.Len()
+}
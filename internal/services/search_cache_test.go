@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestSearchCache(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T, config SearchCacheConfig) (*MemoryService, *SearchCache) {
+		cache := NewSearchCache(config)
+		db := setupTestDB(t)
+		require.NoError(t, db.Use(cache))
+		return NewMemoryService(db, nil, zerolog.Nop(), map[string]interface{}{"search_cache": cache}), cache
+	}
+	store := func(t *testing.T, service *MemoryService, content string) *models.Memory {
+		memory, err := service.Store(ctx, StoreRequest{Content: content, Category: models.CategoryPersonal, Type: models.TypeFact})
+		require.NoError(t, err)
+		return memory
+	}
+	// search caches the given results for req and returns whatever the cache then answers
+	search := func(service *MemoryService, req SearchRequest, results []*models.Memory) []*models.Memory {
+		cached, cacheResults := service.cachedSearch(ctx, SearchModeSemantic, req)
+		if cached == nil && cacheResults != nil {
+			cacheResults(results)
+		}
+		return cached
+	}
+
+	t.Run("repeated searches are answered until memories change", func(t *testing.T) {
+		service, cache := setup(t, DefaultSearchCacheConfig())
+		first := store(t, service, "likes green tea")
+		second := store(t, service, "drinks coffee in the morning")
+		similarity := 0.9
+		second.Similarity = &similarity
+		req := SearchRequest{Query: "drinks", Limit: 5}
+
+		assert.Nil(t, search(service, req, []*models.Memory{second, first}))
+		cached := search(service, req, nil)
+		require.Len(t, cached, 2)
+		assert.Equal(t, second.ID, cached[0].ID, "results keep their order")
+		assert.Equal(t, "drinks coffee in the morning", cached[0].Content)
+		require.NotNil(t, cached[0].Similarity)
+		assert.Equal(t, 0.9, *cached[0].Similarity)
+		assert.Nil(t, search(service, SearchRequest{Query: "drinks", Limit: 10}, nil), "other requests are cached apart")
+
+		// Recalling memories leaves results cached
+		service.recordAccess(ctx, cached)
+		assert.NotNil(t, search(service, req, nil))
+
+		// Another user's writes leave them cached too
+		other := NewMemoryServiceWithUser(service.db, nil, zerolog.Nop(), service.config, 2)
+		store(t, other, "someone else's memory")
+		assert.NotNil(t, search(service, req, nil))
+
+		store(t, service, "drinks tea in the afternoon")
+		assert.Nil(t, search(service, req, []*models.Memory{second}), "a new memory invalidates the results")
+		assert.Len(t, search(service, req, nil), 1)
+
+		require.NoError(t, service.Delete(ctx, first.ID))
+		assert.Nil(t, search(service, req, nil), "a deletion invalidates the results")
+
+		stats := cache.Stats()
+		assert.Equal(t, int64(4), stats.ResultHits)
+		assert.Equal(t, int64(4), stats.ResultMisses)
+		assert.Positive(t, stats.Invalidations)
+	})
+
+	t.Run("raw writes invalidate every user", func(t *testing.T) {
+		service, _ := setup(t, DefaultSearchCacheConfig())
+		memory := store(t, service, "likes green tea")
+		req := SearchRequest{Query: "tea"}
+		search(service, req, []*models.Memory{memory})
+
+		require.NoError(t, service.db.Exec("UPDATE memories SET category = ?", models.CategoryProject).Error)
+		assert.Nil(t, search(service, req, nil))
+	})
+
+	t.Run("results found while a transaction is open are stale once it commits", func(t *testing.T) {
+		service, _ := setup(t, DefaultSearchCacheConfig())
+		memory := store(t, service, "likes green tea")
+		req := SearchRequest{Query: "tea"}
+
+		err := service.transaction(ctx, func(tx *gorm.DB) error {
+			require.NoError(t, tx.Model(memory).Update("category", models.CategoryProject).Error)
+			// A search outside the transaction still finds the memory as it was
+			assert.Nil(t, search(service, req, []*models.Memory{memory}))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, search(service, req, nil), "the commit invalidates the results")
+	})
+
+	t.Run("rolled back transactions are not invalidated again", func(t *testing.T) {
+		service, cache := setup(t, DefaultSearchCacheConfig())
+		memory := store(t, service, "likes green tea")
+		req := SearchRequest{Query: "tea"}
+
+		err := service.transaction(ctx, func(tx *gorm.DB) error {
+			require.NoError(t, tx.Model(memory).Update("category", models.CategoryProject).Error)
+			search(service, req, []*models.Memory{memory})
+			return errors.New("rolled back")
+		})
+		require.Error(t, err)
+		assert.NotNil(t, search(service, req, nil))
+		assert.Empty(t, cache.pending)
+	})
+
+	t.Run("results expire", func(t *testing.T) {
+		service, _ := setup(t, SearchCacheConfig{Results: 10, ResultTTL: time.Nanosecond})
+		memory := store(t, service, "likes green tea")
+		req := SearchRequest{Query: "tea"}
+		search(service, req, []*models.Memory{memory})
+
+		time.Sleep(time.Millisecond)
+		assert.Nil(t, search(service, req, nil))
+	})
+
+	t.Run("query embeddings are reused", func(t *testing.T) {
+		provider := &textRecordingEmbeddingService{}
+		cache := NewSearchCache(SearchCacheConfig{Embeddings: 1})
+		service := NewMemoryService(setupTestDB(t), provider, zerolog.Nop(), map[string]interface{}{"search_cache": cache})
+
+		for _, query := range []string{"tea", "tea", "coffee", "tea"} {
+			_, err := service.queryEmbedding(ctx, query)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"tea", "coffee", "tea"}, provider.texts, "the least recently used query is evicted")
+
+		stats := cache.Stats()
+		assert.Equal(t, int64(1), stats.EmbeddingHits)
+		assert.Equal(t, int64(3), stats.EmbeddingMisses)
+		assert.Equal(t, 1, stats.Embeddings)
+	})
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// CurrentSession names the user's open session wherever a session ID is taken
+	CurrentSession = "current"
+
+	maxSessionIDLength   = 128
+	maxSessionNameLength = 255
+	// DefaultSessionListLimit caps ListSessions when no limit is given
+	DefaultSessionListLimit = 50
+)
+
+// SessionSummary is a working session with how many memories were captured in it
+type SessionSummary struct {
+	models.MemorySession
+	MemoryCount int64 `json:"memory_count"`
+}
+
+// SessionPurge reports the memories deleted with a session. Critical memories
+// are kept; they can only be deleted one at a time with confirmation.
+type SessionPurge struct {
+	SessionID    string `json:"session_id"`
+	Deleted      int64  `json:"deleted"`
+	KeptCritical int64  `json:"kept_critical,omitempty"`
+}
+
+// EndSessionResult is the session that was ended and, when it was purged, what
+// was deleted
+type EndSessionResult struct {
+	Session *SessionSummary `json:"session"`
+	Purge   *SessionPurge   `json:"purge,omitempty"`
+}
+
+// ActiveSession returns the user's open session, or nil when none is open
+func (s *MemoryService) ActiveSession(ctx context.Context) (*models.MemorySession, error) {
+	var sessions []models.MemorySession
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND ended_at IS NULL", s.userID).
+		Order("started_at DESC").
+		Limit(1).
+		Find(&sessions).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get active session", err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return &sessions[0], nil
+}
+
+// StartSession opens a working session, ending any session still open. Memories
+// stored while it is open are captured in it. An empty sessionID generates one;
+// clients may instead reuse their own conversation ID.
+func (s *MemoryService) StartSession(ctx context.Context, sessionID, name string) (*models.MemorySession, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		generated, err := newSessionID()
+		if err != nil {
+			return nil, err
+		}
+		sessionID = generated
+	}
+	if sessionID == CurrentSession {
+		return nil, utils.InvalidFieldError("session_id", "current names the open session and cannot be used as an ID")
+	}
+	if err := validateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	if len(name) > maxSessionNameLength {
+		return nil, utils.InvalidFieldError("name", fmt.Sprintf("must be at most %d characters", maxSessionNameLength))
+	}
+
+	session := &models.MemorySession{
+		UserID:    s.userID,
+		SessionID: sessionID,
+		Name:      strings.TrimSpace(name),
+		StartedAt: time.Now().UTC(),
+	}
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.MemorySession{}).
+			Where("user_id = ? AND session_id = ?", s.userID, sessionID).
+			Count(&existing).Error; err != nil {
+			return utils.WrapDatabaseError("check session", err)
+		}
+		if existing > 0 {
+			return utils.WrapConflictError("session", "session_id", sessionID)
+		}
+
+		if err := tx.Model(&models.MemorySession{}).
+			Where("user_id = ? AND ended_at IS NULL", s.userID).
+			Update("ended_at", session.StartedAt).Error; err != nil {
+			return utils.WrapDatabaseError("end previous session", err)
+		}
+		if err := tx.Create(session).Error; err != nil {
+			return utils.WrapDatabaseError("start session", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("session_id", sessionID).Msg("failed to start session")
+		return nil, err
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Str("session_id", sessionID).Msg("session started")
+	return session, nil
+}
+
+// EndSession ends the user's open session. With purge its memories are deleted
+// too, apart from critical ones.
+func (s *MemoryService) EndSession(ctx context.Context, purge bool) (*EndSessionResult, error) {
+	session, err := s.ActiveSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, utils.WrapNotFoundError("session", CurrentSession)
+	}
+
+	now := time.Now().UTC()
+	if err := s.db.WithContext(ctx).Model(session).Update("ended_at", now).Error; err != nil {
+		s.logger.Error().Err(err).Str("session_id", session.SessionID).Msg("failed to end session")
+		return nil, utils.WrapDatabaseError("end session", err)
+	}
+	session.EndedAt = &now
+
+	result := &EndSessionResult{Session: &SessionSummary{MemorySession: *session}}
+	if purge {
+		if result.Purge, err = s.PurgeSession(ctx, session.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("user_id = ? AND session_id = ?", s.userID, session.SessionID).
+		Count(&result.Session.MemoryCount).Error; err != nil {
+		return nil, utils.WrapDatabaseError("count session memories", err)
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Str("session_id", session.SessionID).Bool("purged", purge).Msg("session ended")
+	return result, nil
+}
+
+// ListSessions returns the user's sessions, most recently started first
+func (s *MemoryService) ListSessions(ctx context.Context, limit int) ([]SessionSummary, error) {
+	if limit <= 0 || limit > DefaultSessionListLimit {
+		limit = DefaultSessionListLimit
+	}
+
+	var sessions []models.MemorySession
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("started_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(&sessions).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list sessions", err)
+	}
+	if len(sessions) == 0 {
+		return []SessionSummary{}, nil
+	}
+
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.SessionID
+	}
+	var counts []struct {
+		SessionID string
+		Count     int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Select("session_id, COUNT(*) AS count").
+		Where("user_id = ? AND session_id IN ?", s.userID, ids).
+		Group("session_id").
+		Scan(&counts).Error; err != nil {
+		return nil, utils.WrapDatabaseError("count session memories", err)
+	}
+	countBySession := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		countBySession[count.SessionID] = count.Count
+	}
+
+	summaries := make([]SessionSummary, len(sessions))
+	for i, session := range sessions {
+		summaries[i] = SessionSummary{MemorySession: session, MemoryCount: countBySession[session.SessionID]}
+	}
+	return summaries, nil
+}
+
+// PurgeSession deletes the memories captured in a session, which need not have
+// been started with StartSession. Critical memories are kept and counted.
+func (s *MemoryService) PurgeSession(ctx context.Context, sessionID string) (*SessionPurge, error) {
+	sessionID, err := s.resolveSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID == "" {
+		return nil, utils.InvalidFieldError("session_id", "is required")
+	}
+
+	purge := &SessionPurge{SessionID: sessionID}
+	purged := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("user_id = ? AND session_id = ? AND priority <> ?", s.userID, sessionID, models.PriorityCritical)
+	// The IDs are only needed for the access audit, so only read when it records
+	var purgedIDs []uint
+	if AccessSourceFromContext(ctx) != "" {
+		if err := purged.Session(&gorm.Session{}).Pluck("id", &purgedIDs).Error; err != nil {
+			return nil, utils.WrapDatabaseError("purge session", err)
+		}
+	}
+	attachmentKeys := s.attachmentObjectKeys(ctx, "memory_id IN (?)", purged.Session(&gorm.Session{}).Select("id"))
+	result := purged.Delete(&models.Memory{})
+	if result.Error != nil {
+		s.logger.Error().Err(result.Error).Str("session_id", sessionID).Msg("failed to purge session")
+		return nil, utils.WrapDatabaseError("purge session", result.Error)
+	}
+	purge.Deleted = result.RowsAffected
+	s.removeAttachmentObjects(ctx, attachmentKeys)
+	s.auditAccess(ctx, models.MemoryAccessDelete, purgedIDs)
+
+	if err := s.db.WithContext(ctx).Model(&models.Memory{}).
+		Where("user_id = ? AND session_id = ?", s.userID, sessionID).
+		Count(&purge.KeptCritical).Error; err != nil {
+		return nil, utils.WrapDatabaseError("count kept memories", err)
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Str("session_id", sessionID).Int64("deleted", purge.Deleted).Msg("session purged")
+	return purge, nil
+}
+
+// resolveSession turns CurrentSession into the open session's ID and validates
+// any other session ID
+func (s *MemoryService) resolveSession(ctx context.Context, sessionID string) (string, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID != CurrentSession {
+		if sessionID == "" {
+			return "", nil
+		}
+		return sessionID, validateSessionID(sessionID)
+	}
+
+	session, err := s.ActiveSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	if session == nil {
+		return "", utils.WrapNotFoundError("session", CurrentSession)
+	}
+	return session.SessionID, nil
+}
+
+// captureSession returns the session a new memory is captured in: the one the
+// store names, or else the open session. Failing to find the open session does
+// not fail the store.
+func (s *MemoryService) captureSession(ctx context.Context, sessionID string) (string, error) {
+	if strings.TrimSpace(sessionID) != "" {
+		return s.resolveSession(ctx, sessionID)
+	}
+	session, err := s.ActiveSession(ctx)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to load active session, storing without one")
+		return "", nil
+	}
+	if session == nil {
+		return "", nil
+	}
+	return session.SessionID, nil
+}
+
+// validateSessionID bounds session IDs to what the column holds
+func validateSessionID(sessionID string) error {
+	if len(sessionID) > maxSessionIDLength {
+		return utils.InvalidFieldError("session_id", fmt.Sprintf("must be at most %d characters", maxSessionIDLength))
+	}
+	return nil
+}
+
+// newSessionID returns a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("failed to generate session ID")
+	}
+	return "session-" + hex.EncodeToString(b), nil
+}
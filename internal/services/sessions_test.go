@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_Sessions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("memories stored while a session is open are captured in it", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		before, _ := storeTestMemory(t, service, "standup notes from monday")
+
+		session, err := service.StartSession(ctx, "", "refactor auth")
+		require.NoError(t, err)
+		assert.NotEmpty(t, session.SessionID)
+		assert.True(t, session.IsOpen())
+
+		during, _ := storeTestMemory(t, service, "standup notes about the auth refactor")
+		assert.Equal(t, session.SessionID, during.SessionID)
+		assert.Empty(t, before.SessionID)
+
+		memories, err := service.Search(ctx, SearchRequest{Query: "standup", Session: CurrentSession})
+		require.NoError(t, err)
+		require.Len(t, memories, 1)
+		assert.Equal(t, during.ID, memories[0].ID)
+
+		page, err := service.SearchPage(ctx, SearchRequest{Query: "*", Session: session.SessionID}, "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), page.TotalCount)
+	})
+
+	t.Run("starting a session ends the open one", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		first, err := service.StartSession(ctx, "conversation-1", "")
+		require.NoError(t, err)
+		second, err := service.StartSession(ctx, "conversation-2", "")
+		require.NoError(t, err)
+
+		active, err := service.ActiveSession(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, second.SessionID, active.SessionID)
+
+		sessions, err := service.ListSessions(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.Equal(t, second.SessionID, sessions[0].SessionID)
+		assert.Equal(t, first.SessionID, sessions[1].SessionID)
+		assert.False(t, sessions[1].IsOpen())
+
+		_, err = service.StartSession(ctx, "conversation-1", "")
+		assert.True(t, utils.IsConflictError(err))
+		_, err = service.StartSession(ctx, CurrentSession, "")
+		assert.True(t, utils.IsValidationError(err))
+	})
+
+	t.Run("ending with purge keeps critical memories", func(t *testing.T) {
+		service := setupMemoryService(t, nil)
+		_, err := service.StartSession(ctx, "scratch", "")
+		require.NoError(t, err)
+		storeTestMemory(t, service, "temporary hypothesis")
+		critical, err := service.Store(ctx, StoreRequest{
+			Content:  "production database password rotates on fridays",
+			Category: models.CategoryProject,
+			Type:     models.TypeFact,
+			Priority: models.PriorityCritical,
+		})
+		require.NoError(t, err)
+
+		result, err := service.EndSession(ctx, true)
+		require.NoError(t, err)
+		assert.False(t, result.Session.IsOpen())
+		require.NotNil(t, result.Purge)
+		assert.Equal(t, int64(1), result.Purge.Deleted)
+		assert.Equal(t, int64(1), result.Purge.KeptCritical)
+		assert.Equal(t, int64(1), result.Session.MemoryCount)
+
+		remaining, err := service.GetByID(ctx, critical.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "scratch", remaining.SessionID)
+
+		_, err = service.EndSession(ctx, false)
+		assert.True(t, utils.IsNotFoundError(err))
+		_, err = service.Search(ctx, SearchRequest{Query: "*", Session: CurrentSession})
+		assert.True(t, utils.IsNotFoundError(err))
+	})
+}
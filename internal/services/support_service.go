@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+const (
+	// DefaultSupportAccessDuration is how long a support grant lasts when none is requested
+	DefaultSupportAccessDuration = 24 * time.Hour
+	// MaxSupportAccessDuration caps how long a user can grant support access for
+	MaxSupportAccessDuration = 7 * 24 * time.Hour
+)
+
+// ErrInvalidSupportToken is returned when a support token is unknown, expired or revoked
+var ErrInvalidSupportToken = errors.New("invalid or expired support access token")
+
+// SupportService handles support-access consent grants and the admin memory lookup
+// that depends on them. Lookups never expose content unless the owning user has
+// issued a still-valid grant.
+type SupportService struct {
+	db         *gorm.DB
+	encryption *utils.EncryptionService
+	logger     zerolog.Logger
+}
+
+// NewSupportService creates a new SupportService
+func NewSupportService(db *gorm.DB, encryption *utils.EncryptionService, logger zerolog.Logger) *SupportService {
+	return &SupportService{
+		db:         db,
+		encryption: encryption,
+		logger:     logger,
+	}
+}
+
+// MemoryLookupRequest identifies the memory being looked up and, optionally,
+// the consent token that allows its content to be revealed
+type MemoryLookupRequest struct {
+	MemoryID     uint
+	ContentHash  string
+	SupportToken string
+}
+
+// MemoryLookupMatch describes a memory found by an admin lookup. Content is only
+// populated when a valid support token from the owner was supplied.
+type MemoryLookupMatch struct {
+	MemoryID        uint      `json:"memory_id"`
+	OwnerID         uint      `json:"owner_id"`
+	OwnerEmail      string    `json:"owner_email"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	ContentRevealed bool      `json:"content_revealed"`
+	Content         string    `json:"content,omitempty"`
+	Category        string    `json:"category,omitempty"`
+	Type            string    `json:"type,omitempty"`
+}
+
+// MemoryLookupResult is the response to an admin lookup
+type MemoryLookupResult struct {
+	Found   bool                `json:"found"`
+	Matches []MemoryLookupMatch `json:"matches"`
+}
+
+// GrantAccess issues a new support-access token for the user. The plaintext token
+// is returned once and never stored.
+func (s *SupportService) GrantAccess(ctx context.Context, userID uint, duration time.Duration, reason string) (*models.SupportAccessGrant, string, error) {
+	if duration == 0 {
+		duration = DefaultSupportAccessDuration
+	}
+	if duration < time.Minute || duration > MaxSupportAccessDuration {
+		return nil, "", utils.InvalidFieldError("duration", fmt.Sprintf("must be between 1m and %s", MaxSupportAccessDuration))
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate support token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	grant := &models.SupportAccessGrant{
+		UserID:    userID,
+		TokenHash: hashSupportToken(token),
+		Reason:    reason,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if err := s.db.WithContext(ctx).Create(grant).Error; err != nil {
+		s.logger.Error().Err(err).Uint("user_id", userID).Msg("failed to create support access grant")
+		return nil, "", utils.WrapDatabaseError("create support access grant", err)
+	}
+
+	s.logger.Info().
+		Uint("user_id", userID).
+		Uint("grant_id", grant.ID).
+		Time("expires_at", grant.ExpiresAt).
+		Msg("support access granted")
+
+	return grant, token, nil
+}
+
+// ListGrants returns the user's support-access grants, newest first
+func (s *SupportService) ListGrants(ctx context.Context, userID uint) ([]models.SupportAccessGrant, error) {
+	var grants []models.SupportAccessGrant
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&grants).Error; err != nil {
+		return nil, utils.WrapDatabaseError("list support access grants", err)
+	}
+
+	return grants, nil
+}
+
+// RevokeGrant immediately invalidates a support-access grant
+func (s *SupportService) RevokeGrant(ctx context.Context, userID, grantID uint) error {
+	result := s.db.WithContext(ctx).
+		Model(&models.SupportAccessGrant{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", grantID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return utils.WrapDatabaseError("revoke support access grant", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.WrapNotFoundError("support access grant", fmt.Sprintf("%d", grantID))
+	}
+
+	return nil
+}
+
+// LookupMemory finds memories across all users by ID or content hash. Only
+// existence, owner and timestamps are returned, unless the request carries a valid
+// support token, in which case content is revealed for memories owned by the
+// user who issued it. Every reveal is recorded in the owner's access audit log as
+// support access by the actor set with WithSupportActor. Where content is
+// encrypted, content hashes are keyed per user, so lookups by hash are refused.
+func (s *SupportService) LookupMemory(ctx context.Context, req *MemoryLookupRequest) (*MemoryLookupResult, error) {
+	if req.MemoryID == 0 && req.ContentHash == "" {
+		return nil, utils.WrapValidationError("", "either memory ID or content hash is required")
+	}
+
+	contentHash := strings.ToLower(strings.TrimSpace(req.ContentHash))
+	if contentHash != "" {
+		if s.encryption != nil {
+			return nil, utils.InvalidFieldError("hash", "lookups by content hash are not available when content is encrypted")
+		}
+		if _, err := hex.DecodeString(contentHash); err != nil || len(contentHash) != sha256.Size*2 {
+			return nil, utils.InvalidFieldError("hash", "must be a hex-encoded SHA-256 digest")
+		}
+	}
+
+	var grant *models.SupportAccessGrant
+	if req.SupportToken != "" {
+		var err error
+		grant, err = s.findActiveGrant(ctx, req.SupportToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := s.db.WithContext(ctx).
+		Model(&models.Memory{}).
+		Select("id", "user_id", "type", "category", "content", "encrypted_content", "is_encrypted", "created_at", "updated_at")
+	if req.MemoryID != 0 {
+		query = query.Where("id = ?", req.MemoryID)
+	}
+	if contentHash != "" {
+		query = query.Where("content_hash = ?", contentHash)
+	}
+
+	var memories []models.Memory
+	if err := query.Order("id ASC").Limit(100).Find(&memories).Error; err != nil {
+		return nil, utils.WrapDatabaseError("lookup memory", err)
+	}
+
+	result := &MemoryLookupResult{
+		Found:   len(memories) > 0,
+		Matches: make([]MemoryLookupMatch, 0, len(memories)),
+	}
+	if len(memories) == 0 {
+		return result, nil
+	}
+
+	owners, err := s.ownerEmails(ctx, memories)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range memories {
+		memory := &memories[i]
+		match := MemoryLookupMatch{
+			MemoryID:   memory.ID,
+			OwnerID:    memory.UserID,
+			OwnerEmail: owners[memory.UserID],
+			CreatedAt:  memory.CreatedAt,
+			UpdatedAt:  memory.UpdatedAt,
+		}
+
+		if grant != nil && grant.UserID == memory.UserID {
+			if memory.IsEncrypted && len(memory.EncryptedContent) > 0 {
+				if err := s.decrypt(ctx, memory); err != nil {
+					s.logger.Error().Err(err).Uint("memory_id", memory.ID).Msg("failed to decrypt memory for support lookup")
+					result.Matches = append(result.Matches, match)
+					continue
+				}
+			}
+			match.ContentRevealed = true
+			match.Content = memory.Content
+			match.Category = memory.Category
+			match.Type = memory.Type
+		}
+
+		result.Matches = append(result.Matches, match)
+	}
+	s.auditReveals(ctx, result.Matches)
+
+	return result, nil
+}
+
+// auditReveals records the memories whose content a lookup revealed as reads in
+// their owners' access audit logs. Support access is recorded whether or not the
+// owner has the audit log turned on.
+func (s *SupportService) auditReveals(ctx context.Context, matches []MemoryLookupMatch) {
+	source := AccessSourceFromContext(ctx)
+	if source == "" {
+		source = AccessSourceHTTP
+	}
+	actor, _ := ctx.Value(accessActorKey{}).(accessActor)
+	var entries []models.MemoryAccessLog
+	for _, match := range matches {
+		if match.ContentRevealed {
+			entries = append(entries, models.MemoryAccessLog{
+				UserID:    match.OwnerID,
+				MemoryID:  match.MemoryID,
+				Action:    models.MemoryAccessRead,
+				Source:    source,
+				ActorID:   actor.id,
+				ActorRole: models.MemoryAccessSupport,
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := s.db.WithContext(ctx).Create(&entries).Error; err != nil {
+		s.logger.Error().Err(err).Int("count", len(entries)).Msg("failed to record support memory access audit")
+	}
+}
+
+// AuthorizeImpersonation checks that token is an active grant issued by userID,
+// which lets an admin run read-only searches as that user until the grant expires
+// or is revoked
+func (s *SupportService) AuthorizeImpersonation(ctx context.Context, userID uint, token string) (*models.SupportAccessGrant, error) {
+	if token == "" {
+		return nil, ErrInvalidSupportToken
+	}
+	grant, err := s.findActiveGrant(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if grant.UserID != userID {
+		return nil, ErrInvalidSupportToken
+	}
+
+	return grant, nil
+}
+
+// findActiveGrant resolves a plaintext support token to a usable grant
+func (s *SupportService) findActiveGrant(ctx context.Context, token string) (*models.SupportAccessGrant, error) {
+	var grant models.SupportAccessGrant
+	if err := s.db.WithContext(ctx).
+		Where("token_hash = ?", hashSupportToken(token)).
+		First(&grant).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidSupportToken
+		}
+		return nil, utils.WrapDatabaseError("find support access grant", err)
+	}
+
+	if !grant.IsActive(time.Now()) {
+		return nil, ErrInvalidSupportToken
+	}
+
+	return &grant, nil
+}
+
+// ownerEmails maps the owners of the given memories to their email addresses
+func (s *SupportService) ownerEmails(ctx context.Context, memories []models.Memory) (map[uint]string, error) {
+	ids := make([]uint, 0, len(memories))
+	seen := make(map[uint]bool, len(memories))
+	for _, memory := range memories {
+		if !seen[memory.UserID] {
+			seen[memory.UserID] = true
+			ids = append(ids, memory.UserID)
+		}
+	}
+
+	var users []models.User
+	if err := s.db.WithContext(ctx).Unscoped().Select("id", "email").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, utils.WrapDatabaseError("lookup memory owners", err)
+	}
+
+	emails := make(map[uint]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+
+	return emails, nil
+}
+
+// decrypt decrypts a memory's content with its owner's data key
+func (s *SupportService) decrypt(ctx context.Context, memory *models.Memory) error {
+	if s.encryption == nil {
+		return decryptMemoryContent(nil, memory, memory.UserID)
+	}
+	dataKey, err := userDataKey(ctx, s.db, s.encryption, memory.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load data key: %w", err)
+	}
+	return decryptWithDataKey(s.encryption, dataKey, memory, memory.UserID)
+}
+
+func hashSupportToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
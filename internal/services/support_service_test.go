@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// setupSupportService creates a SupportService over an in-memory database with two
+// users who each own one memory
+func setupSupportService(t *testing.T) (*SupportService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.SupportAccessGrant{}, &models.MemoryAccessLog{}))
+	require.NoError(t, db.Exec(`
+		CREATE TABLE memories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			type TEXT NOT NULL,
+			category TEXT NOT NULL,
+			content TEXT NOT NULL,
+			encrypted_content TEXT,
+			is_encrypted BOOLEAN DEFAULT FALSE,
+			content_hash TEXT,
+			content_index TEXT,
+			keyword_index TEXT,
+			created_at DATETIME,
+			updated_at DATETIME
+		)
+	`).Error)
+
+	for _, user := range []models.User{
+		{ID: 2, Email: "alice@example.com", Password: "x"},
+		{ID: 3, Email: "bob@example.com", Password: "x"},
+	} {
+		require.NoError(t, db.Create(&user).Error)
+	}
+
+	now := time.Now()
+	for _, m := range []struct {
+		userID  uint
+		content string
+	}{
+		{2, "alice likes tea"},
+		{3, "bob likes coffee"},
+	} {
+		require.NoError(t, db.Exec(
+			"INSERT INTO memories (user_id, type, category, content, content_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			m.userID, models.TypePreference, models.CategoryPersonal, m.content, models.HashContent(m.content), now, now,
+		).Error)
+	}
+
+	return NewSupportService(db, nil, zerolog.New(nil).Level(zerolog.Disabled)), db
+}
+
+func TestSupportService_LookupMemory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Returns metadata only without a token", func(t *testing.T) {
+		service, _ := setupSupportService(t)
+
+		result, err := service.LookupMemory(ctx, &MemoryLookupRequest{MemoryID: 1})
+		require.NoError(t, err)
+		require.True(t, result.Found)
+		require.Len(t, result.Matches, 1)
+
+		match := result.Matches[0]
+		assert.Equal(t, uint(2), match.OwnerID)
+		assert.Equal(t, "alice@example.com", match.OwnerEmail)
+		assert.False(t, match.ContentRevealed)
+		assert.Empty(t, match.Content)
+		assert.Empty(t, match.Category)
+	})
+
+	t.Run("Finds by content hash", func(t *testing.T) {
+		service, _ := setupSupportService(t)
+
+		result, err := service.LookupMemory(ctx, &MemoryLookupRequest{ContentHash: models.HashContent("bob likes coffee")})
+		require.NoError(t, err)
+		require.Len(t, result.Matches, 1)
+		assert.Equal(t, uint(3), result.Matches[0].OwnerID)
+
+		result, err = service.LookupMemory(ctx, &MemoryLookupRequest{ContentHash: models.HashContent("nobody")})
+		require.NoError(t, err)
+		assert.False(t, result.Found)
+		assert.Empty(t, result.Matches)
+	})
+
+	t.Run("Reveals content only for the granting user", func(t *testing.T) {
+		service, db := setupSupportService(t)
+
+		_, token, err := service.GrantAccess(ctx, 2, time.Hour, "ticket")
+		require.NoError(t, err)
+
+		result, err := service.LookupMemory(WithSupportActor(ctx, 9), &MemoryLookupRequest{MemoryID: 1, SupportToken: token})
+		require.NoError(t, err)
+		require.Len(t, result.Matches, 1)
+		assert.True(t, result.Matches[0].ContentRevealed)
+		assert.Equal(t, "alice likes tea", result.Matches[0].Content)
+
+		result, err = service.LookupMemory(ctx, &MemoryLookupRequest{MemoryID: 2, SupportToken: token})
+		require.NoError(t, err)
+		require.Len(t, result.Matches, 1)
+		assert.False(t, result.Matches[0].ContentRevealed)
+		assert.Empty(t, result.Matches[0].Content)
+
+		// Only the reveal is audited, as the admin's support access
+		var entries []models.MemoryAccessLog
+		require.NoError(t, db.Find(&entries).Error)
+		require.Len(t, entries, 1)
+		assert.Equal(t, uint(2), entries[0].UserID)
+		assert.Equal(t, uint(1), entries[0].MemoryID)
+		assert.Equal(t, models.MemoryAccessRead, entries[0].Action)
+		assert.Equal(t, uint(9), entries[0].ActorID)
+		assert.Equal(t, models.MemoryAccessSupport, entries[0].ActorRole)
+	})
+
+	t.Run("Rejects revoked and expired tokens", func(t *testing.T) {
+		service, db := setupSupportService(t)
+
+		grant, token, err := service.GrantAccess(ctx, 2, time.Hour, "")
+		require.NoError(t, err)
+		require.NoError(t, service.RevokeGrant(ctx, 2, grant.ID))
+
+		_, err = service.LookupMemory(ctx, &MemoryLookupRequest{MemoryID: 1, SupportToken: token})
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+
+		grant, token, err = service.GrantAccess(ctx, 2, time.Hour, "")
+		require.NoError(t, err)
+		require.NoError(t, db.Model(grant).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+		_, err = service.LookupMemory(ctx, &MemoryLookupRequest{MemoryID: 1, SupportToken: token})
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+
+		_, err = service.LookupMemory(ctx, &MemoryLookupRequest{MemoryID: 1, SupportToken: "bogus"})
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+	})
+
+	t.Run("Authorizes impersonation only of the granting user", func(t *testing.T) {
+		service, _ := setupSupportService(t)
+
+		grant, token, err := service.GrantAccess(ctx, 2, time.Hour, "recall bug")
+		require.NoError(t, err)
+
+		authorized, err := service.AuthorizeImpersonation(ctx, 2, token)
+		require.NoError(t, err)
+		assert.Equal(t, grant.ID, authorized.ID)
+
+		_, err = service.AuthorizeImpersonation(ctx, 3, token)
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+		_, err = service.AuthorizeImpersonation(ctx, 2, "")
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+
+		require.NoError(t, service.RevokeGrant(ctx, 2, grant.ID))
+		_, err = service.AuthorizeImpersonation(ctx, 2, token)
+		assert.ErrorIs(t, err, ErrInvalidSupportToken)
+	})
+
+	t.Run("Validates input", func(t *testing.T) {
+		service, _ := setupSupportService(t)
+
+		_, err := service.LookupMemory(ctx, &MemoryLookupRequest{})
+		assert.True(t, utils.IsValidationError(err))
+
+		_, err = service.LookupMemory(ctx, &MemoryLookupRequest{ContentHash: "not-a-hash"})
+		assert.True(t, utils.IsValidationError(err))
+
+		_, _, err = service.GrantAccess(ctx, 2, MaxSupportAccessDuration+time.Hour, "")
+		assert.True(t, utils.IsValidationError(err))
+	})
+
+	t.Run("Refuses hash lookups when content is encrypted", func(t *testing.T) {
+		service, _ := setupSupportService(t)
+		service.encryption = newTestEncryption(t)
+
+		_, err := service.LookupMemory(ctx, &MemoryLookupRequest{ContentHash: models.HashContent("bob likes coffee")})
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Tag match modes for a search's tag filter
+const (
+	// TagMatchAny matches memories with at least one of the tags (the default)
+	TagMatchAny = "any"
+	// TagMatchAll matches memories with every one of the tags
+	TagMatchAll = "all"
+)
+
+// TagFilter filters memories on their tags. Both modes translate to text[] operators
+// (&& and @>) so the filter runs in the database, on the keyword and semantic paths.
+type TagFilter struct {
+	tags  []string
+	match string
+}
+
+// ParseTagFilter validates a tag filter. Blank and duplicate tags are dropped; no
+// tags yields nil.
+func ParseTagFilter(tags []string, match string) (*TagFilter, error) {
+	seen := make(map[string]bool, len(tags))
+	var cleaned []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+
+	switch match {
+	case "":
+		match = TagMatchAny
+	case TagMatchAny, TagMatchAll:
+	default:
+		return nil, utils.InvalidFieldError("tagMatch", "must be any or all")
+	}
+
+	if len(cleaned) == 0 {
+		return nil, nil
+	}
+	return &TagFilter{tags: cleaned, match: match}, nil
+}
+
+// parseTagFilter parses a search's tag filter, which needs PostgreSQL's array
+// operators
+func (s *MemoryService) parseTagFilter(tags []string, match string) (*TagFilter, error) {
+	tagFilter, err := ParseTagFilter(tags, match)
+	if err != nil || tagFilter == nil {
+		return tagFilter, err
+	}
+	if s.db.Dialector.Name() != "postgres" {
+		return nil, fmt.Errorf("tag filters are only supported on PostgreSQL")
+	}
+	return tagFilter, nil
+}
+
+// condition returns the SQL condition for the filter using the given placeholder
+// for its single argument
+func (f *TagFilter) condition(placeholder string) string {
+	if f.match == TagMatchAll {
+		return fmt.Sprintf("tags @> CAST(%s AS text[])", placeholder)
+	}
+	return fmt.Sprintf("tags && CAST(%s AS text[])", placeholder)
+}
+
+// value returns the argument bound to the condition's placeholder
+func (f *TagFilter) value() pq.StringArray {
+	return pq.StringArray(f.tags)
+}
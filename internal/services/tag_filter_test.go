@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	filter, err := ParseTagFilter([]string{" work ", "", "urgent", "work"}, "")
+	require.NoError(t, err)
+	require.NotNil(t, filter)
+	assert.Equal(t, "tags && CAST($4 AS text[])", filter.condition("$4"))
+	assert.Equal(t, pq.StringArray{"work", "urgent"}, filter.value())
+
+	filter, err = ParseTagFilter([]string{"work"}, TagMatchAll)
+	require.NoError(t, err)
+	assert.Equal(t, "tags @> CAST(? AS text[])", filter.condition("?"))
+
+	filter, err = ParseTagFilter([]string{" "}, TagMatchAny)
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	_, err = ParseTagFilter([]string{"work"}, "some")
+	assert.True(t, utils.IsValidationError(err))
+}
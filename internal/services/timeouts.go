@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// defaultWriteTimeout bounds detached work when write_timeout is not configured
+const defaultWriteTimeout = 30 * time.Second
+
+// writeTimeout returns how long work the service finishes independently of its
+// caller may take, as set by write_timeout. Zero means no deadline.
+func (s *MemoryService) writeTimeout() time.Duration {
+	if timeout, ok := s.config["write_timeout"].(time.Duration); ok && timeout >= 0 {
+		return timeout
+	}
+	return defaultWriteTimeout
+}
+
+// detachedContext returns a context for work that must complete even if ctx is
+// cancelled, such as a write the client has stopped waiting for. It keeps ctx's
+// values but not its deadline, and is bounded by write_timeout instead.
+func (s *MemoryService) detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	if timeout := s.writeTimeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
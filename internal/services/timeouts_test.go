@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryService_DetachedContext(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	cancelParent()
+
+	t.Run("outlives the caller under write_timeout", func(t *testing.T) {
+		service := NewMemoryService(nil, nil, zerolog.Nop(), map[string]interface{}{"write_timeout": time.Minute})
+		ctx, cancel := service.detachedContext(parent)
+		defer cancel()
+
+		assert.NoError(t, ctx.Err())
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+	})
+
+	t.Run("defaults when unconfigured", func(t *testing.T) {
+		service := NewMemoryService(nil, nil, zerolog.Nop(), nil)
+		assert.Equal(t, defaultWriteTimeout, service.writeTimeout())
+	})
+
+	t.Run("zero means no deadline", func(t *testing.T) {
+		service := NewMemoryService(nil, nil, zerolog.Nop(), map[string]interface{}{"write_timeout": time.Duration(0)})
+		ctx, cancel := service.detachedContext(parent)
+		defer cancel()
+
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		assert.NoError(t, ctx.Err())
+	})
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // StoreMemoryRequest represents a request to store a new memory
@@ -11,15 +12,41 @@ type StoreMemoryRequest struct {
 	Content  string                 `json:"content" validate:"required,min=1"`
 	Tags     []string               `json:"tags,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// OnDuplicate overrides the configured action for near-duplicates
+	OnDuplicate string `json:"on_duplicate,omitempty" validate:"omitempty,oneof=merge update reject allow"`
+	// SessionID captures the memory in a working session; see StoreRequest
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // SearchMemoriesRequest represents a request to search memories
 type SearchMemoriesRequest struct {
-	Query             string `json:"query" validate:"required,min=1"`
-	Category          string `json:"category,omitempty" validate:"omitempty,oneof=personal project business"`
-	Type              string `json:"type,omitempty" validate:"omitempty,oneof=fact conversation context preference"`
-	Limit             int    `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
-	UseSemanticSearch bool   `json:"use_semantic_search"`
+	Query             string   `json:"query" validate:"required,min=1"`
+	Category          string   `json:"category,omitempty" validate:"omitempty,oneof=personal project business"`
+	Type              string   `json:"type,omitempty" validate:"omitempty,oneof=fact conversation context preference"`
+	Limit             int      `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+	UseSemanticSearch bool     `json:"use_semantic_search"`
+	MetadataQuery     string   `json:"metadata_query,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	TagMatch          string   `json:"tag_match,omitempty" validate:"omitempty,oneof=any all"`
+	SearchMode        string   `json:"search_mode,omitempty" validate:"omitempty,oneof=keyword semantic hybrid"`
+	Offset            int      `json:"offset,omitempty" validate:"omitempty,min=0"`
+	Cursor            string   `json:"cursor,omitempty"`
+	// IncludeContext blends hits from the session's short-term buffer into the results
+	IncludeContext bool   `json:"include_context,omitempty"`
+	SessionID      string `json:"session_id,omitempty"`
+	// WithinIDs and RefineCursor search only earlier results; see SearchRequest
+	WithinIDs    []uint `json:"within_ids,omitempty"`
+	RefineCursor string `json:"refine_cursor,omitempty"`
+	// CreatedAfter and the other bounds scope the search by time; see DateRange
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	UpdatedAfter  *time.Time `json:"updated_after,omitempty"`
+	UpdatedBefore *time.Time `json:"updated_before,omitempty"`
+	// Priorities and MinPriority scope the search by priority; see PriorityFilter
+	Priorities  []string `json:"priorities,omitempty"`
+	MinPriority string   `json:"min_priority,omitempty" validate:"omitempty,oneof=low medium high critical"`
+	// Session keeps memories captured in a working session; see SearchRequest
+	Session string `json:"session,omitempty"`
 }
 
 // SetDefaults sets default values for SearchMemoriesRequest
@@ -34,7 +61,8 @@ func (r *SearchMemoriesRequest) SetDefaults() {
 
 // DeleteMemoryRequest represents a request to delete a memory
 type DeleteMemoryRequest struct {
-	ID uint `json:"id" validate:"required,min=1"`
+	ID      uint   `json:"id" validate:"required,min=1"`
+	Confirm string `json:"confirm,omitempty"`
 }
 
 // MemoryResponse represents a standard response for memory operations
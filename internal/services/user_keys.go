@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// openAIKeyValidationTimeout bounds the test request made when a key is saved
+const openAIKeyValidationTimeout = 15 * time.Second
+
+// validateOpenAIKey checks a key by embedding a short text with it. Tests replace
+// it to avoid calling OpenAI.
+var validateOpenAIKey = func(ctx context.Context, svc *OpenAIEmbeddingService) error {
+	_, err := svc.generateEmbeddingDirect(ctx, "test")
+	return err
+}
+
+// OpenAIKeyStatus reports whether the user has their own OpenAI key. The key is
+// never returned; Hint shows its last characters so users can tell keys apart.
+type OpenAIKeyStatus struct {
+	Configured bool       `json:"configured"`
+	Hint       string     `json:"hint,omitempty"`
+	SetAt      *time.Time `json:"set_at,omitempty"`
+}
+
+// SetOpenAIKey validates the user's own OpenAI API key and stores it encrypted
+// with the master key. From then on the user's embeddings use their key, with
+// the server's embedding model so vectors stay comparable, and their usage is
+// attributed to their key rather than the server's budgets.
+func (s *MemoryService) SetOpenAIKey(ctx context.Context, apiKey string) (*OpenAIKeyStatus, error) {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return nil, utils.RequiredFieldError("api_key")
+	}
+	if !strings.HasPrefix(apiKey, "sk-") {
+		return nil, utils.InvalidFieldError("api_key", "must be an OpenAI API key starting with sk-")
+	}
+	if s.encryption == nil {
+		return nil, utils.InvalidFieldError("api_key", "cannot be stored because encryption is not enabled on this server")
+	}
+
+	validateCtx, cancel := context.WithTimeout(ctx, openAIKeyValidationTimeout)
+	defer cancel()
+	if err := validateOpenAIKey(validateCtx, s.newUserEmbeddingService(apiKey)); err != nil {
+		s.logger.Info().Err(err).Uint("user_id", s.userID).Msg("rejected OpenAI API key")
+		return nil, utils.InvalidFieldError("api_key", "was rejected by OpenAI")
+	}
+
+	encryptedData, err := s.encryption.EncryptField(apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+	encryptedJSON, err := json.Marshal(encryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encrypted API key: %w", err)
+	}
+
+	now := time.Now()
+	hint := apiKey[len(apiKey)-4:]
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Updates(map[string]interface{}{
+			"openai_key":        encryptedJSON,
+			"openai_key_hint":   hint,
+			"openai_key_set_at": now,
+		})
+	if result.Error != nil {
+		return nil, utils.WrapDatabaseError("set OpenAI key", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.WrapNotFoundError("user", fmt.Sprintf("%d", s.userID))
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Msg("user OpenAI API key set")
+	return &OpenAIKeyStatus{Configured: true, Hint: hint, SetAt: &now}, nil
+}
+
+// DeleteOpenAIKey removes the user's own OpenAI key, returning them to the
+// server's key
+func (s *MemoryService) DeleteOpenAIKey(ctx context.Context) error {
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", s.userID).
+		Updates(map[string]interface{}{
+			"openai_key":        nil,
+			"openai_key_hint":   "",
+			"openai_key_set_at": nil,
+		}).Error; err != nil {
+		return utils.WrapDatabaseError("delete OpenAI key", err)
+	}
+	s.logger.Info().Uint("user_id", s.userID).Msg("user OpenAI API key removed")
+	return nil
+}
+
+// OpenAIKeyStatus returns whether the user has their own OpenAI key
+func (s *MemoryService) OpenAIKeyStatus(ctx context.Context) (*OpenAIKeyStatus, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).
+		Select("id", "openai_key_hint", "openai_key_set_at").
+		Where("id = ?", s.userID).
+		Limit(1).
+		Find(&users).Error; err != nil {
+		return nil, utils.WrapDatabaseError("get OpenAI key status", err)
+	}
+	if len(users) == 0 || users[0].OpenAIKeySetAt == nil {
+		return &OpenAIKeyStatus{}, nil
+	}
+	return &OpenAIKeyStatus{
+		Configured: true,
+		Hint:       users[0].OpenAIKeyHint,
+		SetAt:      users[0].OpenAIKeySetAt,
+	}, nil
+}
+
+// userOpenAIKey returns the user's decrypted OpenAI key, or "" when they use the
+// server's key
+func (s *MemoryService) userOpenAIKey(ctx context.Context) string {
+	return s.openAIKeyFor(ctx, s.userID)
+}
+
+// openAIKeyFor returns a user's decrypted OpenAI key, or "" when they have none.
+// Lookup failures are logged and fall back to the server's key.
+func (s *MemoryService) openAIKeyFor(ctx context.Context, userID uint) string {
+	if s.encryption == nil || userID == 0 {
+		return ""
+	}
+
+	var users []models.User
+	if err := s.db.WithContext(ctx).
+		Select("id", "openai_key").
+		Where("id = ? AND openai_key IS NOT NULL", userID).
+		Limit(1).
+		Find(&users).Error; err != nil {
+		s.logger.Warn().Err(err).Uint("user_id", userID).Msg("failed to look up user OpenAI key, using server key")
+		return ""
+	}
+	if len(users) == 0 || len(users[0].OpenAIKey) == 0 {
+		return ""
+	}
+
+	var encryptedData utils.EncryptedData
+	if err := json.Unmarshal(users[0].OpenAIKey, &encryptedData); err != nil {
+		s.logger.Warn().Err(err).Uint("user_id", userID).Msg("failed to read user OpenAI key, using server key")
+		return ""
+	}
+	apiKey, err := s.encryption.DecryptField(&encryptedData)
+	if err != nil {
+		s.logger.Warn().Err(err).Uint("user_id", userID).Msg("failed to decrypt user OpenAI key, using server key")
+		return ""
+	}
+	return apiKey
+}
+
+// keySource returns which key pays for the user's requests
+func (s *MemoryService) keySource(ctx context.Context) string {
+	if s.userOpenAIKey(ctx) != "" {
+		return models.KeySourceUser
+	}
+	return models.KeySourceServer
+}
+
+// newUserEmbeddingService creates an OpenAI embedding service for a user's key,
+// using the server's model, timeouts and retries
+func (s *MemoryService) newUserEmbeddingService(apiKey string) *OpenAIEmbeddingService {
+	cfg := config.NewDefault().OpenAI
+	var baseURL string
+	var dimensions int
+	if server, ok := s.embedding.(*OpenAIEmbeddingService); ok {
+		cfg = *server.config
+		baseURL = server.baseURL
+		dimensions = server.dimensions
+	} else if namer, ok := s.embedding.(embeddingModelNamer); ok {
+		cfg.Model = namer.GetModel()
+	}
+	cfg.APIKey = apiKey
+
+	return &OpenAIEmbeddingService{
+		client:     openai.NewClient(apiKey),
+		config:     &cfg,
+		logger:     s.logger.With().Str("service", "openai_embedding").Str("key_source", models.KeySourceUser).Logger(),
+		baseURL:    baseURL,
+		dimensions: dimensions,
+	}
+}
+
+// embedderFor returns the embedding service for a user's requests: their own key
+// when they have one, otherwise the server's service, or its batcher when batched
+// is set. Own keys are not used when the server embeds with another provider,
+// whose vectors OpenAI's would not match. Usage is recorded against the user and
+// the key that paid for it.
+func (s *MemoryService) embedderFor(ctx context.Context, userID uint, batched bool) EmbeddingService {
+	_, otherProvider := s.embedding.(*httpEmbeddingProvider)
+	if apiKey := s.openAIKeyFor(ctx, userID); apiKey != "" && !otherProvider {
+		return &usageRecordingEmbedder{
+			EmbeddingService: s.newUserEmbeddingService(apiKey),
+			service:          s,
+			userID:           userID,
+			keySource:        models.KeySourceUser,
+		}
+	}
+
+	var embedder EmbeddingService = s.embedding
+	if batched {
+		if batcher := s.GetEmbeddingBatcher(); batcher != nil {
+			embedder = batcher
+		}
+	}
+	return &usageRecordingEmbedder{
+		EmbeddingService: embedder,
+		service:          s,
+		userID:           userID,
+		keySource:        models.KeySourceServer,
+	}
+}
+
+// usageRecordingEmbedder records the estimated tokens of successful embeddings as
+// embedding usage
+type usageRecordingEmbedder struct {
+	EmbeddingService
+	service   *MemoryService
+	userID    uint
+	keySource string
+}
+
+func (e *usageRecordingEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embedding, err := e.EmbeddingService.GenerateEmbedding(ctx, text)
+	if err == nil {
+		e.record(ctx, len(text))
+	}
+	return embedding, err
+}
+
+func (e *usageRecordingEmbedder) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := e.EmbeddingService.GenerateEmbeddings(ctx, texts)
+	if err == nil {
+		chars := 0
+		for _, text := range texts {
+			chars += len(text)
+		}
+		e.record(ctx, chars)
+	}
+	return embeddings, err
+}
+
+// record adds usage for the given number of characters, estimated at four
+// characters per token. Failures are logged; they never fail the embedding.
+func (e *usageRecordingEmbedder) record(ctx context.Context, chars int) {
+	tokens := (chars + 3) / 4
+	cost := e.service.GetLLMBudget().EmbeddingCost(tokens)
+	if err := e.service.recordUsage(context.WithoutCancel(ctx), e.userID, LLMFeatureEmbedding, e.keySource, tokens, 0, cost); err != nil {
+		e.service.logger.Debug().Err(err).Uint("user_id", e.userID).Msg("failed to record embedding usage")
+	}
+}
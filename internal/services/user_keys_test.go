@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// stubOpenAIKeyValidation replaces key validation for the test, recording the
+// keys it was asked to check
+func stubOpenAIKeyValidation(t *testing.T, err error) *[]string {
+	var checked []string
+	original := validateOpenAIKey
+	validateOpenAIKey = func(ctx context.Context, svc *OpenAIEmbeddingService) error {
+		checked = append(checked, svc.config.APIKey)
+		return err
+	}
+	t.Cleanup(func() { validateOpenAIKey = original })
+	return &checked
+}
+
+func setupUserKeyService(t *testing.T, cfg map[string]interface{}) *MemoryService {
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+	if cfg == nil {
+		cfg = make(map[string]interface{})
+	}
+	cfg["encryption_service"] = encryption
+
+	service := setupMemoryService(t, cfg)
+	require.NoError(t, service.db.AutoMigrate(&models.User{}))
+	require.NoError(t, service.db.Create(&models.User{ID: 1, Email: "user@example.com", Password: "x"}).Error)
+	return service
+}
+
+func TestOpenAIKey_SetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	checked := stubOpenAIKeyValidation(t, nil)
+	service := setupUserKeyService(t, nil)
+
+	status, err := service.OpenAIKeyStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Configured)
+
+	status, err = service.SetOpenAIKey(ctx, " sk-test-abcd1234 ")
+	require.NoError(t, err)
+	assert.True(t, status.Configured)
+	assert.Equal(t, "1234", status.Hint)
+	assert.Equal(t, []string{"sk-test-abcd1234"}, *checked)
+
+	// The key is stored encrypted and only its hint is reported
+	var user models.User
+	require.NoError(t, service.db.First(&user, 1).Error)
+	assert.NotEmpty(t, user.OpenAIKey)
+	assert.NotContains(t, string(user.OpenAIKey), "sk-test-abcd1234")
+	assert.Equal(t, "sk-test-abcd1234", service.userOpenAIKey(ctx))
+	assert.Equal(t, models.KeySourceUser, service.keySource(ctx))
+
+	status, err = service.OpenAIKeyStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Configured)
+	assert.Equal(t, "1234", status.Hint)
+	assert.NotNil(t, status.SetAt)
+
+	require.NoError(t, service.DeleteOpenAIKey(ctx))
+	status, err = service.OpenAIKeyStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Configured)
+	assert.Empty(t, service.userOpenAIKey(ctx))
+	assert.Equal(t, models.KeySourceServer, service.keySource(ctx))
+}
+
+func TestOpenAIKey_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rejected by OpenAI", func(t *testing.T) {
+		stubOpenAIKeyValidation(t, errors.New("401 invalid api key"))
+		service := setupUserKeyService(t, nil)
+
+		_, err := service.SetOpenAIKey(ctx, "sk-bad")
+		assert.True(t, utils.IsValidationError(err))
+
+		status, err := service.OpenAIKeyStatus(ctx)
+		require.NoError(t, err)
+		assert.False(t, status.Configured)
+	})
+
+	t.Run("not an OpenAI key", func(t *testing.T) {
+		checked := stubOpenAIKeyValidation(t, nil)
+		service := setupUserKeyService(t, nil)
+
+		_, err := service.SetOpenAIKey(ctx, "not-a-key")
+		assert.True(t, utils.IsValidationError(err))
+		_, err = service.SetOpenAIKey(ctx, "")
+		assert.True(t, utils.IsValidationError(err))
+		assert.Empty(t, *checked)
+	})
+
+	t.Run("encryption disabled", func(t *testing.T) {
+		stubOpenAIKeyValidation(t, nil)
+		service := setupIncognitoService(t)
+
+		_, err := service.SetOpenAIKey(ctx, "sk-test-abcd1234")
+		assert.True(t, utils.IsValidationError(err))
+	})
+}
+
+func TestOpenAIKey_UsageAttribution(t *testing.T) {
+	ctx := context.Background()
+	stubOpenAIKeyValidation(t, nil)
+	budget := &LLMBudget{UserDailyUSD: 0.01, InputCostPerMillion: 10, EmbeddingCostPerMillion: 1}
+	service := setupUserKeyService(t, map[string]interface{}{"llm_budget": budget})
+	service.embedding = NewMockEmbeddingService()
+
+	// Embeddings on the server key are attributed but not budgeted
+	_, err := service.embedderFor(ctx, service.userID, false).GenerateEmbedding(ctx, "twelve chars")
+	require.NoError(t, err)
+	_, err = service.RecordLLMUsage(ctx, LLMFeatureExtraction, 1000, 0)
+	require.NoError(t, err)
+	assert.True(t, errors.Is(service.CheckLLMBudget(ctx, LLMFeatureAsk), ErrLLMBudgetExceeded))
+
+	var embedding models.LLMUsage
+	require.NoError(t, service.db.Where("feature = ?", LLMFeatureEmbedding).First(&embedding).Error)
+	assert.Equal(t, models.KeySourceServer, embedding.KeySource)
+	assert.Equal(t, int64(3), embedding.InputTokens)
+
+	// With their own key the user is no longer limited, and usage is kept apart
+	_, err = service.SetOpenAIKey(ctx, "sk-test-abcd1234")
+	require.NoError(t, err)
+	assert.NoError(t, service.CheckLLMBudget(ctx, LLMFeatureAsk))
+
+	_, err = service.RecordLLMUsage(ctx, LLMFeatureExtraction, 5000, 0)
+	require.NoError(t, err)
+
+	var usage []models.LLMUsage
+	require.NoError(t, service.db.Where("feature = ?", LLMFeatureExtraction).Order("key_source").Find(&usage).Error)
+	require.Len(t, usage, 2)
+	assert.Equal(t, models.KeySourceServer, usage[0].KeySource)
+	assert.Equal(t, int64(1000), usage[0].InputTokens)
+	assert.Equal(t, models.KeySourceUser, usage[1].KeySource)
+	assert.Equal(t, int64(5000), usage[1].InputTokens)
+
+	status, err := service.LLMBudgetStatus(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.01, status.UserSpentUSD, 1e-9)
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Transcription providers
+const (
+	TranscriptionProviderNone    = "none"
+	TranscriptionProviderOpenAI  = "openai"
+	TranscriptionProviderWhisper = "whisper"
+)
+
+// MaxVoiceMemoBytes bounds an uploaded voice memo; a few minutes of compressed
+// speech fit comfortably
+const MaxVoiceMemoBytes = 10 << 20
+
+// ErrTranscriptionDisabled is returned for voice memos when no transcription
+// provider is configured
+var ErrTranscriptionDisabled = errors.New("voice memos are disabled: no transcription provider is configured")
+
+// ErrTranscriptionFailed is returned when the transcription provider fails
+var ErrTranscriptionFailed = errors.New("failed to transcribe voice memo")
+
+// voiceMemoExtensions are the audio formats the transcription API accepts, with
+// the content type each is stored as
+var voiceMemoExtensions = map[string]string{
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".mp4":  "audio/mp4",
+	".mpeg": "audio/mpeg",
+	".mpga": "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".wav":  "audio/wav",
+	".webm": "audio/webm",
+}
+
+// voiceMemoContentTypes gives the extension uploads of each audio content type
+// are sent to the transcriber with
+var voiceMemoContentTypes = map[string]string{
+	"audio/flac":  ".flac",
+	"audio/mp4":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/mpeg":  ".mp3",
+	"audio/ogg":   ".ogg",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/webm":  ".webm",
+}
+
+// Transcriber turns recorded speech into text
+type Transcriber interface {
+	// Name identifies the provider, e.g. "openai" or "whisper"
+	Name() string
+	// Transcribe returns the text spoken in audio. The filename's extension tells
+	// the service the audio format; language, an ISO-639-1 code, is an optional
+	// hint.
+	Transcribe(ctx context.Context, audio []byte, filename, language string) (string, error)
+}
+
+// WhisperTranscriber transcribes with the OpenAI transcription API or a
+// self-hosted server implementing it
+type WhisperTranscriber struct {
+	name     string
+	apiKey   string
+	model    string
+	endpoint string
+	client   *http.Client
+}
+
+// NewWhisperTranscriber creates a transcriber for the transcription API at
+// baseURL, such as https://api.openai.com
+func NewWhisperTranscriber(name, baseURL, apiKey, model string, timeout time.Duration) *WhisperTranscriber {
+	return &WhisperTranscriber{
+		name:     name,
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/v1/audio/transcriptions",
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// NewTranscriberFromConfig builds the configured transcriber, or returns nil when
+// voice memos are disabled
+func NewTranscriberFromConfig(cfg *config.Config, logger zerolog.Logger) (Transcriber, error) {
+	t := cfg.Transcription
+	switch t.Provider {
+	case "", TranscriptionProviderNone:
+		return nil, nil
+	case TranscriptionProviderOpenAI:
+		logger.Info().Str("model", t.Model).Msg("Voice memo transcription enabled")
+		return NewWhisperTranscriber(t.Provider, "https://api.openai.com", cfg.OpenAI.APIKey, t.Model, t.Timeout), nil
+	case TranscriptionProviderWhisper:
+		logger.Info().Str("model", t.Model).Str("base_url", t.BaseURL).Msg("Voice memo transcription enabled")
+		return NewWhisperTranscriber(t.Provider, t.BaseURL, t.APIKey, t.Model, t.Timeout), nil
+	default:
+		return nil, fmt.Errorf("invalid transcription provider: %s", t.Provider)
+	}
+}
+
+// Name identifies the provider
+func (t *WhisperTranscriber) Name() string {
+	return t.name
+}
+
+// withAPIKey returns a copy of the transcriber that sends requests with another key
+func (t *WhisperTranscriber) withAPIKey(apiKey string) *WhisperTranscriber {
+	copied := *t
+	copied.apiKey = apiKey
+	return &copied
+}
+
+// Transcribe uploads audio to the transcription API and returns the text
+func (t *WhisperTranscriber) Transcribe(ctx context.Context, audio []byte, filename, language string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	fields := map[string]string{"model": t.model, "response_format": "json"}
+	if language != "" {
+		fields["language"] = language
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("failed to create request: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return strings.TrimSpace(response.Text), nil
+}
+
+// GetTranscriber returns the configured transcriber, or nil when voice memos are
+// disabled
+func (s *MemoryService) GetTranscriber() Transcriber {
+	if transcriber, ok := s.config["transcriber"].(Transcriber); ok {
+		return transcriber
+	}
+	return nil
+}
+
+// VoiceMemoRequest is a recorded voice memo. Type and category are detected from
+// the transcript unless given.
+type VoiceMemoRequest struct {
+	Audio []byte
+	// Filename's extension gives the audio format when ContentType doesn't
+	Filename    string
+	ContentType string
+	// Language is an optional ISO-639-1 hint for the transcriber
+	Language string
+	Type     string
+	Category string
+	Tags     []string
+}
+
+// VoiceMemoResult reports what a voice memo did: its transcript is captured like
+// quick-captured text, and the recording is attached to the memory it created or
+// updated
+type VoiceMemoResult struct {
+	CaptureResult
+	Transcript string             `json:"transcript"`
+	Attachment *models.Attachment `json:"attachment,omitempty"`
+}
+
+// CaptureVoiceMemo transcribes a voice memo and captures the transcript, running
+// memory detection and deduplication as Capture does. Nothing is sent to the
+// transcriber while incognito.
+func (s *MemoryService) CaptureVoiceMemo(ctx context.Context, req VoiceMemoRequest) (*VoiceMemoResult, error) {
+	if len(req.Audio) == 0 {
+		return nil, utils.RequiredFieldError("audio")
+	}
+	if len(req.Audio) > MaxVoiceMemoBytes {
+		return nil, utils.InvalidFieldError("audio", fmt.Sprintf("must be at most %d bytes", MaxVoiceMemoBytes))
+	}
+	filename, contentType, err := voiceMemoFormat(req.Filename, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if req.Type != "" && !models.IsValidType(req.Type) {
+		return nil, utils.InvalidFieldError("type", "must be one of: fact, conversation, context, preference")
+	}
+	if req.Category != "" && !models.IsValidCategory(req.Category) {
+		return nil, utils.InvalidFieldError("category", "must be one of: personal, project, business")
+	}
+
+	if err := s.checkIncognito(ctx); err != nil {
+		return nil, err
+	}
+	transcriber := s.GetTranscriber()
+	if transcriber == nil {
+		return nil, ErrTranscriptionDisabled
+	}
+	if whisper, ok := transcriber.(*WhisperTranscriber); ok && whisper.Name() == TranscriptionProviderOpenAI {
+		if apiKey := s.userOpenAIKey(ctx); apiKey != "" {
+			transcriber = whisper.withAPIKey(apiKey)
+		}
+	}
+
+	transcript, err := transcriber.Transcribe(ctx, req.Audio, filename, req.Language)
+	if err != nil {
+		s.logger.Error().Err(err).Str("provider", transcriber.Name()).Msg("failed to transcribe voice memo")
+		return nil, fmt.Errorf("%w: %v", ErrTranscriptionFailed, err)
+	}
+	if transcript == "" {
+		return nil, utils.InvalidFieldError("audio", "contains no recognisable speech")
+	}
+	if len(transcript) > maxCaptureLength {
+		return nil, utils.InvalidFieldError("audio", "transcript must be at most 10000 characters")
+	}
+
+	storeReq, detected := captureStoreRequest(transcript, CaptureRequest{Type: req.Type, Category: req.Category, Tags: req.Tags})
+	storeReq.Metadata["source"] = "voice"
+	storeReq.Metadata["transcriber"] = transcriber.Name()
+
+	captured, err := s.storeCaptured(ctx, transcript, storeReq, &CaptureResult{Detected: detected})
+	if err != nil {
+		return nil, err
+	}
+	result := &VoiceMemoResult{CaptureResult: *captured, Transcript: transcript}
+
+	// A skipped memo was already remembered; its recording adds nothing
+	if captured.Action != CaptureSkipped {
+		attachment, err := s.attach(ctx, captured.Memory.ID, filename, contentType, req.Audio, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.Attachment = attachment
+	}
+	return result, nil
+}
+
+// voiceMemoFormat checks that an upload is audio in a format the transcriber
+// accepts, returning a filename with the right extension and the content type
+func voiceMemoFormat(filename, contentType string) (string, string, error) {
+	filename = filepath.Base(strings.TrimSpace(filename))
+	if filename == "." || filename == "/" {
+		filename = ""
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if _, ok := voiceMemoExtensions[ext]; !ok {
+		var known bool
+		if ext, known = voiceMemoContentTypes[contentType]; !known {
+			return "", "", utils.InvalidFieldError("audio", "must be flac, m4a, mp3, mp4, mpeg, mpga, oga, ogg, wav or webm audio")
+		}
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename))
+		if filename == "" {
+			filename = "voice-memo"
+		}
+		filename += ext
+	}
+
+	if !strings.HasPrefix(contentType, "audio/") {
+		contentType = voiceMemoExtensions[ext]
+	}
+	return filename, contentType, nil
+}
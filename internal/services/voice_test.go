@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// stubTranscriber returns a fixed transcript and records what it was sent
+type stubTranscriber struct {
+	text     string
+	filename string
+	calls    int
+}
+
+func (t *stubTranscriber) Name() string { return "stub" }
+
+func (t *stubTranscriber) Transcribe(ctx context.Context, audio []byte, filename, language string) (string, error) {
+	t.calls++
+	t.filename = filename
+	return t.text, nil
+}
+
+func TestWhisperTranscriber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "en", r.FormValue("language"))
+		_, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		assert.Equal(t, "memo.m4a", header.Filename)
+		w.Write([]byte(`{"text": " Remember that I prefer tea. "}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewWhisperTranscriber(TranscriptionProviderWhisper, server.URL+"/", "secret", "whisper-1", time.Second)
+	text, err := transcriber.Transcribe(context.Background(), []byte("audio"), "memo.m4a", "en")
+	require.NoError(t, err)
+	assert.Equal(t, "Remember that I prefer tea.", text)
+}
+
+func TestVoiceMemoFormat(t *testing.T) {
+	filename, contentType, err := voiceMemoFormat("Recording.M4A", "application/octet-stream")
+	require.NoError(t, err)
+	assert.Equal(t, "Recording.M4A", filename)
+	assert.Equal(t, "audio/mp4", contentType)
+
+	// iOS shortcuts send raw bodies without a useful name
+	filename, contentType, err = voiceMemoFormat("", "audio/x-m4a")
+	require.NoError(t, err)
+	assert.Equal(t, "voice-memo.m4a", filename)
+	assert.Equal(t, "audio/x-m4a", contentType)
+
+	_, _, err = voiceMemoFormat("notes.txt", "text/plain")
+	assert.True(t, utils.IsValidationError(err))
+}
+
+func TestMemoryService_CaptureVoiceMemo(t *testing.T) {
+	ctx := context.Background()
+	transcriber := &stubTranscriber{text: "I prefer tea over coffee"}
+	service := setupMemoryService(t, map[string]interface{}{"transcriber": transcriber})
+	require.NoError(t, service.db.AutoMigrate(&models.Attachment{}))
+
+	audio := []byte("fake mp3 data")
+	result, err := service.CaptureVoiceMemo(ctx, VoiceMemoRequest{Audio: audio, ContentType: "audio/mpeg", Tags: []string{"drinks"}})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureCreated, result.Action)
+	assert.Equal(t, "I prefer tea over coffee", result.Transcript)
+	assert.Equal(t, "voice-memo.mp3", transcriber.filename)
+	assert.Equal(t, models.TypePreference, result.Memory.Type)
+	require.NotNil(t, result.Attachment)
+	assert.Equal(t, result.Memory.ID, result.Attachment.MemoryID)
+	assert.EqualValues(t, len(audio), result.Attachment.Size)
+
+	attachments, err := service.ListAttachments(ctx, result.Memory.ID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Empty(t, attachments[0].Data)
+
+	attachment, err := service.GetAttachment(ctx, result.Attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, audio, attachment.Data)
+	assert.Equal(t, "audio/mpeg", attachment.ContentType)
+
+	// The same memo again is already remembered and its recording isn't kept
+	result, err = service.CaptureVoiceMemo(ctx, VoiceMemoRequest{Audio: audio, Filename: "again.mp3"})
+	require.NoError(t, err)
+	assert.Equal(t, CaptureSkipped, result.Action)
+	assert.Nil(t, result.Attachment)
+
+	_, err = service.CaptureVoiceMemo(ctx, VoiceMemoRequest{Filename: "empty.mp3"})
+	assert.True(t, utils.IsValidationError(err))
+
+	_, err = setupMemoryService(t, nil).CaptureVoiceMemo(ctx, VoiceMemoRequest{Audio: audio, Filename: "memo.mp3"})
+	assert.True(t, errors.Is(err, ErrTranscriptionDisabled))
+}
+
+func TestMemoryService_CaptureVoiceMemoEncrypted(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := utils.GenerateMasterKey()
+	require.NoError(t, err)
+	encryption, err := utils.NewEncryptionService(masterKey)
+	require.NoError(t, err)
+
+	service := setupMemoryService(t, map[string]interface{}{
+		"transcriber":        &stubTranscriber{text: "My locker code is in the blue notebook"},
+		"encryption_service": encryption,
+	})
+	require.NoError(t, service.db.AutoMigrate(&models.Attachment{}))
+
+	audio := []byte("fake wav data")
+	result, err := service.CaptureVoiceMemo(ctx, VoiceMemoRequest{Audio: audio, Filename: "memo.wav"})
+	require.NoError(t, err)
+
+	var stored models.Attachment
+	require.NoError(t, service.db.First(&stored, result.Attachment.ID).Error)
+	assert.True(t, stored.IsEncrypted)
+	assert.Empty(t, stored.Data)
+
+	attachment, err := service.GetAttachment(ctx, result.Attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, audio, attachment.Data)
+}
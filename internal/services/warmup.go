@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// warmUpSearchLimit is the default search limit of the HTTP and MCP search paths, so
+// the statement WarmUp prepares is the one the first real search runs
+const warmUpSearchLimit = 100
+
+// WarmUpReport records how long each warm-up step took. A failed step is listed in
+// Errors and does not stop the others.
+type WarmUpReport struct {
+	Embedding   time.Duration     `json:"embedding"`
+	VectorIndex time.Duration     `json:"vector_index"`
+	Statements  time.Duration     `json:"statements"`
+	Total       time.Duration     `json:"total"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// WarmUp pays the cold costs of the first semantic search up front: it primes the
+// embedding client (connection and TLS handshake) with a dummy call, reads the
+// vector index into the buffer cache with a nearest-neighbour probe, and prepares
+// the hot search statements. Run it after migrations and before serving traffic.
+func (s *MemoryService) WarmUp(ctx context.Context) *WarmUpReport {
+	start := time.Now()
+	report := &WarmUpReport{Errors: make(map[string]string)}
+
+	// Prime the embedding client; the vector also drives the probes below
+	vector := make([]float32, EmbeddingDimension)
+	vector[0] = 1
+	if s.embedding != nil {
+		stepStart := time.Now()
+		embedding, err := s.embedding.GenerateEmbedding(ctx, "warm-up")
+		report.Embedding = time.Since(stepStart)
+		if err != nil {
+			report.Errors["embedding"] = err.Error()
+		} else if len(embedding) == EmbeddingDimension {
+			vector = embedding
+		}
+	}
+
+	// The sqlite schema used in tests has no vector type
+	if s.db.Dialector.Name() == "postgres" {
+		stepStart := time.Now()
+		var ids []uint
+		if err := s.db.WithContext(ctx).Raw(
+			"SELECT id FROM memories WHERE embedding IS NOT NULL ORDER BY embedding <=> $1 LIMIT 1",
+			pgvector.NewVector(vector),
+		).Scan(&ids).Error; err != nil {
+			report.Errors["vector_index"] = err.Error()
+		}
+		report.VectorIndex = time.Since(stepStart)
+
+		// User 0 never exists, so these prepare and plan the statements without
+		// returning rows or recording access. Requests run in a workspace, so the
+		// statements filter on one.
+		stepStart = time.Now()
+		scoped := s.InWorkspace(0)
+		var count int64
+		if err := scoped.countEmbedded(ctx, 0, &count); err != nil {
+			report.Errors["statements"] = err.Error()
+		}
+		var memories []*models.Memory
+		var filters strings.Builder
+		args := scoped.workspaceFilter(&filters, []interface{}{pgvector.NewVector(vector), 0, warmUpSearchLimit})
+		if err := scoped.db.WithContext(ctx).Raw(
+			s.semanticSearchSQL(filters.String(), warmUpSearchLimit, 0), args...,
+		).Scan(&memories).Error; err != nil {
+			report.Errors["statements"] = err.Error()
+		}
+		report.Statements = time.Since(stepStart)
+	}
+
+	report.Total = time.Since(start)
+	if len(report.Errors) == 0 {
+		report.Errors = nil
+	}
+
+	event := s.logger.Info()
+	if report.Errors != nil {
+		event = s.logger.Warn().Interface("errors", report.Errors)
+	}
+	event.
+		Dur("embedding", report.Embedding).
+		Dur("vector_index", report.VectorIndex).
+		Dur("statements", report.Statements).
+		Dur("total", report.Total).
+		Msg("warm-up completed")
+
+	return report
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryService_WarmUp(t *testing.T) {
+	service := setupMemoryService(t, nil)
+	service.embedding = NewMockEmbeddingService()
+	storeTestMemory(t, service, "untouched by warm-up")
+
+	report := service.WarmUp(context.Background())
+	assert.Nil(t, report.Errors)
+	assert.Positive(t, report.Total)
+
+	// Warm-up must not count as an access
+	var accessCount int
+	service.db.Raw("SELECT access_count FROM memories").Scan(&accessCount)
+	assert.Zero(t, accessCount)
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// workspaceNamePattern is what workspace names may look like: letters, digits,
+// dashes, underscores and dots, starting with a letter or digit
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// WorkspaceSummary is a workspace with the number of memories it holds
+type WorkspaceSummary struct {
+	models.Workspace
+	MemoryCount int64 `json:"memory_count"`
+}
+
+// InWorkspace returns a copy of the service confined to one of the user's
+// workspaces: searches, lists and duplicate checks only see its memories, and
+// new memories are stored in it. Counts, quota and eviction still cover the whole
+// account, as the memory limit does. A service that was never scoped sees every
+// workspace, which is what workers running over the account need.
+func (s *MemoryService) InWorkspace(workspaceID uint) *MemoryService {
+	return &MemoryService{
+		db:              database.InWorkspace(s.db, workspaceID),
+		embedding:       s.embedding,
+		encryption:      s.encryption,
+		logger:          s.logger,
+		config:          s.config,
+		userID:          s.userID,
+		asyncEmbeddings: s.asyncEmbeddings,
+	}
+}
+
+// Workspace returns the workspace the service is confined to, if any
+func (s *MemoryService) Workspace() (uint, bool) {
+	return database.Workspace(s.db)
+}
+
+// workspaceFilter appends the service's workspace to the filters of a raw
+// statement on memories, which the workspace scope does not reach
+func (s *MemoryService) workspaceFilter(filters *strings.Builder, args []interface{}) []interface{} {
+	workspaceID, ok := s.Workspace()
+	if !ok {
+		return args
+	}
+	args = append(args, workspaceID)
+	fmt.Fprintf(filters, " AND workspace_id = $%d", len(args))
+	return args
+}
+
+// ListWorkspaces returns the user's workspaces ordered by name, after the default
+// workspace every user has
+func (s *MemoryService) ListWorkspaces(ctx context.Context) ([]WorkspaceSummary, error) {
+	var workspaces []models.Workspace
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", s.userID).
+		Order("name ASC").
+		Find(&workspaces).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to list workspaces")
+		return nil, utils.WrapDatabaseError("list workspaces", err)
+	}
+
+	var counts []struct {
+		WorkspaceID uint
+		Count       int64
+	}
+	if err := database.AllWorkspaces(s.db).WithContext(ctx).Model(&models.Memory{}).
+		Select("workspace_id, COUNT(*) AS count").
+		Where("user_id = ?", s.userID).
+		Group("workspace_id").
+		Scan(&counts).Error; err != nil {
+		s.logger.Error().Err(err).Msg("failed to count workspace memories")
+		return nil, utils.WrapDatabaseError("count workspace memories", err)
+	}
+	memoryCounts := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		memoryCounts[count.WorkspaceID] = count.Count
+	}
+
+	summaries := make([]WorkspaceSummary, 0, len(workspaces)+1)
+	summaries = append(summaries, WorkspaceSummary{
+		Workspace:   models.Workspace{UserID: s.userID, Name: models.DefaultWorkspace},
+		MemoryCount: memoryCounts[0],
+	})
+	for _, workspace := range workspaces {
+		summaries = append(summaries, WorkspaceSummary{
+			Workspace:   workspace,
+			MemoryCount: memoryCounts[workspace.ID],
+		})
+	}
+	return summaries, nil
+}
+
+// CreateWorkspace creates a workspace for the user
+func (s *MemoryService) CreateWorkspace(ctx context.Context, name, description string) (*models.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if err := validateWorkspaceName(name); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(name, models.DefaultWorkspace) {
+		return nil, utils.InvalidFieldError("name", fmt.Sprintf("%q is the workspace every user has", models.DefaultWorkspace))
+	}
+
+	workspace := &models.Workspace{
+		UserID:      s.userID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+	}
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.Workspace{}).
+			Where("user_id = ? AND name = ?", s.userID, name).
+			Count(&existing).Error; err != nil {
+			return utils.WrapDatabaseError("check workspace", err)
+		}
+		if existing > 0 {
+			return utils.WrapConflictError("workspace", "name", name)
+		}
+		if err := tx.Create(workspace).Error; err != nil {
+			return utils.WrapDatabaseError("create workspace", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("workspace", name).Msg("failed to create workspace")
+		return nil, err
+	}
+
+	s.logger.Info().Uint("user_id", s.userID).Str("workspace", name).Msg("workspace created")
+	return workspace, nil
+}
+
+// ResolveWorkspace returns the ID of the user's workspace with the given name.
+// An empty name and "default" resolve to the default workspace.
+func (s *MemoryService) ResolveWorkspace(ctx context.Context, name string) (uint, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, models.DefaultWorkspace) {
+		return 0, nil
+	}
+
+	var workspace models.Workspace
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND name = ?", s.userID, name).
+		First(&workspace).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, utils.WrapNotFoundError("workspace", name)
+		}
+		return 0, utils.WrapDatabaseError("find workspace", err)
+	}
+	return workspace.ID, nil
+}
+
+// DeleteWorkspace deletes one of the user's workspaces. Only empty workspaces
+// can be deleted, so memories are never lost along with one: delete them first,
+// for example with a bulk delete scoped to the workspace. The default workspace
+// cannot be deleted.
+func (s *MemoryService) DeleteWorkspace(ctx context.Context, name string) error {
+	workspaceID, err := s.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+	if workspaceID == 0 {
+		return utils.InvalidFieldError("name", "the default workspace cannot be deleted")
+	}
+
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		var memories int64
+		if err := database.AllWorkspaces(tx).Model(&models.Memory{}).
+			Where("user_id = ? AND workspace_id = ?", s.userID, workspaceID).
+			Count(&memories).Error; err != nil {
+			return utils.WrapDatabaseError("count workspace memories", err)
+		}
+		if memories > 0 {
+			return utils.InvalidFieldError("name", fmt.Sprintf("workspace still holds %d memories; delete them first", memories))
+		}
+		if err := tx.Where("id = ? AND user_id = ?", workspaceID, s.userID).Delete(&models.Workspace{}).Error; err != nil {
+			return utils.WrapDatabaseError("delete workspace", err)
+		}
+		if err := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND workspace_id = ?", s.userID, workspaceID).
+			Update("workspace_id", 0).Error; err != nil {
+			return utils.WrapDatabaseError("reset API key workspaces", err)
+		}
+		s.logger.Info().Uint("user_id", s.userID).Str("workspace", name).Msg("workspace deleted")
+		return nil
+	})
+}
+
+// validateWorkspaceName checks a workspace name is usable in a header or URL
+func validateWorkspaceName(name string) error {
+	if name == "" {
+		return utils.RequiredFieldError("name")
+	}
+	if !workspaceNamePattern.MatchString(name) {
+		return utils.InvalidFieldError("name", "must be at most 64 letters, digits, dashes, underscores or dots, starting with a letter or digit")
+	}
+	return nil
+}
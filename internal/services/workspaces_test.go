@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+func TestMemoryService_Workspaces(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *MemoryService {
+		db := setupTestDB(t)
+		require.NoError(t, db.AutoMigrate(&models.User{}, &models.APIKey{}, &models.Workspace{}))
+		return NewMemoryService(db, nil, zerolog.Nop(), nil)
+	}
+
+	t.Run("memories are kept apart by workspace", func(t *testing.T) {
+		service := setup(t)
+		work, err := service.CreateWorkspace(ctx, "work", "")
+		require.NoError(t, err)
+
+		inDefault := service.InWorkspace(0)
+		inWork := service.InWorkspace(work.ID)
+		personal, _ := storeTestMemory(t, inDefault, "standup moved to ten")
+		job, _ := storeTestMemory(t, inWork, "standup is at nine")
+		assert.Equal(t, uint(0), personal.WorkspaceID)
+		assert.Equal(t, work.ID, job.WorkspaceID)
+
+		found, err := inWork.Search(ctx, SearchRequest{Query: "standup"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, job.ID, found[0].ID)
+
+		_, err = inWork.GetByID(ctx, personal.ID)
+		assert.True(t, utils.IsNotFoundError(err))
+		assert.True(t, utils.IsNotFoundError(inWork.Delete(ctx, personal.ID)))
+
+		listed, err := inDefault.List(ctx, ListRequest{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, personal.ID, listed[0].ID)
+
+		// An unscoped service, as workers use, sees every workspace, and counts
+		// cover the whole account
+		all, err := service.Search(ctx, SearchRequest{Query: "standup"})
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+		count, err := inWork.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("the same content is not a duplicate across workspaces", func(t *testing.T) {
+		service := setup(t)
+		client, err := service.CreateWorkspace(ctx, "client-x", "")
+		require.NoError(t, err)
+
+		first, _ := storeTestMemory(t, service.InWorkspace(0), "deploys go out on tuesdays")
+		second, _ := storeTestMemory(t, service.InWorkspace(client.ID), "deploys go out on tuesdays")
+		assert.NotEqual(t, first.ID, second.ID)
+
+		again, _ := storeTestMemory(t, service.InWorkspace(client.ID), "deploys go out on tuesdays")
+		assert.Equal(t, second.ID, again.ID)
+	})
+
+	t.Run("workspaces are created, resolved, listed and deleted by name", func(t *testing.T) {
+		service := setup(t)
+		work, err := service.CreateWorkspace(ctx, "work", "my job")
+		require.NoError(t, err)
+
+		_, err = service.CreateWorkspace(ctx, "work", "")
+		assert.True(t, utils.IsConflictError(err))
+		_, err = service.CreateWorkspace(ctx, "default", "")
+		assert.True(t, utils.IsValidationError(err))
+		_, err = service.CreateWorkspace(ctx, "no spaces", "")
+		assert.True(t, utils.IsValidationError(err))
+
+		id, err := service.ResolveWorkspace(ctx, "work")
+		require.NoError(t, err)
+		assert.Equal(t, work.ID, id)
+		id, err = service.ResolveWorkspace(ctx, "default")
+		require.NoError(t, err)
+		assert.Zero(t, id)
+		_, err = service.ResolveWorkspace(ctx, "nope")
+		assert.True(t, utils.IsNotFoundError(err))
+
+		memory, _ := storeTestMemory(t, service.InWorkspace(work.ID), "quarterly planning next week")
+		workspaces, err := service.ListWorkspaces(ctx)
+		require.NoError(t, err)
+		require.Len(t, workspaces, 2)
+		assert.Equal(t, models.DefaultWorkspace, workspaces[0].Name)
+		assert.Equal(t, "work", workspaces[1].Name)
+		assert.Equal(t, int64(1), workspaces[1].MemoryCount)
+
+		// Only empty workspaces are deleted
+		assert.True(t, utils.IsValidationError(service.DeleteWorkspace(ctx, "work")))
+		require.NoError(t, service.InWorkspace(work.ID).Delete(ctx, memory.ID))
+		require.NoError(t, service.DeleteWorkspace(ctx, "work"))
+		assert.True(t, utils.IsValidationError(service.DeleteWorkspace(ctx, "default")))
+
+		_, err = service.ResolveWorkspace(ctx, "work")
+		assert.True(t, utils.IsNotFoundError(err))
+	})
+}
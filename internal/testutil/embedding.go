@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// EmbeddingDimension matches the memories.embedding column
+const EmbeddingDimension = 1536
+
+// SeededEmbedding returns a unit-length embedding derived from seed. The same seed
+// always gives the same vector, and different seeds give nearly orthogonal ones.
+func SeededEmbedding(seed string) []float32 {
+	sum := sha256.Sum256([]byte(seed))
+	rng := rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(sum[:8]))))
+
+	vector := make([]float32, EmbeddingDimension)
+	for i := range vector {
+		vector[i] = float32(rng.NormFloat64())
+	}
+	return normalize(vector)
+}
+
+// SimilarEmbedding returns a unit-length embedding whose cosine similarity to the
+// embedding of seed is about similarity, for testing thresholds and ranking
+func SimilarEmbedding(seed string, similarity float64) []float32 {
+	base := SeededEmbedding(seed)
+	noise := SeededEmbedding(seed + "\x00noise")
+
+	// Remove the component of the noise along the base so the two are orthogonal
+	var dot float64
+	for i := range base {
+		dot += float64(base[i]) * float64(noise[i])
+	}
+	for i := range noise {
+		noise[i] -= float32(dot) * base[i]
+	}
+	noise = normalize(noise)
+
+	orthogonal := math.Sqrt(math.Max(0, 1-similarity*similarity))
+	vector := make([]float32, EmbeddingDimension)
+	for i := range vector {
+		vector[i] = float32(similarity)*base[i] + float32(orthogonal)*noise[i]
+	}
+	return normalize(vector)
+}
+
+// SeededVector returns SeededEmbedding(seed) as a pgvector value
+func SeededVector(seed string) pgvector.Vector {
+	return pgvector.NewVector(SeededEmbedding(seed))
+}
+
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return vector
+	}
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
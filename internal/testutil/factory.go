@@ -0,0 +1,255 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+// sequence numbers fixtures so defaults such as content and emails stay unique
+var sequence atomic.Int64
+
+func next() int64 {
+	return sequence.Add(1)
+}
+
+// MemoryBuilder builds a memory with sensible defaults: a unique personal fact of
+// medium priority owned by user 1, with no embedding
+type MemoryBuilder struct {
+	memory models.Memory
+}
+
+// NewMemory starts a memory builder with defaults
+func NewMemory() *MemoryBuilder {
+	return &MemoryBuilder{memory: models.Memory{
+		UserID:   1,
+		Type:     models.TypeFact,
+		Category: models.CategoryPersonal,
+		Priority: models.PriorityMedium,
+		Content:  fmt.Sprintf("test memory %d", next()),
+	}}
+}
+
+// ForUser sets the owning user
+func (b *MemoryBuilder) ForUser(userID uint) *MemoryBuilder {
+	b.memory.UserID = userID
+	return b
+}
+
+// Content sets the memory's content
+func (b *MemoryBuilder) Content(content string) *MemoryBuilder {
+	b.memory.Content = content
+	return b
+}
+
+// Type sets the memory type
+func (b *MemoryBuilder) Type(memoryType string) *MemoryBuilder {
+	b.memory.Type = memoryType
+	return b
+}
+
+// Category sets the memory category
+func (b *MemoryBuilder) Category(category string) *MemoryBuilder {
+	b.memory.Category = category
+	return b
+}
+
+// Priority sets the memory priority
+func (b *MemoryBuilder) Priority(priority string) *MemoryBuilder {
+	b.memory.Priority = priority
+	return b
+}
+
+// UpdateKey sets the memory's update key
+func (b *MemoryBuilder) UpdateKey(key string) *MemoryBuilder {
+	b.memory.UpdateKey = key
+	return b
+}
+
+// Tags sets the memory's tags
+func (b *MemoryBuilder) Tags(tags ...string) *MemoryBuilder {
+	b.memory.Tags = tags
+	return b
+}
+
+// Metadata sets the memory's metadata
+func (b *MemoryBuilder) Metadata(metadata map[string]interface{}) *MemoryBuilder {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		panic(fmt.Sprintf("testutil: invalid metadata: %v", err))
+	}
+	b.memory.Metadata = data
+	return b
+}
+
+// CreatedAt sets both timestamps, for tests that depend on ordering or age
+func (b *MemoryBuilder) CreatedAt(at time.Time) *MemoryBuilder {
+	b.memory.CreatedAt = at
+	b.memory.UpdatedAt = at
+	return b
+}
+
+// Embedding sets the memory's embedding
+func (b *MemoryBuilder) Embedding(vector []float32) *MemoryBuilder {
+	b.memory.Embedding = pgvector.NewVector(vector)
+	return b
+}
+
+// SeededEmbedding gives the memory the seeded embedding of its content
+func (b *MemoryBuilder) SeededEmbedding() *MemoryBuilder {
+	return b.Embedding(SeededEmbedding(b.memory.Content))
+}
+
+// Build returns the memory without saving it. The content hash is filled in.
+func (b *MemoryBuilder) Build() *models.Memory {
+	memory := b.memory
+	memory.ContentHash = models.HashContent(memory.Content)
+	return &memory
+}
+
+// Create saves the memory. On SQLite, or when no embedding was set, the
+// embedding column is left empty.
+func (b *MemoryBuilder) Create(t testing.TB, db *gorm.DB) *models.Memory {
+	t.Helper()
+
+	memory := b.Build()
+	query := db
+	if memory.Embedding.Slice() == nil || db.Dialector.Name() == "sqlite" {
+		query = query.Omit("embedding")
+	}
+	require.NoError(t, query.Create(memory).Error)
+	return memory
+}
+
+// UserBuilder builds a user with a unique email and the user role
+type UserBuilder struct {
+	user models.User
+}
+
+// NewUser starts a user builder with defaults
+func NewUser() *UserBuilder {
+	return &UserBuilder{user: models.User{
+		Email:    fmt.Sprintf("user%d@example.com", next()),
+		Password: "x",
+		Role:     models.RoleUser,
+	}}
+}
+
+// ID sets the user's ID, for tests that rely on fixed IDs
+func (b *UserBuilder) ID(id uint) *UserBuilder {
+	b.user.ID = id
+	return b
+}
+
+// Email sets the user's email
+func (b *UserBuilder) Email(email string) *UserBuilder {
+	b.user.Email = email
+	return b
+}
+
+// Admin gives the user the admin role
+func (b *UserBuilder) Admin() *UserBuilder {
+	b.user.Role = models.RoleAdmin
+	return b
+}
+
+// EvictionPolicy sets the user's eviction policy override
+func (b *UserBuilder) EvictionPolicy(policy string) *UserBuilder {
+	b.user.EvictionPolicy = policy
+	return b
+}
+
+// Build returns the user without saving it
+func (b *UserBuilder) Build() *models.User {
+	user := b.user
+	return &user
+}
+
+// Create saves the user
+func (b *UserBuilder) Create(t testing.TB, db *gorm.DB) *models.User {
+	t.Helper()
+
+	user := b.Build()
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+// APIKeyBuilder builds an active standard API key with a random key
+type APIKeyBuilder struct {
+	apiKey models.APIKey
+}
+
+// NewAPIKey starts an API key builder for the given user
+func NewAPIKey(userID uint) *APIKeyBuilder {
+	b := &APIKeyBuilder{apiKey: models.APIKey{
+		UserID:   userID,
+		Key:      randomKey(),
+		Name:     fmt.Sprintf("test key %d", next()),
+		IsActive: true,
+		KeyType:  models.APIKeyTypeStandard,
+	}}
+	b.apiKey.SetPermissions(models.APIKeyTypePermissions[models.APIKeyTypeStandard])
+	return b
+}
+
+// Name sets the key's name
+func (b *APIKeyBuilder) Name(name string) *APIKeyBuilder {
+	b.apiKey.Name = name
+	return b
+}
+
+// Permissions replaces the key's permissions
+func (b *APIKeyBuilder) Permissions(permissions ...string) *APIKeyBuilder {
+	b.apiKey.SetPermissions(permissions)
+	return b
+}
+
+// ExpiresAt sets when the key expires
+func (b *APIKeyBuilder) ExpiresAt(at time.Time) *APIKeyBuilder {
+	b.apiKey.ExpiresAt = &at
+	return b
+}
+
+// Inactive revokes the key
+func (b *APIKeyBuilder) Inactive() *APIKeyBuilder {
+	b.apiKey.IsActive = false
+	return b
+}
+
+// Build returns the API key without saving it
+func (b *APIKeyBuilder) Build() *models.APIKey {
+	apiKey := b.apiKey
+	return &apiKey
+}
+
+// Create saves the API key. IsActive defaults to true in the schema, so an
+// inactive key is updated after it is created.
+func (b *APIKeyBuilder) Create(t testing.TB, db *gorm.DB) *models.APIKey {
+	t.Helper()
+
+	apiKey := b.Build()
+	require.NoError(t, db.Create(apiKey).Error)
+	if !b.apiKey.IsActive {
+		require.NoError(t, db.Model(apiKey).Update("is_active", false).Error)
+		apiKey.IsActive = false
+	}
+	return apiKey
+}
+
+func randomKey() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("testutil: failed to generate API key: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
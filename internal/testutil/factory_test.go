@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func TestSeededEmbedding(t *testing.T) {
+	a := SeededEmbedding("alpha")
+	assert.Len(t, a, EmbeddingDimension)
+	assert.Equal(t, a, SeededEmbedding("alpha"))
+	assert.InDelta(t, 1.0, dot(a, a), 1e-5)
+	assert.InDelta(t, 0.0, dot(a, SeededEmbedding("beta")), 0.1)
+
+	similar := SimilarEmbedding("alpha", 0.9)
+	assert.InDelta(t, 1.0, dot(similar, similar), 1e-5)
+	assert.InDelta(t, 0.9, dot(a, similar), 1e-3)
+}
+
+func TestBuilders(t *testing.T) {
+	first := NewMemory().Build()
+	second := NewMemory().ForUser(2).Tags("work").Metadata(map[string]interface{}{"source": "test"}).Build()
+	assert.NotEqual(t, first.Content, second.Content)
+	assert.Equal(t, uint(1), first.UserID)
+	assert.Equal(t, models.HashContent(first.Content), first.ContentHash)
+	assert.Equal(t, uint(2), second.UserID)
+	assert.JSONEq(t, `{"source":"test"}`, string(second.Metadata))
+
+	assert.NotEqual(t, NewUser().Build().Email, NewUser().Build().Email)
+	assert.True(t, NewUser().Admin().Build().IsAdmin())
+
+	apiKey := NewAPIKey(2).Build()
+	assert.Len(t, apiKey.Key, 64)
+	assert.True(t, apiKey.IsActive)
+	assert.Equal(t, models.APIKeyTypePermissions[models.APIKeyTypeStandard], apiKey.GetPermissions())
+	assert.Equal(t, []string{models.PermissionMemoryRead},
+		NewAPIKey(2).Permissions(models.PermissionMemoryRead).Build().GetPermissions())
+}
+
+func TestWithSearchPath(t *testing.T) {
+	assert.Equal(t, "host=db search_path=s,public", withSearchPath("host=db", "s"))
+	assert.Equal(t, "postgres://db/x?search_path=s,public", withSearchPath("postgres://db/x", "s"))
+	assert.Equal(t, "postgres://db/x?sslmode=disable&search_path=s,public", withSearchPath("postgres://db/x?sslmode=disable", "s"))
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
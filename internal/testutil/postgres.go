@@ -0,0 +1,196 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+)
+
+// PostgresImage is the pgvector image the shared test container runs, matching
+// docker-compose.yml
+const PostgresImage = "pgvector/pgvector:pg15"
+
+// postgresStartTimeout bounds how long the shared container may take to accept
+// connections
+const postgresStartTimeout = 60 * time.Second
+
+var (
+	postgresOnce      sync.Once
+	postgresDSN       string
+	postgresContainer string
+	postgresErr       error
+	schemaCounter     atomic.Int64
+)
+
+// PostgresDB returns a migrated PostgreSQL database with pgvector, isolated in a
+// schema of its own that is dropped when the test ends. It connects to
+// TEST_DATABASE_URL when set; otherwise it starts a pgvector container through
+// the docker CLI, shared by every test in the binary. The test is skipped in
+// short mode and when neither is available.
+//
+// Packages using it should stop the container from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		testutil.StopPostgres()
+//		os.Exit(code)
+//	}
+func PostgresDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping PostgreSQL test in short mode")
+	}
+
+	postgresOnce.Do(startPostgres)
+	if postgresErr != nil {
+		t.Skipf("PostgreSQL is not available: %v", postgresErr)
+	}
+
+	admin, err := openPostgres(postgresDSN)
+	require.NoError(t, err)
+	defer closeDB(admin)
+
+	schema := fmt.Sprintf("test_%d_%d", os.Getpid(), schemaCounter.Add(1))
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+
+	db, err := openPostgres(withSearchPath(postgresDSN, schema))
+	require.NoError(t, err)
+	require.NoError(t, database.RunMigrations(db))
+
+	t.Cleanup(func() {
+		closeDB(db)
+		if admin, err := openPostgres(postgresDSN); err == nil {
+			admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+			closeDB(admin)
+		}
+	})
+
+	return db
+}
+
+// StopPostgres removes the shared container, if one was started
+func StopPostgres() {
+	if postgresContainer != "" {
+		exec.Command("docker", "rm", "-f", postgresContainer).Run()
+	}
+}
+
+// startPostgres resolves the DSN of the shared database, starting a container if
+// no database was configured, and installs the vector extension
+func startPostgres() {
+	postgresDSN = os.Getenv("TEST_DATABASE_URL")
+	if postgresDSN == "" {
+		postgresDSN, postgresErr = runPostgresContainer()
+		if postgresErr != nil {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postgresStartTimeout)
+	defer cancel()
+
+	for {
+		db, err := openPostgres(postgresDSN)
+		if err == nil {
+			// The extension lives in public so every test schema can use it
+			err = db.Exec("CREATE EXTENSION IF NOT EXISTS vector SCHEMA public").Error
+			closeDB(db)
+			if err == nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			postgresErr = fmt.Errorf("database did not become ready: %w", err)
+			StopPostgres()
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// runPostgresContainer starts the pgvector image on a random local port and
+// returns its DSN
+func runPostgresContainer() (string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", fmt.Errorf("TEST_DATABASE_URL is not set and docker is not installed")
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_USER=postgres",
+		"-e", "POSTGRES_PASSWORD=postgres",
+		"-e", "POSTGRES_DB=remember_me_test",
+		"-p", "127.0.0.1::5432",
+		PostgresImage,
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to start %s: %w", PostgresImage, err)
+	}
+	postgresContainer = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", postgresContainer, "5432/tcp").Output()
+	if err != nil {
+		StopPostgres()
+		return "", fmt.Errorf("failed to find container port: %w", err)
+	}
+	// docker port may list several bindings; the first is enough
+	address := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	host, port, ok := strings.Cut(address, ":")
+	if !ok {
+		StopPostgres()
+		return "", fmt.Errorf("unexpected container port %q", address)
+	}
+
+	return fmt.Sprintf("host=%s port=%s user=postgres password=postgres dbname=remember_me_test sslmode=disable", host, port), nil
+}
+
+// withSearchPath points a DSN, in URL or key/value form, at the given schema with
+// public as a fallback for the vector type
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema + ",public"
+	}
+	return dsn + " search_path=" + schema + ",public"
+}
+
+func openPostgres(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(database.NewWorkspaceScope()); err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	if err := db.Exec("SELECT 1").Error; err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,89 @@
+// Package testutil provides shared fixtures for tests: database helpers, builders
+// for memories, users and API keys with sensible defaults, and deterministic
+// embeddings. It is only imported from _test.go files.
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ksred/remember-me-mcp/internal/database"
+)
+
+// MemoriesTableSQLite creates the memories table without pgvector types, for
+// SQLite test databases. Embeddings are stored as opaque blobs and tags as text.
+const MemoriesTableSQLite = `
+	CREATE TABLE memories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL DEFAULT 1,
+		workspace_id INTEGER NOT NULL DEFAULT 0,
+		type TEXT NOT NULL,
+		category TEXT NOT NULL,
+		content TEXT NOT NULL,
+		encrypted_content TEXT,
+		is_encrypted BOOLEAN DEFAULT FALSE,
+		priority TEXT DEFAULT 'medium',
+		update_key TEXT,
+		content_hash TEXT,
+		content_index TEXT,
+		keyword_index TEXT,
+		access_count INTEGER NOT NULL DEFAULT 0,
+		last_accessed_at DATETIME,
+		session_id TEXT,
+		embedding BLOB,
+		tags TEXT,
+		metadata TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
+	)
+`
+
+// MemoryChunksTableSQLite creates the memory_chunks table without pgvector types,
+// for SQLite test databases
+const MemoryChunksTableSQLite = `
+	CREATE TABLE memory_chunks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		memory_id INTEGER NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		start INTEGER NOT NULL,
+		length INTEGER NOT NULL,
+		embedding BLOB,
+		created_at DATETIME,
+		UNIQUE (memory_id, position)
+	)
+`
+
+// SQLiteDB creates an in-memory SQLite database with the memories and
+// memory_chunks tables and auto-migrates any other models given. SQLite has no
+// vector type, so semantic search needs PostgresDB.
+func SQLiteDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(database.NewWorkspaceScope()))
+
+	require.NoError(t, db.Exec(MemoriesTableSQLite).Error)
+	require.NoError(t, db.Exec(`CREATE INDEX idx_memories_type ON memories(type)`).Error)
+	require.NoError(t, db.Exec(`CREATE INDEX idx_memories_category ON memories(category)`).Error)
+	require.NoError(t, db.Exec(MemoryChunksTableSQLite).Error)
+
+	if len(models) > 0 {
+		require.NoError(t, db.AutoMigrate(models...))
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
@@ -0,0 +1,100 @@
+// Package tracing sets up OpenTelemetry tracing for the servers and starts the
+// spans the MCP, HTTP and service layers record. Database queries are traced by
+// the otelgorm plugin, registered when the database connects.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+)
+
+// instrumentationName names the tracer the application's own spans come from
+const instrumentationName = "github.com/ksred/remember-me-mcp"
+
+// Setup installs a tracer provider exporting spans to the configured OTLP
+// collector and returns the function that flushes and stops it. With tracing
+// disabled nothing is installed and spans are no-ops. serviceName is used when
+// the configuration does not name the service.
+func Setup(ctx context.Context, cfg config.Tracing, serviceName string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.ServiceName != "" {
+		serviceName = cfg.ServiceName
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service for traces: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span, as a child of the span in ctx if there is one
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartToolCall starts the span of an MCP tool call, over stdio or HTTP
+func StartToolCall(ctx context.Context, tool string) (context.Context, trace.Span) {
+	return Start(ctx, "tools/call "+tool,
+		attribute.String("mcp.method.name", "tools/call"),
+		attribute.String("mcp.tool.name", tool),
+	)
+}
+
+// StartEmbedding starts the span of a request to an embedding provider
+func StartEmbedding(ctx context.Context, provider, model string, inputs int) (context.Context, trace.Span) {
+	return Start(ctx, "embeddings "+model,
+		attribute.String("gen_ai.operation.name", "embeddings"),
+		attribute.String("gen_ai.system", provider),
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("embedding.inputs", inputs),
+	)
+}
+
+// End marks the span failed when err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxBlindIndexWords caps the distinct words of one text that are indexed
+	maxBlindIndexWords = 1024
+	// blindWordSize is how many bytes of each word's HMAC are kept. Truncating
+	// makes unrelated words collide now and then, which only adds false matches.
+	blindWordSize = 8
+)
+
+// BlindIndex computes keyed hashes of plaintext, so encrypted content can still
+// be matched exactly and by keyword without decrypting it. The key is derived for
+// one user, so equal texts of different users hash differently, and hashes cannot
+// be checked against guessed texts without the key.
+type BlindIndex struct {
+	key []byte
+}
+
+// BlindIndex returns the blind index of a user's content, derived from this
+// service's key
+func (s *EncryptionService) BlindIndex(userID uint) (*BlindIndex, error) {
+	key, err := s.DeriveKey(nil, []byte(fmt.Sprintf("remember-me:blind-index:user:%d", userID)))
+	if err != nil {
+		return nil, err
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// Content returns the hash matching texts exactly equal to content
+func (b *BlindIndex) Content(content string) string {
+	return hex.EncodeToString(b.sum("content:" + content))
+}
+
+// Keywords returns the hashes of the distinct words of content, separated and
+// surrounded by spaces so each can be matched with LIKE '% hash %'
+func (b *BlindIndex) Keywords(content string) string {
+	words := b.Words(content)
+	if len(words) == 0 {
+		return ""
+	}
+	return " " + strings.Join(words, " ") + " "
+}
+
+// Words returns the hashes of the distinct words of text, in the order they
+// first appear. Words are compared case-insensitively and split on anything but
+// letters and digits.
+func (b *BlindIndex) Words(text string) []string {
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		hashes = append(hashes, hex.EncodeToString(b.sum("word:" + word)[:blindWordSize]))
+		if len(hashes) == maxBlindIndexWords {
+			break
+		}
+	}
+	return hashes
+}
+
+func (b *BlindIndex) sum(value string) []byte {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
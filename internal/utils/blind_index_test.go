@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestBlindIndex(t *testing.T) {
+	masterKey, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Failed to generate master key: %v", err)
+	}
+	service, err := NewEncryptionService(masterKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+
+	index, err := service.BlindIndex(2)
+	if err != nil {
+		t.Fatalf("Failed to derive blind index: %v", err)
+	}
+	same, _ := service.BlindIndex(2)
+	other, _ := service.BlindIndex(3)
+
+	if index.Content("Apollo launch") != same.Content("Apollo launch") {
+		t.Error("Same user and content should produce the same hash")
+	}
+	if index.Content("Apollo launch") == index.Content("apollo launch") {
+		t.Error("Content hashes should be exact")
+	}
+	if index.Content("Apollo launch") == other.Content("Apollo launch") {
+		t.Error("Different users should produce different hashes")
+	}
+
+	words := index.Words("Apollo's launch, apollo LAUNCH!")
+	if len(words) != 3 {
+		t.Fatalf("Expected 3 distinct words (apollo, s, launch), got %d", len(words))
+	}
+	if upper := index.Words("APOLLO"); len(upper) != 1 || upper[0] != words[0] {
+		t.Error("Words should be compared ignoring case")
+	}
+	if keywords := index.Keywords("Apollo's launch"); keywords != " "+words[0]+" "+words[1]+" "+words[2]+" " {
+		t.Errorf("Unexpected keywords %q", keywords)
+	}
+	if keywords := index.Keywords("  --  "); keywords != "" {
+		t.Errorf("Expected no keywords, got %q", keywords)
+	}
+}
@@ -3,12 +3,15 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"golang.org/x/crypto/hkdf"
 )
@@ -22,41 +25,164 @@ const (
 	SaltSize = 32
 )
 
-// EncryptionService handles field-level encryption for sensitive data
+// Envelope versions of EncryptedData
+const (
+	// EnvelopeV1 is the original envelope, written without a version field and
+	// sealed without additional authenticated data
+	EnvelopeV1 = 1
+	// EnvelopeV2 seals the ciphertext with additional authenticated data naming
+	// what it belongs to, so it cannot be moved to another record
+	EnvelopeV2 = 2
+	// EnvelopeV3 is sealed as EnvelopeV2 is, but callers name the record's own ID
+	// in the additional authenticated data, where EnvelopeV2 data may name only
+	// its owner
+	EnvelopeV3 = 3
+	// CurrentEnvelopeVersion is the version EncryptFieldWithAAD writes
+	CurrentEnvelopeVersion = EnvelopeV3
+)
+
+// ErrUnsupportedEnvelope is returned when decrypting an envelope version this
+// build does not know
+var ErrUnsupportedEnvelope = errors.New("unsupported encrypted data version")
+
+// ErrUnknownKey is returned when decrypting data whose key ID matches none of the
+// service's master keys
+var ErrUnknownKey = errors.New("encrypted with an unknown master key")
+
+// EncryptionService handles field-level encryption for sensitive data. It holds
+// the current master key, which encrypts, and any previous master keys, which
+// only decrypt, so the master key can be rotated while servers keep running.
 type EncryptionService struct {
 	masterKey []byte
+	keyID     string
+	// previousKeys decrypt data encrypted before the master key was rotated
+	previousKeys []masterKey
+	// dataKeys caches the services for keys unwrapped by UnwrapKey
+	dataKeys sync.Map
 }
 
-// NewEncryptionService creates a new encryption service with the provided master key
-func NewEncryptionService(masterKeyBase64 string) (*EncryptionService, error) {
+// masterKey is a master key together with its ID
+type masterKey struct {
+	id  string
+	key []byte
+}
+
+// NewEncryptionService creates a new encryption service with the provided master
+// key. Previous master keys, newest first, are kept for decrypting data written
+// before the rotation to the current key.
+func NewEncryptionService(masterKeyBase64 string, previousKeysBase64 ...string) (*EncryptionService, error) {
 	if masterKeyBase64 == "" {
 		return nil, errors.New("master key cannot be empty")
 	}
 
-	masterKey, err := base64.StdEncoding.DecodeString(masterKeyBase64)
+	key, err := decodeMasterKey(masterKeyBase64)
+	if err != nil {
+		return nil, err
+	}
+	service := &EncryptionService{
+		masterKey: key,
+		keyID:     keyIDOf(key),
+	}
+
+	for i, previousBase64 := range previousKeysBase64 {
+		previous, err := decodeMasterKey(previousBase64)
+		if err != nil {
+			return nil, fmt.Errorf("previous key %d: %w", i+1, err)
+		}
+		if service.findKey(keyIDOf(previous)) != nil {
+			continue
+		}
+		service.previousKeys = append(service.previousKeys, masterKey{id: keyIDOf(previous), key: previous})
+	}
+	return service, nil
+}
+
+// decodeMasterKey decodes a base64 master key
+func decodeMasterKey(masterKeyBase64 string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(masterKeyBase64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid master key format: %w", err)
 	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// keyIDOf identifies a master key without revealing it: the first 8 bytes of an
+// HMAC of a fixed label under the key, hex encoded
+func keyIDOf(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("remember-me:key-id"))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// KeyID returns the ID of the current master key, which new data is encrypted with
+func (s *EncryptionService) KeyID() string {
+	return s.keyID
+}
 
-	if len(masterKey) != KeySize {
-		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(masterKey))
+// KeyIDs returns the IDs of every master key held, the current one first
+func (s *EncryptionService) KeyIDs() []string {
+	ids := []string{s.keyID}
+	for _, previous := range s.previousKeys {
+		ids = append(ids, previous.id)
 	}
+	return ids
+}
 
-	return &EncryptionService{
-		masterKey: masterKey,
-	}, nil
+// findKey returns the master key with the given ID, or nil
+func (s *EncryptionService) findKey(id string) []byte {
+	if id == s.keyID {
+		return s.masterKey
+	}
+	for _, previous := range s.previousKeys {
+		if previous.id == id {
+			return previous.key
+		}
+	}
+	return nil
 }
 
 // EncryptedData contains all the components needed to decrypt data
 type EncryptedData struct {
-	Ciphertext    string `json:"ciphertext"`     // Base64 encoded encrypted data
-	EncryptedKey  string `json:"encrypted_key"`  // Base64 encoded encrypted data key
-	Nonce         string `json:"nonce"`          // Base64 encoded GCM nonce
-	KeyNonce      string `json:"key_nonce"`      // Base64 encoded nonce for key encryption
+	Version      int    `json:"version,omitempty"` // Envelope version; absent for EnvelopeV1
+	Ciphertext   string `json:"ciphertext"`        // Base64 encoded encrypted data
+	EncryptedKey string `json:"encrypted_key"`     // Base64 encoded encrypted data key
+	Nonce        string `json:"nonce"`             // Base64 encoded GCM nonce
+	KeyNonce     string `json:"key_nonce"`         // Base64 encoded nonce for key encryption
+	// KeyID identifies the master key the data key is encrypted with; absent for
+	// data encrypted before key IDs, which is tried with every key
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// EnvelopeVersion returns the envelope's version, treating a missing version as EnvelopeV1
+func (d *EncryptedData) EnvelopeVersion() int {
+	if d.Version == 0 {
+		return EnvelopeV1
+	}
+	return d.Version
 }
 
-// EncryptField encrypts a field value using a unique data key
+// NeedsUpgrade reports whether the envelope predates CurrentEnvelopeVersion
+func (d *EncryptedData) NeedsUpgrade() bool {
+	return d.EnvelopeVersion() < CurrentEnvelopeVersion
+}
+
+// EncryptField encrypts a field value using a unique data key. The envelope is
+// EnvelopeV1; use EncryptFieldWithAAD to bind the ciphertext to its record.
 func (s *EncryptionService) EncryptField(plaintext string) (*EncryptedData, error) {
+	return s.encryptField(plaintext, nil, EnvelopeV1)
+}
+
+// EncryptFieldWithAAD encrypts a field value using a unique data key and binds it
+// to aad, which must be passed again to decrypt it
+func (s *EncryptionService) EncryptFieldWithAAD(plaintext string, aad []byte) (*EncryptedData, error) {
+	return s.encryptField(plaintext, aad, CurrentEnvelopeVersion)
+}
+
+// encryptField seals plaintext in an envelope of the given version
+func (s *EncryptionService) encryptField(plaintext string, aad []byte, version int) (*EncryptedData, error) {
 	if plaintext == "" {
 		return nil, errors.New("plaintext cannot be empty")
 	}
@@ -92,27 +218,48 @@ func (s *EncryptionService) EncryptField(plaintext string) (*EncryptedData, erro
 	}
 
 	// Encrypt the plaintext
-	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), nil)
+	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), aad)
 
 	// Clear the data key from memory
 	for i := range dataKey {
 		dataKey[i] = 0
 	}
 
-	return &EncryptedData{
+	envelope := &EncryptedData{
 		Ciphertext:   base64.StdEncoding.EncodeToString(ciphertext),
 		EncryptedKey: base64.StdEncoding.EncodeToString(encryptedKey),
 		Nonce:        base64.StdEncoding.EncodeToString(nonce),
 		KeyNonce:     base64.StdEncoding.EncodeToString(keyNonce),
-	}, nil
+		KeyID:        s.keyID,
+	}
+	if version != EnvelopeV1 {
+		envelope.Version = version
+	}
+	return envelope, nil
 }
 
-// DecryptField decrypts an encrypted field value
+// DecryptField decrypts an encrypted field value sealed without additional
+// authenticated data
 func (s *EncryptionService) DecryptField(data *EncryptedData) (string, error) {
+	return s.DecryptFieldWithAAD(data, nil)
+}
+
+// DecryptFieldWithAAD decrypts an encrypted field value. EnvelopeV2 and later
+// fields only decrypt with the aad they were encrypted with; EnvelopeV1 fields
+// carry none, so aad is ignored for them.
+func (s *EncryptionService) DecryptFieldWithAAD(data *EncryptedData, aad []byte) (string, error) {
 	if data == nil {
 		return "", errors.New("encrypted data cannot be nil")
 	}
 
+	switch data.EnvelopeVersion() {
+	case EnvelopeV1:
+		aad = nil
+	case EnvelopeV2, EnvelopeV3:
+	default:
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedEnvelope, data.Version)
+	}
+
 	// Decode base64 values
 	ciphertext, err := base64.StdEncoding.DecodeString(data.Ciphertext)
 	if err != nil {
@@ -135,7 +282,7 @@ func (s *EncryptionService) DecryptField(data *EncryptedData) (string, error) {
 	}
 
 	// Decrypt the data key
-	dataKey, err := s.decryptDataKey(encryptedKey, keyNonce)
+	dataKey, err := s.decryptDataKey(encryptedKey, keyNonce, data.KeyID)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt data key: %w", err)
 	}
@@ -161,7 +308,7 @@ func (s *EncryptionService) DecryptField(data *EncryptedData) (string, error) {
 	}
 
 	// Decrypt the ciphertext
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
 		// Clear the data key before returning
 		for i := range dataKey {
@@ -178,7 +325,7 @@ func (s *EncryptionService) DecryptField(data *EncryptedData) (string, error) {
 	return string(plaintext), nil
 }
 
-// encryptDataKey encrypts a data key using the master key
+// encryptDataKey encrypts a data key using the current master key
 func (s *EncryptionService) encryptDataKey(dataKey []byte) ([]byte, []byte, error) {
 	// Create AES cipher with master key
 	block, err := aes.NewCipher(s.masterKey)
@@ -204,10 +351,31 @@ func (s *EncryptionService) encryptDataKey(dataKey []byte) ([]byte, []byte, erro
 	return encryptedKey, nonce, nil
 }
 
-// decryptDataKey decrypts a data key using the master key
-func (s *EncryptionService) decryptDataKey(encryptedKey, nonce []byte) ([]byte, error) {
+// decryptDataKey decrypts a data key using the master key with the given ID, or
+// each master key in turn when the ID is empty
+func (s *EncryptionService) decryptDataKey(encryptedKey, nonce []byte, keyID string) ([]byte, error) {
+	if keyID != "" {
+		key := s.findKey(keyID)
+		if key == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+		}
+		return openDataKey(key, encryptedKey, nonce)
+	}
+
+	dataKey, err := openDataKey(s.masterKey, encryptedKey, nonce)
+	for _, previous := range s.previousKeys {
+		if err == nil {
+			break
+		}
+		dataKey, err = openDataKey(previous.key, encryptedKey, nonce)
+	}
+	return dataKey, err
+}
+
+// openDataKey decrypts a data key using a master key
+func openDataKey(masterKey, encryptedKey, nonce []byte) ([]byte, error) {
 	// Create AES cipher with master key
-	block, err := aes.NewCipher(s.masterKey)
+	block, err := aes.NewCipher(masterKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
@@ -227,7 +395,143 @@ func (s *EncryptionService) decryptDataKey(encryptedKey, nonce []byte) ([]byte,
 	return dataKey, nil
 }
 
-// DeriveKey derives a key from the master key using HKDF
+// RewrapField re-encrypts a field's data key under another service's current
+// master key. The ciphertext is left as it is, so the plaintext is never exposed
+// while the master key is rotated.
+func (s *EncryptionService) RewrapField(data *EncryptedData, to *EncryptionService) (*EncryptedData, error) {
+	if data == nil {
+		return nil, errors.New("encrypted data cannot be nil")
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(data.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted key: %w", err)
+	}
+
+	keyNonce, err := base64.StdEncoding.DecodeString(data.KeyNonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key nonce: %w", err)
+	}
+
+	dataKey, err := s.decryptDataKey(encryptedKey, keyNonce, data.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+
+	rewrapped, newKeyNonce, err := to.encryptDataKey(dataKey)
+
+	// Clear the data key from memory
+	for i := range dataKey {
+		dataKey[i] = 0
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+
+	return &EncryptedData{
+		Version:      data.Version,
+		Ciphertext:   data.Ciphertext,
+		EncryptedKey: base64.StdEncoding.EncodeToString(rewrapped),
+		Nonce:        data.Nonce,
+		KeyNonce:     base64.StdEncoding.EncodeToString(newKeyNonce),
+		KeyID:        to.keyID,
+	}, nil
+}
+
+// OwnsField reports whether a field's data key was encrypted with one of this
+// service's master keys
+func (s *EncryptionService) OwnsField(data *EncryptedData) bool {
+	if data == nil {
+		return false
+	}
+	if data.KeyID != "" && s.findKey(data.KeyID) == nil {
+		return false
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(data.EncryptedKey)
+	if err != nil {
+		return false
+	}
+
+	keyNonce, err := base64.StdEncoding.DecodeString(data.KeyNonce)
+	if err != nil {
+		return false
+	}
+
+	dataKey, err := s.decryptDataKey(encryptedKey, keyNonce, data.KeyID)
+	if err != nil {
+		return false
+	}
+	for i := range dataKey {
+		dataKey[i] = 0
+	}
+	return true
+}
+
+// UsesCurrentKey reports whether a field's data key was encrypted with this
+// service's current master key, so it needs no rewrapping after a rotation
+func (s *EncryptionService) UsesCurrentKey(data *EncryptedData) bool {
+	if data == nil {
+		return false
+	}
+	if data.KeyID != "" {
+		return data.KeyID == s.keyID
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(data.EncryptedKey)
+	if err != nil {
+		return false
+	}
+	keyNonce, err := base64.StdEncoding.DecodeString(data.KeyNonce)
+	if err != nil {
+		return false
+	}
+	dataKey, err := s.decryptDataKey(encryptedKey, keyNonce, s.keyID)
+	if err != nil {
+		return false
+	}
+	for i := range dataKey {
+		dataKey[i] = 0
+	}
+	return true
+}
+
+// NewWrappedKey generates a key for a separate encryption service, such as one
+// per user, and returns it encrypted with this service's master key
+func (s *EncryptionService) NewWrappedKey() (*EncryptedData, error) {
+	key, err := GenerateMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	return s.EncryptField(key)
+}
+
+// UnwrapKey decrypts a key created by NewWrappedKey and returns an encryption
+// service using it. Services are cached, so unwrapping the same key is cheap.
+func (s *EncryptionService) UnwrapKey(wrapped *EncryptedData) (*EncryptionService, error) {
+	if wrapped == nil {
+		return nil, errors.New("wrapped key cannot be nil")
+	}
+
+	cacheKey := wrapped.EncryptedKey + ":" + wrapped.Ciphertext
+	if service, ok := s.dataKeys.Load(cacheKey); ok {
+		return service.(*EncryptionService), nil
+	}
+
+	key, err := s.DecryptField(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+	service, err := NewEncryptionService(key)
+	if err != nil {
+		return nil, err
+	}
+	s.dataKeys.Store(cacheKey, service)
+	return service, nil
+}
+
+// DeriveKey derives a key from the current master key using HKDF
 func (s *EncryptionService) DeriveKey(salt []byte, info []byte) ([]byte, error) {
 	hash := sha256.New
 	hkdfReader := hkdf.New(hash, s.masterKey, salt, info)
@@ -240,6 +544,25 @@ func (s *EncryptionService) DeriveKey(salt []byte, info []byte) ([]byte, error)
 	return key, nil
 }
 
+// DeriveKeys derives a key from each master key using HKDF, the current key's
+// first, for opening data sealed with a key derived before a rotation
+func (s *EncryptionService) DeriveKeys(salt []byte, info []byte) ([][]byte, error) {
+	masterKeys := [][]byte{s.masterKey}
+	for _, previous := range s.previousKeys {
+		masterKeys = append(masterKeys, previous.key)
+	}
+
+	keys := make([][]byte, 0, len(masterKeys))
+	for _, masterKey := range masterKeys {
+		key := make([]byte, KeySize)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, salt, info), key); err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 // GenerateMasterKey generates a new random master key
 func GenerateMasterKey() (string, error) {
 	key := make([]byte, KeySize)
@@ -247,4 +570,4 @@ func GenerateMasterKey() (string, error) {
 		return "", fmt.Errorf("failed to generate key: %w", err)
 	}
 	return base64.StdEncoding.EncodeToString(key), nil
-}
\ No newline at end of file
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -287,4 +289,179 @@ func TestDeriveKey(t *testing.T) {
 	if string(key1) == string(key3) {
 		t.Error("Different salt should produce different derived key")
 	}
-}
\ No newline at end of file
+}
+func TestEncryptWithAAD(t *testing.T) {
+	masterKey, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Failed to generate master key: %v", err)
+	}
+
+	service, err := NewEncryptionService(masterKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+
+	aad := []byte("memory:user:2")
+	bound, err := service.EncryptFieldWithAAD("secret", aad)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if bound.EnvelopeVersion() != EnvelopeV3 || bound.NeedsUpgrade() {
+		t.Errorf("Expected a current v3 envelope, got version %d", bound.EnvelopeVersion())
+	}
+
+	decrypted, err := service.DecryptFieldWithAAD(bound, aad)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if decrypted != "secret" {
+		t.Errorf("Expected 'secret', got %q", decrypted)
+	}
+
+	// Bound ciphertext does not open for another record
+	if _, err := service.DecryptFieldWithAAD(bound, []byte("memory:user:3")); err == nil {
+		t.Error("Expected decrypting with other AAD to fail")
+	}
+	if _, err := service.DecryptField(bound); err == nil {
+		t.Error("Expected decrypting without AAD to fail")
+	}
+
+	// Legacy envelopes carry no version and ignore AAD
+	legacy, err := service.EncryptField("old secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if legacy.Version != 0 || !legacy.NeedsUpgrade() {
+		t.Errorf("Expected an unversioned envelope needing upgrade, got version %d", legacy.Version)
+	}
+	decrypted, err = service.DecryptFieldWithAAD(legacy, aad)
+	if err != nil {
+		t.Fatalf("Failed to decrypt legacy envelope: %v", err)
+	}
+	if decrypted != "old secret" {
+		t.Errorf("Expected 'old secret', got %q", decrypted)
+	}
+
+	// Rewrapping keeps the version
+	other, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Failed to generate master key: %v", err)
+	}
+	otherService, err := NewEncryptionService(other)
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+	rewrapped, err := service.RewrapField(bound, otherService)
+	if err != nil {
+		t.Fatalf("Failed to rewrap: %v", err)
+	}
+	if _, err := otherService.DecryptFieldWithAAD(rewrapped, aad); err != nil {
+		t.Errorf("Failed to decrypt rewrapped envelope: %v", err)
+	}
+
+	future := *bound
+	future.Version = 99
+	if _, err := service.DecryptFieldWithAAD(&future, aad); !errors.Is(err, ErrUnsupportedEnvelope) {
+		t.Errorf("Expected ErrUnsupportedEnvelope, got %v", err)
+	}
+}
+
+func TestPreviousMasterKeys(t *testing.T) {
+	oldKey, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Failed to generate master key: %v", err)
+	}
+	newKey, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Failed to generate master key: %v", err)
+	}
+	oldService, err := NewEncryptionService(oldKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+	service, err := NewEncryptionService(newKey, oldKey, newKey, oldKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption service: %v", err)
+	}
+	if ids := service.KeyIDs(); len(ids) != 2 || ids[0] != service.KeyID() || ids[1] != oldService.KeyID() {
+		t.Errorf("Expected the current and previous key IDs without duplicates, got %v", ids)
+	}
+	if _, err := NewEncryptionService(newKey, "not-base64!@#$"); err == nil {
+		t.Error("Expected an invalid previous key to fail")
+	}
+
+	old, err := oldService.EncryptField("old secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if old.KeyID != oldService.KeyID() {
+		t.Errorf("Expected key ID %s, got %q", oldService.KeyID(), old.KeyID)
+	}
+	decrypted, err := service.DecryptField(old)
+	if err != nil {
+		t.Fatalf("Failed to decrypt with a previous key: %v", err)
+	}
+	if decrypted != "old secret" {
+		t.Errorf("Expected 'old secret', got %q", decrypted)
+	}
+	if !service.OwnsField(old) || service.UsesCurrentKey(old) {
+		t.Error("Expected data under a previous key to be owned but not current")
+	}
+
+	// New data is encrypted with the current key
+	current, err := service.EncryptField("new secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if current.KeyID != service.KeyID() || !service.UsesCurrentKey(current) {
+		t.Errorf("Expected the current key ID, got %q", current.KeyID)
+	}
+	if _, err := oldService.DecryptField(current); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	if oldService.OwnsField(current) {
+		t.Error("Expected data under an unknown key not to be owned")
+	}
+
+	// Rewrapping moves data to the current key
+	rewrapped, err := service.RewrapField(old, service)
+	if err != nil {
+		t.Fatalf("Failed to rewrap: %v", err)
+	}
+	if !service.UsesCurrentKey(rewrapped) {
+		t.Error("Expected rewrapped data to use the current key")
+	}
+
+	// Data from before key IDs is tried with every key
+	legacy := *old
+	legacy.KeyID = ""
+	decrypted, err = service.DecryptField(&legacy)
+	if err != nil {
+		t.Fatalf("Failed to decrypt data without a key ID: %v", err)
+	}
+	if decrypted != "old secret" {
+		t.Errorf("Expected 'old secret', got %q", decrypted)
+	}
+	if !service.OwnsField(&legacy) || service.UsesCurrentKey(&legacy) {
+		t.Error("Expected legacy data under a previous key to be owned but not current")
+	}
+	legacyCurrent := *current
+	legacyCurrent.KeyID = ""
+	if !service.UsesCurrentKey(&legacyCurrent) {
+		t.Error("Expected legacy data under the current key to be current")
+	}
+
+	// Keys derived before the rotation are still derived
+	salt := []byte("salt")
+	derived, err := oldService.DeriveKey(salt, []byte("info"))
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	keys, err := service.DeriveKeys(salt, []byte("info"))
+	if err != nil {
+		t.Fatalf("Failed to derive keys: %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[1], derived) {
+		t.Error("Expected the previous key's derived key second")
+	}
+}
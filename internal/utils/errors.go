@@ -3,8 +3,6 @@ package utils
 import (
 	"errors"
 	"fmt"
-
-	// "github.com/mark3labs/mcp-go/mcp"
 )
 
 // Custom error types
@@ -148,86 +146,86 @@ func IsDatabaseError(err error) bool {
 	return errors.Is(err, ErrDatabase)
 }
 
-// ToMCPError converts our custom errors to appropriate MCP error responses
-func ToMCPError(err error) error {
+// JSON-RPC error codes returned to MCP clients. Codes from -32000 to -32099 are
+// reserved for implementation-defined server errors.
+const (
+	MCPCodeInvalidParams = -32602
+	MCPCodeInternalError = -32603
+	MCPCodeNotFound      = -32001
+	MCPCodeQuotaExceeded = -32002
+	MCPCodeConflict      = -32003
+)
+
+// MCPError is a JSON-RPC error. Data always carries a "type" naming the failure
+// (validation, not_found, conflict, internal, ...) plus fields specific to it, so
+// clients can branch on the failure without parsing the message.
+type MCPError struct {
+	Code    int                    `json:"code"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	return e.Message
+}
+
+// NewMCPError creates an MCP error of the given type
+func NewMCPError(code int, errorType, message string, data map[string]interface{}) *MCPError {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["type"] = errorType
+	return &MCPError{Code: code, Message: message, Data: data}
+}
+
+// ToMCPError converts our custom errors to the matching MCP error. Errors that
+// are already MCP errors are returned as is; anything unrecognised is an internal
+// error.
+func ToMCPError(err error) *MCPError {
 	if err == nil {
 		return nil
 	}
 
-	// Temporarily return the error as is until MCP package is properly configured
-	return err
-	
-	// TODO: Uncomment when MCP package functions are available
-	// Check for specific error types and convert to appropriate MCP errors
-	// switch {
-	// case IsValidationError(err):
-	// 	var validationErr *ValidationError
-	// 	if errors.As(err, &validationErr) {
-	// 		return mcp.NewError(
-	// 			mcp.ErrInvalidParams,
-	// 			validationErr.Error(),
-	// 			nil,
-	// 		)
-	// 	}
-	// 	return mcp.NewError(
-	// 		mcp.ErrInvalidParams,
-	// 		err.Error(),
-	// 		nil,
-	// 	)
-
-	// case IsNotFoundError(err):
-	// 	var notFoundErr *NotFoundError
-	// 	if errors.As(err, &notFoundErr) {
-	// 		return mcp.NewError(
-	// 			mcp.ErrResourceNotFound,
-	// 			notFoundErr.Error(),
-	// 			nil,
-	// 		)
-	// 	}
-	// 	return mcp.NewError(
-	// 		mcp.ErrResourceNotFound,
-	// 		err.Error(),
-	// 		nil,
-	// 	)
-
-	// case IsConflictError(err):
-	// 	var conflictErr *ConflictError
-	// 	if errors.As(err, &conflictErr) {
-	// 		return mcp.NewError(
-	// 			mcp.ErrResourceAlreadyExists,
-	// 			conflictErr.Error(),
-	// 			nil,
-	// 		)
-	// 	}
-	// 	return mcp.NewError(
-	// 		mcp.ErrResourceAlreadyExists,
-	// 		err.Error(),
-	// 		nil,
-	// 	)
-
-	// case IsDatabaseError(err):
-	// 	var dbErr *DatabaseError
-	// 	if errors.As(err, &dbErr) {
-	// 		return mcp.NewError(
-	// 			mcp.ErrInternalError,
-	// 			fmt.Sprintf("Internal server error: %s", dbErr.Operation),
-	// 			nil,
-	// 		)
-	// 	}
-	// 	return mcp.NewError(
-	// 		mcp.ErrInternalError,
-	// 		"Internal server error",
-	// 		nil,
-	// 	)
-
-	// default:
-	// 	// For any other errors, return a generic internal error
-	// 	return mcp.NewError(
-	// 		mcp.ErrInternalError,
-	// 		"Internal server error",
-	// 		nil,
-	// 	)
-	// }
+	var mcpErr *MCPError
+	if errors.As(err, &mcpErr) {
+		return mcpErr
+	}
+
+	var validationErr *ValidationError
+	var notFoundErr *NotFoundError
+	var conflictErr *ConflictError
+	var dbErr *DatabaseError
+	switch {
+	case errors.As(err, &validationErr):
+		return NewMCPError(MCPCodeInvalidParams, "validation", err.Error(), map[string]interface{}{
+			"field": validationErr.Field,
+		})
+	case errors.Is(err, ErrValidation):
+		return NewMCPError(MCPCodeInvalidParams, "validation", err.Error(), nil)
+
+	case errors.As(err, &notFoundErr):
+		return NewMCPError(MCPCodeNotFound, "not_found", err.Error(), map[string]interface{}{
+			"resource": notFoundErr.Resource,
+			"id":       notFoundErr.ID,
+		})
+	case errors.Is(err, ErrNotFound):
+		return NewMCPError(MCPCodeNotFound, "not_found", err.Error(), nil)
+
+	case errors.As(err, &conflictErr):
+		return NewMCPError(MCPCodeConflict, "conflict", err.Error(), map[string]interface{}{
+			"resource": conflictErr.Resource,
+			"field":    conflictErr.Field,
+		})
+	case errors.Is(err, ErrConflict):
+		return NewMCPError(MCPCodeConflict, "conflict", err.Error(), nil)
+
+	case errors.As(err, &dbErr):
+		// The cause may include SQL, so only the operation is reported
+		return NewMCPError(MCPCodeInternalError, "internal", fmt.Sprintf("Internal server error: %s", dbErr.Operation), nil)
+
+	default:
+		return NewMCPError(MCPCodeInternalError, "internal", err.Error(), nil)
+	}
 }
 
 // Helper function to create a validation error for required fields
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -314,34 +315,43 @@ func TestToMCPError(t *testing.T) {
 		assert.Nil(t, result)
 	})
 
-	t.Run("Returns error as-is for now", func(t *testing.T) {
-		originalErr := errors.New("test error")
-		result := ToMCPError(originalErr)
-		assert.Equal(t, originalErr, result)
+	t.Run("Unknown error is internal", func(t *testing.T) {
+		result := ToMCPError(errors.New("test error"))
+		assert.Equal(t, MCPCodeInternalError, result.Code)
+		assert.Equal(t, "test error", result.Message)
+		assert.Equal(t, "internal", result.Data["type"])
 	})
 
-	t.Run("ValidationError returns error as-is", func(t *testing.T) {
-		originalErr := WrapValidationError("field", "message")
-		result := ToMCPError(originalErr)
-		assert.Equal(t, originalErr, result)
+	t.Run("ValidationError is invalid params", func(t *testing.T) {
+		result := ToMCPError(fmt.Errorf("store: %w", WrapValidationError("field", "message")))
+		assert.Equal(t, MCPCodeInvalidParams, result.Code)
+		assert.Equal(t, "validation", result.Data["type"])
+		assert.Equal(t, "field", result.Data["field"])
 	})
 
-	t.Run("NotFoundError returns error as-is", func(t *testing.T) {
-		originalErr := WrapNotFoundError("resource", "id")
-		result := ToMCPError(originalErr)
-		assert.Equal(t, originalErr, result)
+	t.Run("NotFoundError is not found", func(t *testing.T) {
+		result := ToMCPError(WrapNotFoundError("memory", "42"))
+		assert.Equal(t, MCPCodeNotFound, result.Code)
+		assert.Equal(t, "not_found", result.Data["type"])
+		assert.Equal(t, "memory", result.Data["resource"])
+		assert.Equal(t, "42", result.Data["id"])
 	})
 
-	t.Run("ConflictError returns error as-is", func(t *testing.T) {
-		originalErr := WrapConflictError("resource", "field", "value")
-		result := ToMCPError(originalErr)
-		assert.Equal(t, originalErr, result)
+	t.Run("ConflictError is conflict", func(t *testing.T) {
+		result := ToMCPError(WrapConflictError("resource", "field", "value"))
+		assert.Equal(t, MCPCodeConflict, result.Code)
+		assert.Equal(t, "conflict", result.Data["type"])
 	})
 
-	t.Run("DatabaseError returns error as-is", func(t *testing.T) {
-		originalErr := WrapDatabaseError("operation", errors.New("cause"))
-		result := ToMCPError(originalErr)
-		assert.Equal(t, originalErr, result)
+	t.Run("DatabaseError hides the cause", func(t *testing.T) {
+		result := ToMCPError(WrapDatabaseError("operation", errors.New("syntax error at SELECT")))
+		assert.Equal(t, MCPCodeInternalError, result.Code)
+		assert.NotContains(t, result.Message, "SELECT")
+	})
+
+	t.Run("MCPError is returned as is", func(t *testing.T) {
+		original := NewMCPError(MCPCodeQuotaExceeded, "quota_exceeded", "limit reached", nil)
+		assert.Same(t, original, ToMCPError(fmt.Errorf("store: %w", original)))
 	})
 }
 
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// KeyManager encrypts and decrypts master keys with a key management service, so
+// the master key is never configured in plaintext
+type KeyManager interface {
+	// Name identifies the service
+	Name() string
+	// Encrypt returns the ciphertext of plaintext in the service's own format
+	Encrypt(ctx context.Context, plaintext []byte) (string, error)
+	// Decrypt returns the plaintext of a ciphertext from Encrypt
+	Decrypt(ctx context.Context, ciphertext string) ([]byte, error)
+}
+
+// NewEncryptionServiceWithKMS creates an encryption service whose master keys are
+// configured encrypted with a key management service, and decrypted by it once
+func NewEncryptionServiceWithKMS(ctx context.Context, kms KeyManager, encryptedMasterKey string, encryptedPreviousKeys ...string) (*EncryptionService, error) {
+	if encryptedMasterKey == "" {
+		return nil, fmt.Errorf("master key cannot be empty")
+	}
+
+	masterKey, err := kms.Decrypt(ctx, encryptedMasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt master key with %s: %w", kms.Name(), err)
+	}
+	previousKeys := make([]string, 0, len(encryptedPreviousKeys))
+	for i, encrypted := range encryptedPreviousKeys {
+		previous, err := kms.Decrypt(ctx, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt previous key %d with %s: %w", i+1, kms.Name(), err)
+		}
+		previousKeys = append(previousKeys, base64.StdEncoding.EncodeToString(previous))
+	}
+	return NewEncryptionService(base64.StdEncoding.EncodeToString(masterKey), previousKeys...)
+}
+
+// AWSCredentials are static AWS credentials. Left empty, the SDK's default
+// credential chain is used: environment variables, the shared config and
+// credentials files, web identity tokens, and container or instance roles.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+}
+
+// AWSKMS encrypts with a key in AWS Key Management Service
+type AWSKMS struct {
+	keyID  string
+	client *awskms.Client
+}
+
+// NewAWSKMS creates a client for the key with the given ID, ARN or alias. An
+// empty endpoint uses the region's public one, and an empty region the SDK's
+// default from the environment or shared config.
+func NewAWSKMS(ctx context.Context, endpoint, region, keyID string, credentials AWSCredentials, timeout time.Duration) (*AWSKMS, error) {
+	options := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(region),
+		awsconfig.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(timeout)),
+	}
+	if credentials.AccessKeyID != "" {
+		options = append(options, awsconfig.WithCredentialsProvider(awscredentials.NewStaticCredentialsProvider(
+			credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken)))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	client := awskms.NewFromConfig(cfg, func(o *awskms.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return &AWSKMS{keyID: keyID, client: client}, nil
+}
+
+// Name identifies the service
+func (k *AWSKMS) Name() string {
+	return "aws"
+}
+
+// Encrypt returns the base64 ciphertext blob of plaintext
+func (k *AWSKMS) Encrypt(ctx context.Context, plaintext []byte) (string, error) {
+	out, err := k.client.Encrypt(ctx, &awskms.EncryptInput{KeyId: aws.String(k.keyID), Plaintext: plaintext})
+	if err != nil {
+		return "", fmt.Errorf("AWS KMS Encrypt request failed: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(out.CiphertextBlob), nil
+}
+
+// Decrypt returns the plaintext of a base64 ciphertext blob
+func (k *AWSKMS) Decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext format: %w", err)
+	}
+	input := &awskms.DecryptInput{CiphertextBlob: blob}
+	if k.keyID != "" {
+		input.KeyId = aws.String(k.keyID)
+	}
+	out, err := k.client.Decrypt(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("AWS KMS Decrypt request failed: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+// GCPKMS encrypts with a key in Google Cloud Key Management Service
+type GCPKMS struct {
+	endpoint string
+	keyName  string
+	token    string
+	client   *http.Client
+	// metadataURL issues access tokens for the instance's service account when no
+	// token is configured
+	metadataURL string
+}
+
+// GCPMetadataTokenURL issues access tokens on Google Cloud instances
+const GCPMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// NewGCPKMS creates a client for the key with the given resource name,
+// projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>. An
+// empty token is fetched from the instance metadata server on each call; an empty
+// endpoint uses the public one.
+func NewGCPKMS(endpoint, keyName, token string, timeout time.Duration) *GCPKMS {
+	if endpoint == "" {
+		endpoint = "https://cloudkms.googleapis.com"
+	}
+	return &GCPKMS{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		keyName:     keyName,
+		token:       token,
+		client:      &http.Client{Timeout: timeout},
+		metadataURL: GCPMetadataTokenURL,
+	}
+}
+
+// Name identifies the service
+func (k *GCPKMS) Name() string {
+	return "gcp"
+}
+
+// Encrypt returns the base64 ciphertext of plaintext
+func (k *GCPKMS) Encrypt(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := k.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Ciphertext, nil
+}
+
+// Decrypt returns the plaintext of a base64 ciphertext
+func (k *GCPKMS) Decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := k.call(ctx, "decrypt", map[string]string{"ciphertext": ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends an authorised request for a KMS method
+func (k *GCPKMS) call(ctx context.Context, method string, request, response interface{}) error {
+	token := k.token
+	if token == "" {
+		var err error
+		if token, err = k.metadataToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode KMS request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint+"/v1/"+k.keyName+":"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create KMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return doKMSRequest(k.client, req, "Google Cloud KMS "+method, response)
+}
+
+// metadataToken fetches an access token for the instance's service account
+func (k *GCPKMS) metadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.metadataURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doKMSRequest(k.client, req, "metadata server token", &resp); err != nil {
+		return "", fmt.Errorf("no Google Cloud KMS token configured: %w", err)
+	}
+	return resp.AccessToken, nil
+}
+
+// VaultTransit encrypts with a key in HashiCorp Vault's transit secrets engine
+type VaultTransit struct {
+	address string
+	mount   string
+	keyName string
+	token   string
+	client  *http.Client
+}
+
+// NewVaultTransit creates a client for the named transit key. An empty mount
+// uses transit.
+func NewVaultTransit(address, mount, keyName, token string, timeout time.Duration) *VaultTransit {
+	if mount == "" {
+		mount = "transit"
+	}
+	return &VaultTransit{
+		address: strings.TrimSuffix(address, "/"),
+		mount:   strings.Trim(mount, "/"),
+		keyName: keyName,
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the service
+func (k *VaultTransit) Name() string {
+	return "vault"
+}
+
+// Encrypt returns the vault:v<n>: ciphertext of plaintext
+func (k *VaultTransit) Encrypt(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := k.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+// Decrypt returns the plaintext of a vault:v<n>: ciphertext
+func (k *VaultTransit) Decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext []byte `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := k.call(ctx, "decrypt", map[string]string{"ciphertext": ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data.Plaintext, nil
+}
+
+// call sends an authorised request to a transit endpoint
+func (k *VaultTransit) call(ctx context.Context, operation string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode Vault request: %w", err)
+	}
+	u := k.address + "/v1/" + k.mount + "/" + operation + "/" + url.PathEscape(k.keyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", k.token)
+
+	return doKMSRequest(k.client, req, "Vault transit "+operation, response)
+}
+
+// doKMSRequest sends a request and decodes its JSON response; what names the
+// request in errors
+func doKMSRequest(client *http.Client, req *http.Request, what string, response interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", what, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d: %s", what, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reversingKMS "encrypts" by reversing the plaintext and tagging it
+func reversingKMS(plaintext []byte) []byte {
+	reversed := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		reversed[len(plaintext)-1-i] = b
+	}
+	return append([]byte("kms:"), reversed...)
+}
+
+func reversingKMSDecrypt(ciphertext []byte) ([]byte, bool) {
+	if !strings.HasPrefix(string(ciphertext), "kms:") {
+		return nil, false
+	}
+	return reversingKMS(ciphertext[4:])[4:], true
+}
+
+// awsKMSServer answers KMS Encrypt and Decrypt calls signed by accessKeyID
+func awsKMSServer(t *testing.T, accessKeyID, sessionToken string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+		assert.Equal(t, sessionToken, r.Header.Get("X-Amz-Security-Token"))
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+
+		var req struct {
+			KeyId          string
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "alias/remember-me", req.KeyId)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": reversingKMS(req.Plaintext)})
+		case "TrentService.Decrypt":
+			plaintext, ok := reversingKMSDecrypt(req.CiphertextBlob)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": plaintext})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestAWSKMS(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("static credentials", func(t *testing.T) {
+		server := awsKMSServer(t, "AKID", "session")
+		defer server.Close()
+
+		kms, err := NewAWSKMS(ctx, server.URL, "eu-west-1", "alias/remember-me", AWSCredentials{
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret",
+			SessionToken:    "session",
+		}, 5*time.Second)
+		require.NoError(t, err)
+		testKeyManager(t, kms)
+
+		_, err = kms.Decrypt(ctx, base64.StdEncoding.EncodeToString([]byte("forged")))
+		assert.ErrorContains(t, err, "InvalidCiphertextException")
+	})
+
+	t.Run("default credential chain", func(t *testing.T) {
+		server := awsKMSServer(t, "ENVKEY", "")
+		defer server.Close()
+
+		t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+		t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+		t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+		t.Setenv("AWS_SESSION_TOKEN", "")
+		t.Setenv("AWS_REGION", "eu-west-1")
+
+		kms, err := NewAWSKMS(ctx, server.URL, "", "alias/remember-me", AWSCredentials{}, 5*time.Second)
+		require.NoError(t, err)
+		testKeyManager(t, kms)
+	})
+}
+
+func TestGCPKMS(t *testing.T) {
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			tokens++
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "metadata-token"})
+			return
+		}
+		assert.Equal(t, "Bearer metadata-token", r.Header.Get("Authorization"))
+
+		var req struct {
+			Plaintext  []byte `json:"plaintext"`
+			Ciphertext []byte `json:"ciphertext"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": reversingKMS(req.Plaintext)})
+		case "/v1/" + keyName + ":decrypt":
+			plaintext, _ := reversingKMSDecrypt(req.Ciphertext)
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": plaintext})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kms := NewGCPKMS(server.URL, keyName, "", 5*time.Second)
+	kms.metadataURL = server.URL + "/token"
+	testKeyManager(t, kms)
+	assert.Positive(t, tokens, "tokens come from the metadata server")
+}
+
+func TestVaultTransit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch r.URL.Path {
+		case "/v1/secrets/transit/encrypt/remember-me":
+			plaintext, err := base64.StdEncoding.DecodeString(req["plaintext"])
+			require.NoError(t, err)
+			ciphertext := "vault:v1:" + base64.StdEncoding.EncodeToString(reversingKMS(plaintext))
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext}})
+		case "/v1/secrets/transit/decrypt/remember-me":
+			encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(req["ciphertext"], "vault:v1:"))
+			require.NoError(t, err)
+			plaintext, _ := reversingKMSDecrypt(encrypted)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string][]byte{"plaintext": plaintext}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kms := NewVaultTransit(server.URL+"/", "/secrets/transit/", "remember-me", "s.token", 5*time.Second)
+	testKeyManager(t, kms)
+
+	ciphertext, err := kms.Encrypt(context.Background(), []byte("key"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "vault:v1:"))
+
+	denied := NewVaultTransit(server.URL, "secrets/transit", "remember-me", "wrong", 5*time.Second)
+	_, err = denied.Decrypt(context.Background(), ciphertext)
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+// testKeyManager checks that master keys encrypted with kms open an encryption
+// service holding the same keys
+func testKeyManager(t *testing.T, kms KeyManager) {
+	t.Helper()
+	ctx := context.Background()
+
+	masterKey, err := GenerateMasterKey()
+	require.NoError(t, err)
+	previousKey, err := GenerateMasterKey()
+	require.NoError(t, err)
+	encryptedMaster := encryptTestKey(t, kms, masterKey)
+	encryptedPrevious := encryptTestKey(t, kms, previousKey)
+
+	service, err := NewEncryptionServiceWithKMS(ctx, kms, encryptedMaster, encryptedPrevious)
+	require.NoError(t, err)
+	plain, err := NewEncryptionService(masterKey, previousKey)
+	require.NoError(t, err)
+	assert.Equal(t, plain.KeyIDs(), service.KeyIDs())
+
+	encrypted, err := plain.EncryptField("secret")
+	require.NoError(t, err)
+	content, err := service.DecryptField(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", content)
+
+	_, err = NewEncryptionServiceWithKMS(ctx, kms, "")
+	assert.Error(t, err)
+}
+
+func encryptTestKey(t *testing.T, kms KeyManager, key string) string {
+	t.Helper()
+	plaintext, err := base64.StdEncoding.DecodeString(key)
+	require.NoError(t, err)
+	ciphertext, err := kms.Encrypt(context.Background(), plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, key)
+	return ciphertext
+}
@@ -0,0 +1,564 @@
+// Package memory embeds the remember-me memory engine in other Go applications,
+// without running the HTTP or MCP servers:
+//
+//	engine, err := memory.Open(ctx, memory.Config{
+//		Host:         "localhost",
+//		DBName:       "remember_me",
+//		OpenAIAPIKey: os.Getenv("OPENAI_API_KEY"),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer engine.Close()
+//
+//	stored, err := engine.Store(ctx, memory.StoreRequest{Content: "Prefers espresso"})
+//	found, err := engine.Search(ctx, memory.SearchRequest{Query: "coffee"})
+//
+// This package follows semantic versioning: its exported API only changes
+// incompatibly in a new major version. The internal packages it is built on carry
+// no such guarantee and should not be imported.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ksred/remember-me-mcp/internal/config"
+	"github.com/ksred/remember-me-mcp/internal/database"
+	"github.com/ksred/remember-me-mcp/internal/models"
+	"github.com/ksred/remember-me-mcp/internal/services"
+	"github.com/ksred/remember-me-mcp/internal/utils"
+)
+
+// Memory types
+const (
+	TypeFact         = models.TypeFact
+	TypeConversation = models.TypeConversation
+	TypeContext      = models.TypeContext
+	TypePreference   = models.TypePreference
+)
+
+// Memory categories
+const (
+	CategoryPersonal = models.CategoryPersonal
+	CategoryProject  = models.CategoryProject
+	CategoryBusiness = models.CategoryBusiness
+)
+
+// Memory priorities
+const (
+	PriorityLow      = models.PriorityLow
+	PriorityMedium   = models.PriorityMedium
+	PriorityHigh     = models.PriorityHigh
+	PriorityCritical = models.PriorityCritical
+)
+
+// Search modes
+const (
+	SearchKeyword  = services.SearchModeKeyword
+	SearchSemantic = services.SearchModeSemantic
+	SearchHybrid   = services.SearchModeHybrid
+)
+
+var (
+	// ErrNotFound is returned for memories that don't exist or belong to another user
+	ErrNotFound = errors.New("memory: not found")
+	// ErrInvalid is returned for requests that fail validation
+	ErrInvalid = errors.New("memory: invalid request")
+	// ErrQuotaExceeded is returned when a store would exceed the memory limit and
+	// the eviction policy rejects new memories
+	ErrQuotaExceeded = errors.New("memory: quota exceeded")
+	// ErrConfirmationRequired is returned when a critical memory is deleted
+	// without a confirmation token; see ConfirmationToken
+	ErrConfirmationRequired = errors.New("memory: confirmation required")
+)
+
+// Config is the minimal configuration of an embedded engine
+type Config struct {
+	// Postgres connection; the database needs the pgvector extension. Ignored
+	// when InMemory is set.
+	Host     string
+	Port     int
+	User     string
+	Password string
+	DBName   string
+	SSLMode  string
+
+	// InMemory keeps memories in an in-memory SQLite database that is lost on
+	// Close. SQLite has no vector type, so searches match keywords.
+	InMemory bool
+
+	// SkipMigrations leaves the schema alone, for databases a server migrates
+	SkipMigrations bool
+
+	// EmbeddingProvider is openai (the default), ollama, voyage, cohere, onnx or
+	// mock. openai without OpenAIAPIKey embeds with mock vectors.
+	EmbeddingProvider string
+	EmbeddingModel    string
+	EmbeddingBaseURL  string
+	// EmbeddingAPIKey authenticates with voyage and cohere
+	EmbeddingAPIKey string
+	OpenAIAPIKey    string
+	// Embedder, when set, is used instead of EmbeddingProvider
+	Embedder Embedder
+
+	// EncryptionKey is a base64 master key, as printed by keygen. When set,
+	// memory content is encrypted at rest.
+	EncryptionKey string
+	// PreviousEncryptionKeys are master keys rotated away from, newest first;
+	// content still encrypted with them remains readable
+	PreviousEncryptionKeys []string
+
+	// MemoryLimit is the number of memories kept per user; 0 means unlimited
+	MemoryLimit int
+	// EvictionPolicy decides what happens at the limit: oldest_first (the
+	// default), least_accessed, least_recently_used, lowest_priority or reject_new
+	EvictionPolicy string
+	// SimilarityThreshold is the minimum similarity of semantic matches,
+	// defaulting to 0.7
+	SimilarityThreshold float64
+
+	// Logger receives the engine's logs; nil discards them
+	Logger *zerolog.Logger
+}
+
+// Embedder turns text into embedding vectors
+type Embedder interface {
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	// GenerateEmbeddings embeds several texts at once, returning vectors in the
+	// same order as the texts
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Memory is a stored memory
+type Memory struct {
+	ID        uint                   `json:"id"`
+	Type      string                 `json:"type"`
+	Category  string                 `json:"category"`
+	Priority  string                 `json:"priority"`
+	Content   string                 `json:"content"`
+	UpdateKey string                 `json:"update_key,omitempty"`
+	Tags      []string               `json:"tags"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// StoreRequest describes a memory to store
+type StoreRequest struct {
+	Content string
+	// Type defaults to fact and Category to personal
+	Type     string
+	Category string
+	// Priority defaults to medium
+	Priority string
+	// UpdateKey, when a memory with the same key exists, updates it instead of
+	// storing a new one
+	UpdateKey string
+	Tags      []string
+	Metadata  map[string]interface{}
+}
+
+// SearchRequest describes a search. An empty Query lists memories, newest first.
+type SearchRequest struct {
+	Query    string
+	Category string
+	Type     string
+	// Tags keeps memories with any of the tags
+	Tags []string
+	// Limit defaults to 10
+	Limit int
+	// Mode is SearchSemantic (the default), SearchKeyword or SearchHybrid
+	Mode string
+}
+
+// UpdateRequest changes a memory; empty fields are left unchanged
+type UpdateRequest struct {
+	Content  string
+	Category string
+	Type     string
+	Priority string
+	Tags     []string
+	Metadata map[string]interface{}
+}
+
+// Engine stores and searches memories for one user, in one of the user's
+// workspaces. It is safe for concurrent use.
+type Engine struct {
+	db            *database.Database
+	embedding     services.EmbeddingService
+	serviceConfig map[string]interface{}
+	logger        zerolog.Logger
+	service       *services.MemoryService
+}
+
+// Open connects to the database, migrates it unless SkipMigrations is set, and
+// returns an engine for the system user's default workspace. Use ForUser to work
+// with other users, and InWorkspace with other workspaces.
+func Open(ctx context.Context, cfg Config) (*Engine, error) {
+	logger := zerolog.Nop()
+	if cfg.Logger != nil {
+		logger = *cfg.Logger
+	}
+	appConfig := cfg.appConfig()
+
+	var encryptionService *utils.EncryptionService
+	if cfg.EncryptionKey != "" {
+		var err error
+		if encryptionService, err = utils.NewEncryptionService(cfg.EncryptionKey, cfg.PreviousEncryptionKeys...); err != nil {
+			return nil, fmt.Errorf("%w: encryption key: %v", ErrInvalid, err)
+		}
+	}
+
+	var embedding services.EmbeddingService = cfg.Embedder
+	if embedding == nil {
+		var err error
+		if embedding, err = services.NewEmbeddingServiceFromConfig(appConfig, logger); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+
+	var (
+		db  *database.Database
+		err error
+	)
+	if cfg.InMemory {
+		db, err = database.OpenInMemory("silent")
+	} else {
+		db, err = database.Open(appConfig.Database, "silent")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.InMemory && !cfg.SkipMigrations {
+		if err := database.RunMigrations(db.DB().WithContext(ctx)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		vectorIndex := database.VectorIndexOptionsFromConfig(appConfig.Database.VectorIndex)
+		if err := database.EnsureVectorIndex(ctx, db.DB(), vectorIndex, logger); err != nil {
+			logger.Error().Err(err).Str("type", vectorIndex.Type).Msg("failed to build vector index")
+		}
+	}
+
+	serviceConfig := map[string]interface{}{
+		"memory_limit":              appConfig.Memory.MaxMemories,
+		"similarity_threshold":      appConfig.Memory.SimilarityThreshold,
+		"priority_boosts":           appConfig.Memory.PriorityBoosts,
+		"eviction_policy":           appConfig.Memory.EvictionPolicy,
+		"duplicate_threshold":       appConfig.Memory.DuplicateThreshold,
+		"feedback_weight":           appConfig.Memory.FeedbackWeight,
+		"max_content_length":        appConfig.Memory.MaxContentLength,
+		"chunk_size":                appConfig.Memory.ChunkSize,
+		"attachment_max_size":       appConfig.Attachments.MaxSize,
+		"attachment_max_per_memory": appConfig.Attachments.MaxPerMemory,
+		"exact_counts":              appConfig.Memory.ExactCounts,
+		"normalize_detected":        appConfig.Memory.NormalizeDetected,
+		"filler_words":              appConfig.Memory.FillerWords,
+		"maintenance_stale_after":   appConfig.Maintenance.StaleAfter,
+		"maintenance_max_actions":   appConfig.Maintenance.MaxActions,
+		"consolidation_similarity":  appConfig.Consolidation.Similarity,
+		"consolidation_min_cluster": appConfig.Consolidation.MinClusterSize,
+		"consolidation_max_cluster": appConfig.Consolidation.MaxClusterSize,
+		"write_timeout":             appConfig.Timeouts.Write,
+		"embedding_cache":           appConfig.Embedding.Cache,
+		"async_embeddings":          services.NewAsyncEmbeddings(logger),
+	}
+	if encryptionService != nil {
+		serviceConfig["encryption_service"] = encryptionService
+	}
+	attachmentStore, err := services.NewAttachmentStoreFromConfig(appConfig)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if attachmentStore != nil {
+		serviceConfig["attachment_store"] = attachmentStore
+	}
+	if searchCache := services.NewSearchCacheFromConfig(appConfig); searchCache != nil {
+		if err := db.DB().Use(searchCache); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to enable search cache: %w", err)
+		}
+		serviceConfig["search_cache"] = searchCache
+	}
+
+	engine := &Engine{
+		db:            db,
+		embedding:     embedding,
+		serviceConfig: serviceConfig,
+		logger:        logger,
+	}
+	engine.service = services.NewMemoryService(db.DB(), embedding, logger, serviceConfig).InWorkspace(0)
+	return engine, nil
+}
+
+// appConfig fills the application configuration from the defaults and cfg
+func (cfg Config) appConfig() *config.Config {
+	appConfig := config.NewDefault()
+	if cfg.Host != "" {
+		appConfig.Database.Host = cfg.Host
+	}
+	if cfg.Port != 0 {
+		appConfig.Database.Port = cfg.Port
+	}
+	if cfg.User != "" {
+		appConfig.Database.User = cfg.User
+	}
+	appConfig.Database.Password = cfg.Password
+	if cfg.DBName != "" {
+		appConfig.Database.DBName = cfg.DBName
+	}
+	if cfg.SSLMode != "" {
+		appConfig.Database.SSLMode = cfg.SSLMode
+	}
+
+	if cfg.EmbeddingProvider != "" {
+		appConfig.Embedding.Provider = cfg.EmbeddingProvider
+	}
+	appConfig.Embedding.Model = cfg.EmbeddingModel
+	appConfig.Embedding.BaseURL = cfg.EmbeddingBaseURL
+	appConfig.Embedding.APIKey = cfg.EmbeddingAPIKey
+	appConfig.OpenAI.APIKey = cfg.OpenAIAPIKey
+
+	appConfig.Memory.MaxMemories = cfg.MemoryLimit
+	if cfg.EvictionPolicy != "" {
+		appConfig.Memory.EvictionPolicy = cfg.EvictionPolicy
+	}
+	if cfg.SimilarityThreshold != 0 {
+		appConfig.Memory.SimilarityThreshold = cfg.SimilarityThreshold
+	}
+	return appConfig
+}
+
+// ForUser returns an engine sharing this one's connection whose memories belong
+// to the given user, which must exist, in their default workspace. Closing it
+// closes the shared connection.
+func (e *Engine) ForUser(userID uint) *Engine {
+	scoped := *e
+	if userID <= 1 {
+		scoped.service = services.NewMemoryService(e.db.DB(), e.embedding, e.logger, e.serviceConfig)
+	} else {
+		scoped.service = services.NewMemoryServiceWithUser(e.db.DB(), e.embedding, e.logger, e.serviceConfig, userID)
+	}
+	scoped.service = scoped.service.InWorkspace(0)
+	return &scoped
+}
+
+// InWorkspace returns an engine sharing this one's connection whose memories are
+// kept in the user's named workspace, which must exist; "default" names the
+// default workspace. Closing it closes the shared connection.
+func (e *Engine) InWorkspace(ctx context.Context, name string) (*Engine, error) {
+	workspaceID, err := e.service.ResolveWorkspace(ctx, name)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	scoped := *e
+	scoped.service = e.service.InWorkspace(workspaceID)
+	return &scoped, nil
+}
+
+// CreateWorkspace creates a workspace for the engine's user
+func (e *Engine) CreateWorkspace(ctx context.Context, name, description string) error {
+	_, err := e.service.CreateWorkspace(ctx, name, description)
+	return translateError(err)
+}
+
+// Drain waits for the embeddings of stored memories, which are generated in the
+// background, shared with engines from ForUser. Ones still running when ctx ends
+// are queued for a server's embedding backfill. Stores after Drain queue their
+// embedding instead of generating it.
+func (e *Engine) Drain(ctx context.Context) error {
+	return e.service.Drain(ctx)
+}
+
+// Close closes the database connection. Embeddings still being generated for
+// memories stored just before are picked up by a server's embedding backfill;
+// call Drain first to wait for them.
+func (e *Engine) Close() error {
+	return e.db.Close()
+}
+
+// Store stores a memory, generating its embedding in the background
+func (e *Engine) Store(ctx context.Context, req StoreRequest) (*Memory, error) {
+	if req.Type == "" {
+		req.Type = TypeFact
+	}
+	if req.Category == "" {
+		req.Category = CategoryPersonal
+	}
+	if err := validateFields(req.Type, req.Category, req.Priority); err != nil {
+		return nil, err
+	}
+
+	memory, err := e.service.Store(ctx, services.StoreRequest{
+		Content:   req.Content,
+		Category:  req.Category,
+		Type:      req.Type,
+		Priority:  req.Priority,
+		UpdateKey: req.UpdateKey,
+		Tags:      req.Tags,
+		Metadata:  req.Metadata,
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return newMemory(memory), nil
+}
+
+// Search finds memories matching the request, best matches first
+func (e *Engine) Search(ctx context.Context, req SearchRequest) ([]*Memory, error) {
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	if req.Mode == "" {
+		req.Mode = SearchSemantic
+	}
+
+	memories, err := e.service.Search(ctx, services.SearchRequest{
+		Query:    req.Query,
+		Category: req.Category,
+		Type:     req.Type,
+		Tags:     req.Tags,
+		Limit:    req.Limit,
+		Mode:     req.Mode,
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	results := make([]*Memory, len(memories))
+	for i, memory := range memories {
+		results[i] = newMemory(memory)
+	}
+	return results, nil
+}
+
+// Get returns the memory with the given ID
+func (e *Engine) Get(ctx context.Context, id uint) (*Memory, error) {
+	memory, err := e.service.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return newMemory(memory), nil
+}
+
+// Update changes a memory, regenerating its embedding when the content changes
+func (e *Engine) Update(ctx context.Context, id uint, req UpdateRequest) (*Memory, error) {
+	if err := validateFields(req.Type, req.Category, req.Priority); err != nil {
+		return nil, err
+	}
+
+	memory, err := e.service.Update(ctx, id, services.UpdateRequest{
+		Content:  req.Content,
+		Category: req.Category,
+		Type:     req.Type,
+		Priority: req.Priority,
+		Tags:     req.Tags,
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return newMemory(memory), nil
+}
+
+// Delete deletes the memory with the given ID. Critical memories are refused
+// with ErrConfirmationRequired; use DeleteConfirmed to delete them.
+func (e *Engine) Delete(ctx context.Context, id uint) error {
+	return translateError(e.service.Delete(ctx, id))
+}
+
+// DeleteConfirmed deletes the memory with the given ID. Deleting a critical
+// memory requires confirm to be the token carried by the ErrConfirmationRequired
+// error of a previous attempt; tokens are single use and expire after five
+// minutes:
+//
+//	err := engine.Delete(ctx, id)
+//	if token, ok := memory.ConfirmationToken(err); ok {
+//		err = engine.DeleteConfirmed(ctx, id, token)
+//	}
+func (e *Engine) DeleteConfirmed(ctx context.Context, id uint, confirm string) error {
+	return translateError(e.service.DeleteConfirmed(ctx, id, confirm))
+}
+
+// ConfirmationToken returns the token that confirms the delete err refused, if
+// err is an ErrConfirmationRequired error
+func ConfirmationToken(err error) (string, bool) {
+	var confirmErr *services.ConfirmationRequiredError
+	if !errors.As(err, &confirmErr) {
+		return "", false
+	}
+	return confirmErr.Token, true
+}
+
+// validateFields checks the type, category and priority of a request, any of
+// which may be empty
+func validateFields(memoryType, category, priority string) error {
+	switch {
+	case memoryType != "" && !models.IsValidType(memoryType):
+		return fmt.Errorf("%w: type must be one of fact, conversation, context or preference", ErrInvalid)
+	case category != "" && !models.IsValidCategory(category):
+		return fmt.Errorf("%w: category must be one of personal, project or business", ErrInvalid)
+	case priority != "" && !models.IsValidPriority(priority):
+		return fmt.Errorf("%w: priority must be one of low, medium, high or critical", ErrInvalid)
+	}
+	return nil
+}
+
+// newMemory converts a stored memory to its public form
+func newMemory(memory *models.Memory) *Memory {
+	result := &Memory{
+		ID:        memory.ID,
+		Type:      memory.Type,
+		Category:  memory.Category,
+		Priority:  memory.Priority,
+		Content:   memory.Content,
+		UpdateKey: memory.UpdateKey,
+		Tags:      []string(memory.Tags),
+		CreatedAt: memory.CreatedAt,
+		UpdatedAt: memory.UpdatedAt,
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if len(memory.Metadata) > 0 {
+		_ = json.Unmarshal(memory.Metadata, &result.Metadata)
+	}
+	return result
+}
+
+// kindError classifies an engine error as one of the exported sentinels while
+// keeping its message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string        { return e.err.Error() }
+func (e *kindError) Unwrap() error        { return e.err }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// translateError classifies err as ErrNotFound, ErrInvalid, ErrQuotaExceeded or
+// ErrConfirmationRequired where it is one of those
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case utils.IsNotFoundError(err):
+		return &kindError{kind: ErrNotFound, err: err}
+	case utils.IsValidationError(err):
+		return &kindError{kind: ErrInvalid, err: err}
+	case errors.Is(err, services.ErrMemoryLimitReached):
+		return &kindError{kind: ErrQuotaExceeded, err: err}
+	case errors.Is(err, services.ErrConfirmationRequired):
+		return &kindError{kind: ErrConfirmationRequired, err: err}
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksred/remember-me-mcp/internal/models"
+)
+
+func openTestEngine(t *testing.T, cfg Config) *Engine {
+	cfg.InMemory = true
+	cfg.EmbeddingProvider = "mock"
+	engine, err := Open(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	engine := openTestEngine(t, Config{})
+
+	stored, err := engine.Store(ctx, StoreRequest{
+		Content:  "Prefers espresso in the morning",
+		Tags:     []string{"coffee"},
+		Metadata: map[string]interface{}{"source": "sdk"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TypeFact, stored.Type)
+	assert.Equal(t, CategoryPersonal, stored.Category)
+	assert.Equal(t, []string{"coffee"}, stored.Tags)
+	assert.Equal(t, "sdk", stored.Metadata["source"])
+
+	found, err := engine.Search(ctx, SearchRequest{Query: "espresso"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, stored.ID, found[0].ID)
+
+	updated, err := engine.Update(ctx, stored.ID, UpdateRequest{Content: "Prefers flat whites", Priority: PriorityHigh})
+	require.NoError(t, err)
+	assert.Equal(t, "Prefers flat whites", updated.Content)
+	assert.Equal(t, PriorityHigh, updated.Priority)
+
+	got, err := engine.Get(ctx, stored.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Prefers flat whites", got.Content)
+
+	require.NoError(t, engine.Delete(ctx, stored.ID))
+	_, err = engine.Get(ctx, stored.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = engine.Store(ctx, StoreRequest{Content: "x", Type: "rumour"})
+	assert.True(t, errors.Is(err, ErrInvalid))
+	_, err = engine.Update(ctx, stored.ID, UpdateRequest{Priority: "urgent"})
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func TestEngine_ForUser(t *testing.T) {
+	ctx := context.Background()
+	engine := openTestEngine(t, Config{})
+	require.NoError(t, engine.db.DB().Create(&models.User{ID: 2, Email: "b@example.com", Password: "x"}).Error)
+
+	other := engine.ForUser(2)
+	stored, err := other.Store(ctx, StoreRequest{Content: "Works on the billing service"})
+	require.NoError(t, err)
+
+	found, err := engine.Search(ctx, SearchRequest{Query: "billing"})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	_, err = engine.Get(ctx, stored.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestEngine_InWorkspace(t *testing.T) {
+	ctx := context.Background()
+	engine := openTestEngine(t, Config{})
+	require.NoError(t, engine.CreateWorkspace(ctx, "work", "Memories about my job"))
+
+	work, err := engine.InWorkspace(ctx, "work")
+	require.NoError(t, err)
+	stored, err := work.Store(ctx, StoreRequest{Content: "Works on the billing service"})
+	require.NoError(t, err)
+
+	found, err := engine.Search(ctx, SearchRequest{Query: "billing"})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	_, err = engine.Get(ctx, stored.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	found, err = work.Search(ctx, SearchRequest{Query: "billing"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, stored.ID, found[0].ID)
+
+	_, err = engine.InWorkspace(ctx, "personal")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestEngine_QuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	engine := openTestEngine(t, Config{MemoryLimit: 1, EvictionPolicy: "reject_new"})
+
+	_, err := engine.Store(ctx, StoreRequest{Content: "First memory"})
+	require.NoError(t, err)
+	_, err = engine.Store(ctx, StoreRequest{Content: "Second memory"})
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+}
+
+func TestEngine_DeleteCritical(t *testing.T) {
+	ctx := context.Background()
+	engine := openTestEngine(t, Config{})
+
+	stored, err := engine.Store(ctx, StoreRequest{Content: "Allergic to penicillin", Priority: PriorityCritical})
+	require.NoError(t, err)
+
+	err = engine.Delete(ctx, stored.ID)
+	assert.True(t, errors.Is(err, ErrConfirmationRequired))
+	token, ok := ConfirmationToken(err)
+	require.True(t, ok)
+
+	err = engine.DeleteConfirmed(ctx, stored.ID, "deadbeef")
+	assert.True(t, errors.Is(err, ErrConfirmationRequired))
+	_, ok = ConfirmationToken(errors.New("other"))
+	assert.False(t, ok)
+
+	require.NoError(t, engine.DeleteConfirmed(ctx, stored.ID, token))
+	_, err = engine.Get(ctx, stored.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -170,7 +170,7 @@ build_binary() {
     cd "$(dirname "$0")/.."
     
     # Build the binary
-    go build -o $BINARY_NAME ./cmd/main.go
+    go build -o $BINARY_NAME ./cmd
     
     # Install to system path
     sudo mv $BINARY_NAME $INSTALL_DIR/